@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/rpc"
+)
+
+// broadcastCmd handles `wgmesh broadcast "<text>"`: the local daemon signs
+// the text with the membership key and floods it to every node.
+func broadcastCmd() {
+	fs := flag.NewFlagSet("broadcast", flag.ExitOnError)
+	socket := fs.String("socket", "", "Daemon RPC socket path (default: $WGMESH_SOCKET or the standard path)")
+	fs.Parse(os.Args[2:])
+	text := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(text) == "" {
+		fmt.Fprintln(os.Stderr, `Usage: wgmesh broadcast "<text>"`)
+		os.Exit(1)
+	}
+
+	client := dialDaemon(*socket)
+	defer client.Close()
+
+	result, err := client.Call("mesh.broadcast", map[string]interface{}{"text": text})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+		os.Exit(1)
+	}
+	resultMap, _ := result.(map[string]interface{})
+	id, _ := resultMap["id"].(string)
+	fmt.Printf("Message %s sent to the mesh\n", id)
+}
+
+// messagesCmd handles "wgmesh messages": it lists the broadcasts the local
+// daemon has sent or received since it started.
+func messagesCmd() {
+	fs := flag.NewFlagSet("messages", flag.ExitOnError)
+	socket := fs.String("socket", "", "Daemon RPC socket path (default: $WGMESH_SOCKET or the standard path)")
+	fs.Parse(os.Args[2:])
+
+	client := dialDaemon(*socket)
+	defer client.Close()
+
+	result, err := client.Call("mesh.messages", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+		os.Exit(1)
+	}
+	resultMap, _ := result.(map[string]interface{})
+	messages, _ := resultMap["messages"].([]interface{})
+	if len(messages) == 0 {
+		fmt.Println("No messages")
+		return
+	}
+	for _, raw := range messages {
+		m, _ := raw.(map[string]interface{})
+		hostname, _ := m["hostname"].(string)
+		pubKey, _ := m["pubkey"].(string)
+		text, _ := m["text"].(string)
+		sentAt, _ := m["sent_at"].(string)
+		if t, err := time.Parse(time.RFC3339, sentAt); err == nil {
+			sentAt = t.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Printf("%s  %-20s %s\n", sentAt, peerDisplayName(hostname, pubKey), text)
+	}
+}
+
+// rpcMessageData converts a daemon broadcast to its RPC form.
+func rpcMessageData(m *daemon.RPCMessageData) *rpc.MessageData {
+	return &rpc.MessageData{
+		ID:         m.ID,
+		PubKey:     m.PubKey,
+		Hostname:   m.Hostname,
+		Text:       m.Text,
+		SentAt:     m.SentAt,
+		ReceivedAt: m.ReceivedAt,
+	}
+}
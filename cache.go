@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+// cacheReport is what "cache inspect" found in a peer cache file.
+type cacheReport struct {
+	Path      string                  `json:"path"`
+	Version   int                     `json:"version"`
+	Migrated  bool                    `json:"migrated"`
+	UpdatedAt time.Time               `json:"updated_at"`
+	Peers     []daemon.PeerCacheEntry `json:"peers"`
+}
+
+// cacheCmd handles "wgmesh cache inspect": it shows the peer cache the
+// daemon restores from at startup, without needing the daemon to run.
+func cacheCmd() {
+	if len(os.Args) < 3 || os.Args[2] != "inspect" {
+		fmt.Fprintln(os.Stderr, "Usage: wgmesh cache inspect [--interface NAME] [--json]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Shows the peers cached for an interface and which of them the daemon")
+		fmt.Fprintln(os.Stderr, "would restore at startup.")
+		os.Exit(1)
+	}
+	defaultIface := daemon.DefaultInterface
+	if runtime.GOOS == "darwin" {
+		defaultIface = daemon.DefaultInterfaceDarwin
+	}
+	fset := flag.NewFlagSet("cache inspect", flag.ExitOnError)
+	iface := fset.String("interface", defaultIface, "WireGuard interface whose cache to read")
+	jsonOutput := fset.Bool("json", false, "Output in JSON format")
+	fset.Parse(os.Args[3:])
+
+	path := daemon.CacheFilePath(*iface)
+	cache, version, err := daemon.ReadPeerCacheFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Printf("No peer cache for %s at %s\n", *iface, path)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	report := cacheReport{
+		Path:      path,
+		Version:   version,
+		Migrated:  version < daemon.CacheVersion,
+		UpdatedAt: time.Unix(cache.UpdatedAt, 0),
+		Peers:     cache.Peers,
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	renderCacheReport(os.Stdout, report, time.Now())
+}
+
+func renderCacheReport(w io.Writer, r cacheReport, now time.Time) {
+	restorable := 0
+	for _, p := range r.Peers {
+		if !p.Expired(now) {
+			restorable++
+		}
+	}
+	format := fmt.Sprintf("version %d", r.Version)
+	if r.Migrated {
+		format += fmt.Sprintf(" (migrated to %d on the next save)", daemon.CacheVersion)
+	} else {
+		format += ", checksum ok"
+	}
+	fmt.Fprintf(w, "Cache:    %s\n", r.Path)
+	fmt.Fprintf(w, "Format:   %s\n", format)
+	fmt.Fprintf(w, "Saved:    %s (%s ago)\n", r.UpdatedAt.Format(time.RFC3339), formatDuration(now.Sub(r.UpdatedAt)))
+	fmt.Fprintf(w, "Peers:    %d, %d restorable, %d expired\n", len(r.Peers), restorable, len(r.Peers)-restorable)
+	if len(r.Peers) == 0 {
+		return
+	}
+
+	fmt.Fprintln(w)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "HOSTNAME\tPUBLIC KEY\tMESH IP\tENDPOINT\tLAST SEEN\tRESTORE")
+	for _, p := range r.Peers {
+		restore := "yes"
+		if p.Expired(now) {
+			restore = "expired"
+		}
+		lastSeen := formatDuration(now.Sub(time.Unix(p.LastSeen, 0))) + " ago"
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", orDash(p.Hostname), shortPubKey(p.WGPubKey), orDash(p.MeshIP), orDash(p.Endpoint), lastSeen, restore)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+func TestRenderCacheReport(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	report := cacheReport{
+		Path:      "/var/lib/wgmesh/wg0-peers.json",
+		Version:   0,
+		Migrated:  true,
+		UpdatedAt: now.Add(-10 * time.Minute),
+		Peers: []daemon.PeerCacheEntry{
+			{WGPubKey: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=", Hostname: "alpha", MeshIP: "10.12.0.1", Endpoint: "203.0.113.1:51820", LastSeen: now.Add(-time.Hour).Unix()},
+			{WGPubKey: "BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB=", MeshIP: "10.12.0.2", LastSeen: now.Add(-48 * time.Hour).Unix()},
+		},
+	}
+
+	var buf bytes.Buffer
+	renderCacheReport(&buf, report, now)
+	out := buf.String()
+	for _, want := range []string{"version 0 (migrated to 1", "10m ago", "2, 1 restorable, 1 expired"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	alpha, expired := lines[len(lines)-2], lines[len(lines)-1]
+	for _, want := range []string{"alpha", "203.0.113.1:51820", "1h ago", "yes"} {
+		if !strings.Contains(alpha, want) {
+			t.Errorf("alpha row %q lacks %q", alpha, want)
+		}
+	}
+	for _, want := range []string{"10.12.0.2", "2d ago", "expired"} {
+		if !strings.Contains(expired, want) {
+			t.Errorf("expired row %q lacks %q", expired, want)
+		}
+	}
+}
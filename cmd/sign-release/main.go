@@ -0,0 +1,55 @@
+// Command sign-release signs a release's checksums.txt for 'wgmesh update'.
+// The release workflow runs it from .goreleaser.yml with the private key in
+// WGMESH_RELEASE_SIGNING_KEY (base64 ed25519 seed); the matching public
+// key is built into release binaries as update.PublicKey.
+//
+//	go run ./cmd/sign-release -genkey
+//	go run ./cmd/sign-release -in checksums.txt -out checksums.txt.sig
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/update"
+)
+
+func main() {
+	genkey := flag.Bool("genkey", false, "Print a new signing key (seed) and its public key")
+	in := flag.String("in", "", "checksums.txt to sign")
+	out := flag.String("out", "", "Signature file to write")
+	flag.Parse()
+
+	if *genkey {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "generate key: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("WGMESH_RELEASE_SIGNING_KEY=%s\n", base64.StdEncoding.EncodeToString(priv.Seed()))
+		fmt.Printf("WGMESH_RELEASE_PUBLIC_KEY=%s\n", base64.StdEncoding.EncodeToString(pub))
+		return
+	}
+
+	if *in == "" || *out == "" {
+		fmt.Fprintln(os.Stderr, "usage: sign-release -in checksums.txt -out checksums.txt.sig")
+		os.Exit(1)
+	}
+	seed, err := base64.StdEncoding.DecodeString(os.Getenv("WGMESH_RELEASE_SIGNING_KEY"))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		fmt.Fprintln(os.Stderr, "WGMESH_RELEASE_SIGNING_KEY must hold a base64 ed25519 seed (see -genkey)")
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read %s: %v\n", *in, err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*out, update.SignChecksums(ed25519.NewKeyFromSeed(seed), data), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "write %s: %v\n", *out, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// command is a wgmesh subcommand. Its run function parses os.Args[2:].
+type command struct {
+	name    string
+	group   string
+	summary string
+	run     func()
+	// actions is the usage of commands that take an action word instead of
+	// flags, e.g. "<list|show>"; their help is this line.
+	actions string
+	// hidden commands work but are left out of 'wgmesh help'; they are for
+	// development or still experimental.
+	hidden bool
+}
+
+const (
+	groupMesh    = "Mesh commands"
+	groupService = "Service commands"
+	groupQuery   = "Commands for a running daemon"
+	groupOther   = "Other commands"
+)
+
+var commandGroups = []string{groupMesh, groupService, groupQuery, groupOther}
+
+// commands lists the subcommands in the order 'wgmesh help' shows them.
+// Anything else falls through to the centralized flag CLI.
+var commands = []command{
+	{name: "init", group: groupMesh, summary: "Generate a new mesh secret", run: initCmd},
+	{name: "join", group: groupMesh, summary: "Join a mesh network and run the daemon", run: joinCmd},
+	{name: "status", group: groupMesh, summary: "Show mesh status", run: statusCmd},
+	{name: "qr", group: groupMesh, summary: "Display the secret as a QR code", run: qrCmd},
+	{name: "leave", group: groupMesh, summary: "Say GOODBYE and remove the service, interface and cached state", run: leaveCmd},
+	{name: "rotate-secret", group: groupMesh, summary: "Rotate the mesh secret via the running daemon", run: rotateSecretCmd},
+	{name: "seal-secret", group: groupMesh, summary: "Store the secret encrypted for join --secret-file", run: sealSecretCmd},
+	{name: "sign-peers", group: groupMesh, summary: "Sign a peer manifest for join --static-peers", run: signPeersCmd},
+	{name: "profile", group: groupMesh, summary: "Save join flags for join --profile", run: profileCmd, actions: "<add|list|show|remove>"},
+	{name: "networks", group: groupMesh, summary: "List the meshes joined on this host", run: networksCmd, actions: "<list>"},
+	{name: "agent", group: groupMesh, summary: "Cache the secret for status, qr and test-peer", run: agentCmd},
+	{name: "cache", group: groupMesh, summary: "Inspect the peer cache restored at startup", run: cacheCmd, actions: "<inspect>"},
+	{name: "mesh", group: groupMesh, summary: "Centralized mode: list hostnames and mesh IPs", run: meshCmd, actions: "<list>"},
+
+	{name: "install-service", group: groupService, summary: "Install the wgmesh service", run: installServiceCmd},
+	{name: "uninstall-service", group: groupService, summary: "Remove the wgmesh service", run: uninstallServiceCmd},
+	{name: "service", group: groupService, summary: "Register services for managed ingress", run: serviceCmd, actions: "<add|list|remove>"},
+	{name: "openwrt", group: groupService, summary: "Install or check the OpenWrt procd service", run: openwrtCmd, actions: "<install|uninstall|check>"},
+	{name: "netns", group: groupService, summary: "Give a container an interface routed into the mesh", run: netnsCmd, actions: "<attach|detach>"},
+
+	{name: "peers", group: groupQuery, summary: "List, inspect, approve and forget peers", run: peersCmd, actions: "<list|watch|count|get|top|quarantine|pending|approve|forget>"},
+	{name: "ping", group: groupQuery, summary: "Ping a peer over the mesh", run: pingCmd},
+	{name: "route", group: groupQuery, summary: "Show how a peer is reached", run: routeCmd},
+	{name: "doctor", group: groupQuery, summary: "Check the daemon, clock skew and NTP sync", run: doctorCmd},
+	{name: "wait-online", group: groupQuery, summary: "Block until the interface is up and peers have handshaken", run: waitOnlineCmd},
+	{name: "state", group: groupQuery, summary: "Export or import the peer store", run: stateCmd, actions: "<export|import>"},
+	{name: "config", group: groupQuery, summary: "Show or change daemon options while it runs", run: configCmd, actions: "<get|set>"},
+	{name: "broadcast", group: groupQuery, summary: "Send a signed message to every node", run: broadcastCmd},
+	{name: "messages", group: groupQuery, summary: "Show recent broadcasts", run: messagesCmd},
+	{name: "proxy", group: groupQuery, summary: "SOCKS5/HTTP proxy to mesh hostnames and IPs", run: proxyCmd},
+
+	{name: "version", group: groupOther, summary: "Show version information", run: func() { fmt.Println(versionOutput()) }},
+	{name: "update", group: groupOther, summary: "Install the latest signed release and restart the daemons", run: updateCmd},
+	{name: "referral", group: groupOther, summary: "Show or validate referral codes", run: referralCmd, actions: "<show|stats|validate>"},
+	{name: "pilot", group: groupOther, summary: "Pilot program enrollment and reports", run: pilotCmd, actions: "<init|start|status|report|complete|validate>"},
+	{name: "test-peer", group: groupOther, summary: "Test direct peer exchange with another node", run: testPeerCmd, hidden: true},
+}
+
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// runCommand runs the subcommand named by os.Args[1] and reports whether
+// there was one.
+func runCommand() bool {
+	if len(os.Args) < 2 {
+		return false
+	}
+	if os.Args[1] == "help" {
+		helpCmd()
+		return true
+	}
+	cmd := findCommand(os.Args[1])
+	if cmd == nil {
+		return false
+	}
+	if cmd.actions != "" && len(os.Args) > 2 && isHelpArg(os.Args[2]) {
+		cmd.printActions()
+		return true
+	}
+	cmd.run()
+	return true
+}
+
+func isHelpArg(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+func (c *command) printActions() {
+	fmt.Printf("Usage: wgmesh %s %s\n\n%s\n", c.name, c.actions, c.summary)
+}
+
+// helpCmd handles "help [command]": without a command it lists the
+// subcommands, with one it shows that command's flags.
+func helpCmd() {
+	if len(os.Args) > 2 {
+		cmd := findCommand(os.Args[2])
+		if cmd == nil {
+			fmt.Fprintf(os.Stderr, "Unknown command %q; run 'wgmesh help' for a list\n", os.Args[2])
+			os.Exit(1)
+		}
+		if cmd.actions != "" {
+			cmd.printActions()
+			return
+		}
+		os.Args = []string{os.Args[0], cmd.name, "-h"}
+		cmd.run()
+		return
+	}
+
+	fmt.Println("Usage: wgmesh <command> [flags]")
+	for _, group := range commandGroups {
+		fmt.Printf("\n%s:\n", group)
+		for _, cmd := range commands {
+			if cmd.group == group && !cmd.hidden {
+				fmt.Printf("  %-18s %s\n", cmd.name, cmd.summary)
+			}
+		}
+	}
+	fmt.Println()
+	fmt.Println("Run 'wgmesh help <command>' for a command's flags, or 'wgmesh -h' for the centralized mode flags.")
+}
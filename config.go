@@ -0,0 +1,103 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// configCmd handles "wgmesh config get|set": it reads or changes the
+// options a running daemon can switch without a restart.
+func configCmd() {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: wgmesh config <get|set>")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Commands:")
+		fmt.Fprintln(os.Stderr, "  get [key]          Show the runtime options, or one of them")
+		fmt.Fprintln(os.Stderr, "  set <key> <value>  Change an option on the running daemon")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Options:")
+		fmt.Fprintln(os.Stderr, "  log-level          debug, info, warn or error")
+		fmt.Fprintln(os.Stderr, "  force-relay        true or false")
+		fmt.Fprintln(os.Stderr, "  lan-discovery      true or false")
+		fmt.Fprintln(os.Stderr, "  advertise-routes   comma-separated CIDRs, or \"none\"")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Changes last until the daemon restarts.")
+		os.Exit(1)
+	}
+	if len(os.Args) < 3 {
+		usage()
+	}
+
+	action := os.Args[2]
+	fs := flag.NewFlagSet("config "+action, flag.ExitOnError)
+	socket := fs.String("socket", "", "Daemon RPC socket path (default: $WGMESH_SOCKET or the standard path)")
+	fs.Parse(os.Args[3:])
+
+	switch action {
+	case "get":
+		if fs.NArg() > 1 {
+			fmt.Fprintln(os.Stderr, "Usage: wgmesh config get [key]")
+			os.Exit(1)
+		}
+		params := map[string]interface{}{}
+		if fs.NArg() == 1 {
+			params["key"] = fs.Arg(0)
+		}
+
+		client := dialDaemon(*socket)
+		defer client.Close()
+
+		result, err := client.Call("config.get", params)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+			os.Exit(1)
+		}
+		m, _ := result.(map[string]interface{})
+		options, _ := m["options"].(map[string]interface{})
+		if fs.NArg() == 1 {
+			fmt.Println(options[fs.Arg(0)])
+			return
+		}
+		keys := make([]string, 0, len(options))
+		for k := range options {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("%-18s %v\n", k, options[k])
+		}
+
+	case "set":
+		if fs.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "Usage: wgmesh config set <key> <value>")
+			os.Exit(1)
+		}
+
+		client := dialDaemon(*socket)
+		defer client.Close()
+
+		result, err := client.Call("config.set", map[string]interface{}{"key": fs.Arg(0), "value": fs.Arg(1)})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+			os.Exit(1)
+		}
+		m, _ := result.(map[string]interface{})
+		fmt.Printf("%s: %s → %s\n", fs.Arg(0), orNone(m["old"]), orNone(m["value"]))
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown action: %s\n", action)
+		usage()
+	}
+}
+
+// orNone prints an empty option value as "(none)".
+func orNone(v interface{}) string {
+	s, _ := v.(string)
+	if strings.TrimSpace(s) == "" {
+		return "(none)"
+	}
+	return s
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+// doctorCmd handles "wgmesh doctor": it checks the running daemon and the
+// host for common causes of a mesh that does not converge, and exits
+// non-zero when it finds one.
+func doctorCmd() {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	socket := fs.String("socket", "", "Daemon RPC socket path (default: $WGMESH_SOCKET or the standard path)")
+	fs.Parse(os.Args[2:])
+
+	client := dialDaemon(*socket)
+	defer client.Close()
+
+	result, err := client.Call("daemon.status", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+		os.Exit(1)
+	}
+	status, _ := result.(map[string]interface{})
+	uptime, _ := status["uptime"].(float64)
+	version, _ := status["version"].(string)
+	fmt.Printf("daemon:      %s on %v, up %v\n", version, status["interface"], time.Duration(uptime).Round(time.Second))
+	if name, _ := status["mesh_name"].(string); name != "" {
+		fmt.Printf("mesh:        %s\n", name)
+	}
+	if identity, _ := status["identity"].(string); identity != "" {
+		fmt.Printf("identity:    %s\n", identity)
+	}
+
+	if result, err := client.Call("peers.count", nil); err == nil {
+		counts, _ := result.(map[string]interface{})
+		active, _ := counts["active"].(float64)
+		total, _ := counts["total"].(float64)
+		fmt.Printf("peers:       %.0f active of %.0f known\n", active, total)
+	}
+
+	versionsOK := true
+	if result, err := client.Call("peers.list", nil); err == nil {
+		list, _ := result.(map[string]interface{})
+		peers, _ := list["peers"].([]interface{})
+		var line string
+		versionsOK, line = describeDoctorVersions(version, peers)
+		fmt.Printf("versions:    %s\n", line)
+	}
+
+	if result, err := client.Call("discovery.lan", nil); err == nil {
+		lan, _ := result.(map[string]interface{})
+		fmt.Printf("LAN:         %s\n", describeDoctorLAN(lan))
+	}
+
+	skew, _ := status["clock_skew"].(float64)
+	skewPeers, _ := status["clock_skew_peers"].(float64)
+	ok, line := describeDoctorClockSkew(time.Duration(skew), int(skewPeers))
+	fmt.Printf("clock skew:  %s\n", line)
+	ok = ok && versionsOK
+
+	if result, err := client.Call("daemon.resources", nil); err == nil {
+		resources, _ := result.(map[string]interface{})
+		line := describeDoctorResources(resources)
+		if pressure, _ := resources["pressure"].(bool); pressure {
+			line += "; WARNING: under pressure, raise the limit or --resource-limits"
+			ok = false
+		}
+		fmt.Printf("resources:   %s\n", line)
+	}
+
+	ntp := ntpSynchronized()
+	fmt.Printf("NTP:         %s\n", ntp)
+	if ntp == "not synchronized" {
+		ok = false
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+// describeDoctorClockSkew renders the daemon's clock skew estimate and
+// reports whether it is within daemon.ClockSkewWarnThreshold.
+func describeDoctorClockSkew(skew time.Duration, peers int) (bool, string) {
+	switch {
+	case peers < daemon.ClockSkewMinSources:
+		return true, fmt.Sprintf("unknown (heard from %d peers, need %d)", peers, daemon.ClockSkewMinSources)
+	case skew == 0:
+		return true, fmt.Sprintf("within %v of the mesh (%d peers)", daemon.ClockSkewSignificant, peers)
+	}
+	direction := "ahead of"
+	if skew < 0 {
+		direction, skew = "behind", -skew
+	}
+	line := fmt.Sprintf("%v %s the mesh (median of %d peers)", skew.Round(time.Second), direction, peers)
+	if skew >= daemon.ClockSkewWarnThreshold {
+		return false, line + "; WARNING: check NTP on this host"
+	}
+	return true, line
+}
+
+// describeDoctorVersions summarizes the wgmesh versions of the daemon's
+// peers and reports false if any runs an incompatible protocol revision.
+func describeDoctorVersions(own string, peers []interface{}) (bool, string) {
+	counts := make(map[string]int)
+	var incompatible []string
+	for _, item := range peers {
+		peer, _ := item.(map[string]interface{})
+		v, _ := peer["version"].(string)
+		if v == "" {
+			v = "unknown"
+		}
+		counts[v]++
+		if compat, _ := peer["compatibility"].(string); compat == daemon.CompatIncompatible {
+			name, _ := peer["hostname"].(string)
+			if name == "" {
+				pubkey, _ := peer["pubkey"].(string)
+				name = shortPubKey(pubkey)
+			}
+			incompatible = append(incompatible, name)
+		}
+	}
+	if len(counts) == 0 {
+		return true, "no peers"
+	}
+	if len(counts) == 1 && counts[own] > 0 {
+		return true, fmt.Sprintf("all peers run %s", own)
+	}
+	versions := make([]string, 0, len(counts))
+	for v := range counts {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	for i, v := range versions {
+		versions[i] = fmt.Sprintf("%s (%d)", v, counts[v])
+	}
+	line := "mixed: " + strings.Join(versions, ", ")
+	if len(incompatible) > 0 {
+		return false, line + "; WARNING: incompatible protocol: " + strings.Join(incompatible, ", ")
+	}
+	return true, line
+}
+
+// describeDoctorResources renders daemon.resources as "name used/limit"
+// pairs, marking those under pressure.
+func describeDoctorResources(resources map[string]interface{}) string {
+	list, _ := resources["resources"].([]interface{})
+	parts := make([]string, 0, len(list))
+	for _, item := range list {
+		r, _ := item.(map[string]interface{})
+		name, _ := r["name"].(string)
+		used, _ := r["used"].(float64)
+		limit, _ := r["limit"].(float64)
+		if limit == 0 {
+			continue
+		}
+		part := fmt.Sprintf("%s %.0f/%.0f", name, used, limit)
+		if pressure, _ := r["pressure"].(bool); pressure {
+			part += " (!)"
+		}
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeDoctorLAN renders discovery.lan as the group and the interfaces
+// LAN discovery runs on.
+func describeDoctorLAN(lan map[string]interface{}) string {
+	if enabled, _ := lan["enabled"].(bool); !enabled {
+		return "disabled"
+	}
+	line := fmt.Sprintf("%v %v", lan["mode"], lan["group"])
+	if broadcast, _ := lan["broadcast"].(bool); broadcast {
+		line += " + broadcast"
+	}
+	list, _ := lan["interfaces"].([]interface{})
+	var active, failed []string
+	for _, item := range list {
+		iface, _ := item.(map[string]interface{})
+		name, _ := iface["name"].(string)
+		if errMsg, _ := iface["error"].(string); errMsg != "" {
+			failed = append(failed, fmt.Sprintf("%s (%s)", name, errMsg))
+			continue
+		}
+		active = append(active, name)
+	}
+	if len(active) == 0 {
+		line += " on no interfaces"
+	} else {
+		line += " on " + strings.Join(active, ", ")
+	}
+	if len(failed) > 0 {
+		line += "; failed: " + strings.Join(failed, ", ")
+	}
+	return line
+}
+
+// ntpSynchronized asks systemd-timedated whether the system clock is
+// synchronized. It returns "synchronized", "not synchronized" or
+// "unknown" where timedatectl is unavailable.
+func ntpSynchronized() string {
+	out, err := exec.Command("timedatectl", "show", "--property=NTPSynchronized", "--value").Output()
+	if err != nil {
+		return "unknown"
+	}
+	switch strings.TrimSpace(string(out)) {
+	case "yes":
+		return "synchronized"
+	case "no":
+		return "not synchronized"
+	}
+	return "unknown"
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/kube"
+)
+
+// How often join --kubernetes checks that its Node's annotations match the
+// daemon, before and after the first successful annotation.
+const (
+	kubeAnnotateRetry    = 2 * time.Second
+	kubeAnnotateInterval = 30 * time.Second
+)
+
+// kubernetesNode fetches the Node join --kubernetes runs on, for its pod
+// CIDRs.
+func kubernetesNode(nodeName string) (*kube.Client, *kube.Node, error) {
+	if nodeName == "" {
+		return nil, nil, fmt.Errorf("node name unknown: set NODE_NAME from spec.nodeName or pass --kubernetes-node")
+	}
+	client, err := kube.InCluster()
+	if err != nil {
+		return nil, nil, err
+	}
+	node, err := client.GetNode(context.Background(), nodeName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read node %s: %w", nodeName, err)
+	}
+	return client, node, nil
+}
+
+// annotateNodeLoop keeps the Node's wgmesh annotations in step with the
+// daemon's mesh address, which is only known once the daemon has started
+// and can change after an address collision.
+func annotateNodeLoop(client *kube.Client, nodeName string, d *daemon.Daemon) {
+	var annotated map[string]string
+	for {
+		if status := d.GetRPCStatus(); status != nil && status.MeshIP != "" {
+			want := map[string]string{
+				kube.AnnotationMeshIP: status.MeshIP,
+				kube.AnnotationPubKey: status.PubKey,
+			}
+			if !maps.Equal(want, annotated) {
+				if err := client.AnnotateNode(context.Background(), nodeName, want); err != nil {
+					log.Printf("[Kubernetes] Failed to annotate node %s: %v", nodeName, err)
+				} else {
+					log.Printf("[Kubernetes] Annotated node %s with mesh IP %s", nodeName, status.MeshIP)
+					annotated = want
+				}
+			}
+		}
+		// Retry quickly until the first annotation is in place.
+		if annotated == nil {
+			time.Sleep(kubeAnnotateRetry)
+		} else {
+			time.Sleep(kubeAnnotateInterval)
+		}
+	}
+}
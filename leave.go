@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/openwrt"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/rpc"
+)
+
+// leaveStopTimeout is how long leave waits for the daemon to stop after
+// daemon.leave.
+const leaveStopTimeout = 15 * time.Second
+
+// leaveCmd handles "wgmesh leave": it asks the running daemon to say
+// GOODBYE and exit, removes the service and the WireGuard interface, and
+// deletes the cached mesh state. With --purge it also deletes the node's
+// keys and the stored mesh secret.
+func leaveCmd() {
+	fs := flag.NewFlagSet("leave", flag.ExitOnError)
+	socket := fs.String("socket", "", "Daemon RPC socket path (default: $WGMESH_SOCKET or the standard path)")
+	iface := fs.String("interface", "", "WireGuard interface (default: the running daemon's, else "+daemon.DefaultInterface+")")
+	purge := fs.Bool("purge", false, "Also delete the node's keys and the stored mesh secret")
+	initSystemFlag := fs.String("init-system", "auto", "Service manager: auto, systemd, openrc or runit")
+	fs.Parse(os.Args[2:])
+
+	if *socket == "" {
+		*socket = os.Getenv("WGMESH_SOCKET")
+	}
+	if *socket == "" {
+		*socket = getRPCSocketPath()
+	}
+
+	ifaceName := *iface
+	if client, err := rpc.NewClient(*socket); err != nil {
+		fmt.Println("No daemon is running; cleaning up what it left behind.")
+	} else {
+		if ifaceName == "" {
+			if result, err := client.Call("daemon.status", nil); err == nil {
+				status, _ := result.(map[string]interface{})
+				ifaceName, _ = status["interface"].(string)
+			}
+		}
+		if _, err := client.Call("daemon.leave", nil); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to leave the mesh: %v\n", err)
+			client.Close()
+			os.Exit(1)
+		}
+		client.Close()
+		fmt.Println("Daemon is saying GOODBYE to its peers and shutting down...")
+	}
+	if ifaceName == "" {
+		ifaceName = daemon.DefaultInterface
+	}
+
+	// The service restarts a daemon that exits, so remove it before
+	// waiting for the daemon to stop.
+	if initSystem, err := daemon.ResolveInitSystem(*initSystemFlag); err == nil && daemon.ServiceInstalled(initSystem) {
+		fmt.Printf("Removing wgmesh %s service...\n", initSystem)
+		if err := daemon.UninstallService(initSystem); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to uninstall service: %v\n", err)
+			os.Exit(1)
+		}
+	} else if openwrt.Installed() {
+		fmt.Println("Removing wgmesh OpenWrt service...")
+		if err := openwrt.Uninstall(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to uninstall service: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if !waitForDaemonExit(*socket, leaveStopTimeout) {
+		fmt.Fprintf(os.Stderr, "Daemon still answers on %s after %v; stop it and run leave again.\n", *socket, leaveStopTimeout)
+		os.Exit(1)
+	}
+
+	removed, err := daemon.RemoveInterface(ifaceName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if removed {
+		fmt.Printf("Removed interface %s\n", ifaceName)
+	}
+
+	paths, err := daemon.RemoveMeshState(ifaceName, *purge)
+	for _, path := range paths {
+		fmt.Printf("Removed %s\n", path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := daemon.ForgetNetwork(ifaceName); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to update %s: %v\n", daemon.NetworksPath(), err)
+	}
+	fmt.Println("Left the mesh.")
+}
+
+// waitForDaemonExit polls the daemon until it stops answering on socket,
+// reporting false if it is still up after timeout.
+func waitForDaemonExit(socket string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		client, err := rpc.NewClient(socket)
+		if err != nil {
+			return true
+		}
+		_, err = client.Call("daemon.ping", nil)
+		client.Close()
+		if err != nil {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
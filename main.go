@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -9,16 +11,22 @@ import (
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
+	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/kube"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/mesh"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/pilot"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/referral"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/rpc"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/tracing"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
@@ -29,6 +37,72 @@ import (
 // version is set at build time via -ldflags "-X main.version=..."
 var version = "dev"
 
+// stringSliceFlag collects a repeatable string flag. Each value may also be
+// a comma-separated list.
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*s = append(*s, v)
+		}
+	}
+	return nil
+}
+
+// hideFlags keeps the named flags working but leaves them out of the flag
+// set's usage output.
+func hideFlags(fs *flag.FlagSet, names ...string) {
+	hidden := make(map[string]bool, len(names))
+	for _, name := range names {
+		hidden[name] = true
+	}
+	fs.Usage = func() {
+		visible := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+		visible.SetOutput(fs.Output())
+		fs.VisitAll(func(f *flag.Flag) {
+			if !hidden[f.Name] {
+				visible.Var(f.Value, f.Name, f.Usage)
+			}
+		})
+		fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+		visible.PrintDefaults()
+	}
+}
+
+// restartedEnv tells a daemon it was exec'd by a previous run restarting in
+// place (see daemon.ErrRestart).
+const restartedEnv = "WGMESH_RESTARTED"
+
+// applyFlagEnv sets every flag of fs not given on the command line from its
+// WGMESH_<FLAG> environment variable, if present. Flags named in skip are
+// left alone.
+func applyFlagEnv(fs *flag.FlagSet, skip ...string) {
+	set := make(map[string]bool)
+	for _, name := range skip {
+		set[name] = true
+	}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	fs.VisitAll(func(f *flag.Flag) {
+		if set[f.Name] {
+			return
+		}
+		env := daemon.FlagEnvName(f.Name)
+		value, ok := os.LookupEnv(env)
+		if !ok || value == "" {
+			return
+		}
+		if err := fs.Set(f.Name, value); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid %s=%q: %v\n", env, value, err)
+			os.Exit(1)
+		}
+	})
+}
+
 func versionOutput() string {
 	return "wgmesh version " + version
 }
@@ -42,66 +116,40 @@ func main() {
 		}
 	}
 
-	// Check for subcommands first
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
-		case "version":
-			fmt.Println(versionOutput())
-			return
-		case "join":
-			joinCmd()
-			return
-		case "init":
-			initCmd()
-			return
-		case "status":
-			statusCmd()
-			return
-		case "test-peer":
-			testPeerCmd()
-			return
-		case "qr":
-			qrCmd()
-			return
-		case "install-service":
-			installServiceCmd()
-			return
-		case "uninstall-service":
-			uninstallServiceCmd()
-			return
-		case "rotate-secret":
-			rotateSecretCmd()
-			return
-		case "mesh":
-			meshCmd()
-			return
-		case "peers":
-			peersCmd()
-			return
-		case "service":
-			serviceCmd()
-			return
-		case "pilot":
-			pilotCmd()
-			return
-		case "referral":
-			referralCmd()
-			return
-		}
+	if runCommand() {
+		return
 	}
 
 	// Original CLI mode
 	var (
 		stateFile  = flag.String("state", filepath.Join(defaultStateDir, "mesh-state.json"), "Path to mesh state file")
-		addNode    = flag.String("add", "", "Add node (format: hostname:ip:ssh_host[:ssh_port])")
+		addNode    = flag.String("add", "", "Add node (format: hostname:ip:[ssh_user@]ssh_host[:ssh_port])")
 		removeNode = flag.String("remove", "", "Remove node by hostname")
 		list       = flag.Bool("list", false, "List all nodes")
 		listSimple = flag.Bool("list-simple", false, "List all nodes in simple format (hostname ip)")
 		deploy     = flag.Bool("deploy", false, "Deploy configuration to all nodes")
+		verify     = flag.Bool("verify", false, "Report drift between the nodes and the state file without changing anything")
+		render     = flag.Bool("render", false, "Write each node's config to -output-dir instead of deploying")
+		outputDir  = flag.String("output-dir", "configs", "Directory for -render")
+		renderUnit = flag.Bool("render-systemd", false, "With -render, also write a systemd unit per node")
+		parallel   = flag.Int("parallel", mesh.DefaultDeployParallel, "Number of nodes to deploy to or verify at once")
+		dryRun     = flag.Bool("dry-run", false, "Show each node's config diff without deploying")
 		init       = flag.Bool("init", false, "Initialize new mesh")
 		network    = flag.String("network", "", "Custom mesh network CIDR for init (default: 10.99.0.0/16)")
 		encrypt    = flag.Bool("encrypt", false, "Encrypt state file with password (asks for password)")
+		keyFile    = flag.String("key-file", "", "Encrypt state file with the 32-byte key in this file instead of a password")
+		kdfTime    = flag.Uint("kdf-time", 0, "argon2id passes for a password-encrypted state file (default 3)")
+		kdfMemory  = flag.Uint("kdf-memory", 0, "argon2id memory in MiB for a password-encrypted state file (default 64)")
+		kdfThreads = flag.Uint("kdf-threads", 0, "argon2id threads for a password-encrypted state file (default 4)")
 	)
+	importIface := flag.String("import-interface", "wg0", "WireGuard interface to read with -import")
+	sshConfigFile := flag.String("ssh-config", "", "Read SSH users, ports, keys and jump hosts from this ssh_config file")
+	sshIdentity := flag.String("ssh-identity", "", "With -add, the SSH private key file for the node")
+	sshJump := flag.String("ssh-jump", "", "With -add, jump host(s) to reach the node through ([user@]host[:port][,...])")
+	var only, limit, importSources stringSliceFlag
+	flag.Var(&importSources, "import", "Build a new state file from existing WireGuard nodes (comma-separated [user@]ssh_host[:port] or wg-quick .conf files)")
+	flag.Var(&only, "only", "Deploy, verify or render only these nodes (repeatable, comma-separated hostnames)")
+	flag.Var(&limit, "limit", "Deploy, verify or render only nodes matching these glob patterns (repeatable, comma-separated)")
 
 	flag.Parse()
 
@@ -110,8 +158,8 @@ func main() {
 		var password string
 		var err error
 
-		if *init {
-			// For init, ask for password twice
+		if *init || len(importSources) > 0 {
+			// For a new state file, ask for password twice
 			password, err = crypto.ReadPasswordTwice("Enter encryption password: ")
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "Failed to read password: %v\n", err)
@@ -128,6 +176,37 @@ func main() {
 
 		mesh.SetEncryptionPassword(password)
 	}
+	if *keyFile != "" {
+		key, err := crypto.ReadKeyFile(*keyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		mesh.SetEncryptionKey(key)
+	}
+	if *kdfTime != 0 || *kdfMemory != 0 || *kdfThreads != 0 {
+		params := crypto.DefaultKDFParams
+		if *kdfTime != 0 {
+			params.Time = uint32(*kdfTime)
+		}
+		if *kdfMemory != 0 {
+			params.MemoryKiB = uint32(min(*kdfMemory, 1<<22) << 10)
+		}
+		if *kdfThreads != 0 {
+			params.Threads = uint8(min(*kdfThreads, 255))
+		}
+		if err := mesh.SetKDFParams(params); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid KDF parameters: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *sshConfigFile != "" {
+		if err := mesh.SetSSHConfig(*sshConfigFile); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	if *init {
 		if err := mesh.InitializeWithNetwork(*stateFile, *network); err != nil {
@@ -138,6 +217,11 @@ func main() {
 		return
 	}
 
+	if len(importSources) > 0 {
+		importMesh(*stateFile, importSources, *importIface)
+		return
+	}
+
 	m, err := mesh.Load(*stateFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to load mesh state: %v\n", err)
@@ -150,6 +234,11 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Failed to add node: %v\n", err)
 			os.Exit(1)
 		}
+		hostname, _, _ := strings.Cut(*addNode, ":")
+		if err := m.SetNodeSSH(hostname, *sshIdentity, *sshJump); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to add node: %v\n", err)
+			os.Exit(1)
+		}
 		if err := m.Save(*stateFile); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to save state: %v\n", err)
 			os.Exit(1)
@@ -174,11 +263,37 @@ func main() {
 		m.ListSimple()
 
 	case *deploy:
-		if err := m.Deploy(); err != nil {
+		opts := mesh.DeployOptions{
+			Parallel: *parallel,
+			Only:     only,
+			Limit:    limit,
+			DryRun:   *dryRun,
+		}
+		if _, err := m.Deploy(opts); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to deploy: %v\n", err)
 			os.Exit(1)
 		}
-		fmt.Println("Deployment completed successfully")
+		if !*dryRun {
+			fmt.Println("Deployment completed successfully")
+		}
+
+	case *render:
+		written, err := m.Render(mesh.RenderOptions{OutputDir: *outputDir, Systemd: *renderUnit, Only: only, Limit: limit})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to render: %v\n", err)
+			os.Exit(1)
+		}
+		for _, path := range written {
+			fmt.Println(path)
+		}
+
+	case *verify:
+		opts := mesh.DeployOptions{Parallel: *parallel, Only: only, Limit: limit}
+		if _, err := m.Verify(opts); err != nil {
+			fmt.Fprintf(os.Stderr, "\n%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("\nAll nodes match the state file")
 
 	default:
 		printUsage()
@@ -186,49 +301,234 @@ func main() {
 	}
 }
 
+// importMesh writes a new state file reconstructed from existing WireGuard
+// nodes. It refuses to replace an existing state file.
+func importMesh(stateFile string, specs []string, iface string) {
+	if _, err := os.Stat(stateFile); err == nil {
+		fmt.Fprintf(os.Stderr, "State file %s already exists; use -state to import into a new file\n", stateFile)
+		os.Exit(1)
+	}
+
+	var sources []mesh.ImportSource
+	for _, spec := range specs {
+		src, err := mesh.ParseImportSource(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		sources = append(sources, src)
+	}
+
+	m, err := mesh.Import(sources, iface)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to import: %v\n", err)
+		os.Exit(1)
+	}
+	if err := m.Save(stateFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to save state: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Imported %d nodes into %s\n", len(m.Nodes), stateFile)
+	m.List()
+	fmt.Println("Run 'wgmesh -verify' to check the imported state against the nodes before deploying.")
+}
+
 func printUsage() {
 	fmt.Println(`wgmesh - WireGuard mesh network builder
 
 FLAGS:
   --version, -v               Show version information
   -state <file>    Path to mesh state file (default: ` + filepath.Join(defaultStateDir, "mesh-state.json") + `)
-  -add <spec>      Add node (format: hostname:ip:ssh_host[:ssh_port])
+  -add <spec>      Add node (format: hostname:ip:[ssh_user@]ssh_host[:ssh_port])
+  -ssh-identity <f>  With -add, SSH private key file for the node
+  -ssh-jump <hosts>  With -add, jump host(s) to reach the node through
   -remove <name>   Remove node by hostname
   -list            List all nodes
   -deploy          Deploy configuration to all nodes
+  -verify          Report drift from the state file on each node (changes nothing)
+  -parallel <n>    Nodes to deploy to or verify at once (default: 8)
+  -only <hosts>    Deploy, verify or render only these nodes (comma-separated)
+  -limit <globs>   Deploy, verify or render only nodes matching these patterns (e.g. 'web*')
+  -dry-run         With -deploy, show each node's config diff without applying it
+  -render          Write each node's wg-quick config to files instead of deploying
+  -output-dir <d>  Directory for -render (default: configs)
+  -render-systemd  With -render, also write a systemd unit per node
   -init            Initialize new mesh state file
+  -import <srcs>   Create the state file from existing WireGuard nodes
+                   (comma-separated [user@]ssh_host[:port] or wg-quick .conf files)
+  -import-interface <name>  Interface to read with -import (default: wg0)
   -network <CIDR>  Custom mesh network for init (default: 10.99.0.0/16)
   -encrypt         Encrypt state file with password
+  -key-file <f>    Encrypt state file with a 32-byte key file instead (for automation)
+  -kdf-time <n>, -kdf-memory <MiB>, -kdf-threads <n>
+                   argon2id cost for a password-encrypted state file
+  -ssh-config <f>  Take SSH users, ports, keys and jump hosts from an ssh_config file
+
+  Run 'wgmesh help' for the list of subcommands.
 
 SUBCOMMANDS (centralized mode):
-  mesh list [--state <file>] [--encrypt]  List hostnames and mesh IPs
+  mesh list [--state <file>] [--encrypt] [--key-file <f>]  List hostnames and mesh IPs
 
 SUBCOMMANDS (decentralized mode):
   init --secret                 Generate a new mesh secret
+	     [--name NAME]           Display name carried in a v2 URI (also --mesh-subnet,
+	                             --gossip-port, --bootstrap HOST:PORT,...)
 	join --secret <SECRET>        Join a mesh network
+	     [--secret-file PATH]    Read the secret from a file instead of --secret
+	     [--profile NAME]        Use the flags saved with 'profile add NAME'
 	     [--account <cr_...>]    Save Lighthouse API key for service commands
 	     [--mesh-subnet CIDR]    Custom mesh subnet (e.g. 192.168.100.0/24)
+	     [--mesh-name NAME]      Display name in status, peers and logs
+	     [--gossip-digest]        With --gossip, send peer-list digests instead of full lists
 	     [--no-lan-discovery]     Disable LAN multicast discovery
+	     [--lan-mdns]             Use mDNS/DNS-SD for LAN discovery
+	     [--lan-interfaces LIST]  Interfaces (or globs) to run LAN discovery on
+	     [--lan-group ADDR[:PORT]] Multicast group for LAN discovery
+	     [--lan-broadcast]        Also announce to interface broadcast addresses
 	     [--no-ipv6]              Ignore IPv6 endpoints for connectivity
 	     [--force-relay]          Prefer relay path for non-LAN peers
 	     [--no-punching]          Disable NAT port punching/rendezvous
 	     [--introducer]           Enable rendezvous introducer role
+	     [--dns-rendezvous NAME]  Bootstrap from DNS SRV/TXT instead of the DHT
+	     [--stun-server HOST:PORT] STUN server to use (repeatable)
+	     [--stun-listen-port N]   STUN responder port on introducers (-1 disables)
+	     [--keepalive SECONDS]    PersistentKeepalive override (0 auto, -1 off)
+	     [--subnet-router]        Enable IP forwarding for --advertise-routes
+	     [--masquerade]           With --subnet-router, NAT mesh traffic to those routes
+	     [--firewall]             Drop inbound mesh traffic except wgmesh's own ports
+	     [--firewall-allow LIST]  With --firewall, also allow e.g. "tcp/22,udp/53"
+	     [--pin-identities]       Quarantine peers whose mesh IP/hostname changes key
+	     [--require-signed]       Ignore announcements without an identity signature
+	     [--socks5-proxy ADDR]    Reach the DHT through a SOCKS5 proxy (UDP ASSOCIATE)
+	     [--max-installed-peers N] Cap peers installed in WireGuard (0 = all)
+	     [--graceful-restart]     Keep the interface up across daemon restarts
+	     [--auto-update]          Install signed releases and restart into them
+	     [--central-state PATH|URL] Add the nodes of a centralized mesh-state.json as peers
+	     [--static-peers PATH]    Run offline: take peers only from a signed manifest
+	     [--discovery-bandwidth RATE] Cap DHT and peer exchange traffic, e.g. 5KB/s
+	     [--dht-shards N]         Spread large meshes over N DHT infohashes (same N on all nodes)
+	     [--tags k=v,...]         Labels announced to peers, e.g. role=db
+	     [--advertise-dns LIST]   Domains peers resolve via this node, e.g. home.lan=192.168.1.53
+	     [--route-check LIST]     Withhold a route from peers while its target is down, e.g. 192.168.1.0/24=192.168.1.10:443
+	     [--no-split-dns]         Do not install DNS rules for domains peers advertise
+	     [--dns-resolv-conf]      Without systemd-resolved, list peers' resolvers first in /etc/resolv.conf
+	     [--resource-limits SPEC] Cap FDs, goroutines and probe sessions, e.g. fds=4096
+	     [--peer-rate-limit LIST] Cap what this node sends to peers, e.g. <pubkey>=10Mbit,all=50Mbit
+	     [--peer-with LIST]       Only tunnel to these peers, e.g. introducers,tag:group=eu
+	     [--route-table N]        Put routes to peer networks in table N, with an ip rule
+	     [--route-metric N]       Metric of routes to peer networks
+	     [--route-fwmark MARK]    Mark WireGuard's packets so they bypass --route-table
+	     [--mtu N]                Interface MTU (default: kernel default, 1420)
+	     [--mtu-probe]            Probe each peer's path MTU and clamp TCP MSS to fit
+	     [--port-hop INTERVAL]    Rotate the WireGuard port on a secret-derived schedule
+	     [--tcp-transport-port N] Introducers: relay nodes whose UDP is blocked over TLS on port N
+	     [--approvers KEYS]       Hold new nodes as pending until one of these identities approves them
+	     [--health-addr ADDR]     Serve /healthz and /readyz JSON for Docker and Kubernetes probes
+	     [--peer-dead-after 5m]   Leave peers unseen this long out of WireGuard
+	     [--peer-remove-after 10m] Forget peers unseen this long
+	     [--kubernetes]           Run as a DaemonSet: advertise the pod CIDR, annotate the Node
+	     [--kubernetes-pod-routes] Route to other nodes' pod CIDRs over the mesh
+	     [--rpc-allow-users LIST] Let these users use the RPC socket (also --rpc-allow-groups)
+	     [--rpc-tcp-port PORT]    Serve RPC on the mesh IP too, with --rpc-token-file
+	     [--rpc-legacy-json]      Also answer pre-gRPC clients (deprecated)
   status --secret <SECRET>      Show mesh status
   qr --secret <SECRET>          Display secret as QR code (text)
-	install-service --secret ...  Install systemd service
+  networks list [--json]        List the meshes joined on this host and their daemons and services
+  cache inspect [--json]        Show the cached peers the daemon restores at startup (also --interface)
+	install-service --secret ...  Install the wgmesh service (secret stored encrypted)
+	     [--init-system NAME]    auto, systemd, openrc, runit or container (prints an env file)
 	     [--account <cr_...>]    Save Lighthouse API key for service commands
+	     [--gossip-digest]        Send gossip digests in service
 	     [--no-lan-discovery]     Disable LAN multicast discovery in service
+	     [--lan-interfaces LIST]  Limit LAN discovery to these interfaces in service
 	     [--no-ipv6]              Ignore IPv6 endpoints in service
 	     [--force-relay]          Prefer relay path in service
 	     [--no-punching]          Disable NAT punching in service
 	     [--introducer]           Enable rendezvous introducer role in service
-  uninstall-service             Remove systemd service
-  rotate-secret                 Rotate mesh secret
+	     [--subnet-router]        Enable subnet router mode in service
+	     [--masquerade]           NAT mesh traffic to advertised routes in service
+	     [--firewall]             Enable the mesh interface firewall in service
+	     [--firewall-allow LIST]  Extra ports the service firewall allows
+	     [--pin-identities]       Enable identity pinning in service
+	     [--require-signed]       Require signed announcements in service
+	     [--socks5-proxy ADDR]    Reach the DHT through a SOCKS5 proxy in service
+	     [--max-installed-peers N] Cap peers installed in WireGuard in service
+	     [--graceful-restart]     Keep tunnels up while the service restarts
+	     [--auto-update]          Keep the service on the latest signed release
+	     [--central-state PATH|URL] Add centrally managed nodes as peers in service
+	     [--static-peers PATH]    Run the service offline from a signed manifest
+	     [--discovery-bandwidth RATE] Cap the service's discovery traffic, e.g. 5KB/s
+	     [--dht-shards N]         Spread the mesh over N DHT infohashes in service
+	     [--tags k=v,...]         Labels the service announces to peers
+	     [--advertise-dns LIST]   Domains the service's peers resolve via it
+	     [--route-check LIST]     Health check targets for the service's advertised routes
+	     [--no-split-dns]         Install no DNS rules for peers' domains in service
+	     [--dns-resolv-conf]      Let the service list peers' resolvers in /etc/resolv.conf
+	     [--resource-limits SPEC] Cap the service's FDs, goroutines and probe sessions
+	     [--peer-rate-limit LIST] Cap what the service sends to peers
+	     [--peer-with LIST]       Only tunnel to these peers in service
+	     [--route-table N]        Put the service's peer routes in table N
+	     [--route-metric N]       Metric of the service's peer routes
+	     [--route-fwmark MARK]    Mark the service's WireGuard packets to bypass --route-table
+	     [--mtu N]                Interface MTU in service
+	     [--mtu-probe]            Probe path MTUs and clamp TCP MSS in service
+	     [--port-hop INTERVAL]    Rotate the service's WireGuard port
+	     [--tcp-transport-port N] Serve the TLS transport on port N in service
+	     [--approvers KEYS]       Run the service in approval mode
+	     [--health-addr ADDR]     Serve the service's healthcheck on ADDR
+	     [--peer-dead-after 5m]   Peer inactivity timeout in service (also --peer-remove-after)
+  uninstall-service             Remove the wgmesh service
+	     [--init-system NAME]    auto, systemd, openrc or runit
+  openwrt install --secret ...  Install a procd service configured in /etc/config/wgmesh
+	     [--advertise-routes LIST] LAN routes to advertise (turns on subnet_router)
+	     [--lan-zone lan]        Firewall zone forwarded to and from the mesh
+	     [--wan-zone wan]        Firewall zone the mesh ports are opened on
+  openwrt uninstall             Remove the OpenWrt service and firewall zone
+  openwrt check                 Validate /etc/config/wgmesh
+  netns attach <container|pid>  Give a container a mesh0 interface routed into the mesh
+	     [--runtime docker]      Runtime used to look up the container (docker or podman)
+  netns detach <container|pid>  Remove the container's mesh0 interface
+  leave                         Say GOODBYE, remove the service, interface and cached state
+	     [--purge]               Also delete the node's keys and stored secret
+	     [--interface NAME]      Interface to remove when no daemon is running
+  seal-secret --secret <SECRET> Store the secret encrypted for join --secret-file
+	     [--out PATH]            Output file (default /var/lib/wgmesh/secret.enc)
+	     [--password]            Seal with a password instead of the machine key
+  sign-peers --secret <SECRET> --in FILE  Sign a peer manifest for join --static-peers
+	     [--out PATH]            Output file (default: overwrite --in)
+  rotate-secret                 Rotate mesh secret (via the running daemon)
+  profile add <name> <flags>    Save join flags for 'join --profile' (also list, show, remove)
+  broadcast "<text>"            Send a signed message to every node (e.g. a maintenance notice)
+  agent                         Cache the secret for status, qr and test-peer
+	     [--secret-file PATH]    Read the secret from a file instead of prompting
+	     [--timeout 8h]          Forget the secret after this long (0 = never)
+  update                        Install the latest signed release and restart the daemons in place
+	     [--check]               Only report whether a newer release exists
+	     [--version TAG]         Install this release instead, e.g. v1.4.0
+	     [--no-restart]          Leave running daemons on the old binary
 
 QUERY SUBCOMMANDS (decentralized mode):
-  peers list                    List all active peers
+  peers list                    List all active peers (--tag role=db to filter)
+  peers watch                   Live view of peers, handshakes, paths and events (--interval 2s)
   peers count                   Show peer statistics
-  peers get <pubkey>            Get specific peer details
+  peers get <pubkey> [--full]   Get specific peer details
+  peers top                     Show busiest peers (--window 1m|5m|15m, -n N)
+  peers quarantine              List peers rejected by --pin-identities
+  peers pending                 List new nodes waiting for approval (--approvers)
+  peers approve <pubkey>        Admit a pending peer or accept a quarantined one
+  peers forget <pubkey>         Drop a decommissioned node now, without waiting for it to expire
+  ping <peer>                   Ping a peer over the mesh (-c N, -i 1s)
+  route <peer>                  Show whether a peer is reached direct, over the LAN or via a relay
+  messages                      Show recent broadcasts sent with 'wgmesh broadcast'
+  proxy [--listen ADDR]         SOCKS5/HTTP proxy to mesh hostnames and IPs (default 127.0.0.1:1080)
+  doctor                        Check the daemon, clock skew from the mesh and NTP sync
+  wait-online                   Block until the interface is up and peers have handshaken
+	     [--min-peers N]         Peers with a recent handshake needed (default 1)
+	     [--timeout 60s]         Fail after this long (0 = never)
+  state export                  Dump the peer store as JSON (e.g. > peers.json)
+  state import <file|->         Seed the peer store from a dump, e.g. on a migrated host
+  config get [key]              Show the options the daemon can change while running
+  config set <key> <value>      Change log-level, force-relay, lan-discovery, advertise-routes or peer-rate-limit live
 
 REFERRAL SUBCOMMANDS:
   referral show                 Show your referral code and share URL
@@ -245,6 +545,7 @@ EXAMPLES:
 
   # Decentralized mode (automatic peer discovery):
   wgmesh init --secret                          # Generate a new mesh secret
+  wgmesh init --secret --name lab               # Same, with mesh options in a v2 URI
   wgmesh join --secret "wgmesh://v1/K7x2..."    # Join mesh on this node
   wgmesh join --secret "..." --account cr_123    # Join and save API key
   wgmesh join --secret "..." --privacy           # Join with Dandelion++ privacy
@@ -252,13 +553,21 @@ EXAMPLES:
 
   # Query running daemon:
   wgmesh peers list                              # List all active peers
+  wgmesh peers watch                             # Watch the mesh converge
   wgmesh peers count                             # Show peer counts
-  wgmesh peers get <pubkey>                      # Get specific peer info
+  wgmesh peers get <pubkey> --full               # Every known attribute of a peer
+  wgmesh peers top --window 5m                   # Busiest peers over 5 minutes
 
   # Centralized mode (SSH-based deployment):
   wgmesh -init -encrypt                         # Initialize encrypted state
+  wgmesh -deploy -key-file /run/secrets/mesh.key  # Unattended, key-file encrypted state
   wgmesh -add node1:10.99.0.1:192.168.1.10     # Add a node
+  wgmesh -add db1:10.99.0.5:admin@10.0.1.5 -ssh-jump bastion.example.com  # Via a bastion
   wgmesh -deploy                               # Deploy to all nodes
+  wgmesh -deploy -dry-run -limit 'web*'        # Preview changes on web nodes
+  wgmesh -verify                               # Check nodes for drift
+  wgmesh -render -output-dir ./configs         # Write configs for review/GitOps
+  wgmesh -import 10.0.0.5,10.0.0.6,gw.conf     # Adopt an existing WireGuard mesh
   wgmesh mesh list                             # List hostnames and mesh IPs`)
 }
 
@@ -267,6 +576,10 @@ func initCmd() {
 	fs := flag.NewFlagSet("init", flag.ExitOnError)
 	secretMode := fs.Bool("secret", false, "Generate a new mesh secret")
 	referralCode := fs.String("referral", "", "Referral share code to attribute this init (format: XXXXX-XXXXX)")
+	name := fs.String("name", "", "With --secret, a display name for the mesh")
+	meshSubnet := fs.String("mesh-subnet", "", "With --secret, a mesh subnet CIDR every node uses (e.g. 192.168.100.0/24)")
+	gossipPort := fs.Int("gossip-port", 0, "With --secret, an exchange port every node uses instead of the derived one")
+	bootstrap := fs.String("bootstrap", "", "With --secret, comma-separated host:port exchange endpoints joining nodes contact first")
 	fs.Parse(os.Args[2:])
 
 	if *secretMode {
@@ -275,6 +588,19 @@ func initCmd() {
 			fmt.Fprintf(os.Stderr, "Failed to generate secret: %v\n", err)
 			os.Exit(1)
 		}
+		// Any option makes this a v2 URI; without them it stays v1, which
+		// every release understands.
+		opts := daemon.SecretOptions{
+			Name:           strings.TrimSpace(*name),
+			MeshSubnet:     *meshSubnet,
+			GossipPort:     *gossipPort,
+			BootstrapPeers: splitList(*bootstrap),
+		}
+		uri, err := daemon.FormatSecretURIWithOptions(secret, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 
 		// Validate and record referral code if provided. The format is checked
 		// with the public referral package; the backend that implements
@@ -287,7 +613,6 @@ func initCmd() {
 			fmt.Printf("Referral applied: %s\n", *referralCode)
 		}
 
-		uri := daemon.FormatSecretURI(secret)
 		fmt.Println("Generated mesh secret:")
 		fmt.Println()
 		fmt.Println(uri)
@@ -305,43 +630,59 @@ func initCmd() {
 func joinCmd() {
 	fs := flag.NewFlagSet("join", flag.ExitOnError)
 	secret := fs.String("secret", "", "Mesh secret (required)")
+	secretFile := fs.String("secret-file", "", "Read the mesh secret from a file (plaintext, sealed with seal-secret, or a systemd credential)")
+	profileName := fs.String("profile", "", "Fill in flags not given on the command line from a profile saved with 'wgmesh profile add'")
 	account := fs.String("account", "", "Lighthouse API key (cr_...) — saved for service commands")
 	stateDir := fs.String("state-dir", defaultStateDir, "State directory for account config")
-	advertiseRoutes := fs.String("advertise-routes", "", "Comma-separated list of routes to advertise")
-	listenPort := fs.Int("listen-port", 51820, "WireGuard listen port")
-	iface := fs.String("interface", "", "WireGuard interface name (default: wg0 on non-macOS, utun20 on macOS)")
 	logLevel := fs.String("log-level", "info", "Log level (debug, info, warn, error)")
-	privacyMode := fs.Bool("privacy", false, "Enable privacy mode (Dandelion++ relay)")
-	gossipMode := fs.Bool("gossip", false, "Enable in-mesh gossip")
+	df := addDaemonFlags(fs)
 	socketPath := fs.String("socket-path", "", "RPC socket path (auto-detected if empty)")
-	noLANDiscovery := fs.Bool("no-lan-discovery", false, "Disable LAN multicast discovery")
-	noIPv6 := fs.Bool("no-ipv6", false, "Ignore IPv6 endpoints for connectivity")
-	forceRelay := fs.Bool("force-relay", false, "Prefer relay path for non-LAN peers")
-	noPunching := fs.Bool("no-punching", false, "Disable NAT port punching/rendezvous")
-	introducerMode := fs.Bool("introducer", false, "Allow this node to act as rendezvous introducer")
-	meshSubnet := fs.String("mesh-subnet", "", "Custom mesh subnet CIDR (e.g. 192.168.100.0/24)")
+	rpcAllowUsers := fs.String("rpc-allow-users", "", "Comma-separated users or UIDs, besides root and the daemon's user, allowed on the RPC socket (Linux)")
+	rpcAllowGroups := fs.String("rpc-allow-groups", "", "Comma-separated groups or GIDs whose members may use the RPC socket (primary group, Linux)")
+	rpcTCPPort := fs.Int("rpc-tcp-port", 0, "Also serve RPC over TCP on the mesh IP at this port (requires --rpc-token-file)")
+	rpcTokenFile := fs.String("rpc-token-file", "", "File holding the token clients of --rpc-tcp-port must send")
+	rpcLegacyJSON := fs.Bool("rpc-legacy-json", false, "Also answer the old line-delimited JSON-RPC protocol (deprecated; removed in the next release)")
+	kubernetes := fs.Bool("kubernetes", false, "Run as a Kubernetes DaemonSet: advertise the node's pod CIDRs and annotate the Node with the mesh IP")
+	kubernetesNodeName := fs.String("kubernetes-node", os.Getenv("NODE_NAME"), "With --kubernetes, the Node this pod runs on (default $NODE_NAME)")
+	kubernetesPodRoutes := fs.Bool("kubernetes-pod-routes", false, "With --kubernetes, route to the pod CIDRs other nodes advertise; use when the CNI does not already route between them, e.g. across clusters")
+	chaosSpec := fs.String("chaos", "", "Inject faults for testing, e.g. drop-exchange=0.2,stun-delay=2s,fail-probe=0.5,seed=42")
 	pprofAddr := fs.String("pprof", "", "Enable pprof HTTP server (e.g. localhost:6060)")
 	metricsAddr := fs.String("metrics", "", "Enable Prometheus metrics server (e.g. :9090)")
 	referralCode := fs.String("referral", "", "Referral share code to attribute this join (format: XXXXX-XXXXX)")
+	// --chaos is for CI and development only; keep it out of -h.
+	hideFlags(fs, "chaos")
 	fs.Parse(os.Args[2:])
+	if *profileName != "" {
+		if err := applyProfile(fs, *profileName); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	// Containers configure join through WGMESH_<FLAG> variables; the secret
+	// variables are handled below.
+	applyFlagEnv(fs, "secret", "secret-file")
 
-	// If secret not provided via flag, try environment variables
-	if *secret == "" {
+	// If secret not provided via flag, try the secret file, then environment variables
+	if *secret == "" && *secretFile == "" {
 		if envSecret := os.Getenv("WGMESH_SECRET"); envSecret != "" {
 			*secret = envSecret
-		} else if secretFile := os.Getenv("WGMESH_SECRET_FILE"); secretFile != "" {
-			secretBytes, err := os.ReadFile(secretFile)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error reading secret file %s: %v\n", secretFile, err)
-				os.Exit(1)
-			}
-			*secret = strings.TrimSpace(string(secretBytes))
+		} else {
+			*secretFile = os.Getenv("WGMESH_SECRET_FILE")
+		}
+	}
+	if *secret == "" && *secretFile != "" {
+		loaded, err := daemon.LoadSecretFile(*secretFile, secretFilePassword)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading secret file: %v\n", err)
+			os.Exit(1)
 		}
+		*secret = loaded
 	}
 
 	if *secret == "" {
 		fmt.Fprintln(os.Stderr, "Error: --secret is required")
 		fmt.Fprintln(os.Stderr, "Usage: wgmesh join --secret <SECRET>")
+		fmt.Fprintln(os.Stderr, "       or wgmesh join --secret-file <PATH>")
 		fmt.Fprintln(os.Stderr, "       or set WGMESH_SECRET environment variable")
 		fmt.Fprintln(os.Stderr, "       or set WGMESH_SECRET_FILE environment variable")
 		os.Exit(1)
@@ -361,31 +702,35 @@ func joinCmd() {
 		}
 	}
 
-	// Parse advertise routes
-	var routes []string
-	if *advertiseRoutes != "" {
-		routes = strings.Split(*advertiseRoutes, ",")
-		for i, r := range routes {
-			routes[i] = strings.TrimSpace(r)
+	routes := df.routes()
+
+	var kubeClient *kube.Client
+	if *kubernetes {
+		client, node, err := kubernetesNode(*kubernetesNodeName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --kubernetes: %v\n", err)
+			os.Exit(1)
+		}
+		kubeClient = client
+		if len(node.PodCIDRs) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: node %s has no pod CIDR (the CNI manages pod addresses itself); none advertised\n", node.Name)
 		}
+		routes = append(routes, node.PodCIDRs...)
 	}
 
 	// Create daemon config
-	cfg, err := daemon.NewConfig(daemon.DaemonOpts{
-		Secret:              *secret,
-		InterfaceName:       *iface,
-		WGListenPort:        *listenPort,
-		AdvertiseRoutes:     routes,
-		LogLevel:            *logLevel,
-		Privacy:             *privacyMode,
-		Gossip:              *gossipMode,
-		DisableLANDiscovery: *noLANDiscovery,
-		DisableIPv6:         *noIPv6,
-		ForceRelay:          *forceRelay,
-		DisablePunching:     *noPunching,
-		Introducer:          *introducerMode,
-		MeshSubnet:          *meshSubnet,
-	})
+	opts := df.daemonOpts(*secret, routes)
+	opts.LogLevel = *logLevel
+	opts.NoPeerRoutes = *kubernetes && !*kubernetesPodRoutes
+	opts.Chaos = *chaosSpec
+	opts.Version = version
+	// Set by the previous run when it restarted in place; adopt its
+	// interface, but only this once.
+	if os.Getenv(restartedEnv) != "" {
+		opts.AdoptInterface = true
+		os.Unsetenv(restartedEnv)
+	}
+	cfg, err := daemon.NewConfig(opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create config: %v\n", err)
 		os.Exit(1)
@@ -394,6 +739,18 @@ func joinCmd() {
 	// Configure logging before creating the daemon (must be done in main,
 	// not inside library code like NewDaemon).
 	daemon.ConfigureLogging(cfg.LogLevel)
+	daemon.SetLogMeshName(cfg.MeshName)
+
+	// Export OpenTelemetry spans when OTEL_EXPORTER_OTLP_ENDPOINT is set.
+	shutdownTracing, err := tracing.Init("wgmesh", version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: tracing disabled: %v\n", err)
+	}
+	flushTracing := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = shutdownTracing(ctx)
+	}
 
 	// Create and run daemon with DHT discovery
 	d, err := daemon.NewDaemon(cfg)
@@ -402,6 +759,10 @@ func joinCmd() {
 		os.Exit(1)
 	}
 
+	if kubeClient != nil {
+		go annotateNodeLoop(kubeClient, *kubernetesNodeName, d)
+	}
+
 	// Start pprof HTTP server if requested (for profiling/flame graphs)
 	if *pprofAddr != "" {
 		go func() {
@@ -432,8 +793,20 @@ func joinCmd() {
 		rpcSocketPath = getRPCSocketPath()
 	}
 
+	access, err := parseRPCAccess(*rpcAllowUsers, *rpcAllowGroups, *rpcTCPPort, *rpcTokenFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	access.legacyJSON = *rpcLegacyJSON
+
+	// Register the mesh so 'wgmesh networks list' can find its daemon.
+	if err := daemon.RecordNetworkStart(cfg, rpcSocketPath); err != nil {
+		log.Printf("Failed to record the mesh in %s: %v", daemon.NetworksPath(), err)
+	}
+
 	// Create RPC server with callback functions
-	rpcServer, err := createRPCServer(d, rpcSocketPath)
+	rpcServer, err := createRPCServer(d, rpcSocketPath, access)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to create RPC server: %v\n", err)
 	} else {
@@ -442,29 +815,70 @@ func joinCmd() {
 	}
 
 	fmt.Println("Initializing mesh node with DHT discovery...")
-	if *privacyMode {
+	if opts.Privacy {
 		fmt.Println("Privacy mode enabled (Dandelion++ relay)")
 	}
-	if *gossipMode {
+	if opts.PrivateFirstContact {
+		fmt.Println("Private first contact enabled (stemmed through introducers)")
+	}
+	if opts.Gossip {
 		fmt.Println("In-mesh gossip enabled")
 	}
-	if *noLANDiscovery {
+	if opts.GossipRatchet {
+		fmt.Println("Envelope key ratchet enabled")
+	}
+	if opts.DisableLANDiscovery {
 		fmt.Println("LAN discovery disabled")
+	} else if opts.LANMDNS {
+		fmt.Println("LAN discovery via mDNS (_wgmesh._udp.local)")
+	} else if opts.LANBroadcast {
+		fmt.Println("LAN discovery via multicast and broadcast")
 	}
-	if *noIPv6 {
+	if opts.DisableIPv6 {
 		fmt.Println("IPv6 connectivity disabled")
 	}
-	if *forceRelay {
+	if opts.ForceRelay {
 		fmt.Println("Force relay mode enabled")
 	}
-	if *noPunching {
+	if opts.DisablePunching {
 		fmt.Println("NAT punching disabled")
 	}
-	if *introducerMode {
+	if opts.Introducer {
 		fmt.Println("Rendezvous introducer enabled")
+		if cfg.STUNListenPort > 0 {
+			fmt.Printf("STUN responder enabled on UDP port %d\n", cfg.STUNListenPort)
+		}
+	}
+	if len(cfg.STUNServers) > 0 {
+		fmt.Printf("Using STUN servers: %s\n", strings.Join(cfg.STUNServers, ", "))
+	}
+	if cfg.DNSRendezvous != "" {
+		fmt.Printf("DNS rendezvous enabled: %s (DHT disabled)\n", cfg.DNSRendezvous)
 	}
 
-	if err := d.RunWithDHTDiscovery(); err != nil {
+	err = d.RunWithDHTDiscovery()
+	flushTracing()
+	if err != nil {
+		if errors.Is(err, daemon.ErrSecretRotated) {
+			// Start over with the same arguments; NewConfig resolves the
+			// old secret to the rotated one.
+			log.Printf("[Rotation] Restarting under the rotated secret")
+			exe, exeErr := os.Executable()
+			if exeErr == nil {
+				exeErr = syscall.Exec(exe, os.Args, os.Environ())
+			}
+			fmt.Fprintf(os.Stderr, "Failed to restart after secret rotation: %v\n", exeErr)
+			os.Exit(1)
+		}
+		if errors.Is(err, daemon.ErrRestart) {
+			// Exec the binary on disk, which an update may have replaced.
+			exe, exeErr := os.Executable()
+			if exeErr == nil {
+				exeErr = syscall.Exec(exe, os.Args, append(os.Environ(), restartedEnv+"=1"))
+			}
+			fmt.Fprintf(os.Stderr, "Failed to restart: %v\n", exeErr)
+			os.Exit(1)
+		}
 		fmt.Fprintf(os.Stderr, "Daemon error: %v\n", err)
 		os.Exit(1)
 	}
@@ -478,6 +892,7 @@ func testPeerCmd() {
 	listenPort := fs.Int("port", 0, "Local port to listen on (0 = random)")
 	fs.Parse(os.Args[2:])
 
+	*secret = resolveSecret(*secret)
 	if *secret == "" || *peerAddr == "" {
 		fmt.Fprintln(os.Stderr, "Usage: wgmesh test-peer --secret <SECRET> --peer <IP:PORT>")
 		fmt.Fprintln(os.Stderr, "")
@@ -547,7 +962,7 @@ func testPeerCmd() {
 	fmt.Printf("Received %d bytes from %s\n", n, from.String())
 
 	// Try to decrypt
-	envelope, reply, err := crypto.OpenEnvelope(buf[:n], cfg.Keys.GossipKey)
+	envelope, reply, err := crypto.OpenEnvelopeWithKeys(buf[:n], cfg.EnvelopeOpenKeys())
 	if err != nil {
 		fmt.Printf("Failed to decrypt (wrong secret?): %v\n", err)
 		os.Exit(1)
@@ -568,6 +983,7 @@ type StatusOutput struct {
 	MeshIPv6Prefix string `json:"mesh_ipv6_prefix"`
 	GossipPort     int    `json:"gossip_port"`
 	RendezvousID   string `json:"rendezvous_id"`
+	MeshName       string `json:"mesh_name,omitempty"`
 	ServiceStatus  string `json:"service_status,omitempty"`
 }
 
@@ -577,11 +993,15 @@ func statusCmd() {
 	jsonOutput := fs.Bool("json", false, "Output in JSON format")
 	iface := fs.String("interface", "", "WireGuard interface name (default: wg0 on non-macOS, utun20 on macOS)")
 	meshSubnet := fs.String("mesh-subnet", "", "Custom mesh subnet CIDR (e.g. 192.168.100.0/24)")
+	meshName := fs.String("mesh-name", "", "Display name of the mesh (default: the name in a v2 secret URI)")
 	fs.Parse(os.Args[2:])
 
+	// NewConfig applies the options of a v2 URI, so keep it whole.
+	*secret = resolveSecretURI(*secret)
 	if *secret == "" {
 		fmt.Fprintln(os.Stderr, "Error: --secret is required")
 		fmt.Fprintln(os.Stderr, "Usage: wgmesh status --secret <SECRET>")
+		fmt.Fprintln(os.Stderr, "       or start 'wgmesh agent' once to skip --secret")
 		os.Exit(1)
 	}
 
@@ -590,6 +1010,7 @@ func statusCmd() {
 		Secret:        *secret,
 		InterfaceName: *iface,
 		MeshSubnet:    *meshSubnet,
+		MeshName:      *meshName,
 	})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create config: %v\n", err)
@@ -603,17 +1024,12 @@ func statusCmd() {
 		MeshIPv6Prefix: formatIPv6Prefix(cfg.Keys.MeshPrefixV6),
 		GossipPort:     int(cfg.Keys.GossipPort),
 		RendezvousID:   fmt.Sprintf("%x", cfg.Keys.RendezvousID),
-	}
-
-	// Set mesh subnet based on custom or derived
-	if cfg.CustomSubnet != nil {
-		output.MeshSubnet = cfg.CustomSubnet.String()
-	} else {
-		output.MeshSubnet = fmt.Sprintf("10.%d.0.0/16", cfg.Keys.MeshSubnet[0])
+		MeshName:       cfg.MeshName,
+		MeshSubnet:     cfg.MeshSubnetString(),
 	}
 
 	// Get service status if available
-	status, err := daemon.ServiceStatus()
+	status, err := daemon.InitServiceStatus(daemon.DetectInitSystem())
 	if err == nil {
 		output.ServiceStatus = status
 	}
@@ -630,6 +1046,9 @@ func statusCmd() {
 		// Text format (original behavior)
 		fmt.Printf("Mesh Status\n")
 		fmt.Printf("===========\n")
+		if output.MeshName != "" {
+			fmt.Printf("Mesh Name: %s\n", output.MeshName)
+		}
 		fmt.Printf("Interface: %s\n", output.Interface)
 		fmt.Printf("Network ID: %s\n", output.NetworkID)
 		if cfg.CustomSubnet != nil {
@@ -657,15 +1076,18 @@ func qrCmd() {
 	secret := fs.String("secret", "", "Mesh secret to encode as QR code")
 	fs.Parse(os.Args[2:])
 
+	given := resolveSecretURI(*secret)
+	*secret = normalizeSecret(given)
 	if *secret == "" {
 		fmt.Fprintln(os.Stderr, "Error: --secret is required")
 		fmt.Fprintln(os.Stderr, "Usage: wgmesh qr --secret <SECRET>")
 		os.Exit(1)
 	}
 
-	uri := *secret
-	if !strings.HasPrefix(uri, daemon.URIPrefix) {
-		uri = daemon.FormatSecretURI(*secret)
+	// A v2 URI is shown as given, so its options go along.
+	uri := daemon.FormatSecretURI(*secret)
+	if strings.HasPrefix(given, daemon.URIPrefix+daemon.URIVersion2+"/") {
+		uri = given
 	}
 
 	fmt.Println("Mesh Secret QR Code")
@@ -760,20 +1182,17 @@ func installServiceCmd() {
 	secret := fs.String("secret", "", "Mesh secret (required)")
 	account := fs.String("account", "", "Lighthouse API key (cr_...) — saved for service commands")
 	stateDir := fs.String("state-dir", defaultStateDir, "State directory for account config")
-	iface := fs.String("interface", "", "WireGuard interface name (default: wg0 on non-macOS, utun20 on macOS)")
-	listenPort := fs.Int("listen-port", 51820, "WireGuard listen port")
-	advertiseRoutes := fs.String("advertise-routes", "", "Comma-separated routes to advertise")
-	privacyMode := fs.Bool("privacy", false, "Enable privacy mode")
-	gossipMode := fs.Bool("gossip", false, "Enable in-mesh gossip")
-	noLANDiscovery := fs.Bool("no-lan-discovery", false, "Disable LAN multicast discovery")
-	noIPv6 := fs.Bool("no-ipv6", false, "Ignore IPv6 endpoints for connectivity")
-	forceRelay := fs.Bool("force-relay", false, "Prefer relay path for non-LAN peers")
-	noPunching := fs.Bool("no-punching", false, "Disable NAT port punching/rendezvous")
-	introducerMode := fs.Bool("introducer", false, "Allow this node to act as rendezvous introducer")
-	meshSubnet := fs.String("mesh-subnet", "", "Custom mesh subnet CIDR (e.g. 192.168.100.0/24)")
+	df := addDaemonFlags(fs)
+	initSystemFlag := fs.String("init-system", "auto", "Service manager: auto, systemd, openrc, runit, or container (print an env file for the image entrypoint)")
 	fs.Parse(os.Args[2:])
 
-	if *secret == "" {
+	initSystem, err := daemon.ResolveInitSystem(*initSystemFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *secret == "" && initSystem != daemon.InitContainer {
 		fmt.Fprintln(os.Stderr, "Error: --secret is required")
 		fmt.Fprintln(os.Stderr, "Usage: wgmesh install-service --secret <SECRET>")
 		os.Exit(1)
@@ -782,61 +1201,228 @@ func installServiceCmd() {
 	// Save account API key if provided
 	handleAccountFlag(*stateDir, *account)
 
-	var routes []string
-	if *advertiseRoutes != "" {
-		routes = strings.Split(*advertiseRoutes, ",")
-		for i, r := range routes {
-			routes[i] = strings.TrimSpace(r)
-		}
-	}
+	cfg := df.serviceConfig(*secret)
 
-	cfg := daemon.SystemdServiceConfig{
-		Secret:              *secret,
-		InterfaceName:       *iface,
-		ListenPort:          *listenPort,
-		AdvertiseRoutes:     routes,
-		Privacy:             *privacyMode,
-		Gossip:              *gossipMode,
-		DisableLANDiscovery: *noLANDiscovery,
-		DisableIPv6:         *noIPv6,
-		ForceRelay:          *forceRelay,
-		DisablePunching:     *noPunching,
-		Introducer:          *introducerMode,
-		MeshSubnet:          *meshSubnet,
+	if initSystem == daemon.InitContainer {
+		// Nothing to install: the env file configures 'wgmesh join' as the
+		// container's long-running process.
+		fmt.Print(daemon.GenerateContainerEnv(cfg))
+		return
 	}
 
-	fmt.Println("Installing wgmesh systemd service...")
-	if err := daemon.InstallSystemdService(cfg); err != nil {
+	fmt.Printf("Installing wgmesh %s service...\n", initSystem)
+	if err := daemon.InstallService(initSystem, cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to install service: %v\n", err)
 		os.Exit(1)
 	}
 
 	fmt.Println("Service installed and started successfully!")
-	fmt.Println("Check status with: systemctl status wgmesh")
+	if meshCfg, err := daemon.NewConfig(df.daemonOpts(*secret, df.routes())); err == nil {
+		if err := daemon.RecordNetworkService(meshCfg, initSystem); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to record the mesh in %s: %v\n", daemon.NetworksPath(), err)
+		}
+	}
+	switch initSystem {
+	case daemon.InitOpenRC:
+		fmt.Println("Check status with: rc-service wgmesh status")
+	case daemon.InitRunit:
+		fmt.Println("Check status with: sv status wgmesh")
+	default:
+		fmt.Println("Check status with: systemctl status wgmesh")
+	}
+}
+
+// agentCmd handles the "agent" subcommand: it keeps the mesh secret in
+// memory and serves it to other wgmesh commands run by the same user.
+func agentCmd() {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	secretFile := fs.String("secret-file", "", "Read the mesh secret from a file instead of prompting")
+	socketPath := fs.String("socket", "", "Agent socket path (default: $WGMESH_AGENT_SOCKET or per-user runtime dir)")
+	timeout := fs.Duration("timeout", 8*time.Hour, "Forget the secret and exit after this long (0 = never)")
+	fs.Parse(os.Args[2:])
+
+	if *socketPath == "" {
+		*socketPath = rpc.AgentSocketPath()
+	}
+
+	secret := os.Getenv("WGMESH_SECRET")
+	if *secretFile != "" {
+		loaded, err := daemon.LoadSecretFile(*secretFile, secretFilePassword)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading secret file: %v\n", err)
+			os.Exit(1)
+		}
+		secret = loaded
+	}
+	if secret == "" {
+		entered, err := crypto.ReadPassword("Mesh secret: ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read secret: %v\n", err)
+			os.Exit(1)
+		}
+		secret = strings.TrimSpace(entered)
+	}
+	if _, err := daemon.NewConfig(daemon.DaemonOpts{Secret: secret}); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid secret: %v\n", err)
+		os.Exit(1)
+	}
+
+	agent, err := rpc.NewAgent(*socketPath, version, secret)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create agent: %v\n", err)
+		os.Exit(1)
+	}
+	if err := agent.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start agent: %v\n", err)
+		os.Exit(1)
+	}
+	defer agent.Stop()
+
+	fmt.Printf("wgmesh agent listening on %s\n", *socketPath)
+	fmt.Println("Commands like 'wgmesh status' no longer need --secret. Press Ctrl+C to stop.")
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	var expired <-chan time.Time
+	if *timeout > 0 {
+		expired = time.After(*timeout)
+	}
+	select {
+	case <-sigCh:
+	case <-expired:
+		fmt.Println("Agent timeout reached, forgetting the secret")
+	}
+}
+
+// secretFilePassword supplies the password for a password-sealed secret
+// file: WGMESH_SECRET_PASSWORD if set, otherwise an interactive prompt.
+func secretFilePassword() (string, error) {
+	if pw := os.Getenv("WGMESH_SECRET_PASSWORD"); pw != "" {
+		return pw, nil
+	}
+	return crypto.ReadPassword("Secret file password: ")
+}
+
+// sealSecretCmd handles the "seal-secret" subcommand: it writes the secret
+// encrypted to a file usable with join --secret-file.
+func sealSecretCmd() {
+	fs := flag.NewFlagSet("seal-secret", flag.ExitOnError)
+	secret := fs.String("secret", "", "Mesh secret to store (required)")
+	out := fs.String("out", daemon.DefaultSecretFilePath, "Output file")
+	usePassword := fs.Bool("password", false, "Seal with a password instead of this machine's key")
+	fs.Parse(os.Args[2:])
+
+	if *secret == "" {
+		fmt.Fprintln(os.Stderr, "Error: --secret is required")
+		fmt.Fprintln(os.Stderr, "Usage: wgmesh seal-secret --secret <SECRET> [--out PATH] [--password]")
+		os.Exit(1)
+	}
+	password := ""
+	if *usePassword {
+		pw, err := crypto.ReadPasswordTwice("Enter password: ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to read password: %v\n", err)
+			os.Exit(1)
+		}
+		password = pw
+	}
+
+	if err := daemon.SaveSecretFile(*out, *secret, password); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write secret file: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Secret sealed to %s\n", *out)
+	fmt.Printf("Start with: wgmesh join --secret-file %s\n", *out)
+}
+
+// signPeersCmd handles the "sign-peers" subcommand: it signs a static peer
+// manifest with the mesh secret so join --static-peers accepts it.
+func signPeersCmd() {
+	fs := flag.NewFlagSet("sign-peers", flag.ExitOnError)
+	secret := fs.String("secret", "", "Mesh secret to sign with (required)")
+	in := fs.String("in", "", "Manifest to sign (required)")
+	out := fs.String("out", "", "Output file (default: overwrite --in)")
+	fs.Parse(os.Args[2:])
+
+	if *secret == "" || *in == "" {
+		fmt.Fprintln(os.Stderr, "Error: --secret and --in are required")
+		fmt.Fprintln(os.Stderr, "Usage: wgmesh sign-peers --secret <SECRET> --in FILE [--out PATH]")
+		os.Exit(1)
+	}
+	if *out == "" {
+		*out = *in
+	}
+
+	keys, err := crypto.DeriveKeys(*secret)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid secret: %v\n", err)
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read manifest: %v\n", err)
+		os.Exit(1)
+	}
+	manifest, err := daemon.ParseStaticManifest(data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid manifest: %v\n", err)
+		os.Exit(1)
+	}
+	if err := manifest.Sign(keys.MembershipKey[:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to sign manifest: %v\n", err)
+		os.Exit(1)
+	}
+	signed, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode manifest: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*out, append(signed, '\n'), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write manifest: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Signed %d peers to %s\n", len(manifest.Peers), *out)
+	fmt.Printf("Start with: wgmesh join --secret <SECRET> --static-peers %s\n", *out)
 }
 
 // uninstallServiceCmd handles the "uninstall-service" subcommand
 func uninstallServiceCmd() {
-	fmt.Println("Removing wgmesh systemd service...")
-	if err := daemon.UninstallSystemdService(); err != nil {
+	fs := flag.NewFlagSet("uninstall-service", flag.ExitOnError)
+	initSystemFlag := fs.String("init-system", "auto", "Service manager: auto, systemd, openrc or runit")
+	fs.Parse(os.Args[2:])
+
+	initSystem, err := daemon.ResolveInitSystem(*initSystemFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Removing wgmesh %s service...\n", initSystem)
+	if err := daemon.UninstallService(initSystem); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to uninstall service: %v\n", err)
 		os.Exit(1)
 	}
+	if err := daemon.ClearNetworkService(initSystem); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to update %s: %v\n", daemon.NetworksPath(), err)
+	}
 	fmt.Println("Service removed successfully!")
 }
 
-// rotateSecretCmd handles the "rotate-secret" subcommand
+// rotateSecretCmd handles the "rotate-secret" subcommand. Without --current
+// it asks the local daemon to gossip the rotation to the mesh; with --current
+// it only generates the new secret for a manual, offline rotation.
 func rotateSecretCmd() {
 	fs := flag.NewFlagSet("rotate-secret", flag.ExitOnError)
-	currentSecret := fs.String("current", "", "Current mesh secret (required)")
+	currentSecret := fs.String("current", "", "Current mesh secret (offline mode: print the new secret without contacting the daemon)")
 	newSecret := fs.String("new", "", "New mesh secret (auto-generated if empty)")
-	gracePeriod := fs.Duration("grace", 24*time.Hour, "Grace period for dual-secret mode")
+	gracePeriod := fs.Duration("grace", daemon.DefaultRotationGracePeriod, "Grace period for dual-secret mode")
+	socket := fs.String("socket", "", "Daemon RPC socket path (default: $WGMESH_SOCKET or the standard path)")
+	force := fs.Bool("force", false, "Rotate even though some peers run a version without secret rotation (they are cut off)")
 	fs.Parse(os.Args[2:])
 
 	if *currentSecret == "" {
-		fmt.Fprintln(os.Stderr, "Error: --current is required")
-		fmt.Fprintln(os.Stderr, "Usage: wgmesh rotate-secret --current <OLD_SECRET> [--new <NEW_SECRET>] [--grace 24h]")
-		os.Exit(1)
+		rotateSecretViaDaemon(*socket, *newSecret, *gracePeriod, *force)
+		return
 	}
 
 	// Generate new secret if not provided
@@ -849,52 +1435,102 @@ func rotateSecretCmd() {
 		*newSecret = secret
 	}
 
-	// Derive keys from old secret for signing
+	// Validate both secrets the same way a daemon would before accepting them
 	oldKeys, err := crypto.DeriveKeys(*currentSecret)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to derive keys from current secret: %v\n", err)
 		os.Exit(1)
 	}
-
-	// Create rotation announcement
-	announcement, err := crypto.GenerateRotationAnnouncement(oldKeys.MembershipKey[:], *newSecret, *gracePeriod)
-	if err != nil {
+	if _, err := crypto.NewRotationMessage(oldKeys.MembershipKey[:], *newSecret, *gracePeriod); err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to create rotation announcement: %v\n", err)
 		os.Exit(1)
 	}
 
-	_ = announcement // Would be broadcast via gossip in a running mesh
-
 	newURI := daemon.FormatSecretURI(*newSecret)
 
-	fmt.Println("Secret Rotation Initiated")
-	fmt.Println("=========================")
-	fmt.Printf("Grace Period: %v\n", *gracePeriod)
+	fmt.Println("Offline Secret Rotation")
+	fmt.Println("=======================")
 	fmt.Printf("New Secret URI: %s\n", newURI)
 	fmt.Println()
-	fmt.Println("During the grace period, both secrets will be accepted.")
-	fmt.Printf("After %v, all nodes should use the new secret.\n", *gracePeriod)
-	fmt.Println()
-	fmt.Println("Share the new secret with all nodes:")
+	fmt.Println("No daemon was contacted. Restart every node with the new secret:")
 	fmt.Printf("  wgmesh join --secret \"%s\"\n", newURI)
+	fmt.Println()
+	fmt.Println("To rotate a running mesh without restarts, run 'wgmesh rotate-secret'")
+	fmt.Println("without --current on any member node.")
 }
 
-// meshCmd handles the "mesh" subcommand for centralized mesh management
-func meshCmd() {
-	// Check for action subcommand first
-	if len(os.Args) < 3 {
-		fmt.Fprintln(os.Stderr, "Error: action required")
-		fmt.Fprintln(os.Stderr, "Usage: wgmesh mesh <action> [options]")
-		fmt.Fprintln(os.Stderr, "Actions: list")
-		os.Exit(1)
+// rotateSecretViaDaemon starts a rotation through the local daemon's
+// mesh.rotate RPC; the daemon gossips it to every peer.
+func rotateSecretViaDaemon(socketPath, newSecret string, grace time.Duration, force bool) {
+	if socketPath == "" {
+		socketPath = os.Getenv("WGMESH_SOCKET")
+	}
+	if socketPath == "" {
+		socketPath = getRPCSocketPath()
 	}
 
-	action := os.Args[2]
-
-	fs := flag.NewFlagSet("mesh "+action, flag.ExitOnError)
-	stateFile := fs.String("state", "mesh-state.json", "Path to mesh state file")
-	encrypt := fs.Bool("encrypt", false, "Encrypt state file with password")
-	fs.Parse(os.Args[3:])
+	client, err := rpc.NewClient(socketPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to daemon: %v\n", err)
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Is wgmesh daemon running? For an offline rotation pass --current <OLD_SECRET>.")
+		fmt.Fprintf(os.Stderr, "  Socket path: %s\n", socketPath)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	params := map[string]interface{}{"grace": grace.String()}
+	if newSecret != "" {
+		params["new_secret"] = newSecret
+	}
+	if force {
+		params["force"] = true
+	}
+	result, err := client.Call("mesh.rotate", params)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+		os.Exit(1)
+	}
+
+	resultMap, ok := result.(map[string]interface{})
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Invalid response format")
+		os.Exit(1)
+	}
+	newURI, _ := resultMap["new_secret_uri"].(string)
+	switchAt, _ := resultMap["switch_at"].(string)
+
+	fmt.Println("Secret Rotation Initiated")
+	fmt.Println("=========================")
+	fmt.Printf("Grace Period: %v\n", grace)
+	fmt.Printf("Switch At:    %s\n", switchAt)
+	fmt.Printf("New Secret URI: %s\n", newURI)
+	fmt.Println()
+	fmt.Println("The rotation is being gossiped to all peers. Until the switch, nodes")
+	fmt.Println("keep the old secret and also discover peers under the new one; at the")
+	fmt.Println("switch time every daemon restarts under the new secret on its own.")
+	fmt.Println()
+	fmt.Println("Nodes that are offline during the grace period must be rejoined with:")
+	fmt.Printf("  wgmesh join --secret \"%s\"\n", newURI)
+}
+
+// meshCmd handles the "mesh" subcommand for centralized mesh management
+func meshCmd() {
+	// Check for action subcommand first
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "Error: action required")
+		fmt.Fprintln(os.Stderr, "Usage: wgmesh mesh <action> [options]")
+		fmt.Fprintln(os.Stderr, "Actions: list")
+		os.Exit(1)
+	}
+
+	action := os.Args[2]
+
+	fs := flag.NewFlagSet("mesh "+action, flag.ExitOnError)
+	stateFile := fs.String("state", "mesh-state.json", "Path to mesh state file")
+	encrypt := fs.Bool("encrypt", false, "Encrypt state file with password")
+	keyFile := fs.String("key-file", "", "Decrypt state file with the key in this file")
+	fs.Parse(os.Args[3:])
 
 	// Handle encryption flag if set
 	if *encrypt {
@@ -905,6 +1541,14 @@ func meshCmd() {
 		}
 		mesh.SetEncryptionPassword(password)
 	}
+	if *keyFile != "" {
+		key, err := crypto.ReadKeyFile(*keyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		mesh.SetEncryptionKey(key)
+	}
 
 	// Load mesh state
 	m, err := mesh.Load(*stateFile)
@@ -928,25 +1572,146 @@ func getRPCSocketPath() string {
 	return rpc.GetSocketPath()
 }
 
+// rpcAccess is who, besides the socket owner, may use the daemon's RPC,
+// and over which protocols.
+type rpcAccess struct {
+	allowUIDs, allowGIDs []uint32
+	tcpPort              int
+	token                string
+	legacyJSON           bool
+}
+
+// parseRPCAccess resolves the --rpc-allow-* users and groups and reads the
+// TCP token file.
+func parseRPCAccess(users, groups string, tcpPort int, tokenFile string) (rpcAccess, error) {
+	access := rpcAccess{tcpPort: tcpPort}
+	for _, name := range splitList(users) {
+		id := name
+		if u, err := user.Lookup(name); err == nil {
+			id = u.Uid
+		}
+		uid, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return rpcAccess{}, fmt.Errorf("--rpc-allow-users: unknown user %q", name)
+		}
+		access.allowUIDs = append(access.allowUIDs, uint32(uid))
+	}
+	for _, name := range splitList(groups) {
+		id := name
+		if g, err := user.LookupGroup(name); err == nil {
+			id = g.Gid
+		}
+		gid, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return rpcAccess{}, fmt.Errorf("--rpc-allow-groups: unknown group %q", name)
+		}
+		access.allowGIDs = append(access.allowGIDs, uint32(gid))
+	}
+
+	switch {
+	case tcpPort != 0 && tokenFile == "":
+		return rpcAccess{}, fmt.Errorf("--rpc-tcp-port requires --rpc-token-file")
+	case tcpPort == 0 && tokenFile != "":
+		return rpcAccess{}, fmt.Errorf("--rpc-token-file is only used with --rpc-tcp-port")
+	case tokenFile != "":
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return rpcAccess{}, fmt.Errorf("failed to read RPC token: %w", err)
+		}
+		access.token = strings.TrimSpace(string(data))
+		if len(access.token) < rpc.MinTCPTokenLength {
+			return rpcAccess{}, fmt.Errorf("RPC token in %s must be at least %d characters", tokenFile, rpc.MinTCPTokenLength)
+		}
+	}
+	return access, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// daemonMeshName returns the display name of the daemon's mesh, or "" when
+// it has none or predates mesh names.
+func daemonMeshName(client *rpc.Client) string {
+	result, err := client.Call("daemon.status", nil)
+	if err != nil {
+		return ""
+	}
+	status, _ := result.(map[string]interface{})
+	name, _ := status["mesh_name"].(string)
+	return name
+}
+
+// rpcPeerData converts a daemon peer to its RPC form.
+func rpcPeerData(p *daemon.RPCPeerData) *rpc.PeerData {
+	return &rpc.PeerData{
+		WGPubKey:          p.WGPubKey,
+		Hostname:          p.Hostname,
+		MeshIP:            p.MeshIP,
+		MeshIPv6:          p.MeshIPv6,
+		Endpoint:          p.Endpoint,
+		ControlEndpoint:   p.ControlEndpoint,
+		EndpointMethod:    p.EndpointMethod,
+		Candidates:        p.Candidates,
+		LastSeen:          p.LastSeen,
+		LastHandshake:     p.LastHandshake,
+		DiscoveredVia:     p.DiscoveredVia,
+		RoutableNetworks:  p.RoutableNetworks,
+		LatencyMs:         p.LatencyMs,
+		PacketLossPct:     p.PacketLossPct,
+		Probes:            p.Probes,
+		ProbesLost:        p.ProbesLost,
+		Installed:         p.Installed,
+		Introducer:        p.Introducer,
+		IntroducerElected: p.IntroducerElected,
+		Version:           p.Version,
+		Revision:          p.Revision,
+		Capabilities:      p.Capabilities,
+		Compatibility:     p.Compatibility,
+		NATType:           p.NATType,
+		Path:              p.Path,
+		RelayPubKey:       p.RelayPubKey,
+		Identity:          p.Identity,
+		Load:              rpcIntroducerLoad(p.Load),
+		Tags:              p.Tags,
+	}
+}
+
+// rpcIntroducerLoad converts an introducer's reported load to its RPC form.
+func rpcIntroducerLoad(l *daemon.RPCIntroducerLoad) *rpc.IntroducerLoad {
+	if l == nil {
+		return nil
+	}
+	return &rpc.IntroducerLoad{
+		Sessions:     l.Sessions,
+		RelayedPeers: l.RelayedPeers,
+		Bps:          l.Bps,
+		Overloaded:   l.Overloaded,
+	}
+}
+
 // createRPCServer creates an RPC server for the daemon
-func createRPCServer(d *daemon.Daemon, socketPath string) (daemon.RPCServer, error) {
+func createRPCServer(d *daemon.Daemon, socketPath string, access rpcAccess) (daemon.RPCServer, error) {
 	config := rpc.ServerConfig{
 		SocketPath: socketPath,
 		Version:    version,
+		AllowUIDs:  access.allowUIDs,
+		AllowGIDs:  access.allowGIDs,
+		TCPPort:    access.tcpPort,
+		TCPToken:   access.token,
+		LegacyJSON: access.legacyJSON,
 		GetPeers: func() []*rpc.PeerData {
 			rpcPeers := d.GetRPCPeers()
 			result := make([]*rpc.PeerData, len(rpcPeers))
 			for i, p := range rpcPeers {
-				result[i] = &rpc.PeerData{
-					WGPubKey:         p.WGPubKey,
-					Hostname:         p.Hostname,
-					MeshIP:           p.MeshIP,
-					Endpoint:         p.Endpoint,
-					LastSeen:         p.LastSeen,
-					DiscoveredVia:    p.DiscoveredVia,
-					RoutableNetworks: p.RoutableNetworks,
-					LatencyMs:        p.LatencyMs,
-				}
+				result[i] = rpcPeerData(p)
 			}
 			return result
 		},
@@ -955,16 +1720,7 @@ func createRPCServer(d *daemon.Daemon, socketPath string) (daemon.RPCServer, err
 			if !exists {
 				return nil, false
 			}
-			return &rpc.PeerData{
-				WGPubKey:         peer.WGPubKey,
-				Hostname:         peer.Hostname,
-				MeshIP:           peer.MeshIP,
-				Endpoint:         peer.Endpoint,
-				LastSeen:         peer.LastSeen,
-				DiscoveredVia:    peer.DiscoveredVia,
-				RoutableNetworks: peer.RoutableNetworks,
-				LatencyMs:        peer.LatencyMs,
-			}, true
+			return rpcPeerData(peer), true
 		},
 		GetPeerCounts: d.GetRPCPeerCounts,
 		GetStatus: func() *rpc.StatusData {
@@ -973,12 +1729,223 @@ func createRPCServer(d *daemon.Daemon, socketPath string) (daemon.RPCServer, err
 				return nil
 			}
 			return &rpc.StatusData{
-				MeshIP:    status.MeshIP,
-				PubKey:    status.PubKey,
-				Uptime:    status.Uptime,
-				Interface: status.Interface,
+				MeshIP:         status.MeshIP,
+				PubKey:         status.PubKey,
+				Uptime:         status.Uptime,
+				Interface:      status.Interface,
+				Identity:       status.Identity,
+				ClockSkew:      status.ClockSkew,
+				ClockSkewPeers: status.ClockSkewPeers,
+				MeshName:       status.MeshName,
 			}
 		},
+		GetEvents: func(sinceSeq uint64) []*rpc.EventData {
+			events := d.GetRPCEvents(sinceSeq)
+			result := make([]*rpc.EventData, len(events))
+			for i, ev := range events {
+				result[i] = &rpc.EventData{
+					Seq:     ev.Seq,
+					Time:    ev.Time,
+					Type:    ev.Type,
+					PubKey:  ev.PubKey,
+					Details: ev.Details,
+				}
+			}
+			return result
+		},
+		GetEventSubs: func() []*rpc.EventSubscriberData {
+			subs := d.GetRPCEventSubscribers()
+			result := make([]*rpc.EventSubscriberData, len(subs))
+			for i, s := range subs {
+				result[i] = &rpc.EventSubscriberData{Name: s.Name, Queued: s.Queued, Delivered: s.Delivered, Coalesced: s.Coalesced, Dropped: s.Dropped}
+			}
+			return result
+		},
+		GetRoutes: func() ([]*rpc.RouteData, []*rpc.RouteConflictData) {
+			routes, conflicts := d.GetRPCRoutes()
+			routeResult := make([]*rpc.RouteData, len(routes))
+			for i, r := range routes {
+				routeResult[i] = &rpc.RouteData{Network: r.Network, PubKey: r.PubKey, Standby: r.Standby}
+			}
+			conflictResult := make([]*rpc.RouteConflictData, len(conflicts))
+			for i, c := range conflicts {
+				conflictResult[i] = &rpc.RouteConflictData{
+					WinnerNetwork: c.WinnerNetwork,
+					WinnerPubKey:  c.WinnerPubKey,
+					LoserNetwork:  c.LoserNetwork,
+					LoserPubKey:   c.LoserPubKey,
+					Dropped:       c.Dropped,
+				}
+			}
+			return routeResult, conflictResult
+		},
+		RotateSecret: func(newSecret string, grace time.Duration, force bool) (*rpc.RotationData, error) {
+			rotation, err := d.RotateSecret(newSecret, grace, force)
+			if err != nil {
+				return nil, err
+			}
+			return &rpc.RotationData{NewSecretURI: rotation.NewSecretURI, SwitchAt: rotation.SwitchAt}, nil
+		},
+		Broadcast: func(text string) (*rpc.MessageData, error) {
+			msg, err := d.SendBroadcast(text)
+			if err != nil {
+				return nil, err
+			}
+			return rpcMessageData(msg), nil
+		},
+		GetMessages: func() []*rpc.MessageData {
+			messages := d.GetRPCMessages()
+			result := make([]*rpc.MessageData, len(messages))
+			for i, m := range messages {
+				result[i] = rpcMessageData(m)
+			}
+			return result
+		},
+		GetSecret: d.GetRPCSecret,
+		Leave:     d.Leave,
+		Restart:   d.Restart,
+		GetReadiness: func() *rpc.ReadinessData {
+			r := d.GetRPCReadiness()
+			return &rpc.ReadinessData{InterfaceUp: r.InterfaceUp, Peers: r.Peers}
+		},
+		GetLAN: func() *rpc.LANData {
+			lan := d.GetRPCLAN()
+			if lan == nil {
+				return nil
+			}
+			result := &rpc.LANData{Mode: lan.Mode, Group: lan.Group, Broadcast: lan.Broadcast}
+			for _, iface := range lan.Interfaces {
+				result.Interfaces = append(result.Interfaces, rpc.LANInterfaceData{
+					Name:      iface.Name,
+					Addresses: iface.Addresses,
+					Joined:    iface.Joined,
+					Error:     iface.Error,
+					LastHeard: iface.LastHeard,
+				})
+			}
+			return result
+		},
+		GetDHT: func() *rpc.DHTData {
+			dht := d.GetRPCDHT()
+			if dht == nil {
+				return nil
+			}
+			return &rpc.DHTData{
+				NATType:          dht.NATType,
+				Peers:            dht.Peers,
+				TargetPeers:      dht.TargetPeers,
+				AnnounceInterval: dht.AnnounceInterval,
+				QueryInterval:    dht.QueryInterval,
+				LastAnnounce:     dht.LastAnnounce,
+				LastQuery:        dht.LastQuery,
+				Nodes:            dht.Nodes,
+			}
+		},
+		GetResources: func() []*rpc.ResourceData {
+			usage := d.GetRPCResources()
+			result := make([]*rpc.ResourceData, len(usage))
+			for i, u := range usage {
+				result[i] = &rpc.ResourceData{Name: u.Name, Used: u.Used, Limit: u.Limit, Pressure: u.Pressure}
+			}
+			return result
+		},
+		GetPeerStats: func() []*rpc.PeerStatsData {
+			stats := d.GetRPCPeerStats()
+			result := make([]*rpc.PeerStatsData, len(stats))
+			for i, s := range stats {
+				rates := make([]rpc.TrafficRateData, len(s.Rates))
+				for j, r := range s.Rates {
+					rates[j] = rpc.TrafficRateData{Window: r.Window, RxBps: r.RxBps, TxBps: r.TxBps}
+				}
+				result[i] = &rpc.PeerStatsData{
+					PubKey:   s.PubKey,
+					Hostname: s.Hostname,
+					MeshIP:   s.MeshIP,
+					RxBytes:  s.RxBytes,
+					TxBytes:  s.TxBytes,
+					Since:    s.Since,
+					Rates:    rates,
+				}
+			}
+			return result
+		},
+		GetQuarantine: func() []*rpc.QuarantineData {
+			quarantined := d.GetRPCQuarantine()
+			result := make([]*rpc.QuarantineData, len(quarantined))
+			for i, q := range quarantined {
+				result[i] = &rpc.QuarantineData{
+					PubKey:       q.PubKey,
+					Hostname:     q.Hostname,
+					MeshIP:       q.MeshIP,
+					Endpoint:     q.Endpoint,
+					Reason:       q.Reason,
+					PinnedPubKey: q.PinnedPubKey,
+					FirstSeen:    q.FirstSeen,
+					LastSeen:     q.LastSeen,
+				}
+			}
+			return result
+		},
+		ApprovePeer: d.ApprovePeer,
+		ForgetPeer:  d.ForgetPeer,
+		GetPending: func() []*rpc.PendingData {
+			pending := d.GetRPCPending()
+			result := make([]*rpc.PendingData, len(pending))
+			for i, p := range pending {
+				result[i] = &rpc.PendingData{
+					PubKey:    p.PubKey,
+					Hostname:  p.Hostname,
+					MeshIP:    p.MeshIP,
+					Endpoint:  p.Endpoint,
+					Identity:  p.Identity,
+					FirstSeen: p.FirstSeen,
+					LastSeen:  p.LastSeen,
+				}
+			}
+			return result
+		},
+		PingPeer: func(peer string) (*rpc.PingData, error) {
+			ping, err := d.PingPeer(peer)
+			if err != nil {
+				return nil, err
+			}
+			return &rpc.PingData{
+				PubKey:   ping.PubKey,
+				Hostname: ping.Hostname,
+				MeshIP:   ping.MeshIP,
+				Path:     ping.Path,
+				RTT:      ping.RTT,
+				Error:    ping.Error,
+			}, nil
+		},
+		GetPeerRoute: func(peer string) (*rpc.PeerRouteData, error) {
+			route, err := d.GetRPCPeerRoute(peer)
+			if err != nil {
+				return nil, err
+			}
+			return &rpc.PeerRouteData{
+				PubKey:        route.PubKey,
+				Hostname:      route.Hostname,
+				MeshIP:        route.MeshIP,
+				Endpoint:      route.Endpoint,
+				Path:          route.Path,
+				Installed:     route.Installed,
+				RelayPubKey:   route.RelayPubKey,
+				RelayHostname: route.RelayHostname,
+				RelayMeshIP:   route.RelayMeshIP,
+				RelayEndpoint: route.RelayEndpoint,
+			}, nil
+		},
+		ExportState: d.ExportState,
+		GetConfig:   d.RuntimeConfig,
+		SetConfig:   d.SetRuntimeConfig,
+		ImportState: func(snapshot []byte) (*rpc.StateImportData, error) {
+			imported, err := d.ImportState(snapshot)
+			if err != nil {
+				return nil, err
+			}
+			return &rpc.StateImportData{Imported: imported.Imported, Skipped: imported.Skipped}, nil
+		},
 	}
 
 	return rpc.NewServer(config)
@@ -987,12 +1954,18 @@ func createRPCServer(d *daemon.Daemon, socketPath string) (daemon.RPCServer, err
 // peersCmd handles the "peers" subcommand for querying the daemon via RPC
 func peersCmd() {
 	if len(os.Args) < 3 {
-		fmt.Fprintln(os.Stderr, "Usage: wgmesh peers <list|count|get>")
+		fmt.Fprintln(os.Stderr, "Usage: wgmesh peers <list|watch|count|get|top|quarantine|pending|approve|forget>")
 		fmt.Fprintln(os.Stderr, "")
 		fmt.Fprintln(os.Stderr, "Commands:")
-		fmt.Fprintln(os.Stderr, "  list            List all active peers")
-		fmt.Fprintln(os.Stderr, "  count           Show peer counts")
-		fmt.Fprintln(os.Stderr, "  get <pubkey>    Get specific peer by public key")
+		fmt.Fprintln(os.Stderr, "  list              List all active peers")
+		fmt.Fprintln(os.Stderr, "  watch             Redraw peers, handshakes, paths and recent events until interrupted")
+		fmt.Fprintln(os.Stderr, "  count             Show peer counts")
+		fmt.Fprintln(os.Stderr, "  get <pubkey>      Get specific peer by public key (--full for every attribute)")
+		fmt.Fprintln(os.Stderr, "  top               Show peers by traffic rate")
+		fmt.Fprintln(os.Stderr, "  quarantine        List peers held back by identity pinning")
+		fmt.Fprintln(os.Stderr, "  pending           List peers waiting for approval (--approvers)")
+		fmt.Fprintln(os.Stderr, "  approve <pubkey>  Admit a pending peer, or accept a quarantined one and re-pin its identity")
+		fmt.Fprintln(os.Stderr, "  forget <pubkey>   Drop a decommissioned peer from the store, cache and WireGuard now")
 		os.Exit(1)
 	}
 
@@ -1015,24 +1988,64 @@ func peersCmd() {
 
 	switch action {
 	case "list":
-		handlePeersList(client)
+		handlePeersList(client, os.Args[3:])
+	case "watch":
+		handlePeersWatch(client, os.Args[3:])
 	case "count":
 		handlePeersCount(client)
 	case "get":
+		fs := flag.NewFlagSet("peers get", flag.ExitOnError)
+		full := fs.Bool("full", false, "Show every known attribute of the peer")
+		args := os.Args[3:]
+		if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+			// Allow the key before the flags.
+			args = append(append([]string{}, args[1:]...), args[0])
+		}
+		fs.Parse(args)
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: wgmesh peers get <pubkey> [--full]")
+			os.Exit(1)
+		}
+		handlePeersGet(client, fs.Arg(0), *full)
+	case "top":
+		handlePeersTop(client, os.Args[3:])
+	case "quarantine":
+		handlePeersQuarantine(client)
+	case "pending":
+		handlePeersPending(client)
+	case "approve":
 		if len(os.Args) < 4 {
-			fmt.Fprintln(os.Stderr, "Usage: wgmesh peers get <pubkey>")
+			fmt.Fprintln(os.Stderr, "Usage: wgmesh peers approve <pubkey>")
 			os.Exit(1)
 		}
-		handlePeersGet(client, os.Args[3])
+		handlePeersApprove(client, os.Args[3])
+	case "forget":
+		if len(os.Args) < 4 {
+			fmt.Fprintln(os.Stderr, "Usage: wgmesh peers forget <pubkey>")
+			os.Exit(1)
+		}
+		handlePeersForget(client, os.Args[3])
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown action: %s\n", action)
-		fmt.Fprintln(os.Stderr, "Available actions: list, count, get")
+		fmt.Fprintln(os.Stderr, "Available actions: list, watch, count, get, top, quarantine, pending, approve, forget")
 		os.Exit(1)
 	}
 }
 
-func handlePeersList(client *rpc.Client) {
-	result, err := client.Call("peers.list", nil)
+func handlePeersList(client *rpc.Client, args []string) {
+	fs := flag.NewFlagSet("peers list", flag.ExitOnError)
+	var tags []string
+	fs.Func("tag", "Only list peers with this tag, as key=value or key (repeatable)", func(s string) error {
+		tags = append(tags, s)
+		return nil
+	})
+	fs.Parse(args)
+
+	var params map[string]interface{}
+	if len(tags) > 0 {
+		params = map[string]interface{}{"tags": tags}
+	}
+	result, err := client.Call("peers.list", params)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
 		os.Exit(1)
@@ -1050,13 +2063,22 @@ func handlePeersList(client *rpc.Client) {
 		os.Exit(1)
 	}
 
+	if name := daemonMeshName(client); name != "" {
+		fmt.Printf("Mesh: %s\n\n", name)
+	}
 	if len(peersData) == 0 {
-		fmt.Println("No active peers")
+		if len(tags) > 0 {
+			fmt.Println("No active peers with these tags")
+		} else {
+			fmt.Println("No active peers")
+		}
 		return
 	}
 
-	fmt.Printf("%-20s %-19s %-15s %-25s %-10s %-10s %s\n", "HOSTNAME", "PUBLIC KEY", "MESH IP", "ENDPOINT", "LAST SEEN", "LATENCY", "DISCOVERED VIA")
-	fmt.Println(strings.Repeat("-", 130))
+	fmt.Printf("%-20s %-19s %-15s %-25s %-10s %-10s %-6s %-9s %-10s %-9s %-16s %-12s %-16s %s\n", "HOSTNAME", "PUBLIC KEY", "MESH IP", "ENDPOINT", "LAST SEEN", "LATENCY", "LOSS", "STATE", "PATH", "NAT", "LOAD", "VERSION", "DISCOVERED VIA", "TAGS")
+	fmt.Println(strings.Repeat("-", 220))
+
+	incompatible := 0
 
 	for _, peerData := range peersData {
 		peer, ok := peerData.(map[string]interface{})
@@ -1068,10 +2090,7 @@ func handlePeersList(client *rpc.Client) {
 		if !ok {
 			continue
 		}
-		pubkeyShort := pubkey
-		if len(pubkeyShort) > 16 {
-			pubkeyShort = pubkeyShort[:16] + "..."
-		}
+		pubkeyShort := shortPubKey(pubkey)
 
 		hostname, _ := peer["hostname"].(string)
 		if hostname == "" {
@@ -1098,21 +2117,56 @@ func handlePeersList(client *rpc.Client) {
 			}
 		}
 
-		var discoveredViaStr []string
-		if v, ok := peer["discovered_via"]; ok {
-			if discoveredVia, ok := v.([]interface{}); ok {
-				for _, item := range discoveredVia {
-					if s, ok := item.(string); ok {
-						discoveredViaStr = append(discoveredViaStr, s)
-					}
-				}
+		lossStr := "-"
+		if v, ok := peer["packet_loss_pct"]; ok && v != nil {
+			if pct, ok := v.(float64); ok {
+				lossStr = fmt.Sprintf("%.0f%%", pct)
 			}
 		}
 
-		fmt.Printf("%-20s %-19s %-15s %-25s %-10s %-10s %s\n", hostname, pubkeyShort, meshIP, endpoint, lastSeenStr, latencyStr, strings.Join(discoveredViaStr, ","))
+		// Daemons predating --max-installed-peers install every peer.
+		stateStr := "installed"
+		if installed, ok := peer["installed"].(bool); ok && !installed {
+			stateStr = "known"
+		}
+
+		version := orDash(peer["version"])
+		if len(version) > 12 {
+			version = version[:9] + "..."
+		}
+		if compat, _ := peer["compatibility"].(string); compat == daemon.CompatIncompatible {
+			version += "!"
+			incompatible++
+		}
+
+		fmt.Printf("%-20s %-19s %-15s %-25s %-10s %-10s %-6s %-9s %-10s %-9s %-16s %-12s %-16s %s\n", hostname, pubkeyShort, meshIP, endpoint, lastSeenStr, latencyStr, lossStr, stateStr, orDash(peer["path"]), orDash(peer["nat_type"]), formatLoad(peer["load"]), version, strings.Join(stringList(peer["discovered_via"]), ","), orDash(formatTags(peer["tags"])))
+	}
+	if incompatible > 0 {
+		fmt.Printf("\n! incompatible protocol revision; see 'wgmesh peers get <peer>'\n")
 	}
 }
 
+// describePeerProtocol renders a peer's protocol revision, compatibility
+// and capabilities for peers get, or "" for daemons that do not report them.
+func describePeerProtocol(peer map[string]interface{}) string {
+	compat, _ := peer["compatibility"].(string)
+	if compat == "" {
+		return ""
+	}
+	if compat == daemon.CompatLegacy {
+		return "unknown (peer predates protocol revisions)"
+	}
+	revision, _ := peer["protocol_revision"].(float64)
+	line := fmt.Sprintf("revision %d", int(revision))
+	if compat == daemon.CompatIncompatible {
+		line += ", INCOMPATIBLE with this node"
+	}
+	if caps := stringList(peer["capabilities"]); len(caps) > 0 {
+		line += " (" + strings.Join(caps, ", ") + ")"
+	}
+	return line
+}
+
 func handlePeersCount(client *rpc.Client) {
 	result, err := client.Call("peers.count", nil)
 	if err != nil {
@@ -1141,7 +2195,7 @@ func handlePeersCount(client *rpc.Client) {
 	fmt.Printf("Dead peers:   %d\n", int(dead))
 }
 
-func handlePeersGet(client *rpc.Client, pubkey string) {
+func handlePeersGet(client *rpc.Client, pubkey string, full bool) {
 	result, err := client.Call("peers.get", map[string]interface{}{"pubkey": pubkey})
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
@@ -1155,43 +2209,55 @@ func handlePeersGet(client *rpc.Client, pubkey string) {
 	}
 
 	pubkeyStr, _ := peer["pubkey"].(string)
+	hostname, _ := peer["hostname"].(string)
 	meshIP, _ := peer["mesh_ip"].(string)
 	endpoint, _ := peer["endpoint"].(string)
 	lastSeen, _ := peer["last_seen"].(string)
 
 	fmt.Printf("Peer Information\n")
 	fmt.Printf("================\n")
+	if hostname != "" {
+		fmt.Printf("Hostname:       %s\n", hostname)
+	}
 	fmt.Printf("Public Key:     %s\n", pubkeyStr)
 	fmt.Printf("Mesh IP:        %s\n", meshIP)
 	fmt.Printf("Endpoint:       %s\n", endpoint)
 	fmt.Printf("Last Seen:      %s\n", lastSeen)
-
-	if v, ok := peer["discovered_via"]; ok {
-		if discoveredVia, ok := v.([]interface{}); ok && len(discoveredVia) > 0 {
-			discoveredViaStr := make([]string, 0, len(discoveredVia))
-			for _, item := range discoveredVia {
-				if s, ok := item.(string); ok {
-					discoveredViaStr = append(discoveredViaStr, s)
-				}
-			}
-			if len(discoveredViaStr) > 0 {
-				fmt.Printf("Discovered Via: %s\n", strings.Join(discoveredViaStr, ", "))
-			}
+	if age, ok := handshakeAge(peer, time.Now()); ok {
+		fmt.Printf("Handshake:      %s ago\n", formatDuration(age))
+	} else {
+		fmt.Printf("Handshake:      never\n")
+	}
+	if installed, ok := peer["installed"].(bool); ok && !installed {
+		fmt.Printf("State:          known (not installed, see --max-installed-peers)\n")
+	}
+	fmt.Printf("Version:        %s\n", orDash(peer["version"]))
+	if protocol := describePeerProtocol(peer); protocol != "" {
+		fmt.Printf("Protocol:       %s\n", protocol)
+	}
+	fmt.Printf("NAT Type:       %s\n", orDash(peer["nat_type"]))
+	if load, ok := peer["load"].(map[string]interface{}); ok {
+		sessions, _ := load["sessions"].(float64)
+		relayed, _ := load["relayed_peers"].(float64)
+		bps, _ := load["bps"].(float64)
+		loadStr := fmt.Sprintf("%d rendezvous, %d relayed peers, %s/s", int(sessions), int(relayed), formatBytes(bps))
+		if overloaded, _ := load["overloaded"].(bool); overloaded {
+			loadStr += " (overloaded)"
 		}
+		fmt.Printf("Load:           %s\n", loadStr)
 	}
-
-	if routesVal, ok := peer["routable_networks"]; ok {
-		if routes, ok := routesVal.([]interface{}); ok && len(routes) > 0 {
-			routeStrs := make([]string, 0, len(routes))
-			for _, r := range routes {
-				if routeStr, ok := r.(string); ok {
-					routeStrs = append(routeStrs, routeStr)
-				}
-			}
-			if len(routeStrs) > 0 {
-				fmt.Printf("Routes:         %s\n", strings.Join(routeStrs, ", "))
-			}
+	if path, _ := peer["path"].(string); path != "" {
+		if relay, _ := peer["relay_pubkey"].(string); relay != "" {
+			path += " via " + shortPubKey(relay)
 		}
+		fmt.Printf("Path:           %s\n", path)
+	}
+
+	if discoveredVia := stringList(peer["discovered_via"]); len(discoveredVia) > 0 {
+		fmt.Printf("Discovered Via: %s\n", strings.Join(discoveredVia, ", "))
+	}
+	if routes := stringList(peer["routable_networks"]); len(routes) > 0 {
+		fmt.Printf("Routes:         %s\n", strings.Join(routes, ", "))
 	}
 
 	if v, ok := peer["latency_ms"]; ok && v != nil {
@@ -1201,6 +2267,317 @@ func handlePeersGet(client *rpc.Client, pubkey string) {
 	} else {
 		fmt.Printf("Latency:        -\n")
 	}
+
+	if v, ok := peer["packet_loss_pct"]; ok && v != nil {
+		if pct, ok := v.(float64); ok {
+			fmt.Printf("Packet Loss:    %.0f%%\n", pct)
+		}
+	} else {
+		fmt.Printf("Packet Loss:    -\n")
+	}
+
+	if !full {
+		return
+	}
+	probes, _ := peer["probes"].(float64)
+	probesLost, _ := peer["probes_lost"].(float64)
+	introducer, _ := peer["introducer"].(bool)
+	fmt.Printf("Probes:         %d in window, %d lost\n", int(probes), int(probesLost))
+	fmt.Printf("Mesh IPv6:      %s\n", orDash(peer["mesh_ipv6"]))
+	fmt.Printf("Control:        %s\n", orDash(peer["control_endpoint"]))
+	fmt.Printf("Endpoint From:  %s\n", orDash(peer["endpoint_method"]))
+	if candidates := stringList(peer["candidates"]); len(candidates) > 0 {
+		fmt.Printf("Candidates:     %s\n", strings.Join(candidates, ", "))
+	} else {
+		fmt.Printf("Candidates:     -\n")
+	}
+	if elected, _ := peer["introducer_elected"].(bool); elected {
+		fmt.Printf("Introducer:     %v (elected)\n", introducer)
+	} else {
+		fmt.Printf("Introducer:     %v\n", introducer)
+	}
+	if relay, _ := peer["relay_pubkey"].(string); relay != "" {
+		fmt.Printf("Relay:          %s\n", relay)
+	}
+	fmt.Printf("Identity:       %s\n", orDash(peer["identity"]))
+	fmt.Printf("Tags:           %s\n", orDash(formatTags(peer["tags"])))
+}
+
+// stringList converts a JSON array of strings from an RPC result.
+func stringList(v interface{}) []string {
+	items, _ := v.([]interface{})
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// formatTags renders a peer's tags from an RPC result as key=value pairs.
+func formatTags(v interface{}) string {
+	raw, _ := v.(map[string]interface{})
+	tags := make(map[string]string, len(raw))
+	for key, value := range raw {
+		tags[key], _ = value.(string)
+	}
+	return daemon.FormatTags(tags)
+}
+
+// orDash prints a missing or empty RPC string field as "-".
+func orDash(v interface{}) string {
+	if s, _ := v.(string); s != "" {
+		return s
+	}
+	return "-"
+}
+
+// formatLoad renders an introducer's load for the peers table as
+// rendezvous sessions/relayed peers/throughput, with "!" when overloaded.
+func formatLoad(v interface{}) string {
+	load, ok := v.(map[string]interface{})
+	if !ok {
+		return "-"
+	}
+	sessions, _ := load["sessions"].(float64)
+	relayed, _ := load["relayed_peers"].(float64)
+	bps, _ := load["bps"].(float64)
+	out := fmt.Sprintf("%d/%d/%s", int(sessions), int(relayed), formatBytes(bps))
+	if overloaded, _ := load["overloaded"].(bool); overloaded {
+		out += "!"
+	}
+	return out
+}
+
+// shortPubKey abbreviates a public key for table columns.
+func shortPubKey(pubkey string) string {
+	if len(pubkey) > 16 {
+		return pubkey[:16] + "..."
+	}
+	return pubkey
+}
+
+func handlePeersTop(client *rpc.Client, args []string) {
+	fs := flag.NewFlagSet("peers top", flag.ExitOnError)
+	window := fs.String("window", "1m", "Rate window to sort by (1m, 5m or 15m)")
+	limit := fs.Int("n", 10, "Number of peers to show (0 = all)")
+	fs.Parse(args)
+
+	result, err := client.Call("peers.stats", map[string]interface{}{"window": *window, "limit": *limit})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+		os.Exit(1)
+	}
+
+	resultMap, ok := result.(map[string]interface{})
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Invalid response format")
+		os.Exit(1)
+	}
+
+	peersData, ok := resultMap["peers"].([]interface{})
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Invalid peers data")
+		os.Exit(1)
+	}
+
+	if len(peersData) == 0 {
+		fmt.Println("No peer traffic recorded yet")
+		return
+	}
+
+	windowDur, _ := time.ParseDuration(*window)
+	fmt.Printf("%-20s %-19s %-15s %-12s %-12s %-10s %-10s\n", "HOSTNAME", "PUBLIC KEY", "MESH IP", "RX/s ("+*window+")", "TX/s ("+*window+")", "RX TOTAL", "TX TOTAL")
+	fmt.Println(strings.Repeat("-", 104))
+
+	for _, peerData := range peersData {
+		peer, ok := peerData.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		pubkey, _ := peer["pubkey"].(string)
+		pubkeyShort := pubkey
+		if len(pubkeyShort) > 16 {
+			pubkeyShort = pubkeyShort[:16] + "..."
+		}
+
+		hostname, _ := peer["hostname"].(string)
+		if hostname == "" {
+			hostname = pubkeyShort
+		}
+		if len(hostname) > 20 {
+			hostname = hostname[:17] + "..."
+		}
+
+		meshIP, _ := peer["mesh_ip"].(string)
+		rxTotal, _ := peer["rx_bytes"].(float64)
+		txTotal, _ := peer["tx_bytes"].(float64)
+
+		var rxRate, txRate float64
+		if rates, ok := peer["rates"].([]interface{}); ok {
+			for _, rv := range rates {
+				r, ok := rv.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if secs, _ := r["window_seconds"].(float64); time.Duration(secs)*time.Second == windowDur {
+					rxRate, _ = r["rx_bps"].(float64)
+					txRate, _ = r["tx_bps"].(float64)
+				}
+			}
+		}
+
+		fmt.Printf("%-20s %-19s %-15s %-12s %-12s %-10s %-10s\n", hostname, pubkeyShort, meshIP,
+			formatBytes(rxRate), formatBytes(txRate), formatBytes(rxTotal), formatBytes(txTotal))
+	}
+}
+
+func handlePeersQuarantine(client *rpc.Client) {
+	result, err := client.Call("peers.quarantine", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+		os.Exit(1)
+	}
+
+	resultMap, ok := result.(map[string]interface{})
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Invalid response format")
+		os.Exit(1)
+	}
+
+	peersData, ok := resultMap["peers"].([]interface{})
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Invalid peers data")
+		os.Exit(1)
+	}
+
+	if len(peersData) == 0 {
+		fmt.Println("No quarantined peers")
+		return
+	}
+
+	fmt.Printf("%-46s %-15s %-20s %-16s %-19s\n", "PUBLIC KEY", "MESH IP", "HOSTNAME", "REASON", "PINNED TO")
+	fmt.Println(strings.Repeat("-", 120))
+
+	for _, peerData := range peersData {
+		peer, ok := peerData.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		pubkey, _ := peer["pubkey"].(string)
+		meshIP, _ := peer["mesh_ip"].(string)
+		hostname, _ := peer["hostname"].(string)
+		reason, _ := peer["reason"].(string)
+		pinned, _ := peer["pinned_pubkey"].(string)
+		if len(pinned) > 16 {
+			pinned = pinned[:16] + "..."
+		}
+		if len(hostname) > 20 {
+			hostname = hostname[:17] + "..."
+		}
+
+		fmt.Printf("%-46s %-15s %-20s %-16s %-19s\n", pubkey, meshIP, hostname, reason, pinned)
+	}
+
+	fmt.Println()
+	fmt.Println("Approve a peer with: wgmesh peers approve <pubkey>")
+}
+
+func handlePeersPending(client *rpc.Client) {
+	result, err := client.Call("peers.pending", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+		os.Exit(1)
+	}
+
+	resultMap, ok := result.(map[string]interface{})
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Invalid response format")
+		os.Exit(1)
+	}
+
+	peersData, ok := resultMap["peers"].([]interface{})
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Invalid peers data")
+		os.Exit(1)
+	}
+
+	if len(peersData) == 0 {
+		fmt.Println("No peers waiting for approval")
+		return
+	}
+
+	fmt.Printf("%-46s %-15s %-20s %-21s %-10s\n", "PUBLIC KEY", "MESH IP", "HOSTNAME", "ENDPOINT", "WAITING")
+	fmt.Println(strings.Repeat("-", 116))
+
+	indirect := false
+	for _, peerData := range peersData {
+		peer, ok := peerData.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		pubkey, _ := peer["pubkey"].(string)
+		meshIP, _ := peer["mesh_ip"].(string)
+		hostname, _ := peer["hostname"].(string)
+		endpoint, _ := peer["endpoint"].(string)
+		if len(hostname) > 20 {
+			hostname = hostname[:17] + "..."
+		}
+		waiting := "-"
+		if firstSeen, _ := peer["first_seen"].(string); firstSeen != "" {
+			if t, err := time.Parse(time.RFC3339, firstSeen); err == nil {
+				waiting = formatDuration(time.Since(t))
+			}
+		}
+		if identity, _ := peer["identity"].(string); identity == "" {
+			// Only heard of through other peers; approving needs its own
+			// signed announcement.
+			waiting += "*"
+			indirect = true
+		}
+
+		fmt.Printf("%-46s %-15s %-20s %-21s %-10s\n", pubkey, meshIP, hostname, endpoint, waiting)
+	}
+
+	fmt.Println()
+	fmt.Println("Approve a peer from an approver node with: wgmesh peers approve <pubkey>")
+	if indirect {
+		fmt.Println("* not heard from directly yet; it cannot be approved until it is")
+	}
+}
+
+func handlePeersApprove(client *rpc.Client, pubkey string) {
+	if _, err := client.Call("peers.approve", map[string]interface{}{"pubkey": pubkey}); err != nil {
+		fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Approved peer %s\n", pubkey)
+}
+
+func handlePeersForget(client *rpc.Client, pubkey string) {
+	if _, err := client.Call("peers.forget", map[string]interface{}{"pubkey": pubkey}); err != nil {
+		fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Forgot peer %s\n", pubkey)
+}
+
+// formatBytes renders a byte count (or bytes/s) with a binary unit suffix.
+func formatBytes(n float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f%s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f%s", n, units[i])
 }
 
 func formatDuration(d time.Duration) string {
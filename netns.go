@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/netns"
+)
+
+// netnsCmd handles "wgmesh netns": giving a container a route into the mesh
+// without host networking.
+func netnsCmd() {
+	if len(os.Args) < 3 || (os.Args[2] != "attach" && os.Args[2] != "detach") {
+		fmt.Fprintln(os.Stderr, "Usage: wgmesh netns <attach|detach> [--interface wg0] [--runtime docker] <container|pid>")
+		os.Exit(1)
+	}
+	action := os.Args[2]
+	fs := flag.NewFlagSet("netns "+action, flag.ExitOnError)
+	iface := fs.String("interface", daemon.DefaultInterface, "Mesh interface of the running daemon")
+	runtime := fs.String("runtime", "docker", "Container runtime used to find the container's PID (docker or podman)")
+	fs.Parse(os.Args[3:])
+	if fs.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Usage: wgmesh netns %s [--interface wg0] [--runtime docker] <container|pid>\n", action)
+		os.Exit(1)
+	}
+	container := fs.Arg(0)
+
+	pid, err := netns.ContainerPID(*runtime, container)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if action == "detach" {
+		if err := netns.Detach(pid, *iface); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to detach %s: %v\n", container, err)
+			os.Exit(1)
+		}
+		fmt.Printf("Detached %s from the mesh\n", container)
+		return
+	}
+
+	a, err := netns.Attach(pid, *iface)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to attach %s (run as root?): %v\n", container, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Attached %s: %s routes %s via %s (host link %s)\n", container, netns.LinkName, a.MeshSubnet, a.HostAddr, a.HostLink)
+	fmt.Println("Mesh peers see its traffic as coming from this host's mesh IP.")
+}
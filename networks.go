@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/rpc"
+)
+
+// networkState is a registry record with what is running for it now.
+type networkState struct {
+	daemon.NetworkRecord
+	Daemon        string `json:"daemon"`                   // running or stopped
+	ServiceStatus string `json:"service_status,omitempty"` // from the init system
+}
+
+// networksCmd handles "wgmesh networks list".
+func networksCmd() {
+	if len(os.Args) < 3 || os.Args[2] != "list" {
+		fmt.Fprintln(os.Stderr, "Usage: wgmesh networks list [--json]")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintf(os.Stderr, "Lists the meshes this host has joined, as recorded in %s.\n", daemon.NetworksPath())
+		os.Exit(1)
+	}
+	fs := flag.NewFlagSet("networks list", flag.ExitOnError)
+	jsonOutput := fs.Bool("json", false, "Output in JSON format")
+	fs.Parse(os.Args[3:])
+
+	recs, err := daemon.LoadNetworks()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	states := make([]networkState, len(recs))
+	for i, rec := range recs {
+		states[i] = networkState{NetworkRecord: rec, Daemon: networkDaemonState(rec)}
+		if rec.Service != "" {
+			if status, err := daemon.InitServiceStatus(rec.Service); err == nil {
+				states[i].ServiceStatus = status
+			}
+		}
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(states); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode JSON: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(states) == 0 {
+		fmt.Println("No meshes joined on this host")
+		return
+	}
+	renderNetworks(os.Stdout, states, time.Now())
+}
+
+// networkDaemonState reports whether a daemon answers on the record's
+// socket for the record's interface.
+func networkDaemonState(rec daemon.NetworkRecord) string {
+	if rec.SocketPath == "" {
+		return "stopped"
+	}
+	client, err := rpc.NewClient(rec.SocketPath)
+	if err != nil {
+		return "stopped"
+	}
+	defer client.Close()
+	result, err := client.Call("daemon.status", nil)
+	if err != nil {
+		return "stopped"
+	}
+	status, _ := result.(map[string]interface{})
+	if iface, _ := status["interface"].(string); iface != rec.Interface {
+		// Another mesh's daemon took over the socket.
+		return "stopped"
+	}
+	return "running"
+}
+
+func renderNetworks(w io.Writer, states []networkState, now time.Time) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "INTERFACE\tNAME\tNETWORK ID\tSUBNET\tDAEMON\tSERVICE\tLAST STARTED")
+	for _, s := range states {
+		service := "-"
+		if s.Service != "" {
+			service = s.Service
+			if s.ServiceStatus != "" {
+				service += " (" + s.ServiceStatus + ")"
+			}
+		}
+		started := "never"
+		if !s.LastStarted.IsZero() {
+			started = formatDuration(now.Sub(s.LastStarted)) + " ago"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", s.Interface, orDash(s.Name), s.NetworkID, s.MeshSubnet, s.Daemon, service, started)
+	}
+	tw.Flush()
+}
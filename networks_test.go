@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+func TestRenderNetworks(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	states := []networkState{
+		{
+			NetworkRecord: daemon.NetworkRecord{Interface: "wg0", Name: "home lab", NetworkID: "0123456789abcdef", MeshSubnet: "10.12.0.0/16", Service: "systemd", LastStarted: now.Add(-2 * time.Hour)},
+			Daemon:        "running",
+			ServiceStatus: "active",
+		},
+		{
+			NetworkRecord: daemon.NetworkRecord{Interface: "wg1", NetworkID: "fedcba9876543210", MeshSubnet: "192.168.100.0/24"},
+			Daemon:        "stopped",
+		},
+	}
+
+	var buf bytes.Buffer
+	renderNetworks(&buf, states, now)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	for _, want := range []string{"wg0", "home lab", "0123456789abcdef", "running", "systemd (active)", "2h ago"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("wg0 row %q lacks %q", lines[1], want)
+		}
+	}
+	for _, want := range []string{"wg1", "192.168.100.0/24", "stopped", "never"} {
+		if !strings.Contains(lines[2], want) {
+			t.Errorf("wg1 row %q lacks %q", lines[2], want)
+		}
+	}
+}
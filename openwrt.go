@@ -0,0 +1,172 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/openwrt"
+)
+
+// openwrtCmd handles "wgmesh openwrt": installing wgmesh as a procd
+// service configured through /etc/config/wgmesh.
+func openwrtCmd() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, "Usage: wgmesh openwrt <install|uninstall|check>")
+		os.Exit(1)
+	}
+	switch os.Args[2] {
+	case "install":
+		openwrtInstall()
+	case "uninstall":
+		if err := openwrt.Uninstall(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to uninstall service: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Service removed; %s was kept.\n", openwrt.ConfigPath)
+	case "check":
+		openwrtCheck()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown openwrt subcommand: %s\n", os.Args[2])
+		fmt.Fprintln(os.Stderr, "Usage: wgmesh openwrt <install|uninstall|check>")
+		os.Exit(1)
+	}
+}
+
+// openwrtInstall seals the secret, writes /etc/config/wgmesh unless it
+// exists, installs the init script and firewall zone and starts the
+// service.
+func openwrtInstall() {
+	fs := flag.NewFlagSet("openwrt install", flag.ExitOnError)
+	secret := fs.String("secret", "", "Mesh secret (required)")
+	iface := fs.String("interface", daemon.DefaultInterface, "WireGuard interface name")
+	listenPort := fs.Int("listen-port", daemon.DefaultWGPort, "WireGuard listen port")
+	advertiseRoutes := fs.String("advertise-routes", "", "Comma-separated LAN routes to advertise; turns on subnet_router")
+	lanZone := fs.String("lan-zone", "lan", "Firewall zone forwarded to and from the mesh (empty = none)")
+	wanZone := fs.String("wan-zone", "wan", "Firewall zone the WireGuard and peer exchange ports are opened on (empty = none)")
+	fs.Parse(os.Args[3:])
+
+	if *secret == "" {
+		fmt.Fprintln(os.Stderr, "Error: --secret is required")
+		fmt.Fprintln(os.Stderr, "Usage: wgmesh openwrt install --secret <SECRET>")
+		os.Exit(1)
+	}
+	if _, err := crypto.DeriveKeys(*secret); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid secret: %v\n", err)
+		os.Exit(1)
+	}
+
+	settings := &openwrt.Section{Type: openwrt.SectionType, Name: openwrt.SectionName}
+	settings.Set(openwrt.OptionEnabled, "1")
+	settings.Set(openwrt.OptionSecretFile, daemon.DefaultSecretFilePath)
+	settings.Set("interface", *iface)
+	settings.Set("listen_port", strconv.Itoa(*listenPort))
+	if routes := splitList(*advertiseRoutes); len(routes) > 0 {
+		settings.SetList("advertise_routes", routes)
+		settings.Set("subnet_router", "1")
+	}
+
+	// An existing config wins over the flags, and the firewall has to
+	// match what the daemon will actually use.
+	if existing, err := openwrt.LoadConfig(); err == nil {
+		fmt.Printf("Keeping the existing %s\n", openwrt.ConfigPath)
+		settings = existing
+	} else if !os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	// The exchange port comes from the config, where a v2 secret URI may
+	// override the derived one.
+	var cfg *daemon.Config
+	opts, _, err := openwrt.DaemonOpts(settings)
+	if err == nil {
+		opts.Secret = *secret
+		cfg, err = daemon.NewConfig(opts)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid settings: %v\n", err)
+		os.Exit(1)
+	}
+	if opts.InterfaceName == "" {
+		opts.InterfaceName = daemon.DefaultInterface
+	}
+	if opts.WGListenPort == 0 {
+		opts.WGListenPort = daemon.DefaultWGPort
+	}
+
+	if err := os.MkdirAll("/var/lib/wgmesh", 0750); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create state directory (run as root?): %v\n", err)
+		os.Exit(1)
+	}
+	if err := daemon.SaveSecretFile(daemon.DefaultSecretFilePath, *secret, ""); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to store secret: %v\n", err)
+		os.Exit(1)
+	}
+
+	binaryPath, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to find the wgmesh binary: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Installing wgmesh OpenWrt service...")
+	err = openwrt.Install(openwrt.InstallConfig{
+		BinaryPath: binaryPath,
+		SecretFile: daemon.DefaultSecretFilePath,
+		Settings:   settings,
+		Zone: openwrt.Zone{
+			Interface:    opts.InterfaceName,
+			LANZone:      *lanZone,
+			WANZone:      *wanZone,
+			ListenPort:   opts.WGListenPort,
+			ExchangePort: int(cfg.Keys.GossipPort),
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to install service: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("Service installed and started successfully!")
+	fmt.Printf("Change settings with: uci set %s.%s.<option>=<value> && uci commit %s && /etc/init.d/wgmesh reload\n",
+		openwrt.ConfigPackage, openwrt.SectionName, openwrt.ConfigPackage)
+	fmt.Println("View logs with: logread -e wgmesh")
+}
+
+// openwrtCheck validates /etc/config/wgmesh the way the daemon will read
+// it, including the stored secret.
+func openwrtCheck() {
+	settings, err := openwrt.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	opts, secretFile, err := openwrt.DaemonOpts(settings)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", openwrt.ConfigPath, err)
+		os.Exit(1)
+	}
+	opts.Secret, err = daemon.LoadSecretFile(secretFile, secretFilePassword)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	cfg, err := daemon.NewConfig(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", openwrt.ConfigPath, err)
+		os.Exit(1)
+	}
+
+	state := "enabled"
+	if !openwrt.Enabled(settings) {
+		state = "disabled"
+	}
+	fmt.Printf("%s is valid: %s on %s, port %d", openwrt.ConfigPath, state, cfg.InterfaceName, cfg.WGListenPort)
+	if len(cfg.AdvertiseRoutes) > 0 {
+		fmt.Printf(", advertising %s", strings.Join(cfg.AdvertiseRoutes, ", "))
+	}
+	fmt.Println()
+}
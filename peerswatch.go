@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/rpc"
+	"golang.org/x/term"
+)
+
+// peersWatchEvents is how many of the daemon's latest events peers watch
+// shows below the table.
+const peersWatchEvents = 8
+
+// handlePeersWatch implements "wgmesh peers watch": it redraws the peers,
+// their handshake ages, paths and routes every --interval, with the
+// daemon's latest events below, until interrupted.
+func handlePeersWatch(client *rpc.Client, args []string) {
+	fs := flag.NewFlagSet("peers watch", flag.ExitOnError)
+	interval := fs.Duration("interval", 2*time.Second, "Refresh interval")
+	var tags []string
+	fs.Func("tag", "Only show peers with this tag, as key=value or key (repeatable)", func(s string) error {
+		tags = append(tags, s)
+		return nil
+	})
+	fs.Parse(args)
+	if *interval < 100*time.Millisecond {
+		fmt.Fprintln(os.Stderr, "Error: --interval must be at least 100ms")
+		os.Exit(1)
+	}
+
+	var params map[string]interface{}
+	if len(tags) > 0 {
+		params = map[string]interface{}{"tags": tags}
+	}
+	tty := term.IsTerminal(int(os.Stdout.Fd()))
+	title := "wgmesh peers watch"
+	if name := daemonMeshName(client); name != "" {
+		title += ": " + name
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	var events []interface{}
+	var since uint64
+	for {
+		result, err := client.Call("peers.list", params)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+			os.Exit(1)
+		}
+		resultMap, _ := result.(map[string]interface{})
+		peers, _ := resultMap["peers"].([]interface{})
+
+		// Daemons predating events.list just get no event pane.
+		if result, err := client.Call("events.list", map[string]interface{}{"since": since}); err == nil {
+			resultMap, _ := result.(map[string]interface{})
+			latest, _ := resultMap["events"].([]interface{})
+			for _, raw := range latest {
+				if ev, ok := raw.(map[string]interface{}); ok {
+					seq, _ := ev["seq"].(float64)
+					since = max(since, uint64(seq))
+				}
+			}
+			events = append(events, latest...)
+			if len(events) > peersWatchEvents {
+				events = events[len(events)-peersWatchEvents:]
+			}
+		}
+
+		var frame bytes.Buffer
+		if tty {
+			frame.WriteString("\033[H\033[2J")
+		}
+		fmt.Fprintf(&frame, "Every %v: %s    %s\n\n", *interval, title, time.Now().Format("15:04:05"))
+		renderPeersWatch(&frame, peers, events, time.Now())
+		if !tty {
+			frame.WriteString("\n")
+		}
+		os.Stdout.Write(frame.Bytes())
+
+		select {
+		case <-sigCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// renderPeersWatch writes one frame of peers watch: a table of peers, as
+// peers.list returns them, and the given events.list events.
+func renderPeersWatch(w io.Writer, peers, events []interface{}, now time.Time) {
+	names := make(map[string]string)
+	rows := make([]map[string]interface{}, 0, len(peers))
+	handshaken := 0
+	for _, raw := range peers {
+		peer, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		pubkey, _ := peer["pubkey"].(string)
+		hostname, _ := peer["hostname"].(string)
+		names[pubkey] = peerDisplayName(hostname, pubkey)
+		if age, ok := handshakeAge(peer, now); ok && age < daemon.HandshakeStaleAfter {
+			handshaken++
+		}
+		rows = append(rows, peer)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, _ := rows[i]["pubkey"].(string)
+		b, _ := rows[j]["pubkey"].(string)
+		if names[a] != names[b] {
+			return names[a] < names[b]
+		}
+		return a < b
+	})
+
+	fmt.Fprintf(w, "%d peers, %d with a recent handshake\n\n", len(rows), handshaken)
+	if len(rows) > 0 {
+		fmt.Fprintf(w, "%-20s %-15s %-25s %-10s %-10s %-8s %-6s %s\n", "PEER", "MESH IP", "ENDPOINT", "HANDSHAKE", "PATH", "LATENCY", "LOSS", "ROUTES")
+		for _, peer := range rows {
+			pubkey, _ := peer["pubkey"].(string)
+			name := names[pubkey]
+			if len(name) > 20 {
+				name = name[:17] + "..."
+			}
+			meshIP, _ := peer["mesh_ip"].(string)
+
+			handshake := "never"
+			if age, ok := handshakeAge(peer, now); ok {
+				handshake = formatDuration(age)
+				if age >= daemon.HandshakeStaleAfter {
+					handshake += " (stale)"
+				}
+			}
+
+			path := orDash(peer["path"])
+			if relay, _ := peer["relay_pubkey"].(string); relay != "" {
+				if relayName, ok := names[relay]; ok {
+					path += " " + relayName
+				} else {
+					path += " " + shortPubKey(relay)
+				}
+			}
+
+			latency := "-"
+			if ms, ok := peer["latency_ms"].(float64); ok {
+				latency = fmt.Sprintf("%.1fms", ms)
+			}
+			loss := "-"
+			if pct, ok := peer["packet_loss_pct"].(float64); ok {
+				loss = fmt.Sprintf("%.0f%%", pct)
+			}
+
+			fmt.Fprintf(w, "%-20s %-15s %-25s %-10s %-10s %-8s %-6s %s\n", name, meshIP, orDash(peer["endpoint"]), handshake, path, latency, loss, orDash(strings.Join(stringList(peer["routable_networks"]), ",")))
+		}
+	}
+
+	if len(events) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nRecent events:\n")
+	for _, raw := range events {
+		ev, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		at, _ := ev["time"].(string)
+		if t, err := time.Parse(time.RFC3339, at); err == nil {
+			at = t.Local().Format("15:04:05")
+		}
+		who := "-"
+		if pubkey, _ := ev["pubkey"].(string); pubkey != "" {
+			who = names[pubkey]
+			if who == "" {
+				who = shortPubKey(pubkey)
+			}
+		}
+		var details []string
+		if m, ok := ev["details"].(map[string]interface{}); ok {
+			for k, v := range m {
+				details = append(details, fmt.Sprintf("%s=%v", k, v))
+			}
+			sort.Strings(details)
+		}
+		fmt.Fprintf(w, "  %s  %-20s %-20s %s\n", at, orDash(ev["type"]), who, strings.Join(details, " "))
+	}
+}
+
+// handshakeAge returns how long ago a peers.list peer last completed a
+// WireGuard handshake, and false if it never has.
+func handshakeAge(peer map[string]interface{}, now time.Time) (time.Duration, bool) {
+	ts, _ := peer["last_handshake"].(string)
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return 0, false
+	}
+	return max(now.Sub(t), 0), true
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderPeersWatch(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	peers := []interface{}{
+		map[string]interface{}{
+			"pubkey":            "relaykey00000000000000000000000000000000000=",
+			"hostname":          "alpha",
+			"mesh_ip":           "10.42.0.2",
+			"endpoint":          "203.0.113.7:51820",
+			"last_handshake":    now.Add(-30 * time.Second).Format(time.RFC3339),
+			"path":              "direct",
+			"latency_ms":        12.5,
+			"routable_networks": []interface{}{"192.168.1.0/24"},
+		},
+		map[string]interface{}{
+			"pubkey":         "betakey000000000000000000000000000000000000=",
+			"hostname":       "beta",
+			"mesh_ip":        "10.42.0.3",
+			"last_handshake": now.Add(-10 * time.Minute).Format(time.RFC3339),
+			"path":           "relay",
+			"relay_pubkey":   "relaykey00000000000000000000000000000000000=",
+		},
+		map[string]interface{}{"pubkey": "newkey0000000000000000000000000000000000000=", "hostname": "gamma", "mesh_ip": "10.42.0.4"},
+	}
+	events := []interface{}{
+		map[string]interface{}{
+			"seq":     float64(7),
+			"time":    now.Format(time.RFC3339),
+			"type":    "route_failover",
+			"pubkey":  "betakey000000000000000000000000000000000000=",
+			"details": map[string]interface{}{"network": "192.168.1.0/24", "prev_gateway": "x"},
+		},
+	}
+
+	var buf bytes.Buffer
+	renderPeersWatch(&buf, peers, events, now)
+	lines := strings.Split(buf.String(), "\n")
+
+	if lines[0] != "3 peers, 1 with a recent handshake" {
+		t.Errorf("summary = %q", lines[0])
+	}
+	want := []struct{ prefix, contains string }{
+		{"alpha ", "30s        direct"},
+		{"beta ", "10m (stale) relay alpha"},
+		{"gamma ", "never"},
+	}
+	for i, w := range want {
+		row := lines[3+i]
+		if !strings.HasPrefix(row, w.prefix) || !strings.Contains(row, w.contains) {
+			t.Errorf("row %d = %q, want %q containing %q", i, row, w.prefix, w.contains)
+		}
+	}
+	if !strings.Contains(lines[3], "192.168.1.0/24") || !strings.Contains(lines[3], "12.5ms") {
+		t.Errorf("alpha row = %q", lines[3])
+	}
+	if !strings.Contains(buf.String(), "route_failover       beta                 network=192.168.1.0/24 prev_gateway=x") {
+		t.Errorf("events pane missing the failover:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/rpc"
+)
+
+// pingCmd handles "wgmesh ping <peer>": it pings a peer over the mesh
+// through the daemon's probe channel and reports RTT and path.
+func pingCmd() {
+	fs := flag.NewFlagSet("ping", flag.ExitOnError)
+	count := fs.Int("c", 4, "Number of pings to send (0 = until interrupted)")
+	interval := fs.Duration("i", time.Second, "Wait between pings")
+	socket := fs.String("socket", "", "Daemon RPC socket path (default: $WGMESH_SOCKET or the standard path)")
+	target := parseTargetArgs(fs, "Usage: wgmesh ping [-c N] [-i 1s] <hostname|mesh-ip|pubkey>")
+
+	client := dialDaemon(*socket)
+	defer client.Close()
+
+	route := callPeerRoute(client, target)
+	name := peerDisplayName(route.hostname, route.pubKey)
+	fmt.Printf("PING %s (%s) %s\n", name, route.meshIP, route.describePath())
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	var rtts []float64
+	sent := 0
+loop:
+	for seq := 1; *count <= 0 || seq <= *count; seq++ {
+		if seq > 1 {
+			select {
+			case <-sigCh:
+				break loop
+			case <-time.After(*interval):
+			}
+		}
+
+		sent++
+		result, err := client.Call("peers.ping", map[string]interface{}{"peer": route.pubKey})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+			os.Exit(1)
+		}
+		ping, _ := result.(map[string]interface{})
+		path, _ := ping["path"].(string)
+		if rtt, ok := ping["rtt_ms"].(float64); ok {
+			rtts = append(rtts, rtt)
+			fmt.Printf("reply from %s: seq=%d time=%.2f ms path=%s\n", route.meshIP, seq, rtt, path)
+		} else {
+			reason, _ := ping["error"].(string)
+			fmt.Printf("no reply from %s: seq=%d (%s)\n", route.meshIP, seq, reason)
+		}
+
+		select {
+		case <-sigCh:
+			break loop
+		default:
+		}
+	}
+
+	fmt.Printf("\n--- %s ping statistics ---\n", name)
+	loss := 100 * float64(sent-len(rtts)) / float64(sent)
+	fmt.Printf("%d sent, %d received, %.0f%% loss\n", sent, len(rtts), loss)
+	if len(rtts) > 0 {
+		lo, hi, sum := math.Inf(1), 0.0, 0.0
+		for _, r := range rtts {
+			lo = math.Min(lo, r)
+			hi = math.Max(hi, r)
+			sum += r
+		}
+		fmt.Printf("rtt min/avg/max = %.2f/%.2f/%.2f ms\n", lo, sum/float64(len(rtts)), hi)
+	}
+	if len(rtts) == 0 {
+		os.Exit(1)
+	}
+}
+
+// routeCmd handles "wgmesh route <peer>": it shows whether traffic to a
+// peer goes direct, direct over the LAN, or through which relay.
+func routeCmd() {
+	fs := flag.NewFlagSet("route", flag.ExitOnError)
+	socket := fs.String("socket", "", "Daemon RPC socket path (default: $WGMESH_SOCKET or the standard path)")
+	target := parseTargetArgs(fs, "Usage: wgmesh route <hostname|mesh-ip|pubkey>")
+
+	client := dialDaemon(*socket)
+	defer client.Close()
+
+	route := callPeerRoute(client, target)
+	name := peerDisplayName(route.hostname, route.pubKey)
+	fmt.Printf("route to %s (%s), %s\n", name, route.meshIP, route.describePath())
+
+	hop := 1
+	if route.relayPubKey != "" {
+		fmt.Printf("%2d  %-24s %-16s %s\n", hop, peerDisplayName(route.relayHostname, route.relayPubKey), route.relayMeshIP, route.relayEndpoint)
+		hop++
+	}
+	endpoint := route.endpoint
+	if route.relayPubKey != "" {
+		endpoint = "(through the relay)"
+	}
+	fmt.Printf("%2d  %-24s %-16s %s\n", hop, name, route.meshIP, endpoint)
+	if !route.installed {
+		fmt.Println("\nThe peer is known but not installed in WireGuard (--max-installed-peers).")
+	}
+}
+
+// parseTargetArgs parses fs and returns its single positional argument.
+// Flags may come before or after the target.
+func parseTargetArgs(fs *flag.FlagSet, usage string) string {
+	fs.Parse(os.Args[2:])
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+	target := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	if fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(1)
+	}
+	return target
+}
+
+// dialDaemon connects to the daemon's RPC socket or exits.
+func dialDaemon(socketPath string) *rpc.Client {
+	if socketPath == "" {
+		socketPath = os.Getenv("WGMESH_SOCKET")
+	}
+	if socketPath == "" {
+		socketPath = getRPCSocketPath()
+	}
+	client, err := rpc.NewClient(socketPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to daemon: %v\n", err)
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Is wgmesh daemon running?")
+		fmt.Fprintln(os.Stderr, "  Start with: wgmesh join --secret <SECRET>")
+		fmt.Fprintf(os.Stderr, "  Socket path: %s\n", socketPath)
+		os.Exit(1)
+	}
+	return client
+}
+
+type peerRoute struct {
+	pubKey, hostname, meshIP, endpoint, path string
+	installed                                bool
+	relayPubKey, relayHostname, relayMeshIP  string
+	relayEndpoint                            string
+}
+
+func callPeerRoute(client *rpc.Client, target string) *peerRoute {
+	result, err := client.Call("peers.route", map[string]interface{}{"peer": target})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+		os.Exit(1)
+	}
+	m, ok := result.(map[string]interface{})
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Invalid response format")
+		os.Exit(1)
+	}
+	r := &peerRoute{}
+	r.pubKey, _ = m["pubkey"].(string)
+	r.hostname, _ = m["hostname"].(string)
+	r.meshIP, _ = m["mesh_ip"].(string)
+	r.endpoint, _ = m["endpoint"].(string)
+	r.path, _ = m["path"].(string)
+	r.installed, _ = m["installed"].(bool)
+	r.relayPubKey, _ = m["relay_pubkey"].(string)
+	r.relayHostname, _ = m["relay_hostname"].(string)
+	r.relayMeshIP, _ = m["relay_mesh_ip"].(string)
+	r.relayEndpoint, _ = m["relay_endpoint"].(string)
+	return r
+}
+
+func (r *peerRoute) describePath() string {
+	if r.relayPubKey != "" {
+		return "via relay " + peerDisplayName(r.relayHostname, r.relayPubKey)
+	}
+	return r.path
+}
+
+// peerDisplayName prefers the hostname and falls back to a short key.
+func peerDisplayName(hostname, pubKey string) string {
+	if hostname != "" {
+		return hostname
+	}
+	if len(pubKey) > 8 {
+		return pubKey[:8] + "..."
+	}
+	return pubKey
+}
@@ -0,0 +1,90 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// In approval mode nodes only admit peers an approver vouched for. The
+// approvers are named by their identity keys, which never leave their
+// nodes, so a leaked mesh secret is not enough to get in.
+const approvalSigningContext = "wgmesh-approval-v1\n"
+
+// AdmissionApproval is an approver's signed statement that the node with
+// WGPubKey and Identity belongs in the mesh. The approved node attaches it
+// to its announcements.
+type AdmissionApproval struct {
+	WGPubKey   string `json:"wg_pubkey"`
+	Identity   string `json:"identity"` // identity key of the approved node
+	Approver   string `json:"approver"` // identity key that signed
+	ApprovedAt int64  `json:"approved_at"`
+	Signature  string `json:"signature"`
+}
+
+// ApprovalMessage carries an approval to the approved node and floods it
+// to the rest of the mesh so pending copies are admitted right away.
+type ApprovalMessage struct {
+	Protocol  string             `json:"protocol"`
+	Timestamp int64              `json:"timestamp"`
+	Approval  *AdmissionApproval `json:"approval"`
+}
+
+// NewAdmissionApproval signs an approval of the node with wgPubKey and
+// identity with the approver's identity key.
+func NewAdmissionApproval(key ed25519.PrivateKey, wgPubKey, identity string) (*AdmissionApproval, error) {
+	if wgPubKey == "" || identity == "" {
+		return nil, fmt.Errorf("node has no WireGuard or identity key")
+	}
+	a := &AdmissionApproval{
+		WGPubKey:   wgPubKey,
+		Identity:   identity,
+		Approver:   IdentityPublicKey(key),
+		ApprovedAt: time.Now().Unix(),
+	}
+	msg, err := a.signedBytes()
+	if err != nil {
+		return nil, err
+	}
+	a.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, msg))
+	return a, nil
+}
+
+// Verify checks that the approval was signed by one of approvers.
+func (a *AdmissionApproval) Verify(approvers []string) error {
+	if !slices.Contains(approvers, a.Approver) {
+		return fmt.Errorf("approver %s is not trusted", a.Approver)
+	}
+	pub, err := base64.StdEncoding.DecodeString(a.Approver)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid approver key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(a.Signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("invalid signature encoding")
+	}
+	msg, err := a.signedBytes()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pub), msg, sig) {
+		return fmt.Errorf("signature does not match approver")
+	}
+	return nil
+}
+
+func (a *AdmissionApproval) signedBytes() ([]byte, error) {
+	payload, err := json.Marshal(struct {
+		WGPubKey   string `json:"wg_pubkey"`
+		Identity   string `json:"identity"`
+		Approver   string `json:"approver"`
+		ApprovedAt int64  `json:"approved_at"`
+	}{a.WGPubKey, a.Identity, a.Approver, a.ApprovedAt})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode approval for signing: %w", err)
+	}
+	return append([]byte(approvalSigningContext), payload...), nil
+}
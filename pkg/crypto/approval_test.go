@@ -0,0 +1,43 @@
+package crypto
+
+import "testing"
+
+func TestAdmissionApproval(t *testing.T) {
+	approverKey, _ := GenerateIdentityKey()
+	nodeKey, _ := GenerateIdentityKey()
+	approvers := []string{IdentityPublicKey(approverKey)}
+
+	a, err := NewAdmissionApproval(approverKey, "node-wg-key", IdentityPublicKey(nodeKey))
+	if err != nil {
+		t.Fatalf("NewAdmissionApproval: %v", err)
+	}
+	if err := a.Verify(approvers); err != nil {
+		t.Errorf("valid approval rejected: %v", err)
+	}
+
+	if err := a.Verify([]string{IdentityPublicKey(nodeKey)}); err == nil {
+		t.Error("approval by an untrusted approver should be rejected")
+	}
+
+	tampered := *a
+	tampered.WGPubKey = "other-wg-key"
+	if err := tampered.Verify(approvers); err == nil {
+		t.Error("approval moved to another WireGuard key should be rejected")
+	}
+	tampered = *a
+	tampered.Identity = IdentityPublicKey(approverKey)
+	if err := tampered.Verify(approvers); err == nil {
+		t.Error("approval moved to another identity should be rejected")
+	}
+
+	// A node cannot approve itself by naming a trusted approver.
+	forged, _ := NewAdmissionApproval(nodeKey, "node-wg-key", IdentityPublicKey(nodeKey))
+	forged.Approver = approvers[0]
+	if err := forged.Verify(approvers); err == nil {
+		t.Error("approval not signed by the named approver should be rejected")
+	}
+
+	if _, err := NewAdmissionApproval(approverKey, "node-wg-key", ""); err == nil {
+		t.Error("approval of a node without an identity key should fail")
+	}
+}
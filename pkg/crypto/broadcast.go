@@ -0,0 +1,98 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxBroadcastLength bounds the text of an operator broadcast.
+	MaxBroadcastLength = 1024
+	// BroadcastWindow is how long after signing a broadcast still
+	// validates, and so how long nodes must remember its ID.
+	BroadcastWindow = time.Hour
+)
+
+// BroadcastMessage is a free-form operator message flooded to every node,
+// such as a maintenance notice. Any member can sign one: the membership key
+// proves the sender is in the mesh, not which node it is.
+type BroadcastMessage struct {
+	Protocol  string `json:"protocol"`
+	Timestamp int64  `json:"timestamp"` // per-hop freshness; SentAt is signed
+	ID        string `json:"id"`
+	Sender    string `json:"sender"` // WireGuard public key of the sending node
+	Hostname  string `json:"hostname,omitempty"`
+	Text      string `json:"text"`
+	SentAt    int64  `json:"sent_at"`
+	Signature []byte `json:"signature"` // HMAC-SHA256(membership_key, message)
+}
+
+// NewBroadcastMessage creates a signed broadcast of text from sender.
+func NewBroadcastMessage(membershipKey []byte, sender, hostname, text string) (*BroadcastMessage, error) {
+	if err := validateBroadcastText(text); err != nil {
+		return nil, err
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate message ID: %w", err)
+	}
+	now := time.Now().Unix()
+	msg := &BroadcastMessage{
+		Protocol:  ProtocolVersion,
+		Timestamp: now,
+		ID:        hex.EncodeToString(id),
+		Sender:    sender,
+		Hostname:  hostname,
+		Text:      text,
+		SentAt:    now,
+	}
+	msg.Signature = signBroadcast(membershipKey, msg)
+	return msg, nil
+}
+
+// Verify checks that the message was signed with membershipKey within the
+// last BroadcastWindow and carries acceptable text.
+func (m *BroadcastMessage) Verify(membershipKey []byte) error {
+	if m.ID == "" || m.Sender == "" {
+		return fmt.Errorf("missing message ID or sender")
+	}
+	if len(m.Hostname) > MaxHostnameLength {
+		return fmt.Errorf("hostname too long")
+	}
+	if err := validateBroadcastText(m.Text); err != nil {
+		return err
+	}
+	sentAt := time.Unix(m.SentAt, 0)
+	window := BroadcastWindow + ClockSkewTolerance()
+	if time.Since(sentAt) > window || sentAt.After(time.Now().Add(window)) {
+		return fmt.Errorf("message sent at %s is outside the accepted window", sentAt.UTC().Format(time.RFC3339))
+	}
+	if !hmac.Equal(m.Signature, signBroadcast(membershipKey, m)) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+func validateBroadcastText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("message text is empty")
+	}
+	if len(text) > MaxBroadcastLength {
+		return fmt.Errorf("message text is %d bytes, at most %d allowed", len(text), MaxBroadcastLength)
+	}
+	return nil
+}
+
+// signBroadcast covers everything but the per-hop timestamp. Free-form
+// fields are quoted so no field can run into the next.
+func signBroadcast(membershipKey []byte, m *BroadcastMessage) []byte {
+	data := fmt.Sprintf("%s|%s|%d|%q|%q", m.ID, m.Sender, m.SentAt, m.Hostname, m.Text)
+	mac := hmac.New(sha256.New, membershipKey)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
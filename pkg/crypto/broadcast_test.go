@@ -0,0 +1,52 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBroadcastMessage(t *testing.T) {
+	key := []byte("membership-key-that-is-32-bytes!")
+	msg, err := NewBroadcastMessage(key, "node1-pubkey", "node1", "maintenance at 22:00")
+	if err != nil {
+		t.Fatalf("NewBroadcastMessage failed: %v", err)
+	}
+	if err := msg.Verify(key); err != nil {
+		t.Errorf("valid message rejected: %v", err)
+	}
+
+	if err := msg.Verify([]byte("wrong-key-that-is-also-32-bytes!")); err == nil {
+		t.Error("message under another mesh's key should be rejected")
+	}
+
+	tampered := *msg
+	tampered.Text = "maintenance at 23:00"
+	if err := tampered.Verify(key); err == nil {
+		t.Error("tampered text should be rejected")
+	}
+
+	// The per-hop timestamp is not signed, so relays can refresh it.
+	hop := *msg
+	hop.Timestamp += 60
+	if err := hop.Verify(key); err != nil {
+		t.Errorf("refreshed hop timestamp rejected: %v", err)
+	}
+
+	old := *msg
+	old.SentAt = time.Now().Add(-2 * BroadcastWindow).Unix()
+	old.Signature = signBroadcast(key, &old)
+	if err := old.Verify(key); err == nil {
+		t.Error("message older than the broadcast window should be rejected")
+	}
+}
+
+func TestBroadcastMessageText(t *testing.T) {
+	key := []byte("membership-key-that-is-32-bytes!")
+	if _, err := NewBroadcastMessage(key, "node1-pubkey", "node1", "  "); err == nil {
+		t.Error("empty text should be rejected")
+	}
+	if _, err := NewBroadcastMessage(key, "node1-pubkey", "node1", strings.Repeat("x", MaxBroadcastLength+1)); err == nil {
+		t.Error("oversized text should be rejected")
+	}
+}
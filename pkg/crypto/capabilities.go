@@ -0,0 +1,66 @@
+package crypto
+
+import (
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// Announcements carry the sender's protocol revision and the optional
+// message types and fields it implements, so that nodes in a mesh running
+// several wgmesh versions can tell what their peers understand instead of
+// failing on missing fields or dropped messages.
+const (
+	// ProtocolRevision numbers changes to the messages within
+	// ProtocolVersion. Bump it when a change needs peers to understand it,
+	// and add a capability bit for anything optional.
+	ProtocolRevision = 1
+
+	// MinProtocolRevision is the oldest revision this node can mesh with.
+	// Nodes that predate revisions announce none and count as revision 0.
+	MinProtocolRevision = 0
+)
+
+// Capabilities is a set of optional protocol features.
+type Capabilities uint64
+
+const (
+	CapGoodbye      Capabilities = 1 << iota // GOODBYE on shutdown
+	CapRotate                                // ROTATE: secret rotation
+	CapBroadcast                             // BROADCAST: signed operator messages
+	CapApproval                              // APPROVAL and announced admissions
+	CapDigest                                // DIGEST gossip anti-entropy
+	CapPortHop                               // follows PortHop schedules
+	CapTCPTransport                          // TLS-wrapped WireGuard via introducers
+)
+
+// LocalCapabilities are the features this build implements.
+const LocalCapabilities = CapGoodbye | CapRotate | CapBroadcast | CapApproval | CapDigest | CapPortHop | CapTCPTransport
+
+var capabilityNames = []string{"goodbye", "rotate", "broadcast", "approval", "digest", "port-hop", "tcp-transport"}
+
+// Has reports whether c includes every feature in want.
+func (c Capabilities) Has(want Capabilities) bool {
+	return c&want == want
+}
+
+// Names lists the features in c, with bits this build does not know as
+// "bitN".
+func (c Capabilities) Names() []string {
+	var names []string
+	for c != 0 {
+		i := bits.TrailingZeros64(uint64(c))
+		if i < len(capabilityNames) {
+			names = append(names, capabilityNames[i])
+		} else {
+			names = append(names, "bit"+strconv.Itoa(i))
+		}
+		c &^= 1 << i
+	}
+	return names
+}
+
+// String returns the comma-separated feature names.
+func (c Capabilities) String() string {
+	return strings.Join(c.Names(), ",")
+}
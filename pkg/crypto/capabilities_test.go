@@ -0,0 +1,44 @@
+package crypto
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestCapabilitiesNames(t *testing.T) {
+	caps := CapRotate | CapPortHop | 1<<40
+	if got, want := caps.Names(), []string{"rotate", "port-hop", "bit40"}; !slices.Equal(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+	if !caps.Has(CapRotate|CapPortHop) || caps.Has(CapGoodbye) {
+		t.Errorf("Has is wrong for %s", caps)
+	}
+	if n := len(LocalCapabilities.Names()); n != len(capabilityNames) {
+		t.Errorf("LocalCapabilities has %d features, capabilityNames %d", n, len(capabilityNames))
+	}
+}
+
+func TestAnnouncementCarriesRevisions(t *testing.T) {
+	ann := CreateAnnouncement("key", "10.0.0.1", "1.2.3.4:51820", false, nil, nil, "", "", "")
+	data, err := json.Marshal(ann)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got PeerAnnouncement
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Revision != ProtocolRevision || got.MinRevision != MinProtocolRevision || got.Capabilities != LocalCapabilities {
+		t.Errorf("round trip = revision %d, min %d, caps %s", got.Revision, got.MinRevision, got.Capabilities)
+	}
+
+	// Announcements from nodes that predate revisions have none.
+	var old PeerAnnouncement
+	if err := json.Unmarshal([]byte(`{"protocol":"wgmesh-v1","wg_pubkey":"key","mesh_ip":"10.0.0.1","version":"0.9.0"}`), &old); err != nil {
+		t.Fatal(err)
+	}
+	if old.Revision != 0 || old.Capabilities != 0 {
+		t.Errorf("old announcement = revision %d, caps %s", old.Revision, old.Capabilities)
+	}
+}
@@ -0,0 +1,59 @@
+package crypto
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// MaxClockSkewTolerance caps how far a detected clock skew widens the
+// timestamp windows, so a bad estimate cannot turn replay protection off.
+const MaxClockSkewTolerance = time.Hour
+
+// clockSkew is the estimated offset of the local clock from the mesh's, in
+// nanoseconds: positive when the local clock runs ahead. See SetClockSkew.
+var clockSkew atomic.Int64
+
+// SetClockSkew records how far the local clock is estimated to run ahead of
+// the rest of the mesh (negative when it runs behind). Timestamp checks then
+// accept messages that far outside their usual window, and the hourly
+// network ID follows the mesh's hour rather than the local one.
+func SetClockSkew(skew time.Duration) {
+	clockSkew.Store(int64(skew))
+}
+
+// ClockSkew returns the skew last set with SetClockSkew.
+func ClockSkew() time.Duration {
+	return time.Duration(clockSkew.Load())
+}
+
+// ClockSkewTolerance is how much timestamp windows are widened by: the
+// magnitude of ClockSkew, at most MaxClockSkewTolerance.
+func ClockSkewTolerance() time.Duration {
+	skew := ClockSkew()
+	if skew < 0 {
+		skew = -skew
+	}
+	return min(skew, MaxClockSkewTolerance)
+}
+
+// meshNow is the local time corrected by ClockSkew.
+func meshNow() time.Time {
+	return now().Add(-ClockSkew())
+}
+
+// TimestampError is returned for a message that decrypted correctly but
+// whose timestamp lies outside the accepted window. Its sender holds the
+// mesh key, so the offset is a usable clock sample even though the message
+// itself is dropped.
+type TimestampError struct {
+	SentAt time.Time
+	Offset time.Duration // local time minus SentAt
+}
+
+func (e *TimestampError) Error() string {
+	if e.Offset < 0 {
+		return "message timestamp in future"
+	}
+	return fmt.Sprintf("message too old: %v", e.Offset)
+}
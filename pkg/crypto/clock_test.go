@@ -0,0 +1,61 @@
+package crypto
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOpenEnvelopeClockSkewTolerance(t *testing.T) {
+	keys, err := DeriveKeys("test-secret-for-clock-skew")
+	if err != nil {
+		t.Fatalf("DeriveKeys: %v", err)
+	}
+	sentAt := time.Now().Add(-MaxMessageAge - 3*time.Minute).Truncate(time.Second)
+	sealed, err := SealEnvelope(MessageTypeHello, map[string]interface{}{
+		"protocol":  ProtocolVersion,
+		"timestamp": sentAt.Unix(),
+	}, keys.GossipKey)
+	if err != nil {
+		t.Fatalf("SealEnvelope: %v", err)
+	}
+	open := func() (*Envelope, error) {
+		env, _, err := OpenEnvelopeRawWithKeys(sealed, [][32]byte{keys.GossipKey})
+		return env, err
+	}
+
+	_, err = open()
+	var stale *TimestampError
+	if !errors.As(err, &stale) {
+		t.Fatalf("err = %v, want a *TimestampError", err)
+	}
+	if !stale.SentAt.Equal(sentAt) || stale.Offset < MaxMessageAge {
+		t.Errorf("TimestampError = %+v, want SentAt %v and an offset past MaxMessageAge", stale, sentAt)
+	}
+
+	// Once the local clock is known to run 5 minutes ahead, the message is
+	// within the widened window.
+	SetClockSkew(5 * time.Minute)
+	defer SetClockSkew(0)
+	env, err := open()
+	if err != nil {
+		t.Fatalf("with 5m skew: %v", err)
+	}
+	if !env.SentAt.Equal(sentAt) {
+		t.Errorf("SentAt = %v, want %v", env.SentAt, sentAt)
+	}
+}
+
+func TestClockSkewToleranceCapped(t *testing.T) {
+	defer SetClockSkew(0)
+	for _, skew := range []time.Duration{-3 * time.Hour, 3 * time.Hour} {
+		SetClockSkew(skew)
+		if got := ClockSkewTolerance(); got != MaxClockSkewTolerance {
+			t.Errorf("ClockSkewTolerance() with skew %v = %v, want %v", skew, got, MaxClockSkewTolerance)
+		}
+	}
+	SetClockSkew(-time.Minute)
+	if got := ClockSkewTolerance(); got != time.Minute {
+		t.Errorf("ClockSkewTolerance() with skew -1m = %v, want 1m", got)
+	}
+}
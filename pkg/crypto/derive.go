@@ -22,9 +22,11 @@ const (
 	hkdfInfoIPv6Prefix   = "wgmesh-ipv6-prefix-v1"
 	hkdfInfoMulticast    = "wgmesh-mcast-v1"
 	hkdfInfoPSK          = "wgmesh-wg-psk-v1"
+	hkdfInfoPairPSK      = "wgmesh-wg-pair-psk-v1"
 	hkdfInfoGossipPort   = "wgmesh-gossip-port-v1"
 	hkdfInfoMembership   = "wgmesh-membership-v1"
 	hkdfInfoEpoch        = "wgmesh-epoch-v1"
+	hkdfInfoHopPort      = "wgmesh-hop-port-v1"
 	hkdfRendezvousSuffix = "rv"
 
 	// Key and parameter sizes.
@@ -35,6 +37,11 @@ const (
 	// GossipPortBase is the starting port for gossip listeners.
 	GossipPortBase  = 51821
 	gossipPortRange = 1000
+
+	// HopPortBase and HopPortRange bound the WireGuard ports port hopping
+	// picks from, below the Linux ephemeral port range.
+	HopPortBase  = 20000
+	HopPortRange = 12768
 )
 
 // DerivedKeys holds all keys and parameters derived from a shared secret
@@ -115,6 +122,22 @@ func DeriveKeys(secret string) (*DerivedKeys, error) {
 	return keys, nil
 }
 
+// DerivePairPSK derives the WireGuard preshared key for the tunnel between
+// the nodes with the two given WireGuard public keys. Both ends derive the
+// same key whichever order they pass the keys in, and a key read off one
+// node's interface is no use for any pair that node is not part of.
+func DerivePairPSK(secret, pubKeyA, pubKeyB string) ([32]byte, error) {
+	var psk [32]byte
+	if pubKeyA > pubKeyB {
+		pubKeyA, pubKeyB = pubKeyB, pubKeyA
+	}
+	// pair_psk = HKDF(secret, info=hkdfInfoPairPSK|lower|higher, 32 bytes)
+	if err := deriveHKDF(secret, hkdfInfoPairPSK+"|"+pubKeyA+"|"+pubKeyB, psk[:]); err != nil {
+		return psk, fmt.Errorf("failed to derive pair PSK: %w", err)
+	}
+	return psk, nil
+}
+
 // DeriveNetworkIDWithTime derives a time-rotating network ID for DHT privacy
 // This rotates hourly to prevent DHT surveillance
 func DeriveNetworkIDWithTime(secret string, t time.Time) ([20]byte, error) {
@@ -130,10 +153,33 @@ func DeriveNetworkIDWithTime(secret string, t time.Time) ([20]byte, error) {
 	return networkID, nil
 }
 
+// DeriveHopPort derives the WireGuard listen port a node with the given
+// public key uses during the port hopping period of length interval that
+// contains t. Every node holding the secret computes the same port.
+func DeriveHopPort(secret, pubKey string, interval time.Duration, t time.Time) (uint16, error) {
+	if interval < time.Second {
+		return 0, fmt.Errorf("port hop interval %v is too short", interval)
+	}
+	period := t.Unix() / int64(interval/time.Second)
+	// hop_port = HopPortBase + (uint16(HKDF(secret, info=hkdfInfoHopPort|pubkey|period)) % HopPortRange)
+	var b [2]byte
+	if err := deriveHKDF(secret, fmt.Sprintf("%s|%s|%d", hkdfInfoHopPort, pubKey, period), b[:]); err != nil {
+		return 0, fmt.Errorf("failed to derive hop port: %w", err)
+	}
+	return HopPortBase + binary.BigEndian.Uint16(b[:])%HopPortRange, nil
+}
+
+// CurrentHopPort is DeriveHopPort for the mesh's current time: the local
+// clock corrected by ClockSkew.
+func CurrentHopPort(secret, pubKey string, interval time.Duration) (uint16, error) {
+	return DeriveHopPort(secret, pubKey, interval, meshNow())
+}
+
 // GetCurrentAndPreviousNetworkIDs returns both current and previous hour's network IDs
-// for smooth transition during hourly rotation
+// for smooth transition during hourly rotation. The hour is the mesh's: the
+// local clock corrected by ClockSkew.
 func GetCurrentAndPreviousNetworkIDs(secret string) (current, previous [20]byte, err error) {
-	now := time.Now().UTC()
+	now := meshNow().UTC()
 
 	current, err = DeriveNetworkIDWithTime(secret, now)
 	if err != nil {
@@ -148,6 +194,45 @@ func GetCurrentAndPreviousNetworkIDs(secret string) (current, previous [20]byte,
 	return current, previous, nil
 }
 
+// DeriveShardNetworkIDWithTime derives the network ID of one of shards DHT
+// shards for the hour containing t. With fewer than two shards there is a
+// single network ID, the one DeriveNetworkIDWithTime returns.
+func DeriveShardNetworkIDWithTime(secret string, shard, shards int, t time.Time) ([20]byte, error) {
+	if shards < 2 {
+		return DeriveNetworkIDWithTime(secret, t)
+	}
+	if shard < 0 || shard >= shards {
+		return [20]byte{}, fmt.Errorf("shard %d out of range for %d shards", shard, shards)
+	}
+	var networkID [20]byte
+	input := fmt.Sprintf("%s||%d||shard-%d-of-%d", secret, t.Unix()/3600, shard, shards)
+	hash := sha256.Sum256([]byte(input))
+	copy(networkID[:], hash[:networkIDSize])
+	return networkID, nil
+}
+
+// GetCurrentAndPreviousShardNetworkIDs is GetCurrentAndPreviousNetworkIDs
+// for one DHT shard.
+func GetCurrentAndPreviousShardNetworkIDs(secret string, shard, shards int) (current, previous [20]byte, err error) {
+	now := meshNow().UTC()
+	current, err = DeriveShardNetworkIDWithTime(secret, shard, shards, now)
+	if err != nil {
+		return current, previous, err
+	}
+	previous, err = DeriveShardNetworkIDWithTime(secret, shard, shards, now.Add(-time.Hour))
+	return current, previous, err
+}
+
+// ShardForKey returns the DHT shard a node announces to, spreading the
+// mesh's nodes evenly and stably over shards.
+func ShardForKey(secret, wgPubKey string, shards int) int {
+	if shards < 2 {
+		return 0
+	}
+	hash := sha256.Sum256([]byte("wgmesh-dht-shard|" + secret + "|" + wgPubKey))
+	return int(binary.BigEndian.Uint64(hash[:8]) % uint64(shards))
+}
+
 // DeriveMeshIP derives a deterministic mesh IP from WG public key and secret.
 // Format: 10.<meshSubnet[0]>.<meshSubnet[1] XOR high>.<low>
 // Both subnet bytes are used. The last octet is clamped to [1,254] to avoid
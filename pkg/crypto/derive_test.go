@@ -75,6 +75,66 @@ func TestDeriveKeysDifferentSecrets(t *testing.T) {
 	}
 }
 
+func TestDerivePairPSK(t *testing.T) {
+	secret := "test-secret-that-is-long-enough"
+	keys, _ := DeriveKeys(secret)
+
+	ab, err := DerivePairPSK(secret, "pubkey-a", "pubkey-b")
+	if err != nil {
+		t.Fatalf("DerivePairPSK failed: %v", err)
+	}
+	ba, _ := DerivePairPSK(secret, "pubkey-b", "pubkey-a")
+	if ab != ba {
+		t.Error("Pair PSK depends on the order of the public keys")
+	}
+
+	ac, _ := DerivePairPSK(secret, "pubkey-a", "pubkey-c")
+	if ab == ac {
+		t.Error("Different pairs produced the same PSK")
+	}
+	if ab == keys.PSK {
+		t.Error("Pair PSK equals the mesh-wide PSK")
+	}
+	other, _ := DerivePairPSK("another-secret-long-enough", "pubkey-a", "pubkey-b")
+	if ab == other {
+		t.Error("Different secrets produced the same pair PSK")
+	}
+}
+
+func TestDeriveHopPort(t *testing.T) {
+	secret := "test-secret-that-is-long-enough"
+	start := time.Unix(1_700_000_400, 0) // a multiple of 10 minutes
+	interval := 10 * time.Minute
+
+	p1, err := DeriveHopPort(secret, "pubkey-a", interval, start)
+	if err != nil {
+		t.Fatalf("DeriveHopPort failed: %v", err)
+	}
+	if p1 < HopPortBase || p1 >= HopPortBase+HopPortRange {
+		t.Errorf("port %d outside [%d, %d)", p1, HopPortBase, HopPortBase+HopPortRange)
+	}
+	if same, _ := DeriveHopPort(secret, "pubkey-a", interval, start.Add(interval-time.Second)); same != p1 {
+		t.Error("port changed within one period")
+	}
+
+	// Over a few periods the port must move, and differ between nodes.
+	moved, differs := false, false
+	for i := 1; i <= 5; i++ {
+		at := start.Add(time.Duration(i) * interval)
+		p, _ := DeriveHopPort(secret, "pubkey-a", interval, at)
+		q, _ := DeriveHopPort(secret, "pubkey-b", interval, at)
+		moved = moved || p != p1
+		differs = differs || p != q
+	}
+	if !moved || !differs {
+		t.Errorf("ports do not hop (moved %v) or do not depend on the key (differs %v)", moved, differs)
+	}
+
+	if _, err := DeriveHopPort(secret, "pubkey-a", time.Millisecond, start); err == nil {
+		t.Error("sub-second interval should be rejected")
+	}
+}
+
 func TestDeriveNetworkIDWithTime(t *testing.T) {
 	secret := "test-secret-that-is-long-enough"
 
@@ -133,6 +193,53 @@ func TestGetCurrentAndPreviousNetworkIDs(t *testing.T) {
 	}
 }
 
+func TestDeriveShardNetworkIDWithTime(t *testing.T) {
+	secret := "test-secret-that-is-long-enough"
+	now := time.Now()
+
+	unsharded, _ := DeriveNetworkIDWithTime(secret, now)
+	if id, err := DeriveShardNetworkIDWithTime(secret, 0, 1, now); err != nil || id != unsharded {
+		t.Errorf("a single shard should use the unsharded network ID, got %x, %v", id, err)
+	}
+
+	seen := map[[20]byte]bool{unsharded: true}
+	for shard := 0; shard < 4; shard++ {
+		id, err := DeriveShardNetworkIDWithTime(secret, shard, 4, now)
+		if err != nil {
+			t.Fatalf("shard %d: %v", shard, err)
+		}
+		if seen[id] {
+			t.Errorf("shard %d repeats a network ID", shard)
+		}
+		seen[id] = true
+	}
+
+	if _, err := DeriveShardNetworkIDWithTime(secret, 4, 4, now); err == nil {
+		t.Error("expected an error for a shard out of range")
+	}
+}
+
+func TestShardForKey(t *testing.T) {
+	secret := "test-secret-that-is-long-enough"
+	counts := make([]int, 4)
+	for i := 0; i < 400; i++ {
+		key := fmt.Sprintf("pubkey-%d", i)
+		shard := ShardForKey(secret, key, 4)
+		if shard != ShardForKey(secret, key, 4) {
+			t.Fatalf("shard of %s is not stable", key)
+		}
+		counts[shard]++
+	}
+	for shard, n := range counts {
+		if n < 50 {
+			t.Errorf("shard %d got only %d of 400 keys", shard, n)
+		}
+	}
+	if got := ShardForKey(secret, "pubkey-0", 1); got != 0 {
+		t.Errorf("ShardForKey with one shard = %d, want 0", got)
+	}
+}
+
 func TestDeriveMeshIP(t *testing.T) {
 	meshSubnet := [2]byte{42, 0}
 	ip1 := DeriveMeshIP(meshSubnet, "pubkey1", "test-secret-that-is-long-enough")
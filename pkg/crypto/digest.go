@@ -0,0 +1,127 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Gossip anti-entropy messages. Instead of shipping the full KnownPeers list
+// every round, a node sends a DIGEST (count and hash of the peers it knows).
+// A receiver whose own summary differs answers with DIGEST_ENTRIES, one short
+// hash per peer, and the original sender then pushes the entries the
+// receiver lacks as an ordinary ANNOUNCE and asks for the ones it lacks
+// itself with DIGEST_WANT.
+const (
+	MessageTypeDigest        = "DIGEST"
+	MessageTypeDigestEntries = "DIGEST_ENTRIES"
+	MessageTypeDigestWant    = "DIGEST_WANT"
+)
+
+// MaxDigestEntries bounds the entries and wants in a digest message.
+const MaxDigestEntries = MaxKnownPeers
+
+// DigestKeyLength is the length of the pubkey prefix identifying a peer in a
+// digest; 12 base64 characters are 72 bits, plenty to tell peers apart.
+const DigestKeyLength = 12
+
+// digestHashLength is the hex length of an entry hash and of the set hash.
+const digestHashLength = 16
+
+// GossipDigest is the payload of all three digest message types. Announcement
+// carries the sender's own signed info without KnownPeers, so digests also
+// keep the sender itself fresh at the receiver.
+type GossipDigest struct {
+	Protocol     string            `json:"protocol"`
+	Timestamp    int64             `json:"timestamp"`
+	Announcement *PeerAnnouncement `json:"announcement"`
+	Count        int               `json:"count"`
+	Hash         string            `json:"hash"`
+	Entries      map[string]string `json:"entries,omitempty"` // DIGEST_ENTRIES: digest key -> entry hash
+	Want         []string          `json:"want,omitempty"`    // DIGEST_WANT: digest keys to send
+}
+
+// Validate checks the digest and its embedded announcement.
+func (d *GossipDigest) Validate() error {
+	if d.Announcement == nil {
+		return fmt.Errorf("missing announcement")
+	}
+	if err := d.Announcement.Validate(); err != nil {
+		return fmt.Errorf("announcement: %w", err)
+	}
+	if d.Count < 0 || d.Count > MaxDigestEntries {
+		return fmt.Errorf("count %d out of range", d.Count)
+	}
+	if len(d.Hash) > digestHashLength {
+		return fmt.Errorf("hash too long")
+	}
+	if len(d.Entries) > MaxDigestEntries {
+		return fmt.Errorf("too many entries (%d)", len(d.Entries))
+	}
+	for key, hash := range d.Entries {
+		if len(key) != DigestKeyLength || len(hash) != digestHashLength {
+			return fmt.Errorf("malformed entry %q", key)
+		}
+	}
+	if len(d.Want) > MaxDigestEntries {
+		return fmt.Errorf("too many wants (%d)", len(d.Want))
+	}
+	for _, key := range d.Want {
+		if len(key) != DigestKeyLength {
+			return fmt.Errorf("malformed want %q", key)
+		}
+	}
+	return nil
+}
+
+// ParseGossipDigest decodes and validates a digest payload returned by
+// OpenEnvelopeRaw.
+func ParseGossipDigest(plaintext []byte) (*GossipDigest, error) {
+	var digest GossipDigest
+	if err := json.Unmarshal(plaintext, &digest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal digest: %w", err)
+	}
+	if err := digest.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid digest: %w", err)
+	}
+	return &digest, nil
+}
+
+// DigestKey returns the key identifying a peer in digest entries and wants.
+func DigestKey(wgPubKey string) string {
+	if len(wgPubKey) < DigestKeyLength {
+		return wgPubKey
+	}
+	return wgPubKey[:DigestKeyLength]
+}
+
+// DigestEntryHash hashes the fields of a known peer that gossip propagates,
+// so two nodes holding the same view of a peer produce the same hash.
+func DigestEntryHash(kp KnownPeer) string {
+	data, _ := json.Marshal(kp)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:digestHashLength/2])
+}
+
+// SummarizeKnownPeers returns the digest entries for peers along with their
+// count and a hash over the whole set.
+func SummarizeKnownPeers(peers []KnownPeer) (entries map[string]string, count int, hash string) {
+	entries = make(map[string]string, len(peers))
+	for _, kp := range peers {
+		entries[DigestKey(kp.WGPubKey)] = DigestEntryHash(kp)
+	}
+
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, key := range keys {
+		h.Write([]byte(key))
+		h.Write([]byte(entries[key]))
+	}
+	return entries, len(entries), hex.EncodeToString(h.Sum(nil)[:digestHashLength/2])
+}
@@ -0,0 +1,57 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummarizeKnownPeers(t *testing.T) {
+	peers := []KnownPeer{
+		{WGPubKey: strings.Repeat("A", 43) + "=", MeshIP: "10.0.0.2", WGEndpoint: "198.51.100.2:51820"},
+		{WGPubKey: strings.Repeat("B", 43) + "=", MeshIP: "10.0.0.3", WGEndpoint: "198.51.100.3:51820"},
+	}
+	entries, count, hash := SummarizeKnownPeers(peers)
+	if count != 2 || len(entries) != 2 {
+		t.Fatalf("count = %d, entries = %d, want 2", count, len(entries))
+	}
+
+	_, _, reversed := SummarizeKnownPeers([]KnownPeer{peers[1], peers[0]})
+	if reversed != hash {
+		t.Error("hash depends on peer order")
+	}
+
+	peers[1].WGEndpoint = "198.51.100.3:51821"
+	changed, _, changedHash := SummarizeKnownPeers(peers)
+	if changedHash == hash {
+		t.Error("hash did not change with an endpoint")
+	}
+	if changed[DigestKey(peers[0].WGPubKey)] != entries[DigestKey(peers[0].WGPubKey)] {
+		t.Error("unchanged entry hash changed")
+	}
+}
+
+func TestParseGossipDigest(t *testing.T) {
+	valid := `{"protocol":"wgmesh-v1","timestamp":1,"announcement":{"protocol":"wgmesh-v1","wg_pubkey":"` + strings.Repeat("A", 43) + `=","mesh_ip":"10.0.0.1","wg_endpoint":""},"count":1,"hash":"0123456789abcdef"`
+	tests := []struct {
+		name    string
+		payload string
+		wantErr bool
+	}{
+		{name: "summary", payload: valid + `}`},
+		{name: "entries", payload: valid + `,"entries":{"AAAAAAAAAAAA":"0123456789abcdef"}}`},
+		{name: "want", payload: valid + `,"want":["AAAAAAAAAAAA"]}`},
+		{name: "no announcement", payload: `{"protocol":"wgmesh-v1","timestamp":1,"count":0}`, wantErr: true},
+		{name: "short entry key", payload: valid + `,"entries":{"AAAA":"0123456789abcdef"}}`, wantErr: true},
+		{name: "bad entry hash", payload: valid + `,"entries":{"AAAAAAAAAAAA":"zz"}}`, wantErr: true},
+		{name: "negative count", payload: strings.Replace(valid, `"count":1`, `"count":-1`, 1) + `}`, wantErr: true},
+		{name: "malformed want", payload: valid + `,"want":["A"]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseGossipDigest([]byte(tt.payload))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
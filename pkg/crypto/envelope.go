@@ -6,11 +6,15 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"strconv"
+	"strings"
 	"time"
+
+	"golang.org/x/net/publicsuffix"
 )
 
 const (
@@ -23,6 +27,10 @@ const (
 	MessageTypeGoodbye         = "GOODBYE"
 	MessageTypeRendezvousOffer = "RENDEZVOUS_OFFER"
 	MessageTypeRendezvousStart = "RENDEZVOUS_START"
+	MessageTypeRotate          = "ROTATE"
+	MessageTypeBroadcast       = "BROADCAST"
+	MessageTypeApproval        = "APPROVAL"
+	MessageTypeDandelionStem   = "DANDELION_STEM"
 )
 
 var now = time.Now
@@ -36,6 +44,39 @@ const MaxRoutableNetworks = 100
 // MaxKnownPeers is the maximum number of transitive peers in a single announcement
 const MaxKnownPeers = 1000
 
+// MaxEndpointCandidates is the maximum number of alternative WireGuard
+// endpoints a peer can advertise
+const MaxEndpointCandidates = 8
+
+// MaxShortFieldLength bounds free-form short fields such as NATType
+const MaxShortFieldLength = 32
+
+// MaxRelays is the maximum number of relays a peer can list as in use
+const MaxRelays = 16
+
+// MaxTags is the maximum number of tags a peer can announce, and
+// MaxTagLength bounds each tag key and value.
+const (
+	MaxTags      = 32
+	MaxTagLength = 63
+)
+
+// MaxDNSDomains is the maximum number of split-DNS domains a peer can
+// advertise
+const MaxDNSDomains = 16
+
+// MaxRouteChecks is the maximum number of route health checks a peer can
+// announce
+const MaxRouteChecks = 32
+
+// MinPortHop and MaxPortHop bound a non-zero PortHop, in seconds. The
+// upper bound keeps the interval well clear of overflowing a
+// time.Duration.
+const (
+	MinPortHop = 60
+	MaxPortHop = 7 * 24 * 60 * 60
+)
+
 // PeerAnnouncement is the encrypted message format for peer discovery
 type PeerAnnouncement struct {
 	Protocol         string      `json:"protocol"`
@@ -57,6 +98,174 @@ type PeerAnnouncement struct {
 	// NATType is the sender's detected NAT behavior: "cone", "symmetric",
 	// or "unknown". Peers use this to decide whether relay is needed.
 	NATType string `json:"nat_type,omitempty"`
+
+	// EndpointCandidates lists other WireGuard endpoints the sender can be
+	// reached at (LAN addresses, IPv6), in addition to WGEndpoint. Receivers
+	// probe them and keep whichever path performs best.
+	EndpointCandidates []string `json:"endpoint_candidates,omitempty"`
+
+	// Identity is the sender's Ed25519 public key, Signature its signature
+	// over every field it wrote and LegacySignature its signature over the
+	// fields older receivers check (see Sign). All are empty from nodes that
+	// predate identity signing, and Signature from nodes that predate it.
+	Identity        string `json:"identity,omitempty"`
+	Signature       string `json:"sig,omitempty"`
+	LegacySignature string `json:"signature,omitempty"`
+
+	// Version is the sender's wgmesh version. It is informational.
+	Version string `json:"version,omitempty"`
+
+	// Revision and MinRevision are the sender's ProtocolRevision and
+	// MinProtocolRevision, and Capabilities the optional features it
+	// implements; all zero from nodes that predate them.
+	Revision     int          `json:"proto_rev,omitempty"`
+	MinRevision  int          `json:"proto_min,omitempty"`
+	Capabilities Capabilities `json:"caps,omitempty"`
+
+	// Load is set by introducers and Relays lists the introducers the
+	// sender currently relays traffic through, from which introducers
+	// count their relayed peers.
+	Load   *IntroducerLoad `json:"load,omitempty"`
+	Relays []string        `json:"relays,omitempty"`
+
+	// IntroducerCandidate is set by nodes that qualify for introducer
+	// election and IntroducerElected by those whose Introducer role comes
+	// from it rather than from configuration.
+	IntroducerCandidate bool `json:"introducer_candidate,omitempty"`
+	IntroducerElected   bool `json:"introducer_elected,omitempty"`
+
+	// PairPSK tells receivers that the sender uses a preshared key derived
+	// for each pair of nodes (see DerivePairPSK) with peers that announce
+	// it too, and the mesh-wide PSK with the rest. Both ends of a tunnel
+	// must agree. Relays pass the flag on in KnownPeers, and a node that
+	// completes no handshake with the key the flags call for tries the
+	// other one.
+	PairPSK bool `json:"pair_psk,omitempty"`
+
+	// Tags are the sender's operator-assigned key=value labels, such as
+	// role=db.
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// PortHop is the sender's port hopping interval in seconds, 0 when its
+	// WireGuard port is fixed. Receivers then derive its current port with
+	// DeriveHopPort instead of using the one in WGEndpoint. Validate bounds
+	// it to MinPortHop..MaxPortHop.
+	PortHop int64 `json:"port_hop,omitempty"`
+
+	// TCPPort is the TCP port on which an introducer accepts WireGuard
+	// wrapped in TLS from nodes whose UDP is blocked; 0 when it does not.
+	TCPPort int `json:"tcp_port,omitempty"`
+
+	// TCPVia is the introducer the sender reaches over the TCP transport,
+	// and so the only relay that can reach it; empty on plain UDP.
+	TCPVia string `json:"tcp_via,omitempty"`
+
+	// Approval is the sender's admission to a mesh running in approval
+	// mode. It carries its own signature, by the approver.
+	Approval *AdmissionApproval `json:"approval,omitempty"`
+
+	// DNS lists domains, such as home.lan, whose names the sender's
+	// resolvers answer. Receivers only use a resolver that lies in a
+	// network they route through the sender.
+	DNS []DNSDomain `json:"dns,omitempty"`
+
+	// RouteChecks report the health of a target behind some of the
+	// sender's RoutableNetworks, so receivers can stop routing a network
+	// whose LAN is down while the sender itself is up. A forged failure
+	// would take the route away from every peer, so the results are
+	// signed like the networks they qualify.
+	RouteChecks []RouteCheck `json:"route_checks,omitempty"`
+
+	// raw is the JSON a received announcement was decoded from, which
+	// Signature covers; nil for announcements built locally.
+	raw []byte
+}
+
+// plainAnnouncement has PeerAnnouncement's fields without its JSON methods.
+type plainAnnouncement PeerAnnouncement
+
+// UnmarshalJSON decodes an announcement and keeps the bytes, so that
+// VerifySignature checks what the sender signed, including fields this
+// build does not know.
+func (pa *PeerAnnouncement) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, (*plainAnnouncement)(pa)); err != nil {
+		return err
+	}
+	pa.raw = append([]byte(nil), data...)
+	return nil
+}
+
+// MarshalJSON encodes a received announcement as the bytes it arrived as,
+// so that relays pass on a signature that still verifies. Announcements
+// built locally are encoded from their fields.
+func (pa *PeerAnnouncement) MarshalJSON() ([]byte, error) {
+	if pa.raw != nil {
+		return pa.raw, nil
+	}
+	return json.Marshal((*plainAnnouncement)(pa))
+}
+
+// DNSDomain is a domain a gateway advertises together with the resolver
+// that answers for it, e.g. home.lan via 192.168.1.53.
+type DNSDomain struct {
+	Domain   string `json:"domain"`
+	Resolver string `json:"resolver"`
+}
+
+// Validate checks that Domain is a DNS name and Resolver an IP address.
+// Domain must have at least two labels and not be a public suffix such as
+// com or co.uk, so a gateway can only claim names below one.
+func (dd DNSDomain) Validate() error {
+	if err := validateDNSName(dd.Domain); err != nil {
+		return fmt.Errorf("domain %q: %w", dd.Domain, err)
+	}
+	if !strings.Contains(dd.Domain, ".") {
+		return fmt.Errorf("domain %q: single-label domains are not allowed", dd.Domain)
+	}
+	if suffix, _ := publicsuffix.PublicSuffix(strings.ToLower(dd.Domain)); suffix == strings.ToLower(dd.Domain) {
+		return fmt.Errorf("domain %q is a public suffix", dd.Domain)
+	}
+	if net.ParseIP(dd.Resolver) == nil {
+		return fmt.Errorf("domain %s: invalid resolver address %q", dd.Domain, dd.Resolver)
+	}
+	return nil
+}
+
+// RouteCheck is the latest result of a gateway's health check for one of
+// the networks it advertises, e.g. 192.168.1.0/24 via a TCP connect to
+// 192.168.1.10:443.
+type RouteCheck struct {
+	Network string `json:"network"`
+	Target  string `json:"target"`
+	Healthy bool   `json:"healthy"`
+}
+
+// Validate checks that Network is a CIDR and Target an IP:port inside it.
+func (rc RouteCheck) Validate() error {
+	_, ipNet, err := net.ParseCIDR(rc.Network)
+	if err != nil {
+		return fmt.Errorf("invalid network %q: %w", rc.Network, err)
+	}
+	if err := validateEndpoint(rc.Target); err != nil {
+		return fmt.Errorf("network %s: target %q: %w", rc.Network, rc.Target, err)
+	}
+	host, _, _ := net.SplitHostPort(rc.Target)
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("network %s: target %q is not an IP address", rc.Network, rc.Target)
+	}
+	if !ipNet.Contains(ip) {
+		return fmt.Errorf("network %s: target %s is outside the network", rc.Network, rc.Target)
+	}
+	return nil
+}
+
+// IntroducerLoad is the load an introducer reports. Nodes pass over
+// overloaded introducers when picking rendezvous coordinators and relays.
+type IntroducerLoad struct {
+	Sessions     int     `json:"sessions"`      // rendezvous sessions in progress
+	RelayedPeers int     `json:"relayed_peers"` // peers relaying traffic through it
+	Bps          float64 `json:"bps"`           // WireGuard throughput, rx+tx bytes per second
 }
 
 // KnownPeer represents a peer that this node knows about (for transitive discovery)
@@ -68,6 +277,7 @@ type KnownPeer struct {
 	WGEndpoint string `json:"wg_endpoint"`
 	Introducer bool   `json:"introducer,omitempty"`
 	NATType    string `json:"nat_type,omitempty"`
+	PairPSK    bool   `json:"pair_psk,omitempty"` // the relay's copy of the peer's own PairPSK flag
 }
 
 // Validate checks all fields of a KnownPeer for correctness.
@@ -88,6 +298,14 @@ func (kp *KnownPeer) Validate() error {
 			return fmt.Errorf("Hostname: %w", err)
 		}
 	}
+	if kp.MeshIPv6 != "" {
+		if err := validateMeshIP(kp.MeshIPv6); err != nil {
+			return fmt.Errorf("MeshIPv6: %w", err)
+		}
+	}
+	if len(kp.NATType) > MaxShortFieldLength {
+		return fmt.Errorf("NATType: too long (%d characters)", len(kp.NATType))
+	}
 	return nil
 }
 
@@ -110,6 +328,27 @@ func (pa *PeerAnnouncement) Validate() error {
 			return fmt.Errorf("Hostname: %w", err)
 		}
 	}
+	if pa.MeshIPv6 != "" {
+		if err := validateMeshIP(pa.MeshIPv6); err != nil {
+			return fmt.Errorf("MeshIPv6: %w", err)
+		}
+	}
+	if pa.ObservedEndpoint != "" {
+		if err := validateEndpoint(pa.ObservedEndpoint); err != nil {
+			return fmt.Errorf("ObservedEndpoint: %w", err)
+		}
+	}
+	if len(pa.NATType) > MaxShortFieldLength {
+		return fmt.Errorf("NATType: too long (%d characters)", len(pa.NATType))
+	}
+	if pa.Revision < 0 || pa.MinRevision < 0 || pa.MinRevision > pa.Revision {
+		return fmt.Errorf("Revision: invalid range %d-%d", pa.MinRevision, pa.Revision)
+	}
+	// Identity and Signature are checked for content by VerifySignature;
+	// bound them here so a bogus value cannot be arbitrarily large.
+	if len(pa.Identity) > 64 || len(pa.Signature) > 128 || len(pa.LegacySignature) > 128 {
+		return fmt.Errorf("Identity/Signature: too long")
+	}
 	if len(pa.RoutableNetworks) > MaxRoutableNetworks {
 		return fmt.Errorf("RoutableNetworks: too many entries (%d, max %d)", len(pa.RoutableNetworks), MaxRoutableNetworks)
 	}
@@ -118,6 +357,47 @@ func (pa *PeerAnnouncement) Validate() error {
 			return fmt.Errorf("RoutableNetworks[%d]: invalid CIDR %q: %w", i, cidr, err)
 		}
 	}
+	if len(pa.EndpointCandidates) > MaxEndpointCandidates {
+		return fmt.Errorf("EndpointCandidates: too many entries (%d, max %d)", len(pa.EndpointCandidates), MaxEndpointCandidates)
+	}
+	for i, ep := range pa.EndpointCandidates {
+		if err := validateEndpoint(ep); err != nil {
+			return fmt.Errorf("EndpointCandidates[%d]: %w", i, err)
+		}
+	}
+	if pa.Load != nil && (pa.Load.Sessions < 0 || pa.Load.RelayedPeers < 0 || pa.Load.Bps < 0) {
+		return fmt.Errorf("Load: negative value")
+	}
+	if pa.PortHop != 0 && (pa.PortHop < MinPortHop || pa.PortHop > MaxPortHop) {
+		return fmt.Errorf("PortHop: %d seconds out of range %d-%d", pa.PortHop, MinPortHop, MaxPortHop)
+	}
+	if len(pa.Relays) > MaxRelays {
+		return fmt.Errorf("Relays: too many entries (%d, max %d)", len(pa.Relays), MaxRelays)
+	}
+	for i, key := range pa.Relays {
+		if err := validateWGPubKey(key); err != nil {
+			return fmt.Errorf("Relays[%d]: %w", i, err)
+		}
+	}
+	if err := ValidateTags(pa.Tags); err != nil {
+		return fmt.Errorf("Tags: %w", err)
+	}
+	if len(pa.DNS) > MaxDNSDomains {
+		return fmt.Errorf("DNS: too many entries (%d, max %d)", len(pa.DNS), MaxDNSDomains)
+	}
+	for i, dd := range pa.DNS {
+		if err := dd.Validate(); err != nil {
+			return fmt.Errorf("DNS[%d]: %w", i, err)
+		}
+	}
+	if len(pa.RouteChecks) > MaxRouteChecks {
+		return fmt.Errorf("RouteChecks: too many entries (%d, max %d)", len(pa.RouteChecks), MaxRouteChecks)
+	}
+	for i, rc := range pa.RouteChecks {
+		if err := rc.Validate(); err != nil {
+			return fmt.Errorf("RouteChecks[%d]: %w", i, err)
+		}
+	}
 	if len(pa.KnownPeers) > MaxKnownPeers {
 		return fmt.Errorf("KnownPeers: too many entries (%d, max %d)", len(pa.KnownPeers), MaxKnownPeers)
 	}
@@ -129,6 +409,48 @@ func (pa *PeerAnnouncement) Validate() error {
 	return nil
 }
 
+// ValidateTags checks that there are at most MaxTags tags, that every key
+// is non-empty, and that keys and values are at most MaxTagLength of
+// letters, digits and ".-_/:".
+func ValidateTags(tags map[string]string) error {
+	if len(tags) > MaxTags {
+		return fmt.Errorf("too many tags (%d, max %d)", len(tags), MaxTags)
+	}
+	for key, value := range tags {
+		if key == "" {
+			return fmt.Errorf("empty tag key")
+		}
+		if err := validateTagString(key); err != nil {
+			return fmt.Errorf("tag key %q: %w", key, err)
+		}
+		if err := validateTagString(value); err != nil {
+			return fmt.Errorf("tag %q value %q: %w", key, value, err)
+		}
+	}
+	return nil
+}
+
+func validateTagString(s string) error {
+	if len(s) > MaxTagLength {
+		return fmt.Errorf("too long (%d characters, max %d)", len(s), MaxTagLength)
+	}
+	for i, b := range []byte(s) {
+		switch {
+		case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		case b == '.', b == '-', b == '_', b == '/', b == ':':
+		default:
+			return fmt.Errorf("invalid character at position %d (byte 0x%02x)", i, b)
+		}
+	}
+	return nil
+}
+
+// ValidateWGPubKey checks that key is a base64 WireGuard public key, for
+// wire messages other than announcements.
+func ValidateWGPubKey(key string) error {
+	return validateWGPubKey(key)
+}
+
 // validateWGPubKey checks that the key is valid base64 encoding of exactly 32 bytes.
 func validateWGPubKey(key string) error {
 	if key == "" {
@@ -185,11 +507,34 @@ func validateHostname(hostname string) error {
 	return nil
 }
 
+// validateDNSName accepts names such as home.lan: dot-separated labels of
+// letters, digits, '-' and '_', without a trailing dot.
+func validateDNSName(name string) error {
+	if name == "" || len(name) > MaxHostnameLength {
+		return fmt.Errorf("invalid length %d", len(name))
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return fmt.Errorf("invalid label %q", label)
+		}
+		for _, b := range []byte(label) {
+			switch {
+			case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9', b == '-', b == '_':
+			default:
+				return fmt.Errorf("invalid character %q", b)
+			}
+		}
+	}
+	return nil
+}
+
 // Envelope wraps encrypted messages with nonce for transmission
 type Envelope struct {
 	MessageType string `json:"type"`
 	Nonce       []byte `json:"nonce"`
 	Ciphertext  []byte `json:"ciphertext"`
+
+	SentAt time.Time `json:"-"` // payload timestamp, set by the Open functions
 }
 
 // SealEnvelope encrypts a message using AES-256-GCM with the gossip key
@@ -239,22 +584,51 @@ func OpenEnvelope(data []byte, gossipKey [32]byte) (*Envelope, *PeerAnnouncement
 		return nil, nil, err
 	}
 
-	// Parse announcement
+	announcement, err := ParseAnnouncement(plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return envelope, announcement, nil
+}
+
+// ParseAnnouncement decodes and validates an announcement payload returned
+// by OpenEnvelopeRaw.
+func ParseAnnouncement(plaintext []byte) (*PeerAnnouncement, error) {
 	var announcement PeerAnnouncement
 	if err := json.Unmarshal(plaintext, &announcement); err != nil {
-		return nil, nil, fmt.Errorf("failed to unmarshal announcement: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal announcement: %w", err)
 	}
 
 	// Validate announcement fields
 	if err := announcement.Validate(); err != nil {
-		return nil, nil, fmt.Errorf("invalid announcement: %w", err)
+		return nil, fmt.Errorf("invalid announcement: %w", err)
 	}
 
-	return envelope, &announcement, nil
+	return &announcement, nil
 }
 
 // OpenEnvelopeRaw decrypts a message and returns raw plaintext payload.
 func OpenEnvelopeRaw(data []byte, gossipKey [32]byte) (*Envelope, []byte, error) {
+	return OpenEnvelopeRawWithKeys(data, [][32]byte{gossipKey})
+}
+
+// OpenEnvelopeWithKeys is OpenEnvelope trying each of keys in turn.
+func OpenEnvelopeWithKeys(data []byte, keys [][32]byte) (*Envelope, *PeerAnnouncement, error) {
+	envelope, plaintext, err := OpenEnvelopeRawWithKeys(data, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	announcement, err := ParseAnnouncement(plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return envelope, announcement, nil
+}
+
+// OpenEnvelopeRawWithKeys is OpenEnvelopeRaw trying each of keys in turn,
+// for receivers that accept more than one envelope key (see GossipEpochKey).
+func OpenEnvelopeRawWithKeys(data []byte, keys [][32]byte) (*Envelope, []byte, error) {
 	// Parse envelope
 	var envelope Envelope
 	if err := json.Unmarshal(data, &envelope); err != nil {
@@ -265,20 +639,26 @@ func OpenEnvelopeRaw(data []byte, gossipKey [32]byte) (*Envelope, []byte, error)
 		return nil, nil, fmt.Errorf("invalid nonce size: %d", len(envelope.Nonce))
 	}
 
-	// Create AES cipher
-	block, err := aes.NewCipher(gossipKey[:])
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create cipher: %w", err)
-	}
+	var plaintext []byte
+	err := errors.New("no keys")
+	for _, key := range keys {
+		// Create AES cipher
+		block, cerr := aes.NewCipher(key[:])
+		if cerr != nil {
+			return nil, nil, fmt.Errorf("failed to create cipher: %w", cerr)
+		}
 
-	// Create GCM mode
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create GCM: %w", err)
-	}
+		// Create GCM mode
+		gcm, cerr := cipher.NewGCM(block)
+		if cerr != nil {
+			return nil, nil, fmt.Errorf("failed to create GCM: %w", cerr)
+		}
 
-	// Decrypt
-	plaintext, err := gcm.Open(nil, envelope.Nonce, envelope.Ciphertext, nil)
+		// Decrypt
+		if plaintext, err = gcm.Open(nil, envelope.Nonce, envelope.Ciphertext, nil); err == nil {
+			break
+		}
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("decryption failed (wrong key?): %w", err)
 	}
@@ -300,12 +680,11 @@ func OpenEnvelopeRaw(data []byte, gossipKey [32]byte) (*Envelope, []byte, error)
 	// Check timestamp to prevent replay attacks
 	currentTime := now()
 	msgTime := time.Unix(meta.Timestamp, 0)
-	if currentTime.Sub(msgTime) > MaxMessageAge {
-		return nil, nil, fmt.Errorf("message too old: %v", currentTime.Sub(msgTime))
-	}
-	if msgTime.After(currentTime.Add(MaxMessageAge)) {
-		return nil, nil, fmt.Errorf("message timestamp in future")
+	window := MaxMessageAge + ClockSkewTolerance()
+	if offset := currentTime.Sub(msgTime); offset > window || offset < -window {
+		return nil, nil, &TimestampError{SentAt: msgTime, Offset: offset}
 	}
+	envelope.SentAt = msgTime
 
 	return &envelope, plaintext, nil
 }
@@ -324,5 +703,9 @@ func CreateAnnouncement(wgPubKey, meshIP, wgEndpoint string, introducer bool, ro
 		Timestamp:        time.Now().Unix(),
 		KnownPeers:       knownPeers,
 		NATType:          natType,
+		PairPSK:          true,
+		Revision:         ProtocolRevision,
+		MinRevision:      MinProtocolRevision,
+		Capabilities:     LocalCapabilities,
 	}
 }
@@ -0,0 +1,95 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+const fuzzSecret = "wgmesh-fuzz-secret-long-enough-for-derivation"
+
+func fuzzAnnouncementSeed(t testing.TB) []byte {
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	pa := CreateAnnouncement(key, "10.0.0.1", "1.2.3.4:51820", true, []string{"192.168.1.0/24"},
+		[]KnownPeer{{WGPubKey: key, MeshIP: "10.0.0.2", WGEndpoint: "5.6.7.8:51820"}}, "node", "fd00::1", "cone")
+	pa.EndpointCandidates = []string{"192.168.1.10:51820"}
+	data, err := json.Marshal(pa)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// FuzzOpenEnvelope feeds arbitrary datagrams to the envelope decoder, as
+// anyone on the network can.
+func FuzzOpenEnvelope(f *testing.F) {
+	keys, err := DeriveKeys(fuzzSecret)
+	if err != nil {
+		f.Fatal(err)
+	}
+	valid, err := SealEnvelope(MessageTypeHello, json.RawMessage(fuzzAnnouncementSeed(f)), keys.GossipKey)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(valid)
+	f.Add([]byte(`{}`))
+	f.Add([]byte(`{"type":"HELLO","nonce":"AAAAAAAAAAAAAAAA","ciphertext":"AAAA"}`))
+	f.Add([]byte(`{"type":"HELLO","nonce":null,"ciphertext":[]}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, announcement, err := OpenEnvelope(data, keys.GossipKey)
+		if err == nil && announcement == nil {
+			t.Fatal("nil announcement without error")
+		}
+	})
+}
+
+// FuzzParseAnnouncement feeds arbitrary decrypted payloads to the
+// announcement parser, as a peer holding the secret can.
+func FuzzParseAnnouncement(f *testing.F) {
+	f.Add(fuzzAnnouncementSeed(f))
+	f.Add([]byte(`{"protocol":"wgmesh-v1","wg_pubkey":"","mesh_ip":"x"}`))
+	f.Add([]byte(`{"known_peers":[{},{},{}],"routable_networks":["::/0","1.2.3.4/99"]}`))
+	f.Add([]byte(`{"identity":"AAAA","signature":"AAAA"}`))
+	f.Add([]byte(`null`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pa, err := ParseAnnouncement(data)
+		if err != nil {
+			return
+		}
+		if len(pa.KnownPeers) > MaxKnownPeers || len(pa.RoutableNetworks) > MaxRoutableNetworks ||
+			len(pa.EndpointCandidates) > MaxEndpointCandidates {
+			t.Fatalf("limits not enforced: %d known peers, %d routes, %d candidates",
+				len(pa.KnownPeers), len(pa.RoutableNetworks), len(pa.EndpointCandidates))
+		}
+		_ = pa.VerifySignature()
+	})
+}
+
+// FuzzOpenEnvelopeRawMetadata seals arbitrary JSON with the right key so the
+// fuzzer reaches the protocol and timestamp checks behind decryption.
+func FuzzOpenEnvelopeRawMetadata(f *testing.F) {
+	keys, err := DeriveKeys(fuzzSecret)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(fuzzAnnouncementSeed(f))
+	f.Add([]byte(`{"protocol":"wgmesh-v1","timestamp":9223372036854775807}`))
+	f.Add([]byte(`{"protocol":"wgmesh-v1","timestamp":-9223372036854775808}`))
+	f.Add([]byte(`[]`))
+
+	f.Fuzz(func(t *testing.T, payload []byte) {
+		if !json.Valid(payload) {
+			return
+		}
+		data, err := SealEnvelope(MessageTypeAnnounce, json.RawMessage(payload), keys.GossipKey)
+		if err != nil {
+			return
+		}
+		now = func() time.Time { return time.Unix(1700000000, 0) }
+		defer func() { now = time.Now }()
+		_, _, _ = OpenEnvelopeRaw(data, keys.GossipKey)
+	})
+}
@@ -52,6 +52,18 @@ func TestPeerAnnouncementValidate(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "valid with protocol revisions",
+			modify: func(pa *PeerAnnouncement) {
+				pa.Revision, pa.MinRevision, pa.Capabilities = 3, 2, LocalCapabilities
+			},
+		},
+		{
+			name:        "minimum revision above revision",
+			modify:      func(pa *PeerAnnouncement) { pa.Revision, pa.MinRevision = 1, 2 },
+			wantErr:     true,
+			errContains: "Revision",
+		},
 		// WGPubKey validation
 		{
 			name:        "empty WGPubKey",
@@ -243,6 +255,177 @@ func TestPeerAnnouncementValidate(t *testing.T) {
 			wantErr:     true,
 			errContains: "KnownPeers",
 		},
+		// EndpointCandidates validation
+		{
+			name: "valid endpoint candidates",
+			modify: func(pa *PeerAnnouncement) {
+				pa.EndpointCandidates = []string{"192.168.1.10:51820", "[2001:db8::1]:51820"}
+			},
+		},
+		{
+			name: "endpoint candidate without port",
+			modify: func(pa *PeerAnnouncement) {
+				pa.EndpointCandidates = []string{"192.168.1.10"}
+			},
+			wantErr:     true,
+			errContains: "EndpointCandidates[0]",
+		},
+		{
+			name: "too many endpoint candidates",
+			modify: func(pa *PeerAnnouncement) {
+				pa.EndpointCandidates = make([]string, MaxEndpointCandidates+1)
+				for i := range pa.EndpointCandidates {
+					pa.EndpointCandidates[i] = "192.168.1.10:51820"
+				}
+			},
+			wantErr:     true,
+			errContains: "EndpointCandidates",
+		},
+		// Load and Relays validation
+		{
+			name: "introducer load and relays",
+			modify: func(pa *PeerAnnouncement) {
+				pa.Load = &IntroducerLoad{Sessions: 2, RelayedPeers: 5, Bps: 1e6}
+				pa.Relays = []string{validKey}
+			},
+		},
+		{
+			name: "negative load",
+			modify: func(pa *PeerAnnouncement) {
+				pa.Load = &IntroducerLoad{Sessions: -1}
+			},
+			wantErr:     true,
+			errContains: "Load",
+		},
+		// PortHop validation
+		{
+			name:   "port hop",
+			modify: func(pa *PeerAnnouncement) { pa.PortHop = 3600 },
+		},
+		{
+			name:        "negative port hop",
+			modify:      func(pa *PeerAnnouncement) { pa.PortHop = -1 },
+			wantErr:     true,
+			errContains: "PortHop",
+		},
+		{
+			name:        "port hop below minimum",
+			modify:      func(pa *PeerAnnouncement) { pa.PortHop = MinPortHop - 1 },
+			wantErr:     true,
+			errContains: "PortHop",
+		},
+		{
+			name:        "port hop overflowing a duration",
+			modify:      func(pa *PeerAnnouncement) { pa.PortHop = 1 << 40 },
+			wantErr:     true,
+			errContains: "PortHop",
+		},
+		{
+			name: "invalid relay key",
+			modify: func(pa *PeerAnnouncement) {
+				pa.Relays = []string{"not-a-key"}
+			},
+			wantErr:     true,
+			errContains: "Relays[0]",
+		},
+		// Tags validation
+		{
+			name: "tags",
+			modify: func(pa *PeerAnnouncement) {
+				pa.Tags = map[string]string{"role": "db", "zone": "eu-west-1a", "owner": ""}
+			},
+		},
+		{
+			name: "tag with a separator in the value",
+			modify: func(pa *PeerAnnouncement) {
+				pa.Tags = map[string]string{"role": "db,web"}
+			},
+			wantErr:     true,
+			errContains: "Tags",
+		},
+		{
+			name: "empty tag key",
+			modify: func(pa *PeerAnnouncement) {
+				pa.Tags = map[string]string{"": "db"}
+			},
+			wantErr:     true,
+			errContains: "Tags",
+		},
+		{
+			name: "too many tags",
+			modify: func(pa *PeerAnnouncement) {
+				pa.Tags = make(map[string]string)
+				for i := 0; i <= MaxTags; i++ {
+					pa.Tags[fmt.Sprintf("k%d", i)] = "v"
+				}
+			},
+			wantErr:     true,
+			errContains: "too many tags",
+		},
+		// DNS validation
+		{
+			name: "valid DNS domains",
+			modify: func(pa *PeerAnnouncement) {
+				pa.DNS = []DNSDomain{{Domain: "home.lan", Resolver: "192.168.1.53"}, {Domain: "corp.example.com", Resolver: "fd00::53"}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "DNS domain with invalid label",
+			modify: func(pa *PeerAnnouncement) {
+				pa.DNS = []DNSDomain{{Domain: "home..lan", Resolver: "192.168.1.53"}}
+			},
+			wantErr:     true,
+			errContains: "DNS[0]",
+		},
+		{
+			name: "DNS single-label domain",
+			modify: func(pa *PeerAnnouncement) {
+				pa.DNS = []DNSDomain{{Domain: "lan", Resolver: "192.168.1.53"}}
+			},
+			wantErr:     true,
+			errContains: "single-label",
+		},
+		{
+			name: "DNS public suffix",
+			modify: func(pa *PeerAnnouncement) {
+				pa.DNS = []DNSDomain{{Domain: "CO.UK", Resolver: "192.168.1.53"}}
+			},
+			wantErr:     true,
+			errContains: "public suffix",
+		},
+		{
+			name: "DNS resolver not an IP",
+			modify: func(pa *PeerAnnouncement) {
+				pa.DNS = []DNSDomain{{Domain: "home.lan", Resolver: "resolver.home.lan"}}
+			},
+			wantErr:     true,
+			errContains: "invalid resolver",
+		},
+		// RouteChecks validation
+		{
+			name: "valid route checks",
+			modify: func(pa *PeerAnnouncement) {
+				pa.RouteChecks = []RouteCheck{{Network: "192.168.1.0/24", Target: "192.168.1.10:443", Healthy: true}, {Network: "fd00::/64", Target: "[fd00::10]:22"}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "route check target outside the network",
+			modify: func(pa *PeerAnnouncement) {
+				pa.RouteChecks = []RouteCheck{{Network: "192.168.1.0/24", Target: "10.0.0.1:443"}}
+			},
+			wantErr:     true,
+			errContains: "outside the network",
+		},
+		{
+			name: "route check target without a port",
+			modify: func(pa *PeerAnnouncement) {
+				pa.RouteChecks = []RouteCheck{{Network: "192.168.1.0/24", Target: "192.168.1.10"}}
+			},
+			wantErr:     true,
+			errContains: "RouteChecks[0]",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,204 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Every node holds an Ed25519 identity key next to its WireGuard key and
+// signs its own announcements with it. The shared secret only proves mesh
+// membership; the signature proves the announcement came from the holder of
+// the identity key, so one member cannot rewrite anything another member
+// announces about itself. Receivers bind the identity to the WireGuard key
+// on first contact (see PeerStore.Update).
+//
+// Signature covers every field the sender wrote, in the form it was sent
+// (see signedBytes). LegacySignature covers the fixed set of fields that
+// nodes from before Signature check; it is still sent so they keep
+// accepting newer nodes.
+const (
+	identitySigningContext       = "wgmesh-announce-v2\n"
+	legacyIdentitySigningContext = "wgmesh-announce-v1\n"
+)
+
+// unsignedFields are the announcement's JSON keys the signature does not
+// cover: the signatures themselves, and the fields the sender fills in
+// about other nodes, which relays may legitimately differ on.
+var unsignedFields = []string{"sig", "signature", "known_peers", "observed_endpoint"}
+
+// ErrUnsignedAnnouncement is returned by VerifySignature for announcements
+// from nodes that predate identity signing.
+var ErrUnsignedAnnouncement = errors.New("announcement is not signed")
+
+// GenerateIdentityKey creates a new Ed25519 identity key.
+func GenerateIdentityKey() (ed25519.PrivateKey, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate identity key: %w", err)
+	}
+	return priv, nil
+}
+
+// EncodeIdentityKey encodes an identity key's seed for the node state file.
+func EncodeIdentityKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Seed())
+}
+
+// DecodeIdentityKey restores an identity key encoded by EncodeIdentityKey.
+func DecodeIdentityKey(s string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid identity key encoding: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid identity key length %d", len(seed))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// IdentityPublicKey returns the base64 public identity announced for key.
+func IdentityPublicKey(key ed25519.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+}
+
+// Sign sets the announcement's identity and signs it with key. Call it after
+// all fields are filled in; KnownPeers and ObservedEndpoint are not covered
+// since they describe other nodes.
+func (pa *PeerAnnouncement) Sign(key ed25519.PrivateKey) error {
+	pa.Identity = IdentityPublicKey(key)
+	pa.Signature, pa.LegacySignature, pa.raw = "", "", nil
+	legacy, err := pa.legacySignedBytes()
+	if err != nil {
+		return err
+	}
+	msg, err := pa.signedBytes()
+	if err != nil {
+		return err
+	}
+	pa.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, msg))
+	pa.LegacySignature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, legacy))
+	return nil
+}
+
+// VerifySignature checks the announcement's signature against its announced
+// identity. It returns ErrUnsignedAnnouncement when there is no signature.
+//
+// An announcement from a node that only sends LegacySignature passes if
+// that verifies, with every field it does not cover cleared, so nothing a
+// third party could have written reaches the caller.
+func (pa *PeerAnnouncement) VerifySignature() error {
+	if pa.Signature == "" && pa.LegacySignature == "" {
+		return ErrUnsignedAnnouncement
+	}
+	pub, err := base64.StdEncoding.DecodeString(pa.Identity)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid identity")
+	}
+	encoded, signedBytes := pa.Signature, pa.signedBytes
+	if encoded == "" {
+		encoded, signedBytes = pa.LegacySignature, pa.legacySignedBytes
+	}
+	sig, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("invalid signature encoding")
+	}
+	msg, err := signedBytes()
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pub), msg, sig) {
+		return fmt.Errorf("signature does not match identity")
+	}
+	if pa.Signature == "" {
+		pa.dropLegacyUnsigned()
+	}
+	return nil
+}
+
+// signedBytes returns the canonical encoding of everything the sender
+// vouches for: the announcement's JSON object as it was received (or would
+// be sent) less unsignedFields, with keys sorted. Working from the received
+// bytes rather than the decoded struct keeps fields this build does not
+// know covered, so nodes of different versions verify each other.
+func (pa *PeerAnnouncement) signedBytes() ([]byte, error) {
+	data := pa.raw
+	if data == nil {
+		var err error
+		if data, err = json.Marshal((*plainAnnouncement)(pa)); err != nil {
+			return nil, fmt.Errorf("failed to encode announcement for signing: %w", err)
+		}
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode announcement for signing: %w", err)
+	}
+	for _, key := range unsignedFields {
+		delete(fields, key)
+	}
+	payload, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode announcement for signing: %w", err)
+	}
+	return append([]byte(identitySigningContext), payload...), nil
+}
+
+// legacySignedBytes returns what LegacySignature covers: a fixed set of
+// fields, frozen when identity signing was introduced.
+func (pa *PeerAnnouncement) legacySignedBytes() ([]byte, error) {
+	payload, err := json.Marshal(struct {
+		Protocol           string   `json:"protocol"`
+		WGPubKey           string   `json:"wg_pubkey"`
+		Identity           string   `json:"identity"`
+		Hostname           string   `json:"hostname"`
+		MeshIP             string   `json:"mesh_ip"`
+		MeshIPv6           string   `json:"mesh_ipv6"`
+		WGEndpoint         string   `json:"wg_endpoint"`
+		Introducer         bool     `json:"introducer"`
+		RoutableNetworks   []string `json:"routable_networks"`
+		Timestamp          int64    `json:"timestamp"`
+		NATType            string   `json:"nat_type"`
+		EndpointCandidates []string `json:"endpoint_candidates"`
+	}{
+		Protocol:           pa.Protocol,
+		WGPubKey:           pa.WGPubKey,
+		Identity:           pa.Identity,
+		Hostname:           pa.Hostname,
+		MeshIP:             pa.MeshIP,
+		MeshIPv6:           pa.MeshIPv6,
+		WGEndpoint:         pa.WGEndpoint,
+		Introducer:         pa.Introducer,
+		RoutableNetworks:   pa.RoutableNetworks,
+		Timestamp:          pa.Timestamp,
+		NATType:            pa.NATType,
+		EndpointCandidates: pa.EndpointCandidates,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode announcement for signing: %w", err)
+	}
+	return append([]byte(legacyIdentitySigningContext), payload...), nil
+}
+
+// dropLegacyUnsigned clears every field LegacySignature does not cover.
+func (pa *PeerAnnouncement) dropLegacyUnsigned() {
+	*pa = PeerAnnouncement{
+		Protocol:           pa.Protocol,
+		WGPubKey:           pa.WGPubKey,
+		Hostname:           pa.Hostname,
+		MeshIP:             pa.MeshIP,
+		MeshIPv6:           pa.MeshIPv6,
+		WGEndpoint:         pa.WGEndpoint,
+		Introducer:         pa.Introducer,
+		RoutableNetworks:   pa.RoutableNetworks,
+		Timestamp:          pa.Timestamp,
+		KnownPeers:         pa.KnownPeers,
+		ObservedEndpoint:   pa.ObservedEndpoint,
+		NATType:            pa.NATType,
+		EndpointCandidates: pa.EndpointCandidates,
+		Identity:           pa.Identity,
+		LegacySignature:    pa.LegacySignature,
+	}
+}
@@ -0,0 +1,232 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func signedTestAnnouncement(t *testing.T) *PeerAnnouncement {
+	t.Helper()
+	key, err := GenerateIdentityKey()
+	if err != nil {
+		t.Fatalf("GenerateIdentityKey: %v", err)
+	}
+	pa := CreateAnnouncement("wg-key", "10.0.0.1", "1.2.3.4:51820", false, []string{"192.168.0.0/24"}, nil, "node-a", "", "cone")
+	pa.EndpointCandidates = []string{"192.168.0.10:51820"}
+	if err := pa.Sign(key); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return pa
+}
+
+func TestAnnouncementSignature(t *testing.T) {
+	pa := signedTestAnnouncement(t)
+	if err := pa.VerifySignature(); err != nil {
+		t.Fatalf("VerifySignature: %v", err)
+	}
+
+	// Fields describing other nodes are not covered.
+	pa.KnownPeers = []KnownPeer{{WGPubKey: "other", MeshIP: "10.0.0.9"}}
+	pa.ObservedEndpoint = "5.6.7.8:4000"
+	if err := pa.VerifySignature(); err != nil {
+		t.Errorf("KnownPeers/ObservedEndpoint changed the signature: %v", err)
+	}
+
+	tampered := []struct {
+		name   string
+		modify func(pa *PeerAnnouncement)
+	}{
+		{"endpoint", func(pa *PeerAnnouncement) { pa.WGEndpoint = "6.6.6.6:51820" }},
+		{"wg key", func(pa *PeerAnnouncement) { pa.WGPubKey = "victim-key" }},
+		{"mesh ip", func(pa *PeerAnnouncement) { pa.MeshIP = "10.0.0.2" }},
+		{"routes", func(pa *PeerAnnouncement) { pa.RoutableNetworks = []string{"0.0.0.0/0"} }},
+		{"candidates", func(pa *PeerAnnouncement) { pa.EndpointCandidates = nil }},
+		{"timestamp", func(pa *PeerAnnouncement) { pa.Timestamp += int64(time.Minute.Seconds()) }},
+		{"identity", func(pa *PeerAnnouncement) { pa.Identity = signedTestAnnouncement(t).Identity }},
+		{"capabilities", func(pa *PeerAnnouncement) { pa.Capabilities = 0 }},
+		{"pair psk", func(pa *PeerAnnouncement) { pa.PairPSK = false }},
+		{"tags", func(pa *PeerAnnouncement) { pa.Tags = map[string]string{"group": "admin"} }},
+		{"port hop", func(pa *PeerAnnouncement) { pa.PortHop = 30 }},
+		{"tcp via", func(pa *PeerAnnouncement) { pa.TCPVia = "victim-key" }},
+		{"dns", func(pa *PeerAnnouncement) { pa.DNS = []DNSDomain{{Domain: "corp.lan", Resolver: "10.0.0.1"}} }},
+		{"route checks", func(pa *PeerAnnouncement) {
+			pa.RouteChecks = []RouteCheck{{Network: "192.168.0.0/24", Target: "192.168.0.1:443"}}
+		}},
+		{"load", func(pa *PeerAnnouncement) { pa.Load = &IntroducerLoad{Sessions: 1000} }},
+	}
+	for _, tt := range tampered {
+		t.Run(tt.name, func(t *testing.T) {
+			pa := signedTestAnnouncement(t)
+			tt.modify(pa)
+			if err := pa.VerifySignature(); err == nil {
+				t.Error("expected verification to fail")
+			}
+		})
+	}
+}
+
+// TestAnnouncementSignatureWire checks verification of announcements as
+// receivers and relays see them: decoded from JSON, possibly re-encoded.
+func TestAnnouncementSignatureWire(t *testing.T) {
+	key, err := GenerateIdentityKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pa := CreateAnnouncement("wg-key", "10.0.0.1", "1.2.3.4:51820", false, nil, nil, "node-a", "", "cone")
+	pa.Tags = map[string]string{"role": "db"}
+	if err := pa.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(pa)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A relay re-encodes the announcement it received inside its own
+	// payload, as a dandelion stem does.
+	var received PeerAnnouncement
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatal(err)
+	}
+	relayed, err := json.Marshal(struct {
+		Announcement *PeerAnnouncement `json:"announcement"`
+	}{&received})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stem struct {
+		Announcement *PeerAnnouncement `json:"announcement"`
+	}
+	if err := json.Unmarshal(relayed, &stem); err != nil {
+		t.Fatal(err)
+	}
+	if err := stem.Announcement.VerifySignature(); err != nil {
+		t.Errorf("relayed announcement: %v", err)
+	}
+
+	// A newer sender's fields are covered although this build drops them.
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	fields["future_field"] = json.RawMessage(`{"grants":["all"]}`)
+	signed := make(map[string]json.RawMessage)
+	for k, v := range fields {
+		signed[k] = v
+	}
+	for _, k := range unsignedFields {
+		delete(signed, k)
+	}
+	payload, err := json.Marshal(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := ed25519.Sign(key, append([]byte(identitySigningContext), payload...))
+	fields["sig"], _ = json.Marshal(base64.StdEncoding.EncodeToString(sig))
+	newer, err := json.Marshal(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fromNewer PeerAnnouncement
+	if err := json.Unmarshal(newer, &fromNewer); err != nil {
+		t.Fatal(err)
+	}
+	if err := fromNewer.VerifySignature(); err != nil {
+		t.Errorf("announcement with an unknown field: %v", err)
+	}
+
+	// Adding a field the sender did not send breaks the signature, even
+	// one that differs from a signed key only in case.
+	for _, key := range []string{"future_field", "Tags"} {
+		fields := make(map[string]json.RawMessage)
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatal(err)
+		}
+		fields[key] = json.RawMessage(`{"group":"admin"}`)
+		forged, _ := json.Marshal(fields)
+		var pa PeerAnnouncement
+		if err := json.Unmarshal(forged, &pa); err != nil {
+			t.Fatal(err)
+		}
+		if err := pa.VerifySignature(); err == nil {
+			t.Errorf("announcement with an added %q verified", key)
+		}
+	}
+}
+
+func TestAnnouncementLegacySignature(t *testing.T) {
+	pa := signedTestAnnouncement(t)
+	pa.Tags = map[string]string{"role": "db"}
+	pa.PortHop = 30
+	// What a node from before full signatures sends, with fields a third
+	// party added: only the legacy signature.
+	pa.Signature = ""
+	if err := pa.Sign(mustIdentityKey(t)); err != nil {
+		t.Fatal(err)
+	}
+	pa.Signature = ""
+	pa.Tags = map[string]string{"group": "admin"}
+
+	if err := pa.VerifySignature(); err != nil {
+		t.Fatalf("VerifySignature: %v", err)
+	}
+	if pa.Tags != nil || pa.PortHop != 0 || pa.PairPSK || pa.Capabilities != 0 {
+		t.Errorf("fields outside the legacy signature kept: tags=%v port_hop=%d pair_psk=%v caps=%v",
+			pa.Tags, pa.PortHop, pa.PairPSK, pa.Capabilities)
+	}
+	if pa.WGEndpoint != "1.2.3.4:51820" || len(pa.RoutableNetworks) != 1 {
+		t.Errorf("legacy fields lost: endpoint=%q routes=%v", pa.WGEndpoint, pa.RoutableNetworks)
+	}
+
+	pa.WGEndpoint = "6.6.6.6:51820"
+	if err := pa.VerifySignature(); err == nil {
+		t.Error("tampered legacy announcement verified")
+	}
+}
+
+func mustIdentityKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	key, err := GenerateIdentityKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestAnnouncementSignatureUnsigned(t *testing.T) {
+	pa := CreateAnnouncement("wg-key", "10.0.0.1", "1.2.3.4:51820", false, nil, nil, "", "", "")
+	if err := pa.VerifySignature(); !errors.Is(err, ErrUnsignedAnnouncement) {
+		t.Errorf("VerifySignature = %v, want ErrUnsignedAnnouncement", err)
+	}
+
+	pa.Signature = "not-base64!"
+	pa.Identity = "also-not-base64!"
+	if err := pa.VerifySignature(); err == nil || errors.Is(err, ErrUnsignedAnnouncement) {
+		t.Errorf("VerifySignature = %v, want a decoding error", err)
+	}
+}
+
+func TestIdentityKeyEncoding(t *testing.T) {
+	key, err := GenerateIdentityKey()
+	if err != nil {
+		t.Fatalf("GenerateIdentityKey: %v", err)
+	}
+	decoded, err := DecodeIdentityKey(EncodeIdentityKey(key))
+	if err != nil {
+		t.Fatalf("DecodeIdentityKey: %v", err)
+	}
+	if !key.Equal(decoded) {
+		t.Error("decoded key differs from the original")
+	}
+	if IdentityPublicKey(decoded) != IdentityPublicKey(key) {
+		t.Error("public identity changed after round trip")
+	}
+
+	if _, err := DecodeIdentityKey("c2hvcnQ="); err == nil {
+		t.Error("expected error for a short seed")
+	}
+}
@@ -0,0 +1,57 @@
+package crypto
+
+import (
+	"crypto/sha256"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// GossipRatchetPeriod is how long one envelope key epoch lasts.
+	GossipRatchetPeriod = 24 * time.Hour
+
+	// GossipRatchetWindow is how many epochs either side of the current one
+	// are still accepted, to tolerate clock skew around an epoch boundary.
+	GossipRatchetWindow = 1
+
+	hkdfInfoRatchet = "wgmesh-gossip-ratchet-v1"
+)
+
+// gossipRatchetAnchor is the start of epoch 0. Chaining from a recent
+// anchor keeps deriving the current key cheap.
+var gossipRatchetAnchor = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// GossipEpoch returns the envelope key epoch t falls in.
+func GossipEpoch(t time.Time) int64 {
+	if t.Before(gossipRatchetAnchor) {
+		return 0
+	}
+	return int64(t.Sub(gossipRatchetAnchor) / GossipRatchetPeriod)
+}
+
+// RatchetKey derives the next epoch's envelope key from the current one.
+// The step is one-way: a key does not reveal the keys of earlier epochs.
+func RatchetKey(key [32]byte) [32]byte {
+	// next = HKDF(key, info=hkdfInfoRatchet, 32 bytes)
+	var next [32]byte
+	reader := hkdf.New(sha256.New, key[:], nil, []byte(hkdfInfoRatchet))
+	if _, err := io.ReadFull(reader, next[:]); err != nil {
+		// HKDF-SHA256 only fails past 255*32 bytes of output.
+		panic("wgmesh: ratchet key derivation failed: " + err.Error())
+	}
+	return next
+}
+
+// GossipEpochKey derives the envelope key of epoch from the static gossip
+// key by ratcheting it epoch+1 times, so that no epoch uses the static key.
+// Anyone holding the gossip key can do the same for every epoch, so the
+// ratchet does not make envelopes forward secret.
+func GossipEpochKey(gossipKey [32]byte, epoch int64) [32]byte {
+	key := RatchetKey(gossipKey)
+	for i := int64(0); i < epoch; i++ {
+		key = RatchetKey(key)
+	}
+	return key
+}
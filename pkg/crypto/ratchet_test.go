@@ -0,0 +1,54 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestGossipEpoch(t *testing.T) {
+	if got := GossipEpoch(gossipRatchetAnchor.Add(-time.Hour)); got != 0 {
+		t.Errorf("epoch before the anchor = %d, want 0", got)
+	}
+	if got := GossipEpoch(gossipRatchetAnchor.Add(GossipRatchetPeriod - time.Second)); got != 0 {
+		t.Errorf("epoch at the end of the first period = %d, want 0", got)
+	}
+	if got := GossipEpoch(gossipRatchetAnchor.Add(3 * GossipRatchetPeriod)); got != 3 {
+		t.Errorf("epoch after three periods = %d, want 3", got)
+	}
+}
+
+func TestGossipEpochKey(t *testing.T) {
+	keys, _ := DeriveKeys("test-secret-that-is-long-enough")
+
+	k0 := GossipEpochKey(keys.GossipKey, 0)
+	if k0 == keys.GossipKey {
+		t.Error("epoch 0 uses the static gossip key")
+	}
+	if k5, k6 := GossipEpochKey(keys.GossipKey, 5), GossipEpochKey(keys.GossipKey, 6); RatchetKey(k5) != k6 {
+		t.Error("ratcheting epoch 5's key does not give epoch 6's")
+	}
+	if GossipEpochKey(keys.GossipKey, 1) == k0 {
+		t.Error("consecutive epochs share a key")
+	}
+}
+
+func TestOpenEnvelopeWithKeys(t *testing.T) {
+	keys, _ := DeriveKeys("test-secret-that-is-long-enough")
+	old, cur := GossipEpochKey(keys.GossipKey, 1), GossipEpochKey(keys.GossipKey, 2)
+	announcement := CreateAnnouncement(base64.StdEncoding.EncodeToString(make([]byte, 32)), "10.0.0.1", "1.2.3.4:51820", false, nil, nil, "", "", "")
+
+	sealed, err := SealEnvelope(MessageTypeAnnounce, announcement, old)
+	if err != nil {
+		t.Fatalf("SealEnvelope: %v", err)
+	}
+	if _, got, err := OpenEnvelopeWithKeys(sealed, [][32]byte{cur, old}); err != nil || got.MeshIP != "10.0.0.1" {
+		t.Fatalf("OpenEnvelopeWithKeys with the sealing key second: %v", err)
+	}
+	if _, _, err := OpenEnvelopeWithKeys(sealed, [][32]byte{cur, keys.GossipKey}); err == nil {
+		t.Error("opened an envelope without its key")
+	}
+	if _, _, err := OpenEnvelopeWithKeys(sealed, nil); err == nil {
+		t.Error("opened an envelope with no keys")
+	}
+}
@@ -38,12 +38,13 @@ func GenerateRotationAnnouncement(oldMembershipKey []byte, newSecret string, gra
 
 // ValidateRotationAnnouncement validates a rotation announcement
 func ValidateRotationAnnouncement(oldMembershipKey []byte, announcement *RotationAnnouncement) bool {
-	// Check timestamp (within last hour)
+	// Check timestamp (within last hour, widened by any clock skew)
 	msgTime := time.Unix(announcement.Timestamp, 0)
-	if time.Since(msgTime) > time.Hour {
+	window := time.Hour + ClockSkewTolerance()
+	if time.Since(msgTime) > window {
 		return false
 	}
-	if msgTime.After(time.Now().Add(time.Hour)) {
+	if msgTime.After(time.Now().Add(window)) {
 		return false
 	}
 
@@ -74,6 +75,57 @@ func signRotation(membershipKey []byte, announcement *RotationAnnouncement) ([]b
 	return mac.Sum(nil), nil
 }
 
+// RotationMessage distributes a rotation announcement together with the new
+// secret. It must only travel inside an envelope sealed with the current
+// gossip key, so the new secret is revealed to existing members only.
+type RotationMessage struct {
+	Protocol     string                `json:"protocol"`
+	Timestamp    int64                 `json:"timestamp"` // per-hop freshness; the announcement keeps its own
+	Announcement *RotationAnnouncement `json:"announcement"`
+	NewSecret    string                `json:"new_secret"`
+}
+
+// NewRotationMessage creates a signed rotation message for newSecret.
+func NewRotationMessage(oldMembershipKey []byte, newSecret string, gracePeriod time.Duration) (*RotationMessage, error) {
+	if len(newSecret) < MinSecretLength {
+		return nil, fmt.Errorf("new secret must be at least %d characters", MinSecretLength)
+	}
+	announcement, err := GenerateRotationAnnouncement(oldMembershipKey, newSecret, gracePeriod)
+	if err != nil {
+		return nil, err
+	}
+	return &RotationMessage{
+		Protocol:     ProtocolVersion,
+		Timestamp:    time.Now().Unix(),
+		Announcement: announcement,
+		NewSecret:    newSecret,
+	}, nil
+}
+
+// Verify checks that the message was signed with the current membership key
+// and that the secret it carries is the one the announcement commits to.
+func (m *RotationMessage) Verify(oldMembershipKey []byte) error {
+	if m.Announcement == nil {
+		return fmt.Errorf("missing announcement")
+	}
+	if m.Announcement.GracePeriod <= 0 {
+		return fmt.Errorf("invalid grace period %d", m.Announcement.GracePeriod)
+	}
+	if !ValidateRotationAnnouncement(oldMembershipKey, m.Announcement) {
+		return fmt.Errorf("invalid or expired announcement signature")
+	}
+	if len(m.NewSecret) < MinSecretLength || !VerifyNewSecret(m.NewSecret, m.Announcement) {
+		return fmt.Errorf("new secret does not match announcement")
+	}
+	return nil
+}
+
+// SwitchAt returns when nodes leave dual-secret mode. It is derived from the
+// signed announcement so every node switches at the same moment.
+func (m *RotationMessage) SwitchAt() time.Time {
+	return time.Unix(m.Announcement.Timestamp, 0).Add(time.Duration(m.Announcement.GracePeriod) * time.Second)
+}
+
 // RotationState tracks the state of an ongoing secret rotation
 type RotationState struct {
 	OldSecret   string        `json:"old_secret"`
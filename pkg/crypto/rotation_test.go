@@ -219,3 +219,33 @@ func TestRotationStateUnmarshalJSONErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestRotationMessageVerify(t *testing.T) {
+	oldKey := []byte("old-membership-key-that-is-32b!!")
+	newSecret := "new-secret-that-is-long-enough!"
+
+	msg, err := NewRotationMessage(oldKey, newSecret, time.Hour)
+	if err != nil {
+		t.Fatalf("NewRotationMessage failed: %v", err)
+	}
+	if err := msg.Verify(oldKey); err != nil {
+		t.Fatalf("valid message rejected: %v", err)
+	}
+	if got, want := msg.SwitchAt(), time.Unix(msg.Announcement.Timestamp, 0).Add(time.Hour); !got.Equal(want) {
+		t.Errorf("SwitchAt = %v, want %v", got, want)
+	}
+
+	if err := msg.Verify([]byte("wrong-key-that-is-also-32-bytes!")); err == nil {
+		t.Error("message signed with another key should be rejected")
+	}
+
+	swapped := *msg
+	swapped.NewSecret = "some-other-secret-long-enough!!"
+	if err := swapped.Verify(oldKey); err == nil {
+		t.Error("message carrying a secret other than the committed one should be rejected")
+	}
+
+	if _, err := NewRotationMessage(oldKey, "short", time.Hour); err == nil {
+		t.Error("short new secret should be rejected")
+	}
+}
@@ -0,0 +1,154 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Secret files hold the mesh secret at rest. A sealed file starts with a
+// header line naming the protection method, followed by the base64 payload:
+//
+//	wgmesh-secret/v1 machine
+//	<base64 nonce||ciphertext>
+//
+// Files without the header are read as the plaintext secret, which is what
+// systemd hands the daemon after decrypting a LoadCredentialEncrypted file.
+const (
+	secretFileMagic = "wgmesh-secret/v1"
+
+	// SecretFileMachine seals with a key bound to this host (see SealSecretWithMachineKey).
+	SecretFileMachine = "machine"
+	// SecretFilePassword seals with a password-derived key (PBKDF2, as Encrypt).
+	SecretFilePassword = "password"
+
+	hkdfInfoSecretFile = "wgmesh-secret-file-v1"
+)
+
+// SealSecretWithMachineKey encrypts secret with AES-256-GCM under a key
+// derived from machineKey (random, root-only) and machineID, so a copy of
+// the state directory does not decrypt on another host.
+func SealSecretWithMachineKey(secret string, machineKey []byte, machineID string) ([]byte, error) {
+	gcm, err := secretFileCipher(machineKey, machineID)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), []byte(secretFileMagic))
+	return formatSecretFile(SecretFileMachine, base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+// SealSecretWithPassword encrypts secret with a password, for hosts where
+// the secret must not be recoverable without an operator.
+func SealSecretWithPassword(secret, password string) ([]byte, error) {
+	if password == "" {
+		return nil, fmt.Errorf("password is required")
+	}
+	encoded, err := Encrypt([]byte(secret), password)
+	if err != nil {
+		return nil, err
+	}
+	return formatSecretFile(SecretFilePassword, encoded), nil
+}
+
+// SecretFileMethod reports how a secret file is protected: SecretFileMachine,
+// SecretFilePassword, or "" for a plaintext file.
+func SecretFileMethod(data []byte) (string, error) {
+	method, _, err := parseSecretFile(data)
+	return method, err
+}
+
+// OpenSecretFile returns the secret stored in data. machineKey and password
+// are only called for files sealed with the matching method.
+func OpenSecretFile(data []byte, machineKey func() ([]byte, string, error), password func() (string, error)) (string, error) {
+	method, payload, err := parseSecretFile(data)
+	if err != nil {
+		return "", err
+	}
+
+	var secret []byte
+	switch method {
+	case "":
+		secret = []byte(payload)
+	case SecretFileMachine:
+		key, machineID, err := machineKey()
+		if err != nil {
+			return "", fmt.Errorf("failed to load machine key: %w", err)
+		}
+		sealed, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode secret file: %w", err)
+		}
+		gcm, err := secretFileCipher(key, machineID)
+		if err != nil {
+			return "", err
+		}
+		if len(sealed) < gcm.NonceSize() {
+			return "", fmt.Errorf("secret file too short")
+		}
+		secret, err = gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(secretFileMagic))
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt secret file (sealed on another host?): %w", err)
+		}
+	case SecretFilePassword:
+		pw, err := password()
+		if err != nil {
+			return "", err
+		}
+		secret, err = Decrypt(payload, pw)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	s := strings.TrimSpace(string(secret))
+	if s == "" {
+		return "", fmt.Errorf("secret file is empty")
+	}
+	return s, nil
+}
+
+func formatSecretFile(method, payload string) []byte {
+	return []byte(secretFileMagic + " " + method + "\n" + payload + "\n")
+}
+
+func parseSecretFile(data []byte) (method, payload string, err error) {
+	if !bytes.HasPrefix(data, []byte(secretFileMagic+" ")) {
+		return "", strings.TrimSpace(string(data)), nil
+	}
+	header, body, _ := strings.Cut(string(data), "\n")
+	method = strings.TrimSpace(strings.TrimPrefix(header, secretFileMagic))
+	switch method {
+	case SecretFileMachine, SecretFilePassword:
+		return method, strings.TrimSpace(body), nil
+	default:
+		return "", "", fmt.Errorf("unsupported secret file method %q", method)
+	}
+}
+
+func secretFileCipher(machineKey []byte, machineID string) (cipher.AEAD, error) {
+	if len(machineKey) < 32 {
+		return nil, fmt.Errorf("machine key too short")
+	}
+	key := make([]byte, keySize)
+	reader := hkdf.New(sha256.New, machineKey, []byte(machineID), []byte(hkdfInfoSecretFile))
+	if _, err := io.ReadFull(reader, key); err != nil {
+		return nil, fmt.Errorf("failed to derive secret file key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
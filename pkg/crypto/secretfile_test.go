@@ -0,0 +1,75 @@
+package crypto
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSecretFileRoundTrip(t *testing.T) {
+	secret := "wgmesh-secret-file-test-value"
+	machineKey := bytes.Repeat([]byte{7}, 32)
+	keyFn := func(id string) func() ([]byte, string, error) {
+		return func() ([]byte, string, error) { return machineKey, id, nil }
+	}
+	noPassword := func() (string, error) { return "", fmt.Errorf("no password") }
+
+	sealed, err := SealSecretWithMachineKey(secret, machineKey, "machine-a")
+	if err != nil {
+		t.Fatalf("SealSecretWithMachineKey: %v", err)
+	}
+	if bytes.Contains(sealed, []byte(secret)) {
+		t.Fatal("sealed file contains the plaintext secret")
+	}
+	if m, _ := SecretFileMethod(sealed); m != SecretFileMachine {
+		t.Errorf("method = %q, want %q", m, SecretFileMachine)
+	}
+	got, err := OpenSecretFile(sealed, keyFn("machine-a"), noPassword)
+	if err != nil || got != secret {
+		t.Fatalf("OpenSecretFile = %q, %v", got, err)
+	}
+	if _, err := OpenSecretFile(sealed, keyFn("machine-b"), noPassword); err == nil {
+		t.Error("expected failure with a different machine ID")
+	}
+
+	sealed, err = SealSecretWithPassword(secret, "hunter2")
+	if err != nil {
+		t.Fatalf("SealSecretWithPassword: %v", err)
+	}
+	got, err = OpenSecretFile(sealed, keyFn("machine-a"), func() (string, error) { return "hunter2", nil })
+	if err != nil || got != secret {
+		t.Fatalf("OpenSecretFile(password) = %q, %v", got, err)
+	}
+	if _, err := OpenSecretFile(sealed, keyFn("machine-a"), func() (string, error) { return "wrong", nil }); err == nil {
+		t.Error("expected failure with a wrong password")
+	}
+}
+
+func TestOpenSecretFilePlaintext(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{name: "trimmed", data: "  my-secret-value\n", want: "my-secret-value"},
+		{name: "empty", data: "\n", wantErr: true},
+		{name: "unknown method", data: "wgmesh-secret/v1 tpm\nAAAA\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := OpenSecretFile([]byte(tt.data), nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := SealSecretWithPassword("x", ""); err == nil || !strings.Contains(err.Error(), "password") {
+		t.Errorf("expected error for empty password, got %v", err)
+	}
+}
@@ -0,0 +1,243 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Encrypted state files start with a header line naming the key source,
+// followed by the base64 payload. The header is authenticated as GCM
+// additional data, so the KDF parameters cannot be swapped.
+//
+//	wgmesh-state/v2 argon2id t=3,m=65536,p=4
+//	<base64 salt||nonce||ciphertext>
+//
+//	wgmesh-state/v2 key
+//	<base64 nonce||ciphertext>
+//
+// Files without the header are the original format: base64 of
+// salt||nonce||ciphertext with a PBKDF2 key (see Encrypt).
+const (
+	stateFileMagic = "wgmesh-state/v2"
+
+	stateMethodArgon2id = "argon2id"
+	stateMethodKey      = "key"
+
+	stateSaltSize = 16
+
+	// Bounds on KDF parameters, also applied to the ones read from a file
+	// so a crafted header cannot make wgmesh allocate without limit.
+	maxKDFTime      = 64
+	maxKDFMemoryKiB = 4 << 20 // 4 GiB
+)
+
+// KDFParams are the argon2id cost parameters for password-encrypted state.
+type KDFParams struct {
+	Time      uint32 // passes over memory
+	MemoryKiB uint32
+	Threads   uint8
+}
+
+// DefaultKDFParams follows the RFC 9106 second recommendation, with a
+// smaller thread count: about a quarter second on current hardware.
+var DefaultKDFParams = KDFParams{Time: 3, MemoryKiB: 64 << 10, Threads: 4}
+
+// Validate checks that p is usable and within the limits wgmesh accepts.
+func (p KDFParams) Validate() error {
+	if p.Time < 1 || p.Time > maxKDFTime {
+		return fmt.Errorf("argon2id time must be between 1 and %d", maxKDFTime)
+	}
+	if p.Threads < 1 {
+		return fmt.Errorf("argon2id threads must be at least 1")
+	}
+	if p.MemoryKiB < 8*uint32(p.Threads) || p.MemoryKiB > maxKDFMemoryKiB {
+		return fmt.Errorf("argon2id memory must be between %d KiB and %d KiB", 8*uint32(p.Threads), maxKDFMemoryKiB)
+	}
+	return nil
+}
+
+func (p KDFParams) String() string {
+	return fmt.Sprintf("t=%d,m=%d,p=%d", p.Time, p.MemoryKiB, p.Threads)
+}
+
+func parseKDFParams(s string) (KDFParams, error) {
+	var p KDFParams
+	if _, err := fmt.Sscanf(s, "t=%d,m=%d,p=%d", &p.Time, &p.MemoryKiB, &p.Threads); err != nil {
+		return KDFParams{}, fmt.Errorf("invalid argon2id parameters %q", s)
+	}
+	if err := p.Validate(); err != nil {
+		return KDFParams{}, err
+	}
+	return p, nil
+}
+
+// StateKey is what a state file is encrypted with. EncryptState uses Key
+// if set, else Password stretched with KDF. DecryptState uses whichever
+// the file needs, so setting both re-encrypts a password-protected file
+// under the key.
+type StateKey struct {
+	Password string
+	Key      []byte    // raw 32-byte key, from ReadKeyFile
+	KDF      KDFParams // zero means DefaultKDFParams
+}
+
+// StateInfo describes how a decrypted state file was protected.
+type StateInfo struct {
+	Legacy  bool      // PBKDF2 format from before the version header
+	KeyFile bool      // encrypted with StateKey.Key
+	KDF     KDFParams // argon2id parameters of a password-encrypted file
+}
+
+// EncryptState encrypts a state file with AES-256-GCM in the current format.
+func EncryptState(plaintext []byte, k StateKey) ([]byte, error) {
+	var header string
+	var key, salt []byte
+
+	if k.Key != nil {
+		if len(k.Key) != keySize {
+			return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(k.Key))
+		}
+		header = stateFileMagic + " " + stateMethodKey
+		key = k.Key
+	} else {
+		if k.Password == "" {
+			return nil, fmt.Errorf("password or key is required")
+		}
+		params := k.KDF
+		if params == (KDFParams{}) {
+			params = DefaultKDFParams
+		}
+		if err := params.Validate(); err != nil {
+			return nil, err
+		}
+		salt = make([]byte, stateSaltSize)
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		header = stateFileMagic + " " + stateMethodArgon2id + " " + params.String()
+		key = argon2.IDKey([]byte(k.Password), salt, params.Time, params.MemoryKiB, params.Threads, keySize)
+	}
+
+	gcm, err := newStateGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	payload := append(salt, nonce...)
+	payload = gcm.Seal(payload, nonce, plaintext, []byte(header))
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	buf.WriteByte('\n')
+	buf.WriteString(base64.StdEncoding.EncodeToString(payload))
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// DecryptState decrypts a state file written by EncryptState, or by Encrypt
+// before the format was versioned.
+func DecryptState(data []byte, k StateKey) ([]byte, StateInfo, error) {
+	text := string(data)
+	if !strings.HasPrefix(text, stateFileMagic+" ") {
+		if k.Password == "" {
+			return nil, StateInfo{}, fmt.Errorf("state file uses the original password format; decrypt it with the password")
+		}
+		plaintext, err := Decrypt(strings.TrimSpace(text), k.Password)
+		return plaintext, StateInfo{Legacy: true}, err
+	}
+
+	header, encoded, _ := strings.Cut(text, "\n")
+	header = strings.TrimSpace(header)
+	payload, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, StateInfo{}, fmt.Errorf("failed to decode base64: %w", err)
+	}
+
+	var info StateInfo
+	var key []byte
+	fields := strings.Fields(header)
+	switch {
+	case len(fields) == 2 && fields[1] == stateMethodKey:
+		if k.Key == nil {
+			return nil, StateInfo{}, fmt.Errorf("state file is encrypted with a key file")
+		}
+		info.KeyFile = true
+		key = k.Key
+	case len(fields) == 3 && fields[1] == stateMethodArgon2id:
+		if k.Password == "" {
+			return nil, StateInfo{}, fmt.Errorf("state file is encrypted with a password")
+		}
+		if info.KDF, err = parseKDFParams(fields[2]); err != nil {
+			return nil, StateInfo{}, err
+		}
+		if len(payload) < stateSaltSize {
+			return nil, StateInfo{}, fmt.Errorf("encrypted data too short")
+		}
+		var salt []byte
+		salt, payload = payload[:stateSaltSize], payload[stateSaltSize:]
+		key = argon2.IDKey([]byte(k.Password), salt, info.KDF.Time, info.KDF.MemoryKiB, info.KDF.Threads, keySize)
+	default:
+		return nil, StateInfo{}, fmt.Errorf("unsupported state file header %q", header)
+	}
+
+	gcm, err := newStateGCM(key)
+	if err != nil {
+		return nil, StateInfo{}, err
+	}
+	if len(payload) < gcm.NonceSize() {
+		return nil, StateInfo{}, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := payload[:gcm.NonceSize()], payload[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(header))
+	if err != nil {
+		if info.KeyFile {
+			return nil, StateInfo{}, fmt.Errorf("decryption failed (wrong key file?): %w", err)
+		}
+		return nil, StateInfo{}, fmt.Errorf("decryption failed (wrong password?): %w", err)
+	}
+	return plaintext, info, nil
+}
+
+func newStateGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+// ReadKeyFile reads a state encryption key: 32 raw bytes, or the same
+// base64-encoded (as from "openssl rand -base64 32").
+func ReadKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	if len(data) == keySize {
+		return data, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data))); err == nil && len(key) == keySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key file %s must hold %d raw bytes or their base64 encoding", path, keySize)
+}
@@ -0,0 +1,138 @@
+package crypto
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testKDF keeps the tests fast; real files use DefaultKDFParams.
+var testKDF = KDFParams{Time: 1, MemoryKiB: 64, Threads: 1}
+
+func TestEncryptStateWithPassword(t *testing.T) {
+	plaintext := []byte(`{"network":"10.99.0.0/16"}`)
+	data, err := EncryptState(plaintext, StateKey{Password: "hunter2", KDF: testKDF})
+	if err != nil {
+		t.Fatalf("EncryptState failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "wgmesh-state/v2 argon2id t=1,m=64,p=1\n") {
+		t.Fatalf("unexpected header in %q", data)
+	}
+
+	got, info, err := DecryptState(data, StateKey{Password: "hunter2"})
+	if err != nil {
+		t.Fatalf("DecryptState failed: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("DecryptState = %q, want %q", got, plaintext)
+	}
+	if info.Legacy || info.KeyFile || info.KDF != testKDF {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	if _, _, err := DecryptState(data, StateKey{Password: "wrong"}); err == nil {
+		t.Error("expected an error for a wrong password")
+	}
+	if _, _, err := DecryptState(data, StateKey{Key: make([]byte, 32)}); err == nil {
+		t.Error("expected an error without the password")
+	}
+
+	// The parameters are authenticated: a cheaper header must not decrypt.
+	tampered := bytes.Replace(data, []byte("t=1,"), []byte("t=2,"), 1)
+	if _, _, err := DecryptState(tampered, StateKey{Password: "hunter2"}); err == nil {
+		t.Error("expected an error for a modified header")
+	}
+}
+
+func TestEncryptStateWithKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	data, err := EncryptState([]byte("state"), StateKey{Key: key, Password: "ignored"})
+	if err != nil {
+		t.Fatalf("EncryptState failed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "wgmesh-state/v2 key\n") {
+		t.Fatalf("unexpected header in %q", data)
+	}
+
+	got, info, err := DecryptState(data, StateKey{Key: key})
+	if err != nil {
+		t.Fatalf("DecryptState failed: %v", err)
+	}
+	if string(got) != "state" || !info.KeyFile {
+		t.Errorf("DecryptState = %q, %+v", got, info)
+	}
+
+	if _, _, err := DecryptState(data, StateKey{Key: bytes.Repeat([]byte{8}, 32)}); err == nil {
+		t.Error("expected an error for a wrong key")
+	}
+	if _, err := EncryptState([]byte("state"), StateKey{Key: key[:16]}); err == nil {
+		t.Error("expected an error for a short key")
+	}
+}
+
+func TestDecryptStateLegacy(t *testing.T) {
+	legacy, err := Encrypt([]byte("old state"), "hunter2")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	got, info, err := DecryptState([]byte(legacy), StateKey{Password: "hunter2"})
+	if err != nil {
+		t.Fatalf("DecryptState failed: %v", err)
+	}
+	if string(got) != "old state" || !info.Legacy {
+		t.Errorf("DecryptState = %q, %+v", got, info)
+	}
+
+	if _, _, err := DecryptState([]byte(legacy), StateKey{Key: make([]byte, 32)}); err == nil {
+		t.Error("expected an error for a legacy file without password")
+	}
+}
+
+func TestKDFParamsValidate(t *testing.T) {
+	if err := DefaultKDFParams.Validate(); err != nil {
+		t.Errorf("DefaultKDFParams: %v", err)
+	}
+	for _, p := range []KDFParams{
+		{Time: 0, MemoryKiB: 64, Threads: 1},
+		{Time: 1, MemoryKiB: 64, Threads: 0},
+		{Time: 1, MemoryKiB: 8, Threads: 4},
+		{Time: 1, MemoryKiB: 8 << 20, Threads: 1},
+	} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want error", p)
+		}
+	}
+
+	data := []byte("wgmesh-state/v2 argon2id t=1,m=67108864,p=1\nAAAA\n")
+	if _, _, err := DecryptState(data, StateKey{Password: "x"}); err == nil {
+		t.Error("expected a file asking for 64 GiB to be rejected")
+	}
+}
+
+func TestReadKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	key := bytes.Repeat([]byte{0xAB}, 32)
+
+	raw := filepath.Join(dir, "raw.key")
+	encoded := filepath.Join(dir, "b64.key")
+	short := filepath.Join(dir, "short.key")
+	os.WriteFile(raw, key, 0600)
+	os.WriteFile(encoded, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600)
+	os.WriteFile(short, key[:31], 0600)
+
+	for _, path := range []string{raw, encoded} {
+		got, err := ReadKeyFile(path)
+		if err != nil {
+			t.Errorf("ReadKeyFile(%s): %v", filepath.Base(path), err)
+		} else if !bytes.Equal(got, key) {
+			t.Errorf("ReadKeyFile(%s) = %x", filepath.Base(path), got)
+		}
+	}
+	if _, err := ReadKeyFile(short); err == nil {
+		t.Error("expected an error for a 31-byte key")
+	}
+}
@@ -0,0 +1,443 @@
+package daemon
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+const (
+	// MaxPendingPeers bounds the lobby, which anyone holding the secret
+	// can add to.
+	MaxPendingPeers = 256
+	// ApprovalResendInterval limits how often an approval is sent back to
+	// an approved peer that still announces itself without it.
+	ApprovalResendInterval = time.Minute
+
+	EventPeerPending  = "peer_pending"
+	EventPeerAdmitted = "peer_admitted"
+)
+
+// ApprovalsPath returns the file holding the admission approvals known to
+// an interface.
+func ApprovalsPath(ifaceName string) string {
+	return filepath.Join(stateDir, fmt.Sprintf("%s-approvals.json", ifaceName))
+}
+
+// ApprovalParticipant is implemented by discovery layers that can deliver
+// admission approvals, including to peers that are not in the PeerStore.
+type ApprovalParticipant interface {
+	SetApprovalHandler(handler func(msg *crypto.ApprovalMessage))
+	BroadcastApproval(msg *crypto.ApprovalMessage)
+	SendApproval(peer *PeerInfo, msg *crypto.ApprovalMessage)
+}
+
+// pendingPeer is an announcement from a node nobody approved yet. It
+// stays out of the PeerStore, so it is neither gossiped nor installed.
+type pendingPeer struct {
+	info      PeerInfo
+	method    string
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// admissionState is the daemon's --approvers state. The zero value has
+// approval mode disabled.
+type admissionState struct {
+	mu        sync.Mutex
+	enabled   bool
+	approvals map[string]*crypto.AdmissionApproval // by WireGuard key, this node's included
+	approvers map[string]string                    // WireGuard key -> identity of approver nodes seen
+	pending   map[string]*pendingPeer              // by WireGuard key
+	resent    map[string]time.Time                 // last approval resend by WireGuard key
+}
+
+// RPCPendingData describes a peer waiting for approval for RPC (matches rpc.PendingData)
+type RPCPendingData struct {
+	PubKey    string
+	Hostname  string
+	MeshIP    string
+	Endpoint  string
+	Identity  string
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// parseApprovers parses --approvers, a comma-separated list of identity
+// keys.
+func parseApprovers(s string) ([]string, error) {
+	var keys []string
+	for _, key := range strings.Split(s, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		if pub, err := base64.StdEncoding.DecodeString(key); err != nil || len(pub) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid approver identity %q", key)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// setupAdmission loads the known approvals and starts holding back
+// announcements from unapproved nodes through admitPeer.
+func (d *Daemon) setupAdmission() {
+	if len(d.config.Approvers) == 0 {
+		return
+	}
+	approvals, err := loadApprovals(d.config.InterfaceName, d.config.Approvers)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("[Admission] Failed to load approvals, starting empty: %v", err)
+	}
+
+	a := &d.admission
+	a.mu.Lock()
+	a.enabled = true
+	a.approvals = approvals
+	a.approvers = make(map[string]string)
+	a.pending = make(map[string]*pendingPeer)
+	a.resent = make(map[string]time.Time)
+	own := approvals[d.localNode.WGPubKey]
+	a.mu.Unlock()
+
+	identity := d.localIdentity()
+	switch {
+	case identity != "" && slices.Contains(d.config.Approvers, identity):
+		log.Printf("[Admission] Approval mode enabled; this node is an approver")
+	case own != nil && own.Identity == identity:
+		d.localNode.setApproval(own)
+		log.Printf("[Admission] Approval mode enabled; this node was approved by %s", own.Approver)
+	default:
+		log.Printf("[Admission] Approval mode enabled; peers hold this node back until an approver runs 'wgmesh peers approve %s'", d.localNode.WGPubKey)
+	}
+	d.peerStore.SetAdmitFunc(d.admitPeer)
+}
+
+// localIdentity returns this node's identity key, or "" without one.
+func (d *Daemon) localIdentity() string {
+	if d.localNode == nil || d.localNode.IdentityKey == nil {
+		return ""
+	}
+	return crypto.IdentityPublicKey(d.localNode.IdentityKey)
+}
+
+// admitPeer is the PeerStore's admit func: announcements must pass
+// approval mode, then identity pinning. Approval goes first so unapproved
+// nodes cannot claim pins.
+func (d *Daemon) admitPeer(info *PeerInfo, discoveryMethod string) bool {
+	return d.admitApproved(info, discoveryMethod) && d.admitPinned(info, discoveryMethod)
+}
+
+// admitApproved admits approvers and peers with a valid approval, and
+// holds everyone else in the lobby.
+func (d *Daemon) admitApproved(info *PeerInfo, discoveryMethod string) bool {
+	if d.localNode != nil && info.WGPubKey == d.localNode.WGPubKey {
+		return true
+	}
+
+	a := &d.admission
+	a.mu.Lock()
+	if !a.enabled {
+		a.mu.Unlock()
+		return true
+	}
+	now := time.Now()
+	key := info.WGPubKey
+
+	if approval, ok := a.approvals[key]; ok {
+		// Transitive claims carry no identity; the PeerStore binds it on
+		// the first signed announcement, which must match the approval.
+		if info.Identity != "" && info.Identity != approval.Identity {
+			a.mu.Unlock()
+			return false
+		}
+		// The peer was approved while it was away; hand it its approval
+		// so it can show it to the nodes that missed it.
+		resend := info.Approval == nil && info.Identity != "" && now.Sub(a.resent[key]) >= ApprovalResendInterval
+		if resend {
+			a.resent[key] = now
+		}
+		a.mu.Unlock()
+		if resend {
+			d.sendApproval(info, approval)
+		}
+		return true
+	}
+
+	if id, ok := a.approvers[key]; ok && (info.Identity == "" || info.Identity == id) {
+		a.mu.Unlock()
+		return true
+	}
+	if info.Identity != "" && slices.Contains(d.config.Approvers, info.Identity) {
+		a.approvers[key] = info.Identity
+		delete(a.pending, key)
+		a.mu.Unlock()
+		return true
+	}
+
+	if approval := info.Approval; approval != nil && info.Identity != "" &&
+		approval.WGPubKey == key && approval.Identity == info.Identity &&
+		approval.Verify(d.config.Approvers) == nil {
+		a.approvals[key] = approval
+		delete(a.pending, key)
+		approvals := a.snapshot()
+		a.mu.Unlock()
+		d.saveApprovals(approvals)
+		log.Printf("[Admission] Admitted peer %s... (%s, %s), approved by %s", shortKey(key), info.MeshIP, info.Hostname, approval.Approver)
+		d.recordEvent(EventPeerAdmitted, key, map[string]string{
+			"mesh_ip":  info.MeshIP,
+			"hostname": info.Hostname,
+			"approver": approval.Approver,
+		})
+		return true
+	}
+
+	p, seen := a.pending[key]
+	if !seen {
+		if len(a.pending) >= MaxPendingPeers {
+			a.mu.Unlock()
+			return false
+		}
+		p = &pendingPeer{firstSeen: now}
+		a.pending[key] = p
+	}
+	// Keep the peer's own signed announcement over transitive claims.
+	if info.Identity != "" || p.info.Identity == "" {
+		p.info = *info
+		p.method = discoveryMethod
+	}
+	p.lastSeen = now
+	a.mu.Unlock()
+
+	if !seen {
+		log.Printf("[Admission] Peer %s... (%s, %s) is waiting for approval ('wgmesh peers approve %s')",
+			shortKey(key), info.MeshIP, info.Hostname, key)
+		d.recordEvent(EventPeerPending, key, map[string]string{
+			"mesh_ip":  info.MeshIP,
+			"hostname": info.Hostname,
+		})
+	}
+	return false
+}
+
+// ApprovePeer accepts a peer waiting for approval or, failing that, a
+// peer quarantined by identity pinning.
+func (d *Daemon) ApprovePeer(pubKey string) error {
+	if d.isPending(pubKey) {
+		return d.approvePending(pubKey)
+	}
+	return d.approveQuarantined(pubKey)
+}
+
+// approvePending signs an approval for a peer in the lobby, admits it and
+// sends the approval to it and the rest of the mesh. Only approvers can.
+func (d *Daemon) approvePending(pubKey string) error {
+	if identity := d.localIdentity(); identity == "" || !slices.Contains(d.config.Approvers, identity) {
+		return fmt.Errorf("this node is not an approver: add its identity %s to --approvers", identity)
+	}
+
+	a := &d.admission
+	a.mu.Lock()
+	p, ok := a.pending[pubKey]
+	if !ok {
+		a.mu.Unlock()
+		return fmt.Errorf("peer %s is not pending", pubKey)
+	}
+	if p.info.Identity == "" {
+		a.mu.Unlock()
+		return fmt.Errorf("peer %s has not sent a signed announcement yet", pubKey)
+	}
+	approval, err := crypto.NewAdmissionApproval(d.localNode.IdentityKey, pubKey, p.info.Identity)
+	if err != nil {
+		a.mu.Unlock()
+		return err
+	}
+	a.approvals[pubKey] = approval
+	a.resent[pubKey] = time.Now()
+	delete(a.pending, pubKey)
+	approvals := a.snapshot()
+	a.mu.Unlock()
+
+	d.saveApprovals(approvals)
+	info := p.info
+	d.peerStore.Update(&info, p.method)
+	log.Printf("[Admission] Approved peer %s... (%s, %s)", shortKey(pubKey), info.MeshIP, info.Hostname)
+	d.recordEvent(EventPeerAdmitted, pubKey, map[string]string{
+		"mesh_ip":  info.MeshIP,
+		"hostname": info.Hostname,
+		"approver": approval.Approver,
+	})
+
+	msg := newApprovalMessage(approval)
+	if participant, ok := d.dhtDiscovery.(ApprovalParticipant); ok {
+		participant.SendApproval(&info, msg)
+		participant.BroadcastApproval(msg)
+	}
+	return nil
+}
+
+// handleApprovalMessage records an approval received from a peer, admits
+// the approved node if it is waiting and floods the approval onwards the
+// first time it is seen. An approval of this node is attached to its
+// announcements from then on.
+func (d *Daemon) handleApprovalMessage(msg *crypto.ApprovalMessage) {
+	approval := msg.Approval
+	a := &d.admission
+	a.mu.Lock()
+	if !a.enabled {
+		a.mu.Unlock()
+		return
+	}
+	if err := approval.Verify(d.config.Approvers); err != nil {
+		a.mu.Unlock()
+		log.Printf("[Admission] Rejected approval of %s...: %v", shortKey(approval.WGPubKey), err)
+		return
+	}
+	if prev, ok := a.approvals[approval.WGPubKey]; ok && prev.ApprovedAt >= approval.ApprovedAt {
+		a.mu.Unlock()
+		return
+	}
+	a.approvals[approval.WGPubKey] = approval
+	p, pending := a.pending[approval.WGPubKey]
+	if pending && p.info.Identity != "" && p.info.Identity != approval.Identity {
+		// Someone else announced under the approved key; keep it waiting.
+		pending = false
+	}
+	if pending {
+		delete(a.pending, approval.WGPubKey)
+		a.resent[approval.WGPubKey] = time.Now()
+	}
+	approvals := a.snapshot()
+	a.mu.Unlock()
+	d.saveApprovals(approvals)
+
+	if approval.WGPubKey == d.localNode.WGPubKey {
+		if approval.Identity == d.localIdentity() {
+			d.localNode.setApproval(approval)
+			log.Printf("[Admission] This node was approved by %s", approval.Approver)
+			if r, ok := d.dhtDiscovery.(Reannouncer); ok {
+				r.Reannounce()
+			}
+		}
+	} else if pending {
+		info := p.info
+		d.peerStore.Update(&info, p.method)
+		log.Printf("[Admission] Admitted peer %s... (%s, %s), approved by %s", shortKey(info.WGPubKey), info.MeshIP, info.Hostname, approval.Approver)
+		d.recordEvent(EventPeerAdmitted, info.WGPubKey, map[string]string{
+			"mesh_ip":  info.MeshIP,
+			"hostname": info.Hostname,
+			"approver": approval.Approver,
+		})
+	}
+
+	if participant, ok := d.dhtDiscovery.(ApprovalParticipant); ok {
+		participant.BroadcastApproval(newApprovalMessage(approval))
+	}
+}
+
+// sendApproval hands approval to the approved peer. admitApproved calls it
+// from inside PeerStore.Update, so it must not touch the store.
+func (d *Daemon) sendApproval(peer *PeerInfo, approval *crypto.AdmissionApproval) {
+	participant, ok := d.dhtDiscovery.(ApprovalParticipant)
+	if !ok {
+		return
+	}
+	info := *peer
+	go participant.SendApproval(&info, newApprovalMessage(approval))
+}
+
+// newApprovalMessage wraps approval with a fresh per-hop timestamp so it
+// passes envelope replay checks.
+func newApprovalMessage(approval *crypto.AdmissionApproval) *crypto.ApprovalMessage {
+	return &crypto.ApprovalMessage{
+		Protocol:  crypto.ProtocolVersion,
+		Timestamp: time.Now().Unix(),
+		Approval:  approval,
+	}
+}
+
+// GetRPCPending returns peers waiting for approval for RPC, oldest first.
+func (d *Daemon) GetRPCPending() []*RPCPendingData {
+	a := &d.admission
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	result := make([]*RPCPendingData, 0, len(a.pending))
+	for pubKey, p := range a.pending {
+		result = append(result, &RPCPendingData{
+			PubKey:    pubKey,
+			Hostname:  p.info.Hostname,
+			MeshIP:    p.info.MeshIP,
+			Endpoint:  p.info.Endpoint,
+			Identity:  p.info.Identity,
+			FirstSeen: p.firstSeen,
+			LastSeen:  p.lastSeen,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].FirstSeen.Equal(result[j].FirstSeen) {
+			return result[i].FirstSeen.Before(result[j].FirstSeen)
+		}
+		return result[i].PubKey < result[j].PubKey
+	})
+	return result
+}
+
+// isPending reports whether pubKey is waiting for approval.
+func (d *Daemon) isPending(pubKey string) bool {
+	d.admission.mu.Lock()
+	defer d.admission.mu.Unlock()
+	_, ok := d.admission.pending[pubKey]
+	return ok
+}
+
+// snapshot copies the approvals for saving outside the lock.
+func (a *admissionState) snapshot() map[string]*crypto.AdmissionApproval {
+	out := make(map[string]*crypto.AdmissionApproval, len(a.approvals))
+	for k, v := range a.approvals {
+		out[k] = v
+	}
+	return out
+}
+
+func (d *Daemon) saveApprovals(approvals map[string]*crypto.AdmissionApproval) {
+	data, err := json.MarshalIndent(approvals, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(ApprovalsPath(d.config.InterfaceName), data, 0600)
+	}
+	if err != nil {
+		log.Printf("[Admission] Failed to save approvals: %v", err)
+	}
+}
+
+// loadApprovals reads the saved approvals, dropping any no longer signed by
+// one of approvers. It always returns a usable map.
+func loadApprovals(ifaceName string, approvers []string) (map[string]*crypto.AdmissionApproval, error) {
+	approvals := make(map[string]*crypto.AdmissionApproval)
+	data, err := os.ReadFile(ApprovalsPath(ifaceName))
+	if err != nil {
+		return approvals, err
+	}
+	var saved map[string]*crypto.AdmissionApproval
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return approvals, err
+	}
+	for key, approval := range saved {
+		if approval != nil && approval.WGPubKey == key && approval.Verify(approvers) == nil {
+			approvals[key] = approval
+		}
+	}
+	return approvals, nil
+}
@@ -0,0 +1,247 @@
+package daemon
+
+import (
+	"crypto/ed25519"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+// fakeApprovalDiscovery records approvals sent to peers and flooded.
+type fakeApprovalDiscovery struct {
+	mu          sync.Mutex
+	sent        map[string][]*crypto.ApprovalMessage // by WireGuard key of the receiver
+	broadcasts  []*crypto.ApprovalMessage
+	reannounced int
+}
+
+func (f *fakeApprovalDiscovery) Start() error { return nil }
+func (f *fakeApprovalDiscovery) Stop() error  { return nil }
+
+func (f *fakeApprovalDiscovery) SetApprovalHandler(func(*crypto.ApprovalMessage)) {}
+
+func (f *fakeApprovalDiscovery) BroadcastApproval(msg *crypto.ApprovalMessage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.broadcasts = append(f.broadcasts, msg)
+}
+
+func (f *fakeApprovalDiscovery) SendApproval(peer *PeerInfo, msg *crypto.ApprovalMessage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.sent == nil {
+		f.sent = make(map[string][]*crypto.ApprovalMessage)
+	}
+	f.sent[peer.WGPubKey] = append(f.sent[peer.WGPubKey], msg)
+}
+
+func (f *fakeApprovalDiscovery) Reannounce() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reannounced++
+}
+
+func newIdentityKey(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+	key, err := crypto.GenerateIdentityKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, crypto.IdentityPublicKey(key)
+}
+
+// newAdmissionTestDaemon returns a daemon in approval mode trusting
+// approvers, with localKey as its identity.
+func newAdmissionTestDaemon(t *testing.T, localKey ed25519.PrivateKey, approvers ...string) (*Daemon, *fakeApprovalDiscovery) {
+	t.Helper()
+	d := newMinimalDaemon(t)
+	d.config.Approvers = approvers
+	d.localNode = &LocalNode{WGPubKey: "local-key", MeshIP: "10.0.0.1", Hostname: "local", IdentityKey: localKey}
+	discovery := &fakeApprovalDiscovery{}
+	d.dhtDiscovery = discovery
+	d.setupAdmission()
+	return d, discovery
+}
+
+func TestParseApprovers(t *testing.T) {
+	_, id := newIdentityKey(t)
+	keys, err := parseApprovers(" " + id + ",,")
+	if err != nil || len(keys) != 1 || keys[0] != id {
+		t.Errorf("parseApprovers = %v, %v", keys, err)
+	}
+	if keys, err := parseApprovers(""); err != nil || keys != nil {
+		t.Errorf("empty --approvers = %v, %v; want approval mode off", keys, err)
+	}
+	if _, err := parseApprovers(id + ",bm90LWEta2V5"); err == nil {
+		t.Error("a key that is not 32 bytes should be rejected")
+	}
+}
+
+func TestAdmissionHoldsUnapprovedPeers(t *testing.T) {
+	useTempStateDir(t)
+	approverKey, approverID := newIdentityKey(t)
+	d, discovery := newAdmissionTestDaemon(t, approverKey, approverID)
+	_, newID := newIdentityKey(t)
+
+	d.peerStore.Update(&PeerInfo{WGPubKey: "new-key", MeshIP: "10.0.0.2", Hostname: "laptop", Identity: newID}, "dht")
+	if _, ok := d.peerStore.Get("new-key"); ok {
+		t.Fatal("unapproved peer was admitted")
+	}
+	pending := d.GetRPCPending()
+	if len(pending) != 1 || pending[0].PubKey != "new-key" || pending[0].Identity != newID {
+		t.Fatalf("pending = %+v", pending)
+	}
+	// Re-announcing does not record another event.
+	d.peerStore.Update(&PeerInfo{WGPubKey: "new-key", MeshIP: "10.0.0.2", Hostname: "laptop", Identity: newID}, "lan")
+	if n := len(d.GetRPCEvents(0)); n != 1 {
+		t.Errorf("recorded %d events, want 1", n)
+	}
+
+	if err := d.ApprovePeer("new-key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.peerStore.Get("new-key"); !ok {
+		t.Fatal("approved peer was not admitted")
+	}
+	if len(d.GetRPCPending()) != 0 {
+		t.Error("approved peer is still pending")
+	}
+	if _, err := os.Stat(ApprovalsPath(d.config.InterfaceName)); err != nil {
+		t.Fatalf("approvals were not saved: %v", err)
+	}
+	sent := discovery.sent["new-key"]
+	if len(sent) != 1 || len(discovery.broadcasts) != 1 {
+		t.Fatalf("sent %d approvals to the peer and flooded %d, want 1 and 1", len(sent), len(discovery.broadcasts))
+	}
+	if a := sent[0].Approval; a.Identity != newID || a.Verify([]string{approverID}) != nil {
+		t.Errorf("sent approval does not verify: %+v", a)
+	}
+
+	// Another key announcing under the approved WireGuard key stays out.
+	_, impostorID := newIdentityKey(t)
+	d.peerStore.Remove("new-key")
+	d.peerStore.Update(&PeerInfo{WGPubKey: "new-key", MeshIP: "10.0.0.2", Identity: impostorID}, "dht")
+	if _, ok := d.peerStore.Get("new-key"); ok {
+		t.Error("peer with another identity was admitted under an approved key")
+	}
+
+	if err := d.ApprovePeer("unknown-key"); err == nil {
+		t.Error("approving a peer that is neither pending nor quarantined should fail")
+	}
+}
+
+func TestAdmissionAdmitsApprovers(t *testing.T) {
+	useTempStateDir(t)
+	localKey, _ := newIdentityKey(t)
+	_, approverID := newIdentityKey(t)
+	d, _ := newAdmissionTestDaemon(t, localKey, approverID)
+
+	d.peerStore.Update(&PeerInfo{WGPubKey: "approver-key", MeshIP: "10.0.0.9", Identity: approverID}, "dht")
+	if _, ok := d.peerStore.Get("approver-key"); !ok {
+		t.Fatal("approver node was not admitted")
+	}
+	// Transitive claims about it carry no identity; the PeerStore decides
+	// what they may change.
+	if !d.admitApproved(&PeerInfo{WGPubKey: "approver-key", MeshIP: "10.0.0.9"}, "gossip") {
+		t.Error("transitive claim about an approver was held back")
+	}
+
+	// A node that is not an approver cannot approve.
+	_, newID := newIdentityKey(t)
+	d.peerStore.Update(&PeerInfo{WGPubKey: "new-key", MeshIP: "10.0.0.2", Identity: newID}, "dht")
+	if err := d.ApprovePeer("new-key"); err == nil {
+		t.Error("non-approver node approved a peer")
+	}
+}
+
+func TestAdmissionAcceptsCarriedApproval(t *testing.T) {
+	useTempStateDir(t)
+	localKey, _ := newIdentityKey(t)
+	approverKey, approverID := newIdentityKey(t)
+	d, _ := newAdmissionTestDaemon(t, localKey, approverID)
+	_, newID := newIdentityKey(t)
+
+	forgerKey, _ := newIdentityKey(t)
+	forged, _ := crypto.NewAdmissionApproval(forgerKey, "new-key", newID)
+	d.peerStore.Update(&PeerInfo{WGPubKey: "new-key", MeshIP: "10.0.0.2", Identity: newID, Approval: forged}, "dht")
+	if _, ok := d.peerStore.Get("new-key"); ok {
+		t.Fatal("peer with an approval from an untrusted key was admitted")
+	}
+
+	approval, err := crypto.NewAdmissionApproval(approverKey, "new-key", newID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The approval is bound to the identity it names.
+	_, otherID := newIdentityKey(t)
+	d.peerStore.Update(&PeerInfo{WGPubKey: "new-key", MeshIP: "10.0.0.2", Identity: otherID, Approval: approval}, "dht")
+	if _, ok := d.peerStore.Get("new-key"); ok {
+		t.Fatal("approval was accepted for another identity")
+	}
+
+	d.peerStore.Update(&PeerInfo{WGPubKey: "new-key", MeshIP: "10.0.0.2", Identity: newID, Approval: approval}, "dht")
+	if _, ok := d.peerStore.Get("new-key"); !ok {
+		t.Fatal("peer carrying a valid approval was not admitted")
+	}
+	if len(d.GetRPCPending()) != 0 {
+		t.Error("admitted peer is still pending")
+	}
+
+	// The approval survives a restart.
+	d2, _ := newAdmissionTestDaemon(t, localKey, approverID)
+	d2.peerStore.Update(&PeerInfo{WGPubKey: "new-key", MeshIP: "10.0.0.2"}, "gossip")
+	if _, ok := d2.peerStore.Get("new-key"); !ok {
+		t.Error("saved approval was not loaded")
+	}
+}
+
+func TestHandleApprovalMessage(t *testing.T) {
+	useTempStateDir(t)
+	localKey, localID := newIdentityKey(t)
+	approverKey, approverID := newIdentityKey(t)
+	d, discovery := newAdmissionTestDaemon(t, localKey, approverID)
+	_, newID := newIdentityKey(t)
+
+	d.peerStore.Update(&PeerInfo{WGPubKey: "new-key", MeshIP: "10.0.0.2", Identity: newID}, "dht")
+	approval, _ := crypto.NewAdmissionApproval(approverKey, "new-key", newID)
+	d.handleApprovalMessage(newApprovalMessage(approval))
+	if _, ok := d.peerStore.Get("new-key"); !ok {
+		t.Fatal("pending peer was not admitted on approval")
+	}
+	if len(discovery.broadcasts) != 1 {
+		t.Fatalf("flooded %d approvals, want 1", len(discovery.broadcasts))
+	}
+	d.handleApprovalMessage(newApprovalMessage(approval))
+	if len(discovery.broadcasts) != 1 {
+		t.Error("echoed approval was flooded again")
+	}
+
+	forgerKey, _ := newIdentityKey(t)
+	forged, _ := crypto.NewAdmissionApproval(forgerKey, "other-key", newID)
+	d.handleApprovalMessage(newApprovalMessage(forged))
+	if len(discovery.broadcasts) != 1 {
+		t.Error("forged approval was flooded")
+	}
+
+	own, _ := crypto.NewAdmissionApproval(approverKey, "local-key", localID)
+	d.handleApprovalMessage(newApprovalMessage(own))
+	if d.localNode.approval != own {
+		t.Error("this node's approval is not attached to its announcements")
+	}
+	if discovery.reannounced != 1 {
+		t.Errorf("reannounced %d times after approval, want 1", discovery.reannounced)
+	}
+}
+
+func TestAdmissionDisabled(t *testing.T) {
+	d := newMinimalDaemon(t)
+	d.localNode = &LocalNode{WGPubKey: "local-key"}
+	d.setupAdmission()
+	d.peerStore.Update(&PeerInfo{WGPubKey: "peer-key", MeshIP: "10.0.0.2"}, "dht")
+	if _, ok := d.peerStore.Get("peer-key"); !ok {
+		t.Error("peer was held back without --approvers")
+	}
+	d.handleApprovalMessage(&crypto.ApprovalMessage{Approval: &crypto.AdmissionApproval{WGPubKey: "peer-key"}})
+}
@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+const (
+	// BroadcastLogSize bounds the operator messages kept for mesh.messages.
+	BroadcastLogSize = 64
+
+	EventBroadcast = "broadcast"
+)
+
+// BroadcastParticipant is implemented by discovery layers that can flood
+// operator broadcasts to the mesh.
+type BroadcastParticipant interface {
+	SetBroadcastHandler(handler func(msg *crypto.BroadcastMessage))
+	BroadcastMessage(msg *crypto.BroadcastMessage)
+}
+
+// RPCMessageData describes an operator broadcast for RPC (matches rpc.MessageData)
+type RPCMessageData struct {
+	ID         string
+	PubKey     string
+	Hostname   string
+	Text       string
+	SentAt     time.Time
+	ReceivedAt time.Time
+}
+
+// broadcastState remembers recent broadcasts. The zero value is ready to use.
+type broadcastState struct {
+	mu       sync.Mutex
+	seen     map[string]time.Time // message ID -> signing time, for dedup
+	messages []RPCMessageData     // oldest first, at most BroadcastLogSize
+}
+
+// SendBroadcast signs text with the membership key and floods it to every
+// node in the mesh.
+func (d *Daemon) SendBroadcast(text string) (*RPCMessageData, error) {
+	participant, ok := d.dhtDiscovery.(BroadcastParticipant)
+	if !ok {
+		return nil, fmt.Errorf("broadcasts require DHT discovery")
+	}
+	msg, err := crypto.NewBroadcastMessage(d.config.Keys.MembershipKey[:], d.localNode.WGPubKey, d.localNode.Hostname, text)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := d.acceptBroadcast(msg, time.Now())
+	participant.BroadcastMessage(msg)
+	return data, nil
+}
+
+// handleBroadcastMessage records a broadcast received from a peer and
+// floods it onwards the first time it is seen.
+func (d *Daemon) handleBroadcastMessage(msg *crypto.BroadcastMessage) {
+	if err := msg.Verify(d.config.Keys.MembershipKey[:]); err != nil {
+		log.Printf("[Broadcast] Rejected message: %v", err)
+		return
+	}
+	if _, fresh := d.acceptBroadcast(msg, time.Now()); !fresh {
+		return
+	}
+	if participant, ok := d.dhtDiscovery.(BroadcastParticipant); ok {
+		next := *msg
+		next.Timestamp = time.Now().Unix()
+		participant.BroadcastMessage(&next)
+	}
+}
+
+// acceptBroadcast stores msg unless its ID was seen before. Both sent and
+// received messages are logged and recorded as events.
+func (d *Daemon) acceptBroadcast(msg *crypto.BroadcastMessage, now time.Time) (*RPCMessageData, bool) {
+	b := &d.broadcasts
+	b.mu.Lock()
+	if _, ok := b.seen[msg.ID]; ok {
+		b.mu.Unlock()
+		return nil, false
+	}
+	if b.seen == nil {
+		b.seen = make(map[string]time.Time)
+	}
+	// Messages past the window fail Verify, so their IDs can go.
+	for id, sentAt := range b.seen {
+		if now.Sub(sentAt) > 2*crypto.BroadcastWindow {
+			delete(b.seen, id)
+		}
+	}
+	b.seen[msg.ID] = time.Unix(msg.SentAt, 0)
+
+	data := RPCMessageData{
+		ID:         msg.ID,
+		PubKey:     msg.Sender,
+		Hostname:   msg.Hostname,
+		Text:       msg.Text,
+		SentAt:     time.Unix(msg.SentAt, 0),
+		ReceivedAt: now,
+	}
+	b.messages = append(b.messages, data)
+	if len(b.messages) > BroadcastLogSize {
+		b.messages = b.messages[len(b.messages)-BroadcastLogSize:]
+	}
+	b.mu.Unlock()
+
+	from := msg.Hostname
+	if from == "" {
+		from = shortKey(msg.Sender)
+	}
+	log.Printf("[Broadcast] Message from %s: %s", from, msg.Text)
+	d.recordEvent(EventBroadcast, msg.Sender, map[string]string{
+		"id":       msg.ID,
+		"hostname": msg.Hostname,
+		"text":     msg.Text,
+	})
+	return &data, true
+}
+
+// GetRPCMessages returns the recent operator broadcasts, oldest first.
+func (d *Daemon) GetRPCMessages() []*RPCMessageData {
+	d.broadcasts.mu.Lock()
+	defer d.broadcasts.mu.Unlock()
+	result := make([]*RPCMessageData, len(d.broadcasts.messages))
+	for i := range d.broadcasts.messages {
+		msg := d.broadcasts.messages[i]
+		result[i] = &msg
+	}
+	return result
+}
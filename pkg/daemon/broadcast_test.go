@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+// fakeBroadcastDiscovery records flooded broadcasts.
+type fakeBroadcastDiscovery struct{ sent []*crypto.BroadcastMessage }
+
+func (f *fakeBroadcastDiscovery) Start() error { return nil }
+func (f *fakeBroadcastDiscovery) Stop() error  { return nil }
+
+func (f *fakeBroadcastDiscovery) SetBroadcastHandler(func(*crypto.BroadcastMessage)) {}
+
+func (f *fakeBroadcastDiscovery) BroadcastMessage(msg *crypto.BroadcastMessage) {
+	f.sent = append(f.sent, msg)
+}
+
+func TestSendBroadcast(t *testing.T) {
+	d := newMinimalDaemon(t)
+	d.localNode = &LocalNode{WGPubKey: "local-pubkey", Hostname: "ops1"}
+	if _, err := d.SendBroadcast("maintenance at 22:00"); err == nil {
+		t.Error("broadcast without DHT discovery should fail")
+	}
+
+	discovery := &fakeBroadcastDiscovery{}
+	d.dhtDiscovery = discovery
+	if _, err := d.SendBroadcast(""); err == nil {
+		t.Error("empty broadcast should be rejected")
+	}
+	sent, err := d.SendBroadcast("maintenance at 22:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discovery.sent) != 1 || discovery.sent[0].ID != sent.ID {
+		t.Fatalf("flooded %d messages, want the sent one", len(discovery.sent))
+	}
+	if err := discovery.sent[0].Verify(d.config.Keys.MembershipKey[:]); err != nil {
+		t.Errorf("flooded message does not verify: %v", err)
+	}
+	if msgs := d.GetRPCMessages(); len(msgs) != 1 || msgs[0].Hostname != "ops1" {
+		t.Errorf("own broadcast not listed: %+v", msgs)
+	}
+
+	// Peers flood it back; it is not accepted or flooded twice.
+	d.handleBroadcastMessage(discovery.sent[0])
+	if len(discovery.sent) != 1 || len(d.GetRPCMessages()) != 1 {
+		t.Error("echoed broadcast was handled again")
+	}
+}
+
+func TestHandleBroadcastMessage(t *testing.T) {
+	d := newMinimalDaemon(t)
+	d.localNode = &LocalNode{WGPubKey: "local-pubkey"}
+	discovery := &fakeBroadcastDiscovery{}
+	d.dhtDiscovery = discovery
+
+	forged, _ := crypto.NewBroadcastMessage([]byte("another-mesh-membership-key-32b!"), "peer-pubkey", "ops2", "rotate now")
+	d.handleBroadcastMessage(forged)
+	if len(d.GetRPCMessages()) != 0 || len(discovery.sent) != 0 {
+		t.Fatal("message signed outside the mesh was accepted")
+	}
+
+	msg, _ := crypto.NewBroadcastMessage(d.config.Keys.MembershipKey[:], "peer-pubkey", "ops2", "rotating secrets at 10:00 UTC")
+	d.handleBroadcastMessage(msg)
+	msgs := d.GetRPCMessages()
+	if len(msgs) != 1 || msgs[0].Text != msg.Text || msgs[0].PubKey != "peer-pubkey" {
+		t.Fatalf("messages = %+v", msgs)
+	}
+	if len(discovery.sent) != 1 || discovery.sent[0].ID != msg.ID {
+		t.Error("received broadcast should be flooded onwards once")
+	}
+
+	var events []RPCEventData
+	for _, ev := range d.events.since(0) {
+		if ev.Type == EventBroadcast {
+			events = append(events, ev)
+		}
+	}
+	if len(events) != 1 || events[0].Details["text"] != msg.Text || events[0].PubKey != "peer-pubkey" {
+		t.Errorf("broadcast events = %+v", events)
+	}
+}
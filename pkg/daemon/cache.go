@@ -1,13 +1,19 @@
 package daemon
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 )
 
 const (
@@ -25,7 +31,20 @@ type PeerCacheEntry struct {
 	Introducer       bool     `json:"introducer,omitempty"`
 	RoutableNetworks []string `json:"routable_networks,omitempty"`
 	NATType          string   `json:"nat_type,omitempty"`
+	Candidates       []string `json:"candidates,omitempty"`
+	Identity         string   `json:"identity,omitempty"`
+	TCPPort          int      `json:"tcp_port,omitempty"`
+	ControlEndpoint  string   `json:"control_endpoint,omitempty"`
+	PairPSK          bool     `json:"pair_psk,omitempty"`
 	LastSeen         int64    `json:"last_seen"`
+
+	Tags map[string]string  `json:"tags,omitempty"`
+	DNS  []crypto.DNSDomain `json:"dns,omitempty"`
+}
+
+// Expired reports whether the entry is too old to restore.
+func (e PeerCacheEntry) Expired(now time.Time) bool {
+	return now.Sub(time.Unix(e.LastSeen, 0)) > CacheExpiration
 }
 
 // PeerCache manages persistent peer storage
@@ -34,25 +53,102 @@ type PeerCache struct {
 	UpdatedAt int64            `json:"updated_at"`
 }
 
+// CacheVersion is the cache format SavePeerCache writes. Version 0 is the
+// bare PeerCache written before the cache had a header.
+const CacheVersion = 1
+
+// peerCacheFile is the cache on disk: the PeerCache as payload, with its
+// format version and a SHA-256 of the compacted payload so that a
+// truncated or corrupted file is refused rather than half restored.
+type peerCacheFile struct {
+	Version  int             `json:"version"`
+	Checksum string          `json:"checksum"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// cacheMigrations[v] turns a version v payload into a version v+1 one.
+// Append one whenever PeerCache changes in a way older fields cannot
+// express, and bump CacheVersion.
+var cacheMigrations = []func(json.RawMessage) (json.RawMessage, error){
+	// 0 → 1 only added the header around the same payload.
+	func(payload json.RawMessage) (json.RawMessage, error) { return payload, nil },
+}
+
+func cacheChecksum(payload []byte) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, payload); err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// encodePeerCache renders cache in the current format.
+func encodePeerCache(cache *PeerCache) ([]byte, error) {
+	payload, err := json.Marshal(cache)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(peerCacheFile{
+		Version:  CacheVersion,
+		Checksum: cacheChecksum(payload),
+		Payload:  payload,
+	}, "", "  ")
+}
+
+// decodePeerCache parses a cache file of any known version, migrating it
+// to the current one, and returns the version it was written in.
+func decodePeerCache(data []byte) (*PeerCache, int, error) {
+	var file peerCacheFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, 0, err
+	}
+	payload := file.Payload
+	switch {
+	case file.Version == 0:
+		payload = data
+	case file.Version > CacheVersion:
+		return nil, file.Version, fmt.Errorf("format version %d is newer than this wgmesh reads (%d)", file.Version, CacheVersion)
+	case file.Checksum != cacheChecksum(payload):
+		return nil, file.Version, errors.New("checksum mismatch")
+	}
+	for v := file.Version; v < CacheVersion; v++ {
+		var err error
+		if payload, err = cacheMigrations[v](payload); err != nil {
+			return nil, file.Version, fmt.Errorf("failed to migrate from version %d: %w", v, err)
+		}
+	}
+
+	var cache PeerCache
+	if err := json.Unmarshal(payload, &cache); err != nil {
+		return nil, file.Version, err
+	}
+	return &cache, file.Version, nil
+}
+
 // CacheFilePath returns the path for the peer cache file
 func CacheFilePath(interfaceName string) string {
-	return filepath.Join("/var/lib/wgmesh", fmt.Sprintf("%s-peers.json", interfaceName))
+	return filepath.Join(stateDir, fmt.Sprintf("%s-peers.json", interfaceName))
 }
 
 // LoadPeerCache loads the peer cache from disk
 func LoadPeerCache(interfaceName string) (*PeerCache, error) {
-	path := CacheFilePath(interfaceName)
+	cache, _, err := ReadPeerCacheFile(CacheFilePath(interfaceName))
+	return cache, err
+}
+
+// ReadPeerCacheFile loads a peer cache file, migrating older formats, and
+// returns the format version it was written in.
+func ReadPeerCacheFile(path string) (*PeerCache, int, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-
-	var cache PeerCache
-	if err := json.Unmarshal(data, &cache); err != nil {
-		return nil, fmt.Errorf("failed to parse peer cache: %w", err)
+	cache, version, err := decodePeerCache(data)
+	if err != nil {
+		return nil, version, fmt.Errorf("failed to parse peer cache: %w", err)
 	}
-
-	return &cache, nil
+	return cache, version, nil
 }
 
 // SavePeerCache saves the peer cache to disk
@@ -72,27 +168,27 @@ func SavePeerCache(interfaceName string, peerStore *PeerStore) error {
 			Introducer:       p.Introducer,
 			RoutableNetworks: p.RoutableNetworks,
 			NATType:          p.NATType,
+			Candidates:       p.Candidates,
+			Identity:         p.Identity,
+			TCPPort:          p.TCPPort,
+			ControlEndpoint:  p.ControlEndpoint,
+			PairPSK:          p.PairPSK,
 			LastSeen:         p.LastSeen.Unix(),
+			Tags:             p.Tags,
+			DNS:              p.DNS,
 		})
 	}
 
-	data, err := json.MarshalIndent(cache, "", "  ")
+	data, err := encodePeerCache(cache)
 	if err != nil {
 		return fmt.Errorf("failed to marshal peer cache: %w", err)
 	}
-
-	path := CacheFilePath(interfaceName)
-	dir := filepath.Dir(path)
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return fmt.Errorf("failed to create cache directory: %w", err)
-	}
-
-	return os.WriteFile(path, data, 0600)
+	return writeFileAtomic(CacheFilePath(interfaceName), data, 0600)
 }
 
 // RestoreFromCache restores peers from the cache into the peer store
 func RestoreFromCache(interfaceName string, peerStore *PeerStore) int {
-	cache, err := LoadPeerCache(interfaceName)
+	cache, version, err := ReadPeerCacheFile(CacheFilePath(interfaceName))
 	if err != nil {
 		if !os.IsNotExist(err) {
 			log.Printf("[Cache] Failed to load peer cache: %v", err)
@@ -104,12 +200,10 @@ func RestoreFromCache(interfaceName string, peerStore *PeerStore) int {
 	restored := 0
 
 	for _, entry := range cache.Peers {
-		lastSeen := time.Unix(entry.LastSeen, 0)
-
-		// Skip expired entries
-		if now.Sub(lastSeen) > CacheExpiration {
+		if entry.Expired(now) {
 			continue
 		}
+		lastSeen := time.Unix(entry.LastSeen, 0)
 
 		peer := &PeerInfo{
 			WGPubKey:         entry.WGPubKey,
@@ -120,7 +214,14 @@ func RestoreFromCache(interfaceName string, peerStore *PeerStore) int {
 			Introducer:       entry.Introducer,
 			RoutableNetworks: entry.RoutableNetworks,
 			NATType:          entry.NATType,
+			Candidates:       entry.Candidates,
+			Identity:         entry.Identity,
+			TCPPort:          entry.TCPPort,
+			ControlEndpoint:  entry.ControlEndpoint,
+			PairPSK:          entry.PairPSK,
 			LastSeen:         lastSeen,
+			Tags:             entry.Tags,
+			DNS:              entry.DNS,
 		}
 
 		peerStore.Update(peer, "cache")
@@ -130,6 +231,9 @@ func RestoreFromCache(interfaceName string, peerStore *PeerStore) int {
 	if restored > 0 {
 		log.Printf("[Cache] Restored %d peers from cache", restored)
 	}
+	if version < CacheVersion {
+		log.Printf("[Cache] Migrated the peer cache from format version %d to %d", version, CacheVersion)
+	}
 
 	return restored
 }
@@ -2,8 +2,10 @@ package daemon
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -104,3 +106,80 @@ func TestCacheExpiration(t *testing.T) {
 		t.Errorf("Expected 1 active peer, got %d", len(active))
 	}
 }
+
+func TestPeerCacheKeepsControlEndpoint(t *testing.T) {
+	useTempStateDir(t)
+
+	ps := NewPeerStore()
+	ps.Update(&PeerInfo{WGPubKey: "pubkey1", MeshIP: "10.0.0.1", Endpoint: "1.2.3.4:51820"}, "test")
+	ps.SetControlEndpoint("pubkey1", "1.2.3.4:51821")
+	if err := SavePeerCache("test-wg0", ps); err != nil {
+		t.Fatalf("SavePeerCache: %v", err)
+	}
+
+	restored := NewPeerStore()
+	if n := RestoreFromCache("test-wg0", restored); n != 1 {
+		t.Fatalf("restored %d peers, want 1", n)
+	}
+	p, ok := restored.Get("pubkey1")
+	if !ok || p.ControlEndpoint != "1.2.3.4:51821" {
+		t.Errorf("restored peer = %+v, want control endpoint 1.2.3.4:51821", p)
+	}
+}
+
+func TestPeerCacheFormat(t *testing.T) {
+	useTempStateDir(t)
+	path := CacheFilePath("test-wg0")
+
+	ps := NewPeerStore()
+	ps.Update(&PeerInfo{WGPubKey: "pubkey1", MeshIP: "10.0.0.1", Endpoint: "1.2.3.4:51820"}, "test")
+	if err := SavePeerCache("test-wg0", ps); err != nil {
+		t.Fatalf("SavePeerCache: %v", err)
+	}
+	cache, version, err := ReadPeerCacheFile(path)
+	if err != nil || version != CacheVersion || len(cache.Peers) != 1 {
+		t.Fatalf("ReadPeerCacheFile = %+v, %d, %v; want one peer at version %d", cache, version, err, CacheVersion)
+	}
+	if matches, _ := filepath.Glob(path + ".*"); len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+
+	// A byte flipped inside the payload is caught.
+	data, _ := os.ReadFile(path)
+	corrupt := strings.Replace(string(data), "10.0.0.1", "10.0.0.9", 1)
+	if err := os.WriteFile(path, []byte(corrupt), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadPeerCacheFile(path); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("corrupted cache: err = %v, want a checksum mismatch", err)
+	}
+	if n := RestoreFromCache("test-wg0", NewPeerStore()); n != 0 {
+		t.Errorf("restored %d peers from a corrupted cache", n)
+	}
+
+	newer := `{"version": 99, "checksum": "", "payload": {}}`
+	if err := os.WriteFile(path, []byte(newer), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, version, err := ReadPeerCacheFile(path); err == nil || version != 99 {
+		t.Errorf("newer cache: version %d, err %v; want it refused", version, err)
+	}
+}
+
+func TestPeerCacheMigratesUnversioned(t *testing.T) {
+	useTempStateDir(t)
+	legacy := fmt.Sprintf(`{"peers": [{"wg_pubkey": "pubkey1", "mesh_ip": "10.0.0.1", "endpoint": "1.2.3.4:51820", "last_seen": %d}], "updated_at": %d}`,
+		time.Now().Unix(), time.Now().Unix())
+	if err := os.WriteFile(CacheFilePath("test-wg0"), []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, version, err := ReadPeerCacheFile(CacheFilePath("test-wg0"))
+	if err != nil || version != 0 || len(cache.Peers) != 1 || cache.Peers[0].MeshIP != "10.0.0.1" {
+		t.Fatalf("ReadPeerCacheFile = %+v, %d, %v; want the legacy peer at version 0", cache, version, err)
+	}
+	restored := NewPeerStore()
+	if n := RestoreFromCache("test-wg0", restored); n != 1 {
+		t.Fatalf("restored %d peers, want 1", n)
+	}
+}
@@ -0,0 +1,168 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// CentralStateRefresh is how often a --central-state source is re-read.
+	// Listed nodes are refreshed each time, so they stay in the peer store
+	// for as long as the source lists them.
+	CentralStateRefresh = time.Minute
+
+	// centralStateMaxSize caps how much of a central state file is read.
+	centralStateMaxSize = 8 << 20
+)
+
+// centralState is the part of a centralized-mode mesh-state.json that a
+// daemon needs. Private keys and SSH details are not read.
+type centralState struct {
+	Nodes map[string]centralNode `json:"nodes"`
+}
+
+type centralNode struct {
+	Hostname         string   `json:"hostname"`
+	MeshIP           string   `json:"mesh_ip"`
+	PublicKey        string   `json:"public_key"`
+	PublicEndpoint   string   `json:"public_endpoint"`
+	RoutableNetworks []string `json:"routable_networks"`
+	PairPSK          bool     `json:"pair_psk,omitempty"` // runs the daemon with per-pair preshared keys
+}
+
+// validateCentralStateSource accepts "", a file path, or an http(s) URL.
+func validateCentralStateSource(src string) error {
+	if src == "" || !strings.Contains(src, "://") {
+		return nil
+	}
+	u, err := url.Parse(src)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q (want a path or an http(s) URL)", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", src)
+	}
+	return nil
+}
+
+// fetchCentralState reads and parses a central state file or URL.
+func fetchCentralState(ctx context.Context, src string) (*centralState, error) {
+	var r io.Reader
+	if strings.Contains(src, "://") {
+		ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("GET %s: %s", src, resp.Status)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, centralStateMaxSize))
+	if err != nil {
+		return nil, err
+	}
+	var state centralState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("not a plain mesh-state.json (encrypted state files are not supported): %w", err)
+	}
+	return &state, nil
+}
+
+// mergeCentralState adds the nodes of a central state to the peer store as
+// static peers. Nodes outside this mesh's subnet are skipped: the daemon
+// only routes its own subnet. A node's pair_psk can only be confirmed here:
+// discovery runs alongside, and a state file written before the flag must
+// not switch a peer that announced it back to the mesh key. It returns how
+// many were merged and skipped.
+func (d *Daemon) mergeCentralState(state *centralState) (merged, skipped int) {
+	for name, n := range state.Nodes {
+		if n.PublicKey == "" || n.PublicKey == d.localNode.WGPubKey || !meshIPInSubnet(n.MeshIP, d.config) {
+			skipped++
+			continue
+		}
+		hostname := n.Hostname
+		if hostname == "" {
+			hostname = name
+		}
+		pairPSK := n.PairPSK
+		if known, ok := d.peerStore.Get(n.PublicKey); ok && known.PairPSK {
+			pairPSK = true
+		}
+		d.peerStore.Update(&PeerInfo{
+			WGPubKey:         n.PublicKey,
+			Hostname:         hostname,
+			MeshIP:           n.MeshIP,
+			Endpoint:         n.PublicEndpoint,
+			RoutableNetworks: n.RoutableNetworks,
+			RoutesAnnounced:  true,
+			PairPSK:          pairPSK,
+		}, StaticMethod)
+		merged++
+	}
+	return merged, skipped
+}
+
+// centralStateLoop merges the --central-state source into the peer store
+// now and every CentralStateRefresh. A source that cannot be read leaves
+// the peers already merged to expire as usual.
+func (d *Daemon) centralStateLoop() {
+	src := d.config.CentralState
+	lastErr := ""
+	lastMerged, lastSkipped := -1, -1
+
+	refresh := func() {
+		state, err := fetchCentralState(d.ctx, src)
+		if err != nil {
+			if msg := err.Error(); msg != lastErr {
+				log.Printf("[Static] Failed to read central state %s: %v", src, err)
+				lastErr = msg
+			}
+			return
+		}
+		lastErr = ""
+		merged, skipped := d.mergeCentralState(state)
+		if merged != lastMerged || skipped != lastSkipped {
+			log.Printf("[Static] Merged %d peers from central state %s (%d skipped: own key or outside the mesh subnet)", merged, src, skipped)
+			lastMerged, lastSkipped = merged, skipped
+		}
+	}
+
+	refresh()
+	ticker := time.NewTicker(CentralStateRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package daemon
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testCentralState = `{
+  "interface_name": "wg0",
+  "network": "10.0.0.0/16",
+  "nodes": {
+    "web1": {"hostname": "web1", "mesh_ip": "10.0.1.1", "public_key": "web1Key", "private_key": "secret",
+             "ssh_host": "203.0.113.1", "public_endpoint": "203.0.113.1:51820", "routable_networks": ["192.168.5.0/24"]},
+    "db1":  {"mesh_ip": "10.0.1.2", "public_key": "db1Key", "behind_nat": true},
+    "self": {"hostname": "self", "mesh_ip": "10.0.1.3", "public_key": "selfKey"},
+    "far":  {"hostname": "far", "mesh_ip": "172.16.0.1", "public_key": "farKey"}
+  }
+}`
+
+func TestValidateCentralStateSource(t *testing.T) {
+	for _, ok := range []string{"", "/etc/wgmesh/mesh-state.json", "https://ops.example.com/mesh-state.json", "http://10.0.0.1:8080/state"} {
+		if err := validateCentralStateSource(ok); err != nil {
+			t.Errorf("validateCentralStateSource(%q) = %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"ftp://example.com/state.json", "https://"} {
+		if err := validateCentralStateSource(bad); err == nil {
+			t.Errorf("validateCentralStateSource(%q) should fail", bad)
+		}
+	}
+}
+
+func TestMergeCentralState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mesh-state.json")
+	if err := os.WriteFile(path, []byte(testCentralState), 0600); err != nil {
+		t.Fatal(err)
+	}
+	state, err := fetchCentralState(context.Background(), path)
+	if err != nil {
+		t.Fatalf("fetchCentralState failed: %v", err)
+	}
+
+	d := newStateTestDaemon(t, testConfigSecret, "selfKey")
+	merged, skipped := d.mergeCentralState(state)
+	if merged != 2 || skipped != 2 {
+		t.Errorf("mergeCentralState = %d merged, %d skipped; want 2, 2", merged, skipped)
+	}
+
+	web1, ok := d.peerStore.Get("web1Key")
+	if !ok {
+		t.Fatal("web1 not merged")
+	}
+	if web1.Endpoint != "203.0.113.1:51820" || web1.MeshIP != "10.0.1.1" || len(web1.RoutableNetworks) != 1 {
+		t.Errorf("web1 = %+v", web1)
+	}
+	if web1.DiscoveredVia[0] != StaticMethod {
+		t.Errorf("DiscoveredVia = %v, want %s", web1.DiscoveredVia, StaticMethod)
+	}
+	if db1, ok := d.peerStore.Get("db1Key"); !ok || db1.Hostname != "db1" || db1.Endpoint != "" {
+		t.Errorf("db1 should be merged under its map key without endpoint, got %+v", db1)
+	}
+}
+
+func TestFetchCentralStateURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mesh-state.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(testCentralState))
+	}))
+	defer srv.Close()
+
+	state, err := fetchCentralState(context.Background(), srv.URL+"/mesh-state.json")
+	if err != nil {
+		t.Fatalf("fetchCentralState failed: %v", err)
+	}
+	if len(state.Nodes) != 4 {
+		t.Errorf("got %d nodes, want 4", len(state.Nodes))
+	}
+
+	if _, err := fetchCentralState(context.Background(), srv.URL+"/missing"); err == nil {
+		t.Error("expected error for 404")
+	}
+
+	encrypted := filepath.Join(t.TempDir(), "enc.json")
+	os.WriteFile(encrypted, []byte("c2VjcmV0IGRhdGE="), 0600)
+	if _, err := fetchCentralState(context.Background(), encrypted); err == nil {
+		t.Error("expected error for an encrypted state file")
+	}
+}
@@ -0,0 +1,124 @@
+package daemon
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Chaos injects faults into a running daemon so that the health monitor,
+// backoff and relay fallback can be exercised on purpose. It is enabled by
+// the hidden --chaos flag and must never be used on a production mesh.
+//
+// A nil *Chaos injects nothing, so call sites need no checks of their own.
+type Chaos struct {
+	DropExchangeRate float64       // Fraction of received exchange packets to drop
+	STUNDelay        time.Duration // Delay before the embedded STUN responder answers
+	FailProbeRate    float64       // Fraction of mesh probes to fail without sending
+	Seed             int64
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// ParseChaos parses a comma-separated fault spec such as
+// "drop-exchange=0.2,stun-delay=2s,fail-probe=0.5,seed=42". Rates are in
+// [0, 1]. The same seed replays the same sequence of decisions. An empty
+// spec returns nil.
+func ParseChaos(spec string) (*Chaos, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	c := &Chaos{Seed: time.Now().UnixNano()}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not key=value", item)
+		}
+		var err error
+		switch key {
+		case "drop-exchange":
+			c.DropExchangeRate, err = parseChaosRate(value)
+		case "fail-probe":
+			c.FailProbeRate, err = parseChaosRate(value)
+		case "stun-delay":
+			c.STUNDelay, err = time.ParseDuration(value)
+			if err == nil && c.STUNDelay < 0 {
+				err = fmt.Errorf("must not be negative")
+			}
+		case "seed":
+			c.Seed, err = strconv.ParseInt(value, 10, 64)
+		default:
+			return nil, fmt.Errorf("unknown fault %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return c, nil
+}
+
+func parseChaosRate(s string) (float64, error) {
+	rate, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("rate %v is outside [0, 1]", rate)
+	}
+	return rate, nil
+}
+
+// String formats the faults in the spec syntax, for the startup warning.
+func (c *Chaos) String() string {
+	if c == nil {
+		return ""
+	}
+	return fmt.Sprintf("drop-exchange=%v,stun-delay=%s,fail-probe=%v,seed=%d",
+		c.DropExchangeRate, c.STUNDelay, c.FailProbeRate, c.Seed)
+}
+
+// DropExchange reports whether to drop the exchange packet just received.
+func (c *Chaos) DropExchange() bool {
+	if c == nil {
+		return false
+	}
+	return c.roll(c.DropExchangeRate)
+}
+
+// FailProbe reports whether to fail the mesh probe about to be sent.
+func (c *Chaos) FailProbe() bool {
+	if c == nil {
+		return false
+	}
+	return c.roll(c.FailProbeRate)
+}
+
+// STUNResponseDelay returns how long the STUN responder waits before answering.
+func (c *Chaos) STUNResponseDelay() time.Duration {
+	if c == nil {
+		return 0
+	}
+	return c.STUNDelay
+}
+
+func (c *Chaos) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rng == nil {
+		c.rng = rand.New(rand.NewSource(c.Seed))
+	}
+	return c.rng.Float64() < rate
+}
@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseChaos(t *testing.T) {
+	c, err := ParseChaos("drop-exchange=0.2, stun-delay=2s,fail-probe=1,seed=42")
+	if err != nil {
+		t.Fatalf("ParseChaos failed: %v", err)
+	}
+	if c.DropExchangeRate != 0.2 || c.STUNResponseDelay() != 2*time.Second || c.FailProbeRate != 1 || c.Seed != 42 {
+		t.Fatalf("unexpected faults: %s", c)
+	}
+	if !c.FailProbe() {
+		t.Error("fail-probe=1 must fail every probe")
+	}
+
+	if c, err := ParseChaos(""); c != nil || err != nil {
+		t.Errorf("empty spec: got %v, %v; want nil, nil", c, err)
+	}
+	for _, spec := range []string{"drop-exchange", "drop-exchange=1.5", "fail-probe=-0.1", "stun-delay=-1s", "seed=x", "flood=1"} {
+		if _, err := ParseChaos(spec); err == nil {
+			t.Errorf("ParseChaos(%q) should fail", spec)
+		}
+	}
+}
+
+func TestChaosSeedIsDeterministic(t *testing.T) {
+	a, _ := ParseChaos("drop-exchange=0.5,seed=7")
+	b, _ := ParseChaos("drop-exchange=0.5,seed=7")
+	drops := 0
+	for i := 0; i < 200; i++ {
+		da, db := a.DropExchange(), b.DropExchange()
+		if da != db {
+			t.Fatalf("decision %d differs between runs with the same seed", i)
+		}
+		if da {
+			drops++
+		}
+	}
+	if drops < 60 || drops > 140 {
+		t.Errorf("dropped %d of 200 at rate 0.5", drops)
+	}
+}
+
+func TestNilChaosInjectsNothing(t *testing.T) {
+	var c *Chaos
+	if c.DropExchange() || c.FailProbe() || c.STUNResponseDelay() != 0 {
+		t.Error("nil Chaos must not inject faults")
+	}
+}
@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+// Peers stamp every discovery message with their clock. Comparing those
+// stamps with the local clock on receipt gives one offset per sender; the
+// median over the senders heard from recently is the local clock's skew
+// from the mesh. Once it is significant, timestamp checks are widened by
+// it (see crypto.SetClockSkew), and past ClockSkewWarnThreshold the daemon
+// warns, since the usual cause is a host without working NTP.
+const (
+	ClockSkewInterval      = time.Minute
+	ClockSkewSampleTTL     = 30 * time.Minute
+	ClockSkewMinSources    = 2
+	ClockSkewSignificant   = 5 * time.Second // timestamps are whole seconds; below this is noise
+	ClockSkewWarnThreshold = 30 * time.Second
+)
+
+// EventClockSkew is recorded when the estimated skew crosses
+// ClockSkewWarnThreshold in either direction.
+const EventClockSkew = "clock_skew"
+
+type clockSample struct {
+	offset time.Duration // receive time minus send time
+	at     time.Time
+}
+
+// ClockSkewEstimator keeps the latest clock offset seen from each source.
+type ClockSkewEstimator struct {
+	mu      sync.Mutex
+	samples map[string]clockSample
+}
+
+// NewClockSkewEstimator creates an empty estimator.
+func NewClockSkewEstimator() *ClockSkewEstimator {
+	return &ClockSkewEstimator{samples: make(map[string]clockSample)}
+}
+
+// Observe records a message from source stamped sentAt and received at
+// receivedAt by the local clock.
+func (e *ClockSkewEstimator) Observe(source string, sentAt, receivedAt time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.samples[source] = clockSample{offset: receivedAt.Sub(sentAt), at: receivedAt}
+}
+
+// Estimate returns the median offset of the sources heard from within
+// ClockSkewSampleTTL of now, and how many there were. Older samples are
+// dropped.
+func (e *ClockSkewEstimator) Estimate(now time.Time) (skew time.Duration, sources int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	offsets := make([]time.Duration, 0, len(e.samples))
+	for source, s := range e.samples {
+		if now.Sub(s.at) > ClockSkewSampleTTL {
+			delete(e.samples, source)
+			continue
+		}
+		offsets = append(offsets, s.offset)
+	}
+	if len(offsets) == 0 {
+		return 0, 0
+	}
+	slices.Sort(offsets)
+	mid := len(offsets) / 2
+	if len(offsets)%2 == 1 {
+		return offsets[mid], len(offsets)
+	}
+	return (offsets[mid-1] + offsets[mid]) / 2, len(offsets)
+}
+
+// peerClocks collects the samples of every discovery layer in the process.
+var peerClocks = NewClockSkewEstimator()
+
+// ObservePeerClock records the timestamp of a message that source (the
+// sender's IP) sent, for the clock skew estimate.
+func ObservePeerClock(source string, sentAt time.Time) {
+	peerClocks.Observe(source, sentAt, time.Now())
+}
+
+// clockSkewState is the daemon's current view of the local clock.
+type clockSkewState struct {
+	mu      sync.Mutex
+	skew    time.Duration // applied skew; 0 while not significant
+	sources int
+	warned  bool
+}
+
+// clockSkewLoop re-estimates the clock skew periodically.
+func (d *Daemon) clockSkewLoop() {
+	ticker := time.NewTicker(ClockSkewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			crypto.SetClockSkew(0)
+			return
+		case <-ticker.C:
+			d.updateClockSkew(peerClocks, time.Now())
+		}
+	}
+}
+
+// updateClockSkew applies the estimate of e and warns when it crosses
+// ClockSkewWarnThreshold.
+func (d *Daemon) updateClockSkew(e *ClockSkewEstimator, now time.Time) {
+	skew, sources := e.Estimate(now)
+	if sources < ClockSkewMinSources || skew.Abs() < ClockSkewSignificant {
+		skew = 0
+	}
+	crypto.SetClockSkew(skew)
+	clockSkewSeconds.Set(skew.Seconds())
+
+	d.clockSkew.mu.Lock()
+	d.clockSkew.skew, d.clockSkew.sources = skew, sources
+	warn := skew.Abs() >= ClockSkewWarnThreshold
+	changed := warn != d.clockSkew.warned
+	d.clockSkew.warned = warn
+	d.clockSkew.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	details := map[string]string{"skew": skew.String(), "sources": strconv.Itoa(sources)}
+	if warn {
+		log.Printf("[Clock] Local clock is %s the mesh (median of %d peers); check NTP. Timestamp checks are widened by up to %v meanwhile", describeClockSkew(skew), sources, crypto.MaxClockSkewTolerance)
+	} else {
+		log.Printf("[Clock] Local clock is back within %v of the mesh", ClockSkewWarnThreshold)
+	}
+	d.recordEvent(EventClockSkew, "", details)
+}
+
+// ClockSkew returns the applied clock skew estimate (positive when the
+// local clock runs ahead of the mesh) and how many peers it is based on.
+func (d *Daemon) ClockSkew() (time.Duration, int) {
+	d.clockSkew.mu.Lock()
+	defer d.clockSkew.mu.Unlock()
+	return d.clockSkew.skew, d.clockSkew.sources
+}
+
+// describeClockSkew renders skew as "<duration> ahead of" or "behind".
+func describeClockSkew(skew time.Duration) string {
+	if skew < 0 {
+		return fmt.Sprintf("%v behind", (-skew).Round(time.Second))
+	}
+	return fmt.Sprintf("%v ahead of", skew.Round(time.Second))
+}
@@ -0,0 +1,63 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+func TestClockSkewEstimatorMedian(t *testing.T) {
+	t.Parallel()
+	e := NewClockSkewEstimator()
+	now := time.Now()
+	e.Observe("a", now.Add(-40*time.Second), now)
+	e.Observe("b", now.Add(-50*time.Second), now)
+	e.Observe("c", now.Add(2*time.Hour), now) // one broken peer does not move the median
+	e.Observe("stale", now.Add(-time.Hour), now.Add(-ClockSkewSampleTTL-time.Minute))
+
+	skew, sources := e.Estimate(now)
+	if sources != 3 || skew != 40*time.Second {
+		t.Errorf("Estimate() = %v from %d sources, want 40s from 3", skew, sources)
+	}
+
+	// A newer sample replaces the source's previous one.
+	e.Observe("c", now.Add(-45*time.Second), now)
+	if skew, _ := e.Estimate(now); skew != 45*time.Second {
+		t.Errorf("Estimate() after update = %v, want 45s", skew)
+	}
+}
+
+func TestUpdateClockSkew(t *testing.T) {
+	d := newMinimalDaemon(t)
+	defer crypto.SetClockSkew(0)
+	now := time.Now()
+
+	e := NewClockSkewEstimator()
+	e.Observe("a", now.Add(-2*time.Second), now)
+	e.Observe("b", now.Add(-3*time.Second), now)
+	d.updateClockSkew(e, now)
+	if skew, sources := d.ClockSkew(); skew != 0 || sources != 2 {
+		t.Errorf("ClockSkew() below the significance floor = %v, %d; want 0, 2", skew, sources)
+	}
+
+	e.Observe("a", now.Add(-2*time.Minute), now)
+	e.Observe("b", now.Add(-2*time.Minute), now)
+	d.updateClockSkew(e, now)
+	if skew, _ := d.ClockSkew(); skew != 2*time.Minute || crypto.ClockSkew() != 2*time.Minute {
+		t.Errorf("ClockSkew() = %v, crypto.ClockSkew() = %v; want 2m", skew, crypto.ClockSkew())
+	}
+	events := d.events.since(0)
+	if len(events) != 1 || events[0].Type != EventClockSkew || events[0].Details["skew"] != "2m0s" {
+		t.Errorf("events = %+v, want one %s with skew 2m0s", events, EventClockSkew)
+	}
+
+	// Staying skewed does not repeat the event; recovering records one.
+	d.updateClockSkew(e, now)
+	e.Observe("a", now, now)
+	e.Observe("b", now, now)
+	d.updateClockSkew(e, now)
+	if events := d.events.since(0); len(events) != 2 {
+		t.Errorf("got %d events, want 2", len(events))
+	}
+}
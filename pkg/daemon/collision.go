@@ -12,6 +12,16 @@ import (
 	node "github.com/atvirokodosprendimai/wgmesh/pkg/node"
 )
 
+// EventMeshIPCollision is recorded when two keys claim the same mesh IP.
+// new_mesh_ip is set when the local node lost and moved.
+const EventMeshIPCollision = "mesh_ip_collision"
+
+// Reannouncer is implemented by discovery layers that can push the local
+// announcement to known peers immediately.
+type Reannouncer interface {
+	Reannounce()
+}
+
 // CollisionInfo represents a mesh IP collision between two peers
 type CollisionInfo struct {
 	MeshIP string
@@ -91,45 +101,134 @@ func DeriveMeshIPWithNonce(meshSubnet [2]byte, wgPubKey, secret string, nonce in
 	)
 }
 
-// CheckAndResolveCollisions checks for collisions and resolves them
+// CheckAndResolveCollisions finds mesh IPs claimed by more than one key,
+// between this node and a peer or between two peers. The higher public key
+// loses: when that is this node it moves to a free address right away (see
+// reassignMeshIP), otherwise the collision is only reported — the losing
+// peer runs the same check and moves itself. Each collision is logged and
+// recorded as an event once, not on every reconcile.
 func (d *Daemon) CheckAndResolveCollisions() {
 	collisions := DetectCollisions(d.peerStore)
-	if len(collisions) == 0 {
-		return
+	if local := d.localCollision(); local != nil {
+		collisions = append(collisions, *local)
 	}
 
+	current := make(map[string]struct{}, len(collisions))
 	for _, collision := range collisions {
 		winner, loser := DeterministicWinner(collision.Peer1, collision.Peer2)
-		log.Printf("[Collision] Mesh IP collision detected: %s claimed by %s and %s",
-			collision.MeshIP, safeKeyPrefix(winner.WGPubKey), safeKeyPrefix(loser.WGPubKey))
 
-		// If we are the loser, re-derive our IP
 		if loser.WGPubKey == d.localNode.WGPubKey {
-			var newIP string
-			if d.config.CustomSubnet != nil {
-				ip, err := crypto.DeriveMeshIPInSubnetWithNonce(d.config.CustomSubnet, d.localNode.WGPubKey, d.config.Secret, 1)
-				if err != nil {
-					log.Printf("[Collision] CRITICAL: Failed to derive IP in custom subnet: %v — keeping current IP", err)
-					continue
-				}
-				newIP = ip
-			} else {
-				newIP = DeriveMeshIPWithNonce(d.config.Keys.MeshSubnet, d.localNode.WGPubKey, d.config.Secret, 1)
-			}
-			log.Printf("[Collision] We lost collision, re-deriving mesh IP: %s -> %s", d.localNode.MeshIP, newIP)
-			d.localNode.MeshIP = newIP
+			log.Printf("[Collision] Mesh IP collision detected: %s claimed by %s and %s (local); re-deriving",
+				collision.MeshIP, safeKeyPrefix(winner.WGPubKey), safeKeyPrefix(loser.WGPubKey))
+			d.reassignMeshIP(collision, winner)
+			continue
+		}
 
-			// Reconfigure WireGuard with new IP using correct prefix length
-			if err := setInterfaceAddress(d.config.InterfaceName, fmt.Sprintf("%s/%d", newIP, d.config.PrefixLen())); err != nil {
-				log.Printf("[Collision] Failed to update interface address: %v", err)
-			}
-		} else {
-			// The loser is a remote peer - update our expectation of their IP
-			newIP := ResolveCollision(collision, d.config.Keys.MeshSubnet, d.config.Secret, d.config.CustomSubnet)
-			if newIP != "" {
-				log.Printf("[Collision] Remote peer %s should re-derive to %s", safeKeyPrefix(loser.WGPubKey), newIP)
-			}
+		key := collision.MeshIP + "|" + winner.WGPubKey + "|" + loser.WGPubKey
+		current[key] = struct{}{}
+		if d.collisionReported(key) {
+			continue
+		}
+		log.Printf("[Collision] Mesh IP collision detected: %s claimed by %s and %s; %s should re-derive",
+			collision.MeshIP, safeKeyPrefix(winner.WGPubKey), safeKeyPrefix(loser.WGPubKey), safeKeyPrefix(loser.WGPubKey))
+		d.recordEvent(EventMeshIPCollision, loser.WGPubKey, map[string]string{
+			"mesh_ip": collision.MeshIP,
+			"winner":  winner.WGPubKey,
+			"loser":   loser.WGPubKey,
+		})
+	}
+
+	d.collisionMu.Lock()
+	d.collisions = current
+	d.collisionMu.Unlock()
+}
+
+// collisionReported reports whether a remote collision was already logged
+// by an earlier check.
+func (d *Daemon) collisionReported(key string) bool {
+	d.collisionMu.Lock()
+	defer d.collisionMu.Unlock()
+	_, ok := d.collisions[key]
+	return ok
+}
+
+// localCollision returns the collision between this node and the peers
+// claiming its mesh IP, against the lowest of their keys so that the local
+// node only moves when it loses to at least one of them.
+func (d *Daemon) localCollision() *CollisionInfo {
+	if d.localNode == nil || d.localNode.MeshIP == "" {
+		return nil
+	}
+	var rival *node.PeerInfo
+	for _, p := range d.peerStore.GetAll() {
+		if p.MeshIP != d.localNode.MeshIP || p.WGPubKey == d.localNode.WGPubKey {
+			continue
 		}
+		if rival == nil || p.WGPubKey < rival.WGPubKey {
+			rival = p
+		}
+	}
+	if rival == nil {
+		return nil
+	}
+	return &CollisionInfo{
+		MeshIP: d.localNode.MeshIP,
+		Peer1:  &node.PeerInfo{WGPubKey: d.localNode.WGPubKey, MeshIP: d.localNode.MeshIP},
+		Peer2:  rival,
+	}
+}
+
+// reassignMeshIP moves the local node off a mesh IP it lost to winner. The
+// new address is the first of the key's derivations (salt counter 0, 1, …)
+// not claimed by a known peer. It is applied to the interface, persisted so
+// a restart keeps it, and announced to known peers at once rather than on
+// the next periodic announcement. A node on a Tunnel stops instead, with
+// ErrMeshIPChanged.
+func (d *Daemon) reassignMeshIP(collision CollisionInfo, winner *node.PeerInfo) {
+	inUse := make(map[string]string)
+	for _, p := range d.peerStore.GetAll() {
+		if p.MeshIP != "" && p.WGPubKey != d.localNode.WGPubKey {
+			inUse[p.MeshIP] = p.WGPubKey
+		}
+	}
+
+	oldIP := d.localNode.MeshIP
+	newIP := DeriveMeshIPWithCollisionCheck(d.config.Keys.MeshSubnet, d.localNode.WGPubKey, d.config.Secret, inUse, d.config.CustomSubnet)
+	if _, taken := inUse[newIP]; newIP == "" || newIP == oldIP || taken {
+		log.Printf("[Collision] CRITICAL: No free mesh IP found for this node — keeping %s", oldIP)
+		return
+	}
+
+	log.Printf("[Collision] We lost collision, re-deriving mesh IP: %s -> %s", oldIP, newIP)
+	d.localNode.MeshIP = newIP
+
+	if err := saveLocalNode(localNodeStatePath(d.config.InterfaceName), d.localNode); err != nil {
+		log.Printf("[Collision] Failed to persist new mesh IP: %v", err)
+	}
+	// Reconfigure WireGuard with new IP using correct prefix length
+	if d.tunnel == nil {
+		if err := setInterfaceAddress(d.config.InterfaceName, fmt.Sprintf("%s/%d", newIP, d.config.PrefixLen())); err != nil {
+			log.Printf("[Collision] Failed to update interface address: %v", err)
+		}
+	}
+
+	d.recordEvent(EventMeshIPCollision, d.localNode.WGPubKey, map[string]string{
+		"mesh_ip":     collision.MeshIP,
+		"winner":      winner.WGPubKey,
+		"loser":       d.localNode.WGPubKey,
+		"new_mesh_ip": newIP,
+	})
+
+	if d.tunnel != nil {
+		// A Tunnel's stack cannot change its address; the next run
+		// starts with the persisted new one.
+		log.Printf("[Collision] Stopping so the userspace stack can move to %s", newIP)
+		d.meshIPChanged.Store(true)
+		d.cancel()
+		return
+	}
+	if r, ok := d.dhtDiscovery.(Reannouncer); ok {
+		r.Reannounce()
 	}
 }
 
@@ -3,6 +3,8 @@ package daemon
 import (
 	"net"
 	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 )
 
 func TestDetectCollisions(t *testing.T) {
@@ -121,3 +123,135 @@ func TestDeriveMeshIPWithCollisionCheckCustomSubnet(t *testing.T) {
 		t.Errorf("Collision-resolved IP %s not in custom subnet %s", ip2, customSubnet)
 	}
 }
+
+type fakeReannouncer struct{ calls int }
+
+func (f *fakeReannouncer) Start() error { return nil }
+func (f *fakeReannouncer) Stop() error  { return nil }
+func (f *fakeReannouncer) Reannounce()  { f.calls++ }
+
+func newCollisionTestDaemon(t *testing.T, pubKey, meshIP string) (*Daemon, *fakeReannouncer) {
+	t.Helper()
+	d := newMinimalDaemon(t)
+	d.config.InterfaceName = "wgtest0"
+	d.localNode = &LocalNode{WGPubKey: pubKey, MeshIP: meshIP}
+	r := &fakeReannouncer{}
+	d.dhtDiscovery = r
+	return d, r
+}
+
+func collisionEvents(d *Daemon) []RPCEventData {
+	var out []RPCEventData
+	for _, ev := range d.events.since(0) {
+		if ev.Type == EventMeshIPCollision {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// Two nodes join at the same time and derive the same mesh IP. Once each
+// has learned of the other, exactly one — the higher key — moves.
+func TestCheckAndResolveCollisions_SimultaneousJoin(t *testing.T) {
+	useTempStateDir(t) // no t.Parallel() — uses global stateDir and cmdExecutor
+
+	const shared = "10.42.1.1"
+	low, lowAnn := newCollisionTestDaemon(t, "aaaa-low-key", shared)
+	high, highAnn := newCollisionTestDaemon(t, "zzzz-high-key", shared)
+	low.peerStore.Update(&PeerInfo{WGPubKey: high.localNode.WGPubKey, MeshIP: shared}, "test")
+	high.peerStore.Update(&PeerInfo{WGPubKey: low.localNode.WGPubKey, MeshIP: shared}, "test")
+
+	withMockExecutor(t, &MockCommandExecutor{}, func() {
+		low.CheckAndResolveCollisions()
+		high.CheckAndResolveCollisions()
+	})
+
+	if low.localNode.MeshIP != shared {
+		t.Errorf("winner moved to %s", low.localNode.MeshIP)
+	}
+	if lowAnn.calls != 0 {
+		t.Errorf("winner re-announced %d times", lowAnn.calls)
+	}
+	newIP := high.localNode.MeshIP
+	want := crypto.DeriveMeshIP(high.config.Keys.MeshSubnet, high.localNode.WGPubKey, high.config.Secret)
+	if newIP != want {
+		t.Errorf("loser moved to %s, want its derived address %s", newIP, want)
+	}
+	if highAnn.calls != 1 {
+		t.Errorf("loser re-announced %d times, want 1", highAnn.calls)
+	}
+
+	saved, err := loadLocalNode(localNodeStatePath("wgtest0"))
+	if err != nil || saved.MeshIP != newIP {
+		t.Errorf("persisted mesh IP = %v (err %v), want %s", saved, err, newIP)
+	}
+
+	events := collisionEvents(high)
+	if len(events) != 1 || events[0].Details["new_mesh_ip"] != newIP || events[0].Details["winner"] != low.localNode.WGPubKey {
+		t.Errorf("loser events = %+v", events)
+	}
+	if events := collisionEvents(low); len(events) != 1 || events[0].Details["loser"] != high.localNode.WGPubKey {
+		t.Errorf("winner events = %+v", events)
+	}
+
+	// The reassignment reaches the winner; nothing is left to resolve.
+	low.peerStore.Update(&PeerInfo{WGPubKey: high.localNode.WGPubKey, MeshIP: newIP}, "test")
+	withMockExecutor(t, &MockCommandExecutor{}, func() {
+		low.CheckAndResolveCollisions()
+		high.CheckAndResolveCollisions()
+	})
+	if high.localNode.MeshIP != newIP || highAnn.calls != 1 {
+		t.Errorf("loser moved again to %s", high.localNode.MeshIP)
+	}
+	if len(collisionEvents(low)) != 1 || len(collisionEvents(high)) != 1 {
+		t.Error("resolved collision recorded again")
+	}
+}
+
+func TestCheckAndResolveCollisions_SaltCounterSkipsTakenIP(t *testing.T) {
+	useTempStateDir(t)
+
+	d, _ := newCollisionTestDaemon(t, "zzzz-high-key", "")
+	meshSubnet, secret, key := d.config.Keys.MeshSubnet, d.config.Secret, d.localNode.WGPubKey
+	base := crypto.DeriveMeshIP(meshSubnet, key, secret)
+	salted := DeriveMeshIPWithNonce(meshSubnet, key, secret, 1)
+	d.localNode.MeshIP = base
+	d.peerStore.Update(&PeerInfo{WGPubKey: "aaaa-low-key", MeshIP: base}, "test")
+	d.peerStore.Update(&PeerInfo{WGPubKey: "bbbb-other-key", MeshIP: salted}, "test")
+
+	withMockExecutor(t, &MockCommandExecutor{}, d.CheckAndResolveCollisions)
+
+	want := DeriveMeshIPWithNonce(meshSubnet, key, secret, 2)
+	if d.localNode.MeshIP != want {
+		t.Errorf("mesh IP = %s, want %s (salt counter 2)", d.localNode.MeshIP, want)
+	}
+}
+
+func TestCheckAndResolveCollisions_RemoteReportedOnce(t *testing.T) {
+	d, ann := newCollisionTestDaemon(t, "mmmm-local-key", "10.42.9.9")
+	d.peerStore.Update(&PeerInfo{WGPubKey: "aaaa-key", MeshIP: "10.42.2.2"}, "test")
+	d.peerStore.Update(&PeerInfo{WGPubKey: "bbbb-key", MeshIP: "10.42.2.2"}, "test")
+
+	d.CheckAndResolveCollisions()
+	d.CheckAndResolveCollisions()
+
+	events := collisionEvents(d)
+	if len(events) != 1 {
+		t.Fatalf("got %d collision events, want 1", len(events))
+	}
+	if events[0].PubKey != "bbbb-key" || events[0].Details["new_mesh_ip"] != "" {
+		t.Errorf("event = %+v", events[0])
+	}
+	if d.localNode.MeshIP != "10.42.9.9" || ann.calls != 0 {
+		t.Error("remote collision moved the local node")
+	}
+
+	// Once the loser has moved, a new collision on the address is reported again.
+	d.peerStore.Update(&PeerInfo{WGPubKey: "bbbb-key", MeshIP: "10.42.3.3"}, "test")
+	d.CheckAndResolveCollisions()
+	d.peerStore.Update(&PeerInfo{WGPubKey: "cccc-key", MeshIP: "10.42.2.2"}, "test")
+	d.CheckAndResolveCollisions()
+	if got := len(collisionEvents(d)); got != 2 {
+		t.Errorf("got %d collision events, want 2", got)
+	}
+}
@@ -7,11 +7,17 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/firewall"
 )
 
 const (
@@ -20,47 +26,173 @@ const (
 	DefaultWGPort          = 51820
 	DefaultInterface       = "wg0"
 	DefaultInterfaceDarwin = "utun20"
+	DefaultSTUNPort        = 3478
 )
 
 // Config holds all derived configuration for the mesh daemon
 type Config struct {
-	Secret          string
-	Keys            *crypto.DerivedKeys
-	InterfaceName   string
-	WGListenPort    int
-	AdvertiseRoutes []string
-	LogLevel        string
-	Privacy         bool
-	Gossip          bool
-	LANDiscovery    bool
-	Introducer      bool
-	DisableIPv6     bool
-	ForceRelay      bool
-	DisablePunching bool
-	CustomSubnet    *net.IPNet // User-specified mesh subnet (nil = use derived)
-}
-
-// DaemonOpts holds options for the daemon
-type DaemonOpts struct {
 	Secret              string
+	Keys                *crypto.DerivedKeys
 	InterfaceName       string
 	WGListenPort        int
 	AdvertiseRoutes     []string
 	LogLevel            string
 	Privacy             bool
+	PrivateFirstContact bool // With Privacy, stem first-contact announcements through introducers instead of sending HELLOs to DHT strangers
 	Gossip              bool
-	DisableLANDiscovery bool
+	GossipDigest        bool // With Gossip, exchange peer-set digests and send only missing or changed entries
+	GossipRatchet       bool // Seal envelopes with the daily ratcheted key instead of the static gossip key
+	LANDiscovery        bool
+	LANMDNS             bool     // Use mDNS/DNS-SD (_wgmesh._udp.local) for LAN discovery
+	LANInterfaces       []string // Interface names or globs LAN discovery runs on; empty = every multicast-capable LAN interface
+	LANGroup            string   // LAN multicast group as IP or IP:port; empty = derived from the secret
+	LANBroadcast        bool     // Also send LAN announcements to each interface's broadcast address
 	Introducer          bool
+	IntroducerElection  bool // Self-promote to introducer when the mesh has none configured
 	DisableIPv6         bool
 	ForceRelay          bool
 	DisablePunching     bool
-	MeshSubnet          string // Custom mesh subnet CIDR (e.g. "192.168.100.0/24")
+	CustomSubnet        *net.IPNet    // User-specified mesh subnet (nil = use derived)
+	SecretOptions       SecretOptions // Mesh options carried by a v2 secret URI
+	MeshName            string        // Display name of the mesh (empty = unnamed)
+	DNSRendezvous       string        // DNS name to resolve for bootstrap endpoints (empty = use DHT)
+	STUNServers         []string      // STUN servers host:port (empty = built-in defaults)
+	STUNListenPort      int           // Embedded STUN responder port on introducers (0 = disabled)
+	Keepalive           int           // PersistentKeepalive override: 0 = auto by NAT type, <0 = off, >0 = seconds
+	SubnetRouter        bool          // Enable IP forwarding for AdvertiseRoutes and undo it on shutdown
+	Masquerade          bool          // With SubnetRouter: masquerade mesh traffic to AdvertiseRoutes via nftables
+	Firewall            bool          // Drop inbound mesh traffic except wgmesh's own ports and the Firewall*Ports
+	FirewallTCPPorts    []int
+	FirewallUDPPorts    []int
+	PinIdentities       bool          // Quarantine announcements claiming a mesh IP or hostname first seen with another key
+	RequireSigned       bool          // Drop announcements without an identity signature instead of accepting them unauthenticated
+	SOCKS5Proxy         *url.URL      // Send DHT traffic and first-contact HELLOs through this proxy (nil = direct)
+	MaxInstalledPeers   int           // Cap on peers installed into WireGuard; introducers and peers with traffic always are (0 = no cap)
+	Chaos               *Chaos        // Fault injection for testing (nil = off)
+	GracefulRestart     bool          // Leave the interface up on exit and adopt a matching one on start
+	AdoptInterface      bool          // Adopt a matching interface on start, as after an in-place restart
+	AutoUpdate          bool          // Install newer signed releases and restart into them
+	CentralState        string        // Centralized mesh-state.json (path or http(s) URL) whose nodes are merged as static peers
+	StaticPeers         string        // Signed peer manifest; when set the daemon runs offline, without DHT, LAN discovery, STUN or peer exchange
+	DiscoveryBandwidth  int           // Budget for DHT and peer exchange traffic, bytes per second (0 = unlimited)
+	DHTShards           int           // DHT infohashes the mesh is spread over; must match on every node (0 or 1 = unsharded)
+	NoPeerRoutes        bool          // Leave routes to networks peers advertise to someone else, e.g. a Kubernetes CNI
+	RouteTable          int           // Routing table for routes to peer networks; 0 = main
+	RouteMetric         int           // Metric of routes to peer networks; 0 = kernel default
+	RouteFwmark         int           // Fwmark WireGuard puts on its packets so they bypass RouteTable; 0 = none
+	MTU                 int           // Interface MTU; 0 = the kernel default
+	MTUProbe            bool          // Probe each peer's path MTU and clamp TCP MSS below it
+	PortHop             time.Duration // Move the WireGuard listen port on this secret-derived schedule; 0 = fixed port
+	TCPTransportPort    int           // Introducers: accept TLS-wrapped WireGuard from nodes whose UDP is blocked on this TCP port; 0 = off
+	Approvers           []string      // Identity keys whose signed approval a node needs to be admitted; empty = everyone with the secret
+	HealthAddr          string        // Serve liveness and readiness JSON over HTTP on this host:port; empty = off
+	PeerDeadAfter       time.Duration // Peers unseen this long are left out of WireGuard; 0 = PeerDeadTimeout
+	PeerRemoveAfter     time.Duration // Peers unseen this long are dropped from the store and cache; 0 = PeerRemoveTimeout
+	NoSignals           bool          // Leave SIGINT, SIGTERM and SIGHUP to the program embedding the daemon
+	Version             string        // wgmesh version announced to peers
+
+	// Tags are labels announced to peers, e.g. role=db.
+	Tags map[string]string
+
+	// AdvertiseDNS are domains announced with the resolver that answers
+	// for them; DisableSplitDNS stops this node installing peers' domains.
+	// SplitDNSResolvConf lets it put their resolvers in /etc/resolv.conf
+	// on hosts without systemd-resolved.
+	AdvertiseDNS       []crypto.DNSDomain
+	DisableSplitDNS    bool
+	SplitDNSResolvConf bool
+
+	// RouteChecks are the targets probed for advertised networks; Healthy
+	// is filled in at run time.
+	RouteChecks []crypto.RouteCheck
+
+	// ResourceLimits caps open FDs, goroutines and probe sessions.
+	ResourceLimits ResourceLimits
+
+	// PeerRateLimits caps what the node sends to peers; see shaping.go.
+	PeerRateLimits RateLimits
+
+	// PeerPolicy limits which peers are installed into WireGuard; nil
+	// installs every peer.
+	PeerPolicy *PeerPolicy
+
+	envelopeKeys *EnvelopeKeys // accepted ratcheted envelope keys; nil outside NewConfig
+}
+
+// DaemonOpts holds options for the daemon
+type DaemonOpts struct {
+	Secret                    string
+	InterfaceName             string
+	WGListenPort              int
+	AdvertiseRoutes           []string
+	LogLevel                  string
+	Privacy                   bool
+	PrivateFirstContact       bool
+	Gossip                    bool
+	GossipDigest              bool
+	GossipRatchet             bool
+	DisableLANDiscovery       bool
+	LANMDNS                   bool
+	LANInterfaces             string // e.g. "eth0,wl*"; empty = every multicast-capable LAN interface
+	LANGroup                  string // e.g. "239.1.2.3:51830"; empty = derived from the secret
+	LANBroadcast              bool
+	Introducer                bool
+	DisableIntroducerElection bool
+	DisableIPv6               bool
+	ForceRelay                bool
+	DisablePunching           bool
+	MeshSubnet                string // Custom mesh subnet CIDR (e.g. "192.168.100.0/24")
+	MeshName                  string // Display name of the mesh; overrides the one in a v2 secret URI
+	DNSRendezvous             string // DNS TXT/SRV name for bootstrap (e.g. "_wgmesh._udp.example.com")
+	STUNServers               []string
+	STUNListenPort            int // 0 = DefaultSTUNPort, negative = disable responder
+	Keepalive                 int // 0 = auto by NAT type, negative = off, positive = seconds
+	SubnetRouter              bool
+	Masquerade                bool
+	Firewall                  bool
+	FirewallAllow             string // Extra ports to allow with Firewall, e.g. "tcp/22,udp/53"
+	PinIdentities             bool
+	RequireSigned             bool
+	SOCKS5Proxy               string // host:port or socks5://[user:pass@]host:port
+	MaxInstalledPeers         int    // 0 = install every peer
+	Chaos                     string // Fault spec, e.g. "drop-exchange=0.2,fail-probe=0.5,seed=1"; testing only
+	GracefulRestart           bool
+	AdoptInterface            bool // set when restarting in place; adopt the interface once
+	AutoUpdate                bool
+	CentralState              string // Path or http(s) URL of a centralized mesh-state.json
+	StaticPeers               string // Path of a signed StaticManifest; disables all network discovery
+	DiscoveryBandwidth        string // e.g. "5KB/s"; empty = unlimited
+	DHTShards                 int    // 0 or 1 = a single DHT infohash
+	NoPeerRoutes              bool
+	RouteTable                int // 0 or 254 = main
+	RouteMetric               int
+	RouteFwmark               int // requires RouteTable
+	MTU                       int // 0 = kernel default (1420)
+	MTUProbe                  bool
+	PortHop                   time.Duration // 0 = fixed listen port
+	TCPTransportPort          int           // requires Introducer
+	Approvers                 string        // comma-separated identity keys; turns on approval mode
+	HealthAddr                string        // e.g. "127.0.0.1:8099"; empty = no healthcheck endpoint
+	PeerDeadAfter             time.Duration // 0 = PeerDeadTimeout
+	PeerRemoveAfter           time.Duration // 0 = PeerRemoveTimeout; at least PeerDeadAfter
+	Tags                      string        // e.g. "role=db,zone=eu"
+	AdvertiseDNS              string        // e.g. "home.lan=192.168.1.53"
+	RouteChecks               string        // e.g. "192.168.1.0/24=192.168.1.10:443"
+	DisableSplitDNS           bool
+	SplitDNSResolvConf        bool
+	ResourceLimits            string // e.g. "fds=4096,probe-sessions=512"; empty = defaults
+	PeerRateLimits            string // e.g. "<pubkey>=10Mbit,all=50Mbit"; empty = unshaped
+	PeerWith                  string // e.g. "introducers,tag:group=eu"; empty = full mesh
+	Version                   string // wgmesh version announced to peers
 }
 
 // NewConfig creates a new daemon configuration from options
 func NewConfig(opts DaemonOpts) (*Config, error) {
 	// Parse secret from URI format if needed
-	secret := parseSecret(opts.Secret)
+	secret, secretOpts, err := ParseSecretURI(opts.Secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret: %w", err)
+	}
 
 	// Warn if secret looks user-chosen rather than auto-generated.
 	if opts.Secret != "" && !strings.HasPrefix(strings.TrimSpace(opts.Secret), "wgmesh://") {
@@ -89,6 +221,24 @@ func NewConfig(opts DaemonOpts) (*Config, error) {
 		}
 	}
 
+	// A rotation that finished (possibly while this node was down) replaces
+	// the configured secret, so restarts with the old --secret keep working.
+	if rotated := rotatedSecret(ifaceName, secret); rotated != "" {
+		log.Printf("[Rotation] Secret for %s was rotated; using the new secret", ifaceName)
+		secret = rotated
+		if keys, err = crypto.DeriveKeys(secret); err != nil {
+			return nil, fmt.Errorf("failed to derive keys for rotated secret: %w", err)
+		}
+	}
+	secretOpts.applyKeys(keys)
+
+	meshName := strings.TrimSpace(opts.MeshName)
+	if meshName == "" {
+		meshName = secretOpts.Name
+	} else if err := (SecretOptions{Name: meshName}).Validate(); err != nil {
+		return nil, err
+	}
+
 	listenPort := opts.WGListenPort
 	if listenPort == 0 {
 		listenPort = DefaultWGPort
@@ -99,8 +249,13 @@ func NewConfig(opts DaemonOpts) (*Config, error) {
 		logLevel = "info"
 	}
 
-	// Parse and validate custom subnet if provided
-	customSubnet, err := crypto.ParseSubnetOrDefault(opts.MeshSubnet)
+	// Parse and validate custom subnet if provided; --mesh-subnet wins
+	// over the secret URI's.
+	meshSubnet := opts.MeshSubnet
+	if meshSubnet == "" {
+		meshSubnet = secretOpts.MeshSubnet
+	}
+	customSubnet, err := crypto.ParseSubnetOrDefault(meshSubnet)
 	if err != nil {
 		return nil, fmt.Errorf("invalid mesh subnet: %w", err)
 	}
@@ -117,24 +272,399 @@ func NewConfig(opts DaemonOpts) (*Config, error) {
 		}
 	}
 
+	stunServers, err := normalizeSTUNServers(opts.STUNServers)
+	if err != nil {
+		return nil, fmt.Errorf("invalid STUN server: %w", err)
+	}
+
+	stunListenPort := opts.STUNListenPort
+	if stunListenPort == 0 {
+		stunListenPort = DefaultSTUNPort
+	} else if stunListenPort < 0 {
+		stunListenPort = 0
+	} else if stunListenPort > 65535 {
+		return nil, fmt.Errorf("invalid STUN listen port %d", stunListenPort)
+	}
+
+	if opts.Keepalive > 65535 {
+		return nil, fmt.Errorf("invalid keepalive %d: must be at most 65535 seconds", opts.Keepalive)
+	}
+
+	if opts.MaxInstalledPeers < 0 {
+		return nil, fmt.Errorf("invalid max installed peers %d: must not be negative", opts.MaxInstalledPeers)
+	}
+
+	if opts.MTU != 0 && (opts.MTU < MinMTU || opts.MTU > MaxMTU) {
+		return nil, fmt.Errorf("invalid MTU %d: must be between %d and %d", opts.MTU, MinMTU, MaxMTU)
+	}
+
+	if opts.PortHop != 0 && (opts.PortHop < MinPortHopInterval || opts.PortHop > MaxPortHopInterval) {
+		return nil, fmt.Errorf("invalid port hop interval %v: must be between %v and %v", opts.PortHop, MinPortHopInterval, MaxPortHopInterval)
+	}
+
+	if opts.TCPTransportPort < 0 || opts.TCPTransportPort > 65535 {
+		return nil, fmt.Errorf("invalid TCP transport port %d", opts.TCPTransportPort)
+	}
+	if opts.TCPTransportPort != 0 && !opts.Introducer {
+		return nil, fmt.Errorf("the TCP transport port is only served by introducers")
+	}
+
+	if err := validatePeerExpiry(opts.PeerDeadAfter, opts.PeerRemoveAfter); err != nil {
+		return nil, err
+	}
+
+	if opts.HealthAddr != "" {
+		if _, _, err := net.SplitHostPort(opts.HealthAddr); err != nil {
+			return nil, fmt.Errorf("invalid health address %q: %w", opts.HealthAddr, err)
+		}
+	}
+
+	approvers, err := parseApprovers(opts.Approvers)
+	if err != nil {
+		return nil, err
+	}
+
+	lanInterfaces, err := parseLANInterfaces(opts.LANInterfaces)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateLANGroup(opts.LANGroup); err != nil {
+		return nil, err
+	}
+	if opts.LANMDNS && (opts.LANGroup != "" || opts.LANBroadcast) {
+		return nil, fmt.Errorf("--lan-group and --lan-broadcast do not apply to mDNS LAN discovery")
+	}
+
+	routeTable, err := validateRouteTable(opts.RouteTable, opts.RouteMetric, opts.RouteFwmark)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DHTShards < 0 || opts.DHTShards > MaxDHTShards {
+		return nil, fmt.Errorf("invalid DHT shards %d: must be between 0 and %d", opts.DHTShards, MaxDHTShards)
+	}
+
+	if opts.Masquerade && !opts.SubnetRouter {
+		return nil, fmt.Errorf("masquerade requires subnet router mode")
+	}
+	if opts.SubnetRouter {
+		if runtime.GOOS != "linux" {
+			return nil, fmt.Errorf("subnet router mode is only supported on Linux")
+		}
+		if len(opts.AdvertiseRoutes) == 0 {
+			return nil, fmt.Errorf("subnet router mode requires advertised routes")
+		}
+		for _, r := range opts.AdvertiseRoutes {
+			if _, _, err := net.ParseCIDR(strings.TrimSpace(r)); err != nil {
+				return nil, fmt.Errorf("invalid advertised route %q: %w", r, err)
+			}
+		}
+	}
+
+	if opts.GossipDigest && !opts.Gossip {
+		return nil, fmt.Errorf("gossip digest requires gossip mode")
+	}
+	if opts.PrivateFirstContact && !opts.Privacy {
+		return nil, fmt.Errorf("private first contact requires privacy mode")
+	}
+
+	if opts.FirewallAllow != "" && !opts.Firewall {
+		return nil, fmt.Errorf("firewall allow list requires firewall mode")
+	}
+	fwTCP, fwUDP, err := firewall.ParseAllowList(opts.FirewallAllow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid firewall allow list: %w", err)
+	}
+	if opts.Firewall && runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("firewall mode is only supported on Linux")
+	}
+
+	socksProxy, err := parseSOCKS5Proxy(opts.SOCKS5Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SOCKS5 proxy: %w", err)
+	}
+
+	centralState := strings.TrimSpace(opts.CentralState)
+	if err := validateCentralStateSource(centralState); err != nil {
+		return nil, fmt.Errorf("invalid central state source: %w", err)
+	}
+
+	staticPeers := strings.TrimSpace(opts.StaticPeers)
+	if staticPeers != "" {
+		if opts.DNSRendezvous != "" {
+			return nil, fmt.Errorf("static peers mode does not use DNS rendezvous")
+		}
+		if _, err := LoadStaticManifest(staticPeers, keys.MembershipKey[:]); err != nil {
+			return nil, fmt.Errorf("invalid static peers manifest %s: %w", staticPeers, err)
+		}
+		// Nothing is looked up or announced outside the manifest.
+		stunServers = nil
+		stunListenPort = 0
+	}
+
+	discoveryBandwidth, err := ParseBandwidth(opts.DiscoveryBandwidth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid discovery bandwidth: %w", err)
+	}
+
+	tags, err := ParseTags(opts.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tags: %w", err)
+	}
+
+	advertiseDNS, err := ParseAdvertiseDNS(opts.AdvertiseDNS)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --advertise-dns: %w", err)
+	}
+	for _, dd := range advertiseDNS {
+		if !routesContain(opts.AdvertiseRoutes, net.ParseIP(dd.Resolver)) {
+			log.Printf("[WARN] Resolver %s for %s is not in --advertise-routes; peers only use it if it is this node's mesh IP", dd.Resolver, dd.Domain)
+		}
+	}
+
+	routeChecks, err := ParseRouteChecks(opts.RouteChecks, opts.AdvertiseRoutes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --route-check: %w", err)
+	}
+
+	resourceLimits, err := ParseResourceLimits(opts.ResourceLimits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource limits: %w", err)
+	}
+
+	peerRateLimits, err := ParseRateLimits(opts.PeerRateLimits)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer rate limit: %w", err)
+	}
+	if len(peerRateLimits) > 0 && runtime.GOOS != "linux" {
+		return nil, fmt.Errorf("peer rate limits are only supported on Linux")
+	}
+
+	peerPolicy, err := ParsePeerPolicy(opts.PeerWith)
+	if err != nil {
+		return nil, fmt.Errorf("invalid peer policy: %w", err)
+	}
+
+	chaos, err := ParseChaos(opts.Chaos)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chaos spec: %w", err)
+	}
+	if chaos != nil {
+		log.Printf("[WARN] Chaos mode enabled (%s): faults are injected on purpose, do not use in production", chaos)
+	}
+
 	return &Config{
-		Secret:          secret,
-		Keys:            keys,
-		InterfaceName:   ifaceName,
-		WGListenPort:    listenPort,
-		AdvertiseRoutes: opts.AdvertiseRoutes,
-		LogLevel:        logLevel,
-		Privacy:         opts.Privacy,
-		Gossip:          opts.Gossip,
-		LANDiscovery:    !opts.DisableLANDiscovery,
-		Introducer:      opts.Introducer,
-		DisableIPv6:     opts.DisableIPv6,
-		ForceRelay:      opts.ForceRelay,
-		DisablePunching: opts.DisablePunching,
-		CustomSubnet:    customSubnet,
+		Secret:              secret,
+		Keys:                keys,
+		InterfaceName:       ifaceName,
+		WGListenPort:        listenPort,
+		AdvertiseRoutes:     opts.AdvertiseRoutes,
+		LogLevel:            logLevel,
+		Privacy:             opts.Privacy,
+		PrivateFirstContact: opts.PrivateFirstContact,
+		Gossip:              opts.Gossip,
+		GossipDigest:        opts.GossipDigest,
+		GossipRatchet:       opts.GossipRatchet,
+		LANDiscovery:        !opts.DisableLANDiscovery && staticPeers == "",
+		LANMDNS:             opts.LANMDNS,
+		LANInterfaces:       lanInterfaces,
+		LANGroup:            opts.LANGroup,
+		LANBroadcast:        opts.LANBroadcast,
+		Introducer:          opts.Introducer,
+		IntroducerElection:  !opts.DisableIntroducerElection,
+		DisableIPv6:         opts.DisableIPv6,
+		ForceRelay:          opts.ForceRelay,
+		DisablePunching:     opts.DisablePunching,
+		CustomSubnet:        customSubnet,
+		SecretOptions:       secretOpts,
+		MeshName:            meshName,
+		DNSRendezvous:       strings.TrimSuffix(strings.TrimSpace(opts.DNSRendezvous), "."),
+		STUNServers:         stunServers,
+		STUNListenPort:      stunListenPort,
+		Keepalive:           opts.Keepalive,
+		SubnetRouter:        opts.SubnetRouter,
+		Masquerade:          opts.Masquerade,
+		Firewall:            opts.Firewall,
+		FirewallTCPPorts:    fwTCP,
+		FirewallUDPPorts:    fwUDP,
+		PinIdentities:       opts.PinIdentities,
+		RequireSigned:       opts.RequireSigned,
+		SOCKS5Proxy:         socksProxy,
+		MaxInstalledPeers:   opts.MaxInstalledPeers,
+		Chaos:               chaos,
+		GracefulRestart:     opts.GracefulRestart,
+		AdoptInterface:      opts.AdoptInterface,
+		AutoUpdate:          opts.AutoUpdate,
+		CentralState:        centralState,
+		StaticPeers:         staticPeers,
+		DiscoveryBandwidth:  discoveryBandwidth,
+		DHTShards:           opts.DHTShards,
+		NoPeerRoutes:        opts.NoPeerRoutes,
+		RouteTable:          routeTable,
+		RouteMetric:         opts.RouteMetric,
+		RouteFwmark:         opts.RouteFwmark,
+		MTU:                 opts.MTU,
+		MTUProbe:            opts.MTUProbe,
+		PortHop:             opts.PortHop,
+		TCPTransportPort:    opts.TCPTransportPort,
+		Approvers:           approvers,
+		HealthAddr:          opts.HealthAddr,
+		PeerDeadAfter:       opts.PeerDeadAfter,
+		PeerRemoveAfter:     opts.PeerRemoveAfter,
+		Tags:                tags,
+		AdvertiseDNS:        advertiseDNS,
+		DisableSplitDNS:     opts.DisableSplitDNS,
+		SplitDNSResolvConf:  opts.SplitDNSResolvConf,
+		RouteChecks:         routeChecks,
+		ResourceLimits:      resourceLimits,
+		PeerRateLimits:      peerRateLimits,
+		PeerPolicy:          peerPolicy,
+		Version:             opts.Version,
+		envelopeKeys:        NewEnvelopeKeys(keys.GossipKey, time.Now()),
 	}, nil
 }
 
+// routesContain reports whether ip lies in one of the CIDRs in routes.
+func routesContain(routes []string, ip net.IP) bool {
+	for _, r := range routes {
+		if _, ipNet, err := net.ParseCIDR(strings.TrimSpace(r)); err == nil && ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSOCKS5Proxy accepts host:port or socks5://[user[:pass]@]host:port and
+// returns it as a socks5 URL. An empty string means no proxy.
+func parseSOCKS5Proxy(s string) (*url.URL, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	if !strings.Contains(s, "://") {
+		s = "socks5://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return nil, fmt.Errorf("unsupported scheme %q (want socks5)", u.Scheme)
+	}
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("unexpected path %q", u.Path)
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil || host == "" {
+		return nil, fmt.Errorf("%q is not host:port", u.Host)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return nil, fmt.Errorf("invalid port %q", port)
+	}
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		if len(u.User.Username()) > 255 || len(pass) > 255 {
+			return nil, fmt.Errorf("username and password must be at most 255 bytes")
+		}
+	}
+	return &url.URL{Scheme: "socks5", Host: u.Host, User: u.User}, nil
+}
+
+// MaxDHTShards caps --dht-shards. Each shard takes a share of the mesh, and
+// a node announces to two of them, so more shards than this would leave
+// most of them nearly empty even in large meshes.
+const MaxDHTShards = 64
+
+// MinDiscoveryBandwidth is the smallest discovery budget accepted: below it
+// a node cannot even answer its peers' exchanges.
+const MinDiscoveryBandwidth = 512
+
+// parseLANInterfaces parses --lan-interfaces, a comma-separated list of
+// interface names or path.Match globs such as "wl*".
+func parseLANInterfaces(s string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, err := path.Match(name, ""); err != nil {
+			return nil, fmt.Errorf("invalid LAN interface pattern %q: %w", name, err)
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// validateLANGroup checks --lan-group: an IPv4 multicast address with an
+// optional port.
+func validateLANGroup(group string) error {
+	if group == "" {
+		return nil
+	}
+	host := group
+	if h, port, err := net.SplitHostPort(group); err == nil {
+		host = h
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid LAN group port %q", port)
+		}
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.To4() == nil || !ip.IsMulticast() {
+		return fmt.Errorf("invalid LAN group %q: must be an IPv4 multicast address such as 239.1.2.3", group)
+	}
+	return nil
+}
+
+// ParseBandwidth parses a rate such as "5KB/s", "512B/s" or "1MB/s" into
+// bytes per second. The "/s" is optional, units are B, KB and MB with
+// KB = 1024 bytes, and an empty string means unlimited (0).
+func ParseBandwidth(rate string) (int, error) {
+	s := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(rate)), "/S")
+	if s == "" {
+		return 0, nil
+	}
+	mult := 1
+	switch {
+	case strings.HasSuffix(s, "MB"):
+		mult, s = 1<<20, strings.TrimSuffix(s, "MB")
+	case strings.HasSuffix(s, "KB"):
+		mult, s = 1<<10, strings.TrimSuffix(s, "KB")
+	case strings.HasSuffix(s, "B"):
+		s = strings.TrimSuffix(s, "B")
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("%q is not a positive rate such as 5KB/s", rate)
+	}
+	bps := int(v * float64(mult))
+	if bps < MinDiscoveryBandwidth {
+		return 0, fmt.Errorf("at least %dB/s is needed", MinDiscoveryBandwidth)
+	}
+	return bps, nil
+}
+
+// normalizeSTUNServers validates host[:port] entries, filling in the
+// standard STUN port when omitted. Empty entries are skipped.
+func normalizeSTUNServers(servers []string) ([]string, error) {
+	var out []string
+	for _, s := range servers {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		host, port, err := net.SplitHostPort(s)
+		if err != nil {
+			host, port = strings.Trim(s, "[]"), fmt.Sprintf("%d", DefaultSTUNPort)
+		}
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("%q: expected host[:port]", s)
+		}
+		out = append(out, net.JoinHostPort(host, port))
+	}
+	return out, nil
+}
+
 // PrefixLen returns the prefix length for the mesh subnet.
 // Uses CustomSubnet mask if set, otherwise defaults to 16.
 func (c *Config) PrefixLen() int {
@@ -145,6 +675,34 @@ func (c *Config) PrefixLen() int {
 	return 16
 }
 
+// MeshSubnetString returns the mesh subnet in CIDR notation: CustomSubnet
+// if set, otherwise the derived 10.x.0.0/16.
+func (c *Config) MeshSubnetString() string {
+	if c.CustomSubnet != nil {
+		return c.CustomSubnet.String()
+	}
+	return fmt.Sprintf("10.%d.0.0/16", c.Keys.MeshSubnet[0])
+}
+
+// EnvelopeSealKey returns the key to seal outgoing envelopes with: the
+// current ratcheted key with GossipRatchet, the static gossip key otherwise.
+func (c *Config) EnvelopeSealKey() [32]byte {
+	if c.GossipRatchet && c.envelopeKeys != nil {
+		return c.envelopeKeys.SealKey(time.Now())
+	}
+	return c.Keys.GossipKey
+}
+
+// EnvelopeOpenKeys returns the keys to try on incoming envelopes: the
+// accepted ratcheted keys, then the static gossip key, which nodes without
+// GossipRatchet (and older versions) still seal with.
+func (c *Config) EnvelopeOpenKeys() [][32]byte {
+	if c.envelopeKeys == nil {
+		return [][32]byte{c.Keys.GossipKey}
+	}
+	return append(c.envelopeKeys.OpenKeys(time.Now()), c.Keys.GossipKey)
+}
+
 // GenerateSecret generates a new random mesh secret
 func GenerateSecret() (string, error) {
 	// Generate 128 random bytes
@@ -171,7 +729,7 @@ func FormatSecretURI(secret string) string {
 //	advertise-routes   comma-separated CIDR list
 //	log-level          debug|info|warn|error
 func ReloadConfigPath(ifaceName string) string {
-	return fmt.Sprintf("/var/lib/wgmesh/%s.reload", ifaceName)
+	return filepath.Join(stateDir, ifaceName+".reload")
 }
 
 // LoadReloadFile parses a reload config file and returns a DaemonOpts with
@@ -220,25 +778,3 @@ func LoadReloadFile(path string) (DaemonOpts, error) {
 	}
 	return opts, nil
 }
-
-// parseSecret extracts the raw secret from various input formats
-func parseSecret(input string) string {
-	input = strings.TrimSpace(input)
-
-	// Handle wgmesh://v1/secret format
-	if strings.HasPrefix(input, URIPrefix) {
-		input = strings.TrimPrefix(input, URIPrefix)
-		parts := strings.SplitN(input, "/", 2)
-		if len(parts) == 2 {
-			// Remove query params if present
-			secret := parts[1]
-			if idx := strings.Index(secret, "?"); idx != -1 {
-				secret = secret[:idx]
-			}
-			return secret
-		}
-		return parts[0]
-	}
-
-	return input
-}
@@ -3,6 +3,7 @@ package daemon
 import (
 	"bufio"
 	"context"
+	"crypto/ed25519"
 	"fmt"
 	"hash/fnv"
 	"log"
@@ -11,15 +12,19 @@ import (
 	"os"
 	"os/signal"
 	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/firewall"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/privacy"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/tracing"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
 )
 
@@ -33,20 +38,29 @@ const (
 	MeshProbeInterval        = 1 * time.Second
 	MeshProbeDialTimeout     = 1200 * time.Millisecond // Increased from 800ms for cross-DC tolerance
 	MeshProbeFailLimit       = 8
+	MeshProbeStatsInterval   = 5 * time.Second // RTT/loss sampling pace for peers with a healthy handshake
 	MeshProbePortOffset      = 2000
 	TemporaryOfflineTTL      = 30 * time.Second
-	soBindToDevice           = 25 // Linux SO_BINDTODEVICE
-	RelayHysteresisThreshold = 3  // Require 3 consecutive stable cycles before switching relay→direct
+	soBindToDevice           = 25                   // Linux SO_BINDTODEVICE
+	RelayHysteresisThreshold = 3                    // Require 3 consecutive stable cycles before switching relay→direct
+	RelayLatencyTolerance    = 0.25                 // relays within 25% of the fastest count as equally fast
+	RelayLatencySlack        = 2 * time.Millisecond // ...or within this much, for very low RTTs
+	RelayMaxProbeLoss        = 0.5                  // relays losing this share of probes are skipped
+	DefaultKeepalive         = 25                   // PersistentKeepalive seconds when either side may be behind NAT
 )
 
 type peerProbeSession struct {
-	conn   net.Conn
-	reader *bufio.Reader
+	conn     net.Conn
+	reader   *bufio.Reader
+	lastUsed time.Time // for shedding the least recently used under resource pressure
 }
 
 // Daemon manages the mesh node lifecycle
 type Daemon struct {
 	config                 *Config
+	wgBackend              wireguard.Backend // the WireGuard interface; see wireGuard
+	tunnel                 Tunnel            // in-process WireGuard instead of an interface; see SetTunnel
+	tunnelUp               atomic.Bool
 	localNode              *LocalNode
 	peerStore              *PeerStore
 	lastAppliedPeerConfigs map[string]string
@@ -62,9 +76,48 @@ type Daemon struct {
 	probeMu                sync.Mutex
 	probeSessions          map[string]*peerProbeSession
 	probeFailures          map[string]int
+	lastStatsProbe         map[string]time.Time
 	probeListeners         []net.Listener
 	offlineMu              sync.Mutex
 	temporaryOffline       map[string]time.Time
+	events                 eventLog
+	pathMu                 sync.Mutex
+	paths                  map[string]*pathState // multi-endpoint peers -> selected path
+	routeMu                sync.Mutex
+	routeAccepted          map[string][]string // pubkey -> networks routed via that peer
+	routeConflicts         []RPCRouteConflictData
+	routeGateways          map[string][]string // network -> gateways advertising it, active first
+	routeActive            map[string]string   // network -> gateway carrying it
+	gatewayFailures        map[string]int      // failover gateway -> consecutive probe failures
+	routeWithheld          map[string][]string // pubkey -> networks withheld for a failing route check
+	routeCheck             routeCheckState
+	advertisedRoutes       map[string][]string // pubkey -> networks last advertised (withdrawal detection)
+	subnetRouter           subnetRouter
+	splitDNS               splitDNS
+	pmtu                   pmtuState
+	portHop                portHopState
+	tcpTransport           tcpTransportState
+	firewall               *firewall.Firewall
+	shaping                shapingState
+	traffic                trafficAccounting
+	install                installState
+	rotation               rotationState
+	admission              admissionState
+	broadcasts             broadcastState
+	identityPins           identityPins
+	adopted                adoptedPeers
+	collisionMu            sync.Mutex
+	collisions             map[string]struct{} // remote collisions already reported
+	election               introducerElection
+	clockSkew              clockSkewState
+	versionSkew            versionSkewState
+	resources              resourceState
+	health                 healthState
+	leaving                atomic.Bool // set by Leave: tear everything down on exit
+	restarting             atomic.Bool // set by Restart: keep the interface for the next run
+	meshIPChanged          atomic.Bool // set when a Tunnel node lost a mesh IP collision
+	staticPairPSK          atomic.Bool // this node's pair_psk in the --static-peers manifest
+	pskProbes              pskProbeState
 
 	// configMu guards the hot-reloadable fields in config and localNode.
 	// Callers that read AdvertiseRoutes or LogLevel at runtime must hold at
@@ -106,9 +159,30 @@ type LocalNode struct {
 	Introducer       bool
 	NATType          string // Detected NAT type: "cone", "symmetric", or "unknown"
 	Hostname         string
+	Tags             map[string]string
+	DNS              []crypto.DNSDomain // --advertise-dns domains
+	PortHop          time.Duration      // --port-hop interval; 0 = fixed listen port
+	TCPPort          int                // --tcp-transport-port; 0 = none
+	IdentityKey      ed25519.PrivateKey // signs this node's announcements
 
 	endpointMu sync.RWMutex
 	wgEndpoint string
+
+	relayStateMu sync.RWMutex
+	load         *crypto.IntroducerLoad // nil unless this node is an introducer
+	relays       []string
+	tcpVia       string // introducer reached over the TCP transport
+	approval     *crypto.AdmissionApproval
+	routeChecks  []crypto.RouteCheck // latest --route-check results
+
+	introducerCandidate atomic.Bool
+	introducerElected   atomic.Bool
+}
+
+// IsIntroducer reports whether the node acts as an introducer, either
+// configured as one or elected (see runIntroducerElection).
+func (n *LocalNode) IsIntroducer() bool {
+	return n.Introducer || n.introducerElected.Load()
 }
 
 // GetEndpoint returns the current WireGuard endpoint (thread-safe).
@@ -125,12 +199,75 @@ func (n *LocalNode) SetEndpoint(ep string) {
 	n.wgEndpoint = ep
 }
 
+// SignAnnouncement signs an outgoing announcement with the node's identity
+// key. Nodes without one (tests, embedders) send unsigned announcements.
+func (n *LocalNode) SignAnnouncement(announcement *crypto.PeerAnnouncement) {
+	if n.IdentityKey == nil {
+		return
+	}
+	if err := announcement.Sign(n.IdentityKey); err != nil {
+		log.Printf("Warning: failed to sign announcement: %v", err)
+	}
+}
+
+// AnnounceRelayState fills in an outgoing announcement's introducer load,
+// election state and the relays this node uses, as last measured by the
+// daemon, along with the node's tags, split-DNS domains, route checks, port
+// hopping interval, TCP transport details and admission approval.
+func (n *LocalNode) AnnounceRelayState(announcement *crypto.PeerAnnouncement) {
+	n.relayStateMu.RLock()
+	defer n.relayStateMu.RUnlock()
+	announcement.Tags = n.Tags
+	announcement.DNS = n.DNS
+	announcement.RouteChecks = n.routeChecks
+	announcement.PortHop = int64(n.PortHop / time.Second)
+	announcement.TCPPort = n.TCPPort
+	announcement.TCPVia = n.tcpVia
+	announcement.Approval = n.approval
+	announcement.Load = n.load
+	announcement.Relays = n.relays
+	announcement.IntroducerCandidate = n.introducerCandidate.Load()
+	announcement.IntroducerElected = n.introducerElected.Load()
+}
+
+func (n *LocalNode) setRelayState(load *crypto.IntroducerLoad, relays []string) {
+	n.relayStateMu.Lock()
+	defer n.relayStateMu.Unlock()
+	n.load = load
+	n.relays = relays
+}
+
+func (n *LocalNode) setTCPVia(introducer string) {
+	n.relayStateMu.Lock()
+	defer n.relayStateMu.Unlock()
+	n.tcpVia = introducer
+}
+
+func (n *LocalNode) setRouteChecks(checks []crypto.RouteCheck) {
+	n.relayStateMu.Lock()
+	defer n.relayStateMu.Unlock()
+	n.routeChecks = checks
+}
+
+func (n *LocalNode) setApproval(approval *crypto.AdmissionApproval) {
+	n.relayStateMu.Lock()
+	defer n.relayStateMu.Unlock()
+	n.approval = approval
+}
+
 // DiscoveryLayer is the interface for discovery implementations
 type DiscoveryLayer interface {
 	Start() error
 	Stop() error
 }
 
+// WireGuardParticipant is implemented by discovery layers that read the
+// WireGuard interface, such as handshake times while hole punching, so they
+// see the same backend as the daemon.
+type WireGuardParticipant interface {
+	SetWireGuardBackend(wg wireguard.Backend)
+}
+
 // parseLogLevel converts a log level string to slog.Level.
 func parseLogLevel(level string) slog.Level {
 	switch strings.ToLower(level) {
@@ -154,28 +291,46 @@ func ConfigureLogging(level string) {
 	configureLogging(level)
 }
 
+// logLevel is the level of the logger installed by ConfigureLogging.
+// Reloads change it in place through setLogLevel.
+var logLevel slog.LevelVar
+
 func configureLogging(level string) {
-	lvl := parseLogLevel(level)
+	setLogLevel(level)
 	handler := slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
-		Level: lvl,
+		Level: &logLevel,
 	})
 	slog.SetDefault(slog.New(handler))
 
 	// Redirect stdlib log.Printf → slog at the configured level so that
 	// legacy log.Printf calls are never silenced by a stricter filter.
 	// e.g. --log-level warn: log.Printf emits at WARN, still visible.
-	log.SetOutput(&slogWriter{level: lvl})
+	log.SetOutput(&slogWriter{level: &logLevel})
 	log.SetFlags(0) // slog adds its own timestamp
 }
 
-// slogWriter adapts log.Printf output to slog at a fixed level.
+// SetLogMeshName adds mesh=<name> to every line of the logger installed by
+// ConfigureLogging, so the logs of several meshes on one host tell apart.
+func SetLogMeshName(name string) {
+	if name != "" {
+		slog.SetDefault(slog.Default().With("mesh", name))
+	}
+}
+
+// setLogLevel changes the level of the logger installed by ConfigureLogging.
+// It has no effect on loggers set up by an embedding application.
+func setLogLevel(level string) {
+	logLevel.Set(parseLogLevel(level))
+}
+
+// slogWriter adapts log.Printf output to slog at the configured level.
 type slogWriter struct {
-	level slog.Level
+	level slog.Leveler
 }
 
 func (w *slogWriter) Write(p []byte) (n int, err error) {
 	msg := strings.TrimRight(string(p), "\n")
-	slog.Log(context.Background(), w.level, msg)
+	slog.Log(context.Background(), w.level.Level(), msg)
 	return len(p), nil
 }
 
@@ -185,6 +340,7 @@ func NewDaemon(config *Config) (*Daemon, error) {
 
 	d := &Daemon{
 		config:                 config,
+		wgBackend:              wireguard.Local{},
 		peerStore:              NewPeerStore(),
 		lastAppliedPeerConfigs: make(map[string]string),
 		relayRoutes:            make(map[string]string),
@@ -199,6 +355,7 @@ func NewDaemon(config *Config) (*Daemon, error) {
 		ctx:                    ctx,
 		cancel:                 cancel,
 	}
+	d.peerStore.SetExpiry(config.PeerDeadAfter, config.PeerRemoveAfter)
 
 	return d, nil
 }
@@ -208,6 +365,34 @@ func (d *Daemon) SetDHTDiscovery(dht DiscoveryLayer) {
 	d.dhtDiscovery = dht
 }
 
+// attachDiscovery makes layer the daemon's discovery and hands it whatever
+// of the daemon's handlers and WireGuard backend it can use, before it is
+// started.
+func (d *Daemon) attachDiscovery(layer DiscoveryLayer) {
+	d.dhtDiscovery = layer
+	if participant, ok := layer.(WireGuardParticipant); ok {
+		participant.SetWireGuardBackend(d.wireGuard())
+	}
+	if participant, ok := layer.(RotationParticipant); ok {
+		participant.SetRotationHandler(d.handleRotationMessage)
+	}
+	if participant, ok := layer.(BroadcastParticipant); ok {
+		participant.SetBroadcastHandler(d.handleBroadcastMessage)
+	}
+	if participant, ok := layer.(ApprovalParticipant); ok {
+		participant.SetApprovalHandler(d.handleApprovalMessage)
+	}
+}
+
+// wireGuard returns the backend for the WireGuard interface. NewDaemon runs
+// the wg tool; tests swap in a wireguard.FakeBackend.
+func (d *Daemon) wireGuard() wireguard.Backend {
+	if d.wgBackend == nil {
+		return wireguard.Local{}
+	}
+	return d.wgBackend
+}
+
 // Run starts the daemon and blocks until stopped
 func (d *Daemon) Run() error {
 	d.startTime = time.Now()
@@ -223,28 +408,74 @@ func (d *Daemon) Run() error {
 	if d.localNode.MeshIPv6 != "" {
 		log.Printf("Mesh IPv6: %s", d.localNode.MeshIPv6)
 	}
+	if err := d.startHealthServer(); err != nil {
+		return fmt.Errorf("failed to start healthcheck: %w", err)
+	}
 
 	// Setup WireGuard interface
 	if err := d.setupWireGuard(); err != nil {
 		return fmt.Errorf("failed to setup WireGuard: %w", err)
 	}
 	defer d.teardownWireGuard()
+	if err := d.setupSubnetRouter(); err != nil {
+		return fmt.Errorf("failed to setup subnet router: %w", err)
+	}
+	defer d.teardownSubnetRouter()
+	defer d.teardownShaping()
+	if err := d.setupRouteTable(); err != nil {
+		return fmt.Errorf("failed to setup route table: %w", err)
+	}
+	defer d.teardownRouteTable()
+	defer d.teardownSplitDNS()
+	if err := d.setupMTU(); err != nil {
+		return err
+	}
+	defer d.teardownMSSClamp()
 	d.setLocalWGEndpoint()
 	if err := d.startMeshProbeServer(); err != nil {
 		log.Printf("[Health] Failed to start mesh probe server: %v", err)
 	}
+	if err := d.setupFirewall(); err != nil {
+		return fmt.Errorf("failed to setup firewall: %w", err)
+	}
+	defer d.teardownFirewall()
+	if err := d.startTCPTransportServer(); err != nil {
+		return fmt.Errorf("failed to start TCP transport: %w", err)
+	}
+	d.setupAdmission()
+	d.setupIdentityPinning()
 
 	// Start DHT discovery if configured
-	if d.dhtDiscovery != nil {
+	if d.dhtDiscovery != nil && d.config.StaticPeers == "" {
 		if err := d.dhtDiscovery.Start(); err != nil {
 			return fmt.Errorf("failed to start DHT discovery: %w", err)
 		}
 		defer d.dhtDiscovery.Stop()
+		d.health.setDiscovery("dht")
+	}
+
+	// Merge statically defined peers from a centralized state file
+	if d.config.CentralState != "" {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.centralStateLoop()
+		}()
+	}
+	if d.config.StaticPeers != "" {
+		d.health.setDiscovery("static")
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.staticPeersLoop()
+		}()
 	}
 
 	// Setup signal handling
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	if !d.config.NoSignals {
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	}
 
 	// Start reconciliation loop
 	d.wg.Add(1)
@@ -269,6 +500,90 @@ func (d *Daemon) Run() error {
 	// Keep persistent mesh-VPN health connections to peers
 	go d.meshProbeLoop()
 
+	// Fail over roamed peers without waiting for reconcile
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.peerEventLoop()
+	}()
+
+	// Pick the best of each peer's announced endpoint candidates
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.pathSelectLoop()
+	}()
+
+	// Clamp TCP MSS towards peers whose path drops full-size packets
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.pmtuLoop()
+	}()
+
+	// Follow --port-hop schedules, ours and our peers'
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.portHopLoop()
+	}()
+
+	// Fall back to the TCP transport when UDP is blocked
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.tcpTransportLoop()
+	}()
+
+	// Repair peer entries dropped from WireGuard behind reconcile's back
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.wgVerifyLoop()
+	}()
+
+	// Elect introducers among well-connected nodes when none is configured
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.introducerElectionLoop()
+	}()
+
+	// Re-discover and re-announce right away on resume or a network change
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.networkMonitorLoop()
+	}()
+
+	// Estimate the local clock's skew from the mesh
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.clockSkewLoop()
+	}()
+
+	// Warn about peers on incompatible protocol revisions
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.versionSkewLoop()
+	}()
+
+	// Watch FDs, goroutines and probe sessions against their limits
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.resourceGuardLoop()
+	}()
+
+	// Probe --route-check targets and announce their health
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.routeCheckLoop()
+	}()
+
 	log.Printf("Daemon running. Press Ctrl+C to stop.")
 
 	// Wait for shutdown signal
@@ -290,6 +605,9 @@ func (d *Daemon) Run() error {
 	d.cancel()
 	log.Printf("Waiting for background tasks to complete...")
 	d.wg.Wait()
+	if d.meshIPChanged.Load() {
+		return ErrMeshIPChanged
+	}
 	return nil
 }
 
@@ -327,7 +645,7 @@ func (d *Daemon) initLocalNode() error {
 	}
 
 	// Try to load existing key from state file
-	stateFile := fmt.Sprintf("/var/lib/wgmesh/%s.json", d.config.InterfaceName)
+	stateFile := localNodeStatePath(d.config.InterfaceName)
 	node, err := loadLocalNode(stateFile)
 	if err == nil && node != nil {
 		d.localNode = node
@@ -363,9 +681,23 @@ func (d *Daemon) initLocalNode() error {
 			}
 		}
 
+		// State files written before identity signing have no identity key.
+		if d.localNode.IdentityKey == nil {
+			if d.localNode.IdentityKey, err = crypto.GenerateIdentityKey(); err != nil {
+				return err
+			}
+			if err := saveLocalNode(stateFile, d.localNode); err != nil {
+				log.Printf("Warning: failed to save local node state: %v", err)
+			}
+		}
+
 		d.localNode.RoutableNetworks = d.config.AdvertiseRoutes
 		d.localNode.Introducer = d.config.Introducer
 		d.localNode.Hostname = hostname
+		d.localNode.Tags = d.config.Tags
+		d.localNode.DNS = d.config.AdvertiseDNS
+		d.localNode.PortHop = d.config.PortHop
+		d.localNode.TCPPort = d.config.TCPTransportPort
 		return nil
 	}
 
@@ -388,6 +720,11 @@ func (d *Daemon) initLocalNode() error {
 	}
 	meshIPv6 := crypto.DeriveMeshIPv6(d.config.Keys.MeshPrefixV6, publicKey, d.config.Secret)
 
+	identityKey, err := crypto.GenerateIdentityKey()
+	if err != nil {
+		return err
+	}
+
 	d.localNode = &LocalNode{
 		WGPubKey:         publicKey,
 		WGPrivateKey:     privateKey,
@@ -396,6 +733,11 @@ func (d *Daemon) initLocalNode() error {
 		RoutableNetworks: d.config.AdvertiseRoutes,
 		Introducer:       d.config.Introducer,
 		Hostname:         hostname,
+		Tags:             d.config.Tags,
+		DNS:              d.config.AdvertiseDNS,
+		PortHop:          d.config.PortHop,
+		TCPPort:          d.config.TCPTransportPort,
+		IdentityKey:      identityKey,
 	}
 
 	// Save to state file
@@ -408,8 +750,17 @@ func (d *Daemon) initLocalNode() error {
 
 // setupWireGuard creates and configures the WireGuard interface
 func (d *Daemon) setupWireGuard() error {
+	if d.tunnel != nil {
+		return d.setupTunnel()
+	}
 	log.Printf("Setting up WireGuard interface %s...", d.config.InterfaceName)
 
+	if d.config.GracefulRestart || d.config.AdoptInterface {
+		if adopted, err := d.adoptInterface(); adopted || err != nil {
+			return err
+		}
+	}
+
 	// Check if interface exists
 	if interfaceExists(d.config.InterfaceName) {
 		// Check if existing interface already has our port
@@ -430,17 +781,9 @@ func (d *Daemon) setupWireGuard() error {
 		}
 	}
 
-	// Check if port is in use by another interface
-	listenPort := d.config.WGListenPort
-	if isPortInUse(listenPort) {
-		// Port is in use - find an available one
-		availablePort := findAvailablePort(listenPort + 1)
-		if availablePort == 0 {
-			return fmt.Errorf("port %d is in use and no available ports found (try --listen-port with a different port)", listenPort)
-		}
-		log.Printf("Port %d is in use, using port %d instead", listenPort, availablePort)
-		listenPort = availablePort
-		d.config.WGListenPort = availablePort
+	listenPort, err := d.pickListenPort()
+	if err != nil {
+		return err
 	}
 
 	// Configure interface with private key and listen port
@@ -467,10 +810,39 @@ func (d *Daemon) setupWireGuard() error {
 	return nil
 }
 
+// pickListenPort returns the configured WireGuard port, or the next free
+// one when another socket holds it.
+func (d *Daemon) pickListenPort() (int, error) {
+	listenPort := d.config.WGListenPort
+	if isPortInUse(listenPort) {
+		// Port is in use - find an available one
+		availablePort := findAvailablePort(listenPort + 1)
+		if availablePort == 0 {
+			return 0, fmt.Errorf("port %d is in use and no available ports found (try --listen-port with a different port)", listenPort)
+		}
+		log.Printf("Port %d is in use, using port %d instead", listenPort, availablePort)
+		listenPort = availablePort
+		d.config.WGListenPort = availablePort
+	}
+	return listenPort, nil
+}
+
 func (d *Daemon) teardownWireGuard() {
 	if d == nil || d.config == nil || d.config.InterfaceName == "" {
 		return
 	}
+	if d.tunnel != nil {
+		d.teardownTunnel()
+		return
+	}
+	if d.config.GracefulRestart && !d.leaving.Load() {
+		log.Printf("[Shutdown] Leaving WireGuard interface %s and its peers up (--graceful-restart)", d.config.InterfaceName)
+		return
+	}
+	if d.restarting.Load() && !d.leaving.Load() {
+		log.Printf("[Shutdown] Leaving WireGuard interface %s and its peers up for the restart", d.config.InterfaceName)
+		return
+	}
 
 	if err := setInterfaceDown(d.config.InterfaceName); err != nil {
 		log.Printf("[Shutdown] Failed to bring down interface %s: %v", d.config.InterfaceName, err)
@@ -500,20 +872,30 @@ func (d *Daemon) reconcileLoop() {
 // reconcile updates WireGuard configuration based on discovered peers
 func (d *Daemon) reconcile() {
 	start := time.Now()
+	d.health.lastReconcile.Store(start.UnixNano())
+	ctx, span := tracing.Start(d.ctx, "reconcile")
+	defer span.End()
 
 	peers := d.peerStore.GetActive()
-	desired, relayRoutes, directStable := d.buildDesiredPeerConfigs(peers)
-	d.relayMu.Lock()
-	d.relayRoutes = relayRoutes
-	d.directStableCycles = directStable
-	d.relayMu.Unlock()
-	if err := d.applyDesiredPeerConfigs(desired); err != nil {
+	resolution := d.resolvePeerRoutes(peers)
+	d.trackRoutes(peers, resolution)
+	if err := d.syncWireGuardPeers(ctx, peers); err != nil {
 		log.Printf("Failed to apply WireGuard peer configuration: %v", err)
+		span.RecordError(err)
 	}
 
+	_, routesSpan := tracing.Start(ctx, "reconcile.routes")
 	if err := d.syncPeerRoutes(peers); err != nil {
 		log.Printf("Failed to sync peer routes: %v", err)
+		routesSpan.RecordError(err)
+		span.RecordError(err)
 	}
+	routesSpan.End()
+	d.syncSplitDNS(peers, resolution.accepted)
+	if err := d.syncShaping(peers, resolution.accepted); err != nil {
+		log.Printf("[Shaping] %v", err)
+	}
+	span.SetAttributes(tracing.Int("peers.active", len(peers)))
 
 	// Check for mesh IP collisions
 	d.CheckAndResolveCollisions()
@@ -525,13 +907,35 @@ func (d *Daemon) reconcile() {
 	ObserveReconcileDuration(start)
 }
 
+// syncWireGuardPeers decides which peers are reached directly and which
+// through a relay, and brings the interface's peers in line.
+func (d *Daemon) syncWireGuardPeers(ctx context.Context, peers []*PeerInfo) error {
+	_, buildSpan := tracing.Start(ctx, "reconcile.build")
+	desired, relayRoutes, directStable := d.buildDesiredPeerConfigs(peers)
+	buildSpan.SetAttributes(tracing.Int("peers.desired", len(desired)), tracing.Int("peers.relayed", len(relayRoutes)))
+	buildSpan.End()
+	d.relayMu.Lock()
+	d.relayRoutes = relayRoutes
+	d.directStableCycles = directStable
+	d.relayMu.Unlock()
+	d.updateRelayState(relayRoutes)
+	_, applySpan := tracing.Start(ctx, "reconcile.apply")
+	defer applySpan.End()
+	if err := d.applyDesiredPeerConfigs(desired); err != nil {
+		applySpan.RecordError(err)
+		return err
+	}
+	return nil
+}
+
 type desiredPeerConfig struct {
 	peer    *PeerInfo
 	allowed map[string]struct{}
 }
 
 func (d *Daemon) buildDesiredPeerConfigs(peers []*PeerInfo) (map[string]*desiredPeerConfig, map[string]string, map[string]int) {
-	handshakes, _ := wireguard.GetLatestHandshakes(d.config.InterfaceName)
+	handshakes, _ := d.wireGuard().GetLatestHandshakes(d.config.InterfaceName)
+	d.probePresharedKeys(peers, handshakes, time.Now())
 	return d.buildDesiredPeerConfigsWithHandshakes(peers, handshakes)
 }
 
@@ -542,6 +946,8 @@ func (d *Daemon) buildDesiredPeerConfigsWithHandshakes(peers []*PeerInfo, handsh
 	relayCandidates := make([]*PeerInfo, 0)
 	now := time.Now()
 	localSubnets := d.getLocalSubnets()
+	peerRoutes := d.resolvePeerRoutes(peers).accepted
+	installed := d.selectInstalledPeers(peers, now)
 
 	for _, p := range peers {
 		if p.WGPubKey == d.localNode.WGPubKey || p.WGPubKey == "" {
@@ -550,11 +956,16 @@ func (d *Daemon) buildDesiredPeerConfigsWithHandshakes(peers []*PeerInfo, handsh
 		if d.isTemporarilyOffline(p.WGPubKey) {
 			continue
 		}
-		if p.Introducer && p.Endpoint != "" && now.Sub(p.LastSeen) <= RelayCandidateMaxAge {
+		if p.Introducer && p.Endpoint != "" && now.Sub(p.LastSeen) <= RelayCandidateMaxAge && d.peerAllowed(p) {
 			relayCandidates = append(relayCandidates, p)
 		}
 	}
 
+	// Over the TCP transport only the introducer at the other end can relay.
+	if via := d.tcpTunnelVia(); via != "" {
+		relayCandidates = slices.DeleteFunc(relayCandidates, func(p *PeerInfo) bool { return p.WGPubKey != via })
+	}
+
 	prevRelayRoutes := d.currentRelayRoutesSnapshot()
 	prevDirectStable := d.directStableCyclesSnapshot()
 
@@ -565,6 +976,18 @@ func (d *Daemon) buildDesiredPeerConfigsWithHandshakes(peers []*PeerInfo, handsh
 		if d.isTemporarilyOffline(p.WGPubKey) {
 			continue
 		}
+		// Peers outside the topology policy get no tunnel of their own;
+		// they stay reachable through a relay the policy allows.
+		if !d.peerAllowed(p) {
+			if relay := d.selectRelayForPeer(p, relayCandidates); relay != nil {
+				relayRoutes[p.WGPubKey] = relay.WGPubKey
+				d.addPeerAllowedIPs(desired, relay, p, peerRoutes[p.WGPubKey])
+			}
+			continue
+		}
+		if installed != nil && !installed[p.WGPubKey] {
+			continue
+		}
 
 		shouldRelay := d.shouldRelayPeerWithSubnets(p, relayCandidates, handshakes, localSubnets)
 
@@ -585,35 +1008,30 @@ func (d *Daemon) buildDesiredPeerConfigsWithHandshakes(peers []*PeerInfo, handsh
 			relay := d.selectRelayForPeer(p, relayCandidates)
 			if relay != nil {
 				relayRoutes[p.WGPubKey] = relay.WGPubKey
-				d.addAllowedIP(desired, relay, p.MeshIP+"/32")
-				if p.MeshIPv6 != "" {
-					d.addAllowedIP(desired, relay, p.MeshIPv6+"/128")
-				}
-				for _, network := range p.RoutableNetworks {
-					network = strings.TrimSpace(network)
-					if network != "" {
-						d.addAllowedIP(desired, relay, network)
-					}
-				}
+				d.addPeerAllowedIPs(desired, relay, p, peerRoutes[p.WGPubKey])
 				continue
 			}
 		}
 
-		d.addAllowedIP(desired, p, p.MeshIP+"/32")
-		if p.MeshIPv6 != "" {
-			d.addAllowedIP(desired, p, p.MeshIPv6+"/128")
-		}
-		for _, network := range p.RoutableNetworks {
-			network = strings.TrimSpace(network)
-			if network != "" {
-				d.addAllowedIP(desired, p, network)
-			}
-		}
+		d.steerEndpoint(p, handshakes, localSubnets, now)
+		d.addPeerAllowedIPs(desired, p, p, peerRoutes[p.WGPubKey])
 	}
 
 	return desired, relayRoutes, newDirectStable
 }
 
+// addPeerAllowedIPs routes p's mesh addresses and networks through via:
+// p itself for a direct tunnel, or the relay that reaches it.
+func (d *Daemon) addPeerAllowedIPs(desired map[string]*desiredPeerConfig, via, p *PeerInfo, networks []string) {
+	d.addAllowedIP(desired, via, p.MeshIP+"/32")
+	if p.MeshIPv6 != "" {
+		d.addAllowedIP(desired, via, p.MeshIPv6+"/128")
+	}
+	for _, network := range networks {
+		d.addAllowedIP(desired, via, network)
+	}
+}
+
 // shouldRelayPeer decides whether traffic to a peer should be routed via
 // an introducer relay. Relay is used when:
 //   - Both this node and the peer have symmetric NAT (hole-punch unreliable), OR
@@ -625,9 +1043,12 @@ func (d *Daemon) shouldRelayPeer(peer *PeerInfo, relayCandidates []*PeerInfo, ha
 }
 
 func (d *Daemon) shouldRelayPeerWithSubnets(peer *PeerInfo, relayCandidates []*PeerInfo, handshakes map[string]int64, localSubnets []*net.IPNet) bool {
-	if d.config.Introducer {
+	if d.config.Introducer || d.localNode.IsIntroducer() {
 		return false // Introducers are always direct
 	}
+	if via := d.tcpTunnelVia(); via != "" {
+		return peer.WGPubKey != via && len(relayCandidates) > 0 // UDP is blocked
+	}
 	if peer.Introducer {
 		return false // Don't relay to an introducer
 	}
@@ -637,12 +1058,15 @@ func (d *Daemon) shouldRelayPeerWithSubnets(peer *PeerInfo, relayCandidates []*P
 	if endpointOnAnyLocalSubnet(peer.Endpoint, localSubnets) {
 		return false // Local subnet peers should stay direct
 	}
-	if d.config.ForceRelay {
+	if d.forceRelay() {
 		return len(relayCandidates) > 0
 	}
 	if len(relayCandidates) == 0 {
 		return false // No relay available
 	}
+	if peer.TCPVia != "" {
+		return true // Only reachable through the introducer it tunnels to
+	}
 
 	// Check WG handshake first — if we've had a recent handshake, direct
 	// connectivity is confirmed regardless of NAT type or IPv6.
@@ -715,13 +1139,89 @@ func (d *Daemon) selectRelayForPeer(peer *PeerInfo, relayCandidates []*PeerInfo)
 		return sorted[i].WGPubKey < sorted[j].WGPubKey
 	})
 
+	// Drop relays whose probes are failing; if all are, keep them all
+	// rather than stranding the peer.
+	healthy := make([]*PeerInfo, 0, len(sorted))
+	for _, candidate := range sorted {
+		if d.relayHealthy(candidate) {
+			healthy = append(healthy, candidate)
+		}
+	}
+	if len(healthy) == 0 {
+		healthy = sorted
+	}
+
+	// Keep new assignments off overloaded introducers. Peers already
+	// relayed through one stay, so its load does not swing back and forth.
+	d.relayMu.RLock()
+	current := d.relayRoutes[peer.WGPubKey]
+	d.relayMu.RUnlock()
+	available := make([]*PeerInfo, 0, len(healthy))
+	for _, candidate := range healthy {
+		if candidate.WGPubKey == current || !IntroducerOverloaded(candidate) {
+			available = append(available, candidate)
+		}
+	}
+	if len(available) > 0 {
+		healthy = available
+	}
+
+	// Prefer the lowest measured RTT. Relays within RelayLatencyTolerance of
+	// the best are treated as equal so small jitter does not move routes.
+	var best time.Duration
+	for _, candidate := range healthy {
+		if candidate.Latency != nil && (best == 0 || *candidate.Latency < best) {
+			best = *candidate.Latency
+		}
+	}
+	tied := healthy
+	if best > 0 {
+		limit := best + time.Duration(float64(best)*RelayLatencyTolerance)
+		if limit < best+RelayLatencySlack {
+			limit = best + RelayLatencySlack
+		}
+		tied = make([]*PeerInfo, 0, len(healthy))
+		for _, candidate := range healthy {
+			if candidate.Latency != nil && *candidate.Latency <= limit {
+				tied = append(tied, candidate)
+			}
+		}
+	}
+
+	// A node on the TCP transport is only reachable through its introducer.
+	if peer.TCPVia != "" {
+		for _, candidate := range sorted {
+			if candidate.WGPubKey == peer.TCPVia {
+				return candidate
+			}
+		}
+	}
+
+	// Stay on the current relay while it is still among the best.
+	for _, candidate := range tied {
+		if candidate.WGPubKey == current {
+			return candidate
+		}
+	}
+
 	h := fnv.New64a()
 	_, _ = h.Write([]byte(d.localNode.WGPubKey))
 	_, _ = h.Write([]byte{0})
 	_, _ = h.Write([]byte(peer.WGPubKey))
-	idx := int(h.Sum64() % uint64(len(sorted)))
+	idx := int(h.Sum64() % uint64(len(tied)))
+
+	return tied[idx]
+}
 
-	return sorted[idx]
+// relayHealthy reports whether a relay candidate's mesh probes are passing.
+func (d *Daemon) relayHealthy(relay *PeerInfo) bool {
+	d.probeMu.Lock()
+	failures := d.probeFailures[relay.WGPubKey]
+	d.probeMu.Unlock()
+	if failures > 0 {
+		return false
+	}
+	return relay.PacketLoss == nil || *relay.PacketLoss < RelayMaxProbeLoss
 }
 
 func (d *Daemon) addAllowedIP(desired map[string]*desiredPeerConfig, peer *PeerInfo, cidr string) {
@@ -738,11 +1238,14 @@ func (d *Daemon) addAllowedIP(desired map[string]*desiredPeerConfig, peer *PeerI
 }
 
 func (d *Daemon) applyDesiredPeerConfigs(desired map[string]*desiredPeerConfig) error {
-	existing, err := wireguard.GetPeers(d.config.InterfaceName)
+	existing, err := d.wireGuard().GetPeers(d.config.InterfaceName)
 	if err == nil {
 		for _, current := range existing {
 			if _, ok := desired[current.PublicKey]; !ok {
-				if err := wireguard.RemovePeer(d.config.InterfaceName, current.PublicKey); err != nil {
+				if d.adopted.hold(current.PublicKey, time.Now()) {
+					continue
+				}
+				if err := d.wireGuard().RemovePeer(d.config.InterfaceName, current.PublicKey); err != nil {
 					log.Printf("Failed to remove obsolete peer %s...: %v", shortKey(current.PublicKey), err)
 				}
 				d.appliedMu.Lock()
@@ -753,10 +1256,12 @@ func (d *Daemon) applyDesiredPeerConfigs(desired map[string]*desiredPeerConfig)
 	}
 
 	for pubKey, cfg := range desired {
-		if cfg.peer.Endpoint == "" {
+		d.adopted.release(pubKey)
+		endpoint := d.effectiveEndpoint(cfg.peer)
+		if endpoint == "" {
 			continue
 		}
-		if d.config.DisableIPv6 && isIPv6Endpoint(cfg.peer.Endpoint) {
+		if d.config.DisableIPv6 && isIPv6Endpoint(endpoint) {
 			continue
 		}
 		allowed := mapKeysSorted(cfg.allowed)
@@ -764,7 +1269,9 @@ func (d *Daemon) applyDesiredPeerConfigs(desired map[string]*desiredPeerConfig)
 			continue
 		}
 		allowedCSV := strings.Join(allowed, ",")
-		signature := cfg.peer.Endpoint + "|" + allowedCSV
+		keepalive := d.keepaliveForPeer(cfg.peer)
+		psk, pskKind := d.presharedKey(cfg.peer)
+		signature := endpoint + "|" + allowedCSV + "|" + strconv.Itoa(keepalive) + "|" + pskKind
 
 		// Check-and-mark under the same lock to avoid TOCTOU (W4)
 		d.appliedMu.Lock()
@@ -776,7 +1283,7 @@ func (d *Daemon) applyDesiredPeerConfigs(desired map[string]*desiredPeerConfig)
 		d.lastAppliedPeerConfigs[pubKey] = signature
 		d.appliedMu.Unlock()
 
-		if err := wireguard.SetPeer(d.config.InterfaceName, pubKey, d.config.Keys.PSK, cfg.peer.Endpoint, allowedCSV); err != nil {
+		if err := d.wireGuard().SetPeer(d.config.InterfaceName, pubKey, psk, endpoint, allowedCSV, keepalive); err != nil {
 			// Rollback the optimistic write on failure
 			d.appliedMu.Lock()
 			delete(d.lastAppliedPeerConfigs, pubKey)
@@ -788,6 +1295,28 @@ func (d *Daemon) applyDesiredPeerConfigs(desired map[string]*desiredPeerConfig)
 	return nil
 }
 
+// keepaliveForPeer returns the PersistentKeepalive interval for a peer.
+// An explicit --keepalive wins; otherwise keepalive is only disabled when
+// both sides are known to have public addresses (NAT type "none"), since
+// there is no NAT mapping to keep open. Any uncertainty keeps it on.
+func (d *Daemon) keepaliveForPeer(peer *PeerInfo) int {
+	switch {
+	case d.config.Keepalive > 0:
+		return d.config.Keepalive
+	case d.config.Keepalive < 0:
+		return 0
+	}
+
+	localNAT := ""
+	if d.localNode != nil {
+		localNAT = d.localNode.NATType
+	}
+	if localNAT == "none" && peer != nil && peer.NATType == "none" {
+		return 0
+	}
+	return DefaultKeepalive
+}
+
 func mapKeysSorted(m map[string]struct{}) []string {
 	out := make([]string, 0, len(m))
 	for k := range m {
@@ -873,7 +1402,7 @@ func endpointOnAnyLocalSubnet(endpoint string, subnets []*net.IPNet) bool {
 
 // removePeer removes a peer from the WireGuard configuration
 func (d *Daemon) removePeer(pubKey string) error {
-	return wireguard.RemovePeer(d.config.InterfaceName, pubKey)
+	return d.wireGuard().RemovePeer(d.config.InterfaceName, pubKey)
 }
 
 // statusLoop periodically prints mesh status
@@ -892,7 +1421,9 @@ func (d *Daemon) statusLoop() {
 }
 
 func (d *Daemon) staleCleanupLoop() {
-	ticker := time.NewTicker(StaleCleanupInterval)
+	// Check often enough that a short --peer-remove-after is honoured.
+	_, removeAfter := d.peerStore.Expiry()
+	ticker := time.NewTicker(min(StaleCleanupInterval, removeAfter/4))
 	defer ticker.Stop()
 
 	for {
@@ -966,6 +1497,10 @@ func (d *Daemon) startMeshProbeServer() error {
 		}
 	}()
 
+	if err := d.startPMTUResponder(); err != nil {
+		log.Printf("[MTU] Path MTU responder bind failed: %v", err)
+	}
+
 	log.Printf("[Health] Mesh probe server listening on tcp/%d", d.healthProbePort)
 	return nil
 }
@@ -981,7 +1516,17 @@ func (d *Daemon) acceptProbeConnections(ln net.Listener) {
 			}
 			continue
 		}
-		go handleProbeConnection(conn)
+		// Refuse rather than grow past the limit; the peer's prober
+		// retries on its next round.
+		if d.probeSessionCount() >= d.config.ResourceLimits.probeSessionLimit() {
+			_ = conn.Close()
+			continue
+		}
+		d.resources.inbound.Add(1)
+		go func() {
+			defer d.resources.inbound.Add(-1)
+			handleProbeConnection(conn)
+		}()
 	}
 }
 
@@ -1021,12 +1566,17 @@ func (d *Daemon) meshProbeLoop() {
 func (d *Daemon) probePeersOverMesh() {
 	peers := d.peerStore.GetActive()
 	activeSet := make(map[string]struct{}, len(peers))
-	handshakes, _ := wireguard.GetLatestHandshakes(d.config.InterfaceName)
+	handshakes, _ := d.wireGuard().GetLatestHandshakes(d.config.InterfaceName)
+	failover := false
 
 	for _, p := range peers {
 		if p == nil || p.WGPubKey == "" || p.WGPubKey == d.localNode.WGPubKey || p.MeshIP == "" {
 			continue
 		}
+		// Peers left out by --max-installed-peers have no tunnel to probe.
+		if !d.isInstalled(p.WGPubKey) {
+			continue
+		}
 		activeSet[p.WGPubKey] = struct{}{}
 
 		// If WG has a recent handshake, treat the peer as healthy and do not let
@@ -1037,7 +1587,16 @@ func (d *Daemon) probePeersOverMesh() {
 			d.probeMu.Lock()
 			d.probeFailures[p.WGPubKey] = 0
 			d.probeMu.Unlock()
-			d.closeProbeSession(p.WGPubKey)
+			// Gateways with standbys are probed every round so their routes
+			// fail over quickly; others still sample RTT/loss at a slower
+			// pace. Neither result leads to eviction.
+			if d.isFailoverGateway(p.WGPubKey) {
+				if d.recordGatewayProbe(p.WGPubKey, d.probePeer(p)) {
+					failover = true
+				}
+			} else if d.statsProbeDue(p.WGPubKey) {
+				d.probePeer(p)
+			}
 			continue
 		}
 
@@ -1052,7 +1611,11 @@ func (d *Daemon) probePeersOverMesh() {
 			continue
 		}
 
-		if d.probePeer(p) {
+		ok := d.probePeer(p)
+		if d.isFailoverGateway(p.WGPubKey) && d.recordGatewayProbe(p.WGPubKey, ok) {
+			failover = true
+		}
+		if ok {
 			d.clearTemporarilyOffline(p.WGPubKey)
 			d.probeMu.Lock()
 			d.probeFailures[p.WGPubKey] = 0
@@ -1072,52 +1635,99 @@ func (d *Daemon) probePeersOverMesh() {
 	}
 
 	d.cleanupProbeSessions(activeSet)
+
+	if failover {
+		d.reconcile()
+	}
 }
 
+// probePeer sends one ping over the peer's mesh probe session and feeds
+// the result into the peer's rolling RTT/loss statistics.
 func (d *Daemon) probePeer(peer *PeerInfo) bool {
 	if peer == nil || peer.WGPubKey == "" {
 		return false
 	}
 
+	var rtt time.Duration
+	ok := false
+	if !d.config.Chaos.FailProbe() {
+		rtt, ok = d.pingProbeSession(peer)
+	}
+	_, loss := d.peerStore.RecordProbe(peer.WGPubKey, rtt, ok)
+	ObservePeerProbeLoss(metricsPeerKey(peer.WGPubKey), loss)
+	d.recordPathProbe(peer.WGPubKey, ok)
+	return ok
+}
+
+// pingProbeSession round-trips one ping over the peer's persistent probe
+// session, dialing it first if needed. The RTT excludes the dial.
+func (d *Daemon) pingProbeSession(peer *PeerInfo) (time.Duration, bool) {
 	session := d.getOrDialProbeSession(peer)
 	if session == nil {
-		return false
+		return 0, false
 	}
 
 	_ = session.conn.SetWriteDeadline(time.Now().Add(MeshProbeDialTimeout))
 	start := time.Now()
 	if _, err := session.conn.Write([]byte("ping\n")); err != nil {
 		d.closeProbeSession(peer.WGPubKey)
-		return false
+		return 0, false
 	}
 
 	_ = session.conn.SetReadDeadline(time.Now().Add(MeshProbeDialTimeout))
 	line, err := session.reader.ReadString('\n')
 	if err != nil {
 		d.closeProbeSession(peer.WGPubKey)
-		return false
+		return 0, false
 	}
 
 	if strings.TrimSpace(line) != "pong" {
 		d.closeProbeSession(peer.WGPubKey)
-		return false
+		return 0, false
 	}
 
 	rtt := time.Since(start)
-	ObserveProbeRTT(peer.WGPubKey[:8], start)
-	ObserveProbeRTTSummary(peer.WGPubKey[:8], rtt)
-	d.peerStore.SetLatency(peer.WGPubKey, rtt)
+	ObserveProbeRTT(metricsPeerKey(peer.WGPubKey), start)
+	ObserveProbeRTTSummary(metricsPeerKey(peer.WGPubKey), rtt)
+	return rtt, true
+}
+
+// statsProbeDue reports whether a peer with a healthy handshake should be
+// sampled for RTT/loss statistics this round, and marks it sampled. Under
+// resource pressure none are.
+func (d *Daemon) statsProbeDue(pubKey string) bool {
+	now := time.Now()
+	if d.underResourcePressure() {
+		return false
+	}
+	d.probeMu.Lock()
+	defer d.probeMu.Unlock()
+	if d.lastStatsProbe == nil {
+		d.lastStatsProbe = make(map[string]time.Time)
+	}
+	if now.Sub(d.lastStatsProbe[pubKey]) < MeshProbeStatsInterval {
+		return false
+	}
+	d.lastStatsProbe[pubKey] = now
 	return true
 }
 
 func (d *Daemon) getOrDialProbeSession(peer *PeerInfo) *peerProbeSession {
 	d.probeMu.Lock()
 	s := d.probeSessions[peer.WGPubKey]
+	if s != nil {
+		s.lastUsed = time.Now()
+	}
 	d.probeMu.Unlock()
 	if s != nil {
 		return s
 	}
 
+	// Make room rather than grow past the limit.
+	if d.probeSessionCount() >= d.config.ResourceLimits.probeSessionLimit() {
+		d.shedProbeSessions(1)
+	}
+
 	addrs := []string{net.JoinHostPort(peer.MeshIP, strconv.Itoa(d.healthProbePort))}
 	if !d.config.DisableIPv6 && peer.MeshIPv6 != "" {
 		addrs = append([]string{net.JoinHostPort(peer.MeshIPv6, strconv.Itoa(d.healthProbePort))}, addrs...)
@@ -1128,7 +1738,7 @@ func (d *Daemon) getOrDialProbeSession(peer *PeerInfo) *peerProbeSession {
 		if err != nil {
 			continue
 		}
-		session := &peerProbeSession{conn: conn, reader: bufio.NewReader(conn)}
+		session := &peerProbeSession{conn: conn, reader: bufio.NewReader(conn), lastUsed: time.Now()}
 		d.probeMu.Lock()
 		d.probeSessions[peer.WGPubKey] = session
 		d.probeMu.Unlock()
@@ -1162,11 +1772,15 @@ func (d *Daemon) cleanupProbeSessions(activeSet map[string]struct{}) {
 		d.closeProbeSession(pubKey)
 		d.probeMu.Lock()
 		delete(d.probeFailures, pubKey)
+		delete(d.lastStatsProbe, pubKey)
 		d.probeMu.Unlock()
 	}
 }
 
 func (d *Daemon) listenProbeOnInterface(addr string) (net.Listener, error) {
+	if d.tunnel != nil {
+		return d.tunnel.Listen("tcp", addr)
+	}
 	lc := net.ListenConfig{}
 	if runtime.GOOS == "linux" && d.config.InterfaceName != "" {
 		iface := d.config.InterfaceName
@@ -1185,6 +1799,11 @@ func (d *Daemon) listenProbeOnInterface(addr string) (net.Listener, error) {
 }
 
 func (d *Daemon) dialProbeOnInterface(addr string) (net.Conn, error) {
+	if d.tunnel != nil {
+		ctx, cancel := context.WithTimeout(d.ctx, MeshProbeDialTimeout)
+		defer cancel()
+		return d.tunnel.DialContext(ctx, "tcp", addr)
+	}
 	dialer := net.Dialer{Timeout: MeshProbeDialTimeout}
 	if local := d.probeLocalAddrForRemote(addr); local != nil {
 		dialer.LocalAddr = local
@@ -1227,17 +1846,19 @@ func (d *Daemon) probeLocalAddrForRemote(remote string) net.Addr {
 }
 
 func (d *Daemon) checkPeerHealth() {
-	handshakes, err := wireguard.GetLatestHandshakes(d.config.InterfaceName)
+	handshakes, err := d.wireGuard().GetLatestHandshakes(d.config.InterfaceName)
 	if err != nil {
 		return
 	}
-	transfers, err := wireguard.GetPeerTransfers(d.config.InterfaceName)
+	transfers, err := d.wireGuard().GetPeerTransfers(d.config.InterfaceName)
 	if err != nil {
 		return
 	}
 
-	peers := d.peerStore.GetActive()
 	now := time.Now()
+	d.traffic.observe(transfers, now)
+
+	peers := d.peerStore.GetActive()
 	activeSet := make(map[string]struct{}, len(peers))
 
 	for _, p := range peers {
@@ -1312,18 +1933,16 @@ func (d *Daemon) attemptPeerReconnect(peer *PeerInfo) {
 	if peer.MeshIPv6 != "" {
 		allowed[peer.MeshIPv6+"/128"] = struct{}{}
 	}
-	for _, route := range peer.RoutableNetworks {
-		r := strings.TrimSpace(route)
-		if r != "" {
-			allowed[r] = struct{}{}
-		}
+	for _, network := range d.acceptedRoutes(peer.WGPubKey) {
+		allowed[network] = struct{}{}
 	}
 	allowedCSV := strings.Join(mapKeysSorted(allowed), ",")
 	if allowedCSV == "" {
 		return
 	}
 
-	if err := wireguard.SetPeer(d.config.InterfaceName, peer.WGPubKey, d.config.Keys.PSK, peer.Endpoint, allowedCSV); err != nil {
+	psk, _ := d.presharedKey(peer)
+	if err := d.wireGuard().SetPeer(d.config.InterfaceName, peer.WGPubKey, psk, d.effectiveEndpoint(peer), allowedCSV, d.keepaliveForPeer(peer)); err != nil {
 		log.Printf("[Health] Failed to reconnect peer %s...: %v", shortKey(peer.WGPubKey), err)
 		return
 	}
@@ -1339,24 +1958,31 @@ func (d *Daemon) evictPeerFromPool(peer *PeerInfo) {
 	log.Printf("[Health] Evicting unresponsive peer %s... from active pool", shortKey(peer.WGPubKey))
 	d.markTemporarilyOffline(peer.WGPubKey)
 	d.peerStore.Remove(peer.WGPubKey)
-	if err := wireguard.RemovePeer(d.config.InterfaceName, peer.WGPubKey); err != nil {
-		log.Printf("[Health] Failed to remove evicted peer %s... from WireGuard: %v", shortKey(peer.WGPubKey), err)
+	d.dropPeer(peer.WGPubKey)
+}
+
+// dropPeer removes a peer that has left the peer store from WireGuard and
+// clears the daemon's per-peer state.
+func (d *Daemon) dropPeer(pubKey string) {
+	if err := d.wireGuard().RemovePeer(d.config.InterfaceName, pubKey); err != nil {
+		log.Printf("[Peers] Failed to remove peer %s... from WireGuard: %v", shortKey(pubKey), err)
 	}
 	d.appliedMu.Lock()
-	delete(d.lastAppliedPeerConfigs, peer.WGPubKey)
+	delete(d.lastAppliedPeerConfigs, pubKey)
 	d.appliedMu.Unlock()
 	d.relayMu.Lock()
-	delete(d.relayRoutes, peer.WGPubKey)
-	delete(d.directStableCycles, peer.WGPubKey)
+	delete(d.relayRoutes, pubKey)
+	delete(d.directStableCycles, pubKey)
 	d.relayMu.Unlock()
 	d.healthMu.Lock()
-	delete(d.peerHealthFailures, peer.WGPubKey)
-	delete(d.lastPeerTransferTotal, peer.WGPubKey)
+	delete(d.peerHealthFailures, pubKey)
+	delete(d.lastPeerTransferTotal, pubKey)
 	d.healthMu.Unlock()
-	d.closeProbeSession(peer.WGPubKey)
+	d.closeProbeSession(pubKey)
 	d.probeMu.Lock()
-	delete(d.probeFailures, peer.WGPubKey)
+	delete(d.probeFailures, pubKey)
 	d.probeMu.Unlock()
+	d.clearPathSelection(pubKey)
 }
 
 func (d *Daemon) markTemporarilyOffline(pubKey string) {
@@ -1462,6 +2088,7 @@ func (d *Daemon) GetConfig() *Config {
 // RunWithDHTDiscovery runs the daemon with DHT discovery enabled
 // This is the main entry point for the join command
 func (d *Daemon) RunWithDHTDiscovery() error {
+	d.startTime = time.Now()
 	log.Printf("Starting wgmesh daemon with DHT discovery...")
 
 	// Load or create local node first
@@ -1475,19 +2102,51 @@ func (d *Daemon) RunWithDHTDiscovery() error {
 		log.Printf("Mesh IPv6: %s", d.localNode.MeshIPv6)
 	}
 	log.Printf("Network ID: %x (both nodes must show the same ID to find each other)", d.config.Keys.NetworkID[:8])
+	if d.config.MeshName != "" {
+		log.Printf("Mesh name: %s", d.config.MeshName)
+	}
+	if err := d.startHealthServer(); err != nil {
+		return fmt.Errorf("failed to start healthcheck: %w", err)
+	}
 
 	// Setup WireGuard interface
 	if err := d.setupWireGuard(); err != nil {
 		return fmt.Errorf("failed to setup WireGuard: %w", err)
 	}
 	defer d.teardownWireGuard()
+	if err := d.setupSubnetRouter(); err != nil {
+		return fmt.Errorf("failed to setup subnet router: %w", err)
+	}
+	defer d.teardownSubnetRouter()
+	defer d.teardownShaping()
+	if err := d.setupRouteTable(); err != nil {
+		return fmt.Errorf("failed to setup route table: %w", err)
+	}
+	defer d.teardownRouteTable()
+	defer d.teardownSplitDNS()
+	if err := d.setupMTU(); err != nil {
+		return err
+	}
+	defer d.teardownMSSClamp()
 	d.setLocalWGEndpoint()
 	if err := d.startMeshProbeServer(); err != nil {
 		log.Printf("[Health] Failed to start mesh probe server: %v", err)
 	}
+	if err := d.setupFirewall(); err != nil {
+		return fmt.Errorf("failed to setup firewall: %w", err)
+	}
+	defer d.teardownFirewall()
+	if err := d.startTCPTransportServer(); err != nil {
+		return fmt.Errorf("failed to start TCP transport: %w", err)
+	}
+	d.setupAdmission()
+	d.setupIdentityPinning()
 
-	// Restore peers from cache for faster startup
-	RestoreFromCache(d.config.InterfaceName, d.peerStore)
+	// Restore peers from cache for faster startup. An offline node takes
+	// its peers from the manifest alone.
+	if d.config.StaticPeers == "" {
+		RestoreFromCache(d.config.InterfaceName, d.peerStore)
+	}
 
 	// Start peer cache saver (cancelled via daemon context)
 	d.wg.Add(1)
@@ -1496,20 +2155,40 @@ func (d *Daemon) RunWithDHTDiscovery() error {
 		StartCacheSaver(d.ctx, d.config.InterfaceName, d.peerStore)
 	}()
 
-	// Now create DHT discovery with the initialized local node
+	// Now create discovery with the initialized local node
 	// Import is handled via interface to avoid circular dependency
-	dhtFactory := GetDHTDiscoveryFactory()
-	if dhtFactory != nil {
+	if d.config.StaticPeers != "" {
+		log.Printf("[Static] Offline mode: peers come only from %s (DHT, LAN discovery and STUN disabled)", d.config.StaticPeers)
+	} else if d.config.DNSRendezvous != "" {
+		dnsFactory := GetDNSDiscoveryFactory()
+		if dnsFactory == nil {
+			return fmt.Errorf("DNS rendezvous %q requested but DNS discovery factory not set", d.config.DNSRendezvous)
+		}
+		dns, err := dnsFactory(d.ctx, d.config, d.localNode, d.peerStore)
+		if err != nil {
+			return fmt.Errorf("failed to create DNS discovery: %w", err)
+		}
+		d.attachDiscovery(dns)
+
+		if err := d.dhtDiscovery.Start(); err != nil {
+			return fmt.Errorf("failed to start DNS discovery: %w", err)
+		}
+		defer d.dhtDiscovery.Stop()
+		d.health.setDiscovery("dns")
+		d.resumeRotation()
+	} else if dhtFactory := GetDHTDiscoveryFactory(); dhtFactory != nil {
 		dht, err := dhtFactory(d.ctx, d.config, d.localNode, d.peerStore)
 		if err != nil {
 			return fmt.Errorf("failed to create DHT discovery: %w", err)
 		}
-		d.dhtDiscovery = dht
+		d.attachDiscovery(dht)
 
 		if err := d.dhtDiscovery.Start(); err != nil {
 			return fmt.Errorf("failed to start DHT discovery: %w", err)
 		}
 		defer d.dhtDiscovery.Stop()
+		d.health.setDiscovery("dht")
+		d.resumeRotation()
 	} else {
 		log.Printf("Warning: DHT discovery factory not set, running without DHT")
 	}
@@ -1519,6 +2198,9 @@ func (d *Daemon) RunWithDHTDiscovery() error {
 		d.epochManager = NewEpochManager(d.config.Keys.EpochSeed)
 		d.epochManager.Start(d.ctx, d.getPrivacyPeers)
 		defer d.epochManager.Stop()
+		if p, ok := d.dhtDiscovery.(DandelionParticipant); ok {
+			p.SetDandelionRouter(d.epochManager.GetRouter())
+		}
 		log.Printf("Privacy mode enabled (Dandelion++ relay)")
 	}
 
@@ -1531,9 +2213,28 @@ func (d *Daemon) RunWithDHTDiscovery() error {
 		}
 	}
 
+	// Merge statically defined peers from a centralized state file
+	if d.config.CentralState != "" {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.centralStateLoop()
+		}()
+	}
+	if d.config.StaticPeers != "" {
+		d.health.setDiscovery("static")
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.staticPeersLoop()
+		}()
+	}
+
 	// Setup signal handling
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	if !d.config.NoSignals {
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	}
 
 	// Start reconciliation loop
 	d.wg.Add(1)
@@ -1558,6 +2259,106 @@ func (d *Daemon) RunWithDHTDiscovery() error {
 	// Keep persistent mesh-VPN health connections to peers
 	go d.meshProbeLoop()
 
+	// Fail over roamed peers without waiting for reconcile
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.peerEventLoop()
+	}()
+
+	// Pick the best of each peer's announced endpoint candidates
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.pathSelectLoop()
+	}()
+
+	// Clamp TCP MSS towards peers whose path drops full-size packets
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.pmtuLoop()
+	}()
+
+	// Follow --port-hop schedules, ours and our peers'
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.portHopLoop()
+	}()
+
+	// Fall back to the TCP transport when UDP is blocked
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.tcpTransportLoop()
+	}()
+
+	// Repair peer entries dropped from WireGuard behind reconcile's back
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.wgVerifyLoop()
+	}()
+
+	// Elect introducers among well-connected nodes when none is configured
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.introducerElectionLoop()
+	}()
+
+	// Re-discover and re-announce right away on resume or a network change
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.networkMonitorLoop()
+	}()
+
+	// Estimate the local clock's skew from the mesh
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.clockSkewLoop()
+	}()
+
+	// Warn about peers on incompatible protocol revisions
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.versionSkewLoop()
+	}()
+
+	// Watch FDs, goroutines and probe sessions against their limits
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.resourceGuardLoop()
+	}()
+
+	// Probe --route-check targets and announce their health
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.routeCheckLoop()
+	}()
+
+	// Switch secrets when a rotation's grace period ends
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.rotationLoop()
+	}()
+
+	// Install newer releases and restart into them
+	if d.config.AutoUpdate {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			d.autoUpdateLoop()
+		}()
+	}
+
 	log.Printf("Daemon running. Press Ctrl+C to stop.")
 
 	// Wait for shutdown signal
@@ -1580,6 +2381,15 @@ func (d *Daemon) RunWithDHTDiscovery() error {
 
 	log.Printf("Waiting for background tasks to complete...")
 	d.wg.Wait()
+	if err := d.finishRotation(); err != nil {
+		return err
+	}
+	if d.restarting.Load() && !d.leaving.Load() {
+		return ErrRestart
+	}
+	if d.meshIPChanged.Load() {
+		return ErrMeshIPChanged
+	}
 	return nil
 }
 
@@ -1601,6 +2411,24 @@ func GetDHTDiscoveryFactory() DHTDiscoveryFactory {
 	return dhtDiscoveryFactory
 }
 
+// DNSDiscoveryFactory creates a discovery layer that bootstraps from DNS
+// TXT/SRV records instead of the public DHT. It has the same shape as
+// DHTDiscoveryFactory so the daemon can treat both backends alike.
+type DNSDiscoveryFactory func(ctx context.Context, config *Config, localNode *LocalNode, peerStore *PeerStore) (DiscoveryLayer, error)
+
+var dnsDiscoveryFactory DNSDiscoveryFactory
+
+// SetDNSDiscoveryFactory sets the factory function for creating DNS discovery.
+// This is called by the discovery package to avoid circular imports
+func SetDNSDiscoveryFactory(factory DNSDiscoveryFactory) {
+	dnsDiscoveryFactory = factory
+}
+
+// GetDNSDiscoveryFactory returns the current DNS discovery factory
+func GetDNSDiscoveryFactory() DNSDiscoveryFactory {
+	return dnsDiscoveryFactory
+}
+
 // handleSIGHUP reads the reload file for the current interface and applies
 // any changed reloadable options, then triggers an immediate reconcile.
 // If no reload file exists the call is a no-op (warning is logged).
@@ -1616,6 +2444,9 @@ func (d *Daemon) handleSIGHUP() {
 		return
 	}
 	d.reloadConfig(opts)
+	if err := d.refreshSubnetRouter(); err != nil {
+		log.Printf("[Reload] Failed to update subnet router: %v", err)
+	}
 	d.reconcile()
 }
 
@@ -1649,6 +2480,7 @@ func (d *Daemon) reloadConfig(opts DaemonOpts) {
 	if d.config.LogLevel != opts.LogLevel && opts.LogLevel != "" {
 		log.Printf("[Reload] LogLevel: %q → %q", d.config.LogLevel, opts.LogLevel)
 		d.config.LogLevel = opts.LogLevel
+		setLogLevel(opts.LogLevel)
 	}
 
 	if opts.AdvertiseRoutes != nil && !routeSlicesEqual(d.config.AdvertiseRoutes, opts.AdvertiseRoutes) {
@@ -1692,9 +2524,10 @@ func (d *Daemon) getPrivacyPeers() []privacy.PeerInfo {
 	for _, p := range peers {
 		if p.WGPubKey != d.localNode.WGPubKey {
 			result = append(result, privacy.PeerInfo{
-				WGPubKey: p.WGPubKey,
-				MeshIP:   p.MeshIP,
-				Endpoint: p.Endpoint,
+				WGPubKey:   p.WGPubKey,
+				MeshIP:     p.MeshIP,
+				Endpoint:   p.Endpoint,
+				Introducer: p.Introducer,
 			})
 		}
 	}
@@ -1724,22 +2557,12 @@ func (d *Daemon) GetInterfaceName() string {
 // GetRPCPeers returns active peers for RPC (converts daemon PeerInfo to RPC PeerData)
 func (d *Daemon) GetRPCPeers() []*RPCPeerData {
 	peers := d.peerStore.GetActive()
+	relayRoutes := d.currentRelayRoutesSnapshot()
+	localSubnets := d.getLocalSubnets()
+	handshakes, _ := d.wireGuard().GetLatestHandshakes(d.config.InterfaceName)
 	result := make([]*RPCPeerData, 0, len(peers))
 	for _, p := range peers {
-		rpcPeer := &RPCPeerData{
-			WGPubKey:         p.WGPubKey,
-			Hostname:         p.Hostname,
-			MeshIP:           p.MeshIP,
-			Endpoint:         p.Endpoint,
-			LastSeen:         p.LastSeen,
-			DiscoveredVia:    p.DiscoveredVia,
-			RoutableNetworks: p.RoutableNetworks,
-		}
-		if p.Latency != nil {
-			ms := float64(p.Latency.Milliseconds())
-			rpcPeer.LatencyMs = &ms
-		}
-		result = append(result, rpcPeer)
+		result = append(result, d.rpcPeerData(p, relayRoutes, localSubnets, handshakes))
 	}
 	return result
 }
@@ -1750,20 +2573,51 @@ func (d *Daemon) GetRPCPeer(pubKey string) (*RPCPeerData, bool) {
 	if !exists {
 		return nil, false
 	}
+	handshakes, _ := d.wireGuard().GetLatestHandshakes(d.config.InterfaceName)
+	return d.rpcPeerData(peer, d.currentRelayRoutesSnapshot(), d.getLocalSubnets(), handshakes), true
+}
+
+// rpcPeerData converts p for RPC. handshakes are the interface's latest
+// handshakes by pubkey, as unix seconds.
+func (d *Daemon) rpcPeerData(p *PeerInfo, relayRoutes map[string]string, localSubnets []*net.IPNet, handshakes map[string]int64) *RPCPeerData {
 	rpcPeer := &RPCPeerData{
-		WGPubKey:         peer.WGPubKey,
-		Hostname:         peer.Hostname,
-		MeshIP:           peer.MeshIP,
-		Endpoint:         peer.Endpoint,
-		LastSeen:         peer.LastSeen,
-		DiscoveredVia:    peer.DiscoveredVia,
-		RoutableNetworks: peer.RoutableNetworks,
-	}
-	if peer.Latency != nil {
-		ms := float64(peer.Latency.Milliseconds())
+		WGPubKey:          p.WGPubKey,
+		Hostname:          p.Hostname,
+		MeshIP:            p.MeshIP,
+		MeshIPv6:          p.MeshIPv6,
+		Endpoint:          p.Endpoint,
+		ControlEndpoint:   d.controlEndpoint(p.Endpoint),
+		EndpointMethod:    p.EndpointMethod,
+		Candidates:        p.Candidates,
+		LastSeen:          p.LastSeen,
+		DiscoveredVia:     p.DiscoveredVia,
+		RoutableNetworks:  p.RoutableNetworks,
+		Installed:         d.isInstalled(p.WGPubKey),
+		Introducer:        p.Introducer,
+		IntroducerElected: p.IntroducerElected,
+		Version:           p.Version,
+		Revision:          p.Revision,
+		Capabilities:      p.Capabilities.Names(),
+		Compatibility:     PeerCompatibility(p),
+		NATType:           p.NATType,
+		Identity:          p.Identity,
+		Load:              rpcIntroducerLoad(p),
+		Tags:              p.Tags,
+	}
+	rpcPeer.Path, rpcPeer.RelayPubKey = peerPathWith(p, relayRoutes, localSubnets)
+	if p.Latency != nil {
+		ms := float64(p.Latency.Microseconds()) / 1000
 		rpcPeer.LatencyMs = &ms
 	}
-	return rpcPeer, true
+	if p.PacketLoss != nil {
+		pct := *p.PacketLoss * 100
+		rpcPeer.PacketLossPct = &pct
+	}
+	rpcPeer.Probes, rpcPeer.ProbesLost = d.peerStore.ProbeCounts(p.WGPubKey)
+	if ts := handshakes[p.WGPubKey]; ts > 0 {
+		rpcPeer.LastHandshake = time.Unix(ts, 0)
+	}
+	return rpcPeer
 }
 
 // GetRPCPeerCounts returns peer counts for RPC
@@ -1782,30 +2636,70 @@ func (d *Daemon) GetRPCStatus() *RPCStatusData {
 		// Return nil if local node is not initialized yet
 		return nil
 	}
+	skew, skewPeers := d.ClockSkew()
 	return &RPCStatusData{
-		MeshIP:    d.localNode.MeshIP,
-		PubKey:    d.localNode.WGPubKey,
-		Uptime:    d.GetUptime(),
-		Interface: d.config.InterfaceName,
+		MeshIP:         d.localNode.MeshIP,
+		PubKey:         d.localNode.WGPubKey,
+		Uptime:         d.GetUptime(),
+		Interface:      d.config.InterfaceName,
+		Identity:       d.localIdentity(),
+		ClockSkew:      skew,
+		ClockSkewPeers: skewPeers,
+		MeshName:       d.config.MeshName,
 	}
 }
 
+// GetRPCSecret returns the mesh secret as a URI for secret.unlock, a v2 URI
+// when the mesh was joined with one.
+func (d *Daemon) GetRPCSecret() string {
+	uri, err := FormatSecretURIWithOptions(d.config.Secret, d.config.SecretOptions)
+	if err != nil {
+		return FormatSecretURI(d.config.Secret)
+	}
+	return uri
+}
+
 // RPCPeerData represents peer info for RPC (matches rpc.PeerData)
 type RPCPeerData struct {
-	WGPubKey         string
-	Hostname         string
-	MeshIP           string
-	Endpoint         string
-	LastSeen         time.Time
-	DiscoveredVia    []string
-	RoutableNetworks []string
-	LatencyMs        *float64 // nil when no probe has succeeded yet
+	WGPubKey          string
+	Hostname          string
+	MeshIP            string
+	MeshIPv6          string
+	Endpoint          string
+	ControlEndpoint   string // the peer's exchange listener, derived from Endpoint
+	EndpointMethod    string // discovery method that set Endpoint
+	Candidates        []string
+	LastSeen          time.Time
+	LastHandshake     time.Time // zero before the first WireGuard handshake
+	DiscoveredVia     []string
+	RoutableNetworks  []string
+	LatencyMs         *float64 // nil when no probe has succeeded yet
+	PacketLossPct     *float64 // nil until the peer has been probed
+	Probes            int      // mesh probes in the loss window
+	ProbesLost        int
+	Installed         bool // false when --max-installed-peers left the peer out of WireGuard
+	Introducer        bool
+	IntroducerElected bool
+	Version           string // empty for peers that do not announce it
+	Revision          int    // protocol revision; 0 for peers that predate revisions
+	Capabilities      []string
+	Compatibility     string // CompatOK, CompatLegacy or CompatIncompatible
+	NATType           string
+	Path              string // PathDirect, PathDirectLAN or PathRelay
+	RelayPubKey       string // set when Path is PathRelay
+	Identity          string
+	Load              *RPCIntroducerLoad // reported by introducers
+	Tags              map[string]string
 }
 
 // RPCStatusData represents daemon status for RPC (matches rpc.StatusData)
 type RPCStatusData struct {
-	MeshIP    string
-	PubKey    string
-	Uptime    time.Duration
-	Interface string
+	MeshIP         string
+	PubKey         string
+	Uptime         time.Duration
+	Interface      string
+	Identity       string
+	ClockSkew      time.Duration
+	ClockSkewPeers int
+	MeshName       string
 }
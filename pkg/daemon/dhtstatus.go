@@ -0,0 +1,31 @@
+package daemon
+
+import "time"
+
+// DHTStatusReporter is implemented by discovery layers that announce to
+// and query the DHT.
+type DHTStatusReporter interface {
+	DHTStatus() *RPCDHTData
+}
+
+// RPCDHTData describes DHT discovery for RPC (matches rpc.DHTData)
+type RPCDHTData struct {
+	NATType          string // NAT type the intervals are based on
+	Peers            int    // peers known when the intervals were last chosen
+	TargetPeers      int    // below this, the node announces and queries more often
+	AnnounceInterval time.Duration
+	QueryInterval    time.Duration
+	LastAnnounce     time.Time
+	LastQuery        time.Time
+	Nodes            int // DHT routing table size
+}
+
+// GetRPCDHT returns the DHT discovery state for RPC, or nil when the node
+// does not use the DHT.
+func (d *Daemon) GetRPCDHT() *RPCDHTData {
+	reporter, ok := d.dhtDiscovery.(DHTStatusReporter)
+	if !ok {
+		return nil
+	}
+	return reporter.DHTStatus()
+}
@@ -0,0 +1,173 @@
+package daemon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"time"
+)
+
+// When no node in the mesh is configured as an introducer, well-connected
+// nodes elect a few of themselves. A node stands as a candidate once it has
+// had an open NAT (none or cone), a public endpoint and working mesh probes
+// for IntroducerElectionStableFor; every candidate ranks the candidates it
+// knows of by the hash of their public keys, and the first
+// IntroducerElectionSeats take the role.
+const (
+	IntroducerElectionInterval  = 30 * time.Second
+	IntroducerElectionSeats     = 2
+	IntroducerElectionStableFor = 10 * time.Minute
+)
+
+// Events recorded when the local node takes or gives up an elected
+// introducer role.
+const (
+	EventIntroducerElected     = "introducer_elected"
+	EventIntroducerSteppedDown = "introducer_stepped_down"
+)
+
+// introducerElection is the daemon's election state.
+type introducerElection struct {
+	candidateSince time.Time // zero while the node does not qualify
+}
+
+// introducerElectionLoop re-runs the introducer election periodically.
+func (d *Daemon) introducerElectionLoop() {
+	ticker := time.NewTicker(IntroducerElectionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.runIntroducerElection(time.Now())
+		}
+	}
+}
+
+// runIntroducerElection updates whether the local node is an introducer
+// candidate and whether it holds an elected seat, and announces a change of
+// role right away. Configured introducers never take part, and elected ones
+// step down as soon as a configured introducer shows up or their own
+// reachability degrades.
+func (d *Daemon) runIntroducerElection(now time.Time) {
+	if d.config.Introducer || !d.config.IntroducerElection {
+		return
+	}
+
+	reason := d.introducerDisqualification()
+	if reason != "" {
+		d.election.candidateSince = time.Time{}
+	} else if d.election.candidateSince.IsZero() {
+		d.election.candidateSince = now
+	}
+	candidate := reason == "" && now.Sub(d.election.candidateSince) >= IntroducerElectionStableFor
+	d.localNode.introducerCandidate.Store(candidate)
+
+	peers := d.peerStore.GetActive()
+	elected := false
+	switch {
+	case !candidate:
+		if reason == "" {
+			reason = "not yet stable"
+		}
+	case configuredIntroducerPresent(peers):
+		reason = "a configured introducer is present"
+	default:
+		elected = wonIntroducerSeat(d.localNode.WGPubKey, peers)
+		if !elected {
+			reason = fmt.Sprintf("outranked by %d other candidate(s)", IntroducerElectionSeats)
+		}
+	}
+
+	if elected == d.localNode.introducerElected.Load() {
+		return
+	}
+	d.localNode.introducerElected.Store(elected)
+	if elected {
+		log.Printf("[Election] Elected introducer: none is configured and this node holds one of %d seats", IntroducerElectionSeats)
+		d.recordEvent(EventIntroducerElected, d.localNode.WGPubKey, nil)
+	} else {
+		log.Printf("[Election] Stepping down as introducer: %s", reason)
+		d.recordEvent(EventIntroducerSteppedDown, d.localNode.WGPubKey, map[string]string{"reason": reason})
+	}
+	if r, ok := d.dhtDiscovery.(Reannouncer); ok {
+		r.Reannounce()
+	}
+}
+
+// introducerDisqualification returns why the local node cannot currently
+// stand for introducer, or "" if it can.
+func (d *Daemon) introducerDisqualification() string {
+	switch nat := d.localNode.NATType; nat {
+	case "none", "cone":
+	case "":
+		return "NAT type unknown"
+	default:
+		return "NAT type " + nat
+	}
+	if !isPublicEndpoint(d.localNode.GetEndpoint()) {
+		return "no public endpoint"
+	}
+
+	measured, lossy := 0, 0
+	for _, p := range d.peerStore.GetActive() {
+		if p.PacketLoss == nil {
+			continue
+		}
+		measured++
+		if *p.PacketLoss >= RelayMaxProbeLoss {
+			lossy++
+		}
+	}
+	if lossy*2 > measured {
+		return fmt.Sprintf("mesh probes failing to %d of %d peers", lossy, measured)
+	}
+	return ""
+}
+
+// configuredIntroducerPresent reports whether any of peers is an introducer
+// by configuration. Peers too old to announce election state count as
+// configured.
+func configuredIntroducerPresent(peers []*PeerInfo) bool {
+	return slices.ContainsFunc(peers, func(p *PeerInfo) bool {
+		return p.Introducer && !p.IntroducerElected
+	})
+}
+
+// wonIntroducerSeat reports whether self ranks among the first
+// IntroducerElectionSeats of itself and the candidate peers. Every
+// candidate ranks the same set the same way, so the mesh converges on the
+// same introducers once announcements have spread.
+func wonIntroducerSeat(self string, peers []*PeerInfo) bool {
+	selfRank := electionRank(self)
+	ahead := 0
+	for _, p := range peers {
+		if !p.IntroducerCandidate || p.WGPubKey == self {
+			continue
+		}
+		if bytes.Compare(electionRank(p.WGPubKey), selfRank) < 0 {
+			ahead++
+		}
+	}
+	return ahead < IntroducerElectionSeats
+}
+
+func electionRank(pubKey string) []byte {
+	sum := sha256.Sum256([]byte("wgmesh-introducer-election|" + pubKey))
+	return sum[:]
+}
+
+// isPublicEndpoint reports whether endpoint is a globally routable ip:port.
+func isPublicEndpoint(endpoint string) bool {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsGlobalUnicast() && !ip.IsPrivate()
+}
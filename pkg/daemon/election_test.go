@@ -0,0 +1,173 @@
+package daemon
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+func newElectionDaemon(t *testing.T, pubKey string) *Daemon {
+	t.Helper()
+	d := newMinimalDaemon(t)
+	d.localNode = &LocalNode{WGPubKey: pubKey, NATType: "cone"}
+	d.localNode.SetEndpoint("203.0.113.10:51820")
+	return d
+}
+
+func TestRunIntroducerElection_PromotesAfterStablePeriod(t *testing.T) {
+	t.Parallel()
+	d := newElectionDaemon(t, "self")
+	now := time.Now()
+
+	d.runIntroducerElection(now)
+	if d.localNode.IsIntroducer() {
+		t.Fatal("promoted before the node qualified for IntroducerElectionStableFor")
+	}
+
+	d.runIntroducerElection(now.Add(IntroducerElectionStableFor))
+	if !d.localNode.IsIntroducer() {
+		t.Fatal("not promoted after qualifying for IntroducerElectionStableFor")
+	}
+	var ann crypto.PeerAnnouncement
+	d.localNode.AnnounceRelayState(&ann)
+	if !ann.IntroducerCandidate || !ann.IntroducerElected {
+		t.Errorf("announcement candidate = %v, elected = %v; want both", ann.IntroducerCandidate, ann.IntroducerElected)
+	}
+	if events := d.events.since(0); len(events) != 1 || events[0].Type != EventIntroducerElected {
+		t.Errorf("events = %+v, want one %s", events, EventIntroducerElected)
+	}
+}
+
+func TestRunIntroducerElection_StepsDownWhenReachabilityDegrades(t *testing.T) {
+	t.Parallel()
+	d := newElectionDaemon(t, "self")
+	now := time.Now()
+	d.runIntroducerElection(now)
+	d.runIntroducerElection(now.Add(IntroducerElectionStableFor))
+	if !d.localNode.IsIntroducer() {
+		t.Fatal("not promoted")
+	}
+
+	d.localNode.NATType = "symmetric"
+	d.runIntroducerElection(now.Add(IntroducerElectionStableFor + time.Minute))
+	if d.localNode.IsIntroducer() {
+		t.Fatal("still an introducer behind a symmetric NAT")
+	}
+	events := d.events.since(0)
+	last := events[len(events)-1]
+	if last.Type != EventIntroducerSteppedDown || last.Details["reason"] != "NAT type symmetric" {
+		t.Errorf("last event = %+v, want %s for NAT type symmetric", last, EventIntroducerSteppedDown)
+	}
+
+	// Qualifying again restarts the stable period.
+	d.localNode.NATType = "cone"
+	d.runIntroducerElection(now.Add(IntroducerElectionStableFor + 2*time.Minute))
+	if d.localNode.IsIntroducer() {
+		t.Error("re-promoted without a new stable period")
+	}
+}
+
+func TestIntroducerDisqualification(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name     string
+		nat      string
+		endpoint string
+		probes   []string // per peer: "ok", "lost" or "" for not probed
+		want     string
+	}{
+		{"public cone", "cone", "203.0.113.10:51820", nil, ""},
+		{"no NAT", "none", "203.0.113.10:51820", nil, ""},
+		{"undetected NAT", "", "203.0.113.10:51820", nil, "NAT type unknown"},
+		{"symmetric", "symmetric", "203.0.113.10:51820", nil, "NAT type symmetric"},
+		{"private endpoint", "cone", "192.168.1.5:51820", nil, "no public endpoint"},
+		{"no endpoint", "cone", "", nil, "no public endpoint"},
+		{"some probe loss", "cone", "203.0.113.10:51820", []string{"lost", "ok"}, ""},
+		{"mostly probe loss", "cone", "203.0.113.10:51820", []string{"lost", "lost", "ok", ""}, "mesh probes failing to 2 of 3 peers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newMinimalDaemon(t)
+			d.localNode = &LocalNode{WGPubKey: "self", NATType: tt.nat}
+			d.localNode.SetEndpoint(tt.endpoint)
+			for i, probe := range tt.probes {
+				key := fmt.Sprintf("peer-%d", i)
+				d.peerStore.Update(&PeerInfo{WGPubKey: key}, "test")
+				if probe != "" {
+					d.peerStore.RecordProbe(key, time.Millisecond, probe == "ok")
+				}
+			}
+			if got := d.introducerDisqualification(); got != tt.want {
+				t.Errorf("introducerDisqualification() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunIntroducerElection_ConfiguredIntroducerWins(t *testing.T) {
+	t.Parallel()
+	d := newElectionDaemon(t, "self")
+	now := time.Now()
+	d.runIntroducerElection(now)
+	d.runIntroducerElection(now.Add(IntroducerElectionStableFor))
+	if !d.localNode.IsIntroducer() {
+		t.Fatal("not promoted")
+	}
+
+	// Another elected introducer does not displace this one...
+	d.peerStore.Update(&PeerInfo{WGPubKey: "elected", Introducer: true, IntroducerElected: true}, "test")
+	if configuredIntroducerPresent(d.peerStore.GetActive()) {
+		t.Fatal("an elected introducer counted as configured")
+	}
+	// ...but a configured one does.
+	d.peerStore.Update(&PeerInfo{WGPubKey: "configured", Introducer: true}, "test")
+	d.runIntroducerElection(now.Add(IntroducerElectionStableFor + time.Minute))
+	if d.localNode.IsIntroducer() {
+		t.Error("elected introducer kept the role next to a configured one")
+	}
+}
+
+func TestRunIntroducerElection_Disabled(t *testing.T) {
+	t.Parallel()
+	d := newElectionDaemon(t, "self")
+	d.config.IntroducerElection = false
+	now := time.Now()
+	d.runIntroducerElection(now)
+	d.runIntroducerElection(now.Add(IntroducerElectionStableFor))
+	if d.localNode.IsIntroducer() {
+		t.Error("promoted with election disabled")
+	}
+}
+
+func TestWonIntroducerSeat_BoundedAndAgreed(t *testing.T) {
+	t.Parallel()
+	var keys []string
+	for i := 0; i < 7; i++ {
+		keys = append(keys, fmt.Sprintf("node-%d", i))
+	}
+
+	// Every candidate sees the same candidate set and must reach the same
+	// result, electing exactly IntroducerElectionSeats of them.
+	winners := 0
+	for _, self := range keys {
+		var peers []*PeerInfo
+		for _, k := range keys {
+			if k != self {
+				peers = append(peers, &PeerInfo{WGPubKey: k, IntroducerCandidate: true})
+			}
+		}
+		peers = append(peers, &PeerInfo{WGPubKey: "not-a-candidate"})
+		if wonIntroducerSeat(self, peers) {
+			winners++
+		}
+	}
+	if winners != IntroducerElectionSeats {
+		t.Errorf("%d nodes won a seat, want %d", winners, IntroducerElectionSeats)
+	}
+
+	if !wonIntroducerSeat("alone", nil) {
+		t.Error("the only candidate must win a seat")
+	}
+}
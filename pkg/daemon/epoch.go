@@ -3,7 +3,10 @@ package daemon
 import (
 	"context"
 	"log"
+	"sync"
+	"time"
 
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/privacy"
 )
 
@@ -13,6 +16,12 @@ type EpochManager struct {
 	cancel context.CancelFunc
 }
 
+// DandelionParticipant is implemented by discovery layers that can route
+// first-contact announcements through the epoch's stem introducers.
+type DandelionParticipant interface {
+	SetDandelionRouter(router *privacy.DandelionRouter)
+}
+
 // NewEpochManager creates a new epoch manager
 func NewEpochManager(epochSeed [32]byte) *EpochManager {
 	return &EpochManager{
@@ -44,3 +53,66 @@ func (em *EpochManager) GetRouter() *privacy.DandelionRouter {
 func (em *EpochManager) GetCurrentEpoch() *privacy.Epoch {
 	return em.router.GetEpoch()
 }
+
+// EnvelopeKeys is the envelope side of the epoch schedule: it ratchets the
+// gossip envelope key forward once per crypto.GossipRatchetPeriod. It holds
+// only the keys of the epochs still accepted, so a leaked epoch key opens no
+// traffic from epochs that have aged out. It is not forward secrecy: the
+// static gossip key the ratchet starts from stays in Config, as does the
+// mesh secret it is derived from, and both derive every epoch's key.
+// Advancing happens lazily whenever a key is asked for.
+type EnvelopeKeys struct {
+	mu    sync.Mutex
+	epoch int64 // epoch of keys[crypto.GossipRatchetWindow]
+	keys  [2*crypto.GossipRatchetWindow + 1][32]byte
+}
+
+// NewEnvelopeKeys derives the accepted epoch keys around now from the
+// static gossip key.
+func NewEnvelopeKeys(gossipKey [32]byte, now time.Time) *EnvelopeKeys {
+	epoch := crypto.GossipEpoch(now)
+	k := &EnvelopeKeys{epoch: epoch}
+	// Epochs before 0 (only right after the anchor) reuse epoch 0's key.
+	first := epoch - crypto.GossipRatchetWindow
+	key := crypto.GossipEpochKey(gossipKey, max(first, 0))
+	for i := range k.keys {
+		k.keys[i] = key
+		if first+int64(i) >= 0 {
+			key = crypto.RatchetKey(key)
+		}
+	}
+	return k
+}
+
+// SealKey returns the current epoch's key.
+func (k *EnvelopeKeys) SealKey(now time.Time) [32]byte {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.advance(now)
+	return k.keys[crypto.GossipRatchetWindow]
+}
+
+// OpenKeys returns the accepted epoch keys, the current one first.
+func (k *EnvelopeKeys) OpenKeys(now time.Time) [][32]byte {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.advance(now)
+	keys := [][32]byte{k.keys[crypto.GossipRatchetWindow]}
+	for i, key := range k.keys {
+		if i != crypto.GossipRatchetWindow {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// advance ratchets forward to now's epoch, dropping the oldest key at each
+// step. It never goes back: keys of past epochs are gone once dropped, so
+// a clock stepped backwards keeps the current window.
+func (k *EnvelopeKeys) advance(now time.Time) {
+	for epoch := crypto.GossipEpoch(now); k.epoch < epoch; k.epoch++ {
+		last := len(k.keys) - 1
+		copy(k.keys[:last], k.keys[1:])
+		k.keys[last] = crypto.RatchetKey(k.keys[last-1])
+	}
+}
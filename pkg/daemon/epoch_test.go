@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+func TestEnvelopeKeys_Window(t *testing.T) {
+	t.Parallel()
+	var base [32]byte
+	base[0] = 7
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	epoch := crypto.GossipEpoch(now)
+	k := NewEnvelopeKeys(base, now)
+
+	cur := crypto.GossipEpochKey(base, epoch)
+	if got := k.SealKey(now); got != cur {
+		t.Fatal("SealKey is not the current epoch's key")
+	}
+	open := k.OpenKeys(now)
+	want := [][32]byte{cur, crypto.GossipEpochKey(base, epoch-1), crypto.GossipEpochKey(base, epoch+1)}
+	if !slices.Equal(open, want) {
+		t.Error("OpenKeys is not the current, previous and next epoch's key")
+	}
+
+	// A day later the window has moved on and the oldest key is gone.
+	later := now.Add(crypto.GossipRatchetPeriod)
+	if got := k.SealKey(later); got != crypto.GossipEpochKey(base, epoch+1) {
+		t.Error("SealKey did not ratchet to the next epoch")
+	}
+	if slices.Contains(k.OpenKeys(later), want[1]) {
+		t.Error("an aged-out epoch key is still accepted")
+	}
+
+	// Clocks stepping back do not bring old keys back.
+	if got := k.SealKey(now); got != crypto.GossipEpochKey(base, epoch+1) {
+		t.Error("SealKey went back to an earlier epoch")
+	}
+}
+
+func TestConfigEnvelopeKeys(t *testing.T) {
+	t.Parallel()
+	cfg, err := NewConfig(DaemonOpts{Secret: testConfigSecret})
+	if err != nil {
+		t.Fatalf("NewConfig: %v", err)
+	}
+	if cfg.EnvelopeSealKey() != cfg.Keys.GossipKey {
+		t.Error("sealed with a ratcheted key without GossipRatchet")
+	}
+	open := cfg.EnvelopeOpenKeys()
+	if len(open) != 2*crypto.GossipRatchetWindow+2 || open[len(open)-1] != cfg.Keys.GossipKey {
+		t.Errorf("open keys = %d, want the ratchet window and the static key last", len(open))
+	}
+
+	cfg.GossipRatchet = true
+	if seal := cfg.EnvelopeSealKey(); seal == cfg.Keys.GossipKey || seal != open[0] {
+		t.Error("GossipRatchet does not seal with the current epoch's key")
+	}
+}
@@ -0,0 +1,201 @@
+package daemon
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// EventLogSize bounds the in-memory event log served over RPC.
+	EventLogSize = 256
+
+	EventEndpointChanged = "endpoint_changed"
+)
+
+// RPCEventData represents a daemon event for RPC (matches rpc.EventData)
+type RPCEventData struct {
+	Seq     uint64
+	Time    time.Time
+	Type    string
+	PubKey  string
+	Details map[string]string
+}
+
+// eventLog is a fixed-size ring of recent daemon events. The zero value is
+// ready to use.
+type eventLog struct {
+	mu     sync.Mutex
+	seq    uint64
+	events []RPCEventData
+	next   int
+}
+
+func (l *eventLog) record(eventType, pubKey string, details map[string]string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	ev := RPCEventData{
+		Seq:     l.seq,
+		Time:    time.Now(),
+		Type:    eventType,
+		PubKey:  pubKey,
+		Details: details,
+	}
+	if len(l.events) < EventLogSize {
+		l.events = append(l.events, ev)
+		return
+	}
+	l.events[l.next] = ev
+	l.next = (l.next + 1) % EventLogSize
+}
+
+// since returns events with Seq > seq, oldest first.
+func (l *eventLog) since(seq uint64) []RPCEventData {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	out := make([]RPCEventData, 0, len(l.events))
+	for i := 0; i < len(l.events); i++ {
+		ev := l.events[(l.next+i)%len(l.events)]
+		if ev.Seq > seq {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+func (d *Daemon) recordEvent(eventType, pubKey string, details map[string]string) {
+	d.events.record(eventType, pubKey, details)
+}
+
+// GetRPCEvents returns recorded events newer than sinceSeq for RPC.
+func (d *Daemon) GetRPCEvents(sinceSeq uint64) []*RPCEventData {
+	events := d.events.since(sinceSeq)
+	result := make([]*RPCEventData, len(events))
+	for i := range events {
+		result[i] = &events[i]
+	}
+	return result
+}
+
+// GetRPCEventSubscribers returns the delivery counters of the peer store's
+// event subscribers for RPC.
+func (d *Daemon) GetRPCEventSubscribers() []PeerEventSubscriberStats {
+	return d.peerStore.SubscriberStats()
+}
+
+// peerEventLoop reacts to peer store events that cannot wait for the next
+// reconcile cycle.
+func (d *Daemon) peerEventLoop() {
+	ch := d.peerStore.Subscribe("roaming")
+	defer d.peerStore.Unsubscribe(ch)
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if ev.EndpointChanged() {
+				d.handleEndpointChange(ev)
+			}
+		}
+	}
+}
+
+// handleEndpointChange fails a roamed peer over to its new endpoint right
+// away: the WireGuard peer is re-pointed using the last applied allowed-IPs
+// and keepalive, and probe/health state tied to the old address is reset.
+// Relay-routed peers are left to reconcile, which owns the relay decision.
+func (d *Daemon) handleEndpointChange(ev PeerEvent) {
+	if ev.PubKey == "" || (d.localNode != nil && ev.PubKey == d.localNode.WGPubKey) {
+		return
+	}
+
+	log.Printf("[Roam] Peer %s... endpoint changed %s -> %s", shortKey(ev.PubKey), ev.PrevEndpoint, ev.Endpoint)
+
+	d.resetPeerPathState(ev.PubKey)
+	d.clearPathSelection(ev.PubKey)
+	applied := d.applyRoamedEndpoint(ev.PubKey, ev.Endpoint)
+
+	details := map[string]string{
+		"endpoint":      ev.Endpoint,
+		"prev_endpoint": ev.PrevEndpoint,
+	}
+	if applied {
+		details["applied"] = "true"
+	}
+	d.recordEvent(EventEndpointChanged, ev.PubKey, details)
+}
+
+// resetPeerPathState forgets probe, health and offline state for a peer so
+// the new path is judged on its own.
+func (d *Daemon) resetPeerPathState(pubKey string) {
+	d.closeProbeSession(pubKey)
+	d.probeMu.Lock()
+	delete(d.probeFailures, pubKey)
+	d.probeMu.Unlock()
+	d.healthMu.Lock()
+	delete(d.peerHealthFailures, pubKey)
+	delete(d.lastPeerTransferTotal, pubKey)
+	d.healthMu.Unlock()
+	d.clearTemporarilyOffline(pubKey)
+}
+
+// applyRoamedEndpoint re-points an already configured direct peer at
+// endpoint. Returns true if WireGuard was updated.
+func (d *Daemon) applyRoamedEndpoint(pubKey, endpoint string) bool {
+	if endpoint == "" || d.isRelayRoutedPeer(pubKey) {
+		return false
+	}
+	if d.config.DisableIPv6 && isIPv6Endpoint(endpoint) {
+		return false
+	}
+	return d.programPeerEndpoint(pubKey, endpoint)
+}
+
+// programPeerEndpoint swaps the endpoint of a peer already present in
+// WireGuard, keeping the allowed-IPs last applied by reconcile. Returns
+// true if WireGuard now uses endpoint; false if the peer has not been
+// applied yet or the update failed.
+func (d *Daemon) programPeerEndpoint(pubKey, endpoint string) bool {
+	d.appliedMu.Lock()
+	prev, ok := d.lastAppliedPeerConfigs[pubKey]
+	if !ok {
+		d.appliedMu.Unlock()
+		return false
+	}
+	parts := strings.SplitN(prev, "|", 4)
+	if len(parts) != 4 || parts[1] == "" {
+		d.appliedMu.Unlock()
+		return false
+	}
+	if parts[0] == endpoint {
+		d.appliedMu.Unlock()
+		return true
+	}
+	peer, exists := d.peerStore.Get(pubKey)
+	if !exists {
+		d.appliedMu.Unlock()
+		return false
+	}
+	keepalive := d.keepaliveForPeer(peer)
+	psk, pskKind := d.presharedKey(peer)
+	d.lastAppliedPeerConfigs[pubKey] = endpoint + "|" + parts[1] + "|" + strconv.Itoa(keepalive) + "|" + pskKind
+	d.appliedMu.Unlock()
+
+	if err := d.wireGuard().SetPeer(d.config.InterfaceName, pubKey, psk, endpoint, parts[1], keepalive); err != nil {
+		log.Printf("Failed to update endpoint for peer %s...: %v", shortKey(pubKey), err)
+		d.appliedMu.Lock()
+		delete(d.lastAppliedPeerConfigs, pubKey)
+		d.appliedMu.Unlock()
+		return false
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEventLogSinceAndWrap(t *testing.T) {
+	var l eventLog
+
+	if got := l.since(0); len(got) != 0 {
+		t.Fatalf("expected empty log, got %d events", len(got))
+	}
+
+	for i := 0; i < EventLogSize+10; i++ {
+		l.record(EventEndpointChanged, "key", nil)
+	}
+
+	all := l.since(0)
+	if len(all) != EventLogSize {
+		t.Fatalf("expected %d events after wrap, got %d", EventLogSize, len(all))
+	}
+	if all[0].Seq != 11 || all[len(all)-1].Seq != EventLogSize+10 {
+		t.Fatalf("expected seq 11..%d, got %d..%d", EventLogSize+10, all[0].Seq, all[len(all)-1].Seq)
+	}
+
+	tail := l.since(EventLogSize + 8)
+	if len(tail) != 2 || tail[0].Seq != EventLogSize+9 {
+		t.Fatalf("unexpected tail: %+v", tail)
+	}
+}
+
+func TestHandleEndpointChangeResetsStateAndRecordsEvent(t *testing.T) {
+	d := &Daemon{
+		config:                 &Config{InterfaceName: "wg-test"},
+		localNode:              &LocalNode{WGPubKey: "local"},
+		peerStore:              NewPeerStore(),
+		lastAppliedPeerConfigs: make(map[string]string),
+		relayRoutes:            make(map[string]string),
+		peerHealthFailures:     map[string]int{"peer": 2},
+		lastPeerTransferTotal:  map[string]uint64{"peer": 100},
+		probeSessions:          make(map[string]*peerProbeSession),
+		probeFailures:          map[string]int{"peer": 3},
+		temporaryOffline:       map[string]time.Time{"peer": time.Now().Add(time.Minute)},
+	}
+
+	d.handleEndpointChange(PeerEvent{
+		PubKey:       "peer",
+		Kind:         PeerEventUpdated,
+		Endpoint:     "5.6.7.8:51820",
+		PrevEndpoint: "1.2.3.4:51820",
+	})
+
+	if _, ok := d.probeFailures["peer"]; ok {
+		t.Error("probe failures not reset")
+	}
+	if _, ok := d.peerHealthFailures["peer"]; ok {
+		t.Error("health failures not reset")
+	}
+	if _, ok := d.lastPeerTransferTotal["peer"]; ok {
+		t.Error("transfer baseline not reset")
+	}
+	if d.isTemporarilyOffline("peer") {
+		t.Error("temporary offline mark not cleared")
+	}
+
+	events := d.GetRPCEvents(0)
+	if len(events) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(events))
+	}
+	ev := events[0]
+	if ev.Type != EventEndpointChanged || ev.PubKey != "peer" {
+		t.Fatalf("unexpected event %+v", ev)
+	}
+	if ev.Details["endpoint"] != "5.6.7.8:51820" || ev.Details["prev_endpoint"] != "1.2.3.4:51820" {
+		t.Errorf("unexpected details %v", ev.Details)
+	}
+	// Peer was never applied to WireGuard, so nothing to re-point
+	if ev.Details["applied"] != "" {
+		t.Errorf("expected endpoint not applied, got %v", ev.Details)
+	}
+}
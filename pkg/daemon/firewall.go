@@ -0,0 +1,52 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/firewall"
+)
+
+// firewallPolicy is what --firewall lets peers reach over the mesh: the
+// mesh probe (TCP, and UDP for path MTU probes) and in-mesh gossip (UDP)
+// ports wgmesh itself needs, ICMP, and whatever --firewall-allow added.
+func (d *Daemon) firewallPolicy() firewall.Policy {
+	return firewall.Policy{
+		Interface: d.config.InterfaceName,
+		TCPPorts:  append([]int{d.healthProbePort}, d.config.FirewallTCPPorts...),
+		UDPPorts:  append([]int{int(d.config.Keys.GossipPort), d.healthProbePort}, d.config.FirewallUDPPorts...),
+		AllowICMP: true,
+	}
+}
+
+// setupFirewall installs the mesh interface firewall when enabled. Failing
+// closed is the point of the option, so an install error stops the daemon.
+func (d *Daemon) setupFirewall() error {
+	if !d.config.Firewall {
+		return nil
+	}
+	policy := d.firewallPolicy()
+	fw, err := firewall.New(policy)
+	if err != nil {
+		return err
+	}
+	if err := fw.Apply(); err != nil {
+		_ = fw.Remove()
+		return fmt.Errorf("failed to install %s rules: %w", fw.Backend(), err)
+	}
+	d.firewall = fw
+	log.Printf("[Firewall] Restricting inbound traffic on %s to tcp %v, udp %v and ICMP (%s)",
+		policy.Interface, policy.TCPPorts, policy.UDPPorts, fw.Backend())
+	return nil
+}
+
+// teardownFirewall removes the rules installed by setupFirewall.
+func (d *Daemon) teardownFirewall() {
+	if d.firewall == nil {
+		return
+	}
+	if err := d.firewall.Remove(); err != nil {
+		log.Printf("[Shutdown] Failed to remove firewall rules: %v", err)
+	}
+	d.firewall = nil
+}
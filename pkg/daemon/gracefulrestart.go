@@ -0,0 +1,119 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// With --graceful-restart the daemon leaves its WireGuard interface and
+// peers in place on exit, so tunnels keep carrying traffic while it is
+// upgraded or restarted. The next start adopts the interface when it still
+// has this node's key and port, instead of flushing it.
+const (
+	// AdoptedPeerGrace is how long peers found on an adopted interface stay
+	// installed without the peer store knowing them. It covers the time
+	// discovery needs to find them again after a restart.
+	AdoptedPeerGrace = 2 * time.Minute
+)
+
+// adoptedPeers tracks the peers inherited from an adopted interface that
+// reconcile has not taken over yet.
+type adoptedPeers struct {
+	mu    sync.Mutex
+	keys  map[string]struct{}
+	until time.Time
+}
+
+// adoptInterface takes over the existing interface if it was left by a
+// previous run with the same key and port. It reports false, and leaves
+// the interface alone, when it has to be reset instead.
+func (d *Daemon) adoptInterface() (bool, error) {
+	name := d.config.InterfaceName
+	if !interfaceExists(name) {
+		return false, nil
+	}
+	if reason := d.adoptMismatch(); reason != "" {
+		log.Printf("Interface %s %s, resetting instead of adopting it", name, reason)
+		return false, nil
+	}
+
+	if !interfaceHasAddress(name, d.localNode.MeshIP) {
+		if err := setInterfaceAddress(name, fmt.Sprintf("%s/%d", d.localNode.MeshIP, d.config.PrefixLen())); err != nil {
+			return false, fmt.Errorf("failed to set IP address: %w", err)
+		}
+	}
+	if d.localNode.MeshIPv6 != "" && !interfaceHasAddress(name, d.localNode.MeshIPv6) {
+		if err := setInterfaceAddress(name, d.localNode.MeshIPv6+"/64"); err != nil {
+			return false, fmt.Errorf("failed to set IPv6 address: %w", err)
+		}
+	}
+	if err := setInterfaceUp(name); err != nil {
+		return false, fmt.Errorf("failed to bring interface up: %w", err)
+	}
+
+	existing, err := d.wireGuard().GetPeers(name)
+	if err != nil {
+		log.Printf("Failed to list peers on %s: %v", name, err)
+	}
+	keys := make([]string, 0, len(existing))
+	for _, p := range existing {
+		keys = append(keys, p.PublicKey)
+	}
+	d.adopted.set(keys, time.Now().Add(AdoptedPeerGrace))
+
+	log.Printf("Adopted WireGuard interface %s on port %d with %d peers (--graceful-restart)", name, d.config.WGListenPort, len(keys))
+	return true, nil
+}
+
+// adoptMismatch explains why the existing interface cannot be adopted, or
+// returns "" when it can.
+func (d *Daemon) adoptMismatch() string {
+	name := d.config.InterfaceName
+	if port := getWGInterfacePort(name); port != d.config.WGListenPort {
+		return fmt.Sprintf("listens on port %d, not %d", port, d.config.WGListenPort)
+	}
+	if key := getWGInterfacePublicKey(name); key != d.localNode.WGPubKey {
+		return "has a different key"
+	}
+	return ""
+}
+
+func (a *adoptedPeers) set(keys []string, until time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keys = make(map[string]struct{}, len(keys))
+	for _, k := range keys {
+		a.keys[k] = struct{}{}
+	}
+	a.until = until
+}
+
+// hold reports whether an inherited peer that reconcile no longer wants
+// should stay installed for now. Once the grace period is over, nothing
+// is held.
+func (a *adoptedPeers) hold(pubKey string, now time.Time) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.keys == nil {
+		return false
+	}
+	if now.After(a.until) {
+		if len(a.keys) > 0 {
+			log.Printf("Grace period for adopted peers over, %d not rediscovered", len(a.keys))
+		}
+		a.keys = nil
+		return false
+	}
+	_, ok := a.keys[pubKey]
+	return ok
+}
+
+// release hands an inherited peer over to reconcile, which then removes it
+// like any other peer when it is no longer wanted.
+func (a *adoptedPeers) release(pubKey string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.keys, pubKey)
+}
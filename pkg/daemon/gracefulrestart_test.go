@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAdoptMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		port   string
+		pubKey string
+		want   bool
+	}{
+		{"same key and port", "51820", "local-key", true},
+		{"different port", "51821", "local-key", false},
+		{"different key", "51820", "other-key", false},
+		{"no key", "51820", "(none)", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := &MockCommandExecutor{
+				commandFunc: func(name string, args ...string) Command {
+					return &MockCommand{outputFunc: func() ([]byte, error) {
+						switch args[len(args)-1] {
+						case "listen-port":
+							return []byte(tt.port + "\n"), nil
+						case "public-key":
+							return []byte(tt.pubKey + "\n"), nil
+						}
+						return nil, fmt.Errorf("unexpected command %v", args)
+					}}
+				},
+			}
+			d := &Daemon{
+				config:    &Config{InterfaceName: "wg0", WGListenPort: 51820},
+				localNode: &LocalNode{WGPubKey: "local-key"},
+			}
+			withMockExecutor(t, mock, func() {
+				reason := d.adoptMismatch()
+				if got := reason == ""; got != tt.want {
+					t.Errorf("adoptMismatch() = %q, want adoptable %v", reason, tt.want)
+				}
+			})
+		})
+	}
+}
+
+func TestAdoptedPeersHeldUntilGraceEnds(t *testing.T) {
+	now := time.Now()
+	var a adoptedPeers
+	if a.hold("peer-a", now) {
+		t.Fatal("nothing should be held before an interface is adopted")
+	}
+
+	a.set([]string{"peer-a", "peer-b"}, now.Add(AdoptedPeerGrace))
+	if !a.hold("peer-a", now) || !a.hold("peer-b", now) {
+		t.Fatal("inherited peers should be held during the grace period")
+	}
+	if a.hold("peer-c", now) {
+		t.Error("peers added after adoption should not be held")
+	}
+
+	a.release("peer-a")
+	if a.hold("peer-a", now) {
+		t.Error("released peers should no longer be held")
+	}
+
+	if a.hold("peer-b", now.Add(AdoptedPeerGrace+time.Second)) {
+		t.Error("peers should not be held after the grace period")
+	}
+	if a.hold("peer-b", now) {
+		t.Error("grace period should not restart once over")
+	}
+}
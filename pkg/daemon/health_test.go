@@ -31,3 +31,57 @@ func TestShouldTreatPeerAsStale(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckPeerHealth(t *testing.T) {
+	tests := []struct {
+		name          string
+		handshake     time.Duration // age of the last handshake; 0 = none yet
+		traffic       bool          // counters grow between checks
+		rounds        int
+		wantFailures  int
+		wantReconnect bool
+		wantEvicted   bool
+	}{
+		{name: "fresh handshake", handshake: 30 * time.Second, rounds: 3},
+		{name: "no handshake yet", rounds: 3},
+		{name: "stale handshake with traffic", handshake: 4 * time.Minute, traffic: true, rounds: 3},
+		{name: "stale and idle is reconnected", handshake: 4 * time.Minute, rounds: 2, wantFailures: 1, wantReconnect: true},
+		{name: "still idle after reconnect is evicted", handshake: 4 * time.Minute, rounds: 3, wantReconnect: true, wantEvicted: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, wg := newFakeWGDaemon(t)
+			peer := &PeerInfo{WGPubKey: "alpha", MeshIP: "10.42.0.1", Endpoint: "203.0.113.1:51820"}
+			d.peerStore.Update(peer, "dht")
+			if err := wg.SetPeer("wg0", "alpha", [32]byte{}, peer.Endpoint, "10.42.0.1/32", 25); err != nil {
+				t.Fatal(err)
+			}
+			if tt.handshake > 0 {
+				wg.SetHandshake("alpha", time.Now().Add(-tt.handshake).Unix())
+			}
+			wg.Sets = 0
+
+			for i := 1; i <= tt.rounds; i++ {
+				total := uint64(1000)
+				if tt.traffic {
+					total *= uint64(i)
+				}
+				wg.SetTransfer("alpha", total, 0)
+				d.checkPeerHealth()
+			}
+
+			if got := d.peerHealthFailures["alpha"]; got != tt.wantFailures {
+				t.Errorf("failures = %d, want %d", got, tt.wantFailures)
+			}
+			if reconnected := wg.Sets > 0; reconnected != tt.wantReconnect {
+				t.Errorf("reconnected = %v, want %v", reconnected, tt.wantReconnect)
+			}
+			_, onInterface := wg.Peer("alpha")
+			_, inStore := d.peerStore.Get("alpha")
+			if evicted := !onInterface && !inStore && d.isTemporarilyOffline("alpha"); evicted != tt.wantEvicted {
+				t.Errorf("evicted = %v (interface %v, store %v), want %v", evicted, onInterface, inStore, tt.wantEvicted)
+			}
+		})
+	}
+}
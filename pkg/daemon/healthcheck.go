@@ -0,0 +1,132 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// healthStallAfter is how long reconcile may go without running before the
+// daemon reports itself not live.
+const healthStallAfter = 3 * ReconcileInterval
+
+// HealthReport is the JSON served on --health-addr.
+type HealthReport struct {
+	Live          bool        `json:"live"`
+	Ready         bool        `json:"ready"`
+	Reason        string      `json:"reason,omitempty"` // why not ready
+	InterfaceUp   bool        `json:"interface_up"`
+	Discovery     string      `json:"discovery"` // "dht", "dns", "static", or empty while not running
+	Peers         HealthPeers `json:"peers"`
+	UptimeSeconds int64       `json:"uptime_seconds"`
+}
+
+// HealthPeers counts peers for HealthReport.
+type HealthPeers struct {
+	Known      int `json:"known"`
+	Active     int `json:"active"`
+	Handshaken int `json:"handshaken"` // handshake within HandshakeStaleAfter
+}
+
+// healthState records what the healthcheck reports on besides the
+// interface and peer store.
+type healthState struct {
+	lastReconcile atomic.Int64 // unix nanoseconds
+	mu            sync.Mutex
+	discovery     string
+}
+
+func (h *healthState) setDiscovery(mode string) {
+	h.mu.Lock()
+	h.discovery = mode
+	h.mu.Unlock()
+}
+
+func (h *healthState) discoveryMode() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.discovery
+}
+
+// Health reports whether the daemon is live (its reconcile loop is still
+// running) and ready (the interface is up and discovery is running). Peers
+// are counted but not required: the first node of a mesh has none.
+func (d *Daemon) Health() *HealthReport {
+	now := time.Now()
+	report := &HealthReport{
+		Discovery:     d.health.discoveryMode(),
+		UptimeSeconds: int64(d.GetUptime() / time.Second),
+	}
+
+	last := d.startTime
+	if ns := d.health.lastReconcile.Load(); ns != 0 {
+		last = time.Unix(0, ns)
+	}
+	report.Live = now.Sub(last) < healthStallAfter
+
+	readiness := d.GetRPCReadiness()
+	report.InterfaceUp = readiness.InterfaceUp
+	report.Peers.Handshaken = readiness.Peers
+	report.Peers.Active, report.Peers.Known, _ = d.GetRPCPeerCounts()
+
+	switch {
+	case !report.Live:
+		report.Reason = "reconcile loop stalled"
+	case !report.InterfaceUp:
+		report.Reason = "interface " + d.config.InterfaceName + " is not up"
+	case report.Discovery == "":
+		report.Reason = "discovery is not running"
+	default:
+		report.Ready = true
+	}
+	return report
+}
+
+// healthHandler serves /healthz (liveness) and /readyz (readiness). Both
+// return the full report; the status code is 503 when the check fails.
+func (d *Daemon) healthHandler() http.Handler {
+	serve := func(check func(*HealthReport) bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			report := d.Health()
+			w.Header().Set("Content-Type", "application/json")
+			if !check(report) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			_ = json.NewEncoder(w).Encode(report)
+		}
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", serve(func(r *HealthReport) bool { return r.Live }))
+	mux.HandleFunc("/readyz", serve(func(r *HealthReport) bool { return r.Ready }))
+	return mux
+}
+
+// startHealthServer serves the healthcheck on HealthAddr until the daemon
+// stops. It is started before the interface so probes get an answer, not
+// ready, while the daemon comes up.
+func (d *Daemon) startHealthServer() error {
+	if d.config.HealthAddr == "" {
+		return nil
+	}
+	ln, err := net.Listen("tcp", d.config.HealthAddr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: d.healthHandler(), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[Health] Healthcheck server stopped: %v", err)
+		}
+	}()
+	go func() {
+		<-d.ctx.Done()
+		_ = srv.Close()
+	}()
+	log.Printf("[Health] Healthcheck on http://%s/healthz and /readyz", ln.Addr())
+	return nil
+}
@@ -0,0 +1,90 @@
+package daemon
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func getHealth(t *testing.T, d *Daemon, path string) (int, HealthReport) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	d.healthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var report HealthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("%s body %q: %v", path, rec.Body.String(), err)
+	}
+	return rec.Code, report
+}
+
+func loopbackInterface(t *testing.T) string {
+	t.Helper()
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 && iface.Flags&net.FlagUp != 0 {
+			return iface.Name
+		}
+	}
+	t.Skip("no loopback interface")
+	return ""
+}
+
+func TestHealthNotReady(t *testing.T) {
+	d := newMinimalDaemon(t)
+	d.config.InterfaceName = "wgmesh-missing0"
+	d.startTime = time.Now()
+	d.peerStore.Update(&PeerInfo{WGPubKey: "peer1", MeshIP: "10.42.0.2"}, "dht")
+
+	code, report := getHealth(t, d, "/healthz")
+	if code != http.StatusOK || !report.Live {
+		t.Errorf("/healthz = %d, live %v; want 200 while starting", code, report.Live)
+	}
+	code, report = getHealth(t, d, "/readyz")
+	if code != http.StatusServiceUnavailable || report.Ready || report.InterfaceUp {
+		t.Errorf("/readyz = %d, %+v; want 503 without the interface", code, report)
+	}
+	if report.Reason == "" {
+		t.Error("not ready without a reason")
+	}
+	if report.Peers.Known != 1 || report.Peers.Active != 1 {
+		t.Errorf("peers = %+v, want one known and active", report.Peers)
+	}
+}
+
+func TestHealthReady(t *testing.T) {
+	d := newMinimalDaemon(t)
+	d.config.InterfaceName = loopbackInterface(t)
+	d.startTime = time.Now()
+
+	if code, report := getHealth(t, d, "/readyz"); code != http.StatusServiceUnavailable || report.Reason != "discovery is not running" {
+		t.Errorf("/readyz before discovery = %d, %q", code, report.Reason)
+	}
+	d.health.setDiscovery("dht")
+	code, report := getHealth(t, d, "/readyz")
+	if code != http.StatusOK || !report.Ready || report.Discovery != "dht" {
+		t.Errorf("/readyz = %d, %+v; want ready", code, report)
+	}
+}
+
+func TestHealthStalled(t *testing.T) {
+	d := newMinimalDaemon(t)
+	d.config.InterfaceName = loopbackInterface(t)
+	d.startTime = time.Now().Add(-time.Hour)
+	d.health.setDiscovery("dht")
+	d.health.lastReconcile.Store(time.Now().Add(-healthStallAfter - time.Second).UnixNano())
+
+	code, report := getHealth(t, d, "/healthz")
+	if code != http.StatusServiceUnavailable || report.Live || report.Ready {
+		t.Errorf("/healthz = %d, %+v; want 503 with reconcile stalled", code, report)
+	}
+	d.health.lastReconcile.Store(time.Now().UnixNano())
+	if code, _ := getHealth(t, d, "/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz after reconcile = %d, want 200", code)
+	}
+}
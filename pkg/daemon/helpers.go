@@ -11,6 +11,8 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 )
 
 // cmdExecutor is the command executor used by helper functions.
@@ -48,6 +50,13 @@ type localNodeState struct {
 	WGPrivateKey string `json:"wg_private_key"`
 	MeshIP       string `json:"mesh_ip,omitempty"`
 	MeshIPv6     string `json:"mesh_ipv6,omitempty"`
+	IdentityKey  string `json:"identity_key,omitempty"`
+}
+
+// localNodeStatePath returns the file holding an interface's keys and mesh
+// addresses.
+func localNodeStatePath(ifaceName string) string {
+	return filepath.Join(stateDir, ifaceName+".json")
 }
 
 // loadLocalNode loads the local node state from a file
@@ -62,12 +71,18 @@ func loadLocalNode(path string) (*LocalNode, error) {
 		return nil, err
 	}
 
-	return &LocalNode{
+	node := &LocalNode{
 		WGPubKey:     state.WGPubKey,
 		WGPrivateKey: state.WGPrivateKey,
 		MeshIP:       state.MeshIP,
 		MeshIPv6:     state.MeshIPv6,
-	}, nil
+	}
+	if state.IdentityKey != "" {
+		if node.IdentityKey, err = crypto.DecodeIdentityKey(state.IdentityKey); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
 }
 
 // saveLocalNode saves the local node state to a file
@@ -84,6 +99,9 @@ func saveLocalNode(path string, node *LocalNode) error {
 		MeshIP:       node.MeshIP,
 		MeshIPv6:     node.MeshIPv6,
 	}
+	if node.IdentityKey != nil {
+		state.IdentityKey = crypto.EncodeIdentityKey(node.IdentityKey)
+	}
 
 	data, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
@@ -388,3 +406,35 @@ func getWGInterfacePort(name string) int {
 	fmt.Sscanf(strings.TrimSpace(string(output)), "%d", &port)
 	return port
 }
+
+// getWGInterfacePublicKey gets the public key of a WireGuard interface ("" if not set)
+func getWGInterfacePublicKey(name string) string {
+	output, err := cmdExecutor.Command(wgBinPath, "show", name, "public-key").Output()
+	if err != nil {
+		return ""
+	}
+	key := strings.TrimSpace(string(output))
+	if key == "(none)" {
+		return ""
+	}
+	return key
+}
+
+// interfaceHasAddress reports whether ip is assigned to the interface.
+func interfaceHasAddress(name, ip string) bool {
+	want := net.ParseIP(ip)
+	iface, err := net.InterfaceByName(name)
+	if want == nil || err != nil {
+		return false
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(want) {
+			return true
+		}
+	}
+	return false
+}
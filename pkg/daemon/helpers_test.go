@@ -9,6 +9,8 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 )
 
 // MockCommandExecutor is a mock implementation of CommandExecutor for testing
@@ -554,6 +556,36 @@ func TestLocalNodeStatePersistsMeshIP(t *testing.T) {
 	if loaded.MeshIPv6 != original.MeshIPv6 {
 		t.Errorf("MeshIPv6: got %q, want %q", loaded.MeshIPv6, original.MeshIPv6)
 	}
+	if loaded.IdentityKey != nil {
+		t.Errorf("IdentityKey: got %v, want none", loaded.IdentityKey)
+	}
+}
+
+func TestLocalNodeStatePersistsIdentityKey(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "wg0.json")
+	key, err := crypto.GenerateIdentityKey()
+	if err != nil {
+		t.Fatalf("GenerateIdentityKey: %v", err)
+	}
+
+	if err := saveLocalNode(path, &LocalNode{WGPubKey: "pub-key-abc", IdentityKey: key}); err != nil {
+		t.Fatalf("saveLocalNode: %v", err)
+	}
+	loaded, err := loadLocalNode(path)
+	if err != nil {
+		t.Fatalf("loadLocalNode: %v", err)
+	}
+	if !key.Equal(loaded.IdentityKey) {
+		t.Error("IdentityKey was not restored")
+	}
+
+	announcement := crypto.CreateAnnouncement("pub-key-abc", "10.0.0.1", "", false, nil, nil, "", "", "")
+	loaded.SignAnnouncement(announcement)
+	if err := announcement.VerifySignature(); err != nil {
+		t.Errorf("announcement signed with the restored key does not verify: %v", err)
+	}
 }
 
 func TestLocalNodeStateBackwardCompatibility(t *testing.T) {
@@ -0,0 +1,337 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Init systems install-service can generate a service for.
+const (
+	InitSystemd   = "systemd"
+	InitOpenRC    = "openrc"
+	InitRunit     = "runit"
+	InitContainer = "container" // no service manager: print an env file for the image's entrypoint
+)
+
+// InitSystems lists the values accepted by --init-system besides "auto".
+var InitSystems = []string{InitSystemd, InitOpenRC, InitRunit, InitContainer}
+
+const (
+	systemdUnitPath  = "/etc/systemd/system/wgmesh.service"
+	openRCScriptPath = "/etc/init.d/wgmesh"
+	runitServiceDir  = "/etc/sv/wgmesh"
+	runitLogDir      = "/var/log/wgmesh"
+
+	// ContainerSecretFile is where the container env file expects the mesh
+	// secret: the default mount point of a Docker or Compose secret.
+	ContainerSecretFile = "/run/secrets/wgmesh_secret"
+)
+
+// initRoot prefixes every path DetectInitSystem looks at, for tests.
+var initRoot = "/"
+
+// runitEnableDirs are the directories runsvdir scans on common runit
+// distributions, Void's first.
+var runitEnableDirs = []string{"/var/service", "/etc/service", "/service"}
+
+// DetectInitSystem reports which init system runs this host from the
+// markers each one leaves in /run, or InitContainer inside a container
+// without one. It returns "" when it cannot tell.
+func DetectInitSystem() string {
+	exists := func(path string) bool {
+		_, err := os.Stat(filepath.Join(initRoot, path))
+		return err == nil
+	}
+	switch {
+	case exists("/run/systemd/system"):
+		return InitSystemd
+	case exists("/run/openrc"):
+		return InitOpenRC
+	case exists("/run/runit") || exists("/etc/runit/runsvdir"):
+		return InitRunit
+	case exists("/.dockerenv") || exists("/run/.containerenv") || os.Getenv("container") != "":
+		return InitContainer
+	}
+	return ""
+}
+
+// ResolveInitSystem validates an --init-system value, detecting the init
+// system for "auto" or "".
+func ResolveInitSystem(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == "auto" {
+		if detected := DetectInitSystem(); detected != "" {
+			return detected, nil
+		}
+		return "", fmt.Errorf("could not detect the init system; pass --init-system %s", strings.Join(InitSystems, "|"))
+	}
+	for _, known := range InitSystems {
+		if name == known {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown init system %q (want auto, %s)", name, strings.Join(InitSystems, ", "))
+}
+
+const openRCScriptTemplate = `#!/sbin/openrc-run
+# Generated by wgmesh install-service
+
+description="WireGuard Mesh Network (wgmesh)"
+command=%s
+command_args="%s"
+supervisor=supervise-daemon
+respawn_delay=5
+respawn_max=0
+rc_ulimit="-n 65535"
+output_log="/var/log/wgmesh.log"
+error_log="/var/log/wgmesh.log"
+
+depend() {
+	need net
+	after firewall
+}
+`
+
+// GenerateOpenRCScript generates an /etc/init.d script for wgmesh that
+// runs under supervise-daemon, which restarts it like systemd's
+// Restart=always.
+func GenerateOpenRCScript(cfg SystemdServiceConfig) (string, error) {
+	if err := resolveBinaryPath(&cfg); err != nil {
+		return "", err
+	}
+	secretFile := cfg.SecretFile
+	if secretFile == "" {
+		secretFile = DefaultSecretFilePath
+	}
+	// openrc-run evals command_args, so the words keep their shell quoting
+	// and only need protecting from the double-quoted assignment.
+	args := strings.Join(shellJoinArgs(secretFile, cfg), " ")
+	return fmt.Sprintf(openRCScriptTemplate, shellQuoteSystemd(cfg.BinaryPath), escapeDoubleQuoted(args)), nil
+}
+
+// GenerateRunitRunScript generates the run script of the runit service.
+// runsv restarts it whenever it exits.
+func GenerateRunitRunScript(cfg SystemdServiceConfig) (string, error) {
+	if err := resolveBinaryPath(&cfg); err != nil {
+		return "", err
+	}
+	secretFile := cfg.SecretFile
+	if secretFile == "" {
+		secretFile = DefaultSecretFilePath
+	}
+	args := append([]string{shellQuoteSystemd(cfg.BinaryPath)}, shellJoinArgs(secretFile, cfg)...)
+	return "#!/bin/sh\n# Generated by wgmesh install-service\nulimit -n 65535\nexec 2>&1\nexec " + strings.Join(args, " ") + "\n", nil
+}
+
+const runitLogScript = `#!/bin/sh
+mkdir -p ` + runitLogDir + `
+exec svlogd -tt ` + runitLogDir + `
+`
+
+// GenerateContainerEnv generates an env file for running "wgmesh join" as
+// a container's long-running process: join reads every flag from a
+// WGMESH_<FLAG> variable, and the secret from a mounted secret file.
+func GenerateContainerEnv(cfg SystemdServiceConfig) string {
+	secretFile := cfg.SecretFile
+	if secretFile == "" {
+		secretFile = ContainerSecretFile
+	}
+
+	var b strings.Builder
+	b.WriteString("# Generated by wgmesh install-service --init-system container\n")
+	b.WriteString("# Run: docker run --env-file wgmesh.env --network host --cap-add NET_ADMIN \\\n")
+	b.WriteString("#        -v /var/lib/wgmesh:/var/lib/wgmesh -v ./secret:" + ContainerSecretFile + ":ro \\\n")
+	b.WriteString("#        ghcr.io/atvirokodosprendimai/wgmesh:latest join\n")
+	fmt.Fprintf(&b, "WGMESH_SECRET_FILE=%s\n", secretFile)
+
+	var stunServers []string
+	for _, f := range joinFlags(cfg) {
+		if f.name == "stun-server" {
+			stunServers = append(stunServers, f.value)
+			continue
+		}
+		value := f.value
+		if value == "" {
+			value = "true"
+		}
+		fmt.Fprintf(&b, "%s=%s\n", FlagEnvName(f.name), value)
+	}
+	if len(stunServers) > 0 {
+		fmt.Fprintf(&b, "%s=%s\n", FlagEnvName("stun-server"), strings.Join(stunServers, ","))
+	}
+	return b.String()
+}
+
+// FlagEnvName returns the environment variable join reads a flag from:
+// "listen-port" becomes WGMESH_LISTEN_PORT.
+func FlagEnvName(flagName string) string {
+	return "WGMESH_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// escapeDoubleQuoted escapes s for use inside a double-quoted shell string.
+func escapeDoubleQuoted(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`").Replace(s)
+}
+
+// InstallService installs, enables and starts wgmesh under initSystem.
+// InitContainer has nothing to install; use GenerateContainerEnv.
+func InstallService(initSystem string, cfg SystemdServiceConfig) error {
+	switch initSystem {
+	case InitSystemd:
+		return InstallSystemdService(cfg)
+	case InitOpenRC:
+		return installOpenRCService(cfg)
+	case InitRunit:
+		return installRunitService(cfg)
+	case InitContainer:
+		return fmt.Errorf("containers have no service to install: run 'wgmesh join' as the entrypoint")
+	}
+	return fmt.Errorf("unknown init system %q", initSystem)
+}
+
+// ServiceInstalled reports whether a wgmesh service of initSystem is
+// installed.
+func ServiceInstalled(initSystem string) bool {
+	var path string
+	switch initSystem {
+	case InitSystemd:
+		path = systemdUnitPath
+	case InitOpenRC:
+		path = openRCScriptPath
+	case InitRunit:
+		path = runitServiceDir
+	default:
+		return false
+	}
+	_, err := os.Stat(filepath.Join(initRoot, path))
+	return err == nil
+}
+
+// UninstallService stops and removes the wgmesh service of initSystem.
+func UninstallService(initSystem string) error {
+	switch initSystem {
+	case InitSystemd:
+		return UninstallSystemdService()
+	case InitOpenRC:
+		cmdExecutor.Command("rc-service", "wgmesh", "stop").Run()
+		cmdExecutor.Command("rc-update", "del", "wgmesh", "default").Run()
+		if err := os.Remove(openRCScriptPath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove init script: %w", err)
+		}
+		return removeServiceSecret()
+	case InitRunit:
+		for _, dir := range runitEnableDirs {
+			link := filepath.Join(dir, "wgmesh")
+			if target, err := os.Readlink(link); err == nil && target == runitServiceDir {
+				cmdExecutor.Command("sv", "down", link).Run()
+				if err := os.Remove(link); err != nil {
+					return fmt.Errorf("failed to disable service: %w", err)
+				}
+			}
+		}
+		if err := os.RemoveAll(runitServiceDir); err != nil {
+			return fmt.Errorf("failed to remove service directory: %w", err)
+		}
+		return removeServiceSecret()
+	case InitContainer:
+		return fmt.Errorf("containers have no service to uninstall")
+	}
+	return fmt.Errorf("unknown init system %q", initSystem)
+}
+
+// InitServiceStatus returns "active" or "inactive" for the wgmesh service
+// of initSystem.
+func InitServiceStatus(initSystem string) (string, error) {
+	switch initSystem {
+	case InitSystemd:
+		return ServiceStatus()
+	case InitOpenRC:
+		if err := cmdExecutor.Command("rc-service", "wgmesh", "status").Run(); err != nil {
+			return "inactive", nil
+		}
+		return "active", nil
+	case InitRunit:
+		out, err := cmdExecutor.Command("sv", "status", "wgmesh").Output()
+		if err != nil || !strings.HasPrefix(string(out), "run:") {
+			return "inactive", nil
+		}
+		return "active", nil
+	}
+	return "", fmt.Errorf("no service status for init system %q", initSystem)
+}
+
+func installOpenRCService(cfg SystemdServiceConfig) error {
+	if err := storeSealedServiceSecret(&cfg); err != nil {
+		return err
+	}
+	script, err := GenerateOpenRCScript(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to generate init script: %w", err)
+	}
+	if err := os.WriteFile(openRCScriptPath, []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write init script (run as root?): %w", err)
+	}
+	if err := cmdExecutor.Command("rc-update", "add", "wgmesh", "default").Run(); err != nil {
+		return fmt.Errorf("failed to enable service: %w", err)
+	}
+	if err := cmdExecutor.Command("rc-service", "wgmesh", "start").Run(); err != nil {
+		return fmt.Errorf("failed to start service: %w", err)
+	}
+	return nil
+}
+
+func installRunitService(cfg SystemdServiceConfig) error {
+	if err := storeSealedServiceSecret(&cfg); err != nil {
+		return err
+	}
+	run, err := GenerateRunitRunScript(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to generate run script: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Join(runitServiceDir, "log"), 0755); err != nil {
+		return fmt.Errorf("failed to create service directory (run as root?): %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(runitServiceDir, "run"), []byte(run), 0755); err != nil {
+		return fmt.Errorf("failed to write run script: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(runitServiceDir, "log", "run"), []byte(runitLogScript), 0755); err != nil {
+		return fmt.Errorf("failed to write log script: %w", err)
+	}
+
+	// runsvdir picks the service up within seconds of the link appearing.
+	for _, dir := range runitEnableDirs {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			link := filepath.Join(dir, "wgmesh")
+			if _, err := os.Lstat(link); err == nil {
+				return nil
+			}
+			if err := os.Symlink(runitServiceDir, link); err != nil {
+				return fmt.Errorf("failed to enable service: %w", err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("no runit service directory found (tried %s)", strings.Join(runitEnableDirs, ", "))
+}
+
+// storeSealedServiceSecret seals the secret with the machine key for init
+// systems without an encrypted credential store of their own.
+func storeSealedServiceSecret(cfg *SystemdServiceConfig) error {
+	if err := os.MkdirAll("/var/lib/wgmesh", 0750); err != nil {
+		return fmt.Errorf("failed to create state directory (run as root?): %w", err)
+	}
+	if err := SaveSecretFile(DefaultSecretFilePath, cfg.Secret, ""); err != nil {
+		return fmt.Errorf("failed to store secret (run as root?): %w", err)
+	}
+	cfg.SecretFile = DefaultSecretFilePath
+	return nil
+}
+
+func removeServiceSecret() error {
+	if err := os.Remove(DefaultSecretFilePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove secret file: %w", err)
+	}
+	return nil
+}
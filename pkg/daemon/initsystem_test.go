@@ -0,0 +1,208 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectInitSystem(t *testing.T) {
+	tests := []struct {
+		name   string
+		marker string
+		want   string
+	}{
+		{"systemd", "run/systemd/system", InitSystemd},
+		{"openrc", "run/openrc", InitOpenRC},
+		{"runit", "etc/runit/runsvdir", InitRunit},
+		{"docker", ".dockerenv", InitContainer},
+		{"podman", "run/.containerenv", InitContainer},
+		{"unknown", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			if tt.marker != "" {
+				path := filepath.Join(root, tt.marker)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			oldRoot := initRoot
+			initRoot = root
+			defer func() { initRoot = oldRoot }()
+			t.Setenv("container", "")
+
+			if got := DetectInitSystem(); got != tt.want {
+				t.Errorf("DetectInitSystem() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveInitSystem(t *testing.T) {
+	oldRoot := initRoot
+	initRoot = t.TempDir()
+	defer func() { initRoot = oldRoot }()
+	t.Setenv("container", "podman")
+
+	if got, err := ResolveInitSystem("auto"); err != nil || got != InitContainer {
+		t.Errorf("ResolveInitSystem(auto) = %q, %v; want %q", got, err, InitContainer)
+	}
+	if got, err := ResolveInitSystem("OpenRC"); err != nil || got != InitOpenRC {
+		t.Errorf("ResolveInitSystem(OpenRC) = %q, %v; want %q", got, err, InitOpenRC)
+	}
+	if _, err := ResolveInitSystem("upstart"); err == nil {
+		t.Error("expected error for unknown init system")
+	}
+
+	t.Setenv("container", "")
+	if _, err := ResolveInitSystem(""); err == nil {
+		t.Error("expected error when nothing can be detected")
+	}
+}
+
+func TestGenerateOpenRCScript(t *testing.T) {
+	cfg := SystemdServiceConfig{
+		Secret:         "test-secret-that-is-long-enough",
+		InterfaceName:  "wg1",
+		Gossip:         true,
+		FirewallAllow:  "tcp/22",
+		DNSRendezvous:  "_wgmesh.$HOME.example.com",
+		BinaryPath:     "/usr/bin/wgmesh",
+		STUNListenPort: -1,
+	}
+	script, err := GenerateOpenRCScript(cfg)
+	if err != nil {
+		t.Fatalf("GenerateOpenRCScript failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"#!/sbin/openrc-run",
+		"command='/usr/bin/wgmesh'",
+		"supervisor=supervise-daemon",
+		"need net",
+		`--secret-file '` + DefaultSecretFilePath + `'`,
+		"--interface 'wg1'",
+		"--gossip",
+		"--firewall-allow 'tcp/22'",
+		`'_wgmesh.\$HOME.example.com'`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("init script missing %q:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, cfg.Secret) {
+		t.Error("secret should not appear in the init script")
+	}
+}
+
+func TestGenerateRunitRunScript(t *testing.T) {
+	cfg := SystemdServiceConfig{
+		Secret:      "test-secret-that-is-long-enough",
+		ListenPort:  51821,
+		STUNServers: []string{"stun.example.com:3478"},
+		BinaryPath:  "/usr/bin/wgmesh",
+	}
+	script, err := GenerateRunitRunScript(cfg)
+	if err != nil {
+		t.Fatalf("GenerateRunitRunScript failed: %v", err)
+	}
+
+	if !strings.HasPrefix(script, "#!/bin/sh\n") {
+		t.Error("run script should be a shell script")
+	}
+	want := "exec '/usr/bin/wgmesh' join --secret-file '" + DefaultSecretFilePath + "'"
+	if !strings.Contains(script, want) {
+		t.Errorf("run script should contain %q:\n%s", want, script)
+	}
+	for _, want := range []string{"--listen-port 51821", "--stun-server 'stun.example.com:3478'", "exec 2>&1"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("run script missing %q", want)
+		}
+	}
+	if strings.Contains(script, cfg.Secret) {
+		t.Error("secret should not appear in the run script")
+	}
+}
+
+func TestGenerateContainerEnv(t *testing.T) {
+	env := GenerateContainerEnv(SystemdServiceConfig{
+		Secret:      "test-secret-that-is-long-enough",
+		ListenPort:  51821,
+		Gossip:      true,
+		STUNServers: []string{"a.example.com:3478", "b.example.com:3478"},
+	})
+
+	for _, want := range []string{
+		"WGMESH_SECRET_FILE=" + ContainerSecretFile + "\n",
+		"WGMESH_LISTEN_PORT=51821\n",
+		"WGMESH_GOSSIP=true\n",
+		"WGMESH_STUN_SERVER=a.example.com:3478,b.example.com:3478\n",
+	} {
+		if !strings.Contains(env, want) {
+			t.Errorf("env file missing %q:\n%s", want, env)
+		}
+	}
+	if strings.Contains(env, "test-secret-that-is-long-enough") {
+		t.Error("secret should not appear in the env file")
+	}
+	for _, line := range strings.Split(strings.TrimSpace(env), "\n") {
+		if !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "WGMESH_") {
+			t.Errorf("unexpected env line %q", line)
+		}
+	}
+}
+
+func TestFlagEnvName(t *testing.T) {
+	if got := FlagEnvName("max-installed-peers"); got != "WGMESH_MAX_INSTALLED_PEERS" {
+		t.Errorf("FlagEnvName = %q", got)
+	}
+}
+
+func TestInitServiceStatus(t *testing.T) {
+	tests := []struct {
+		init   string
+		output string
+		err    error
+		want   string
+	}{
+		{InitOpenRC, "", nil, "active"},
+		{InitOpenRC, "", fmt.Errorf("exit status 3"), "inactive"},
+		{InitRunit, "run: wgmesh: (pid 42) 100s\n", nil, "active"},
+		{InitRunit, "down: wgmesh: 3s, normally up\n", nil, "inactive"},
+	}
+	for _, tt := range tests {
+		var called string
+		mock := &MockCommandExecutor{
+			commandFunc: func(name string, args ...string) Command {
+				called = name
+				return &MockCommand{
+					runFunc:    func() error { return tt.err },
+					outputFunc: func() ([]byte, error) { return []byte(tt.output), tt.err },
+				}
+			},
+		}
+		withMockExecutor(t, mock, func() {
+			got, err := InitServiceStatus(tt.init)
+			if err != nil {
+				t.Fatalf("InitServiceStatus(%s) failed: %v", tt.init, err)
+			}
+			if got != tt.want {
+				t.Errorf("InitServiceStatus(%s) with %q = %q, want %q", tt.init, tt.output, got, tt.want)
+			}
+		})
+		if (tt.init == InitOpenRC && called != "rc-service") || (tt.init == InitRunit && called != "sv") {
+			t.Errorf("InitServiceStatus(%s) ran %q", tt.init, called)
+		}
+	}
+
+	if _, err := InitServiceStatus(InitContainer); err == nil {
+		t.Error("expected error for container status")
+	}
+}
@@ -0,0 +1,91 @@
+package daemon
+
+import (
+	"slices"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+// An introducer reporting any of these is overloaded. Nodes leave it out
+// of new rendezvous and relay assignments while another introducer is
+// available.
+const (
+	IntroducerMaxSessions     = 32
+	IntroducerMaxRelayedPeers = 64
+	IntroducerMaxBps          = 12.5e6 // 100 Mbit/s
+)
+
+// RendezvousCounter is implemented by discovery layers that coordinate
+// rendezvous sessions when the node is an introducer.
+type RendezvousCounter interface {
+	RendezvousSessions() int
+}
+
+// RPCIntroducerLoad is an introducer's reported load for RPC (matches
+// rpc.IntroducerLoad).
+type RPCIntroducerLoad struct {
+	Sessions     int
+	RelayedPeers int
+	Bps          float64
+	Overloaded   bool
+}
+
+// IntroducerOverloaded reports whether p reported a load over any of the
+// introducer limits. Peers that report no load never are.
+func IntroducerOverloaded(p *PeerInfo) bool {
+	if p == nil || p.Load == nil {
+		return false
+	}
+	return p.Load.Sessions >= IntroducerMaxSessions ||
+		p.Load.RelayedPeers >= IntroducerMaxRelayedPeers ||
+		p.Load.Bps >= IntroducerMaxBps
+}
+
+// updateRelayState refreshes what the local node announces about relaying:
+// the relays its routes go through and, on an introducer, its own load.
+func (d *Daemon) updateRelayState(relayRoutes map[string]string) {
+	var relays []string
+	for _, relay := range relayRoutes {
+		if !slices.Contains(relays, relay) {
+			relays = append(relays, relay)
+		}
+	}
+	slices.Sort(relays)
+	if len(relays) > crypto.MaxRelays {
+		relays = relays[:crypto.MaxRelays]
+	}
+
+	var load *crypto.IntroducerLoad
+	if d.localNode.IsIntroducer() {
+		load = d.introducerLoad()
+	}
+	d.localNode.setRelayState(load, relays)
+}
+
+// introducerLoad measures the local node's load as an introducer. Relayed
+// peers are those whose announcements list this node as one of their relays.
+func (d *Daemon) introducerLoad() *crypto.IntroducerLoad {
+	load := &crypto.IntroducerLoad{Bps: d.traffic.totalRate(TrafficRateWindows[0])}
+	if c, ok := d.dhtDiscovery.(RendezvousCounter); ok {
+		load.Sessions = c.RendezvousSessions()
+	}
+	for _, p := range d.peerStore.GetActive() {
+		if slices.Contains(p.Relays, d.localNode.WGPubKey) {
+			load.RelayedPeers++
+		}
+	}
+	return load
+}
+
+// rpcIntroducerLoad converts a peer's reported load for RPC.
+func rpcIntroducerLoad(p *PeerInfo) *RPCIntroducerLoad {
+	if p.Load == nil {
+		return nil
+	}
+	return &RPCIntroducerLoad{
+		Sessions:     p.Load.Sessions,
+		RelayedPeers: p.Load.RelayedPeers,
+		Bps:          p.Load.Bps,
+		Overloaded:   IntroducerOverloaded(p),
+	}
+}
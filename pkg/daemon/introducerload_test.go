@@ -0,0 +1,81 @@
+package daemon
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+type fakeRendezvousCounter struct{ sessions int }
+
+func (f *fakeRendezvousCounter) Start() error            { return nil }
+func (f *fakeRendezvousCounter) Stop() error             { return nil }
+func (f *fakeRendezvousCounter) RendezvousSessions() int { return f.sessions }
+
+func TestUpdateRelayState_Introducer(t *testing.T) {
+	t.Parallel()
+	d := newMinimalDaemon(t)
+	d.localNode = &LocalNode{WGPubKey: "intro", Introducer: true}
+	d.dhtDiscovery = &fakeRendezvousCounter{sessions: 4}
+
+	now := time.Now()
+	for i := 0; i <= 3; i++ {
+		d.traffic.observe(map[string]wireguard.PeerTransfer{
+			"a": {RxBytes: uint64(i) * 20 * 1000, TxBytes: uint64(i) * 20 * 1000},
+		}, now.Add(time.Duration(i-3)*20*time.Second))
+	}
+	d.peerStore.Update(&PeerInfo{WGPubKey: "a", Relays: []string{"intro"}}, "test")
+	d.peerStore.Update(&PeerInfo{WGPubKey: "b", Relays: []string{"other", "intro"}}, "test")
+	d.peerStore.Update(&PeerInfo{WGPubKey: "c", Relays: []string{"other"}}, "test")
+
+	d.updateRelayState(map[string]string{"x": "relay-2", "y": "relay-1", "z": "relay-2"})
+
+	var ann crypto.PeerAnnouncement
+	d.localNode.AnnounceRelayState(&ann)
+	if want := []string{"relay-1", "relay-2"}; !reflect.DeepEqual(ann.Relays, want) {
+		t.Errorf("Relays = %v, want %v", ann.Relays, want)
+	}
+	if ann.Load == nil {
+		t.Fatal("introducer announced no load")
+	}
+	if ann.Load.Sessions != 4 || ann.Load.RelayedPeers != 2 || math.Abs(ann.Load.Bps-2000) > 1 {
+		t.Errorf("Load = %+v, want 4 sessions, 2 relayed peers, 2000 B/s", *ann.Load)
+	}
+}
+
+func TestUpdateRelayState_NonIntroducer(t *testing.T) {
+	t.Parallel()
+	d := newMinimalDaemon(t)
+	d.localNode = &LocalNode{WGPubKey: "node"}
+
+	d.updateRelayState(nil)
+
+	var ann crypto.PeerAnnouncement
+	d.localNode.AnnounceRelayState(&ann)
+	if ann.Load != nil || ann.Relays != nil {
+		t.Errorf("non-introducer without relays announced load %+v, relays %v", ann.Load, ann.Relays)
+	}
+}
+
+func TestIntroducerOverloaded(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		load *crypto.IntroducerLoad
+		want bool
+	}{
+		{nil, false},
+		{&crypto.IntroducerLoad{Sessions: 3, RelayedPeers: 10, Bps: 1e6}, false},
+		{&crypto.IntroducerLoad{Sessions: IntroducerMaxSessions}, true},
+		{&crypto.IntroducerLoad{RelayedPeers: IntroducerMaxRelayedPeers}, true},
+		{&crypto.IntroducerLoad{Bps: IntroducerMaxBps}, true},
+	}
+	for _, tt := range tests {
+		if got := IntroducerOverloaded(&PeerInfo{Load: tt.load}); got != tt.want {
+			t.Errorf("IntroducerOverloaded(%+v) = %v, want %v", tt.load, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package daemon
+
+import "time"
+
+// LANStatusReporter is implemented by discovery layers that can describe
+// their LAN discovery.
+type LANStatusReporter interface {
+	LANStatus() *RPCLANData
+}
+
+// RPCLANData describes LAN discovery for RPC (matches rpc.LANData)
+type RPCLANData struct {
+	Mode       string // "multicast" or "mdns"
+	Group      string // multicast group and port
+	Broadcast  bool   // announcements also go to interface broadcast addresses
+	Interfaces []RPCLANInterfaceData
+}
+
+// RPCLANInterfaceData is one interface LAN discovery runs on, or was asked
+// to run on by --lan-interfaces.
+type RPCLANInterfaceData struct {
+	Name      string
+	Addresses []string
+	Joined    bool   // member of the multicast group
+	Error     string // why the interface is not used
+	LastHeard time.Time
+}
+
+// GetRPCLAN returns the LAN discovery state for RPC, or nil while LAN
+// discovery is off.
+func (d *Daemon) GetRPCLAN() *RPCLANData {
+	reporter, ok := d.dhtDiscovery.(LANStatusReporter)
+	if !ok {
+		return nil
+	}
+	return reporter.LANStatus()
+}
@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LeaveShutdownDelay is how long the daemon keeps running after accepting
+// daemon.leave, so the reply reaches the caller before the RPC server stops.
+const LeaveShutdownDelay = 500 * time.Millisecond
+
+// Leave takes the node out of the mesh. The daemon shuts down right after,
+// saying GOODBYE to its peers as on every shutdown, and removes the
+// WireGuard interface even with --graceful-restart. `wgmesh leave` then
+// removes the service and the state files.
+func (d *Daemon) Leave() error {
+	if !d.leaving.CompareAndSwap(false, true) {
+		return nil
+	}
+	log.Printf("[Leave] Leaving the mesh: saying GOODBYE to peers and removing %s", d.config.InterfaceName)
+	time.AfterFunc(LeaveShutdownDelay, d.Shutdown)
+	return nil
+}
+
+// MeshStatePaths returns the files in which a node on ifaceName keeps what
+// it learned about its mesh: the peer cache, DHT routing tables, identity
+// pins, rotation history and a pending reload. With keys it adds the node's
+// own WireGuard and identity keys and the sealed mesh secret, after which
+// the node rejoins as a new member.
+func MeshStatePaths(ifaceName string, keys bool) []string {
+	paths := []string{
+		CacheFilePath(ifaceName),
+		IdentityPinsPath(ifaceName),
+		RotationStatePath(ifaceName),
+		ReloadConfigPath(ifaceName),
+	}
+	// One routing table per network ID the node announced under.
+	if nodes, err := filepath.Glob(filepath.Join(stateDir, ifaceName+"-*-dht.nodes")); err == nil {
+		paths = append(paths, nodes...)
+	}
+	if keys {
+		paths = append(paths, localNodeStatePath(ifaceName), DefaultSecretFilePath, SecretCredentialPath, machineKeyPath)
+	}
+	return paths
+}
+
+// RemoveMeshState deletes the MeshStatePaths that exist and returns them.
+func RemoveMeshState(ifaceName string, keys bool) ([]string, error) {
+	var removed []string
+	for _, path := range MeshStatePaths(ifaceName, keys) {
+		err := os.Remove(path)
+		switch {
+		case err == nil:
+			removed = append(removed, path)
+		case !errors.Is(err, os.ErrNotExist):
+			return removed, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	return removed, nil
+}
+
+// RemoveInterface deletes a WireGuard interface that no daemon removed,
+// such as one kept by --graceful-restart. It reports whether the interface
+// existed.
+func RemoveInterface(name string) (bool, error) {
+	if !interfaceExists(name) {
+		return false, nil
+	}
+	if err := setInterfaceDown(name); err != nil {
+		return true, fmt.Errorf("failed to bring down interface %s: %w", name, err)
+	}
+	if err := deleteInterface(name); err != nil {
+		return true, fmt.Errorf("failed to delete interface %s: %w", name, err)
+	}
+	return true, nil
+}
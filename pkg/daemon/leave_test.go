@@ -0,0 +1,93 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLeaveShutsDownOnce(t *testing.T) {
+	d := newMinimalDaemon(t)
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+
+	if err := d.Leave(); err != nil {
+		t.Fatalf("Leave: %v", err)
+	}
+	if err := d.Leave(); err != nil {
+		t.Fatalf("second Leave: %v", err)
+	}
+	if !d.leaving.Load() {
+		t.Error("leaving not set")
+	}
+	select {
+	case <-d.ctx.Done():
+	case <-time.After(LeaveShutdownDelay + 2*time.Second):
+		t.Fatal("daemon did not shut down after Leave")
+	}
+}
+
+func TestRemoveMeshState(t *testing.T) {
+	prev := stateDir
+	stateDir = t.TempDir()
+	t.Cleanup(func() { stateDir = prev })
+
+	keep := []string{
+		localNodeStatePath("wg0"),
+		CacheFilePath("wg1"),
+	}
+	remove := []string{
+		CacheFilePath("wg0"),
+		IdentityPinsPath("wg0"),
+		filepath.Join(stateDir, "wg0-abcdef-dht.nodes"),
+	}
+	for _, path := range append(keep, remove...) {
+		if err := os.WriteFile(path, []byte("{}"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if paths := MeshStatePaths("wg0", true); !slices.Contains(paths, localNodeStatePath("wg0")) || !slices.Contains(paths, machineKeyPath) {
+		t.Errorf("MeshStatePaths with keys = %v, want the node and machine keys", paths)
+	}
+
+	removed, err := RemoveMeshState("wg0", false)
+	if err != nil {
+		t.Fatalf("RemoveMeshState: %v", err)
+	}
+	slices.Sort(removed)
+	slices.Sort(remove)
+	if !slices.Equal(removed, remove) {
+		t.Errorf("removed %v, want %v", removed, remove)
+	}
+	for _, path := range keep {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s should be kept without keys: %v", path, err)
+		}
+	}
+}
+
+func TestServiceInstalled(t *testing.T) {
+	oldRoot := initRoot
+	initRoot = t.TempDir()
+	t.Cleanup(func() { initRoot = oldRoot })
+
+	if ServiceInstalled(InitSystemd) {
+		t.Fatal("systemd service reported before the unit exists")
+	}
+	unit := filepath.Join(initRoot, systemdUnitPath)
+	if err := os.MkdirAll(filepath.Dir(unit), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(unit, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !ServiceInstalled(InitSystemd) {
+		t.Error("systemd service not reported with the unit in place")
+	}
+	if ServiceInstalled(InitContainer) {
+		t.Error("containers have no service")
+	}
+}
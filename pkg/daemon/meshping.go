@@ -0,0 +1,176 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Peer paths as reported by 'wgmesh route' and the status log.
+const (
+	PathDirect    = "direct"
+	PathDirectLAN = "direct-lan"
+	PathRelay     = "relay"
+)
+
+// RPCPingData is the outcome of one operator ping (matches rpc.PingData).
+type RPCPingData struct {
+	PubKey   string
+	Hostname string
+	MeshIP   string
+	Path     string
+	RTT      time.Duration // zero when the peer did not answer
+	Error    string        // why the peer did not answer
+}
+
+// RPCPeerRouteData describes how traffic to a peer leaves this node
+// (matches rpc.PeerRouteData).
+type RPCPeerRouteData struct {
+	PubKey        string
+	Hostname      string
+	MeshIP        string
+	Endpoint      string
+	Path          string
+	Installed     bool
+	RelayPubKey   string // set when Path is PathRelay
+	RelayHostname string
+	RelayMeshIP   string
+	RelayEndpoint string
+}
+
+// ResolvePeer finds a known peer by hostname, mesh IP (v4 or v6), public
+// key or unique public key prefix.
+func (d *Daemon) ResolvePeer(target string) (*PeerInfo, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, fmt.Errorf("no peer given")
+	}
+	if p, ok := d.peerStore.Get(target); ok {
+		return p, nil
+	}
+
+	ip := net.ParseIP(target)
+	var prefixMatches []*PeerInfo
+	for _, p := range d.peerStore.GetAll() {
+		if ip != nil && (ip.Equal(net.ParseIP(p.MeshIP)) || ip.Equal(net.ParseIP(p.MeshIPv6))) {
+			return p, nil
+		}
+		if p.Hostname != "" && strings.EqualFold(p.Hostname, target) {
+			return p, nil
+		}
+		if strings.HasPrefix(p.WGPubKey, target) {
+			prefixMatches = append(prefixMatches, p)
+		}
+	}
+	switch len(prefixMatches) {
+	case 0:
+		return nil, fmt.Errorf("no peer matches %q", target)
+	case 1:
+		return prefixMatches[0], nil
+	default:
+		return nil, fmt.Errorf("%q matches %d peers; give more of the key", target, len(prefixMatches))
+	}
+}
+
+// PingPeer sends one ping to the peer's mesh probe listener, over the
+// tunnel, and returns the round-trip time. It uses its own connection, so
+// it neither waits for nor disturbs the health monitor's probe sessions,
+// and the result does not count towards the peer's health.
+func (d *Daemon) PingPeer(target string) (*RPCPingData, error) {
+	peer, err := d.ResolvePeer(target)
+	if err != nil {
+		return nil, err
+	}
+	path, _ := d.peerPath(peer)
+	result := &RPCPingData{
+		PubKey:   peer.WGPubKey,
+		Hostname: peer.Hostname,
+		MeshIP:   peer.MeshIP,
+		Path:     path,
+	}
+	if !d.isInstalled(peer.WGPubKey) {
+		result.Error = "peer is not installed in WireGuard (--max-installed-peers)"
+		return result, nil
+	}
+
+	rtt, err := d.pingOnce(peer)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.RTT = rtt
+	return result, nil
+}
+
+func (d *Daemon) pingOnce(peer *PeerInfo) (time.Duration, error) {
+	addr := net.JoinHostPort(peer.MeshIP, strconv.Itoa(d.healthProbePort))
+	if !d.config.DisableIPv6 && peer.MeshIPv6 != "" {
+		addr = net.JoinHostPort(peer.MeshIPv6, strconv.Itoa(d.healthProbePort))
+	}
+	conn, err := d.dialProbeOnInterface(addr)
+	if err != nil {
+		return 0, fmt.Errorf("no answer from %s", addr)
+	}
+	defer conn.Close()
+
+	_ = conn.SetDeadline(time.Now().Add(MeshProbeDialTimeout))
+	start := time.Now()
+	if _, err := conn.Write([]byte("ping\n")); err != nil {
+		return 0, fmt.Errorf("send failed: %w", err)
+	}
+	buf := make([]byte, 16)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return 0, fmt.Errorf("no reply: %w", err)
+	}
+	if strings.TrimSpace(string(buf[:n])) != "pong" {
+		return 0, fmt.Errorf("unexpected reply %q", strings.TrimSpace(string(buf[:n])))
+	}
+	return time.Since(start), nil
+}
+
+// GetRPCPeerRoute reports the path traffic to the peer takes.
+func (d *Daemon) GetRPCPeerRoute(target string) (*RPCPeerRouteData, error) {
+	peer, err := d.ResolvePeer(target)
+	if err != nil {
+		return nil, err
+	}
+	path, relayKey := d.peerPath(peer)
+	route := &RPCPeerRouteData{
+		PubKey:    peer.WGPubKey,
+		Hostname:  peer.Hostname,
+		MeshIP:    peer.MeshIP,
+		Endpoint:  peer.Endpoint,
+		Path:      path,
+		Installed: d.isInstalled(peer.WGPubKey),
+	}
+	if relayKey != "" {
+		route.RelayPubKey = relayKey
+		if relay, ok := d.peerStore.Get(relayKey); ok {
+			route.RelayHostname = relay.Hostname
+			route.RelayMeshIP = relay.MeshIP
+			route.RelayEndpoint = relay.Endpoint
+		}
+	}
+	return route, nil
+}
+
+// peerPath classifies how traffic to p leaves this node, and for relayed
+// peers returns the relay's key.
+func (d *Daemon) peerPath(p *PeerInfo) (string, string) {
+	return peerPathWith(p, d.currentRelayRoutesSnapshot(), d.getLocalSubnets())
+}
+
+// peerPathWith is peerPath for callers classifying many peers against one
+// snapshot of the relay routes and local subnets.
+func peerPathWith(p *PeerInfo, relayRoutes map[string]string, localSubnets []*net.IPNet) (string, string) {
+	if relayKey, ok := relayRoutes[p.WGPubKey]; ok {
+		return PathRelay, relayKey
+	}
+	if hasDiscoveryMethod(p.DiscoveredVia, LANMethod) || endpointOnAnyLocalSubnet(p.Endpoint, localSubnets) {
+		return PathDirectLAN, ""
+	}
+	return PathDirect, ""
+}
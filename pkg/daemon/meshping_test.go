@@ -0,0 +1,127 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+func newMeshPingTestDaemon() *Daemon {
+	d := &Daemon{
+		config:           &Config{},
+		localNode:        &LocalNode{WGPubKey: "local1"},
+		peerStore:        NewPeerStore(),
+		relayRoutes:      make(map[string]string),
+		temporaryOffline: make(map[string]time.Time),
+		localSubnetsFn: func() []*net.IPNet {
+			_, lan, _ := net.ParseCIDR("192.168.1.0/24")
+			return []*net.IPNet{lan}
+		},
+	}
+	d.peerStore.Update(&PeerInfo{WGPubKey: "alphaKey1", Hostname: "alpha", MeshIP: "10.0.0.2", MeshIPv6: "fd00::2", Endpoint: "192.168.1.20:51820"}, "dht")
+	d.peerStore.Update(&PeerInfo{WGPubKey: "alphaKey2", Hostname: "beta", MeshIP: "10.0.0.3", Endpoint: "203.0.113.5:51820"}, "dht")
+	d.peerStore.Update(&PeerInfo{WGPubKey: "introKey", Hostname: "intro1", MeshIP: "10.0.0.1", Endpoint: "198.51.100.1:51820", Introducer: true}, "dht")
+	return d
+}
+
+func TestResolvePeer(t *testing.T) {
+	d := newMeshPingTestDaemon()
+	for target, want := range map[string]string{
+		"alphaKey2": "alphaKey2",
+		"ALPHA":     "alphaKey1",
+		"10.0.0.3":  "alphaKey2",
+		"fd00::2":   "alphaKey1",
+		"intro":     "introKey",
+	} {
+		p, err := d.ResolvePeer(target)
+		if err != nil {
+			t.Errorf("ResolvePeer(%q) failed: %v", target, err)
+			continue
+		}
+		if p.WGPubKey != want {
+			t.Errorf("ResolvePeer(%q) = %s, want %s", target, p.WGPubKey, want)
+		}
+	}
+	for _, target := range []string{"", "alphaKey", "10.0.0.99", "gamma"} {
+		if _, err := d.ResolvePeer(target); err == nil {
+			t.Errorf("ResolvePeer(%q) should fail", target)
+		}
+	}
+}
+
+func TestGetRPCPeerRoute(t *testing.T) {
+	d := newMeshPingTestDaemon()
+	d.relayRoutes["alphaKey2"] = "introKey"
+
+	route, err := d.GetRPCPeerRoute("alpha")
+	if err != nil {
+		t.Fatalf("GetRPCPeerRoute failed: %v", err)
+	}
+	if route.Path != PathDirectLAN || route.RelayPubKey != "" {
+		t.Errorf("alpha: path %s via %q, want direct-lan", route.Path, route.RelayPubKey)
+	}
+
+	route, err = d.GetRPCPeerRoute("beta")
+	if err != nil {
+		t.Fatalf("GetRPCPeerRoute failed: %v", err)
+	}
+	if route.Path != PathRelay || route.RelayHostname != "intro1" || route.RelayMeshIP != "10.0.0.1" {
+		t.Errorf("beta: unexpected route %+v", route)
+	}
+
+	route, err = d.GetRPCPeerRoute("intro1")
+	if err != nil {
+		t.Fatalf("GetRPCPeerRoute failed: %v", err)
+	}
+	if route.Path != PathDirect || !route.Installed {
+		t.Errorf("intro1: unexpected route %+v", route)
+	}
+}
+
+func TestGetRPCPeersDetails(t *testing.T) {
+	d := newMeshPingTestDaemon()
+	d.config.Keys = &crypto.DerivedKeys{GossipPort: 51821}
+	d.relayRoutes["alphaKey2"] = "introKey"
+	d.peerStore.Update(&PeerInfo{WGPubKey: "alphaKey2", NATType: "symmetric", Version: "v0.9.0"}, "gossip")
+	d.peerStore.RecordProbe("alphaKey2", 0, false)
+
+	peer, ok := d.GetRPCPeer("alphaKey2")
+	if !ok {
+		t.Fatal("GetRPCPeer(alphaKey2) not found")
+	}
+	if peer.Version != "v0.9.0" || peer.NATType != "symmetric" {
+		t.Errorf("version %q, NAT %q; want v0.9.0, symmetric", peer.Version, peer.NATType)
+	}
+	if peer.Path != PathRelay || peer.RelayPubKey != "introKey" {
+		t.Errorf("path %s via %q, want relay via introKey", peer.Path, peer.RelayPubKey)
+	}
+	if peer.ControlEndpoint != "203.0.113.5:51821" {
+		t.Errorf("control endpoint = %q", peer.ControlEndpoint)
+	}
+	if peer.Probes != 1 || peer.ProbesLost != 1 {
+		t.Errorf("probes %d/%d lost, want 1/1", peer.ProbesLost, peer.Probes)
+	}
+
+	paths := make(map[string]string)
+	for _, p := range d.GetRPCPeers() {
+		paths[p.Hostname] = p.Path
+	}
+	if paths["alpha"] != PathDirectLAN || paths["beta"] != PathRelay || paths["intro1"] != PathDirect {
+		t.Errorf("unexpected paths %v", paths)
+	}
+}
+
+func TestPingPeerUninstalled(t *testing.T) {
+	d := newMeshPingTestDaemon()
+	d.install.installed = map[string]bool{"introKey": true}
+
+	ping, err := d.PingPeer("beta")
+	if err != nil {
+		t.Fatalf("PingPeer failed: %v", err)
+	}
+	if ping.Error == "" || ping.RTT != 0 {
+		t.Errorf("pinging a peer left out of WireGuard should fail: %+v", ping)
+	}
+}
@@ -45,6 +45,10 @@ var (
 		MaxAge:     10 * time.Minute,
 		AgeBuckets: 5,
 	}, []string{"peer_key"})
+	probeLoss = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "wgmesh_probe_loss_ratio",
+		Help: "Fraction of recent mesh health probes lost, per peer",
+	}, []string{"peer_key"})
 	natTraversalAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name: "wgmesh_nat_traversal_attempts_total",
 		Help: "NAT traversal attempts by method",
@@ -53,6 +57,42 @@ var (
 		Name: "wgmesh_nat_traversal_successes_total",
 		Help: "Successful NAT traversal exchanges by method",
 	}, []string{"method"})
+	exchangeDrops = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wgmesh_exchange_dropped_packets_total",
+		Help: "Packets dropped by the peer exchange listener, by reason",
+	}, []string{"reason"})
+	gossipDigests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wgmesh_gossip_digests_total",
+		Help: "Gossip digests received, by whether they matched the local peer set",
+	}, []string{"result"})
+	discoverySentBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wgmesh_discovery_sent_bytes_total",
+		Help: "Bytes sent by DHT and peer exchange discovery, including UDP/IP headers, by kind",
+	}, []string{"kind"})
+	discoveryThrottled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wgmesh_discovery_throttled_total",
+		Help: "Discovery sends skipped or shrunk to stay within --discovery-bandwidth, by kind",
+	}, []string{"kind"})
+	discoveryBandwidthAvailable = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "wgmesh_discovery_bandwidth_available_bytes",
+		Help: "Bytes left in the --discovery-bandwidth token bucket",
+	})
+	clockSkewSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "wgmesh_clock_skew_seconds",
+		Help: "Estimated offset of the local clock from the mesh (positive = ahead), 0 while below the significance threshold",
+	})
+	resourceUsage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "wgmesh_resource_usage",
+		Help: "Open file descriptors, goroutines and probe sessions, by resource",
+	}, []string{"resource"})
+	resourceLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "wgmesh_resource_limit",
+		Help: "Limit the resource guard holds each resource to (0 = not tracked), by resource",
+	}, []string{"resource"})
+	probeSessionsShed = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "wgmesh_probe_sessions_shed_total",
+		Help: "Probe sessions closed early to stay within --resource-limits",
+	})
 
 	goCollector      = collectors.NewGoCollector()
 	processCollector = collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})
@@ -75,8 +115,18 @@ func RegisterMetrics() {
 	prometheus.MustRegister(reconcileDuration)
 	prometheus.MustRegister(probeRTT)
 	prometheus.MustRegister(probeRTTSummary)
+	prometheus.MustRegister(probeLoss)
 	prometheus.MustRegister(natTraversalAttempts)
 	prometheus.MustRegister(natTraversalSuccesses)
+	prometheus.MustRegister(exchangeDrops)
+	prometheus.MustRegister(gossipDigests)
+	prometheus.MustRegister(clockSkewSeconds)
+	prometheus.MustRegister(discoverySentBytes)
+	prometheus.MustRegister(discoveryThrottled)
+	prometheus.MustRegister(discoveryBandwidthAvailable)
+	prometheus.MustRegister(resourceUsage)
+	prometheus.MustRegister(resourceLimit)
+	prometheus.MustRegister(probeSessionsShed)
 	prometheus.MustRegister(goCollector)
 	prometheus.MustRegister(processCollector)
 }
@@ -119,6 +169,38 @@ func RecordDiscoveryEvent(layer string) {
 	discoveryEvents.WithLabelValues(layer).Inc()
 }
 
+// RecordExchangeDrop increments the dropped packet counter of the peer
+// exchange listener: "rate_limited", "budget", "busy", "decrypt_failed",
+// "clock_skew" (a mesh message outside the timestamp window) or "chaos"
+// (dropped on purpose by --chaos).
+func RecordExchangeDrop(reason string) {
+	exchangeDrops.WithLabelValues(reason).Inc()
+}
+
+// RecordGossipDigest counts a received gossip digest as "in_sync" or
+// "mismatch".
+func RecordGossipDigest(result string) {
+	gossipDigests.WithLabelValues(result).Inc()
+}
+
+// RecordDiscoverySent counts n bytes sent by discovery: "dht", "exchange"
+// or "gossip".
+func RecordDiscoverySent(kind string, n int) {
+	discoverySentBytes.WithLabelValues(kind).Add(float64(n))
+}
+
+// RecordDiscoveryThrottled counts a discovery send of the given kind that
+// was skipped or shrunk for the bandwidth budget.
+func RecordDiscoveryThrottled(kind string) {
+	discoveryThrottled.WithLabelValues(kind).Inc()
+}
+
+// SetDiscoveryBandwidthAvailable sets how many bytes are left in the
+// discovery bandwidth budget.
+func SetDiscoveryBandwidthAvailable(available float64) {
+	discoveryBandwidthAvailable.Set(available)
+}
+
 // ObserveProbeRTT records the round-trip time for a mesh probe to the given peer.
 // peerKey should be the first 8 characters of the WireGuard public key.
 func ObserveProbeRTT(peerKey string, start time.Time) {
@@ -132,6 +214,20 @@ func ObserveProbeRTTSummary(peerKey string, rtt time.Duration) {
 	probeRTTSummary.WithLabelValues(peerKey).Observe(rtt.Seconds())
 }
 
+// ObservePeerProbeLoss sets the rolling probe loss ratio for the given peer.
+// peerKey should be the first 8 characters of the WireGuard public key.
+func ObservePeerProbeLoss(peerKey string, loss float64) {
+	probeLoss.WithLabelValues(peerKey).Set(loss)
+}
+
+// metricsPeerKey returns the peer_key label for a WireGuard public key.
+func metricsPeerKey(pubKey string) string {
+	if len(pubKey) > 8 {
+		return pubKey[:8]
+	}
+	return pubKey
+}
+
 // RecordNATTraversalAttempt increments the attempt counter for the given method.
 // method is the discovery method string, e.g. "dht", "dht-rendezvous", "dht-ipv6-sync".
 func RecordNATTraversalAttempt(method string) {
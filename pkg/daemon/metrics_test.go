@@ -181,6 +181,27 @@ func TestRecordDiscoveryEvent(t *testing.T) {
 	}
 }
 
+func TestRecordExchangeDrop(t *testing.T) {
+	exchangeDrops.DeleteLabelValues("budget")
+
+	RecordExchangeDrop("budget")
+	RecordExchangeDrop("budget")
+
+	if val := testutil.ToFloat64(exchangeDrops.WithLabelValues("budget")); val != 2 {
+		t.Errorf("expected 2 budget drops, got %v", val)
+	}
+}
+
+func TestRecordGossipDigest(t *testing.T) {
+	gossipDigests.DeleteLabelValues("mismatch")
+
+	RecordGossipDigest("mismatch")
+
+	if val := testutil.ToFloat64(gossipDigests.WithLabelValues("mismatch")); val != 1 {
+		t.Errorf("expected 1 mismatched digest, got %v", val)
+	}
+}
+
 func TestProbeRTTHistogram(t *testing.T) {
 	// Record a probe RTT and verify histogram has at least one observation.
 	start := time.Now()
@@ -264,3 +285,10 @@ func TestProbeRTTSummary(t *testing.T) {
 		t.Error("expected summary observation for peer_key=testkey1")
 	}
 }
+
+func TestPeerProbeLossGauge(t *testing.T) {
+	ObservePeerProbeLoss("losskey1", 0.25)
+	if got := testutil.ToFloat64(probeLoss.WithLabelValues("losskey1")); got != 0.25 {
+		t.Errorf("expected loss ratio 0.25, got %v", got)
+	}
+}
@@ -0,0 +1,128 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// A laptop that resumes from suspend or joins another network keeps stale
+// NAT mappings, probe sessions and an old public endpoint until the STUN
+// refresh and handshake staleness catch up, a minute or more. The network
+// monitor notices both cases right away: address, link and default route
+// changes (from netlink on Linux, by polling elsewhere), and jumps of the
+// wall clock against the monotonic clock, which stands still in suspend.
+const (
+	NetworkMonitorInterval = 5 * time.Second
+	// NetworkChangeSettle is how long changes must stop before they are
+	// acted on, so a DHCP lease and the routes that follow count once.
+	NetworkChangeSettle = 2 * time.Second
+	// ResumeClockJump is how far the wall clock must run ahead of the
+	// monotonic clock between two checks to count as a resume.
+	ResumeClockJump = 10 * time.Second
+)
+
+// EventNetworkChange is recorded when the host resumed or changed networks.
+const EventNetworkChange = "network_change"
+
+// NetworkChangeHandler is implemented by discovery layers that re-learn
+// the local endpoint and re-announce it when the host changes networks.
+type NetworkChangeHandler interface {
+	HandleNetworkChange()
+}
+
+// networkMonitorLoop watches for resumes and network changes and handles
+// each burst of them once it settles.
+func (d *Daemon) networkMonitorLoop() {
+	changes, err := watchNetworkChanges(d.ctx, d.config.InterfaceName)
+	if err != nil {
+		log.Printf("[Network] Not watching for network changes: %v", err)
+	}
+
+	ticker := time.NewTicker(NetworkMonitorInterval)
+	defer ticker.Stop()
+	settle := time.NewTimer(NetworkChangeSettle)
+	settle.Stop()
+
+	last := time.Now()
+	pending := ""
+	for {
+		select {
+		case <-d.ctx.Done():
+			settle.Stop()
+			return
+		case now := <-ticker.C:
+			wall, monotonic := now.Round(0).Sub(last.Round(0)), now.Sub(last)
+			if reason := clockJumpReason(wall, monotonic); reason != "" && pending == "" {
+				pending = reason
+				settle.Reset(NetworkChangeSettle)
+			}
+			last = now
+		case reason, ok := <-changes:
+			if !ok {
+				changes = nil
+				continue
+			}
+			if pending == "" {
+				pending = reason
+			}
+			settle.Reset(NetworkChangeSettle)
+		case <-settle.C:
+			d.handleNetworkChange(pending)
+			pending = ""
+		}
+	}
+}
+
+// clockJumpReason compares how much wall-clock and monotonic time passed
+// between two checks. The wall clock running ahead means the host was
+// suspended; it falling behind means it was stepped back.
+func clockJumpReason(wall, monotonic time.Duration) string {
+	jump := wall - monotonic
+	switch {
+	case jump >= ResumeClockJump:
+		return fmt.Sprintf("resumed after about %v", jump.Round(time.Second))
+	case jump <= -ResumeClockJump:
+		return fmt.Sprintf("wall clock stepped back %v", (-jump).Round(time.Second))
+	}
+	return ""
+}
+
+// handleNetworkChange drops what was learned about the old network: probe
+// results and sessions, probe-based offline marks, and the local endpoint,
+// which discovery re-learns and pushes to the mesh.
+func (d *Daemon) handleNetworkChange(reason string) {
+	log.Printf("[Network] %s: resetting probes, re-discovering the endpoint and re-announcing", reason)
+	d.recordEvent(EventNetworkChange, "", map[string]string{"reason": reason})
+	d.resetProbes()
+	d.stopTCPTunnel(reason)
+	if h, ok := d.dhtDiscovery.(NetworkChangeHandler); ok {
+		h.HandleNetworkChange()
+	}
+}
+
+// resetProbes forgets all mesh probe state, so peers are judged by the new
+// network's paths from the next probe round on.
+func (d *Daemon) resetProbes() {
+	d.probeMu.Lock()
+	keys := make([]string, 0, len(d.probeSessions))
+	for pubKey := range d.probeSessions {
+		keys = append(keys, pubKey)
+	}
+	clear(d.probeFailures)
+	clear(d.lastStatsProbe)
+	d.probeMu.Unlock()
+	for _, pubKey := range keys {
+		d.closeProbeSession(pubKey)
+	}
+
+	d.healthMu.Lock()
+	clear(d.peerHealthFailures)
+	d.healthMu.Unlock()
+
+	d.offlineMu.Lock()
+	clear(d.temporaryOffline)
+	d.offlineMu.Unlock()
+
+	d.peerStore.ResetProbes()
+}
@@ -0,0 +1,55 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClockJumpReason(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name            string
+		wall, monotonic time.Duration
+		want            string
+	}{
+		{"steady", NetworkMonitorInterval, NetworkMonitorInterval, ""},
+		{"small drift", NetworkMonitorInterval + time.Second, NetworkMonitorInterval, ""},
+		{"resume", time.Hour, NetworkMonitorInterval, "resumed after about 59m55s"},
+		{"stepped back", -time.Minute, NetworkMonitorInterval, "wall clock stepped back 1m5s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clockJumpReason(tt.wall, tt.monotonic); got != tt.want {
+				t.Errorf("clockJumpReason(%v, %v) = %q, want %q", tt.wall, tt.monotonic, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleNetworkChangeResetsProbesAndRecordsEvent(t *testing.T) {
+	t.Parallel()
+	d := newMinimalDaemon(t)
+	d.probeSessions = make(map[string]*peerProbeSession)
+	d.probeFailures = map[string]int{"peer": 3}
+	d.peerHealthFailures = map[string]int{"peer": 2}
+	d.temporaryOffline["peer"] = time.Now().Add(time.Minute)
+	d.peerStore.Update(&PeerInfo{WGPubKey: "peer"}, "test")
+	d.peerStore.RecordProbe("peer", time.Millisecond, false)
+
+	d.handleNetworkChange("address change on eth0")
+
+	if len(d.probeFailures) != 0 || len(d.peerHealthFailures) != 0 {
+		t.Error("probe and health failures not reset")
+	}
+	if d.isTemporarilyOffline("peer") {
+		t.Error("temporary offline mark not cleared")
+	}
+	if p, _ := d.peerStore.Get("peer"); p.PacketLoss != nil {
+		t.Errorf("packet loss = %v, want unknown after the change", *p.PacketLoss)
+	}
+	events := d.events.since(0)
+	if len(events) != 1 || events[0].Type != EventNetworkChange || !strings.Contains(events[0].Details["reason"], "eth0") {
+		t.Errorf("events = %+v, want one %s naming eth0", events, EventNetworkChange)
+	}
+}
@@ -0,0 +1,141 @@
+package daemon
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// rtnetlink multicast groups (linux/rtnetlink.h), which package syscall
+// does not define.
+const (
+	rtmgrpLink       = 0x1
+	rtmgrpIPv4Ifaddr = 0x10
+	rtmgrpIPv4Route  = 0x40
+	rtmgrpIPv6Ifaddr = 0x100
+	rtmgrpIPv6Route  = 0x400
+)
+
+// watchNetworkChanges reports link, address and default route changes on
+// interfaces other than ignoreIface (the mesh's own), read from an
+// rtnetlink multicast socket. The channel is closed when ctx ends.
+func watchNetworkChanges(ctx context.Context, ignoreIface string) (<-chan string, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC|syscall.SOCK_NONBLOCK, syscall.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %w", err)
+	}
+	groups := uint32(rtmgrpLink | rtmgrpIPv4Ifaddr | rtmgrpIPv6Ifaddr | rtmgrpIPv4Route | rtmgrpIPv6Route)
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: groups}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("netlink bind: %w", err)
+	}
+	// A non-blocking descriptor goes through the runtime poller, so Close
+	// unblocks the pending Read.
+	f := os.NewFile(uintptr(fd), "rtnetlink")
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+
+	ignoreIndex := 0
+	if iface, err := net.InterfaceByName(ignoreIface); err == nil {
+		ignoreIndex = iface.Index
+	}
+
+	ch := make(chan string, 1)
+	go func() {
+		defer close(ch)
+		buf := make([]byte, 1<<16)
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				continue
+			}
+			for i := range msgs {
+				reason := describeNetlinkChange(&msgs[i], ignoreIface, &ignoreIndex)
+				if reason == "" {
+					continue
+				}
+				select {
+				case ch <- reason:
+				default: // one pending change is enough
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// describeNetlinkChange returns why m is a network change worth acting on,
+// or "" for messages about the mesh interface, link-local addresses and
+// routes other than default routes. Link messages naming ignoreIface update
+// *ignoreIndex, as the interface may be recreated.
+func describeNetlinkChange(m *syscall.NetlinkMessage, ignoreIface string, ignoreIndex *int) string {
+	switch m.Header.Type {
+	case syscall.RTM_NEWLINK, syscall.RTM_DELLINK:
+		if len(m.Data) < syscall.SizeofIfInfomsg {
+			return ""
+		}
+		index := int(int32(binary.NativeEndian.Uint32(m.Data[4:8])))
+		attrs, _ := syscall.ParseNetlinkRouteAttr(m)
+		for _, a := range attrs {
+			if a.Attr.Type == syscall.IFLA_IFNAME && string(trimNUL(a.Value)) == ignoreIface {
+				*ignoreIndex = index
+			}
+		}
+		if index == *ignoreIndex {
+			return ""
+		}
+		return "link change on " + interfaceName(index)
+	case syscall.RTM_NEWADDR, syscall.RTM_DELADDR:
+		if len(m.Data) < syscall.SizeofIfAddrmsg {
+			return ""
+		}
+		scope := m.Data[3]
+		index := int(binary.NativeEndian.Uint32(m.Data[4:8]))
+		if index == *ignoreIndex || scope == syscall.RT_SCOPE_LINK || scope == syscall.RT_SCOPE_HOST {
+			return ""
+		}
+		return "address change on " + interfaceName(index)
+	case syscall.RTM_NEWROUTE, syscall.RTM_DELROUTE:
+		if len(m.Data) < syscall.SizeofRtMsg {
+			return ""
+		}
+		if dstLen := m.Data[1]; dstLen != 0 {
+			return ""
+		}
+		attrs, _ := syscall.ParseNetlinkRouteAttr(m)
+		for _, a := range attrs {
+			if a.Attr.Type == syscall.RTA_OIF && len(a.Value) >= 4 {
+				index := int(binary.NativeEndian.Uint32(a.Value))
+				if index == *ignoreIndex {
+					return ""
+				}
+				return "default route change via " + interfaceName(index)
+			}
+		}
+		return "default route change"
+	}
+	return ""
+}
+
+func interfaceName(index int) string {
+	if iface, err := net.InterfaceByIndex(index); err == nil {
+		return iface.Name
+	}
+	return fmt.Sprintf("interface %d", index)
+}
+
+func trimNUL(b []byte) []byte {
+	for len(b) > 0 && b[len(b)-1] == 0 {
+		b = b[:len(b)-1]
+	}
+	return b
+}
@@ -0,0 +1,68 @@
+//go:build !linux
+
+package daemon
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// watchNetworkChanges reports changes to the addresses of interfaces other
+// than ignoreIface (the mesh's own). Without netlink it polls them every
+// NetworkMonitorInterval. The channel is closed when ctx ends.
+func watchNetworkChanges(ctx context.Context, ignoreIface string) (<-chan string, error) {
+	ch := make(chan string, 1)
+	go func() {
+		defer close(ch)
+		ticker := time.NewTicker(NetworkMonitorInterval)
+		defer ticker.Stop()
+		last := addressFingerprint(ignoreIface)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				current := addressFingerprint(ignoreIface)
+				if current == last {
+					continue
+				}
+				last = current
+				select {
+				case ch <- "address change":
+				default:
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// addressFingerprint lists the non-link-local addresses of the interfaces
+// that are up, except ignoreIface and loopback.
+func addressFingerprint(ignoreIface string) string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	var entries []string
+	for _, iface := range ifaces {
+		if iface.Name == ignoreIface || iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if ipNet, ok := a.(*net.IPNet); ok && ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			entries = append(entries, iface.Name+" "+a.String())
+		}
+	}
+	slices.Sort(entries)
+	return strings.Join(entries, ",")
+}
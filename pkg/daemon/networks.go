@@ -0,0 +1,129 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// NetworksPath returns the registry of meshes this host has joined.
+func NetworksPath() string {
+	return filepath.Join(stateDir, "networks.json")
+}
+
+// NetworkRecord is a mesh in the registry. Each interface runs one mesh, so
+// records are keyed by interface.
+type NetworkRecord struct {
+	Interface   string    `json:"interface"`
+	Name        string    `json:"name,omitempty"`
+	NetworkID   string    `json:"network_id"` // first 8 bytes, as in logs and status
+	MeshSubnet  string    `json:"mesh_subnet"`
+	SocketPath  string    `json:"socket_path,omitempty"`
+	Service     string    `json:"service,omitempty"` // init system of the installed service, if any
+	JoinedAt    time.Time `json:"joined_at"`
+	LastStarted time.Time `json:"last_started,omitempty"`
+}
+
+type networksFile struct {
+	Networks []NetworkRecord `json:"networks"`
+}
+
+// LoadNetworks returns the registry sorted by interface; a missing registry
+// is empty.
+func LoadNetworks() ([]NetworkRecord, error) {
+	data, err := os.ReadFile(NetworksPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f networksFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", NetworksPath(), err)
+	}
+	sort.Slice(f.Networks, func(i, j int) bool { return f.Networks[i].Interface < f.Networks[j].Interface })
+	return f.Networks, nil
+}
+
+// RecordNetworkStart registers the mesh of cfg as started now, answering
+// RPC on socketPath. A different mesh on the same interface replaces the
+// old record.
+func RecordNetworkStart(cfg *Config, socketPath string) error {
+	return updateNetwork(cfg, func(rec *NetworkRecord) {
+		rec.SocketPath = socketPath
+		rec.LastStarted = time.Now().UTC()
+	})
+}
+
+// RecordNetworkService registers the mesh of cfg as run by the service of
+// initSystem.
+func RecordNetworkService(cfg *Config, initSystem string) error {
+	return updateNetwork(cfg, func(rec *NetworkRecord) {
+		rec.Service = initSystem
+	})
+}
+
+// ClearNetworkService unmarks the meshes run by the service of initSystem
+// after it is uninstalled.
+func ClearNetworkService(initSystem string) error {
+	return editNetworks(func(recs []NetworkRecord) []NetworkRecord {
+		for i := range recs {
+			if recs[i].Service == initSystem {
+				recs[i].Service = ""
+			}
+		}
+		return recs
+	})
+}
+
+// ForgetNetwork drops the record of the mesh on ifaceName.
+func ForgetNetwork(ifaceName string) error {
+	return editNetworks(func(recs []NetworkRecord) []NetworkRecord {
+		out := recs[:0]
+		for _, rec := range recs {
+			if rec.Interface != ifaceName {
+				out = append(out, rec)
+			}
+		}
+		return out
+	})
+}
+
+// updateNetwork applies fn to the record of cfg's interface, first
+// refreshing what cfg says about the mesh.
+func updateNetwork(cfg *Config, fn func(*NetworkRecord)) error {
+	networkID := fmt.Sprintf("%x", cfg.Keys.NetworkID[:8])
+	return editNetworks(func(recs []NetworkRecord) []NetworkRecord {
+		i := 0
+		for ; i < len(recs) && recs[i].Interface != cfg.InterfaceName; i++ {
+		}
+		if i == len(recs) {
+			recs = append(recs, NetworkRecord{Interface: cfg.InterfaceName})
+		}
+		rec := &recs[i]
+		if rec.NetworkID != networkID {
+			*rec = NetworkRecord{Interface: cfg.InterfaceName, NetworkID: networkID, JoinedAt: time.Now().UTC()}
+		}
+		rec.Name = cfg.MeshName
+		rec.MeshSubnet = cfg.MeshSubnetString()
+		fn(rec)
+		return recs
+	})
+}
+
+func editNetworks(fn func([]NetworkRecord) []NetworkRecord) error {
+	recs, err := LoadNetworks()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(networksFile{Networks: fn(recs)}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(NetworksPath(), data, 0600)
+}
@@ -0,0 +1,56 @@
+package daemon
+
+import (
+	"testing"
+)
+
+func TestNetworkRegistry(t *testing.T) {
+	prev := stateDir
+	stateDir = t.TempDir()
+	t.Cleanup(func() { stateDir = prev })
+
+	cfg, err := NewConfig(DaemonOpts{Secret: testConfigSecret, InterfaceName: "wg1", MeshName: "lab"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordNetworkService(cfg, InitSystemd); err != nil {
+		t.Fatalf("RecordNetworkService: %v", err)
+	}
+	if err := RecordNetworkStart(cfg, "/run/wgmesh-wg1.sock"); err != nil {
+		t.Fatalf("RecordNetworkStart: %v", err)
+	}
+
+	recs, err := LoadNetworks()
+	if err != nil {
+		t.Fatalf("LoadNetworks: %v", err)
+	}
+	if len(recs) != 1 {
+		t.Fatalf("got %d records, want 1", len(recs))
+	}
+	rec := recs[0]
+	if rec.Interface != "wg1" || rec.Name != "lab" || rec.SocketPath != "/run/wgmesh-wg1.sock" || rec.Service != InitSystemd {
+		t.Errorf("record = %+v", rec)
+	}
+	if rec.MeshSubnet != cfg.MeshSubnetString() || rec.JoinedAt.IsZero() || rec.LastStarted.IsZero() {
+		t.Errorf("record = %+v", rec)
+	}
+
+	// Another mesh on the same interface replaces the record.
+	other, err := NewConfig(DaemonOpts{Secret: testConfigSecret + "-other", InterfaceName: "wg1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordNetworkStart(other, "/run/wgmesh-wg1.sock"); err != nil {
+		t.Fatal(err)
+	}
+	if recs, _ := LoadNetworks(); len(recs) != 1 || recs[0].Name != "" || recs[0].Service != "" {
+		t.Errorf("after a new mesh on wg1: %+v", recs)
+	}
+
+	if err := ForgetNetwork("wg1"); err != nil {
+		t.Fatalf("ForgetNetwork: %v", err)
+	}
+	if recs, _ := LoadNetworks(); len(recs) != 0 {
+		t.Errorf("after ForgetNetwork: %+v", recs)
+	}
+}
@@ -0,0 +1,162 @@
+package daemon
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+// WireGuard preshared key kinds, as recorded in applied peer signatures.
+const (
+	pskMesh = "mesh" // Keys.PSK, shared by the whole mesh
+	pskPair = "pair" // crypto.DerivePairPSK for this node and the peer
+)
+
+// Both ends of a tunnel must pick the same key kind, which holds as long as
+// each knows the other's PairPSK flag. One that only heard of the peer from
+// an older relay, or from a relay that lied about the flag, may not. When
+// no handshake completes within PSKHandshakeTimeout of applying a kind,
+// the other one is tried. The end with the higher public key waits three
+// times as long between tries, so two ends that both switch do not keep
+// missing each other. A kind that completed a handshake is kept until the
+// flags call for another or, on a tunnel with keepalives, until the
+// handshakes stop for PSKHandshakeTimeout.
+const PSKHandshakeTimeout = 3 * time.Minute
+
+// pskProbeState tracks the key kind in use with each peer.
+type pskProbeState struct {
+	mu    sync.Mutex
+	peers map[string]*pskProbe
+}
+
+type pskProbe struct {
+	announced string    // the kind the flags call for
+	flipped   bool      // using the other kind instead
+	since     time.Time // when the current kind was applied
+	confirmed bool      // a handshake completed since then
+}
+
+// presharedKey returns the preshared key for the tunnel to peer and its
+// kind. Peers that announce per-pair keys get one derived for the pair;
+// older peers only know the mesh-wide key.
+func (d *Daemon) presharedKey(peer *PeerInfo) ([32]byte, string) {
+	if peer == nil {
+		return d.config.Keys.PSK, pskMesh
+	}
+	if kind := d.pskProbes.kind(peer.WGPubKey, d.announcedPSKKind(peer)); kind == pskMesh {
+		return d.config.Keys.PSK, pskMesh
+	}
+	psk, err := crypto.DerivePairPSK(d.config.Secret, d.localNode.WGPubKey, peer.WGPubKey)
+	if err != nil {
+		log.Printf("Failed to derive preshared key for peer %s..., using the mesh key: %v", shortKey(peer.WGPubKey), err)
+		return d.config.Keys.PSK, pskMesh
+	}
+	return psk, pskPair
+}
+
+// announcedPSKKind returns the key kind the PairPSK flags of this node and
+// peer call for.
+func (d *Daemon) announcedPSKKind(peer *PeerInfo) string {
+	if peer.PairPSK && d.pairPSKEnabled() {
+		return pskPair
+	}
+	return pskMesh
+}
+
+// kind returns the key kind to use with pubKey when the flags call for
+// announced.
+func (s *pskProbeState) kind(pubKey, announced string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st := s.peers[pubKey]; st != nil && st.announced == announced && st.flipped {
+		return otherPSKKind(announced)
+	}
+	return announced
+}
+
+func otherPSKKind(kind string) string {
+	if kind == pskPair {
+		return pskMesh
+	}
+	return pskPair
+}
+
+// probePresharedKeys switches peers that have not completed a handshake
+// with the current key kind within PSKHandshakeTimeout to the other kind.
+// A node without per-pair keys has only the mesh key to offer, and leaves
+// the switching to its peers.
+func (d *Daemon) probePresharedKeys(peers []*PeerInfo, handshakes map[string]int64, now time.Time) {
+	if handshakes == nil || d.localNode == nil {
+		return
+	}
+	enabled := d.pairPSKEnabled()
+	d.pskProbes.mu.Lock()
+	defer d.pskProbes.mu.Unlock()
+	if !enabled {
+		d.pskProbes.peers = nil
+		return
+	}
+	if d.pskProbes.peers == nil {
+		d.pskProbes.peers = make(map[string]*pskProbe)
+	}
+	seen := make(map[string]struct{}, len(peers))
+	for _, p := range peers {
+		if p.WGPubKey == "" || p.WGPubKey == d.localNode.WGPubKey {
+			continue
+		}
+		seen[p.WGPubKey] = struct{}{}
+		announced := d.announcedPSKKind(p)
+		st := d.pskProbes.peers[p.WGPubKey]
+		if st == nil || st.announced != announced {
+			d.pskProbes.peers[p.WGPubKey] = &pskProbe{announced: announced, since: now}
+			continue
+		}
+		hs := handshakes[p.WGPubKey]
+		switch {
+		case st.confirmed:
+			// A tunnel with keepalives that stops handshaking may have
+			// been switched at the other end. Without them an idle tunnel
+			// stops too, so those keep their kind.
+			if now.Sub(time.Unix(hs, 0)) < PSKHandshakeTimeout || d.keepaliveForPeer(p) == 0 {
+				continue
+			}
+			st.confirmed = false
+			st.since = time.Unix(hs, 0)
+		case hs != 0 && hs >= st.since.Unix():
+			st.confirmed = true
+			continue
+		}
+		timeout := PSKHandshakeTimeout
+		if d.localNode.WGPubKey > p.WGPubKey {
+			timeout *= 3
+		}
+		if now.Sub(st.since) < timeout {
+			continue
+		}
+		st.flipped = !st.flipped
+		st.since = now
+		kind := announced
+		if st.flipped {
+			kind = otherPSKKind(announced)
+		}
+		log.Printf("No handshake with peer %s... within %v, trying the %s preshared key", shortKey(p.WGPubKey), timeout, kind)
+	}
+	for key := range d.pskProbes.peers {
+		if _, ok := seen[key]; !ok {
+			delete(d.pskProbes.peers, key)
+		}
+	}
+}
+
+// pairPSKEnabled reports whether this node uses per-pair preshared keys,
+// which is what it tells its peers. A node that announces itself always
+// does. An offline node announces nothing, so its peers go by its entry in
+// the --static-peers manifest and so must it.
+func (d *Daemon) pairPSKEnabled() bool {
+	if d.config.StaticPeers != "" {
+		return d.staticPairPSK.Load()
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+func TestPresharedKey(t *testing.T) {
+	t.Parallel()
+	d := newMinimalDaemon(t)
+	d.localNode = &LocalNode{WGPubKey: "local"}
+
+	psk, kind := d.presharedKey(&PeerInfo{WGPubKey: "old-peer"})
+	if kind != pskMesh || psk != d.config.Keys.PSK {
+		t.Errorf("peer without pair PSK support got kind %q, want the mesh key", kind)
+	}
+
+	psk, kind = d.presharedKey(&PeerInfo{WGPubKey: "new-peer", PairPSK: true})
+	if kind != pskPair || psk == d.config.Keys.PSK {
+		t.Fatalf("peer with pair PSK support got kind %q", kind)
+	}
+
+	// The peer derives the same key from its side of the pair.
+	peer := newMinimalDaemon(t)
+	peer.localNode = &LocalNode{WGPubKey: "new-peer"}
+	if theirs, _ := peer.presharedKey(&PeerInfo{WGPubKey: "local", PairPSK: true}); theirs != psk {
+		t.Error("the two ends of a pair derived different preshared keys")
+	}
+
+	other, _ := crypto.DerivePairPSK(d.config.Secret, "local", "another-peer")
+	if other == psk {
+		t.Error("different pairs share a preshared key")
+	}
+}
+
+func TestPresharedKeyAfterCacheRestore(t *testing.T) {
+	useTempStateDir(t)
+	alpha := &PeerInfo{WGPubKey: "alpha", MeshIP: "10.42.0.1", Endpoint: "203.0.113.1:51820", RoutesAnnounced: true, PairPSK: true}
+	beta := &PeerInfo{WGPubKey: "beta", MeshIP: "10.42.0.2", Endpoint: "203.0.113.2:51820", RoutesAnnounced: true, PairPSK: true}
+
+	a := newMinimalDaemon(t)
+	a.localNode = &LocalNode{WGPubKey: "alpha"}
+	a.peerStore.Update(beta, "dht")
+	if err := SavePeerCache("test-wg0", a.peerStore); err != nil {
+		t.Fatalf("SavePeerCache: %v", err)
+	}
+	b := newMinimalDaemon(t)
+	b.localNode = &LocalNode{WGPubKey: "beta"}
+	b.peerStore.Update(alpha, "dht")
+	theirs, _ := b.presharedKey(alpha)
+
+	// alpha restarts and knows beta only from its cache until beta
+	// announces again; a transitive mention from an older relay carries no
+	// flag.
+	restarted := newMinimalDaemon(t)
+	restarted.localNode = &LocalNode{WGPubKey: "alpha"}
+	if n := RestoreFromCache("test-wg0", restarted.peerStore); n != 1 {
+		t.Fatalf("restored %d peers, want 1", n)
+	}
+	restarted.peerStore.Update(&PeerInfo{WGPubKey: "beta", MeshIP: "10.42.0.2"}, "dht-transitive")
+	cached, _ := restarted.peerStore.Get("beta")
+	if mine, kind := restarted.presharedKey(cached); kind != pskPair || mine != theirs {
+		t.Errorf("after restoring from cache alpha uses the %s key, and it does not match beta's", kind)
+	}
+}
+
+func TestPresharedKeyHandshakeFallback(t *testing.T) {
+	t.Parallel()
+	// alpha knows beta announces per-pair keys; beta only heard of alpha
+	// from a relay that left the flag out.
+	alpha := newMinimalDaemon(t)
+	alpha.localNode = &LocalNode{WGPubKey: "alpha"}
+	toBeta := &PeerInfo{WGPubKey: "beta", PairPSK: true}
+	beta := newMinimalDaemon(t)
+	beta.localNode = &LocalNode{WGPubKey: "beta"}
+	toAlpha := &PeerInfo{WGPubKey: "alpha"}
+
+	// A handshake completes whenever both ends use the same key.
+	var handshake int64
+	step := func(now time.Time) bool {
+		mine, _ := alpha.presharedKey(toBeta)
+		theirs, _ := beta.presharedKey(toAlpha)
+		if mine == theirs {
+			handshake = now.Unix()
+		}
+		alpha.probePresharedKeys([]*PeerInfo{toBeta}, map[string]int64{"beta": handshake}, now)
+		beta.probePresharedKeys([]*PeerInfo{toAlpha}, map[string]int64{"alpha": handshake}, now)
+		return mine == theirs
+	}
+
+	now := time.Unix(1700000000, 0)
+	if step(now) {
+		t.Fatal("the two ends agreed on a key before any fallback")
+	}
+	agreedAt := time.Time{}
+	for i := 0; i < 120; i++ {
+		now = now.Add(30 * time.Second)
+		agreed := step(now)
+		switch {
+		case agreed && agreedAt.IsZero():
+			agreedAt = now
+		case !agreed && !agreedAt.IsZero():
+			t.Fatalf("the ends agreed at %v and drifted apart again at %v", agreedAt, now)
+		}
+	}
+	if agreedAt.IsZero() {
+		t.Fatal("the two ends never agreed on a preshared key")
+	}
+	if _, kind := alpha.presharedKey(toBeta); kind != pskMesh {
+		t.Errorf("alpha settled on the %s key, want the mesh key beta uses", kind)
+	}
+
+	// Once beta learns alpha's flag it switches to the per-pair key, and
+	// alpha follows when the handshakes stop.
+	toAlpha.PairPSK = true
+	for i := 0; i < 120; i++ {
+		now = now.Add(30 * time.Second)
+		step(now)
+	}
+	_, mine := alpha.presharedKey(toBeta)
+	_, theirs := beta.presharedKey(toAlpha)
+	if mine != pskPair || theirs != pskPair {
+		t.Errorf("alpha uses the %s key and beta the %s key, want both on the pair key", mine, theirs)
+	}
+}
+
+func TestPresharedKeyStaticManifest(t *testing.T) {
+	tests := []struct {
+		name        string
+		alpha, beta bool // pair_psk of each manifest entry
+		want        string
+	}{
+		{name: "both marked", alpha: true, beta: true, want: pskPair},
+		{name: "only the peer marked", beta: true, want: pskMesh},
+		{name: "only this node marked", alpha: true, want: pskMesh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &StaticManifest{Peers: []StaticPeer{
+				{PubKey: testStaticKey(1), MeshIP: "10.0.1.1", PairPSK: tt.alpha},
+				{PubKey: testStaticKey(2), MeshIP: "10.0.1.2", PairPSK: tt.beta},
+			}}
+			kinds := make(map[string]string)
+			for i, self := range []string{"alpha", "beta"} {
+				d := newStateTestDaemon(t, testConfigSecret, testStaticKey(byte(i+1)))
+				d.config.StaticPeers = "peers.json"
+				if merged, _ := d.mergeStaticManifest(m); merged != 1 {
+					t.Fatalf("%s merged %d peers, want 1", self, merged)
+				}
+				other := d.peerStore.GetAll()[0]
+				_, kinds[self] = d.presharedKey(other)
+			}
+			if kinds["alpha"] != tt.want || kinds["beta"] != tt.want {
+				t.Errorf("alpha uses the %s key and beta the %s key, want %s", kinds["alpha"], kinds["beta"], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package daemon
+
+import (
+	"bufio"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	PathSelectCheckInterval = 30 * time.Second
+	PathReevaluateInterval  = 10 * time.Minute
+	PathTrialAttempts       = 3
+	PathTrialRetryDelay     = 1 * time.Second
+	PathTrialPings          = 3
+	PathSwitchMargin        = 0.2 // a healthy path is only replaced by one at least 20% faster
+
+	EventPathChanged = "path_changed"
+)
+
+// pathState tracks the endpoint chosen for a peer that announced several.
+type pathState struct {
+	endpoint  string // programmed endpoint; "" means the announced one
+	evaluated time.Time
+	trial     bool // evaluatePaths is cycling through the candidates
+	lan       lanPathState
+}
+
+// pathCandidates returns the peer's announced endpoint followed by its
+// alternative candidates, de-duplicated and filtered for local config.
+func (d *Daemon) pathCandidates(peer *PeerInfo) []string {
+	all := make([]string, 0, 1+len(peer.Candidates))
+	seen := make(map[string]struct{}, 1+len(peer.Candidates))
+	for _, ep := range append([]string{peer.Endpoint}, peer.Candidates...) {
+		if ep == "" {
+			continue
+		}
+		if d.config.DisableIPv6 && isIPv6Endpoint(ep) {
+			continue
+		}
+		if _, ok := seen[ep]; ok {
+			continue
+		}
+		seen[ep] = struct{}{}
+		all = append(all, ep)
+	}
+	return all
+}
+
+// effectiveEndpoint returns the endpoint WireGuard should use for peer: its
+// TCP transport socket if it has one, else the selected path, moved to the
+// peer's current port if it hops.
+func (d *Daemon) effectiveEndpoint(peer *PeerInfo) string {
+	if ep := d.tcpTransportEndpoint(peer.WGPubKey); ep != "" {
+		return ep
+	}
+	return d.hopEndpoint(peer, d.selectedEndpoint(peer))
+}
+
+// selectedEndpoint returns the path selected by the last trial if it is
+// still among the peer's candidates, otherwise the announced endpoint.
+func (d *Daemon) selectedEndpoint(peer *PeerInfo) string {
+	d.pathMu.Lock()
+	st := d.paths[peer.WGPubKey]
+	d.pathMu.Unlock()
+	if st == nil || st.endpoint == "" {
+		return peer.Endpoint
+	}
+	for _, ep := range d.pathCandidates(peer) {
+		if ep == st.endpoint {
+			return ep
+		}
+	}
+	return peer.Endpoint
+}
+
+func (d *Daemon) setPathEndpoint(pubKey, endpoint string) {
+	d.pathMu.Lock()
+	defer d.pathMu.Unlock()
+	d.pathStateLocked(pubKey).endpoint = endpoint
+}
+
+// pathStateLocked returns pubKey's path state, creating it. pathMu must be
+// held.
+func (d *Daemon) pathStateLocked(pubKey string) *pathState {
+	if d.paths == nil {
+		d.paths = make(map[string]*pathState)
+	}
+	st := d.paths[pubKey]
+	if st == nil {
+		st = &pathState{}
+		d.paths[pubKey] = st
+	}
+	return st
+}
+
+func (d *Daemon) markPathEvaluated(pubKey string, at time.Time) {
+	d.pathMu.Lock()
+	defer d.pathMu.Unlock()
+	if st := d.paths[pubKey]; st != nil {
+		st.evaluated = at
+	}
+}
+
+func (d *Daemon) pathEvaluatedAt(pubKey string) time.Time {
+	d.pathMu.Lock()
+	defer d.pathMu.Unlock()
+	if st := d.paths[pubKey]; st != nil {
+		return st.evaluated
+	}
+	return time.Time{}
+}
+
+// clearPathSelection drops any selected path so the announced endpoint is
+// used again and the peer is re-evaluated on the next check.
+func (d *Daemon) clearPathSelection(pubKey string) {
+	d.pathMu.Lock()
+	delete(d.paths, pubKey)
+	d.pathMu.Unlock()
+}
+
+func (d *Daemon) pathSelectLoop() {
+	ticker := time.NewTicker(PathSelectCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.selectPaths()
+		}
+	}
+}
+
+// selectPaths trials the candidates of every multi-homed direct peer whose
+// current path looks degraded, or whose last evaluation is older than
+// PathReevaluateInterval.
+func (d *Daemon) selectPaths() {
+	handshakes, _ := d.wireGuard().GetLatestHandshakes(d.config.InterfaceName)
+	now := time.Now()
+
+	for _, peer := range d.peerStore.GetActive() {
+		if d.ctx.Err() != nil {
+			return
+		}
+		if peer.WGPubKey == "" || peer.WGPubKey == d.localNode.WGPubKey || peer.MeshIP == "" {
+			continue
+		}
+		if len(d.pathCandidates(peer)) < 2 || d.isRelayRoutedPeer(peer.WGPubKey) || d.tcpTransportEndpoint(peer.WGPubKey) != "" {
+			continue
+		}
+		d.appliedMu.Lock()
+		_, applied := d.lastAppliedPeerConfigs[peer.WGPubKey]
+		d.appliedMu.Unlock()
+		if !applied {
+			continue
+		}
+
+		d.probeMu.Lock()
+		failures := d.probeFailures[peer.WGPubKey]
+		d.probeMu.Unlock()
+		ts := handshakes[peer.WGPubKey]
+		degraded := failures > 0 || (ts > 0 && now.Sub(time.Unix(ts, 0)) > HandshakeStaleAfter)
+		due := now.Sub(d.pathEvaluatedAt(peer.WGPubKey)) > PathReevaluateInterval
+
+		if degraded || due {
+			d.evaluatePaths(peer, degraded)
+		}
+	}
+}
+
+// evaluatePaths points WireGuard at each candidate in turn, measures RTT
+// over the mesh probe port, and settles on the result of choosePath,
+// preferring reachable LAN paths.
+func (d *Daemon) evaluatePaths(peer *PeerInfo, degraded bool) {
+	current := d.selectedEndpoint(peer)
+	candidates := d.pathCandidates(peer)
+	rtts := make(map[string]time.Duration, len(candidates))
+	localSubnets := d.getLocalSubnets()
+
+	d.pathMu.Lock()
+	d.pathStateLocked(peer.WGPubKey).trial = true
+	d.pathMu.Unlock()
+
+	for _, ep := range candidates {
+		if d.ctx.Err() != nil {
+			break
+		}
+		d.setPathEndpoint(peer.WGPubKey, ep)
+		if !d.programPeerEndpoint(peer.WGPubKey, d.hopEndpoint(peer, ep)) {
+			continue
+		}
+		if rtt, ok := d.measurePath(peer); ok {
+			rtts[ep] = rtt
+		}
+	}
+
+	chosen := choosePath(current, preferLANPaths(rtts, localSubnets), degraded)
+	d.settleLANPath(peer, chosen, localSubnets, time.Now())
+	d.programPeerEndpoint(peer.WGPubKey, d.hopEndpoint(peer, chosen))
+	d.markPathEvaluated(peer.WGPubKey, time.Now())
+
+	if chosen == current {
+		return
+	}
+	rtt, ok := rtts[chosen]
+	if ok {
+		d.resetPeerPathState(peer.WGPubKey)
+	}
+	log.Printf("[Path] Peer %s... switched path %s -> %s (rtt %v)", shortKey(peer.WGPubKey), current, chosen, rtt)
+	d.recordEvent(EventPathChanged, peer.WGPubKey, map[string]string{
+		"endpoint":      chosen,
+		"prev_endpoint": current,
+		"rtt_ms":        strconv.FormatInt(rtt.Milliseconds(), 10),
+	})
+}
+
+// choosePath picks the endpoint to keep after a trial round. A degraded or
+// unreachable current path is abandoned for the fastest reachable
+// candidate; a healthy one is only displaced by a candidate at least
+// PathSwitchMargin faster. Ties break on the endpoint string so all nodes
+// agree. With no reachable candidate the current path is kept.
+func choosePath(current string, rtts map[string]time.Duration, degraded bool) string {
+	best := ""
+	for ep, rtt := range rtts {
+		if best == "" || rtt < rtts[best] || (rtt == rtts[best] && ep < best) {
+			best = ep
+		}
+	}
+	if best == "" {
+		return current
+	}
+
+	currentRTT, currentOK := rtts[current]
+	if degraded || !currentOK {
+		return best
+	}
+	if float64(rtts[best]) <= float64(currentRTT)*(1-PathSwitchMargin) {
+		return best
+	}
+	return current
+}
+
+// measurePath dials a dedicated probe connection over the mesh (so it does
+// not interleave with the health probe session) and returns the lowest RTT
+// seen. A fresh endpoint may need a moment for WireGuard to roam, hence the
+// retries.
+func (d *Daemon) measurePath(peer *PeerInfo) (time.Duration, bool) {
+	addr := net.JoinHostPort(peer.MeshIP, strconv.Itoa(d.healthProbePort))
+	for attempt := 0; attempt < PathTrialAttempts; attempt++ {
+		if rtt, ok := d.pingProbeAddr(addr); ok {
+			return rtt, true
+		}
+		select {
+		case <-d.ctx.Done():
+			return 0, false
+		case <-time.After(PathTrialRetryDelay):
+		}
+	}
+	return 0, false
+}
+
+func (d *Daemon) pingProbeAddr(addr string) (time.Duration, bool) {
+	conn, err := d.dialProbeOnInterface(addr)
+	if err != nil {
+		return 0, false
+	}
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	var best time.Duration
+	ok := false
+	for i := 0; i < PathTrialPings; i++ {
+		_ = conn.SetDeadline(time.Now().Add(MeshProbeDialTimeout))
+		start := time.Now()
+		if _, err := conn.Write([]byte("ping\n")); err != nil {
+			break
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil || strings.TrimSpace(line) != "pong" {
+			break
+		}
+		if rtt := time.Since(start); !ok || rtt < best {
+			best = rtt
+			ok = true
+		}
+	}
+	return best, ok
+}
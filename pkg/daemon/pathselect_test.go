@@ -0,0 +1,114 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChoosePath(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name     string
+		current  string
+		rtts     map[string]time.Duration
+		degraded bool
+		want     string
+	}{
+		{
+			name:    "nothing reachable keeps current",
+			current: "1.1.1.1:51820",
+			rtts:    map[string]time.Duration{},
+			want:    "1.1.1.1:51820",
+		},
+		{
+			name:    "healthy current kept when alternative is only slightly faster",
+			current: "1.1.1.1:51820",
+			rtts:    map[string]time.Duration{"1.1.1.1:51820": 10 * ms, "192.168.1.2:51820": 9 * ms},
+			want:    "1.1.1.1:51820",
+		},
+		{
+			name:    "healthy current replaced by much faster alternative",
+			current: "1.1.1.1:51820",
+			rtts:    map[string]time.Duration{"1.1.1.1:51820": 30 * ms, "192.168.1.2:51820": 1 * ms},
+			want:    "192.168.1.2:51820",
+		},
+		{
+			name:     "degraded current replaced by fastest",
+			current:  "1.1.1.1:51820",
+			rtts:     map[string]time.Duration{"1.1.1.1:51820": 10 * ms, "192.168.1.2:51820": 9 * ms},
+			degraded: true,
+			want:     "192.168.1.2:51820",
+		},
+		{
+			name:    "unreachable current replaced",
+			current: "1.1.1.1:51820",
+			rtts:    map[string]time.Duration{"[2001:db8::2]:51820": 50 * ms},
+			want:    "[2001:db8::2]:51820",
+		},
+		{
+			name:     "ties break on endpoint",
+			current:  "9.9.9.9:51820",
+			rtts:     map[string]time.Duration{"b.example:51820": 5 * ms, "a.example:51820": 5 * ms},
+			degraded: true,
+			want:     "a.example:51820",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := choosePath(tt.current, tt.rtts, tt.degraded); got != tt.want {
+				t.Errorf("choosePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathCandidates(t *testing.T) {
+	peer := &PeerInfo{
+		WGPubKey:   "peer",
+		Endpoint:   "1.1.1.1:51820",
+		Candidates: []string{"192.168.1.2:51820", "1.1.1.1:51820", "[2001:db8::2]:51820"},
+	}
+
+	d := &Daemon{config: &Config{}}
+	want := []string{"1.1.1.1:51820", "192.168.1.2:51820", "[2001:db8::2]:51820"}
+	if got := d.pathCandidates(peer); !reflect.DeepEqual(got, want) {
+		t.Errorf("pathCandidates() = %v, want %v", got, want)
+	}
+
+	d.config.DisableIPv6 = true
+	want = []string{"1.1.1.1:51820", "192.168.1.2:51820"}
+	if got := d.pathCandidates(peer); !reflect.DeepEqual(got, want) {
+		t.Errorf("pathCandidates() with IPv6 disabled = %v, want %v", got, want)
+	}
+}
+
+func TestEffectiveEndpoint(t *testing.T) {
+	d := &Daemon{config: &Config{}}
+	peer := &PeerInfo{
+		WGPubKey:   "peer",
+		Endpoint:   "1.1.1.1:51820",
+		Candidates: []string{"192.168.1.2:51820"},
+	}
+
+	if got := d.effectiveEndpoint(peer); got != peer.Endpoint {
+		t.Fatalf("without selection got %q, want announced endpoint", got)
+	}
+
+	d.setPathEndpoint("peer", "192.168.1.2:51820")
+	if got := d.effectiveEndpoint(peer); got != "192.168.1.2:51820" {
+		t.Fatalf("with selection got %q, want selected candidate", got)
+	}
+
+	// Candidate no longer announced: fall back to the announced endpoint
+	peer.Candidates = nil
+	if got := d.effectiveEndpoint(peer); got != peer.Endpoint {
+		t.Fatalf("with stale selection got %q, want announced endpoint", got)
+	}
+
+	d.clearPathSelection("peer")
+	if !d.pathEvaluatedAt("peer").IsZero() {
+		t.Fatal("expected evaluation time to be cleared")
+	}
+}
@@ -0,0 +1,98 @@
+package daemon
+
+import (
+	"crypto/sha256"
+	"sort"
+	"sync"
+	"time"
+)
+
+// With --max-installed-peers, a node in a large mesh keeps every peer it
+// discovers in the peer store but installs only some of them into
+// WireGuard. Introducers (relays and bootstrap) and peers carrying traffic
+// are always installed, even past the cap. Room left under the cap goes to
+// demanded peers, most recent first, then to the rest in pair order (see
+// pairRank), so two nodes with spare room tend to pick each other.
+const (
+	// InstallDemandTTL is how long a demanded peer stays preferred after
+	// the last demand.
+	InstallDemandTTL = 5 * time.Minute
+
+	// InstallActiveBps is the average rx+tx rate over the last minute above
+	// which a peer counts as carrying traffic. It sits well above the
+	// keepalive and handshake chatter of an idle tunnel.
+	InstallActiveBps = 64
+)
+
+// installState holds the outcome of the last installed-peer selection.
+type installState struct {
+	mu        sync.RWMutex
+	installed map[string]bool // nil when no cap applies: every peer is installed
+}
+
+// selectInstalledPeers picks the peers to install into WireGuard and
+// records the choice for isInstalled. It returns nil when no cap is set.
+func (d *Daemon) selectInstalledPeers(peers []*PeerInfo, now time.Time) map[string]bool {
+	if d.config.MaxInstalledPeers <= 0 {
+		d.install.mu.Lock()
+		d.install.installed = nil
+		d.install.mu.Unlock()
+		return nil
+	}
+
+	active := d.traffic.activePeers(time.Minute, InstallActiveBps)
+	selected := make(map[string]bool)
+	var demanded, idle []*PeerInfo
+	for _, p := range peers {
+		if p.WGPubKey == d.localNode.WGPubKey || p.WGPubKey == "" || p.MeshIP == "" {
+			continue
+		}
+		if d.isTemporarilyOffline(p.WGPubKey) || !d.peerAllowed(p) {
+			continue
+		}
+		switch {
+		case p.Introducer || active[p.WGPubKey]:
+			selected[p.WGPubKey] = true
+		case now.Sub(p.LastDemand) < InstallDemandTTL:
+			demanded = append(demanded, p)
+		default:
+			idle = append(idle, p)
+		}
+	}
+
+	sort.Slice(demanded, func(i, j int) bool {
+		return demanded[i].LastDemand.After(demanded[j].LastDemand)
+	})
+	sort.Slice(idle, func(i, j int) bool {
+		return pairRank(d.localNode.WGPubKey, idle[i].WGPubKey) < pairRank(d.localNode.WGPubKey, idle[j].WGPubKey)
+	})
+	for _, p := range append(demanded, idle...) {
+		if len(selected) >= d.config.MaxInstalledPeers {
+			break
+		}
+		selected[p.WGPubKey] = true
+	}
+
+	d.install.mu.Lock()
+	d.install.installed = selected
+	d.install.mu.Unlock()
+	return selected
+}
+
+// isInstalled reports whether the last selection installed the peer.
+func (d *Daemon) isInstalled(pubKey string) bool {
+	d.install.mu.RLock()
+	defer d.install.mu.RUnlock()
+	return d.install.installed == nil || d.install.installed[pubKey]
+}
+
+// pairRank orders peers for the spare room under the cap. It depends only
+// on the two keys, not on which side computes it, so nodes rank their pair
+// the same way.
+func pairRank(a, b string) string {
+	if b < a {
+		a, b = b, a
+	}
+	sum := sha256.Sum256([]byte(a + "\x00" + b))
+	return string(sum[:8])
+}
@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+func newCapTestDaemon(maxInstalled int) *Daemon {
+	return &Daemon{
+		config:             &Config{MaxInstalledPeers: maxInstalled},
+		localNode:          &LocalNode{WGPubKey: "local1", NATType: "cone"},
+		relayRoutes:        make(map[string]string),
+		directStableCycles: make(map[string]int),
+		temporaryOffline:   make(map[string]time.Time),
+		localSubnetsFn:     func() []*net.IPNet { return nil },
+	}
+}
+
+func TestBuildDesiredPeerConfigsMaxInstalledPeers(t *testing.T) {
+	d := newCapTestDaemon(3)
+	now := time.Now()
+
+	// "busy" carries 1 KB/s over the last minute.
+	for i := 0; i <= 3; i++ {
+		d.traffic.observe(map[string]wireguard.PeerTransfer{
+			"busy": {RxBytes: uint64(i) * 20 * 1000},
+		}, now.Add(time.Duration(i-3)*20*time.Second))
+	}
+
+	peers := []*PeerInfo{
+		{WGPubKey: "intro", MeshIP: "10.0.0.1", Introducer: true, Endpoint: "1.2.3.4:51820", LastSeen: now},
+		{WGPubKey: "busy", MeshIP: "10.0.0.2", Endpoint: "1.2.3.5:51820"},
+		{WGPubKey: "old-demand", MeshIP: "10.0.0.3", LastDemand: now.Add(-time.Minute)},
+		{WGPubKey: "new-demand", MeshIP: "10.0.0.4", LastDemand: now.Add(-time.Second)},
+		{WGPubKey: "expired", MeshIP: "10.0.0.5", LastDemand: now.Add(-InstallDemandTTL - time.Second)},
+		{WGPubKey: "idle", MeshIP: "10.0.0.6"},
+	}
+
+	desired, _, _ := d.buildDesiredPeerConfigsWithHandshakes(peers, nil)
+	for _, key := range []string{"intro", "busy", "new-demand"} {
+		if desired[key] == nil || !d.isInstalled(key) {
+			t.Errorf("%s should be installed", key)
+		}
+	}
+	for _, key := range []string{"old-demand", "expired", "idle"} {
+		if desired[key] != nil || d.isInstalled(key) {
+			t.Errorf("%s should only be known", key)
+		}
+	}
+
+	// With room to spare the oldest demand comes next, then idle peers.
+	d.config.MaxInstalledPeers = 4
+	desired, _, _ = d.buildDesiredPeerConfigsWithHandshakes(peers, nil)
+	if desired["old-demand"] == nil {
+		t.Error("old-demand should be installed once there is room")
+	}
+	d.config.MaxInstalledPeers = 6
+	desired, _, _ = d.buildDesiredPeerConfigsWithHandshakes(peers, nil)
+	if len(desired) != 6 {
+		t.Errorf("expected all 6 peers installed, got %d", len(desired))
+	}
+}
+
+func TestBuildDesiredPeerConfigsMaxInstalledPeersNeverDropsIntroducers(t *testing.T) {
+	d := newCapTestDaemon(1)
+	peers := []*PeerInfo{
+		{WGPubKey: "intro1", MeshIP: "10.0.0.1", Introducer: true},
+		{WGPubKey: "intro2", MeshIP: "10.0.0.2", Introducer: true},
+		{WGPubKey: "peer", MeshIP: "10.0.0.3", LastDemand: time.Now()},
+	}
+
+	desired, _, _ := d.buildDesiredPeerConfigsWithHandshakes(peers, nil)
+	if desired["intro1"] == nil || desired["intro2"] == nil {
+		t.Error("introducers must be installed past the cap")
+	}
+	if desired["peer"] != nil {
+		t.Error("demanded peer should wait for room under the cap")
+	}
+}
+
+func TestIsInstalledWithoutCap(t *testing.T) {
+	d := newCapTestDaemon(0)
+	d.buildDesiredPeerConfigsWithHandshakes([]*PeerInfo{{WGPubKey: "peer", MeshIP: "10.0.0.3"}}, nil)
+	if !d.isInstalled("peer") || !d.isInstalled("unknown") {
+		t.Error("every peer counts as installed without a cap")
+	}
+}
+
+func TestPairRankIsSymmetric(t *testing.T) {
+	if pairRank("a", "b") != pairRank("b", "a") {
+		t.Error("pairRank must not depend on which side computes it")
+	}
+	if pairRank("a", "b") == pairRank("a", "c") {
+		t.Error("different pairs should rank differently")
+	}
+}
@@ -0,0 +1,64 @@
+package daemon
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// EventPeerForgotten is recorded when an operator drops a peer with
+	// 'wgmesh peers forget'.
+	EventPeerForgotten = "peer_forgotten"
+
+	// MinPeerExpiry bounds --peer-dead-after and --peer-remove-after: peers
+	// are heard from about every DHT query round, and a shorter expiry
+	// would drop healthy ones between rounds.
+	MinPeerExpiry = time.Minute
+)
+
+// ForgetPeer drops a peer from the peer store, WireGuard and the peer cache
+// at once, for nodes decommissioned without saying GOODBYE. Announcements
+// for it are ignored for the remove timeout, so copies other nodes still
+// hold do not bring it back.
+func (d *Daemon) ForgetPeer(pubKey string) error {
+	if d.localNode != nil && pubKey == d.localNode.WGPubKey {
+		return fmt.Errorf("cannot forget the local node")
+	}
+	peer, ok := d.peerStore.Get(pubKey)
+	if !ok {
+		return fmt.Errorf("peer %s is not known", pubKey)
+	}
+
+	d.peerStore.Forget(pubKey)
+	d.clearTemporarilyOffline(pubKey)
+	d.dropPeer(pubKey)
+	if err := SavePeerCache(d.config.InterfaceName, d.peerStore); err != nil {
+		log.Printf("[Peers] Failed to save peer cache: %v", err)
+	}
+
+	_, removeAfter := d.peerStore.Expiry()
+	log.Printf("[Peers] Forgot peer %s... (%s, %s); ignoring it for %v", shortKey(pubKey), peer.MeshIP, peer.Hostname, removeAfter)
+	d.recordEvent(EventPeerForgotten, pubKey, map[string]string{
+		"mesh_ip":  peer.MeshIP,
+		"hostname": peer.Hostname,
+	})
+	return nil
+}
+
+// validatePeerExpiry checks the peer expiry options; zero means the
+// default.
+func validatePeerExpiry(deadAfter, removeAfter time.Duration) error {
+	if deadAfter != 0 && deadAfter < MinPeerExpiry {
+		return fmt.Errorf("invalid peer dead timeout %v: must be at least %v", deadAfter, MinPeerExpiry)
+	}
+	if removeAfter != 0 && removeAfter < MinPeerExpiry {
+		return fmt.Errorf("invalid peer remove timeout %v: must be at least %v", removeAfter, MinPeerExpiry)
+	}
+	dead, remove := cmp.Or(deadAfter, PeerDeadTimeout), cmp.Or(removeAfter, PeerRemoveTimeout)
+	if remove < dead {
+		return fmt.Errorf("peer remove timeout %v is shorter than the dead timeout %v", remove, dead)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package daemon
+
+import (
+	"testing"
+)
+
+func TestForgetPeer(t *testing.T) {
+	useTempStateDir(t)
+	d := newMinimalDaemon(t)
+	d.localNode = &LocalNode{WGPubKey: "local-key"}
+	d.peerStore.Update(&PeerInfo{WGPubKey: "retired", MeshIP: "10.42.0.9", Hostname: "old-box"}, "dht")
+	d.peerStore.Update(&PeerInfo{WGPubKey: "keeper", MeshIP: "10.42.0.2"}, "dht")
+	d.lastAppliedPeerConfigs["retired"] = "applied"
+
+	mock := &MockCommandExecutor{}
+	withMockExecutor(t, mock, func() {
+		if err := d.ForgetPeer("retired"); err != nil {
+			t.Fatalf("ForgetPeer: %v", err)
+		}
+	})
+
+	if _, ok := d.peerStore.Get("retired"); ok {
+		t.Error("peer still in the store")
+	}
+	if _, ok := d.lastAppliedPeerConfigs["retired"]; ok {
+		t.Error("peer still recorded as applied to WireGuard")
+	}
+	cache, err := LoadPeerCache(d.config.InterfaceName)
+	if err != nil {
+		t.Fatalf("LoadPeerCache: %v", err)
+	}
+	if len(cache.Peers) != 1 || cache.Peers[0].WGPubKey != "keeper" {
+		t.Errorf("cache peers = %+v, want only keeper", cache.Peers)
+	}
+	events := d.GetRPCEvents(0)
+	if len(events) != 1 || events[0].Type != EventPeerForgotten || events[0].Details["hostname"] != "old-box" {
+		t.Errorf("events = %+v", events)
+	}
+
+	if err := d.ForgetPeer("retired"); err == nil {
+		t.Error("forgetting an unknown peer succeeded")
+	}
+	if err := d.ForgetPeer("local-key"); err == nil {
+		t.Error("forgetting the local node succeeded")
+	}
+}
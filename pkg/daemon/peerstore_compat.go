@@ -8,17 +8,23 @@ type PeerInfo = node.PeerInfo
 type PeerStore = node.PeerStore
 type PeerEvent = node.PeerEvent
 type PeerEventKind = node.PeerEventKind
+type PeerEventSubscriberStats = node.PeerEventSubscriberStats
 
 const (
-	PeerDeadTimeout   = node.PeerDeadTimeout
-	PeerRemoveTimeout = node.PeerRemoveTimeout
-	PeerEventBufSize  = node.PeerEventBufSize
-	DefaultMaxPeers   = node.DefaultMaxPeers
-	PeerEventNew      = node.PeerEventNew
-	PeerEventUpdated  = node.PeerEventUpdated
+	PeerDeadTimeout    = node.PeerDeadTimeout
+	PeerRemoveTimeout  = node.PeerRemoveTimeout
+	PeerEventBufSize   = node.PeerEventBufSize
+	PeerEventQueueSize = node.PeerEventQueueSize
+	DefaultMaxPeers    = node.DefaultMaxPeers
+	PeerProbeWindow    = node.PeerProbeWindow
+	PeerEventNew       = node.PeerEventNew
+	PeerEventUpdated   = node.PeerEventUpdated
+	PeerEventRemoved   = node.PeerEventRemoved
 
 	LANMethod        = node.LANMethod
 	RendezvousMethod = node.RendezvousMethod
+	StaticMethod     = node.StaticMethod
+	TCPMethod        = node.TCPMethod
 )
 
 func NewPeerStore() *PeerStore { return node.NewPeerStore() }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"testing"
 	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 )
 
 func TestPeerStoreUpdate(t *testing.T) {
@@ -56,6 +58,22 @@ func TestPeerStoreUpdateMerge(t *testing.T) {
 	}
 }
 
+func TestPeerStoreUpdateRouteWithdrawal(t *testing.T) {
+	ps := NewPeerStore()
+	ps.Update(&PeerInfo{WGPubKey: "key1", RoutableNetworks: []string{"192.168.1.0/24"}, RoutesAnnounced: true}, "dht")
+
+	// Transitive updates carry no routes and must not clear them.
+	ps.Update(&PeerInfo{WGPubKey: "key1"}, "dht-transitive")
+	if p, _ := ps.Get("key1"); len(p.RoutableNetworks) != 1 {
+		t.Fatalf("routes cleared by transitive update: %v", p.RoutableNetworks)
+	}
+
+	ps.Update(&PeerInfo{WGPubKey: "key1", RoutesAnnounced: true}, "dht")
+	if p, _ := ps.Get("key1"); len(p.RoutableNetworks) != 0 {
+		t.Errorf("expected routes withdrawn, got %v", p.RoutableNetworks)
+	}
+}
+
 func TestPeerStorePrefersLANEndpoint(t *testing.T) {
 	ps := NewPeerStore()
 
@@ -258,7 +276,7 @@ func TestPeerStoreTransitiveDoesNotRefreshLastSeen(t *testing.T) {
 
 func TestPeerStoreSubscribe(t *testing.T) {
 	ps := NewPeerStore()
-	ch := ps.Subscribe()
+	ch := ps.Subscribe("test")
 
 	// New peer should emit event
 	ps.Update(&PeerInfo{WGPubKey: "key1", MeshIP: "10.0.0.1", Endpoint: "1.2.3.4:51820"}, "dht")
@@ -282,7 +300,7 @@ func TestPeerStoreSubscribeUpdate(t *testing.T) {
 	// Add peer before subscribing
 	ps.Update(&PeerInfo{WGPubKey: "key1", MeshIP: "10.0.0.1", Endpoint: "1.2.3.4:51820"}, "dht")
 
-	ch := ps.Subscribe()
+	ch := ps.Subscribe("test")
 
 	// Update peer — should emit update event
 	ps.Update(&PeerInfo{WGPubKey: "key1", Endpoint: "5.6.7.8:51820"}, "lan")
@@ -300,42 +318,84 @@ func TestPeerStoreSubscribeUpdate(t *testing.T) {
 	}
 }
 
+func TestPeerStoreSubscribeRemove(t *testing.T) {
+	ps := NewPeerStore()
+	ps.Update(&PeerInfo{WGPubKey: "key1", MeshIP: "10.0.0.1"}, "dht")
+	ps.SetPeerDirectly("old", &PeerInfo{WGPubKey: "old", LastSeen: time.Now().Add(-15 * time.Minute)})
+
+	ch := ps.Subscribe("test")
+	ps.Remove("key1")
+	ps.Remove("unknown") // not in the store, no event
+	ps.CleanupStale()
+
+	for _, want := range []string{"key1", "old"} {
+		select {
+		case ev := <-ch:
+			if ev.PubKey != want || ev.Kind != PeerEventRemoved {
+				t.Errorf("got event %+v, want PeerEventRemoved for %s", ev, want)
+			}
+		case <-time.After(100 * time.Millisecond):
+			t.Fatalf("Timed out waiting for removal of %s", want)
+		}
+	}
+	select {
+	case ev := <-ch:
+		t.Errorf("unexpected event %+v", ev)
+	default:
+	}
+}
+
 func TestPeerStoreSubscribeNonBlocking(t *testing.T) {
 	ps := NewPeerStore()
-	ch := ps.Subscribe()
+	ch := ps.Subscribe("test")
 
-	// Fill the channel buffer
-	for i := 0; i < PeerEventBufSize+5; i++ {
+	// Nothing reads ch yet, so Update must not block. Repeated updates of
+	// one peer collapse into the event queued for it, and peers beyond the
+	// queue's size are dropped.
+	const n = PeerEventQueueSize + 5
+	for i := 0; i < n; i++ {
 		ps.Update(&PeerInfo{
 			WGPubKey: "key1",
 			MeshIP:   "10.0.0.1",
 			Endpoint: "1.2.3.4:51820",
 		}, "dht")
 	}
+	for i := 0; i < n; i++ {
+		ps.Update(&PeerInfo{WGPubKey: fmt.Sprintf("peer-%d", i), MeshIP: "10.0.1.1"}, "dht")
+	}
 
-	// Should not deadlock — Update() must be non-blocking even when
-	// subscriber is slow. Drain what we can.
-	drained := 0
-	for {
+	var received []PeerEvent
+	for done := false; !done; {
 		select {
-		case <-ch:
-			drained++
-		default:
-			goto done
+		case ev := <-ch:
+			received = append(received, ev)
+		case <-time.After(50 * time.Millisecond):
+			done = true
 		}
 	}
-done:
-	if drained == 0 {
-		t.Error("Expected at least one event to be buffered")
+	if len(received) == 0 || received[0].PubKey != "key1" || received[0].Kind != PeerEventNew {
+		t.Fatalf("expected key1 as a new peer first, got %+v", received)
+	}
+	if len(received) > PeerEventQueueSize+2 {
+		t.Errorf("received %d events, want at most the queue plus one event in flight per peer", len(received))
+	}
+
+	stats := ps.SubscriberStats()
+	if len(stats) != 1 || stats[0].Name != "test" {
+		t.Fatalf("stats = %+v", stats)
 	}
-	if drained > PeerEventBufSize {
-		t.Errorf("Drained %d events, expected at most %d (buffer size)", drained, PeerEventBufSize)
+	s := stats[0]
+	if s.Dropped == 0 || s.Coalesced < n-2 || s.Queued != 0 {
+		t.Errorf("stats = %+v, want drops, coalesced key1 updates and an empty queue", s)
+	}
+	if s.Delivered != uint64(len(received)) || s.Delivered+s.Coalesced+s.Dropped != 2*n {
+		t.Errorf("stats = %+v do not account for %d events published and %d received", s, 2*n, len(received))
 	}
 }
 
 func TestPeerStoreUnsubscribe(t *testing.T) {
 	ps := NewPeerStore()
-	ch := ps.Subscribe()
+	ch := ps.Subscribe("test")
 
 	ps.Unsubscribe(ch)
 
@@ -354,8 +414,8 @@ func TestPeerStoreUnsubscribe(t *testing.T) {
 
 func TestPeerStoreMultipleSubscribers(t *testing.T) {
 	ps := NewPeerStore()
-	ch1 := ps.Subscribe()
-	ch2 := ps.Subscribe()
+	ch1 := ps.Subscribe("test")
+	ch2 := ps.Subscribe("test")
 
 	ps.Update(&PeerInfo{WGPubKey: "key1", MeshIP: "10.0.0.1"}, "dht")
 
@@ -451,6 +511,63 @@ func TestPeerStoreSetLatencyUnknownPeer(t *testing.T) {
 	ps.SetLatency("nonexistent", 10*time.Millisecond)
 }
 
+func TestPeerStoreRecordProbe(t *testing.T) {
+	ps := NewPeerStore()
+	ps.Update(&PeerInfo{WGPubKey: "key1", MeshIP: "10.0.0.1"}, "dht")
+
+	ps.RecordProbe("key1", 10*time.Millisecond, true)
+	ps.RecordProbe("key1", 30*time.Millisecond, true)
+	mean, loss := ps.RecordProbe("key1", 0, false)
+	if mean != 20*time.Millisecond {
+		t.Errorf("expected mean RTT 20ms, got %v", mean)
+	}
+	if loss < 0.33 || loss > 0.34 {
+		t.Errorf("expected loss 1/3, got %v", loss)
+	}
+
+	got, _ := ps.Get("key1")
+	if got.Latency == nil || *got.Latency != mean {
+		t.Errorf("expected Latency %v, got %v", mean, got.Latency)
+	}
+	if got.PacketLoss == nil || *got.PacketLoss != loss {
+		t.Errorf("expected PacketLoss %v, got %v", loss, got.PacketLoss)
+	}
+	if probes, lost := ps.ProbeCounts("key1"); probes != 3 || lost != 1 {
+		t.Errorf("expected 3 probes with 1 lost, got %d/%d", probes, lost)
+	}
+
+	// Old results age out of the window
+	for i := 0; i < PeerProbeWindow; i++ {
+		ps.RecordProbe("key1", 5*time.Millisecond, true)
+	}
+	got, _ = ps.Get("key1")
+	if *got.PacketLoss != 0 || *got.Latency != 5*time.Millisecond {
+		t.Errorf("expected window to roll over, got latency %v loss %v", *got.Latency, *got.PacketLoss)
+	}
+
+	// Unknown peers are ignored
+	if mean, loss := ps.RecordProbe("nonexistent", time.Millisecond, true); mean != 0 || loss != 0 {
+		t.Errorf("expected zero stats for unknown peer, got %v %v", mean, loss)
+	}
+}
+
+func TestPeerStoreResetProbes(t *testing.T) {
+	ps := NewPeerStore()
+	ps.Update(&PeerInfo{WGPubKey: "key1", MeshIP: "10.0.0.1"}, "dht")
+	ps.RecordProbe("key1", 10*time.Millisecond, true)
+	ps.RecordProbe("key1", 0, false)
+
+	ps.ResetProbes()
+
+	got, _ := ps.Get("key1")
+	if got.Latency != nil || got.PacketLoss != nil {
+		t.Errorf("expected no latency or loss after reset, got %v %v", got.Latency, got.PacketLoss)
+	}
+	if probes, lost := ps.ProbeCounts("key1"); probes != 0 || lost != 0 {
+		t.Errorf("expected an empty probe window after reset, got %d/%d", probes, lost)
+	}
+}
+
 func TestPeerStoreMaxPeersAfterCleanup(t *testing.T) {
 	t.Parallel()
 	ps := NewPeerStore()
@@ -477,3 +594,197 @@ func TestPeerStoreMaxPeersAfterCleanup(t *testing.T) {
 		t.Errorf("expected 1 peer after cleanup and insert, got %d", ps.Count())
 	}
 }
+
+func TestPeerStoreSubscribeEndpointChanged(t *testing.T) {
+	ps := NewPeerStore()
+	ps.Update(&PeerInfo{WGPubKey: "key1", MeshIP: "10.0.0.1", Endpoint: "1.2.3.4:51820"}, "dht")
+
+	ch := ps.Subscribe("test")
+
+	// Same endpoint: plain update
+	ps.Update(&PeerInfo{WGPubKey: "key1", Endpoint: "1.2.3.4:51820"}, "dht")
+	ev := <-ch
+	if ev.EndpointChanged() {
+		t.Fatalf("expected no endpoint change, got %q -> %q", ev.PrevEndpoint, ev.Endpoint)
+	}
+
+	// Roamed: event carries old and new endpoint
+	ps.Update(&PeerInfo{WGPubKey: "key1", Endpoint: "5.6.7.8:51820"}, "dht")
+	ev = <-ch
+	if ev.Kind != PeerEventUpdated {
+		t.Errorf("Expected PeerEventUpdated, got %d", ev.Kind)
+	}
+	if !ev.EndpointChanged() {
+		t.Fatal("expected endpoint change")
+	}
+	if ev.PrevEndpoint != "1.2.3.4:51820" || ev.Endpoint != "5.6.7.8:51820" {
+		t.Errorf("unexpected endpoints %q -> %q", ev.PrevEndpoint, ev.Endpoint)
+	}
+
+	// Lower-priority method cannot replace the endpoint, so no change either
+	ps.Update(&PeerInfo{WGPubKey: "key1", Endpoint: "9.9.9.9:51820"}, "gossip-transitive")
+	ev = <-ch
+	if ev.EndpointChanged() {
+		t.Errorf("expected no endpoint change for rejected endpoint, got %q -> %q", ev.PrevEndpoint, ev.Endpoint)
+	}
+}
+
+func TestPeerStoreAdmitFunc(t *testing.T) {
+	ps := NewPeerStore()
+	ps.SetAdmitFunc(func(info *PeerInfo, discoveryMethod string) bool {
+		return discoveryMethod != "rejected"
+	})
+
+	ps.Update(&PeerInfo{WGPubKey: "key1", MeshIP: "10.0.0.1"}, "rejected")
+	if ps.Count() != 0 {
+		t.Fatalf("Expected rejected peer to be dropped, got %d peers", ps.Count())
+	}
+	ps.Update(&PeerInfo{WGPubKey: "key1", MeshIP: "10.0.0.1"}, "dht")
+	if ps.Count() != 1 {
+		t.Fatalf("Expected 1 peer, got %d", ps.Count())
+	}
+
+	ps.SetAdmitFunc(nil)
+	ps.Update(&PeerInfo{WGPubKey: "key2", MeshIP: "10.0.0.2"}, "rejected")
+	if ps.Count() != 2 {
+		t.Errorf("Expected 2 peers after clearing the admit func, got %d", ps.Count())
+	}
+}
+
+func TestPeerStoreIdentityBinding(t *testing.T) {
+	ps := NewPeerStore()
+
+	// Unsigned entries can be claimed by the first signed announcement.
+	ps.Update(&PeerInfo{WGPubKey: "key1", Endpoint: "1.1.1.1:51820"}, "dht-transitive")
+	ps.Update(&PeerInfo{WGPubKey: "key1", Endpoint: "2.2.2.2:51820", Identity: "id-a"}, "dht")
+	if p, _ := ps.Get("key1"); p.Identity != "id-a" || p.Endpoint != "2.2.2.2:51820" {
+		t.Fatalf("signed update not applied: identity %q endpoint %q", p.Identity, p.Endpoint)
+	}
+
+	ps.Update(&PeerInfo{WGPubKey: "key1", Endpoint: "6.6.6.6:51820", Identity: "id-b"}, "dht")
+	ps.Update(&PeerInfo{WGPubKey: "key1", Endpoint: "7.7.7.7:51820"}, "lan")
+	if p, _ := ps.Get("key1"); p.Identity != "id-a" || p.Endpoint != "2.2.2.2:51820" {
+		t.Errorf("peer changed by an unsigned or foreign announcement: identity %q endpoint %q", p.Identity, p.Endpoint)
+	}
+
+	ps.Update(&PeerInfo{WGPubKey: "key1", Endpoint: "3.3.3.3:51820", Identity: "id-a"}, "lan")
+	if p, _ := ps.Get("key1"); p.Endpoint != "3.3.3.3:51820" {
+		t.Errorf("update signed by the bound identity not applied, endpoint %q", p.Endpoint)
+	}
+}
+
+func TestPeerStoreMarkDemand(t *testing.T) {
+	ps := NewPeerStore()
+	ps.Update(&PeerInfo{WGPubKey: "key1", MeshIP: "10.0.0.1"}, "dht")
+
+	before := time.Now()
+	ps.MarkDemand("key1")
+	if p, _ := ps.Get("key1"); p.LastDemand.Before(before) {
+		t.Errorf("LastDemand = %v, want at least %v", p.LastDemand, before)
+	}
+
+	// A later update keeps the demand.
+	ps.Update(&PeerInfo{WGPubKey: "key1", Endpoint: "1.1.1.1:51820"}, "gossip")
+	if p, _ := ps.Get("key1"); p.LastDemand.IsZero() {
+		t.Error("update cleared LastDemand")
+	}
+
+	ps.MarkDemand("unknown")
+	if _, ok := ps.Get("unknown"); ok {
+		t.Error("MarkDemand must not create peers")
+	}
+}
+
+func TestPeerStoreLoadAndRelays(t *testing.T) {
+	ps := NewPeerStore()
+	load := &crypto.IntroducerLoad{Sessions: 2}
+	ps.Update(&PeerInfo{WGPubKey: "key1", Load: load, Relays: []string{"relay"}, RoutesAnnounced: true}, "dht")
+
+	// Second-hand information about the peer leaves them alone.
+	ps.Update(&PeerInfo{WGPubKey: "key1", Endpoint: "1.1.1.1:51820"}, "dht-transitive")
+	if p, _ := ps.Get("key1"); p.Load != load || len(p.Relays) != 1 {
+		t.Errorf("transitive update changed load %+v / relays %v", p.Load, p.Relays)
+	}
+
+	// The peer's own announcement without them clears them.
+	ps.Update(&PeerInfo{WGPubKey: "key1", RoutesAnnounced: true}, "gossip")
+	if p, _ := ps.Get("key1"); p.Load != nil || p.Relays != nil {
+		t.Errorf("load %+v / relays %v not cleared", p.Load, p.Relays)
+	}
+}
+
+func TestPeerStoreTags(t *testing.T) {
+	ps := NewPeerStore()
+	ps.Update(&PeerInfo{WGPubKey: "key1", Tags: map[string]string{"role": "db"}, RoutesAnnounced: true}, "dht")
+
+	ps.Update(&PeerInfo{WGPubKey: "key1", Endpoint: "1.1.1.1:51820"}, "dht-transitive")
+	if p, _ := ps.Get("key1"); p.Tags["role"] != "db" {
+		t.Errorf("transitive update changed tags to %v", p.Tags)
+	}
+
+	ps.Update(&PeerInfo{WGPubKey: "key1", RoutesAnnounced: true}, "gossip")
+	if p, _ := ps.Get("key1"); p.Tags != nil {
+		t.Errorf("tags %v not cleared by the peer's own announcement", p.Tags)
+	}
+}
+
+func TestPeerStoreDNS(t *testing.T) {
+	ps := NewPeerStore()
+	dns := []crypto.DNSDomain{{Domain: "home.lan", Resolver: "192.168.1.53"}}
+	ps.Update(&PeerInfo{WGPubKey: "key1", DNS: dns, RoutesAnnounced: true}, "dht")
+
+	ps.Update(&PeerInfo{WGPubKey: "key1", Endpoint: "1.1.1.1:51820"}, "dht-transitive")
+	if p, _ := ps.Get("key1"); len(p.DNS) != 1 {
+		t.Errorf("transitive update changed DNS domains to %v", p.DNS)
+	}
+
+	ps.Update(&PeerInfo{WGPubKey: "key1", RoutesAnnounced: true}, "gossip")
+	if p, _ := ps.Get("key1"); p.DNS != nil {
+		t.Errorf("DNS domains %v not cleared by the peer's own announcement", p.DNS)
+	}
+}
+
+func TestPeerStoreSetExpiry(t *testing.T) {
+	ps := NewPeerStore()
+	ps.SetExpiry(2*time.Minute, 3*time.Minute)
+	ps.SetPeerDirectly("quiet", &PeerInfo{WGPubKey: "quiet", LastSeen: time.Now().Add(-150 * time.Second)})
+
+	if !ps.IsDead("quiet") || len(ps.GetActive()) != 0 {
+		t.Error("peer unseen for 2m30s should be dead with a 2m dead timeout")
+	}
+	if removed := ps.CleanupStale(); len(removed) != 0 {
+		t.Errorf("removed %v before the 3m remove timeout", removed)
+	}
+
+	ps.SetExpiry(0, 2*time.Minute)
+	if dead, remove := ps.Expiry(); dead != 2*time.Minute || remove != 2*time.Minute {
+		t.Errorf("Expiry() = %v, %v; zero should keep the dead timeout", dead, remove)
+	}
+	if removed := ps.CleanupStale(); len(removed) != 1 {
+		t.Errorf("expected the quiet peer removed, got %v", removed)
+	}
+}
+
+func TestPeerStoreForget(t *testing.T) {
+	ps := NewPeerStore()
+	ps.Update(&PeerInfo{WGPubKey: "gone", MeshIP: "10.0.0.9"}, "dht")
+
+	if !ps.Forget("gone") {
+		t.Fatal("Forget reported the peer unknown")
+	}
+	if _, ok := ps.Get("gone"); ok {
+		t.Fatal("forgotten peer still in the store")
+	}
+
+	// Gossip from nodes that still have it must not bring it back.
+	ps.Update(&PeerInfo{WGPubKey: "gone", MeshIP: "10.0.0.9"}, "gossip")
+	if _, ok := ps.Get("gone"); ok {
+		t.Error("forgotten peer re-added by an announcement")
+	}
+
+	ps.Forget("other")
+	ps.Update(&PeerInfo{WGPubKey: "other"}, "dht")
+	if ps.Count() != 0 {
+		t.Errorf("Count() = %d, want 0", ps.Count())
+	}
+}
@@ -0,0 +1,307 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	EventPeerQuarantined = "peer_quarantined"
+	EventPeerApproved    = "peer_approved"
+
+	QuarantineReasonMeshIP   = "mesh_ip_pinned"
+	QuarantineReasonHostname = "hostname_pinned"
+)
+
+// IdentityPinsPath returns the file holding the first-seen identity pins
+// for an interface.
+func IdentityPinsPath(ifaceName string) string {
+	return filepath.Join(stateDir, fmt.Sprintf("%s-pins.json", ifaceName))
+}
+
+// identityPin binds a mesh IP or hostname to the public key that first
+// claimed it.
+type identityPin struct {
+	PubKey    string    `json:"pubkey"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+type identityPinFile struct {
+	MeshIPs   map[string]identityPin `json:"mesh_ips"`
+	Hostnames map[string]identityPin `json:"hostnames"`
+}
+
+// quarantinedPeer is an announcement that conflicted with a pin. The peer
+// stays out of the PeerStore until an operator approves it.
+type quarantinedPeer struct {
+	info      PeerInfo
+	method    string
+	reason    string
+	pinnedKey string
+	firstSeen time.Time
+	lastSeen  time.Time
+}
+
+// identityPins is the daemon's --pin-identities state. The zero value has
+// pinning disabled.
+type identityPins struct {
+	mu          sync.Mutex
+	enabled     bool
+	pins        identityPinFile
+	quarantined map[string]*quarantinedPeer // by pubkey
+}
+
+// RPCQuarantineData describes a quarantined peer for RPC (matches rpc.QuarantineData)
+type RPCQuarantineData struct {
+	PubKey       string
+	Hostname     string
+	MeshIP       string
+	Endpoint     string
+	Reason       string
+	PinnedPubKey string
+	FirstSeen    time.Time
+	LastSeen     time.Time
+}
+
+// setupIdentityPinning loads the pins and starts filtering announcements
+// through admitPeer.
+func (d *Daemon) setupIdentityPinning() {
+	if !d.config.PinIdentities {
+		return
+	}
+	pins, err := loadIdentityPins(d.config.InterfaceName)
+	if err != nil && !os.IsNotExist(err) {
+		log.Printf("[Pinning] Failed to load identity pins, starting empty: %v", err)
+	}
+
+	ip := &d.identityPins
+	ip.mu.Lock()
+	ip.enabled = true
+	ip.pins = pins
+	ip.quarantined = make(map[string]*quarantinedPeer)
+	ip.mu.Unlock()
+
+	d.peerStore.SetAdmitFunc(d.admitPeer)
+	log.Printf("[Pinning] Identity pinning enabled (%d mesh IPs, %d hostnames pinned)", len(pins.MeshIPs), len(pins.Hostnames))
+}
+
+// admitPinned pins the mesh IP and hostname of first-seen peers and rejects
+// announcements that claim a pinned mesh IP or hostname with another key.
+func (d *Daemon) admitPinned(info *PeerInfo, discoveryMethod string) bool {
+	if d.localNode != nil && info.WGPubKey == d.localNode.WGPubKey {
+		return true
+	}
+
+	ip := &d.identityPins
+	ip.mu.Lock()
+	if !ip.enabled {
+		ip.mu.Unlock()
+		return true
+	}
+
+	reason, pinnedKey := ip.conflict(info)
+	if reason != "" {
+		now := time.Now()
+		q, seen := ip.quarantined[info.WGPubKey]
+		if !seen {
+			q = &quarantinedPeer{firstSeen: now}
+			ip.quarantined[info.WGPubKey] = q
+		}
+		q.info = *info
+		q.method = discoveryMethod
+		q.reason = reason
+		q.pinnedKey = pinnedKey
+		q.lastSeen = now
+		ip.mu.Unlock()
+
+		if !seen {
+			log.Printf("[Pinning] Quarantined peer %s...: %s claims %s pinned to %s... (approve with 'wgmesh peers approve')",
+				shortKey(info.WGPubKey), reason, pinnedValue(info, reason), shortKey(pinnedKey))
+			d.recordEvent(EventPeerQuarantined, info.WGPubKey, map[string]string{
+				"reason":        reason,
+				"mesh_ip":       info.MeshIP,
+				"hostname":      info.Hostname,
+				"pinned_pubkey": pinnedKey,
+			})
+		}
+		return false
+	}
+
+	changed := ip.pin(info.WGPubKey, info.MeshIP, info.Hostname, time.Now())
+	pins := ip.snapshot(changed)
+	ip.mu.Unlock()
+
+	if changed {
+		if err := saveIdentityPins(d.config.InterfaceName, pins); err != nil {
+			log.Printf("[Pinning] Failed to save identity pins: %v", err)
+		}
+	}
+	return true
+}
+
+// approveQuarantined accepts a quarantined peer: its mesh IP and hostname are
+// re-pinned to its key and the peer that previously held them is dropped.
+func (d *Daemon) approveQuarantined(pubKey string) error {
+	ip := &d.identityPins
+	ip.mu.Lock()
+	q, ok := ip.quarantined[pubKey]
+	if !ok {
+		ip.mu.Unlock()
+		return fmt.Errorf("peer %s is not quarantined", pubKey)
+	}
+	delete(ip.quarantined, pubKey)
+	now := time.Now()
+	replaced := ip.repin(pubKey, q.info.MeshIP, q.info.Hostname, now)
+	pins := ip.snapshot(true)
+	ip.mu.Unlock()
+
+	if err := saveIdentityPins(d.config.InterfaceName, pins); err != nil {
+		log.Printf("[Pinning] Failed to save identity pins: %v", err)
+	}
+	for _, old := range replaced {
+		d.peerStore.Remove(old)
+	}
+
+	info := q.info
+	d.peerStore.Update(&info, q.method)
+	log.Printf("[Pinning] Approved peer %s... (%s, %s)", shortKey(pubKey), q.info.MeshIP, q.info.Hostname)
+	d.recordEvent(EventPeerApproved, pubKey, map[string]string{
+		"mesh_ip":  q.info.MeshIP,
+		"hostname": q.info.Hostname,
+	})
+	return nil
+}
+
+// GetRPCQuarantine returns quarantined peers for RPC, oldest first.
+func (d *Daemon) GetRPCQuarantine() []*RPCQuarantineData {
+	ip := &d.identityPins
+	ip.mu.Lock()
+	defer ip.mu.Unlock()
+
+	result := make([]*RPCQuarantineData, 0, len(ip.quarantined))
+	for pubKey, q := range ip.quarantined {
+		result = append(result, &RPCQuarantineData{
+			PubKey:       pubKey,
+			Hostname:     q.info.Hostname,
+			MeshIP:       q.info.MeshIP,
+			Endpoint:     q.info.Endpoint,
+			Reason:       q.reason,
+			PinnedPubKey: q.pinnedKey,
+			FirstSeen:    q.firstSeen,
+			LastSeen:     q.lastSeen,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].FirstSeen.Equal(result[j].FirstSeen) {
+			return result[i].FirstSeen.Before(result[j].FirstSeen)
+		}
+		return result[i].PubKey < result[j].PubKey
+	})
+	return result
+}
+
+// conflict reports whether info claims a mesh IP or hostname pinned to a
+// different key.
+func (ip *identityPins) conflict(info *PeerInfo) (reason, pinnedKey string) {
+	if pin, ok := ip.pins.MeshIPs[info.MeshIP]; ok && info.MeshIP != "" && pin.PubKey != info.WGPubKey {
+		return QuarantineReasonMeshIP, pin.PubKey
+	}
+	if pin, ok := ip.pins.Hostnames[info.Hostname]; ok && info.Hostname != "" && pin.PubKey != info.WGPubKey {
+		return QuarantineReasonHostname, pin.PubKey
+	}
+	return "", ""
+}
+
+// pin records first-seen bindings for pubKey, reporting whether any were new.
+func (ip *identityPins) pin(pubKey, meshIP, hostname string, now time.Time) bool {
+	changed := false
+	if meshIP != "" {
+		if _, ok := ip.pins.MeshIPs[meshIP]; !ok {
+			if ip.pins.MeshIPs == nil {
+				ip.pins.MeshIPs = make(map[string]identityPin)
+			}
+			ip.pins.MeshIPs[meshIP] = identityPin{PubKey: pubKey, FirstSeen: now}
+			changed = true
+		}
+	}
+	if hostname != "" {
+		if _, ok := ip.pins.Hostnames[hostname]; !ok {
+			if ip.pins.Hostnames == nil {
+				ip.pins.Hostnames = make(map[string]identityPin)
+			}
+			ip.pins.Hostnames[hostname] = identityPin{PubKey: pubKey, FirstSeen: now}
+			changed = true
+		}
+	}
+	return changed
+}
+
+// repin overwrites the bindings for meshIP and hostname with pubKey and
+// returns the keys that lost them.
+func (ip *identityPins) repin(pubKey, meshIP, hostname string, now time.Time) []string {
+	var replaced []string
+	if old, ok := ip.pins.MeshIPs[meshIP]; ok && old.PubKey != pubKey {
+		replaced = append(replaced, old.PubKey)
+		delete(ip.pins.MeshIPs, meshIP)
+	}
+	if old, ok := ip.pins.Hostnames[hostname]; ok && old.PubKey != pubKey {
+		if len(replaced) == 0 || replaced[0] != old.PubKey {
+			replaced = append(replaced, old.PubKey)
+		}
+		delete(ip.pins.Hostnames, hostname)
+	}
+	ip.pin(pubKey, meshIP, hostname, now)
+	return replaced
+}
+
+// snapshot copies the pins for saving outside the lock; it returns the zero
+// value when nothing needs saving.
+func (ip *identityPins) snapshot(needed bool) identityPinFile {
+	if !needed {
+		return identityPinFile{}
+	}
+	out := identityPinFile{
+		MeshIPs:   make(map[string]identityPin, len(ip.pins.MeshIPs)),
+		Hostnames: make(map[string]identityPin, len(ip.pins.Hostnames)),
+	}
+	for k, v := range ip.pins.MeshIPs {
+		out.MeshIPs[k] = v
+	}
+	for k, v := range ip.pins.Hostnames {
+		out.Hostnames[k] = v
+	}
+	return out
+}
+
+func pinnedValue(info *PeerInfo, reason string) string {
+	if reason == QuarantineReasonHostname {
+		return "hostname " + info.Hostname
+	}
+	return "mesh IP " + info.MeshIP
+}
+
+func loadIdentityPins(ifaceName string) (identityPinFile, error) {
+	var pins identityPinFile
+	data, err := os.ReadFile(IdentityPinsPath(ifaceName))
+	if err != nil {
+		return pins, err
+	}
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return identityPinFile{}, err
+	}
+	return pins, nil
+}
+
+func saveIdentityPins(ifaceName string, pins identityPinFile) error {
+	data, err := json.MarshalIndent(pins, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(IdentityPinsPath(ifaceName), data, 0600)
+}
@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"os"
+	"testing"
+)
+
+func newPinningTestDaemon(t *testing.T) *Daemon {
+	t.Helper()
+	d := &Daemon{
+		config:    &Config{InterfaceName: "wgtest0", PinIdentities: true},
+		localNode: &LocalNode{WGPubKey: "local-key", MeshIP: "10.0.0.1", Hostname: "local"},
+		peerStore: NewPeerStore(),
+	}
+	d.setupIdentityPinning()
+	return d
+}
+
+func TestIdentityPinningQuarantinesConflicts(t *testing.T) {
+	useTempStateDir(t)
+	d := newPinningTestDaemon(t)
+
+	d.peerStore.Update(&PeerInfo{WGPubKey: "key-a", MeshIP: "10.0.0.2", Hostname: "alpha"}, "dht")
+	if _, ok := d.peerStore.Get("key-a"); !ok {
+		t.Fatal("first-seen peer was not admitted")
+	}
+	if _, err := os.Stat(IdentityPinsPath("wgtest0")); err != nil {
+		t.Fatalf("pins were not saved: %v", err)
+	}
+
+	// Same key re-announcing is fine, also with a changed endpoint.
+	d.peerStore.Update(&PeerInfo{WGPubKey: "key-a", MeshIP: "10.0.0.2", Hostname: "alpha", Endpoint: "1.2.3.4:51820"}, "lan")
+	if p, _ := d.peerStore.Get("key-a"); p.Endpoint != "1.2.3.4:51820" {
+		t.Errorf("re-announcement was not applied, endpoint = %q", p.Endpoint)
+	}
+
+	d.peerStore.Update(&PeerInfo{WGPubKey: "key-b", MeshIP: "10.0.0.2", Hostname: "beta"}, "dht")
+	if _, ok := d.peerStore.Get("key-b"); ok {
+		t.Error("peer claiming a pinned mesh IP was admitted")
+	}
+	d.peerStore.Update(&PeerInfo{WGPubKey: "key-c", MeshIP: "10.0.0.3", Hostname: "alpha"}, "dht")
+	if _, ok := d.peerStore.Get("key-c"); ok {
+		t.Error("peer claiming a pinned hostname was admitted")
+	}
+
+	quarantined := d.GetRPCQuarantine()
+	if len(quarantined) != 2 {
+		t.Fatalf("quarantined %d peers, want 2", len(quarantined))
+	}
+	if q := quarantined[0]; q.PubKey != "key-b" || q.Reason != QuarantineReasonMeshIP || q.PinnedPubKey != "key-a" {
+		t.Errorf("unexpected first quarantine entry: %+v", q)
+	}
+	if q := quarantined[1]; q.PubKey != "key-c" || q.Reason != QuarantineReasonHostname || q.PinnedPubKey != "key-a" {
+		t.Errorf("unexpected second quarantine entry: %+v", q)
+	}
+	if n := len(d.GetRPCEvents(0)); n != 2 {
+		t.Errorf("recorded %d events, want 2", n)
+	}
+
+	// A rejected peer must not have claimed pins of its own.
+	d.peerStore.Update(&PeerInfo{WGPubKey: "key-d", MeshIP: "10.0.0.4", Hostname: "beta"}, "dht")
+	if _, ok := d.peerStore.Get("key-d"); !ok {
+		t.Error("hostname of a quarantined peer was pinned")
+	}
+}
+
+func TestApprovePeerReplacesPinnedPeer(t *testing.T) {
+	useTempStateDir(t)
+	d := newPinningTestDaemon(t)
+
+	d.peerStore.Update(&PeerInfo{WGPubKey: "key-a", MeshIP: "10.0.0.2", Hostname: "alpha"}, "dht")
+	d.peerStore.Update(&PeerInfo{WGPubKey: "key-b", MeshIP: "10.0.0.2", Hostname: "alpha"}, "dht")
+
+	if err := d.ApprovePeer("key-unknown"); err == nil {
+		t.Error("expected error approving a peer that is not quarantined")
+	}
+	if err := d.ApprovePeer("key-b"); err != nil {
+		t.Fatalf("ApprovePeer: %v", err)
+	}
+
+	if _, ok := d.peerStore.Get("key-b"); !ok {
+		t.Error("approved peer is not in the peer store")
+	}
+	if _, ok := d.peerStore.Get("key-a"); ok {
+		t.Error("peer that lost its pins is still in the peer store")
+	}
+	if n := len(d.GetRPCQuarantine()); n != 0 {
+		t.Errorf("quarantine has %d entries after approval, want 0", n)
+	}
+
+	// The old key is now the impostor, also after a restart.
+	restarted := newPinningTestDaemon(t)
+	restarted.peerStore.Update(&PeerInfo{WGPubKey: "key-a", MeshIP: "10.0.0.2", Hostname: "alpha"}, "dht")
+	if _, ok := restarted.peerStore.Get("key-a"); ok {
+		t.Error("old key was admitted after the pins were reloaded")
+	}
+	restarted.peerStore.Update(&PeerInfo{WGPubKey: "key-b", MeshIP: "10.0.0.2", Hostname: "alpha"}, "dht")
+	if _, ok := restarted.peerStore.Get("key-b"); !ok {
+		t.Error("approved key was rejected after the pins were reloaded")
+	}
+}
+
+func TestIdentityPinningDisabled(t *testing.T) {
+	useTempStateDir(t)
+	d := &Daemon{config: &Config{InterfaceName: "wgtest0"}, peerStore: NewPeerStore()}
+	d.setupIdentityPinning()
+
+	d.peerStore.Update(&PeerInfo{WGPubKey: "key-a", MeshIP: "10.0.0.2"}, "dht")
+	d.peerStore.Update(&PeerInfo{WGPubKey: "key-b", MeshIP: "10.0.0.2"}, "dht")
+	if d.peerStore.Count() != 2 {
+		t.Errorf("Count = %d, want 2 without pinning", d.peerStore.Count())
+	}
+	if _, err := os.Stat(IdentityPinsPath("wgtest0")); !os.IsNotExist(err) {
+		t.Errorf("pins file written with pinning disabled: %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+package daemon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	// MinMTU and MaxMTU bound --mtu. 1280 is the smallest MTU IPv6 allows.
+	MinMTU = 1280
+	MaxMTU = 9000
+
+	PMTUProbeInterval = 10 * time.Minute
+	PMTUProbeDelay    = 1 * time.Minute // first round, once handshakes settled
+	PMTUProbeTimeout  = 500 * time.Millisecond
+	PMTUProbeAttempts = 2
+	pmtuGranularity   = 8 // stop the search once the bounds are this close
+
+	EventPathMTUChanged = "path_mtu_changed"
+
+	pmtuMagic       = "wgmesh-pmtu"
+	pmtuHeaderLen   = 28 // IPv4 + UDP
+	ipMTUDiscover   = 10 // Linux IP_MTU_DISCOVER
+	ipPMTUDiscProbe = 3  // Linux IP_PMTUDISC_PROBE: set DF, ignore the cached path MTU
+)
+
+// pmtuState tracks the path MTUs found by --mtu-probe and the MSS clamp
+// built from them.
+type pmtuState struct {
+	mu      sync.Mutex
+	mtu     map[string]int // pubkey -> path MTU, only for paths below the interface MTU
+	ruleset string         // MSS clamp ruleset currently installed; "" = none
+}
+
+// setupMTU applies --mtu to the interface. It runs after setupWireGuard so
+// an interface adopted across a graceful restart gets it too. A Tunnel got
+// it in setupTunnel.
+func (d *Daemon) setupMTU() error {
+	if d.config.MTU == 0 || d.tunnel != nil {
+		return nil
+	}
+	var cmd Command
+	switch runtime.GOOS {
+	case "linux":
+		cmd = cmdExecutor.Command("ip", "link", "set", "dev", d.config.InterfaceName, "mtu", strconv.Itoa(d.config.MTU))
+	case "darwin":
+		cmd = cmdExecutor.Command("ifconfig", d.config.InterfaceName, "mtu", strconv.Itoa(d.config.MTU))
+	default:
+		return fmt.Errorf("unsupported OS: %s", runtime.GOOS)
+	}
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set MTU %d: %s: %w", d.config.MTU, strings.TrimSpace(string(out)), err)
+	}
+	log.Printf("Interface %s MTU set to %d", d.config.InterfaceName, d.config.MTU)
+	return nil
+}
+
+// startPMTUResponder answers path MTU probes on udp/healthProbePort of the
+// mesh IP. It runs on every node, so peers with --mtu-probe can probe it.
+func (d *Daemon) startPMTUResponder() error {
+	addr := net.JoinHostPort(d.localNode.MeshIP, strconv.Itoa(d.healthProbePort))
+	listen := net.ListenPacket
+	if d.tunnel != nil {
+		listen = d.tunnel.ListenPacket
+	}
+	conn, err := listen("udp4", addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-d.ctx.Done()
+		_ = conn.Close()
+	}()
+	go func() {
+		buf := make([]byte, 65536)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				if errors.Is(err, net.ErrClosed) {
+					return
+				}
+				continue
+			}
+			if reply := pmtuReply(buf[:n]); reply != nil {
+				_, _ = conn.WriteTo(reply, from)
+			}
+		}
+	}()
+	return nil
+}
+
+// pmtuReply answers a probe with the size that arrived. The reply is
+// small so the return path's MTU does not matter.
+func pmtuReply(probe []byte) []byte {
+	if !bytes.HasPrefix(probe, []byte(pmtuMagic+" ")) {
+		return nil
+	}
+	return []byte(pmtuAck(len(probe)))
+}
+
+func pmtuAck(size int) string {
+	return fmt.Sprintf("%s-ok %d", pmtuMagic, size)
+}
+
+// searchPathMTU finds the largest packet size between MinMTU and ifaceMTU
+// for which try succeeds. It reports false when even MinMTU fails, e.g.
+// because the peer predates the responder.
+func searchPathMTU(ifaceMTU int, try func(size int) bool) (int, bool) {
+	if ifaceMTU <= MinMTU || !try(MinMTU) {
+		return 0, false
+	}
+	if try(ifaceMTU) {
+		return ifaceMTU, true
+	}
+	lo, hi := MinMTU, ifaceMTU // lo got through, hi did not
+	for hi-lo > pmtuGranularity {
+		mid := (lo + hi) / 2
+		if try(mid) {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo, true
+}
+
+// probePathMTU measures the path MTU to a peer's mesh IP by sending
+// unfragmentable UDP probes of decreasing size through the tunnel. Probes
+// that WireGuard has to fragment on the outside are lost on paths that
+// drop fragments, which is what the search detects.
+func (d *Daemon) probePathMTU(meshIP string, ifaceMTU int) (int, bool) {
+	dialer := net.Dialer{}
+	if runtime.GOOS == "linux" {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, ipMTUDiscover, ipPMTUDiscProbe)
+			})
+			if err != nil {
+				return err
+			}
+			return sockErr
+		}
+	}
+	conn, err := dialer.DialContext(d.ctx, "udp4", net.JoinHostPort(meshIP, strconv.Itoa(d.healthProbePort)))
+	if err != nil {
+		return 0, false
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 64)
+	try := func(size int) bool {
+		payload := make([]byte, size-pmtuHeaderLen)
+		copy(payload, pmtuMagic+" ")
+		want := pmtuAck(len(payload))
+		for attempt := 0; attempt < PMTUProbeAttempts; attempt++ {
+			if _, err := conn.Write(payload); err != nil {
+				return false // EMSGSIZE: larger than the interface allows
+			}
+			_ = conn.SetReadDeadline(time.Now().Add(PMTUProbeTimeout))
+			for {
+				n, err := conn.Read(buf)
+				if err != nil {
+					break
+				}
+				// Skip late answers to earlier, differently sized probes.
+				if string(buf[:n]) == want {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	return searchPathMTU(ifaceMTU, try)
+}
+
+func (d *Daemon) pmtuLoop() {
+	if !d.config.MTUProbe {
+		return
+	}
+	timer := time.NewTimer(PMTUProbeDelay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-timer.C:
+			d.probePathMTUs()
+			timer.Reset(PMTUProbeInterval)
+		}
+	}
+}
+
+// probePathMTUs probes every installed peer with a recent handshake and
+// clamps TCP MSS towards those whose path is smaller than the interface.
+func (d *Daemon) probePathMTUs() {
+	iface, err := net.InterfaceByName(d.config.InterfaceName)
+	if err != nil {
+		return
+	}
+	handshakes, _ := d.wireGuard().GetLatestHandshakes(d.config.InterfaceName)
+
+	d.pmtu.mu.Lock()
+	prev := d.pmtu.mtu
+	d.pmtu.mu.Unlock()
+
+	peers := d.peerStore.GetActive()
+	next := make(map[string]int)
+	for _, p := range peers {
+		if p.WGPubKey == d.localNode.WGPubKey || p.MeshIP == "" || !d.isInstalled(p.WGPubKey) {
+			continue
+		}
+		ts := handshakes[p.WGPubKey]
+		if ts == 0 || time.Since(time.Unix(ts, 0)) >= HandshakeStaleAfter {
+			continue
+		}
+		mtu, ok := d.probePathMTU(p.MeshIP, iface.MTU)
+		if !ok {
+			// Keep what we knew; a lost round says nothing new.
+			if old, known := prev[p.WGPubKey]; known {
+				next[p.WGPubKey] = old
+			}
+			continue
+		}
+		if mtu < iface.MTU {
+			next[p.WGPubKey] = mtu
+		}
+		if old := prev[p.WGPubKey]; old != next[p.WGPubKey] {
+			d.reportPathMTU(p.WGPubKey, old, next[p.WGPubKey], iface.MTU)
+		}
+	}
+
+	d.pmtu.mu.Lock()
+	d.pmtu.mtu = next
+	d.pmtu.mu.Unlock()
+
+	if err := d.applyMSSClamp(peers, next); err != nil {
+		log.Printf("[MTU] %v", err)
+	}
+}
+
+func (d *Daemon) reportPathMTU(pubKey string, old, mtu, ifaceMTU int) {
+	if mtu == 0 {
+		log.Printf("[MTU] Path to %s... carries the full interface MTU %d again", shortKey(pubKey), ifaceMTU)
+		mtu = ifaceMTU
+	} else {
+		log.Printf("[MTU] Path to %s... only carries %d of the interface MTU %d, clamping TCP MSS", shortKey(pubKey), mtu, ifaceMTU)
+	}
+	if old == 0 {
+		old = ifaceMTU
+	}
+	d.recordEvent(EventPathMTUChanged, pubKey, map[string]string{
+		"from": strconv.Itoa(old),
+		"to":   strconv.Itoa(mtu),
+	})
+}
+
+// PathMTU returns the probed path MTU to a peer, or 0 when it is not below
+// the interface MTU or was not probed.
+func (d *Daemon) PathMTU(pubKey string) int {
+	d.pmtu.mu.Lock()
+	defer d.pmtu.mu.Unlock()
+	return d.pmtu.mtu[pubKey]
+}
+
+// mssClamp clamps TCP to and from one peer: its mesh addresses and the
+// networks routed through it, split by family.
+type mssClamp struct {
+	mtu  int
+	v4   []string
+	v6   []string
+	peer string
+}
+
+func (d *Daemon) applyMSSClamp(peers []*PeerInfo, mtus map[string]int) error {
+	peerRoutes := d.resolvePeerRoutes(peers).accepted
+	var clamps []mssClamp
+	for _, p := range peers {
+		mtu, ok := mtus[p.WGPubKey]
+		if !ok {
+			continue
+		}
+		c := mssClamp{mtu: mtu, peer: p.WGPubKey, v4: []string{p.MeshIP}}
+		if p.MeshIPv6 != "" && !d.config.DisableIPv6 {
+			c.v6 = append(c.v6, p.MeshIPv6)
+		}
+		v4, v6 := splitRouteFamilies(peerRoutes[p.WGPubKey])
+		c.v4 = append(c.v4, v4...)
+		if !d.config.DisableIPv6 {
+			c.v6 = append(c.v6, v6...)
+		}
+		clamps = append(clamps, c)
+	}
+	sort.Slice(clamps, func(i, j int) bool { return clamps[i].peer < clamps[j].peer })
+
+	table := mssClampTable(d.config.InterfaceName)
+	ruleset := ""
+	if len(clamps) > 0 {
+		ruleset = buildMSSClampRuleset(table, d.config.InterfaceName, clamps)
+	}
+
+	d.pmtu.mu.Lock()
+	defer d.pmtu.mu.Unlock()
+	if ruleset == d.pmtu.ruleset {
+		return nil
+	}
+	if ruleset == "" {
+		d.deleteMSSClampLocked()
+		return nil
+	}
+	cmd := cmdExecutor.Command("nft", "-f", "-")
+	cmd.SetStdin(strings.NewReader(ruleset))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to install MSS clamp rules: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	d.pmtu.ruleset = ruleset
+	return nil
+}
+
+// teardownMSSClamp removes the MSS clamp table on shutdown.
+func (d *Daemon) teardownMSSClamp() {
+	d.pmtu.mu.Lock()
+	defer d.pmtu.mu.Unlock()
+	d.deleteMSSClampLocked()
+}
+
+func (d *Daemon) deleteMSSClampLocked() {
+	if d.pmtu.ruleset == "" {
+		return
+	}
+	table := mssClampTable(d.config.InterfaceName)
+	if out, err := cmdExecutor.Command("nft", "delete", "table", "inet", table).CombinedOutput(); err != nil {
+		log.Printf("[MTU] Failed to remove nftables table %s: %s: %v", table, strings.TrimSpace(string(out)), err)
+	}
+	d.pmtu.ruleset = ""
+}
+
+func mssClampTable(iface string) string {
+	return subnetRouterTable(iface) + "_mss"
+}
+
+// buildMSSClampRuleset renders an nft script that atomically replaces the
+// clamp table. SYNs in both directions are clamped, so neither end sends
+// segments the path cannot carry. IPv4 TCP adds 40 bytes of headers to the
+// MSS, IPv6 TCP 60.
+func buildMSSClampRuleset(table, iface string, clamps []mssClamp) string {
+	var out, in strings.Builder
+	for _, c := range clamps {
+		for _, fam := range []struct {
+			proto string
+			dests []string
+			mss   int
+		}{{"ip", c.v4, c.mtu - 40}, {"ip6", c.v6, c.mtu - 60}} {
+			if len(fam.dests) == 0 {
+				continue
+			}
+			match := fmt.Sprintf("tcp flags syn tcp option maxseg size > %d tcp option maxseg size set %d", fam.mss, fam.mss)
+			set := strings.Join(fam.dests, ", ")
+			fmt.Fprintf(&out, "\t\toifname %q %s daddr { %s } %s\n", iface, fam.proto, set, match)
+			fmt.Fprintf(&in, "\t\tiifname %q %s saddr { %s } %s\n", iface, fam.proto, set, match)
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "table inet %s {}\ndelete table inet %s\n", table, table)
+	fmt.Fprintf(&sb, "table inet %s {\n", table)
+	sb.WriteString("\tchain postrouting {\n\t\ttype filter hook postrouting priority mangle; policy accept;\n")
+	sb.WriteString(out.String())
+	sb.WriteString("\t}\n")
+	sb.WriteString("\tchain prerouting {\n\t\ttype filter hook prerouting priority mangle; policy accept;\n")
+	sb.WriteString(in.String())
+	sb.WriteString("\t}\n}\n")
+	return sb.String()
+}
@@ -0,0 +1,124 @@
+package daemon
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestSearchPathMTU(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   int // largest size that gets through; 0 = nothing does
+		iface  int
+		want   int
+		wantOK bool
+	}{
+		{"full path", 1500, 1420, 1420, true},
+		{"pppoe", 1392, 1420, 1385, true},
+		{"minimum", 1280, 1420, 1280, true},
+		{"no responder", 0, 1420, 0, false},
+		{"tiny interface", 1500, 1280, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := searchPathMTU(tt.iface, func(size int) bool { return size <= tt.path })
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: searchPathMTU = %d, %v; want %d, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+		if ok && (got > tt.path || tt.path-got > pmtuGranularity && got != tt.iface) {
+			t.Errorf("%s: %d is not within %d of %d", tt.name, got, pmtuGranularity, tt.path)
+		}
+	}
+}
+
+func TestPMTUReply(t *testing.T) {
+	probe := make([]byte, 1372)
+	copy(probe, pmtuMagic+" ")
+	if got := string(pmtuReply(probe)); got != "wgmesh-pmtu-ok 1372" {
+		t.Errorf("pmtuReply = %q", got)
+	}
+	if pmtuReply([]byte("ping\n")) != nil {
+		t.Error("non-probe datagrams should get no reply")
+	}
+}
+
+func TestProbePathMTULoopback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp not available: %v", err)
+	}
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+	pc.Close()
+
+	d := &Daemon{ctx: ctx, localNode: &LocalNode{MeshIP: "127.0.0.1"}, healthProbePort: port}
+	if err := d.startPMTUResponder(); err != nil {
+		t.Fatal(err)
+	}
+	if mtu, ok := d.probePathMTU("127.0.0.1", 1420); !ok || mtu != 1420 {
+		t.Errorf("probePathMTU = %d, %v; want 1420, true", mtu, ok)
+	}
+}
+
+func TestBuildMSSClampRuleset(t *testing.T) {
+	ruleset := buildMSSClampRuleset("wgmesh_wg0_mss", "wg0", []mssClamp{
+		{mtu: 1380, v4: []string{"10.42.0.7", "192.168.1.0/24"}, v6: []string{"fd00::7"}},
+	})
+	for _, want := range []string{
+		"delete table inet wgmesh_wg0_mss",
+		`oifname "wg0" ip daddr { 10.42.0.7, 192.168.1.0/24 } tcp flags syn tcp option maxseg size > 1340 tcp option maxseg size set 1340`,
+		`iifname "wg0" ip saddr { 10.42.0.7, 192.168.1.0/24 } tcp flags syn`,
+		`oifname "wg0" ip6 daddr { fd00::7 } tcp flags syn tcp option maxseg size > 1320 tcp option maxseg size set 1320`,
+	} {
+		if !strings.Contains(ruleset, want) {
+			t.Errorf("ruleset missing %q:\n%s", want, ruleset)
+		}
+	}
+}
+
+func TestApplyMSSClamp(t *testing.T) {
+	var cmds []string
+	mock := &MockCommandExecutor{
+		commandFunc: func(name string, args ...string) Command {
+			cmds = append(cmds, name+" "+strings.Join(args, " "))
+			return &MockCommand{}
+		},
+	}
+	d := newMinimalDaemon(t)
+	d.config.InterfaceName = "wg0"
+	d.localNode = &LocalNode{WGPubKey: "local1"}
+	peers := []*PeerInfo{{WGPubKey: "peer1", MeshIP: "10.42.0.7"}}
+
+	withMockExecutor(t, mock, func() {
+		if err := d.applyMSSClamp(peers, map[string]int{"peer1": 1380}); err != nil {
+			t.Fatal(err)
+		}
+		// Unchanged clamps are not reinstalled.
+		if err := d.applyMSSClamp(peers, map[string]int{"peer1": 1380}); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.applyMSSClamp(peers, nil); err != nil {
+			t.Fatal(err)
+		}
+		d.teardownMSSClamp()
+	})
+
+	want := []string{"nft -f -", "nft delete table inet wgmesh_wg0_mss"}
+	if strings.Join(cmds, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands = %q, want %q", cmds, want)
+	}
+}
+
+func TestConfigMTU(t *testing.T) {
+	for _, mtu := range []int{1000, 9001} {
+		if _, err := NewConfig(DaemonOpts{Secret: testConfigSecret, MTU: mtu}); err == nil {
+			t.Errorf("MTU %d should be rejected", mtu)
+		}
+	}
+	cfg, err := NewConfig(DaemonOpts{Secret: testConfigSecret, MTU: 1380, MTUProbe: true})
+	if err != nil || cfg.MTU != 1380 || !cfg.MTUProbe {
+		t.Errorf("NewConfig = %+v, %v", cfg, err)
+	}
+}
@@ -0,0 +1,128 @@
+package daemon
+
+import (
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+const (
+	// MinPortHopInterval and MaxPortHopInterval bound --port-hop to what
+	// peers accept in announcements. Each hop costs peers a few seconds of
+	// catching up, so shorter periods mostly hurt.
+	MinPortHopInterval   = crypto.MinPortHop * time.Second
+	MaxPortHopInterval   = crypto.MaxPortHop * time.Second
+	PortHopCheckInterval = 5 * time.Second
+)
+
+// portHopState tracks this node's hop port and the hop ports reconcile last
+// saw for hopping peers.
+type portHopState struct {
+	mu        sync.Mutex
+	port      uint16            // listen port set by the last hop; 0 = not hopped yet
+	peerPorts map[string]uint16 // pubkey -> hop port of peers with PortHop
+}
+
+// hopEndpoint returns endpoint with its port replaced by the peer's current
+// hop port when the peer announced port hopping.
+func (d *Daemon) hopEndpoint(peer *PeerInfo, endpoint string) string {
+	if peer.PortHop <= 0 || endpoint == "" {
+		return endpoint
+	}
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	port, err := crypto.CurrentHopPort(d.config.Secret, peer.WGPubKey, peer.PortHop)
+	if err != nil {
+		return endpoint
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+func (d *Daemon) portHopLoop() {
+	ticker := time.NewTicker(PortHopCheckInterval)
+	defer ticker.Stop()
+	for {
+		if d.config.PortHop > 0 {
+			d.hopListenPort()
+		}
+		if d.peerPortsHopped() {
+			d.reconcile()
+		}
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// hopListenPort moves the WireGuard listen port to this period's port.
+// Established tunnels survive: peers see packets from the new port and
+// WireGuard roams them there, and they derive the port themselves anyway.
+func (d *Daemon) hopListenPort() {
+	port, err := crypto.CurrentHopPort(d.config.Secret, d.localNode.WGPubKey, d.config.PortHop)
+	if err != nil {
+		return
+	}
+	d.portHop.mu.Lock()
+	defer d.portHop.mu.Unlock()
+	if port == d.portHop.port {
+		return
+	}
+	var out []byte
+	if d.tunnel != nil {
+		err = d.tunnel.SetListenPort(int(port))
+	} else {
+		out, err = cmdExecutor.Command("wg", "set", d.config.InterfaceName, "listen-port", strconv.Itoa(int(port))).CombinedOutput()
+	}
+	if err != nil {
+		// Most likely another socket holds the port; peers will not find
+		// us there, but WireGuard roams them to our current port as soon
+		// as we send, and the next period brings a new port.
+		log.Printf("[PortHop] Failed to move listen port to %d: %s: %v", port, string(out), err)
+		return
+	}
+	log.Printf("[PortHop] Listen port is now %d (hops every %v)", port, d.config.PortHop)
+	d.portHop.port = port
+}
+
+// wgListenPort returns the port WireGuard listens on right now.
+func (d *Daemon) wgListenPort() int {
+	d.portHop.mu.Lock()
+	defer d.portHop.mu.Unlock()
+	if d.portHop.port != 0 {
+		return int(d.portHop.port)
+	}
+	return d.config.WGListenPort
+}
+
+// peerPortsHopped reports whether any hopping peer moved to a new port
+// since the last call, so reconcile should re-point WireGuard at it.
+func (d *Daemon) peerPortsHopped() bool {
+	ports := make(map[string]uint16)
+	for _, p := range d.peerStore.GetActive() {
+		if p.PortHop <= 0 || p.WGPubKey == d.localNode.WGPubKey {
+			continue
+		}
+		if port, err := crypto.CurrentHopPort(d.config.Secret, p.WGPubKey, p.PortHop); err == nil {
+			ports[p.WGPubKey] = port
+		}
+	}
+
+	d.portHop.mu.Lock()
+	defer d.portHop.mu.Unlock()
+	hopped := false
+	for key, port := range ports {
+		if prev, ok := d.portHop.peerPorts[key]; ok && prev != port {
+			hopped = true
+		}
+	}
+	d.portHop.peerPorts = ports
+	return hopped
+}
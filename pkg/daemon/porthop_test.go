@@ -0,0 +1,93 @@
+package daemon
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+func TestHopEndpoint(t *testing.T) {
+	d := newMinimalDaemon(t)
+	peer := &PeerInfo{WGPubKey: "peer1", Endpoint: "203.0.113.7:51820"}
+	if got := d.hopEndpoint(peer, peer.Endpoint); got != peer.Endpoint {
+		t.Errorf("non-hopping peer: got %s", got)
+	}
+
+	peer.PortHop = 24 * time.Hour
+	port, err := crypto.CurrentHopPort(d.config.Secret, "peer1", peer.PortHop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := net.JoinHostPort("203.0.113.7", strconv.Itoa(int(port)))
+	if got := d.hopEndpoint(peer, peer.Endpoint); got != want {
+		t.Errorf("hopEndpoint = %s, want %s", got, want)
+	}
+	if got := d.hopEndpoint(peer, ""); got != "" {
+		t.Errorf("empty endpoint should stay empty, got %s", got)
+	}
+}
+
+func TestHopListenPort(t *testing.T) {
+	var cmds []string
+	mock := &MockCommandExecutor{
+		commandFunc: func(name string, args ...string) Command {
+			cmds = append(cmds, name+" "+strings.Join(args, " "))
+			return &MockCommand{}
+		},
+	}
+	d := newMinimalDaemon(t)
+	d.config.InterfaceName = "wg0"
+	d.config.PortHop = 24 * time.Hour
+	d.localNode = &LocalNode{WGPubKey: "local1"}
+	port, _ := crypto.CurrentHopPort(d.config.Secret, "local1", d.config.PortHop)
+
+	withMockExecutor(t, mock, func() {
+		d.hopListenPort()
+		// Within the same period the port stays put.
+		d.hopListenPort()
+	})
+
+	want := "wg set wg0 listen-port " + strconv.Itoa(int(port))
+	if len(cmds) != 1 || cmds[0] != want {
+		t.Errorf("commands = %q, want [%q]", cmds, want)
+	}
+}
+
+func TestPeerPortsHopped(t *testing.T) {
+	d := newMinimalDaemon(t)
+	d.localNode = &LocalNode{WGPubKey: "local1"}
+	d.peerStore.Update(&PeerInfo{WGPubKey: "hopper", MeshIP: "10.42.0.2", PortHop: 24 * time.Hour, LastSeen: time.Now()}, "test")
+	d.peerStore.Update(&PeerInfo{WGPubKey: "fixed", MeshIP: "10.42.0.3", LastSeen: time.Now()}, "test")
+
+	if d.peerPortsHopped() {
+		t.Error("first look at a peer is not a hop")
+	}
+	if d.peerPortsHopped() {
+		t.Error("port did not change within the period")
+	}
+	if _, ok := d.portHop.peerPorts["fixed"]; ok {
+		t.Error("peer without port hopping should not be tracked")
+	}
+
+	d.portHop.peerPorts["hopper"]++
+	if !d.peerPortsHopped() {
+		t.Error("changed port should be reported")
+	}
+}
+
+func TestConfigPortHop(t *testing.T) {
+	if _, err := NewConfig(DaemonOpts{Secret: testConfigSecret, PortHop: 30 * time.Second}); err == nil {
+		t.Error("port hop interval below a minute should be rejected")
+	}
+	if _, err := NewConfig(DaemonOpts{Secret: testConfigSecret, PortHop: MaxPortHopInterval + time.Second}); err == nil {
+		t.Error("port hop interval above the maximum should be rejected")
+	}
+	cfg, err := NewConfig(DaemonOpts{Secret: testConfigSecret, PortHop: 10 * time.Minute})
+	if err != nil || cfg.PortHop != 10*time.Minute {
+		t.Errorf("NewConfig = %+v, %v", cfg, err)
+	}
+}
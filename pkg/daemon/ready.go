@@ -0,0 +1,42 @@
+package daemon
+
+import (
+	"net"
+	"time"
+)
+
+// RPCReadinessData is what daemon.ready decides readiness from.
+type RPCReadinessData struct {
+	InterfaceUp bool
+	Peers       int // peers with a handshake within HandshakeStaleAfter
+}
+
+// GetRPCReadiness reports whether the WireGuard interface is up and how
+// many peers it has handshaken with recently. The caller decides how many
+// peers make the mesh usable.
+func (d *Daemon) GetRPCReadiness() *RPCReadinessData {
+	if d.tunnel != nil {
+		if !d.tunnelUp.Load() {
+			return &RPCReadinessData{}
+		}
+	} else if iface, err := net.InterfaceByName(d.config.InterfaceName); err != nil || iface.Flags&net.FlagUp == 0 {
+		return &RPCReadinessData{}
+	}
+	handshakes, _ := d.wireGuard().GetLatestHandshakes(d.config.InterfaceName)
+	return &RPCReadinessData{
+		InterfaceUp: true,
+		Peers:       countRecentHandshakes(handshakes, time.Now()),
+	}
+}
+
+// countRecentHandshakes counts the peers whose latest handshake is newer
+// than HandshakeStaleAfter at now.
+func countRecentHandshakes(handshakes map[string]int64, now time.Time) int {
+	n := 0
+	for _, ts := range handshakes {
+		if ts > 0 && now.Sub(time.Unix(ts, 0)) < HandshakeStaleAfter {
+			n++
+		}
+	}
+	return n
+}
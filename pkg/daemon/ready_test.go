@@ -0,0 +1,23 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCountRecentHandshakes(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1_700_000_000, 0)
+	handshakes := map[string]int64{
+		"fresh":  now.Add(-10 * time.Second).Unix(),
+		"recent": now.Add(-HandshakeStaleAfter + time.Second).Unix(),
+		"stale":  now.Add(-HandshakeStaleAfter - time.Second).Unix(),
+		"never":  0,
+	}
+	if got := countRecentHandshakes(handshakes, now); got != 2 {
+		t.Errorf("countRecentHandshakes = %d, want 2", got)
+	}
+	if got := countRecentHandshakes(nil, now); got != 0 {
+		t.Errorf("countRecentHandshakes(nil) = %d, want 0", got)
+	}
+}
@@ -0,0 +1,223 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+// newFakeWGDaemon returns a daemon whose WireGuard interface is a
+// wireguard.FakeBackend.
+func newFakeWGDaemon(t *testing.T) (*Daemon, *wireguard.FakeBackend) {
+	t.Helper()
+	d := newMinimalDaemon(t)
+	wg := wireguard.NewFakeBackend()
+	d.wgBackend = wg
+	d.localNode = &LocalNode{WGPubKey: "local", NATType: "cone"}
+	d.directStableCycles = make(map[string]int)
+	d.localSubnetsFn = func() []*net.IPNet { return nil }
+	d.peerHealthFailures = make(map[string]int)
+	d.lastPeerTransferTotal = make(map[string]uint64)
+	return d, wg
+}
+
+// installedPeer renders a peer on the fake interface as
+// endpoint|allowed-ips|keepalive, or "" when it is not there.
+func installedPeer(wg *wireguard.FakeBackend, pubKey string) string {
+	p, ok := wg.Peer(pubKey)
+	if !ok {
+		return ""
+	}
+	return p.Endpoint + "|" + strings.Join(p.AllowedIPs, ",") + "|" + strconv.Itoa(p.PersistentKeepalive)
+}
+
+func TestApplyDesiredPeerConfigs(t *testing.T) {
+	alpha := &PeerInfo{WGPubKey: "alpha", MeshIP: "10.42.0.1", Endpoint: "203.0.113.1:51820", LastSeen: time.Now()}
+	beta := &PeerInfo{WGPubKey: "beta", MeshIP: "10.42.0.2", MeshIPv6: "fd42::2", Endpoint: "203.0.113.2:51820", LastSeen: time.Now()}
+	noEndpoint := &PeerInfo{WGPubKey: "gamma", MeshIP: "10.42.0.3", LastSeen: time.Now()}
+
+	tests := []struct {
+		name      string
+		installed []string // peers already on the interface
+		adopted   []string // of those, the ones inherited from a previous run
+		peers     []*PeerInfo
+		rounds    int
+		want      map[string]string
+		wantSets  int
+	}{
+		{
+			name:     "installs new peers",
+			peers:    []*PeerInfo{alpha, beta},
+			rounds:   1,
+			want:     map[string]string{"alpha": "203.0.113.1:51820|10.42.0.1/32|25", "beta": "203.0.113.2:51820|10.42.0.2/32,fd42::2/128|25"},
+			wantSets: 2,
+		},
+		{
+			name:     "leaves unchanged peers alone",
+			peers:    []*PeerInfo{alpha},
+			rounds:   3,
+			want:     map[string]string{"alpha": "203.0.113.1:51820|10.42.0.1/32|25"},
+			wantSets: 1,
+		},
+		{
+			name:      "removes peers no longer wanted",
+			installed: []string{"stale"},
+			peers:     []*PeerInfo{alpha},
+			rounds:    1,
+			want:      map[string]string{"alpha": "203.0.113.1:51820|10.42.0.1/32|25", "stale": ""},
+			wantSets:  1,
+		},
+		{
+			name:      "holds adopted peers during the grace period",
+			installed: []string{"stale"},
+			adopted:   []string{"stale"},
+			peers:     []*PeerInfo{alpha},
+			rounds:    1,
+			want:      map[string]string{"alpha": "203.0.113.1:51820|10.42.0.1/32|25", "stale": "198.51.100.9:51820|10.42.0.9/32|25"},
+			wantSets:  1,
+		},
+		{
+			name:   "skips peers without an endpoint",
+			peers:  []*PeerInfo{noEndpoint},
+			rounds: 1,
+			want:   map[string]string{"gamma": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, wg := newFakeWGDaemon(t)
+			for _, key := range tt.installed {
+				if err := wg.SetPeer("wg0", key, [32]byte{}, "198.51.100.9:51820", "10.42.0.9/32", 25); err != nil {
+					t.Fatal(err)
+				}
+			}
+			d.adopted.set(tt.adopted, time.Now().Add(AdoptedPeerGrace))
+			wg.Sets = 0
+
+			for i := 0; i < tt.rounds; i++ {
+				if err := d.syncWireGuardPeers(context.Background(), tt.peers); err != nil {
+					t.Fatalf("round %d: %v", i+1, err)
+				}
+			}
+			for key, want := range tt.want {
+				if got := installedPeer(wg, key); got != want {
+					t.Errorf("peer %s = %q, want %q", key, got, want)
+				}
+			}
+			if wg.Sets != tt.wantSets {
+				t.Errorf("wg set called %d times, want %d", wg.Sets, tt.wantSets)
+			}
+		})
+	}
+}
+
+func TestApplyDesiredPeerConfigsError(t *testing.T) {
+	d, wg := newFakeWGDaemon(t)
+	peer := &PeerInfo{WGPubKey: "alpha", MeshIP: "10.42.0.1", Endpoint: "203.0.113.1:51820", LastSeen: time.Now()}
+
+	wg.Err = errors.New("no such device")
+	if err := d.syncWireGuardPeers(context.Background(), []*PeerInfo{peer}); err == nil {
+		t.Fatal("sync succeeded on a failing interface")
+	}
+	if _, ok := d.lastAppliedPeerConfigs["alpha"]; ok {
+		t.Error("failed wg set left the peer marked as applied")
+	}
+
+	wg.Err = nil
+	if err := d.syncWireGuardPeers(context.Background(), []*PeerInfo{peer}); err != nil {
+		t.Fatalf("sync after recovery: %v", err)
+	}
+	if got := installedPeer(wg, "alpha"); got == "" {
+		t.Error("peer not installed once the interface recovered")
+	}
+}
+
+func TestSyncWireGuardPeersRelay(t *testing.T) {
+	const (
+		fresh = -30 * time.Second
+		stale = -5 * time.Minute
+	)
+	tests := []struct {
+		name       string
+		introducer bool          // this node is an introducer
+		localNAT   string        // this node's NAT type
+		peerNAT    string        // the target's NAT type
+		handshake  time.Duration // age of the target's last handshake; 0 = none, target not installed yet
+		wantRelay  bool
+	}{
+		{name: "fresh handshake stays direct", localNAT: "symmetric", peerNAT: "symmetric", handshake: fresh},
+		{name: "stale handshake falls back to relay", localNAT: "cone", peerNAT: "cone", handshake: stale, wantRelay: true},
+		{name: "symmetric pair relays from the start", localNAT: "symmetric", peerNAT: "symmetric", wantRelay: true},
+		{name: "cone pair tries direct first", localNAT: "cone", peerNAT: "symmetric"},
+		{name: "introducers never relay", introducer: true, localNAT: "cone", peerNAT: "cone", handshake: stale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, wg := newFakeWGDaemon(t)
+			d.config.Introducer = tt.introducer
+			d.localNode.NATType = tt.localNAT
+
+			relay := &PeerInfo{WGPubKey: "relay", MeshIP: "10.42.0.1", Endpoint: "203.0.113.1:51820", Introducer: true, LastSeen: time.Now()}
+			target := &PeerInfo{WGPubKey: "target", MeshIP: "10.42.0.2", Endpoint: "203.0.113.2:51820", NATType: tt.peerNAT, LastSeen: time.Now()}
+			if tt.handshake != 0 {
+				if err := wg.SetPeer("wg0", "target", [32]byte{}, target.Endpoint, "10.42.0.2/32", 25); err != nil {
+					t.Fatal(err)
+				}
+				wg.SetHandshake("target", time.Now().Add(tt.handshake).Unix())
+			}
+
+			if err := d.syncWireGuardPeers(context.Background(), []*PeerInfo{relay, target}); err != nil {
+				t.Fatal(err)
+			}
+
+			_, relayed := d.currentRelayRoutesSnapshot()["target"]
+			if relayed != tt.wantRelay {
+				t.Fatalf("relayed = %v, want %v", relayed, tt.wantRelay)
+			}
+			wantRelay, wantTarget := "203.0.113.1:51820|10.42.0.1/32|25", "203.0.113.2:51820|10.42.0.2/32|25"
+			if tt.wantRelay {
+				wantRelay, wantTarget = "203.0.113.1:51820|10.42.0.1/32,10.42.0.2/32|25", ""
+			}
+			if got := installedPeer(wg, "relay"); got != wantRelay {
+				t.Errorf("relay = %q, want %q", got, wantRelay)
+			}
+			if got := installedPeer(wg, "target"); got != wantTarget {
+				t.Errorf("target = %q, want %q", got, wantTarget)
+			}
+		})
+	}
+}
+
+func TestSyncWireGuardPeersRelayHysteresis(t *testing.T) {
+	d, wg := newFakeWGDaemon(t)
+	relay := &PeerInfo{WGPubKey: "relay", MeshIP: "10.42.0.1", Endpoint: "203.0.113.1:51820", Introducer: true, LastSeen: time.Now()}
+	target := &PeerInfo{WGPubKey: "target", MeshIP: "10.42.0.2", Endpoint: "203.0.113.2:51820", NATType: "cone", LastSeen: time.Now()}
+	peers := []*PeerInfo{relay, target}
+
+	// A cone peer with no handshake would be tried directly, but one on a
+	// relay keeps it until that has held for RelayHysteresisThreshold rounds.
+	d.relayRoutes["target"] = "relay"
+	for round := 1; round <= RelayHysteresisThreshold; round++ {
+		if err := d.syncWireGuardPeers(context.Background(), peers); err != nil {
+			t.Fatal(err)
+		}
+		_, relayed := d.currentRelayRoutesSnapshot()["target"]
+		if want := round < RelayHysteresisThreshold; relayed != want {
+			t.Fatalf("round %d: relayed = %v, want %v", round, relayed, want)
+		}
+	}
+	if got := installedPeer(wg, "target"); got != "203.0.113.2:51820|10.42.0.2/32|25" {
+		t.Errorf("target = %q after switching to direct", got)
+	}
+	if p, _ := wg.Peer("relay"); len(p.AllowedIPs) != 1 {
+		t.Errorf("relay still carries %v", p.AllowedIPs)
+	}
+}
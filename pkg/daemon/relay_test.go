@@ -392,6 +392,156 @@ func TestBuildDesiredPeerConfigs_RelayRouting(t *testing.T) {
 	}
 }
 
+func TestSelectRelayForPeer(t *testing.T) {
+	ms := func(n int) *time.Duration { d := time.Duration(n) * time.Millisecond; return &d }
+	loss := func(f float64) *float64 { return &f }
+
+	tests := []struct {
+		name          string
+		relays        []*PeerInfo
+		failing       []string
+		current       string
+		want          string
+		deterministic bool
+	}{
+		{
+			name: "lowest latency wins",
+			relays: []*PeerInfo{
+				{WGPubKey: "relay-a", Endpoint: "1.1.1.1:51820", Latency: ms(80)},
+				{WGPubKey: "relay-b", Endpoint: "2.2.2.2:51820", Latency: ms(12)},
+				{WGPubKey: "relay-c", Endpoint: "3.3.3.3:51820", Latency: ms(40)},
+			},
+			want: "relay-b",
+		},
+		{
+			name: "measured relay preferred over unmeasured",
+			relays: []*PeerInfo{
+				{WGPubKey: "relay-a", Endpoint: "1.1.1.1:51820"},
+				{WGPubKey: "relay-b", Endpoint: "2.2.2.2:51820", Latency: ms(90)},
+			},
+			want: "relay-b",
+		},
+		{
+			name: "fails over when probes to fastest relay fail",
+			relays: []*PeerInfo{
+				{WGPubKey: "relay-a", Endpoint: "1.1.1.1:51820", Latency: ms(10)},
+				{WGPubKey: "relay-b", Endpoint: "2.2.2.2:51820", Latency: ms(50)},
+			},
+			failing: []string{"relay-a"},
+			current: "relay-a",
+			want:    "relay-b",
+		},
+		{
+			name: "lossy relay skipped",
+			relays: []*PeerInfo{
+				{WGPubKey: "relay-a", Endpoint: "1.1.1.1:51820", Latency: ms(10), PacketLoss: loss(0.6)},
+				{WGPubKey: "relay-b", Endpoint: "2.2.2.2:51820", Latency: ms(50), PacketLoss: loss(0.05)},
+			},
+			want: "relay-b",
+		},
+		{
+			name: "all unhealthy falls back to latency",
+			relays: []*PeerInfo{
+				{WGPubKey: "relay-a", Endpoint: "1.1.1.1:51820", Latency: ms(30)},
+				{WGPubKey: "relay-b", Endpoint: "2.2.2.2:51820", Latency: ms(60)},
+			},
+			failing: []string{"relay-a", "relay-b"},
+			want:    "relay-a",
+		},
+		{
+			name: "current relay kept within tolerance",
+			relays: []*PeerInfo{
+				{WGPubKey: "relay-a", Endpoint: "1.1.1.1:51820", Latency: ms(20)},
+				{WGPubKey: "relay-b", Endpoint: "2.2.2.2:51820", Latency: ms(24)},
+			},
+			current: "relay-b",
+			want:    "relay-b",
+		},
+		{
+			name: "current relay replaced when clearly slower",
+			relays: []*PeerInfo{
+				{WGPubKey: "relay-a", Endpoint: "1.1.1.1:51820", Latency: ms(20)},
+				{WGPubKey: "relay-b", Endpoint: "2.2.2.2:51820", Latency: ms(60)},
+			},
+			current: "relay-b",
+			want:    "relay-a",
+		},
+		{
+			name: "unmeasured relays use hash tiebreak",
+			relays: []*PeerInfo{
+				{WGPubKey: "relay-a", Endpoint: "1.1.1.1:51820"},
+				{WGPubKey: "relay-b", Endpoint: "2.2.2.2:51820"},
+			},
+			deterministic: true,
+		},
+		{
+			name: "overloaded relay skipped",
+			relays: []*PeerInfo{
+				{WGPubKey: "relay-a", Endpoint: "1.1.1.1:51820", Latency: ms(10), Load: &crypto.IntroducerLoad{RelayedPeers: IntroducerMaxRelayedPeers}},
+				{WGPubKey: "relay-b", Endpoint: "2.2.2.2:51820", Latency: ms(50), Load: &crypto.IntroducerLoad{RelayedPeers: 3}},
+			},
+			want: "relay-b",
+		},
+		{
+			name: "overloaded current relay kept",
+			relays: []*PeerInfo{
+				{WGPubKey: "relay-a", Endpoint: "1.1.1.1:51820", Latency: ms(20), Load: &crypto.IntroducerLoad{Sessions: IntroducerMaxSessions}},
+				{WGPubKey: "relay-b", Endpoint: "2.2.2.2:51820", Latency: ms(22)},
+			},
+			current: "relay-a",
+			want:    "relay-a",
+		},
+		{
+			name: "all overloaded falls back to latency",
+			relays: []*PeerInfo{
+				{WGPubKey: "relay-a", Endpoint: "1.1.1.1:51820", Latency: ms(30), Load: &crypto.IntroducerLoad{Bps: IntroducerMaxBps}},
+				{WGPubKey: "relay-b", Endpoint: "2.2.2.2:51820", Latency: ms(60), Load: &crypto.IntroducerLoad{Bps: IntroducerMaxBps}},
+			},
+			want: "relay-a",
+		},
+		{
+			name: "relays without endpoint ignored",
+			relays: []*PeerInfo{
+				{WGPubKey: "relay-a", Latency: ms(5)},
+				{WGPubKey: "relay-b", Endpoint: "2.2.2.2:51820", Latency: ms(50)},
+			},
+			want: "relay-b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Daemon{
+				config:        &Config{},
+				localNode:     &LocalNode{WGPubKey: "local"},
+				probeFailures: make(map[string]int),
+				relayRoutes:   make(map[string]string),
+			}
+			for _, key := range tt.failing {
+				d.probeFailures[key] = 2
+			}
+			if tt.current != "" {
+				d.relayRoutes["peer"] = tt.current
+			}
+			peer := &PeerInfo{WGPubKey: "peer"}
+
+			got := d.selectRelayForPeer(peer, tt.relays)
+			if got == nil {
+				t.Fatal("selectRelayForPeer returned nil")
+			}
+			if tt.want != "" && got.WGPubKey != tt.want {
+				t.Errorf("selected %q, want %q", got.WGPubKey, tt.want)
+			}
+			if tt.deterministic {
+				again := d.selectRelayForPeer(peer, tt.relays)
+				if again.WGPubKey != got.WGPubKey {
+					t.Errorf("tiebreak not deterministic: %q then %q", got.WGPubKey, again.WGPubKey)
+				}
+			}
+		})
+	}
+}
+
 // makeTestKeys derives a minimal DerivedKeys for tests that need it.
 func makeTestKeys(t *testing.T) *crypto.DerivedKeys {
 	t.Helper()
@@ -401,3 +551,33 @@ func makeTestKeys(t *testing.T) *crypto.DerivedKeys {
 	}
 	return keys
 }
+
+func TestKeepaliveForPeer(t *testing.T) {
+	tests := []struct {
+		name      string
+		override  int
+		localNAT  string
+		peerNAT   string
+		wantValue int
+	}{
+		{name: "public to public disabled", localNAT: "none", peerNAT: "none", wantValue: 0},
+		{name: "local behind NAT", localNAT: "cone", peerNAT: "none", wantValue: DefaultKeepalive},
+		{name: "peer behind NAT", localNAT: "none", peerNAT: "symmetric", wantValue: DefaultKeepalive},
+		{name: "unknown NAT keeps keepalive", localNAT: "", peerNAT: "", wantValue: DefaultKeepalive},
+		{name: "override wins", override: 10, localNAT: "none", peerNAT: "none", wantValue: 10},
+		{name: "override off", override: -1, localNAT: "cone", peerNAT: "cone", wantValue: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Daemon{
+				config:    &Config{Keepalive: tt.override},
+				localNode: &LocalNode{NATType: tt.localNAT},
+			}
+			got := d.keepaliveForPeer(&PeerInfo{WGPubKey: "peer1", NATType: tt.peerNAT})
+			if got != tt.wantValue {
+				t.Errorf("keepaliveForPeer() = %d, want %d", got, tt.wantValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// The daemon keeps a probe session per peer, a handler goroutine per
+// inbound probe connection and a few sockets per discovery layer. On a
+// large mesh these add up, and running into RLIMIT_NOFILE makes unrelated
+// sockets fail with EMFILE. The resource guard compares open file
+// descriptors, goroutines and probe sessions with --resource-limits; past
+// ResourcePressureRatio of any limit it closes the least recently used
+// probe sessions and skips stats-only probes until usage drops again.
+const (
+	ResourceGuardInterval    = 10 * time.Second
+	ResourcePressureRatio    = 0.9
+	DefaultGoroutineLimit    = 10000
+	DefaultProbeSessionLimit = 1024
+)
+
+// Resources watched by the guard, as named in --resource-limits.
+const (
+	ResourceFDs           = "fds"
+	ResourceGoroutines    = "goroutines"
+	ResourceProbeSessions = "probe-sessions"
+)
+
+// EventResourcePressure is recorded when the daemon comes under resource
+// pressure and when it recovers.
+const EventResourcePressure = "resource_pressure"
+
+// ResourceLimits caps what the daemon holds open. Zero means the default:
+// the soft RLIMIT_NOFILE for FDs, DefaultGoroutineLimit and
+// DefaultProbeSessionLimit.
+type ResourceLimits struct {
+	FDs           int
+	Goroutines    int
+	ProbeSessions int // outbound and inbound mesh probe connections together
+}
+
+// ParseResourceLimits parses a comma-separated spec such as
+// "fds=4096,goroutines=20000,probe-sessions=512". Resources left out keep
+// their default.
+func ParseResourceLimits(spec string) (ResourceLimits, error) {
+	var limits ResourceLimits
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return ResourceLimits{}, fmt.Errorf("%q is not key=value", item)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err == nil && n < 0 {
+			err = fmt.Errorf("must not be negative")
+		}
+		if err != nil {
+			return ResourceLimits{}, fmt.Errorf("%s: %w", key, err)
+		}
+		switch strings.TrimSpace(key) {
+		case ResourceFDs:
+			limits.FDs = n
+		case ResourceGoroutines:
+			limits.Goroutines = n
+		case ResourceProbeSessions:
+			limits.ProbeSessions = n
+		default:
+			return ResourceLimits{}, fmt.Errorf("unknown resource %q", key)
+		}
+	}
+	return limits, nil
+}
+
+func (l ResourceLimits) fdLimit() int {
+	if l.FDs > 0 {
+		return l.FDs
+	}
+	return softFDLimit()
+}
+
+func (l ResourceLimits) goroutineLimit() int {
+	if l.Goroutines > 0 {
+		return l.Goroutines
+	}
+	return DefaultGoroutineLimit
+}
+
+func (l ResourceLimits) probeSessionLimit() int {
+	if l.ProbeSessions > 0 {
+		return l.ProbeSessions
+	}
+	return DefaultProbeSessionLimit
+}
+
+// RPCResourceData is one watched resource as of the last check.
+type RPCResourceData struct {
+	Name     string
+	Used     int
+	Limit    int // 0 = not tracked on this platform
+	Pressure bool
+}
+
+type resourceState struct {
+	inbound  atomic.Int64 // inbound probe connections being served
+	pressure atomic.Bool
+
+	mu    sync.Mutex
+	usage []RPCResourceData // as of the last check
+}
+
+// resourceGuardLoop checks resource usage periodically.
+func (d *Daemon) resourceGuardLoop() {
+	ticker := time.NewTicker(ResourceGuardInterval)
+	defer ticker.Stop()
+
+	d.checkResources()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.checkResources()
+		}
+	}
+}
+
+// measureResources returns the current usage of each watched resource.
+func (d *Daemon) measureResources() []RPCResourceData {
+	limits := d.config.ResourceLimits
+	fds, fdLimit := 0, 0
+	if n, ok := countOpenFDs(); ok {
+		fds, fdLimit = n, limits.fdLimit()
+	}
+	usage := []RPCResourceData{
+		{Name: ResourceFDs, Used: fds, Limit: fdLimit},
+		{Name: ResourceGoroutines, Used: runtime.NumGoroutine(), Limit: limits.goroutineLimit()},
+		{Name: ResourceProbeSessions, Used: d.probeSessionCount(), Limit: limits.probeSessionLimit()},
+	}
+	for i := range usage {
+		usage[i].Pressure = usage[i].Limit > 0 && float64(usage[i].Used) >= ResourcePressureRatio*float64(usage[i].Limit)
+	}
+	return usage
+}
+
+// checkResources measures usage, sheds probe sessions under pressure and
+// reports transitions into and out of pressure.
+func (d *Daemon) checkResources() {
+	usage := d.measureResources()
+	pressure := false
+	var strained []string
+	details := make(map[string]string, len(usage))
+	for _, u := range usage {
+		resourceUsage.WithLabelValues(u.Name).Set(float64(u.Used))
+		resourceLimit.WithLabelValues(u.Name).Set(float64(u.Limit))
+		if u.Limit > 0 {
+			details[u.Name] = fmt.Sprintf("%d/%d", u.Used, u.Limit)
+		}
+		if u.Pressure {
+			pressure = true
+			strained = append(strained, fmt.Sprintf("%s %d/%d", u.Name, u.Used, u.Limit))
+		}
+	}
+
+	d.resources.mu.Lock()
+	d.resources.usage = usage
+	d.resources.mu.Unlock()
+
+	if d.resources.pressure.Swap(pressure) != pressure {
+		if pressure {
+			log.Printf("[Resources] Under pressure (%s): shedding probe sessions and skipping stats probes", strings.Join(strained, ", "))
+		} else {
+			log.Printf("[Resources] Usage is back below %.0f%% of every limit", ResourcePressureRatio*100)
+		}
+		details["pressure"] = strconv.FormatBool(pressure)
+		d.recordEvent(EventResourcePressure, "", details)
+	}
+
+	if pressure {
+		d.probeMu.Lock()
+		half := len(d.probeSessions) / 2
+		d.probeMu.Unlock()
+		if shed := d.shedProbeSessions(half); shed > 0 {
+			log.Printf("[Resources] Closed %d least recently used probe sessions", shed)
+		}
+	}
+}
+
+// underResourcePressure reports whether the last check found any resource
+// past ResourcePressureRatio of its limit.
+func (d *Daemon) underResourcePressure() bool {
+	return d.resources.pressure.Load()
+}
+
+// probeSessionCount returns the outbound probe sessions plus the inbound
+// probe connections being served.
+func (d *Daemon) probeSessionCount() int {
+	d.probeMu.Lock()
+	n := len(d.probeSessions)
+	d.probeMu.Unlock()
+	return n + int(d.resources.inbound.Load())
+}
+
+// shedProbeSessions closes up to n outbound probe sessions, least recently
+// used first, and returns how many it closed. Their peers are redialed on
+// the next probe that needs them.
+func (d *Daemon) shedProbeSessions(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	d.probeMu.Lock()
+	keys := make([]string, 0, len(d.probeSessions))
+	for pubKey := range d.probeSessions {
+		keys = append(keys, pubKey)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		return d.probeSessions[a].lastUsed.Compare(d.probeSessions[b].lastUsed)
+	})
+	d.probeMu.Unlock()
+
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+	for _, pubKey := range keys {
+		d.closeProbeSession(pubKey)
+	}
+	probeSessionsShed.Add(float64(len(keys)))
+	return len(keys)
+}
+
+// GetRPCResources returns resource usage as of the last check, measuring
+// it now if the guard has not run yet.
+func (d *Daemon) GetRPCResources() []RPCResourceData {
+	d.resources.mu.Lock()
+	usage := slices.Clone(d.resources.usage)
+	d.resources.mu.Unlock()
+	if usage == nil {
+		usage = d.measureResources()
+	}
+	return usage
+}
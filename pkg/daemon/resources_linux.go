@@ -0,0 +1,27 @@
+package daemon
+
+import (
+	"math"
+	"os"
+	"syscall"
+)
+
+// countOpenFDs counts the process's open file descriptors.
+func countOpenFDs() (int, bool) {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return 0, false
+	}
+	// One of them is the descriptor ReadDir itself had open.
+	return len(entries) - 1, true
+}
+
+// softFDLimit returns the soft RLIMIT_NOFILE, or 0 when it is unknown or
+// effectively unlimited.
+func softFDLimit() int {
+	var rlim syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlim); err != nil || rlim.Cur > math.MaxInt32 {
+		return 0
+	}
+	return int(rlim.Cur)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package daemon
+
+// countOpenFDs is only implemented on Linux; elsewhere open file
+// descriptors are not watched.
+func countOpenFDs() (int, bool) {
+	return 0, false
+}
+
+func softFDLimit() int {
+	return 0
+}
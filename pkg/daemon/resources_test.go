@@ -0,0 +1,99 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseResourceLimits(t *testing.T) {
+	t.Parallel()
+	limits, err := ParseResourceLimits("fds=4096, goroutines=20000,probe-sessions=512")
+	if err != nil {
+		t.Fatalf("ParseResourceLimits: %v", err)
+	}
+	if want := (ResourceLimits{FDs: 4096, Goroutines: 20000, ProbeSessions: 512}); limits != want {
+		t.Errorf("limits = %+v, want %+v", limits, want)
+	}
+
+	limits, err = ParseResourceLimits("")
+	if err != nil || limits != (ResourceLimits{}) {
+		t.Errorf("empty spec = %+v, %v; want defaults", limits, err)
+	}
+	if limits.goroutineLimit() != DefaultGoroutineLimit || limits.probeSessionLimit() != DefaultProbeSessionLimit {
+		t.Errorf("defaults = %d goroutines, %d probe sessions", limits.goroutineLimit(), limits.probeSessionLimit())
+	}
+	for _, spec := range []string{"fds", "fds=-1", "goroutines=lots", "sockets=10"} {
+		if _, err := ParseResourceLimits(spec); err == nil {
+			t.Errorf("ParseResourceLimits(%q) should fail", spec)
+		}
+	}
+}
+
+// addProbeSessions gives d a probe session per key, the first one used
+// longest ago.
+func addProbeSessions(t *testing.T, d *Daemon, keys ...string) {
+	t.Helper()
+	if d.probeSessions == nil {
+		d.probeSessions = make(map[string]*peerProbeSession)
+	}
+	start := time.Now().Add(-time.Hour)
+	for i, key := range keys {
+		conn, peer := net.Pipe()
+		t.Cleanup(func() { conn.Close(); peer.Close() })
+		d.probeSessions[key] = &peerProbeSession{conn: conn, lastUsed: start.Add(time.Duration(i) * time.Minute)}
+	}
+}
+
+func TestShedProbeSessionsLeastRecentlyUsed(t *testing.T) {
+	d := newMinimalDaemon(t)
+	addProbeSessions(t, d, "oldest", "older", "newer", "newest")
+
+	if shed := d.shedProbeSessions(2); shed != 2 {
+		t.Fatalf("shed %d sessions, want 2", shed)
+	}
+	for _, key := range []string{"oldest", "older"} {
+		if _, ok := d.probeSessions[key]; ok {
+			t.Errorf("%s should have been shed", key)
+		}
+	}
+	for _, key := range []string{"newer", "newest"} {
+		if _, ok := d.probeSessions[key]; !ok {
+			t.Errorf("%s should have been kept", key)
+		}
+	}
+}
+
+func TestCheckResourcesPressure(t *testing.T) {
+	d := newMinimalDaemon(t)
+	d.config.ResourceLimits.ProbeSessions = 4
+	addProbeSessions(t, d, "a", "b", "c", "d")
+
+	d.checkResources()
+	if !d.underResourcePressure() {
+		t.Fatal("4 of 4 probe sessions should be pressure")
+	}
+	if len(d.probeSessions) != 2 {
+		t.Errorf("%d probe sessions left, want half shed", len(d.probeSessions))
+	}
+	if d.statsProbeDue("a") {
+		t.Error("stats probes should be skipped under pressure")
+	}
+	events := d.events.since(0)
+	if len(events) != 1 || events[0].Type != EventResourcePressure || events[0].Details["pressure"] != "true" {
+		t.Errorf("events = %+v, want one %s", events, EventResourcePressure)
+	}
+
+	d.checkResources()
+	if d.underResourcePressure() {
+		t.Error("2 of 4 probe sessions should not be pressure")
+	}
+	for _, r := range d.GetRPCResources() {
+		if r.Name == ResourceProbeSessions && (r.Used != 2 || r.Limit != 4 || r.Pressure) {
+			t.Errorf("probe sessions = %+v, want 2/4 without pressure", r)
+		}
+	}
+	if events := d.events.since(0); len(events) != 2 || events[1].Details["pressure"] != "false" {
+		t.Errorf("events = %+v, want a recovery event", events)
+	}
+}
@@ -0,0 +1,454 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+const (
+	DefaultRotationGracePeriod  = 24 * time.Hour
+	RotationCheckInterval       = 30 * time.Second
+	RotationRebroadcastInterval = 5 * time.Minute
+	MinRotationGracePeriod      = 5 * time.Minute
+	// RotationAnnounceWindow is how long after signing a rotation
+	// announcement still validates, and so how long nodes keep flooding it.
+	RotationAnnounceWindow = time.Hour
+	// rotationHistoryLimit bounds the rotations remembered on disk for
+	// resolving an old --secret to the current one.
+	rotationHistoryLimit = 8
+
+	EventRotationStarted   = "rotation_started"
+	EventRotationCompleted = "rotation_completed"
+)
+
+// ErrSecretRotated is returned by RunWithDHTDiscovery when the daemon stopped
+// at the end of a rotation grace period. Starting again with the old secret
+// picks up the new one from the rotation history (see NewConfig).
+var ErrSecretRotated = errors.New("mesh secret rotated")
+
+// stateDir holds per-interface daemon state such as the rotation history
+// and identity pins.
+var stateDir = "/var/lib/wgmesh"
+
+// StateDir returns the directory daemon state is kept in.
+func StateDir() string {
+	return stateDir
+}
+
+// SetStateDir moves daemon state to dir, for programs that cannot write
+// /var/lib/wgmesh. It must be called before any daemon starts.
+func SetStateDir(dir string) {
+	stateDir = dir
+}
+
+// RotationStatePath returns the rotation history file for an interface.
+func RotationStatePath(ifaceName string) string {
+	return filepath.Join(stateDir, fmt.Sprintf("%s-rotation.json", ifaceName))
+}
+
+// RotationParticipant is implemented by discovery layers that can carry
+// rotation messages and run a second instance under the new secret while a
+// rotation is in its grace period.
+type RotationParticipant interface {
+	SetRotationHandler(handler func(msg *crypto.RotationMessage))
+	BroadcastRotation(msg *crypto.RotationMessage)
+	StartDualSecret(config *Config, localNode *LocalNode, peerStore *PeerStore) error
+}
+
+// RPCRotationData describes a started rotation for RPC (matches rpc.RotationData)
+type RPCRotationData struct {
+	NewSecretURI string
+	SwitchAt     time.Time
+}
+
+// secretRotation is an accepted rotation waiting for its switch time.
+type secretRotation struct {
+	msg           *crypto.RotationMessage // nil when resumed from disk
+	state         *crypto.RotationState
+	config        *Config    // configuration under the new secret
+	peers         *PeerStore // peers discovered under the new secret
+	lastBroadcast time.Time
+}
+
+func (r *secretRotation) switchAt() time.Time {
+	return r.state.StartedAt.Add(r.state.GracePeriod)
+}
+
+// rotationState guards the daemon's rotation. The zero value is ready to use.
+type rotationState struct {
+	mu      sync.Mutex
+	current *secretRotation
+	rotated bool // grace period ended; the daemon is stopping to switch
+}
+
+// RotateSecret starts a mesh-wide rotation to newSecret (generated when
+// empty): the signed announcement is flooded to peers, every node runs under
+// both secrets until the grace period (default 24h) ends, then all switch
+// together. Active peers known not to support rotation would be cut off,
+// so it refuses while there are any unless force is set.
+func (d *Daemon) RotateSecret(newSecret string, grace time.Duration, force bool) (*RPCRotationData, error) {
+	participant, ok := d.dhtDiscovery.(RotationParticipant)
+	if !ok {
+		return nil, fmt.Errorf("secret rotation requires DHT or DNS discovery")
+	}
+	if lacking := d.activePeersLacking(crypto.CapRotate); len(lacking) > 0 && !force {
+		return nil, fmt.Errorf("peers %s do not support secret rotation and would be cut off; upgrade them or force the rotation", strings.Join(lacking, ", "))
+	}
+	if grace == 0 {
+		grace = DefaultRotationGracePeriod
+	}
+	if grace < MinRotationGracePeriod {
+		return nil, fmt.Errorf("grace period must be at least %v", MinRotationGracePeriod)
+	}
+
+	newSecret, _, err := ParseSecretURI(newSecret)
+	if err != nil {
+		return nil, err
+	}
+	if newSecret == "" {
+		generated, err := GenerateSecret()
+		if err != nil {
+			return nil, err
+		}
+		newSecret = generated
+	}
+	if newSecret == d.config.Secret {
+		return nil, fmt.Errorf("new secret is the current secret")
+	}
+
+	msg, err := crypto.NewRotationMessage(d.config.Keys.MembershipKey[:], newSecret, grace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rotation announcement: %w", err)
+	}
+	rot, err := d.beginRotation(msg)
+	if err != nil {
+		return nil, err
+	}
+	participant.BroadcastRotation(msg)
+
+	return &RPCRotationData{
+		NewSecretURI: FormatSecretURI(newSecret),
+		SwitchAt:     rot.switchAt(),
+	}, nil
+}
+
+// handleRotationMessage accepts a rotation received from a peer and floods
+// it onwards the first time it is seen.
+func (d *Daemon) handleRotationMessage(msg *crypto.RotationMessage) {
+	if err := msg.Verify(d.config.Keys.MembershipKey[:]); err != nil {
+		log.Printf("[Rotation] Rejected rotation announcement: %v", err)
+		return
+	}
+
+	d.rotation.mu.Lock()
+	cur := d.rotation.current
+	d.rotation.mu.Unlock()
+	if cur != nil {
+		if cur.state.NewSecret != msg.NewSecret {
+			log.Printf("[Rotation] Ignoring a second rotation while one is in progress (switching at %s)", cur.switchAt().Format(time.RFC3339))
+		}
+		return
+	}
+
+	if _, err := d.beginRotation(msg); err != nil {
+		log.Printf("[Rotation] Failed to start rotation: %v", err)
+		return
+	}
+	if participant, ok := d.dhtDiscovery.(RotationParticipant); ok {
+		participant.BroadcastRotation(freshRotationMessage(msg))
+	}
+}
+
+// beginRotation records the rotation and starts discovery under the new
+// secret alongside the current one.
+func (d *Daemon) beginRotation(msg *crypto.RotationMessage) (*secretRotation, error) {
+	state := &crypto.RotationState{
+		OldSecret:   d.config.Secret,
+		NewSecret:   msg.NewSecret,
+		GracePeriod: time.Duration(msg.Announcement.GracePeriod) * time.Second,
+		StartedAt:   time.Unix(msg.Announcement.Timestamp, 0),
+	}
+	rot, err := d.newSecretRotation(state)
+	if err != nil {
+		return nil, err
+	}
+	rot.msg = msg
+	rot.lastBroadcast = time.Now()
+
+	d.rotation.mu.Lock()
+	if cur := d.rotation.current; cur != nil {
+		d.rotation.mu.Unlock()
+		return nil, fmt.Errorf("rotation already in progress (switching at %s)", cur.switchAt().Format(time.RFC3339))
+	}
+	d.rotation.current = rot
+	d.rotation.mu.Unlock()
+
+	if err := saveRotationState(d.config.InterfaceName, state); err != nil {
+		log.Printf("[Rotation] Failed to persist rotation state: %v", err)
+	}
+	// Starting discovery involves STUN and DHT bootstrap; don't hold up the
+	// RPC caller or the exchange listener.
+	go d.startDualSecret(rot)
+
+	log.Printf("[Rotation] Secret rotation started; switching at %s", rot.switchAt().Format(time.RFC3339))
+	d.recordEvent(EventRotationStarted, "", map[string]string{
+		"switch_at":  rot.switchAt().Format(time.RFC3339),
+		"network_id": fmt.Sprintf("%x", rot.config.Keys.NetworkID[:8]),
+	})
+	return rot, nil
+}
+
+// resumeRotation picks up a rotation that was in its grace period when the
+// daemon last stopped.
+func (d *Daemon) resumeRotation() {
+	state := pendingRotation(d.config.InterfaceName, d.config.Secret)
+	if state == nil || state.ShouldComplete() {
+		return
+	}
+	rot, err := d.newSecretRotation(state)
+	if err != nil {
+		log.Printf("[Rotation] Failed to resume rotation: %v", err)
+		return
+	}
+	d.rotation.mu.Lock()
+	d.rotation.current = rot
+	d.rotation.mu.Unlock()
+	d.startDualSecret(rot)
+	log.Printf("[Rotation] Resumed secret rotation; switching at %s", rot.switchAt().Format(time.RFC3339))
+}
+
+func (d *Daemon) newSecretRotation(state *crypto.RotationState) (*secretRotation, error) {
+	cfg, err := rotatedConfig(d.config, state.NewSecret)
+	if err != nil {
+		return nil, err
+	}
+	return &secretRotation{state: state, config: cfg, peers: NewPeerStore()}, nil
+}
+
+func (d *Daemon) startDualSecret(rot *secretRotation) {
+	participant, ok := d.dhtDiscovery.(RotationParticipant)
+	if !ok {
+		return
+	}
+	node, err := rotatedLocalNode(rot.config, d.localNode)
+	if err != nil {
+		log.Printf("[Rotation] Failed to derive identity under the new secret: %v", err)
+		return
+	}
+	if err := participant.StartDualSecret(rot.config, node, rot.peers); err != nil {
+		log.Printf("[Rotation] Failed to start discovery under the new secret: %v", err)
+	}
+}
+
+// rotatedConfig returns a copy of cfg running under secret. The copy does not
+// run its own STUN responder; the port belongs to the current instance.
+func rotatedConfig(cfg *Config, secret string) (*Config, error) {
+	keys, err := crypto.DeriveKeys(secret)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive keys for the new secret: %w", err)
+	}
+	next := *cfg
+	next.Secret = secret
+	next.Keys = keys
+	cfg.SecretOptions.applyKeys(keys)
+	next.envelopeKeys = NewEnvelopeKeys(keys.GossipKey, time.Now())
+	next.STUNListenPort = 0
+	return &next, nil
+}
+
+// rotatedLocalNode mirrors initLocalNode for the new secret: same WireGuard
+// identity, mesh addresses in the new subnet unless the current ones still fit.
+func rotatedLocalNode(cfg *Config, cur *LocalNode) (*LocalNode, error) {
+	node := &LocalNode{
+		WGPubKey:         cur.WGPubKey,
+		WGPrivateKey:     cur.WGPrivateKey,
+		MeshIP:           cur.MeshIP,
+		RoutableNetworks: cur.RoutableNetworks,
+		Introducer:       cur.Introducer,
+		NATType:          cur.NATType,
+		Hostname:         cur.Hostname,
+		Tags:             cur.Tags,
+		DNS:              cur.DNS,
+	}
+	node.SetEndpoint(cur.GetEndpoint())
+	if !meshIPInSubnet(node.MeshIP, cfg) {
+		if cfg.CustomSubnet != nil {
+			ip, err := crypto.DeriveMeshIPInSubnet(cfg.CustomSubnet, node.WGPubKey, cfg.Secret)
+			if err != nil {
+				return nil, err
+			}
+			node.MeshIP = ip
+		} else {
+			node.MeshIP = crypto.DeriveMeshIP(cfg.Keys.MeshSubnet, node.WGPubKey, cfg.Secret)
+		}
+	}
+	node.MeshIPv6 = crypto.DeriveMeshIPv6(cfg.Keys.MeshPrefixV6, node.WGPubKey, cfg.Secret)
+	return node, nil
+}
+
+// freshRotationMessage copies msg with a new per-hop timestamp so it passes
+// envelope replay checks; the signed announcement is unchanged.
+func freshRotationMessage(msg *crypto.RotationMessage) *crypto.RotationMessage {
+	next := *msg
+	next.Timestamp = time.Now().Unix()
+	return &next
+}
+
+func (d *Daemon) rotationLoop() {
+	ticker := time.NewTicker(RotationCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case now := <-ticker.C:
+			d.checkRotation(now)
+		}
+	}
+}
+
+// checkRotation re-floods a fresh announcement for peers that missed it and
+// stops the daemon once the grace period is over.
+func (d *Daemon) checkRotation(now time.Time) {
+	d.rotation.mu.Lock()
+	rot := d.rotation.current
+	if rot == nil || d.rotation.rotated {
+		d.rotation.mu.Unlock()
+		return
+	}
+	if !now.Before(rot.switchAt()) {
+		d.rotation.rotated = true
+		d.rotation.mu.Unlock()
+		d.completeRotation(rot)
+		return
+	}
+	var rebroadcast *crypto.RotationMessage
+	if rot.msg != nil && now.Sub(rot.state.StartedAt) < RotationAnnounceWindow && now.Sub(rot.lastBroadcast) >= RotationRebroadcastInterval {
+		rot.lastBroadcast = now
+		rebroadcast = freshRotationMessage(rot.msg)
+	}
+	d.rotation.mu.Unlock()
+
+	if rebroadcast != nil {
+		if participant, ok := d.dhtDiscovery.(RotationParticipant); ok {
+			participant.BroadcastRotation(rebroadcast)
+		}
+	}
+}
+
+func (d *Daemon) completeRotation(rot *secretRotation) {
+	rot.state.Completed = true
+	if err := saveRotationState(d.config.InterfaceName, rot.state); err != nil {
+		log.Printf("[Rotation] Failed to persist completed rotation: %v", err)
+	}
+	log.Printf("[Rotation] Grace period over, restarting under the new secret")
+	d.recordEvent(EventRotationCompleted, "", map[string]string{
+		"network_id": fmt.Sprintf("%x", rot.config.Keys.NetworkID[:8]),
+	})
+	d.cancel()
+}
+
+// finishRotation runs after shutdown: if the daemon stopped to switch
+// secrets, the peers found under the new secret replace the peer cache so
+// the restarted daemon reconnects immediately.
+func (d *Daemon) finishRotation() error {
+	d.rotation.mu.Lock()
+	rot, rotated := d.rotation.current, d.rotation.rotated
+	d.rotation.mu.Unlock()
+	if !rotated || rot == nil {
+		return nil
+	}
+	if err := SavePeerCache(d.config.InterfaceName, rot.peers); err != nil {
+		log.Printf("[Rotation] Failed to save peer cache for the new secret: %v", err)
+	}
+	return ErrSecretRotated
+}
+
+// loadRotationHistory reads the rotation history, oldest first.
+func loadRotationHistory(ifaceName string) ([]*crypto.RotationState, error) {
+	data, err := os.ReadFile(RotationStatePath(ifaceName))
+	if err != nil {
+		return nil, err
+	}
+	var history []*crypto.RotationState
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// saveRotationState adds or updates state in the rotation history. The file
+// holds secrets, so it is only readable by its owner.
+func saveRotationState(ifaceName string, state *crypto.RotationState) error {
+	history, _ := loadRotationHistory(ifaceName)
+	replaced := false
+	for i, h := range history {
+		if h.OldSecret == state.OldSecret && h.NewSecret == state.NewSecret {
+			history[i] = state
+			replaced = true
+		}
+	}
+	if !replaced {
+		history = append(history, state)
+	}
+	if len(history) > rotationHistoryLimit {
+		history = history[len(history)-rotationHistoryLimit:]
+	}
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(RotationStatePath(ifaceName), data, 0600)
+}
+
+// pendingRotation returns the unfinished rotation away from secret, if any.
+func pendingRotation(ifaceName, secret string) *crypto.RotationState {
+	history, err := loadRotationHistory(ifaceName)
+	if err != nil {
+		return nil
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].OldSecret == secret && !history[i].Completed {
+			return history[i]
+		}
+	}
+	return nil
+}
+
+// rotatedSecret follows finished rotations (including ones whose grace
+// period ended while the daemon was down) from secret to the newest secret.
+// It returns "" when secret has not been rotated.
+func rotatedSecret(ifaceName, secret string) string {
+	history, err := loadRotationHistory(ifaceName)
+	if err != nil {
+		return ""
+	}
+	current := secret
+	for hops := 0; hops < len(history); hops++ {
+		next := ""
+		for _, h := range history {
+			if h.OldSecret == current && (h.Completed || h.ShouldComplete()) {
+				next = h.NewSecret
+			}
+		}
+		if next == "" {
+			break
+		}
+		current = next
+	}
+	if current == secret {
+		return ""
+	}
+	return current
+}
@@ -0,0 +1,223 @@
+package daemon
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+const (
+	rotationTestOld  = "rotation-test-secret-aaaaaaaaaaaaaaaa"
+	rotationTestNew  = "rotation-test-secret-bbbbbbbbbbbbbbbb"
+	rotationTestNext = "rotation-test-secret-cccccccccccccccc"
+)
+
+func useTempStateDir(t *testing.T) {
+	t.Helper()
+	prev := stateDir
+	stateDir = t.TempDir()
+	t.Cleanup(func() { stateDir = prev })
+}
+
+// fakeRotationDiscovery records broadcasts and dual-secret starts.
+type fakeRotationDiscovery struct {
+	mu         sync.Mutex
+	broadcasts []*crypto.RotationMessage
+	dualPeers  *PeerStore
+}
+
+func (f *fakeRotationDiscovery) Start() error { return nil }
+func (f *fakeRotationDiscovery) Stop() error  { return nil }
+
+func (f *fakeRotationDiscovery) SetRotationHandler(func(*crypto.RotationMessage)) {}
+
+func (f *fakeRotationDiscovery) BroadcastRotation(msg *crypto.RotationMessage) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.broadcasts = append(f.broadcasts, msg)
+}
+
+func (f *fakeRotationDiscovery) StartDualSecret(_ *Config, _ *LocalNode, peerStore *PeerStore) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dualPeers = peerStore
+	return nil
+}
+
+func (f *fakeRotationDiscovery) broadcastCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.broadcasts)
+}
+
+func newRotationTestDaemon(t *testing.T, discovery DiscoveryLayer) *Daemon {
+	t.Helper()
+	keys, err := crypto.DeriveKeys(rotationTestOld)
+	if err != nil {
+		t.Fatalf("DeriveKeys: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &Daemon{
+		config:       &Config{Secret: rotationTestOld, Keys: keys, InterfaceName: "wgtest0"},
+		localNode:    &LocalNode{WGPubKey: "local-pubkey", MeshIP: "10.0.0.1"},
+		peerStore:    NewPeerStore(),
+		dhtDiscovery: discovery,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+func TestRotatedSecretFollowsHistory(t *testing.T) {
+	useTempStateDir(t)
+	iface := "wgtest0"
+
+	if got := rotatedSecret(iface, rotationTestOld); got != "" {
+		t.Fatalf("no history: got %q, want empty", got)
+	}
+
+	// Still in its grace period: not rotated yet, but pending.
+	inGrace := &crypto.RotationState{OldSecret: rotationTestOld, NewSecret: rotationTestNew, GracePeriod: time.Hour, StartedAt: time.Now()}
+	if err := saveRotationState(iface, inGrace); err != nil {
+		t.Fatalf("saveRotationState: %v", err)
+	}
+	if got := rotatedSecret(iface, rotationTestOld); got != "" {
+		t.Errorf("in grace period: got %q, want empty", got)
+	}
+	if p := pendingRotation(iface, rotationTestOld); p == nil || p.NewSecret != rotationTestNew {
+		t.Errorf("pendingRotation = %+v, want rotation to new secret", p)
+	}
+
+	// Grace period elapsed while the daemon was down.
+	inGrace.StartedAt = time.Now().Add(-2 * time.Hour)
+	if err := saveRotationState(iface, inGrace); err != nil {
+		t.Fatalf("saveRotationState: %v", err)
+	}
+	if got := rotatedSecret(iface, rotationTestOld); got != rotationTestNew {
+		t.Errorf("expired grace: got %q, want new secret", got)
+	}
+
+	// A completed second rotation chains on.
+	next := &crypto.RotationState{OldSecret: rotationTestNew, NewSecret: rotationTestNext, GracePeriod: time.Hour, StartedAt: time.Now(), Completed: true}
+	if err := saveRotationState(iface, next); err != nil {
+		t.Fatalf("saveRotationState: %v", err)
+	}
+	if got := rotatedSecret(iface, rotationTestOld); got != rotationTestNext {
+		t.Errorf("chained: got %q, want %q", got, rotationTestNext)
+	}
+	if got := rotatedSecret(iface, rotationTestNext); got != "" {
+		t.Errorf("latest secret: got %q, want empty", got)
+	}
+	if p := pendingRotation(iface, rotationTestNew); p != nil {
+		t.Errorf("completed rotation reported as pending: %+v", p)
+	}
+
+	history, err := loadRotationHistory(iface)
+	if err != nil {
+		t.Fatalf("loadRotationHistory: %v", err)
+	}
+	if len(history) != 2 {
+		t.Errorf("history has %d entries, want 2 (updates replace)", len(history))
+	}
+}
+
+func TestRotateSecretBroadcastsAndStartsDualSecret(t *testing.T) {
+	useTempStateDir(t)
+	discovery := &fakeRotationDiscovery{}
+	d := newRotationTestDaemon(t, discovery)
+
+	if _, err := d.RotateSecret(rotationTestNew, time.Minute, false); err == nil {
+		t.Error("expected error for grace period below the minimum")
+	}
+
+	rotation, err := d.RotateSecret(rotationTestNew, time.Hour, false)
+	if err != nil {
+		t.Fatalf("RotateSecret: %v", err)
+	}
+	if rotation.NewSecretURI != FormatSecretURI(rotationTestNew) {
+		t.Errorf("NewSecretURI = %q", rotation.NewSecretURI)
+	}
+	if until := time.Until(rotation.SwitchAt); until < 59*time.Minute || until > time.Hour {
+		t.Errorf("SwitchAt %v is not about an hour away", rotation.SwitchAt)
+	}
+	if discovery.broadcastCount() != 1 {
+		t.Errorf("broadcasts = %d, want 1", discovery.broadcastCount())
+	}
+	if err := discovery.broadcasts[0].Verify(d.config.Keys.MembershipKey[:]); err != nil {
+		t.Errorf("broadcast rotation does not verify: %v", err)
+	}
+	if p := pendingRotation("wgtest0", rotationTestOld); p == nil {
+		t.Error("rotation was not persisted")
+	}
+
+	if _, err := d.RotateSecret(rotationTestNext, time.Hour, false); err == nil {
+		t.Error("expected error for a second concurrent rotation")
+	}
+}
+
+func TestHandleRotationMessage(t *testing.T) {
+	useTempStateDir(t)
+	discovery := &fakeRotationDiscovery{}
+	d := newRotationTestDaemon(t, discovery)
+
+	otherKeys, _ := crypto.DeriveKeys(rotationTestNext)
+	forged, _ := crypto.NewRotationMessage(otherKeys.MembershipKey[:], rotationTestNew, time.Hour)
+	d.handleRotationMessage(forged)
+	if d.rotation.current != nil || discovery.broadcastCount() != 0 {
+		t.Fatal("rotation signed with a foreign key was accepted")
+	}
+
+	msg, _ := crypto.NewRotationMessage(d.config.Keys.MembershipKey[:], rotationTestNew, time.Hour)
+	d.handleRotationMessage(msg)
+	if d.rotation.current == nil || d.rotation.current.state.NewSecret != rotationTestNew {
+		t.Fatal("valid rotation was not accepted")
+	}
+	if discovery.broadcastCount() != 1 {
+		t.Errorf("broadcasts = %d, want 1 (flooded onwards)", discovery.broadcastCount())
+	}
+
+	// Duplicates are not flooded again.
+	d.handleRotationMessage(freshRotationMessage(msg))
+	if discovery.broadcastCount() != 1 {
+		t.Errorf("broadcasts = %d after duplicate, want 1", discovery.broadcastCount())
+	}
+}
+
+func TestCheckRotation(t *testing.T) {
+	useTempStateDir(t)
+	discovery := &fakeRotationDiscovery{}
+	d := newRotationTestDaemon(t, discovery)
+
+	msg, _ := crypto.NewRotationMessage(d.config.Keys.MembershipKey[:], rotationTestNew, time.Hour)
+	rot, err := d.beginRotation(msg)
+	if err != nil {
+		t.Fatalf("beginRotation: %v", err)
+	}
+
+	// Within the announce window the rotation is re-flooded periodically.
+	d.checkRotation(rot.lastBroadcast.Add(RotationRebroadcastInterval))
+	if discovery.broadcastCount() != 1 {
+		t.Errorf("broadcasts = %d, want 1 rebroadcast", discovery.broadcastCount())
+	}
+	d.checkRotation(rot.lastBroadcast.Add(time.Second))
+	if discovery.broadcastCount() != 1 {
+		t.Errorf("broadcasts = %d, want no rebroadcast before the interval", discovery.broadcastCount())
+	}
+	if err := d.finishRotation(); err != nil {
+		t.Errorf("finishRotation before switch = %v, want nil", err)
+	}
+
+	d.checkRotation(rot.switchAt())
+	if d.ctx.Err() == nil {
+		t.Error("daemon was not stopped at the switch time")
+	}
+	if !d.rotation.rotated {
+		t.Error("rotation not marked as rotated")
+	}
+	if got := rotatedSecret("wgtest0", rotationTestOld); got != rotationTestNew {
+		t.Errorf("rotatedSecret = %q, want new secret", got)
+	}
+}
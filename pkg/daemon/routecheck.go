@@ -0,0 +1,234 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+const (
+	// RouteCheckInterval is how often a gateway probes its --route-check
+	// targets. Peers see a change with the next announcement they get.
+	RouteCheckInterval = 10 * time.Second
+	// RouteCheckTimeout bounds each TCP connect to a target.
+	RouteCheckTimeout = 3 * time.Second
+	// RouteCheckFailLimit is how many checks in a row must fail before a
+	// network is announced unhealthy. One success makes it healthy again.
+	RouteCheckFailLimit = 2
+
+	EventRouteUnhealthy = "route_unhealthy"
+	EventRouteRecovered = "route_recovered"
+)
+
+// routeCheckState counts consecutive failures of this node's route checks.
+type routeCheckState struct {
+	mu       sync.Mutex
+	failures map[string]int // network -> failed checks in a row
+}
+
+// ParseRouteChecks parses a --route-check value such as
+// "192.168.1.0/24=192.168.1.10:443,10.1.0.0/16=10.1.0.5:22". Each network
+// must be one of routes, the advertised networks. An empty string gives
+// none.
+func ParseRouteChecks(s string, routes []string) ([]crypto.RouteCheck, error) {
+	advertised := make(map[string]bool, len(routes))
+	for _, r := range routes {
+		if _, ipNet, err := net.ParseCIDR(strings.TrimSpace(r)); err == nil {
+			advertised[ipNet.String()] = true
+		}
+	}
+
+	var checks []crypto.RouteCheck
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		network, target, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not NETWORK=IP:PORT", part)
+		}
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(network))
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", network, err)
+		}
+		rc := crypto.RouteCheck{Network: ipNet.String(), Target: strings.TrimSpace(target), Healthy: true}
+		if err := rc.Validate(); err != nil {
+			return nil, err
+		}
+		if !advertised[rc.Network] {
+			return nil, fmt.Errorf("network %s is not in --advertise-routes", rc.Network)
+		}
+		if seen[rc.Network] {
+			return nil, fmt.Errorf("network %s given twice", rc.Network)
+		}
+		seen[rc.Network] = true
+		checks = append(checks, rc)
+	}
+	if len(checks) > crypto.MaxRouteChecks {
+		return nil, fmt.Errorf("too many checks (%d, max %d)", len(checks), crypto.MaxRouteChecks)
+	}
+	return checks, nil
+}
+
+// routeCheckLoop probes the --route-check targets every RouteCheckInterval
+// and announces the results with the node's routes.
+func (d *Daemon) routeCheckLoop() {
+	if len(d.config.RouteChecks) == 0 {
+		return
+	}
+	ticker := time.NewTicker(RouteCheckInterval)
+	defer ticker.Stop()
+	for {
+		d.runRouteChecks()
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runRouteChecks probes every target for a network the node still
+// advertises, all at once, and updates the checks it announces.
+func (d *Daemon) runRouteChecks() {
+	advertised := make(map[string]bool)
+	for _, r := range d.GetAdvertiseRoutes() {
+		if _, ipNet, err := net.ParseCIDR(strings.TrimSpace(r)); err == nil {
+			advertised[ipNet.String()] = true
+		}
+	}
+	var checks []crypto.RouteCheck
+	for _, rc := range d.config.RouteChecks {
+		if advertised[rc.Network] {
+			checks = append(checks, rc)
+		}
+	}
+
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, rc := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = probeRouteTarget(d.ctx, rc.Target)
+		}()
+	}
+	wg.Wait()
+	if d.ctx.Err() != nil {
+		return
+	}
+
+	d.routeCheck.mu.Lock()
+	if d.routeCheck.failures == nil {
+		d.routeCheck.failures = make(map[string]int)
+	}
+	var changed []int
+	for i := range checks {
+		network := checks[i].Network
+		prev := d.routeCheck.failures[network]
+		if errs[i] == nil {
+			delete(d.routeCheck.failures, network)
+		} else {
+			d.routeCheck.failures[network] = prev + 1
+		}
+		checks[i].Healthy = d.routeCheck.failures[network] < RouteCheckFailLimit
+		if checks[i].Healthy != (prev < RouteCheckFailLimit) {
+			changed = append(changed, i)
+		}
+	}
+	d.routeCheck.mu.Unlock()
+
+	d.localNode.setRouteChecks(checks)
+
+	for _, i := range changed {
+		rc := checks[i]
+		details := map[string]string{"network": rc.Network, "target": rc.Target}
+		if rc.Healthy {
+			log.Printf("[Routes] Route check for %s is passing again; peers will route it via this node", rc.Network)
+			d.recordEvent(EventRouteRecovered, d.localNode.WGPubKey, details)
+			continue
+		}
+		log.Printf("[Routes] Route check for %s failed %d times (%v); peers will withhold the route", rc.Network, RouteCheckFailLimit, errs[i])
+		details["error"] = errs[i].Error()
+		d.recordEvent(EventRouteUnhealthy, d.localNode.WGPubKey, details)
+	}
+}
+
+// advertisesNetwork reports whether networks holds cidr, in any spelling.
+func advertisesNetwork(networks []string, cidr string) bool {
+	for _, network := range networks {
+		if _, ipNet, err := net.ParseCIDR(strings.TrimSpace(network)); err == nil && ipNet.String() == cidr {
+			return true
+		}
+	}
+	return false
+}
+
+// probeRouteTarget opens a TCP connection to target and closes it again.
+func probeRouteTarget(ctx context.Context, target string) error {
+	dialer := net.Dialer{Timeout: RouteCheckTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// checkedRoutes splits the networks p advertises into those to route and
+// those withheld because p reports their route check failing.
+func checkedRoutes(p *PeerInfo) (routed, withheld []string) {
+	failing := make(map[string]bool)
+	for _, rc := range p.RouteChecks {
+		if rc.Healthy {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(rc.Network); err == nil {
+			failing[ipNet.String()] = true
+		}
+	}
+	if len(failing) == 0 {
+		return p.RoutableNetworks, nil
+	}
+	for _, network := range p.RoutableNetworks {
+		if _, ipNet, err := net.ParseCIDR(strings.TrimSpace(network)); err == nil && failing[ipNet.String()] {
+			withheld = append(withheld, ipNet.String())
+			continue
+		}
+		routed = append(routed, network)
+	}
+	return routed, withheld
+}
+
+// trackWithheldRoutes logs and records networks peers report unhealthy, and
+// their recovery, the first time reconcile sees the change. advertised maps
+// the peers still around to their networks; a network that is gone, or whose
+// peer left, does not count as recovered.
+func (d *Daemon) trackWithheldRoutes(advertised, prev, cur map[string][]string) {
+	for pubKey, networks := range cur {
+		for _, network := range networks {
+			if slices.Contains(prev[pubKey], network) {
+				continue
+			}
+			log.Printf("[Routes] Withholding %s via %s...: its route check is failing", network, shortKey(pubKey))
+			d.recordEvent(EventRouteUnhealthy, pubKey, map[string]string{"network": network})
+		}
+	}
+	for pubKey, networks := range prev {
+		for _, network := range networks {
+			if slices.Contains(cur[pubKey], network) || !advertisesNetwork(advertised[pubKey], network) {
+				continue
+			}
+			log.Printf("[Routes] Route check for %s via %s... is passing again", network, shortKey(pubKey))
+			d.recordEvent(EventRouteRecovered, pubKey, map[string]string{"network": network})
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+func TestParseRouteChecks(t *testing.T) {
+	routes := []string{"192.168.1.0/24", "10.1.0.0/16"}
+	checks, err := ParseRouteChecks(" 192.168.1.0/24=192.168.1.10:443, 10.1.2.3/16=10.1.0.5:22", routes)
+	if err != nil {
+		t.Fatalf("ParseRouteChecks: %v", err)
+	}
+	want := []crypto.RouteCheck{
+		{Network: "192.168.1.0/24", Target: "192.168.1.10:443", Healthy: true},
+		{Network: "10.1.0.0/16", Target: "10.1.0.5:22", Healthy: true},
+	}
+	if len(checks) != len(want) || checks[0] != want[0] || checks[1] != want[1] {
+		t.Errorf("checks = %+v, want %+v", checks, want)
+	}
+
+	for _, s := range []string{
+		"192.168.1.0/24",
+		"192.168.1.0/24=10.1.0.5:22",
+		"192.168.1.0/24=192.168.1.10",
+		"192.168.2.0/24=192.168.2.10:443",
+		"192.168.1.0/24=192.168.1.10:443,192.168.1.0/24=192.168.1.11:443",
+	} {
+		if _, err := ParseRouteChecks(s, routes); err == nil {
+			t.Errorf("ParseRouteChecks(%q) succeeded", s)
+		}
+	}
+}
+
+func TestResolveRoutesWithholdsFailingRouteCheck(t *testing.T) {
+	peers := []*PeerInfo{
+		{WGPubKey: "gw-a", RoutableNetworks: []string{"192.168.1.0/24", "192.168.2.0/24"}, RouteChecks: []crypto.RouteCheck{
+			{Network: "192.168.1.0/24", Target: "192.168.1.10:443", Healthy: false},
+			{Network: "192.168.2.0/24", Target: "192.168.2.10:443", Healthy: true},
+		}},
+		{WGPubKey: "gw-b", RoutableNetworks: []string{"192.168.1.0/24"}},
+	}
+	res := resolveRoutes(peers, "local", nil, nil)
+
+	if got := res.accepted["gw-b"]; len(got) != 1 || got[0] != "192.168.1.0/24" {
+		t.Errorf("gw-b accepted %v, want the network gw-a withholds", got)
+	}
+	if got := res.accepted["gw-a"]; len(got) != 1 || got[0] != "192.168.2.0/24" {
+		t.Errorf("gw-a accepted %v, want only its healthy network", got)
+	}
+	if got := res.withheld["gw-a"]; len(got) != 1 || got[0] != "192.168.1.0/24" {
+		t.Errorf("withheld %v", res.withheld)
+	}
+}
+
+// TestForgedRouteCheckIgnored verifies that a mesh member relaying a
+// gateway's announcement cannot mark its routes down: the edit breaks the
+// gateway's signature, and claims not signed by the gateway do not change
+// what is known about it.
+func TestForgedRouteCheckIgnored(t *testing.T) {
+	key, err := crypto.GenerateIdentityKey()
+	if err != nil {
+		t.Fatalf("GenerateIdentityKey: %v", err)
+	}
+	healthy := []crypto.RouteCheck{{Network: "192.168.1.0/24", Target: "192.168.1.10:443", Healthy: true}}
+	failing := []crypto.RouteCheck{{Network: "192.168.1.0/24", Target: "192.168.1.10:443", Healthy: false}}
+
+	announcement := &crypto.PeerAnnouncement{
+		WGPubKey:         "gw-a",
+		MeshIP:           "10.42.0.1",
+		RoutableNetworks: []string{"192.168.1.0/24"},
+		RouteChecks:      healthy,
+	}
+	if err := announcement.Sign(key); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	data, err := json.Marshal(announcement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forgedData := bytes.Replace(data, []byte(`"healthy":true`), []byte(`"healthy":false`), 1)
+	if bytes.Equal(forgedData, data) {
+		t.Fatalf("announcement %s has no route check to forge", data)
+	}
+	var forged crypto.PeerAnnouncement
+	if err := json.Unmarshal(forgedData, &forged); err != nil {
+		t.Fatal(err)
+	}
+	if err := forged.VerifySignature(); err == nil {
+		t.Fatal("an announcement with a forged route check failure verified")
+	}
+
+	store := NewPeerStore()
+	store.Update(&PeerInfo{
+		WGPubKey:         "gw-a",
+		MeshIP:           "10.42.0.1",
+		RoutableNetworks: []string{"192.168.1.0/24"},
+		RouteChecks:      healthy,
+		RoutesAnnounced:  true,
+		Identity:         crypto.IdentityPublicKey(key),
+	}, "dht")
+	store.Update(&PeerInfo{
+		WGPubKey:         "gw-a",
+		MeshIP:           "10.42.0.1",
+		RoutableNetworks: []string{"192.168.1.0/24"},
+		RouteChecks:      failing,
+		RoutesAnnounced:  true,
+	}, "dht")
+	gw, _ := store.Get("gw-a")
+	res := resolveRoutes([]*PeerInfo{gw}, "local", nil, nil)
+	if got := res.accepted["gw-a"]; len(got) != 1 || len(res.withheld["gw-a"]) != 0 {
+		t.Errorf("accepted %v, withheld %v after an unsigned route check failure", got, res.withheld)
+	}
+}
+
+func TestTrackRoutesRecordsRouteChecks(t *testing.T) {
+	d := &Daemon{
+		config:    &Config{},
+		localNode: &LocalNode{WGPubKey: "local"},
+	}
+	peers := []*PeerInfo{{
+		WGPubKey:         "gw-a",
+		MeshIP:           "10.250.0.2",
+		RoutableNetworks: []string{"192.168.1.0/24"},
+		RouteChecks:      []crypto.RouteCheck{{Network: "192.168.1.0/24", Target: "192.168.1.10:443"}},
+	}}
+
+	d.trackRoutes(peers, d.resolvePeerRoutes(peers))
+	d.trackRoutes(peers, d.resolvePeerRoutes(peers))
+	if got := d.acceptedRoutes("gw-a"); len(got) != 0 {
+		t.Errorf("failing network still routed: %v", got)
+	}
+	events := d.GetRPCEvents(0)
+	if len(events) != 1 || events[0].Type != EventRouteUnhealthy || events[0].Details["network"] != "192.168.1.0/24" {
+		t.Fatalf("expected one route_unhealthy event, got %+v", events)
+	}
+
+	peers[0].RouteChecks[0].Healthy = true
+	d.trackRoutes(peers, d.resolvePeerRoutes(peers))
+	if got := d.acceptedRoutes("gw-a"); len(got) != 1 {
+		t.Errorf("recovered network not routed again: %v", got)
+	}
+	events = d.GetRPCEvents(events[0].Seq)
+	if len(events) != 1 || events[0].Type != EventRouteRecovered || events[0].PubKey != "gw-a" {
+		t.Errorf("expected one route_recovered event, got %+v", events)
+	}
+}
+
+func TestRunRouteChecks(t *testing.T) {
+	up, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	defer up.Close()
+	down, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	downAddr := down.Addr().String()
+	down.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d := &Daemon{
+		ctx:       ctx,
+		localNode: &LocalNode{WGPubKey: "local"},
+		config: &Config{
+			AdvertiseRoutes: []string{"127.0.0.0/8", "127.0.0.0/16"},
+			RouteChecks: []crypto.RouteCheck{
+				{Network: "127.0.0.0/8", Target: up.Addr().String()},
+				{Network: "127.0.0.0/16", Target: downAddr},
+			},
+		},
+	}
+
+	announced := func() []crypto.RouteCheck {
+		var ann crypto.PeerAnnouncement
+		d.localNode.AnnounceRelayState(&ann)
+		return ann.RouteChecks
+	}
+	for i := 1; i <= RouteCheckFailLimit; i++ {
+		d.runRouteChecks()
+		checks := announced()
+		if len(checks) != 2 || !checks[0].Healthy {
+			t.Fatalf("round %d: checks = %+v", i, checks)
+		}
+		if checks[1].Healthy != (i < RouteCheckFailLimit) {
+			t.Errorf("round %d: unreachable target healthy = %v", i, checks[1].Healthy)
+		}
+	}
+	events := d.GetRPCEvents(0)
+	if len(events) != 1 || events[0].Type != EventRouteUnhealthy || events[0].Details["target"] != downAddr {
+		t.Errorf("expected one route_unhealthy event, got %+v", events)
+	}
+
+	// A network no longer advertised is no longer checked.
+	d.config.AdvertiseRoutes = []string{"127.0.0.0/8"}
+	d.runRouteChecks()
+	if checks := announced(); len(checks) != 1 || checks[0].Network != "127.0.0.0/8" {
+		t.Errorf("checks after withdrawing 127.0.0.0/16 = %+v", checks)
+	}
+}
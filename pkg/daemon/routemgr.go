@@ -0,0 +1,396 @@
+package daemon
+
+import (
+	"log"
+	"net"
+	"sort"
+	"strings"
+)
+
+const (
+	// RouteFailoverFailLimit is how many consecutive mesh probes a gateway
+	// sharing a network with other gateways may miss before the network
+	// fails over. Such gateways are probed every MeshProbeInterval.
+	RouteFailoverFailLimit = 2
+
+	EventRouteConflict  = "route_conflict"
+	EventRouteWithdrawn = "route_withdrawn"
+	EventRouteFailover  = "route_failover"
+)
+
+// RPCRouteData represents an installed mesh route for RPC (matches rpc.RouteData)
+type RPCRouteData struct {
+	Network string
+	PubKey  string   // peer the route is sent to
+	Standby []string // other gateways advertising the same network
+}
+
+// RPCRouteConflictData represents two overlapping route advertisements for
+// RPC (matches rpc.RouteConflictData)
+type RPCRouteConflictData struct {
+	WinnerNetwork string
+	WinnerPubKey  string
+	LoserNetwork  string
+	LoserPubKey   string
+	Dropped       bool // the loser's network is not installed at all
+}
+
+// routeResolution is the outcome of resolveRoutes.
+type routeResolution struct {
+	accepted  map[string][]string // pubkey -> networks routed via that peer
+	gateways  map[string][]string // network -> every peer advertising it, active first
+	withheld  map[string][]string // pubkey -> networks left out for a failing route check
+	conflicts []RPCRouteConflictData
+}
+
+type routeClaim struct {
+	network  *net.IPNet
+	cidr     string
+	pubKey   string
+	local    bool
+	down     bool
+	dropped  bool
+	shadowed bool // overlaps a local network
+}
+
+// resolveRoutes decides which peer carries each advertised network.
+//
+// Several peers advertising the same prefix form a failover group: WireGuard
+// can only bind a prefix to one peer, so the first gateway not in down (then
+// the lowest pubkey) carries it and the rest stand by. Other overlaps are
+// conflicts; nested prefixes are both installed and the more specific one
+// wins the overlap through longest-prefix matching in WireGuard and the
+// kernel.
+//
+// Networks this node advertises itself always win locally: any peer network
+// overlapping one of them is dropped, so local subnets never get routed into
+// the mesh. Networks whose route check the peer reports failing are left
+// out altogether, so another gateway can take them. Unparseable networks
+// are ignored.
+func resolveRoutes(peers []*PeerInfo, localKey string, localRoutes []string, down map[string]bool) routeResolution {
+	res := routeResolution{
+		accepted: make(map[string][]string),
+		gateways: make(map[string][]string),
+		withheld: make(map[string][]string),
+	}
+	var claims []*routeClaim
+	addClaims := func(pubKey string, networks []string, local bool) {
+		seen := make(map[string]struct{}, len(networks))
+		for _, network := range networks {
+			_, ipNet, err := net.ParseCIDR(strings.TrimSpace(network))
+			if err != nil {
+				continue
+			}
+			cidr := ipNet.String()
+			if _, dup := seen[cidr]; dup {
+				continue
+			}
+			seen[cidr] = struct{}{}
+			claims = append(claims, &routeClaim{network: ipNet, cidr: cidr, pubKey: pubKey, local: local, down: down[pubKey]})
+		}
+	}
+
+	addClaims(localKey, localRoutes, true)
+	for _, p := range peers {
+		if p == nil || p.WGPubKey == "" || p.WGPubKey == localKey {
+			continue
+		}
+		routed, withheld := checkedRoutes(p)
+		if len(withheld) > 0 {
+			res.withheld[p.WGPubKey] = withheld
+		}
+		addClaims(p.WGPubKey, routed, false)
+	}
+
+	sort.Slice(claims, func(i, j int) bool {
+		if claims[i].cidr != claims[j].cidr {
+			return claims[i].cidr < claims[j].cidr
+		}
+		return claimBefore(claims[i], claims[j])
+	})
+
+	for i, a := range claims {
+		for _, b := range claims[i+1:] {
+			if a.pubKey == b.pubKey || !networksOverlap(a.network, b.network) {
+				continue
+			}
+			winner, loser := routeWinner(a, b)
+			if winner.cidr == loser.cidr && !winner.local {
+				// Same prefix from two gateways; handled as a failover group.
+				loser.dropped = true
+				continue
+			}
+			if winner.local {
+				loser.dropped = true
+				loser.shadowed = true
+			}
+			res.conflicts = append(res.conflicts, RPCRouteConflictData{
+				WinnerNetwork: winner.cidr,
+				WinnerPubKey:  winner.pubKey,
+				LoserNetwork:  loser.cidr,
+				LoserPubKey:   loser.pubKey,
+				Dropped:       loser.dropped,
+			})
+		}
+	}
+
+	for _, c := range claims {
+		if c.local || c.shadowed {
+			continue
+		}
+		// Claims are sorted active-first within a prefix.
+		res.gateways[c.cidr] = append(res.gateways[c.cidr], c.pubKey)
+		if !c.dropped {
+			res.accepted[c.pubKey] = append(res.accepted[c.pubKey], c.cidr)
+		}
+	}
+	for cidr, gws := range res.gateways {
+		if len(gws) < 2 {
+			delete(res.gateways, cidr)
+		}
+	}
+	return res
+}
+
+// routeWinner orders two overlapping claims: the local node first, then the
+// longest prefix, then a gateway that is up, then the lowest pubkey.
+func routeWinner(a, b *routeClaim) (winner, loser *routeClaim) {
+	aOnes, _ := a.network.Mask.Size()
+	bOnes, _ := b.network.Mask.Size()
+	switch {
+	case a.local != b.local:
+		if a.local {
+			return a, b
+		}
+		return b, a
+	case aOnes != bOnes:
+		if aOnes > bOnes {
+			return a, b
+		}
+		return b, a
+	case claimBefore(a, b):
+		return a, b
+	default:
+		return b, a
+	}
+}
+
+func claimBefore(a, b *routeClaim) bool {
+	if a.down != b.down {
+		return !a.down
+	}
+	return a.pubKey < b.pubKey
+}
+
+func networksOverlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// resolvePeerRoutes runs resolveRoutes over the peers reconcile would
+// configure, so offline peers do not claim networks.
+func (d *Daemon) resolvePeerRoutes(peers []*PeerInfo) routeResolution {
+	eligible := make([]*PeerInfo, 0, len(peers))
+	for _, p := range peers {
+		if p == nil || p.MeshIP == "" || d.isTemporarilyOffline(p.WGPubKey) {
+			continue
+		}
+		eligible = append(eligible, p)
+	}
+	return resolveRoutes(eligible, d.localNode.WGPubKey, d.GetAdvertiseRoutes(), d.downGateways())
+}
+
+// trackRoutes logs and records route conflicts the first time they appear
+// and networks a peer stopped advertising, and keeps the latest resolution
+// for RPC.
+func (d *Daemon) trackRoutes(peers []*PeerInfo, res routeResolution) {
+	advertised := make(map[string][]string, len(peers))
+	for _, p := range peers {
+		if p != nil && p.WGPubKey != "" && p.WGPubKey != d.localNode.WGPubKey {
+			advertised[p.WGPubKey] = p.RoutableNetworks
+		}
+	}
+
+	active := make(map[string]string)
+	for pubKey, networks := range res.accepted {
+		for _, network := range networks {
+			active[network] = pubKey
+		}
+	}
+
+	d.routeMu.Lock()
+	prevAdvertised := d.advertisedRoutes
+	prevActive := d.routeActive
+	prevWithheld := d.routeWithheld
+	prevConflicts := make(map[RPCRouteConflictData]struct{}, len(d.routeConflicts))
+	for _, c := range d.routeConflicts {
+		prevConflicts[c] = struct{}{}
+	}
+	d.advertisedRoutes = advertised
+	d.routeConflicts = res.conflicts
+	d.routeAccepted = res.accepted
+	d.routeGateways = res.gateways
+	d.routeActive = active
+	d.routeWithheld = res.withheld
+	for pubKey := range d.gatewayFailures {
+		if !d.isFailoverGatewayLocked(pubKey) {
+			delete(d.gatewayFailures, pubKey)
+		}
+	}
+	d.routeMu.Unlock()
+
+	for network, gateway := range active {
+		prev, ok := prevActive[network]
+		if !ok || prev == gateway {
+			continue
+		}
+		log.Printf("[Routes] %s failed over from %s... to %s...", network, shortKey(prev), shortKey(gateway))
+		d.recordEvent(EventRouteFailover, gateway, map[string]string{
+			"network":      network,
+			"prev_gateway": prev,
+		})
+	}
+
+	for _, c := range res.conflicts {
+		if _, known := prevConflicts[c]; known {
+			continue
+		}
+		action := "more specific route wins the overlap"
+		if c.WinnerPubKey == d.localNode.WGPubKey {
+			action = "keeping local route"
+		} else if c.Dropped {
+			action = "ignoring " + shortKey(c.LoserPubKey) + "..."
+		}
+		log.Printf("[Routes] Conflict: %s from %s... overlaps %s from %s...; %s",
+			c.WinnerNetwork, shortKey(c.WinnerPubKey), c.LoserNetwork, shortKey(c.LoserPubKey), action)
+		details := map[string]string{
+			"network":       c.WinnerNetwork,
+			"loser_network": c.LoserNetwork,
+			"loser_pubkey":  c.LoserPubKey,
+			"loser_dropped": "false",
+		}
+		if c.Dropped {
+			details["loser_dropped"] = "true"
+		}
+		d.recordEvent(EventRouteConflict, c.WinnerPubKey, details)
+	}
+
+	for pubKey, networks := range advertised {
+		prev, ok := prevAdvertised[pubKey]
+		if !ok {
+			continue
+		}
+		for _, network := range prev {
+			if containsString(networks, network) {
+				continue
+			}
+			log.Printf("[Routes] Peer %s... withdrew %s", shortKey(pubKey), network)
+			d.recordEvent(EventRouteWithdrawn, pubKey, map[string]string{"network": network})
+		}
+	}
+
+	d.trackWithheldRoutes(advertised, prevWithheld, res.withheld)
+}
+
+// acceptedRoutes returns the networks the last reconcile routed via pubKey.
+func (d *Daemon) acceptedRoutes(pubKey string) []string {
+	d.routeMu.Lock()
+	defer d.routeMu.Unlock()
+	return append([]string(nil), d.routeAccepted[pubKey]...)
+}
+
+// GetRPCRoutes returns the routes installed via peers and any conflicts
+// found by the last reconcile.
+func (d *Daemon) GetRPCRoutes() ([]*RPCRouteData, []*RPCRouteConflictData) {
+	d.routeMu.Lock()
+	defer d.routeMu.Unlock()
+
+	routes := make([]*RPCRouteData, 0)
+	for pubKey, networks := range d.routeAccepted {
+		for _, network := range networks {
+			route := &RPCRouteData{Network: network, PubKey: pubKey}
+			for _, gw := range d.routeGateways[network] {
+				if gw != pubKey {
+					route.Standby = append(route.Standby, gw)
+				}
+			}
+			routes = append(routes, route)
+		}
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Network != routes[j].Network {
+			return routes[i].Network < routes[j].Network
+		}
+		return routes[i].PubKey < routes[j].PubKey
+	})
+
+	conflicts := make([]*RPCRouteConflictData, len(d.routeConflicts))
+	for i := range d.routeConflicts {
+		c := d.routeConflicts[i]
+		conflicts[i] = &c
+	}
+	return routes, conflicts
+}
+
+// isFailoverGatewayLocked reports whether pubKey advertises a network that other
+// gateways advertise too. Caller must hold routeMu.
+func (d *Daemon) isFailoverGatewayLocked(pubKey string) bool {
+	for _, gws := range d.routeGateways {
+		if containsString(gws, pubKey) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Daemon) isFailoverGateway(pubKey string) bool {
+	d.routeMu.Lock()
+	defer d.routeMu.Unlock()
+	return d.isFailoverGatewayLocked(pubKey)
+}
+
+// recordGatewayProbe feeds a mesh probe result for a failover gateway and
+// reports whether the gateway just went down or came back up.
+func (d *Daemon) recordGatewayProbe(pubKey string, ok bool) bool {
+	d.routeMu.Lock()
+	defer d.routeMu.Unlock()
+	if d.gatewayFailures == nil {
+		d.gatewayFailures = make(map[string]int)
+	}
+	prev := d.gatewayFailures[pubKey]
+	if ok {
+		delete(d.gatewayFailures, pubKey)
+		if prev >= RouteFailoverFailLimit {
+			log.Printf("[Routes] Gateway %s... is reachable again", shortKey(pubKey))
+			return true
+		}
+		return false
+	}
+	d.gatewayFailures[pubKey] = prev + 1
+	if prev+1 == RouteFailoverFailLimit {
+		log.Printf("[Routes] Gateway %s... missed %d probes, failing over its routes", shortKey(pubKey), prev+1)
+		return true
+	}
+	return false
+}
+
+// downGateways returns the failover gateways currently failing probes.
+func (d *Daemon) downGateways() map[string]bool {
+	d.routeMu.Lock()
+	defer d.routeMu.Unlock()
+	down := make(map[string]bool)
+	for pubKey, failures := range d.gatewayFailures {
+		if failures >= RouteFailoverFailLimit {
+			down[pubKey] = true
+		}
+	}
+	return down
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,229 @@
+package daemon
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResolveRoutes(t *testing.T) {
+	tests := []struct {
+		name          string
+		peers         []*PeerInfo
+		local         []string
+		down          map[string]bool
+		wantAccepted  map[string][]string
+		wantGateways  map[string][]string
+		wantConflicts []RPCRouteConflictData
+	}{
+		{
+			name: "disjoint networks",
+			peers: []*PeerInfo{
+				{WGPubKey: "peer-a", RoutableNetworks: []string{"192.168.1.0/24"}},
+				{WGPubKey: "peer-b", RoutableNetworks: []string{"192.168.2.0/24"}},
+			},
+			wantAccepted: map[string][]string{
+				"peer-a": {"192.168.1.0/24"},
+				"peer-b": {"192.168.2.0/24"},
+			},
+		},
+		{
+			name: "identical prefix goes to lowest pubkey",
+			peers: []*PeerInfo{
+				{WGPubKey: "peer-b", RoutableNetworks: []string{"10.1.0.0/16"}},
+				{WGPubKey: "peer-a", RoutableNetworks: []string{"10.1.0.0/16"}},
+			},
+			wantAccepted: map[string][]string{"peer-a": {"10.1.0.0/16"}},
+			wantGateways: map[string][]string{"10.1.0.0/16": {"peer-a", "peer-b"}},
+		},
+		{
+			name: "identical prefix fails over from down gateway",
+			peers: []*PeerInfo{
+				{WGPubKey: "peer-a", RoutableNetworks: []string{"10.1.0.0/16"}},
+				{WGPubKey: "peer-b", RoutableNetworks: []string{"10.1.0.0/16"}},
+				{WGPubKey: "peer-c", RoutableNetworks: []string{"10.1.0.0/16"}},
+			},
+			down:         map[string]bool{"peer-a": true},
+			wantAccepted: map[string][]string{"peer-b": {"10.1.0.0/16"}},
+			wantGateways: map[string][]string{"10.1.0.0/16": {"peer-b", "peer-c", "peer-a"}},
+		},
+		{
+			name: "all gateways down keeps lowest pubkey",
+			peers: []*PeerInfo{
+				{WGPubKey: "peer-b", RoutableNetworks: []string{"10.1.0.0/16"}},
+				{WGPubKey: "peer-a", RoutableNetworks: []string{"10.1.0.0/16"}},
+			},
+			down:         map[string]bool{"peer-a": true, "peer-b": true},
+			wantAccepted: map[string][]string{"peer-a": {"10.1.0.0/16"}},
+			wantGateways: map[string][]string{"10.1.0.0/16": {"peer-a", "peer-b"}},
+		},
+		{
+			name: "nested prefixes both kept, longest wins",
+			peers: []*PeerInfo{
+				{WGPubKey: "peer-a", RoutableNetworks: []string{"10.0.0.0/8"}},
+				{WGPubKey: "peer-b", RoutableNetworks: []string{"10.5.0.0/16"}},
+			},
+			wantAccepted: map[string][]string{
+				"peer-a": {"10.0.0.0/8"},
+				"peer-b": {"10.5.0.0/16"},
+			},
+			wantConflicts: []RPCRouteConflictData{{
+				WinnerNetwork: "10.5.0.0/16", WinnerPubKey: "peer-b",
+				LoserNetwork: "10.0.0.0/8", LoserPubKey: "peer-a",
+			}},
+		},
+		{
+			name: "local network always wins",
+			peers: []*PeerInfo{
+				{WGPubKey: "peer-a", RoutableNetworks: []string{"192.168.1.128/25", "172.16.0.0/12"}},
+			},
+			local:        []string{"192.168.1.0/24"},
+			wantAccepted: map[string][]string{"peer-a": {"172.16.0.0/12"}},
+			wantConflicts: []RPCRouteConflictData{{
+				WinnerNetwork: "192.168.1.0/24", WinnerPubKey: "local",
+				LoserNetwork: "192.168.1.128/25", LoserPubKey: "peer-a", Dropped: true,
+			}},
+		},
+		{
+			name: "host bits, duplicates and junk normalised",
+			peers: []*PeerInfo{
+				{WGPubKey: "peer-a", RoutableNetworks: []string{" 192.168.7.9/24", "192.168.7.0/24", "not-a-cidr", ""}},
+			},
+			wantAccepted: map[string][]string{"peer-a": {"192.168.7.0/24"}},
+		},
+		{
+			name: "IPv4 and IPv6 never conflict",
+			peers: []*PeerInfo{
+				{WGPubKey: "peer-a", RoutableNetworks: []string{"0.0.0.0/0"}},
+				{WGPubKey: "peer-b", RoutableNetworks: []string{"fd00::/8"}},
+			},
+			wantAccepted: map[string][]string{
+				"peer-a": {"0.0.0.0/0"},
+				"peer-b": {"fd00::/8"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := resolveRoutes(tt.peers, "local", tt.local, tt.down)
+			if !reflect.DeepEqual(res.accepted, tt.wantAccepted) {
+				t.Errorf("accepted = %v, want %v", res.accepted, tt.wantAccepted)
+			}
+			if len(res.gateways) != len(tt.wantGateways) || (len(tt.wantGateways) > 0 && !reflect.DeepEqual(res.gateways, tt.wantGateways)) {
+				t.Errorf("gateways = %v, want %v", res.gateways, tt.wantGateways)
+			}
+			if len(res.conflicts) != len(tt.wantConflicts) || (len(tt.wantConflicts) > 0 && !reflect.DeepEqual(res.conflicts, tt.wantConflicts)) {
+				t.Errorf("conflicts = %+v, want %+v", res.conflicts, tt.wantConflicts)
+			}
+		})
+	}
+}
+
+func TestBuildDesiredPeerConfigsSkipsConflictingRoute(t *testing.T) {
+	d := &Daemon{
+		config:    &Config{},
+		localNode: &LocalNode{WGPubKey: "local"},
+	}
+	now := time.Now()
+	peers := []*PeerInfo{
+		{WGPubKey: "peer-a", MeshIP: "10.250.0.2", Endpoint: "1.1.1.1:51820", LastSeen: now, RoutableNetworks: []string{"192.168.50.0/24"}},
+		{WGPubKey: "peer-b", MeshIP: "10.250.0.3", Endpoint: "2.2.2.2:51820", LastSeen: now, RoutableNetworks: []string{"192.168.50.0/24"}},
+	}
+
+	desired, _, _ := d.buildDesiredPeerConfigsWithHandshakes(peers, nil)
+	if _, ok := desired["peer-a"].allowed["192.168.50.0/24"]; !ok {
+		t.Error("winning peer should carry the network")
+	}
+	if _, ok := desired["peer-b"].allowed["192.168.50.0/24"]; ok {
+		t.Error("losing peer must not get the conflicting network")
+	}
+	if _, ok := desired["peer-b"].allowed["10.250.0.3/32"]; !ok {
+		t.Error("losing peer should keep its mesh IP")
+	}
+}
+
+func TestTrackRoutesRecordsConflictsAndWithdrawals(t *testing.T) {
+	d := &Daemon{
+		config:    &Config{},
+		localNode: &LocalNode{WGPubKey: "local"},
+	}
+	peers := []*PeerInfo{
+		{WGPubKey: "peer-a", MeshIP: "10.250.0.2", RoutableNetworks: []string{"192.168.0.0/16", "192.168.60.0/24"}},
+		{WGPubKey: "peer-b", MeshIP: "10.250.0.3", RoutableNetworks: []string{"192.168.50.0/24"}},
+	}
+
+	d.trackRoutes(peers, d.resolvePeerRoutes(peers))
+	d.trackRoutes(peers, d.resolvePeerRoutes(peers))
+
+	events := d.GetRPCEvents(0)
+	if len(events) != 1 || events[0].Type != EventRouteConflict {
+		t.Fatalf("expected one route_conflict event, got %+v", events)
+	}
+	if events[0].PubKey != "peer-b" || events[0].Details["loser_network"] != "192.168.0.0/16" {
+		t.Errorf("unexpected conflict %+v", events[0])
+	}
+
+	routes, conflicts := d.GetRPCRoutes()
+	if len(routes) != 3 || len(conflicts) != 1 {
+		t.Errorf("expected 3 routes and 1 conflict, got %d and %d", len(routes), len(conflicts))
+	}
+
+	peers[0].RoutableNetworks = []string{"192.168.0.0/16"}
+	d.trackRoutes(peers, d.resolvePeerRoutes(peers))
+
+	events = d.GetRPCEvents(events[0].Seq)
+	if len(events) != 1 || events[0].Type != EventRouteWithdrawn {
+		t.Fatalf("expected one route_withdrawn event, got %+v", events)
+	}
+	if events[0].PubKey != "peer-a" || events[0].Details["network"] != "192.168.60.0/24" {
+		t.Errorf("unexpected withdrawal %+v", events[0])
+	}
+}
+
+func TestRouteFailoverOnGatewayProbeFailures(t *testing.T) {
+	d := &Daemon{
+		config:    &Config{},
+		localNode: &LocalNode{WGPubKey: "local"},
+	}
+	peers := []*PeerInfo{
+		{WGPubKey: "gw-a", MeshIP: "10.250.0.2", RoutableNetworks: []string{"192.168.1.0/24"}},
+		{WGPubKey: "gw-b", MeshIP: "10.250.0.3", RoutableNetworks: []string{"192.168.1.0/24"}},
+	}
+	d.trackRoutes(peers, d.resolvePeerRoutes(peers))
+
+	if !d.isFailoverGateway("gw-a") || !d.isFailoverGateway("gw-b") {
+		t.Fatal("both gateways should be failover gateways")
+	}
+	routes, _ := d.GetRPCRoutes()
+	if len(routes) != 1 || routes[0].PubKey != "gw-a" || !reflect.DeepEqual(routes[0].Standby, []string{"gw-b"}) {
+		t.Fatalf("unexpected routes %+v", routes)
+	}
+
+	for i := 1; i <= RouteFailoverFailLimit; i++ {
+		changed := d.recordGatewayProbe("gw-a", false)
+		if changed != (i == RouteFailoverFailLimit) {
+			t.Fatalf("probe failure %d: changed = %v", i, changed)
+		}
+	}
+	d.trackRoutes(peers, d.resolvePeerRoutes(peers))
+
+	if got := d.acceptedRoutes("gw-b"); len(got) != 1 || got[0] != "192.168.1.0/24" {
+		t.Fatalf("expected gw-b to carry the route, got %v", got)
+	}
+	if got := d.acceptedRoutes("gw-a"); len(got) != 0 {
+		t.Errorf("down gateway still carries %v", got)
+	}
+	events := d.GetRPCEvents(0)
+	if len(events) != 1 || events[0].Type != EventRouteFailover || events[0].Details["prev_gateway"] != "gw-a" {
+		t.Fatalf("expected route_failover from gw-a, got %+v", events)
+	}
+
+	if !d.recordGatewayProbe("gw-a", true) {
+		t.Error("recovery should be reported")
+	}
+	d.trackRoutes(peers, d.resolvePeerRoutes(peers))
+	if got := d.acceptedRoutes("gw-a"); len(got) != 1 {
+		t.Errorf("expected route back on gw-a, got %v", got)
+	}
+}
@@ -4,18 +4,23 @@ import (
 	"fmt"
 	"log"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/atvirokodosprendimai/wgmesh/pkg/routes"
 )
 
 func (d *Daemon) syncPeerRoutes(peers []*PeerInfo) error {
-	if runtime.GOOS != "linux" {
+	// A Tunnel's stack hands every packet to WireGuard, whose allowed IPs
+	// do the routing.
+	if runtime.GOOS != "linux" || d.config.NoPeerRoutes || d.tunnel != nil {
 		return nil
 	}
 
 	desired := make([]routes.Entry, 0)
+	rt := d.routeTarget()
 	relayRoutes := d.currentRelayRoutesSnapshot()
+	peerRoutes := d.resolvePeerRoutes(peers).accepted
 	meshIPByPubKey := make(map[string]string, len(peers))
 	for _, p := range peers {
 		if p != nil && p.WGPubKey != "" && p.MeshIP != "" {
@@ -26,7 +31,7 @@ func (d *Daemon) syncPeerRoutes(peers []*PeerInfo) error {
 		if peer.WGPubKey == d.localNode.WGPubKey || peer.MeshIP == "" {
 			continue
 		}
-		if d.isTemporarilyOffline(peer.WGPubKey) {
+		if d.isTemporarilyOffline(peer.WGPubKey) || !d.isInstalled(peer.WGPubKey) {
 			continue
 		}
 		gateway := peer.MeshIP
@@ -35,22 +40,18 @@ func (d *Daemon) syncPeerRoutes(peers []*PeerInfo) error {
 				gateway = relayIP
 			}
 		}
-		for _, network := range peer.RoutableNetworks {
-			network = strings.TrimSpace(network)
-			if network == "" {
-				continue
-			}
-			desired = append(desired, routes.Entry{Network: network, Gateway: gateway})
+		for _, network := range peerRoutes[peer.WGPubKey] {
+			desired = append(desired, routes.Entry{Network: network, Gateway: gateway, Metric: rt.metric})
 		}
 	}
 
-	current, err := getCurrentRoutes(d.config.InterfaceName)
+	current, err := getCurrentRoutes(d.config.InterfaceName, rt)
 	if err != nil {
 		return err
 	}
 
 	toAdd, toRemove := routes.CalculateDiff(current, desired)
-	return applyRouteDiff(d.config.InterfaceName, toAdd, toRemove)
+	return applyRouteDiff(d.config.InterfaceName, rt, toAdd, toRemove)
 }
 
 func (d *Daemon) currentRelayRoutesSnapshot() map[string]string {
@@ -73,8 +74,13 @@ func (d *Daemon) directStableCyclesSnapshot() map[string]int {
 	return out
 }
 
-func getCurrentRoutes(iface string) ([]routes.Entry, error) {
-	cmd := cmdExecutor.Command("ip", "route", "show", "dev", iface)
+// getCurrentRoutes reads the gateway routes on iface in rt's table.
+func getCurrentRoutes(iface string, rt routeTarget) ([]routes.Entry, error) {
+	args := []string{"route", "show", "dev", iface}
+	if rt.table != 0 {
+		args = append(args, "table", strconv.Itoa(rt.table))
+	}
+	cmd := cmdExecutor.Command("ip", args...)
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to read routes: %w", err)
@@ -94,31 +100,40 @@ func getCurrentRoutes(iface string) ([]routes.Entry, error) {
 
 		network := routes.NormalizeNetwork(parts[0])
 		gateway := ""
+		metric := 0
 		for i, part := range parts {
-			if part == "via" && i+1 < len(parts) {
-				gateway = parts[i+1]
+			if i+1 >= len(parts) {
 				break
 			}
+			switch part {
+			case "via":
+				gateway = parts[i+1]
+			case "metric":
+				metric, _ = strconv.Atoi(parts[i+1])
+			}
 		}
 
 		if gateway == "" {
 			continue
 		}
 
-		result = append(result, routes.Entry{Network: network, Gateway: gateway})
+		result = append(result, routes.Entry{Network: network, Gateway: gateway, Metric: metric})
 	}
 
 	return result, nil
 }
 
-func applyRouteDiff(iface string, toAdd, toRemove []routes.Entry) error {
+func applyRouteDiff(iface string, rt routeTarget, toAdd, toRemove []routes.Entry) error {
 	for _, route := range toRemove {
-		cmd := cmdExecutor.Command("ip", "route", "del", route.Network, "via", route.Gateway, "dev", iface)
-		_ = cmd.Run()
+		args := []string{"route", "del", route.Network, "via", route.Gateway, "dev", iface}
+		args = append(args, routeTarget{table: rt.table, metric: route.Metric}.args()...)
+		_ = cmdExecutor.Command("ip", args...).Run()
 	}
 
 	for _, route := range toAdd {
-		cmd := cmdExecutor.Command("ip", "route", "replace", route.Network, "via", route.Gateway, "dev", iface)
+		args := []string{"route", "replace", route.Network, "via", route.Gateway, "dev", iface}
+		args = append(args, routeTarget{table: rt.table, metric: route.Metric}.args()...)
+		cmd := cmdExecutor.Command("ip", args...)
 		if output, err := cmd.CombinedOutput(); err != nil {
 			return fmt.Errorf("failed to add route %s via %s: %s: %w", route.Network, route.Gateway, string(output), err)
 		}
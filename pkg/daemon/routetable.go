@@ -0,0 +1,119 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+const (
+	// RouteRulePriority is the ip rule priority of the --route-table rule,
+	// ahead of the main table (32766).
+	RouteRulePriority = 5210
+
+	// Reserved kernel routing tables.
+	routeTableMain  = 254
+	routeTableLocal = 255
+)
+
+// routeTarget says where syncPeerRoutes installs routes to peer networks.
+// The zero value is the main table without a metric.
+type routeTarget struct {
+	table  int
+	metric int
+}
+
+func (d *Daemon) routeTarget() routeTarget {
+	return routeTarget{table: d.config.RouteTable, metric: d.config.RouteMetric}
+}
+
+// args returns the table and metric arguments for "ip route".
+func (rt routeTarget) args() []string {
+	var args []string
+	if rt.table != 0 {
+		args = append(args, "table", strconv.Itoa(rt.table))
+	}
+	if rt.metric != 0 {
+		args = append(args, "metric", strconv.Itoa(rt.metric))
+	}
+	return args
+}
+
+// validateRouteTable checks --route-table, --route-metric and
+// --route-fwmark and maps the main table to 0.
+func validateRouteTable(table, metric, fwmark int) (int, error) {
+	if table == routeTableMain {
+		table = 0
+	}
+	if table < 0 || table == routeTableLocal || int64(table) > math.MaxUint32 {
+		return 0, fmt.Errorf("invalid route table %d", table)
+	}
+	if metric < 0 || int64(metric) > math.MaxUint32 {
+		return 0, fmt.Errorf("invalid route metric %d", metric)
+	}
+	if fwmark < 0 || int64(fwmark) > math.MaxUint32 {
+		return 0, fmt.Errorf("invalid route fwmark %d", fwmark)
+	}
+	if fwmark != 0 && table == 0 {
+		return 0, fmt.Errorf("route fwmark requires a route table other than main")
+	}
+	return table, nil
+}
+
+// routeRuleArgs returns "ip" arguments that add or delete (verb) the
+// --route-table rule for one address family. With a fwmark, WireGuard
+// marks its own encrypted packets so they skip the table and cannot loop
+// back into the tunnel when a peer routes a network that contains another
+// peer's endpoint.
+func (d *Daemon) routeRuleArgs(family, verb string) []string {
+	args := []string{family, "rule", verb}
+	if d.config.RouteFwmark != 0 {
+		args = append(args, "not", "fwmark", fmt.Sprintf("0x%x", d.config.RouteFwmark))
+	}
+	return append(args, "table", strconv.Itoa(d.config.RouteTable), "priority", strconv.Itoa(RouteRulePriority))
+}
+
+func (d *Daemon) routeRuleFamilies() []string {
+	if d.config.DisableIPv6 {
+		return []string{"-4"}
+	}
+	return []string{"-4", "-6"}
+}
+
+// setupRouteTable sets the WireGuard fwmark and adds the ip rules that send
+// traffic through --route-table. Rules left by a daemon that did not shut
+// down cleanly are replaced.
+func (d *Daemon) setupRouteTable() error {
+	if runtime.GOOS != "linux" || d.config.RouteTable == 0 {
+		return nil
+	}
+	if d.config.RouteFwmark != 0 {
+		mark := fmt.Sprintf("0x%x", d.config.RouteFwmark)
+		if out, err := cmdExecutor.Command("wg", "set", d.config.InterfaceName, "fwmark", mark).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to set fwmark %s: %s: %w", mark, strings.TrimSpace(string(out)), err)
+		}
+	}
+	for _, family := range d.routeRuleFamilies() {
+		_ = cmdExecutor.Command("ip", d.routeRuleArgs(family, "del")...).Run()
+		if out, err := cmdExecutor.Command("ip", d.routeRuleArgs(family, "add")...).CombinedOutput(); err != nil {
+			d.teardownRouteTable()
+			return fmt.Errorf("failed to add routing rule for table %d: %s: %w", d.config.RouteTable, strings.TrimSpace(string(out)), err)
+		}
+	}
+	log.Printf("[Routes] Peer routes go to table %d (rule priority %d)", d.config.RouteTable, RouteRulePriority)
+	return nil
+}
+
+// teardownRouteTable removes the ip rules. Routes in the table go away
+// with the interface.
+func (d *Daemon) teardownRouteTable() {
+	if runtime.GOOS != "linux" || d.config.RouteTable == 0 {
+		return
+	}
+	for _, family := range d.routeRuleFamilies() {
+		_ = cmdExecutor.Command("ip", d.routeRuleArgs(family, "del")...).Run()
+	}
+}
@@ -0,0 +1,106 @@
+package daemon
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/routes"
+)
+
+func TestValidateRouteTable(t *testing.T) {
+	tests := []struct {
+		table, metric, fwmark int
+		want                  int
+		wantErr               bool
+	}{
+		{0, 0, 0, 0, false},
+		{254, 0, 0, 0, false},
+		{51820, 100, 0xca6c, 51820, false},
+		{255, 0, 0, 0, true},
+		{-1, 0, 0, 0, true},
+		{100, -1, 0, 0, true},
+		{0, 0, 0xca6c, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := validateRouteTable(tt.table, tt.metric, tt.fwmark)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("validateRouteTable(%d, %d, %d) = %d, %v; want %d, error %v", tt.table, tt.metric, tt.fwmark, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSetupRouteTable(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("route tables are Linux-only")
+	}
+	var cmds []string
+	mock := &MockCommandExecutor{
+		commandFunc: func(name string, args ...string) Command {
+			cmds = append(cmds, name+" "+strings.Join(args, " "))
+			return &MockCommand{}
+		},
+	}
+	d := &Daemon{config: &Config{InterfaceName: "wg0", RouteTable: 51820, RouteFwmark: 0xca6c, DisableIPv6: true}}
+
+	withMockExecutor(t, mock, func() {
+		if err := d.setupRouteTable(); err != nil {
+			t.Fatalf("setupRouteTable: %v", err)
+		}
+		d.teardownRouteTable()
+	})
+
+	want := []string{
+		"wg set wg0 fwmark 0xca6c",
+		"ip -4 rule del not fwmark 0xca6c table 51820 priority 5210",
+		"ip -4 rule add not fwmark 0xca6c table 51820 priority 5210",
+		"ip -4 rule del not fwmark 0xca6c table 51820 priority 5210",
+	}
+	if strings.Join(cmds, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands:\n%s\nwant:\n%s", strings.Join(cmds, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRouteDiffInTable(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("applyRouteDiff is Linux-only")
+	}
+	var cmds []string
+	mock := &MockCommandExecutor{
+		commandFunc: func(name string, args ...string) Command {
+			cmds = append(cmds, name+" "+strings.Join(args, " "))
+			return &MockCommand{outputFunc: func() ([]byte, error) {
+				return []byte("192.168.1.0/24 via 10.0.0.1 proto static metric 50\n"), nil
+			}}
+		},
+	}
+	rt := routeTarget{table: 51820, metric: 100}
+
+	withMockExecutor(t, mock, func() {
+		current, err := getCurrentRoutes("wg0", rt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(current) != 1 || current[0].Metric != 50 {
+			t.Fatalf("current = %+v, want one route with metric 50", current)
+		}
+		toAdd, toRemove := routes.CalculateDiff(current, []routes.Entry{{Network: "192.168.1.0/24", Gateway: "10.0.0.1", Metric: 100}})
+		if err := applyRouteDiff("wg0", rt, toAdd, toRemove); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	for _, want := range []string{
+		"ip route show dev wg0 table 51820",
+		"ip route del 192.168.1.0/24 via 10.0.0.1 dev wg0 table 51820 metric 50",
+		"ip route replace 192.168.1.0/24 via 10.0.0.1 dev wg0 table 51820 metric 100",
+	} {
+		found := false
+		for _, c := range cmds {
+			found = found || c == want
+		}
+		if !found {
+			t.Errorf("missing %q in %v", want, cmds)
+		}
+	}
+}
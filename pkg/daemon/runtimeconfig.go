@@ -0,0 +1,158 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RuntimeOptions are the options config.set can change on a running
+// daemon, by their join flag names. lan-discovery is the inverse of
+// --no-lan-discovery.
+var RuntimeOptions = []string{"advertise-routes", "force-relay", "lan-discovery", "log-level", "peer-rate-limit"}
+
+// LANToggler is implemented by discovery layers that can start and stop
+// LAN discovery while running.
+type LANToggler interface {
+	SetLANDiscovery(enabled bool) error
+}
+
+// RuntimeConfig returns the current value of each of RuntimeOptions.
+func (d *Daemon) RuntimeConfig() map[string]string {
+	d.configMu.RLock()
+	defer d.configMu.RUnlock()
+	return map[string]string{
+		"advertise-routes": strings.Join(d.config.AdvertiseRoutes, ","),
+		"force-relay":      strconv.FormatBool(d.config.ForceRelay),
+		"lan-discovery":    strconv.FormatBool(d.config.LANDiscovery),
+		"log-level":        d.config.LogLevel,
+		"peer-rate-limit":  d.config.PeerRateLimits.String(),
+	}
+}
+
+// SetRuntimeConfig changes one of RuntimeOptions without restarting the
+// daemon and returns the previous value. The change lasts until the daemon
+// restarts; the service unit or reload file still decide the next start.
+func (d *Daemon) SetRuntimeConfig(key, value string) (string, error) {
+	old, ok := d.RuntimeConfig()[key]
+	if !ok {
+		return "", fmt.Errorf("option %q cannot be changed at runtime (supported: %s)", key, strings.Join(RuntimeOptions, ", "))
+	}
+	value = strings.TrimSpace(value)
+
+	switch key {
+	case "log-level":
+		level := strings.ToLower(value)
+		if level == "warning" {
+			level = "warn"
+		}
+		if !slices.Contains([]string{"debug", "info", "warn", "error"}, level) {
+			return "", fmt.Errorf("invalid log level %q (debug, info, warn or error)", value)
+		}
+		d.configMu.Lock()
+		d.config.LogLevel = level
+		d.configMu.Unlock()
+		setLogLevel(level)
+		value = level
+
+	case "force-relay":
+		on, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for force-relay (true or false)", value)
+		}
+		d.configMu.Lock()
+		d.config.ForceRelay = on
+		d.configMu.Unlock()
+		value = strconv.FormatBool(on)
+		d.reconcile()
+
+	case "lan-discovery":
+		on, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid value %q for lan-discovery (true or false)", value)
+		}
+		toggler, ok := d.dhtDiscovery.(LANToggler)
+		if !ok {
+			return "", fmt.Errorf("LAN discovery can only be switched at runtime with DHT or DNS discovery")
+		}
+		if err := toggler.SetLANDiscovery(on); err != nil {
+			return "", err
+		}
+		d.configMu.Lock()
+		d.config.LANDiscovery = on
+		d.configMu.Unlock()
+		value = strconv.FormatBool(on)
+
+	case "advertise-routes":
+		routes, err := parseRuntimeRoutes(value)
+		if err != nil {
+			return "", err
+		}
+		if len(routes) == 0 && d.config.SubnetRouter {
+			return "", fmt.Errorf("subnet router mode requires advertised routes")
+		}
+		d.reloadConfig(DaemonOpts{AdvertiseRoutes: routes})
+		if err := d.refreshSubnetRouter(); err != nil {
+			log.Printf("[Config] Failed to update subnet router: %v", err)
+		}
+		value = strings.Join(routes, ",")
+		d.reconcile()
+
+	case "peer-rate-limit":
+		limits, err := ParseRateLimits(value)
+		if err != nil {
+			return "", err
+		}
+		if len(limits) > 0 && runtime.GOOS != "linux" {
+			return "", fmt.Errorf("peer rate limits are only supported on Linux")
+		}
+		d.configMu.Lock()
+		prev := d.config.PeerRateLimits
+		d.config.PeerRateLimits = limits
+		d.configMu.Unlock()
+		peers := d.peerStore.GetActive()
+		networks := d.resolvePeerRoutes(peers).accepted
+		if err := d.syncShaping(peers, networks); err != nil {
+			d.configMu.Lock()
+			d.config.PeerRateLimits = prev
+			d.configMu.Unlock()
+			_ = d.syncShaping(peers, networks)
+			return "", err
+		}
+		value = limits.String()
+	}
+
+	log.Printf("[Config] %s: %q → %q", key, old, value)
+	return old, nil
+}
+
+// forceRelay reports whether every peer with a relay available is relayed.
+func (d *Daemon) forceRelay() bool {
+	d.configMu.RLock()
+	defer d.configMu.RUnlock()
+	return d.config.ForceRelay
+}
+
+// parseRuntimeRoutes parses a comma-separated CIDR list. An empty value or
+// "none" withdraws all routes.
+func parseRuntimeRoutes(value string) ([]string, error) {
+	routes := []string{}
+	if value == "" || value == "none" {
+		return routes, nil
+	}
+	for _, r := range strings.Split(value, ",") {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(r); err != nil {
+			return nil, fmt.Errorf("invalid advertised route %q: %w", r, err)
+		}
+		routes = append(routes, r)
+	}
+	return routes, nil
+}
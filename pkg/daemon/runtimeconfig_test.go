@@ -0,0 +1,133 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+type fakeLANToggler struct {
+	enabled bool
+	err     error
+}
+
+func (f *fakeLANToggler) Start() error { return nil }
+func (f *fakeLANToggler) Stop() error  { return nil }
+
+func (f *fakeLANToggler) SetLANDiscovery(enabled bool) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.enabled = enabled
+	return nil
+}
+
+func TestSetRuntimeConfig_LogLevel(t *testing.T) {
+	d := newMinimalDaemon(t) // no t.Parallel() — changes the global log level
+	d.config.LogLevel = "info"
+	t.Cleanup(func() { setLogLevel("info") })
+
+	old, err := d.SetRuntimeConfig("log-level", "WARNING")
+	if err != nil {
+		t.Fatalf("SetRuntimeConfig: %v", err)
+	}
+	if old != "info" || d.GetLogLevel() != "warn" {
+		t.Errorf("old = %q, level = %q; want info, warn", old, d.GetLogLevel())
+	}
+	if logLevel.Level() != slog.LevelWarn {
+		t.Errorf("logger level = %v, want WARN", logLevel.Level())
+	}
+
+	if _, err := d.SetRuntimeConfig("log-level", "verbose"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+	if d.GetLogLevel() != "warn" {
+		t.Errorf("rejected value changed the level to %q", d.GetLogLevel())
+	}
+}
+
+func TestSetRuntimeConfig_UnknownKey(t *testing.T) {
+	t.Parallel()
+	d := newMinimalDaemon(t)
+	for _, key := range []string{"secret", "interface", ""} {
+		if _, err := d.SetRuntimeConfig(key, "x"); err == nil {
+			t.Errorf("SetRuntimeConfig(%q) succeeded, want error", key)
+		}
+	}
+}
+
+func TestSetRuntimeConfig_LANDiscovery(t *testing.T) {
+	t.Parallel()
+	d := newMinimalDaemon(t)
+	d.config.LANDiscovery = true
+
+	if _, err := d.SetRuntimeConfig("lan-discovery", "false"); err == nil {
+		t.Error("expected an error without a discovery layer that can toggle LAN discovery")
+	}
+
+	toggler := &fakeLANToggler{enabled: true}
+	d.dhtDiscovery = toggler
+	if _, err := d.SetRuntimeConfig("lan-discovery", "off"); err == nil {
+		t.Error("expected an error for a non-boolean value")
+	}
+	old, err := d.SetRuntimeConfig("lan-discovery", "false")
+	if err != nil {
+		t.Fatalf("SetRuntimeConfig: %v", err)
+	}
+	if old != "true" || toggler.enabled || d.RuntimeConfig()["lan-discovery"] != "false" {
+		t.Errorf("old = %q, toggler enabled = %v, config = %q", old, toggler.enabled, d.RuntimeConfig()["lan-discovery"])
+	}
+
+	toggler.err = errors.New("multicast unavailable")
+	if _, err := d.SetRuntimeConfig("lan-discovery", "true"); err == nil {
+		t.Error("expected the toggler error to be returned")
+	}
+	if d.RuntimeConfig()["lan-discovery"] != "false" {
+		t.Error("a failed toggle must not change the reported value")
+	}
+}
+
+func TestSetRuntimeConfig_ForceRelayAndRoutes(t *testing.T) {
+	d := newMinimalDaemon(t) // no t.Parallel() — uses global cmdExecutor via reconcile path
+	d.ctx = context.Background()
+	d.localNode = &LocalNode{RoutableNetworks: []string{"10.0.0.0/8"}}
+	d.config.AdvertiseRoutes = []string{"10.0.0.0/8"}
+
+	withMockExecutor(t, &MockCommandExecutor{}, func() {
+		if _, err := d.SetRuntimeConfig("force-relay", "maybe"); err == nil {
+			t.Error("expected an error for a non-boolean value")
+		}
+		if _, err := d.SetRuntimeConfig("force-relay", "1"); err != nil {
+			t.Fatalf("SetRuntimeConfig(force-relay): %v", err)
+		}
+		if !d.forceRelay() {
+			t.Error("force-relay was not enabled")
+		}
+
+		if _, err := d.SetRuntimeConfig("advertise-routes", "192.168.1.0/24,bogus"); err == nil {
+			t.Error("expected an error for an invalid CIDR")
+		}
+		old, err := d.SetRuntimeConfig("advertise-routes", "192.168.1.0/24, 172.16.0.0/12")
+		if err != nil {
+			t.Fatalf("SetRuntimeConfig(advertise-routes): %v", err)
+		}
+		want := []string{"192.168.1.0/24", "172.16.0.0/12"}
+		if old != "10.0.0.0/8" || !reflect.DeepEqual(d.GetAdvertiseRoutes(), want) {
+			t.Errorf("old = %q, routes = %v; want 10.0.0.0/8, %v", old, d.GetAdvertiseRoutes(), want)
+		}
+
+		if _, err := d.SetRuntimeConfig("advertise-routes", "none"); err != nil {
+			t.Fatalf("SetRuntimeConfig(advertise-routes none): %v", err)
+		}
+		if got := d.GetAdvertiseRoutes(); len(got) != 0 {
+			t.Errorf("routes = %v, want none", got)
+		}
+
+		d.config.SubnetRouter = true
+		if _, err := d.SetRuntimeConfig("advertise-routes", ""); err == nil {
+			t.Error("expected an error withdrawing all routes in subnet router mode")
+		}
+	})
+}
@@ -0,0 +1,166 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+const (
+	// SecretCredentialName is the systemd credential the service unit loads
+	// the secret from.
+	SecretCredentialName = "wgmesh-secret"
+
+	machineKeySize = 32
+)
+
+var (
+	// DefaultSecretFilePath is where install-service seals the secret when
+	// systemd-creds is unavailable.
+	DefaultSecretFilePath = "/var/lib/wgmesh/secret.enc"
+	// SecretCredentialPath holds the secret encrypted by systemd-creds
+	// (TPM2-sealed when the host has one, host key otherwise).
+	SecretCredentialPath = "/var/lib/wgmesh/secret.cred"
+
+	machineKeyPath = "/var/lib/wgmesh/machine.key"
+	machineIDPath  = "/etc/machine-id"
+
+	// legacySecretEnvPath is the plaintext environment file older versions
+	// of install-service wrote.
+	legacySecretEnvPath = "/etc/wgmesh/secret.env"
+)
+
+// LoadSecretFile reads the mesh secret from path. Plaintext files (including
+// systemd credentials) are returned as-is; sealed files are decrypted with
+// the machine key or, for password-sealed files, the password returned by
+// password.
+func LoadSecretFile(path string, password func() (string, error)) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	if password == nil {
+		password = func() (string, error) {
+			return "", fmt.Errorf("secret file %s is password-protected", path)
+		}
+	}
+	secret, err := crypto.OpenSecretFile(data, func() ([]byte, string, error) {
+		return loadMachineKey(false)
+	}, password)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	return secret, nil
+}
+
+// SaveSecretFile seals secret into path with the machine key, or with
+// password when one is given. The file is only readable by its owner.
+func SaveSecretFile(path, secret, password string) error {
+	var data []byte
+	var err error
+	if password != "" {
+		data, err = crypto.SealSecretWithPassword(secret, password)
+	} else {
+		var key []byte
+		var machineID string
+		if key, machineID, err = loadMachineKey(true); err == nil {
+			data, err = crypto.SealSecretWithMachineKey(secret, key, machineID)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to seal secret: %w", err)
+	}
+	return writeFileAtomic(path, data, 0600)
+}
+
+// loadMachineKey returns the host's random secret-file key together with
+// the machine ID it is bound to, generating the key on first use if create
+// is set.
+func loadMachineKey(create bool) ([]byte, string, error) {
+	machineID := ""
+	if data, err := os.ReadFile(machineIDPath); err == nil {
+		machineID = strings.TrimSpace(string(data))
+	}
+
+	key, err := os.ReadFile(machineKeyPath)
+	if err == nil {
+		if len(key) != machineKeySize {
+			return nil, "", fmt.Errorf("%s: invalid key length %d", machineKeyPath, len(key))
+		}
+		return key, machineID, nil
+	}
+	if !os.IsNotExist(err) || !create {
+		return nil, "", err
+	}
+
+	key = make([]byte, machineKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, "", fmt.Errorf("failed to generate machine key: %w", err)
+	}
+	if err := writeFileAtomic(machineKeyPath, key, 0600); err != nil {
+		return nil, "", err
+	}
+	return key, machineID, nil
+}
+
+// storeServiceSecret puts the secret where the service unit will load it
+// from and records the location in cfg: a systemd-creds credential when the
+// tool is available, otherwise a machine-sealed secret file.
+func storeServiceSecret(cfg *SystemdServiceConfig) error {
+	if _, err := cmdExecutor.LookPath("systemd-creds"); err == nil {
+		cmd := cmdExecutor.Command("systemd-creds", "encrypt", "--name="+SecretCredentialName, "-", SecretCredentialPath)
+		cmd.SetStdin(strings.NewReader(cfg.Secret))
+		out, err := cmd.CombinedOutput()
+		if err == nil {
+			_ = os.Chmod(SecretCredentialPath, 0600)
+			cfg.SecretCredential = SecretCredentialPath
+			return nil
+		}
+		// Older systemd without a usable host key; fall back to our own sealing.
+		log.Printf("systemd-creds encrypt failed (%s), sealing the secret with the machine key instead", strings.TrimSpace(string(out)))
+	}
+
+	if err := SaveSecretFile(DefaultSecretFilePath, cfg.Secret, ""); err != nil {
+		return err
+	}
+	cfg.SecretFile = DefaultSecretFilePath
+	return nil
+}
+
+// writeFileAtomic replaces path with data through a synced temporary file
+// in the same directory, so readers and a crash mid-write see either the
+// old file or the new one. Concurrent writers each use their own
+// temporary file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	err = f.Chmod(perm)
+	if err == nil {
+		_, err = f.Write(data)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func useTempSecretPaths(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	prevKey, prevID := machineKeyPath, machineIDPath
+	machineKeyPath = filepath.Join(dir, "machine.key")
+	machineIDPath = filepath.Join(dir, "machine-id")
+	t.Cleanup(func() { machineKeyPath, machineIDPath = prevKey, prevID })
+	if err := os.WriteFile(machineIDPath, []byte("0123456789abcdef\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestSaveAndLoadSecretFile(t *testing.T) {
+	dir := useTempSecretPaths(t)
+	path := filepath.Join(dir, "secret.enc")
+	secret := "wgmesh-daemon-secret-file-test"
+
+	if err := SaveSecretFile(path, secret, ""); err != nil {
+		t.Fatalf("SaveSecretFile: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("secret file mode = %v, want 0600", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), secret) {
+		t.Fatal("secret stored in plaintext")
+	}
+	got, err := LoadSecretFile(path, nil)
+	if err != nil || got != secret {
+		t.Fatalf("LoadSecretFile = %q, %v", got, err)
+	}
+
+	// Another host (different machine ID) cannot open it.
+	if err := os.WriteFile(machineIDPath, []byte("fedcba9876543210\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSecretFile(path, nil); err == nil {
+		t.Error("expected failure after machine ID change")
+	}
+
+	pwPath := filepath.Join(dir, "secret.pw")
+	if err := SaveSecretFile(pwPath, secret, "correct horse"); err != nil {
+		t.Fatalf("SaveSecretFile(password): %v", err)
+	}
+	if _, err := LoadSecretFile(pwPath, nil); err == nil {
+		t.Error("expected error without a password prompt")
+	}
+	got, err = LoadSecretFile(pwPath, func() (string, error) { return "correct horse", nil })
+	if err != nil || got != secret {
+		t.Fatalf("LoadSecretFile(password) = %q, %v", got, err)
+	}
+
+	plainPath := filepath.Join(dir, "plain")
+	if err := os.WriteFile(plainPath, []byte(secret+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := LoadSecretFile(plainPath, nil); err != nil || got != secret {
+		t.Errorf("LoadSecretFile(plaintext) = %q, %v", got, err)
+	}
+}
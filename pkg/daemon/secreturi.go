@@ -0,0 +1,169 @@
+package daemon
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"unicode"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+const (
+	// URIVersion2 marks secret URIs that carry SecretOptions.
+	URIVersion2 = "v2"
+
+	// MaxMeshNameLength and MaxBootstrapPeers bound what a v2 URI carries.
+	MaxMeshNameLength = 64
+	MaxBootstrapPeers = 16
+)
+
+// SecretOptions are mesh parameters a v2 secret URI carries along with the
+// secret, so that every node joining with the URI uses the same ones.
+type SecretOptions struct {
+	Name           string   `json:"name,omitempty"`        // display name of the mesh
+	MeshSubnet     string   `json:"subnet,omitempty"`      // mesh subnet CIDR instead of the derived one
+	GossipPort     int      `json:"gossip_port,omitempty"` // exchange port instead of the derived one
+	BootstrapPeers []string `json:"bootstrap,omitempty"`   // host:port exchange endpoints to contact first
+}
+
+// IsZero reports whether o sets nothing, in which case a v1 URI will do.
+func (o SecretOptions) IsZero() bool {
+	return o.Name == "" && o.MeshSubnet == "" && o.GossipPort == 0 && len(o.BootstrapPeers) == 0
+}
+
+// Validate checks each option.
+func (o SecretOptions) Validate() error {
+	if len(o.Name) > MaxMeshNameLength {
+		return fmt.Errorf("mesh name is longer than %d characters", MaxMeshNameLength)
+	}
+	for _, r := range o.Name {
+		if !unicode.IsPrint(r) {
+			return fmt.Errorf("mesh name %q contains unprintable characters", o.Name)
+		}
+	}
+	if o.MeshSubnet != "" {
+		_, subnet, err := net.ParseCIDR(o.MeshSubnet)
+		if err != nil {
+			return fmt.Errorf("invalid mesh subnet: %w", err)
+		}
+		if ones, bits := subnet.Mask.Size(); bits != 32 || bits-ones < 2 {
+			return fmt.Errorf("mesh subnet must be an IPv4 CIDR of /30 or larger, got %q", o.MeshSubnet)
+		}
+	}
+	if o.GossipPort < 0 || o.GossipPort > 65535 {
+		return fmt.Errorf("gossip port %d out of range", o.GossipPort)
+	}
+	if len(o.BootstrapPeers) > MaxBootstrapPeers {
+		return fmt.Errorf("too many bootstrap peers (%d, max %d)", len(o.BootstrapPeers), MaxBootstrapPeers)
+	}
+	for _, addr := range o.BootstrapPeers {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || host == "" || port == "" {
+			return fmt.Errorf("bootstrap peer %q is not host:port", addr)
+		}
+	}
+	return nil
+}
+
+// applyKeys overrides keys derived from the secret with the options that
+// replace them.
+func (o SecretOptions) applyKeys(keys *crypto.DerivedKeys) {
+	if o.GossipPort != 0 {
+		keys.GossipPort = uint16(o.GossipPort)
+	}
+}
+
+// secretPayload is the JSON a v2 URI encodes.
+type secretPayload struct {
+	Secret string `json:"secret"`
+	SecretOptions
+}
+
+// FormatSecretURIWithOptions formats a secret with options as a v2 URI,
+// wgmesh://v2/<payload>.<signature>. The payload is the base64url-encoded
+// JSON of the secret and options, and the signature an HMAC of it under
+// the mesh's membership key, so edits and truncation are caught. Without
+// options it returns the v1 URI.
+func FormatSecretURIWithOptions(secret string, opts SecretOptions) (string, error) {
+	if opts.IsZero() {
+		return FormatSecretURI(secret), nil
+	}
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	keys, err := crypto.DeriveKeys(secret)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(secretPayload{Secret: secret, SecretOptions: opts})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode secret URI: %w", err)
+	}
+	return fmt.Sprintf("%s%s/%s.%s", URIPrefix, URIVersion2,
+		base64.RawURLEncoding.EncodeToString(payload),
+		base64.RawURLEncoding.EncodeToString(secretURIMAC(keys.MembershipKey[:], payload))), nil
+}
+
+// ParseSecretURI returns the secret and options of a raw secret, a v1 URI
+// or a v2 URI. Only v2 URIs carry options; their signature is checked.
+func ParseSecretURI(input string) (string, SecretOptions, error) {
+	input = strings.TrimSpace(input)
+	rest, ok := strings.CutPrefix(input, URIPrefix)
+	if !ok {
+		return input, SecretOptions{}, nil
+	}
+	version, body, ok := strings.Cut(rest, "/")
+	if !ok {
+		return stripURIQuery(version), SecretOptions{}, nil
+	}
+	if version != URIVersion2 {
+		return stripURIQuery(body), SecretOptions{}, nil
+	}
+
+	encoded, encodedSig, ok := strings.Cut(stripURIQuery(body), ".")
+	if !ok {
+		return "", SecretOptions{}, errors.New("v2 secret URI has no signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", SecretOptions{}, fmt.Errorf("invalid v2 secret URI encoding: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil {
+		return "", SecretOptions{}, fmt.Errorf("invalid v2 secret URI signature encoding: %w", err)
+	}
+	var p secretPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return "", SecretOptions{}, fmt.Errorf("invalid v2 secret URI payload: %w", err)
+	}
+	keys, err := crypto.DeriveKeys(p.Secret)
+	if err != nil {
+		return "", SecretOptions{}, err
+	}
+	if !hmac.Equal(sig, secretURIMAC(keys.MembershipKey[:], payload)) {
+		return "", SecretOptions{}, errors.New("v2 secret URI signature does not match (edited or cut short)")
+	}
+	if err := p.SecretOptions.Validate(); err != nil {
+		return "", SecretOptions{}, err
+	}
+	return p.Secret, p.SecretOptions, nil
+}
+
+// stripURIQuery drops query parameters older URIs may carry.
+func stripURIQuery(s string) string {
+	s, _, _ = strings.Cut(s, "?")
+	return s
+}
+
+func secretURIMAC(membershipKey, payload []byte) []byte {
+	mac := hmac.New(sha256.New, membershipKey)
+	mac.Write([]byte("wgmesh-secret-uri-v2|"))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,96 @@
+package daemon
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSecretURIv2RoundTrip(t *testing.T) {
+	opts := SecretOptions{
+		Name:           "home lab",
+		MeshSubnet:     "192.168.100.0/24",
+		GossipPort:     40000,
+		BootstrapPeers: []string{"203.0.113.5:40000", "gw.example.com:40000"},
+	}
+	uri, err := FormatSecretURIWithOptions(testConfigSecret, opts)
+	if err != nil {
+		t.Fatalf("FormatSecretURIWithOptions: %v", err)
+	}
+	if !strings.HasPrefix(uri, URIPrefix+URIVersion2+"/") {
+		t.Fatalf("uri = %q, want a v2 URI", uri)
+	}
+
+	secret, got, err := ParseSecretURI(uri)
+	if err != nil {
+		t.Fatalf("ParseSecretURI: %v", err)
+	}
+	if secret != testConfigSecret {
+		t.Errorf("secret = %q, want %q", secret, testConfigSecret)
+	}
+	if !reflect.DeepEqual(got, opts) {
+		t.Errorf("options = %+v, want %+v", got, opts)
+	}
+}
+
+func TestSecretURIv2RejectsTampering(t *testing.T) {
+	uri, err := FormatSecretURIWithOptions(testConfigSecret, SecretOptions{GossipPort: 40000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, sig, _ := strings.Cut(strings.TrimPrefix(uri, URIPrefix+URIVersion2+"/"), ".")
+
+	other, err := FormatSecretURIWithOptions(testConfigSecret, SecretOptions{GossipPort: 40001})
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPayload, _, _ := strings.Cut(strings.TrimPrefix(other, URIPrefix+URIVersion2+"/"), ".")
+
+	for name, bad := range map[string]string{
+		"swapped payload": URIPrefix + URIVersion2 + "/" + otherPayload + "." + sig,
+		"truncated":       uri[:len(uri)-4],
+		"no signature":    URIPrefix + URIVersion2 + "/" + payload,
+	} {
+		if _, _, err := ParseSecretURI(bad); err == nil {
+			t.Errorf("%s: ParseSecretURI accepted %q", name, bad)
+		}
+	}
+}
+
+func TestParseSecretURIv1(t *testing.T) {
+	for _, input := range []string{
+		testConfigSecret,
+		FormatSecretURI(testConfigSecret),
+		" " + FormatSecretURI(testConfigSecret) + "?relay=true\n",
+	} {
+		secret, opts, err := ParseSecretURI(input)
+		if err != nil {
+			t.Errorf("ParseSecretURI(%q): %v", input, err)
+			continue
+		}
+		if secret != testConfigSecret || !opts.IsZero() {
+			t.Errorf("ParseSecretURI(%q) = %q, %+v", input, secret, opts)
+		}
+	}
+
+	// Without options there is nothing to sign, so the URI stays v1.
+	uri, err := FormatSecretURIWithOptions(testConfigSecret, SecretOptions{})
+	if err != nil || uri != FormatSecretURI(testConfigSecret) {
+		t.Errorf("FormatSecretURIWithOptions without options = %q, %v", uri, err)
+	}
+}
+
+func TestSecretOptionsValidate(t *testing.T) {
+	for _, opts := range []SecretOptions{
+		{Name: strings.Repeat("x", MaxMeshNameLength+1)},
+		{Name: "bad\nname"},
+		{MeshSubnet: "fd00::/64"},
+		{MeshSubnet: "10.0.0.0/31"},
+		{GossipPort: 70000},
+		{BootstrapPeers: []string{"203.0.113.5"}},
+	} {
+		if _, err := FormatSecretURIWithOptions(testConfigSecret, opts); err == nil {
+			t.Errorf("options %+v were accepted", opts)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/update"
+)
+
+// With --auto-update the daemon checks GitHub for a newer release every
+// AutoUpdateInterval, installs its binary over the running one once the
+// signature checks out (see pkg/update) and restarts in place. The first
+// check waits a random part of the interval, so a mesh whose nodes all
+// started together does not restart all at once.
+const (
+	AutoUpdateInterval = 6 * time.Hour
+	autoUpdateTimeout  = 10 * time.Minute
+)
+
+// EventUpdated is recorded when the daemon installs a new release.
+const EventUpdated = "updated"
+
+// RestartDelay is how long the daemon keeps running after accepting a
+// restart, so the reply reaches the caller before the RPC server stops.
+const RestartDelay = 500 * time.Millisecond
+
+// ErrRestart is returned by RunWithDHTDiscovery when the daemon stopped to
+// restart in place, e.g. after an update. The caller should exec the
+// binary again with Config.AdoptInterface set; the WireGuard interface and
+// its peers stay up in between.
+var ErrRestart = errors.New("daemon restarting")
+
+// releaseSource is where auto-update finds releases; *update.Client in
+// production.
+type releaseSource interface {
+	Latest(ctx context.Context) (*update.Release, error)
+	Download(ctx context.Context, rel *update.Release) ([]byte, error)
+}
+
+// Restart stops the daemon so that its binary, possibly replaced since it
+// started, takes over the interface. It does nothing while the node is
+// leaving.
+func (d *Daemon) Restart() error {
+	if d.leaving.Load() {
+		return errors.New("daemon is leaving the mesh")
+	}
+	if !d.restarting.CompareAndSwap(false, true) {
+		return nil
+	}
+	log.Printf("[Update] Restarting in place; %s stays up", d.config.InterfaceName)
+	time.AfterFunc(RestartDelay, d.Shutdown)
+	return nil
+}
+
+func (d *Daemon) autoUpdateLoop() {
+	src, err := update.NewClient()
+	if err != nil {
+		log.Printf("[Update] Auto-update disabled: %v", err)
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		log.Printf("[Update] Auto-update disabled: %v", err)
+		return
+	}
+
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(AutoUpdateInterval))))
+	defer timer.Stop()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-timer.C:
+		}
+		installed, err := d.applyUpdate(src, exe)
+		if err != nil {
+			log.Printf("[Update] Update check failed: %v", err)
+		}
+		if installed {
+			d.Restart()
+			return
+		}
+		timer.Reset(AutoUpdateInterval)
+	}
+}
+
+// applyUpdate installs the latest release over exe if it is newer than the
+// running version, reporting whether it did.
+func (d *Daemon) applyUpdate(src releaseSource, exe string) (bool, error) {
+	ctx, cancel := context.WithTimeout(d.ctx, autoUpdateTimeout)
+	defer cancel()
+
+	rel, err := src.Latest(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !update.Newer(d.config.Version, rel.Version()) {
+		return false, nil
+	}
+	log.Printf("[Update] Installing wgmesh %s (running %s)", rel.Version(), d.config.Version)
+	data, err := src.Download(ctx, rel)
+	if err != nil {
+		return false, err
+	}
+	if err := update.Install(exe, data); err != nil {
+		return false, err
+	}
+	d.recordEvent(EventUpdated, "", map[string]string{
+		"from": d.config.Version,
+		"to":   rel.Version(),
+	})
+	return true, nil
+}
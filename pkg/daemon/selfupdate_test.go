@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/update"
+)
+
+type fakeReleases struct {
+	rel        *update.Release
+	binary     []byte
+	downloaded bool
+}
+
+func (f *fakeReleases) Latest(context.Context) (*update.Release, error) {
+	return f.rel, nil
+}
+
+func (f *fakeReleases) Download(context.Context, *update.Release) ([]byte, error) {
+	f.downloaded = true
+	return f.binary, nil
+}
+
+func TestApplyUpdate(t *testing.T) {
+	d := newMinimalDaemon(t)
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	defer d.cancel()
+	d.config.Version = "1.2.0"
+
+	exe := filepath.Join(t.TempDir(), "wgmesh")
+	if err := os.WriteFile(exe, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	same := &fakeReleases{rel: &update.Release{Tag: "v1.2.0"}, binary: []byte("new")}
+	if installed, err := d.applyUpdate(same, exe); err != nil || installed || same.downloaded {
+		t.Fatalf("same version: installed=%v downloaded=%v err=%v", installed, same.downloaded, err)
+	}
+
+	newer := &fakeReleases{rel: &update.Release{Tag: "v1.3.0"}, binary: []byte("new")}
+	installed, err := d.applyUpdate(newer, exe)
+	if err != nil || !installed {
+		t.Fatalf("newer version: installed=%v err=%v", installed, err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "new" {
+		t.Errorf("binary = %q, want the new one", data)
+	}
+	events := d.GetRPCEvents(0)
+	if len(events) != 1 || events[0].Type != EventUpdated || events[0].Details["to"] != "1.3.0" {
+		t.Errorf("events = %+v, want one %s event to 1.3.0", events, EventUpdated)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	d := newMinimalDaemon(t)
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+
+	if err := d.Restart(); err != nil {
+		t.Fatalf("Restart: %v", err)
+	}
+	select {
+	case <-d.ctx.Done():
+	case <-time.After(RestartDelay + 2*time.Second):
+		t.Fatal("daemon did not shut down after Restart")
+	}
+	if !d.restarting.Load() {
+		t.Error("restarting not set")
+	}
+
+	d = newMinimalDaemon(t)
+	d.leaving.Store(true)
+	if err := d.Restart(); err == nil || d.restarting.Load() {
+		t.Error("Restart while leaving should be refused")
+	}
+}
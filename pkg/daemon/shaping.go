@@ -0,0 +1,235 @@
+package daemon
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// With --peer-rate-limit the daemon shapes what this node sends into the
+// mesh with tc: an HTB tree on the mesh interface with one class per
+// limited peer, matched by the peer's mesh addresses and the networks
+// routed through it, and fq_codel under every class so a bulk transfer
+// does not add latency to other flows. Only egress is shaped; limit the
+// other direction on the sending node.
+const (
+	// RateLimitAll is the --peer-rate-limit target that caps everything
+	// the node sends into the mesh.
+	RateLimitAll = "all"
+
+	// shapingUnlimited is the HTB rate standing in for no limit.
+	shapingUnlimited = 10_000_000_000
+)
+
+// RateLimits caps the rate in bits per second at which this node sends to
+// each peer, by WireGuard public key, or in total under RateLimitAll.
+type RateLimits map[string]uint64
+
+// ParseRateLimits parses a comma-separated spec such as
+// "<pubkey>=10Mbit,all=50Mbit". Rates are bits per second with an
+// optional k, M or G prefix (powers of 1000) and an optional "bit" suffix.
+// An empty spec or "none" means no limits.
+func ParseRateLimits(spec string) (RateLimits, error) {
+	limits := RateLimits{}
+	if s := strings.TrimSpace(spec); s == "" || s == "none" {
+		return limits, nil
+	}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		// Public keys end in '=' padding, so split at the last one.
+		i := strings.LastIndex(item, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("%q is not peer=rate", item)
+		}
+		target, rate := strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		if target != RateLimitAll {
+			if key, err := base64.StdEncoding.DecodeString(target); err != nil || len(key) != 32 {
+				return nil, fmt.Errorf("%q is neither a WireGuard public key nor %q", target, RateLimitAll)
+			}
+		}
+		bps, err := parseRate(rate)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", shortKey(target), err)
+		}
+		limits[target] = bps
+	}
+	return limits, nil
+}
+
+// String renders limits in the form ParseRateLimits accepts, sorted.
+func (l RateLimits) String() string {
+	targets := make([]string, 0, len(l))
+	for target := range l {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+	parts := make([]string, len(targets))
+	for i, target := range targets {
+		parts[i] = target + "=" + formatRate(l[target])
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseRate parses a rate such as "10Mbit", "512k" or "1.5G" into bits per
+// second.
+func parseRate(rate string) (uint64, error) {
+	s := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(rate)), "bit")
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "g"):
+		mult, s = 1e9, strings.TrimSuffix(s, "g")
+	case strings.HasSuffix(s, "m"):
+		mult, s = 1e6, strings.TrimSuffix(s, "m")
+	case strings.HasSuffix(s, "k"):
+		mult, s = 1e3, strings.TrimSuffix(s, "k")
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	bps := v * mult
+	if err != nil || bps < 8000 || bps > shapingUnlimited {
+		return 0, fmt.Errorf("%q is not a rate between 8kbit and 10Gbit", rate)
+	}
+	return uint64(math.Round(bps)), nil
+}
+
+// formatRate renders bits per second in tc's notation, e.g. "10mbit".
+func formatRate(bps uint64) string {
+	switch {
+	case bps%1e9 == 0:
+		return strconv.FormatUint(bps/1e9, 10) + "gbit"
+	case bps%1e6 == 0:
+		return strconv.FormatUint(bps/1e6, 10) + "mbit"
+	case bps%1e3 == 0:
+		return strconv.FormatUint(bps/1e3, 10) + "kbit"
+	}
+	return strconv.FormatUint(bps, 10) + "bit"
+}
+
+// shapingState is what --peer-rate-limit installed on the interface.
+type shapingState struct {
+	mu     sync.Mutex
+	script string // tc batch last applied; "" = no qdisc of ours
+}
+
+// rateLimits returns a copy of the configured limits.
+func (d *Daemon) rateLimits() RateLimits {
+	d.configMu.RLock()
+	defer d.configMu.RUnlock()
+	limits := make(RateLimits, len(d.config.PeerRateLimits))
+	for target, bps := range d.config.PeerRateLimits {
+		limits[target] = bps
+	}
+	return limits
+}
+
+// syncShaping brings the interface's qdiscs in line with the configured
+// limits and the limited peers' addresses. It is cheap to call when
+// nothing changed, and does not retry a script tc rejected until the
+// limits or addresses change.
+func (d *Daemon) syncShaping(peers []*PeerInfo, networks map[string][]string) error {
+	if d.tunnel != nil {
+		return nil
+	}
+	script := buildShapingScript(d.config.InterfaceName, d.rateLimits(), peers, networks)
+
+	sh := &d.shaping
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if script == sh.script {
+		return nil
+	}
+	if sh.script != "" {
+		d.removeShapingLocked()
+	}
+	if script == "" {
+		log.Printf("[Shaping] Rate limits removed from %s", d.config.InterfaceName)
+		return nil
+	}
+	sh.script = script
+	cmd := cmdExecutor.Command("tc", "-batch", "-")
+	cmd.SetStdin(strings.NewReader(script))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to install rate limits: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	log.Printf("[Shaping] Rate limits on %s: %s", d.config.InterfaceName, d.rateLimits())
+	return nil
+}
+
+// teardownShaping removes the qdiscs installed for --peer-rate-limit.
+func (d *Daemon) teardownShaping() {
+	sh := &d.shaping
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if sh.script != "" {
+		d.removeShapingLocked()
+	}
+}
+
+func (d *Daemon) removeShapingLocked() {
+	iface := d.config.InterfaceName
+	if out, err := cmdExecutor.Command("tc", "qdisc", "del", "dev", iface, "root").CombinedOutput(); err != nil {
+		log.Printf("[Shaping] Failed to remove the qdisc on %s: %s: %v", iface, strings.TrimSpace(string(out)), err)
+	}
+	d.shaping.script = ""
+}
+
+// buildShapingScript renders a tc batch installing limits on iface: class
+// 1:1 carries the total, 1:2 the traffic of unlimited peers, and 1:10 on
+// each limited peer with known addresses, in public key order. It returns
+// "" when nothing is to be shaped.
+func buildShapingScript(iface string, limits RateLimits, peers []*PeerInfo, networks map[string][]string) string {
+	total, hasTotal := limits[RateLimitAll]
+	if !hasTotal {
+		total = shapingUnlimited
+	}
+
+	limited := make([]*PeerInfo, 0, len(limits))
+	for _, p := range peers {
+		if _, ok := limits[p.WGPubKey]; ok && p.WGPubKey != RateLimitAll && p.MeshIP != "" {
+			limited = append(limited, p)
+		}
+	}
+	if len(limited) == 0 && !hasTotal {
+		return ""
+	}
+	sort.Slice(limited, func(i, j int) bool { return limited[i].WGPubKey < limited[j].WGPubKey })
+
+	var sb strings.Builder
+	rate := formatRate(total)
+	fmt.Fprintf(&sb, "qdisc add dev %s root handle 1: htb default 2\n", iface)
+	fmt.Fprintf(&sb, "class add dev %s parent 1: classid 1:1 htb rate %s ceil %s\n", iface, rate, rate)
+	fmt.Fprintf(&sb, "class add dev %s parent 1:1 classid 1:2 htb rate %s ceil %s\n", iface, rate, rate)
+	fmt.Fprintf(&sb, "qdisc add dev %s parent 1:2 fq_codel\n", iface)
+	for i, p := range limited {
+		class := fmt.Sprintf("1:%x", 0x10+i)
+		rate := formatRate(min(limits[p.WGPubKey], total))
+		fmt.Fprintf(&sb, "class add dev %s parent 1:1 classid %s htb rate %s ceil %s\n", iface, class, rate, rate)
+		fmt.Fprintf(&sb, "qdisc add dev %s parent %s fq_codel\n", iface, class)
+
+		dsts := []string{p.MeshIP + "/32"}
+		if p.MeshIPv6 != "" {
+			dsts = append(dsts, p.MeshIPv6+"/128")
+		}
+		dsts = append(dsts, networks[p.WGPubKey]...)
+		for _, dst := range dsts {
+			_, ipNet, err := net.ParseCIDR(dst)
+			if err != nil {
+				continue
+			}
+			if ipNet.IP.To4() != nil {
+				fmt.Fprintf(&sb, "filter add dev %s parent 1: protocol ip prio 1 u32 match ip dst %s flowid %s\n", iface, ipNet, class)
+			} else {
+				fmt.Fprintf(&sb, "filter add dev %s parent 1: protocol ipv6 prio 2 u32 match ip6 dst %s flowid %s\n", iface, ipNet, class)
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const shapingTestKey = "YWJjZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXoxMjM0NTY="
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits(shapingTestKey + "=10Mbit, all=1.5G")
+	if err != nil {
+		t.Fatalf("ParseRateLimits: %v", err)
+	}
+	want := RateLimits{shapingTestKey: 10_000_000, RateLimitAll: 1_500_000_000}
+	if !reflect.DeepEqual(limits, want) {
+		t.Fatalf("limits = %v, want %v", limits, want)
+	}
+	if got := limits.String(); got != "YWJjZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXoxMjM0NTY==10mbit,all=1500mbit" {
+		t.Errorf("String() = %q", got)
+	}
+
+	for _, spec := range []string{"", "none"} {
+		if limits, err := ParseRateLimits(spec); err != nil || len(limits) != 0 {
+			t.Errorf("ParseRateLimits(%q) = %v, %v; want no limits", spec, limits, err)
+		}
+	}
+	for _, spec := range []string{"all", "host1=10mbit", "all=fast", "all=100bit", "all=20gbit"} {
+		if _, err := ParseRateLimits(spec); err == nil {
+			t.Errorf("ParseRateLimits(%q) should fail", spec)
+		}
+	}
+}
+
+func TestBuildShapingScript(t *testing.T) {
+	peers := []*PeerInfo{
+		{WGPubKey: shapingTestKey, MeshIP: "10.42.0.2", MeshIPv6: "fd00::2"},
+		{WGPubKey: "other", MeshIP: "10.42.0.3"},
+	}
+	networks := map[string][]string{shapingTestKey: {"192.168.1.0/24"}}
+
+	if got := buildShapingScript("wg0", RateLimits{}, peers, networks); got != "" {
+		t.Errorf("script without limits = %q, want none", got)
+	}
+	if got := buildShapingScript("wg0", RateLimits{"absent": 1e6}, peers, networks); got != "" {
+		t.Errorf("script for an unknown peer = %q, want none", got)
+	}
+
+	got := buildShapingScript("wg0", RateLimits{shapingTestKey: 10e6, RateLimitAll: 5e6}, peers, networks)
+	want := `qdisc add dev wg0 root handle 1: htb default 2
+class add dev wg0 parent 1: classid 1:1 htb rate 5mbit ceil 5mbit
+class add dev wg0 parent 1:1 classid 1:2 htb rate 5mbit ceil 5mbit
+qdisc add dev wg0 parent 1:2 fq_codel
+class add dev wg0 parent 1:1 classid 1:10 htb rate 5mbit ceil 5mbit
+qdisc add dev wg0 parent 1:10 fq_codel
+filter add dev wg0 parent 1: protocol ip prio 1 u32 match ip dst 10.42.0.2/32 flowid 1:10
+filter add dev wg0 parent 1: protocol ipv6 prio 2 u32 match ip6 dst fd00::2/128 flowid 1:10
+filter add dev wg0 parent 1: protocol ip prio 1 u32 match ip dst 192.168.1.0/24 flowid 1:10
+`
+	if got != want {
+		t.Errorf("script =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSyncShaping(t *testing.T) {
+	var calls []string
+	var script string
+	mock := &MockCommandExecutor{
+		commandFunc: func(name string, args ...string) Command {
+			call := name + " " + strings.Join(args, " ")
+			calls = append(calls, call)
+			cmd := &MockCommand{}
+			if call == "tc -batch -" {
+				cmd.combinedOutputFunc = func() ([]byte, error) {
+					b, _ := io.ReadAll(cmd.stdin)
+					script = string(b)
+					return nil, nil
+				}
+			}
+			return cmd
+		},
+	}
+
+	d := newMinimalDaemon(t)
+	d.localNode = &LocalNode{WGPubKey: "local"}
+	d.config.InterfaceName = "wg0"
+	d.config.PeerRateLimits = RateLimits{shapingTestKey: 10e6}
+	peers := []*PeerInfo{{WGPubKey: shapingTestKey, MeshIP: "10.42.0.2"}}
+
+	withMockExecutor(t, mock, func() {
+		if err := d.syncShaping(peers, nil); err != nil {
+			t.Fatalf("syncShaping: %v", err)
+		}
+		// Nothing changed: tc is not run again.
+		if err := d.syncShaping(peers, nil); err != nil {
+			t.Fatalf("syncShaping: %v", err)
+		}
+		if _, err := d.SetRuntimeConfig("peer-rate-limit", "none"); err != nil {
+			t.Fatalf("SetRuntimeConfig: %v", err)
+		}
+		d.teardownShaping()
+	})
+
+	want := []string{"tc -batch -", "tc qdisc del dev wg0 root"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if !strings.Contains(script, "match ip dst 10.42.0.2/32 flowid 1:10") {
+		t.Errorf("script does not match the peer:\n%s", script)
+	}
+	if got := d.RuntimeConfig()["peer-rate-limit"]; got != "" {
+		t.Errorf("peer-rate-limit = %q after removing the limits", got)
+	}
+}
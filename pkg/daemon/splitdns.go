@@ -0,0 +1,382 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+const (
+	// resolverDir holds macOS per-domain resolver files (see resolver(5)).
+	resolverDir = "/etc/resolver"
+	// resolvConfPath is the resolver configuration edited where neither
+	// systemd-resolved nor /etc/resolver is available.
+	resolvConfPath = "/etc/resolv.conf"
+	// resolvedRuntimeDir exists while systemd-resolved runs.
+	resolvedRuntimeDir = "/run/systemd/resolve"
+
+	splitDNSMarker      = "# Managed by wgmesh"
+	resolvConfBlockEnd  = "# End of wgmesh"
+	resolvConfMaxServer = 3 // glibc reads at most three nameserver lines
+)
+
+// ParseAdvertiseDNS parses an --advertise-dns value such as
+// "home.lan=192.168.1.53,corp.lan=10.0.0.53". An empty string gives none.
+func ParseAdvertiseDNS(s string) ([]crypto.DNSDomain, error) {
+	var domains []crypto.DNSDomain
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		domain, resolver, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not DOMAIN=RESOLVER", part)
+		}
+		dd := crypto.DNSDomain{
+			Domain:   strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), ".")),
+			Resolver: strings.TrimSpace(resolver),
+		}
+		if err := dd.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[dd.Domain] {
+			return nil, fmt.Errorf("domain %s given twice", dd.Domain)
+		}
+		seen[dd.Domain] = true
+		domains = append(domains, dd)
+	}
+	if len(domains) > crypto.MaxDNSDomains {
+		return nil, fmt.Errorf("too many domains (%d, max %d)", len(domains), crypto.MaxDNSDomains)
+	}
+	return domains, nil
+}
+
+// resolveSplitDNS maps each domain peers advertise to the resolvers that
+// answer for it. A resolver is only used if it is the peer's own mesh
+// address or lies in a network routed through that peer, so a peer cannot
+// steer names to a resolver reached some other way. routed is the
+// accepted map of resolveRoutes.
+func resolveSplitDNS(peers []*PeerInfo, localKey string, routed map[string][]string) map[string][]string {
+	domains := make(map[string][]string)
+	for _, p := range peers {
+		if p.WGPubKey == localKey || len(p.DNS) == 0 {
+			continue
+		}
+		var nets []*net.IPNet
+		for _, cidr := range routed[p.WGPubKey] {
+			if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+				nets = append(nets, ipNet)
+			}
+		}
+		for _, dd := range p.DNS {
+			ip := net.ParseIP(dd.Resolver)
+			if ip == nil {
+				continue
+			}
+			reachable := dd.Resolver == p.MeshIP || (p.MeshIPv6 != "" && ip.Equal(net.ParseIP(p.MeshIPv6)))
+			for _, n := range nets {
+				reachable = reachable || n.Contains(ip)
+			}
+			if !reachable {
+				continue
+			}
+			resolver := ip.String()
+			if !slices.Contains(domains[dd.Domain], resolver) {
+				domains[dd.Domain] = append(domains[dd.Domain], resolver)
+			}
+		}
+	}
+	for domain := range domains {
+		slices.Sort(domains[domain])
+	}
+	return domains
+}
+
+// dnsConfigurator installs split-DNS rules for the mesh interface on the
+// host.
+type dnsConfigurator interface {
+	// Apply replaces the installed rules with domains, a map of domain to
+	// resolver addresses; an empty map removes them.
+	Apply(iface string, domains map[string][]string) error
+	Name() string
+}
+
+// newDNSConfigurator picks systemd-resolved where it runs and per-domain
+// resolver files on macOS. Elsewhere there is no split DNS, and it returns
+// nil unless resolvConf allows putting the mesh resolvers in
+// /etc/resolv.conf.
+func newDNSConfigurator(resolvConf bool) dnsConfigurator {
+	if runtime.GOOS == "darwin" {
+		return &resolverDirDNS{dir: resolverDir}
+	}
+	if _, err := cmdExecutor.LookPath("resolvectl"); err == nil {
+		if _, err := os.Stat(resolvedRuntimeDir); err == nil {
+			return resolvedDNS{}
+		}
+	}
+	if resolvConf {
+		return &resolvConfDNS{path: resolvConfPath}
+	}
+	return nil
+}
+
+// resolvedDNS configures systemd-resolved through resolvectl, which drives
+// its D-Bus API. resolved keeps DNS servers per link, not per domain, so
+// when several gateways advertise domains, each of their resolvers is
+// asked about, and can answer for, the others' domains too.
+type resolvedDNS struct{}
+
+func (resolvedDNS) Name() string { return "systemd-resolved" }
+
+func (resolvedDNS) Apply(iface string, domains map[string][]string) error {
+	if len(domains) == 0 {
+		return runResolvectl("revert", iface)
+	}
+	var servers, routing []string
+	for _, domain := range sortedDomains(domains) {
+		routing = append(routing, "~"+domain)
+		for _, r := range domains[domain] {
+			if !slices.Contains(servers, r) {
+				servers = append(servers, r)
+			}
+		}
+	}
+	for _, resolvers := range domains {
+		if len(resolvers) != len(servers) {
+			log.Printf("[DNS] Resolvers for different domains share %s: each is sent queries for all of %s", iface, strings.Join(sortedDomains(domains), ", "))
+			break
+		}
+	}
+	if err := runResolvectl(append([]string{"dns", iface}, servers...)...); err != nil {
+		return err
+	}
+	if err := runResolvectl(append([]string{"domain", iface}, routing...)...); err != nil {
+		return err
+	}
+	// Only the mesh domains go to the mesh resolvers.
+	return runResolvectl("default-route", iface, "false")
+}
+
+func runResolvectl(args ...string) error {
+	if out, err := cmdExecutor.Command("resolvectl", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("resolvectl %s: %s: %w", strings.Join(args, " "), strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// resolverDirDNS writes one resolver(5) file per domain, as macOS reads
+// from /etc/resolver. Files it did not write are left alone.
+type resolverDirDNS struct {
+	dir     string
+	written map[string]bool
+}
+
+func (r *resolverDirDNS) Name() string { return r.dir }
+
+func (r *resolverDirDNS) Apply(iface string, domains map[string][]string) error {
+	if err := os.MkdirAll(r.dir, 0755); err != nil {
+		return err
+	}
+	var errs []string
+	for domain := range r.written {
+		if _, keep := domains[domain]; keep {
+			continue
+		}
+		if err := os.Remove(filepath.Join(r.dir, domain)); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err.Error())
+			continue
+		}
+		delete(r.written, domain)
+	}
+	for _, domain := range sortedDomains(domains) {
+		path := filepath.Join(r.dir, domain)
+		if !r.written[domain] {
+			if data, err := os.ReadFile(path); err == nil && !strings.HasPrefix(string(data), splitDNSMarker) {
+				errs = append(errs, fmt.Sprintf("%s exists and was not written by wgmesh", path))
+				continue
+			}
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%s for %s\n", splitDNSMarker, iface)
+		for _, resolver := range domains[domain] {
+			fmt.Fprintf(&sb, "nameserver %s\n", resolver)
+		}
+		if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if r.written == nil {
+			r.written = make(map[string]bool)
+		}
+		r.written[domain] = true
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// resolvConfDNS puts the mesh resolvers first in /etc/resolv.conf, on hosts
+// without systemd-resolved whose operator asked for it. The file has no
+// per-domain servers, so this is not split: every lookup goes to them
+// first. They only take nameserver slots the host's own servers leave
+// free.
+type resolvConfDNS struct {
+	path string
+}
+
+func (r *resolvConfDNS) Name() string { return r.path }
+
+func (r *resolvConfDNS) Apply(iface string, domains map[string][]string) error {
+	data, err := os.ReadFile(r.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	updated, err := rewriteResolvConf(string(data), iface, domains)
+	if err != nil {
+		return fmt.Errorf("%s: %w", r.path, err)
+	}
+	if updated == string(data) {
+		return nil
+	}
+	// Written in place: /etc/resolv.conf is often a symlink.
+	return os.WriteFile(r.path, []byte(updated), 0644)
+}
+
+// rewriteResolvConf replaces the wgmesh block at the top of a resolv.conf
+// with nameserver lines for the mesh resolvers, or drops it when domains
+// is empty. The block only takes the nameserver slots the rest of the file
+// leaves free, so the host's own servers are never pushed out; with none
+// free it fails.
+func rewriteResolvConf(current, iface string, domains map[string][]string) (string, error) {
+	rest := current
+	if strings.HasPrefix(rest, splitDNSMarker) {
+		if i := strings.Index(rest, resolvConfBlockEnd+"\n"); i >= 0 {
+			rest = rest[i+len(resolvConfBlockEnd)+1:]
+		}
+	}
+	if len(domains) == 0 {
+		return rest, nil
+	}
+	free := resolvConfMaxServer
+	for _, line := range strings.Split(rest, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && fields[0] == "nameserver" {
+			free--
+		}
+	}
+	if free <= 0 {
+		return "", fmt.Errorf("the host's nameservers fill all %d slots, none left for the mesh resolvers", resolvConfMaxServer)
+	}
+	var servers []string
+	for _, domain := range sortedDomains(domains) {
+		for _, resolver := range domains[domain] {
+			if !slices.Contains(servers, resolver) && len(servers) < free {
+				servers = append(servers, resolver)
+			}
+		}
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s for %s: %s\n", splitDNSMarker, iface, strings.Join(sortedDomains(domains), " "))
+	for _, server := range servers {
+		fmt.Fprintf(&sb, "nameserver %s\n", server)
+	}
+	sb.WriteString(resolvConfBlockEnd + "\n")
+	return sb.String() + rest, nil
+}
+
+func sortedDomains(domains map[string][]string) []string {
+	names := make([]string, 0, len(domains))
+	for domain := range domains {
+		names = append(names, domain)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// splitDNS tracks the split-DNS rules installed for peers' domains.
+type splitDNS struct {
+	mu          sync.Mutex
+	backend     dnsConfigurator
+	installed   map[string][]string
+	unavailable bool // this host has no backend
+	warned      bool // and that was logged
+}
+
+// syncSplitDNS installs the domains peers advertise, when they changed.
+// routed is the accepted map of resolveRoutes.
+func (d *Daemon) syncSplitDNS(peers []*PeerInfo, routed map[string][]string) {
+	// A node on a Tunnel leaves the host resolver alone.
+	if d.config.DisableSplitDNS || d.tunnel != nil {
+		return
+	}
+	domains := resolveSplitDNS(peers, d.localNode.WGPubKey, routed)
+
+	s := &d.splitDNS
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if splitDNSEqual(s.installed, domains) {
+		return
+	}
+	if s.backend == nil && !s.unavailable {
+		s.backend = newDNSConfigurator(d.config.SplitDNSResolvConf)
+		s.unavailable = s.backend == nil
+	}
+	if s.backend == nil {
+		if !s.warned {
+			log.Printf("[DNS] Split DNS is unavailable without systemd-resolved; not installing %s (--dns-resolv-conf puts the mesh resolvers in %s instead)",
+				strings.Join(sortedDomains(domains), ", "), resolvConfPath)
+			s.warned = true
+		}
+		return
+	}
+	if err := s.backend.Apply(d.config.InterfaceName, domains); err != nil {
+		log.Printf("[DNS] Failed to update split DNS via %s: %v", s.backend.Name(), err)
+		return
+	}
+	if len(domains) == 0 {
+		log.Printf("[DNS] Removed split DNS rules (%s)", s.backend.Name())
+	} else {
+		parts := make([]string, 0, len(domains))
+		for _, domain := range sortedDomains(domains) {
+			parts = append(parts, domain+" via "+strings.Join(domains[domain], ","))
+		}
+		log.Printf("[DNS] Split DNS via %s: %s", s.backend.Name(), strings.Join(parts, ", "))
+	}
+	s.installed = domains
+}
+
+// teardownSplitDNS removes the rules installed by syncSplitDNS.
+func (d *Daemon) teardownSplitDNS() {
+	s := &d.splitDNS
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.installed) == 0 || s.backend == nil {
+		return
+	}
+	if err := s.backend.Apply(d.config.InterfaceName, nil); err != nil {
+		log.Printf("[Shutdown] Failed to remove split DNS rules: %v", err)
+	}
+	s.installed = nil
+}
+
+func splitDNSEqual(a, b map[string][]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for domain, resolvers := range a {
+		if !slices.Equal(resolvers, b[domain]) {
+			return false
+		}
+	}
+	return true
+}
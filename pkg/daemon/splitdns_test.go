@@ -0,0 +1,173 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+func TestParseAdvertiseDNS(t *testing.T) {
+	domains, err := ParseAdvertiseDNS(" Home.LAN.=192.168.1.53, corp.lan=10.0.0.53,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []crypto.DNSDomain{{Domain: "home.lan", Resolver: "192.168.1.53"}, {Domain: "corp.lan", Resolver: "10.0.0.53"}}
+	if !reflect.DeepEqual(domains, want) {
+		t.Errorf("ParseAdvertiseDNS = %v, want %v", domains, want)
+	}
+	if domains, err := ParseAdvertiseDNS(""); err != nil || domains != nil {
+		t.Errorf("empty --advertise-dns = %v, %v", domains, err)
+	}
+	for _, bad := range []string{"home.lan", "home.lan=nas", "-home.lan=10.0.0.1", "a.lan=10.0.0.1,a.lan=10.0.0.2", "lan=10.0.0.1", "com=10.0.0.1"} {
+		if _, err := ParseAdvertiseDNS(bad); err == nil {
+			t.Errorf("ParseAdvertiseDNS(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestResolveSplitDNS(t *testing.T) {
+	peers := []*PeerInfo{
+		{WGPubKey: "gw1", MeshIP: "10.42.0.1", DNS: []crypto.DNSDomain{
+			{Domain: "home.lan", Resolver: "192.168.1.53"},
+			{Domain: "mesh.lan", Resolver: "10.42.0.1"},
+			// Not routed through gw1: ignored.
+			{Domain: "corp.lan", Resolver: "10.0.0.53"},
+		}},
+		{WGPubKey: "gw2", MeshIP: "10.42.0.2", DNS: []crypto.DNSDomain{{Domain: "home.lan", Resolver: "192.168.1.1"}}},
+		{WGPubKey: "local", MeshIP: "10.42.0.3", DNS: []crypto.DNSDomain{{Domain: "own.lan", Resolver: "10.42.0.3"}}},
+	}
+	routed := map[string][]string{
+		"gw1": {"192.168.1.0/24"},
+		"gw2": {"192.168.1.0/24"},
+	}
+	got := resolveSplitDNS(peers, "local", routed)
+	want := map[string][]string{
+		"home.lan": {"192.168.1.1", "192.168.1.53"},
+		"mesh.lan": {"10.42.0.1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveSplitDNS = %v, want %v", got, want)
+	}
+}
+
+func TestRewriteResolvConf(t *testing.T) {
+	original := "nameserver 1.1.1.1\nsearch example.com\n"
+	domains := map[string][]string{"home.lan": {"192.168.1.53"}, "corp.lan": {"10.0.0.53", "192.168.1.53"}}
+
+	updated, err := rewriteResolvConf(original, "wg0", domains)
+	want := splitDNSMarker + " for wg0: corp.lan home.lan\nnameserver 10.0.0.53\nnameserver 192.168.1.53\n" + resolvConfBlockEnd + "\n" + original
+	if err != nil || updated != want {
+		t.Errorf("rewriteResolvConf =\n%s\n%v\nwant\n%s", updated, err, want)
+	}
+	// Rewriting replaces the block rather than stacking another one.
+	if again, _ := rewriteResolvConf(updated, "wg0", domains); again != want {
+		t.Errorf("second rewrite =\n%s", again)
+	}
+	if restored, _ := rewriteResolvConf(updated, "wg0", nil); restored != original {
+		t.Errorf("removing the block left\n%s", restored)
+	}
+
+	// The host's own nameservers keep their slots.
+	two := "nameserver 1.1.1.1\nnameserver 8.8.8.8\n"
+	updated, err = rewriteResolvConf(two, "wg0", domains)
+	if err != nil || strings.Count(updated, "nameserver") != resolvConfMaxServer || !strings.HasSuffix(updated, two) {
+		t.Errorf("with two host nameservers: %v\n%s", err, updated)
+	}
+	if _, err := rewriteResolvConf(two+"nameserver 9.9.9.9\n", "wg0", domains); err == nil {
+		t.Error("mesh resolvers pushed out a host nameserver")
+	}
+}
+
+func TestSplitDNSUnavailable(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("macOS always has /etc/resolver")
+	}
+	d := &Daemon{
+		config:    &Config{InterfaceName: "wg0"},
+		localNode: &LocalNode{WGPubKey: "local"},
+	}
+	peers := []*PeerInfo{{WGPubKey: "gw1", MeshIP: "10.42.0.1", DNS: []crypto.DNSDomain{{Domain: "home.lan", Resolver: "10.42.0.1"}}}}
+
+	// No resolvectl, and no --dns-resolv-conf: nothing is installed.
+	withMockExecutor(t, &MockCommandExecutor{}, func() {
+		d.syncSplitDNS(peers, nil)
+	})
+	if d.splitDNS.backend != nil || d.splitDNS.installed != nil || !d.splitDNS.warned {
+		t.Errorf("split DNS without a backend: backend=%v installed=%v warned=%v", d.splitDNS.backend, d.splitDNS.installed, d.splitDNS.warned)
+	}
+
+	d.config.SplitDNSResolvConf = true
+	withMockExecutor(t, &MockCommandExecutor{}, func() {
+		if _, ok := newDNSConfigurator(d.config.SplitDNSResolvConf).(*resolvConfDNS); !ok {
+			t.Error("--dns-resolv-conf did not select /etc/resolv.conf")
+		}
+	})
+}
+
+func TestResolverDirDNS(t *testing.T) {
+	dir := t.TempDir()
+	foreign := filepath.Join(dir, "other.lan")
+	if err := os.WriteFile(foreign, []byte("nameserver 9.9.9.9\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := &resolverDirDNS{dir: dir}
+
+	if err := r.Apply("utun5", map[string][]string{"home.lan": {"192.168.1.53"}}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "home.lan"))
+	if err != nil || !strings.Contains(string(data), "nameserver 192.168.1.53\n") {
+		t.Fatalf("home.lan resolver file = %q, %v", data, err)
+	}
+	if err := r.Apply("utun5", map[string][]string{"other.lan": {"10.0.0.53"}}); err == nil {
+		t.Error("a resolver file wgmesh did not write was overwritten")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "home.lan")); !os.IsNotExist(err) {
+		t.Error("resolver file of a withdrawn domain was kept")
+	}
+	if err := r.Apply("utun5", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Errorf("foreign resolver file was removed: %v", err)
+	}
+}
+
+func TestSplitDNSResolved(t *testing.T) {
+	var calls []string
+	mock := &MockCommandExecutor{
+		commandFunc: func(name string, args ...string) Command {
+			calls = append(calls, name+" "+strings.Join(args, " "))
+			return &MockCommand{}
+		},
+	}
+	d := &Daemon{
+		config:    &Config{InterfaceName: "wg0"},
+		localNode: &LocalNode{WGPubKey: "local"},
+	}
+	d.splitDNS.backend = resolvedDNS{}
+	peers := []*PeerInfo{{WGPubKey: "gw1", MeshIP: "10.42.0.1", DNS: []crypto.DNSDomain{{Domain: "home.lan", Resolver: "192.168.1.53"}}}}
+	routed := map[string][]string{"gw1": {"192.168.1.0/24"}}
+
+	withMockExecutor(t, mock, func() {
+		d.syncSplitDNS(peers, routed)
+		// Unchanged domains are not reapplied.
+		d.syncSplitDNS(peers, routed)
+		d.teardownSplitDNS()
+	})
+
+	want := []string{
+		"resolvectl dns wg0 192.168.1.53",
+		"resolvectl domain wg0 ~home.lan",
+		"resolvectl default-route wg0 false",
+		"resolvectl revert wg0",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %q, want %q", calls, want)
+	}
+}
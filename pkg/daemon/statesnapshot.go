@@ -0,0 +1,170 @@
+package daemon
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+)
+
+// StateSnapshotVersion is the format version written by ExportState.
+const StateSnapshotVersion = 1
+
+// ImportMethod is the discovery method of peers seeded by ImportState.
+const ImportMethod = "import"
+
+// StateSnapshot is a dump of the peer store that can seed another node of
+// the same mesh, so a migrated or rebuilt host does not have to wait for
+// DHT discovery to find its peers again.
+type StateSnapshot struct {
+	Version    int            `json:"version"`
+	NetworkID  string         `json:"network_id"` // hex prefix, as logged at startup
+	ExportedBy string         `json:"exported_by"`
+	ExportedAt int64          `json:"exported_at"`
+	Peers      []SnapshotPeer `json:"peers"`
+}
+
+// SnapshotPeer is one peer of a StateSnapshot: its cache entry plus how it
+// was discovered and where its exchange listener is.
+type SnapshotPeer struct {
+	PeerCacheEntry
+	RoutesAnnounced bool     `json:"routes_announced,omitempty"`
+	EndpointMethod  string   `json:"endpoint_method,omitempty"`
+	DiscoveredVia   []string `json:"discovered_via,omitempty"`
+	ControlEndpoint string   `json:"control_endpoint,omitempty"` // derived from Endpoint; informational
+}
+
+// StateImportData summarizes an ImportState call.
+type StateImportData struct {
+	Imported int
+	Skipped  int
+}
+
+// ExportState dumps the peer store, with this node as one of the peers so
+// an importing node can reach it too.
+func (d *Daemon) ExportState() ([]byte, error) {
+	snap := &StateSnapshot{
+		Version:    StateSnapshotVersion,
+		NetworkID:  d.networkIDPrefix(),
+		ExportedBy: d.localNode.WGPubKey,
+		ExportedAt: time.Now().Unix(),
+	}
+
+	self := &PeerInfo{
+		WGPubKey:         d.localNode.WGPubKey,
+		Hostname:         d.localNode.Hostname,
+		MeshIP:           d.localNode.MeshIP,
+		MeshIPv6:         d.localNode.MeshIPv6,
+		Endpoint:         d.localNode.GetEndpoint(),
+		Introducer:       d.localNode.Introducer,
+		RoutableNetworks: d.GetAdvertiseRoutes(),
+		RoutesAnnounced:  true,
+		NATType:          d.localNode.NATType,
+		PairPSK:          d.pairPSKEnabled(),
+		LastSeen:         time.Now(),
+		Tags:             d.localNode.Tags,
+		DNS:              d.localNode.DNS,
+	}
+	snap.Peers = append(snap.Peers, d.snapshotPeer(self))
+	for _, p := range d.peerStore.GetAll() {
+		if p.WGPubKey == d.localNode.WGPubKey {
+			continue
+		}
+		snap.Peers = append(snap.Peers, d.snapshotPeer(p))
+	}
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	return data, nil
+}
+
+func (d *Daemon) snapshotPeer(p *PeerInfo) SnapshotPeer {
+	return SnapshotPeer{
+		PeerCacheEntry: PeerCacheEntry{
+			WGPubKey:         p.WGPubKey,
+			Hostname:         p.Hostname,
+			MeshIP:           p.MeshIP,
+			MeshIPv6:         p.MeshIPv6,
+			Endpoint:         p.Endpoint,
+			Introducer:       p.Introducer,
+			RoutableNetworks: p.RoutableNetworks,
+			NATType:          p.NATType,
+			Candidates:       p.Candidates,
+			Identity:         p.Identity,
+			PairPSK:          p.PairPSK,
+			LastSeen:         p.LastSeen.Unix(),
+			Tags:             p.Tags,
+			DNS:              p.DNS,
+		},
+		RoutesAnnounced: p.RoutesAnnounced,
+		EndpointMethod:  p.EndpointMethod,
+		DiscoveredVia:   p.DiscoveredVia,
+		ControlEndpoint: d.controlEndpoint(p.Endpoint),
+	}
+}
+
+// controlEndpoint returns the exchange address that goes with a WireGuard
+// endpoint: the same host on the mesh's gossip port.
+func (d *Daemon) controlEndpoint(endpoint string) string {
+	host, _, err := net.SplitHostPort(endpoint)
+	if err != nil || host == "" || net.ParseIP(host).IsUnspecified() {
+		return ""
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(d.config.Keys.GossipPort)))
+}
+
+func (d *Daemon) networkIDPrefix() string {
+	return hex.EncodeToString(d.config.Keys.NetworkID[:8])
+}
+
+// ImportState seeds the peer store from a snapshot taken by ExportState on
+// a node of the same mesh. Imported peers count as seen now, so discovery
+// has the usual PeerRemoveTimeout to confirm them before they expire.
+// Peers the store already knows are merged like any other announcement.
+func (d *Daemon) ImportState(data []byte) (*StateImportData, error) {
+	var snap StateSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if snap.Version != StateSnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d (want %d)", snap.Version, StateSnapshotVersion)
+	}
+	if snap.NetworkID != d.networkIDPrefix() {
+		return nil, fmt.Errorf("snapshot is from another mesh (network ID %s, this mesh is %s)", snap.NetworkID, d.networkIDPrefix())
+	}
+
+	result := &StateImportData{}
+	now := time.Now()
+	for _, entry := range snap.Peers {
+		if entry.WGPubKey == "" || entry.WGPubKey == d.localNode.WGPubKey || !meshIPInSubnet(entry.MeshIP, d.config) {
+			result.Skipped++
+			continue
+		}
+		d.peerStore.Update(&PeerInfo{
+			WGPubKey:         entry.WGPubKey,
+			Hostname:         entry.Hostname,
+			MeshIP:           entry.MeshIP,
+			MeshIPv6:         entry.MeshIPv6,
+			Endpoint:         entry.Endpoint,
+			Introducer:       entry.Introducer,
+			RoutableNetworks: entry.RoutableNetworks,
+			RoutesAnnounced:  entry.RoutesAnnounced,
+			NATType:          entry.NATType,
+			Candidates:       entry.Candidates,
+			Identity:         entry.Identity,
+			PairPSK:          entry.PairPSK,
+			LastSeen:         now,
+			Tags:             entry.Tags,
+			DNS:              entry.DNS,
+		}, ImportMethod)
+		result.Imported++
+	}
+
+	log.Printf("[State] Imported %d peers from a snapshot by %s... (%d skipped)", result.Imported, shortKey(snap.ExportedBy), result.Skipped)
+	return result, nil
+}
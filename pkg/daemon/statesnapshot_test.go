@@ -0,0 +1,117 @@
+package daemon
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newStateTestDaemon(t *testing.T, secret, pubKey string) *Daemon {
+	t.Helper()
+	cfg, err := NewConfig(DaemonOpts{Secret: secret, MeshSubnet: "10.0.0.0/16"})
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	d := &Daemon{
+		config:    cfg,
+		localNode: &LocalNode{WGPubKey: pubKey, Hostname: pubKey, MeshIP: "10.0.0.1"},
+		peerStore: NewPeerStore(),
+	}
+	d.localNode.SetEndpoint("198.51.100.1:51820")
+	return d
+}
+
+func TestExportImportState(t *testing.T) {
+	src := newStateTestDaemon(t, testConfigSecret, "srcKey")
+	src.peerStore.Update(&PeerInfo{
+		WGPubKey:         "peerA",
+		Hostname:         "alpha",
+		MeshIP:           "10.0.0.2",
+		Endpoint:         "203.0.113.5:51820",
+		RoutableNetworks: []string{"192.168.10.0/24"},
+		RoutesAnnounced:  true,
+		Candidates:       []string{"192.168.1.20:51820"},
+		Identity:         "identityA",
+		LastSeen:         time.Now().Add(-time.Hour),
+	}, "dht")
+	src.peerStore.Update(&PeerInfo{WGPubKey: "dstKey", MeshIP: "10.0.0.3"}, "gossip")
+	src.peerStore.Update(&PeerInfo{WGPubKey: "outside", MeshIP: "172.16.0.9"}, "lan")
+
+	data, err := src.ExportState()
+	if err != nil {
+		t.Fatalf("ExportState failed: %v", err)
+	}
+
+	var snap StateSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatalf("snapshot is not valid JSON: %v", err)
+	}
+	if snap.Version != StateSnapshotVersion || snap.ExportedBy != "srcKey" || len(snap.Peers) != 4 {
+		t.Fatalf("unexpected snapshot header: version %d, by %s, %d peers", snap.Version, snap.ExportedBy, len(snap.Peers))
+	}
+	if self := snap.Peers[0]; self.WGPubKey != "srcKey" || self.Endpoint != "198.51.100.1:51820" {
+		t.Errorf("first entry should be the exporting node, got %+v", self)
+	}
+	for _, p := range snap.Peers {
+		if p.WGPubKey == "peerA" {
+			if p.ControlEndpoint != "203.0.113.5:"+strconv.Itoa(int(src.config.Keys.GossipPort)) {
+				t.Errorf("control endpoint = %q", p.ControlEndpoint)
+			}
+			if len(p.DiscoveredVia) != 1 || p.DiscoveredVia[0] != "dht" || p.EndpointMethod != "dht" {
+				t.Errorf("provenance not exported: %+v", p)
+			}
+		}
+	}
+
+	dst := newStateTestDaemon(t, testConfigSecret, "dstKey")
+	result, err := dst.ImportState(data)
+	if err != nil {
+		t.Fatalf("ImportState failed: %v", err)
+	}
+	// dstKey is the importing node itself and "outside" is not in the mesh subnet.
+	if result.Imported != 2 || result.Skipped != 2 {
+		t.Errorf("ImportState = %+v, want 2 imported, 2 skipped", result)
+	}
+
+	peer, ok := dst.peerStore.Get("peerA")
+	if !ok {
+		t.Fatal("peerA was not imported")
+	}
+	if peer.Endpoint != "203.0.113.5:51820" || peer.Identity != "identityA" || len(peer.RoutableNetworks) != 1 {
+		t.Errorf("imported peer lost fields: %+v", peer)
+	}
+	if peer.DiscoveredVia[0] != ImportMethod {
+		t.Errorf("DiscoveredVia = %v, want %s", peer.DiscoveredVia, ImportMethod)
+	}
+	if time.Since(peer.LastSeen) > time.Minute {
+		t.Error("imported peers should count as seen at import time")
+	}
+	if _, ok := dst.peerStore.Get("srcKey"); !ok {
+		t.Error("the exporting node should be imported as a peer")
+	}
+}
+
+func TestImportStateRejectsOtherMesh(t *testing.T) {
+	src := newStateTestDaemon(t, "another-mesh-secret-that-is-long-enough", "srcKey")
+	data, err := src.ExportState()
+	if err != nil {
+		t.Fatalf("ExportState failed: %v", err)
+	}
+
+	dst := newStateTestDaemon(t, testConfigSecret, "dstKey")
+	if _, err := dst.ImportState(data); err == nil || !strings.Contains(err.Error(), "another mesh") {
+		t.Errorf("ImportState from another mesh: err = %v", err)
+	}
+	if dst.peerStore.Count() != 0 {
+		t.Error("nothing should be imported from another mesh")
+	}
+
+	if _, err := dst.ImportState([]byte(`{"version": 99}`)); err == nil {
+		t.Error("expected error for unknown snapshot version")
+	}
+	if _, err := dst.ImportState([]byte(`not json`)); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
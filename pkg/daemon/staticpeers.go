@@ -0,0 +1,203 @@
+package daemon
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+const (
+	// StaticPeersRefresh is how often a --static-peers manifest is re-read,
+	// keeping its peers in the peer store and picking up edits.
+	StaticPeersRefresh = time.Minute
+
+	// staticManifestMaxSize caps how much of a manifest file is read.
+	staticManifestMaxSize = 8 << 20
+)
+
+// StaticManifest is the peer list of an offline mesh, which takes its peers
+// only from this file instead of the DHT, LAN discovery and peer exchange.
+// It is signed with an HMAC under the mesh's membership key, so only holders
+// of the secret can produce one that nodes accept.
+type StaticManifest struct {
+	Peers     []StaticPeer `json:"peers"`
+	Signature string       `json:"signature,omitempty"` // base64 HMAC-SHA256 of the JSON-encoded Peers
+}
+
+// StaticPeer is one peer of a StaticManifest.
+type StaticPeer struct {
+	PubKey   string   `json:"pubkey"`
+	Hostname string   `json:"hostname,omitempty"`
+	MeshIP   string   `json:"mesh_ip"`
+	Endpoint string   `json:"endpoint,omitempty"` // host:port; empty for peers that only dial out
+	Routes   []string `json:"routes,omitempty"`
+	PairPSK  bool     `json:"pair_psk,omitempty"` // uses per-pair preshared keys with peers also marked
+}
+
+// ParseStaticManifest decodes a manifest and checks each peer's fields. It
+// does not check the signature.
+func ParseStaticManifest(data []byte) (*StaticManifest, error) {
+	var m StaticManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	for i, p := range m.Peers {
+		if err := crypto.ValidateWGPubKey(p.PubKey); err != nil {
+			return nil, fmt.Errorf("peer %d: pubkey: %w", i, err)
+		}
+		if net.ParseIP(p.MeshIP) == nil {
+			return nil, fmt.Errorf("peer %d: invalid mesh IP %q", i, p.MeshIP)
+		}
+		if p.Endpoint != "" {
+			if _, _, err := net.SplitHostPort(p.Endpoint); err != nil {
+				return nil, fmt.Errorf("peer %d: invalid endpoint %q: %w", i, p.Endpoint, err)
+			}
+		}
+		for _, r := range p.Routes {
+			if _, _, err := net.ParseCIDR(r); err != nil {
+				return nil, fmt.Errorf("peer %d: invalid route %q: %w", i, r, err)
+			}
+		}
+	}
+	return &m, nil
+}
+
+// Sign sets the manifest's signature under membershipKey.
+func (m *StaticManifest) Sign(membershipKey []byte) error {
+	sig, err := m.mac(membershipKey)
+	if err != nil {
+		return err
+	}
+	m.Signature = base64.StdEncoding.EncodeToString(sig)
+	return nil
+}
+
+// Verify checks the manifest's signature under membershipKey.
+func (m *StaticManifest) Verify(membershipKey []byte) error {
+	if m.Signature == "" {
+		return errors.New("manifest is not signed")
+	}
+	got, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	want, err := m.mac(membershipKey)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(got, want) {
+		return errors.New("signature does not match (signed with another mesh secret, or edited after signing)")
+	}
+	return nil
+}
+
+func (m *StaticManifest) mac(membershipKey []byte) ([]byte, error) {
+	data, err := json.Marshal(m.Peers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode peers: %w", err)
+	}
+	mac := hmac.New(sha256.New, membershipKey)
+	mac.Write([]byte("wgmesh-static-peers-v1|"))
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+// LoadStaticManifest reads a manifest file and verifies its signature.
+func LoadStaticManifest(path string, membershipKey []byte) (*StaticManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, staticManifestMaxSize))
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseStaticManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.Verify(membershipKey); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// mergeStaticManifest adds the peers of a manifest to the peer store as
+// static peers, skipping the local node and mesh IPs outside this mesh's
+// subnet. The local node's own entry only says whether it uses per-pair
+// preshared keys. It returns how many were merged and skipped.
+func (d *Daemon) mergeStaticManifest(m *StaticManifest) (merged, skipped int) {
+	self := false
+	defer func() { d.staticPairPSK.Store(self) }()
+	for _, p := range m.Peers {
+		if p.PubKey == d.localNode.WGPubKey {
+			self = p.PairPSK
+			skipped++
+			continue
+		}
+		if !meshIPInSubnet(p.MeshIP, d.config) {
+			skipped++
+			continue
+		}
+		d.peerStore.Update(&PeerInfo{
+			WGPubKey:         p.PubKey,
+			Hostname:         p.Hostname,
+			MeshIP:           p.MeshIP,
+			Endpoint:         p.Endpoint,
+			RoutableNetworks: p.Routes,
+			RoutesAnnounced:  true,
+			PairPSK:          p.PairPSK,
+		}, StaticMethod)
+		merged++
+	}
+	return merged, skipped
+}
+
+// staticPeersLoop merges the --static-peers manifest into the peer store now
+// and every StaticPeersRefresh. A manifest that no longer reads or verifies
+// leaves the peers already merged to expire as usual.
+func (d *Daemon) staticPeersLoop() {
+	path := d.config.StaticPeers
+	lastErr := ""
+	lastMerged, lastSkipped := -1, -1
+
+	refresh := func() {
+		m, err := LoadStaticManifest(path, d.config.Keys.MembershipKey[:])
+		if err != nil {
+			if msg := err.Error(); msg != lastErr {
+				log.Printf("[Static] Failed to load static peers %s: %v", path, err)
+				lastErr = msg
+			}
+			return
+		}
+		lastErr = ""
+		merged, skipped := d.mergeStaticManifest(m)
+		if merged != lastMerged || skipped != lastSkipped {
+			log.Printf("[Static] Merged %d peers from static peers %s (%d skipped: own key or outside the mesh subnet)", merged, path, skipped)
+			lastMerged, lastSkipped = merged, skipped
+		}
+	}
+
+	refresh()
+	ticker := time.NewTicker(StaticPeersRefresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
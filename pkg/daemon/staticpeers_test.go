@@ -0,0 +1,123 @@
+package daemon
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+func testStaticKey(b byte) string {
+	key := make([]byte, 32)
+	key[0] = b
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// writeStaticManifest writes peers as a manifest signed under secret.
+func writeStaticManifest(t *testing.T, secret string, peers []StaticPeer) string {
+	t.Helper()
+	keys, err := crypto.DeriveKeys(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &StaticManifest{Peers: peers}
+	if err := m.Sign(keys.MembershipKey[:]); err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "peers.json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadStaticManifest_Verifies(t *testing.T) {
+	peers := []StaticPeer{{PubKey: testStaticKey(1), MeshIP: "10.0.1.1", Endpoint: "192.0.2.1:51820", Routes: []string{"192.168.5.0/24"}}}
+	path := writeStaticManifest(t, testConfigSecret, peers)
+	keys, _ := crypto.DeriveKeys(testConfigSecret)
+
+	m, err := LoadStaticManifest(path, keys.MembershipKey[:])
+	if err != nil {
+		t.Fatalf("LoadStaticManifest failed: %v", err)
+	}
+	if len(m.Peers) != 1 || m.Peers[0].Endpoint != "192.0.2.1:51820" {
+		t.Errorf("Peers = %+v", m.Peers)
+	}
+
+	other, _ := crypto.DeriveKeys("another-mesh-secret-entirely")
+	if _, err := LoadStaticManifest(path, other.MembershipKey[:]); err == nil {
+		t.Error("manifest verified under another mesh's key")
+	}
+
+	data, _ := os.ReadFile(path)
+	tampered := strings.Replace(string(data), "192.0.2.1", "192.0.2.66", 1)
+	if err := os.WriteFile(path, []byte(tampered), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadStaticManifest(path, keys.MembershipKey[:]); err == nil {
+		t.Error("manifest edited after signing verified")
+	}
+}
+
+func TestParseStaticManifest_RejectsInvalidPeers(t *testing.T) {
+	for _, peer := range []string{
+		`{"pubkey":"not-a-key","mesh_ip":"10.0.1.1"}`,
+		`{"pubkey":"` + testStaticKey(1) + `","mesh_ip":"10.0.1"}`,
+		`{"pubkey":"` + testStaticKey(1) + `","mesh_ip":"10.0.1.1","endpoint":"192.0.2.1"}`,
+		`{"pubkey":"` + testStaticKey(1) + `","mesh_ip":"10.0.1.1","routes":["192.168.5.0"]}`,
+	} {
+		if _, err := ParseStaticManifest([]byte(`{"peers":[` + peer + `]}`)); err == nil {
+			t.Errorf("accepted %s", peer)
+		}
+	}
+}
+
+func TestMergeStaticManifest(t *testing.T) {
+	self := testStaticKey(9)
+	d := newStateTestDaemon(t, testConfigSecret, self)
+	merged, skipped := d.mergeStaticManifest(&StaticManifest{Peers: []StaticPeer{
+		{PubKey: testStaticKey(1), Hostname: "web1", MeshIP: "10.0.1.1", Endpoint: "192.0.2.1:51820"},
+		{PubKey: self, MeshIP: "10.0.0.1"},
+		{PubKey: testStaticKey(2), MeshIP: "172.16.0.1"},
+	}})
+	if merged != 1 || skipped != 2 {
+		t.Errorf("mergeStaticManifest = %d merged, %d skipped; want 1, 2", merged, skipped)
+	}
+	web1, ok := d.peerStore.Get(testStaticKey(1))
+	if !ok {
+		t.Fatal("web1 not merged")
+	}
+	if web1.Hostname != "web1" || web1.Endpoint != "192.0.2.1:51820" || web1.DiscoveredVia[0] != StaticMethod {
+		t.Errorf("web1 = %+v", web1)
+	}
+}
+
+func TestNewConfigStaticPeersDisablesDiscovery(t *testing.T) {
+	path := writeStaticManifest(t, testConfigSecret, []StaticPeer{{PubKey: testStaticKey(1), MeshIP: "10.0.1.1"}})
+	cfg, err := NewConfig(DaemonOpts{Secret: testConfigSecret, StaticPeers: path, STUNServers: []string{"stun.example.com:3478"}})
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	if cfg.LANDiscovery || cfg.STUNListenPort != 0 || len(cfg.STUNServers) != 0 {
+		t.Errorf("LANDiscovery = %v, STUNListenPort = %d, STUNServers = %v; want all off", cfg.LANDiscovery, cfg.STUNListenPort, cfg.STUNServers)
+	}
+
+	unsigned := filepath.Join(t.TempDir(), "unsigned.json")
+	if err := os.WriteFile(unsigned, []byte(`{"peers":[]}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewConfig(DaemonOpts{Secret: testConfigSecret, StaticPeers: unsigned}); err == nil {
+		t.Error("accepted an unsigned manifest")
+	}
+	if _, err := NewConfig(DaemonOpts{Secret: testConfigSecret, StaticPeers: path, DNSRendezvous: "_wgmesh._udp.example.com"}); err == nil {
+		t.Error("accepted static peers with DNS rendezvous")
+	}
+}
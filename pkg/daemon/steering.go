@@ -0,0 +1,176 @@
+package daemon
+
+import (
+	"log"
+	"net"
+	"time"
+)
+
+// Peers announce their LAN addresses among their endpoint candidates. When
+// one of them is on a subnet this node is attached to, reconcile points
+// WireGuard at it rather than at the peer's public endpoint, so traffic
+// between nodes on the same network stays off the internet. A LAN path is
+// given up for the WAN one after LANFailbackMisses mesh probes in a row
+// fail over it, or when a peer never handshaken with does not answer over
+// it within LANHandshakeTimeout. It is tried again after a backoff that
+// doubles with each failure, so an address that only looks local (two
+// sites numbering their networks alike) does not make the peer flap.
+const (
+	LANFailbackMisses   = 3
+	LANHandshakeTimeout = 30 * time.Second
+	LANStableProbes     = 12 // probes answered over a LAN path before its backoff resets
+	LANRetryMin         = time.Minute
+	LANRetryMax         = 30 * time.Minute
+)
+
+// lanPathState is the LAN steering part of a peer's pathState.
+type lanPathState struct {
+	active  bool      // the selected endpoint is a LAN path
+	since   time.Time // when it was selected
+	misses  int       // consecutive failed probes over it
+	hits    int       // probes answered over it
+	retryAt time.Time // no LAN path is tried before this
+	backoff time.Duration
+}
+
+// failed switches st off its LAN path and schedules the next attempt.
+func (st *lanPathState) failed(now time.Time) time.Duration {
+	st.backoff = min(max(2*st.backoff, LANRetryMin), LANRetryMax)
+	st.retryAt = now.Add(st.backoff)
+	st.active = false
+	return st.backoff
+}
+
+func (st *lanPathState) selected(now time.Time) {
+	st.active, st.since, st.misses, st.hits = true, now, 0, 0
+}
+
+// lanCandidate returns the first of peer's endpoints on a local subnet.
+func (d *Daemon) lanCandidate(peer *PeerInfo, localSubnets []*net.IPNet) string {
+	for _, ep := range d.pathCandidates(peer) {
+		if endpointOnAnyLocalSubnet(ep, localSubnets) {
+			return ep
+		}
+	}
+	return ""
+}
+
+// wanCandidate returns the first of peer's endpoints, the announced one
+// first, that is not on a local subnet.
+func (d *Daemon) wanCandidate(peer *PeerInfo, localSubnets []*net.IPNet) string {
+	for _, ep := range d.pathCandidates(peer) {
+		if !endpointOnAnyLocalSubnet(ep, localSubnets) {
+			return ep
+		}
+	}
+	return ""
+}
+
+// steerEndpoint selects peer's LAN path if it has one and none failed
+// recently, and falls back to its WAN path once the LAN one stops
+// answering.
+func (d *Daemon) steerEndpoint(peer *PeerInfo, handshakes map[string]int64, localSubnets []*net.IPNet, now time.Time) {
+	if d.tcpTransportEndpoint(peer.WGPubKey) != "" {
+		return
+	}
+	lan := d.lanCandidate(peer, localSubnets)
+	if lan == "" {
+		return
+	}
+	current := d.selectedEndpoint(peer)
+
+	var to, reason string
+	var retry time.Duration
+	d.pathMu.Lock()
+	st := d.pathStateLocked(peer.WGPubKey)
+	switch {
+	case st.trial:
+	case st.lan.active && current == st.endpoint:
+		silent := handshakes != nil && handshakes[peer.WGPubKey] == 0 && now.Sub(st.lan.since) > LANHandshakeTimeout
+		if st.lan.misses < LANFailbackMisses && !silent {
+			if st.lan.hits >= LANStableProbes {
+				st.lan.backoff = 0
+			}
+			break
+		}
+		if wan := d.wanCandidate(peer, localSubnets); wan != "" {
+			retry = st.lan.failed(now)
+			st.endpoint, to, reason = wan, wan, "lan_failed"
+		}
+	case now.Before(st.lan.retryAt):
+	default:
+		st.lan.selected(now)
+		st.endpoint = lan
+		if lan != current {
+			to, reason = lan, "lan_preferred"
+		}
+	}
+	d.pathMu.Unlock()
+
+	if to == "" {
+		return
+	}
+	if reason == "lan_failed" {
+		log.Printf("[Path] Peer %s... LAN path %s stopped answering, falling back to %s (retry in %v)", shortKey(peer.WGPubKey), current, to, retry)
+		d.resetPeerPathState(peer.WGPubKey)
+	} else {
+		log.Printf("[Path] Peer %s... is on a local subnet, using %s instead of %s", shortKey(peer.WGPubKey), to, current)
+	}
+	d.recordEvent(EventPathChanged, peer.WGPubKey, map[string]string{
+		"endpoint":      to,
+		"prev_endpoint": current,
+		"reason":        reason,
+	})
+}
+
+// settleLANPath records the endpoint a trial round settled on. Settling on
+// a WAN path while a LAN one is announced counts as a LAN failure.
+func (d *Daemon) settleLANPath(peer *PeerInfo, chosen string, localSubnets []*net.IPNet, now time.Time) {
+	lan := d.lanCandidate(peer, localSubnets)
+	d.pathMu.Lock()
+	defer d.pathMu.Unlock()
+	st := d.pathStateLocked(peer.WGPubKey)
+	st.endpoint = chosen
+	st.trial = false
+	switch {
+	case endpointOnAnyLocalSubnet(chosen, localSubnets):
+		if !st.lan.active {
+			st.lan.selected(now)
+		}
+	case lan != "":
+		st.lan.failed(now)
+	default:
+		st.lan.active = false
+	}
+}
+
+// recordPathProbe counts a mesh probe result against peer's LAN path.
+func (d *Daemon) recordPathProbe(pubKey string, ok bool) {
+	d.pathMu.Lock()
+	defer d.pathMu.Unlock()
+	st := d.paths[pubKey]
+	if st == nil || !st.lan.active || st.trial {
+		return
+	}
+	if ok {
+		st.lan.misses = 0
+		st.lan.hits++
+	} else {
+		st.lan.misses++
+	}
+}
+
+// preferLANPaths narrows trial results to the LAN paths among them, if any
+// answered.
+func preferLANPaths(rtts map[string]time.Duration, localSubnets []*net.IPNet) map[string]time.Duration {
+	lan := make(map[string]time.Duration)
+	for ep, rtt := range rtts {
+		if endpointOnAnyLocalSubnet(ep, localSubnets) {
+			lan[ep] = rtt
+		}
+	}
+	if len(lan) == 0 {
+		return rtts
+	}
+	return lan
+}
@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func steeringTestSubnets(t *testing.T) []*net.IPNet {
+	t.Helper()
+	_, subnet, err := net.ParseCIDR("192.168.1.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []*net.IPNet{subnet}
+}
+
+func TestSteerEndpoint(t *testing.T) {
+	d := newMinimalDaemon(t)
+	subnets := steeringTestSubnets(t)
+	peer := &PeerInfo{
+		WGPubKey:   "peer",
+		Endpoint:   "1.1.1.1:51820",
+		Candidates: []string{"192.168.1.2:51820"},
+	}
+	handshakes := map[string]int64{"peer": time.Now().Unix()}
+	now := time.Now()
+
+	d.steerEndpoint(peer, handshakes, subnets, now)
+	if got := d.selectedEndpoint(peer); got != "192.168.1.2:51820" {
+		t.Fatalf("selected %q, want the LAN path", got)
+	}
+
+	// Two misses are tolerated, the third falls back.
+	for i := 0; i < LANFailbackMisses; i++ {
+		if got := d.selectedEndpoint(peer); got != "192.168.1.2:51820" {
+			t.Fatalf("fell back after %d misses", i)
+		}
+		d.recordPathProbe("peer", false)
+		d.steerEndpoint(peer, handshakes, subnets, now)
+	}
+	if got := d.selectedEndpoint(peer); got != "1.1.1.1:51820" {
+		t.Fatalf("selected %q after failed probes, want the WAN path", got)
+	}
+
+	d.steerEndpoint(peer, handshakes, subnets, now.Add(LANRetryMin-time.Second))
+	if got := d.selectedEndpoint(peer); got != "1.1.1.1:51820" {
+		t.Fatal("LAN path retried before its backoff")
+	}
+	now = now.Add(LANRetryMin)
+	d.steerEndpoint(peer, handshakes, subnets, now)
+	if got := d.selectedEndpoint(peer); got != "192.168.1.2:51820" {
+		t.Fatal("LAN path not retried after its backoff")
+	}
+
+	// A second failure doubles the backoff; a stable LAN path resets it.
+	for i := 0; i < LANFailbackMisses; i++ {
+		d.recordPathProbe("peer", false)
+	}
+	d.steerEndpoint(peer, handshakes, subnets, now)
+	if got := d.paths["peer"].lan.backoff; got != 2*LANRetryMin {
+		t.Fatalf("backoff = %v, want %v", got, 2*LANRetryMin)
+	}
+	now = now.Add(2 * LANRetryMin)
+	d.steerEndpoint(peer, handshakes, subnets, now)
+	for i := 0; i < LANStableProbes; i++ {
+		d.recordPathProbe("peer", true)
+	}
+	d.steerEndpoint(peer, handshakes, subnets, now)
+	if got := d.paths["peer"].lan.backoff; got != 0 {
+		t.Errorf("backoff = %v after a stable LAN path, want 0", got)
+	}
+
+	var reasons []string
+	for _, ev := range d.GetRPCEvents(0) {
+		if ev.Type == EventPathChanged {
+			reasons = append(reasons, ev.Details["reason"])
+		}
+	}
+	want := []string{"lan_preferred", "lan_failed", "lan_preferred", "lan_failed", "lan_preferred"}
+	if len(reasons) != len(want) {
+		t.Fatalf("path_changed reasons = %v, want %v", reasons, want)
+	}
+	for i := range want {
+		if reasons[i] != want[i] {
+			t.Fatalf("path_changed reasons = %v, want %v", reasons, want)
+		}
+	}
+}
+
+func TestSteerEndpointSilentPeer(t *testing.T) {
+	d := newMinimalDaemon(t)
+	subnets := steeringTestSubnets(t)
+	peer := &PeerInfo{
+		WGPubKey:   "peer",
+		Endpoint:   "1.1.1.1:51820",
+		Candidates: []string{"192.168.1.2:51820"},
+	}
+	now := time.Now()
+
+	d.steerEndpoint(peer, map[string]int64{}, subnets, now)
+	d.steerEndpoint(peer, map[string]int64{}, subnets, now.Add(LANHandshakeTimeout-time.Second))
+	if got := d.selectedEndpoint(peer); got != "192.168.1.2:51820" {
+		t.Fatalf("selected %q before the handshake timeout, want the LAN path", got)
+	}
+	d.steerEndpoint(peer, map[string]int64{}, subnets, now.Add(LANHandshakeTimeout+time.Second))
+	if got := d.selectedEndpoint(peer); got != "1.1.1.1:51820" {
+		t.Fatalf("selected %q for a peer that never answered, want the WAN path", got)
+	}
+}
+
+func TestSteerEndpointWithoutLANCandidate(t *testing.T) {
+	d := newMinimalDaemon(t)
+	peer := &PeerInfo{
+		WGPubKey:   "peer",
+		Endpoint:   "1.1.1.1:51820",
+		Candidates: []string{"10.9.0.2:51820"},
+	}
+	d.steerEndpoint(peer, nil, steeringTestSubnets(t), time.Now())
+	if d.paths["peer"] != nil {
+		t.Error("peer without a LAN candidate should not be steered")
+	}
+}
+
+func TestPreferLANPaths(t *testing.T) {
+	subnets := steeringTestSubnets(t)
+	rtts := map[string]time.Duration{"1.1.1.1:51820": time.Millisecond, "192.168.1.2:51820": 3 * time.Millisecond}
+	if got := choosePath("1.1.1.1:51820", preferLANPaths(rtts, subnets), false); got != "192.168.1.2:51820" {
+		t.Errorf("choosePath = %q, want the LAN path even though it is slower", got)
+	}
+
+	delete(rtts, "192.168.1.2:51820")
+	if got := preferLANPaths(rtts, subnets); len(got) != 1 {
+		t.Errorf("without a reachable LAN path got %v, want all results", got)
+	}
+}
@@ -0,0 +1,176 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+)
+
+const (
+	sysctlIPv4Forward = "net.ipv4.ip_forward"
+	sysctlIPv6Forward = "net.ipv6.conf.all.forwarding"
+)
+
+// subnetRouter remembers what --subnet-router changed on the host so it can
+// be undone on shutdown.
+type subnetRouter struct {
+	mu        sync.Mutex
+	sysctls   map[string]string // sysctl key -> value before we enabled it
+	natRoutes []string          // networks the masquerade table was built for
+	natTable  bool
+}
+
+// setupSubnetRouter enables IP forwarding for the advertised networks and,
+// with --masquerade, installs an nftables table that masquerades mesh
+// traffic leaving towards them.
+func (d *Daemon) setupSubnetRouter() error {
+	if !d.config.SubnetRouter {
+		return nil
+	}
+	if err := d.refreshSubnetRouter(); err != nil {
+		d.teardownSubnetRouter()
+		return err
+	}
+	log.Printf("[Router] Subnet router enabled for %s", strings.Join(d.GetAdvertiseRoutes(), ", "))
+	return nil
+}
+
+// refreshSubnetRouter brings forwarding and NAT in line with the current
+// advertised routes. It is cheap to call when nothing changed.
+func (d *Daemon) refreshSubnetRouter() error {
+	if !d.config.SubnetRouter {
+		return nil
+	}
+	routes := d.GetAdvertiseRoutes()
+	v4, v6 := splitRouteFamilies(routes)
+
+	sr := &d.subnetRouter
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if len(v4) > 0 {
+		if err := sr.enableSysctl(sysctlIPv4Forward); err != nil {
+			return err
+		}
+	}
+	if len(v6) > 0 && !d.config.DisableIPv6 {
+		if err := sr.enableSysctl(sysctlIPv6Forward); err != nil {
+			return err
+		}
+	}
+
+	if !d.config.Masquerade || (sr.natTable && routeSlicesEqual(sr.natRoutes, routes)) {
+		return nil
+	}
+	table := subnetRouterTable(d.config.InterfaceName)
+	ruleset := buildMasqueradeRuleset(table, d.config.InterfaceName, v4, v6)
+	cmd := cmdExecutor.Command("nft", "-f", "-")
+	cmd.SetStdin(strings.NewReader(ruleset))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to install masquerade rules: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	sr.natTable = true
+	sr.natRoutes = append([]string(nil), routes...)
+	log.Printf("[Router] Masquerading mesh traffic to %s (nftables table inet %s)", strings.Join(routes, ", "), table)
+	return nil
+}
+
+// teardownSubnetRouter removes the masquerade table and restores the
+// forwarding sysctls to their previous values.
+func (d *Daemon) teardownSubnetRouter() {
+	sr := &d.subnetRouter
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	if sr.natTable {
+		table := subnetRouterTable(d.config.InterfaceName)
+		if out, err := cmdExecutor.Command("nft", "delete", "table", "inet", table).CombinedOutput(); err != nil {
+			log.Printf("[Shutdown] Failed to remove nftables table %s: %s: %v", table, strings.TrimSpace(string(out)), err)
+		}
+		sr.natTable = false
+		sr.natRoutes = nil
+	}
+	for key, prev := range sr.sysctls {
+		if out, err := cmdExecutor.Command("sysctl", "-w", key+"="+prev).CombinedOutput(); err != nil {
+			log.Printf("[Shutdown] Failed to restore %s=%s: %s: %v", key, prev, strings.TrimSpace(string(out)), err)
+		}
+	}
+	sr.sysctls = nil
+}
+
+// enableSysctl sets key to 1, remembering the old value the first time.
+func (sr *subnetRouter) enableSysctl(key string) error {
+	if _, done := sr.sysctls[key]; done {
+		return nil
+	}
+	out, err := cmdExecutor.Command("sysctl", "-n", key).Output()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	prev := strings.TrimSpace(string(out))
+	if prev != "1" {
+		if out, err := cmdExecutor.Command("sysctl", "-w", key+"=1").CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to enable %s: %s: %w", key, strings.TrimSpace(string(out)), err)
+		}
+	}
+	if sr.sysctls == nil {
+		sr.sysctls = make(map[string]string)
+	}
+	sr.sysctls[key] = prev
+	return nil
+}
+
+// subnetRouterTable names the per-interface nftables table. nft identifiers
+// only allow letters, digits and underscores here.
+func subnetRouterTable(iface string) string {
+	var sb strings.Builder
+	sb.WriteString("wgmesh_")
+	for _, r := range iface {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
+
+// buildMasqueradeRuleset renders an nft script that atomically replaces the
+// table with one masquerading forwarded mesh traffic bound for the given
+// networks. Declaring the table before deleting it keeps the script valid
+// when the table does not exist yet.
+func buildMasqueradeRuleset(table, iface string, v4, v6 []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "table inet %s\n", table)
+	fmt.Fprintf(&sb, "delete table inet %s\n", table)
+	fmt.Fprintf(&sb, "table inet %s {\n", table)
+	sb.WriteString("\tchain postrouting {\n")
+	sb.WriteString("\t\ttype nat hook postrouting priority srcnat; policy accept;\n")
+	if len(v4) > 0 {
+		fmt.Fprintf(&sb, "\t\tiifname %q oifname != %q ip daddr { %s } masquerade\n", iface, iface, strings.Join(v4, ", "))
+	}
+	if len(v6) > 0 {
+		fmt.Fprintf(&sb, "\t\tiifname %q oifname != %q ip6 daddr { %s } masquerade\n", iface, iface, strings.Join(v6, ", "))
+	}
+	sb.WriteString("\t}\n}\n")
+	return sb.String()
+}
+
+// splitRouteFamilies normalizes CIDRs and splits them by address family.
+// Invalid entries are skipped.
+func splitRouteFamilies(routes []string) (v4, v6 []string) {
+	for _, r := range routes {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(r))
+		if err != nil {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			v4 = append(v4, ipNet.String())
+		} else {
+			v6 = append(v6, ipNet.String())
+		}
+	}
+	return v4, v6
+}
@@ -0,0 +1,88 @@
+package daemon
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBuildMasqueradeRuleset(t *testing.T) {
+	got := buildMasqueradeRuleset("wgmesh_wg0", "wg0", []string{"192.168.1.0/24", "10.5.0.0/16"}, []string{"fd00:1::/64"})
+
+	for _, want := range []string{
+		"table inet wgmesh_wg0\ndelete table inet wgmesh_wg0\n",
+		"type nat hook postrouting priority srcnat; policy accept;",
+		`iifname "wg0" oifname != "wg0" ip daddr { 192.168.1.0/24, 10.5.0.0/16 } masquerade`,
+		`iifname "wg0" oifname != "wg0" ip6 daddr { fd00:1::/64 } masquerade`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ruleset missing %q:\n%s", want, got)
+		}
+	}
+
+	if v4only := buildMasqueradeRuleset("t", "wg0", []string{"192.168.1.0/24"}, nil); strings.Contains(v4only, "ip6 daddr") {
+		t.Errorf("IPv4-only ruleset should not match IPv6:\n%s", v4only)
+	}
+}
+
+func TestSubnetRouterTable(t *testing.T) {
+	if got := subnetRouterTable("wg-mesh.0"); got != "wgmesh_wg_mesh_0" {
+		t.Errorf("subnetRouterTable = %q", got)
+	}
+}
+
+func TestSubnetRouterSetupAndTeardown(t *testing.T) {
+	var calls []string
+	var ruleset string
+	mock := &MockCommandExecutor{
+		commandFunc: func(name string, args ...string) Command {
+			call := name + " " + strings.Join(args, " ")
+			calls = append(calls, call)
+			cmd := &MockCommand{}
+			switch {
+			case call == "sysctl -n "+sysctlIPv4Forward:
+				cmd.outputFunc = func() ([]byte, error) { return []byte("0\n"), nil }
+			case call == "nft -f -":
+				cmd.combinedOutputFunc = func() ([]byte, error) {
+					b, _ := io.ReadAll(cmd.stdin)
+					ruleset = string(b)
+					return nil, nil
+				}
+			}
+			return cmd
+		},
+	}
+
+	d := &Daemon{config: &Config{
+		InterfaceName:   "wg0",
+		AdvertiseRoutes: []string{"192.168.1.0/24"},
+		SubnetRouter:    true,
+		Masquerade:      true,
+		DisableIPv6:     true,
+	}}
+
+	withMockExecutor(t, mock, func() {
+		if err := d.setupSubnetRouter(); err != nil {
+			t.Fatalf("setupSubnetRouter: %v", err)
+		}
+		// Unchanged routes must not reinstall the table.
+		if err := d.refreshSubnetRouter(); err != nil {
+			t.Fatalf("refreshSubnetRouter: %v", err)
+		}
+		d.teardownSubnetRouter()
+	})
+
+	want := []string{
+		"sysctl -n " + sysctlIPv4Forward,
+		"sysctl -w " + sysctlIPv4Forward + "=1",
+		"nft -f -",
+		"nft delete table inet wgmesh_wg0",
+		"sysctl -w " + sysctlIPv4Forward + "=0",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("commands:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+	if !strings.Contains(ruleset, "ip daddr { 192.168.1.0/24 } masquerade") {
+		t.Errorf("unexpected ruleset:\n%s", ruleset)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+	"time"
 )
 
 const systemdUnitTemplate = `[Unit]
@@ -15,7 +16,9 @@ Wants=network-online.target
 
 [Service]
 Type=simple
-EnvironmentFile=/etc/wgmesh/secret.env
+{{- if .Credential}}
+LoadCredentialEncrypted={{.Credential}}
+{{- end}}
 ExecStart=/bin/sh -c 'exec {{.ExecStart}}'
 Restart=always
 RestartSec=5
@@ -40,99 +43,295 @@ func shellQuoteSystemd(s string) string {
 
 // SystemdServiceConfig holds configuration for generating the systemd service
 type SystemdServiceConfig struct {
-	Secret              string
-	InterfaceName       string
-	ListenPort          int
-	AdvertiseRoutes     []string
-	Privacy             bool
-	Gossip              bool
-	DisableLANDiscovery bool
-	DisableIPv6         bool
-	ForceRelay          bool
-	DisablePunching     bool
-	Introducer          bool
-	MeshSubnet          string
-	BinaryPath          string
+	Secret                    string
+	SecretFile                string // sealed or plaintext secret file passed as --secret-file
+	SecretCredential          string // systemd-creds encrypted credential; takes precedence over SecretFile
+	InterfaceName             string
+	ListenPort                int
+	AdvertiseRoutes           []string
+	Privacy                   bool
+	PrivateFirstContact       bool
+	Gossip                    bool
+	GossipDigest              bool
+	GossipRatchet             bool
+	DisableLANDiscovery       bool
+	LANMDNS                   bool
+	LANInterfaces             string
+	LANGroup                  string
+	LANBroadcast              bool
+	DisableIPv6               bool
+	ForceRelay                bool
+	DisablePunching           bool
+	Introducer                bool
+	DisableIntroducerElection bool
+	MeshSubnet                string
+	MeshName                  string
+	DNSRendezvous             string
+	STUNServers               []string
+	STUNListenPort            int
+	Keepalive                 int
+	SubnetRouter              bool
+	Masquerade                bool
+	Firewall                  bool
+	FirewallAllow             string
+	PinIdentities             bool
+	RequireSigned             bool
+	SOCKS5Proxy               string
+	MaxInstalledPeers         int
+	GracefulRestart           bool
+	AutoUpdate                bool
+	CentralState              string
+	StaticPeers               string
+	DiscoveryBandwidth        string
+	DHTShards                 int
+	Tags                      string
+	AdvertiseDNS              string
+	RouteChecks               string
+	DisableSplitDNS           bool
+	SplitDNSResolvConf        bool
+	ResourceLimits            string
+	PeerRateLimits            string
+	PeerWith                  string
+	RouteTable                int
+	RouteMetric               int
+	RouteFwmark               int
+	MTU                       int
+	MTUProbe                  bool
+	PortHop                   time.Duration
+	TCPTransportPort          int
+	Approvers                 string
+	HealthAddr                string
+	PeerDeadAfter             time.Duration
+	PeerRemoveAfter           time.Duration
+	BinaryPath                string
 }
 
 // GenerateSystemdUnit generates a systemd unit file for wgmesh
 func GenerateSystemdUnit(cfg SystemdServiceConfig) (string, error) {
-	if cfg.BinaryPath == "" {
-		// Find wgmesh binary
-		path, err := cmdExecutor.LookPath("wgmesh")
+	if err := resolveBinaryPath(&cfg); err != nil {
+		return "", err
+	}
+
+	// The secret is read from a file so it never appears in the unit or in
+	// the process list. %d is systemd's credentials directory.
+	secretFile := cfg.SecretFile
+	credential := ""
+	if cfg.SecretCredential != "" {
+		secretFile = "%d/" + SecretCredentialName
+		credential = SecretCredentialName + ":" + cfg.SecretCredential
+	} else if secretFile == "" {
+		secretFile = DefaultSecretFilePath
+	}
+	args := append([]string{cfg.BinaryPath}, shellJoinArgs(secretFile, cfg)...)
+
+	data := struct {
+		ExecStart  string
+		Credential string
+	}{
+		ExecStart:  strings.Join(args, " "),
+		Credential: credential,
+	}
+
+	tmpl, err := template.New("systemd").Parse(systemdUnitTemplate)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// resolveBinaryPath fills in cfg.BinaryPath from PATH or the running
+// executable when it is not set.
+func resolveBinaryPath(cfg *SystemdServiceConfig) error {
+	if cfg.BinaryPath != "" {
+		return nil
+	}
+	path, err := cmdExecutor.LookPath("wgmesh")
+	if err != nil {
+		path, err = filepath.Abs(os.Args[0])
 		if err != nil {
-			path, err = filepath.Abs(os.Args[0])
-			if err != nil {
-				return "", fmt.Errorf("could not determine wgmesh binary path: %w", err)
-			}
+			return fmt.Errorf("could not determine wgmesh binary path: %w", err)
 		}
-		cfg.BinaryPath = path
 	}
+	cfg.BinaryPath = path
+	return nil
+}
 
-	// Build ExecStart command - use env var for secret to avoid exposing in process list
-	args := []string{cfg.BinaryPath, "join", "--secret", "${WGMESH_SECRET}"}
+// serviceFlag is one join flag of a generated service. quote marks values
+// that may hold shell metacharacters; the rest are numbers, CIDRs and the
+// like, written as is to keep the generated command line readable.
+type serviceFlag struct {
+	name  string
+	value string // empty for boolean flags
+	quote bool
+}
+
+// joinFlags returns the join flags that reproduce cfg, in the order every
+// service generator writes them. The secret is not among them: each init
+// system has its own way of handing it over.
+func joinFlags(cfg SystemdServiceConfig) []serviceFlag {
+	var flags []serviceFlag
+	add := func(name, value string, quote bool) {
+		flags = append(flags, serviceFlag{name: name, value: value, quote: quote})
+	}
+	addBool := func(name string, on bool) {
+		if on {
+			add(name, "", false)
+		}
+	}
 
 	if cfg.InterfaceName != "" && cfg.InterfaceName != DefaultInterface {
-		// Shell-quote the interface name because ExecStart runs inside sh -c.
-		args = append(args, "--interface", shellQuoteSystemd(cfg.InterfaceName))
+		add("interface", cfg.InterfaceName, true)
 	}
 	if cfg.ListenPort != 0 && cfg.ListenPort != DefaultWGPort {
-		args = append(args, "--listen-port", fmt.Sprintf("%d", cfg.ListenPort))
+		add("listen-port", fmt.Sprintf("%d", cfg.ListenPort), false)
 	}
 	if len(cfg.AdvertiseRoutes) > 0 {
-		args = append(args, "--advertise-routes", strings.Join(cfg.AdvertiseRoutes, ","))
+		add("advertise-routes", strings.Join(cfg.AdvertiseRoutes, ","), false)
 	}
-	if cfg.Privacy {
-		args = append(args, "--privacy")
+	addBool("privacy", cfg.Privacy)
+	addBool("private-first-contact", cfg.PrivateFirstContact)
+	addBool("gossip", cfg.Gossip)
+	addBool("gossip-digest", cfg.GossipDigest)
+	addBool("gossip-ratchet", cfg.GossipRatchet)
+	addBool("no-lan-discovery", cfg.DisableLANDiscovery)
+	addBool("lan-mdns", cfg.LANMDNS)
+	if cfg.LANInterfaces != "" {
+		add("lan-interfaces", cfg.LANInterfaces, true)
 	}
-	if cfg.Gossip {
-		args = append(args, "--gossip")
+	if cfg.LANGroup != "" {
+		add("lan-group", cfg.LANGroup, false)
 	}
-	if cfg.DisableLANDiscovery {
-		args = append(args, "--no-lan-discovery")
+	addBool("lan-broadcast", cfg.LANBroadcast)
+	addBool("no-ipv6", cfg.DisableIPv6)
+	addBool("force-relay", cfg.ForceRelay)
+	addBool("no-punching", cfg.DisablePunching)
+	addBool("introducer", cfg.Introducer)
+	addBool("no-introducer-election", cfg.DisableIntroducerElection)
+	if cfg.MeshSubnet != "" {
+		add("mesh-subnet", cfg.MeshSubnet, false)
 	}
-	if cfg.DisableIPv6 {
-		args = append(args, "--no-ipv6")
+	if cfg.MeshName != "" {
+		add("mesh-name", cfg.MeshName, true)
 	}
-	if cfg.ForceRelay {
-		args = append(args, "--force-relay")
+	if cfg.DNSRendezvous != "" {
+		add("dns-rendezvous", cfg.DNSRendezvous, true)
 	}
-	if cfg.DisablePunching {
-		args = append(args, "--no-punching")
+	for _, server := range cfg.STUNServers {
+		add("stun-server", server, true)
 	}
-	if cfg.Introducer {
-		args = append(args, "--introducer")
+	if cfg.STUNListenPort != 0 {
+		add("stun-listen-port", fmt.Sprintf("%d", cfg.STUNListenPort), false)
 	}
-	if cfg.MeshSubnet != "" {
-		args = append(args, "--mesh-subnet", cfg.MeshSubnet)
+	if cfg.Keepalive != 0 {
+		add("keepalive", fmt.Sprintf("%d", cfg.Keepalive), false)
 	}
-
-	data := struct {
-		ExecStart string
-	}{
-		ExecStart: strings.Join(args, " "),
+	addBool("subnet-router", cfg.SubnetRouter)
+	addBool("masquerade", cfg.Masquerade)
+	addBool("firewall", cfg.Firewall)
+	if cfg.FirewallAllow != "" {
+		add("firewall-allow", cfg.FirewallAllow, true)
 	}
-
-	tmpl, err := template.New("systemd").Parse(systemdUnitTemplate)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse template: %w", err)
+	addBool("pin-identities", cfg.PinIdentities)
+	addBool("require-signed", cfg.RequireSigned)
+	if cfg.SOCKS5Proxy != "" {
+		add("socks5-proxy", cfg.SOCKS5Proxy, true)
 	}
-
-	var buf strings.Builder
-	if err := tmpl.Execute(&buf, data); err != nil {
-		return "", fmt.Errorf("failed to execute template: %w", err)
+	if cfg.MaxInstalledPeers > 0 {
+		add("max-installed-peers", fmt.Sprintf("%d", cfg.MaxInstalledPeers), false)
+	}
+	addBool("graceful-restart", cfg.GracefulRestart)
+	addBool("auto-update", cfg.AutoUpdate)
+	if cfg.CentralState != "" {
+		add("central-state", cfg.CentralState, true)
+	}
+	if cfg.StaticPeers != "" {
+		add("static-peers", cfg.StaticPeers, true)
+	}
+	if cfg.DiscoveryBandwidth != "" {
+		add("discovery-bandwidth", cfg.DiscoveryBandwidth, true)
+	}
+	if cfg.DHTShards > 1 {
+		add("dht-shards", fmt.Sprintf("%d", cfg.DHTShards), false)
+	}
+	if cfg.Tags != "" {
+		add("tags", cfg.Tags, true)
+	}
+	if cfg.AdvertiseDNS != "" {
+		add("advertise-dns", cfg.AdvertiseDNS, true)
+	}
+	if cfg.RouteChecks != "" {
+		add("route-check", cfg.RouteChecks, true)
+	}
+	addBool("no-split-dns", cfg.DisableSplitDNS)
+	addBool("dns-resolv-conf", cfg.SplitDNSResolvConf)
+	if cfg.ResourceLimits != "" {
+		add("resource-limits", cfg.ResourceLimits, true)
+	}
+	if cfg.PeerRateLimits != "" {
+		add("peer-rate-limit", cfg.PeerRateLimits, true)
+	}
+	if cfg.PeerWith != "" {
+		add("peer-with", cfg.PeerWith, true)
+	}
+	if cfg.RouteTable != 0 {
+		add("route-table", fmt.Sprintf("%d", cfg.RouteTable), false)
+	}
+	if cfg.RouteMetric != 0 {
+		add("route-metric", fmt.Sprintf("%d", cfg.RouteMetric), false)
+	}
+	if cfg.RouteFwmark != 0 {
+		add("route-fwmark", fmt.Sprintf("0x%x", cfg.RouteFwmark), false)
+	}
+	if cfg.MTU != 0 {
+		add("mtu", fmt.Sprintf("%d", cfg.MTU), false)
+	}
+	addBool("mtu-probe", cfg.MTUProbe)
+	if cfg.PortHop != 0 {
+		add("port-hop", cfg.PortHop.String(), false)
+	}
+	if cfg.TCPTransportPort != 0 {
+		add("tcp-transport-port", fmt.Sprintf("%d", cfg.TCPTransportPort), false)
 	}
+	if cfg.Approvers != "" {
+		add("approvers", cfg.Approvers, false)
+	}
+	if cfg.HealthAddr != "" {
+		add("health-addr", cfg.HealthAddr, false)
+	}
+	if cfg.PeerDeadAfter != 0 && cfg.PeerDeadAfter != PeerDeadTimeout {
+		add("peer-dead-after", cfg.PeerDeadAfter.String(), false)
+	}
+	if cfg.PeerRemoveAfter != 0 && cfg.PeerRemoveAfter != PeerRemoveTimeout {
+		add("peer-remove-after", cfg.PeerRemoveAfter.String(), false)
+	}
+	return flags
+}
 
-	return buf.String(), nil
+// shellJoinArgs renders "join --secret-file <secretFile>" and the flags
+// for cfg as shell words.
+func shellJoinArgs(secretFile string, cfg SystemdServiceConfig) []string {
+	args := []string{"join", "--secret-file", shellQuoteSystemd(secretFile)}
+	for _, f := range joinFlags(cfg) {
+		args = append(args, "--"+f.name)
+		switch {
+		case f.value == "":
+		case f.quote:
+			args = append(args, shellQuoteSystemd(f.value))
+		default:
+			args = append(args, f.value)
+		}
+	}
+	return args
 }
 
 // InstallSystemdService installs and enables the wgmesh systemd service
 func InstallSystemdService(cfg SystemdServiceConfig) error {
-	unit, err := GenerateSystemdUnit(cfg)
-	if err != nil {
-		return fmt.Errorf("failed to generate unit file: %w", err)
-	}
-
 	// Create state directory (required by ReadWritePaths in systemd unit).
 	// ProtectSystem=full will fail with status=226/NAMESPACE if this dir doesn't exist.
 	stateDir := "/var/lib/wgmesh"
@@ -140,23 +339,21 @@ func InstallSystemdService(cfg SystemdServiceConfig) error {
 		return fmt.Errorf("failed to create state directory (run as root?): %w", err)
 	}
 
-	// Write secret to environment file with restricted permissions
-	secretDir := "/etc/wgmesh"
-	if err := os.MkdirAll(secretDir, 0700); err != nil {
-		return fmt.Errorf("failed to create secret directory (run as root?): %w", err)
+	// Store the secret encrypted; the unit only references its location.
+	if err := storeServiceSecret(&cfg); err != nil {
+		return fmt.Errorf("failed to store secret (run as root?): %w", err)
 	}
-
-	// Quote the secret value for safe systemd environment file parsing
-	escapedSecret := strings.ReplaceAll(cfg.Secret, `\`, `\\`)
-	escapedSecret = strings.ReplaceAll(escapedSecret, `"`, `\"`)
-	secretEnv := fmt.Sprintf("WGMESH_SECRET=\"%s\"\n", escapedSecret)
-	secretPath := filepath.Join(secretDir, "secret.env")
-	if err := os.WriteFile(secretPath, []byte(secretEnv), 0600); err != nil {
-		return fmt.Errorf("failed to write secret file (run as root?): %w", err)
+	unit, err := GenerateSystemdUnit(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to generate unit file: %w", err)
+	}
+	// Drop the plaintext secret a previous install may have left behind.
+	if err := os.Remove(legacySecretEnvPath); err == nil {
+		_ = os.Remove(filepath.Dir(legacySecretEnvPath))
 	}
 
 	// Write unit file
-	unitPath := "/etc/systemd/system/wgmesh.service"
+	unitPath := systemdUnitPath
 	if err := os.WriteFile(unitPath, []byte(unit), 0644); err != nil {
 		return fmt.Errorf("failed to write unit file (run as root?): %w", err)
 	}
@@ -188,20 +385,20 @@ func UninstallSystemdService() error {
 	cmdExecutor.Command("systemctl", "disable", "wgmesh.service").Run()
 
 	// Remove unit file
-	unitPath := "/etc/systemd/system/wgmesh.service"
+	unitPath := systemdUnitPath
 	if err := os.Remove(unitPath); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to remove unit file: %w", err)
 	}
 
-	// Remove secret environment file
-	secretDir := "/etc/wgmesh"
-	secretPath := filepath.Join(secretDir, "secret.env")
-	if err := os.Remove(secretPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove secret file: %w", err)
+	// Remove the stored secret, including the plaintext file older versions wrote
+	for _, path := range []string{SecretCredentialPath, DefaultSecretFilePath, legacySecretEnvPath} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove secret file: %w", err)
+		}
 	}
 
-	// Attempt to remove secret directory (ignore errors; it may not be empty or may not exist)
-	_ = os.Remove(secretDir)
+	// Attempt to remove the old secret directory (ignore errors; it may not be empty or may not exist)
+	_ = os.Remove(filepath.Dir(legacySecretEnvPath))
 
 	// Reload systemd
 	cmdExecutor.Command("systemctl", "daemon-reload").Run()
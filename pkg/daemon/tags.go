@@ -0,0 +1,48 @@
+package daemon
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+// ParseTags parses a --tags value such as "role=db,zone=eu". A tag without
+// "=" has an empty value. An empty string gives no tags.
+func ParseTags(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	tags := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if _, dup := tags[key]; dup {
+			return nil, fmt.Errorf("tag %q given twice", key)
+		}
+		tags[key] = value
+	}
+	if err := crypto.ValidateTags(tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// FormatTags formats tags the way ParseTags reads them, sorted by key.
+func FormatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = key + "=" + tags[key]
+	}
+	return strings.Join(parts, ",")
+}
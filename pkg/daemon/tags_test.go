@@ -0,0 +1,46 @@
+package daemon
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		in      string
+		want    map[string]string
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "role=db", want: map[string]string{"role": "db"}},
+		{in: " role=db , zone=eu-west,", want: map[string]string{"role": "db", "zone": "eu-west"}},
+		{in: "canary", want: map[string]string{"canary": ""}},
+		{in: "role=db,role=web", wantErr: true},
+		{in: "=db", wantErr: true},
+		{in: "role=d b", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTags(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTags(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !maps.Equal(got, tt.want) {
+			t.Errorf("ParseTags(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatTagsRoundTrip(t *testing.T) {
+	t.Parallel()
+	tags := map[string]string{"zone": "eu", "role": "db", "canary": ""}
+	s := FormatTags(tags)
+	if s != "canary=,role=db,zone=eu" {
+		t.Errorf("FormatTags = %q", s)
+	}
+	back, err := ParseTags(s)
+	if err != nil || !maps.Equal(back, tags) {
+		t.Errorf("ParseTags(FormatTags) = %v, %v; want %v", back, err, tags)
+	}
+}
@@ -0,0 +1,544 @@
+package daemon
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Hotel, guest and corporate networks often block UDP outright, which
+// leaves a node unable to reach anyone. An introducer started with
+// --tcp-transport-port (443 blends in best) also accepts WireGuard packets
+// framed inside a TLS session. A node that has gone TCPTransportFallbackAfter
+// without a handshake with any peer, while an introducer it knows of
+// offers the transport, tunnels to it: WireGuard is pointed at a loopback
+// socket whose packets go into the TLS session, the introducer hands them
+// to its own WireGuard from a loopback socket of its own, and every other
+// peer is reached with the introducer as relay. The node tries UDP again
+// after a network change.
+
+const (
+	TCPTransportFallbackAfter = 2 * time.Minute
+	TCPTransportCheckInterval = 15 * time.Second
+
+	EventTCPTransport = "tcp_transport"
+
+	tcpTransportHelloTimeout = 10 * time.Second
+	tcpTransportDialTimeout  = 10 * time.Second
+	tcpTransportMaxRedial    = time.Minute
+	tcpTransportMaxFrame     = 65535
+	tcpTransportHelloInfo    = "wgmesh-tcp-transport-v1"
+)
+
+// streamTransport carries framed WireGuard packets over a stream. TLS is
+// the only one so far; framing and relaying do not depend on it.
+type streamTransport interface {
+	Listen(addr string) (net.Listener, error)
+	Dial(ctx context.Context, addr string) (net.Conn, error)
+}
+
+// tcpStreamTransport is the transport in use, replaceable in tests.
+var tcpStreamTransport streamTransport = tlsTransport{}
+
+// tlsTransport looks like any HTTPS connection from the outside. The
+// certificate is self-signed and not checked: WireGuard authenticates the
+// packets inside, and the hello proves mesh membership.
+type tlsTransport struct{}
+
+func (tlsTransport) Listen(addr string) (net.Listener, error) {
+	cert, err := selfSignedCertificate()
+	if err != nil {
+		return nil, err
+	}
+	return tls.Listen("tcp", addr, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+		MinVersion:   tls.VersionTLS12,
+	})
+}
+
+func (tlsTransport) Dial(ctx context.Context, addr string) (net.Conn, error) {
+	cfg := &tls.Config{
+		InsecureSkipVerify: true, // see tlsTransport
+		NextProtos:         []string{"h2", "http/1.1"},
+		MinVersion:         tls.VersionTLS12,
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil && net.ParseIP(host) == nil {
+		cfg.ServerName = host
+	}
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: tcpTransportDialTimeout}, Config: cfg}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+func selfSignedCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.AddDate(1, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// writeFrame writes one packet with a two-byte length prefix, in a single
+// write so it goes out as one TLS record.
+func writeFrame(w io.Writer, packet []byte) error {
+	if len(packet) > tcpTransportMaxFrame {
+		return fmt.Errorf("frame of %d bytes is too large", len(packet))
+	}
+	buf := make([]byte, 2+len(packet))
+	binary.BigEndian.PutUint16(buf, uint16(len(packet)))
+	copy(buf[2:], packet)
+	_, err := w.Write(buf)
+	return err
+}
+
+// readFrame reads one packet into buf, which must hold tcpTransportMaxFrame
+// bytes.
+func readFrame(r io.Reader, buf []byte) ([]byte, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint16(hdr[:]))
+	if _, err := io.ReadFull(r, buf[:n]); err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// tcpTransportHello is the first frame of a session. It tells the
+// introducer who is on the other end, since a node whose UDP is blocked
+// has had no way to announce itself. Once the tunnel is up, gossip brings
+// the full announcement.
+type tcpTransportHello struct {
+	WGPubKey string `json:"wg_pubkey"`
+	MeshIP   string `json:"mesh_ip"`
+	MeshIPv6 string `json:"mesh_ipv6,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+}
+
+// sealHello returns the hello frame: an HMAC under the membership key
+// followed by the JSON body.
+func sealHello(key [32]byte, hello tcpTransportHello) ([]byte, error) {
+	body, err := json.Marshal(hello)
+	if err != nil {
+		return nil, err
+	}
+	return append(helloMAC(key, body), body...), nil
+}
+
+func openHello(key [32]byte, frame []byte) (*tcpTransportHello, error) {
+	if len(frame) <= sha256.Size {
+		return nil, errors.New("short hello")
+	}
+	body := frame[sha256.Size:]
+	if !hmac.Equal(frame[:sha256.Size], helloMAC(key, body)) {
+		return nil, errors.New("hello not signed with this mesh's key")
+	}
+	var hello tcpTransportHello
+	if err := json.Unmarshal(body, &hello); err != nil {
+		return nil, fmt.Errorf("malformed hello: %w", err)
+	}
+	if hello.WGPubKey == "" || net.ParseIP(hello.MeshIP) == nil {
+		return nil, errors.New("hello without public key or mesh IP")
+	}
+	return &hello, nil
+}
+
+func helloMAC(key [32]byte, body []byte) []byte {
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(tcpTransportHelloInfo))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// tcpTransportState tracks TCP transport sessions on both ends.
+type tcpTransportState struct {
+	mu        sync.Mutex
+	endpoints map[string]string   // pubkey -> loopback endpoint WireGuard uses for the peer
+	sessions  map[string]net.Conn // introducer side: pubkey -> client session
+	via       string              // client side: introducer tunnelled to; "" = plain UDP
+	cancel    context.CancelFunc  // stops the tunnel to via
+	udpOK     time.Time           // latest handshake seen, or when UDP got a fresh chance
+	changed   chan struct{}       // sessions changed; reconcile to re-point WireGuard
+}
+
+func (s *tcpTransportState) changes() chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.changed == nil {
+		s.changed = make(chan struct{}, 1)
+	}
+	return s.changed
+}
+
+func (s *tcpTransportState) notify() {
+	select {
+	case s.changes() <- struct{}{}:
+	default:
+	}
+}
+
+// tcpTransportEndpoint returns the loopback endpoint that reaches the peer
+// over the TCP transport, or "" if none does.
+func (d *Daemon) tcpTransportEndpoint(pubKey string) string {
+	d.tcpTransport.mu.Lock()
+	defer d.tcpTransport.mu.Unlock()
+	return d.tcpTransport.endpoints[pubKey]
+}
+
+// tcpTunnelVia returns the introducer this node tunnels to, or "".
+func (d *Daemon) tcpTunnelVia() string {
+	d.tcpTransport.mu.Lock()
+	defer d.tcpTransport.mu.Unlock()
+	return d.tcpTransport.via
+}
+
+func (d *Daemon) setTCPTransportEndpoint(pubKey, endpoint string) {
+	d.tcpTransport.mu.Lock()
+	defer d.tcpTransport.mu.Unlock()
+	if d.tcpTransport.endpoints == nil {
+		d.tcpTransport.endpoints = make(map[string]string)
+	}
+	d.tcpTransport.endpoints[pubKey] = endpoint
+}
+
+// relayDatagrams shuttles packets between a stream and WireGuard's
+// listening socket through a loopback UDP socket until the stream fails.
+// Packets reaching udp from anywhere but loopback are dropped.
+func (d *Daemon) relayDatagrams(conn net.Conn, udp *net.UDPConn) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, tcpTransportMaxFrame)
+		for {
+			n, from, err := udp.ReadFromUDP(buf)
+			if err != nil {
+				_ = conn.Close()
+				return
+			}
+			if !from.IP.IsLoopback() {
+				continue
+			}
+			if err := writeFrame(conn, buf[:n]); err != nil {
+				_ = conn.Close()
+				return
+			}
+		}
+	}()
+
+	buf := make([]byte, tcpTransportMaxFrame)
+	for {
+		packet, err := readFrame(conn, buf)
+		if err != nil {
+			break
+		}
+		wg := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: d.wgListenPort()}
+		_, _ = udp.WriteToUDP(packet, wg)
+	}
+
+	// Unblock the reader without closing udp, whose port WireGuard knows.
+	_ = udp.SetReadDeadline(time.Now())
+	<-done
+	_ = udp.SetReadDeadline(time.Time{})
+}
+
+func listenLoopbackUDP() (*net.UDPConn, error) {
+	return net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+}
+
+// startTCPTransportServer accepts TCP transport sessions on introducers
+// started with --tcp-transport-port.
+func (d *Daemon) startTCPTransportServer() error {
+	if d.config.TCPTransportPort == 0 {
+		return nil
+	}
+	ln, err := tcpStreamTransport.Listen(net.JoinHostPort("", strconv.Itoa(d.config.TCPTransportPort)))
+	if err != nil {
+		return fmt.Errorf("failed to listen on tcp/%d: %w", d.config.TCPTransportPort, err)
+	}
+	go func() {
+		<-d.ctx.Done()
+		_ = ln.Close()
+	}()
+	go d.acceptTCPTransport(ln)
+	log.Printf("[TCP] Accepting TLS-wrapped WireGuard on tcp/%d", d.config.TCPTransportPort)
+	return nil
+}
+
+func (d *Daemon) acceptTCPTransport(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if d.ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Printf("[TCP] Accept failed: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		go d.serveTCPTransport(conn)
+	}
+}
+
+// serveTCPTransport runs one client session: it checks the hello, gives
+// the client a loopback endpoint and relays until the session ends. A new
+// session from the same node replaces the old one.
+func (d *Daemon) serveTCPTransport(conn net.Conn) {
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(tcpTransportHelloTimeout))
+	frame, err := readFrame(conn, make([]byte, tcpTransportMaxFrame))
+	if err != nil {
+		return
+	}
+	hello, err := openHello(d.config.Keys.MembershipKey, frame)
+	if err != nil {
+		log.Printf("[TCP] Rejected session from %s: %v", conn.RemoteAddr(), err)
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+	if hello.WGPubKey == d.localNode.WGPubKey {
+		return
+	}
+
+	udp, err := listenLoopbackUDP()
+	if err != nil {
+		log.Printf("[TCP] Failed to open loopback socket: %v", err)
+		return
+	}
+	defer udp.Close()
+
+	pubKey := hello.WGPubKey
+	d.tcpTransport.mu.Lock()
+	if old := d.tcpTransport.sessions[pubKey]; old != nil {
+		_ = old.Close()
+	}
+	if d.tcpTransport.sessions == nil {
+		d.tcpTransport.sessions = make(map[string]net.Conn)
+	}
+	d.tcpTransport.sessions[pubKey] = conn
+	d.tcpTransport.mu.Unlock()
+	d.setTCPTransportEndpoint(pubKey, udp.LocalAddr().String())
+
+	d.peerStore.Update(&PeerInfo{
+		WGPubKey: pubKey,
+		MeshIP:   hello.MeshIP,
+		MeshIPv6: hello.MeshIPv6,
+		Hostname: hello.Hostname,
+	}, TCPMethod)
+	d.peerStore.MarkSeen(pubKey)
+	log.Printf("[TCP] Peer %s... connected from %s", shortKey(pubKey), conn.RemoteAddr())
+	d.tcpTransport.notify()
+
+	d.relayDatagrams(conn, udp)
+
+	d.tcpTransport.mu.Lock()
+	current := d.tcpTransport.sessions[pubKey] == conn
+	if current {
+		delete(d.tcpTransport.sessions, pubKey)
+		delete(d.tcpTransport.endpoints, pubKey)
+	}
+	d.tcpTransport.mu.Unlock()
+	if current {
+		log.Printf("[TCP] Peer %s... disconnected", shortKey(pubKey))
+	}
+}
+
+func (d *Daemon) tcpTransportLoop() {
+	ticker := time.NewTicker(TCPTransportCheckInterval)
+	defer ticker.Stop()
+	changed := d.tcpTransport.changes()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-changed:
+			d.reconcile()
+		case <-ticker.C:
+			handshakes, _ := d.wireGuard().GetLatestHandshakes(d.config.InterfaceName)
+			d.checkTCPTransport(handshakes, time.Now())
+		}
+	}
+}
+
+// checkTCPTransport keeps TCP transport peers alive in the peer store and,
+// on a node that has gone TCPTransportFallbackAfter without any handshake,
+// opens a tunnel to an introducer that offers the transport.
+func (d *Daemon) checkTCPTransport(handshakes map[string]int64, now time.Time) {
+	d.tcpTransport.mu.Lock()
+	seen := make([]string, 0, len(d.tcpTransport.sessions)+1)
+	for pubKey := range d.tcpTransport.sessions {
+		seen = append(seen, pubKey)
+	}
+	if d.tcpTransport.via != "" {
+		seen = append(seen, d.tcpTransport.via)
+	}
+	for _, ts := range handshakes {
+		if at := time.Unix(ts, 0); ts > 0 && at.After(d.tcpTransport.udpOK) {
+			d.tcpTransport.udpOK = at
+		}
+	}
+	if d.tcpTransport.udpOK.IsZero() {
+		d.tcpTransport.udpOK = now
+	}
+	blocked := now.Sub(d.tcpTransport.udpOK) > TCPTransportFallbackAfter
+	tunnelled := d.tcpTransport.via != ""
+	d.tcpTransport.mu.Unlock()
+
+	for _, pubKey := range seen {
+		d.peerStore.MarkSeen(pubKey)
+	}
+	if tunnelled || !blocked || d.config.Introducer || d.localNode.IsIntroducer() {
+		return
+	}
+	if introducer := d.selectTCPTransportIntroducer(); introducer != nil {
+		d.startTCPTunnel(introducer)
+	}
+}
+
+// selectTCPTransportIntroducer picks the most recently seen introducer
+// offering the TCP transport. Peers from the cache count, since a node
+// whose UDP is blocked hears from no one.
+func (d *Daemon) selectTCPTransportIntroducer() *PeerInfo {
+	var candidates []*PeerInfo
+	for _, p := range d.peerStore.GetAll() {
+		if p.Introducer && p.TCPPort > 0 && p.Endpoint != "" && p.WGPubKey != d.localNode.WGPubKey && d.peerAllowed(p) {
+			candidates = append(candidates, p)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if !candidates[i].LastSeen.Equal(candidates[j].LastSeen) {
+			return candidates[i].LastSeen.After(candidates[j].LastSeen)
+		}
+		return candidates[i].WGPubKey < candidates[j].WGPubKey
+	})
+	return candidates[0]
+}
+
+// startTCPTunnel points WireGuard at a loopback socket relayed to the
+// introducer's TCP transport and routes every other peer through it.
+func (d *Daemon) startTCPTunnel(introducer *PeerInfo) {
+	host, _, err := net.SplitHostPort(introducer.Endpoint)
+	if err != nil {
+		return
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(introducer.TCPPort))
+	udp, err := listenLoopbackUDP()
+	if err != nil {
+		log.Printf("[TCP] Failed to open loopback socket: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(d.ctx)
+	d.tcpTransport.mu.Lock()
+	d.tcpTransport.via = introducer.WGPubKey
+	d.tcpTransport.cancel = cancel
+	d.tcpTransport.mu.Unlock()
+	d.setTCPTransportEndpoint(introducer.WGPubKey, udp.LocalAddr().String())
+	d.peerStore.MarkSeen(introducer.WGPubKey)
+	d.localNode.setTCPVia(introducer.WGPubKey)
+
+	log.Printf("[TCP] No WireGuard handshake for %v, UDP looks blocked: tunnelling to introducer %s... over TLS at %s",
+		TCPTransportFallbackAfter, shortKey(introducer.WGPubKey), addr)
+	d.recordEvent(EventTCPTransport, introducer.WGPubKey, map[string]string{"state": "up", "addr": addr})
+	go d.runTCPTunnel(ctx, addr, udp)
+	d.tcpTransport.notify()
+}
+
+// runTCPTunnel keeps a session to the introducer open, redialling with
+// backoff, until ctx ends.
+func (d *Daemon) runTCPTunnel(ctx context.Context, addr string, udp *net.UDPConn) {
+	defer udp.Close()
+	hello, err := sealHello(d.config.Keys.MembershipKey, tcpTransportHello{
+		WGPubKey: d.localNode.WGPubKey,
+		MeshIP:   d.localNode.MeshIP,
+		MeshIPv6: d.localNode.MeshIPv6,
+		Hostname: d.localNode.Hostname,
+	})
+	if err != nil {
+		return
+	}
+
+	delay := time.Second
+	for ctx.Err() == nil {
+		conn, err := tcpStreamTransport.Dial(ctx, addr)
+		if err == nil {
+			if err = writeFrame(conn, hello); err == nil {
+				delay = time.Second
+				stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+				d.relayDatagrams(conn, udp)
+				stop()
+			}
+			_ = conn.Close()
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("[TCP] Session to %s failed: %v", addr, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, tcpTransportMaxRedial)
+	}
+}
+
+// stopTCPTunnel returns to plain UDP and gives it a fresh
+// TCPTransportFallbackAfter to prove itself.
+func (d *Daemon) stopTCPTunnel(reason string) {
+	d.tcpTransport.mu.Lock()
+	via, cancel := d.tcpTransport.via, d.tcpTransport.cancel
+	d.tcpTransport.via, d.tcpTransport.cancel = "", nil
+	d.tcpTransport.udpOK = time.Now()
+	if via != "" {
+		delete(d.tcpTransport.endpoints, via)
+	}
+	d.tcpTransport.mu.Unlock()
+	if via == "" {
+		return
+	}
+	cancel()
+	d.localNode.setTCPVia("")
+	log.Printf("[TCP] Trying UDP again (%s)", reason)
+	d.recordEvent(EventTCPTransport, via, map[string]string{"state": "down", "reason": reason})
+	d.tcpTransport.notify()
+}
@@ -0,0 +1,194 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	for _, packet := range []string{"handshake", "", strings.Repeat("x", 1420)} {
+		if err := writeFrame(&buf, []byte(packet)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := make([]byte, tcpTransportMaxFrame)
+	for _, want := range []string{"handshake", "", strings.Repeat("x", 1420)} {
+		got, err := readFrame(&buf, out)
+		if err != nil || string(got) != want {
+			t.Fatalf("readFrame = %q, %v; want %d bytes", got, err, len(want))
+		}
+	}
+	if err := writeFrame(&buf, make([]byte, tcpTransportMaxFrame+1)); err == nil {
+		t.Error("oversized frame should be rejected")
+	}
+}
+
+func TestTCPTransportHello(t *testing.T) {
+	key := [32]byte{1}
+	frame, err := sealHello(key, tcpTransportHello{WGPubKey: "laptop", MeshIP: "10.42.0.9"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hello, err := openHello(key, frame)
+	if err != nil || hello.WGPubKey != "laptop" || hello.MeshIP != "10.42.0.9" {
+		t.Fatalf("openHello = %+v, %v", hello, err)
+	}
+
+	if _, err := openHello([32]byte{2}, frame); err == nil {
+		t.Error("hello under another mesh's key should be rejected")
+	}
+	tampered := bytes.Replace(frame, []byte("10.42.0.9"), []byte("10.42.0.1"), 1)
+	if _, err := openHello(key, tampered); err == nil {
+		t.Error("tampered hello should be rejected")
+	}
+	noIP, _ := sealHello(key, tcpTransportHello{WGPubKey: "laptop"})
+	if _, err := openHello(key, noIP); err == nil {
+		t.Error("hello without a mesh IP should be rejected")
+	}
+}
+
+// fakeWireGuard stands in for a WireGuard listening socket.
+func fakeWireGuard(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := listenLoopbackUDP()
+	if err != nil {
+		t.Skipf("udp not available: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readDatagram(t *testing.T, conn *net.UDPConn) (string, *net.UDPAddr) {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 2048)
+	n, from, err := conn.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("no datagram: %v", err)
+	}
+	return string(buf[:n]), from
+}
+
+func TestTCPTransportLoopback(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The introducer, serving the transport.
+	wgIntro := fakeWireGuard(t)
+	intro := newMinimalDaemon(t)
+	intro.ctx = ctx
+	intro.localNode = &LocalNode{WGPubKey: "intro"}
+	intro.config.WGListenPort = wgIntro.LocalAddr().(*net.UDPAddr).Port
+	ln, err := tcpStreamTransport.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go intro.acceptTCPTransport(ln)
+
+	// The node whose UDP is blocked.
+	wgLaptop := fakeWireGuard(t)
+	laptop := newMinimalDaemon(t)
+	laptop.ctx = ctx
+	laptop.localNode = &LocalNode{WGPubKey: "laptop", MeshIP: "10.42.0.9"}
+	laptop.config.WGListenPort = wgLaptop.LocalAddr().(*net.UDPAddr).Port
+	laptop.peerStore.Update(&PeerInfo{
+		WGPubKey:   "intro",
+		MeshIP:     "10.42.0.1",
+		Introducer: true,
+		Endpoint:   "127.0.0.1:51820",
+		TCPPort:    ln.Addr().(*net.TCPAddr).Port,
+	}, "cache")
+
+	now := time.Now()
+	laptop.checkTCPTransport(map[string]int64{"intro": 0}, now)
+	if laptop.tcpTunnelVia() != "" {
+		t.Fatal("tunnel opened before UDP had its chance")
+	}
+	laptop.checkTCPTransport(map[string]int64{"intro": 0}, now.Add(TCPTransportFallbackAfter+time.Second))
+	if laptop.tcpTunnelVia() != "intro" {
+		t.Fatal("tunnel not opened after the fallback period")
+	}
+	endpoint, err := net.ResolveUDPAddr("udp", laptop.tcpTransportEndpoint("intro"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// WireGuard on the laptop sends to its loopback endpoint and reaches
+	// WireGuard on the introducer, and back.
+	if _, err := wgLaptop.WriteToUDP([]byte("initiation"), endpoint); err != nil {
+		t.Fatal(err)
+	}
+	got, from := readDatagram(t, wgIntro)
+	if got != "initiation" {
+		t.Fatalf("introducer got %q", got)
+	}
+	if intro.tcpTransportEndpoint("laptop") != from.String() {
+		t.Errorf("introducer's endpoint for laptop = %q, packets come from %s", intro.tcpTransportEndpoint("laptop"), from)
+	}
+	if p, ok := intro.peerStore.Get("laptop"); !ok || p.MeshIP != "10.42.0.9" {
+		t.Errorf("introducer did not learn laptop from its hello: %+v", p)
+	}
+	if _, err := wgIntro.WriteToUDP([]byte("response"), from); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := readDatagram(t, wgLaptop); got != "response" {
+		t.Fatalf("laptop got %q", got)
+	}
+
+	laptop.stopTCPTunnel("test")
+	if laptop.tcpTunnelVia() != "" || laptop.tcpTransportEndpoint("intro") != "" {
+		t.Error("tunnel still in use after stopTCPTunnel")
+	}
+}
+
+func TestTCPTransportRelaysThroughTunnelIntroducer(t *testing.T) {
+	d := newCapTestDaemon(0)
+	now := time.Now()
+	peers := []*PeerInfo{
+		{WGPubKey: "hub1", MeshIP: "10.0.0.1", Introducer: true, Endpoint: "1.2.3.4:51820", LastSeen: now},
+		{WGPubKey: "hub2", MeshIP: "10.0.0.2", Introducer: true, Endpoint: "1.2.3.5:51820", LastSeen: now},
+		{WGPubKey: "laptop", MeshIP: "10.0.0.3", TCPVia: "hub2"},
+		{WGPubKey: "web", MeshIP: "10.0.0.4", Endpoint: "1.2.3.6:51820"},
+	}
+
+	// Peers reach a node on the TCP transport through its introducer.
+	_, relayRoutes, _ := d.buildDesiredPeerConfigsWithHandshakes(peers, nil)
+	if relayRoutes["laptop"] != "hub2" {
+		t.Errorf("laptop should be relayed via hub2, got %q", relayRoutes["laptop"])
+	}
+	if _, ok := relayRoutes["web"]; ok {
+		t.Error("web should stay direct")
+	}
+
+	// The node itself relays everyone through the introducer it tunnels to.
+	d.tcpTransport.via = "hub1"
+	_, relayRoutes, _ = d.buildDesiredPeerConfigsWithHandshakes(peers, nil)
+	if relayRoutes["web"] != "hub1" {
+		t.Errorf("web should be relayed via hub1, got %q", relayRoutes["web"])
+	}
+	if relayRoutes["hub2"] != "hub1" {
+		t.Errorf("other introducers should be relayed via hub1 too, got %q", relayRoutes["hub2"])
+	}
+	if _, ok := relayRoutes["hub1"]; ok {
+		t.Error("the tunnel's introducer must stay direct")
+	}
+}
+
+func TestConfigTCPTransportPort(t *testing.T) {
+	if _, err := NewConfig(DaemonOpts{Secret: testConfigSecret, TCPTransportPort: 443}); err == nil {
+		t.Error("TCP transport port without --introducer should be rejected")
+	}
+	if _, err := NewConfig(DaemonOpts{Secret: testConfigSecret, Introducer: true, TCPTransportPort: 70000}); err == nil {
+		t.Error("out of range port should be rejected")
+	}
+	cfg, err := NewConfig(DaemonOpts{Secret: testConfigSecret, Introducer: true, TCPTransportPort: 443})
+	if err != nil || cfg.TCPTransportPort != 443 {
+		t.Errorf("NewConfig = %+v, %v", cfg, err)
+	}
+}
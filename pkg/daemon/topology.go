@@ -0,0 +1,136 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A node started with --peer-with installs only the peers its policy
+// selects into WireGuard. Discovery still tracks the whole mesh, and a peer
+// outside the policy is reached through a relay the policy does select, if
+// there is one; otherwise it gets no tunnel at all. Hub-and-spoke is
+// "--peer-with introducers" on the spokes, with the hubs running as
+// introducers. Policies should agree on both ends: a peer that installs
+// this node while this node does not install it sees a dead tunnel. Tag
+// selectors only match peers that sign their announcements.
+
+// Peer selector kinds accepted by ParsePeerPolicy.
+const (
+	PeerSelectorTag         = "tag"         // tag:role=db, or tag:role for any value
+	PeerSelectorHost        = "host"        // host:db-1
+	PeerSelectorKey         = "key"         // key:<public key or prefix>
+	PeerSelectorIntroducers = "introducers" // every introducer
+)
+
+// PeerPolicy is an allowlist of peer selectors. A nil policy allows every
+// peer.
+type PeerPolicy struct {
+	selectors []peerSelector
+}
+
+type peerSelector struct {
+	kind  string
+	key   string // tag key, hostname or key prefix
+	value string // tag value
+	any   bool   // tag selector without a value
+}
+
+// ParsePeerPolicy parses a --peer-with value such as
+// "introducers,tag:group=eu,host:backup-1". An empty spec allows every peer
+// and gives a nil policy.
+func ParsePeerPolicy(spec string) (*PeerPolicy, error) {
+	if strings.TrimSpace(spec) == "" {
+		return nil, nil
+	}
+	policy := &PeerPolicy{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if part == PeerSelectorIntroducers {
+			policy.selectors = append(policy.selectors, peerSelector{kind: part})
+			continue
+		}
+		kind, arg, ok := strings.Cut(part, ":")
+		if !ok || arg == "" {
+			return nil, fmt.Errorf("invalid peer selector %q: want introducers, tag:K[=V], host:NAME or key:PREFIX", part)
+		}
+		sel := peerSelector{kind: kind, key: arg}
+		switch kind {
+		case PeerSelectorTag:
+			key, value, hasValue := strings.Cut(arg, "=")
+			sel.key, sel.value, sel.any = key, value, !hasValue
+		case PeerSelectorHost:
+		case PeerSelectorKey:
+			if len(arg) < 4 {
+				return nil, fmt.Errorf("invalid peer selector %q: give at least 4 characters of the key", part)
+			}
+		default:
+			return nil, fmt.Errorf("unknown peer selector %q", kind)
+		}
+		policy.selectors = append(policy.selectors, sel)
+	}
+	if len(policy.selectors) == 0 {
+		return nil, nil
+	}
+	return policy, nil
+}
+
+// Allows reports whether any selector matches the peer.
+func (p *PeerPolicy) Allows(peer *PeerInfo) bool {
+	if p == nil {
+		return true
+	}
+	for _, sel := range p.selectors {
+		if sel.matches(peer) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s peerSelector) matches(peer *PeerInfo) bool {
+	switch s.kind {
+	case PeerSelectorIntroducers:
+		return peer.Introducer
+	case PeerSelectorTag:
+		// Any member can put tags in an unsigned announcement, so only
+		// the tags of a peer bound to a signing identity select it.
+		if peer.Identity == "" {
+			return false
+		}
+		value, ok := peer.Tags[s.key]
+		return ok && (s.any || value == s.value)
+	case PeerSelectorHost:
+		return peer.Hostname != "" && strings.EqualFold(peer.Hostname, s.key)
+	case PeerSelectorKey:
+		return strings.HasPrefix(peer.WGPubKey, s.key)
+	}
+	return false
+}
+
+// String formats the policy the way ParsePeerPolicy reads it.
+func (p *PeerPolicy) String() string {
+	if p == nil {
+		return ""
+	}
+	parts := make([]string, len(p.selectors))
+	for i, sel := range p.selectors {
+		switch {
+		case sel.kind == PeerSelectorIntroducers:
+			parts[i] = sel.kind
+		case sel.kind == PeerSelectorTag && !sel.any:
+			parts[i] = sel.kind + ":" + sel.key + "=" + sel.value
+		default:
+			parts[i] = sel.kind + ":" + sel.key
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// peerAllowed reports whether this node's --peer-with policy selects the
+// peer.
+func (d *Daemon) peerAllowed(peer *PeerInfo) bool {
+	return d.config.PeerPolicy.Allows(peer)
+}
@@ -0,0 +1,95 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePeerPolicy(t *testing.T) {
+	for _, spec := range []string{"", " ", ","} {
+		if p, err := ParsePeerPolicy(spec); err != nil || p != nil {
+			t.Errorf("ParsePeerPolicy(%q) = %v, %v; want nil policy", spec, p, err)
+		}
+	}
+
+	p, err := ParsePeerPolicy(" introducers, tag:group=eu ,tag:gpu,host:backup-1,key:AbCd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.String(), "introducers,tag:group=eu,tag:gpu,host:backup-1,key:AbCd"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	for _, spec := range []string{"hub", "tag:", "key:abc", "zone:eu"} {
+		if _, err := ParsePeerPolicy(spec); err == nil {
+			t.Errorf("ParsePeerPolicy(%q) should fail", spec)
+		}
+	}
+}
+
+func TestPeerPolicyAllows(t *testing.T) {
+	p, err := ParsePeerPolicy("introducers,tag:group=eu,tag:gpu,host:backup-1,key:AbCd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		peer PeerInfo
+		want bool
+	}{
+		{"introducer", PeerInfo{Introducer: true}, true},
+		{"tag value", PeerInfo{Identity: "id", Tags: map[string]string{"group": "eu"}}, true},
+		{"other tag value", PeerInfo{Identity: "id", Tags: map[string]string{"group": "us"}}, false},
+		{"tag key", PeerInfo{Identity: "id", Tags: map[string]string{"gpu": "a100"}}, true},
+		{"unsigned tags", PeerInfo{Tags: map[string]string{"group": "eu"}}, false},
+		{"hostname", PeerInfo{Hostname: "Backup-1"}, true},
+		{"key prefix", PeerInfo{WGPubKey: "AbCdEf=="}, true},
+		{"key case", PeerInfo{WGPubKey: "abcdef=="}, false},
+		{"nothing", PeerInfo{WGPubKey: "zzzz", Hostname: "web-1"}, false},
+	}
+	for _, tt := range tests {
+		if got := p.Allows(&tt.peer); got != tt.want {
+			t.Errorf("%s: Allows = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	var none *PeerPolicy
+	if !none.Allows(&PeerInfo{}) {
+		t.Error("nil policy should allow every peer")
+	}
+}
+
+func TestBuildDesiredPeerConfigsPeerPolicy(t *testing.T) {
+	d := newCapTestDaemon(0)
+	d.config.PeerPolicy, _ = ParsePeerPolicy("introducers,tag:group=eu")
+	now := time.Now()
+	peers := []*PeerInfo{
+		{WGPubKey: "hub", MeshIP: "10.0.0.1", Introducer: true, Endpoint: "1.2.3.4:51820", LastSeen: now},
+		{WGPubKey: "eu", MeshIP: "10.0.0.2", Endpoint: "1.2.3.5:51820", Identity: "eu-id", Tags: map[string]string{"group": "eu"}},
+		{WGPubKey: "us", MeshIP: "10.0.0.3", Endpoint: "1.2.3.6:51820", Identity: "us-id", Tags: map[string]string{"group": "us"}},
+	}
+
+	desired, relayRoutes, _ := d.buildDesiredPeerConfigsWithHandshakes(peers, nil)
+	if desired["hub"] == nil || desired["eu"] == nil {
+		t.Fatal("peers the policy allows should be installed")
+	}
+	if desired["us"] != nil {
+		t.Error("peer outside the policy should not be installed")
+	}
+	if relayRoutes["us"] != "hub" {
+		t.Errorf("peer outside the policy should be relayed via hub, got %q", relayRoutes["us"])
+	}
+	if _, ok := desired["hub"].allowed["10.0.0.3/32"]; !ok {
+		t.Error("hub should carry the relayed peer's mesh IP")
+	}
+
+	// Without an allowed relay the peer is left out entirely.
+	d.config.PeerPolicy, _ = ParsePeerPolicy("tag:group=eu")
+	desired, relayRoutes, _ = d.buildDesiredPeerConfigsWithHandshakes(peers, nil)
+	if desired["hub"] != nil || desired["us"] != nil || relayRoutes["us"] != "" {
+		t.Errorf("only eu should be installed, got %d peers and relays %v", len(desired), relayRoutes)
+	}
+	if desired["eu"] == nil {
+		t.Error("eu should be installed")
+	}
+}
@@ -0,0 +1,184 @@
+package daemon
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+// TrafficRateWindows are the averaging windows reported for each peer.
+var TrafficRateWindows = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute}
+
+// RPCTrafficRate is a peer's average throughput over one window.
+type RPCTrafficRate struct {
+	Window time.Duration
+	RxBps  float64 // bytes per second received from the peer
+	TxBps  float64 // bytes per second sent to the peer
+}
+
+// RPCPeerStatsData represents per-peer traffic accounting for RPC (matches rpc.PeerStatsData)
+type RPCPeerStatsData struct {
+	PubKey   string
+	Hostname string
+	MeshIP   string
+	RxBytes  uint64
+	TxBytes  uint64
+	Since    time.Time // when accounting for the peer started
+	Rates    []RPCTrafficRate
+}
+
+type trafficSample struct {
+	at     time.Time
+	rx, tx uint64 // accumulated totals, not raw WireGuard counters
+}
+
+type peerTraffic struct {
+	since          time.Time
+	lastRx, lastTx uint64 // raw WireGuard counters from the previous sample
+	rx, tx         uint64
+	samples        []trafficSample // oldest first, covering the longest window
+}
+
+// trafficAccounting turns WireGuard's per-peer transfer counters into
+// running totals and windowed rates. The zero value is ready to use.
+type trafficAccounting struct {
+	mu    sync.Mutex
+	peers map[string]*peerTraffic
+}
+
+// observe records one sample of the interface counters. Peers no longer on
+// the interface are forgotten. A counter that went backwards means the
+// peer or interface was recreated; the new value is counted from zero.
+func (a *trafficAccounting) observe(transfers map[string]wireguard.PeerTransfer, now time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.peers == nil {
+		a.peers = make(map[string]*peerTraffic)
+	}
+	for pubKey := range a.peers {
+		if _, ok := transfers[pubKey]; !ok {
+			delete(a.peers, pubKey)
+		}
+	}
+
+	keep := TrafficRateWindows[len(TrafficRateWindows)-1]
+	for pubKey, t := range transfers {
+		pt := a.peers[pubKey]
+		if pt == nil {
+			pt = &peerTraffic{since: now}
+			a.peers[pubKey] = pt
+		}
+		pt.rx += counterDelta(pt.lastRx, t.RxBytes)
+		pt.tx += counterDelta(pt.lastTx, t.TxBytes)
+		pt.lastRx, pt.lastTx = t.RxBytes, t.TxBytes
+		pt.samples = append(pt.samples, trafficSample{at: now, rx: pt.rx, tx: pt.tx})
+
+		// Keep one sample at or beyond the longest window as its baseline.
+		drop := 0
+		for drop+1 < len(pt.samples) && now.Sub(pt.samples[drop+1].at) >= keep {
+			drop++
+		}
+		pt.samples = pt.samples[drop:]
+	}
+}
+
+func counterDelta(prev, cur uint64) uint64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+// rate averages throughput over the window ending at the latest sample,
+// using the newest sample at least window old as the baseline (or the
+// oldest one while history is still shorter than the window).
+func (pt *peerTraffic) rate(window time.Duration) (rxBps, txBps float64) {
+	if len(pt.samples) < 2 {
+		return 0, 0
+	}
+	last := pt.samples[len(pt.samples)-1]
+	base := pt.samples[0]
+	for _, s := range pt.samples[1:] {
+		if last.at.Sub(s.at) < window {
+			break
+		}
+		base = s
+	}
+	secs := last.at.Sub(base.at).Seconds()
+	if secs <= 0 {
+		return 0, 0
+	}
+	return float64(last.rx-base.rx) / secs, float64(last.tx-base.tx) / secs
+}
+
+// activePeers returns the peers whose combined rx+tx rate over window is
+// at least minBps.
+func (a *trafficAccounting) activePeers(window time.Duration, minBps float64) map[string]bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	active := make(map[string]bool)
+	for pubKey, pt := range a.peers {
+		if rx, tx := pt.rate(window); rx+tx >= minBps {
+			active[pubKey] = true
+		}
+	}
+	return active
+}
+
+// totalRate returns the combined rx+tx rate of all peers over window.
+func (a *trafficAccounting) totalRate(window time.Duration) float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var total float64
+	for _, pt := range a.peers {
+		rx, tx := pt.rate(window)
+		total += rx + tx
+	}
+	return total
+}
+
+// stats snapshots accounting for every tracked peer, sorted by key.
+func (a *trafficAccounting) stats() []RPCPeerStatsData {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	out := make([]RPCPeerStatsData, 0, len(a.peers))
+	for pubKey, pt := range a.peers {
+		s := RPCPeerStatsData{
+			PubKey:  pubKey,
+			RxBytes: pt.rx,
+			TxBytes: pt.tx,
+			Since:   pt.since,
+			Rates:   make([]RPCTrafficRate, 0, len(TrafficRateWindows)),
+		}
+		for _, w := range TrafficRateWindows {
+			rx, tx := pt.rate(w)
+			s.Rates = append(s.Rates, RPCTrafficRate{Window: w, RxBps: rx, TxBps: tx})
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].PubKey < out[j].PubKey })
+	return out
+}
+
+// GetRPCPeerStats returns traffic accounting for peers on the interface,
+// annotated with hostname and mesh IP where the peer is known.
+func (d *Daemon) GetRPCPeerStats() []*RPCPeerStatsData {
+	stats := d.traffic.stats()
+	result := make([]*RPCPeerStatsData, len(stats))
+	for i := range stats {
+		if d.peerStore != nil {
+			if peer, ok := d.peerStore.Get(stats[i].PubKey); ok {
+				stats[i].Hostname = peer.Hostname
+				stats[i].MeshIP = peer.MeshIP
+			}
+		}
+		result[i] = &stats[i]
+	}
+	return result
+}
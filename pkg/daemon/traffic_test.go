@@ -0,0 +1,68 @@
+package daemon
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+func TestTrafficAccountingRates(t *testing.T) {
+	var a trafficAccounting
+	start := time.Unix(1700000000, 0)
+
+	// 1 KB/s received steadily for 20 minutes, sampled every 20s; for the
+	// last minute the peer also sends 3 KB/s.
+	for i := 0; i <= 60; i++ {
+		at := start.Add(time.Duration(i) * 20 * time.Second)
+		tx := uint64(0)
+		if i > 57 {
+			tx = uint64(i-57) * 20 * 3000
+		}
+		a.observe(map[string]wireguard.PeerTransfer{
+			"peer": {RxBytes: uint64(i) * 20 * 1000, TxBytes: tx},
+		}, at)
+	}
+
+	stats := a.stats()
+	if len(stats) != 1 {
+		t.Fatalf("expected 1 peer, got %d", len(stats))
+	}
+	s := stats[0]
+	if s.RxBytes != 60*20*1000 || s.TxBytes != 3*20*3000 {
+		t.Errorf("totals = %d/%d", s.RxBytes, s.TxBytes)
+	}
+	if !s.Since.Equal(start) {
+		t.Errorf("Since = %v, want %v", s.Since, start)
+	}
+
+	want := map[time.Duration][2]float64{
+		time.Minute:      {1000, 3000},
+		5 * time.Minute:  {1000, 600},
+		15 * time.Minute: {1000, 200},
+	}
+	for _, r := range s.Rates {
+		w := want[r.Window]
+		if math.Abs(r.RxBps-w[0]) > 0.01 || math.Abs(r.TxBps-w[1]) > 0.01 {
+			t.Errorf("window %v: rates %.2f/%.2f, want %.2f/%.2f", r.Window, r.RxBps, r.TxBps, w[0], w[1])
+		}
+	}
+}
+
+func TestTrafficAccountingCounterResetAndRemoval(t *testing.T) {
+	var a trafficAccounting
+	now := time.Unix(1700000000, 0)
+
+	a.observe(map[string]wireguard.PeerTransfer{"a": {RxBytes: 500}, "b": {RxBytes: 10}}, now)
+	// Interface recreated: counters restart below their previous values.
+	a.observe(map[string]wireguard.PeerTransfer{"a": {RxBytes: 100}}, now.Add(20*time.Second))
+
+	stats := a.stats()
+	if len(stats) != 1 || stats[0].PubKey != "a" {
+		t.Fatalf("expected only peer a to remain, got %+v", stats)
+	}
+	if stats[0].RxBytes != 600 {
+		t.Errorf("RxBytes = %d, want 600 across the counter reset", stats[0].RxBytes)
+	}
+}
@@ -0,0 +1,124 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+// TunnelMTU is the MTU of a Tunnel's stack when --mtu is not set, the same
+// as the kernel module's default.
+const TunnelMTU = 1420
+
+// ErrMeshIPChanged is returned by RunWithDHTDiscovery when a node running
+// on a Tunnel lost a mesh IP collision. A Tunnel cannot change its address
+// in place, so the daemon stops; starting again uses the new address.
+var ErrMeshIPChanged = errors.New("mesh IP changed")
+
+// Tunnel is a WireGuard device with its own TCP/IP stack that runs inside
+// the process, such as wireguard-go with a userspace netstack. With a
+// Tunnel (see SetTunnel) the daemon needs no privileges: it creates no
+// interface and touches no routes, firewall or resolver. Programs reach
+// the mesh only through Listen, ListenPacket and DialContext.
+type Tunnel interface {
+	wireguard.Backend
+
+	// Up starts the device with the node's base64 private key, listening
+	// for WireGuard on listenPort, and gives its stack the mesh addresses.
+	Up(privateKey string, listenPort int, addrs []netip.Addr, mtu int) error
+	// SetListenPort moves the WireGuard listen port.
+	SetListenPort(port int) error
+	// Listen and ListenPacket accept connections to the mesh addresses,
+	// DialContext opens them to mesh addresses. Hosts are IP literals.
+	Listen(network, address string) (net.Listener, error)
+	ListenPacket(network, address string) (net.PacketConn, error)
+	DialContext(ctx context.Context, network, address string) (net.Conn, error)
+	// Close stops the device and everything using its stack.
+	Close() error
+}
+
+// SetTunnel runs the node on t instead of a kernel WireGuard interface. It
+// must be called before Run or RunWithDHTDiscovery.
+func (d *Daemon) SetTunnel(t Tunnel) {
+	d.tunnel = t
+	d.wgBackend = t
+}
+
+// checkTunnel rejects the options that need a kernel interface or root.
+func (d *Daemon) checkTunnel() error {
+	c := d.config
+	var unsupported []string
+	for _, opt := range []struct {
+		set  bool
+		name string
+	}{
+		{len(c.AdvertiseRoutes) > 0, "advertised routes"},
+		{c.SubnetRouter, "subnet router"},
+		{c.Firewall, "firewall"},
+		{c.RouteTable != 0, "route table"},
+		{c.MTUProbe, "MTU probing"},
+		{c.TCPTransportPort != 0, "TCP transport"},
+		{c.GracefulRestart || c.AdoptInterface, "graceful restart"},
+		{c.AutoUpdate, "auto update"},
+		{len(d.rateLimits()) > 0, "peer rate limits"},
+	} {
+		if opt.set {
+			unsupported = append(unsupported, opt.name)
+		}
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("not supported without a kernel interface: %s", strings.Join(unsupported, ", "))
+	}
+	return nil
+}
+
+// setupTunnel brings the Tunnel up in place of the kernel interface.
+func (d *Daemon) setupTunnel() error {
+	if err := d.checkTunnel(); err != nil {
+		return err
+	}
+	var addrs []netip.Addr
+	for _, ip := range []string{d.localNode.MeshIP, d.localNode.MeshIPv6} {
+		if ip == "" {
+			continue
+		}
+		addr, err := netip.ParseAddr(ip)
+		if err != nil {
+			return fmt.Errorf("invalid mesh IP %q: %w", ip, err)
+		}
+		addrs = append(addrs, addr)
+	}
+	listenPort, err := d.pickListenPort()
+	if err != nil {
+		return err
+	}
+	mtu := d.config.MTU
+	if mtu == 0 {
+		mtu = TunnelMTU
+	}
+	if err := d.tunnel.Up(d.localNode.WGPrivateKey, listenPort, addrs, mtu); err != nil {
+		return fmt.Errorf("failed to start userspace WireGuard: %w", err)
+	}
+	d.tunnelUp.Store(true)
+	log.Printf("Userspace WireGuard ready on port %d", listenPort)
+	return nil
+}
+
+// teardownTunnel stops the Tunnel; its listeners and connections close
+// with it.
+func (d *Daemon) teardownTunnel() {
+	if !d.tunnelUp.Swap(false) {
+		return
+	}
+	if err := d.tunnel.Close(); err != nil {
+		log.Printf("[Shutdown] Failed to stop userspace WireGuard: %v", err)
+		return
+	}
+	log.Printf("[Shutdown] Userspace WireGuard stopped")
+}
@@ -0,0 +1,85 @@
+package daemon
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/userspace"
+)
+
+// TestRunOnTunnel runs the daemon on a userspace tunnel: it comes up
+// without an interface, serves its mesh probe port on the tunnel's stack
+// and stops the tunnel on shutdown.
+func TestRunOnTunnel(t *testing.T) {
+	prev := stateDir
+	stateDir = t.TempDir()
+	t.Cleanup(func() { stateDir = prev })
+
+	cfg, err := NewConfig(DaemonOpts{Secret: testConfigSecret, InterfaceName: "wgtun0", WGListenPort: 51980, DisableLANDiscovery: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.NoSignals = true
+	d, err := NewDaemon(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tun := userspace.New()
+	d.SetTunnel(tun)
+	done := make(chan error, 1)
+	go func() { done <- d.Run() }()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for !d.GetRPCReadiness().InterfaceUp {
+		if time.Now().After(deadline) {
+			d.Shutdown()
+			t.Fatalf("tunnel did not come up: %v", <-done)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, err := net.InterfaceByName("wgtun0"); err == nil {
+		t.Error("daemon created a kernel interface")
+	}
+	addr := net.JoinHostPort(d.localNode.MeshIP, strconv.Itoa(d.healthProbePort))
+	conn, err := d.dialProbeOnInterface(addr)
+	if err != nil {
+		t.Errorf("mesh probe port not served on the tunnel: %v", err)
+	} else {
+		conn.Close()
+	}
+
+	d.Shutdown()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("daemon did not stop")
+	}
+	if _, err := tun.GetPeers(""); err != userspace.ErrNotUp {
+		t.Errorf("tunnel still up after shutdown: %v", err)
+	}
+}
+
+func TestCheckTunnel(t *testing.T) {
+	cfg, err := NewConfig(DaemonOpts{Secret: testConfigSecret, Firewall: true, MTUProbe: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := NewDaemon(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.checkTunnel()
+	if err == nil || !strings.Contains(err.Error(), "firewall, MTU probing") {
+		t.Errorf("checkTunnel = %v, want firewall and MTU probing rejected", err)
+	}
+	d.config.Firewall, d.config.MTUProbe = false, false
+	if err := d.checkTunnel(); err != nil {
+		t.Errorf("checkTunnel = %v for a plain config", err)
+	}
+}
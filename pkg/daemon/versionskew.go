@@ -0,0 +1,180 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+// Peers announce their wgmesh version, protocol revision range and
+// capabilities (see crypto.Capabilities). Every VersionSkewInterval the
+// daemon compares them with its own. It warns once about each peer outside
+// the revisions it can mesh with, and about peers lacking a feature this
+// node is configured to use, and logs the mix of versions whenever it
+// changes. Features that would cut off peers lacking them, such as secret
+// rotation, are refused while such peers are active.
+const VersionSkewInterval = time.Minute
+
+// EventVersionSkew is recorded when a peer turns out to be incompatible.
+const EventVersionSkew = "version_skew"
+
+// Peer compatibility, as reported by peers.list.
+const (
+	CompatOK           = "ok"
+	CompatLegacy       = "legacy"       // predates protocol revisions; capabilities unknown
+	CompatIncompatible = "incompatible" // outside the revisions this node meshes with, or the reverse
+)
+
+type versionSkewState struct {
+	mu       sync.Mutex
+	warned   map[string]string // pubkey → warning already logged
+	versions string            // last logged version mix
+}
+
+// PeerCompatibility classifies p's announced protocol revisions against
+// this node's.
+func PeerCompatibility(p *PeerInfo) string {
+	if incompatibility(p) != "" {
+		return CompatIncompatible
+	}
+	if p.Revision == 0 {
+		return CompatLegacy
+	}
+	return CompatOK
+}
+
+// incompatibility explains why this node and p cannot mesh, or returns "".
+func incompatibility(p *PeerInfo) string {
+	switch {
+	case p.Revision < crypto.MinProtocolRevision:
+		return fmt.Sprintf("runs protocol revision %d, this node needs %d or later; upgrade the peer", p.Revision, crypto.MinProtocolRevision)
+	case p.MinRevision > crypto.ProtocolRevision:
+		return fmt.Sprintf("needs protocol revision %d or later, this node runs %d; upgrade this node", p.MinRevision, crypto.ProtocolRevision)
+	}
+	return ""
+}
+
+// peerLacks reports whether p is known not to implement cap. Legacy peers
+// are given the benefit of the doubt.
+func peerLacks(p *PeerInfo, cap crypto.Capabilities) bool {
+	if incompatibility(p) != "" {
+		return true
+	}
+	return p.Revision > 0 && !p.Capabilities.Has(cap)
+}
+
+// activePeersLacking returns the names of active peers that lack cap.
+func (d *Daemon) activePeersLacking(cap crypto.Capabilities) []string {
+	var names []string
+	for _, p := range d.peerStore.GetActive() {
+		if peerLacks(p, cap) {
+			names = append(names, peerName(p))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func peerName(p *PeerInfo) string {
+	if p.Hostname != "" {
+		return p.Hostname
+	}
+	return shortKey(p.WGPubKey)
+}
+
+func (d *Daemon) versionSkewLoop() {
+	ticker := time.NewTicker(VersionSkewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.checkVersionSkew()
+		}
+	}
+}
+
+// checkVersionSkew warns about peers that are new to being incompatible or
+// lacking a configured feature, and logs the version mix when it changed.
+func (d *Daemon) checkVersionSkew() {
+	peers := d.peerStore.GetActive()
+
+	d.versionSkew.mu.Lock()
+	warned := make(map[string]string, len(d.versionSkew.warned))
+	type warning struct {
+		peer   *PeerInfo
+		reason string
+		event  bool
+	}
+	var warnings []warning
+	counts := make(map[string]int)
+	for _, p := range peers {
+		if p.Version != d.config.Version {
+			counts[orUnknown(p.Version)]++
+		}
+		reason, event := incompatibility(p), true
+		if reason == "" && d.config.PortHop > 0 && peerLacks(p, crypto.CapPortHop) {
+			reason, event = "does not follow --port-hop; its tunnel breaks at this node's next hop", false
+		}
+		if reason == "" {
+			continue
+		}
+		warned[p.WGPubKey] = reason
+		if d.versionSkew.warned[p.WGPubKey] != reason {
+			warnings = append(warnings, warning{p, reason, event})
+		}
+	}
+	d.versionSkew.warned = warned
+	versions := describeVersionMix(counts)
+	mixChanged := versions != d.versionSkew.versions
+	d.versionSkew.versions = versions
+	d.versionSkew.mu.Unlock()
+
+	for _, w := range warnings {
+		log.Printf("[WARN] [Version] Peer %s (wgmesh %s) %s", peerName(w.peer), orUnknown(w.peer.Version), w.reason)
+		if w.event {
+			d.recordEvent(EventVersionSkew, w.peer.WGPubKey, map[string]string{
+				"version":  w.peer.Version,
+				"revision": strconv.Itoa(w.peer.Revision),
+				"reason":   w.reason,
+			})
+		}
+	}
+	if mixChanged && versions != "" {
+		log.Printf("[Version] Peers run other wgmesh versions than this node's %s: %s", orUnknown(d.config.Version), versions)
+	}
+}
+
+// describeVersionMix renders peer counts by version, e.g. "1.2.0 (3
+// peers), unknown (1 peer)".
+func describeVersionMix(counts map[string]int) string {
+	versions := make([]string, 0, len(counts))
+	for v := range counts {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	parts := make([]string, len(versions))
+	for i, v := range versions {
+		unit := "peers"
+		if counts[v] == 1 {
+			unit = "peer"
+		}
+		parts[i] = fmt.Sprintf("%s (%d %s)", v, counts[v], unit)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func orUnknown(version string) string {
+	if version == "" {
+		return "unknown"
+	}
+	return version
+}
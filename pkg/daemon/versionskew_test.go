@@ -0,0 +1,76 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+)
+
+func TestPeerCompatibility(t *testing.T) {
+	tests := []struct {
+		name string
+		peer PeerInfo
+		want string
+	}{
+		{"legacy", PeerInfo{Version: "0.9.0"}, CompatLegacy},
+		{"same revision", PeerInfo{Revision: crypto.ProtocolRevision, Capabilities: crypto.LocalCapabilities}, CompatOK},
+		{"newer, still compatible", PeerInfo{Revision: crypto.ProtocolRevision + 1, MinRevision: crypto.ProtocolRevision}, CompatOK},
+		{"needs a newer node", PeerInfo{Revision: crypto.ProtocolRevision + 2, MinRevision: crypto.ProtocolRevision + 1}, CompatIncompatible},
+	}
+	for _, tt := range tests {
+		if got := PeerCompatibility(&tt.peer); got != tt.want {
+			t.Errorf("%s: PeerCompatibility = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	legacy := &PeerInfo{}
+	if peerLacks(legacy, crypto.CapRotate) {
+		t.Error("legacy peers should be given the benefit of the doubt")
+	}
+	if !peerLacks(&PeerInfo{Revision: crypto.ProtocolRevision, Capabilities: crypto.CapGoodbye}, crypto.CapRotate) {
+		t.Error("a peer announcing capabilities without rotate lacks it")
+	}
+}
+
+func TestCheckVersionSkewWarnsOnce(t *testing.T) {
+	d := newMinimalDaemon(t)
+	d.config.Version = "1.3.0"
+	d.peerStore.Update(&PeerInfo{WGPubKey: "same-key", MeshIP: "10.0.0.2", Version: "1.3.0", Revision: crypto.ProtocolRevision, RoutesAnnounced: true}, "dht")
+	d.peerStore.Update(&PeerInfo{WGPubKey: "new-key", MeshIP: "10.0.0.3", Hostname: "future", Version: "3.0.0",
+		Revision: crypto.ProtocolRevision + 2, MinRevision: crypto.ProtocolRevision + 1, RoutesAnnounced: true}, "dht")
+
+	d.checkVersionSkew()
+	d.checkVersionSkew()
+
+	events := d.GetRPCEvents(0)
+	if len(events) != 1 || events[0].Type != EventVersionSkew || events[0].PubKey != "new-key" {
+		t.Fatalf("events = %+v, want one %s event for new-key", events, EventVersionSkew)
+	}
+	if !strings.Contains(events[0].Details["reason"], "upgrade this node") {
+		t.Errorf("reason = %q", events[0].Details["reason"])
+	}
+	if got := d.versionSkew.versions; got != "3.0.0 (1 peer)" {
+		t.Errorf("version mix = %q", got)
+	}
+}
+
+func TestRotateSecretRefusesPeersWithoutRotation(t *testing.T) {
+	useTempStateDir(t)
+	discovery := &fakeRotationDiscovery{}
+	d := newRotationTestDaemon(t, discovery)
+	d.peerStore.Update(&PeerInfo{WGPubKey: "old-key", MeshIP: "10.0.0.2", Hostname: "old-node",
+		Revision: crypto.ProtocolRevision, Capabilities: crypto.CapGoodbye, RoutesAnnounced: true, LastSeen: time.Now()}, "dht")
+
+	_, err := d.RotateSecret(rotationTestNew, time.Hour, false)
+	if err == nil || !strings.Contains(err.Error(), "old-node") {
+		t.Fatalf("RotateSecret = %v, want a refusal naming old-node", err)
+	}
+	if discovery.broadcastCount() != 0 {
+		t.Error("refused rotation was broadcast")
+	}
+	if _, err := d.RotateSecret(rotationTestNew, time.Hour, true); err != nil {
+		t.Fatalf("forced RotateSecret: %v", err)
+	}
+}
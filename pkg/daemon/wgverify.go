@@ -0,0 +1,139 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+// WGVerifyInterval is how often the applied peer configuration is checked
+// against what WireGuard actually has.
+const WGVerifyInterval = 30 * time.Second
+
+// wgVerifyLoop periodically cross-checks the interface against the
+// configuration reconcile last applied. reconcile only calls wg set when a
+// peer's desired configuration changes, so entries dropped behind its back
+// (an external wg set, an interface flap) would otherwise stay missing.
+func (d *Daemon) wgVerifyLoop() {
+	ticker := time.NewTicker(WGVerifyInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.verifyWireGuardPeers()
+		}
+	}
+}
+
+// verifyWireGuardPeers reads the interface's peers and reconciles at once
+// if any of them drifted from the applied configuration.
+func (d *Daemon) verifyWireGuardPeers() {
+	actual, err := d.wireGuard().GetPeers(d.config.InterfaceName)
+	if err != nil {
+		log.Printf("[WGVerify] Failed to read WireGuard peers: %v", err)
+		return
+	}
+	if drifted := d.checkAppliedPeers(actual); drifted > 0 {
+		log.Printf("[WGVerify] Repairing %d drifted peer(s)", drifted)
+		d.reconcile()
+	}
+}
+
+// checkAppliedPeers compares the interface's peers with the configuration
+// last applied to each, logs the differences and forgets the applied
+// configuration of every drifted peer so the next reconcile sets it again.
+// It returns the number of drifted peers.
+func (d *Daemon) checkAppliedPeers(actual []wireguard.WGPeer) int {
+	byKey := make(map[string]*wireguard.WGPeer, len(actual))
+	for i := range actual {
+		byKey[actual[i].PublicKey] = &actual[i]
+	}
+
+	d.appliedMu.Lock()
+	defer d.appliedMu.Unlock()
+
+	drifted := 0
+	for pubKey, signature := range d.lastAppliedPeerConfigs {
+		diff := peerConfigDiff(signature, byKey[pubKey])
+		if diff == "" {
+			continue
+		}
+		log.Printf("[WGVerify] Peer %s... drifted from applied config: %s", shortKey(pubKey), diff)
+		delete(d.lastAppliedPeerConfigs, pubKey)
+		drifted++
+	}
+	return drifted
+}
+
+// peerConfigDiff describes how a peer on the interface differs from an
+// applied signature (endpoint|allowed-ips|keepalive|psk-kind, as built by
+// applyDesiredPeerConfigs), or returns "" when it matches. A different
+// endpoint is not drift: WireGuard follows a peer that roams.
+func peerConfigDiff(signature string, actual *wireguard.WGPeer) string {
+	if actual == nil {
+		return "missing from interface"
+	}
+	parts := strings.Split(signature, "|")
+	if len(parts) != 4 {
+		return ""
+	}
+	endpoint, keepalive := parts[0], parts[2]
+
+	want := normalizeAllowedIPs(strings.Split(parts[1], ","))
+	have := normalizeAllowedIPs(actual.AllowedIPs)
+	var missing, unexpected []string
+	for cidr := range want {
+		if _, ok := have[cidr]; !ok {
+			missing = append(missing, cidr)
+		}
+	}
+	for cidr := range have {
+		if _, ok := want[cidr]; !ok {
+			unexpected = append(unexpected, cidr)
+		}
+	}
+
+	slices.Sort(missing)
+	slices.Sort(unexpected)
+
+	var diffs []string
+	if len(missing) > 0 {
+		diffs = append(diffs, fmt.Sprintf("allowed IPs missing %s", strings.Join(missing, ",")))
+	}
+	if len(unexpected) > 0 {
+		diffs = append(diffs, fmt.Sprintf("unexpected allowed IPs %s", strings.Join(unexpected, ",")))
+	}
+	if endpoint != "" && actual.Endpoint == "" {
+		diffs = append(diffs, fmt.Sprintf("endpoint missing, want %s", endpoint))
+	}
+	if got := strconv.Itoa(actual.PersistentKeepalive); got != keepalive {
+		diffs = append(diffs, fmt.Sprintf("keepalive %s, want %s", got, keepalive))
+	}
+	return strings.Join(diffs, "; ")
+}
+
+// normalizeAllowedIPs returns CIDRs in canonical form, so IPv6 prefixes
+// compare equal however they were written.
+func normalizeAllowedIPs(cidrs []string) map[string]struct{} {
+	out := make(map[string]struct{}, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if prefix, err := netip.ParsePrefix(cidr); err == nil {
+			cidr = prefix.Masked().String()
+		}
+		out[cidr] = struct{}{}
+	}
+	return out
+}
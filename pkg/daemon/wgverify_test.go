@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+func TestCheckAppliedPeers(t *testing.T) {
+	t.Parallel()
+	d := newMinimalDaemon(t)
+	d.lastAppliedPeerConfigs = map[string]string{
+		"intact":  "203.0.113.1:51820|10.42.0.1/32,fd42::1/128|25|mesh",
+		"removed": "203.0.113.2:51820|10.42.0.2/32,fd42::2/128|25|mesh",
+		"no-v6":   "203.0.113.3:51820|10.42.0.3/32,fd42::3/128|25|mesh",
+		"roamed":  "203.0.113.4:51820|10.42.0.4/32|0|mesh",
+		"extra":   "203.0.113.5:51820|10.42.0.5/32|25|mesh",
+	}
+	actual := []wireguard.WGPeer{
+		{PublicKey: "intact", Endpoint: "203.0.113.1:51820", AllowedIPs: []string{"fd42:0:0::1/128", "10.42.0.1/32"}, PersistentKeepalive: 25},
+		{PublicKey: "no-v6", Endpoint: "203.0.113.3:51820", AllowedIPs: []string{"10.42.0.3/32"}, PersistentKeepalive: 25},
+		{PublicKey: "roamed", Endpoint: "198.51.100.4:40000", AllowedIPs: []string{"10.42.0.4/32"}},
+		{PublicKey: "extra", Endpoint: "203.0.113.5:51820", AllowedIPs: []string{"10.42.0.5/32", "192.168.0.0/16"}, PersistentKeepalive: 25},
+		{PublicKey: "unmanaged", AllowedIPs: []string{"10.42.0.9/32"}},
+	}
+
+	if got := d.checkAppliedPeers(actual); got != 3 {
+		t.Errorf("checkAppliedPeers() = %d drifted, want 3", got)
+	}
+	for _, key := range []string{"removed", "no-v6", "extra"} {
+		if _, ok := d.lastAppliedPeerConfigs[key]; ok {
+			t.Errorf("drifted peer %s still marked as applied", key)
+		}
+	}
+	for _, key := range []string{"intact", "roamed"} {
+		if _, ok := d.lastAppliedPeerConfigs[key]; !ok {
+			t.Errorf("peer %s was forgotten but has not drifted", key)
+		}
+	}
+}
+
+func TestPeerConfigDiff(t *testing.T) {
+	t.Parallel()
+	sig := "203.0.113.1:51820|10.42.0.1/32,fd42::1/128|25|mesh"
+	tests := []struct {
+		name   string
+		actual *wireguard.WGPeer
+		want   string
+	}{
+		{"missing", nil, "missing from interface"},
+		{"match", &wireguard.WGPeer{Endpoint: "203.0.113.1:51820", AllowedIPs: []string{"10.42.0.1/32", "fd42::1/128"}, PersistentKeepalive: 25}, ""},
+		{"dropped", &wireguard.WGPeer{AllowedIPs: []string{"10.42.0.1/32", "10.0.0.0/8"}}, "allowed IPs missing fd42::1/128; unexpected allowed IPs 10.0.0.0/8; endpoint missing, want 203.0.113.1:51820; keepalive 0, want 25"},
+	}
+	for _, tt := range tests {
+		if got := peerConfigDiff(sig, tt.actual); got != tt.want {
+			t.Errorf("%s: peerConfigDiff() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
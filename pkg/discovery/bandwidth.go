@@ -0,0 +1,143 @@
+package discovery
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"golang.org/x/time/rate"
+)
+
+// With --discovery-bandwidth, the DHT and the peer exchange share a token
+// bucket of bytes. Traffic the mesh cannot do without (replies, goodbyes,
+// rendezvous) is always sent and charged to it. Optional traffic (DHT
+// lookups and announces, HELLOs, gossip) is skipped while the bucket is
+// short, and announcements carry fewer KnownPeers.
+const (
+	// BandwidthBurst is how much of the budget a quiet node saves up.
+	BandwidthBurst = 10 * time.Second
+
+	udpHeaderBytes    = 28       // IPv4 and UDP headers, charged per datagram
+	knownPeerBytes    = 200      // rough encoded size of one KnownPeer
+	minKnownPeers     = 4        // KnownPeers kept however small the budget
+	dhtTraversalBytes = 16 << 10 // rough cost of one get_peers or announce traversal
+	dhtPacketBytes    = 256      // rough size of one DHT datagram
+	dhtBandwidthShare = 0.5      // of the budget the DHT may send at most
+)
+
+// errBandwidthExhausted is returned for optional traffic skipped because
+// the bandwidth budget is spent.
+var errBandwidthExhausted = errors.New("discovery bandwidth budget exhausted")
+
+// bandwidthBudget is the token bucket of --discovery-bandwidth. With a zero
+// rate it only counts bytes for the metrics.
+type bandwidthBudget struct {
+	rate  float64 // bytes per second; 0 = unlimited
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newBandwidthBudget(bytesPerSecond int) *bandwidthBudget {
+	b := &bandwidthBudget{
+		rate:  float64(bytesPerSecond),
+		burst: float64(bytesPerSecond) * BandwidthBurst.Seconds(),
+		now:   time.Now,
+	}
+	b.tokens = b.burst
+	b.last = b.now()
+	return b
+}
+
+func (b *bandwidthBudget) limited() bool {
+	return b.rate > 0
+}
+
+// refill adds the tokens earned since the last call. b.mu must be held.
+func (b *bandwidthBudget) refill() {
+	now := b.now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+// spend charges a datagram of n bytes of kind whether or not the budget
+// has room. The debt is capped at one burst, so required traffic delays
+// optional traffic but cannot stop it for good.
+func (b *bandwidthBudget) spend(kind string, n int) {
+	n += udpHeaderBytes
+	daemon.RecordDiscoverySent(kind, n)
+	if !b.limited() {
+		return
+	}
+	b.mu.Lock()
+	b.refill()
+	b.tokens = max(-b.burst, b.tokens-float64(n))
+	available := b.tokens
+	b.mu.Unlock()
+	daemon.SetDiscoveryBandwidthAvailable(available)
+}
+
+// allow charges a datagram of n bytes of kind if the budget has room for
+// it, and reports whether it did.
+func (b *bandwidthBudget) allow(kind string, n int) bool {
+	if !b.has(kind, n+udpHeaderBytes) {
+		return false
+	}
+	b.spend(kind, n)
+	return true
+}
+
+// has reports whether the budget has n bytes left, without charging them.
+// A false result counts as throttled traffic of kind.
+func (b *bandwidthBudget) has(kind string, n int) bool {
+	if !b.limited() {
+		return true
+	}
+	b.mu.Lock()
+	b.refill()
+	ok := b.tokens >= min(float64(n), b.burst)
+	b.mu.Unlock()
+	if !ok {
+		daemon.RecordDiscoveryThrottled(kind)
+	}
+	return ok
+}
+
+// knownPeersLimit is how many KnownPeers an announcement may carry: about
+// one second of budget. 0 means no limit.
+func (b *bandwidthBudget) knownPeersLimit() int {
+	if !b.limited() {
+		return 0
+	}
+	return max(minKnownPeers, int(b.rate)/knownPeerBytes)
+}
+
+// dhtSendLimiter paces the DHT server's own queries to its share of the
+// budget. It returns nil, the library's default, when unlimited.
+func (b *bandwidthBudget) dhtSendLimiter() *rate.Limiter {
+	if !b.limited() {
+		return nil
+	}
+	packets := b.rate * dhtBandwidthShare / dhtPacketBytes
+	return rate.NewLimiter(rate.Limit(packets), max(1, int(packets*BandwidthBurst.Seconds())))
+}
+
+// meteredPacketConn charges every datagram written to budget.
+type meteredPacketConn struct {
+	net.PacketConn
+	budget *bandwidthBudget
+	kind   string
+}
+
+func (c *meteredPacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	n, err := c.PacketConn.WriteTo(p, addr)
+	if err == nil {
+		c.budget.spend(c.kind, n)
+	}
+	return n, err
+}
@@ -0,0 +1,122 @@
+package discovery
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+func newTestBandwidthBudget(bytesPerSecond int) (*bandwidthBudget, *time.Time) {
+	b := newBandwidthBudget(bytesPerSecond)
+	now := b.last
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+func TestBandwidthBudgetAllowAndRefill(t *testing.T) {
+	t.Parallel()
+	b, now := newTestBandwidthBudget(1000)
+
+	// A full bucket holds BandwidthBurst of budget.
+	sent := 0
+	for b.allow("gossip", 972) {
+		sent++
+	}
+	if want := int(BandwidthBurst.Seconds()); sent != want {
+		t.Fatalf("sent %d 1000-byte datagrams from a full bucket, want %d", sent, want)
+	}
+
+	*now = now.Add(time.Second)
+	if !b.allow("gossip", 972) {
+		t.Fatal("one second of budget did not refill one datagram")
+	}
+	if b.allow("gossip", 972) {
+		t.Fatal("allowed more than was refilled")
+	}
+}
+
+func TestBandwidthBudgetRequiredTrafficDefersOptional(t *testing.T) {
+	t.Parallel()
+	b, now := newTestBandwidthBudget(1000)
+
+	// Required traffic is charged past zero, but the debt is capped at one
+	// burst.
+	for i := 0; i < 100; i++ {
+		b.spend("exchange", 972)
+	}
+	if b.has("dht", 1) {
+		t.Fatal("optional traffic allowed while in debt")
+	}
+	*now = now.Add(2*BandwidthBurst + time.Second)
+	if !b.has("dht", 1000) {
+		t.Fatal("the debt was not capped at one burst")
+	}
+}
+
+func TestBandwidthBudgetUnlimited(t *testing.T) {
+	t.Parallel()
+	b := newBandwidthBudget(0)
+	for i := 0; i < 1000; i++ {
+		if !b.allow("gossip", MaxExchangeSize) {
+			t.Fatal("an unlimited budget throttled")
+		}
+	}
+	if b.knownPeersLimit() != 0 || b.dhtSendLimiter() != nil {
+		t.Error("an unlimited budget must not limit KnownPeers or the DHT")
+	}
+}
+
+func TestGetKnownPeersLimitedByBandwidth(t *testing.T) {
+	t.Parallel()
+	config := &daemon.Config{DiscoveryBandwidth: daemon.MinDiscoveryBandwidth}
+	localNode := &daemon.LocalNode{WGPubKey: "self"}
+	peerStore := daemon.NewPeerStore()
+	for i := 0; i < 20; i++ {
+		peerStore.Update(&daemon.PeerInfo{WGPubKey: fmt.Sprintf("peer-%d", i)}, "test")
+	}
+	pe := NewPeerExchange(config, localNode, peerStore)
+
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		known := pe.getKnownPeers()
+		if len(known) != minKnownPeers {
+			t.Fatalf("got %d known peers, want %d", len(known), minKnownPeers)
+		}
+		for _, kp := range known {
+			seen[kp.WGPubKey] = true
+		}
+	}
+	if len(seen) < 15 {
+		t.Errorf("only %d of 20 peers announced over 50 rounds, want a spread", len(seen))
+	}
+}
+
+func TestSendOptionalThrottled(t *testing.T) {
+	t.Parallel()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	pe := NewPeerExchange(&daemon.Config{DiscoveryBandwidth: daemon.MinDiscoveryBandwidth}, &daemon.LocalNode{}, daemon.NewPeerStore())
+	pe.conn = conn
+	target := conn.LocalAddr().(*net.UDPAddr)
+	data := make([]byte, 1000)
+
+	var sendErr error
+	for i := 0; i < 10 && sendErr == nil; i++ {
+		sendErr = pe.sendOptional("gossip", data, target)
+	}
+	if !errors.Is(sendErr, errBandwidthExhausted) {
+		t.Fatalf("sendOptional err = %v, want errBandwidthExhausted", sendErr)
+	}
+	// Required traffic still goes out.
+	if err := pe.send(data, target); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package discovery
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+// localEndpointCandidates lists the WireGuard endpoints this node can be
+// reached at besides its advertised one: each LAN address on a multicast
+// capable interface (which excludes the WireGuard interface itself) paired
+// with the WG listen port.
+func localEndpointCandidates(localNode *daemon.LocalNode, config *daemon.Config) []string {
+	ips := localIPv4Addrs()
+	if !config.DisableIPv6 {
+		ips = append(ips, localGlobalIPv6Addrs()...)
+	}
+	return buildEndpointCandidates(localNode.GetEndpoint(), ips, config.WGListenPort, config.DisableIPv6)
+}
+
+func buildEndpointCandidates(advertised string, ips []net.IP, wgPort int, disableIPv6 bool) []string {
+	if wgPort <= 0 {
+		return nil
+	}
+	port := strconv.Itoa(wgPort)
+
+	seen := map[string]struct{}{advertised: {}}
+	var out []string
+	for _, ip := range ips {
+		if ip == nil || ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() {
+			continue
+		}
+		if disableIPv6 && ip.To4() == nil {
+			continue
+		}
+		ep := net.JoinHostPort(ip.String(), port)
+		if _, ok := seen[ep]; ok {
+			continue
+		}
+		seen[ep] = struct{}{}
+		out = append(out, ep)
+		if len(out) >= crypto.MaxEndpointCandidates {
+			break
+		}
+	}
+	return out
+}
+
+func localGlobalIPv6Addrs() []net.IP {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var ips []net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.To4() != nil {
+				continue
+			}
+			if ipNet.IP.IsGlobalUnicast() {
+				ips = append(ips, ipNet.IP)
+			}
+		}
+	}
+	return ips
+}
+
+// announcedCandidates sanitizes the endpoint candidates carried in a peer's
+// announcement for local use.
+func announcedCandidates(candidates []string, disableIPv6 bool) []string {
+	out := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		if ep := normalizeKnownPeerEndpoint(candidate); ep != "" {
+			out = append(out, ep)
+		}
+	}
+	return filterCandidatesForConfig(out, disableIPv6)
+}
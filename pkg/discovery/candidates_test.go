@@ -0,0 +1,50 @@
+package discovery
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestBuildEndpointCandidates(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("192.168.1.10"),
+		net.ParseIP("127.0.0.1"),
+		net.ParseIP("169.254.1.1"),
+		net.ParseIP("203.0.113.5"), // same as advertised
+		net.ParseIP("2001:db8::10"),
+		net.ParseIP("192.168.1.10"), // duplicate
+	}
+
+	got := buildEndpointCandidates("203.0.113.5:51820", ips, 51820, false)
+	want := []string{"192.168.1.10:51820", "[2001:db8::10]:51820"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildEndpointCandidates() = %v, want %v", got, want)
+	}
+
+	got = buildEndpointCandidates("203.0.113.5:51820", ips, 51820, true)
+	want = []string{"192.168.1.10:51820"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("buildEndpointCandidates() with IPv6 disabled = %v, want %v", got, want)
+	}
+
+	if got := buildEndpointCandidates("", ips, 0, false); got != nil {
+		t.Errorf("expected no candidates without a listen port, got %v", got)
+	}
+}
+
+func TestAnnouncedCandidates(t *testing.T) {
+	in := []string{"192.168.1.10:51820", "0.0.0.0:51820", "bogus", "[2001:db8::10]:51820", "192.168.1.10:51820"}
+
+	got := announcedCandidates(in, false)
+	want := []string{"192.168.1.10:51820", "[2001:db8::10]:51820"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("announcedCandidates() = %v, want %v", got, want)
+	}
+
+	got = announcedCandidates(in, true)
+	want = []string{"192.168.1.10:51820"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("announcedCandidates() with IPv6 disabled = %v, want %v", got, want)
+	}
+}
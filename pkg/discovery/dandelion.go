@@ -0,0 +1,193 @@
+package discovery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/privacy"
+)
+
+// DandelionStemInterval is how often a node in private first-contact mode
+// sends its announcement down a stem. DHT lookups turn up strangers far
+// more often than that; one stem per interval reaches all of them once it
+// has been fluffed.
+const DandelionStemInterval = 5 * time.Minute
+
+// dandelionStem is the DANDELION_STEM payload: a first-contact announcement
+// being passed along a stem of introducers before it is fluffed into the
+// mesh. The announcement is the origin's own, signed by it, so relays can
+// pass it on but not alter it.
+type dandelionStem struct {
+	Announcement *crypto.PeerAnnouncement `json:"announcement"`
+	HopCount     uint8                    `json:"hop_count"`
+}
+
+// parseDandelionStem decodes a DANDELION_STEM payload and validates the
+// announcement it carries.
+func parseDandelionStem(plaintext []byte) (*dandelionStem, error) {
+	var stem dandelionStem
+	if err := json.Unmarshal(plaintext, &stem); err != nil {
+		return nil, err
+	}
+	if stem.Announcement == nil {
+		return nil, errors.New("missing announcement")
+	}
+	if err := stem.Announcement.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid announcement: %w", err)
+	}
+	return &stem, nil
+}
+
+// SetDandelionRouter enables private first contact: StemFirstContact and
+// the relaying of other nodes' stems then follow router's epoch.
+func (pe *PeerExchange) SetDandelionRouter(router *privacy.DandelionRouter) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.dandelion = router
+}
+
+// StemFirstContact sends the local announcement down a stem of introducers
+// in place of a direct HELLO to a stranger, and reports whether the caller
+// should skip the direct exchange. It returns false, so first contact stays
+// direct, unless private first contact is enabled and the current epoch
+// has an introducer to stem through; a node that knows no introducer yet
+// has to bootstrap directly.
+func (pe *PeerExchange) StemFirstContact() bool {
+	if !pe.config.PrivateFirstContact {
+		return false
+	}
+	pe.mu.Lock()
+	router := pe.dandelion
+	if router == nil {
+		pe.mu.Unlock()
+		return false
+	}
+	if time.Since(pe.lastStem) < DandelionStemInterval {
+		pe.mu.Unlock()
+		return true
+	}
+	relay, ok := router.RouteFirstContact(0, pe.localNode.WGPubKey)
+	if !ok {
+		pe.mu.Unlock()
+		return false
+	}
+	pe.lastStem = time.Now()
+	pe.mu.Unlock()
+
+	announcement := crypto.CreateAnnouncement(
+		pe.localNode.WGPubKey,
+		pe.localNode.MeshIP,
+		pe.localNode.GetEndpoint(),
+		pe.localNode.IsIntroducer(),
+		pe.localNode.RoutableNetworks,
+		nil,
+		pe.localNode.Hostname,
+		pe.localNode.MeshIPv6,
+		string(pe.localNode.NATType),
+	)
+	announcement.EndpointCandidates = localEndpointCandidates(pe.localNode, pe.config)
+	announcement.Version = pe.config.Version
+	pe.localNode.AnnounceRelayState(announcement)
+	pe.localNode.SignAnnouncement(announcement)
+
+	if err := pe.sendDandelionStem(relay, &dandelionStem{Announcement: announcement}); err != nil {
+		log.Printf("[Dandelion] Failed to stem first-contact announcement: %v", err)
+		pe.mu.Lock()
+		pe.lastStem = time.Time{}
+		pe.mu.Unlock()
+		return false
+	}
+	log.Printf("[Dandelion] Stemmed first-contact announcement via %s", shortKey(relay.WGPubKey))
+	return true
+}
+
+// handleDandelionStem passes a stemmed announcement to the next introducer
+// of the epoch, or fluffs it: the origin is added to the peer store, from
+// where gossip and exchange replies spread it like any other known peer.
+// Nodes without a router, such as introducers not in privacy mode, fluff
+// every stem they receive.
+func (pe *PeerExchange) handleDandelionStem(stem *dandelionStem) {
+	announcement := stem.Announcement
+	if announcement.WGPubKey == pe.localNode.WGPubKey {
+		return
+	}
+	identity, ok := verifiedIdentity(announcement, pe.config, "Dandelion")
+	if !ok {
+		return
+	}
+
+	hop := min(stem.HopCount, privacy.MaxStemHops-1) + 1
+	pe.mu.RLock()
+	router := pe.dandelion
+	pe.mu.RUnlock()
+	if router != nil && pe.localNode.IsIntroducer() {
+		if relay, ok := router.RouteFirstContact(hop, announcement.WGPubKey, pe.localNode.WGPubKey); ok {
+			err := pe.sendDandelionStem(relay, &dandelionStem{Announcement: announcement, HopCount: hop})
+			if err == nil {
+				return
+			}
+			log.Printf("[Dandelion] Failed to relay stem to %s, fluffing: %v", shortKey(relay.WGPubKey), err)
+		}
+	}
+
+	// The sender is the previous hop, not the origin, so the endpoint can
+	// only come from the announcement itself.
+	log.Printf("[Dandelion] Fluffing first-contact announcement from %s after %d hops", shortKey(announcement.WGPubKey), hop)
+	pe.peerStore.Update(&daemon.PeerInfo{
+		WGPubKey:            announcement.WGPubKey,
+		Hostname:            announcement.Hostname,
+		MeshIP:              announcement.MeshIP,
+		MeshIPv6:            announcement.MeshIPv6,
+		Endpoint:            filterEndpointForConfig(normalizeKnownPeerEndpoint(announcement.WGEndpoint), pe.config.DisableIPv6),
+		Introducer:          announcement.Introducer,
+		RoutableNetworks:    announcement.RoutableNetworks,
+		RoutesAnnounced:     true,
+		NATType:             announcement.NATType,
+		Version:             announcement.Version,
+		Revision:            announcement.Revision,
+		MinRevision:         announcement.MinRevision,
+		Capabilities:        announcement.Capabilities,
+		Load:                announcement.Load,
+		Relays:              announcement.Relays,
+		IntroducerCandidate: announcement.IntroducerCandidate,
+		IntroducerElected:   announcement.IntroducerElected,
+		PairPSK:             announcement.PairPSK,
+		Tags:                announcement.Tags,
+		PortHop:             time.Duration(announcement.PortHop) * time.Second,
+		TCPPort:             announcement.TCPPort,
+		TCPVia:              announcement.TCPVia,
+		Approval:            announcement.Approval,
+		DNS:                 announcement.DNS,
+		RouteChecks:         announcement.RouteChecks,
+		Candidates:          announcedCandidates(announcement.EndpointCandidates, pe.config.DisableIPv6),
+		Identity:            identity,
+	}, privacy.DandelionMethod)
+}
+
+// sendDandelionStem sends stem to relay's exchange port.
+func (pe *PeerExchange) sendDandelionStem(relay privacy.PeerInfo, stem *dandelionStem) error {
+	addr := controlEndpointFromPeerEndpoint(relay.Endpoint, int(pe.config.Keys.GossipPort))
+	if addr == "" {
+		return fmt.Errorf("no exchange endpoint for %s", shortKey(relay.WGPubKey))
+	}
+	remoteAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to resolve stem target %s: %w", addr, err)
+	}
+
+	data, err := crypto.SealEnvelope(crypto.MessageTypeDandelionStem, stem, pe.config.EnvelopeSealKey())
+	if err != nil {
+		return fmt.Errorf("failed to seal stem: %w", err)
+	}
+
+	if err := pe.sendOptional("exchange", data, remoteAddr); err != nil {
+		return fmt.Errorf("failed to send stem: %w", err)
+	}
+	return nil
+}
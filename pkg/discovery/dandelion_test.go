@@ -0,0 +1,84 @@
+package discovery
+
+import (
+	"net"
+	"slices"
+	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/privacy"
+)
+
+func TestHandleDandelionStem_FluffsIntoPeerStore(t *testing.T) {
+	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: "wgmesh-test-dandelion-fluff-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerStore := daemon.NewPeerStore()
+	localNode := &daemon.LocalNode{WGPubKey: "introducer-pubkey", MeshIP: "10.0.0.1", Introducer: true}
+	pe := NewPeerExchange(cfg, localNode, peerStore)
+
+	// Without a router an introducer fluffs at the first hop.
+	ann := crypto.CreateAnnouncement("origin-pubkey", "10.0.0.7", "198.51.100.7:51820", false, nil, nil, "origin", "", "")
+	pe.handleDandelionStem(&dandelionStem{Announcement: ann})
+
+	peer, ok := peerStore.Get("origin-pubkey")
+	if !ok {
+		t.Fatal("fluffed origin not added to the peer store")
+	}
+	if peer.Endpoint != "198.51.100.7:51820" {
+		t.Errorf("Endpoint = %q, want the announced endpoint", peer.Endpoint)
+	}
+	if !slices.Contains(peer.DiscoveredVia, privacy.DandelionMethod) {
+		t.Errorf("DiscoveredVia = %v, want %s", peer.DiscoveredVia, privacy.DandelionMethod)
+	}
+
+	// Our own announcement coming back is ignored.
+	pe.handleDandelionStem(&dandelionStem{Announcement: crypto.CreateAnnouncement("introducer-pubkey", "10.0.0.1", "", true, nil, nil, "", "", "")})
+	if _, ok := peerStore.Get("introducer-pubkey"); ok {
+		t.Error("own stemmed announcement added to the peer store")
+	}
+}
+
+func TestStemFirstContact(t *testing.T) {
+	opts := daemon.DaemonOpts{Secret: "wgmesh-test-dandelion-stem-1", Privacy: true, PrivateFirstContact: true}
+	cfg, err := daemon.NewConfig(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localNode := &daemon.LocalNode{WGPubKey: "local-pubkey", MeshIP: "10.0.0.1"}
+	localNode.SetEndpoint("198.51.100.1:51820")
+	pe := NewPeerExchange(cfg, localNode, daemon.NewPeerStore())
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	pe.conn = conn
+
+	if pe.StemFirstContact() {
+		t.Fatal("stemmed without a router")
+	}
+
+	router := privacy.NewDandelionRouter([32]byte{1})
+	pe.SetDandelionRouter(router)
+	router.RotateEpoch([]privacy.PeerInfo{{WGPubKey: "peer-pubkey", Endpoint: "127.0.0.1:51820"}})
+	if pe.StemFirstContact() {
+		t.Fatal("stemmed with no introducer to stem through")
+	}
+
+	router.RotateEpoch([]privacy.PeerInfo{{WGPubKey: "introducer-pubkey", Endpoint: "127.0.0.1:51820", Introducer: true}})
+	if !pe.StemFirstContact() {
+		t.Fatal("did not stem through the epoch's introducer")
+	}
+	sent := pe.lastStem
+	if !pe.StemFirstContact() || pe.lastStem != sent {
+		t.Error("stemmed again within DandelionStemInterval")
+	}
+
+	cfg.PrivateFirstContact = false
+	if pe.StemFirstContact() {
+		t.Error("stemmed with private first contact disabled")
+	}
+}
@@ -5,26 +5,26 @@ import (
 	"fmt"
 	"hash/fnv"
 	"log"
+	"maps"
 	"math/rand"
 	"net"
+	"net/url"
 	"os"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/anacrolix/dht/v2"
 	"github.com/anacrolix/dht/v2/krpc"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
-	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
 )
 
 const (
-	DHTAnnounceInterval       = 15 * time.Minute
-	DHTQueryInterval          = 30 * time.Second
+	DHTAnnounceInterval       = 15 * time.Minute // NAT type unknown; see dhtIntervals for the others
+	DHTQueryInterval          = 30 * time.Second // below DHTTargetPeers
 	DHTQueryIntervalStable    = 60 * time.Second
 	DHTTransitiveInterval     = 1 * time.Second // Legacy: used only for initial backfill
 	DHTBootstrapTimeout       = 30 * time.Second
@@ -32,7 +32,7 @@ const (
 	DHTBootstrapInitialDelay  = 5 * time.Second
 	DHTBootstrapMaxDelay      = 60 * time.Second
 	DHTMethod                 = "dht"
-	DHTMaxConcurrentExchanges = 10 // Limit concurrent transitive exchanges to prevent resource exhaustion
+	DHTMaxConcurrentExchanges = 10 // Limit concurrent connection attempts (see dialScheduler) to prevent resource exhaustion
 	RendezvousWindow          = 20 * time.Second
 	RendezvousPhase           = 4 * time.Second
 	RendezvousPunchDelay      = 500 * time.Millisecond
@@ -44,6 +44,45 @@ const (
 	IPv6SyncPhase             = 2 * time.Second
 )
 
+// DHTTransport opens the packet socket the DHT server sends and receives on.
+// port is the preferred local port; implementations may fall back to another.
+type DHTTransport interface {
+	ListenPacket(port int) (net.PacketConn, error)
+}
+
+// directDHTTransport talks to the DHT from a plain UDP socket.
+type directDHTTransport struct{}
+
+func (directDHTTransport) ListenPacket(port int) (net.PacketConn, error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: port})
+	if err != nil {
+		// Try another port if this one is in use
+		conn, err = net.ListenUDP("udp", &net.UDPAddr{Port: 0})
+		if err != nil {
+			return nil, fmt.Errorf("failed to bind DHT port: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+// socks5DHTTransport relays DHT traffic through a SOCKS5 proxy, so DHT nodes
+// see the proxy's address instead of ours.
+type socks5DHTTransport struct {
+	proxy *url.URL
+}
+
+func (t socks5DHTTransport) ListenPacket(port int) (net.PacketConn, error) {
+	return dialSOCKS5UDP(t.proxy, port)
+}
+
+// newDHTTransport picks the transport for config.
+func newDHTTransport(config *daemon.Config) DHTTransport {
+	if config != nil && config.SOCKS5Proxy != nil {
+		return socks5DHTTransport{proxy: config.SOCKS5Proxy}
+	}
+	return directDHTTransport{}
+}
+
 type backoffEntry struct {
 	nextAttempt time.Time
 	duration    time.Duration
@@ -57,46 +96,50 @@ var DHTBootstrapNodes = []string{
 	"dht.libtorrent.org:25401",
 }
 
+// DHTBootstrapEnv names an environment variable holding a comma-separated
+// host:port list that replaces DHTBootstrapNodes, for a private DHT such as
+// the one in the test/e2e lab.
+const DHTBootstrapEnv = "WGMESH_DHT_BOOTSTRAP"
+
+// dhtBootstrapNodes returns the bootstrap nodes to use.
+func dhtBootstrapNodes() []string {
+	var nodes []string
+	for _, node := range strings.Split(os.Getenv(DHTBootstrapEnv), ",") {
+		if node = strings.TrimSpace(node); node != "" {
+			nodes = append(nodes, node)
+		}
+	}
+	if len(nodes) == 0 {
+		return DHTBootstrapNodes
+	}
+	return nodes
+}
+
 // DHTDiscovery handles peer discovery via BitTorrent Mainline DHT
 type DHTDiscovery struct {
-	config    *daemon.Config
-	localNode *daemon.LocalNode
-	peerStore *daemon.PeerStore
-	exchange  *PeerExchange
-	gossip    *MeshGossip
-	lan       *LANDiscovery
+	meshDiscovery
+
 	server    *dht.Server
+	transport DHTTransport
 	dhtPort   int
-
-	mu                sync.RWMutex
-	running           bool
-	ctx               context.Context
-	cancel            context.CancelFunc
-	contactedPeers    map[string]time.Time    // Dedup: don't spam same IP
-	controlPeers      map[string]string       // peer pubkey -> exchange/control endpoint
-	rendezvousBackoff map[string]backoffEntry // peer pubkey -> backoff state
+	dual      *DHTDiscovery // discovery under the new secret during a rotation
+
+	// Announce and query cadence (see dhtIntervals)
+	announceInterval time.Duration
+	queryInterval    time.Duration
+	cadencePeers     int
+	lastAnnounce     time.Time
+	lastQuery        time.Time
 }
 
 // NewDHTDiscovery creates a new DHT discovery instance.
 // parentCtx should be the daemon's context so that discovery goroutines are
 // cancelled when the daemon shuts down.
 func NewDHTDiscovery(parentCtx context.Context, config *daemon.Config, localNode *daemon.LocalNode, peerStore *daemon.PeerStore) (*DHTDiscovery, error) {
-	ctx, cancel := context.WithCancel(parentCtx)
-
 	d := &DHTDiscovery{
-		config:            config,
-		localNode:         localNode,
-		peerStore:         peerStore,
-		ctx:               ctx,
-		cancel:            cancel,
-		contactedPeers:    make(map[string]time.Time),
-		controlPeers:      make(map[string]string),
-		rendezvousBackoff: make(map[string]backoffEntry),
+		meshDiscovery: newMeshDiscovery(parentCtx, config, localNode, peerStore),
+		transport:     newDHTTransport(config),
 	}
-
-	// Create peer exchange handler
-	d.exchange = NewPeerExchange(config, localNode, peerStore)
-
 	return d, nil
 }
 
@@ -125,6 +168,7 @@ func (d *DHTDiscovery) Start() error {
 		}
 		d.gossip = gossip
 		d.exchange.SetAnnounceHandler(d.gossip.HandleAnnounceFrom)
+		d.exchange.SetDigestHandler(d.gossip.HandleDigest)
 	}
 
 	// Start the peer exchange server (listens for incoming connections)
@@ -147,6 +191,8 @@ func (d *DHTDiscovery) Start() error {
 		log.Printf("[LAN] LAN discovery disabled by configuration")
 	}
 
+	d.stun = startSTUNResponder(d.config)
+
 	// Start gossip loop after exchange is listening
 	if d.gossip != nil {
 		if err := d.gossip.Start(); err != nil {
@@ -164,7 +210,14 @@ func (d *DHTDiscovery) Start() error {
 		return fmt.Errorf("failed to initialize DHT server: %w", err)
 	}
 
+	if d.exchange.bandwidth.limited() {
+		log.Printf("[DHT] Discovery bandwidth limited to %d B/s (%d known peers per announcement)", d.config.DiscoveryBandwidth, d.exchange.bandwidth.knownPeersLimit())
+	}
+
 	// Start background goroutines
+	d.revalidateControlEndpoints()
+	d.contactBootstrapPeers()
+	go d.dialer.run(d.ctx)
 	go d.announceLoop()
 	go d.queryLoop()
 	go d.persistLoop()
@@ -187,8 +240,16 @@ func (d *DHTDiscovery) Stop() error {
 		return nil
 	}
 	d.running = false
+	dual := d.dual
+	d.dual = nil
+	lan := d.lan
+	d.lan = nil
 	d.mu.Unlock()
 
+	if dual != nil {
+		dual.Stop()
+	}
+
 	d.broadcastGoodbye()
 
 	d.cancel()
@@ -198,8 +259,12 @@ func (d *DHTDiscovery) Stop() error {
 		d.server.Close()
 	}
 
-	if d.lan != nil {
-		d.lan.Stop()
+	if lan != nil {
+		lan.Stop()
+	}
+
+	if d.stun != nil {
+		d.stun.Stop()
 	}
 
 	if d.gossip != nil {
@@ -214,33 +279,73 @@ func (d *DHTDiscovery) Stop() error {
 	return nil
 }
 
-func (d *DHTDiscovery) broadcastGoodbye() {
+// Reannounce exchanges the local announcement with every known peer now,
+// so a changed mesh IP reaches them before the next periodic round.
+func (d *DHTDiscovery) Reannounce() {
 	if d.exchange == nil {
 		return
 	}
+	for endpoint := range d.peerControlEndpoints() {
+		d.dialer.schedule(endpoint, dialKnown, 0, func() {
+			d.exchangeWithAddress(endpoint, DHTMethod+"-reannounce")
+		})
+	}
+}
 
-	peers := d.peerStore.GetAll()
-	targets := make(map[string]struct{})
-	for _, p := range peers {
-		if p == nil || p.WGPubKey == "" || p.WGPubKey == d.localNode.WGPubKey {
-			continue
-		}
-		if endpoint := d.controlEndpointForPeer(p); endpoint != "" {
-			targets[endpoint] = struct{}{}
-			continue
-		}
-		if endpoint := toControlEndpoint(p.Endpoint, int(d.config.Keys.GossipPort)); endpoint != "" {
-			targets[endpoint] = struct{}{}
-		}
+// HandleNetworkChange re-learns the external endpoint after the host has
+// resumed or changed networks, then pushes it to known peers and the DHT
+// instead of waiting for the next STUN refresh and announce. Contact dedup
+// and rendezvous backoff are cleared, since addresses that failed from the
+// old network may work from the new one.
+func (d *DHTDiscovery) HandleNetworkChange() {
+	d.mu.Lock()
+	if !d.running {
+		d.mu.Unlock()
+		return
 	}
+	dual := d.dual
+	d.mu.Unlock()
 
-	for endpoint := range targets {
-		if err := d.exchange.SendGoodbye(endpoint); err != nil {
-			d.debugf("[Exchange] Failed to send GOODBYE to %s: %v", endpoint, err)
+	d.dialer.reset()
+
+	go func() {
+		d.discoverExternalEndpoint()
+		d.Reannounce()
+		if d.server != nil {
+			d.announce()
+			d.queryPeers()
 		}
+	}()
+	if dual != nil {
+		dual.HandleNetworkChange()
 	}
 }
 
+// StartDualSecret runs a second discovery instance under the new secret of
+// a rotation, so this node is announced under both network IDs and accepts
+// both gossip keys until the daemon switches over. Peers it finds go to
+// peerStore, separate from the live one, since they are only reachable once
+// WireGuard uses the new keys.
+func (d *DHTDiscovery) StartDualSecret(config *daemon.Config, localNode *daemon.LocalNode, peerStore *daemon.PeerStore) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dual != nil {
+		return nil
+	}
+	dual, err := NewDHTDiscovery(d.ctx, config, localNode, peerStore)
+	if err != nil {
+		return err
+	}
+	dual.exchange.bandwidth = d.exchange.bandwidth // one budget for both secrets
+	dual.exchange.wg = d.exchange.wg
+	if err := dual.Start(); err != nil {
+		return err
+	}
+	d.dual = dual
+	log.Printf("[Rotation] Dual-secret mode: also announcing under network ID %x", config.Keys.NetworkID[:8])
+	return nil
+}
+
 // discoverExternalEndpoint queries two STUN servers to find this node's
 // server-reflexive address and detect NAT type. Updates localNode.WGEndpoint
 // and localNode.NATType. Falls back to the existing endpoint if STUN fails.
@@ -258,18 +363,19 @@ func (d *DHTDiscovery) discoverExternalEndpoint() {
 		}
 	}
 
-	servers := DefaultSTUNServers
+	servers := stunServersFor(d.config)
 	if len(servers) < 2 {
 		// Need at least 2 servers for NAT type detection; fall back to simple query
-		ip, _, err := DiscoverExternalEndpoint(0)
+		ip, _, err := DiscoverExternalEndpointWith(servers, 0)
 		if err != nil {
 			log.Printf("[STUN] Failed to discover external endpoint: %v (keeping %s)", err, d.localNode.GetEndpoint())
 			return
 		}
 		endpoint := net.JoinHostPort(ip.String(), strconv.Itoa(d.config.WGListenPort))
-		log.Printf("[STUN] External endpoint discovered: %s (NAT type unknown — need 2 servers)", endpoint)
+		natType := refineNATType(NATUnknown, ip)
+		log.Printf("[STUN] External endpoint discovered: %s (NAT type %s — need 2 servers to classify)", endpoint, natType)
 		d.localNode.SetEndpoint(endpoint)
-		d.localNode.NATType = string(NATUnknown)
+		d.localNode.NATType = string(natType)
 		return
 	}
 
@@ -278,6 +384,7 @@ func (d *DHTDiscovery) discoverExternalEndpoint() {
 		log.Printf("[STUN] Failed to discover external endpoint: %v (keeping %s)", err, d.localNode.GetEndpoint())
 		return
 	}
+	natType = refineNATType(natType, ip)
 
 	endpoint := net.JoinHostPort(ip.String(), strconv.Itoa(d.config.WGListenPort))
 	log.Printf("[STUN] External endpoint: %s, NAT type: %s", endpoint, natType)
@@ -424,7 +531,7 @@ func (d *DHTDiscovery) stunRefreshLoop() {
 				}
 			}
 
-			servers := DefaultSTUNServers
+			servers := stunServersFor(d.config)
 			if len(servers) >= 2 {
 				// Full NAT type re-detection with two servers
 				natType, ip, _, err := DetectNATType(servers[0], servers[1], 0, 3000)
@@ -432,6 +539,7 @@ func (d *DHTDiscovery) stunRefreshLoop() {
 					log.Printf("[STUN] Refresh failed: %v", err)
 					continue
 				}
+				natType = refineNATType(natType, ip)
 				newEndpoint := net.JoinHostPort(ip.String(), strconv.Itoa(d.config.WGListenPort))
 				currentEP := d.localNode.GetEndpoint()
 				oldNAT := d.localNode.NATType
@@ -445,7 +553,7 @@ func (d *DHTDiscovery) stunRefreshLoop() {
 				d.localNode.NATType = string(natType)
 			} else {
 				// Fallback: single-server IP-only refresh
-				ip, _, err := DiscoverExternalEndpoint(0)
+				ip, _, err := DiscoverExternalEndpointWith(servers, 0)
 				if err != nil {
 					log.Printf("[STUN] Refresh failed: %v", err)
 					continue
@@ -467,27 +575,26 @@ func (d *DHTDiscovery) stunRefreshLoop() {
 func (d *DHTDiscovery) initDHTServer() error {
 	// Use a separate port for DHT (exchange port + 1)
 	// This avoids conflicts with peer exchange read deadlines
-	dhtPort := d.exchange.Port() + 1
-	dhtAddr := &net.UDPAddr{Port: dhtPort}
-	dhtConn, err := net.ListenUDP("udp", dhtAddr)
+	dhtConn, err := d.transport.ListenPacket(d.exchange.Port() + 1)
 	if err != nil {
-		// Try another port if this one is in use
-		dhtAddr = &net.UDPAddr{Port: 0} // Let OS pick
-		dhtConn, err = net.ListenUDP("udp", dhtAddr)
-		if err != nil {
-			return fmt.Errorf("failed to bind DHT port: %w", err)
-		}
+		return err
 	}
 	d.dhtPort = dhtConn.LocalAddr().(*net.UDPAddr).Port
+	if d.config.SOCKS5Proxy != nil {
+		log.Printf("[DHT] Relaying DHT traffic through SOCKS5 proxy %s", d.config.SOCKS5Proxy.Host)
+	}
 
 	// Configure DHT server
 	cfg := dht.NewDefaultServerConfig()
-	cfg.Conn = dhtConn
+	cfg.Conn = &meteredPacketConn{PacketConn: dhtConn, budget: d.exchange.bandwidth, kind: "dht"}
 	cfg.NoSecurity = false
+	if limiter := d.exchange.bandwidth.dhtSendLimiter(); limiter != nil {
+		cfg.SendLimiter = limiter
+	}
 
 	// Resolve bootstrap nodes
 	var bootstrapAddrs []dht.Addr
-	for _, node := range DHTBootstrapNodes {
+	for _, node := range dhtBootstrapNodes() {
 		addr, err := net.ResolveUDPAddr("udp", node)
 		if err != nil {
 			log.Printf("[DHT] Failed to resolve bootstrap node %s: %v", node, err)
@@ -617,7 +724,7 @@ func (d *DHTDiscovery) persistLoop() {
 
 func (d *DHTDiscovery) nodesFilePath() string {
 	networkTag := fmt.Sprintf("%x", d.config.Keys.NetworkID[:8])
-	return filepath.Join("/var/lib/wgmesh", fmt.Sprintf("%s-%s-dht.nodes", d.config.InterfaceName, networkTag))
+	return filepath.Join(daemon.StateDir(), fmt.Sprintf("%s-%s-dht.nodes", d.config.InterfaceName, networkTag))
 }
 
 func (d *DHTDiscovery) loadPersistedNodes() {
@@ -661,12 +768,13 @@ func (d *DHTDiscovery) persistNodes() {
 	}
 }
 
-// announceLoop periodically announces our presence to the DHT
+// announceLoop periodically announces our presence to the DHT, as often
+// as dhtIntervals says for the node's NAT type and peer count
 func (d *DHTDiscovery) announceLoop() {
 	// Initial announce
 	d.announce()
 
-	ticker := time.NewTicker(DHTAnnounceInterval)
+	ticker := time.NewTicker(DHTCadenceCheckInterval)
 	defer ticker.Stop()
 
 	for {
@@ -674,36 +782,75 @@ func (d *DHTDiscovery) announceLoop() {
 		case <-d.ctx.Done():
 			return
 		case <-ticker.C:
-			d.announce()
+			if d.announceDue() {
+				d.announce()
+			}
 		}
 	}
 }
 
-// announce publishes our presence to the DHT under the network ID
+// announce publishes our presence to the DHT under the network ID, or
+// under the network IDs of the shards from dhtShardTargets
 func (d *DHTDiscovery) announce() {
-	// Get current and previous network IDs (for hourly rotation)
-	current, previous, err := crypto.GetCurrentAndPreviousNetworkIDs(d.config.Secret)
-	if err != nil {
-		log.Printf("[DHT] Failed to derive network IDs: %v", err)
-		return
-	}
+	d.mu.Lock()
+	d.lastAnnounce = time.Now()
+	d.mu.Unlock()
 
 	port := d.exchange.Port()
+	for _, shard := range d.dhtShardTargets() {
+		// Get current and previous network IDs (for hourly rotation)
+		current, previous, err := crypto.GetCurrentAndPreviousShardNetworkIDs(d.config.Secret, shard, d.config.DHTShards)
+		if err != nil {
+			log.Printf("[DHT] Failed to derive network IDs: %v", err)
+			return
+		}
 
-	log.Printf("[DHT] Announcing to network ID %x on exchange port %d (DHT port %d)", current[:8], port, d.dhtPort)
+		log.Printf("[DHT] Announcing to network ID %x%s on exchange port %d (DHT port %d)", current[:8], d.shardLabel(shard), port, d.dhtPort)
 
-	// Announce to current network ID
-	d.announceToInfohash(current, port)
+		// Announce to current network ID
+		d.announceToInfohash(current, port)
 
-	// Also announce to previous hour's ID during transition
-	if current != previous {
-		log.Printf("[DHT] Also announcing to previous network ID %x", previous[:8])
-		d.announceToInfohash(previous, port)
+		// Also announce to previous hour's ID during transition
+		if current != previous {
+			log.Printf("[DHT] Also announcing to previous network ID %x", previous[:8])
+			d.announceToInfohash(previous, port)
+		}
 	}
 }
 
+// dhtShardTargets returns the DHT shards to announce to and query this
+// round: with --dht-shards, the node's own shard and one other picked at
+// random. Each shard's peer set, and so each DHT lookup, stays a fraction
+// of the mesh, while the random shard links the shards together; peers
+// found there pass on the members of their own shard through the peer
+// exchange. Unsharded meshes have the single shard 0.
+func (d *DHTDiscovery) dhtShardTargets() []int {
+	shards := d.config.DHTShards
+	if shards < 2 {
+		return []int{0}
+	}
+	own := crypto.ShardForKey(d.config.Secret, d.localNode.WGPubKey, shards)
+	other := rand.Intn(shards - 1)
+	if other >= own {
+		other++
+	}
+	return []int{own, other}
+}
+
+// shardLabel describes shard for log lines, or "" when unsharded.
+func (d *DHTDiscovery) shardLabel(shard int) string {
+	if d.config.DHTShards < 2 {
+		return ""
+	}
+	return fmt.Sprintf(" (shard %d of %d)", shard, d.config.DHTShards)
+}
+
 // announceToInfohash announces our port to a specific infohash
 func (d *DHTDiscovery) announceToInfohash(infohash [20]byte, port int) {
+	if !d.exchange.bandwidth.has("dht", dhtTraversalBytes) {
+		log.Printf("[DHT] Skipping announce: discovery bandwidth budget exhausted")
+		return
+	}
 	ctx, cancel := context.WithTimeout(d.ctx, 30*time.Second)
 	defer cancel()
 
@@ -731,56 +878,63 @@ func (d *DHTDiscovery) announceToInfohash(infohash [20]byte, port int) {
 	}
 }
 
-// queryLoop periodically queries the DHT for peers
+// queryLoop periodically queries the DHT for peers, faster while the node
+// has few peers or is behind NAT (see dhtIntervals)
 func (d *DHTDiscovery) queryLoop() {
 	// Initial query
 	d.queryPeers()
 
-	// Start with faster queries, slow down once mesh is stable
-	interval := DHTQueryInterval
-
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	_, interval := d.updateCadence()
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-d.ctx.Done():
 			return
-		case <-ticker.C:
+		case <-timer.C:
 			d.queryPeers()
-
-			// Slow down queries once we have enough peers
-			if d.peerStore.Count() >= 3 && interval == DHTQueryInterval {
-				interval = DHTQueryIntervalStable
-				ticker.Reset(interval)
-				log.Printf("[DHT] Mesh stable, slowing query interval to %v", interval)
+			if d.peerStore.Count() == 0 {
+				d.contactBootstrapPeers()
 			}
+			_, interval = d.updateCadence()
+			timer.Reset(interval)
 		}
 	}
 }
 
 // queryPeers queries the DHT for other peers in our mesh
 func (d *DHTDiscovery) queryPeers() {
-	// Get current and previous network IDs
-	current, previous, err := crypto.GetCurrentAndPreviousNetworkIDs(d.config.Secret)
-	if err != nil {
-		log.Printf("[DHT] Failed to derive network IDs: %v", err)
-		return
-	}
+	d.mu.Lock()
+	d.lastQuery = time.Now()
+	d.mu.Unlock()
+
+	for _, shard := range d.dhtShardTargets() {
+		// Get current and previous network IDs
+		current, previous, err := crypto.GetCurrentAndPreviousShardNetworkIDs(d.config.Secret, shard, d.config.DHTShards)
+		if err != nil {
+			log.Printf("[DHT] Failed to derive network IDs: %v", err)
+			return
+		}
 
-	log.Printf("[DHT] Querying network ID %x (DHT has %d nodes)", current[:8], d.server.NumNodes())
+		log.Printf("[DHT] Querying network ID %x%s (DHT has %d nodes)", current[:8], d.shardLabel(shard), d.server.NumNodes())
 
-	// Query current network ID
-	d.queryInfohash(current)
+		// Query current network ID
+		d.queryInfohash(current)
 
-	// Also query previous hour's ID during transition
-	if current != previous {
-		d.queryInfohash(previous)
+		// Also query previous hour's ID during transition
+		if current != previous {
+			d.queryInfohash(previous)
+		}
 	}
 }
 
 // queryInfohash queries a specific infohash for peers
 func (d *DHTDiscovery) queryInfohash(infohash [20]byte) {
+	if !d.exchange.bandwidth.has("dht", dhtTraversalBytes) {
+		log.Printf("[DHT] Skipping query: discovery bandwidth budget exhausted")
+		return
+	}
 	ctx, cancel := context.WithTimeout(d.ctx, 30*time.Second)
 	defer cancel()
 
@@ -804,13 +958,13 @@ func (d *DHTDiscovery) queryInfohash(infohash [20]byte) {
 			}
 			for _, addr := range peerAddrs.Peers {
 				discovered++
-				go d.contactPeer(addr)
+				d.contactPeer(addr)
 			}
 		}
 	}
 }
 
-// contactPeer initiates peer exchange with a discovered address
+// contactPeer schedules a peer exchange with a discovered address
 func (d *DHTDiscovery) contactPeer(addr krpc.NodeAddr) {
 	addrStr := addr.String()
 	if d.config.DisableIPv6 && isIPv6Endpoint(addrStr) {
@@ -822,19 +976,35 @@ func (d *DHTDiscovery) contactPeer(addr krpc.NodeAddr) {
 		return
 	}
 
-	if !d.markContacted(addrStr, 60*time.Second) {
-		return
-	}
+	d.dialer.schedule(addrStr, d.dialPriorityFor(addrStr), 60*time.Second, func() {
+		log.Printf("[DHT] Contacting potential peer at %s", addrStr)
+		d.exchangeWithAddress(addrStr, DHTMethod)
+	})
+}
 
-	log.Printf("[DHT] Contacting potential peer at %s", addrStr)
+// dialPriorityFor ranks an address from the DHT by the peer announcing
+// it: introducers first, then other known peers, then strangers.
+func (d *DHTDiscovery) dialPriorityFor(addr string) dialPriority {
+	d.mu.RLock()
+	controlPeers := maps.Clone(d.controlPeers)
+	d.mu.RUnlock()
 
-	// Attempt peer exchange
-	d.exchangeWithAddress(addrStr, DHTMethod)
+	priority := dialDiscovered
+	for _, p := range d.peerStore.GetActive() {
+		if p.Endpoint != addr && controlPeers[p.WGPubKey] != addr {
+			continue
+		}
+		if p.Introducer {
+			return dialIntroducer
+		}
+		priority = dialKnown
+	}
+	return priority
 }
 
 func (d *DHTDiscovery) transitiveConnectLoop() {
 	// Subscribe to peer store events for immediate reaction
-	peerEventCh := d.peerStore.Subscribe()
+	peerEventCh := d.peerStore.Subscribe("transitive-connect")
 	defer d.peerStore.Unsubscribe(peerEventCh)
 
 	// Stale handshake check ticker (replaces 1s poll with 30s check)
@@ -866,6 +1036,12 @@ func (d *DHTDiscovery) handlePeerEvent(ev daemon.PeerEvent) {
 		return
 	}
 
+	// A roamed peer deserves a fresh attempt; the old backoff was earned
+	// against an address that no longer applies.
+	if ev.EndpointChanged() {
+		d.dialer.recordResult(ev.PubKey, true)
+	}
+
 	peer, ok := d.peerStore.Get(ev.PubKey)
 	if !ok {
 		return
@@ -907,45 +1083,14 @@ func (d *DHTDiscovery) checkStaleHandshakes() {
 }
 
 func (d *DHTDiscovery) canAttemptRendezvous(pubKey string) bool {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-
-	entry, ok := d.rendezvousBackoff[pubKey]
-	if !ok {
-		return true
-	}
-	return time.Now().After(entry.nextAttempt)
+	return d.dialer.canRetry(pubKey)
 }
 
+// recordRendezvousAttempt resets the peer's rendezvous backoff on success
+// and grows it, between RendezvousMinBackoff and RendezvousMaxBackoff, on
+// failure.
 func (d *DHTDiscovery) recordRendezvousAttempt(pubKey string, success bool) {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	if success {
-		// Reset backoff on success
-		delete(d.rendezvousBackoff, pubKey)
-		return
-	}
-
-	// Exponential backoff: double the previous duration, capped at max
-	existing, ok := d.rendezvousBackoff[pubKey]
-	var nextBackoff time.Duration
-	if !ok {
-		nextBackoff = RendezvousMinBackoff
-	} else {
-		nextBackoff = existing.duration * 2
-		if nextBackoff < RendezvousMinBackoff {
-			nextBackoff = RendezvousMinBackoff
-		}
-		if nextBackoff > RendezvousMaxBackoff {
-			nextBackoff = RendezvousMaxBackoff
-		}
-	}
-
-	d.rendezvousBackoff[pubKey] = backoffEntry{
-		nextAttempt: time.Now().Add(nextBackoff),
-		duration:    nextBackoff,
-	}
+	d.dialer.recordResult(pubKey, success)
 }
 
 func (d *DHTDiscovery) tryRendezvousForPeer(peer *daemon.PeerInfo) {
@@ -957,7 +1102,7 @@ func (d *DHTDiscovery) tryRendezvousForPeer(peer *daemon.PeerInfo) {
 	}
 
 	// Skip peers we already have a recent WG handshake with — no rendezvous needed.
-	hs := getPeerHandshakeTS(d.config.InterfaceName, peer.WGPubKey)
+	hs := d.peerHandshakeTS(peer.WGPubKey)
 	if hs > 0 && time.Since(time.Unix(hs, 0)) < 2*time.Minute {
 		return
 	}
@@ -981,8 +1126,9 @@ func (d *DHTDiscovery) tryRendezvousForPeer(peer *daemon.PeerInfo) {
 			targetControlEndpoint := d.controlEndpointForPeer(peer)
 			if targetControlEndpoint != "" {
 				key := "ipv6-sync:" + targetControlEndpoint
-				if d.markContacted(key, 3*time.Second) {
-					go d.exchangeWithAddress(targetControlEndpoint, DHTMethod+"-ipv6-sync")
+				if d.dialer.schedule(key, dialKnown, 3*time.Second, func() {
+					d.exchangeWithAddress(targetControlEndpoint, DHTMethod+"-ipv6-sync")
+				}) {
 					log.Printf("[Path] Scheduled synchronized IPv6 direct attempt for %s via %s", shortKey(peer.WGPubKey), targetControlEndpoint)
 				}
 			}
@@ -1000,13 +1146,8 @@ func (d *DHTDiscovery) tryRendezvousForPeer(peer *daemon.PeerInfo) {
 			if introducer.ControlEndpoint == "" {
 				continue
 			}
-			if !d.markContacted(introducer.ControlEndpoint, 20*time.Second) {
-				continue
-			}
-
-			log.Printf("[NAT] Event-driven rendezvous: %s <-> %s via %s", shortKey(d.localNode.WGPubKey), shortKey(peer.WGPubKey), shortKey(introducer.WGPubKey))
-
-			go func(endpoint string, target *daemon.PeerInfo) {
+			endpoint, target := introducer.ControlEndpoint, peer
+			queued := d.dialer.schedule(endpoint, dialIntroducer, 20*time.Second, func() {
 				err := d.exchange.RequestRendezvous(endpoint, target.WGPubKey, nil)
 				if err != nil {
 					log.Printf("[NAT] Rendezvous failed via %s for %s: %v", endpoint, shortKey(target.WGPubKey), err)
@@ -1017,7 +1158,11 @@ func (d *DHTDiscovery) tryRendezvousForPeer(peer *daemon.PeerInfo) {
 				// Don't record success here — sending the UDP packet doesn't mean
 				// the rendezvous succeeded. Success is recorded when the WG handshake
 				// completes via handleRendezvousStart → runRendezvousPunch.
-			}(introducer.ControlEndpoint, peer)
+			})
+			if !queued {
+				continue
+			}
+			log.Printf("[NAT] Event-driven rendezvous: %s <-> %s via %s", shortKey(d.localNode.WGPubKey), shortKey(peer.WGPubKey), shortKey(introducer.WGPubKey))
 			sent++
 		}
 		if sent == 0 {
@@ -1036,14 +1181,9 @@ func (d *DHTDiscovery) tryRendezvousForPeer(peer *daemon.PeerInfo) {
 		return
 	}
 
-	if !d.markContacted(targetControlEndpoint, 20*time.Second) {
-		return
-	}
-
-	log.Printf("[NAT] Event-driven punch: %s via %s (no introducer)", shortKey(peer.WGPubKey), targetControlEndpoint)
-
-	go func(endpoint string, target *daemon.PeerInfo) {
-		baseline := getPeerHandshakeTS(d.config.InterfaceName, target.WGPubKey)
+	endpoint, target := targetControlEndpoint, peer
+	queued := d.dialer.schedule(endpoint, dialKnown, 20*time.Second, func() {
+		baseline := d.peerHandshakeTS(target.WGPubKey)
 		peerInfo, err := d.exchange.ExchangeWithPeer(endpoint)
 		if err != nil {
 			if !strings.Contains(err.Error(), "timeout") {
@@ -1053,7 +1193,7 @@ func (d *DHTDiscovery) tryRendezvousForPeer(peer *daemon.PeerInfo) {
 			return
 		}
 		if peerInfo != nil {
-			newHandshake := waitForPeerHandshake(d.config.InterfaceName, target.WGPubKey, baseline, HandshakeWaitTimeout)
+			newHandshake := d.waitForPeerHandshake(target.WGPubKey, baseline, HandshakeWaitTimeout)
 			if newHandshake <= baseline {
 				log.Printf("[NAT] Control path reached %s via %s but WG handshake not established", shortKey(target.WGPubKey), endpoint)
 				d.recordRendezvousAttempt(target.WGPubKey, false)
@@ -1064,11 +1204,14 @@ func (d *DHTDiscovery) tryRendezvousForPeer(peer *daemon.PeerInfo) {
 			} else {
 				log.Printf("[NAT] Punch succeeded: %s (%s)", shortKey(peerInfo.WGPubKey), peerInfo.Endpoint)
 			}
-			d.setControlEndpoint(peerInfo.WGPubKey, endpoint)
 			d.peerStore.Update(peerInfo, DHTMethod+"-transitive")
+			d.setControlEndpoint(peerInfo.WGPubKey, endpoint)
 			d.recordRendezvousAttempt(target.WGPubKey, true)
 		}
-	}(targetControlEndpoint, peer)
+	})
+	if queued {
+		log.Printf("[NAT] Event-driven punch: %s via %s (no introducer)", shortKey(peer.WGPubKey), targetControlEndpoint)
+	}
 }
 
 // tryTransitivePeersWithBackoff is the legacy path for initial backfill
@@ -1102,7 +1245,17 @@ func (d *DHTDiscovery) exchangeWithAddress(addrStr string, discoveryMethod strin
 
 	daemon.RecordNATTraversalAttempt(discoveryMethod)
 
-	peerInfo, err := d.exchange.ExchangeWithPeer(addrStr)
+	if discoveryMethod == DHTMethod && d.exchange.StemFirstContact() {
+		return
+	}
+
+	exchange := d.exchange.ExchangeWithPeer
+	if discoveryMethod == DHTMethod {
+		// Addresses straight from the DHT may belong to anyone watching
+		// the infohash, not just mesh members.
+		exchange = d.exchange.ExchangeWithUnknownPeer
+	}
+	peerInfo, err := exchange(addrStr)
 	if err != nil {
 		// Timeouts are expected for some addresses during NAT traversal.
 		if !strings.Contains(err.Error(), "timeout") {
@@ -1125,72 +1278,81 @@ func (d *DHTDiscovery) exchangeWithAddress(addrStr string, discoveryMethod strin
 			log.Printf("[NAT] Peer established via NAT traversal path: %s (%s)", shortKey(peerInfo.WGPubKey), peerInfo.Endpoint)
 		}
 	}
-	d.setControlEndpoint(peerInfo.WGPubKey, addrStr)
 	d.peerStore.Update(peerInfo, discoveryMethod)
+	d.setControlEndpoint(peerInfo.WGPubKey, addrStr)
 }
 
-func getPeerHandshakeTS(iface, peerPubKey string) int64 {
-	if iface == "" || peerPubKey == "" {
+func (d *DHTDiscovery) peerHandshakeTS(peerPubKey string) int64 {
+	if d.config.InterfaceName == "" || peerPubKey == "" {
 		return 0
 	}
-	hs, err := wireguard.GetLatestHandshakes(iface)
+	hs, err := d.exchange.wireGuard().GetLatestHandshakes(d.config.InterfaceName)
 	if err != nil {
 		return 0
 	}
 	return hs[peerPubKey]
 }
 
-func waitForPeerHandshake(iface, peerPubKey string, baseline int64, timeout time.Duration) int64 {
+func (d *DHTDiscovery) waitForPeerHandshake(peerPubKey string, baseline int64, timeout time.Duration) int64 {
 	deadline := time.Now().Add(timeout)
 	for time.Now().Before(deadline) {
-		current := getPeerHandshakeTS(iface, peerPubKey)
+		current := d.peerHandshakeTS(peerPubKey)
 		if current > baseline {
 			return current
 		}
 		time.Sleep(HandshakePollInterval)
 	}
-	return getPeerHandshakeTS(iface, peerPubKey)
+	return d.peerHandshakeTS(peerPubKey)
 }
 
-func (d *DHTDiscovery) setControlEndpoint(peerPubKey, endpoint string) {
-	if peerPubKey == "" {
-		return
+// revalidateControlEndpoints queues a HELLO to every control endpoint
+// restored from the peer cache, ahead of any address the DHT turns up, so
+// peers that kept their address are back one round trip after a restart.
+// An endpoint is used again only once it has answered; one that does not,
+// or answers as a different peer, is dropped and the peer is left to DHT
+// discovery.
+func (d *DHTDiscovery) revalidateControlEndpoints() {
+	queued := 0
+	for _, p := range d.peerStore.GetAll() {
+		if p.ControlEndpoint == "" || p.WGPubKey == d.localNode.WGPubKey {
+			continue
+		}
+		pubKey := p.WGPubKey
+		endpoint := filterEndpointForConfig(normalizeKnownPeerEndpoint(p.ControlEndpoint), d.config.DisableIPv6)
+		if endpoint == "" {
+			d.peerStore.SetControlEndpoint(pubKey, "")
+			continue
+		}
+		if d.dialer.schedule(endpoint, dialKnown, 0, func() {
+			peerInfo, err := d.exchange.ExchangeWithPeer(endpoint)
+			if err != nil || peerInfo == nil || peerInfo.WGPubKey != pubKey {
+				log.Printf("[DHT] Cached control endpoint %s no longer reaches %s; leaving it to DHT discovery", endpoint, shortKey(pubKey))
+				d.peerStore.SetControlEndpoint(pubKey, "")
+			}
+			if err != nil || peerInfo == nil {
+				return
+			}
+			d.peerStore.Update(peerInfo, DHTMethod)
+			d.setControlEndpoint(peerInfo.WGPubKey, endpoint)
+		}) {
+			queued++
+		}
 	}
-	normalized := normalizeKnownPeerEndpoint(endpoint)
-	normalized = filterEndpointForConfig(normalized, d.config.DisableIPv6)
-	if normalized == "" {
-		return
+	if queued > 0 {
+		log.Printf("[DHT] Checking %d cached control endpoints", queued)
 	}
-	d.mu.Lock()
-	defer d.mu.Unlock()
-	d.controlPeers[peerPubKey] = normalized
 }
 
-func (d *DHTDiscovery) controlEndpointForPeer(peer *daemon.PeerInfo) string {
-	if peer == nil || peer.WGPubKey == "" {
-		return ""
-	}
-
-	// Prevent self-connection: don't return control endpoint for own node
-	if peer.WGPubKey == d.localNode.WGPubKey {
-		return ""
-	}
-
-	d.mu.RLock()
-	if endpoint, ok := d.controlPeers[peer.WGPubKey]; ok {
-		d.mu.RUnlock()
-		return endpoint
-	}
-	d.mu.RUnlock()
-
-	if endpoint := toControlEndpoint(peer.Endpoint, int(d.config.Keys.GossipPort)); endpoint != "" {
-		if d.config.DisableIPv6 && isIPv6Endpoint(endpoint) {
-			return ""
-		}
-		return endpoint
+// contactBootstrapPeers exchanges with the bootstrap peers of a v2 secret
+// URI, which find the mesh without waiting for the DHT. They come from the
+// signed URI, so they are trusted like configured introducers.
+func (d *DHTDiscovery) contactBootstrapPeers() {
+	for _, endpoint := range d.config.SecretOptions.BootstrapPeers {
+		endpoint := endpoint
+		d.dialer.schedule(endpoint, dialIntroducer, 20*time.Second, func() {
+			d.exchangeWithAddress(endpoint, DHTMethod+"-bootstrap")
+		})
 	}
-
-	return ""
 }
 
 func (d *DHTDiscovery) shouldAttemptRendezvous(remoteKey string, now time.Time) bool {
@@ -1292,10 +1454,11 @@ func (d *DHTDiscovery) selectRendezvousIntroducers(remoteKey string, peers []*da
 		endpoint        string
 		controlEndpoint string
 		isExplicit      bool
+		overloaded      bool
 	}
 
 	// Fetch handshakes once for all candidates (D6: avoid forking wg show per peer)
-	handshakes, _ := wireguard.GetLatestHandshakes(d.config.InterfaceName)
+	handshakes, _ := d.exchange.wireGuard().GetLatestHandshakes(d.config.InterfaceName)
 
 	candidates := make([]introducerCandidate, 0, len(peers))
 	for _, p := range peers {
@@ -1306,21 +1469,21 @@ func (d *DHTDiscovery) selectRendezvousIntroducers(remoteKey string, peers []*da
 			continue
 		}
 		if !hasAnyDHTReachability(p.DiscoveredVia) {
-			d.debugf("[NAT] DEBUG: %s skipped - no DHT reachability (via=%v)", shortKey(p.WGPubKey), p.DiscoveredVia)
+			d.debugf("[NAT] %s skipped - no DHT reachability (via=%v)", shortKey(p.WGPubKey), p.DiscoveredVia)
 			continue
 		}
 		if p.Endpoint == "" || !isLikelyPublicEndpoint(p.Endpoint) {
-			d.debugf("[NAT] DEBUG: %s skipped - endpoint not public (%s)", shortKey(p.WGPubKey), p.Endpoint)
+			d.debugf("[NAT] %s skipped - endpoint not public (%s)", shortKey(p.WGPubKey), p.Endpoint)
 			continue
 		}
 		if d.config.DisableIPv6 && isIPv6Endpoint(p.Endpoint) {
-			d.debugf("[NAT] DEBUG: %s skipped - IPv6 disabled (%s)", shortKey(p.WGPubKey), p.Endpoint)
+			d.debugf("[NAT] %s skipped - IPv6 disabled (%s)", shortKey(p.WGPubKey), p.Endpoint)
 			continue
 		}
 
 		controlEndpoint := d.controlEndpointForPeer(p)
 		if controlEndpoint == "" || !isLikelyPublicEndpoint(controlEndpoint) {
-			d.debugf("[NAT] DEBUG: %s skipped - control endpoint not public (%s)", shortKey(p.WGPubKey), controlEndpoint)
+			d.debugf("[NAT] %s skipped - control endpoint not public (%s)", shortKey(p.WGPubKey), controlEndpoint)
 			continue
 		}
 
@@ -1328,21 +1491,35 @@ func (d *DHTDiscovery) selectRendezvousIntroducers(remoteKey string, peers []*da
 		isAuto := !isExplicit && d.isAutoIntroducerCandidate(p, handshakes)
 
 		if !isExplicit && !isAuto {
-			d.debugf("[NAT] DEBUG: %s skipped - not explicit introducer and not auto-eligible (explicit=%v auto=%v)", shortKey(p.WGPubKey), isExplicit, isAuto)
+			d.debugf("[NAT] %s skipped - not explicit introducer and not auto-eligible (explicit=%v auto=%v)", shortKey(p.WGPubKey), isExplicit, isAuto)
 			continue
 		}
 
-		d.debugf("[NAT] DEBUG: %s selected as introducer (explicit=%v auto=%v control=%s)", shortKey(p.WGPubKey), isExplicit, isAuto, controlEndpoint)
+		d.debugf("[NAT] %s selected as introducer (explicit=%v auto=%v control=%s)", shortKey(p.WGPubKey), isExplicit, isAuto, controlEndpoint)
 		candidates = append(candidates, introducerCandidate{
 			pubKey:          p.WGPubKey,
 			endpoint:        p.Endpoint,
 			controlEndpoint: controlEndpoint,
 			isExplicit:      isExplicit,
+			overloaded:      daemon.IntroducerOverloaded(p),
 		})
 	}
 
+	// Pass over overloaded introducers unless every candidate is one.
+	available := candidates[:0:0]
+	for _, c := range candidates {
+		if !c.overloaded {
+			available = append(available, c)
+		} else {
+			d.debugf("[NAT] %s skipped - introducer overloaded", shortKey(c.pubKey))
+		}
+	}
+	if len(available) > 0 {
+		candidates = available
+	}
+
 	if len(candidates) == 0 {
-		d.debugf("[NAT] DEBUG: no introducer candidates for %s", shortKey(remoteKey))
+		d.debugf("[NAT] no introducer candidates for %s", shortKey(remoteKey))
 	}
 
 	if len(candidates) == 0 || maxCount <= 0 {
@@ -1433,12 +1610,6 @@ func shortKey(key string) string {
 	return key[:8] + "..."
 }
 
-func (d *DHTDiscovery) debugf(format string, args ...interface{}) {
-	if strings.EqualFold(d.config.LogLevel, "debug") {
-		log.Printf(format, args...)
-	}
-}
-
 func hasDiscoveryMethod(methods []string, method string) bool {
 	for _, m := range methods {
 		if m == method {
@@ -1456,17 +1627,3 @@ func hasAnyDHTReachability(methods []string) bool {
 	}
 	return false
 }
-
-func (d *DHTDiscovery) markContacted(addr string, minInterval time.Duration) bool {
-	d.mu.Lock()
-	defer d.mu.Unlock()
-
-	if lastContact, ok := d.contactedPeers[addr]; ok {
-		if time.Since(lastContact) < minInterval {
-			return false
-		}
-	}
-
-	d.contactedPeers[addr] = time.Now()
-	return true
-}
@@ -8,9 +8,67 @@ import (
 	"testing"
 	"time"
 
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
 )
 
+func TestDHTDiscoveryIsRotationParticipant(t *testing.T) {
+	var dl daemon.DiscoveryLayer = &DHTDiscovery{}
+	if _, ok := dl.(daemon.RotationParticipant); !ok {
+		t.Fatal("DHTDiscovery must implement daemon.RotationParticipant for mesh.rotate to work")
+	}
+}
+
+func TestDiscoveryLayersAreParticipants(t *testing.T) {
+	for _, dl := range []daemon.DiscoveryLayer{&DHTDiscovery{}, &DNSDiscovery{}} {
+		for name, ok := range map[string]bool{
+			"RotationParticipant":  implements[daemon.RotationParticipant](dl),
+			"BroadcastParticipant": implements[daemon.BroadcastParticipant](dl),
+			"ApprovalParticipant":  implements[daemon.ApprovalParticipant](dl),
+			"WireGuardParticipant": implements[daemon.WireGuardParticipant](dl),
+			"Reannouncer":          implements[daemon.Reannouncer](dl),
+			"NetworkChangeHandler": implements[daemon.NetworkChangeHandler](dl),
+			"LANStatusReporter":    implements[daemon.LANStatusReporter](dl),
+			"DandelionParticipant": implements[daemon.DandelionParticipant](dl),
+			"RendezvousCounter":    implements[daemon.RendezvousCounter](dl),
+		} {
+			if !ok {
+				t.Errorf("%T does not implement daemon.%s", dl, name)
+			}
+		}
+	}
+}
+
+func implements[T any](v any) bool {
+	_, ok := v.(T)
+	return ok
+}
+
+func TestDiscoveryLayersAreLANTogglers(t *testing.T) {
+	for _, dl := range []daemon.DiscoveryLayer{&DHTDiscovery{}, &DNSDiscovery{}} {
+		toggler, ok := dl.(daemon.LANToggler)
+		if !ok {
+			t.Fatalf("%T must implement daemon.LANToggler for config.set lan-discovery to work", dl)
+		}
+		if err := toggler.SetLANDiscovery(true); err == nil {
+			t.Errorf("%T: expected an error while not running", dl)
+		}
+	}
+}
+
+func TestDHTBootstrapNodesEnvOverride(t *testing.T) {
+	t.Setenv(DHTBootstrapEnv, "")
+	if got := dhtBootstrapNodes(); len(got) != len(DHTBootstrapNodes) {
+		t.Fatalf("expected the public bootstrap nodes, got %v", got)
+	}
+
+	t.Setenv(DHTBootstrapEnv, " 198.18.0.10:51822, ,[2001:db8::10]:51822")
+	got := dhtBootstrapNodes()
+	if len(got) != 2 || got[0] != "198.18.0.10:51822" || got[1] != "[2001:db8::10]:51822" {
+		t.Fatalf("unexpected bootstrap nodes %v", got)
+	}
+}
+
 func TestNodesFilePathIncludesNetworkTag(t *testing.T) {
 	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: "wgmesh-test-secret-network-tag-1"})
 	if err != nil {
@@ -57,6 +115,31 @@ func TestNodesFilePathDiffersBySecret(t *testing.T) {
 	}
 }
 
+func TestDHTShardTargets(t *testing.T) {
+	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: "wgmesh-test-secret-dht-shards-1"})
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	d := &DHTDiscovery{meshDiscovery: meshDiscovery{config: cfg, localNode: &daemon.LocalNode{WGPubKey: "a"}}}
+	if got := d.dhtShardTargets(); len(got) != 1 || got[0] != 0 {
+		t.Fatalf("unsharded targets = %v, want [0]", got)
+	}
+
+	cfg.DHTShards = 4
+	own := crypto.ShardForKey(cfg.Secret, "a", 4)
+	others := make(map[int]bool)
+	for i := 0; i < 200; i++ {
+		got := d.dhtShardTargets()
+		if len(got) != 2 || got[0] != own || got[1] == own || got[1] < 0 || got[1] >= 4 {
+			t.Fatalf("sharded targets = %v, want own shard %d and another", got, own)
+		}
+		others[got[1]] = true
+	}
+	if len(others) != 3 {
+		t.Errorf("random shard covered %v, want all 3 other shards", others)
+	}
+}
+
 func TestCanAttemptRendezvous_NewPeer(t *testing.T) {
 	cfg, _ := daemon.NewConfig(daemon.DaemonOpts{Secret: "test"})
 	d, _ := NewDHTDiscovery(context.Background(), cfg, &daemon.LocalNode{WGPubKey: "local"}, daemon.NewPeerStore())
@@ -70,9 +153,9 @@ func TestCanAttemptRendezvous_BackoffNotExpired(t *testing.T) {
 	cfg, _ := daemon.NewConfig(daemon.DaemonOpts{Secret: "test"})
 	d, _ := NewDHTDiscovery(context.Background(), cfg, &daemon.LocalNode{WGPubKey: "local"}, daemon.NewPeerStore())
 
-	d.mu.Lock()
-	d.rendezvousBackoff["peer1"] = backoffEntry{nextAttempt: time.Now().Add(30 * time.Second), duration: 30 * time.Second}
-	d.mu.Unlock()
+	d.dialer.mu.Lock()
+	d.dialer.backoff["peer1"] = backoffEntry{nextAttempt: time.Now().Add(30 * time.Second), duration: 30 * time.Second}
+	d.dialer.mu.Unlock()
 
 	if d.canAttemptRendezvous("peer1") {
 		t.Error("Peer in backoff should not be allowed to attempt rendezvous")
@@ -83,9 +166,9 @@ func TestCanAttemptRendezvous_BackoffExpired(t *testing.T) {
 	cfg, _ := daemon.NewConfig(daemon.DaemonOpts{Secret: "test"})
 	d, _ := NewDHTDiscovery(context.Background(), cfg, &daemon.LocalNode{WGPubKey: "local"}, daemon.NewPeerStore())
 
-	d.mu.Lock()
-	d.rendezvousBackoff["peer1"] = backoffEntry{nextAttempt: time.Now().Add(-1 * time.Second), duration: RendezvousMinBackoff}
-	d.mu.Unlock()
+	d.dialer.mu.Lock()
+	d.dialer.backoff["peer1"] = backoffEntry{nextAttempt: time.Now().Add(-1 * time.Second), duration: RendezvousMinBackoff}
+	d.dialer.mu.Unlock()
 
 	if !d.canAttemptRendezvous("peer1") {
 		t.Error("Peer with expired backoff should be allowed to attempt rendezvous")
@@ -96,15 +179,15 @@ func TestRecordRendezvousAttempt_SuccessResetsBackoff(t *testing.T) {
 	cfg, _ := daemon.NewConfig(daemon.DaemonOpts{Secret: "test"})
 	d, _ := NewDHTDiscovery(context.Background(), cfg, &daemon.LocalNode{WGPubKey: "local"}, daemon.NewPeerStore())
 
-	d.mu.Lock()
-	d.rendezvousBackoff["peer1"] = backoffEntry{nextAttempt: time.Now().Add(30 * time.Second), duration: 30 * time.Second}
-	d.mu.Unlock()
+	d.dialer.mu.Lock()
+	d.dialer.backoff["peer1"] = backoffEntry{nextAttempt: time.Now().Add(30 * time.Second), duration: 30 * time.Second}
+	d.dialer.mu.Unlock()
 
 	d.recordRendezvousAttempt("peer1", true)
 
-	d.mu.RLock()
-	_, exists := d.rendezvousBackoff["peer1"]
-	d.mu.RUnlock()
+	d.dialer.mu.RLock()
+	_, exists := d.dialer.backoff["peer1"]
+	d.dialer.mu.RUnlock()
 
 	if exists {
 		t.Error("Success should remove peer from backoff map")
@@ -117,9 +200,9 @@ func TestRecordRendezvousAttempt_FailureSetsMinBackoff(t *testing.T) {
 
 	d.recordRendezvousAttempt("peer1", false)
 
-	d.mu.RLock()
-	nextAttempt, exists := d.rendezvousBackoff["peer1"]
-	d.mu.RUnlock()
+	d.dialer.mu.RLock()
+	nextAttempt, exists := d.dialer.backoff["peer1"]
+	d.dialer.mu.RUnlock()
 
 	if !exists {
 		t.Fatal("Failure should add peer to backoff map")
@@ -139,16 +222,16 @@ func TestRecordRendezvousAttempt_ExponentialBackoff(t *testing.T) {
 	d, _ := NewDHTDiscovery(context.Background(), cfg, &daemon.LocalNode{WGPubKey: "local"}, daemon.NewPeerStore())
 
 	d.recordRendezvousAttempt("peer1", false)
-	d.mu.RLock()
-	firstBackoff := d.rendezvousBackoff["peer1"]
-	d.mu.RUnlock()
+	d.dialer.mu.RLock()
+	firstBackoff := d.dialer.backoff["peer1"]
+	d.dialer.mu.RUnlock()
 
 	time.Sleep(10 * time.Millisecond)
 
 	d.recordRendezvousAttempt("peer1", false)
-	d.mu.RLock()
-	secondBackoff := d.rendezvousBackoff["peer1"]
-	d.mu.RUnlock()
+	d.dialer.mu.RLock()
+	secondBackoff := d.dialer.backoff["peer1"]
+	d.dialer.mu.RUnlock()
 
 	if secondBackoff.duration <= firstBackoff.duration {
 		t.Errorf("Second failure should have longer backoff duration: first=%v, second=%v", firstBackoff.duration, secondBackoff.duration)
@@ -167,9 +250,9 @@ func TestRecordRendezvousAttempt_BackoffCappedAtMax(t *testing.T) {
 		time.Sleep(time.Millisecond)
 	}
 
-	d.mu.RLock()
-	nextAttempt := d.rendezvousBackoff["peer1"]
-	d.mu.RUnlock()
+	d.dialer.mu.RLock()
+	nextAttempt := d.dialer.backoff["peer1"]
+	d.dialer.mu.RUnlock()
 
 	maxAllowed := time.Now().Add(RendezvousMaxBackoff + 100*time.Millisecond)
 	if nextAttempt.nextAttempt.After(maxAllowed) {
@@ -251,25 +334,25 @@ func TestControlEndpointForPeer_RejectsSelf(t *testing.T) {
 }
 
 // TestRendezvous_ThrottledIntroducerFallsBackToNext verifies that when the primary
-// (first-selected) introducer endpoint is in the contactedPeers throttle window,
+// (first-selected) introducer endpoint was dialed within the throttle window,
 // the iteration continues and uses the next available introducer.
 func TestRendezvous_ThrottledIntroducerFallsBackToNext(t *testing.T) {
 	cfg, _ := daemon.NewConfig(daemon.DaemonOpts{Secret: "wgmesh-test-rendezvous-fallback"})
 	d, _ := NewDHTDiscovery(context.Background(), cfg, &daemon.LocalNode{WGPubKey: "local"}, daemon.NewPeerStore())
 
 	// Throttle the first endpoint — it was contacted within the last 20 seconds.
-	d.mu.Lock()
-	d.contactedPeers["1.2.3.4:9000"] = time.Now()
-	d.mu.Unlock()
+	d.dialer.mu.Lock()
+	d.dialer.lastDial["1.2.3.4:9000"] = time.Now()
+	d.dialer.mu.Unlock()
 
-	// markContacted on the first (throttled) endpoint must return false.
-	if d.markContacted("1.2.3.4:9000", 20*time.Second) {
-		t.Error("expected markContacted to return false for throttled endpoint")
+	// Scheduling the first (throttled) endpoint must fail.
+	if d.dialer.schedule("1.2.3.4:9000", dialIntroducer, 20*time.Second, func() {}) {
+		t.Error("expected schedule to refuse the throttled endpoint")
 	}
 
-	// markContacted on a different (unthrottled) endpoint must return true.
-	if !d.markContacted("5.6.7.8:9000", 20*time.Second) {
-		t.Error("expected markContacted to return true for unthrottled endpoint")
+	// Scheduling a different (unthrottled) endpoint must succeed.
+	if !d.dialer.schedule("5.6.7.8:9000", dialIntroducer, 20*time.Second, func() {}) {
+		t.Error("expected schedule to accept the unthrottled endpoint")
 	}
 }
 
@@ -282,23 +365,56 @@ func TestRendezvous_AllIntroducersBusy_FallsBackToRelay(t *testing.T) {
 	d, _ := NewDHTDiscovery(context.Background(), cfg, &daemon.LocalNode{WGPubKey: "local"}, daemon.NewPeerStore())
 
 	// Throttle every conceivable introducer endpoint.
-	d.mu.Lock()
-	d.contactedPeers["intro1:9000"] = time.Now()
-	d.contactedPeers["intro2:9000"] = time.Now()
-	d.contactedPeers["intro3:9000"] = time.Now()
-	d.mu.Unlock()
+	d.dialer.mu.Lock()
+	d.dialer.lastDial["intro1:9000"] = time.Now()
+	d.dialer.lastDial["intro2:9000"] = time.Now()
+	d.dialer.lastDial["intro3:9000"] = time.Now()
+	d.dialer.mu.Unlock()
 
 	// None of the throttled endpoints should be allowed.
 	for _, ep := range []string{"intro1:9000", "intro2:9000", "intro3:9000"} {
-		if d.markContacted(ep, 20*time.Second) {
+		if d.dialer.schedule(ep, dialIntroducer, 20*time.Second, func() {}) {
 			t.Errorf("expected throttle for %s", ep)
 		}
 	}
 
 	// The peer should still be contactable once the throttle window expires
 	// (simulate expiry by using a very short window).
-	if !d.markContacted("intro1:9000", 0) {
-		t.Error("expected markContacted to succeed once throttle window is zero")
+	if !d.dialer.schedule("intro1:9000", dialIntroducer, 0, func() {}) {
+		t.Error("expected schedule to succeed once throttle window is zero")
+	}
+}
+
+func TestSelectRendezvousIntroducers_SkipsOverloaded(t *testing.T) {
+	cfg, _ := daemon.NewConfig(daemon.DaemonOpts{Secret: "wgmesh-test-rendezvous-load"})
+	d, _ := NewDHTDiscovery(context.Background(), cfg, &daemon.LocalNode{WGPubKey: "local"}, daemon.NewPeerStore())
+
+	introducer := func(key, endpoint string, load *crypto.IntroducerLoad) *daemon.PeerInfo {
+		return &daemon.PeerInfo{WGPubKey: key, Endpoint: endpoint, Introducer: true, DiscoveredVia: []string{DHTMethod}, Load: load}
+	}
+	busy := &crypto.IntroducerLoad{Sessions: daemon.IntroducerMaxSessions}
+	peers := []*daemon.PeerInfo{
+		introducer("intro-a", "203.0.113.1:51820", busy),
+		introducer("intro-b", "203.0.113.2:51820", &crypto.IntroducerLoad{Sessions: 1}),
+		introducer("intro-c", "203.0.113.3:51820", nil),
+	}
+
+	got := d.selectRendezvousIntroducers("remote", peers, RendezvousMaxIntroducers)
+	if len(got) != 2 {
+		t.Fatalf("selected %d introducers, want 2: %+v", len(got), got)
+	}
+	for _, intro := range got {
+		if intro.WGPubKey == "intro-a" {
+			t.Error("overloaded introducer was selected")
+		}
+	}
+
+	// With every introducer overloaded, they are all still used.
+	for _, p := range peers {
+		p.Load = busy
+	}
+	if got := d.selectRendezvousIntroducers("remote", peers, RendezvousMaxIntroducers); len(got) != 3 {
+		t.Errorf("selected %d introducers with all overloaded, want 3", len(got))
 	}
 }
 
@@ -368,3 +484,43 @@ func TestBootstrapWithRetry_StopsOnContextCancel(t *testing.T) {
 		t.Error("bootstrapWithRetry did not stop within 500ms after context cancel")
 	}
 }
+
+func TestRevalidateControlEndpoints_DropsUnreachable(t *testing.T) {
+	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: "wgmesh-test-control-cache-1"})
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ps := daemon.NewPeerStore()
+	ps.Update(&daemon.PeerInfo{WGPubKey: "b", MeshIP: "10.0.0.2", ControlEndpoint: "198.51.100.2:51821"}, "cache")
+	d, err := NewDHTDiscovery(ctx, cfg, &daemon.LocalNode{WGPubKey: "a"}, ps)
+	if err != nil {
+		t.Fatalf("NewDHTDiscovery failed: %v", err)
+	}
+	// A closed conn makes the HELLO fail at once.
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	d.exchange.conn = conn
+
+	d.revalidateControlEndpoints()
+	go d.dialer.run(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		p, _ := ps.Get("b")
+		if p.ControlEndpoint == "" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("cached control endpoint %s kept after a failed HELLO", p.ControlEndpoint)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := d.controlEndpointForPeer(&daemon.PeerInfo{WGPubKey: "b"}); got != "" {
+		t.Errorf("controlEndpointForPeer = %q, want the unverified endpoint unused", got)
+	}
+}
@@ -0,0 +1,103 @@
+package discovery
+
+import (
+	"log"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+const (
+	// DHTTargetPeers is the peer count below which a node announces and
+	// queries more often, to find the rest of the mesh sooner.
+	DHTTargetPeers = 3
+
+	// Announce intervals by NAT type once DHTTargetPeers is reached. The
+	// DHT keeps the address it saw the announce come from, so a node
+	// behind NAT re-announces before its mapping is likely to expire.
+	DHTAnnounceIntervalSymmetric = 2 * time.Minute
+	DHTAnnounceIntervalCone      = 5 * time.Minute
+	DHTAnnounceIntervalPublic    = 30 * time.Minute
+	// DHTAnnounceIntervalSparse caps the announce interval below
+	// DHTTargetPeers.
+	DHTAnnounceIntervalSparse = 5 * time.Minute
+	// DHTQueryIntervalPublic is the query interval of a node without NAT
+	// that has DHTTargetPeers.
+	DHTQueryIntervalPublic = 2 * time.Minute
+	// DHTCadenceCheckInterval is how often announceLoop checks whether an
+	// announce is due, so a shorter interval takes effect without waiting
+	// out a longer one.
+	DHTCadenceCheckInterval = 30 * time.Second
+)
+
+// dhtIntervals returns how often a node with the given NAT type and peer
+// count announces to and queries the DHT. Nodes behind NAT, whose DHT
+// entries go stale sooner, and nodes that still lack peers do both more
+// often; a public node with enough peers does both less often.
+func dhtIntervals(natType string, peers int) (announce, query time.Duration) {
+	switch NATType(natType) {
+	case NATSymmetric:
+		announce, query = DHTAnnounceIntervalSymmetric, DHTQueryIntervalStable
+	case NATCone:
+		announce, query = DHTAnnounceIntervalCone, DHTQueryIntervalStable
+	case NATNone:
+		announce, query = DHTAnnounceIntervalPublic, DHTQueryIntervalPublic
+	default:
+		announce, query = DHTAnnounceInterval, DHTQueryIntervalStable
+	}
+	if peers < DHTTargetPeers {
+		announce = min(announce, DHTAnnounceIntervalSparse)
+		query = DHTQueryInterval
+	}
+	return announce, query
+}
+
+// updateCadence picks the announce and query intervals for the node's
+// current NAT type and peer count, logging when they change.
+func (d *DHTDiscovery) updateCadence() (announce, query time.Duration) {
+	natType := d.localNode.NATType
+	peers := d.peerStore.Count()
+	announce, query = dhtIntervals(natType, peers)
+
+	d.mu.Lock()
+	changed := announce != d.announceInterval || query != d.queryInterval
+	d.announceInterval, d.queryInterval = announce, query
+	d.cadencePeers = peers
+	d.mu.Unlock()
+
+	if changed {
+		log.Printf("[DHT] Announcing every %v and querying every %v (NAT type %q, %d peers)", announce, query, natType, peers)
+	}
+	return announce, query
+}
+
+// announceDue reports whether the current announce interval has passed
+// since the last announce.
+func (d *DHTDiscovery) announceDue() bool {
+	interval, _ := d.updateCadence()
+	d.mu.RLock()
+	last := d.lastAnnounce
+	d.mu.RUnlock()
+	return time.Since(last) >= interval
+}
+
+// DHTStatus describes the DHT announce and query cadence.
+func (d *DHTDiscovery) DHTStatus() *daemon.RPCDHTData {
+	d.mu.RLock()
+	status := &daemon.RPCDHTData{
+		NATType:          d.localNode.NATType,
+		Peers:            d.cadencePeers,
+		TargetPeers:      DHTTargetPeers,
+		AnnounceInterval: d.announceInterval,
+		QueryInterval:    d.queryInterval,
+		LastAnnounce:     d.lastAnnounce,
+		LastQuery:        d.lastQuery,
+	}
+	// The DHT server is set up before the first announce.
+	announced := !d.lastAnnounce.IsZero()
+	d.mu.RUnlock()
+	if announced && d.server != nil {
+		status.Nodes = d.server.NumNodes()
+	}
+	return status
+}
@@ -0,0 +1,67 @@
+package discovery
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+func TestDHTIntervals(t *testing.T) {
+	tests := []struct {
+		natType  string
+		peers    int
+		announce time.Duration
+		query    time.Duration
+	}{
+		{"symmetric", 10, DHTAnnounceIntervalSymmetric, DHTQueryIntervalStable},
+		{"cone", 10, DHTAnnounceIntervalCone, DHTQueryIntervalStable},
+		{"none", 10, DHTAnnounceIntervalPublic, DHTQueryIntervalPublic},
+		{"unknown", 10, DHTAnnounceInterval, DHTQueryIntervalStable},
+		{"", 10, DHTAnnounceInterval, DHTQueryIntervalStable},
+		{"none", 1, DHTAnnounceIntervalSparse, DHTQueryInterval},
+		{"symmetric", 0, DHTAnnounceIntervalSymmetric, DHTQueryInterval},
+	}
+	for _, tt := range tests {
+		announce, query := dhtIntervals(tt.natType, tt.peers)
+		if announce != tt.announce || query != tt.query {
+			t.Errorf("dhtIntervals(%q, %d) = %v, %v; want %v, %v", tt.natType, tt.peers, announce, query, tt.announce, tt.query)
+		}
+	}
+}
+
+func TestDHTStatusFollowsCadence(t *testing.T) {
+	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: "wgmesh-test-dht-cadence-1"})
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	ps := daemon.NewPeerStore()
+	localNode := &daemon.LocalNode{WGPubKey: "local", NATType: "none"}
+	d, err := NewDHTDiscovery(context.Background(), cfg, localNode, ps)
+	if err != nil {
+		t.Fatalf("NewDHTDiscovery failed: %v", err)
+	}
+
+	if !d.announceDue() {
+		t.Error("announce not due before the first one")
+	}
+	status := d.DHTStatus()
+	if status.AnnounceInterval != DHTAnnounceIntervalSparse || status.QueryInterval != DHTQueryInterval || status.TargetPeers != DHTTargetPeers {
+		t.Errorf("status with no peers = %+v", status)
+	}
+
+	for _, key := range []string{"a", "b", "c"} {
+		ps.Update(&daemon.PeerInfo{WGPubKey: key}, DHTMethod)
+	}
+	d.mu.Lock()
+	d.lastAnnounce = time.Now().Add(-10 * time.Minute)
+	d.mu.Unlock()
+	if d.announceDue() {
+		t.Error("public node with enough peers announces again after 10 minutes")
+	}
+	status = d.DHTStatus()
+	if status.AnnounceInterval != DHTAnnounceIntervalPublic || status.QueryInterval != DHTQueryIntervalPublic || status.Peers != 3 || status.NATType != "none" {
+		t.Errorf("status with 3 peers = %+v", status)
+	}
+}
@@ -0,0 +1,202 @@
+package discovery
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// dialPriority orders queued connection attempts; lower values go first.
+type dialPriority int
+
+const (
+	// dialIntroducer is for introducers, which rendezvous and relays
+	// depend on.
+	dialIntroducer dialPriority = iota
+	// dialKnown is for peers already in the peer store.
+	dialKnown
+	// dialDiscovered is for addresses from the DHT or DNS that are not
+	// known mesh members yet.
+	dialDiscovered
+
+	numDialPriorities
+)
+
+// DialQueueLimit caps how many connection attempts wait for a free slot.
+// A full queue drops the newest attempt of a lower priority to make room,
+// or else turns the new one away.
+const DialQueueLimit = 256
+
+// dialScheduler runs the connection attempts of a discovery layer with a
+// global concurrency cap, introducers and known peers first. It also keeps
+// the per-target state that decides whether an attempt is due: when each
+// target was last dialed, and the jittered exponential backoff of targets
+// whose attempts failed.
+type dialScheduler struct {
+	retryMin, retryMax time.Duration
+	slots              chan struct{}
+	wake               chan struct{}
+	mu                 sync.RWMutex
+	queues             [numDialPriorities][]dialJob
+	queued             map[string]struct{}
+	lastDial           map[string]time.Time    // target -> last attempt
+	backoff            map[string]backoffEntry // target -> retry state after failures
+}
+
+type dialJob struct {
+	key string
+	run func()
+}
+
+// newDialScheduler returns a scheduler running at most maxConcurrent
+// attempts at once, whose failed targets back off from retryMin doubling
+// up to retryMax. Queued attempts run once run is called.
+func newDialScheduler(maxConcurrent int, retryMin, retryMax time.Duration) *dialScheduler {
+	return &dialScheduler{
+		retryMin: retryMin,
+		retryMax: retryMax,
+		slots:    make(chan struct{}, maxConcurrent),
+		wake:     make(chan struct{}, 1),
+		queued:   make(map[string]struct{}),
+		lastDial: make(map[string]time.Time),
+		backoff:  make(map[string]backoffEntry),
+	}
+}
+
+// run starts queued attempts as slots free up, until ctx ends.
+func (s *dialScheduler) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s.slots <- struct{}{}:
+		}
+		job, ok := s.next(ctx)
+		if !ok {
+			<-s.slots
+			return
+		}
+		go func() {
+			defer func() { <-s.slots }()
+			job.run()
+		}()
+	}
+}
+
+// next waits for the most urgent queued attempt.
+func (s *dialScheduler) next(ctx context.Context) (dialJob, bool) {
+	for {
+		s.mu.Lock()
+		for p := range s.queues {
+			if q := s.queues[p]; len(q) > 0 {
+				job := q[0]
+				s.queues[p] = q[1:]
+				delete(s.queued, job.key)
+				s.mu.Unlock()
+				return job, true
+			}
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return dialJob{}, false
+		case <-s.wake:
+		}
+	}
+}
+
+// schedule queues fn as an attempt on key, unless key is already queued or
+// was dialed within minInterval. It reports whether fn was queued.
+func (s *dialScheduler) schedule(key string, priority dialPriority, minInterval time.Duration, fn func()) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.queued[key]; ok {
+		return false
+	}
+	if last, ok := s.lastDial[key]; ok && time.Since(last) < minInterval {
+		return false
+	}
+	if s.queueLen() >= DialQueueLimit && !s.dropLowerThan(priority) {
+		return false
+	}
+	s.lastDial[key] = time.Now()
+	s.queued[key] = struct{}{}
+	s.queues[priority] = append(s.queues[priority], dialJob{key: key, run: fn})
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (s *dialScheduler) queueLen() int {
+	n := 0
+	for _, q := range s.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// dropLowerThan drops the newest queued attempt of the lowest priority
+// below priority, forgetting that its target was dialed. It reports
+// whether one was dropped.
+func (s *dialScheduler) dropLowerThan(priority dialPriority) bool {
+	for p := numDialPriorities - 1; p > priority; p-- {
+		q := s.queues[p]
+		if len(q) == 0 {
+			continue
+		}
+		job := q[len(q)-1]
+		s.queues[p] = q[:len(q)-1]
+		delete(s.queued, job.key)
+		delete(s.lastDial, job.key)
+		return true
+	}
+	return false
+}
+
+// canRetry reports whether key is out of backoff.
+func (s *dialScheduler) canRetry(key string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, ok := s.backoff[key]
+	return !ok || time.Now().After(entry.nextAttempt)
+}
+
+// recordResult clears key's backoff after a success. After a failure it
+// doubles the backoff, from retryMin up to retryMax, and jitters the next
+// attempt so that targets failing together do not retry together.
+func (s *dialScheduler) recordResult(key string, success bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if success {
+		delete(s.backoff, key)
+		return
+	}
+
+	next := s.retryMin
+	if existing, ok := s.backoff[key]; ok {
+		next = min(max(existing.duration*2, s.retryMin), s.retryMax)
+	}
+	delay := min(max(dhtBackoffDelay(next), s.retryMin), s.retryMax)
+	s.backoff[key] = backoffEntry{
+		nextAttempt: time.Now().Add(delay),
+		duration:    next,
+	}
+}
+
+// reset forgets when targets were dialed and their backoff, for when the
+// local network changed and earlier failures say nothing about new
+// attempts. Queued attempts stay queued.
+func (s *dialScheduler) reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clear(s.lastDial)
+	clear(s.backoff)
+}
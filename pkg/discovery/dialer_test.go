@@ -0,0 +1,148 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDialScheduler_RunsByPriority(t *testing.T) {
+	s := newDialScheduler(1, RendezvousMinBackoff, RendezvousMaxBackoff)
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) func() {
+		return func() {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+		}
+	}
+	s.schedule("stranger", dialDiscovered, 0, record("stranger"))
+	s.schedule("known", dialKnown, 0, record("known"))
+	s.schedule("introducer", dialIntroducer, 0, record("introducer"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.run(ctx)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(order)
+		mu.Unlock()
+		if n == 3 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(order) != "[introducer known stranger]" {
+		t.Errorf("ran %v, want introducer, known, stranger", order)
+	}
+}
+
+func TestDialScheduler_CapsConcurrency(t *testing.T) {
+	const limit = 3
+	s := newDialScheduler(limit, RendezvousMinBackoff, RendezvousMaxBackoff)
+
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		s.schedule(fmt.Sprintf("addr-%d", i), dialDiscovered, 0, func() {
+			defer wg.Done()
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.run(ctx)
+	wg.Wait()
+
+	if p := peak.Load(); p > limit {
+		t.Errorf("%d attempts ran at once, want at most %d", p, limit)
+	}
+}
+
+func TestDialScheduler_DedupsAndThrottles(t *testing.T) {
+	s := newDialScheduler(1, RendezvousMinBackoff, RendezvousMaxBackoff)
+
+	if !s.schedule("addr", dialDiscovered, time.Minute, func() {}) {
+		t.Fatal("first attempt refused")
+	}
+	if s.schedule("addr", dialDiscovered, 0, func() {}) {
+		t.Error("an attempt already queued was queued twice")
+	}
+
+	s.mu.Lock()
+	s.queues[dialDiscovered] = nil
+	clear(s.queued)
+	s.mu.Unlock()
+	if s.schedule("addr", dialDiscovered, time.Minute, func() {}) {
+		t.Error("attempt within the minimum interval was queued")
+	}
+
+	s.reset()
+	if !s.schedule("addr", dialDiscovered, time.Minute, func() {}) {
+		t.Error("attempt refused after reset")
+	}
+}
+
+func TestDialScheduler_FullQueueDropsLowerPriority(t *testing.T) {
+	s := newDialScheduler(1, RendezvousMinBackoff, RendezvousMaxBackoff)
+	for i := 0; i < DialQueueLimit; i++ {
+		if !s.schedule(fmt.Sprintf("addr-%d", i), dialDiscovered, 0, func() {}) {
+			t.Fatalf("attempt %d refused before the queue was full", i)
+		}
+	}
+
+	if s.schedule("one-more", dialDiscovered, 0, func() {}) {
+		t.Error("a full queue took another attempt of the lowest priority")
+	}
+	if !s.schedule("introducer", dialIntroducer, 0, func() {}) {
+		t.Fatal("a full queue turned away an introducer")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n := s.queueLen(); n != DialQueueLimit {
+		t.Errorf("queue holds %d attempts, want %d", n, DialQueueLimit)
+	}
+	last := fmt.Sprintf("addr-%d", DialQueueLimit-1)
+	if _, ok := s.queued[last]; ok {
+		t.Errorf("the newest stranger %s was not the one dropped", last)
+	}
+	if _, ok := s.lastDial[last]; ok {
+		t.Error("the dropped attempt still counts as dialed")
+	}
+}
+
+func TestDialScheduler_RetryBackoffJitteredWithinBounds(t *testing.T) {
+	s := newDialScheduler(1, RendezvousMinBackoff, RendezvousMaxBackoff)
+	for i := 0; i < 50; i++ {
+		key := fmt.Sprintf("peer-%d", i)
+		start := time.Now()
+		s.recordResult(key, false)
+		entry := s.backoff[key]
+		if delay := entry.nextAttempt.Sub(start); delay < RendezvousMinBackoff || delay > RendezvousMaxBackoff+time.Second {
+			t.Fatalf("retry after %v, want within [%v, %v]", delay, RendezvousMinBackoff, RendezvousMaxBackoff)
+		}
+		if s.canRetry(key) {
+			t.Fatal("retry allowed during backoff")
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+const (
+	DNSMethod              = "dns"
+	DNSQueryInterval       = 30 * time.Second
+	DNSQueryIntervalStable = 2 * time.Minute
+	DNSLookupTimeout       = 10 * time.Second
+	DNSContactInterval     = 60 * time.Second
+	DNSMaxEndpoints        = 32 // Cap on endpoints taken from a single lookup
+	dnsTXTEndpointPrefix   = "endpoint="
+)
+
+// DNSDiscovery bootstraps the mesh from DNS records instead of the public
+// BitTorrent DHT. The configured name (e.g. "_wgmesh._udp.example.com") is
+// resolved as both SRV and TXT; every endpoint found is contacted over the
+// encrypted peer exchange, after which gossip/transitive discovery take over.
+//
+// SRV records point at introducer exchange ports directly. TXT records carry
+// whitespace-separated "endpoint=host[:port]" tokens; a missing port defaults
+// to the mesh's derived exchange port.
+type DNSDiscovery struct {
+	meshDiscovery
+
+	resolver *net.Resolver
+	dual     *DNSDiscovery // discovery under the new secret during a rotation
+}
+
+// NewDNSDiscovery creates a new DNS rendezvous discovery instance.
+// parentCtx should be the daemon's context so that discovery goroutines are
+// cancelled when the daemon shuts down.
+func NewDNSDiscovery(parentCtx context.Context, config *daemon.Config, localNode *daemon.LocalNode, peerStore *daemon.PeerStore) (*DNSDiscovery, error) {
+	if config.DNSRendezvous == "" {
+		return nil, fmt.Errorf("DNS rendezvous name is empty")
+	}
+
+	d := &DNSDiscovery{
+		meshDiscovery: newMeshDiscovery(parentCtx, config, localNode, peerStore),
+		resolver:      net.DefaultResolver,
+	}
+	return d, nil
+}
+
+// Start begins DNS discovery
+func (d *DNSDiscovery) Start() error {
+	d.mu.Lock()
+	if d.running {
+		d.mu.Unlock()
+		return fmt.Errorf("DNS discovery already running")
+	}
+	d.running = true
+	d.mu.Unlock()
+
+	if d.config.Gossip {
+		gossip, err := NewMeshGossipWithExchange(d.config, d.localNode, d.peerStore, d.exchange)
+		if err != nil {
+			return fmt.Errorf("failed to create gossip: %w", err)
+		}
+		d.gossip = gossip
+		d.exchange.SetAnnounceHandler(d.gossip.HandleAnnounceFrom)
+		d.exchange.SetDigestHandler(d.gossip.HandleDigest)
+	}
+
+	if err := d.exchange.Start(); err != nil {
+		return fmt.Errorf("failed to start peer exchange: %w", err)
+	}
+
+	if d.config.LANDiscovery {
+		lan, err := NewLANDiscovery(d.config, d.localNode, d.peerStore)
+		if err != nil {
+			log.Printf("[LAN] Failed to initialize LAN discovery: %v", err)
+		} else {
+			d.lan = lan
+			if err := d.lan.Start(); err != nil {
+				log.Printf("[LAN] Failed to start LAN discovery: %v", err)
+				d.lan = nil
+			}
+		}
+	}
+
+	d.stun = startSTUNResponder(d.config)
+
+	if d.gossip != nil {
+		if err := d.gossip.Start(); err != nil {
+			d.exchange.Stop()
+			return fmt.Errorf("failed to start gossip: %w", err)
+		}
+	}
+
+	go d.dialer.run(d.ctx)
+	go d.queryLoop()
+
+	log.Printf("[DNS] Discovery started for %s, listening on port %d", d.config.DNSRendezvous, d.exchange.Port())
+	return nil
+}
+
+// Stop stops DNS discovery
+func (d *DNSDiscovery) Stop() error {
+	d.mu.Lock()
+	if !d.running {
+		d.mu.Unlock()
+		return nil
+	}
+	d.running = false
+	dual := d.dual
+	d.dual = nil
+	lan := d.lan
+	d.lan = nil
+	d.mu.Unlock()
+
+	if dual != nil {
+		dual.Stop()
+	}
+
+	d.cancel()
+
+	if lan != nil {
+		lan.Stop()
+	}
+
+	if d.stun != nil {
+		d.stun.Stop()
+	}
+
+	if d.gossip != nil {
+		d.gossip.Stop()
+	}
+
+	if d.exchange != nil {
+		d.exchange.Stop()
+	}
+
+	log.Printf("[DNS] Discovery stopped")
+	return nil
+}
+
+// Reannounce exchanges the local announcement with every known peer now,
+// so a changed mesh IP reaches them before the next periodic round.
+func (d *DNSDiscovery) Reannounce() {
+	for endpoint := range d.peerControlEndpoints() {
+		d.dialer.schedule(endpoint, dialKnown, 0, func() {
+			d.exchangeWithEndpoint(endpoint)
+		})
+	}
+}
+
+// HandleNetworkChange pushes the local announcement to known peers and
+// resolves the rendezvous name again after the host has resumed or changed
+// networks. Contact backoff is cleared, since endpoints that failed from
+// the old network may work from the new one.
+func (d *DNSDiscovery) HandleNetworkChange() {
+	d.mu.Lock()
+	if !d.running {
+		d.mu.Unlock()
+		return
+	}
+	dual := d.dual
+	d.mu.Unlock()
+
+	d.dialer.reset()
+
+	go func() {
+		d.Reannounce()
+		d.queryPeers()
+	}()
+	if dual != nil {
+		dual.HandleNetworkChange()
+	}
+}
+
+// StartDualSecret runs a second discovery instance under the new secret of
+// a rotation, resolving the same rendezvous name, so this node accepts both
+// gossip keys until the daemon switches over. Peers it finds go to
+// peerStore, separate from the live one, since they are only reachable once
+// WireGuard uses the new keys.
+func (d *DNSDiscovery) StartDualSecret(config *daemon.Config, localNode *daemon.LocalNode, peerStore *daemon.PeerStore) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dual != nil {
+		return nil
+	}
+	dual, err := NewDNSDiscovery(d.ctx, config, localNode, peerStore)
+	if err != nil {
+		return err
+	}
+	dual.resolver = d.resolver
+	dual.exchange.bandwidth = d.exchange.bandwidth // one budget for both secrets
+	dual.exchange.wg = d.exchange.wg
+	if err := dual.Start(); err != nil {
+		return err
+	}
+	d.dual = dual
+	log.Printf("[Rotation] Dual-secret mode: also resolving %s under network ID %x", config.DNSRendezvous, config.Keys.NetworkID[:8])
+	return nil
+}
+
+// queryLoop periodically resolves the rendezvous name and contacts the
+// endpoints it returns. Like the DHT query loop it slows down once the mesh
+// has a few peers, since gossip keeps the membership fresh from then on.
+func (d *DNSDiscovery) queryLoop() {
+	d.queryPeers()
+
+	interval := DNSQueryInterval
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+			d.queryPeers()
+
+			if d.peerStore.Count() >= 3 && interval == DNSQueryInterval {
+				interval = DNSQueryIntervalStable
+				ticker.Reset(interval)
+				log.Printf("[DNS] Mesh stable, slowing query interval to %v", interval)
+			}
+		}
+	}
+}
+
+func (d *DNSDiscovery) queryPeers() {
+	ctx, cancel := context.WithTimeout(d.ctx, DNSLookupTimeout)
+	defer cancel()
+
+	endpoints, err := d.resolveEndpoints(ctx)
+	if err != nil {
+		log.Printf("[DNS] Lookup of %s failed: %v", d.config.DNSRendezvous, err)
+		return
+	}
+
+	log.Printf("[DNS] Resolved %d bootstrap endpoint(s) from %s", len(endpoints), d.config.DNSRendezvous)
+	for _, ep := range endpoints {
+		d.contactEndpoint(ep)
+	}
+}
+
+// resolveEndpoints looks up SRV and TXT records for the rendezvous name and
+// returns the de-duplicated union of endpoints. It only fails when neither
+// record type could be resolved.
+func (d *DNSDiscovery) resolveEndpoints(ctx context.Context) ([]string, error) {
+	name := d.config.DNSRendezvous
+	defaultPort := int(d.config.Keys.GossipPort)
+
+	var endpoints []string
+
+	_, srvs, srvErr := d.resolver.LookupSRV(ctx, "", "", name)
+	endpoints = append(endpoints, srvEndpoints(srvs)...)
+
+	txts, txtErr := d.resolver.LookupTXT(ctx, name)
+	endpoints = append(endpoints, parseDNSTXTEndpoints(txts, defaultPort)...)
+
+	if srvErr != nil && txtErr != nil {
+		return nil, fmt.Errorf("SRV: %v; TXT: %w", srvErr, txtErr)
+	}
+
+	return dedupEndpoints(endpoints, DNSMaxEndpoints), nil
+}
+
+// contactEndpoint schedules a peer exchange with a bootstrap endpoint.
+func (d *DNSDiscovery) contactEndpoint(addrStr string) {
+	if d.config.DisableIPv6 && isIPv6Endpoint(addrStr) {
+		return
+	}
+	d.dialer.schedule(addrStr, dialDiscovered, DNSContactInterval, func() {
+		d.exchangeWithEndpoint(addrStr)
+	})
+}
+
+func (d *DNSDiscovery) exchangeWithEndpoint(addrStr string) {
+	daemon.RecordNATTraversalAttempt(DNSMethod)
+
+	peerInfo, err := d.exchange.ExchangeWithPeer(addrStr)
+	if err != nil {
+		if !strings.Contains(err.Error(), "timeout") {
+			log.Printf("[DNS] Peer exchange failed with %s: %v", addrStr, err)
+		}
+		return
+	}
+	if peerInfo == nil || peerInfo.WGPubKey == d.localNode.WGPubKey {
+		return
+	}
+
+	log.Printf("[DNS] Found wgmesh peer %s (%s) at %s", shortKey(peerInfo.WGPubKey), peerInfo.MeshIP, peerInfo.Endpoint)
+	daemon.RecordNATTraversalSuccess(DNSMethod)
+	daemon.RecordDiscoveryEvent(DNSMethod)
+	d.peerStore.Update(peerInfo, DNSMethod)
+	d.setControlEndpoint(peerInfo.WGPubKey, addrStr)
+}
+
+// srvEndpoints converts SRV answers to host:port strings, honouring the
+// record priority (lower first) and weight (higher first).
+func srvEndpoints(srvs []*net.SRV) []string {
+	sorted := make([]*net.SRV, 0, len(srvs))
+	for _, srv := range srvs {
+		if srv == nil || srv.Port == 0 {
+			continue
+		}
+		target := strings.TrimSuffix(srv.Target, ".")
+		if target == "" {
+			continue
+		}
+		sorted = append(sorted, srv)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority < sorted[j].Priority
+		}
+		return sorted[i].Weight > sorted[j].Weight
+	})
+
+	out := make([]string, 0, len(sorted))
+	for _, srv := range sorted {
+		out = append(out, net.JoinHostPort(strings.TrimSuffix(srv.Target, "."), strconv.Itoa(int(srv.Port))))
+	}
+	return out
+}
+
+// parseDNSTXTEndpoints extracts "endpoint=host[:port]" tokens from TXT
+// record strings. Tokens without a port use defaultPort. Unrelated tokens
+// (e.g. "v=wgmesh1") are ignored so records can carry other metadata.
+func parseDNSTXTEndpoints(records []string, defaultPort int) []string {
+	var out []string
+	for _, record := range records {
+		for _, field := range strings.Fields(record) {
+			if !strings.HasPrefix(field, dnsTXTEndpointPrefix) {
+				continue
+			}
+			if ep := normalizeDNSEndpoint(strings.TrimPrefix(field, dnsTXTEndpointPrefix), defaultPort); ep != "" {
+				out = append(out, ep)
+			}
+		}
+	}
+	return out
+}
+
+// normalizeDNSEndpoint validates host[:port] and returns host:port, or ""
+// if the value is unusable.
+func normalizeDNSEndpoint(value string, defaultPort int) string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return ""
+	}
+
+	host, portStr, err := net.SplitHostPort(value)
+	if err != nil {
+		// No port: accept a bare hostname, IPv4 or bracketed/unbracketed IPv6.
+		host = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+		if host == "" || defaultPort <= 0 {
+			return ""
+		}
+		return net.JoinHostPort(host, strconv.Itoa(defaultPort))
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 || host == "" {
+		return ""
+	}
+	return net.JoinHostPort(host, portStr)
+}
+
+func dedupEndpoints(endpoints []string, limit int) []string {
+	seen := make(map[string]struct{}, len(endpoints))
+	out := make([]string, 0, len(endpoints))
+	for _, ep := range endpoints {
+		if _, ok := seen[ep]; ok {
+			continue
+		}
+		seen[ep] = struct{}{}
+		out = append(out, ep)
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+	}
+	return out
+}
@@ -0,0 +1,137 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+func TestParseDNSTXTEndpoints(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		records []string
+		want    []string
+	}{
+		{name: "explicit port", records: []string{"v=wgmesh1 endpoint=203.0.113.9:51821"}, want: []string{"203.0.113.9:51821"}},
+		{name: "default port", records: []string{"endpoint=intro.example.com"}, want: []string{"intro.example.com:51999"}},
+		{name: "ipv6", records: []string{"endpoint=[2001:db8::1]:51821 endpoint=2001:db8::2"}, want: []string{"[2001:db8::1]:51821", "[2001:db8::2]:51999"}},
+		{name: "multiple records", records: []string{"endpoint=a.example:1", "endpoint=b.example:2"}, want: []string{"a.example:1", "b.example:2"}},
+		{name: "ignores other tokens", records: []string{"v=wgmesh1 foo=bar"}, want: nil},
+		{name: "invalid port", records: []string{"endpoint=host:99999 endpoint=host:abc endpoint=:51820"}, want: nil},
+		{name: "empty value", records: []string{"endpoint="}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := parseDNSTXTEndpoints(tt.records, 51999)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseDNSTXTEndpoints(%q) = %v, want %v", tt.records, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSRVEndpointsOrdering(t *testing.T) {
+	t.Parallel()
+
+	srvs := []*net.SRV{
+		{Target: "backup.example.com.", Port: 51821, Priority: 20, Weight: 10},
+		{Target: "light.example.com.", Port: 51821, Priority: 10, Weight: 5},
+		{Target: "heavy.example.com.", Port: 51822, Priority: 10, Weight: 50},
+		{Target: "noport.example.com.", Port: 0, Priority: 0},
+		{Target: ".", Port: 51821},
+		nil,
+	}
+
+	got := srvEndpoints(srvs)
+	want := []string{"heavy.example.com:51822", "light.example.com:51821", "backup.example.com:51821"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("srvEndpoints() = %v, want %v", got, want)
+	}
+}
+
+func TestDedupEndpoints(t *testing.T) {
+	t.Parallel()
+
+	got := dedupEndpoints([]string{"a:1", "b:2", "a:1", "c:3"}, 2)
+	want := []string{"a:1", "b:2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("dedupEndpoints() = %v, want %v", got, want)
+	}
+}
+
+func TestDNSDiscoveryReceivesRotation(t *testing.T) {
+	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: "wgmesh-test-secret-dns-rotation-1", DNSRendezvous: "_wgmesh._udp.example.com"})
+	if err != nil {
+		t.Fatalf("NewConfig failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sender, err := NewDNSDiscovery(ctx, cfg, &daemon.LocalNode{WGPubKey: "alpha", MeshIP: "10.0.0.1"}, daemon.NewPeerStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiver, err := NewDNSDiscovery(ctx, cfg, &daemon.LocalNode{WGPubKey: "beta", MeshIP: "10.0.0.2"}, daemon.NewPeerStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The daemon hands its rotation handler to whichever discovery layer
+	// it runs.
+	var layer daemon.DiscoveryLayer = receiver
+	participant, ok := layer.(daemon.RotationParticipant)
+	if !ok {
+		t.Fatal("DNSDiscovery must implement daemon.RotationParticipant for mesh.rotate to work")
+	}
+	got := make(chan *crypto.RotationMessage, 1)
+	participant.SetRotationHandler(func(msg *crypto.RotationMessage) { got <- msg })
+
+	// recvConn stands in for the receiver's exchange port, which the sender
+	// learned when it found the receiver through the rendezvous name.
+	sendConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sendConn.Close()
+	recvConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recvConn.Close()
+	sender.exchange.conn = sendConn
+	sender.peerStore.Update(&daemon.PeerInfo{WGPubKey: "beta", MeshIP: "10.0.0.2"}, DNSMethod)
+	sender.setControlEndpoint("beta", recvConn.LocalAddr().String())
+
+	msg, err := crypto.NewRotationMessage(cfg.Keys.MembershipKey[:], "wgmesh-test-secret-dns-rotation-2", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender.BroadcastRotation(msg)
+
+	buf := make([]byte, 64<<10)
+	if err := recvConn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	n, from, err := recvConn.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("no rotation sent to the DNS-discovered peer: %v", err)
+	}
+	receiver.exchange.handleMessage(buf[:n], from)
+
+	select {
+	case rot := <-got:
+		if err := rot.Verify(cfg.Keys.MembershipKey[:]); err != nil {
+			t.Errorf("received rotation does not verify: %v", err)
+		}
+	default:
+		t.Fatal("rotation did not reach the daemon's handler")
+	}
+}
@@ -1,9 +1,12 @@
 package discovery
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"net"
 	"strconv"
 	"sync"
@@ -11,7 +14,9 @@ import (
 
 	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/privacy"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/ratelimit"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/tracing"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
 )
 
@@ -28,6 +33,20 @@ const (
 	HandshakeWaitTimeout    = 10 * time.Second // Increased from 3s - WG handshake needs more time for cross-DC
 	HandshakePollInterval   = 250 * time.Millisecond
 	ExchangeLogCooldown     = 30 * time.Second
+	GoodbyeMaxAge           = 60 * time.Second
+
+	// MaxRendezvousCandidates bounds the candidate list of a rendezvous
+	// offer; START messages carry the introducer's port-expanded copy.
+	MaxRendezvousCandidates = 32
+	maxStartCandidates      = (MaxRendezvousCandidates + 1) * (2*RendezvousPortSpread + 1)
+
+	// The listener decrypts at most ExchangeDecryptRate packets per second
+	// across all sources (after the per-source limit) and runs at most
+	// ExchangeMaxInflight handlers at once, so a flood of garbage from
+	// spoofed addresses cannot pin the CPU with AEAD failures.
+	ExchangeDecryptRate  = 200
+	ExchangeDecryptBurst = 400
+	ExchangeMaxInflight  = 64
 )
 
 type rendezvousOffer struct {
@@ -57,6 +76,61 @@ type goodbyeMessage struct {
 	WGPubKey  string `json:"wg_pubkey"`
 }
 
+// parseRendezvousOffer decodes a RENDEZVOUS_OFFER payload and checks the
+// fields handleRendezvousOffer relies on.
+func parseRendezvousOffer(plaintext []byte) (*rendezvousOffer, error) {
+	var offer rendezvousOffer
+	if err := json.Unmarshal(plaintext, &offer); err != nil {
+		return nil, err
+	}
+	if err := crypto.ValidateWGPubKey(offer.FromPubKey); err != nil {
+		return nil, fmt.Errorf("from_pubkey: %w", err)
+	}
+	if err := crypto.ValidateWGPubKey(offer.TargetPubKey); err != nil {
+		return nil, fmt.Errorf("target_pubkey: %w", err)
+	}
+	if len(offer.Candidates) > MaxRendezvousCandidates {
+		return nil, fmt.Errorf("too many candidates (%d, max %d)", len(offer.Candidates), MaxRendezvousCandidates)
+	}
+	if len(offer.PairID) > 64 {
+		return nil, fmt.Errorf("pair_id too long")
+	}
+	return &offer, nil
+}
+
+// parseRendezvousStart decodes a RENDEZVOUS_START payload. The start time
+// must be near now: a punch job for the pair is held until it fires.
+func parseRendezvousStart(plaintext []byte) (*rendezvousStart, error) {
+	var start rendezvousStart
+	if err := json.Unmarshal(plaintext, &start); err != nil {
+		return nil, err
+	}
+	if err := crypto.ValidateWGPubKey(start.PeerPubKey); err != nil {
+		return nil, fmt.Errorf("peer_pubkey: %w", err)
+	}
+	if len(start.PeerCandidates) > maxStartCandidates {
+		return nil, fmt.Errorf("too many candidates (%d, max %d)", len(start.PeerCandidates), maxStartCandidates)
+	}
+	if start.StartAtUnixMs != 0 {
+		window := RendezvousSessionTTL + crypto.ClockSkewTolerance()
+		if d := time.Until(time.UnixMilli(start.StartAtUnixMs)); d > window || d < -window {
+			return nil, fmt.Errorf("start time %v away", d.Round(time.Second))
+		}
+	}
+	return &start, nil
+}
+
+func parseGoodbye(plaintext []byte) (*goodbyeMessage, error) {
+	var bye goodbyeMessage
+	if err := json.Unmarshal(plaintext, &bye); err != nil {
+		return nil, err
+	}
+	if err := crypto.ValidateWGPubKey(bye.WGPubKey); err != nil {
+		return nil, fmt.Errorf("wg_pubkey: %w", err)
+	}
+	return &bye, nil
+}
+
 type rendezvousState struct {
 	offers    map[string]*rendezvousOffer
 	endpoints map[string]string
@@ -68,10 +142,15 @@ type PeerExchange struct {
 	config    *daemon.Config
 	localNode *daemon.LocalNode
 	peerStore *daemon.PeerStore
+	wg        wireguard.Backend // the daemon's WireGuard interface; nil runs the wg tool
 
-	conn    *net.UDPConn
-	port    int
-	limiter *ratelimit.IPRateLimiter
+	conn          *net.UDPConn
+	proxyConn     net.PacketConn // SOCKS5 association for first-contact HELLOs (nil = direct)
+	port          int
+	limiter       *ratelimit.IPRateLimiter
+	decryptBudget *ratelimit.IPRateLimiter // single bucket shared by all sources
+	handlerSlots  chan struct{}
+	bandwidth     *bandwidthBudget // --discovery-bandwidth, shared with the DHT
 
 	mu      sync.RWMutex
 	running bool
@@ -80,7 +159,14 @@ type PeerExchange struct {
 	pendingMu      sync.Mutex
 	pendingReplies map[string]chan *daemon.PeerInfo
 
-	announceHandler func(*crypto.PeerAnnouncement, *net.UDPAddr)
+	announceHandler  func(*crypto.PeerAnnouncement, *net.UDPAddr)
+	digestHandler    func(string, *crypto.GossipDigest, *net.UDPAddr)
+	rotationHandler  func(*crypto.RotationMessage)
+	broadcastHandler func(*crypto.BroadcastMessage)
+	approvalHandler  func(*crypto.ApprovalMessage)
+
+	dandelion *privacy.DandelionRouter // set with SetDandelionRouter in privacy mode
+	lastStem  time.Time                // when our announcement last went down a stem
 
 	rendezvousMu       sync.Mutex
 	rendezvousSessions map[string]*rendezvousState
@@ -93,11 +179,18 @@ type PeerExchange struct {
 
 // NewPeerExchange creates a new peer exchange handler
 func NewPeerExchange(config *daemon.Config, localNode *daemon.LocalNode, peerStore *daemon.PeerStore) *PeerExchange {
+	var bandwidth int
+	if config != nil {
+		bandwidth = config.DiscoveryBandwidth
+	}
 	return &PeerExchange{
 		config:             config,
 		localNode:          localNode,
 		peerStore:          peerStore,
 		limiter:            ratelimit.NewDefault(),
+		decryptBudget:      ratelimit.New(ExchangeDecryptRate, ExchangeDecryptBurst, 1),
+		handlerSlots:       make(chan struct{}, ExchangeMaxInflight),
+		bandwidth:          newBandwidthBudget(bandwidth),
 		stopCh:             make(chan struct{}),
 		pendingReplies:     make(map[string]chan *daemon.PeerInfo),
 		rendezvousSessions: make(map[string]*rendezvousState),
@@ -126,6 +219,15 @@ func (pe *PeerExchange) Start() error {
 		return fmt.Errorf("failed to bind UDP port %d: %w", port, err)
 	}
 
+	if pe.config.SOCKS5Proxy != nil {
+		proxyConn, err := dialSOCKS5UDP(pe.config.SOCKS5Proxy, 0)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		pe.proxyConn = proxyConn
+	}
+
 	pe.conn = conn
 	pe.port = port
 	pe.running = true
@@ -133,6 +235,9 @@ func (pe *PeerExchange) Start() error {
 
 	// Start listener
 	go pe.listenLoop()
+	if pe.proxyConn != nil {
+		go pe.listenProxyLoop(pe.proxyConn)
+	}
 
 	log.Printf("[Exchange] Listening on UDP port %d", port)
 	return nil
@@ -153,6 +258,10 @@ func (pe *PeerExchange) Stop() {
 	if pe.conn != nil {
 		pe.conn.Close()
 	}
+	if pe.proxyConn != nil {
+		pe.proxyConn.Close()
+		pe.proxyConn = nil
+	}
 }
 
 // Port returns the listening port
@@ -192,48 +301,148 @@ func (pe *PeerExchange) listenLoop() {
 			continue
 		}
 
-		// Rate-limit per source IP before dispatching
-		if !pe.limiter.Allow(remoteAddr.IP.String()) {
+		if pe.config.Chaos.DropExchange() {
+			daemon.RecordExchangeDrop("chaos")
+			continue
+		}
+
+		if !pe.admitPacket(remoteAddr.IP.String()) {
 			continue
 		}
 
 		// Handle message in goroutine
 		data := make([]byte, n)
 		copy(data, buf[:n])
-		go pe.handleMessage(data, remoteAddr)
+		go func() {
+			defer pe.releasePacket()
+			pe.handleMessage(data, remoteAddr)
+		}()
+	}
+}
+
+// listenProxyLoop reads datagrams relayed by the SOCKS5 proxy. Only REPLYs to
+// our proxied HELLOs are expected there; anything else is dropped.
+func (pe *PeerExchange) listenProxyLoop(conn net.PacketConn) {
+	buf := make([]byte, MaxExchangeSize)
+
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-pe.stopCh:
+			default:
+				log.Printf("[Exchange] SOCKS5 relay closed: %v", err)
+			}
+			return
+		}
+		remoteAddr, ok := addr.(*net.UDPAddr)
+		if !ok || !pe.admitPacket(remoteAddr.IP.String()) {
+			continue
+		}
+
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		go func() {
+			defer pe.releasePacket()
+			pe.handleProxiedMessage(data, remoteAddr)
+		}()
+	}
+}
+
+// handleProxiedMessage processes a packet that arrived through the SOCKS5
+// relay.
+func (pe *PeerExchange) handleProxiedMessage(data []byte, remoteAddr *net.UDPAddr) {
+	envelope, plaintext, err := crypto.OpenEnvelopeRawWithKeys(data, pe.config.EnvelopeOpenKeys())
+	if err != nil {
+		daemon.RecordExchangeDrop("decrypt_failed")
+		return
+	}
+	if envelope.MessageType != crypto.MessageTypeReply {
+		return
+	}
+	reply, err := crypto.ParseAnnouncement(plaintext)
+	if err != nil {
+		log.Printf("[Exchange] Invalid proxied REPLY payload from %s: %v", remoteAddr.String(), err)
+		return
 	}
+	// The responder saw the proxy, not us.
+	reply.ObservedEndpoint = ""
+	pe.handleReply(reply, remoteAddr)
+}
+
+// admitPacket decides whether a received packet is decrypted at all. It is
+// dropped when its source exceeds the per-source rate, when the global
+// decryption budget is spent, or when ExchangeMaxInflight handlers are busy.
+// An admitted packet holds a handler slot until releasePacket.
+func (pe *PeerExchange) admitPacket(sourceIP string) bool {
+	if !pe.limiter.Allow(sourceIP) {
+		daemon.RecordExchangeDrop("rate_limited")
+		return false
+	}
+	if !pe.decryptBudget.Allow("") {
+		daemon.RecordExchangeDrop("budget")
+		return false
+	}
+	select {
+	case pe.handlerSlots <- struct{}{}:
+		return true
+	default:
+		daemon.RecordExchangeDrop("busy")
+		return false
+	}
+}
+
+func (pe *PeerExchange) releasePacket() {
+	<-pe.handlerSlots
 }
 
 // handleMessage processes an incoming peer exchange message
 func (pe *PeerExchange) handleMessage(data []byte, remoteAddr *net.UDPAddr) {
 	// Try to decrypt the message
-	envelope, plaintext, err := crypto.OpenEnvelopeRaw(data, pe.config.Keys.GossipKey)
+	envelope, plaintext, err := crypto.OpenEnvelopeRawWithKeys(data, pe.config.EnvelopeOpenKeys())
+	var stale *crypto.TimestampError
+	if errors.As(err, &stale) {
+		// A mesh message outside the timestamp window: our clock or the
+		// sender's is off. It still counts towards the skew estimate, which
+		// widens the window once most peers agree that ours is the one off.
+		daemon.RecordExchangeDrop("clock_skew")
+		daemon.ObservePeerClock(remoteAddr.IP.String(), stale.SentAt)
+		if pe.shouldLogPacket("stale|" + remoteAddr.IP.String()) {
+			log.Printf("[Exchange] Rejected message from %s: %v", remoteAddr.String(), err)
+		}
+		return
+	}
 	if err != nil {
-		// Could be a DHT message or wrong key - log for debugging
-		log.Printf("[Exchange] Received non-wgmesh packet from %s (len=%d, possibly DHT or wrong secret)", remoteAddr.String(), len(data))
+		// Could be a DHT message or wrong key - log for debugging, but not
+		// once per packet of a flood.
+		daemon.RecordExchangeDrop("decrypt_failed")
+		if pe.shouldLogPacket("invalid|" + remoteAddr.IP.String()) {
+			log.Printf("[Exchange] Received non-wgmesh packet from %s (len=%d, possibly DHT or wrong secret)", remoteAddr.String(), len(data))
+		}
 		return
 	}
 
 	pe.logIncomingPacket(envelope.MessageType, remoteAddr)
+	daemon.ObservePeerClock(remoteAddr.IP.String(), envelope.SentAt)
 
 	switch envelope.MessageType {
 	case crypto.MessageTypeHello:
-		var announcement crypto.PeerAnnouncement
-		if err := json.Unmarshal(plaintext, &announcement); err != nil {
+		announcement, err := crypto.ParseAnnouncement(plaintext)
+		if err != nil {
 			log.Printf("[Exchange] Invalid HELLO payload from %s: %v", remoteAddr.String(), err)
 			return
 		}
-		pe.handleHello(&announcement, remoteAddr)
+		pe.handleHello(announcement, remoteAddr)
 	case crypto.MessageTypeReply:
-		var reply crypto.PeerAnnouncement
-		if err := json.Unmarshal(plaintext, &reply); err != nil {
+		reply, err := crypto.ParseAnnouncement(plaintext)
+		if err != nil {
 			log.Printf("[Exchange] Invalid REPLY payload from %s: %v", remoteAddr.String(), err)
 			return
 		}
-		pe.handleReply(&reply, remoteAddr)
+		pe.handleReply(reply, remoteAddr)
 	case crypto.MessageTypeAnnounce:
-		var announcement crypto.PeerAnnouncement
-		if err := json.Unmarshal(plaintext, &announcement); err != nil {
+		announcement, err := crypto.ParseAnnouncement(plaintext)
+		if err != nil {
 			log.Printf("[Exchange] Invalid ANNOUNCE payload from %s: %v", remoteAddr.String(), err)
 			return
 		}
@@ -241,38 +450,58 @@ func (pe *PeerExchange) handleMessage(data []byte, remoteAddr *net.UDPAddr) {
 		handler := pe.announceHandler
 		pe.mu.RUnlock()
 		if handler != nil {
-			handler(&announcement, remoteAddr)
+			handler(announcement, remoteAddr)
+		}
+	case crypto.MessageTypeDigest, crypto.MessageTypeDigestEntries, crypto.MessageTypeDigestWant:
+		digest, err := crypto.ParseGossipDigest(plaintext)
+		if err != nil {
+			log.Printf("[Exchange] Invalid %s payload from %s: %v", envelope.MessageType, remoteAddr.String(), err)
+			return
+		}
+		pe.mu.RLock()
+		handler := pe.digestHandler
+		pe.mu.RUnlock()
+		if handler != nil {
+			handler(envelope.MessageType, digest, remoteAddr)
 		}
 	case crypto.MessageTypeRendezvousOffer:
-		var offer rendezvousOffer
-		if err := json.Unmarshal(plaintext, &offer); err != nil {
+		offer, err := parseRendezvousOffer(plaintext)
+		if err != nil {
 			log.Printf("[NAT] Invalid RENDEZVOUS_OFFER from %s: %v", remoteAddr.String(), err)
 			return
 		}
-		pe.handleRendezvousOffer(&offer, remoteAddr)
+		pe.handleRendezvousOffer(offer, remoteAddr)
 	case crypto.MessageTypeRendezvousStart:
-		var start rendezvousStart
-		if err := json.Unmarshal(plaintext, &start); err != nil {
+		start, err := parseRendezvousStart(plaintext)
+		if err != nil {
 			log.Printf("[NAT] Invalid RENDEZVOUS_START from %s: %v", remoteAddr.String(), err)
 			return
 		}
-		pe.handleRendezvousStart(&start, remoteAddr)
+		pe.handleRendezvousStart(start, remoteAddr)
+	case crypto.MessageTypeDandelionStem:
+		stem, err := parseDandelionStem(plaintext)
+		if err != nil {
+			log.Printf("[Dandelion] Invalid DANDELION_STEM payload from %s: %v", remoteAddr.String(), err)
+			return
+		}
+		pe.handleDandelionStem(stem)
 	case crypto.MessageTypeGoodbye:
-		var bye goodbyeMessage
-		if err := json.Unmarshal(plaintext, &bye); err != nil {
+		bye, err := parseGoodbye(plaintext)
+		if err != nil {
 			log.Printf("[Exchange] Invalid GOODBYE payload from %s: %v", remoteAddr.String(), err)
 			return
 		}
-		if bye.WGPubKey == "" || bye.WGPubKey == pe.localNode.WGPubKey {
+		if bye.WGPubKey == pe.localNode.WGPubKey {
 			return
 		}
 		// Validate timestamp to prevent replay attacks
 		msgTime := time.Unix(bye.Timestamp, 0)
-		if time.Since(msgTime) > 60*time.Second {
+		window := GoodbyeMaxAge + crypto.ClockSkewTolerance()
+		if time.Since(msgTime) > window {
 			log.Printf("[Exchange] Rejected stale GOODBYE from %s (age: %v)", remoteAddr.String(), time.Since(msgTime))
 			return
 		}
-		if msgTime.After(time.Now().Add(60 * time.Second)) {
+		if msgTime.After(time.Now().Add(window)) {
 			log.Printf("[Exchange] Rejected GOODBYE with future timestamp from %s", remoteAddr.String())
 			return
 		}
@@ -282,6 +511,42 @@ func (pe *PeerExchange) handleMessage(data []byte, remoteAddr *net.UDPAddr) {
 			name = name[:8] + "..."
 		}
 		log.Printf("[Exchange] Peer %s reported shutdown, removed from active set", name)
+	case crypto.MessageTypeRotate:
+		var msg crypto.RotationMessage
+		if err := json.Unmarshal(plaintext, &msg); err != nil {
+			log.Printf("[Rotation] Invalid ROTATE payload from %s: %v", remoteAddr.String(), err)
+			return
+		}
+		pe.mu.RLock()
+		handler := pe.rotationHandler
+		pe.mu.RUnlock()
+		if handler != nil {
+			handler(&msg)
+		}
+	case crypto.MessageTypeBroadcast:
+		var msg crypto.BroadcastMessage
+		if err := json.Unmarshal(plaintext, &msg); err != nil {
+			log.Printf("[Broadcast] Invalid BROADCAST payload from %s: %v", remoteAddr.String(), err)
+			return
+		}
+		pe.mu.RLock()
+		handler := pe.broadcastHandler
+		pe.mu.RUnlock()
+		if handler != nil {
+			handler(&msg)
+		}
+	case crypto.MessageTypeApproval:
+		var msg crypto.ApprovalMessage
+		if err := json.Unmarshal(plaintext, &msg); err != nil || msg.Approval == nil {
+			log.Printf("[Admission] Invalid APPROVAL payload from %s", remoteAddr.String())
+			return
+		}
+		pe.mu.RLock()
+		handler := pe.approvalHandler
+		pe.mu.RUnlock()
+		if handler != nil {
+			handler(&msg)
+		}
 	default:
 		log.Printf("[Exchange] Unknown message type: %s", envelope.MessageType)
 	}
@@ -294,19 +559,46 @@ func (pe *PeerExchange) handleHello(announcement *crypto.PeerAnnouncement, remot
 		return
 	}
 
+	identity, ok := verifiedIdentity(announcement, pe.config, "Exchange")
+	if !ok {
+		return
+	}
+
 	// Update peer store with the sender's info
 	peerInfo := &daemon.PeerInfo{
-		WGPubKey:         announcement.WGPubKey,
-		Hostname:         announcement.Hostname,
-		MeshIP:           announcement.MeshIP,
-		MeshIPv6:         announcement.MeshIPv6,
-		Endpoint:         filterEndpointForConfig(resolvePeerEndpoint(announcement.WGEndpoint, remoteAddr), pe.config.DisableIPv6),
-		Introducer:       announcement.Introducer,
-		RoutableNetworks: announcement.RoutableNetworks,
-		NATType:          announcement.NATType,
+		WGPubKey:            announcement.WGPubKey,
+		Hostname:            announcement.Hostname,
+		MeshIP:              announcement.MeshIP,
+		MeshIPv6:            announcement.MeshIPv6,
+		Endpoint:            filterEndpointForConfig(resolvePeerEndpoint(announcement.WGEndpoint, remoteAddr), pe.config.DisableIPv6),
+		Introducer:          announcement.Introducer,
+		RoutableNetworks:    announcement.RoutableNetworks,
+		RoutesAnnounced:     true,
+		NATType:             announcement.NATType,
+		Version:             announcement.Version,
+		Revision:            announcement.Revision,
+		MinRevision:         announcement.MinRevision,
+		Capabilities:        announcement.Capabilities,
+		Load:                announcement.Load,
+		Relays:              announcement.Relays,
+		IntroducerCandidate: announcement.IntroducerCandidate,
+		IntroducerElected:   announcement.IntroducerElected,
+		PairPSK:             announcement.PairPSK,
+		Tags:                announcement.Tags,
+		PortHop:             time.Duration(announcement.PortHop) * time.Second,
+		TCPPort:             announcement.TCPPort,
+		TCPVia:              announcement.TCPVia,
+		Approval:            announcement.Approval,
+		DNS:                 announcement.DNS,
+		RouteChecks:         announcement.RouteChecks,
+		Candidates:          announcedCandidates(announcement.EndpointCandidates, pe.config.DisableIPv6),
+		Identity:            identity,
 	}
 
 	pe.peerStore.Update(peerInfo, DHTMethod)
+	// The sender is about to configure a tunnel to us; make sure we install
+	// it too when the installed-peer cap is in effect.
+	pe.peerStore.MarkDemand(peerInfo.WGPubKey)
 
 	pe.updateTransitivePeers(announcement.KnownPeers)
 
@@ -324,15 +616,39 @@ func (pe *PeerExchange) handleReply(reply *crypto.PeerAnnouncement, remoteAddr *
 	// IP:port looks like. Use the reflected IP combined with our WG port.
 	pe.applyObservedEndpoint(reply.ObservedEndpoint)
 
+	identity, ok := verifiedIdentity(reply, pe.config, "Exchange")
+	if !ok {
+		return
+	}
+
 	peerInfo := &daemon.PeerInfo{
-		WGPubKey:         reply.WGPubKey,
-		Hostname:         reply.Hostname,
-		MeshIP:           reply.MeshIP,
-		MeshIPv6:         reply.MeshIPv6,
-		Endpoint:         filterEndpointForConfig(resolvePeerEndpoint(reply.WGEndpoint, remoteAddr), pe.config.DisableIPv6),
-		Introducer:       reply.Introducer,
-		RoutableNetworks: reply.RoutableNetworks,
-		NATType:          reply.NATType,
+		WGPubKey:            reply.WGPubKey,
+		Hostname:            reply.Hostname,
+		MeshIP:              reply.MeshIP,
+		MeshIPv6:            reply.MeshIPv6,
+		Endpoint:            filterEndpointForConfig(resolvePeerEndpoint(reply.WGEndpoint, remoteAddr), pe.config.DisableIPv6),
+		Introducer:          reply.Introducer,
+		RoutableNetworks:    reply.RoutableNetworks,
+		RoutesAnnounced:     true,
+		NATType:             reply.NATType,
+		Version:             reply.Version,
+		Revision:            reply.Revision,
+		MinRevision:         reply.MinRevision,
+		Capabilities:        reply.Capabilities,
+		Load:                reply.Load,
+		Relays:              reply.Relays,
+		IntroducerCandidate: reply.IntroducerCandidate,
+		IntroducerElected:   reply.IntroducerElected,
+		PairPSK:             reply.PairPSK,
+		Tags:                reply.Tags,
+		PortHop:             time.Duration(reply.PortHop) * time.Second,
+		TCPPort:             reply.TCPPort,
+		TCPVia:              reply.TCPVia,
+		Approval:            reply.Approval,
+		DNS:                 reply.DNS,
+		RouteChecks:         reply.RouteChecks,
+		Candidates:          announcedCandidates(reply.EndpointCandidates, pe.config.DisableIPv6),
+		Identity:            identity,
 	}
 
 	pe.updateTransitivePeers(reply.KnownPeers)
@@ -340,6 +656,7 @@ func (pe *PeerExchange) handleReply(reply *crypto.PeerAnnouncement, remoteAddr *
 	// Always update the peer store so reconcile can configure WG promptly,
 	// even when we also route this reply to a pending ExchangeWithPeer caller.
 	pe.peerStore.Update(peerInfo, DHTMethod)
+	pe.peerStore.MarkDemand(peerInfo.WGPubKey)
 
 	if ch, ok := pe.getPendingReplyChannel(remoteAddr.String()); ok {
 		select {
@@ -412,22 +729,25 @@ func (pe *PeerExchange) sendReply(remoteAddr *net.UDPAddr) error {
 		pe.localNode.WGPubKey,
 		pe.localNode.MeshIP,
 		pe.localNode.GetEndpoint(),
-		pe.localNode.Introducer,
+		pe.localNode.IsIntroducer(),
 		pe.localNode.RoutableNetworks,
 		knownPeers,
 		pe.localNode.Hostname,
 		pe.localNode.MeshIPv6,
 		string(pe.localNode.NATType),
 	)
+	announcement.EndpointCandidates = localEndpointCandidates(pe.localNode, pe.config)
+	announcement.Version = pe.config.Version
+	pe.localNode.AnnounceRelayState(announcement)
 	announcement.ObservedEndpoint = remoteAddr.String()
+	pe.localNode.SignAnnouncement(announcement)
 
-	data, err := crypto.SealEnvelope(crypto.MessageTypeReply, announcement, pe.config.Keys.GossipKey)
+	data, err := crypto.SealEnvelope(crypto.MessageTypeReply, announcement, pe.config.EnvelopeSealKey())
 	if err != nil {
 		return fmt.Errorf("failed to seal reply: %w", err)
 	}
 
-	_, err = pe.conn.WriteToUDP(data, remoteAddr)
-	if err != nil {
+	if err := pe.send(data, remoteAddr); err != nil {
 		return fmt.Errorf("failed to send reply: %w", err)
 	}
 	return nil
@@ -435,6 +755,39 @@ func (pe *PeerExchange) sendReply(remoteAddr *net.UDPAddr) error {
 
 // ExchangeWithPeer initiates a peer exchange with a remote address
 func (pe *PeerExchange) ExchangeWithPeer(addrStr string) (*daemon.PeerInfo, error) {
+	return pe.exchangeWithPeer(context.Background(), addrStr, nil)
+}
+
+// ExchangeWithUnknownPeer is ExchangeWithPeer for addresses that are not yet
+// known to belong to a mesh member, such as those returned by the DHT. With
+// a SOCKS5 proxy configured the HELLO goes through the proxy, so whoever
+// announced the address learns ours only by answering as a member. Without
+// one it is the same as ExchangeWithPeer.
+func (pe *PeerExchange) ExchangeWithUnknownPeer(addrStr string) (*daemon.PeerInfo, error) {
+	pe.mu.RLock()
+	proxyConn := pe.proxyConn
+	pe.mu.RUnlock()
+	return pe.exchangeWithPeer(context.Background(), addrStr, proxyConn)
+}
+
+// exchangeWithPeer sends HELLOs to addrStr and waits for the REPLY. With a
+// proxy connection the HELLO is sent once through it; there is no point in
+// punching, as the proxy's NAT is not ours. The round trip is traced as a
+// child of any span in ctx.
+func (pe *PeerExchange) exchangeWithPeer(ctx context.Context, addrStr string, proxyConn net.PacketConn) (peerInfo *daemon.PeerInfo, err error) {
+	attempts := 0
+	_, span := tracing.Start(ctx, "exchange.round_trip",
+		tracing.String("peer.address", addrStr),
+		tracing.Bool("exchange.proxied", proxyConn != nil))
+	defer func() {
+		span.SetAttributes(tracing.Int("exchange.hello_attempts", attempts))
+		if peerInfo != nil {
+			span.SetAttributes(tracing.String("peer.pubkey", peerInfo.WGPubKey))
+		}
+		span.RecordError(err)
+		span.End()
+	}()
+
 	remoteAddr, err := net.ResolveUDPAddr("udp", addrStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve address: %w", err)
@@ -465,28 +818,44 @@ func (pe *PeerExchange) ExchangeWithPeer(addrStr string) (*daemon.PeerInfo, erro
 		pe.localNode.WGPubKey,
 		pe.localNode.MeshIP,
 		pe.localNode.GetEndpoint(),
-		pe.localNode.Introducer,
+		pe.localNode.IsIntroducer(),
 		pe.localNode.RoutableNetworks,
 		knownPeers,
 		pe.localNode.Hostname,
 		pe.localNode.MeshIPv6,
 		string(pe.localNode.NATType),
 	)
+	announcement.EndpointCandidates = localEndpointCandidates(pe.localNode, pe.config)
+	announcement.Version = pe.config.Version
+	pe.localNode.AnnounceRelayState(announcement)
+	pe.localNode.SignAnnouncement(announcement)
 
-	data, err := crypto.SealEnvelope(crypto.MessageTypeHello, announcement, pe.config.Keys.GossipKey)
+	data, err := crypto.SealEnvelope(crypto.MessageTypeHello, announcement, pe.config.EnvelopeSealKey())
 	if err != nil {
 		return nil, fmt.Errorf("failed to seal hello: %w", err)
 	}
 
-	log.Printf("[Exchange] Sending HELLO to %s (exchange port: %d)", remoteAddr.String(), pe.port)
-	if !pe.config.DisablePunching {
+	punching := !pe.config.DisablePunching && proxyConn == nil
+	if proxyConn != nil {
+		log.Printf("[Exchange] Sending HELLO to %s via SOCKS5 proxy", remoteAddr.String())
+	} else {
+		log.Printf("[Exchange] Sending HELLO to %s (exchange port: %d)", remoteAddr.String(), pe.port)
+	}
+	if punching {
 		log.Printf("[NAT] Punch attempt started with %s (timeout=%v interval=%v local_port=%d)", remoteAddr.String(), ExchangeTimeout, PunchInterval, pe.port)
 	}
 
-	attempts := 0
 	sendHello := func() error {
+		if !pe.bandwidth.allow("exchange", len(data)) {
+			return errBandwidthExhausted
+		}
 		attempts++
-		_, sendErr := pe.conn.WriteToUDP(data, remoteAddr)
+		var sendErr error
+		if proxyConn != nil {
+			_, sendErr = proxyConn.WriteTo(data, remoteAddr)
+		} else {
+			_, sendErr = pe.conn.WriteToUDP(data, remoteAddr)
+		}
 		if sendErr != nil {
 			return fmt.Errorf("failed to send hello: %w", sendErr)
 		}
@@ -497,7 +866,7 @@ func (pe *PeerExchange) ExchangeWithPeer(addrStr string) (*daemon.PeerInfo, erro
 		return nil, err
 	}
 
-	if pe.config.DisablePunching {
+	if !punching {
 		timeout := time.NewTimer(ExchangeTimeout)
 		defer timeout.Stop()
 		select {
@@ -524,7 +893,7 @@ func (pe *PeerExchange) ExchangeWithPeer(addrStr string) (*daemon.PeerInfo, erro
 			}
 			return peerInfo, nil
 		case <-punchTicker.C:
-			if err := sendHello(); err != nil {
+			if err := sendHello(); err != nil && !errors.Is(err, errBandwidthExhausted) {
 				log.Printf("[Exchange] HELLO resend to %s failed: %v", remoteAddr.String(), err)
 			}
 		case <-timeout.C:
@@ -534,6 +903,28 @@ func (pe *PeerExchange) ExchangeWithPeer(addrStr string) (*daemon.PeerInfo, erro
 	}
 }
 
+// RendezvousSessions returns how many rendezvous this node is coordinating
+// as an introducer: offers waiting for the other side, plus pairs started
+// within the last RendezvousSessionTTL.
+func (pe *PeerExchange) RendezvousSessions() int {
+	pe.rendezvousMu.Lock()
+	defer pe.rendezvousMu.Unlock()
+
+	now := time.Now()
+	n := 0
+	for _, st := range pe.rendezvousSessions {
+		if now.Sub(st.createdAt) <= RendezvousSessionTTL {
+			n++
+		}
+	}
+	for _, t := range pe.rendezvousStarts {
+		if now.Sub(t) <= RendezvousSessionTTL {
+			n++
+		}
+	}
+	return n
+}
+
 // RequestRendezvous asks an introducer to coordinate synchronized NAT punching
 // between this node and the target peer.
 func (pe *PeerExchange) RequestRendezvous(introducerAddr, targetPubKey string, candidates []string) error {
@@ -559,12 +950,12 @@ func (pe *PeerExchange) RequestRendezvous(introducerAddr, targetPubKey string, c
 		IntroducerKey: "",
 	}
 
-	data, err := crypto.SealEnvelope(crypto.MessageTypeRendezvousOffer, offer, pe.config.Keys.GossipKey)
+	data, err := crypto.SealEnvelope(crypto.MessageTypeRendezvousOffer, offer, pe.config.EnvelopeSealKey())
 	if err != nil {
 		return fmt.Errorf("failed to seal rendezvous offer: %w", err)
 	}
 
-	if _, err := pe.conn.WriteToUDP(data, remoteAddr); err != nil {
+	if err := pe.send(data, remoteAddr); err != nil {
 		return fmt.Errorf("failed to send rendezvous offer: %w", err)
 	}
 
@@ -586,7 +977,7 @@ func (pe *PeerExchange) handleRendezvousOffer(offer *rendezvousOffer, remoteAddr
 		// We are a participant, not an introducer for this pair.
 		return
 	}
-	if !pe.localNode.Introducer {
+	if !pe.localNode.IsIntroducer() {
 		// Only designated introducers relay offers between other peers.
 		return
 	}
@@ -699,17 +1090,17 @@ func (pe *PeerExchange) sendRendezvousStart(pairID, targetPubKey, targetEndpoint
 		PairID:         pairID,
 		PeerPubKey:     peerPubKey,
 		PeerCandidates: filterCandidatesForConfig(normalizeCandidates(peerCandidates), pe.config.DisableIPv6),
-		StartAtUnixMs:  startAt.UnixMilli(),
+		StartAtUnixMs:  startAt.Add(-crypto.ClockSkew()).UnixMilli(), // in mesh time
 		IntroducerKey:  pe.localNode.WGPubKey,
 	}
 
-	data, err := crypto.SealEnvelope(crypto.MessageTypeRendezvousStart, msg, pe.config.Keys.GossipKey)
+	data, err := crypto.SealEnvelope(crypto.MessageTypeRendezvousStart, msg, pe.config.EnvelopeSealKey())
 	if err != nil {
 		log.Printf("[NAT] Failed to seal rendezvous START: %v", err)
 		return
 	}
 
-	if _, err := pe.conn.WriteToUDP(data, remoteAddr); err != nil {
+	if err := pe.send(data, remoteAddr); err != nil {
 		log.Printf("[NAT] Failed to send rendezvous START to %s: %v", remoteAddr.String(), err)
 		return
 	}
@@ -736,7 +1127,8 @@ func (pe *PeerExchange) handleRendezvousStart(start *rendezvousStart, remoteAddr
 
 	var startAt time.Time
 	if start.StartAtUnixMs > 0 {
-		startAt = time.UnixMilli(start.StartAtUnixMs)
+		// The introducer sends mesh time; punch by the local clock.
+		startAt = time.UnixMilli(start.StartAtUnixMs).Add(crypto.ClockSkew())
 	} else {
 		startAt = time.Now().Add(100 * time.Millisecond)
 	}
@@ -767,11 +1159,17 @@ func (pe *PeerExchange) runRendezvousPunch(pairID, peerPubKey string, candidates
 		time.Sleep(wait)
 	}
 
+	ctx, span := tracing.Start(context.Background(), "rendezvous.session",
+		tracing.String("rendezvous.pair", pairID),
+		tracing.String("peer.pubkey", peerPubKey),
+		tracing.Int("rendezvous.candidates", len(candidates)))
+	defer span.End()
+
 	baselineHandshake := pe.getLatestHandshake(peerPubKey)
 
 	for _, candidate := range candidates {
 		log.Printf("[NAT] Rendezvous punching peer %s via candidate %s", shortKey(peerPubKey), candidate)
-		peerInfo, err := pe.ExchangeWithPeer(candidate)
+		peerInfo, err := pe.exchangeWithPeer(ctx, candidate, nil)
 		if err != nil {
 			continue
 		}
@@ -795,17 +1193,31 @@ func (pe *PeerExchange) runRendezvousPunch(pairID, peerPubKey string, candidates
 			log.Printf("[NAT] Rendezvous punch succeeded for pair %s with %s at %s", shortKey(pairID), shortKey(peerInfo.WGPubKey), peerInfo.Endpoint)
 		}
 		pe.peerStore.Update(peerInfo, DHTMethod+"-rendezvous")
+		span.SetAttributes(tracing.String("rendezvous.endpoint", peerInfo.Endpoint))
 		return
 	}
 
 	log.Printf("[NAT] Rendezvous punch failed for pair %s peer %s", shortKey(pairID), shortKey(peerPubKey))
+	span.RecordError(fmt.Errorf("no candidate reached a WireGuard handshake"))
+}
+
+// SetWireGuardBackend sets the WireGuard interface handshakes are read from.
+func (pe *PeerExchange) SetWireGuardBackend(wg wireguard.Backend) {
+	pe.wg = wg
+}
+
+func (pe *PeerExchange) wireGuard() wireguard.Backend {
+	if pe.wg == nil {
+		return wireguard.Local{}
+	}
+	return pe.wg
 }
 
 func (pe *PeerExchange) getLatestHandshake(peerPubKey string) int64 {
 	if peerPubKey == "" {
 		return 0
 	}
-	hs, err := wireguard.GetLatestHandshakes(pe.config.InterfaceName)
+	hs, err := pe.wireGuard().GetLatestHandshakes(pe.config.InterfaceName)
 	if err != nil {
 		return 0
 	}
@@ -937,6 +1349,7 @@ func (pe *PeerExchange) updateTransitivePeers(knownPeers []crypto.KnownPeer) {
 			Endpoint:   filterEndpointForConfig(normalizeKnownPeerEndpoint(kp.WGEndpoint), pe.config.DisableIPv6),
 			Introducer: kp.Introducer,
 			NATType:    kp.NATType,
+			PairPSK:    kp.PairPSK,
 		}
 		pe.peerStore.Update(transitivePeer, DHTMethod+"-transitive")
 	}
@@ -1041,8 +1454,30 @@ func controlEndpointFromPeerEndpoint(endpoint string, controlPort int) string {
 	return net.JoinHostPort(host, strconv.Itoa(controlPort))
 }
 
+// send writes a message the mesh cannot do without, charging it to the
+// bandwidth budget even when that is spent.
+func (pe *PeerExchange) send(data []byte, remoteAddr *net.UDPAddr) error {
+	if _, err := pe.conn.WriteToUDP(data, remoteAddr); err != nil {
+		return err
+	}
+	pe.bandwidth.spend("exchange", len(data))
+	return nil
+}
+
+// sendOptional writes a message of kind only if the bandwidth budget has
+// room for it, returning errBandwidthExhausted otherwise.
+func (pe *PeerExchange) sendOptional(kind string, data []byte, remoteAddr *net.UDPAddr) error {
+	if !pe.bandwidth.allow(kind, len(data)) {
+		return errBandwidthExhausted
+	}
+	_, err := pe.conn.WriteToUDP(data, remoteAddr)
+	return err
+}
+
 // getKnownPeers returns a list of known peers for sharing with other nodes.
 // Filters out the local node to prevent self-advertisement via gossip.
+// Under a bandwidth budget it returns a random subset of at most
+// knownPeersLimit, so repeated announcements still cover every peer.
 func (pe *PeerExchange) getKnownPeers() []crypto.KnownPeer {
 	peers := pe.peerStore.GetActive()
 	knownPeers := make([]crypto.KnownPeer, 0, len(peers))
@@ -1061,40 +1496,68 @@ func (pe *PeerExchange) getKnownPeers() []crypto.KnownPeer {
 			WGEndpoint: p.Endpoint,
 			Introducer: p.Introducer,
 			NATType:    p.NATType,
+			PairPSK:    p.PairPSK,
 		})
 	}
 
+	if limit := pe.bandwidth.knownPeersLimit(); limit > 0 && len(knownPeers) > limit {
+		rand.Shuffle(len(knownPeers), func(i, j int) {
+			knownPeers[i], knownPeers[j] = knownPeers[j], knownPeers[i]
+		})
+		knownPeers = knownPeers[:limit]
+		daemon.RecordDiscoveryThrottled("known_peers")
+	}
 	return knownPeers
 }
 
 // SendAnnounce sends an announce message to a specific peer (used for gossip)
 func (pe *PeerExchange) SendAnnounce(remoteAddr *net.UDPAddr) error {
-	knownPeers := pe.getKnownPeers()
+	return pe.SendKnownPeers(remoteAddr, pe.getKnownPeers())
+}
 
+// SendKnownPeers sends an announce message carrying only knownPeers, as
+// gossip does after comparing digests.
+func (pe *PeerExchange) SendKnownPeers(remoteAddr *net.UDPAddr, knownPeers []crypto.KnownPeer) error {
 	announcement := crypto.CreateAnnouncement(
 		pe.localNode.WGPubKey,
 		pe.localNode.MeshIP,
 		pe.localNode.GetEndpoint(),
-		pe.localNode.Introducer,
+		pe.localNode.IsIntroducer(),
 		pe.localNode.RoutableNetworks,
 		knownPeers,
 		pe.localNode.Hostname,
 		pe.localNode.MeshIPv6,
 		string(pe.localNode.NATType),
 	)
+	announcement.EndpointCandidates = localEndpointCandidates(pe.localNode, pe.config)
+	announcement.Version = pe.config.Version
+	pe.localNode.AnnounceRelayState(announcement)
+	pe.localNode.SignAnnouncement(announcement)
 
-	data, err := crypto.SealEnvelope(crypto.MessageTypeAnnounce, announcement, pe.config.Keys.GossipKey)
+	data, err := crypto.SealEnvelope(crypto.MessageTypeAnnounce, announcement, pe.config.EnvelopeSealKey())
 	if err != nil {
 		return fmt.Errorf("failed to seal announce: %w", err)
 	}
 
-	_, err = pe.conn.WriteToUDP(data, remoteAddr)
-	if err != nil {
+	if err := pe.sendOptional("gossip", data, remoteAddr); err != nil {
 		return fmt.Errorf("failed to send announce: %w", err)
 	}
 	return nil
 }
 
+// SendDigest sends a gossip digest message of the given type.
+func (pe *PeerExchange) SendDigest(remoteAddr *net.UDPAddr, messageType string, digest *crypto.GossipDigest) error {
+	data, err := crypto.SealEnvelope(messageType, digest, pe.config.EnvelopeSealKey())
+	if err != nil {
+		return fmt.Errorf("failed to seal digest: %w", err)
+	}
+
+	if err := pe.sendOptional("gossip", data, remoteAddr); err != nil {
+		return fmt.Errorf("failed to send digest: %w", err)
+	}
+	return nil
+}
+
 // SendGoodbye sends a shutdown notification to a specific peer exchange endpoint.
 func (pe *PeerExchange) SendGoodbye(addr string) error {
 	remoteAddr, err := net.ResolveUDPAddr("udp", addr)
@@ -1108,18 +1571,95 @@ func (pe *PeerExchange) SendGoodbye(addr string) error {
 		WGPubKey:  pe.localNode.WGPubKey,
 	}
 
-	data, err := crypto.SealEnvelope(crypto.MessageTypeGoodbye, msg, pe.config.Keys.GossipKey)
+	data, err := crypto.SealEnvelope(crypto.MessageTypeGoodbye, msg, pe.config.EnvelopeSealKey())
 	if err != nil {
 		return fmt.Errorf("failed to seal goodbye: %w", err)
 	}
 
-	_, err = pe.conn.WriteToUDP(data, remoteAddr)
-	if err != nil {
+	if err := pe.send(data, remoteAddr); err != nil {
 		return fmt.Errorf("failed to send goodbye: %w", err)
 	}
 	return nil
 }
 
+// SendRotation sends a secret rotation message to a specific peer exchange
+// endpoint.
+func (pe *PeerExchange) SendRotation(addr string, msg *crypto.RotationMessage) error {
+	remoteAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to resolve rotation target %s: %w", addr, err)
+	}
+
+	data, err := crypto.SealEnvelope(crypto.MessageTypeRotate, msg, pe.config.EnvelopeSealKey())
+	if err != nil {
+		return fmt.Errorf("failed to seal rotation: %w", err)
+	}
+
+	if err := pe.send(data, remoteAddr); err != nil {
+		return fmt.Errorf("failed to send rotation: %w", err)
+	}
+	return nil
+}
+
+// SetRotationHandler sets a handler for secret rotation messages.
+func (pe *PeerExchange) SetRotationHandler(handler func(*crypto.RotationMessage)) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.rotationHandler = handler
+}
+
+// SendBroadcast sends an operator broadcast to a specific peer exchange
+// endpoint.
+func (pe *PeerExchange) SendBroadcast(addr string, msg *crypto.BroadcastMessage) error {
+	remoteAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to resolve broadcast target %s: %w", addr, err)
+	}
+
+	data, err := crypto.SealEnvelope(crypto.MessageTypeBroadcast, msg, pe.config.EnvelopeSealKey())
+	if err != nil {
+		return fmt.Errorf("failed to seal broadcast: %w", err)
+	}
+
+	if err := pe.send(data, remoteAddr); err != nil {
+		return fmt.Errorf("failed to send broadcast: %w", err)
+	}
+	return nil
+}
+
+// SetBroadcastHandler sets a handler for operator broadcasts.
+func (pe *PeerExchange) SetBroadcastHandler(handler func(*crypto.BroadcastMessage)) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.broadcastHandler = handler
+}
+
+// SendApproval sends an admission approval to a specific peer exchange
+// endpoint.
+func (pe *PeerExchange) SendApproval(addr string, msg *crypto.ApprovalMessage) error {
+	remoteAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to resolve approval target %s: %w", addr, err)
+	}
+
+	data, err := crypto.SealEnvelope(crypto.MessageTypeApproval, msg, pe.config.EnvelopeSealKey())
+	if err != nil {
+		return fmt.Errorf("failed to seal approval: %w", err)
+	}
+
+	if err := pe.send(data, remoteAddr); err != nil {
+		return fmt.Errorf("failed to send approval: %w", err)
+	}
+	return nil
+}
+
+// SetApprovalHandler sets a handler for admission approvals.
+func (pe *PeerExchange) SetApprovalHandler(handler func(*crypto.ApprovalMessage)) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.approvalHandler = handler
+}
+
 // SetAnnounceHandler sets a handler for gossip announcements.
 func (pe *PeerExchange) SetAnnounceHandler(handler func(*crypto.PeerAnnouncement, *net.UDPAddr)) {
 	pe.mu.Lock()
@@ -1127,6 +1667,13 @@ func (pe *PeerExchange) SetAnnounceHandler(handler func(*crypto.PeerAnnouncement
 	pe.announceHandler = handler
 }
 
+// SetDigestHandler sets a handler for gossip digest messages.
+func (pe *PeerExchange) SetDigestHandler(handler func(string, *crypto.GossipDigest, *net.UDPAddr)) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+	pe.digestHandler = handler
+}
+
 // MarshalJSON implements json.Marshaler for debugging
 func (pe *PeerExchange) MarshalJSON() ([]byte, error) {
 	pe.mu.RLock()
@@ -1142,14 +1689,21 @@ func (pe *PeerExchange) logIncomingPacket(messageType string, remoteAddr *net.UD
 	if remoteAddr == nil {
 		return
 	}
-	key := messageType + "|" + remoteAddr.String()
+	if pe.shouldLogPacket(messageType + "|" + remoteAddr.String()) {
+		log.Printf("[Exchange] Received valid %s from %s", messageType, remoteAddr.String())
+	}
+}
+
+// shouldLogPacket reports whether a packet log line for key is due, allowing
+// one per ExchangeLogCooldown.
+func (pe *PeerExchange) shouldLogPacket(key string) bool {
 	now := time.Now()
 
 	pe.logMu.Lock()
 	last, exists := pe.lastPacketLog[key]
 	if exists && now.Sub(last) < ExchangeLogCooldown {
 		pe.logMu.Unlock()
-		return
+		return false
 	}
 	pe.lastPacketLog[key] = now
 	// Periodic cleanup of stale log entries
@@ -1161,6 +1715,5 @@ func (pe *PeerExchange) logIncomingPacket(messageType string, remoteAddr *net.UD
 		}
 	}
 	pe.logMu.Unlock()
-
-	log.Printf("[Exchange] Received valid %s from %s", messageType, remoteAddr.String())
+	return true
 }
@@ -0,0 +1,104 @@
+package discovery
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+var fuzzPubKey = base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+// FuzzParseExchangeMessages feeds arbitrary decrypted payloads to the
+// rendezvous, goodbye and Dandelion stem parsers.
+func FuzzParseExchangeMessages(f *testing.F) {
+	f.Add([]byte(fmt.Sprintf(`{"from_pubkey":%q,"target_pubkey":%q,"candidates":["1.2.3.4:5"]}`, fuzzPubKey, fuzzPubKey)))
+	f.Add([]byte(fmt.Sprintf(`{"peer_pubkey":%q,"pair_id":"x","start_at_unix_ms":%d}`, fuzzPubKey, time.Now().UnixMilli())))
+	f.Add([]byte(fmt.Sprintf(`{"wg_pubkey":%q,"timestamp":0}`, fuzzPubKey)))
+	f.Add([]byte(`{"candidates":null,"peer_candidates":[""]}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if offer, err := parseRendezvousOffer(data); err == nil && len(offer.Candidates) > MaxRendezvousCandidates {
+			t.Fatalf("offer with %d candidates accepted", len(offer.Candidates))
+		}
+		if start, err := parseRendezvousStart(data); err == nil && len(start.PeerCandidates) > maxStartCandidates {
+			t.Fatalf("start with %d candidates accepted", len(start.PeerCandidates))
+		}
+		if bye, err := parseGoodbye(data); err == nil && bye.WGPubKey == "" {
+			t.Fatal("goodbye without pubkey accepted")
+		}
+		if stem, err := parseDandelionStem(data); err == nil && stem.Announcement == nil {
+			t.Fatal("stem without announcement accepted")
+		}
+	})
+}
+
+// FuzzHandleMessage seals arbitrary JSON payloads with the mesh key and runs
+// them through the exchange dispatcher, as a peer holding the secret can.
+// Punching is disabled so no punch goroutines outlive an iteration; replies
+// go to a loopback socket nobody reads.
+func FuzzHandleMessage(f *testing.F) {
+	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: testSecret, DisablePunching: true})
+	if err != nil {
+		f.Fatal(err)
+	}
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		f.Fatal(err)
+	}
+	defer conn.Close()
+	sink, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		f.Fatal(err)
+	}
+	defer sink.Close()
+	remoteAddr := sink.LocalAddr().(*net.UDPAddr)
+	messageTypes := []string{
+		crypto.MessageTypeHello,
+		crypto.MessageTypeReply,
+		crypto.MessageTypeAnnounce,
+		crypto.MessageTypeGoodbye,
+		crypto.MessageTypeRendezvousOffer,
+		crypto.MessageTypeRendezvousStart,
+		crypto.MessageTypeRotate,
+	}
+
+	now := time.Now().Unix()
+	announcement := crypto.CreateAnnouncement(fuzzPubKey, "10.0.0.2", "1.2.3.4:51820", false, nil,
+		[]crypto.KnownPeer{{WGPubKey: fuzzPubKey, MeshIP: "10.0.0.3"}}, "peer", "", "cone")
+	seed, _ := json.Marshal(announcement)
+	for i := range messageTypes {
+		f.Add(uint8(i), seed)
+	}
+	f.Add(uint8(3), []byte(fmt.Sprintf(`{"protocol":"wgmesh-v1","timestamp":%d,"wg_pubkey":%q}`, now, fuzzPubKey)))
+	f.Add(uint8(6), []byte(fmt.Sprintf(`{"protocol":"wgmesh-v1","timestamp":%d,"announcement":null}`, now)))
+
+	f.Fuzz(func(t *testing.T, kind uint8, payload []byte) {
+		if !json.Valid(payload) {
+			return
+		}
+		msgType := messageTypes[int(kind)%len(messageTypes)]
+		data, err := crypto.SealEnvelope(msgType, json.RawMessage(payload), cfg.Keys.GossipKey)
+		if err != nil {
+			return
+		}
+
+		store := daemon.NewPeerStore()
+		pe := NewPeerExchange(cfg, &daemon.LocalNode{WGPubKey: "local-pubkey", MeshIP: "10.0.0.1"}, store)
+		pe.conn = conn
+		pe.SetAnnounceHandler(func(a *crypto.PeerAnnouncement, _ *net.UDPAddr) {
+			if a == nil {
+				t.Fatal("nil announcement dispatched")
+			}
+		})
+		pe.SetRotationHandler(func(msg *crypto.RotationMessage) {
+			_ = msg.Verify(cfg.Keys.MembershipKey[:])
+		})
+		pe.handleMessage(data, remoteAddr)
+	})
+}
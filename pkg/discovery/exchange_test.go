@@ -701,3 +701,88 @@ func TestGetKnownPeers_ExcludesSelfAndEmpty(t *testing.T) {
 		t.Error("getKnownPeers() should include remote peer (remote-pubkey-xyz)")
 	}
 }
+
+// TestKnownPeersCarryPairPSK verifies that relays pass on a peer's PairPSK
+// flag and that receivers adopt it, but do not clear it from a relayed
+// entry that leaves it out.
+func TestKnownPeersCarryPairPSK(t *testing.T) {
+	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: "wgmesh-test-known-peers-pair-psk"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayStore := daemon.NewPeerStore()
+	relayStore.Update(&daemon.PeerInfo{
+		WGPubKey:        "remote-pubkey-xyz",
+		MeshIP:          "10.0.0.2",
+		Endpoint:        "203.0.113.42:51820",
+		PairPSK:         true,
+		RoutesAnnounced: true,
+	}, "dht")
+	relay := NewPeerExchange(cfg, &daemon.LocalNode{WGPubKey: "relay-pubkey", MeshIP: "10.0.0.3"}, relayStore)
+	known := relay.getKnownPeers()
+	if len(known) != 1 || !known[0].PairPSK {
+		t.Fatalf("getKnownPeers() = %+v, want the peer with PairPSK set", known)
+	}
+
+	store := daemon.NewPeerStore()
+	pe := NewPeerExchange(cfg, &daemon.LocalNode{WGPubKey: "local-pubkey-abc", MeshIP: "10.0.0.1"}, store)
+	pe.updateTransitivePeers(known)
+	if p, ok := store.Get("remote-pubkey-xyz"); !ok || !p.PairPSK {
+		t.Fatalf("transitive peer = %+v, want PairPSK set", p)
+	}
+
+	known[0].PairPSK = false
+	pe.updateTransitivePeers(known)
+	if p, _ := store.Get("remote-pubkey-xyz"); !p.PairPSK {
+		t.Error("a relayed entry without the flag cleared it")
+	}
+}
+
+// TestAdmitPacket_Limits verifies the per-source rate, the global decryption
+// budget and the in-flight handler cap of the exchange listener.
+func TestAdmitPacket_Limits(t *testing.T) {
+	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: "wgmesh-test-admit-packet"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pe := NewPeerExchange(cfg, &daemon.LocalNode{WGPubKey: "local-pubkey"}, daemon.NewPeerStore())
+
+	// One source is cut off after its burst.
+	admitted := 0
+	for i := 0; i < 100; i++ {
+		if pe.admitPacket("192.0.2.1") {
+			admitted++
+			pe.releasePacket()
+		}
+	}
+	if admitted != pe.limiter.Burst() {
+		t.Errorf("admitted %d packets from one source, want burst %d", admitted, pe.limiter.Burst())
+	}
+
+	// Many sources share the global budget.
+	admitted = 0
+	for i := 0; i < 2*ExchangeDecryptBurst; i++ {
+		if pe.admitPacket(net.IPv4(10, byte(i>>16), byte(i>>8), byte(i)).String()) {
+			admitted++
+			pe.releasePacket()
+		}
+	}
+	if admitted > ExchangeDecryptBurst {
+		t.Errorf("admitted %d packets across sources, want at most %d", admitted, ExchangeDecryptBurst)
+	}
+
+	// Handler slots are not released until the handler finishes.
+	pe = NewPeerExchange(cfg, &daemon.LocalNode{WGPubKey: "local-pubkey"}, daemon.NewPeerStore())
+	for i := 0; i < ExchangeMaxInflight; i++ {
+		if !pe.admitPacket(net.IPv4(10, 1, byte(i>>8), byte(i)).String()) {
+			t.Fatalf("packet %d dropped before the in-flight cap", i)
+		}
+	}
+	if pe.admitPacket("10.2.0.1") {
+		t.Error("packet admitted with all handler slots busy")
+	}
+	pe.releasePacket()
+	if !pe.admitPacket("10.2.0.1") {
+		t.Error("packet dropped after a handler slot was released")
+	}
+}
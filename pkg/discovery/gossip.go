@@ -25,12 +25,12 @@ type MeshGossip struct {
 	config    *daemon.Config
 	localNode *daemon.LocalNode
 	peerStore *daemon.PeerStore
-	gossipKey [32]byte
 	port      uint16
 
 	conn     *net.UDPConn
 	exchange *PeerExchange
 	limiter  *ratelimit.IPRateLimiter
+	digest   digestState
 
 	mu      sync.RWMutex
 	running bool
@@ -43,7 +43,6 @@ func NewMeshGossip(config *daemon.Config, localNode *daemon.LocalNode, peerStore
 		config:    config,
 		localNode: localNode,
 		peerStore: peerStore,
-		gossipKey: config.Keys.GossipKey,
 		port:      config.Keys.GossipPort,
 		limiter:   ratelimit.NewDefault(),
 		stopCh:    make(chan struct{}),
@@ -56,7 +55,6 @@ func NewMeshGossipWithExchange(config *daemon.Config, localNode *daemon.LocalNod
 		config:    config,
 		localNode: localNode,
 		peerStore: peerStore,
-		gossipKey: config.Keys.GossipKey,
 		port:      config.Keys.GossipPort,
 		exchange:  exchange,
 		limiter:   ratelimit.NewDefault(),
@@ -179,6 +177,12 @@ func (g *MeshGossip) exchangeWithRandomPeer() {
 
 	// When using the exchange socket, delegate sending (exchange builds its own peer list)
 	if g.exchange != nil {
+		if g.config.GossipDigest {
+			if err := g.sendDigest(target, targetAddr); err != nil {
+				log.Printf("[Gossip] Failed to send digest to %s: %v", target.MeshIP, err)
+			}
+			return
+		}
 		if err := g.exchange.SendAnnounce(targetAddr); err != nil {
 			log.Printf("[Gossip] Failed to send to %s: %v", target.MeshIP, err)
 		}
@@ -197,6 +201,7 @@ func (g *MeshGossip) exchangeWithRandomPeer() {
 				WGEndpoint: p.Endpoint,
 				Introducer: p.Introducer,
 				NATType:    p.NATType,
+				PairPSK:    p.PairPSK,
 			})
 		}
 	}
@@ -205,15 +210,19 @@ func (g *MeshGossip) exchangeWithRandomPeer() {
 		g.localNode.WGPubKey,
 		g.localNode.MeshIP,
 		g.localNode.GetEndpoint(),
-		g.localNode.Introducer,
+		g.localNode.IsIntroducer(),
 		g.localNode.RoutableNetworks,
 		knownPeers,
 		g.localNode.Hostname,
 		g.localNode.MeshIPv6,
 		string(g.localNode.NATType),
 	)
+	announcement.EndpointCandidates = localEndpointCandidates(g.localNode, g.config)
+	announcement.Version = g.config.Version
+	g.localNode.AnnounceRelayState(announcement)
+	g.localNode.SignAnnouncement(announcement)
 
-	data, err := crypto.SealEnvelope(crypto.MessageTypeAnnounce, announcement, g.gossipKey)
+	data, err := crypto.SealEnvelope(crypto.MessageTypeAnnounce, announcement, g.config.EnvelopeSealKey())
 	if err != nil {
 		log.Printf("[Gossip] Failed to seal gossip message: %v", err)
 		return
@@ -255,7 +264,7 @@ func (g *MeshGossip) listenLoop() {
 			continue
 		}
 
-		_, announcement, err := crypto.OpenEnvelope(buf[:n], g.gossipKey)
+		_, announcement, err := crypto.OpenEnvelopeWithKeys(buf[:n], g.config.EnvelopeOpenKeys())
 		if err != nil {
 			continue
 		}
@@ -272,12 +281,14 @@ func (g *MeshGossip) HandleAnnounceFrom(announcement *crypto.PeerAnnouncement, s
 	g.handleAnnouncement(announcement, sender)
 }
 
-func (g *MeshGossip) handleAnnouncement(announcement *crypto.PeerAnnouncement, sender *net.UDPAddr) {
+// handleAnnouncement applies an announcement and the peers it carries. It
+// returns false when the announcement was dropped.
+func (g *MeshGossip) handleAnnouncement(announcement *crypto.PeerAnnouncement, sender *net.UDPAddr) bool {
 	if announcement == nil {
-		return
+		return false
 	}
 	if announcement.WGPubKey == g.localNode.WGPubKey {
-		return
+		return false
 	}
 
 	endpoint := resolvePeerEndpoint(announcement.WGEndpoint, sender)
@@ -286,16 +297,40 @@ func (g *MeshGossip) handleAnnouncement(announcement *crypto.PeerAnnouncement, s
 	}
 	endpoint = filterEndpointForConfig(endpoint, g.config.DisableIPv6)
 
+	identity, ok := verifiedIdentity(announcement, g.config, "Gossip")
+	if !ok {
+		return false
+	}
+
 	// Update the sender's info
 	peer := &daemon.PeerInfo{
-		WGPubKey:         announcement.WGPubKey,
-		Hostname:         announcement.Hostname,
-		MeshIP:           announcement.MeshIP,
-		MeshIPv6:         announcement.MeshIPv6,
-		Endpoint:         endpoint,
-		Introducer:       announcement.Introducer,
-		RoutableNetworks: announcement.RoutableNetworks,
-		NATType:          announcement.NATType,
+		WGPubKey:            announcement.WGPubKey,
+		Hostname:            announcement.Hostname,
+		MeshIP:              announcement.MeshIP,
+		MeshIPv6:            announcement.MeshIPv6,
+		Endpoint:            endpoint,
+		Introducer:          announcement.Introducer,
+		RoutableNetworks:    announcement.RoutableNetworks,
+		RoutesAnnounced:     true,
+		NATType:             announcement.NATType,
+		Version:             announcement.Version,
+		Revision:            announcement.Revision,
+		MinRevision:         announcement.MinRevision,
+		Capabilities:        announcement.Capabilities,
+		Load:                announcement.Load,
+		Relays:              announcement.Relays,
+		IntroducerCandidate: announcement.IntroducerCandidate,
+		IntroducerElected:   announcement.IntroducerElected,
+		PairPSK:             announcement.PairPSK,
+		Tags:                announcement.Tags,
+		PortHop:             time.Duration(announcement.PortHop) * time.Second,
+		TCPPort:             announcement.TCPPort,
+		TCPVia:              announcement.TCPVia,
+		Approval:            announcement.Approval,
+		DNS:                 announcement.DNS,
+		RouteChecks:         announcement.RouteChecks,
+		Candidates:          announcedCandidates(announcement.EndpointCandidates, g.config.DisableIPv6),
+		Identity:            identity,
 	}
 	g.peerStore.Update(peer, GossipMethod)
 	daemon.RecordDiscoveryEvent("gossip")
@@ -313,9 +348,11 @@ func (g *MeshGossip) handleAnnouncement(announcement *crypto.PeerAnnouncement, s
 			Endpoint:   filterEndpointForConfig(normalizeKnownPeerEndpoint(kp.WGEndpoint), g.config.DisableIPv6),
 			Introducer: kp.Introducer,
 			NATType:    kp.NATType,
+			PairPSK:    kp.PairPSK,
 		}
 		g.peerStore.Update(transitivePeer, GossipMethod+"-transitive")
 	}
+	return true
 }
 
 // MarshalJSON implements json.Marshaler for debugging
@@ -0,0 +1,162 @@
+package discovery
+
+import (
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+// DigestEntriesMinInterval limits how often one peer can make us send our
+// per-entry hashes, which are much larger than the digest asking for them.
+const DigestEntriesMinInterval = GossipInterval / 2
+
+// digestState tracks when DIGEST_ENTRIES last went to each peer.
+type digestState struct {
+	mu          sync.Mutex
+	entriesSent map[string]time.Time
+}
+
+// sendDigest starts an anti-entropy round with target: it sends the count
+// and hash of the peers we know besides target and ourselves.
+func (g *MeshGossip) sendDigest(target *daemon.PeerInfo, targetAddr *net.UDPAddr) error {
+	_, count, hash := crypto.SummarizeKnownPeers(g.knownPeersExcept(target.WGPubKey))
+	return g.exchange.SendDigest(targetAddr, crypto.MessageTypeDigest, g.newDigest(count, hash))
+}
+
+// HandleDigest processes the three digest message types; see crypto.GossipDigest.
+func (g *MeshGossip) HandleDigest(messageType string, digest *crypto.GossipDigest, sender *net.UDPAddr) {
+	if !g.handleAnnouncement(digest.Announcement, sender) {
+		return
+	}
+	remoteKey := digest.Announcement.WGPubKey
+	peers := g.knownPeersExcept(remoteKey)
+
+	switch messageType {
+	case crypto.MessageTypeDigest:
+		entries, count, hash := crypto.SummarizeKnownPeers(peers)
+		if count == digest.Count && hash == digest.Hash {
+			daemon.RecordGossipDigest("in_sync")
+			return
+		}
+		daemon.RecordGossipDigest("mismatch")
+		if !g.allowDigestEntries(remoteKey) {
+			return
+		}
+		reply := g.newDigest(count, hash)
+		reply.Entries = entries
+		if err := g.exchange.SendDigest(sender, crypto.MessageTypeDigestEntries, reply); err != nil {
+			log.Printf("[Gossip] Failed to send digest entries to %s: %v", sender, err)
+		}
+
+	case crypto.MessageTypeDigestEntries:
+		var missing []crypto.KnownPeer
+		local := make(map[string]string, len(peers))
+		for _, kp := range peers {
+			key := crypto.DigestKey(kp.WGPubKey)
+			local[key] = crypto.DigestEntryHash(kp)
+			if digest.Entries[key] != local[key] {
+				missing = append(missing, kp)
+			}
+		}
+		var want []string
+		for key, hash := range digest.Entries {
+			if local[key] != hash {
+				want = append(want, key)
+			}
+		}
+		if len(missing) > 0 {
+			if err := g.exchange.SendKnownPeers(sender, missing); err != nil {
+				log.Printf("[Gossip] Failed to send %d peers to %s: %v", len(missing), sender, err)
+			}
+		}
+		if len(want) > 0 {
+			request := g.newDigest(0, "")
+			request.Want = want
+			if err := g.exchange.SendDigest(sender, crypto.MessageTypeDigestWant, request); err != nil {
+				log.Printf("[Gossip] Failed to request %d peers from %s: %v", len(want), sender, err)
+			}
+		}
+
+	case crypto.MessageTypeDigestWant:
+		wanted := make(map[string]bool, len(digest.Want))
+		for _, key := range digest.Want {
+			wanted[key] = true
+		}
+		var send []crypto.KnownPeer
+		for _, kp := range peers {
+			if wanted[crypto.DigestKey(kp.WGPubKey)] {
+				send = append(send, kp)
+			}
+		}
+		if len(send) > 0 {
+			if err := g.exchange.SendKnownPeers(sender, send); err != nil {
+				log.Printf("[Gossip] Failed to send %d peers to %s: %v", len(send), sender, err)
+			}
+		}
+	}
+}
+
+// newDigest builds a digest message around our own signed announcement.
+func (g *MeshGossip) newDigest(count int, hash string) *crypto.GossipDigest {
+	announcement := crypto.CreateAnnouncement(
+		g.localNode.WGPubKey,
+		g.localNode.MeshIP,
+		g.localNode.GetEndpoint(),
+		g.localNode.IsIntroducer(),
+		g.localNode.RoutableNetworks,
+		nil,
+		g.localNode.Hostname,
+		g.localNode.MeshIPv6,
+		string(g.localNode.NATType),
+	)
+	announcement.EndpointCandidates = localEndpointCandidates(g.localNode, g.config)
+	announcement.Version = g.config.Version
+	g.localNode.AnnounceRelayState(announcement)
+	g.localNode.SignAnnouncement(announcement)
+
+	return &crypto.GossipDigest{
+		Protocol:     crypto.ProtocolVersion,
+		Timestamp:    time.Now().Unix(),
+		Announcement: announcement,
+		Count:        count,
+		Hash:         hash,
+	}
+}
+
+// knownPeersExcept returns the peers we would gossip, minus pubKey. Both
+// sides of a digest exchange leave out each other, so their summaries cover
+// the same set once they agree.
+func (g *MeshGossip) knownPeersExcept(pubKey string) []crypto.KnownPeer {
+	all := g.exchange.getKnownPeers()
+	peers := all[:0]
+	for _, kp := range all {
+		if kp.WGPubKey != pubKey {
+			peers = append(peers, kp)
+		}
+	}
+	return peers
+}
+
+func (g *MeshGossip) allowDigestEntries(pubKey string) bool {
+	g.digest.mu.Lock()
+	defer g.digest.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := g.digest.entriesSent[pubKey]; ok && now.Sub(last) < DigestEntriesMinInterval {
+		return false
+	}
+	if g.digest.entriesSent == nil {
+		g.digest.entriesSent = make(map[string]time.Time)
+	}
+	for key, last := range g.digest.entriesSent {
+		if now.Sub(last) >= DigestEntriesMinInterval {
+			delete(g.digest.entriesSent, key)
+		}
+	}
+	g.digest.entriesSent[pubKey] = now
+	return true
+}
@@ -0,0 +1,113 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+func digestTestKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+// newDigestTestNode runs a gossip instance on a loopback exchange socket.
+func newDigestTestNode(t *testing.T, key, meshIP string) (*MeshGossip, *net.UDPAddr) {
+	t.Helper()
+	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: testSecret, Gossip: true, GossipDigest: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	localNode := &daemon.LocalNode{WGPubKey: key, MeshIP: meshIP}
+	localNode.SetEndpoint("198.51.100.1:51820")
+	store := daemon.NewPeerStore()
+
+	pe := NewPeerExchange(cfg, localNode, store)
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pe.conn = conn
+	pe.running = true
+	go pe.listenLoop()
+	t.Cleanup(pe.Stop)
+
+	gossip, err := NewMeshGossipWithExchange(cfg, localNode, store, pe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pe.SetAnnounceHandler(gossip.HandleAnnounceFrom)
+	pe.SetDigestHandler(gossip.HandleDigest)
+	return gossip, conn.LocalAddr().(*net.UDPAddr)
+}
+
+func TestGossipDigestExchangesOnlyMissingPeers(t *testing.T) {
+	keyA, keyB, keyC, keyD, keyE := digestTestKey(1), digestTestKey(2), digestTestKey(3), digestTestKey(4), digestTestKey(5)
+	a, _ := newDigestTestNode(t, keyA, "10.0.0.1")
+	b, addrB := newDigestTestNode(t, keyB, "10.0.0.2")
+
+	a.peerStore.Update(&daemon.PeerInfo{WGPubKey: keyC, MeshIP: "10.0.0.3", Endpoint: "198.51.100.3:51820"}, DHTMethod)
+	a.peerStore.Update(&daemon.PeerInfo{WGPubKey: keyD, MeshIP: "10.0.0.4", Endpoint: "198.51.100.4:51820"}, DHTMethod)
+	b.peerStore.Update(&daemon.PeerInfo{WGPubKey: keyC, MeshIP: "10.0.0.3", Endpoint: "198.51.100.3:51820"}, DHTMethod)
+	b.peerStore.Update(&daemon.PeerInfo{WGPubKey: keyE, MeshIP: "10.0.0.5", Endpoint: "198.51.100.5:51820"}, DHTMethod)
+
+	if err := a.sendDigest(&daemon.PeerInfo{WGPubKey: keyB}, addrB); err != nil {
+		t.Fatalf("sendDigest: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		_, aHasE := a.peerStore.Get(keyE)
+		_, bHasD := b.peerStore.Get(keyD)
+		if aHasE && bHasD {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := a.peerStore.Get(keyE); !ok {
+		t.Error("A did not learn E from B")
+	}
+	if _, ok := b.peerStore.Get(keyD); !ok {
+		t.Error("B did not learn D from A")
+	}
+	if _, ok := b.peerStore.Get(keyA); !ok {
+		t.Error("B did not learn A from the digest announcement")
+	}
+}
+
+func TestGossipDigestInSyncSendsNothing(t *testing.T) {
+	keyA, keyB, keyC := digestTestKey(1), digestTestKey(2), digestTestKey(3)
+	a, _ := newDigestTestNode(t, keyA, "10.0.0.1")
+	b, addrB := newDigestTestNode(t, keyB, "10.0.0.2")
+
+	c := &daemon.PeerInfo{WGPubKey: keyC, MeshIP: "10.0.0.3", Endpoint: "198.51.100.3:51820"}
+	a.peerStore.Update(c, DHTMethod)
+	cCopy := *c
+	b.peerStore.Update(&cCopy, DHTMethod)
+
+	if err := a.sendDigest(&daemon.PeerInfo{WGPubKey: keyB}, addrB); err != nil {
+		t.Fatalf("sendDigest: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// B answers a matching digest with nothing, so it may send again at once.
+	if !b.allowDigestEntries(keyA) {
+		t.Error("B sent digest entries for a digest that matched")
+	}
+}
+
+func TestAllowDigestEntriesRateLimits(t *testing.T) {
+	g := &MeshGossip{}
+	if !g.allowDigestEntries("key") {
+		t.Fatal("first request refused")
+	}
+	if g.allowDigestEntries("key") {
+		t.Error("second request within the interval allowed")
+	}
+	if !g.allowDigestEntries("other") {
+		t.Error("request from another peer refused")
+	}
+}
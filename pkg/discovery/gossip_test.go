@@ -218,3 +218,53 @@ func TestNewMeshGossipWithExchangeSetsExchange(t *testing.T) {
 		t.Error("expected conn to be nil when using exchange mode")
 	}
 }
+
+func TestHandleAnnouncementVerifiesSignature(t *testing.T) {
+	cfg := newTestConfig(t)
+	store := daemon.NewPeerStore()
+
+	localNode := &daemon.LocalNode{WGPubKey: "local-key", MeshIP: "10.0.0.1"}
+	gossip, err := NewMeshGossip(cfg, localNode, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := crypto.GenerateIdentityKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := crypto.CreateAnnouncement("remote-key-A", "10.0.0.2", "192.168.1.10:51820", false, nil, nil, "", "", "")
+	if err := signed.Sign(key); err != nil {
+		t.Fatal(err)
+	}
+	gossip.HandleAnnounceFrom(signed, nil)
+
+	peer, ok := store.Get("remote-key-A")
+	if !ok {
+		t.Fatal("expected signed announcement to be accepted")
+	}
+	if peer.Identity != crypto.IdentityPublicKey(key) {
+		t.Errorf("expected identity %s, got %q", crypto.IdentityPublicKey(key), peer.Identity)
+	}
+
+	// A forged announcement re-using the signature is dropped.
+	forged := *signed
+	forged.WGEndpoint = "6.6.6.6:51820"
+	gossip.HandleAnnounceFrom(&forged, nil)
+	if peer, _ := store.Get("remote-key-A"); peer.Endpoint != "192.168.1.10:51820" {
+		t.Errorf("forged announcement changed endpoint to %s", peer.Endpoint)
+	}
+
+	// Unsigned announcements are dropped only with RequireSigned.
+	unsigned := crypto.CreateAnnouncement("remote-key-B", "10.0.0.3", "192.168.1.20:51820", false, nil, nil, "", "", "")
+	cfg.RequireSigned = true
+	gossip.HandleAnnounceFrom(unsigned, nil)
+	if _, ok := store.Get("remote-key-B"); ok {
+		t.Error("unsigned announcement accepted with RequireSigned")
+	}
+	cfg.RequireSigned = false
+	gossip.HandleAnnounceFrom(unsigned, nil)
+	if _, ok := store.Get("remote-key-B"); !ok {
+		t.Error("unsigned announcement rejected without RequireSigned")
+	}
+}
@@ -9,6 +9,7 @@ import (
 func init() {
 	// Register the DHT discovery factory with the daemon package
 	daemon.SetDHTDiscoveryFactory(createDHTDiscovery)
+	daemon.SetDNSDiscoveryFactory(createDNSDiscovery)
 }
 
 // createDHTDiscovery creates a new DHT discovery instance
@@ -16,3 +17,9 @@ func init() {
 func createDHTDiscovery(ctx context.Context, config *daemon.Config, localNode *daemon.LocalNode, peerStore *daemon.PeerStore) (daemon.DiscoveryLayer, error) {
 	return NewDHTDiscovery(ctx, config, localNode, peerStore)
 }
+
+// createDNSDiscovery creates a new DNS rendezvous discovery instance
+// This is called by the daemon when a DNS rendezvous name is configured
+func createDNSDiscovery(ctx context.Context, config *daemon.Config, localNode *daemon.LocalNode, peerStore *daemon.PeerStore) (daemon.DiscoveryLayer, error) {
+	return NewDNSDiscovery(ctx, config, localNode, peerStore)
+}
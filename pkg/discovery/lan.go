@@ -1,13 +1,23 @@
 package discovery
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net"
+	"path"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"golang.org/x/net/ipv4"
+	"golang.org/x/sys/unix"
+
 	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
 )
@@ -25,17 +35,34 @@ type LANDiscovery struct {
 	config    *daemon.Config
 	localNode *daemon.LocalNode
 	peerStore *daemon.PeerStore
-	gossipKey [32]byte
 
 	multicastAddr *net.UDPAddr
 	conn          *net.UDPConn
+	pconn         *ipv4.PacketConn
+	mdns          bool
+	broadcast     bool
+
+	mu         sync.RWMutex
+	running    bool
+	stopCh     chan struct{}
+	interfaces map[string]*lanInterface // by name
+
+	sendMu sync.Mutex // serializes choosing the multicast interface and sending
+}
 
-	mu      sync.RWMutex
-	running bool
-	stopCh  chan struct{}
+// lanInterface is a network interface LAN discovery announces and listens
+// on.
+type lanInterface struct {
+	index     int
+	addrs     []*net.IPNet
+	joined    bool
+	err       error
+	lastHeard time.Time
 }
 
-// NewLANDiscovery creates a new LAN multicast discovery instance
+// NewLANDiscovery creates a new LAN multicast discovery instance.
+// With config.LANMDNS set, announcements are carried as DNS-SD records for
+// _wgmesh._udp.local on the standard mDNS group instead of the derived group.
 func NewLANDiscovery(config *daemon.Config, localNode *daemon.LocalNode, peerStore *daemon.PeerStore) (*LANDiscovery, error) {
 	// Derive multicast address from the multicast ID
 	// Use 239.192.X.Y where X.Y come from MulticastID
@@ -47,17 +74,47 @@ func NewLANDiscovery(config *daemon.Config, localNode *daemon.LocalNode, peerSto
 		IP:   multicastIP,
 		Port: LANMulticastPort,
 	}
+	if config.LANGroup != "" {
+		addr, err := parseLANGroup(config.LANGroup)
+		if err != nil {
+			return nil, err
+		}
+		multicastAddr = addr
+	}
+	if config.LANMDNS {
+		multicastAddr = MDNSGroupAddr
+	}
 
 	return &LANDiscovery{
 		config:        config,
 		localNode:     localNode,
 		peerStore:     peerStore,
-		gossipKey:     config.Keys.GossipKey,
 		multicastAddr: multicastAddr,
+		mdns:          config.LANMDNS,
+		broadcast:     config.LANBroadcast && !config.LANMDNS,
 		stopCh:        make(chan struct{}),
+		interfaces:    make(map[string]*lanInterface),
 	}, nil
 }
 
+// parseLANGroup parses --lan-group, an IPv4 multicast address with an
+// optional port.
+func parseLANGroup(group string) (*net.UDPAddr, error) {
+	host, port := group, LANMulticastPort
+	if h, p, err := net.SplitHostPort(group); err == nil {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LAN group port %q", p)
+		}
+		host, port = h, n
+	}
+	ip := net.ParseIP(host).To4()
+	if ip == nil || !ip.IsMulticast() {
+		return nil, fmt.Errorf("invalid LAN group %q", group)
+	}
+	return &net.UDPAddr{IP: ip, Port: port}, nil
+}
+
 // Start begins LAN multicast discovery
 func (l *LANDiscovery) Start() error {
 	l.mu.Lock()
@@ -67,26 +124,80 @@ func (l *LANDiscovery) Start() error {
 		return fmt.Errorf("LAN discovery already running")
 	}
 
-	// Listen on the multicast address
-	conn, err := net.ListenMulticastUDP("udp4", nil, l.multicastAddr)
+	// One socket on the group port serves every interface: it joins the
+	// group on each of them and, unlike a socket bound to the group, also
+	// receives broadcasts.
+	conn, err := listenLAN(l.multicastAddr.Port)
 	if err != nil {
-		return fmt.Errorf("failed to join multicast group %s: %w", l.multicastAddr.String(), err)
+		return fmt.Errorf("failed to listen on LAN discovery port %d: %w", l.multicastAddr.Port, err)
 	}
 
 	// Set read buffer size
 	conn.SetReadBuffer(LANMaxMessageSize)
 
 	l.conn = conn
+	l.pconn = ipv4.NewPacketConn(conn)
+	// Tells which interface a packet arrived on; not supported everywhere.
+	l.pconn.SetControlMessage(ipv4.FlagInterface, true)
+	l.refreshInterfacesLocked()
+	if len(l.interfaces) == 0 {
+		// Keep running: refreshInterfacesLocked picks up links that come
+		// up later, e.g. Wi-Fi connecting after boot.
+		log.Printf("[LAN] No usable interface yet")
+	}
 	l.running = true
 
 	// Start listener and announcer
 	go l.listenLoop()
 	go l.announceLoop()
 
-	log.Printf("[LAN] Multicast discovery started on %s", l.multicastAddr.String())
+	if l.mdns {
+		// Ask already-running peers to answer right away instead of waiting
+		// for their next periodic announcement.
+		l.sendMulticastLocked(buildMDNSQuery())
+		log.Printf("[LAN] mDNS discovery started for %s on %s (%s)", MDNSServiceName, l.multicastAddr.String(), l.interfaceNamesLocked())
+		return nil
+	}
+
+	mode := "Multicast"
+	if l.broadcast {
+		mode = "Multicast and broadcast"
+	}
+	log.Printf("[LAN] %s discovery started on %s (%s)", mode, l.multicastAddr.String(), l.interfaceNamesLocked())
 	return nil
 }
 
+// listenLAN opens the shared LAN discovery socket. Address reuse lets it
+// share the port with other daemons, and with mDNS responders on 5353.
+func listenLAN(port int) (*net.UDPConn, error) {
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			var sockErr error
+			err := c.Control(func(fd uintptr) {
+				if sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1); sockErr != nil {
+					return
+				}
+				if runtime.GOOS == "darwin" {
+					// mDNSResponder holds 5353 with SO_REUSEPORT.
+					if sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1); sockErr != nil {
+						return
+					}
+				}
+				sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_BROADCAST, 1)
+			})
+			if err != nil {
+				return err
+			}
+			return sockErr
+		},
+	}
+	pc, err := lc.ListenPacket(context.Background(), "udp4", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return nil, err
+	}
+	return pc.(*net.UDPConn), nil
+}
+
 // Stop stops LAN multicast discovery
 func (l *LANDiscovery) Stop() error {
 	l.mu.Lock()
@@ -107,6 +218,243 @@ func (l *LANDiscovery) Stop() error {
 	return nil
 }
 
+// selectsInterface reports whether LAN discovery should run on ifi: an up,
+// non-loopback interface other than the mesh's own, matching
+// --lan-interfaces when given.
+func (l *LANDiscovery) selectsInterface(ifi net.Interface) bool {
+	if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagLoopback != 0 || ifi.Name == l.config.InterfaceName {
+		return false
+	}
+	if ifi.Flags&net.FlagMulticast == 0 && !(l.broadcast && ifi.Flags&net.FlagBroadcast != 0) {
+		return false
+	}
+	if len(l.config.LANInterfaces) == 0 {
+		return true
+	}
+	for _, pattern := range l.config.LANInterfaces {
+		if ok, _ := path.Match(pattern, ifi.Name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshInterfacesLocked joins the group on interfaces that came up and
+// forgets those that went away. It runs before every announcement, so LAN
+// discovery follows Wi-Fi and USB links being plugged in.
+func (l *LANDiscovery) refreshInterfacesLocked() {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		log.Printf("[LAN] Failed to list interfaces: %v", err)
+		return
+	}
+
+	seen := make(map[string]bool)
+	for _, ifi := range ifaces {
+		if !l.selectsInterface(ifi) {
+			continue
+		}
+		addrs := interfaceIPv4Nets(ifi)
+		if len(addrs) == 0 {
+			continue
+		}
+		seen[ifi.Name] = true
+
+		li, ok := l.interfaces[ifi.Name]
+		if !ok || li.index != ifi.Index {
+			li = &lanInterface{index: ifi.Index}
+			l.interfaces[ifi.Name] = li
+		}
+		li.addrs = addrs
+		if li.joined || ifi.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		group := &net.UDPAddr{IP: l.multicastAddr.IP}
+		if err := l.pconn.JoinGroup(&ifi, group); err != nil {
+			if li.err == nil {
+				log.Printf("[LAN] Failed to join %s on %s: %v", group.IP, ifi.Name, err)
+			}
+			li.err = err
+			continue
+		}
+		li.joined, li.err = true, nil
+		if l.running {
+			log.Printf("[LAN] Discovery running on %s", ifi.Name)
+		}
+	}
+
+	for name, li := range l.interfaces {
+		if seen[name] {
+			continue
+		}
+		if li.joined {
+			l.pconn.LeaveGroup(&net.Interface{Index: li.index, Name: name}, &net.UDPAddr{IP: l.multicastAddr.IP})
+		}
+		delete(l.interfaces, name)
+		log.Printf("[LAN] Discovery stopped on %s: interface gone", name)
+	}
+}
+
+// interfaceIPv4Nets returns the usable IPv4 networks of ifi.
+func interfaceIPv4Nets(ifi net.Interface) []*net.IPNet {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil
+	}
+	var nets []*net.IPNet
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		if ip4 := ipNet.IP.To4(); ip4 != nil && !ip4.IsLinkLocalUnicast() {
+			nets = append(nets, &net.IPNet{IP: ip4, Mask: ipNet.Mask})
+		}
+	}
+	return nets
+}
+
+// broadcastAddr returns the directed broadcast address of n, or nil for
+// networks without one (/31 and /32).
+func broadcastAddr(n *net.IPNet) net.IP {
+	mask := n.Mask
+	if len(mask) == net.IPv6len {
+		mask = mask[12:]
+	}
+	if ones, bits := mask.Size(); bits != 32 || ones > 30 {
+		return nil
+	}
+	ip := make(net.IP, net.IPv4len)
+	for i := range ip {
+		ip[i] = n.IP[i] | ^mask[i]
+	}
+	return ip
+}
+
+func (l *LANDiscovery) interfaceNamesLocked() string {
+	names := make([]string, 0, len(l.interfaces))
+	for name := range l.interfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// sendMulticast sends data to the group on every interface that joined
+// it, and with --lan-broadcast to each interface's broadcast address too.
+func (l *LANDiscovery) sendMulticast(data []byte) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	l.sendMulticastLocked(data)
+}
+
+func (l *LANDiscovery) sendMulticastLocked(data []byte) {
+	l.sendMu.Lock()
+	defer l.sendMu.Unlock()
+
+	for name, li := range l.interfaces {
+		if li.joined {
+			ifi := &net.Interface{Index: li.index, Name: name}
+			if err := l.pconn.SetMulticastInterface(ifi); err != nil {
+				log.Printf("[LAN] Failed to select %s for multicast: %v", name, err)
+			} else if _, err := l.pconn.WriteTo(data, nil, l.multicastAddr); err != nil {
+				log.Printf("[LAN] Failed to send announcement on %s: %v", name, err)
+			}
+		}
+		if !l.broadcast {
+			continue
+		}
+		for _, n := range li.addrs {
+			bcast := broadcastAddr(n)
+			if bcast == nil {
+				continue
+			}
+			if _, err := l.pconn.WriteTo(data, nil, &net.UDPAddr{IP: bcast, Port: l.multicastAddr.Port}); err != nil {
+				log.Printf("[LAN] Failed to send broadcast on %s: %v", name, err)
+			}
+		}
+	}
+}
+
+// LANStatus describes the running LAN discovery for RPC. Interfaces named
+// in --lan-interfaces that are not in use are listed with the reason.
+func (l *LANDiscovery) LANStatus() *daemon.RPCLANData {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	status := &daemon.RPCLANData{
+		Mode:      "multicast",
+		Group:     l.multicastAddr.String(),
+		Broadcast: l.broadcast,
+	}
+	if l.mdns {
+		status.Mode = "mdns"
+	}
+	for name, li := range l.interfaces {
+		iface := daemon.RPCLANInterfaceData{
+			Name:      name,
+			Joined:    li.joined,
+			LastHeard: li.lastHeard,
+		}
+		for _, n := range li.addrs {
+			iface.Addresses = append(iface.Addresses, n.IP.String())
+		}
+		if li.err != nil {
+			iface.Error = li.err.Error()
+		}
+		status.Interfaces = append(status.Interfaces, iface)
+	}
+	for _, name := range l.config.LANInterfaces {
+		if _, ok := l.interfaces[name]; ok || strings.ContainsAny(name, "*?[") {
+			continue
+		}
+		status.Interfaces = append(status.Interfaces, daemon.RPCLANInterfaceData{
+			Name:  name,
+			Error: "not found, down or without an IPv4 address",
+		})
+	}
+	sort.Slice(status.Interfaces, func(i, j int) bool {
+		return status.Interfaces[i].Name < status.Interfaces[j].Name
+	})
+	return status
+}
+
+// markHeard records that a wgmesh packet arrived on the interface with
+// index ifIndex.
+func (l *LANDiscovery) markHeard(ifIndex int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, li := range l.interfaces {
+		if li.index == ifIndex {
+			li.lastHeard = time.Now()
+			return
+		}
+	}
+}
+
+// switchLAN starts or stops LAN discovery at runtime and returns the
+// instance to keep. A stopped LANDiscovery cannot be restarted, so enabling
+// always creates a new one.
+func switchLAN(current *LANDiscovery, enabled bool, config *daemon.Config, localNode *daemon.LocalNode, peerStore *daemon.PeerStore) (*LANDiscovery, error) {
+	if !enabled {
+		if current != nil {
+			current.Stop()
+		}
+		return nil, nil
+	}
+	if current != nil {
+		return current, nil
+	}
+	lan, err := NewLANDiscovery(config, localNode, peerStore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize LAN discovery: %w", err)
+	}
+	if err := lan.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start LAN discovery: %w", err)
+	}
+	return lan, nil
+}
+
 // announceLoop periodically sends multicast announcements
 func (l *LANDiscovery) announceLoop() {
 	// Initial announce immediately
@@ -120,6 +468,11 @@ func (l *LANDiscovery) announceLoop() {
 		case <-l.stopCh:
 			return
 		case <-ticker.C:
+			l.mu.Lock()
+			if l.running {
+				l.refreshInterfacesLocked()
+			}
+			l.mu.Unlock()
 			l.announce()
 		}
 	}
@@ -132,31 +485,30 @@ func (l *LANDiscovery) announce() {
 		l.localNode.WGPubKey,
 		l.localNode.MeshIP,
 		l.localNode.GetEndpoint(),
-		l.localNode.Introducer,
+		l.localNode.IsIntroducer(),
 		l.localNode.RoutableNetworks,
 		nil, // No known peers in LAN announce (keep small)
 		l.localNode.Hostname,
 		l.localNode.MeshIPv6,
 		string(l.localNode.NATType),
 	)
+	announcement.EndpointCandidates = localEndpointCandidates(l.localNode, l.config)
+	announcement.Version = l.config.Version
+	l.localNode.AnnounceRelayState(announcement)
+	l.localNode.SignAnnouncement(announcement)
 
-	data, err := crypto.SealEnvelope(crypto.MessageTypeAnnounce, announcement, l.gossipKey)
+	data, err := crypto.SealEnvelope(crypto.MessageTypeAnnounce, announcement, l.config.EnvelopeSealKey())
 	if err != nil {
 		log.Printf("[LAN] Failed to create announcement: %v", err)
 		return
 	}
 
-	// Send multicast via a new UDP connection (send socket)
-	sendConn, err := net.DialUDP("udp4", nil, l.multicastAddr)
-	if err != nil {
-		log.Printf("[LAN] Failed to create send socket: %v", err)
+	if l.mdns {
+		l.announceMDNS(data)
 		return
 	}
-	defer sendConn.Close()
 
-	if _, err := sendConn.Write(data); err != nil {
-		log.Printf("[LAN] Failed to send announcement: %v", err)
-	}
+	l.sendMulticast(data)
 }
 
 // listenLoop listens for multicast announcements
@@ -170,8 +522,8 @@ func (l *LANDiscovery) listenLoop() {
 		default:
 		}
 
-		l.conn.SetReadDeadline(time.Now().Add(1 * time.Second))
-		n, remoteAddr, err := l.conn.ReadFromUDP(buf)
+		l.pconn.SetReadDeadline(time.Now().Add(1 * time.Second))
+		n, cm, src, err := l.pconn.ReadFrom(buf)
 		if err != nil {
 			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
 				continue
@@ -185,36 +537,168 @@ func (l *LANDiscovery) listenLoop() {
 			continue
 		}
 
-		// Try to decrypt
-		_, announcement, err := crypto.OpenEnvelope(buf[:n], l.gossipKey)
-		if err != nil {
-			// Not a wgmesh packet or wrong secret - silently ignore
-			continue
+		remoteAddr, _ := src.(*net.UDPAddr)
+		var ok bool
+		if l.mdns {
+			ok = l.handleMDNSPacket(buf[:n], remoteAddr)
+		} else {
+			ok = l.handleEnvelope(buf[:n], remoteAddr)
 		}
+		if ok && cm != nil {
+			l.markHeard(cm.IfIndex)
+		}
+	}
+}
 
-		// Skip our own announcements
-		if announcement.WGPubKey == l.localNode.WGPubKey {
-			continue
+// handleEnvelope decrypts a sealed announcement and records the peer. It
+// reports whether the announcement came from another node of the mesh.
+func (l *LANDiscovery) handleEnvelope(data []byte, remoteAddr *net.UDPAddr) bool {
+	// Try to decrypt
+	_, announcement, err := crypto.OpenEnvelopeWithKeys(data, l.config.EnvelopeOpenKeys())
+	if err != nil {
+		// Not a wgmesh packet or wrong secret - silently ignore
+		return false
+	}
+
+	// Skip our own announcements
+	if announcement.WGPubKey == l.localNode.WGPubKey {
+		return false
+	}
+
+	identity, ok := verifiedIdentity(announcement, l.config, "LAN")
+	if !ok {
+		return false
+	}
+
+	// Resolve endpoint from the sender's address if the announced one is 0.0.0.0
+	endpoint := resolveEndpoint(announcement.WGEndpoint, remoteAddr)
+
+	peer := &daemon.PeerInfo{
+		WGPubKey:            announcement.WGPubKey,
+		Hostname:            announcement.Hostname,
+		MeshIP:              announcement.MeshIP,
+		MeshIPv6:            announcement.MeshIPv6,
+		Endpoint:            endpoint,
+		Introducer:          announcement.Introducer,
+		RoutableNetworks:    announcement.RoutableNetworks,
+		RoutesAnnounced:     true,
+		NATType:             announcement.NATType,
+		Version:             announcement.Version,
+		Revision:            announcement.Revision,
+		MinRevision:         announcement.MinRevision,
+		Capabilities:        announcement.Capabilities,
+		Load:                announcement.Load,
+		Relays:              announcement.Relays,
+		IntroducerCandidate: announcement.IntroducerCandidate,
+		IntroducerElected:   announcement.IntroducerElected,
+		PairPSK:             announcement.PairPSK,
+		Tags:                announcement.Tags,
+		PortHop:             time.Duration(announcement.PortHop) * time.Second,
+		TCPPort:             announcement.TCPPort,
+		TCPVia:              announcement.TCPVia,
+		Approval:            announcement.Approval,
+		DNS:                 announcement.DNS,
+		RouteChecks:         announcement.RouteChecks,
+		Candidates:          announcedCandidates(announcement.EndpointCandidates, l.config.DisableIPv6),
+		Identity:            identity,
+	}
+
+	log.Printf("[LAN] Discovered peer %s (%s) at %s", safeTruncate(peer.WGPubKey, 8), peer.MeshIP, peer.Endpoint)
+	l.peerStore.Update(peer, LANMethod)
+	daemon.RecordDiscoveryEvent("lan")
+	return true
+}
+
+// announceMDNS publishes the sealed announcement as DNS-SD records. It is
+// sent from the listening socket so the source port is 5353, as mDNS
+// responders expect.
+func (l *LANDiscovery) announceMDNS(envelope []byte) {
+	port := l.config.WGListenPort
+	if _, p, err := net.SplitHostPort(l.localNode.GetEndpoint()); err == nil {
+		if n, err := strconv.Atoi(p); err == nil && n > 0 {
+			port = n
+		}
+	}
+
+	packet, err := buildMDNSResponse(mdnsService{
+		Instance: mdnsInstanceName(l.localNode.WGPubKey),
+		Port:     port,
+		IPs:      l.interfaceAddrs(),
+		Envelope: envelope,
+	})
+	if err != nil {
+		log.Printf("[LAN] Failed to build mDNS response: %v", err)
+		return
+	}
+	l.sendMulticast(packet)
+}
+
+// interfaceAddrs returns the IPv4 addresses of the interfaces LAN
+// discovery runs on.
+func (l *LANDiscovery) interfaceAddrs() []net.IP {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var ips []net.IP
+	for _, li := range l.interfaces {
+		for _, n := range li.addrs {
+			ips = append(ips, n.IP)
 		}
+	}
+	return ips
+}
+
+// handleMDNSPacket answers queries for the wgmesh service and ingests any
+// announcements carried in DNS-SD responses. It reports whether one came
+// from another node of the mesh.
+func (l *LANDiscovery) handleMDNSPacket(data []byte, remoteAddr *net.UDPAddr) bool {
+	msg, err := parseMDNSMessage(data)
+	if err != nil {
+		// Other mDNS traffic on the segment - silently ignore
+		return false
+	}
 
-		// Resolve endpoint from the sender's address if the announced one is 0.0.0.0
-		endpoint := resolveEndpoint(announcement.WGEndpoint, remoteAddr)
+	if !msg.Response && msg.QueriesSvc {
+		l.announce()
+		return false
+	}
 
-		peer := &daemon.PeerInfo{
-			WGPubKey:         announcement.WGPubKey,
-			Hostname:         announcement.Hostname,
-			MeshIP:           announcement.MeshIP,
-			MeshIPv6:         announcement.MeshIPv6,
-			Endpoint:         endpoint,
-			Introducer:       announcement.Introducer,
-			RoutableNetworks: announcement.RoutableNetworks,
-			NATType:          announcement.NATType,
+	ok := false
+	for _, envelope := range msg.Announcements {
+		if l.handleEnvelope(envelope, remoteAddr) {
+			ok = true
 		}
+	}
+	return ok
+}
 
-		log.Printf("[LAN] Discovered peer %s (%s) at %s", safeTruncate(peer.WGPubKey, 8), peer.MeshIP, peer.Endpoint)
-		l.peerStore.Update(peer, LANMethod)
-		daemon.RecordDiscoveryEvent("lan")
+// localIPv4Addrs returns the non-loopback IPv4 addresses of up interfaces,
+// used as A records for the advertised SRV target.
+func localIPv4Addrs() []net.IP {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	var ips []net.IP
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || iface.Flags&net.FlagMulticast == 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if ip4 := ipNet.IP.To4(); ip4 != nil && !ip4.IsLinkLocalUnicast() {
+				ips = append(ips, ip4)
+			}
+		}
 	}
+	return ips
 }
 
 // resolveEndpoint resolves the peer endpoint from the announcement and sender address
@@ -240,6 +724,9 @@ func (l *LANDiscovery) MarshalJSON() ([]byte, error) {
 
 	return json.Marshal(map[string]interface{}{
 		"multicast_addr": l.multicastAddr.String(),
+		"mdns":           l.mdns,
+		"broadcast":      l.broadcast,
+		"interfaces":     l.interfaceNamesLocked(),
 		"running":        l.running,
 	})
 }
@@ -3,6 +3,8 @@ package discovery
 import (
 	"net"
 	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
 )
 
 func TestResolveEndpointPrefersLANSourceIP(t *testing.T) {
@@ -30,3 +32,72 @@ func TestResolveEndpointPrefersLANSourceIP(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLANGroup(t *testing.T) {
+	addr, err := parseLANGroup("239.1.2.3")
+	if err != nil || addr.String() != "239.1.2.3:51830" {
+		t.Errorf("parseLANGroup(239.1.2.3) = %v, %v", addr, err)
+	}
+	addr, err = parseLANGroup("239.1.2.3:5000")
+	if err != nil || addr.Port != 5000 {
+		t.Errorf("parseLANGroup(239.1.2.3:5000) = %v, %v", addr, err)
+	}
+	if _, err := parseLANGroup("10.0.0.1"); err == nil {
+		t.Error("unicast group should be rejected")
+	}
+}
+
+func TestBroadcastAddr(t *testing.T) {
+	tests := []struct {
+		cidr string
+		want string
+	}{
+		{"192.168.1.10/24", "192.168.1.255"},
+		{"10.1.2.3/16", "10.1.255.255"},
+		{"172.16.0.5/30", "172.16.0.7"},
+		{"10.0.0.0/31", "<nil>"},
+		{"10.0.0.1/32", "<nil>"},
+	}
+	for _, tt := range tests {
+		ip, n, _ := net.ParseCIDR(tt.cidr)
+		n.IP = ip.To4()
+		if got := broadcastAddr(n).String(); got != tt.want {
+			t.Errorf("broadcastAddr(%s) = %s, want %s", tt.cidr, got, tt.want)
+		}
+	}
+}
+
+func TestSelectsInterface(t *testing.T) {
+	l := &LANDiscovery{config: &daemon.Config{InterfaceName: "wg0", LANInterfaces: []string{"eth0", "wl*"}}}
+	up := net.FlagUp | net.FlagMulticast | net.FlagBroadcast
+	tests := []struct {
+		ifi  net.Interface
+		want bool
+	}{
+		{net.Interface{Name: "eth0", Flags: up}, true},
+		{net.Interface{Name: "wlan0", Flags: up}, true},
+		{net.Interface{Name: "eth1", Flags: up}, false},
+		{net.Interface{Name: "wlan1", Flags: net.FlagMulticast}, false},
+		{net.Interface{Name: "wlo", Flags: up | net.FlagLoopback}, false},
+	}
+	for _, tt := range tests {
+		if got := l.selectsInterface(tt.ifi); got != tt.want {
+			t.Errorf("selectsInterface(%s) = %v, want %v", tt.ifi.Name, got, tt.want)
+		}
+	}
+
+	// Without --lan-interfaces every interface but the mesh's own qualifies;
+	// broadcast-only links need --lan-broadcast.
+	l.config.LANInterfaces = nil
+	if l.selectsInterface(net.Interface{Name: "wg0", Flags: up}) {
+		t.Error("mesh interface was selected")
+	}
+	bcastOnly := net.Interface{Name: "eth2", Flags: net.FlagUp | net.FlagBroadcast}
+	if l.selectsInterface(bcastOnly) {
+		t.Error("broadcast-only interface was selected without --lan-broadcast")
+	}
+	l.broadcast = true
+	if !l.selectsInterface(bcastOnly) {
+		t.Error("broadcast-only interface was not selected with --lan-broadcast")
+	}
+}
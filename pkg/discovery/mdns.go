@@ -0,0 +1,330 @@
+package discovery
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// mDNS / DNS-SD constants (RFC 6762, RFC 6763)
+const (
+	MDNSPort        = 5353
+	MDNSServiceName = "_wgmesh._udp.local"
+	MDNSRecordTTL   = 120
+
+	mdnsTypeA   = 1
+	mdnsTypePTR = 12
+	mdnsTypeTXT = 16
+	mdnsTypeSRV = 33
+	mdnsTypeANY = 255
+
+	mdnsClassIN         = 1
+	mdnsClassCacheFlush = 0x8000
+	mdnsFlagResponse    = 0x8400 // QR + AA
+	mdnsHeaderSize      = 12
+
+	// Announcement payload is carried in TXT strings "a0=...", "a1=...".
+	// Each TXT string is limited to 255 bytes on the wire.
+	mdnsTXTChunkSize = 200
+	mdnsTXTVersion   = "v=wgmesh1"
+)
+
+var MDNSGroupAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: MDNSPort}
+
+// mdnsService describes the DNS-SD records a node advertises.
+type mdnsService struct {
+	Instance string   // instance label, e.g. "wgmesh-AbCdEf12"
+	Port     int      // SRV port (WireGuard listen port)
+	IPs      []net.IP // A records for the SRV target
+	Envelope []byte   // sealed announcement carried in TXT
+}
+
+// mdnsMessage is the subset of a parsed mDNS packet LAN discovery cares about.
+type mdnsMessage struct {
+	Response      bool
+	QueriesSvc    bool              // a question asked for MDNSServiceName
+	Announcements map[string][]byte // instance name -> reassembled envelope
+}
+
+// buildMDNSQuery builds a one-question PTR query for MDNSServiceName.
+func buildMDNSQuery() []byte {
+	buf := make([]byte, mdnsHeaderSize)
+	binary.BigEndian.PutUint16(buf[4:6], 1) // QDCOUNT
+	buf = appendDNSName(buf, MDNSServiceName)
+	buf = binary.BigEndian.AppendUint16(buf, mdnsTypePTR)
+	buf = binary.BigEndian.AppendUint16(buf, mdnsClassIN)
+	return buf
+}
+
+// buildMDNSResponse builds an unsolicited DNS-SD response advertising svc:
+// PTR service -> instance, SRV/TXT for the instance and A for its target.
+func buildMDNSResponse(svc mdnsService) ([]byte, error) {
+	if svc.Instance == "" {
+		return nil, fmt.Errorf("empty instance name")
+	}
+	instanceName := svc.Instance + "." + MDNSServiceName
+	target := svc.Instance + ".local"
+
+	txt, err := encodeMDNSTXT(svc.Envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	var answers int
+	buf := make([]byte, mdnsHeaderSize)
+	binary.BigEndian.PutUint16(buf[2:4], mdnsFlagResponse)
+
+	// PTR records are shared, so no cache-flush bit
+	buf = appendDNSRecord(buf, MDNSServiceName, mdnsTypePTR, mdnsClassIN, appendDNSName(nil, instanceName))
+	answers++
+
+	srv := make([]byte, 6)
+	binary.BigEndian.PutUint16(srv[4:6], uint16(svc.Port))
+	srv = appendDNSName(srv, target)
+	buf = appendDNSRecord(buf, instanceName, mdnsTypeSRV, mdnsClassIN|mdnsClassCacheFlush, srv)
+	answers++
+
+	buf = appendDNSRecord(buf, instanceName, mdnsTypeTXT, mdnsClassIN|mdnsClassCacheFlush, txt)
+	answers++
+
+	for _, ip := range svc.IPs {
+		if ip4 := ip.To4(); ip4 != nil {
+			buf = appendDNSRecord(buf, target, mdnsTypeA, mdnsClassIN|mdnsClassCacheFlush, ip4)
+			answers++
+		}
+	}
+
+	binary.BigEndian.PutUint16(buf[6:8], uint16(answers))
+	return buf, nil
+}
+
+// encodeMDNSTXT encodes the envelope as TXT rdata: a version string followed
+// by base64 chunks of the sealed announcement.
+func encodeMDNSTXT(envelope []byte) ([]byte, error) {
+	strs := []string{mdnsTXTVersion}
+	encoded := base64.RawStdEncoding.EncodeToString(envelope)
+	for i := 0; len(encoded) > 0; i++ {
+		n := mdnsTXTChunkSize
+		if n > len(encoded) {
+			n = len(encoded)
+		}
+		strs = append(strs, "a"+strconv.Itoa(i)+"="+encoded[:n])
+		encoded = encoded[n:]
+	}
+
+	var rdata []byte
+	for _, s := range strs {
+		if len(s) > 255 {
+			return nil, fmt.Errorf("TXT string too long: %d bytes", len(s))
+		}
+		rdata = append(rdata, byte(len(s)))
+		rdata = append(rdata, s...)
+	}
+	if len(rdata) > 0xffff {
+		return nil, fmt.Errorf("TXT record too large: %d bytes", len(rdata))
+	}
+	return rdata, nil
+}
+
+// decodeMDNSTXT reassembles the envelope from TXT strings. Returns nil if the
+// record is not a wgmesh announcement.
+func decodeMDNSTXT(strs []string) []byte {
+	var versioned bool
+	chunks := make(map[int]string)
+	for _, s := range strs {
+		if s == mdnsTXTVersion {
+			versioned = true
+			continue
+		}
+		key, value, ok := strings.Cut(s, "=")
+		if !ok || len(key) < 2 || key[0] != 'a' {
+			continue
+		}
+		idx, err := strconv.Atoi(key[1:])
+		if err != nil || idx < 0 || idx > 64 {
+			continue
+		}
+		chunks[idx] = value
+	}
+	if !versioned || len(chunks) == 0 {
+		return nil
+	}
+
+	indexes := make([]int, 0, len(chunks))
+	for idx := range chunks {
+		indexes = append(indexes, idx)
+	}
+	sort.Ints(indexes)
+
+	var sb strings.Builder
+	for i, idx := range indexes {
+		if idx != i {
+			return nil // missing chunk
+		}
+		sb.WriteString(chunks[idx])
+	}
+
+	envelope, err := base64.RawStdEncoding.DecodeString(sb.String())
+	if err != nil {
+		return nil
+	}
+	return envelope
+}
+
+// parseMDNSMessage parses an mDNS packet, noting whether it queries the
+// wgmesh service and collecting TXT-carried announcements from responses.
+func parseMDNSMessage(data []byte) (*mdnsMessage, error) {
+	if len(data) < mdnsHeaderSize {
+		return nil, fmt.Errorf("message too short: %d bytes", len(data))
+	}
+
+	flags := binary.BigEndian.Uint16(data[2:4])
+	qdCount := int(binary.BigEndian.Uint16(data[4:6]))
+	rrCount := int(binary.BigEndian.Uint16(data[6:8])) +
+		int(binary.BigEndian.Uint16(data[8:10])) +
+		int(binary.BigEndian.Uint16(data[10:12]))
+
+	msg := &mdnsMessage{
+		Response:      flags&0x8000 != 0,
+		Announcements: make(map[string][]byte),
+	}
+
+	off := mdnsHeaderSize
+	for i := 0; i < qdCount; i++ {
+		name, next, err := readDNSName(data, off)
+		if err != nil {
+			return nil, fmt.Errorf("question %d: %w", i, err)
+		}
+		if next+4 > len(data) {
+			return nil, fmt.Errorf("question %d truncated", i)
+		}
+		qtype := binary.BigEndian.Uint16(data[next : next+2])
+		if strings.EqualFold(name, MDNSServiceName) && (qtype == mdnsTypePTR || qtype == mdnsTypeANY) {
+			msg.QueriesSvc = true
+		}
+		off = next + 4
+	}
+
+	suffix := "." + strings.ToLower(MDNSServiceName)
+	for i := 0; i < rrCount; i++ {
+		name, next, err := readDNSName(data, off)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", i, err)
+		}
+		if next+10 > len(data) {
+			return nil, fmt.Errorf("record %d truncated", i)
+		}
+		rtype := binary.BigEndian.Uint16(data[next : next+2])
+		rdlen := int(binary.BigEndian.Uint16(data[next+8 : next+10]))
+		rdStart := next + 10
+		if rdStart+rdlen > len(data) {
+			return nil, fmt.Errorf("record %d rdata truncated", i)
+		}
+		off = rdStart + rdlen
+
+		if !msg.Response || rtype != mdnsTypeTXT || !strings.HasSuffix(strings.ToLower(name), suffix) {
+			continue
+		}
+		if envelope := decodeMDNSTXT(readTXTStrings(data[rdStart:off])); envelope != nil {
+			msg.Announcements[name] = envelope
+		}
+	}
+
+	return msg, nil
+}
+
+func readTXTStrings(rdata []byte) []string {
+	var out []string
+	for i := 0; i < len(rdata); {
+		n := int(rdata[i])
+		i++
+		if i+n > len(rdata) {
+			break
+		}
+		out = append(out, string(rdata[i:i+n]))
+		i += n
+	}
+	return out
+}
+
+// readDNSName reads a (possibly compressed) domain name starting at off and
+// returns it without the trailing dot plus the offset just past it.
+func readDNSName(data []byte, off int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if off >= len(data) {
+			return "", 0, fmt.Errorf("name out of bounds")
+		}
+		l := int(data[off])
+		switch {
+		case l == 0:
+			if next < 0 {
+				next = off + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case l&0xC0 == 0xC0:
+			if off+1 >= len(data) {
+				return "", 0, fmt.Errorf("truncated compression pointer")
+			}
+			if jumps++; jumps > 16 {
+				return "", 0, fmt.Errorf("too many compression pointers")
+			}
+			if next < 0 {
+				next = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(data[off:off+2]) & 0x3FFF)
+		case l&0xC0 != 0:
+			return "", 0, fmt.Errorf("unsupported label type 0x%02x", l)
+		default:
+			if off+1+l > len(data) {
+				return "", 0, fmt.Errorf("label out of bounds")
+			}
+			labels = append(labels, string(data[off+1:off+1+l]))
+			off += 1 + l
+		}
+	}
+}
+
+func appendDNSName(buf []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if label == "" {
+			continue
+		}
+		if len(label) > 63 {
+			label = label[:63]
+		}
+		buf = append(buf, byte(len(label)))
+		buf = append(buf, label...)
+	}
+	return append(buf, 0)
+}
+
+func appendDNSRecord(buf []byte, name string, rtype, class uint16, rdata []byte) []byte {
+	buf = appendDNSName(buf, name)
+	buf = binary.BigEndian.AppendUint16(buf, rtype)
+	buf = binary.BigEndian.AppendUint16(buf, class)
+	buf = binary.BigEndian.AppendUint32(buf, MDNSRecordTTL)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(rdata)))
+	return append(buf, rdata...)
+}
+
+// mdnsInstanceName derives a stable DNS-SD instance label from a WireGuard
+// public key. Only alphanumerics are kept so the label is valid everywhere.
+func mdnsInstanceName(pubKey string) string {
+	var sb strings.Builder
+	sb.WriteString("wgmesh-")
+	for _, r := range pubKey {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		}
+		if sb.Len() >= len("wgmesh-")+12 {
+			break
+		}
+	}
+	return sb.String()
+}
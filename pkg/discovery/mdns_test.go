@@ -0,0 +1,113 @@
+package discovery
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestMDNSResponseRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	envelope := bytes.Repeat([]byte{0xAB, 0x01, 0x7F}, 300) // forces several TXT chunks
+	packet, err := buildMDNSResponse(mdnsService{
+		Instance: mdnsInstanceName("AbC+/dEf123456789=="),
+		Port:     51820,
+		IPs:      []net.IP{net.ParseIP("192.168.1.10"), net.ParseIP("fe80::1")},
+		Envelope: envelope,
+	})
+	if err != nil {
+		t.Fatalf("buildMDNSResponse() error = %v", err)
+	}
+
+	msg, err := parseMDNSMessage(packet)
+	if err != nil {
+		t.Fatalf("parseMDNSMessage() error = %v", err)
+	}
+	if !msg.Response {
+		t.Fatal("expected response flag to be set")
+	}
+	if msg.QueriesSvc {
+		t.Fatal("response should not be treated as a service query")
+	}
+	if len(msg.Announcements) != 1 {
+		t.Fatalf("got %d announcements, want 1", len(msg.Announcements))
+	}
+	for name, got := range msg.Announcements {
+		if name != "wgmesh-AbCdEf123456._wgmesh._udp.local" {
+			t.Errorf("instance name = %q", name)
+		}
+		if !bytes.Equal(got, envelope) {
+			t.Error("reassembled envelope does not match original")
+		}
+	}
+}
+
+func TestMDNSQueryDetection(t *testing.T) {
+	t.Parallel()
+
+	msg, err := parseMDNSMessage(buildMDNSQuery())
+	if err != nil {
+		t.Fatalf("parseMDNSMessage() error = %v", err)
+	}
+	if msg.Response || !msg.QueriesSvc {
+		t.Fatalf("got Response=%v QueriesSvc=%v, want false/true", msg.Response, msg.QueriesSvc)
+	}
+}
+
+func TestReadDNSNameCompression(t *testing.T) {
+	t.Parallel()
+
+	// "local" at offset 0, then "_udp" + pointer to offset 0
+	data := []byte{5, 'l', 'o', 'c', 'a', 'l', 0, 4, '_', 'u', 'd', 'p', 0xC0, 0x00}
+	name, next, err := readDNSName(data, 7)
+	if err != nil {
+		t.Fatalf("readDNSName() error = %v", err)
+	}
+	if name != "_udp.local" || next != len(data) {
+		t.Fatalf("readDNSName() = %q, %d; want %q, %d", name, next, "_udp.local", len(data))
+	}
+
+	loop := []byte{0xC0, 0x00}
+	if _, _, err := readDNSName(loop, 0); err == nil {
+		t.Fatal("expected error for compression loop")
+	}
+}
+
+func TestParseMDNSMessageRejectsTruncated(t *testing.T) {
+	t.Parallel()
+
+	packet, err := buildMDNSResponse(mdnsService{Instance: "wgmesh-x", Port: 1, Envelope: []byte("hello")})
+	if err != nil {
+		t.Fatalf("buildMDNSResponse() error = %v", err)
+	}
+	for i := 0; i < len(packet)-1; i++ {
+		// Must never panic; most truncations are errors.
+		parseMDNSMessage(packet[:i])
+	}
+}
+
+func TestDecodeMDNSTXTIgnoresForeignRecords(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		strs []string
+	}{
+		{name: "no version", strs: []string{"a0=aGVsbG8"}},
+		{name: "missing chunk", strs: []string{mdnsTXTVersion, "a1=aGVsbG8"}},
+		{name: "bad base64", strs: []string{mdnsTXTVersion, "a0=!!!"}},
+		{name: "other service", strs: []string{"path=/", "txtvers=1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeMDNSTXT(tt.strs); got != nil {
+				t.Fatalf("decodeMDNSTXT(%v) = %q, want nil", tt.strs, got)
+			}
+		})
+	}
+	if got := decodeMDNSTXT([]string{mdnsTXTVersion, "a0=" + strings.TrimRight("aGVsbG8=", "=")}); string(got) != "hello" {
+		t.Fatalf("decodeMDNSTXT valid = %q, want hello", got)
+	}
+}
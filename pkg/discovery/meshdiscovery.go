@@ -0,0 +1,234 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/privacy"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+// meshDiscovery is what DHT and DNS discovery share once a peer is found:
+// the peer exchange, gossip, LAN discovery and the daemon's messages to the
+// whole mesh. Both embed it, so the daemon gets the same rotation,
+// broadcast, approval and LAN status support whichever one bootstraps the
+// mesh.
+type meshDiscovery struct {
+	config    *daemon.Config
+	localNode *daemon.LocalNode
+	peerStore *daemon.PeerStore
+	exchange  *PeerExchange
+	gossip    *MeshGossip
+	lan       *LANDiscovery
+	stun      *STUNResponder
+	dialer    *dialScheduler
+
+	mu           sync.RWMutex
+	running      bool
+	ctx          context.Context
+	cancel       context.CancelFunc
+	controlPeers map[string]string // peer pubkey -> exchange/control endpoint
+}
+
+func newMeshDiscovery(parentCtx context.Context, config *daemon.Config, localNode *daemon.LocalNode, peerStore *daemon.PeerStore) meshDiscovery {
+	ctx, cancel := context.WithCancel(parentCtx)
+	return meshDiscovery{
+		config:       config,
+		localNode:    localNode,
+		peerStore:    peerStore,
+		exchange:     NewPeerExchange(config, localNode, peerStore),
+		dialer:       newDialScheduler(DHTMaxConcurrentExchanges, RendezvousMinBackoff, RendezvousMaxBackoff),
+		ctx:          ctx,
+		cancel:       cancel,
+		controlPeers: make(map[string]string),
+	}
+}
+
+// SetLANDiscovery starts or stops LAN discovery while running.
+func (d *meshDiscovery) SetLANDiscovery(enabled bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.running {
+		return fmt.Errorf("discovery is not running")
+	}
+	lan, err := switchLAN(d.lan, enabled, d.config, d.localNode, d.peerStore)
+	d.lan = lan
+	return err
+}
+
+// LANStatus describes LAN discovery, or returns nil while it is off.
+func (d *meshDiscovery) LANStatus() *daemon.RPCLANData {
+	d.mu.RLock()
+	lan := d.lan
+	d.mu.RUnlock()
+	if lan == nil {
+		return nil
+	}
+	return lan.LANStatus()
+}
+
+// SetWireGuardBackend implements daemon.WireGuardParticipant.
+func (d *meshDiscovery) SetWireGuardBackend(wg wireguard.Backend) {
+	d.exchange.SetWireGuardBackend(wg)
+}
+
+func (d *meshDiscovery) broadcastGoodbye() {
+	if d.exchange == nil {
+		return
+	}
+
+	for endpoint := range d.peerControlEndpoints() {
+		if err := d.exchange.SendGoodbye(endpoint); err != nil {
+			d.debugf("[Exchange] Failed to send GOODBYE to %s: %v", endpoint, err)
+		}
+	}
+}
+
+// peerControlEndpoints returns the exchange endpoints of all known peers.
+func (d *meshDiscovery) peerControlEndpoints() map[string]struct{} {
+	peers := d.peerStore.GetAll()
+	targets := make(map[string]struct{})
+	for _, p := range peers {
+		if p == nil || p.WGPubKey == "" || p.WGPubKey == d.localNode.WGPubKey {
+			continue
+		}
+		if endpoint := d.controlEndpointForPeer(p); endpoint != "" {
+			targets[endpoint] = struct{}{}
+			continue
+		}
+		if endpoint := toControlEndpoint(p.Endpoint, int(d.config.Keys.GossipPort)); endpoint != "" {
+			targets[endpoint] = struct{}{}
+		}
+	}
+	return targets
+}
+
+// RendezvousSessions returns the rendezvous this node is coordinating as
+// an introducer.
+func (d *meshDiscovery) RendezvousSessions() int {
+	if d.exchange == nil {
+		return 0
+	}
+	return d.exchange.RendezvousSessions()
+}
+
+// SetRotationHandler passes received secret rotation messages to handler.
+func (d *meshDiscovery) SetRotationHandler(handler func(msg *crypto.RotationMessage)) {
+	d.exchange.SetRotationHandler(handler)
+}
+
+// SetBroadcastHandler passes received operator broadcasts to handler.
+func (d *meshDiscovery) SetBroadcastHandler(handler func(msg *crypto.BroadcastMessage)) {
+	d.exchange.SetBroadcastHandler(handler)
+}
+
+// SetApprovalHandler passes received admission approvals to handler.
+func (d *meshDiscovery) SetApprovalHandler(handler func(msg *crypto.ApprovalMessage)) {
+	d.exchange.SetApprovalHandler(handler)
+}
+
+// SetDandelionRouter enables private first contact; see
+// PeerExchange.StemFirstContact.
+func (d *meshDiscovery) SetDandelionRouter(router *privacy.DandelionRouter) {
+	d.exchange.SetDandelionRouter(router)
+}
+
+// BroadcastRotation sends a rotation message to every known peer under the
+// current secret.
+func (d *meshDiscovery) BroadcastRotation(msg *crypto.RotationMessage) {
+	targets := d.peerControlEndpoints()
+	for endpoint := range targets {
+		if err := d.exchange.SendRotation(endpoint, msg); err != nil {
+			d.debugf("[Rotation] Failed to send ROTATE to %s: %v", endpoint, err)
+		}
+	}
+	log.Printf("[Rotation] Sent rotation announcement to %d peers", len(targets))
+}
+
+// BroadcastMessage sends an operator broadcast to every known peer.
+func (d *meshDiscovery) BroadcastMessage(msg *crypto.BroadcastMessage) {
+	targets := d.peerControlEndpoints()
+	for endpoint := range targets {
+		if err := d.exchange.SendBroadcast(endpoint, msg); err != nil {
+			d.debugf("[Broadcast] Failed to send BROADCAST to %s: %v", endpoint, err)
+		}
+	}
+	d.debugf("[Broadcast] Sent message %s to %d peers", msg.ID, len(targets))
+}
+
+// BroadcastApproval sends an admission approval to every known peer.
+func (d *meshDiscovery) BroadcastApproval(msg *crypto.ApprovalMessage) {
+	for endpoint := range d.peerControlEndpoints() {
+		if err := d.exchange.SendApproval(endpoint, msg); err != nil {
+			d.debugf("[Admission] Failed to send APPROVAL to %s: %v", endpoint, err)
+		}
+	}
+}
+
+// SendApproval sends an admission approval to peer, which need not be in
+// the peer store: peers waiting for approval are kept out of it.
+func (d *meshDiscovery) SendApproval(peer *daemon.PeerInfo, msg *crypto.ApprovalMessage) {
+	endpoint := d.controlEndpointForPeer(peer)
+	if endpoint == "" {
+		return
+	}
+	if err := d.exchange.SendApproval(endpoint, msg); err != nil {
+		d.debugf("[Admission] Failed to send APPROVAL to %s: %v", endpoint, err)
+	}
+}
+
+// setControlEndpoint records the endpoint a peer's exchange listener
+// answered on, in memory and in the peer store, which persists it in the
+// peer cache for the next start.
+func (d *meshDiscovery) setControlEndpoint(peerPubKey, endpoint string) {
+	if peerPubKey == "" {
+		return
+	}
+	normalized := normalizeKnownPeerEndpoint(endpoint)
+	normalized = filterEndpointForConfig(normalized, d.config.DisableIPv6)
+	if normalized == "" {
+		return
+	}
+	d.mu.Lock()
+	d.controlPeers[peerPubKey] = normalized
+	d.mu.Unlock()
+	d.peerStore.SetControlEndpoint(peerPubKey, normalized)
+}
+
+func (d *meshDiscovery) controlEndpointForPeer(peer *daemon.PeerInfo) string {
+	if peer == nil || peer.WGPubKey == "" {
+		return ""
+	}
+
+	// Prevent self-connection: don't return control endpoint for own node
+	if peer.WGPubKey == d.localNode.WGPubKey {
+		return ""
+	}
+
+	d.mu.RLock()
+	if endpoint, ok := d.controlPeers[peer.WGPubKey]; ok {
+		d.mu.RUnlock()
+		return endpoint
+	}
+	d.mu.RUnlock()
+
+	if endpoint := toControlEndpoint(peer.Endpoint, int(d.config.Keys.GossipPort)); endpoint != "" {
+		if d.config.DisableIPv6 && isIPv6Endpoint(endpoint) {
+			return ""
+		}
+		return endpoint
+	}
+
+	return ""
+}
+
+func (d *meshDiscovery) debugf(format string, args ...interface{}) {
+	if strings.EqualFold(d.config.LogLevel, "debug") {
+		log.Printf(format, args...)
+	}
+}
@@ -184,6 +184,13 @@ func (r *RendezvousRegistry) decryptPeerList(body string) []*daemon.PeerInfo {
 			Endpoint:         announcement.WGEndpoint,
 			RoutableNetworks: announcement.RoutableNetworks,
 			NATType:          announcement.NATType,
+			Version:          announcement.Version,
+			Revision:         announcement.Revision,
+			MinRevision:      announcement.MinRevision,
+			Capabilities:     announcement.Capabilities,
+			Tags:             announcement.Tags,
+			DNS:              announcement.DNS,
+			RouteChecks:      announcement.RouteChecks,
 		})
 	}
 
@@ -196,6 +203,7 @@ func (r *RendezvousRegistry) decryptPeerList(body string) []*daemon.PeerInfo {
 			MeshIPv6: kp.MeshIPv6,
 			Endpoint: kp.WGEndpoint,
 			NATType:  kp.NATType,
+			PairPSK:  kp.PairPSK,
 		})
 	}
 
@@ -333,6 +341,7 @@ func (r *RendezvousRegistry) buildIssueBody(peers []*daemon.PeerInfo) (string, e
 			WGEndpoint: p.Endpoint,
 			Introducer: p.Introducer,
 			NATType:    p.NATType,
+			PairPSK:    p.PairPSK,
 		})
 	}
 
@@ -349,6 +358,10 @@ func (r *RendezvousRegistry) buildIssueBody(peers []*daemon.PeerInfo) (string, e
 		first.MeshIPv6,
 		first.NATType,
 	)
+	announcement.Version = first.Version
+	announcement.Revision = first.Revision
+	announcement.MinRevision = first.MinRevision
+	announcement.Capabilities = first.Capabilities
 
 	encrypted, err := crypto.SealEnvelope(crypto.MessageTypeAnnounce, announcement, r.GossipKey)
 	if err != nil {
@@ -0,0 +1,31 @@
+package discovery
+
+import (
+	"errors"
+	"log"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+// verifiedIdentity checks the signature on a received announcement and
+// returns the identity to record for the sender. ok is false when the
+// announcement must be dropped: its signature is invalid, or it is unsigned
+// and the daemon runs with --require-signed. Unsigned announcements are
+// otherwise accepted with an empty identity so older nodes keep working.
+func verifiedIdentity(announcement *crypto.PeerAnnouncement, config *daemon.Config, source string) (identity string, ok bool) {
+	err := announcement.VerifySignature()
+	switch {
+	case err == nil:
+		return announcement.Identity, true
+	case errors.Is(err, crypto.ErrUnsignedAnnouncement):
+		if config != nil && config.RequireSigned {
+			log.Printf("[%s] Dropping unsigned announcement from %s...", source, safeTruncate(announcement.WGPubKey, 8))
+			return "", false
+		}
+		return "", true
+	default:
+		log.Printf("[%s] Dropping announcement from %s...: %v", source, safeTruncate(announcement.WGPubKey, 8), err)
+		return "", false
+	}
+}
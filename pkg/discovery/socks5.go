@@ -0,0 +1,302 @@
+package discovery
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SOCKS5 UDP ASSOCIATE (RFC 1928) client. The proxy relays datagrams for as
+// long as the TCP control connection stays open; each datagram to or from the
+// relay carries a small header naming the remote address.
+//
+// Tor's own SOCKS port does not implement UDP ASSOCIATE; using Tor needs a
+// UDP-capable SOCKS5 front end that tunnels through it.
+
+const (
+	socks5Version       = 0x05
+	socks5AuthNone      = 0x00
+	socks5AuthPassword  = 0x02
+	socks5AuthNoAccept  = 0xff
+	socks5CmdAssociate  = 0x03
+	socks5AddrIPv4      = 0x01
+	socks5AddrDomain    = 0x03
+	socks5AddrIPv6      = 0x04
+	socks5HandshakeTime = 10 * time.Second
+
+	// maxSOCKS5Datagram is the largest UDP payload, header included.
+	maxSOCKS5Datagram = 65535
+)
+
+// socks5PacketConn is a net.PacketConn whose datagrams go through a SOCKS5
+// proxy's UDP relay.
+type socks5PacketConn struct {
+	ctrl  net.Conn
+	conn  *net.UDPConn
+	relay *net.UDPAddr
+
+	readMu  sync.Mutex
+	readBuf []byte
+
+	closed    atomic.Bool
+	closeOnce sync.Once
+}
+
+// dialSOCKS5UDP opens a UDP association through the proxy. localPort is the
+// port of the local socket that talks to the relay (0 lets the OS pick).
+func dialSOCKS5UDP(proxy *url.URL, localPort int) (*socks5PacketConn, error) {
+	ctrl, err := net.DialTimeout("tcp", proxy.Host, socks5HandshakeTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SOCKS5 proxy %s: %w", proxy.Host, err)
+	}
+	ctrl.SetDeadline(time.Now().Add(socks5HandshakeTime))
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: localPort})
+	if err != nil && localPort != 0 {
+		conn, err = net.ListenUDP("udp", &net.UDPAddr{})
+	}
+	if err != nil {
+		ctrl.Close()
+		return nil, fmt.Errorf("failed to bind UDP socket for SOCKS5 relay: %w", err)
+	}
+
+	relay, err := socks5Associate(ctrl, proxy, conn.LocalAddr().(*net.UDPAddr).Port)
+	if err != nil {
+		ctrl.Close()
+		conn.Close()
+		return nil, fmt.Errorf("SOCKS5 proxy %s: %w", proxy.Host, err)
+	}
+	ctrl.SetDeadline(time.Time{})
+
+	c := &socks5PacketConn{
+		ctrl:    ctrl,
+		conn:    conn,
+		relay:   relay,
+		readBuf: make([]byte, maxSOCKS5Datagram),
+	}
+	go c.watchControl()
+	return c, nil
+}
+
+// socks5Associate negotiates authentication and requests a UDP association,
+// returning the relay address datagrams must be sent to.
+func socks5Associate(ctrl net.Conn, proxy *url.URL, localPort int) (*net.UDPAddr, error) {
+	methods := []byte{socks5AuthNone}
+	if proxy.User != nil {
+		methods = []byte{socks5AuthPassword}
+	}
+	greeting := append([]byte{socks5Version, byte(len(methods))}, methods...)
+	if _, err := ctrl.Write(greeting); err != nil {
+		return nil, err
+	}
+	var choice [2]byte
+	if _, err := io.ReadFull(ctrl, choice[:]); err != nil {
+		return nil, fmt.Errorf("reading method selection: %w", err)
+	}
+	if choice[0] != socks5Version {
+		return nil, fmt.Errorf("unexpected protocol version %d", choice[0])
+	}
+	switch choice[1] {
+	case socks5AuthNone:
+	case socks5AuthPassword:
+		if proxy.User == nil {
+			return nil, errors.New("proxy requires a username and password")
+		}
+		user := proxy.User.Username()
+		pass, _ := proxy.User.Password()
+		req := []byte{0x01, byte(len(user))}
+		req = append(req, user...)
+		req = append(req, byte(len(pass)))
+		req = append(req, pass...)
+		if _, err := ctrl.Write(req); err != nil {
+			return nil, err
+		}
+		var status [2]byte
+		if _, err := io.ReadFull(ctrl, status[:]); err != nil {
+			return nil, fmt.Errorf("reading authentication status: %w", err)
+		}
+		if status[1] != 0x00 {
+			return nil, errors.New("authentication failed")
+		}
+	case socks5AuthNoAccept:
+		return nil, errors.New("no acceptable authentication method")
+	default:
+		return nil, fmt.Errorf("unexpected authentication method %d", choice[1])
+	}
+
+	// The client address is left unspecified: behind NAT we do not know
+	// the address the proxy will see.
+	req := []byte{socks5Version, socks5CmdAssociate, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(req[8:], uint16(localPort))
+	if _, err := ctrl.Write(req); err != nil {
+		return nil, err
+	}
+	var head [3]byte
+	if _, err := io.ReadFull(ctrl, head[:]); err != nil {
+		return nil, fmt.Errorf("reading associate reply: %w", err)
+	}
+	if head[1] != 0x00 {
+		return nil, fmt.Errorf("UDP associate rejected (reply code %d)", head[1])
+	}
+	host, port, err := readSOCKS5Addr(ctrl)
+	if err != nil {
+		return nil, fmt.Errorf("reading relay address: %w", err)
+	}
+
+	// Many proxies answer with an unspecified address, meaning "the
+	// address you connected to".
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = ctrl.RemoteAddr().(*net.TCPAddr).IP.String()
+	}
+	return net.ResolveUDPAddr("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+}
+
+// readSOCKS5Addr reads ATYP, the address and the port from r.
+func readSOCKS5Addr(r io.Reader) (string, int, error) {
+	var atyp [1]byte
+	if _, err := io.ReadFull(r, atyp[:]); err != nil {
+		return "", 0, err
+	}
+	var host string
+	switch atyp[0] {
+	case socks5AddrIPv4, socks5AddrIPv6:
+		size := net.IPv4len
+		if atyp[0] == socks5AddrIPv6 {
+			size = net.IPv6len
+		}
+		ip := make(net.IP, size)
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return "", 0, err
+		}
+		host = ip.String()
+	case socks5AddrDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(r, n[:]); err != nil {
+			return "", 0, err
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(r, name); err != nil {
+			return "", 0, err
+		}
+		host = string(name)
+	default:
+		return "", 0, fmt.Errorf("unknown address type %d", atyp[0])
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(r, port[:]); err != nil {
+		return "", 0, err
+	}
+	return host, int(binary.BigEndian.Uint16(port[:])), nil
+}
+
+// watchControl closes the relay socket once the proxy drops the control
+// connection, since the association ends with it.
+func (c *socks5PacketConn) watchControl() {
+	io.Copy(io.Discard, c.ctrl)
+	if !c.closed.Load() {
+		log.Printf("[SOCKS5] Proxy closed the UDP association")
+	}
+	c.Close()
+}
+
+// ReadFrom returns the next datagram relayed by the proxy and the address it
+// came from. Fragmented datagrams and packets not from the relay are dropped.
+func (c *socks5PacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	c.readMu.Lock()
+	defer c.readMu.Unlock()
+
+	for {
+		n, from, err := c.conn.ReadFromUDP(c.readBuf)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !from.IP.Equal(c.relay.IP) || from.Port != c.relay.Port {
+			continue
+		}
+		payload, addr, err := parseSOCKS5Datagram(c.readBuf[:n])
+		if err != nil {
+			continue
+		}
+		return copy(b, payload), addr, nil
+	}
+}
+
+// WriteTo sends b to addr through the relay.
+func (c *socks5PacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	udpAddr, ok := addr.(*net.UDPAddr)
+	if !ok {
+		var err error
+		if udpAddr, err = net.ResolveUDPAddr("udp", addr.String()); err != nil {
+			return 0, err
+		}
+	}
+	packet := append(socks5DatagramHeader(udpAddr), b...)
+	if _, err := c.conn.WriteToUDP(packet, c.relay); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close ends the association.
+func (c *socks5PacketConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.closed.Store(true)
+		c.ctrl.Close()
+		err = c.conn.Close()
+	})
+	return err
+}
+
+func (c *socks5PacketConn) LocalAddr() net.Addr                { return c.conn.LocalAddr() }
+func (c *socks5PacketConn) SetDeadline(t time.Time) error      { return c.conn.SetDeadline(t) }
+func (c *socks5PacketConn) SetReadDeadline(t time.Time) error  { return c.conn.SetReadDeadline(t) }
+func (c *socks5PacketConn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
+
+// socks5DatagramHeader builds the RSV/FRAG/address header for a datagram to addr.
+func socks5DatagramHeader(addr *net.UDPAddr) []byte {
+	header := []byte{0, 0, 0}
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		header = append(header, socks5AddrIPv4)
+		header = append(header, ip4...)
+	} else {
+		header = append(header, socks5AddrIPv6)
+		header = append(header, addr.IP.To16()...)
+	}
+	return binary.BigEndian.AppendUint16(header, uint16(addr.Port))
+}
+
+// parseSOCKS5Datagram splits a relayed datagram into its payload and source.
+func parseSOCKS5Datagram(packet []byte) ([]byte, *net.UDPAddr, error) {
+	if len(packet) < 4 {
+		return nil, nil, errors.New("short datagram")
+	}
+	if packet[2] != 0 {
+		return nil, nil, errors.New("fragmented datagram")
+	}
+	var ipLen int
+	switch packet[3] {
+	case socks5AddrIPv4:
+		ipLen = net.IPv4len
+	case socks5AddrIPv6:
+		ipLen = net.IPv6len
+	default:
+		// The relay reports the peer we sent to, always an IP here.
+		return nil, nil, fmt.Errorf("unsupported address type %d", packet[3])
+	}
+	if len(packet) < 4+ipLen+2 {
+		return nil, nil, errors.New("short datagram")
+	}
+	ip := make(net.IP, ipLen)
+	copy(ip, packet[4:4+ipLen])
+	port := int(binary.BigEndian.Uint16(packet[4+ipLen:]))
+	return packet[4+ipLen+2:], &net.UDPAddr{IP: ip, Port: port}, nil
+}
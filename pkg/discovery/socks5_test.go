@@ -0,0 +1,286 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+// startTestSOCKS5Proxy runs a minimal SOCKS5 server supporting UDP ASSOCIATE
+// on loopback. With a non-empty user it requires username/password auth.
+func startTestSOCKS5Proxy(t *testing.T, user, pass string) *url.URL {
+	t.Helper()
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			ctrl, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveTestSOCKS5(t, ctrl, user, pass)
+		}
+	}()
+
+	u := &url.URL{Scheme: "socks5", Host: ln.Addr().String()}
+	if user != "" {
+		u.User = url.UserPassword(user, pass)
+	}
+	return u
+}
+
+func serveTestSOCKS5(t *testing.T, ctrl net.Conn, user, pass string) {
+	defer ctrl.Close()
+
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(ctrl, head); err != nil {
+		return
+	}
+	methods := make([]byte, head[1])
+	io.ReadFull(ctrl, methods)
+	want := byte(socks5AuthNone)
+	if user != "" {
+		want = socks5AuthPassword
+	}
+	if !bytes.Contains(methods, []byte{want}) {
+		ctrl.Write([]byte{socks5Version, socks5AuthNoAccept})
+		return
+	}
+	ctrl.Write([]byte{socks5Version, want})
+	if user != "" {
+		var n [1]byte
+		io.ReadFull(ctrl, make([]byte, 1))
+		io.ReadFull(ctrl, n[:])
+		gotUser := make([]byte, n[0])
+		io.ReadFull(ctrl, gotUser)
+		io.ReadFull(ctrl, n[:])
+		gotPass := make([]byte, n[0])
+		io.ReadFull(ctrl, gotPass)
+		if string(gotUser) != user || string(gotPass) != pass {
+			ctrl.Write([]byte{0x01, 0x01})
+			return
+		}
+		ctrl.Write([]byte{0x01, 0x00})
+	}
+
+	req := make([]byte, 3)
+	if _, err := io.ReadFull(ctrl, req); err != nil || req[1] != socks5CmdAssociate {
+		return
+	}
+	if _, _, err := readSOCKS5Addr(ctrl); err != nil {
+		return
+	}
+
+	relay, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return
+	}
+	defer relay.Close()
+	upstream, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	// Answer with an unspecified address, as many servers do.
+	reply := []byte{socks5Version, 0x00, 0x00, socks5AddrIPv4, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(reply[8:], uint16(relay.LocalAddr().(*net.UDPAddr).Port))
+	ctrl.Write(reply)
+
+	clientCh := make(chan *net.UDPAddr, 1)
+	go func() {
+		buf := make([]byte, maxSOCKS5Datagram)
+		for {
+			n, from, err := relay.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			payload, dst, err := parseSOCKS5Datagram(buf[:n])
+			if err != nil {
+				t.Errorf("proxy received malformed datagram: %v", err)
+				continue
+			}
+			select {
+			case clientCh <- from:
+			default:
+			}
+			upstream.WriteToUDP(payload, dst)
+		}
+	}()
+	go func() {
+		var client *net.UDPAddr
+		buf := make([]byte, maxSOCKS5Datagram)
+		for {
+			n, from, err := upstream.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			select {
+			case client = <-clientCh:
+			default:
+			}
+			if client != nil {
+				relay.WriteToUDP(append(socks5DatagramHeader(from), buf[:n]...), client)
+			}
+		}
+	}()
+
+	io.Copy(io.Discard, ctrl)
+}
+
+func TestSOCKS5PacketConnRoundTrip(t *testing.T) {
+	echo, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, from, err := echo.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			echo.WriteToUDP(buf[:n], from)
+		}
+	}()
+
+	proxy := startTestSOCKS5Proxy(t, "mesh", "hunter2")
+	conn, err := dialSOCKS5UDP(proxy, 0)
+	if err != nil {
+		t.Fatalf("dialSOCKS5UDP: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.WriteTo([]byte("ping"), echo.LocalAddr()); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 64)
+	n, from, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom: %v", err)
+	}
+	if string(buf[:n]) != "ping" {
+		t.Errorf("payload = %q, want ping", buf[:n])
+	}
+	if from.String() != echo.LocalAddr().String() {
+		t.Errorf("from = %s, want %s", from, echo.LocalAddr())
+	}
+}
+
+func TestSOCKS5AuthFailure(t *testing.T) {
+	proxy := startTestSOCKS5Proxy(t, "mesh", "hunter2")
+	proxy.User = url.UserPassword("mesh", "wrong")
+	if conn, err := dialSOCKS5UDP(proxy, 0); err == nil {
+		conn.Close()
+		t.Fatal("expected authentication error")
+	}
+
+	proxy.User = nil
+	if conn, err := dialSOCKS5UDP(proxy, 0); err == nil {
+		conn.Close()
+		t.Fatal("expected error without credentials")
+	}
+}
+
+func TestParseSOCKS5DatagramRejectsFragments(t *testing.T) {
+	packet := append(socks5DatagramHeader(&net.UDPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 6881}), 'x')
+	if payload, addr, err := parseSOCKS5Datagram(packet); err != nil || string(payload) != "x" || addr.Port != 6881 {
+		t.Fatalf("parse = %q, %v, %v", payload, addr, err)
+	}
+	packet[2] = 1
+	if _, _, err := parseSOCKS5Datagram(packet); err == nil {
+		t.Error("fragmented datagram accepted")
+	}
+	if _, _, err := parseSOCKS5Datagram(packet[:5]); err == nil {
+		t.Error("short datagram accepted")
+	}
+}
+
+// TestExchangeWithUnknownPeerViaSOCKS5 runs a full HELLO/REPLY exchange
+// through the proxy.
+func TestExchangeWithUnknownPeerViaSOCKS5(t *testing.T) {
+	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: testSecret, DisablePunching: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyA := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	keyB := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+
+	responderNode := &daemon.LocalNode{WGPubKey: keyB, MeshIP: "10.0.0.2"}
+	responderNode.SetEndpoint("198.51.100.2:51820")
+	responder := NewPeerExchange(cfg, responderNode, daemon.NewPeerStore())
+	responderConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	responder.conn = responderConn
+	responder.running = true
+	go responder.listenLoop()
+	defer responder.Stop()
+
+	initiatorNode := &daemon.LocalNode{WGPubKey: keyA, MeshIP: "10.0.0.1"}
+	initiatorNode.SetEndpoint("198.51.100.1:51820")
+	initiator := NewPeerExchange(cfg, initiatorNode, daemon.NewPeerStore())
+	proxyConn, err := dialSOCKS5UDP(startTestSOCKS5Proxy(t, "", ""), 0)
+	if err != nil {
+		t.Fatalf("dialSOCKS5UDP: %v", err)
+	}
+	initiator.proxyConn = proxyConn
+	initiator.running = true
+	go initiator.listenProxyLoop(proxyConn)
+	defer initiator.Stop()
+
+	info, err := initiator.ExchangeWithUnknownPeer(responderConn.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("ExchangeWithUnknownPeer: %v", err)
+	}
+	if info.WGPubKey != keyB {
+		t.Errorf("exchanged with %q, want responder", info.WGPubKey)
+	}
+	if _, ok := responder.peerStore.Get(keyA); !ok {
+		t.Error("responder did not learn the initiator")
+	}
+}
+
+// TestHandleProxiedMessage_IgnoresObservedEndpoint checks that a REPLY that
+// came through the proxy does not move our endpoint to the proxy's address.
+func TestHandleProxiedMessage_IgnoresObservedEndpoint(t *testing.T) {
+	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	localNode := &daemon.LocalNode{WGPubKey: "local-pubkey", MeshIP: "10.0.0.1"}
+	localNode.SetEndpoint("198.51.100.1:51820")
+	pe := NewPeerExchange(cfg, localNode, daemon.NewPeerStore())
+
+	remoteKey := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, 32))
+	reply := crypto.CreateAnnouncement(remoteKey, "10.0.0.2", "198.51.100.2:51820", false, nil, nil, "", "", "")
+	reply.ObservedEndpoint = "203.0.113.9:40000" // the proxy, as the responder saw it
+	data, err := crypto.SealEnvelope(crypto.MessageTypeReply, reply, cfg.Keys.GossipKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pe.handleProxiedMessage(data, &net.UDPAddr{IP: net.ParseIP("198.51.100.2"), Port: int(cfg.Keys.GossipPort)})
+
+	if _, ok := pe.peerStore.Get(remoteKey); !ok {
+		t.Error("proxied REPLY was not applied")
+	}
+	if got := localNode.GetEndpoint(); got != "198.51.100.1:51820" {
+		t.Errorf("endpoint = %q, want it unchanged", got)
+	}
+}
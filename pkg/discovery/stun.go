@@ -234,7 +234,13 @@ func STUNQuery(server string, localPort int, timeoutMs int) (net.IP, int, error)
 // DiscoverExternalEndpoint tries multiple STUN servers and returns the first
 // successful result. Returns the external IP and the mapped port.
 func DiscoverExternalEndpoint(localPort int) (net.IP, int, error) {
-	for _, server := range DefaultSTUNServers {
+	return DiscoverExternalEndpointWith(DefaultSTUNServers, localPort)
+}
+
+// DiscoverExternalEndpointWith is DiscoverExternalEndpoint over an explicit
+// server list (e.g. from --stun-server).
+func DiscoverExternalEndpointWith(servers []string, localPort int) (net.IP, int, error) {
+	for _, server := range servers {
 		ip, port, err := STUNQuery(server, localPort, 3000)
 		if err == nil {
 			return ip, port, nil
@@ -255,8 +261,36 @@ const (
 	// NATSymmetric means STUN servers saw different external mappings
 	// (endpoint-dependent). Direct hole-punching is unreliable; relay needed.
 	NATSymmetric NATType = "symmetric"
+	// NATNone means the STUN-reflected address is assigned to a local
+	// interface: the node is directly reachable and needs no keepalive.
+	NATNone NATType = "none"
 )
 
+// refineNATType upgrades a non-symmetric result to NATNone when the
+// reflexive IP is one of this host's own addresses.
+func refineNATType(natType NATType, ip net.IP) NATType {
+	if natType == NATSymmetric || ip == nil {
+		return natType
+	}
+	if isLocalInterfaceIP(ip) {
+		return NATNone
+	}
+	return natType
+}
+
+func isLocalInterfaceIP(ip net.IP) bool {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 // DetectNATType queries two STUN servers from the same local socket and
 // compares the reflected external addresses.
 //
@@ -0,0 +1,204 @@
+package discovery
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+// STUNResponder is a minimal RFC 5389 Binding responder. Introducer nodes run
+// one so that a mesh can detect external endpoints and NAT type without any
+// third-party STUN infrastructure: other nodes simply list two introducers
+// via --stun-server.
+type STUNResponder struct {
+	port  int
+	conn  *net.UDPConn
+	delay time.Duration // answer late on purpose (chaos mode)
+
+	mu      sync.RWMutex
+	running bool
+	stopCh  chan struct{}
+}
+
+// NewSTUNResponder creates a responder that will listen on the given UDP port.
+func NewSTUNResponder(port int) *STUNResponder {
+	return &STUNResponder{
+		port:   port,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start binds the UDP socket and begins answering Binding requests.
+func (s *STUNResponder) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running {
+		return fmt.Errorf("STUN responder already running")
+	}
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{Port: s.port})
+	if err != nil {
+		return fmt.Errorf("failed to listen on STUN port %d: %w", s.port, err)
+	}
+
+	s.conn = conn
+	s.running = true
+
+	go s.serveLoop()
+
+	log.Printf("[STUN] Responder listening on port %d", s.Port())
+	return nil
+}
+
+// Stop closes the responder socket.
+func (s *STUNResponder) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.running {
+		return
+	}
+	s.running = false
+	close(s.stopCh)
+	if s.conn != nil {
+		s.conn.Close()
+	}
+}
+
+// Port returns the bound UDP port (useful when started with port 0).
+func (s *STUNResponder) Port() int {
+	if s.conn == nil {
+		return s.port
+	}
+	return s.conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func (s *STUNResponder) serveLoop() {
+	buf := make([]byte, 1500)
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		default:
+		}
+
+		s.conn.SetReadDeadline(time.Now().Add(1 * time.Second))
+		n, remoteAddr, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			continue
+		}
+
+		txnID, ok := parseBindingRequest(buf[:n])
+		if !ok {
+			continue
+		}
+
+		resp := buildBindingResponse(txnID, remoteAddr)
+		if s.delay > 0 {
+			time.AfterFunc(s.delay, func() {
+				if _, err := s.conn.WriteToUDP(resp, remoteAddr); err != nil {
+					log.Printf("[STUN] Failed to answer %s: %v", remoteAddr, err)
+				}
+			})
+			continue
+		}
+		if _, err := s.conn.WriteToUDP(resp, remoteAddr); err != nil {
+			log.Printf("[STUN] Failed to answer %s: %v", remoteAddr, err)
+		}
+	}
+}
+
+// parseBindingRequest validates a STUN Binding Request and returns its
+// transaction ID. Attributes are ignored — a Binding request needs none.
+func parseBindingRequest(data []byte) ([12]byte, bool) {
+	var txnID [12]byte
+	if len(data) < stunHeaderSize {
+		return txnID, false
+	}
+	if binary.BigEndian.Uint16(data[0:2]) != stunBindingRequest {
+		return txnID, false
+	}
+	msgLen := int(binary.BigEndian.Uint16(data[2:4]))
+	if msgLen%4 != 0 || stunHeaderSize+msgLen > len(data) {
+		return txnID, false
+	}
+	if binary.BigEndian.Uint32(data[4:8]) != stunMagicCookie {
+		return txnID, false
+	}
+	copy(txnID[:], data[8:20])
+	return txnID, true
+}
+
+// buildBindingResponse creates a Binding Success Response carrying the
+// client's reflexive address as XOR-MAPPED-ADDRESS (RFC 5389 Section 15.2).
+func buildBindingResponse(txnID [12]byte, addr *net.UDPAddr) []byte {
+	var cookieBytes [4]byte
+	binary.BigEndian.PutUint32(cookieBytes[:], stunMagicCookie)
+
+	var family byte
+	var xorIP []byte
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		family = 0x01
+		xorIP = make([]byte, 4)
+		for i := 0; i < 4; i++ {
+			xorIP[i] = ip4[i] ^ cookieBytes[i]
+		}
+	} else {
+		family = 0x02
+		var xorKey [16]byte
+		copy(xorKey[0:4], cookieBytes[:])
+		copy(xorKey[4:16], txnID[:])
+		ip16 := addr.IP.To16()
+		xorIP = make([]byte, 16)
+		for i := 0; i < 16; i++ {
+			xorIP[i] = ip16[i] ^ xorKey[i]
+		}
+	}
+
+	valueLen := 4 + len(xorIP)
+	attr := make([]byte, 4+valueLen)
+	binary.BigEndian.PutUint16(attr[0:2], stunAttrXORMappedAddress)
+	binary.BigEndian.PutUint16(attr[2:4], uint16(valueLen))
+	attr[5] = family
+	binary.BigEndian.PutUint16(attr[6:8], uint16(addr.Port)^uint16(stunMagicCookie>>16))
+	copy(attr[8:], xorIP)
+
+	resp := make([]byte, stunHeaderSize+len(attr))
+	binary.BigEndian.PutUint16(resp[0:2], stunBindingResponse)
+	binary.BigEndian.PutUint16(resp[2:4], uint16(len(attr)))
+	binary.BigEndian.PutUint32(resp[4:8], stunMagicCookie)
+	copy(resp[8:20], txnID[:])
+	copy(resp[20:], attr)
+	return resp
+}
+
+// startSTUNResponder starts the embedded responder when this node is an
+// introducer with a STUN listen port configured. Failure is not fatal:
+// the node keeps working, peers just fall back to other STUN servers.
+func startSTUNResponder(config *daemon.Config) *STUNResponder {
+	if !config.Introducer || config.STUNListenPort <= 0 {
+		return nil
+	}
+	responder := NewSTUNResponder(config.STUNListenPort)
+	responder.delay = config.Chaos.STUNResponseDelay()
+	if err := responder.Start(); err != nil {
+		log.Printf("[STUN] Failed to start responder: %v", err)
+		return nil
+	}
+	return responder
+}
+
+// stunServersFor returns the STUN servers to query: the configured list if
+// any, otherwise DefaultSTUNServers.
+func stunServersFor(config *daemon.Config) []string {
+	if len(config.STUNServers) > 0 {
+		return config.STUNServers
+	}
+	return DefaultSTUNServers
+}
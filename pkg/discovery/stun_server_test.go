@@ -0,0 +1,83 @@
+package discovery
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSTUNResponderAnswersBindingRequest(t *testing.T) {
+	responder := NewSTUNResponder(0)
+	if err := responder.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer responder.Stop()
+
+	server := (&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: responder.Port()}).String()
+	ip, port, err := STUNQuery(server, 0, 2000)
+	if err != nil {
+		t.Fatalf("STUNQuery() error = %v", err)
+	}
+	if !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("reflected IP = %v, want 127.0.0.1", ip)
+	}
+	if port == 0 {
+		t.Error("reflected port should be non-zero")
+	}
+}
+
+func TestSTUNResponderChaosDelay(t *testing.T) {
+	responder := NewSTUNResponder(0)
+	responder.delay = 300 * time.Millisecond
+	if err := responder.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer responder.Stop()
+
+	server := (&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: responder.Port()}).String()
+	start := time.Now()
+	if _, _, err := STUNQuery(server, 0, 2000); err != nil {
+		t.Fatalf("STUNQuery() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < responder.delay {
+		t.Errorf("answered after %v, want at least %v", elapsed, responder.delay)
+	}
+}
+
+func TestBuildBindingResponseIPv6(t *testing.T) {
+	var txnID [12]byte
+	copy(txnID[:], "abcdefghijkl")
+	addr := &net.UDPAddr{IP: net.ParseIP("2001:db8::42"), Port: 51820}
+
+	ip, port, err := parseBindingResponse(buildBindingResponse(txnID, addr), txnID)
+	if err != nil {
+		t.Fatalf("parseBindingResponse() error = %v", err)
+	}
+	if !ip.Equal(addr.IP) || port != addr.Port {
+		t.Fatalf("got %v:%d, want %v:%d", ip, port, addr.IP, addr.Port)
+	}
+}
+
+func TestParseBindingRequest(t *testing.T) {
+	req := buildBindingRequest()
+	if _, ok := parseBindingRequest(req); !ok {
+		t.Fatal("valid binding request rejected")
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "short", data: req[:10]},
+		{name: "wrong type", data: append([]byte{0x01, 0x01}, req[2:]...)},
+		{name: "bad cookie", data: append(append([]byte{}, req[:4]...), append([]byte{0, 0, 0, 0}, req[8:]...)...)},
+		{name: "length overrun", data: append([]byte{0x00, 0x01, 0x00, 0x08}, req[4:]...)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := parseBindingRequest(tt.data); ok {
+				t.Fatal("expected request to be rejected")
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+// Package firewall restricts what mesh peers can reach on the local host.
+//
+// A Policy lists the TCP/UDP ports (and whether ICMP) peers may use on the
+// WireGuard interface; everything else arriving on that interface is dropped.
+// Rules are installed with nftables when available, otherwise with
+// iptables/ip6tables, in objects owned by wgmesh so they can be removed
+// cleanly without touching the host's own ruleset.
+package firewall
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Backend identifies the tool used to install rules.
+type Backend string
+
+const (
+	BackendNFTables Backend = "nftables"
+	BackendIPTables Backend = "iptables"
+)
+
+// Policy describes the inbound traffic allowed on the mesh interface.
+// Established/related traffic is always allowed.
+type Policy struct {
+	Interface string
+	TCPPorts  []int
+	UDPPorts  []int
+	AllowICMP bool
+}
+
+// runFunc runs a command, feeding stdin if non-empty, and returns its
+// combined output.
+type runFunc func(stdin string, name string, args ...string) ([]byte, error)
+
+// Firewall installs and removes a Policy.
+type Firewall struct {
+	policy   Policy
+	backend  Backend
+	run      runFunc
+	lookPath func(file string) (string, error)
+}
+
+// New validates the policy and picks nftables if the nft binary is present,
+// falling back to iptables.
+func New(policy Policy) (*Firewall, error) {
+	if policy.Interface == "" {
+		return nil, fmt.Errorf("interface name is required")
+	}
+	for _, p := range append(append([]int(nil), policy.TCPPorts...), policy.UDPPorts...) {
+		if p <= 0 || p > 65535 {
+			return nil, fmt.Errorf("invalid port %d", p)
+		}
+	}
+	policy.TCPPorts = uniquePorts(policy.TCPPorts)
+	policy.UDPPorts = uniquePorts(policy.UDPPorts)
+
+	backend := BackendNFTables
+	if _, err := exec.LookPath("nft"); err != nil {
+		if _, err := exec.LookPath("iptables"); err != nil {
+			return nil, fmt.Errorf("neither nft nor iptables found in PATH")
+		}
+		backend = BackendIPTables
+	}
+	return &Firewall{policy: policy, backend: backend, run: execRun, lookPath: exec.LookPath}, nil
+}
+
+// Backend returns the tool the firewall uses.
+func (f *Firewall) Backend() Backend {
+	return f.backend
+}
+
+// Apply installs the policy, replacing any rules from a previous run.
+func (f *Firewall) Apply() error {
+	if f.backend == BackendNFTables {
+		if out, err := f.run(NFTablesRuleset(f.policy), "nft", "-f", "-"); err != nil {
+			return fmt.Errorf("nft: %s: %w", strings.TrimSpace(string(out)), err)
+		}
+		return nil
+	}
+
+	chain := IPTablesChain(f.policy.Interface)
+	for _, tool := range []string{"iptables", "ip6tables"} {
+		if tool == "ip6tables" {
+			if _, err := f.lookPath(tool); err != nil {
+				continue
+			}
+		}
+		// -N fails if the chain exists; flushing afterwards handles both.
+		_, _ = f.run("", tool, "-N", chain)
+		if out, err := f.run("", tool, "-F", chain); err != nil {
+			return fmt.Errorf("%s: flush %s: %s: %w", tool, chain, strings.TrimSpace(string(out)), err)
+		}
+		for _, rule := range IPTablesRules(f.policy, tool == "ip6tables") {
+			args := append([]string{"-A", chain}, rule...)
+			if out, err := f.run("", tool, args...); err != nil {
+				return fmt.Errorf("%s: %s: %s: %w", tool, strings.Join(args, " "), strings.TrimSpace(string(out)), err)
+			}
+		}
+		jump := []string{"INPUT", "-i", f.policy.Interface, "-j", chain}
+		if _, err := f.run("", tool, append([]string{"-C"}, jump...)...); err != nil {
+			args := append([]string{"-I", "INPUT", "1"}, jump[1:]...)
+			if out, err := f.run("", tool, args...); err != nil {
+				return fmt.Errorf("%s: %s: %s: %w", tool, strings.Join(args, " "), strings.TrimSpace(string(out)), err)
+			}
+		}
+	}
+	return nil
+}
+
+// Remove deletes everything Apply installed. Missing rules are not an
+// error, so Remove is safe to call after a failed or partial Apply.
+func (f *Firewall) Remove() error {
+	if f.backend == BackendNFTables {
+		table := NFTablesTable(f.policy.Interface)
+		if out, err := f.run("", "nft", "delete", "table", "inet", table); err != nil && !strings.Contains(string(out), "No such file") {
+			return fmt.Errorf("nft: delete table %s: %s: %w", table, strings.TrimSpace(string(out)), err)
+		}
+		return nil
+	}
+
+	chain := IPTablesChain(f.policy.Interface)
+	for _, tool := range []string{"iptables", "ip6tables"} {
+		_, _ = f.run("", tool, "-D", "INPUT", "-i", f.policy.Interface, "-j", chain)
+		_, _ = f.run("", tool, "-F", chain)
+		_, _ = f.run("", tool, "-X", chain)
+	}
+	return nil
+}
+
+// NFTablesTable names the table holding the policy for iface.
+func NFTablesTable(iface string) string {
+	return "wgmesh_fw_" + sanitize(iface, '_')
+}
+
+// IPTablesChain names the chain holding the policy for iface.
+func IPTablesChain(iface string) string {
+	return "WGMESH-FW-" + sanitize(iface, '-')
+}
+
+// NFTablesRuleset renders an nft script that atomically replaces the
+// policy table. Declaring the table before deleting it keeps the script
+// valid on first use.
+func NFTablesRuleset(p Policy) string {
+	table := NFTablesTable(p.Interface)
+	iif := fmt.Sprintf("iifname %q", p.Interface)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "table inet %s\n", table)
+	fmt.Fprintf(&sb, "delete table inet %s\n", table)
+	fmt.Fprintf(&sb, "table inet %s {\n", table)
+	sb.WriteString("\tchain input {\n")
+	sb.WriteString("\t\ttype filter hook input priority filter; policy accept;\n")
+	fmt.Fprintf(&sb, "\t\t%s ct state established,related accept\n", iif)
+	if p.AllowICMP {
+		fmt.Fprintf(&sb, "\t\t%s meta l4proto { icmp, ipv6-icmp } accept\n", iif)
+	}
+	if len(p.TCPPorts) > 0 {
+		fmt.Fprintf(&sb, "\t\t%s tcp dport { %s } accept\n", iif, joinPorts(p.TCPPorts))
+	}
+	if len(p.UDPPorts) > 0 {
+		fmt.Fprintf(&sb, "\t\t%s udp dport { %s } accept\n", iif, joinPorts(p.UDPPorts))
+	}
+	fmt.Fprintf(&sb, "\t\t%s drop\n", iif)
+	sb.WriteString("\t}\n}\n")
+	return sb.String()
+}
+
+// IPTablesRules returns the rules (arguments after "-A <chain>") for the
+// policy chain, for iptables or, with ipv6, ip6tables.
+func IPTablesRules(p Policy, ipv6 bool) [][]string {
+	rules := [][]string{
+		{"-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"},
+	}
+	if p.AllowICMP {
+		proto := "icmp"
+		if ipv6 {
+			proto = "ipv6-icmp"
+		}
+		rules = append(rules, []string{"-p", proto, "-j", "ACCEPT"})
+	}
+	for _, proto := range []struct {
+		name  string
+		ports []int
+	}{{"tcp", p.TCPPorts}, {"udp", p.UDPPorts}} {
+		// multiport takes at most 15 ports per rule.
+		for i := 0; i < len(proto.ports); i += 15 {
+			end := min(i+15, len(proto.ports))
+			rules = append(rules, []string{"-p", proto.name, "-m", "multiport", "--dports", joinPortsComma(proto.ports[i:end]), "-j", "ACCEPT"})
+		}
+	}
+	return append(rules, []string{"-j", "DROP"})
+}
+
+// ParseAllowList parses a comma-separated list of "tcp/PORT", "udp/PORT"
+// or bare "PORT" (TCP) entries.
+func ParseAllowList(s string) (tcp, udp []int, err error) {
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		proto, portStr := "tcp", entry
+		if p, rest, ok := strings.Cut(entry, "/"); ok {
+			proto, portStr = strings.ToLower(p), rest
+		}
+		port, convErr := strconv.Atoi(portStr)
+		if convErr != nil || port <= 0 || port > 65535 {
+			return nil, nil, fmt.Errorf("invalid port in %q", entry)
+		}
+		switch proto {
+		case "tcp":
+			tcp = append(tcp, port)
+		case "udp":
+			udp = append(udp, port)
+		default:
+			return nil, nil, fmt.Errorf("unknown protocol in %q (want tcp or udp)", entry)
+		}
+	}
+	return tcp, udp, nil
+}
+
+func execRun(stdin string, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	return cmd.CombinedOutput()
+}
+
+func uniquePorts(ports []int) []int {
+	seen := make(map[int]struct{}, len(ports))
+	out := make([]int, 0, len(ports))
+	for _, p := range ports {
+		if _, ok := seen[p]; ok {
+			continue
+		}
+		seen[p] = struct{}{}
+		out = append(out, p)
+	}
+	sort.Ints(out)
+	return out
+}
+
+func joinPorts(ports []int) string {
+	strs := make([]string, len(ports))
+	for i, p := range ports {
+		strs[i] = strconv.Itoa(p)
+	}
+	return strings.Join(strs, ", ")
+}
+
+func joinPortsComma(ports []int) string {
+	return strings.ReplaceAll(joinPorts(ports), " ", "")
+}
+
+func sanitize(s string, repl byte) string {
+	var sb strings.Builder
+	for _, r := range s {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteByte(repl)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,148 @@
+package firewall
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNFTablesRuleset(t *testing.T) {
+	got := NFTablesRuleset(Policy{
+		Interface: "wg0",
+		TCPPorts:  []int{22, 53820},
+		UDPPorts:  []int{51821},
+		AllowICMP: true,
+	})
+
+	for _, want := range []string{
+		"table inet wgmesh_fw_wg0\ndelete table inet wgmesh_fw_wg0\ntable inet wgmesh_fw_wg0 {",
+		"type filter hook input priority filter; policy accept;",
+		`iifname "wg0" ct state established,related accept`,
+		`iifname "wg0" meta l4proto { icmp, ipv6-icmp } accept`,
+		`iifname "wg0" tcp dport { 22, 53820 } accept`,
+		`iifname "wg0" udp dport { 51821 } accept`,
+		`iifname "wg0" drop`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ruleset missing %q:\n%s", want, got)
+		}
+	}
+
+	bare := NFTablesRuleset(Policy{Interface: "wg0"})
+	if strings.Contains(bare, "icmp") || strings.Contains(bare, "dport") {
+		t.Errorf("empty policy should only allow established traffic:\n%s", bare)
+	}
+}
+
+func TestIPTablesRules(t *testing.T) {
+	ports := make([]int, 17)
+	for i := range ports {
+		ports[i] = 1000 + i
+	}
+	rules := IPTablesRules(Policy{Interface: "wg0", TCPPorts: ports, UDPPorts: []int{51821}, AllowICMP: true}, true)
+
+	var joined []string
+	for _, r := range rules {
+		joined = append(joined, strings.Join(r, " "))
+	}
+	want := []string{
+		"-m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT",
+		"-p ipv6-icmp -j ACCEPT",
+		"-p tcp -m multiport --dports 1000,1001,1002,1003,1004,1005,1006,1007,1008,1009,1010,1011,1012,1013,1014 -j ACCEPT",
+		"-p tcp -m multiport --dports 1015,1016 -j ACCEPT",
+		"-p udp -m multiport --dports 51821 -j ACCEPT",
+		"-j DROP",
+	}
+	if strings.Join(joined, "\n") != strings.Join(want, "\n") {
+		t.Errorf("rules:\n%s\nwant:\n%s", strings.Join(joined, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseAllowList(t *testing.T) {
+	tests := []struct {
+		in       string
+		tcp, udp []int
+		wantErr  bool
+	}{
+		{in: "", tcp: nil, udp: nil},
+		{in: "22, tcp/443,UDP/53", tcp: []int{22, 443}, udp: []int{53}},
+		{in: "icmp/1", wantErr: true},
+		{in: "tcp/0", wantErr: true},
+		{in: "tcp/http", wantErr: true},
+	}
+	for _, tt := range tests {
+		tcp, udp, err := ParseAllowList(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseAllowList(%q): expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseAllowList(%q): %v", tt.in, err)
+			continue
+		}
+		if joinPorts(tcp) != joinPorts(tt.tcp) || joinPorts(udp) != joinPorts(tt.udp) {
+			t.Errorf("ParseAllowList(%q) = %v, %v; want %v, %v", tt.in, tcp, udp, tt.tcp, tt.udp)
+		}
+	}
+}
+
+func TestIPTablesApplyAndRemove(t *testing.T) {
+	var calls []string
+	fw := &Firewall{
+		policy:  Policy{Interface: "wg0", TCPPorts: []int{22}},
+		backend: BackendIPTables,
+		run: func(stdin, name string, args ...string) ([]byte, error) {
+			call := name + " " + strings.Join(args, " ")
+			calls = append(calls, call)
+			if strings.Contains(call, " -C INPUT") {
+				return nil, errors.New("no such rule")
+			}
+			return nil, nil
+		},
+		lookPath: func(string) (string, error) { return "", errors.New("not found") },
+	}
+
+	if err := fw.Apply(); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	want := []string{
+		"iptables -N WGMESH-FW-wg0",
+		"iptables -F WGMESH-FW-wg0",
+		"iptables -A WGMESH-FW-wg0 -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT",
+		"iptables -A WGMESH-FW-wg0 -p tcp -m multiport --dports 22 -j ACCEPT",
+		"iptables -A WGMESH-FW-wg0 -j DROP",
+		"iptables -C INPUT -i wg0 -j WGMESH-FW-wg0",
+		"iptables -I INPUT 1 -i wg0 -j WGMESH-FW-wg0",
+	}
+	if strings.Join(calls, "\n") != strings.Join(want, "\n") {
+		t.Errorf("apply commands:\n%s\nwant:\n%s", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+
+	calls = nil
+	if err := fw.Remove(); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if len(calls) == 0 || calls[0] != "iptables -D INPUT -i wg0 -j WGMESH-FW-wg0" {
+		t.Errorf("remove should unhook the chain first, got %v", calls)
+	}
+}
+
+func TestNFTablesApplyFeedsRuleset(t *testing.T) {
+	var gotStdin string
+	fw := &Firewall{
+		policy:  Policy{Interface: "wg0", UDPPorts: []int{51821}},
+		backend: BackendNFTables,
+		run: func(stdin, name string, args ...string) ([]byte, error) {
+			gotStdin = stdin
+			return nil, nil
+		},
+	}
+	if err := fw.Apply(); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if gotStdin != NFTablesRuleset(fw.policy) {
+		t.Errorf("nft got unexpected ruleset:\n%s", gotStdin)
+	}
+}
@@ -0,0 +1,173 @@
+// Package kube talks to the Kubernetes API server from a pod, with the
+// pod's service account, for the two things a wgmesh DaemonSet needs:
+// reading its Node's pod CIDRs and annotating the Node with its mesh
+// address. It uses plain HTTPS so wgmesh does not depend on client-go.
+package kube
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ServiceAccountDir is where Kubernetes mounts a pod's service account.
+const ServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Annotations wgmesh sets on its Node.
+const (
+	AnnotationMeshIP = "wgmesh.io/mesh-ip"
+	AnnotationPubKey = "wgmesh.io/pubkey"
+)
+
+const requestTimeout = 10 * time.Second
+
+// Client calls the Kubernetes API server.
+type Client struct {
+	baseURL   string
+	tokenPath string
+	http      *http.Client
+}
+
+// InCluster returns a client for the API server the pod runs under, found
+// through KUBERNETES_SERVICE_HOST/PORT and authenticated with the mounted
+// service account token.
+func InCluster() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes pod (KUBERNETES_SERVICE_HOST is unset)")
+	}
+	ca, err := os.ReadFile(filepath.Join(ServiceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates in the service account CA")
+	}
+	transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}}
+	return NewClient("https://"+net.JoinHostPort(host, port), filepath.Join(ServiceAccountDir, "token"), &http.Client{Transport: transport}), nil
+}
+
+// NewClient returns a client for the API server at baseURL that sends the
+// bearer token read from tokenPath, if any, with every request.
+func NewClient(baseURL, tokenPath string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), tokenPath: tokenPath, http: httpClient}
+}
+
+// Node is the part of a Node object wgmesh uses.
+type Node struct {
+	Name        string
+	PodCIDRs    []string
+	Annotations map[string]string
+}
+
+type nodeObject struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		PodCIDR  string   `json:"podCIDR"`
+		PodCIDRs []string `json:"podCIDRs"`
+	} `json:"spec"`
+}
+
+// GetNode fetches the Node called name.
+func (c *Client) GetNode(ctx context.Context, name string) (*Node, error) {
+	var obj nodeObject
+	if err := c.do(ctx, http.MethodGet, nodePath(name), "", nil, &obj); err != nil {
+		return nil, err
+	}
+	node := &Node{Name: obj.Metadata.Name, PodCIDRs: obj.Spec.PodCIDRs, Annotations: obj.Metadata.Annotations}
+	// Clusters older than dual-stack only fill in the singular field.
+	if len(node.PodCIDRs) == 0 && obj.Spec.PodCIDR != "" {
+		node.PodCIDRs = []string{obj.Spec.PodCIDR}
+	}
+	return node, nil
+}
+
+// AnnotateNode sets annotations on the Node called name, leaving its other
+// annotations alone. An empty value removes the annotation.
+func (c *Client) AnnotateNode(ctx context.Context, name string, annotations map[string]string) error {
+	values := make(map[string]interface{}, len(annotations))
+	for key, value := range annotations {
+		if value == "" {
+			values[key] = nil
+		} else {
+			values[key] = value
+		}
+	}
+	patch := map[string]interface{}{"metadata": map[string]interface{}{"annotations": values}}
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPatch, nodePath(name), "application/merge-patch+json", body, nil)
+}
+
+func nodePath(name string) string {
+	return "/api/v1/nodes/" + url.PathEscape(name)
+}
+
+// do sends one request and decodes a JSON response into out when it is
+// not nil.
+func (c *Client) do(ctx context.Context, method, path, contentType string, body []byte, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	// Bound service account tokens are rotated on disk, so read it fresh.
+	if c.tokenPath != "" {
+		token, err := os.ReadFile(c.tokenPath)
+		if err != nil {
+			return fmt.Errorf("failed to read service account token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		var status struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &status) == nil && status.Message != "" {
+			return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, status.Message)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s %s: invalid response: %w", method, path, err)
+	}
+	return nil
+}
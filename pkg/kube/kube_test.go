@@ -0,0 +1,101 @@
+package kube
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetNode(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		want []string
+	}{
+		{name: "dual-stack", spec: `{"podCIDR":"10.244.1.0/24","podCIDRs":["10.244.1.0/24","fd00:10:244:1::/64"]}`, want: []string{"10.244.1.0/24", "fd00:10:244:1::/64"}},
+		{name: "podCIDR only", spec: `{"podCIDR":"10.244.2.0/24"}`, want: []string{"10.244.2.0/24"}},
+		{name: "none", spec: `{}`, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != "/api/v1/nodes/node-a" {
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+				}
+				io.WriteString(w, `{"metadata":{"name":"node-a","annotations":{"x":"y"}},"spec":`+tt.spec+`}`)
+			}))
+			defer srv.Close()
+
+			node, err := NewClient(srv.URL, "", nil).GetNode(context.Background(), "node-a")
+			if err != nil {
+				t.Fatalf("GetNode: %v", err)
+			}
+			if node.Name != "node-a" || node.Annotations["x"] != "y" {
+				t.Errorf("node = %+v", node)
+			}
+			if !reflect.DeepEqual(node.PodCIDRs, tt.want) {
+				t.Errorf("PodCIDRs = %v, want %v", node.PodCIDRs, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnotateNode(t *testing.T) {
+	tokenPath := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenPath, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	var patch map[string]map[string]map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("method = %s, want PATCH", r.Method)
+		}
+		if got := r.Header.Get("Content-Type"); got != "application/merge-patch+json" {
+			t.Errorf("Content-Type = %q", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer s3cret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			t.Errorf("decode patch: %v", err)
+		}
+		io.WriteString(w, `{}`)
+	}))
+	defer srv.Close()
+
+	err := NewClient(srv.URL, tokenPath, nil).AnnotateNode(context.Background(), "node-a", map[string]string{
+		AnnotationMeshIP: "10.99.0.1",
+		AnnotationPubKey: "",
+	})
+	if err != nil {
+		t.Fatalf("AnnotateNode: %v", err)
+	}
+	annotations := patch["metadata"]["annotations"]
+	if annotations[AnnotationMeshIP] != "10.99.0.1" {
+		t.Errorf("mesh IP annotation = %v", annotations[AnnotationMeshIP])
+	}
+	if value, ok := annotations[AnnotationPubKey]; !ok || value != nil {
+		t.Errorf("empty value should patch to null, got %v (present %v)", value, ok)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `{"kind":"Status","message":"nodes \"node-a\" is forbidden"}`)
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(srv.URL, "", nil).GetNode(context.Background(), "node-a")
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "is forbidden") {
+		t.Errorf("err = %v, want the status and API message", err)
+	}
+}
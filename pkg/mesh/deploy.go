@@ -2,8 +2,14 @@ package mesh
 
 import (
 	"fmt"
+	"maps"
 	"net"
 	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/atvirokodosprendimai/wgmesh/pkg/ssh"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
@@ -13,142 +19,377 @@ type WireGuardConfig = wireguard.FullConfig
 type WGInterface = wireguard.WGInterface
 type WGPeer = wireguard.WGPeer
 
-func (m *Mesh) Deploy() error {
-	// Validate groups and policies if access control is enabled
-	if m.IsAccessControlEnabled() {
-		fmt.Println("Validating access control configuration...")
+// DefaultDeployParallel is how many nodes Deploy configures at once.
+const DefaultDeployParallel = 8
 
-		if err := m.ValidateGroups(); err != nil {
-			return fmt.Errorf("groups validation failed: %w", err)
-		}
+// DeployOptions selects the nodes Deploy configures and how.
+type DeployOptions struct {
+	Parallel int      // nodes deployed concurrently (0 = DefaultDeployParallel)
+	Only     []string // deploy only these hostnames
+	Limit    []string // deploy only hostnames matching one of these glob patterns
+	DryRun   bool     // show each node's config diff without changing anything
+}
+
+// DeployResult is the outcome of deploying to one node.
+type DeployResult struct {
+	Hostname string
+	Changed  bool   // peers, routes or the config file changed (or would, in a dry run)
+	Diff     string // dry run: the wg-quick config diff, keys redacted
+	Err      error
+}
+
+// Deploy pushes the WireGuard configuration to the selected nodes over SSH,
+// several at a time. A failing node does not stop the others; the returned
+// error reports how many failed, and the results say which and why.
+func (m *Mesh) Deploy(opts DeployOptions) ([]DeployResult, error) {
+	if err := m.validateAccessControl(); err != nil {
+		return nil, err
+	}
 
-		if err := m.ValidatePolicies(); err != nil {
-			return fmt.Errorf("policies validation failed: %w", err)
+	targets, err := m.deployTargets(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	parallel := opts.Parallel
+	if parallel <= 0 {
+		parallel = DefaultDeployParallel
+	}
+
+	// Every node's endpoint goes into its peers' configs, so detect them all
+	// even when deploying to a subset.
+	m.detectEndpoints(parallel)
+
+	verb := "Deploying to"
+	if opts.DryRun {
+		verb = "Dry run for"
+	}
+	fmt.Printf("%s %d of %d nodes (%d at a time)\n\n", verb, len(targets), len(m.Nodes), parallel)
+
+	var printMu sync.Mutex
+	results := make([]DeployResult, len(targets))
+	forEachParallel(len(targets), parallel, func(i int) {
+		hostname := targets[i]
+		if opts.DryRun {
+			results[i] = m.dryRunNode(hostname)
+		} else {
+			results[i] = m.deployNode(hostname)
 		}
 
-		// Warn if groups exist without policies
-		if m.HasGroups() && !m.HasPolicies() {
-			fmt.Println("Warning: Groups are defined but no access policies exist.")
-			fmt.Println("         Nodes in groups will have no connectivity unless policies are added.")
+		printMu.Lock()
+		defer printMu.Unlock()
+		printDeployResult(results[i], opts.DryRun)
+	})
+
+	failed := 0
+	fmt.Println("Summary:")
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Printf("  ✗ %-24s %v\n", r.Hostname, r.Err)
+		case r.Changed && opts.DryRun:
+			fmt.Printf("  ~ %-24s would change\n", r.Hostname)
+		case r.Changed:
+			fmt.Printf("  ✓ %-24s updated\n", r.Hostname)
+		default:
+			fmt.Printf("  ✓ %-24s up to date\n", r.Hostname)
 		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("deployment failed on %d of %d nodes", failed, len(results))
+	}
+	return results, nil
+}
 
-		fmt.Println("Access control configuration valid.")
+// validateAccessControl checks groups and policies, if access control is
+// enabled, before any config is generated from them.
+func (m *Mesh) validateAccessControl() error {
+	if !m.IsAccessControlEnabled() {
+		return nil
 	}
+	fmt.Println("Validating access control configuration...")
 
-	if err := m.detectEndpoints(); err != nil {
-		return fmt.Errorf("failed to detect endpoints: %w", err)
+	if err := m.ValidateGroups(); err != nil {
+		return fmt.Errorf("groups validation failed: %w", err)
 	}
 
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	if err := m.ValidatePolicies(); err != nil {
+		return fmt.Errorf("policies validation failed: %w", err)
+	}
 
-	for hostname, node := range m.Nodes {
-		fmt.Printf("Deploying to %s...\n", hostname)
+	// Warn if groups exist without policies
+	if m.HasGroups() && !m.HasPolicies() {
+		fmt.Println("Warning: Groups are defined but no access policies exist.")
+		fmt.Println("         Nodes in groups will have no connectivity unless policies are added.")
+	}
 
-		client, err := ssh.NewClient(node.SSHHost, node.SSHPort)
-		if err != nil {
-			return fmt.Errorf("failed to connect to %s: %w", hostname, err)
+	fmt.Println("Access control configuration valid.")
+	return nil
+}
+
+// deployTargets returns the hostnames selected by opts, sorted.
+func (m *Mesh) deployTargets(opts DeployOptions) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, pattern := range opts.Limit {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid limit pattern %q: %w", pattern, err)
+		}
+	}
+	only := make(map[string]bool, len(opts.Only))
+	for _, hostname := range opts.Only {
+		if _, ok := m.Nodes[hostname]; !ok {
+			return nil, fmt.Errorf("unknown node %q", hostname)
 		}
+		only[hostname] = true
+	}
 
-		if err := ssh.EnsureWireGuardInstalled(client); err != nil {
-			client.Close()
-			return fmt.Errorf("failed to ensure WireGuard on %s: %w", hostname, err)
+	var targets []string
+	for hostname := range m.Nodes {
+		if len(only) > 0 && !only[hostname] {
+			continue
+		}
+		if len(opts.Limit) > 0 && !matchesAny(hostname, opts.Limit) {
+			continue
 		}
+		targets = append(targets, hostname)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no nodes selected for deployment")
+	}
+	sort.Strings(targets)
+	return targets, nil
+}
 
-		config := m.generateConfigForNode(node)
-		desiredRoutes := m.collectAllRoutesForNode(node)
+func matchesAny(hostname string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, hostname); ok {
+			return true
+		}
+	}
+	return false
+}
 
-		currentConfig, err := wireguard.GetCurrentConfig(client, m.InterfaceName)
-		if err != nil {
-			fmt.Printf("  No existing config, applying fresh persistent configuration\n")
-			if err := wireguard.ApplyPersistentConfig(client, m.InterfaceName, config, desiredRoutes); err != nil {
-				client.Close()
-				return fmt.Errorf("failed to apply config to %s: %w", hostname, err)
-			}
-		} else {
-			diff := wireguard.CalculateDiff(currentConfig, wireguard.FullConfigToConfig(config))
-			if diff.HasChanges() {
-				fmt.Printf("  Applying changes with persistent configuration\n")
-				if err := wireguard.UpdatePersistentConfig(client, m.InterfaceName, config, desiredRoutes, diff); err != nil {
-					client.Close()
-					return fmt.Errorf("failed to update config on %s: %w", hostname, err)
-				}
-			} else {
-				fmt.Printf("  No WireGuard peer changes needed\n")
+// forEachParallel calls fn(0..n-1) from at most parallel goroutines and
+// waits for all of them.
+func forEachParallel(n, parallel int, fn func(i int)) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallel && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
 			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
 
-			// Always check and sync routes
-			if err := m.syncRoutesForNode(client, node, desiredRoutes); err != nil {
-				client.Close()
-				return fmt.Errorf("failed to sync routes on %s: %w", hostname, err)
-			}
+func printDeployResult(r DeployResult, dryRun bool) {
+	switch {
+	case r.Err != nil:
+		fmt.Printf("[%s] ✗ %v\n\n", r.Hostname, r.Err)
+	case dryRun && r.Diff == "":
+		fmt.Printf("[%s] No changes\n\n", r.Hostname)
+	case dryRun:
+		fmt.Printf("[%s] Config diff:\n%s\n", r.Hostname, r.Diff)
+	default:
+		fmt.Printf("[%s] ✓ Deployed successfully\n\n", r.Hostname)
+	}
+}
 
-			// Always ensure config file is up to date
-			configContent := wireguard.GenerateWgQuickConfig(config, desiredRoutes)
-			configPath := fmt.Sprintf("/etc/wireguard/%s.conf", m.InterfaceName)
-			if err := client.WriteFile(configPath, []byte(configContent), 0600); err != nil {
-				fmt.Printf("  Warning: failed to update config file: %v\n", err)
-			}
-		}
+// deployNode installs WireGuard if needed and brings the node's peers,
+// routes and wg-quick config in line with the mesh state.
+func (m *Mesh) deployNode(hostname string) DeployResult {
+	result := DeployResult{Hostname: hostname}
+	m.mu.RLock()
+	node := m.Nodes[hostname]
+	m.mu.RUnlock()
 
-		client.Close()
-		fmt.Printf("  ✓ Deployed successfully\n\n")
+	client, err := connect(node)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to connect: %w", err)
+		return result
 	}
+	defer client.Close()
 
-	return nil
-}
+	if err := ssh.EnsureWireGuardInstalled(client); err != nil {
+		result.Err = fmt.Errorf("failed to ensure WireGuard: %w", err)
+		return result
+	}
 
-func (m *Mesh) detectEndpoints() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+	config := m.generateConfigForNode(node)
+	desiredRoutes := m.collectAllRoutesForNode(node)
 
-	for hostname, node := range m.Nodes {
-		if node.IsLocal {
-			// For local node, get hostname directly
-			if node.ActualHostname == "" {
-				if h, err := os.Hostname(); err == nil {
-					node.ActualHostname = h
-				}
-			}
-			continue
+	currentConfig, err := wireguard.GetCurrentConfig(client, m.InterfaceName)
+	if err != nil {
+		fmt.Printf("[%s] No existing config, applying fresh persistent configuration\n", hostname)
+		if err := wireguard.ApplyPersistentConfig(client, m.InterfaceName, config, desiredRoutes); err != nil {
+			result.Err = fmt.Errorf("failed to apply config: %w", err)
+			return result
 		}
+		result.Changed = true
+		return result
+	}
 
-		client, err := ssh.NewClient(node.SSHHost, node.SSHPort)
-		if err != nil {
-			return fmt.Errorf("failed to connect to %s: %w", hostname, err)
+	diff := wireguard.CalculateDiff(currentConfig, wireguard.FullConfigToConfig(config))
+	if diff.HasChanges() {
+		fmt.Printf("[%s] Applying changes with persistent configuration\n", hostname)
+		if err := wireguard.UpdatePersistentConfig(client, m.InterfaceName, config, desiredRoutes, diff); err != nil {
+			result.Err = fmt.Errorf("failed to update config: %w", err)
+			return result
 		}
+		result.Changed = true
+	} else {
+		fmt.Printf("[%s] No WireGuard peer changes needed\n", hostname)
+	}
 
-		// Collect hostname and FQDN
-		if actualHostname, err := ssh.GetHostname(client); err == nil {
-			node.ActualHostname = actualHostname
-		} else {
-			fmt.Printf("Warning: failed to get hostname for %s: %v\n", hostname, err)
-		}
+	// Always check and sync routes
+	routesChanged, err := m.syncRoutesForNode(client, node, desiredRoutes)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to sync routes: %w", err)
+		return result
+	}
+	result.Changed = result.Changed || routesChanged
+
+	// Always ensure config file is up to date
+	configContent := wireguard.GenerateWgQuickConfig(config, desiredRoutes)
+	configPath := fmt.Sprintf("/etc/wireguard/%s.conf", m.InterfaceName)
+	if err := client.WriteFile(configPath, []byte(configContent), 0600); err != nil {
+		fmt.Printf("[%s] Warning: failed to update config file: %v\n", hostname, err)
+	}
+	return result
+}
+
+// dryRunNode reports what deployNode would change without changing it: a
+// diff of the wg-quick config file, plus the live peer and route changes.
+func (m *Mesh) dryRunNode(hostname string) DeployResult {
+	result := DeployResult{Hostname: hostname}
+	m.mu.RLock()
+	node := m.Nodes[hostname]
+	m.mu.RUnlock()
+
+	client, err := connect(node)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to connect: %w", err)
+		return result
+	}
+	defer client.Close()
+
+	config := m.generateConfigForNode(node)
+	desiredRoutes := m.collectAllRoutesForNode(node)
+	configPath := fmt.Sprintf("/etc/wireguard/%s.conf", m.InterfaceName)
+
+	current, err := client.Run(fmt.Sprintf("cat %s 2>/dev/null || true", configPath))
+	if err != nil {
+		result.Err = fmt.Errorf("failed to read %s: %w", configPath, err)
+		return result
+	}
+	var sb strings.Builder
+	sb.WriteString(diffLines(redactKeys(current), redactKeys(wireguard.GenerateWgQuickConfig(config, desiredRoutes))))
 
-		// FQDN may not be configured on all systems, silently ignore errors to avoid cluttering output
-		if fqdn, err := ssh.GetFQDN(client); err == nil {
-			node.FQDN = fqdn
+	if live, err := wireguard.GetCurrentConfig(client, m.InterfaceName); err != nil {
+		sb.WriteString("  (interface not up: the config would be applied fresh)\n")
+	} else {
+		diff := wireguard.CalculateDiff(live, wireguard.FullConfigToConfig(config))
+		if diff.HasChanges() {
+			fmt.Fprintf(&sb, "  live peers: %d to add, %d to remove, %d to update\n",
+				len(diff.AddedPeers), len(diff.RemovedPeers), len(diff.ModifiedPeers))
+		}
+		if routes, err := ssh.GetCurrentRoutes(client, m.InterfaceName); err == nil {
+			toAdd, toRemove := ssh.CalculateRouteDiff(routes, desiredRoutes)
+			if len(toAdd)+len(toRemove) > 0 {
+				fmt.Fprintf(&sb, "  live routes: %d to add, %d to remove\n", len(toAdd), len(toRemove))
+			}
 		}
+	}
 
-		publicIP, err := ssh.DetectPublicIP(client)
-		client.Close()
+	result.Diff = sb.String()
+	result.Changed = result.Diff != ""
+	return result
+}
 
-		if err != nil {
-			fmt.Printf("Warning: failed to detect public IP for %s: %v\n", hostname, err)
-			node.BehindNAT = true
-			continue
+// detectEndpoints records each remote node's hostname, FQDN and whether it
+// has a public endpoint. Nodes that cannot be reached keep what the state
+// file says and are reported by the deploy itself.
+func (m *Mesh) detectEndpoints(parallel int) {
+	m.mu.RLock()
+	var nodes []*Node
+	for _, node := range m.Nodes {
+		nodes = append(nodes, node)
+	}
+	m.mu.RUnlock()
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Hostname < nodes[j].Hostname })
+
+	var printMu sync.Mutex
+	forEachParallel(len(nodes), parallel, func(i int) {
+		node := nodes[i]
+		msgs := m.detectNodeEndpoint(node)
+		printMu.Lock()
+		for _, msg := range msgs {
+			fmt.Println(msg)
 		}
+		printMu.Unlock()
+	})
+}
 
-		if publicIP != "" && publicIP != node.SSHHost {
-			node.BehindNAT = true
-			fmt.Printf("Detected %s is behind NAT (public IP: %s)\n", hostname, publicIP)
-		} else {
-			node.PublicEndpoint = fmt.Sprintf("%s:%d", node.SSHHost, node.ListenPort)
-			fmt.Printf("Detected %s has public endpoint: %s\n", hostname, node.PublicEndpoint)
+func (m *Mesh) detectNodeEndpoint(node *Node) []string {
+	hostname := node.Hostname
+	if node.IsLocal {
+		// For local node, get hostname directly
+		m.mu.Lock()
+		if node.ActualHostname == "" {
+			if h, err := os.Hostname(); err == nil {
+				node.ActualHostname = h
+			}
 		}
+		m.mu.Unlock()
+		return nil
 	}
 
-	return nil
+	client, err := connect(node)
+	if err != nil {
+		return []string{fmt.Sprintf("Warning: failed to connect to %s for endpoint detection: %v", hostname, err)}
+	}
+	defer client.Close()
+
+	var msgs []string
+	actualHostname, hostErr := ssh.GetHostname(client)
+	if hostErr != nil {
+		msgs = append(msgs, fmt.Sprintf("Warning: failed to get hostname for %s: %v", hostname, hostErr))
+	}
+	// FQDN may not be configured on all systems, silently ignore errors to avoid cluttering output
+	fqdn, _ := ssh.GetFQDN(client)
+	publicIP, ipErr := ssh.DetectPublicIP(client)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hostErr == nil {
+		node.ActualHostname = actualHostname
+	}
+	if fqdn != "" {
+		node.FQDN = fqdn
+	}
+	switch {
+	case ipErr != nil:
+		msgs = append(msgs, fmt.Sprintf("Warning: failed to detect public IP for %s: %v", hostname, ipErr))
+		node.BehindNAT = true
+	case publicIP != "" && publicIP != node.SSHHost:
+		node.BehindNAT = true
+		msgs = append(msgs, fmt.Sprintf("Detected %s is behind NAT (public IP: %s)", hostname, publicIP))
+	default:
+		node.PublicEndpoint = fmt.Sprintf("%s:%d", node.SSHHost, node.ListenPort)
+		msgs = append(msgs, fmt.Sprintf("Detected %s has public endpoint: %s", hostname, node.PublicEndpoint))
+	}
+	return msgs
 }
 
 func (m *Mesh) collectRoutesForNode(node *Node) []ssh.RouteEntry {
@@ -191,7 +432,8 @@ func (m *Mesh) collectAllRoutesForNode(node *Node) []ssh.RouteEntry {
 	if m.IsAccessControlEnabled() {
 		// Use policy-based route collection
 		allowedPeers := m.GetAllowedPeers(node.Hostname)
-		for peerHostname, access := range allowedPeers {
+		for _, peerHostname := range slices.Sorted(maps.Keys(allowedPeers)) {
+			access := allowedPeers[peerHostname]
 			peer := m.Nodes[peerHostname]
 			if access.AllowRoutableNetworks {
 				for _, network := range peer.RoutableNetworks {
@@ -204,10 +446,11 @@ func (m *Mesh) collectAllRoutesForNode(node *Node) []ssh.RouteEntry {
 		}
 	} else {
 		// Default: all nodes' networks (current behavior)
-		for peerHostname, peer := range m.Nodes {
+		for _, peerHostname := range slices.Sorted(maps.Keys(m.Nodes)) {
 			if peerHostname == node.Hostname {
 				continue
 			}
+			peer := m.Nodes[peerHostname]
 
 			for _, network := range peer.RoutableNetworks {
 				routes = append(routes, ssh.RouteEntry{
@@ -221,7 +464,9 @@ func (m *Mesh) collectAllRoutesForNode(node *Node) []ssh.RouteEntry {
 	return routes
 }
 
-func (m *Mesh) syncRoutesForNode(client *ssh.Client, node *Node, desiredRoutes []ssh.RouteEntry) error {
+// syncRoutesForNode brings the node's kernel routes in line with
+// desiredRoutes and reports whether anything changed.
+func (m *Mesh) syncRoutesForNode(client *ssh.Client, node *Node, desiredRoutes []ssh.RouteEntry) (bool, error) {
 	currentRoutes, err := ssh.GetCurrentRoutes(client, m.InterfaceName)
 	if err != nil {
 		fmt.Printf("  Warning: could not get current routes, will try to add all: %v\n", err)
@@ -237,11 +482,12 @@ func (m *Mesh) syncRoutesForNode(client *ssh.Client, node *Node, desiredRoutes [
 			}
 			client.RunQuiet(cmd)
 		}
-		return nil
+		return len(desiredRoutes) > 0, nil
 	}
 
 	toAdd, toRemove := ssh.CalculateRouteDiff(currentRoutes, desiredRoutes)
-	return ssh.ApplyRouteDiff(client, m.InterfaceName, toAdd, toRemove)
+	err = ssh.ApplyRouteDiff(client, m.InterfaceName, toAdd, toRemove)
+	return len(toAdd)+len(toRemove) > 0, err
 }
 
 func (m *Mesh) generateConfigForNode(node *Node) *WireGuardConfig {
@@ -273,17 +519,19 @@ func (m *Mesh) generateConfigForNode(node *Node) *WireGuardConfig {
 	if m.IsAccessControlEnabled() {
 		// Use policy-based peer selection
 		allowedPeers := m.GetAllowedPeers(node.Hostname)
-		for peerHostname, access := range allowedPeers {
+		for _, peerHostname := range slices.Sorted(maps.Keys(allowedPeers)) {
+			access := allowedPeers[peerHostname]
 			peer := m.Nodes[peerHostname]
 			peerConfig := m.buildPeerConfig(peer, access)
 			config.Peers = append(config.Peers, peerConfig)
 		}
 	} else {
 		// Default: full mesh (current behavior)
-		for peerHostname, peer := range m.Nodes {
+		for _, peerHostname := range slices.Sorted(maps.Keys(m.Nodes)) {
 			if peerHostname == node.Hostname {
 				continue
 			}
+			peer := m.Nodes[peerHostname]
 			peerConfig := m.buildPeerConfigFullAccess(peer)
 			config.Peers = append(config.Peers, peerConfig)
 		}
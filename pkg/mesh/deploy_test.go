@@ -0,0 +1,107 @@
+package mesh
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDeployTargets(t *testing.T) {
+	m := &Mesh{Nodes: map[string]*Node{
+		"web1": {Hostname: "web1"},
+		"web2": {Hostname: "web2"},
+		"db1":  {Hostname: "db1"},
+	}}
+
+	tests := []struct {
+		name    string
+		opts    DeployOptions
+		want    []string
+		wantErr bool
+	}{
+		{"all", DeployOptions{}, []string{"db1", "web1", "web2"}, false},
+		{"only", DeployOptions{Only: []string{"web2", "db1"}}, []string{"db1", "web2"}, false},
+		{"limit", DeployOptions{Limit: []string{"web*"}}, []string{"web1", "web2"}, false},
+		{"only and limit", DeployOptions{Only: []string{"web1", "db1"}, Limit: []string{"web*"}}, []string{"web1"}, false},
+		{"unknown only", DeployOptions{Only: []string{"web3"}}, nil, true},
+		{"limit matches nothing", DeployOptions{Limit: []string{"cache*"}}, nil, true},
+		{"bad pattern", DeployOptions{Limit: []string{"web["}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := m.deployTargets(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("deployTargets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deployTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForEachParallel(t *testing.T) {
+	var running, peak atomic.Int32
+	var mu sync.Mutex
+	seen := make(map[int]int)
+
+	forEachParallel(20, 3, func(i int) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		mu.Lock()
+		seen[i]++
+		mu.Unlock()
+		running.Add(-1)
+	})
+
+	if len(seen) != 20 {
+		t.Errorf("called for %d items, want 20", len(seen))
+	}
+	for i, n := range seen {
+		if n != 1 {
+			t.Errorf("item %d called %d times", i, n)
+		}
+	}
+	if peak.Load() > 3 {
+		t.Errorf("%d calls ran at once, want at most 3", peak.Load())
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	if d := diffLines("a\nb\n", "a\nb\n"); d != "" {
+		t.Errorf("equal input should give no diff, got %q", d)
+	}
+
+	old := "[Interface]\nAddress = 10.0.0.1/16\nListenPort = 51820\n\n[Peer]\nPublicKey = A\nEndpoint = 1.2.3.4:51820\nAllowedIPs = 10.0.0.2/32\n"
+	new := "[Interface]\nAddress = 10.0.0.1/16\nListenPort = 51820\n\n[Peer]\nPublicKey = A\nEndpoint = 5.6.7.8:51820\nAllowedIPs = 10.0.0.2/32\n"
+	d := diffLines(old, new)
+	for _, want := range []string{"- Endpoint = 1.2.3.4:51820\n", "+ Endpoint = 5.6.7.8:51820\n", "  PublicKey = A\n", "  ...\n"} {
+		if !strings.Contains(d, want) {
+			t.Errorf("diff missing %q:\n%s", want, d)
+		}
+	}
+	if strings.Contains(d, "[Interface]") {
+		t.Errorf("lines far from the change should be collapsed:\n%s", d)
+	}
+
+	if d := diffLines("", "a\n"); d != "+ a\n" {
+		t.Errorf("diff against an empty file = %q", d)
+	}
+}
+
+func TestRedactKeys(t *testing.T) {
+	got := redactKeys("[Interface]\nPrivateKey = secret1\n[Peer]\nPublicKey = pub\nPresharedKey=secret2\n")
+	if strings.Contains(got, "secret") {
+		t.Errorf("keys not redacted:\n%s", got)
+	}
+	if !strings.Contains(got, "PublicKey = pub") {
+		t.Errorf("public key should be kept:\n%s", got)
+	}
+}
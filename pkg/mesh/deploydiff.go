@@ -0,0 +1,107 @@
+package mesh
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffContext is how many unchanged lines diffLines keeps around a change.
+const diffContext = 2
+
+// diffLines returns a unified-style line diff from old to new ("-" removed,
+// "+" added, "  " context), or "" when they are equal. Long unchanged runs
+// are collapsed. Config files are small, so a plain LCS table is enough.
+func diffLines(old, new string) string {
+	if old == new {
+		return ""
+	}
+	a := splitLines(old)
+	b := splitLines(new)
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte // ' ', '-' or '+'
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i]})
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			lines = append(lines, line{'+', b[j]})
+			j++
+		default:
+			lines = append(lines, line{'-', a[i]})
+			i++
+		}
+	}
+
+	// Keep context lines only near a change.
+	keep := make([]bool, len(lines))
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for c := max(0, k-diffContext); c <= min(len(lines)-1, k+diffContext); c++ {
+			keep[c] = true
+		}
+	}
+
+	var sb strings.Builder
+	skipped := false
+	for k, l := range lines {
+		if !keep[k] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			sb.WriteString("  ...\n")
+			skipped = false
+		}
+		fmt.Fprintf(&sb, "%c %s\n", l.op, l.text)
+	}
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// redactKeys hides the secret values of a wg-quick config so a dry run can
+// be shown or pasted without leaking them.
+func redactKeys(config string) string {
+	lines := strings.Split(config, "\n")
+	for i, l := range lines {
+		key, _, ok := strings.Cut(l, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "PrivateKey", "PresharedKey":
+			lines[i] = strings.TrimSpace(key) + " = (redacted)"
+		}
+	}
+	return strings.Join(lines, "\n")
+}
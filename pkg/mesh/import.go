@@ -0,0 +1,292 @@
+package mesh
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/ifname"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/ssh"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+// ImportSource is one node of an existing WireGuard deployment: either an
+// SSH host whose live config is read, or a local wg-quick config file.
+type ImportSource struct {
+	SSHHost    string
+	SSHPort    int
+	SSHUser    string
+	ConfigFile string
+}
+
+// ParseImportSource parses an -import entry. Entries that name a local file
+// or end in ".conf" are config files; anything else is
+// [ssh_user@]ssh_host[:ssh_port].
+func ParseImportSource(spec string) (ImportSource, error) {
+	if strings.HasSuffix(spec, ".conf") {
+		return ImportSource{ConfigFile: spec}, nil
+	}
+	if info, err := os.Stat(spec); err == nil && info.Mode().IsRegular() {
+		return ImportSource{ConfigFile: spec}, nil
+	}
+
+	src := ImportSource{SSHHost: spec, SSHPort: 22}
+	if user, host, ok := strings.Cut(spec, "@"); ok {
+		src.SSHUser, src.SSHHost, spec = user, host, host
+	}
+	if host, port, err := net.SplitHostPort(spec); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return ImportSource{}, fmt.Errorf("invalid SSH port in %q", spec)
+		}
+		src.SSHHost, src.SSHPort = host, p
+	}
+	if src.SSHHost == "" {
+		return ImportSource{}, fmt.Errorf("invalid import source %q", spec)
+	}
+	return src, nil
+}
+
+// importedNode is what one source contributed before the nodes are
+// matched up with each other.
+type importedNode struct {
+	src      ImportSource
+	hostname string
+	config   *wireguard.FullConfig
+	hasPSK   bool
+}
+
+// Import reconstructs mesh state from an existing WireGuard deployment, so
+// it can be managed without re-keying. Every node of the mesh must be one
+// of the sources: wgmesh needs each node's private key to deploy to it.
+// Endpoints and routable networks are taken from how the nodes' peers see
+// them.
+func Import(sources []ImportSource, iface string) (*Mesh, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no import sources")
+	}
+	if err := ifname.Validate(iface); err != nil {
+		return nil, fmt.Errorf("invalid interface name: %w", err)
+	}
+
+	nodes := make([]importedNode, len(sources))
+	errs := make([]error, len(sources))
+	forEachParallel(len(sources), DefaultDeployParallel, func(i int) {
+		nodes[i], errs[i] = readImportSource(sources[i], iface)
+	})
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", sourceName(sources[i]), err)
+		}
+	}
+
+	localHostname, _ := os.Hostname()
+	return buildImportedMesh(nodes, iface, localHostname)
+}
+
+func sourceName(src ImportSource) string {
+	if src.ConfigFile != "" {
+		return src.ConfigFile
+	}
+	return src.SSHHost
+}
+
+func readImportSource(src ImportSource, iface string) (importedNode, error) {
+	node := importedNode{src: src}
+	var text string
+
+	if src.ConfigFile != "" {
+		data, err := os.ReadFile(src.ConfigFile)
+		if err != nil {
+			return node, fmt.Errorf("failed to read config: %w", err)
+		}
+		text = string(data)
+		node.hostname = strings.TrimSuffix(filepath.Base(src.ConfigFile), filepath.Ext(src.ConfigFile))
+	} else {
+		client, err := connect(&Node{SSHHost: src.SSHHost, SSHPort: src.SSHPort, SSHUser: src.SSHUser})
+		if err != nil {
+			return node, fmt.Errorf("failed to connect: %w", err)
+		}
+		defer client.Close()
+
+		if node.hostname, err = ssh.GetHostname(client); err != nil {
+			return node, err
+		}
+		text, err = client.Run(fmt.Sprintf("cat /etc/wireguard/%s.conf 2>/dev/null || true", iface))
+		if err != nil {
+			return node, fmt.Errorf("failed to read config: %w", err)
+		}
+		if strings.TrimSpace(text) == "" {
+			// Not a wg-quick setup: read the live interface instead.
+			if text, err = client.Run(fmt.Sprintf("wg showconf %s", iface)); err != nil {
+				return node, fmt.Errorf("no /etc/wireguard/%s.conf and no live interface: %w", iface, err)
+			}
+			addr, err := client.Run(fmt.Sprintf("ip -o -4 addr show dev %s | awk '{print $4}' | head -1", iface))
+			if err != nil || strings.TrimSpace(addr) == "" {
+				return node, fmt.Errorf("failed to read the address of %s", iface)
+			}
+			text = strings.Replace(text, "[Interface]", "[Interface]\nAddress = "+strings.TrimSpace(addr), 1)
+		}
+	}
+
+	config, err := wireguard.ParseWgQuickConfig(text)
+	if err != nil {
+		return node, fmt.Errorf("invalid config: %w", err)
+	}
+	node.config = config
+	node.hasPSK = strings.Contains(strings.ToLower(text), "presharedkey")
+	return node, nil
+}
+
+// peerView is how one node's config describes another.
+type peerView struct {
+	endpoint   string
+	allowedIPs []string
+}
+
+func buildImportedMesh(imported []importedNode, iface, localHostname string) (*Mesh, error) {
+	m := &Mesh{
+		InterfaceName: iface,
+		Nodes:         make(map[string]*Node),
+		LocalHostname: localHostname,
+	}
+
+	byKey := make(map[string]*Node)
+	views := make(map[string][]peerView)
+	peersOf := make(map[string]map[string]bool)
+	ports := make(map[int]int)
+
+	for _, in := range imported {
+		if _, exists := m.Nodes[in.hostname]; exists {
+			return nil, fmt.Errorf("two sources have hostname %s", in.hostname)
+		}
+		publicKey, err := wireguard.PublicKeyFromPrivate(in.config.Interface.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", in.hostname, err)
+		}
+		if other, exists := byKey[publicKey]; exists {
+			return nil, fmt.Errorf("%s and %s have the same key", other.Hostname, in.hostname)
+		}
+
+		meshIP, network, err := firstIPv4Address(in.config.Interface.Address)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", in.hostname, err)
+		}
+		if m.Network == "" {
+			m.Network = network.String()
+		} else if network.String() != m.Network {
+			fmt.Printf("Warning: %s uses %s, not %s; keeping %s\n", in.hostname, network, m.Network, m.Network)
+		}
+
+		listenPort := in.config.Interface.ListenPort
+		if listenPort == 0 {
+			listenPort = 51820
+		}
+		ports[listenPort]++
+
+		node := &Node{
+			Hostname:   in.hostname,
+			MeshIP:     meshIP,
+			PublicKey:  publicKey,
+			PrivateKey: in.config.Interface.PrivateKey,
+			SSHHost:    in.src.SSHHost,
+			SSHPort:    in.src.SSHPort,
+			SSHUser:    in.src.SSHUser,
+			ListenPort: listenPort,
+			IsLocal:    in.hostname == localHostname,
+		}
+		m.Nodes[in.hostname] = node
+		byKey[publicKey] = node
+
+		peersOf[publicKey] = make(map[string]bool)
+		for _, p := range in.config.Peers {
+			views[p.PublicKey] = append(views[p.PublicKey], peerView{endpoint: p.Endpoint, allowedIPs: p.AllowedIPs})
+			peersOf[publicKey][p.PublicKey] = true
+		}
+		if in.hasPSK {
+			fmt.Printf("Warning: %s uses preshared keys; wgmesh does not manage them and the next deploy removes them\n", in.hostname)
+		}
+	}
+
+	// The most common port becomes the default for nodes added later.
+	for port, n := range ports {
+		if n > ports[m.ListenPort] || (n == ports[m.ListenPort] && port < m.ListenPort) {
+			m.ListenPort = port
+		}
+	}
+
+	for _, hostname := range slices.Sorted(maps.Keys(m.Nodes)) {
+		node := m.Nodes[hostname]
+		meshHost := node.MeshIP.String() + "/32"
+		networks := make(map[string]bool)
+		for _, v := range views[node.PublicKey] {
+			if node.PublicEndpoint == "" && v.endpoint != "" {
+				node.PublicEndpoint = v.endpoint
+			}
+			for _, ip := range v.allowedIPs {
+				if ip != meshHost && !strings.Contains(ip, ":") {
+					networks[ip] = true
+				}
+			}
+		}
+		node.BehindNAT = node.PublicEndpoint == ""
+		node.RoutableNetworks = slices.Sorted(maps.Keys(networks))
+
+		if node.SSHHost == "" {
+			if host, _, err := net.SplitHostPort(node.PublicEndpoint); err == nil {
+				node.SSHHost, node.SSHPort = host, 22
+			} else {
+				fmt.Printf("Warning: no SSH host known for %s; set ssh_host in the state file before deploying\n", hostname)
+			}
+		}
+
+		var unpeered []string
+		for _, other := range m.Nodes {
+			if other != node && !peersOf[node.PublicKey][other.PublicKey] {
+				unpeered = append(unpeered, other.Hostname)
+			}
+		}
+		if len(unpeered) > 0 {
+			sort.Strings(unpeered)
+			fmt.Printf("Warning: %s does not peer with %s; without access policies the next deploy connects them\n",
+				hostname, strings.Join(unpeered, ", "))
+		}
+	}
+
+	var unknown []string
+	for pubKey := range views {
+		if _, ok := byKey[pubKey]; !ok {
+			unknown = append(unknown, pubKey)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		for _, pubKey := range unknown {
+			fmt.Printf("Warning: peer %s is not among the imported nodes and will be dropped on the next deploy\n", pubKey)
+		}
+	}
+
+	return m, nil
+}
+
+// firstIPv4Address returns the first IPv4 address of a wg-quick Address
+// setting and the network it is in.
+func firstIPv4Address(address string) (net.IP, *net.IPNet, error) {
+	for _, a := range strings.Split(address, ",") {
+		ip, network, err := net.ParseCIDR(strings.TrimSpace(a))
+		if err != nil {
+			continue
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			network.IP = network.IP.To4()
+			return ip4, network, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("no IPv4 address with prefix length in Address %q", address)
+}
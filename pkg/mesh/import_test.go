@@ -0,0 +1,136 @@
+package mesh
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+func testKeyPair(t *testing.T) (string, string) {
+	t.Helper()
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key.Bytes()), base64.StdEncoding.EncodeToString(key.PublicKey().Bytes())
+}
+
+func TestParseImportSource(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "gw")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		spec    string
+		want    ImportSource
+		wantErr bool
+	}{
+		{"192.168.1.10", ImportSource{SSHHost: "192.168.1.10", SSHPort: 22}, false},
+		{"gw.example.com:2222", ImportSource{SSHHost: "gw.example.com", SSHPort: 2222}, false},
+		{"[2001:db8::1]:22", ImportSource{SSHHost: "2001:db8::1", SSHPort: 22}, false},
+		{"deploy@gw.example.com:2222", ImportSource{SSHHost: "gw.example.com", SSHPort: 2222, SSHUser: "deploy"}, false},
+		{"configs/node1.conf", ImportSource{ConfigFile: "configs/node1.conf"}, false},
+		{file, ImportSource{ConfigFile: file}, false},
+		{"host:0", ImportSource{}, true},
+		{":22", ImportSource{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseImportSource(tt.spec)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseImportSource(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseImportSource(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestImportFromConfigFiles(t *testing.T) {
+	privA, pubA := testKeyPair(t)
+	privB, pubB := testKeyPair(t)
+	privC, pubC := testKeyPair(t)
+
+	configs := map[string]*wireguard.FullConfig{
+		"gw": {
+			Interface: wireguard.WGInterface{PrivateKey: privA, Address: "10.50.0.1/24", ListenPort: 51820},
+			Peers: []wireguard.WGPeer{
+				{PublicKey: pubB, AllowedIPs: []string{"10.50.0.2/32", "192.168.20.0/24"}},
+				{PublicKey: pubC, AllowedIPs: []string{"10.50.0.3/32"}},
+			},
+		},
+		"office": {
+			Interface: wireguard.WGInterface{PrivateKey: privB, Address: "10.50.0.2/24", ListenPort: 51820},
+			Peers: []wireguard.WGPeer{
+				{PublicKey: pubA, Endpoint: "203.0.113.1:51820", AllowedIPs: []string{"10.50.0.1/32"}},
+			},
+		},
+		"laptop": {
+			Interface: wireguard.WGInterface{PrivateKey: privC, Address: "10.50.0.3/24", ListenPort: 51821},
+			Peers: []wireguard.WGPeer{
+				{PublicKey: pubA, Endpoint: "203.0.113.1:51820", AllowedIPs: []string{"10.50.0.1/32"}},
+				{PublicKey: "c3RyYW5nZXI=", AllowedIPs: []string{"10.50.0.9/32"}},
+			},
+		},
+	}
+
+	dir := t.TempDir()
+	var sources []ImportSource
+	for name, cfg := range configs {
+		path := filepath.Join(dir, name+".conf")
+		if err := os.WriteFile(path, []byte(wireguard.GenerateWgQuickConfig(cfg, nil)), 0600); err != nil {
+			t.Fatal(err)
+		}
+		sources = append(sources, ImportSource{ConfigFile: path})
+	}
+
+	m, err := Import(sources, "wg1")
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+
+	if m.Network != "10.50.0.0/24" || m.InterfaceName != "wg1" || m.ListenPort != 51820 {
+		t.Errorf("mesh = network %s, interface %s, port %d", m.Network, m.InterfaceName, m.ListenPort)
+	}
+	if len(m.Nodes) != 3 {
+		t.Fatalf("imported %d nodes, want 3", len(m.Nodes))
+	}
+
+	gw := m.Nodes["gw"]
+	if gw.PublicKey != pubA || gw.PrivateKey != privA || gw.MeshIP.String() != "10.50.0.1" {
+		t.Errorf("gw keys or IP wrong: %+v", gw)
+	}
+	if gw.PublicEndpoint != "203.0.113.1:51820" || gw.BehindNAT || gw.SSHHost != "203.0.113.1" || gw.SSHPort != 22 {
+		t.Errorf("gw endpoint not taken from its peers: %+v", gw)
+	}
+
+	office := m.Nodes["office"]
+	if !office.BehindNAT || !reflect.DeepEqual(office.RoutableNetworks, []string{"192.168.20.0/24"}) {
+		t.Errorf("office = %+v", office)
+	}
+	if m.Nodes["laptop"].ListenPort != 51821 {
+		t.Errorf("laptop listen port = %d", m.Nodes["laptop"].ListenPort)
+	}
+}
+
+func TestImportRejectsDuplicates(t *testing.T) {
+	priv, _ := testKeyPair(t)
+	cfg := &wireguard.FullConfig{Interface: wireguard.WGInterface{PrivateKey: priv, Address: "10.50.0.1/24"}}
+	imported := []importedNode{{hostname: "a", config: cfg}, {hostname: "b", config: cfg}}
+
+	if _, err := buildImportedMesh(imported, "wg0", ""); err == nil || !strings.Contains(err.Error(), "same key") {
+		t.Errorf("expected duplicate key error, got %v", err)
+	}
+	imported[1].hostname = "a"
+	if _, err := buildImportedMesh(imported, "wg0", ""); err == nil || !strings.Contains(err.Error(), "hostname") {
+		t.Errorf("expected duplicate hostname error, got %v", err)
+	}
+}
@@ -9,15 +9,78 @@ import (
 	"strings"
 
 	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/ssh"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
 )
 
-var encryptionPassword string
+var (
+	encryptionPassword string
+	encryptionKey      []byte
+	kdfParams          *crypto.KDFParams
+)
+
+// sshConfig, if set, fills in SSH details that nodes leave unset.
+var sshConfig *ssh.Config
 
 func SetEncryptionPassword(password string) {
 	encryptionPassword = password
 }
 
+// SetEncryptionKey encrypts the state file with a raw 32-byte key instead
+// of a password. If a password is also set, it is only used to read a
+// password-encrypted file, which is then re-encrypted under the key.
+func SetEncryptionKey(key []byte) {
+	encryptionKey = key
+}
+
+// SetKDFParams sets the argon2id cost for password-encrypted state files.
+// Without it, a file keeps the cost it was written with.
+func SetKDFParams(p crypto.KDFParams) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	kdfParams = &p
+	return nil
+}
+
+func stateKey(kdf crypto.KDFParams) crypto.StateKey {
+	if kdfParams != nil {
+		kdf = *kdfParams
+	}
+	return crypto.StateKey{Password: encryptionPassword, Key: encryptionKey, KDF: kdf}
+}
+
+// SetSSHConfig makes deploys read an OpenSSH client config file for users,
+// ports, keys and jump hosts that nodes do not set themselves.
+func SetSSHConfig(path string) error {
+	cfg, err := ssh.LoadConfig(path)
+	if err != nil {
+		return err
+	}
+	sshConfig = cfg
+	return nil
+}
+
+// sshTarget returns how to reach node over SSH. Port 22 counts as unset so
+// an ssh_config Port can replace it.
+func sshTarget(node *Node) ssh.Target {
+	t := ssh.Target{
+		Host:         node.SSHHost,
+		User:         node.SSHUser,
+		IdentityFile: node.SSHIdentity,
+		Jump:         node.SSHJump,
+	}
+	if node.SSHPort != 22 {
+		t.Port = node.SSHPort
+	}
+	return t
+}
+
+// connect opens an SSH connection to node.
+func connect(node *Node) (*ssh.Client, error) {
+	return ssh.Connect(sshTarget(node), sshConfig)
+}
+
 func Initialize(stateFile string) error {
 	return InitializeWithNetwork(stateFile, "")
 }
@@ -63,20 +126,31 @@ func Load(stateFile string) (*Mesh, error) {
 		return nil, fmt.Errorf("failed to read state file: %w", err)
 	}
 
-	// Check if file is encrypted (base64 encoded data)
-	if encryptionPassword != "" {
-		// Decrypt the data
-		decrypted, err := crypto.Decrypt(string(data), encryptionPassword)
+	var info crypto.StateInfo
+	encrypted := encryptionPassword != "" || encryptionKey != nil
+	if encrypted {
+		decrypted, stateInfo, err := crypto.DecryptState(data, stateKey(crypto.KDFParams{}))
 		if err != nil {
 			return nil, fmt.Errorf("failed to decrypt state file: %w", err)
 		}
-		data = decrypted
+		data, info = decrypted, stateInfo
 	}
 
 	var m Mesh
 	if err := json.Unmarshal(data, &m); err != nil {
 		return nil, fmt.Errorf("failed to parse state file: %w", err)
 	}
+	m.kdf = info.KDF
+
+	// Bring files in an older format, or under a different key or cost,
+	// up to what Save writes now.
+	if encrypted && (info.Legacy || info.KeyFile != (encryptionKey != nil) ||
+		(encryptionKey == nil && kdfParams != nil && *kdfParams != info.KDF)) {
+		if err := m.Save(stateFile); err != nil {
+			return nil, fmt.Errorf("failed to re-encrypt state file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Re-encrypted %s\n", stateFile)
+	}
 
 	return &m, nil
 }
@@ -87,13 +161,13 @@ func (m *Mesh) Save(stateFile string) error {
 		return fmt.Errorf("failed to marshal state: %w", err)
 	}
 
-	// Encrypt if password is set
-	if encryptionPassword != "" {
-		encrypted, err := crypto.Encrypt(data, encryptionPassword)
+	// Encrypt if a password or key is set
+	if encryptionPassword != "" || encryptionKey != nil {
+		encrypted, err := crypto.EncryptState(data, stateKey(m.kdf))
 		if err != nil {
 			return fmt.Errorf("failed to encrypt state: %w", err)
 		}
-		data = []byte(encrypted)
+		data = encrypted
 	}
 
 	// Ensure directory exists
@@ -102,8 +176,9 @@ func (m *Mesh) Save(stateFile string) error {
 		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	if err := os.WriteFile(stateFile, data, 0600); err != nil {
-		return fmt.Errorf("failed to write state file: %w", err)
+	// Written atomically: Load may rewrite an existing file to migrate it.
+	if err := writeFileAtomic(stateFile, data, 0600); err != nil {
+		return err
 	}
 
 	return nil
@@ -112,7 +187,7 @@ func (m *Mesh) Save(stateFile string) error {
 func (m *Mesh) AddNode(nodeSpec string) error {
 	parts := strings.Split(nodeSpec, ":")
 	if len(parts) < 3 {
-		return fmt.Errorf("invalid node spec, expected hostname:mesh_ip:ssh_host[:ssh_port]")
+		return fmt.Errorf("invalid node spec, expected hostname:mesh_ip:[ssh_user@]ssh_host[:ssh_port]")
 	}
 
 	hostname := parts[0]
@@ -122,6 +197,10 @@ func (m *Mesh) AddNode(nodeSpec string) error {
 	}
 
 	sshHost := parts[2]
+	var sshUser string
+	if user, host, ok := strings.Cut(sshHost, "@"); ok {
+		sshUser, sshHost = user, host
+	}
 	sshPort := 22
 	if len(parts) >= 4 {
 		if _, err := fmt.Sscanf(parts[3], "%d", &sshPort); err != nil {
@@ -150,6 +229,7 @@ func (m *Mesh) AddNode(nodeSpec string) error {
 		PrivateKey: privateKey,
 		SSHHost:    sshHost,
 		SSHPort:    sshPort,
+		SSHUser:    sshUser,
 		ListenPort: m.ListenPort,
 		IsLocal:    isLocal,
 	}
@@ -160,6 +240,30 @@ func (m *Mesh) AddNode(nodeSpec string) error {
 	return nil
 }
 
+// SetNodeSSH sets the SSH identity file and jump hosts of a node. Empty
+// values leave the current setting alone.
+func (m *Mesh) SetNodeSSH(hostname, identity, jump string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	node, exists := m.Nodes[hostname]
+	if !exists {
+		return fmt.Errorf("node %s not found", hostname)
+	}
+	if jump != "" {
+		for _, hop := range strings.Split(jump, ",") {
+			if _, err := ssh.ParseTarget(strings.TrimSpace(hop)); err != nil {
+				return fmt.Errorf("invalid jump host: %w", err)
+			}
+		}
+		node.SSHJump = jump
+	}
+	if identity != "" {
+		node.SSHIdentity = identity
+	}
+	return nil
+}
+
 func (m *Mesh) RemoveNode(hostname string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -228,7 +332,17 @@ func (m *Mesh) List() {
 
 		fmt.Printf("  %s%s%s:\n", hostname, localMarker, natMarker)
 		fmt.Printf("    Mesh IP: %s\n", node.MeshIP)
-		fmt.Printf("    SSH: %s:%d\n", node.SSHHost, node.SSHPort)
+		sshHost := node.SSHHost
+		if node.SSHUser != "" {
+			sshHost = node.SSHUser + "@" + sshHost
+		}
+		fmt.Printf("    SSH: %s:%d\n", sshHost, node.SSHPort)
+		if node.SSHJump != "" {
+			fmt.Printf("    SSH Jump: %s\n", node.SSHJump)
+		}
+		if node.SSHIdentity != "" {
+			fmt.Printf("    SSH Identity: %s\n", node.SSHIdentity)
+		}
 		fmt.Printf("    Public Key: %s\n", node.PublicKey)
 		if node.PublicEndpoint != "" {
 			fmt.Printf("    Endpoint: %s\n", node.PublicEndpoint)
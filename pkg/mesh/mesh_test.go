@@ -5,8 +5,11 @@ import (
 	"io"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 )
 
 func TestListSimple(t *testing.T) {
@@ -213,13 +216,7 @@ func ExampleMesh_ListSimple() {
 
 func TestMesh_SaveLoad_WithActualHostname(t *testing.T) {
 	// Test that ActualHostname and FQDN fields are properly saved and loaded
-	tmpFile, err := os.CreateTemp("", "test-mesh-state-*.json")
-	if err != nil {
-		t.Fatalf("Failed to create temp file: %v", err)
-	}
-	tmpPath := tmpFile.Name()
-	tmpFile.Close()
-	defer os.Remove(tmpPath)
+	tmpPath := filepath.Join(t.TempDir(), "mesh-state.json")
 
 	original := &Mesh{
 		InterfaceName: "wg0",
@@ -241,8 +238,7 @@ func TestMesh_SaveLoad_WithActualHostname(t *testing.T) {
 	}
 
 	// Save
-	err = original.Save(tmpPath)
-	if err != nil {
+	if err := original.Save(tmpPath); err != nil {
 		t.Fatalf("Failed to save mesh: %v", err)
 	}
 
@@ -266,3 +262,84 @@ func TestMesh_SaveLoad_WithActualHostname(t *testing.T) {
 		t.Errorf("Expected FQDN 'server01.example.com', got %s", node.FQDN)
 	}
 }
+
+func TestNodeSSHTarget(t *testing.T) {
+	m := &Mesh{Nodes: map[string]*Node{
+		"node1": {Hostname: "node1", SSHHost: "192.168.1.10", SSHPort: 2222, SSHUser: "deploy"},
+		"node2": {Hostname: "node2", SSHHost: "192.168.1.11", SSHPort: 22},
+	}}
+	if err := m.SetNodeSSH("node1", "~/.ssh/mesh_ed25519", "ops@bastion.example.com:2200"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetNodeSSH("node1", "", "bad host:x"); err == nil {
+		t.Error("expected an error for an invalid jump host")
+	}
+	if err := m.SetNodeSSH("node3", "key", ""); err == nil {
+		t.Error("expected an error for an unknown node")
+	}
+
+	got := sshTarget(m.Nodes["node1"])
+	if got.User != "deploy" || got.IdentityFile != "~/.ssh/mesh_ed25519" || got.Jump != "ops@bastion.example.com:2200" || got.Port != 2222 {
+		t.Errorf("sshTarget() = %+v", got)
+	}
+
+	// The default port is left for ssh_config to override.
+	if got := sshTarget(m.Nodes["node2"]); got.Port != 0 || got.User != "" {
+		t.Errorf("sshTarget() = %+v, want port and user unset", got)
+	}
+}
+
+func TestLoadMigratesEncryptedState(t *testing.T) {
+	t.Cleanup(func() {
+		encryptionPassword, encryptionKey, kdfParams = "", nil, nil
+	})
+	stateFile := filepath.Join(t.TempDir(), "mesh-state.json")
+
+	legacy, err := crypto.Encrypt([]byte(`{"network":"10.99.0.0/16","nodes":{}}`), "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stateFile, []byte(legacy), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	SetEncryptionPassword("hunter2")
+	if err := SetKDFParams(crypto.KDFParams{Time: 1, MemoryKiB: 64, Threads: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(stateFile); err != nil {
+		t.Fatalf("Load of legacy file failed: %v", err)
+	}
+	data, _ := os.ReadFile(stateFile)
+	if !strings.HasPrefix(string(data), "wgmesh-state/v2 argon2id t=1,m=64,p=1\n") {
+		t.Fatalf("legacy file was not re-encrypted: %q", data)
+	}
+
+	// Saving without explicit parameters keeps the file's cost.
+	kdfParams = nil
+	m, err := Load(stateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(stateFile); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(stateFile)
+	if !strings.HasPrefix(string(data), "wgmesh-state/v2 argon2id t=1,m=64,p=1\n") {
+		t.Fatalf("Save changed the KDF cost: %q", data)
+	}
+
+	// A key file together with the password moves the file to the key.
+	SetEncryptionKey(bytes.Repeat([]byte{1}, 32))
+	if _, err := Load(stateFile); err != nil {
+		t.Fatal(err)
+	}
+	SetEncryptionPassword("")
+	m, err = Load(stateFile)
+	if err != nil {
+		t.Fatalf("Load with key only failed: %v", err)
+	}
+	if m.Network != "10.99.0.0/16" {
+		t.Errorf("Network = %q after migration", m.Network)
+	}
+}
@@ -2,6 +2,7 @@ package mesh
 
 import (
 	"net"
+	"path/filepath"
 	"testing"
 )
 
@@ -593,11 +594,7 @@ func TestBuildPeerConfig(t *testing.T) {
 }
 
 func TestMesh_SaveLoad_WithGroupsAndPolicies(t *testing.T) {
-	tmpFile, err := createTempMeshFile(t)
-	if err != nil {
-		t.Fatalf("Failed to create temp file: %v", err)
-	}
-	defer cleanupTempMeshFile(t, tmpFile)
+	tmpFile := filepath.Join(t.TempDir(), "mesh-state.json")
 
 	original := &Mesh{
 		InterfaceName: "wg0",
@@ -639,8 +636,7 @@ func TestMesh_SaveLoad_WithGroupsAndPolicies(t *testing.T) {
 	}
 
 	// Save
-	err = original.Save(tmpFile)
-	if err != nil {
+	if err := original.Save(tmpFile); err != nil {
 		t.Fatalf("Failed to save mesh: %v", err)
 	}
 
@@ -690,28 +686,3 @@ func TestMesh_SaveLoad_WithGroupsAndPolicies(t *testing.T) {
 		t.Errorf("Expected AllowRoutableNetworks to be false")
 	}
 }
-
-// Helper functions for temp file management
-func createTempMeshFile(t *testing.T) (string, error) {
-	return createTempFile(t, "test-mesh-state-*.json")
-}
-
-func createTempFile(t *testing.T, pattern string) (string, error) {
-	t.Helper()
-	tmpFile, err := createTempFileHelper(pattern)
-	return tmpFile, err
-}
-
-func createTempFileHelper(pattern string) (string, error) {
-	// This is a placeholder - in a real implementation, use os.CreateTemp
-	return pattern, nil
-}
-
-func cleanupTempMeshFile(t *testing.T, path string) {
-	t.Helper()
-	cleanupTempFile(path)
-}
-
-func cleanupTempFile(path string) {
-	// Placeholder for cleanup
-}
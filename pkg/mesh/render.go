@@ -0,0 +1,110 @@
+package mesh
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+// RenderOptions selects the nodes Render writes configs for and where.
+type RenderOptions struct {
+	OutputDir string
+	Systemd   bool     // also write a unit that brings the interface up
+	Only      []string // as in DeployOptions
+	Limit     []string // as in DeployOptions
+}
+
+// Render writes each selected node's wg-quick config, and optionally a
+// systemd unit, to OutputDir/<hostname>/ instead of pushing them over SSH,
+// so they can be reviewed and shipped by other tooling. The configs are
+// the ones Deploy would install, access policies included. It returns the
+// paths written.
+//
+// Render does not contact the nodes. Endpoints come from the state file;
+// nodes without one that are not marked behind NAT are assumed reachable
+// on ssh_host at their listen port, as Deploy would detect them.
+func (m *Mesh) Render(opts RenderOptions) ([]string, error) {
+	if opts.OutputDir == "" {
+		return nil, fmt.Errorf("no output directory")
+	}
+	if err := m.validateAccessControl(); err != nil {
+		return nil, err
+	}
+	targets, err := m.deployTargets(DeployOptions{Only: opts.Only, Limit: opts.Limit})
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	for _, node := range m.Nodes {
+		if node.PublicEndpoint == "" && !node.BehindNAT && node.SSHHost != "" {
+			node.PublicEndpoint = fmt.Sprintf("%s:%d", node.SSHHost, node.ListenPort)
+		}
+	}
+	m.mu.Unlock()
+
+	var written []string
+	for _, hostname := range targets {
+		m.mu.RLock()
+		node := m.Nodes[hostname]
+		m.mu.RUnlock()
+
+		dir := filepath.Join(opts.OutputDir, hostname)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return written, fmt.Errorf("failed to create %s: %w", dir, err)
+		}
+
+		config := wireguard.GenerateWgQuickConfig(m.generateConfigForNode(node), m.collectAllRoutesForNode(node))
+		path := filepath.Join(dir, m.InterfaceName+".conf")
+		// The config holds the node's private key.
+		if err := writeFileAtomic(path, []byte(config), 0600); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+
+		if opts.Systemd {
+			path := filepath.Join(dir, "wgmesh-"+m.InterfaceName+".service")
+			if err := writeFileAtomic(path, []byte(renderSystemdUnit(m.InterfaceName)), 0644); err != nil {
+				return written, err
+			}
+			written = append(written, path)
+		}
+	}
+	return written, nil
+}
+
+// renderSystemdUnit returns a unit that brings iface up from
+// /etc/wireguard/<iface>.conf, for hosts without the wg-quick@ template.
+func renderSystemdUnit(iface string) string {
+	var sb strings.Builder
+	sb.WriteString("[Unit]\n")
+	fmt.Fprintf(&sb, "Description=WireGuard mesh interface %s (rendered by wgmesh)\n", iface)
+	sb.WriteString("After=network-online.target\n")
+	sb.WriteString("Wants=network-online.target\n")
+	sb.WriteString("\n[Service]\n")
+	sb.WriteString("Type=oneshot\n")
+	sb.WriteString("RemainAfterExit=yes\n")
+	fmt.Fprintf(&sb, "ExecStart=/usr/bin/wg-quick up %s\n", iface)
+	fmt.Fprintf(&sb, "ExecStop=/usr/bin/wg-quick down %s\n", iface)
+	fmt.Fprintf(&sb, "ExecReload=/bin/bash -c 'exec /usr/bin/wg syncconf %s <(exec /usr/bin/wg-quick strip %s)'\n", iface, iface)
+	sb.WriteString("\n[Install]\n")
+	sb.WriteString("WantedBy=multi-user.target\n")
+	return sb.String()
+}
+
+// writeFileAtomic replaces path with data, so a reader never sees a
+// partly written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package mesh
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	m := &Mesh{
+		InterfaceName: "wg0",
+		Network:       "10.99.0.0/16",
+		Nodes: map[string]*Node{
+			"web1": {Hostname: "web1", MeshIP: net.ParseIP("10.99.0.1"), PublicKey: "pubWeb1", PrivateKey: "privWeb1", SSHHost: "203.0.113.1", ListenPort: 51820},
+			"web2": {Hostname: "web2", MeshIP: net.ParseIP("10.99.0.2"), PublicKey: "pubWeb2", PrivateKey: "privWeb2", SSHHost: "203.0.113.2", ListenPort: 51820},
+			"db1":  {Hostname: "db1", MeshIP: net.ParseIP("10.99.0.3"), PublicKey: "pubDb1", PrivateKey: "privDb1", SSHHost: "192.168.1.3", ListenPort: 51820, BehindNAT: true, RoutableNetworks: []string{"192.168.50.0/24"}},
+		},
+		Groups: map[string]*Group{
+			"web": {Members: []string{"web1", "web2"}},
+			"db":  {Members: []string{"db1"}},
+		},
+		AccessPolicies: []*AccessPolicy{
+			{Name: "web-to-db", FromGroups: []string{"web"}, ToGroups: []string{"db"}, AllowMeshIPs: true},
+		},
+	}
+
+	dir := t.TempDir()
+	written, err := m.Render(RenderOptions{OutputDir: dir, Systemd: true})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if len(written) != 6 {
+		t.Errorf("wrote %d files, want 6: %v", len(written), written)
+	}
+
+	read := func(rel string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	web1 := read("web1/wg0.conf")
+	if !strings.Contains(web1, "PrivateKey = privWeb1") || !strings.Contains(web1, "PublicKey = pubDb1") {
+		t.Errorf("web1 config missing its key or db1:\n%s", web1)
+	}
+	if strings.Contains(web1, "pubWeb2") {
+		t.Errorf("web1 should not peer with web2 under the policies:\n%s", web1)
+	}
+	if strings.Contains(web1, "192.168.50.0/24") {
+		t.Errorf("policy does not allow db1's routable networks:\n%s", web1)
+	}
+
+	db1 := read("db1/wg0.conf")
+	if !strings.Contains(db1, "Endpoint = 203.0.113.1:51820") {
+		t.Errorf("public nodes should get an endpoint from ssh_host:\n%s", db1)
+	}
+	if strings.Contains(web1, "Endpoint = 192.168.1.3") {
+		t.Errorf("nodes behind NAT should get no endpoint:\n%s", web1)
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, "web1", "wg0.conf")); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("config should be private, got %v, %v", info.Mode(), err)
+	}
+	if unit := read("web1/wgmesh-wg0.service"); !strings.Contains(unit, "ExecStart=/usr/bin/wg-quick up wg0") {
+		t.Errorf("unexpected unit:\n%s", unit)
+	}
+
+	// Re-rendering the same state must give byte-identical files.
+	if _, err := m.Render(RenderOptions{OutputDir: dir, Only: []string{"web1"}}); err != nil {
+		t.Fatalf("second Render failed: %v", err)
+	}
+	if again := read("web1/wg0.conf"); again != web1 {
+		t.Errorf("render is not stable:\n%s\nvs\n%s", web1, again)
+	}
+}
@@ -3,6 +3,8 @@ package mesh
 import (
 	"net"
 	"sync"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 )
 
 type Node struct {
@@ -13,6 +15,11 @@ type Node struct {
 
 	SSHHost string `json:"ssh_host"`
 	SSHPort int    `json:"ssh_port"`
+	// SSH login details; empty means root, the default keys and no bastion,
+	// unless the -ssh-config file says otherwise for SSHHost.
+	SSHUser     string `json:"ssh_user,omitempty"`
+	SSHIdentity string `json:"ssh_identity,omitempty"` // private key file
+	SSHJump     string `json:"ssh_jump,omitempty"`     // [user@]host[:port][,...] bastion(s)
 
 	PublicEndpoint string `json:"public_endpoint,omitempty"`
 	ListenPort     int    `json:"listen_port"`
@@ -57,4 +64,7 @@ type Mesh struct {
 	Groups         map[string]*Group `json:"groups,omitempty"`
 	AccessPolicies []*AccessPolicy   `json:"access_policies,omitempty"`
 	mu             sync.RWMutex      `json:"-"`
+
+	// kdf is the argon2id cost the state file was loaded with, kept on save.
+	kdf crypto.KDFParams
 }
@@ -0,0 +1,218 @@
+package mesh
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/ssh"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+// VerifyResult is the drift found on one node: every way its live
+// WireGuard setup differs from what a deploy would produce.
+type VerifyResult struct {
+	Hostname string
+	Drift    []string
+	Err      error
+}
+
+// Verify compares the live WireGuard interface, routes and wg-quick config
+// of the nodes selected by opts with the state file, without changing
+// anything. It returns an error when any node has drifted or could not be
+// checked. opts.DryRun is ignored.
+func (m *Mesh) Verify(opts DeployOptions) ([]VerifyResult, error) {
+	targets, err := m.deployTargets(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	parallel := opts.Parallel
+	if parallel <= 0 {
+		parallel = DefaultDeployParallel
+	}
+
+	// Endpoints are not all kept in the state file; detect them the way
+	// Deploy does so the desired configs match what it would push.
+	m.detectEndpoints(parallel)
+
+	fmt.Printf("Verifying %d of %d nodes\n\n", len(targets), len(m.Nodes))
+
+	results := make([]VerifyResult, len(targets))
+	forEachParallel(len(targets), parallel, func(i int) {
+		results[i] = m.verifyNode(targets[i])
+	})
+
+	drifted, failed := 0, 0
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			fmt.Printf("  ✗ %-24s %v\n", r.Hostname, r.Err)
+		case len(r.Drift) > 0:
+			drifted++
+			fmt.Printf("  ~ %-24s %d differences\n", r.Hostname, len(r.Drift))
+			for _, d := range r.Drift {
+				fmt.Printf("      %s\n", d)
+			}
+		default:
+			fmt.Printf("  ✓ %-24s in sync\n", r.Hostname)
+		}
+	}
+
+	switch {
+	case failed > 0:
+		return results, fmt.Errorf("could not verify %d of %d nodes (%d drifted)", failed, len(results), drifted)
+	case drifted > 0:
+		return results, fmt.Errorf("drift found on %d of %d nodes", drifted, len(results))
+	}
+	return results, nil
+}
+
+func (m *Mesh) verifyNode(hostname string) VerifyResult {
+	result := VerifyResult{Hostname: hostname}
+	m.mu.RLock()
+	node := m.Nodes[hostname]
+	names := make(map[string]string, len(m.Nodes))
+	for name, n := range m.Nodes {
+		names[n.PublicKey] = name
+	}
+	m.mu.RUnlock()
+
+	client, err := connect(node)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to connect: %w", err)
+		return result
+	}
+	defer client.Close()
+
+	config := m.generateConfigForNode(node)
+	desiredRoutes := m.collectAllRoutesForNode(node)
+
+	live, err := wireguard.GetCurrentConfig(client, m.InterfaceName)
+	if err != nil {
+		result.Drift = append(result.Drift, fmt.Sprintf("interface %s is not up", m.InterfaceName))
+	} else {
+		result.Drift = append(result.Drift, configDrift(wireguard.FullConfigToConfig(config), live, names)...)
+	}
+
+	if routes, err := ssh.GetCurrentRoutes(client, m.InterfaceName); err == nil {
+		toAdd, toRemove := ssh.CalculateRouteDiff(routes, desiredRoutes)
+		for _, r := range toAdd {
+			result.Drift = append(result.Drift, "missing route "+routeString(r))
+		}
+		for _, r := range toRemove {
+			result.Drift = append(result.Drift, "unexpected route "+routeString(r))
+		}
+	}
+
+	configPath := fmt.Sprintf("/etc/wireguard/%s.conf", m.InterfaceName)
+	current, err := client.Run(fmt.Sprintf("cat %s 2>/dev/null || true", configPath))
+	if err != nil {
+		result.Err = fmt.Errorf("failed to read %s: %w", configPath, err)
+		return result
+	}
+	switch {
+	case strings.TrimSpace(current) == "":
+		result.Drift = append(result.Drift, configPath+" is missing")
+	case current != wireguard.GenerateWgQuickConfig(config, desiredRoutes):
+		result.Drift = append(result.Drift, configPath+" differs (see -deploy -dry-run)")
+	}
+	return result
+}
+
+// configDrift lists the differences between the desired and live WireGuard
+// configs that matter to the mesh: keys, port, peers, AllowedIPs and
+// configured endpoints. Peers are named by hostname where known.
+func configDrift(desired, live *wireguard.Config, names map[string]string) []string {
+	var drift []string
+	peerName := func(pubKey string) string {
+		if name, ok := names[pubKey]; ok {
+			return name
+		}
+		if len(pubKey) > 16 {
+			return pubKey[:16] + "..."
+		}
+		return pubKey
+	}
+
+	if live.Interface.PrivateKey != desired.Interface.PrivateKey {
+		drift = append(drift, "interface has a different private key")
+	}
+	if live.Interface.ListenPort != desired.Interface.ListenPort {
+		drift = append(drift, fmt.Sprintf("listen port is %d, want %d", live.Interface.ListenPort, desired.Interface.ListenPort))
+	}
+
+	var missing, extra, changed []string
+	for pubKey, want := range desired.Peers {
+		got, ok := live.Peers[pubKey]
+		if !ok {
+			missing = append(missing, "missing peer "+peerName(pubKey))
+			continue
+		}
+		if !sameSet(got.AllowedIPs, want.AllowedIPs) {
+			changed = append(changed, fmt.Sprintf("peer %s has AllowedIPs %s, want %s",
+				peerName(pubKey), strings.Join(sortedCopy(got.AllowedIPs), ","), strings.Join(sortedCopy(want.AllowedIPs), ",")))
+		}
+		if want.Endpoint != "" && !sameEndpoint(got.Endpoint, want.Endpoint) {
+			changed = append(changed, fmt.Sprintf("peer %s has endpoint %s, want %s", peerName(pubKey), got.Endpoint, want.Endpoint))
+		}
+	}
+	for pubKey := range live.Peers {
+		if _, ok := desired.Peers[pubKey]; !ok {
+			extra = append(extra, "unexpected peer "+peerName(pubKey))
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(extra)
+	sort.Strings(changed)
+	drift = append(drift, missing...)
+	drift = append(drift, extra...)
+	return append(drift, changed...)
+}
+
+func sameSet(a, b []string) bool {
+	return slices.Equal(sortedCopy(a), sortedCopy(b))
+}
+
+func sortedCopy(s []string) []string {
+	c := slices.Clone(s)
+	sort.Strings(c)
+	return c
+}
+
+// resolveCache keeps sameEndpoint from looking up the same hostname once
+// per peer per node.
+var resolveCache sync.Map
+
+// sameEndpoint reports whether the live endpoint is the configured one.
+// wg show prints addresses, so a configured hostname is resolved first.
+func sameEndpoint(live, want string) bool {
+	if live == want {
+		return true
+	}
+	liveHost, livePort, err := net.SplitHostPort(live)
+	if err != nil {
+		return false
+	}
+	wantHost, wantPort, err := net.SplitHostPort(want)
+	if err != nil || livePort != wantPort || net.ParseIP(wantHost) != nil {
+		return false
+	}
+	addrs, ok := resolveCache.Load(wantHost)
+	if !ok {
+		resolved, _ := net.LookupHost(wantHost)
+		addrs, _ = resolveCache.LoadOrStore(wantHost, resolved)
+	}
+	return slices.Contains(addrs.([]string), liveHost)
+}
+
+func routeString(r ssh.RouteEntry) string {
+	if r.Gateway == "" {
+		return r.Network
+	}
+	return r.Network + " via " + r.Gateway
+}
@@ -0,0 +1,68 @@
+package mesh
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+func TestConfigDrift(t *testing.T) {
+	desired := &wireguard.Config{
+		Interface: wireguard.Interface{PrivateKey: "priv", ListenPort: 51820},
+		Peers: map[string]wireguard.Peer{
+			"keyA": {PublicKey: "keyA", Endpoint: "203.0.113.1:51820", AllowedIPs: []string{"10.99.0.2/32", "192.168.10.0/24"}},
+			"keyB": {PublicKey: "keyB", AllowedIPs: []string{"10.99.0.3/32"}},
+			"keyC": {PublicKey: "keyC", AllowedIPs: []string{"10.99.0.4/32"}},
+		},
+	}
+	names := map[string]string{"keyA": "node2", "keyB": "node3", "keyC": "node4"}
+
+	inSync := &wireguard.Config{
+		Interface: wireguard.Interface{PrivateKey: "priv", ListenPort: 51820},
+		Peers: map[string]wireguard.Peer{
+			"keyA": {PublicKey: "keyA", Endpoint: "203.0.113.1:51820", AllowedIPs: []string{"192.168.10.0/24", "10.99.0.2/32"}},
+			// Peers without a configured endpoint may roam freely.
+			"keyB": {PublicKey: "keyB", Endpoint: "198.51.100.7:40000", AllowedIPs: []string{"10.99.0.3/32"}},
+			"keyC": {PublicKey: "keyC", Endpoint: "(none)", AllowedIPs: []string{"10.99.0.4/32"}},
+		},
+	}
+	if drift := configDrift(desired, inSync, names); len(drift) != 0 {
+		t.Errorf("expected no drift, got %v", drift)
+	}
+
+	drifted := &wireguard.Config{
+		Interface: wireguard.Interface{PrivateKey: "other", ListenPort: 51821},
+		Peers: map[string]wireguard.Peer{
+			"keyA":      {PublicKey: "keyA", Endpoint: "203.0.113.9:51820", AllowedIPs: []string{"10.99.0.2/32"}},
+			"keyB":      {PublicKey: "keyB", AllowedIPs: []string{"10.99.0.3/32"}},
+			"strangerX": {PublicKey: "strangerX", AllowedIPs: []string{"10.99.0.9/32"}},
+		},
+	}
+	want := []string{
+		"interface has a different private key",
+		"listen port is 51821, want 51820",
+		"missing peer node4",
+		"unexpected peer strangerX",
+		"peer node2 has AllowedIPs 10.99.0.2/32, want 10.99.0.2/32,192.168.10.0/24",
+		"peer node2 has endpoint 203.0.113.9:51820, want 203.0.113.1:51820",
+	}
+	if drift := configDrift(desired, drifted, names); !reflect.DeepEqual(drift, want) {
+		t.Errorf("configDrift() =\n%q\nwant\n%q", drift, want)
+	}
+}
+
+func TestSameEndpoint(t *testing.T) {
+	if !sameEndpoint("203.0.113.1:51820", "203.0.113.1:51820") {
+		t.Error("identical endpoints should match")
+	}
+	if sameEndpoint("203.0.113.1:51821", "203.0.113.1:51820") {
+		t.Error("different ports should not match")
+	}
+	if sameEndpoint("(none)", "203.0.113.1:51820") {
+		t.Error("a peer without endpoint should not match")
+	}
+	if !sameEndpoint("127.0.0.1:51820", "localhost:51820") {
+		t.Error("a hostname endpoint should match its resolved address")
+	}
+}
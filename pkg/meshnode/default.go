@@ -0,0 +1,70 @@
+package meshnode
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultStartTimeout bounds how long Listen and Dial wait for the default
+// node to come up.
+const DefaultStartTimeout = 30 * time.Second
+
+var defaultNode struct {
+	mu   sync.Mutex
+	node *Node
+}
+
+// Default returns the node Listen and Dial use, starting it on first use: a
+// userspace node joining the mesh whose secret is in $WGMESH_SECRET, with
+// its state in $WGMESH_STATE_DIR if set. A node that stopped is replaced on
+// the next call.
+func Default() (*Node, error) {
+	defaultNode.mu.Lock()
+	defer defaultNode.mu.Unlock()
+	if n := defaultNode.node; n != nil {
+		select {
+		case <-n.done:
+		default:
+			return n, nil
+		}
+	}
+	secret := os.Getenv("WGMESH_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("meshnode: WGMESH_SECRET is not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultStartTimeout)
+	defer cancel()
+	n, err := Start(ctx, Options{
+		Secret:    secret,
+		Userspace: true,
+		StateDir:  os.Getenv("WGMESH_STATE_DIR"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defaultNode.node = n
+	return n, nil
+}
+
+// Listen accepts connections from the mesh on the default node; see
+// Node.Listen. Peers reach ":8080" at the node's mesh IP, port 8080.
+func Listen(network, address string) (net.Listener, error) {
+	n, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return n.Listen(network, address)
+}
+
+// Dial connects over the mesh from the default node; see Node.DialContext.
+func Dial(network, address string) (net.Conn, error) {
+	n, err := Default()
+	if err != nil {
+		return nil, err
+	}
+	return n.Dial(network, address)
+}
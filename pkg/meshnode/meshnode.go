@@ -0,0 +1,411 @@
+// Package meshnode embeds a wgmesh node in a Go program.
+//
+//	node, err := meshnode.Start(ctx, meshnode.Options{Secret: secret})
+//	if err != nil { ... }
+//	defer node.Close()
+//	conn, err := node.DialContext(ctx, "tcp", "db-1:5432")
+//
+// The node is the same daemon "wgmesh join" runs: it creates the WireGuard
+// interface, so the program needs the same privileges (root or
+// CAP_NET_ADMIN). With Options.Userspace it runs WireGuard and a TCP/IP
+// stack inside the program instead and needs no privileges, but only the
+// program itself is on the mesh: it listens and dials through the Node.
+// The package-level Listen and Dial use such a node, started on first use:
+//
+//	ln, err := meshnode.Listen("tcp", ":8080") // reachable at the mesh IP
+//
+// The node does not serve the RPC socket; the program talks to it through
+// the Node methods instead. Logging goes through the standard log package;
+// call daemon.ConfigureLogging to change the level.
+package meshnode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	// Registers the DHT and DNS discovery layers with the daemon.
+	_ "github.com/atvirokodosprendimai/wgmesh/pkg/discovery"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/userspace"
+)
+
+var _ daemon.Tunnel = (*userspace.Tunnel)(nil)
+
+// startPoll is how often Start checks whether the node is up.
+const startPoll = 100 * time.Millisecond
+
+// ErrClosed is returned by Wait after Close stopped the node.
+var ErrClosed = errors.New("meshnode: closed")
+
+// Options configures an embedded node. Secret is required; the other
+// fields override the matching fields of Daemon, which carries every
+// setting "wgmesh join" has a flag for.
+type Options struct {
+	Secret          string
+	InterfaceName   string   // default wg0; names the state files in userspace mode
+	ListenPort      int      // WireGuard port, default 51820
+	AdvertiseRoutes []string // networks behind this node; not in userspace mode
+	Tags            string   // e.g. "role=db"
+
+	// Userspace runs WireGuard and its TCP/IP stack inside the program
+	// (wireguard-go with gVisor's netstack): no root, no interface, and
+	// only connections made through the Node use the mesh.
+	Userspace bool
+	// StateDir holds the node's key and caches. The default is
+	// /var/lib/wgmesh, or wgmesh in the user's config directory in
+	// userspace mode. It applies to every node in the process.
+	StateDir string
+
+	Daemon daemon.DaemonOpts
+}
+
+// stateDir returns the state directory to use, "" for the daemon's default.
+func (o Options) stateDir() (string, error) {
+	if o.StateDir != "" || !o.Userspace {
+		return o.StateDir, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("no state directory: %w", err)
+	}
+	return filepath.Join(dir, "wgmesh"), nil
+}
+
+func (o Options) daemonOpts() daemon.DaemonOpts {
+	opts := o.Daemon
+	opts.Secret = o.Secret
+	if o.InterfaceName != "" {
+		opts.InterfaceName = o.InterfaceName
+	}
+	if o.ListenPort != 0 {
+		opts.WGListenPort = o.ListenPort
+	}
+	if len(o.AdvertiseRoutes) > 0 {
+		opts.AdvertiseRoutes = o.AdvertiseRoutes
+	}
+	if o.Tags != "" {
+		opts.Tags = o.Tags
+	}
+	return opts
+}
+
+// Node is a running mesh node.
+type Node struct {
+	d      *daemon.Daemon
+	tunnel *userspace.Tunnel // nil unless Options.Userspace
+	done   chan struct{}
+	err    error // set before done is closed
+	closed sync.Once
+	dialer net.Dialer
+}
+
+// Start brings up a node and returns once its WireGuard interface is up
+// and it has a mesh IP. ctx bounds only the startup; the node runs until
+// Close. Peers are found in the background; use Peers or Subscribe to see
+// them arrive.
+func Start(ctx context.Context, opts Options) (*Node, error) {
+	if opts.Secret == "" {
+		return nil, fmt.Errorf("meshnode: secret is required")
+	}
+	dir, err := opts.stateDir()
+	if err != nil {
+		return nil, fmt.Errorf("meshnode: %w", err)
+	}
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("meshnode: %w", err)
+		}
+		daemon.SetStateDir(dir)
+	}
+	cfg, err := daemon.NewConfig(opts.daemonOpts())
+	if err != nil {
+		return nil, fmt.Errorf("meshnode: %w", err)
+	}
+	// The program owns its signals; it stops the node with Close.
+	cfg.NoSignals = true
+	d, err := daemon.NewDaemon(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("meshnode: %w", err)
+	}
+
+	n := &Node{d: d, done: make(chan struct{})}
+	if opts.Userspace {
+		n.tunnel = userspace.New()
+		d.SetTunnel(n.tunnel)
+	}
+	go func() {
+		n.err = d.RunWithDHTDiscovery()
+		close(n.done)
+	}()
+
+	ticker := time.NewTicker(startPoll)
+	defer ticker.Stop()
+	for !n.up() {
+		select {
+		case <-n.done:
+			if n.err == nil {
+				return nil, fmt.Errorf("meshnode: node stopped during startup")
+			}
+			return nil, fmt.Errorf("meshnode: %w", n.err)
+		case <-ctx.Done():
+			_ = n.Close()
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return n, nil
+}
+
+func (n *Node) up() bool {
+	status := n.d.GetRPCStatus()
+	return status != nil && status.MeshIP != "" && n.d.GetRPCReadiness().InterfaceUp
+}
+
+// MeshIP returns the node's IPv4 mesh address.
+func (n *Node) MeshIP() string {
+	return n.d.GetRPCStatus().MeshIP
+}
+
+// PubKey returns the node's WireGuard public key.
+func (n *Node) PubKey() string {
+	return n.d.GetRPCStatus().PubKey
+}
+
+// Daemon returns the underlying daemon, for what Node does not wrap.
+func (n *Node) Daemon() *daemon.Daemon {
+	return n.d
+}
+
+// Peer is a mesh peer as the node currently knows it.
+type Peer struct {
+	PubKey   string
+	Hostname string
+	MeshIP   string
+	MeshIPv6 string
+	Endpoint string
+	Routes   []string
+	Tags     map[string]string
+	LastSeen time.Time
+}
+
+func peerFrom(p *daemon.PeerInfo) Peer {
+	return Peer{
+		PubKey:   p.WGPubKey,
+		Hostname: p.Hostname,
+		MeshIP:   p.MeshIP,
+		MeshIPv6: p.MeshIPv6,
+		Endpoint: p.Endpoint,
+		Routes:   append([]string(nil), p.RoutableNetworks...),
+		Tags:     p.Tags,
+		LastSeen: p.LastSeen,
+	}
+}
+
+// Peers returns the peers that are currently active.
+func (n *Node) Peers() []Peer {
+	active := n.d.GetPeerStore().GetActive()
+	peers := make([]Peer, 0, len(active))
+	for _, p := range active {
+		peers = append(peers, peerFrom(p))
+	}
+	return peers
+}
+
+// EventKind says what happened to a peer.
+type EventKind int
+
+const (
+	PeerJoined  EventKind = iota // first seen
+	PeerUpdated                  // announced again or changed, e.g. roamed
+	PeerLeft                     // evicted as unresponsive or forgotten after PeerRemoveTimeout
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case PeerJoined:
+		return "joined"
+	case PeerUpdated:
+		return "updated"
+	case PeerLeft:
+		return "left"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// Event is a change to one peer. Peer is empty apart from PubKey for
+// PeerLeft.
+type Event struct {
+	Kind EventKind
+	Peer Peer
+}
+
+// Subscribe delivers peer events until ctx is done or the node stops,
+// then closes the returned channel. Events are dropped rather than
+// blocking the node when the receiver falls behind, so treat them as hints
+// and re-read Peers when the exact state matters.
+func (n *Node) Subscribe(ctx context.Context) <-chan Event {
+	store := n.d.GetPeerStore()
+	in := store.Subscribe("meshnode")
+	out := make(chan Event, daemon.PeerEventBufSize)
+	go func() {
+		defer close(out)
+		defer store.Unsubscribe(in)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-n.done:
+				return
+			case ev, ok := <-in:
+				if !ok {
+					return
+				}
+				e, ok := n.event(ev)
+				if !ok {
+					continue
+				}
+				select {
+				case out <- e:
+				default:
+				}
+			}
+		}
+	}()
+	return out
+}
+
+func (n *Node) event(ev daemon.PeerEvent) (Event, bool) {
+	if ev.Kind == daemon.PeerEventRemoved {
+		return Event{Kind: PeerLeft, Peer: Peer{PubKey: ev.PubKey}}, true
+	}
+	if local := n.d.GetLocalNode(); local != nil && local.WGPubKey == ev.PubKey {
+		return Event{}, false
+	}
+	// A peer removed before its event was read is reported by PeerLeft.
+	p, ok := n.d.GetPeerStore().Get(ev.PubKey)
+	if !ok {
+		return Event{}, false
+	}
+	kind := PeerUpdated
+	if ev.Kind == daemon.PeerEventNew {
+		kind = PeerJoined
+	}
+	return Event{Kind: kind, Peer: peerFrom(p)}, true
+}
+
+// DialContext connects to address over the mesh. The host may be a mesh
+// IP, a peer's hostname (optionally ending in ".mesh") or a public key
+// prefix; anything else is dialled as given, which a userspace node can
+// only do for IP literals. Its signature matches net.Dialer.DialContext, so
+// it can be used as an http.Transport DialContext.
+func (n *Node) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if ip, err := n.resolve(host); err == nil {
+		address = net.JoinHostPort(ip, port)
+	}
+	if n.tunnel != nil {
+		return n.tunnel.DialContext(ctx, network, address)
+	}
+	return n.dialer.DialContext(ctx, network, address)
+}
+
+// Dial is DialContext without a context.
+func (n *Node) Dial(network, address string) (net.Conn, error) {
+	return n.DialContext(context.Background(), network, address)
+}
+
+// Listen accepts connections from the mesh. An empty host listens on the
+// node's mesh IP (IPv6 for tcp6); so does "0.0.0.0" in userspace mode,
+// where only mesh addresses exist. Peers reach the listener at
+// MeshIP():port.
+func (n *Node) Listen(network, address string) (net.Listener, error) {
+	address, err := n.listenAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if n.tunnel != nil {
+		return n.tunnel.Listen(network, address)
+	}
+	return net.Listen(network, address)
+}
+
+// ListenPacket is Listen for UDP.
+func (n *Node) ListenPacket(network, address string) (net.PacketConn, error) {
+	address, err := n.listenAddr(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if n.tunnel != nil {
+		return n.tunnel.ListenPacket(network, address)
+	}
+	return net.ListenPacket(network, address)
+}
+
+// listenAddr fills in the mesh IP when address has no host.
+func (n *Node) listenAddr(network, address string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", err
+	}
+	if host != "" {
+		return address, nil
+	}
+	local := n.d.GetLocalNode()
+	if local == nil {
+		return "", fmt.Errorf("meshnode: node is not up")
+	}
+	ip := local.MeshIP
+	if strings.HasSuffix(network, "6") {
+		ip = local.MeshIPv6
+	}
+	if ip == "" {
+		return "", fmt.Errorf("meshnode: no mesh address for %s", network)
+	}
+	return net.JoinHostPort(ip, port), nil
+}
+
+// resolve maps a peer name to its mesh IP. IP literals are left alone.
+func (n *Node) resolve(host string) (string, error) {
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+	p, err := n.d.ResolvePeer(strings.TrimSuffix(host, ".mesh"))
+	if err != nil {
+		return "", err
+	}
+	if p.MeshIP == "" {
+		return p.MeshIPv6, nil
+	}
+	return p.MeshIP, nil
+}
+
+// Close stops the node, removes its interface and waits for it to finish.
+// It returns the error the daemon stopped with, if any.
+func (n *Node) Close() error {
+	n.closed.Do(n.d.Shutdown)
+	<-n.done
+	return n.err
+}
+
+// Wait blocks until the node stops and returns why: ErrClosed after
+// Close, daemon.ErrSecretRotated when the mesh moved to a new secret (start
+// a new node with it), daemon.ErrMeshIPChanged when a userspace node lost
+// its mesh IP to another node (start a new node to use the new one), or
+// the error that stopped the daemon.
+func (n *Node) Wait() error {
+	<-n.done
+	if n.err != nil {
+		return n.err
+	}
+	return ErrClosed
+}
@@ -0,0 +1,127 @@
+package meshnode
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+const testSecret = "wgmesh-test-secret-for-meshnode-0123456789"
+
+func newTestNode(t *testing.T) *Node {
+	t.Helper()
+	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: testSecret, InterfaceName: "wgtest0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := daemon.NewDaemon(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(d.Shutdown)
+	return &Node{d: d, done: make(chan struct{})}
+}
+
+func TestStartRequiresSecret(t *testing.T) {
+	if _, err := Start(context.Background(), Options{}); err == nil || !strings.Contains(err.Error(), "secret is required") {
+		t.Errorf("err = %v, want secret is required", err)
+	}
+}
+
+func TestOptionsOverrideDaemon(t *testing.T) {
+	opts := Options{
+		Secret:     testSecret,
+		ListenPort: 51999,
+		Tags:       "role=db",
+		Daemon:     daemon.DaemonOpts{InterfaceName: "wg7", WGListenPort: 51000, Gossip: true},
+	}.daemonOpts()
+	if opts.Secret != testSecret || opts.WGListenPort != 51999 || opts.Tags != "role=db" {
+		t.Errorf("fields were not applied: %+v", opts)
+	}
+	if opts.InterfaceName != "wg7" || !opts.Gossip {
+		t.Errorf("daemon options were lost: %+v", opts)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	n := newTestNode(t)
+	store := n.d.GetPeerStore()
+	store.Update(&daemon.PeerInfo{WGPubKey: "AAAApeerkey1", Hostname: "db-1", MeshIP: "10.42.0.7"}, "test")
+	store.Update(&daemon.PeerInfo{WGPubKey: "BBBBpeerkey2", Hostname: "v6only", MeshIPv6: "fd00::9"}, "test")
+
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"db-1", "10.42.0.7"},
+		{"db-1.mesh", "10.42.0.7"},
+		{"AAAA", "10.42.0.7"},
+		{"v6only", "fd00::9"},
+		{"192.0.2.1", "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		got, err := n.resolve(tt.host)
+		if err != nil || got != tt.want {
+			t.Errorf("resolve(%q) = %q, %v; want %q", tt.host, got, err, tt.want)
+		}
+	}
+	if _, err := n.resolve("example.com"); err == nil {
+		t.Error("resolve(example.com) should not match a peer")
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	n := newTestNode(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	events := n.Subscribe(ctx)
+
+	store := n.d.GetPeerStore()
+	changes := []func(){
+		func() { store.Update(&daemon.PeerInfo{WGPubKey: "peer1", MeshIP: "10.42.0.7"}, "test") },
+		func() { store.Update(&daemon.PeerInfo{WGPubKey: "peer1", Endpoint: "192.0.2.1:51820"}, "test") },
+		func() { store.Remove("peer1") },
+	}
+	for i, want := range []EventKind{PeerJoined, PeerUpdated, PeerLeft} {
+		changes[i]()
+		select {
+		case ev := <-events:
+			if ev.Kind != want || ev.Peer.PubKey != "peer1" {
+				t.Errorf("got %s for %q, want %s for peer1", ev.Kind, ev.Peer.PubKey, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("unexpected event after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Error("channel not closed after cancel")
+	}
+}
+
+func TestUserspaceStateDir(t *testing.T) {
+	if dir, err := (Options{}).stateDir(); err != nil || dir != "" {
+		t.Errorf("kernel mode state dir = %q, %v; want the daemon default", dir, err)
+	}
+	if dir, _ := (Options{Userspace: true, StateDir: "/srv/mesh"}).stateDir(); dir != "/srv/mesh" {
+		t.Errorf("state dir = %q, want /srv/mesh", dir)
+	}
+	t.Setenv("XDG_CONFIG_HOME", "/home/app/.config")
+	config, err := os.UserConfigDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	if dir, _ := (Options{Userspace: true}).stateDir(); dir != filepath.Join(config, "wgmesh") {
+		t.Errorf("userspace state dir = %q, want wgmesh in %s", dir, config)
+	}
+}
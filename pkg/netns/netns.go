@@ -0,0 +1,255 @@
+// Package netns gives a container access to the mesh without host
+// networking.
+//
+// Attach creates a veth pair between the host and the container's network
+// namespace, routes the mesh subnet through it and masquerades the
+// container's traffic as the host's mesh IP, so peers need no route to the
+// container. Host-side ends are named wgns<N> and use the link-local /30
+// 169.254.200.<4N>, which keeps several attached containers apart without
+// any state on disk; the container end is always LinkName. Everything is
+// done with ip, nsenter, sysctl, nft and iptables.
+package netns
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// LinkName is the name of the mesh interface inside the container.
+const LinkName = "mesh0"
+
+const (
+	hostLinkPrefix = "wgns"
+	maxLinks       = 64 // /30s in 169.254.200.0/24
+	natTable       = "wgmesh_netns"
+)
+
+// runFunc runs a command, feeding stdin if non-empty, and returns its
+// combined output.
+type runFunc func(stdin string, name string, args ...string) ([]byte, error)
+
+var run runFunc = execRun
+
+// Attachment describes a container attached to the mesh.
+type Attachment struct {
+	PID         int
+	HostLink    string
+	HostAddr    string // gateway for the container, on HostLink
+	ContainerIP string // address of LinkName inside the container
+	MeshSubnet  string // routed through the host
+}
+
+// ContainerPID returns the PID of the container's init process, as
+// reported by runtime inspect (docker, podman). A decimal target is taken
+// as a PID already.
+func ContainerPID(runtime, container string) (int, error) {
+	if pid, err := strconv.Atoi(container); err == nil && pid > 0 {
+		return pid, nil
+	}
+	out, err := run("", runtime, "inspect", "--format", "{{.State.Pid}}", container)
+	if err != nil {
+		return 0, fmt.Errorf("%s inspect %s: %s: %w", runtime, container, strings.TrimSpace(string(out)), err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("container %s is not running", container)
+	}
+	return pid, nil
+}
+
+// Attach connects the network namespace of pid to the mesh behind
+// meshIface. It fails if the namespace already has a LinkName interface.
+func Attach(pid int, meshIface string) (*Attachment, error) {
+	subnet, err := meshSubnet(meshIface)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := nsRun(pid, "ip", "link", "show", LinkName); err == nil {
+		return nil, fmt.Errorf("process %d already has a %s interface; detach it first", pid, LinkName)
+	}
+	index, err := freeLinkIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Attachment{
+		PID:         pid,
+		HostLink:    hostLinkPrefix + strconv.Itoa(index),
+		HostAddr:    fmt.Sprintf("169.254.200.%d", 4*index+1),
+		ContainerIP: fmt.Sprintf("169.254.200.%d", 4*index+2),
+		MeshSubnet:  subnet,
+	}
+	peer := a.HostLink + "c"
+	if err := runAll([][]string{
+		{"ip", "link", "add", a.HostLink, "type", "veth", "peer", "name", peer},
+		{"ip", "link", "set", peer, "netns", strconv.Itoa(pid)},
+		{"ip", "addr", "add", a.HostAddr + "/30", "dev", a.HostLink},
+		{"ip", "link", "set", a.HostLink, "up"},
+	}); err != nil {
+		_, _ = run("", "ip", "link", "del", a.HostLink)
+		return nil, err
+	}
+	for _, args := range [][]string{
+		{"ip", "link", "set", peer, "name", LinkName},
+		{"ip", "addr", "add", a.ContainerIP + "/30", "dev", LinkName},
+		{"ip", "link", "set", LinkName, "up"},
+		{"ip", "route", "add", subnet, "via", a.HostAddr, "dev", LinkName},
+	} {
+		if out, err := nsRun(pid, args...); err != nil {
+			_, _ = run("", "ip", "link", "del", a.HostLink)
+			return nil, fmt.Errorf("in process %d: %s: %s: %w", pid, strings.Join(args, " "), strings.TrimSpace(string(out)), err)
+		}
+	}
+
+	if err := setupForwarding(meshIface); err != nil {
+		_, _ = run("", "ip", "link", "del", a.HostLink)
+		return nil, err
+	}
+	return a, nil
+}
+
+// Detach removes the mesh interface from the namespace of pid; deleting
+// one end of the veth pair deletes the host end too. Once no container is
+// attached any more, the NAT and forwarding rules are removed as well.
+func Detach(pid int, meshIface string) error {
+	if out, err := nsRun(pid, "ip", "link", "del", LinkName); err != nil {
+		return fmt.Errorf("in process %d: ip link del %s: %s: %w", pid, LinkName, strings.TrimSpace(string(out)), err)
+	}
+	links, err := hostLinks()
+	if err != nil {
+		return err
+	}
+	if len(links) == 0 {
+		teardownForwarding(meshIface)
+	}
+	return nil
+}
+
+// meshSubnet returns the IPv4 network of the mesh interface's address.
+func meshSubnet(meshIface string) (string, error) {
+	out, err := run("", "ip", "-o", "-4", "addr", "show", "dev", meshIface)
+	if err != nil {
+		return "", fmt.Errorf("mesh interface %s: %s: %w", meshIface, strings.TrimSpace(string(out)), err)
+	}
+	fields := strings.Fields(string(out))
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] != "inet" {
+			continue
+		}
+		if _, ipNet, err := net.ParseCIDR(fields[i+1]); err == nil {
+			return ipNet.String(), nil
+		}
+	}
+	return "", fmt.Errorf("mesh interface %s has no IPv4 address (is wgmesh running?)", meshIface)
+}
+
+var hostLinkPattern = regexp.MustCompile(`^\d+: ` + hostLinkPrefix + `(\d+)[@:]`)
+
+// hostLinks returns the indexes of the wgns<N> links on the host.
+func hostLinks() (map[int]bool, error) {
+	out, err := run("", "ip", "-o", "link", "show")
+	if err != nil {
+		return nil, fmt.Errorf("ip link show: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	links := make(map[int]bool)
+	for _, line := range strings.Split(string(out), "\n") {
+		if m := hostLinkPattern.FindStringSubmatch(line); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			links[n] = true
+		}
+	}
+	return links, nil
+}
+
+func freeLinkIndex() (int, error) {
+	links, err := hostLinks()
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < maxLinks; i++ {
+		if !links[i] {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("all %d container links are in use", maxLinks)
+}
+
+// setupForwarding enables IPv4 forwarding and masquerades traffic from the
+// wgns links into the mesh. Docker sets the FORWARD policy to DROP, so on
+// Docker hosts the traffic is also accepted in DOCKER-USER.
+func setupForwarding(meshIface string) error {
+	if out, err := run("", "sysctl", "-w", "net.ipv4.ip_forward=1"); err != nil {
+		return fmt.Errorf("failed to enable IPv4 forwarding: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	if out, err := run(MasqueradeRuleset(meshIface), "nft", "-f", "-"); err != nil {
+		return fmt.Errorf("failed to install masquerade rules: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	if _, err := run("", "iptables", "-n", "-L", "DOCKER-USER"); err != nil {
+		return nil
+	}
+	for _, rule := range dockerUserRules(meshIface) {
+		if _, err := run("", "iptables", append([]string{"-C", "DOCKER-USER"}, rule...)...); err == nil {
+			continue
+		}
+		args := append([]string{"-I", "DOCKER-USER"}, rule...)
+		if out, err := run("", "iptables", args...); err != nil {
+			return fmt.Errorf("iptables %s: %s: %w", strings.Join(args, " "), strings.TrimSpace(string(out)), err)
+		}
+	}
+	return nil
+}
+
+// teardownForwarding removes what setupForwarding installed, apart from
+// the forwarding sysctl, which other software on the host may rely on.
+func teardownForwarding(meshIface string) {
+	_, _ = run("", "nft", "delete", "table", "ip", natTable)
+	for _, rule := range dockerUserRules(meshIface) {
+		_, _ = run("", "iptables", append([]string{"-D", "DOCKER-USER"}, rule...)...)
+	}
+}
+
+// MasqueradeRuleset renders the nft script that replaces the NAT table.
+func MasqueradeRuleset(meshIface string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "table ip %s\n", natTable)
+	fmt.Fprintf(&sb, "delete table ip %s\n", natTable)
+	fmt.Fprintf(&sb, "table ip %s {\n", natTable)
+	sb.WriteString("\tchain postrouting {\n")
+	sb.WriteString("\t\ttype nat hook postrouting priority srcnat; policy accept;\n")
+	fmt.Fprintf(&sb, "\t\tiifname %q oifname %q masquerade\n", hostLinkPrefix+"*", meshIface)
+	sb.WriteString("\t}\n}\n")
+	return sb.String()
+}
+
+func dockerUserRules(meshIface string) [][]string {
+	return [][]string{
+		{"-i", hostLinkPrefix + "+", "-o", meshIface, "-j", "ACCEPT"},
+		{"-i", meshIface, "-o", hostLinkPrefix + "+", "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"},
+	}
+}
+
+// nsRun runs a command inside the network namespace of pid.
+func nsRun(pid int, args ...string) ([]byte, error) {
+	return run("", "nsenter", append([]string{"--net=/proc/" + strconv.Itoa(pid) + "/ns/net", "--"}, args...)...)
+}
+
+func runAll(cmds [][]string) error {
+	for _, args := range cmds {
+		if out, err := run("", args[0], args[1:]...); err != nil {
+			return fmt.Errorf("%s: %s: %w", strings.Join(args, " "), strings.TrimSpace(string(out)), err)
+		}
+	}
+	return nil
+}
+
+func execRun(stdin string, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	return cmd.CombinedOutput()
+}
@@ -0,0 +1,170 @@
+package netns
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// fakeHost answers the commands Attach and Detach run and records them.
+type fakeHost struct {
+	links   string // output of ip -o link show
+	hasMesh bool   // the namespace already has mesh0
+	docker  bool   // DOCKER-USER exists
+	cmds    []string
+	stdin   []string
+}
+
+func (h *fakeHost) run(stdin string, name string, args ...string) ([]byte, error) {
+	cmd := strings.Join(append([]string{name}, args...), " ")
+	h.cmds = append(h.cmds, cmd)
+	if stdin != "" {
+		h.stdin = append(h.stdin, stdin)
+	}
+	switch {
+	case cmd == "ip -o -4 addr show dev wg0":
+		return []byte("7: wg0    inet 10.99.12.34/16 scope global wg0\\       valid_lft forever preferred_lft forever\n"), nil
+	case cmd == "ip -o link show":
+		return []byte(h.links), nil
+	case strings.HasSuffix(cmd, "ip link show mesh0"):
+		if h.hasMesh {
+			return nil, nil
+		}
+		return []byte("Device \"mesh0\" does not exist."), fmt.Errorf("exit status 1")
+	case cmd == "iptables -n -L DOCKER-USER", strings.HasPrefix(cmd, "iptables -C"):
+		if !h.docker {
+			return nil, fmt.Errorf("exit status 1")
+		}
+		// Rules are checked only after the chain exists; pretend they do not.
+		if strings.HasPrefix(cmd, "iptables -C") {
+			return nil, fmt.Errorf("exit status 1")
+		}
+		return nil, nil
+	}
+	return nil, nil
+}
+
+func (h *fakeHost) install(t *testing.T) {
+	prev := run
+	run = h.run
+	t.Cleanup(func() { run = prev })
+}
+
+func (h *fakeHost) ran(cmd string) bool {
+	for _, c := range h.cmds {
+		if c == cmd {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAttach(t *testing.T) {
+	h := &fakeHost{
+		links:  "1: lo: <LOOPBACK,UP> mtu 65536\n5: wgns0@if4: <BROADCAST,UP> mtu 1500\n9: wgns2@if8: <BROADCAST,UP> mtu 1500\n",
+		docker: true,
+	}
+	h.install(t)
+
+	a, err := Attach(4242, "wg0")
+	if err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	if a.HostLink != "wgns1" || a.HostAddr != "169.254.200.5" || a.ContainerIP != "169.254.200.6" || a.MeshSubnet != "10.99.0.0/16" {
+		t.Errorf("attachment = %+v", a)
+	}
+	for _, want := range []string{
+		"ip link add wgns1 type veth peer name wgns1c",
+		"ip link set wgns1c netns 4242",
+		"ip addr add 169.254.200.5/30 dev wgns1",
+		"nsenter --net=/proc/4242/ns/net -- ip link set wgns1c name mesh0",
+		"nsenter --net=/proc/4242/ns/net -- ip route add 10.99.0.0/16 via 169.254.200.5 dev mesh0",
+		"sysctl -w net.ipv4.ip_forward=1",
+		"nft -f -",
+		"iptables -I DOCKER-USER -i wgns+ -o wg0 -j ACCEPT",
+	} {
+		if !h.ran(want) {
+			t.Errorf("did not run %q; ran:\n%s", want, strings.Join(h.cmds, "\n"))
+		}
+	}
+	if len(h.stdin) != 1 || !strings.Contains(h.stdin[0], `iifname "wgns*" oifname "wg0" masquerade`) {
+		t.Errorf("nft ruleset = %q", h.stdin)
+	}
+}
+
+func TestAttachWithoutDocker(t *testing.T) {
+	h := &fakeHost{}
+	h.install(t)
+
+	if _, err := Attach(4242, "wg0"); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	for _, c := range h.cmds {
+		if strings.HasPrefix(c, "iptables -I") {
+			t.Errorf("added %q without a DOCKER-USER chain", c)
+		}
+	}
+}
+
+func TestAttachTwice(t *testing.T) {
+	h := &fakeHost{hasMesh: true}
+	h.install(t)
+
+	_, err := Attach(4242, "wg0")
+	if err == nil || !strings.Contains(err.Error(), "already has a mesh0") {
+		t.Fatalf("err = %v, want already attached", err)
+	}
+	for _, c := range h.cmds {
+		if strings.HasPrefix(c, "ip link add") {
+			t.Errorf("created a link anyway: %q", c)
+		}
+	}
+}
+
+func TestDetach(t *testing.T) {
+	tests := []struct {
+		name     string
+		links    string
+		teardown bool
+	}{
+		{name: "last container", links: "1: lo: <LOOPBACK,UP>\n", teardown: true},
+		{name: "others attached", links: "5: wgns0@if4: <BROADCAST,UP>\n", teardown: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &fakeHost{links: tt.links}
+			h.install(t)
+
+			if err := Detach(4242, "wg0"); err != nil {
+				t.Fatalf("Detach: %v", err)
+			}
+			if !h.ran("nsenter --net=/proc/4242/ns/net -- ip link del mesh0") {
+				t.Errorf("did not delete mesh0; ran %v", h.cmds)
+			}
+			if got := h.ran("nft delete table ip wgmesh_netns"); got != tt.teardown {
+				t.Errorf("removed NAT table = %v, want %v", got, tt.teardown)
+			}
+		})
+	}
+}
+
+func TestContainerPID(t *testing.T) {
+	h := &fakeHost{}
+	h.install(t)
+	if pid, err := ContainerPID("docker", "1234"); err != nil || pid != 1234 {
+		t.Errorf("ContainerPID(1234) = %d, %v", pid, err)
+	}
+	if len(h.cmds) != 0 {
+		t.Errorf("inspected a PID: %v", h.cmds)
+	}
+
+	run = func(stdin string, name string, args ...string) ([]byte, error) {
+		if name != "podman" || strings.Join(args, " ") != "inspect --format {{.State.Pid}} web" {
+			t.Errorf("ran %s %v", name, args)
+		}
+		return []byte("0\n"), nil
+	}
+	if _, err := ContainerPID("podman", "web"); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Errorf("err = %v, want not running", err)
+	}
+}
@@ -0,0 +1,196 @@
+package node
+
+import (
+	"log"
+	"sync"
+)
+
+// PeerEventQueueSize bounds how many peers' events wait for one subscriber.
+const PeerEventQueueSize = 256
+
+// subscriber is one Subscribe channel with the queue that feeds it.
+type subscriber struct {
+	name   string
+	ch     chan PeerEvent
+	wake   chan struct{}
+	done   chan struct{}
+	exited chan struct{}
+
+	mu          sync.Mutex
+	queue       []PeerEvent // at most one event per peer, oldest first
+	delivered   uint64
+	coalesced   uint64
+	dropped     uint64
+	overflowing bool // dropping since the queue was last drained
+}
+
+// PeerEventSubscriberStats describes how one subscriber keeps up with the
+// store's events.
+type PeerEventSubscriberStats struct {
+	Name      string
+	Queued    int    // events waiting to be read
+	Delivered uint64 // events read from the channel
+	Coalesced uint64 // events merged into one already waiting for the same peer
+	Dropped   uint64 // events lost because the queue was full
+}
+
+// Subscribe returns a channel of peer events; name identifies the
+// subscriber in SubscriberStats and logs. Delivery works as follows:
+//
+//   - Publishing never blocks. A subscriber that falls behind only delays
+//     its own events.
+//   - Each subscriber has its own queue of up to PeerEventQueueSize peers.
+//     An event for a peer that already has one waiting is merged into it:
+//     a removal replaces what was waiting, an update to a new peer stays
+//     PeerEventNew, and endpoint changes combine into one from the first
+//     PrevEndpoint to the last Endpoint.
+//   - Events for different peers arrive in the order they were first
+//     queued.
+//   - When the queue is full, events for further peers are dropped and
+//     counted. A subscriber that must not miss a peer re-reads the store,
+//     e.g. when its Dropped count goes up.
+//   - Unsubscribe discards what is still queued and closes the channel.
+func (ps *PeerStore) Subscribe(name string) <-chan PeerEvent {
+	sub := &subscriber{
+		name:   name,
+		ch:     make(chan PeerEvent),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go sub.run()
+
+	ps.mu.Lock()
+	ps.subscribers = append(ps.subscribers, sub)
+	ps.mu.Unlock()
+	return sub.ch
+}
+
+// Unsubscribe stops the events of a channel returned by Subscribe and
+// closes it.
+func (ps *PeerStore) Unsubscribe(ch <-chan PeerEvent) {
+	ps.mu.Lock()
+	var sub *subscriber
+	for i, s := range ps.subscribers {
+		if s.ch == ch {
+			sub = s
+			ps.subscribers = append(ps.subscribers[:i], ps.subscribers[i+1:]...)
+			break
+		}
+	}
+	ps.mu.Unlock()
+
+	if sub != nil {
+		close(sub.done)
+		<-sub.exited
+	}
+}
+
+// SubscriberStats returns the delivery counters of every subscriber.
+func (ps *PeerStore) SubscriberStats() []PeerEventSubscriberStats {
+	ps.mu.RLock()
+	subs := append([]*subscriber(nil), ps.subscribers...)
+	ps.mu.RUnlock()
+
+	stats := make([]PeerEventSubscriberStats, len(subs))
+	for i, sub := range subs {
+		sub.mu.Lock()
+		stats[i] = PeerEventSubscriberStats{
+			Name:      sub.name,
+			Queued:    len(sub.queue),
+			Delivered: sub.delivered,
+			Coalesced: sub.coalesced,
+			Dropped:   sub.dropped,
+		}
+		sub.mu.Unlock()
+	}
+	return stats
+}
+
+func (ps *PeerStore) notify(ev PeerEvent) {
+	ps.mu.RLock()
+	subs := append([]*subscriber(nil), ps.subscribers...)
+	ps.mu.RUnlock()
+
+	for _, sub := range subs {
+		sub.publish(ev)
+	}
+}
+
+// publish queues ev, merging it into an event already waiting for the
+// same peer.
+func (s *subscriber) publish(ev PeerEvent) {
+	s.mu.Lock()
+	for i := range s.queue {
+		if s.queue[i].PubKey == ev.PubKey {
+			s.queue[i].merge(ev)
+			s.coalesced++
+			s.mu.Unlock()
+			return
+		}
+	}
+	if len(s.queue) >= PeerEventQueueSize {
+		s.dropped++
+		first := !s.overflowing
+		s.overflowing = true
+		s.mu.Unlock()
+		if first {
+			log.Printf("[PeerStore] subscriber %s is falling behind; dropping peer events", s.name)
+		}
+		return
+	}
+	s.queue = append(s.queue, ev)
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run hands queued events to the channel until Unsubscribe.
+func (s *subscriber) run() {
+	defer close(s.exited)
+	defer close(s.ch)
+
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.overflowing = false
+			s.mu.Unlock()
+			select {
+			case <-s.done:
+				return
+			case <-s.wake:
+			}
+			continue
+		}
+		ev := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+
+		select {
+		case <-s.done:
+			return
+		case s.ch <- ev:
+			s.mu.Lock()
+			s.delivered++
+			s.mu.Unlock()
+		}
+	}
+}
+
+// merge folds a later event for the same peer into e.
+func (e *PeerEvent) merge(later PeerEvent) {
+	switch {
+	case later.Kind == PeerEventRemoved || e.Kind == PeerEventRemoved:
+		*e = later
+	case e.Kind == PeerEventNew:
+		// The subscriber reads the new peer as it is now.
+	case later.EndpointChanged():
+		if !e.EndpointChanged() {
+			e.PrevEndpoint = later.PrevEndpoint
+		}
+		e.Endpoint = later.Endpoint
+	}
+}
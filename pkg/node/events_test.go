@@ -0,0 +1,76 @@
+package node
+
+import "testing"
+
+func TestPeerEventMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		queued PeerEvent
+		later  PeerEvent
+		want   PeerEvent
+	}{
+		{
+			name:   "update to a new peer",
+			queued: PeerEvent{PubKey: "k", Kind: PeerEventNew},
+			later:  PeerEvent{PubKey: "k", Kind: PeerEventUpdated, Endpoint: "2.2.2.2:1", PrevEndpoint: "1.1.1.1:1"},
+			want:   PeerEvent{PubKey: "k", Kind: PeerEventNew},
+		},
+		{
+			name:   "removal",
+			queued: PeerEvent{PubKey: "k", Kind: PeerEventUpdated, Endpoint: "2.2.2.2:1", PrevEndpoint: "1.1.1.1:1"},
+			later:  PeerEvent{PubKey: "k", Kind: PeerEventRemoved},
+			want:   PeerEvent{PubKey: "k", Kind: PeerEventRemoved},
+		},
+		{
+			name:   "back after removal",
+			queued: PeerEvent{PubKey: "k", Kind: PeerEventRemoved},
+			later:  PeerEvent{PubKey: "k", Kind: PeerEventNew},
+			want:   PeerEvent{PubKey: "k", Kind: PeerEventNew},
+		},
+		{
+			name:   "endpoint changes combine",
+			queued: PeerEvent{PubKey: "k", Kind: PeerEventUpdated, Endpoint: "2.2.2.2:1", PrevEndpoint: "1.1.1.1:1"},
+			later:  PeerEvent{PubKey: "k", Kind: PeerEventUpdated, Endpoint: "3.3.3.3:1", PrevEndpoint: "2.2.2.2:1"},
+			want:   PeerEvent{PubKey: "k", Kind: PeerEventUpdated, Endpoint: "3.3.3.3:1", PrevEndpoint: "1.1.1.1:1"},
+		},
+		{
+			name:   "endpoint change after a plain update",
+			queued: PeerEvent{PubKey: "k", Kind: PeerEventUpdated},
+			later:  PeerEvent{PubKey: "k", Kind: PeerEventUpdated, Endpoint: "2.2.2.2:1", PrevEndpoint: "1.1.1.1:1"},
+			want:   PeerEvent{PubKey: "k", Kind: PeerEventUpdated, Endpoint: "2.2.2.2:1", PrevEndpoint: "1.1.1.1:1"},
+		},
+		{
+			name:   "plain update after an endpoint change",
+			queued: PeerEvent{PubKey: "k", Kind: PeerEventUpdated, Endpoint: "2.2.2.2:1", PrevEndpoint: "1.1.1.1:1"},
+			later:  PeerEvent{PubKey: "k", Kind: PeerEventUpdated},
+			want:   PeerEvent{PubKey: "k", Kind: PeerEventUpdated, Endpoint: "2.2.2.2:1", PrevEndpoint: "1.1.1.1:1"},
+		},
+	}
+	for _, tt := range tests {
+		got := tt.queued
+		got.merge(tt.later)
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	roamedBack := PeerEvent{PubKey: "k", Kind: PeerEventUpdated, Endpoint: "2.2.2.2:1", PrevEndpoint: "1.1.1.1:1"}
+	roamedBack.merge(PeerEvent{PubKey: "k", Kind: PeerEventUpdated, Endpoint: "1.1.1.1:1", PrevEndpoint: "2.2.2.2:1"})
+	if roamedBack.EndpointChanged() {
+		t.Errorf("a peer that roamed back reports an endpoint change: %+v", roamedBack)
+	}
+}
+
+func TestUnsubscribeClosesChannel(t *testing.T) {
+	ps := NewPeerStore()
+	ch := ps.Subscribe("test")
+	ps.Update(&PeerInfo{WGPubKey: "k", MeshIP: "10.0.0.1"}, "dht")
+	ps.Unsubscribe(ch)
+
+	if _, ok := <-ch; ok {
+		t.Error("channel still delivers after Unsubscribe")
+	}
+	if stats := ps.SubscriberStats(); len(stats) != 0 {
+		t.Errorf("stats after Unsubscribe = %+v", stats)
+	}
+}
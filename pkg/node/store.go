@@ -11,11 +11,14 @@ import (
 const (
 	PeerDeadTimeout   = 5 * time.Minute
 	PeerRemoveTimeout = 10 * time.Minute
-	PeerEventBufSize  = 16
+	PeerEventBufSize  = 16 // buffer of channels that pass peer events on, such as meshnode's
 	DefaultMaxPeers   = 1000
+	PeerProbeWindow   = 20 // mesh probe results kept per peer for RTT/loss stats
 
 	LANMethod        = "lan"
 	RendezvousMethod = "dht-rendezvous"
+	StaticMethod     = "static" // defined by an operator rather than discovered
+	TCPMethod        = "tcp-transport"
 )
 
 type PeerEventKind int
@@ -23,75 +26,109 @@ type PeerEventKind int
 const (
 	PeerEventNew     PeerEventKind = iota
 	PeerEventUpdated PeerEventKind = iota
+	PeerEventRemoved PeerEventKind = iota
 )
 
 type PeerEvent struct {
 	PubKey string
 	Kind   PeerEventKind
+
+	// Endpoint and PrevEndpoint are set on PeerEventUpdated when the update
+	// replaced a known endpoint with a different one (roaming, NAT rebinding).
+	Endpoint     string
+	PrevEndpoint string
+}
+
+// EndpointChanged reports whether the event carries an endpoint change.
+func (e PeerEvent) EndpointChanged() bool {
+	return e.PrevEndpoint != "" && e.Endpoint != e.PrevEndpoint
 }
 
 // PeerStore is a thread-safe store for discovered peers.
 type PeerStore struct {
 	mu          sync.RWMutex
 	peers       map[string]*PeerInfo
-	subscribers []chan PeerEvent
+	probes      map[string]*probeWindow
+	subscribers []*subscriber
+	admit       func(info *PeerInfo, discoveryMethod string) bool
+
+	deadAfter   time.Duration        // unseen this long: inactive
+	removeAfter time.Duration        // unseen this long: dropped by CleanupStale
+	forgotten   map[string]time.Time // key -> until when announcements are ignored
+}
+
+// probeWindow is a ring of the most recent mesh probe results for a peer.
+type probeWindow struct {
+	rtts  [PeerProbeWindow]time.Duration
+	ok    [PeerProbeWindow]bool
+	count int
+	next  int
 }
 
 // NewPeerStore creates a new peer store.
 func NewPeerStore() *PeerStore {
 	return &PeerStore{
-		peers: make(map[string]*PeerInfo),
+		peers:       make(map[string]*PeerInfo),
+		probes:      make(map[string]*probeWindow),
+		deadAfter:   PeerDeadTimeout,
+		removeAfter: PeerRemoveTimeout,
+		forgotten:   make(map[string]time.Time),
 	}
 }
 
-func (ps *PeerStore) Subscribe() <-chan PeerEvent {
+// SetExpiry replaces PeerDeadTimeout and PeerRemoveTimeout for this store.
+// Zero keeps the current value.
+func (ps *PeerStore) SetExpiry(deadAfter, removeAfter time.Duration) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
+	if deadAfter > 0 {
+		ps.deadAfter = deadAfter
+	}
+	if removeAfter > 0 {
+		ps.removeAfter = removeAfter
+	}
+}
 
-	ch := make(chan PeerEvent, PeerEventBufSize)
-	ps.subscribers = append(ps.subscribers, ch)
-	return ch
+// Expiry returns how long a peer may go unseen before it is inactive and
+// before it is removed.
+func (ps *PeerStore) Expiry() (deadAfter, removeAfter time.Duration) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+	return ps.deadAfter, ps.removeAfter
 }
 
-func (ps *PeerStore) Unsubscribe(ch <-chan PeerEvent) {
+// SetAdmitFunc installs a filter consulted by Update before any change;
+// announcements it rejects are dropped. It must not call back into the store.
+func (ps *PeerStore) SetAdmitFunc(fn func(info *PeerInfo, discoveryMethod string) bool) {
 	ps.mu.Lock()
 	defer ps.mu.Unlock()
-
-	for i, sub := range ps.subscribers {
-		if sub == ch {
-			ps.subscribers = append(ps.subscribers[:i], ps.subscribers[i+1:]...)
-			close(sub)
-			return
-		}
-	}
+	ps.admit = fn
 }
 
-func (ps *PeerStore) notify(pubKey string, kind PeerEventKind) {
+// Update adds or updates a peer in the store.
+// Merge logic: newest timestamp wins for mutable fields (endpoint, routable_networks)
+func (ps *PeerStore) Update(info *PeerInfo, discoveryMethod string) {
 	ps.mu.RLock()
-	subs := make([]chan PeerEvent, len(ps.subscribers))
-	copy(subs, ps.subscribers)
+	admit := ps.admit
 	ps.mu.RUnlock()
-
-	ev := PeerEvent{PubKey: pubKey, Kind: kind}
-	for _, ch := range subs {
-		select {
-		case ch <- ev:
-		default:
-		}
+	if admit != nil && !admit(info, discoveryMethod) {
+		return
 	}
-}
 
-// Update adds or updates a peer in the store.
-// Merge logic: newest timestamp wins for mutable fields (endpoint, routable_networks)
-func (ps *PeerStore) Update(info *PeerInfo, discoveryMethod string) {
-	var eventKey string
-	var eventKind PeerEventKind
+	var event PeerEvent
 
 	func() {
 		ps.mu.Lock()
 		defer ps.mu.Unlock()
 		now := time.Now()
 
+		if until, ok := ps.forgotten[info.WGPubKey]; ok {
+			if now.Before(until) {
+				return
+			}
+			delete(ps.forgotten, info.WGPubKey)
+		}
+
 		existing, exists := ps.peers[info.WGPubKey]
 		if !exists {
 			if len(ps.peers) >= DefaultMaxPeers {
@@ -107,18 +144,39 @@ func (ps *PeerStore) Update(info *PeerInfo, discoveryMethod string) {
 				info.EndpointMethod = discoveryMethod
 			}
 			ps.peers[info.WGPubKey] = info
-			eventKey = info.WGPubKey
-			eventKind = PeerEventNew
+			event = PeerEvent{PubKey: info.WGPubKey, Kind: PeerEventNew}
 			return
 		}
 
+		// Once a peer has signed an announcement, only announcements signed by
+		// the same identity may change it; unsigned and transitive claims are
+		// ignored.
+		if existing.Identity != "" && info.Identity != existing.Identity {
+			if info.Identity != "" {
+				log.Printf("[PeerStore] ignoring announcement for %s... via %s: signed by a different identity",
+					shortKey(info.WGPubKey), discoveryMethod)
+			}
+			return
+		}
+		if info.Identity != "" {
+			existing.Identity = info.Identity
+		}
+
+		event = PeerEvent{PubKey: info.WGPubKey, Kind: PeerEventUpdated}
 		if info.Endpoint != "" && shouldUpdateEndpoint(existing, info.Endpoint, discoveryMethod) {
+			if existing.Endpoint != "" && existing.Endpoint != info.Endpoint {
+				event.Endpoint = info.Endpoint
+				event.PrevEndpoint = existing.Endpoint
+			}
 			existing.Endpoint = info.Endpoint
 			existing.EndpointMethod = discoveryMethod
 		}
-		if len(info.RoutableNetworks) > 0 {
+		if len(info.RoutableNetworks) > 0 || info.RoutesAnnounced {
 			existing.RoutableNetworks = info.RoutableNetworks
 		}
+		if len(info.Candidates) > 0 {
+			existing.Candidates = info.Candidates
+		}
 		if info.MeshIP != "" {
 			existing.MeshIP = info.MeshIP
 		}
@@ -132,6 +190,43 @@ func (ps *PeerStore) Update(info *PeerInfo, discoveryMethod string) {
 		if info.NATType != "" {
 			existing.NATType = info.NATType
 		}
+		if info.Version != "" {
+			existing.Version = info.Version
+		}
+		// Only the peer's own announcements carry load and relays.
+		if info.Load != nil || info.RoutesAnnounced {
+			existing.Load = info.Load
+		}
+		if len(info.Relays) > 0 || info.RoutesAnnounced {
+			existing.Relays = info.Relays
+		}
+		if len(info.Tags) > 0 || info.RoutesAnnounced {
+			existing.Tags = info.Tags
+		}
+		if len(info.DNS) > 0 || info.RoutesAnnounced {
+			existing.DNS = info.DNS
+		}
+		if len(info.RouteChecks) > 0 || info.RoutesAnnounced {
+			existing.RouteChecks = info.RouteChecks
+		}
+		if info.ControlEndpoint != "" {
+			existing.ControlEndpoint = info.ControlEndpoint
+		}
+		// Relays older than the flag leave it out of their peer lists, so
+		// only the peer's own announcement clears it.
+		if info.PairPSK || info.RoutesAnnounced {
+			existing.PairPSK = info.PairPSK
+		}
+		if info.RoutesAnnounced {
+			existing.IntroducerCandidate = info.IntroducerCandidate
+			existing.IntroducerElected = info.IntroducerElected
+			existing.Revision = info.Revision
+			existing.MinRevision = info.MinRevision
+			existing.Capabilities = info.Capabilities
+			existing.PortHop = info.PortHop
+			existing.TCPPort = info.TCPPort
+			existing.TCPVia = info.TCPVia
+		}
 
 		if shouldRefreshLastSeen(discoveryMethod) {
 			existing.LastSeen = now
@@ -149,13 +244,10 @@ func (ps *PeerStore) Update(info *PeerInfo, discoveryMethod string) {
 		if !found {
 			existing.DiscoveredVia = append(existing.DiscoveredVia, discoveryMethod)
 		}
-
-		eventKey = info.WGPubKey
-		eventKind = PeerEventUpdated
 	}()
 
-	if eventKey != "" {
-		ps.notify(eventKey, eventKind)
+	if event.PubKey != "" {
+		ps.notify(event)
 	}
 }
 
@@ -185,7 +277,7 @@ func endpointMethodRank(method string) int {
 	if strings.Contains(method, RendezvousMethod) {
 		return 90
 	}
-	if method == "dht" {
+	if method == "dht" || method == "dns" {
 		return 70
 	}
 	if strings.Contains(method, "dht-transitive") {
@@ -285,7 +377,7 @@ func (ps *PeerStore) GetActive() []*PeerInfo {
 	result := make([]*PeerInfo, 0, len(ps.peers))
 	now := time.Now()
 	for _, peer := range ps.peers {
-		if now.Sub(peer.LastSeen) < PeerDeadTimeout {
+		if now.Sub(peer.LastSeen) < ps.deadAfter {
 			peerCopy := *peer
 			result = append(result, &peerCopy)
 		}
@@ -296,23 +388,56 @@ func (ps *PeerStore) GetActive() []*PeerInfo {
 // Remove removes a peer by public key.
 func (ps *PeerStore) Remove(pubKey string) {
 	ps.mu.Lock()
-	defer ps.mu.Unlock()
+	_, existed := ps.peers[pubKey]
 	delete(ps.peers, pubKey)
+	delete(ps.probes, pubKey)
+	ps.mu.Unlock()
+
+	if existed {
+		ps.notify(PeerEvent{PubKey: pubKey, Kind: PeerEventRemoved})
+	}
+}
+
+// Forget removes a peer and ignores announcements for it for the store's
+// remove timeout, by which time other nodes have dropped it too, so that a
+// decommissioned node does not come back through gossip. It reports whether
+// the peer was known.
+func (ps *PeerStore) Forget(pubKey string) bool {
+	ps.mu.Lock()
+	_, existed := ps.peers[pubKey]
+	delete(ps.peers, pubKey)
+	delete(ps.probes, pubKey)
+	ps.forgotten[pubKey] = time.Now().Add(ps.removeAfter)
+	ps.mu.Unlock()
+
+	if existed {
+		ps.notify(PeerEvent{PubKey: pubKey, Kind: PeerEventRemoved})
+	}
+	return existed
 }
 
 // CleanupStale removes peers that haven't been seen for too long.
 func (ps *PeerStore) CleanupStale() []string {
 	ps.mu.Lock()
-	defer ps.mu.Unlock()
-
 	var removed []string
 	now := time.Now()
 	for pubKey, peer := range ps.peers {
-		if now.Sub(peer.LastSeen) > PeerRemoveTimeout {
+		if now.Sub(peer.LastSeen) > ps.removeAfter {
 			delete(ps.peers, pubKey)
+			delete(ps.probes, pubKey)
 			removed = append(removed, pubKey)
 		}
 	}
+	for pubKey, until := range ps.forgotten {
+		if now.After(until) {
+			delete(ps.forgotten, pubKey)
+		}
+	}
+	ps.mu.Unlock()
+
+	for _, pubKey := range removed {
+		ps.notify(PeerEvent{PubKey: pubKey, Kind: PeerEventRemoved})
+	}
 	return removed
 }
 
@@ -335,6 +460,82 @@ func (ps *PeerStore) SetLatency(pubKey string, rtt time.Duration) {
 	peer.Latency = &rtt
 }
 
+// RecordProbe adds a mesh probe result to the peer's rolling window and
+// refreshes its Latency (mean RTT of successful probes in the window) and
+// PacketLoss (fraction of probes in the window that failed). It returns
+// the updated values; rtt is ignored when ok is false.
+func (ps *PeerStore) RecordProbe(pubKey string, rtt time.Duration, ok bool) (time.Duration, float64) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	peer, exists := ps.peers[pubKey]
+	if !exists {
+		return 0, 0
+	}
+
+	w := ps.probes[pubKey]
+	if w == nil {
+		w = &probeWindow{}
+		ps.probes[pubKey] = w
+	}
+	w.rtts[w.next] = rtt
+	w.ok[w.next] = ok
+	w.next = (w.next + 1) % PeerProbeWindow
+	if w.count < PeerProbeWindow {
+		w.count++
+	}
+
+	var sum time.Duration
+	var succeeded int
+	for i := 0; i < w.count; i++ {
+		if w.ok[i] {
+			sum += w.rtts[i]
+			succeeded++
+		}
+	}
+
+	loss := float64(w.count-succeeded) / float64(w.count)
+	peer.PacketLoss = &loss
+	if succeeded == 0 {
+		return 0, loss
+	}
+	mean := sum / time.Duration(succeeded)
+	peer.Latency = &mean
+	return mean, loss
+}
+
+// ResetProbes forgets every peer's probe window, Latency and PacketLoss,
+// for when the local network changed and old results say nothing about the
+// new paths.
+func (ps *PeerStore) ResetProbes() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	clear(ps.probes)
+	for _, peer := range ps.peers {
+		peer.Latency = nil
+		peer.PacketLoss = nil
+	}
+}
+
+// ProbeCounts returns how many mesh probes are in the peer's window and how
+// many of them failed.
+func (ps *PeerStore) ProbeCounts(pubKey string) (probes, lost int) {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	w := ps.probes[pubKey]
+	if w == nil {
+		return 0, 0
+	}
+	for i := 0; i < w.count; i++ {
+		if !w.ok[i] {
+			lost++
+		}
+	}
+	return w.count, lost
+}
+
 // IsDead checks if a peer is considered dead.
 func (ps *PeerStore) IsDead(pubKey string) bool {
 	ps.mu.RLock()
@@ -344,7 +545,7 @@ func (ps *PeerStore) IsDead(pubKey string) bool {
 	if !exists {
 		return true
 	}
-	return time.Since(peer.LastSeen) > PeerDeadTimeout
+	return time.Since(peer.LastSeen) > ps.deadAfter
 }
 
 // shortKey safely truncates a key for logging.
@@ -367,6 +568,46 @@ func (ps *PeerStore) SetEndpointMethod(pubKey, method string) {
 	peer.EndpointMethod = method
 }
 
+// SetControlEndpoint records the control endpoint a peer last answered on,
+// or clears it when endpoint is empty. It emits no event.
+func (ps *PeerStore) SetControlEndpoint(pubKey, endpoint string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	peer, exists := ps.peers[pubKey]
+	if !exists {
+		return
+	}
+	peer.ControlEndpoint = endpoint
+}
+
+// MarkDemand records that the peer is wanted right now, e.g. because it just
+// contacted us directly. When --max-installed-peers limits the WireGuard
+// peers the daemon installs, demanded peers come before idle ones.
+func (ps *PeerStore) MarkDemand(pubKey string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	peer, exists := ps.peers[pubKey]
+	if !exists {
+		return
+	}
+	peer.LastDemand = time.Now()
+}
+
+// MarkSeen refreshes the peer's LastSeen when it was heard from over a
+// channel that carries no announcement, such as the TCP transport.
+func (ps *PeerStore) MarkSeen(pubKey string) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	peer, exists := ps.peers[pubKey]
+	if !exists {
+		return
+	}
+	peer.LastSeen = time.Now()
+}
+
 func (ps *PeerStore) SetPeerDirectly(key string, info *PeerInfo) {
 	ps.mu.Lock()
 	ps.peers[key] = info
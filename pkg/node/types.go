@@ -5,6 +5,8 @@ package node
 import (
 	"sync"
 	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
 )
 
 // DiscoveryLayer is the interface implemented by every discovery backend.
@@ -15,18 +17,40 @@ type DiscoveryLayer interface {
 
 // PeerInfo represents a discovered mesh peer.
 type PeerInfo struct {
-	WGPubKey         string
-	Hostname         string
-	MeshIP           string
-	MeshIPv6         string
-	Endpoint         string // best known endpoint (ip:port)
-	Introducer       bool
-	RoutableNetworks []string
-	LastSeen         time.Time
-	DiscoveredVia    []string       // ["lan", "dht", "gossip"]
-	Latency          *time.Duration // measured via WG handshake
-	NATType          string         // "cone", "symmetric", or "unknown"
-	EndpointMethod   string
+	WGPubKey            string
+	Hostname            string
+	MeshIP              string
+	MeshIPv6            string
+	Endpoint            string // best known endpoint (ip:port)
+	Introducer          bool
+	RoutableNetworks    []string
+	RoutesAnnounced     bool // RoutableNetworks came from the peer itself, so empty means withdrawn
+	LastSeen            time.Time
+	DiscoveredVia       []string       // ["lan", "dht", "gossip"]
+	Latency             *time.Duration // rolling mean RTT of mesh probes
+	PacketLoss          *float64       // fraction of recent mesh probes lost (0..1)
+	NATType             string         // "none", "cone", "symmetric", or "unknown"
+	EndpointMethod      string
+	Candidates          []string                  // alternative endpoints announced by the peer
+	Identity            string                    // Ed25519 identity that signed the peer's announcements
+	LastDemand          time.Time                 // last time something needed a tunnel to the peer (see MarkDemand)
+	Version             string                    // wgmesh version the peer announces; empty for older peers
+	Revision            int                       // protocol revision the peer announces; 0 for older peers
+	MinRevision         int                       // oldest protocol revision the peer meshes with
+	Capabilities        crypto.Capabilities       // optional protocol features the peer implements
+	Load                *crypto.IntroducerLoad    // reported by introducers
+	Relays              []string                  // introducers the peer relays traffic through
+	IntroducerCandidate bool                      // qualifies for introducer election
+	IntroducerElected   bool                      // Introducer by election rather than configuration
+	PairPSK             bool                      // derives a preshared key per pair (see crypto.DerivePairPSK)
+	Tags                map[string]string         // operator-assigned labels the peer announces, e.g. role=db
+	PortHop             time.Duration             // port hopping interval; 0 = the endpoint's port is fixed
+	TCPPort             int                       // TLS-wrapped WireGuard port an introducer serves; 0 = none
+	TCPVia              string                    // introducer the peer tunnels to over TCP; "" = plain UDP
+	Approval            *crypto.AdmissionApproval // admission the peer announced in approval mode; nil otherwise
+	DNS                 []crypto.DNSDomain        // split-DNS domains the peer advertises with their resolvers
+	RouteChecks         []crypto.RouteCheck       // health of targets behind the peer's RoutableNetworks, as it reports them
+	ControlEndpoint     string                    // exchange listener that last answered our HELLO; kept in the peer cache
 }
 
 // LocalNode represents the local WireGuard node.
@@ -0,0 +1,69 @@
+package openwrt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ZoneName is the firewall zone the mesh interface is put in.
+const ZoneName = "wgmesh"
+
+// Named sections in /etc/config/firewall owned by wgmesh. Fixed names make
+// installing twice update them in place rather than add duplicates.
+var firewallSections = []string{ZoneName, "wgmesh_to_lan", "lan_to_wgmesh", "wgmesh_wan"}
+
+// Zone describes how the mesh interface joins the OpenWrt firewall.
+type Zone struct {
+	Interface    string // WireGuard interface, e.g. wg0
+	LANZone      string // zone forwarded to and from the mesh, usually "lan"; empty = none
+	WANZone      string // zone the mesh ports are opened on, usually "wan"; empty = none
+	ListenPort   int    // WireGuard listen port
+	ExchangePort int    // peer exchange port, derived from the mesh secret
+}
+
+// FirewallBatch returns "uci batch" commands that add the mesh zone, the
+// forwardings between it and the LAN, and a rule opening the WireGuard and
+// peer exchange ports on the WAN.
+func FirewallBatch(z Zone) string {
+	var b strings.Builder
+	set := func(section, option, value string) {
+		if option == "" {
+			fmt.Fprintf(&b, "set firewall.%s=%s\n", section, value)
+			return
+		}
+		fmt.Fprintf(&b, "set firewall.%s.%s=%s\n", section, option, quoteUCI(value))
+	}
+
+	set(ZoneName, "", "zone")
+	set(ZoneName, "name", ZoneName)
+	set(ZoneName, "device", z.Interface)
+	set(ZoneName, "input", "ACCEPT")
+	set(ZoneName, "output", "ACCEPT")
+	set(ZoneName, "forward", "REJECT")
+	set(ZoneName, "mtu_fix", "1")
+
+	if z.LANZone != "" {
+		set("wgmesh_to_lan", "", "forwarding")
+		set("wgmesh_to_lan", "src", ZoneName)
+		set("wgmesh_to_lan", "dest", z.LANZone)
+		set("lan_to_wgmesh", "", "forwarding")
+		set("lan_to_wgmesh", "src", z.LANZone)
+		set("lan_to_wgmesh", "dest", ZoneName)
+	}
+
+	if z.WANZone != "" {
+		ports := []string{fmt.Sprint(z.ListenPort)}
+		if z.ExchangePort > 0 && z.ExchangePort != z.ListenPort {
+			ports = append(ports, fmt.Sprint(z.ExchangePort))
+		}
+		set("wgmesh_wan", "", "rule")
+		set("wgmesh_wan", "name", "Allow-wgmesh")
+		set("wgmesh_wan", "src", z.WANZone)
+		set("wgmesh_wan", "proto", "udp")
+		set("wgmesh_wan", "dest_port", strings.Join(ports, " "))
+		set("wgmesh_wan", "target", "ACCEPT")
+	}
+
+	b.WriteString("commit firewall\n")
+	return b.String()
+}
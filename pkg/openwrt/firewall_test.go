@@ -0,0 +1,30 @@
+package openwrt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFirewallBatch(t *testing.T) {
+	batch := FirewallBatch(Zone{Interface: "wg0", LANZone: "lan", WANZone: "wan", ListenPort: 51820, ExchangePort: 52100})
+	for _, want := range []string{
+		"set firewall.wgmesh=zone\n",
+		"set firewall.wgmesh.device='wg0'\n",
+		"set firewall.wgmesh_to_lan.dest='lan'\n",
+		"set firewall.lan_to_wgmesh.src='lan'\n",
+		"set firewall.wgmesh_wan.src='wan'\n",
+		"set firewall.wgmesh_wan.dest_port='51820 52100'\n",
+	} {
+		if !strings.Contains(batch, want) {
+			t.Errorf("batch missing %q:\n%s", want, batch)
+		}
+	}
+	if !strings.HasSuffix(batch, "commit firewall\n") {
+		t.Errorf("batch should end with a commit:\n%s", batch)
+	}
+
+	bare := FirewallBatch(Zone{Interface: "wg0", ListenPort: 51820})
+	if strings.Contains(bare, "forwarding") || strings.Contains(bare, "rule") {
+		t.Errorf("without zones only the mesh zone is added:\n%s", bare)
+	}
+}
@@ -0,0 +1,98 @@
+package openwrt
+
+import (
+	"fmt"
+	"strings"
+)
+
+const initScriptHeader = `#!/bin/sh /etc/rc.common
+# Generated by wgmesh openwrt install; settings live in /etc/config/wgmesh
+
+START=95
+STOP=10
+USE_PROCD=1
+
+PROG=%s
+
+append_option() {
+	local value
+	config_get value ` + SectionName + ` "$1"
+	[ -n "$value" ] || return 0
+	procd_append_param command "--$2" "$value"
+}
+
+# append_bool <option> <flag> <default> <value that adds the flag>
+append_bool() {
+	local value
+	config_get_bool value ` + SectionName + ` "$1" "$3"
+	[ "$value" = "$4" ] || return 0
+	procd_append_param command "--$2"
+}
+
+append_item() {
+	procd_append_param command "--$2" "$1"
+}
+
+append_csv_item() {
+	csv="${csv:+$csv,}$1"
+}
+
+append_csv() {
+	csv=
+	config_list_foreach ` + SectionName + ` "$1" append_csv_item
+	[ -n "$csv" ] || return 0
+	procd_append_param command "--$2" "$csv"
+}
+
+start_service() {
+	local enabled secret_file
+	config_load ` + ConfigPackage + `
+	config_get_bool enabled ` + SectionName + ` ` + OptionEnabled + ` 1
+	[ "$enabled" = 1 ] || return 0
+	config_get secret_file ` + SectionName + ` ` + OptionSecretFile + ` %s
+
+	procd_open_instance
+	procd_set_param command "$PROG" join --secret-file "$secret_file"
+`
+
+const initScriptFooter = `	procd_set_param respawn
+	procd_set_param limits nofile="65535 65535"
+	procd_set_param stdout 1
+	procd_set_param stderr 1
+	procd_close_instance
+}
+
+service_triggers() {
+	procd_add_reload_trigger ` + ConfigPackage + `
+}
+`
+
+// GenerateInitScript generates the procd script installed as
+// /etc/init.d/wgmesh. It reads the UCI options in Options on every start,
+// so "uci set" followed by "/etc/init.d/wgmesh reload" applies a change
+// without reinstalling.
+func GenerateInitScript(binaryPath, defaultSecretFile string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, initScriptHeader, quoteUCI(binaryPath), quoteUCI(defaultSecretFile))
+	for _, o := range Options {
+		switch o.Kind {
+		case KindString, KindInt:
+			fmt.Fprintf(&b, "\tappend_option %s %s\n", o.Name, o.Flag)
+		case KindBool:
+			fmt.Fprintf(&b, "\tappend_bool %s %s %d %d\n", o.Name, o.Flag, boolDigit(o.Default), boolDigit(o.Want))
+		case KindList:
+			fmt.Fprintf(&b, "\tappend_csv %s %s\n", o.Name, o.Flag)
+		case KindRepeated:
+			fmt.Fprintf(&b, "\tconfig_list_foreach %s %s append_item %s\n", SectionName, o.Name, o.Flag)
+		}
+	}
+	b.WriteString(initScriptFooter)
+	return b.String()
+}
+
+func boolDigit(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
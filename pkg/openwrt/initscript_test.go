@@ -0,0 +1,27 @@
+package openwrt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateInitScript(t *testing.T) {
+	script := GenerateInitScript("/usr/bin/wgmesh", "/var/lib/wgmesh/secret.enc")
+	for _, want := range []string{
+		"#!/bin/sh /etc/rc.common",
+		"USE_PROCD=1",
+		"PROG='/usr/bin/wgmesh'",
+		"config_get secret_file main secret_file '/var/lib/wgmesh/secret.enc'",
+		`procd_set_param command "$PROG" join --secret-file "$secret_file"`,
+		"append_option listen_port listen-port",
+		"append_bool subnet_router subnet-router 0 1",
+		"append_bool lan_discovery no-lan-discovery 1 0",
+		"append_csv advertise_routes advertise-routes",
+		"config_list_foreach main stun_server append_item stun-server",
+		"procd_add_reload_trigger wgmesh",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("init script missing %q:\n%s", want, script)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package openwrt
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ConfigPath     = "/etc/config/wgmesh"
+	InitScriptPath = "/etc/init.d/wgmesh"
+)
+
+// root prefixes every path the installer touches, for tests.
+var root = "/"
+
+// runFunc runs a command, feeding stdin if non-empty, and returns its
+// combined output.
+type runFunc func(stdin string, name string, args ...string) ([]byte, error)
+
+var run runFunc = execRun
+
+// InstallConfig is what Install sets up.
+type InstallConfig struct {
+	BinaryPath string
+	SecretFile string   // default secret_file of the init script
+	Settings   *Section // written to /etc/config/wgmesh unless the file exists
+	Zone       Zone
+}
+
+// Install writes the UCI config (keeping an existing one), the procd init
+// script and the firewall zone, then enables and (re)starts the service.
+func Install(cfg InstallConfig) error {
+	configPath := filepath.Join(root, ConfigPath)
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		content := "# wgmesh settings; apply changes with: /etc/init.d/wgmesh reload\n\n" + FormatUCI([]*Section{cfg.Settings})
+		if err := os.WriteFile(configPath, []byte(content), 0600); err != nil {
+			return fmt.Errorf("failed to write %s (run as root?): %w", ConfigPath, err)
+		}
+	}
+
+	script := GenerateInitScript(cfg.BinaryPath, cfg.SecretFile)
+	if err := os.WriteFile(filepath.Join(root, InitScriptPath), []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write init script (run as root?): %w", err)
+	}
+
+	// Start from a clean slate so options dropped since the last install
+	// do not linger.
+	removeFirewallSections()
+	if out, err := run(FirewallBatch(cfg.Zone), "uci", "batch"); err != nil {
+		return fmt.Errorf("failed to add firewall zone: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	if out, err := run("", "/etc/init.d/firewall", "reload"); err != nil {
+		return fmt.Errorf("failed to reload firewall: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+
+	if out, err := run("", InitScriptPath, "enable"); err != nil {
+		return fmt.Errorf("failed to enable service: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	if out, err := run("", InitScriptPath, "restart"); err != nil {
+		return fmt.Errorf("failed to start service: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// Uninstall stops and disables the service and removes its init script and
+// firewall zone. /etc/config/wgmesh is kept, like opkg keeps conffiles.
+func Uninstall() error {
+	scriptPath := filepath.Join(root, InitScriptPath)
+	if _, err := os.Stat(scriptPath); err == nil {
+		_, _ = run("", InitScriptPath, "stop")
+		_, _ = run("", InitScriptPath, "disable")
+		if err := os.Remove(scriptPath); err != nil {
+			return fmt.Errorf("failed to remove init script: %w", err)
+		}
+	}
+
+	removeFirewallSections()
+	if out, err := run("", "/etc/init.d/firewall", "reload"); err != nil {
+		return fmt.Errorf("failed to reload firewall: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// Installed reports whether the wgmesh init script is in place.
+func Installed() bool {
+	_, err := os.Stat(filepath.Join(root, InitScriptPath))
+	return err == nil
+}
+
+// LoadConfig reads the wgmesh section of /etc/config/wgmesh.
+func LoadConfig() (*Section, error) {
+	f, err := os.Open(filepath.Join(root, ConfigPath))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sections, err := ParseUCI(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ConfigPath, err)
+	}
+	s := FindSection(sections, SectionType, SectionName)
+	if s == nil {
+		return nil, fmt.Errorf("%s has no config %s '%s' section", ConfigPath, SectionType, SectionName)
+	}
+	return s, nil
+}
+
+// removeFirewallSections deletes wgmesh's sections from the firewall
+// config. Sections that do not exist are not an error.
+func removeFirewallSections() {
+	for _, name := range firewallSections {
+		_, _ = run("", "uci", "-q", "delete", "firewall."+name)
+	}
+	_, _ = run("", "uci", "commit", "firewall")
+}
+
+func execRun(stdin string, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	return cmd.CombinedOutput()
+}
@@ -0,0 +1,83 @@
+package openwrt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeRun records the commands the installer runs.
+func fakeRun(t *testing.T) *[]string {
+	t.Helper()
+	var calls []string
+	prev := run
+	run = func(stdin string, name string, args ...string) ([]byte, error) {
+		calls = append(calls, strings.Join(append([]string{name}, args...), " "))
+		return nil, nil
+	}
+	t.Cleanup(func() { run = prev })
+	return &calls
+}
+
+func useRoot(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, sub := range []string{"etc/config", "etc/init.d"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prev := root
+	root = dir
+	t.Cleanup(func() { root = prev })
+	return dir
+}
+
+func TestInstallKeepsExistingConfig(t *testing.T) {
+	dir := useRoot(t)
+	calls := fakeRun(t)
+
+	settings := &Section{Type: SectionType, Name: SectionName}
+	settings.Set("interface", "wg0")
+	cfg := InstallConfig{BinaryPath: "/usr/bin/wgmesh", SecretFile: "/var/lib/wgmesh/secret.enc", Settings: settings, Zone: Zone{Interface: "wg0", ListenPort: 51820}}
+	if err := Install(cfg); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if !Installed() {
+		t.Error("init script not installed")
+	}
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if v, _ := loaded.Get("interface"); v != "wg0" {
+		t.Errorf("interface = %q, want wg0", v)
+	}
+	if !strings.Contains(strings.Join(*calls, "\n"), "/etc/init.d/wgmesh restart") {
+		t.Errorf("service not started: %v", *calls)
+	}
+
+	// A second install must not overwrite what the user changed.
+	if err := os.WriteFile(filepath.Join(dir, ConfigPath), []byte("config wgmesh 'main'\n\toption interface 'wg7'\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Install(cfg); err != nil {
+		t.Fatalf("second Install: %v", err)
+	}
+	if loaded, _ := LoadConfig(); loaded == nil {
+		t.Fatal("config lost")
+	} else if v, _ := loaded.Get("interface"); v != "wg7" {
+		t.Errorf("interface = %q, want the edited wg7", v)
+	}
+
+	if err := Uninstall(); err != nil {
+		t.Fatalf("Uninstall: %v", err)
+	}
+	if Installed() {
+		t.Error("init script still installed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, ConfigPath)); err != nil {
+		t.Errorf("config should be kept: %v", err)
+	}
+}
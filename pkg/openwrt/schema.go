@@ -0,0 +1,293 @@
+package openwrt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+// The daemon's settings live in one named section of /etc/config/wgmesh.
+const (
+	ConfigPackage = "wgmesh"
+	SectionType   = "wgmesh"
+	SectionName   = "main"
+)
+
+// OptionKind is how a UCI option turns into join flags.
+type OptionKind int
+
+const (
+	KindString   OptionKind = iota // --flag value
+	KindInt                        // --flag N
+	KindBool                       // --flag when the option equals Want
+	KindList                       // one --flag a,b,c for all items
+	KindRepeated                   // --flag a --flag b, one per item
+)
+
+// Option maps one UCI option to a join flag and a DaemonOpts field.
+type Option struct {
+	Name string // UCI option name
+	Flag string // join flag without dashes
+	Kind OptionKind
+
+	// For KindBool: the option's value when unset, and the value that
+	// adds the flag. Options such as lan_discovery default to on and add
+	// a --no-... flag when turned off.
+	Default bool
+	Want    bool
+
+	apply func(opts *daemon.DaemonOpts, values []string) error
+}
+
+// Options is the UCI schema of the wgmesh section. Options not listed here
+// are rejected by DaemonOpts, so typos do not go unnoticed.
+var Options = []Option{
+	{Name: "interface", Flag: "interface", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.InterfaceName = v[0]
+		return nil
+	}},
+	{Name: "listen_port", Flag: "listen-port", Kind: KindInt, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseInt(v[0], &o.WGListenPort)
+	}},
+	{Name: "advertise_routes", Flag: "advertise-routes", Kind: KindList, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.AdvertiseRoutes = v
+		return nil
+	}},
+	{Name: "subnet_router", Flag: "subnet-router", Kind: KindBool, Want: true, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseBool(v[0], &o.SubnetRouter)
+	}},
+	{Name: "masquerade", Flag: "masquerade", Kind: KindBool, Want: true, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseBool(v[0], &o.Masquerade)
+	}},
+	{Name: "mesh_subnet", Flag: "mesh-subnet", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.MeshSubnet = v[0]
+		return nil
+	}},
+	{Name: "mesh_name", Flag: "mesh-name", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.MeshName = v[0]
+		return nil
+	}},
+	{Name: "log_level", Flag: "log-level", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.LogLevel = v[0]
+		return nil
+	}},
+	{Name: "privacy", Flag: "privacy", Kind: KindBool, Want: true, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseBool(v[0], &o.Privacy)
+	}},
+	{Name: "gossip", Flag: "gossip", Kind: KindBool, Want: true, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseBool(v[0], &o.Gossip)
+	}},
+	{Name: "introducer", Flag: "introducer", Kind: KindBool, Want: true, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseBool(v[0], &o.Introducer)
+	}},
+	{Name: "lan_discovery", Flag: "no-lan-discovery", Kind: KindBool, Default: true, Want: false, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseNegatedBool(v[0], &o.DisableLANDiscovery)
+	}},
+	{Name: "lan_interfaces", Flag: "lan-interfaces", Kind: KindList, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.LANInterfaces = strings.Join(v, ",")
+		return nil
+	}},
+	{Name: "lan_group", Flag: "lan-group", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.LANGroup = v[0]
+		return nil
+	}},
+	{Name: "lan_broadcast", Flag: "lan-broadcast", Kind: KindBool, Want: true, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseBool(v[0], &o.LANBroadcast)
+	}},
+	{Name: "ipv6", Flag: "no-ipv6", Kind: KindBool, Default: true, Want: false, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseNegatedBool(v[0], &o.DisableIPv6)
+	}},
+	{Name: "keepalive", Flag: "keepalive", Kind: KindInt, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseInt(v[0], &o.Keepalive)
+	}},
+	{Name: "stun_server", Flag: "stun-server", Kind: KindRepeated, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.STUNServers = v
+		return nil
+	}},
+	{Name: "dns_rendezvous", Flag: "dns-rendezvous", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.DNSRendezvous = v[0]
+		return nil
+	}},
+	{Name: "pin_identities", Flag: "pin-identities", Kind: KindBool, Want: true, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseBool(v[0], &o.PinIdentities)
+	}},
+	{Name: "require_signed", Flag: "require-signed", Kind: KindBool, Want: true, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseBool(v[0], &o.RequireSigned)
+	}},
+	{Name: "max_installed_peers", Flag: "max-installed-peers", Kind: KindInt, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseInt(v[0], &o.MaxInstalledPeers)
+	}},
+	{Name: "discovery_bandwidth", Flag: "discovery-bandwidth", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.DiscoveryBandwidth = v[0]
+		return nil
+	}},
+	{Name: "tags", Flag: "tags", Kind: KindList, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.Tags = strings.Join(v, ",")
+		return nil
+	}},
+	{Name: "advertise_dns", Flag: "advertise-dns", Kind: KindList, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.AdvertiseDNS = strings.Join(v, ",")
+		return nil
+	}},
+	{Name: "route_check", Flag: "route-check", Kind: KindList, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.RouteChecks = strings.Join(v, ",")
+		return nil
+	}},
+	{Name: "split_dns", Flag: "no-split-dns", Kind: KindBool, Default: true, Want: false, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseNegatedBool(v[0], &o.DisableSplitDNS)
+	}},
+	{Name: "dns_resolv_conf", Flag: "dns-resolv-conf", Kind: KindBool, Want: true, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseBool(v[0], &o.SplitDNSResolvConf)
+	}},
+	{Name: "resource_limits", Flag: "resource-limits", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.ResourceLimits = v[0]
+		return nil
+	}},
+	{Name: "route_table", Flag: "route-table", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseInt(v[0], &o.RouteTable)
+	}},
+	{Name: "route_metric", Flag: "route-metric", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseInt(v[0], &o.RouteMetric)
+	}},
+	{Name: "mtu", Flag: "mtu", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseInt(v[0], &o.MTU)
+	}},
+	{Name: "mtu_probe", Flag: "mtu-probe", Kind: KindBool, Want: true, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseBool(v[0], &o.MTUProbe)
+	}},
+	{Name: "port_hop", Flag: "port-hop", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseDuration(v[0], &o.PortHop)
+	}},
+	{Name: "tcp_transport_port", Flag: "tcp-transport-port", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseInt(v[0], &o.TCPTransportPort)
+	}},
+	{Name: "approvers", Flag: "approvers", Kind: KindList, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.Approvers = strings.Join(v, ",")
+		return nil
+	}},
+	{Name: "peer_with", Flag: "peer-with", Kind: KindList, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.PeerWith = strings.Join(v, ",")
+		return nil
+	}},
+	{Name: "health_addr", Flag: "health-addr", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		o.HealthAddr = v[0]
+		return nil
+	}},
+	{Name: "peer_dead_after", Flag: "peer-dead-after", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseDuration(v[0], &o.PeerDeadAfter)
+	}},
+	{Name: "peer_remove_after", Flag: "peer-remove-after", Kind: KindString, apply: func(o *daemon.DaemonOpts, v []string) error {
+		return parseDuration(v[0], &o.PeerRemoveAfter)
+	}},
+}
+
+// Options handled by the init script itself rather than passed to join.
+const (
+	OptionEnabled    = "enabled"
+	OptionSecretFile = "secret_file"
+)
+
+// DaemonOpts converts the wgmesh section into daemon options. The secret
+// is not part of the section; it is read from the returned secret_file.
+func DaemonOpts(s *Section) (daemon.DaemonOpts, string, error) {
+	var opts daemon.DaemonOpts
+	byName := make(map[string]Option, len(Options))
+	for _, o := range Options {
+		byName[o.Name] = o
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range s.Entries {
+		if seen[e.Name] {
+			continue
+		}
+		seen[e.Name] = true
+		switch e.Name {
+		case OptionEnabled:
+			var enabled bool
+			if err := parseBool(e.Value, &enabled); err != nil {
+				return daemon.DaemonOpts{}, "", fmt.Errorf("option %s: %w", e.Name, err)
+			}
+			continue
+		case OptionSecretFile:
+			continue
+		}
+		o, ok := byName[e.Name]
+		if !ok {
+			return daemon.DaemonOpts{}, "", fmt.Errorf("unknown option %q", e.Name)
+		}
+		values := s.Values(e.Name)
+		if o.Kind == KindList || o.Kind == KindRepeated {
+			// config_list_foreach in the init script skips plain options.
+			if !e.List {
+				return daemon.DaemonOpts{}, "", fmt.Errorf("%s must be given as \"list %s\"", e.Name, e.Name)
+			}
+		} else {
+			value, _ := s.Get(e.Name)
+			values = []string{value}
+		}
+		if err := o.apply(&opts, values); err != nil {
+			return daemon.DaemonOpts{}, "", fmt.Errorf("option %s: %w", e.Name, err)
+		}
+	}
+
+	secretFile, ok := s.Get(OptionSecretFile)
+	if !ok {
+		secretFile = daemon.DefaultSecretFilePath
+	}
+	return opts, secretFile, nil
+}
+
+// Enabled reports whether the section's enabled option is on; unset means
+// enabled.
+func Enabled(s *Section) bool {
+	value, ok := s.Get(OptionEnabled)
+	if !ok {
+		return true
+	}
+	var enabled bool
+	return parseBool(value, &enabled) == nil && enabled
+}
+
+// parseBool accepts the boolean spellings of uci's config_get_bool.
+func parseBool(s string, out *bool) error {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "on", "true", "yes", "enabled":
+		*out = true
+	case "0", "off", "false", "no", "disabled":
+		*out = false
+	default:
+		return fmt.Errorf("%q is not a boolean", s)
+	}
+	return nil
+}
+
+func parseNegatedBool(s string, out *bool) error {
+	var on bool
+	if err := parseBool(s, &on); err != nil {
+		return err
+	}
+	*out = !on
+	return nil
+}
+
+func parseDuration(s string, out *time.Duration) error {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("%q is not a duration such as 10m", s)
+	}
+	*out = d
+	return nil
+}
+
+func parseInt(s string, out *int) error {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("%q is not a number", s)
+	}
+	*out = n
+	return nil
+}
@@ -0,0 +1,63 @@
+package openwrt
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+func TestDaemonOpts(t *testing.T) {
+	sections, err := ParseUCI(strings.NewReader(`
+config wgmesh 'main'
+	option enabled 'yes'
+	option secret_file '/etc/wgmesh.secret'
+	option interface 'wg1'
+	option listen_port '51900'
+	list advertise_routes '192.168.1.0/24'
+	option subnet_router '1'
+	option lan_discovery '0'
+	list stun_server 'stun.example.com'
+	list stun_server 'stun2.example.com:3479'
+	list tags 'role=gateway'
+	list tags 'site=home'
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, secretFile, err := DaemonOpts(sections[0])
+	if err != nil {
+		t.Fatalf("DaemonOpts: %v", err)
+	}
+	if secretFile != "/etc/wgmesh.secret" {
+		t.Errorf("secret file = %q", secretFile)
+	}
+	if opts.InterfaceName != "wg1" || opts.WGListenPort != 51900 || !opts.SubnetRouter || !opts.DisableLANDiscovery || opts.DisableIPv6 {
+		t.Errorf("opts = %+v", opts)
+	}
+	if !slices.Equal(opts.AdvertiseRoutes, []string{"192.168.1.0/24"}) || len(opts.STUNServers) != 2 || opts.Tags != "role=gateway,site=home" {
+		t.Errorf("lists = %v, %v, %q", opts.AdvertiseRoutes, opts.STUNServers, opts.Tags)
+	}
+
+	if _, secretFile, err := DaemonOpts(&Section{}); err != nil || secretFile != daemon.DefaultSecretFilePath {
+		t.Errorf("empty section: secret file %q, %v", secretFile, err)
+	}
+	for _, entry := range []Entry{{Name: "listen_pot", Value: "1"}, {Name: "listen_port", Value: "x"}, {Name: "privacy", Value: "maybe"}, {Name: "tags", Value: "role=db"}} {
+		if _, _, err := DaemonOpts(&Section{Entries: []Entry{entry}}); err == nil {
+			t.Errorf("DaemonOpts(%+v) should fail", entry)
+		}
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	for value, want := range map[string]bool{"": true, "1": true, "0": false, "off": false, "bogus": false} {
+		s := &Section{}
+		if value != "" {
+			s.Set(OptionEnabled, value)
+		}
+		if got := Enabled(s); got != want {
+			t.Errorf("Enabled(%q) = %v, want %v", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// Package openwrt runs wgmesh as a native OpenWrt service.
+//
+// The daemon is configured in /etc/config/wgmesh like any other OpenWrt
+// package, started by a procd init script that turns the UCI options into
+// "wgmesh join" flags, and its interface is put in a firewall zone of its
+// own that forwards to and from the LAN, so a home router can advertise
+// its LAN to the mesh.
+package openwrt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Entry is one option or list item of a UCI section.
+type Entry struct {
+	Name  string
+	Value string
+	List  bool // written as "list" rather than "option"
+}
+
+// Section is a UCI "config" section with its entries in file order.
+type Section struct {
+	Type    string
+	Name    string // empty for anonymous sections
+	Entries []Entry
+}
+
+// Get returns the value of option name and whether it is set. For an
+// option given more than once the last value wins, as in uci.
+func (s *Section) Get(name string) (string, bool) {
+	value, ok := "", false
+	for _, e := range s.Entries {
+		if e.Name == name {
+			value, ok = e.Value, true
+		}
+	}
+	return value, ok
+}
+
+// Values returns every value of list name in order. A plain option of the
+// same name counts as a one-item list.
+func (s *Section) Values(name string) []string {
+	var values []string
+	for _, e := range s.Entries {
+		if e.Name == name {
+			values = append(values, e.Value)
+		}
+	}
+	return values
+}
+
+// Set replaces option name with value.
+func (s *Section) Set(name, value string) {
+	s.remove(name)
+	s.Entries = append(s.Entries, Entry{Name: name, Value: value})
+}
+
+// SetList replaces list name with values.
+func (s *Section) SetList(name string, values []string) {
+	s.remove(name)
+	for _, v := range values {
+		s.Entries = append(s.Entries, Entry{Name: name, Value: v, List: true})
+	}
+}
+
+func (s *Section) remove(name string) {
+	kept := s.Entries[:0]
+	for _, e := range s.Entries {
+		if e.Name != name {
+			kept = append(kept, e)
+		}
+	}
+	s.Entries = kept
+}
+
+// ParseUCI reads a UCI config file such as /etc/config/wgmesh.
+func ParseUCI(r io.Reader) ([]*Section, error) {
+	var sections []*Section
+	var current *Section
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		words, err := splitUCIWords(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if len(words) == 0 {
+			continue
+		}
+		switch words[0] {
+		case "config":
+			if len(words) < 2 || len(words) > 3 {
+				return nil, fmt.Errorf("line %d: want config <type> [<name>]", lineNo)
+			}
+			current = &Section{Type: words[1]}
+			if len(words) == 3 {
+				current.Name = words[2]
+			}
+			sections = append(sections, current)
+		case "option", "list":
+			if current == nil {
+				return nil, fmt.Errorf("line %d: %s outside a config section", lineNo, words[0])
+			}
+			if len(words) != 3 {
+				return nil, fmt.Errorf("line %d: want %s <name> <value>", lineNo, words[0])
+			}
+			current.Entries = append(current.Entries, Entry{Name: words[1], Value: words[2], List: words[0] == "list"})
+		case "package":
+			// Only meaningful in multi-package imports; nothing to do.
+		default:
+			return nil, fmt.Errorf("line %d: unknown keyword %q", lineNo, words[0])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// FindSection returns the section of type typ named name, or nil.
+func FindSection(sections []*Section, typ, name string) *Section {
+	for _, s := range sections {
+		if s.Type == typ && s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// FormatUCI writes sections back in the layout uci itself uses.
+func FormatUCI(sections []*Section) string {
+	var b strings.Builder
+	for i, s := range sections {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("config " + s.Type)
+		if s.Name != "" {
+			b.WriteString(" " + quoteUCI(s.Name))
+		}
+		b.WriteString("\n")
+		for _, e := range s.Entries {
+			keyword := "option"
+			if e.List {
+				keyword = "list"
+			}
+			fmt.Fprintf(&b, "\t%s %s %s\n", keyword, e.Name, quoteUCI(e.Value))
+		}
+	}
+	return b.String()
+}
+
+// quoteUCI single-quotes s, closing and reopening the quotes around any
+// single quote in it.
+func quoteUCI(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// splitUCIWords splits a line into words the way uci's parser does:
+// whitespace separates words, single quotes are literal, double quotes and
+// bare words honour backslash escapes, and "#" starts a comment.
+func splitUCIWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '#' && !inWord:
+			return words, nil
+		case c == '\'':
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+			inWord = true
+		case c == '"':
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+				}
+				word.WriteByte(line[i])
+			}
+			if i >= len(line) {
+				return nil, fmt.Errorf("unterminated double quote")
+			}
+			inWord = true
+		case c == '\\' && i+1 < len(line):
+			i++
+			word.WriteByte(line[i])
+			inWord = true
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
@@ -0,0 +1,74 @@
+package openwrt
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+const sampleConfig = `# comment
+config wgmesh 'main'
+	option enabled '1'
+	option interface "wg0"
+	option listen_port 51820 # trailing comment
+	list advertise_routes '192.168.1.0/24'
+	list advertise_routes '192.168.2.0/24'
+	option tags 'it'\''s'
+
+config other
+	option name x\ y
+`
+
+func TestParseUCI(t *testing.T) {
+	sections, err := ParseUCI(strings.NewReader(sampleConfig))
+	if err != nil {
+		t.Fatalf("ParseUCI: %v", err)
+	}
+	if len(sections) != 2 {
+		t.Fatalf("got %d sections, want 2", len(sections))
+	}
+	main := FindSection(sections, SectionType, SectionName)
+	if main == nil {
+		t.Fatal("main section not found")
+	}
+	if v, _ := main.Get("interface"); v != "wg0" {
+		t.Errorf("interface = %q, want wg0", v)
+	}
+	if v, _ := main.Get("listen_port"); v != "51820" {
+		t.Errorf("listen_port = %q, want 51820", v)
+	}
+	if got := main.Values("advertise_routes"); !slices.Equal(got, []string{"192.168.1.0/24", "192.168.2.0/24"}) {
+		t.Errorf("advertise_routes = %v", got)
+	}
+	if v, _ := main.Get("tags"); v != "it's" {
+		t.Errorf("tags = %q, want it's", v)
+	}
+	if v, _ := sections[1].Get("name"); sections[1].Name != "" || v != "x y" {
+		t.Errorf("anonymous section = %+v", sections[1])
+	}
+
+	for _, bad := range []string{"option a 'b'", "config", "config wgmesh 'main\n", "config a b\n\tbogus x y"} {
+		if _, err := ParseUCI(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseUCI(%q) should fail", bad)
+		}
+	}
+}
+
+func TestFormatUCIRoundTrip(t *testing.T) {
+	s := &Section{Type: SectionType, Name: SectionName}
+	s.Set("interface", "wg0")
+	s.SetList("advertise_routes", []string{"192.168.1.0/24", "10.0.0.0/8"})
+	s.Set("tags", "it's")
+	s.Set("interface", "wg1")
+
+	sections, err := ParseUCI(strings.NewReader(FormatUCI([]*Section{s})))
+	if err != nil {
+		t.Fatalf("ParseUCI: %v", err)
+	}
+	if len(sections) != 1 || !slices.Equal(sections[0].Entries, s.Entries) {
+		t.Errorf("round trip = %+v, want %+v", sections, s.Entries)
+	}
+	if got := s.Values("interface"); !slices.Equal(got, []string{"wg1"}) {
+		t.Errorf("Set should replace the option, got %v", got)
+	}
+}
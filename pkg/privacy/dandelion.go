@@ -10,6 +10,7 @@ import (
 	"log"
 	"math/big"
 	"math/rand"
+	"slices"
 	"sort"
 	"sync"
 	"time"
@@ -24,6 +25,9 @@ const (
 	DandelionMethod = "dandelion"
 	// DefaultEpochDuration is the default duration for relay epochs
 	DefaultEpochDuration = 10 * time.Minute
+	// StemIntroducerCount is the number of introducers each epoch selects
+	// to stem first-contact announcements through
+	StemIntroducerCount = 2
 )
 
 // DandelionAnnounce represents an announcement being relayed through the Dandelion++ protocol
@@ -39,17 +43,19 @@ type DandelionAnnounce struct {
 
 // PeerInfo represents a minimal peer info for relay selection
 type PeerInfo struct {
-	WGPubKey string
-	MeshIP   string
-	Endpoint string
+	WGPubKey   string
+	MeshIP     string
+	Endpoint   string
+	Introducer bool
 }
 
 // Epoch represents a time-based relay configuration
 type Epoch struct {
-	ID         uint64
-	RelayPeers []PeerInfo
-	StartedAt  time.Time
-	Duration   time.Duration
+	ID              uint64
+	RelayPeers      []PeerInfo
+	StemIntroducers []PeerInfo // first-contact stem relays, see RouteFirstContact
+	StartedAt       time.Time
+	Duration        time.Duration
 }
 
 // DandelionRouter manages the Dandelion++ stem/fluff protocol
@@ -131,6 +137,36 @@ func (d *DandelionRouter) HandleAnnounce(msg DandelionAnnounce) {
 	}
 }
 
+// RouteFirstContact decides where a first-contact announcement goes after
+// hopCount stem hops. It returns the epoch's stem introducer to forward it
+// to, skipping the keys in exclude (the origin and the local node), or
+// ok=false when the announcement should be fluffed here instead. The origin
+// (hopCount 0) always stems when it has an introducer to stem to; relays
+// fluff with FluffProbability, and always after MaxStemHops.
+//
+// Nodes that learn of the origin after the fluff see it appear at the
+// fluffing introducer; the first introducer on the stem still sees the
+// origin itself.
+func (d *DandelionRouter) RouteFirstContact(hopCount uint8, exclude ...string) (relay PeerInfo, ok bool) {
+	if hopCount > 0 && ShouldFluff(hopCount) {
+		return PeerInfo{}, false
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.epoch == nil || len(d.epoch.StemIntroducers) == 0 {
+		return PeerInfo{}, false
+	}
+	n := len(d.epoch.StemIntroducers)
+	for i := 0; i < n; i++ {
+		relay := d.epoch.StemIntroducers[(int(hopCount)+i)%n]
+		if !slices.Contains(exclude, relay.WGPubKey) {
+			return relay, true
+		}
+	}
+	return PeerInfo{}, false
+}
+
 // ShouldFluff determines whether to transition from stem to fluff
 func ShouldFluff(hopCount uint8) bool {
 	// Force fluff after max hops
@@ -156,11 +192,20 @@ func (d *DandelionRouter) RotateEpoch(allPeers []PeerInfo) {
 	// Select relay peers deterministically using epoch seed
 	relayPeers := selectRelayPeers(d.epochSeed, newEpochID, allPeers, 2)
 
+	var introducers []PeerInfo
+	for _, p := range allPeers {
+		if p.Introducer {
+			introducers = append(introducers, p)
+		}
+	}
+	stemIntroducers := selectRelayPeers(d.epochSeed, newEpochID, introducers, StemIntroducerCount)
+
 	d.epoch = &Epoch{
-		ID:         newEpochID,
-		RelayPeers: relayPeers,
-		StartedAt:  time.Now(),
-		Duration:   DefaultEpochDuration,
+		ID:              newEpochID,
+		RelayPeers:      relayPeers,
+		StemIntroducers: stemIntroducers,
+		StartedAt:       time.Now(),
+		Duration:        DefaultEpochDuration,
 	}
 
 	if len(relayPeers) > 0 {
@@ -185,6 +230,9 @@ func (d *DandelionRouter) GetEpoch() *Epoch {
 		copy(relayPeersCopy, epochCopy.RelayPeers)
 		epochCopy.RelayPeers = relayPeersCopy
 	}
+	if epochCopy.StemIntroducers != nil {
+		epochCopy.StemIntroducers = append([]PeerInfo(nil), epochCopy.StemIntroducers...)
+	}
 
 	return &epochCopy
 }
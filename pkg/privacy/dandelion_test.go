@@ -152,3 +152,43 @@ func TestFormatEpochInfo(t *testing.T) {
 		t.Error("Expected non-empty epoch info")
 	}
 }
+
+func TestRotateEpochSelectsStemIntroducers(t *testing.T) {
+	router := NewDandelionRouter([32]byte{7})
+	router.RotateEpoch([]PeerInfo{
+		{WGPubKey: "peer-a"},
+		{WGPubKey: "intro-a", Introducer: true},
+		{WGPubKey: "peer-b"},
+		{WGPubKey: "intro-b", Introducer: true},
+		{WGPubKey: "intro-c", Introducer: true},
+	})
+
+	epoch := router.GetEpoch()
+	if len(epoch.StemIntroducers) != StemIntroducerCount {
+		t.Fatalf("Expected %d stem introducers, got %d", StemIntroducerCount, len(epoch.StemIntroducers))
+	}
+	for _, p := range epoch.StemIntroducers {
+		if !p.Introducer {
+			t.Errorf("Non-introducer %s selected as stem introducer", p.WGPubKey)
+		}
+	}
+}
+
+func TestRouteFirstContact(t *testing.T) {
+	router := NewDandelionRouter([32]byte{7})
+	if _, ok := router.RouteFirstContact(0); ok {
+		t.Error("Should fluff with no stem introducers")
+	}
+
+	router.RotateEpoch([]PeerInfo{{WGPubKey: "peer-a"}, {WGPubKey: "intro-a", Introducer: true}})
+	relay, ok := router.RouteFirstContact(0, "self")
+	if !ok || relay.WGPubKey != "intro-a" {
+		t.Errorf("Origin should stem to intro-a, got %q (ok=%v)", relay.WGPubKey, ok)
+	}
+	if _, ok := router.RouteFirstContact(0, "intro-a"); ok {
+		t.Error("Should not stem to an excluded introducer")
+	}
+	if _, ok := router.RouteFirstContact(MaxStemHops); ok {
+		t.Error("Should always fluff at max hops")
+	}
+}
@@ -0,0 +1,337 @@
+// Package proxy lets machines that cannot run WireGuard reach the mesh
+// through a host that can. A Server accepts SOCKS5 and HTTP proxy clients
+// on the same listener, telling them apart by the first byte, and only
+// connects to destinations its Directory places in the mesh, so it is not
+// an open proxy to the rest of the network.
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DialTimeout bounds connecting to a mesh destination.
+	DialTimeout = 10 * time.Second
+	// handshakeTimeout bounds reading a client's proxy request.
+	handshakeTimeout = 30 * time.Second
+)
+
+// ErrNotInMesh is returned by Directory.Resolve for destinations outside
+// the mesh.
+var ErrNotInMesh = errors.New("destination is not in the mesh")
+
+// Peer is what the Directory needs to know about a mesh node.
+type Peer struct {
+	Hostname string
+	MeshIP   string
+	MeshIPv6 string
+	Routes   []string // networks the node advertises
+}
+
+// Directory resolves proxy destinations to mesh addresses. The zero value
+// resolves nothing until Update is called.
+type Directory struct {
+	mu    sync.RWMutex
+	names map[string]net.IP // lower-case hostname -> mesh IP
+	ips   map[string]bool   // mesh IPs
+	nets  []*net.IPNet      // advertised routes
+}
+
+// Update replaces the directory's view of the mesh.
+func (d *Directory) Update(peers []Peer) {
+	names := make(map[string]net.IP)
+	ips := make(map[string]bool)
+	var nets []*net.IPNet
+	for _, p := range peers {
+		var first net.IP
+		for _, addr := range []string{p.MeshIP, p.MeshIPv6} {
+			if ip := net.ParseIP(addr); ip != nil {
+				ips[ip.String()] = true
+				if first == nil {
+					first = ip
+				}
+			}
+		}
+		if p.Hostname != "" && first != nil {
+			names[strings.ToLower(p.Hostname)] = first
+		}
+		for _, r := range p.Routes {
+			if _, ipNet, err := net.ParseCIDR(r); err == nil {
+				nets = append(nets, ipNet)
+			}
+		}
+	}
+
+	d.mu.Lock()
+	d.names, d.ips, d.nets = names, ips, nets
+	d.mu.Unlock()
+}
+
+// Resolve maps host, a peer hostname with or without a ".mesh" suffix or
+// an IP address, to the IP to dial. IP addresses must be a node's mesh
+// address or inside a network a node advertises.
+func (d *Directory) Resolve(host string) (net.IP, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil {
+		if d.ips[ip.String()] {
+			return ip, nil
+		}
+		for _, n := range d.nets {
+			if n.Contains(ip) {
+				return ip, nil
+			}
+		}
+		return nil, fmt.Errorf("%s: %w", host, ErrNotInMesh)
+	}
+	name := strings.ToLower(strings.TrimSuffix(strings.TrimSuffix(host, "."), ".mesh"))
+	if ip, ok := d.names[name]; ok {
+		return ip, nil
+	}
+	return nil, fmt.Errorf("%s: %w", host, ErrNotInMesh)
+}
+
+// Server is a SOCKS5 and HTTP proxy into the mesh.
+type Server struct {
+	Directory *Directory
+	// Dial connects to a resolved destination; nil uses net.Dialer.
+	Dial func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// Serve accepts clients on ln until it fails, e.g. because ln was closed.
+func (s *Server) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	br := bufio.NewReader(conn)
+	first, err := br.Peek(1)
+	if err != nil {
+		return
+	}
+	if first[0] == socksVersion {
+		err = s.serveSOCKS(conn, br)
+	} else {
+		err = s.serveHTTP(conn, br)
+	}
+	if err != nil {
+		log.Printf("[Proxy] %s: %v", conn.RemoteAddr(), err)
+	}
+}
+
+// connect resolves and dials hostport, a host:port as the client sent it.
+func (s *Server) connect(hostport string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return nil, err
+	}
+	ip, err := s.Directory.Resolve(host)
+	if err != nil {
+		return nil, err
+	}
+	dial := s.Dial
+	if dial == nil {
+		var d net.Dialer
+		dial = d.DialContext
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DialTimeout)
+	defer cancel()
+	return dial(ctx, "tcp", net.JoinHostPort(ip.String(), port))
+}
+
+// relay copies between the client and the destination until the
+// destination is done sending. The caller closes both connections, which
+// ends the copy from the client.
+func relay(client io.ReadWriter, upstream net.Conn) {
+	go func() {
+		_, _ = io.Copy(upstream, client)
+		if tcp, ok := upstream.(interface{ CloseWrite() error }); ok {
+			_ = tcp.CloseWrite()
+		}
+	}()
+	_, _ = io.Copy(client, upstream)
+}
+
+// SOCKS5 (RFC 1928), CONNECT only and without authentication.
+const (
+	socksVersion      = 0x05
+	socksNoAuth       = 0x00
+	socksNoAcceptable = 0xff
+	socksConnect      = 0x01
+
+	socksAddrIPv4   = 0x01
+	socksAddrDomain = 0x03
+	socksAddrIPv6   = 0x04
+
+	socksSucceeded          = 0x00
+	socksNotAllowed         = 0x02
+	socksHostUnreachable    = 0x04
+	socksCommandUnsupported = 0x07
+	socksAddrUnsupported    = 0x08
+)
+
+func (s *Server) serveSOCKS(conn net.Conn, br *bufio.Reader) error {
+	var hdr [2]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return err
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(br, methods); err != nil {
+		return err
+	}
+	method := byte(socksNoAcceptable)
+	for _, m := range methods {
+		if m == socksNoAuth {
+			method = socksNoAuth
+		}
+	}
+	if _, err := conn.Write([]byte{socksVersion, method}); err != nil || method != socksNoAuth {
+		return err
+	}
+
+	var req [4]byte
+	if _, err := io.ReadFull(br, req[:]); err != nil {
+		return err
+	}
+	if req[0] != socksVersion {
+		return fmt.Errorf("bad SOCKS version %d", req[0])
+	}
+	var host string
+	switch req[3] {
+	case socksAddrIPv4, socksAddrIPv6:
+		ip := make(net.IP, 4)
+		if req[3] == socksAddrIPv6 {
+			ip = make(net.IP, 16)
+		}
+		if _, err := io.ReadFull(br, ip); err != nil {
+			return err
+		}
+		host = ip.String()
+	case socksAddrDomain:
+		n, err := br.ReadByte()
+		if err != nil {
+			return err
+		}
+		name := make([]byte, n)
+		if _, err := io.ReadFull(br, name); err != nil {
+			return err
+		}
+		host = string(name)
+	default:
+		return socksReply(conn, socksAddrUnsupported)
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(br, port[:]); err != nil {
+		return err
+	}
+	if req[1] != socksConnect {
+		return socksReply(conn, socksCommandUnsupported)
+	}
+
+	target := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:]))))
+	upstream, err := s.connect(target)
+	if err != nil {
+		code := byte(socksHostUnreachable)
+		if errors.Is(err, ErrNotInMesh) {
+			code = socksNotAllowed
+		}
+		_ = socksReply(conn, code)
+		return err
+	}
+	defer upstream.Close()
+	if err := socksReply(conn, socksSucceeded); err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	relay(struct {
+		io.Reader
+		io.Writer
+	}{br, conn}, upstream)
+	return nil
+}
+
+// socksReply sends a reply with an empty IPv4 bound address, which
+// clients ignore for CONNECT.
+func socksReply(conn net.Conn, code byte) error {
+	_, err := conn.Write([]byte{socksVersion, code, 0, socksAddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+// serveHTTP handles CONNECT tunnels and plain http:// requests, one
+// request per connection.
+func (s *Server) serveHTTP(conn net.Conn, br *bufio.Reader) error {
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return err
+	}
+
+	if req.Method == http.MethodConnect {
+		upstream, err := s.connect(req.Host)
+		if err != nil {
+			return httpError(conn, err)
+		}
+		defer upstream.Close()
+		if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+			return err
+		}
+		_ = conn.SetDeadline(time.Time{})
+		relay(struct {
+			io.Reader
+			io.Writer
+		}{br, conn}, upstream)
+		return nil
+	}
+
+	if req.URL.Scheme != "http" || req.URL.Host == "" {
+		_, err := io.WriteString(conn, "HTTP/1.1 400 Bad Request\r\nConnection: close\r\n\r\n")
+		return err
+	}
+	hostport := req.URL.Host
+	if req.URL.Port() == "" {
+		hostport = net.JoinHostPort(req.URL.Hostname(), "80")
+	}
+	upstream, err := s.connect(hostport)
+	if err != nil {
+		return httpError(conn, err)
+	}
+	defer upstream.Close()
+	req.Header.Del("Proxy-Connection")
+	req.Header.Del("Proxy-Authorization")
+	req.Close = true
+	if err := req.Write(upstream); err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Time{})
+	_, err = io.Copy(conn, upstream)
+	return err
+}
+
+func httpError(conn net.Conn, err error) error {
+	status := "502 Bad Gateway"
+	if errors.Is(err, ErrNotInMesh) {
+		status = "403 Forbidden"
+	}
+	_, _ = fmt.Fprintf(conn, "HTTP/1.1 %s\r\nContent-Type: text/plain\r\nConnection: close\r\n\r\n%v\n", status, err)
+	return err
+}
@@ -0,0 +1,202 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func testDirectory() *Directory {
+	d := &Directory{}
+	d.Update([]Peer{
+		{Hostname: "db-1", MeshIP: "10.42.0.7", MeshIPv6: "fd00::7"},
+		{Hostname: "gw", MeshIP: "10.42.0.1", Routes: []string{"192.168.10.0/24"}},
+		{MeshIP: "10.42.0.9"},
+	})
+	return d
+}
+
+func TestDirectoryResolve(t *testing.T) {
+	d := testDirectory()
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"db-1", "10.42.0.7"},
+		{"DB-1.mesh", "10.42.0.7"},
+		{"db-1.mesh.", "10.42.0.7"},
+		{"10.42.0.9", "10.42.0.9"},
+		{"[fd00::7]", "fd00::7"},
+		{"192.168.10.20", "192.168.10.20"},
+	}
+	for _, tt := range tests {
+		ip, err := d.Resolve(tt.host)
+		if err != nil || ip.String() != tt.want {
+			t.Errorf("Resolve(%q) = %v, %v; want %s", tt.host, ip, err, tt.want)
+		}
+	}
+	for _, host := range []string{"example.com", "8.8.8.8", "10.42.0.99", "web.mesh"} {
+		if _, err := d.Resolve(host); !errors.Is(err, ErrNotInMesh) {
+			t.Errorf("Resolve(%q) err = %v, want ErrNotInMesh", host, err)
+		}
+	}
+}
+
+// startProxy serves a proxy whose dials all reach an echo server that
+// first writes the address it was asked to dial.
+func startProxy(t *testing.T) string {
+	t.Helper()
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { echo.Close() })
+	dialed := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.WriteString(conn, <-dialed+"\n")
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return serveProxy(t, echo.Addr().String(), dialed)
+}
+
+// serveProxy serves a proxy whose dials all reach backend; the address it
+// was asked to dial is sent on dialed.
+func serveProxy(t *testing.T, backend string, dialed chan<- string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	var dialer net.Dialer
+	s := &Server{
+		Directory: testDirectory(),
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialed <- address
+			return dialer.DialContext(ctx, network, backend)
+		},
+	}
+	go s.Serve(ln)
+	return ln.Addr().String()
+}
+
+func TestSOCKSConnect(t *testing.T) {
+	addr := startProxy(t)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte{socksVersion, 1, socksNoAuth})
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil || reply[1] != socksNoAuth {
+		t.Fatalf("method reply = %v, %v", reply, err)
+	}
+	name := "db-1.mesh"
+	req := append([]byte{socksVersion, socksConnect, 0, socksAddrDomain, byte(len(name))}, name...)
+	conn.Write(append(req, 0x15, 0x38)) // port 5432
+	reply = make([]byte, 10)
+	if _, err := io.ReadFull(conn, reply); err != nil || reply[1] != socksSucceeded {
+		t.Fatalf("connect reply = %v, %v", reply, err)
+	}
+
+	br := bufio.NewReader(conn)
+	if line, _ := br.ReadString('\n'); line != "10.42.0.7:5432\n" {
+		t.Errorf("dialed %q, want 10.42.0.7:5432", line)
+	}
+	conn.Write([]byte("ping\n"))
+	if line, _ := br.ReadString('\n'); line != "ping\n" {
+		t.Errorf("echo = %q", line)
+	}
+}
+
+func TestSOCKSRejectsOutsideMesh(t *testing.T) {
+	addr := startProxy(t)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	conn.Write([]byte{socksVersion, 1, socksNoAuth})
+	io.ReadFull(conn, make([]byte, 2))
+	conn.Write([]byte{socksVersion, socksConnect, 0, socksAddrIPv4, 8, 8, 8, 8, 0, 53})
+	reply := make([]byte, 10)
+	if _, err := io.ReadFull(conn, reply); err != nil || reply[1] != socksNotAllowed {
+		t.Errorf("reply = %v, %v; want not allowed", reply, err)
+	}
+}
+
+func TestHTTPConnect(t *testing.T) {
+	addr := startProxy(t)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	io.WriteString(conn, "CONNECT gw:443 HTTP/1.1\r\nHost: gw:443\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("CONNECT response = %v, %v", resp, err)
+	}
+	if line, _ := br.ReadString('\n'); line != "10.42.0.1:443\n" {
+		t.Errorf("dialed %q, want 10.42.0.1:443", line)
+	}
+}
+
+func TestHTTPForward(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.URL.Path+" proxy-connection="+r.Header.Get("Proxy-Connection"))
+	}))
+	defer backend.Close()
+	dialed := make(chan string, 1)
+	addr := serveProxy(t, backend.Listener.Addr().String(), dialed)
+
+	proxyURL, _ := url.Parse("http://" + addr)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+	resp, err := client.Get("http://db-1/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "/status proxy-connection=" {
+		t.Errorf("body = %q", body)
+	}
+	if got := <-dialed; got != "10.42.0.7:80" {
+		t.Errorf("dialed %q, want 10.42.0.7:80", got)
+	}
+}
+
+func TestHTTPRejectsOutsideMesh(t *testing.T) {
+	addr := startProxy(t)
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	io.WriteString(conn, "CONNECT example.com:443 HTTP/1.1\r\nHost: example.com:443\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("response = %v, %v; want 403", resp, err)
+	}
+}
@@ -13,6 +13,7 @@ import (
 type Entry struct {
 	Network string // CIDR, e.g. "10.0.0.0/8" or "192.168.5.5/32"
 	Gateway string // Next-hop IP, empty for directly-connected routes
+	Metric  int    // Route priority; 0 = the kernel default
 }
 
 // NormalizeNetwork normalizes a network string returned by the kernel.
@@ -45,7 +46,7 @@ func MakeKey(network, gateway string) string {
 //
 // Rules:
 //   - If a desired route already exists exactly (same network + gateway) it
-//     is skipped.
+//     is skipped, unless its metric differs, in which case it is replaced.
 //   - If a desired network exists with a *different* gateway, the old route is
 //     queued for removal before the new one is added.
 //   - If a current network is no longer in the desired set at all, and it has
@@ -71,7 +72,12 @@ func CalculateDiff(current, desired []Entry) (toAdd, toRemove []Entry) {
 
 	// Determine routes to add (and any prerequisite removals for gateway changes).
 	for key, route := range desiredMap {
-		if _, exists := currentMap[key]; !exists {
+		if currentRoute, exists := currentMap[key]; exists && currentRoute.Metric != route.Metric {
+			// Metric changed — the kernel keeps routes with different
+			// metrics side by side, so remove the old one explicitly.
+			toRemove = append(toRemove, currentRoute)
+			toAdd = append(toAdd, route)
+		} else if !exists {
 			// Route with this exact network+gateway doesn't exist yet.
 			if currentRoute, networkExists := currentByNetwork[route.Network]; networkExists {
 				if currentRoute.Gateway != route.Gateway && currentRoute.Gateway != "" {
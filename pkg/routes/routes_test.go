@@ -106,6 +106,20 @@ func TestCalculateDiff_GatewayChanged(t *testing.T) {
 	}
 }
 
+func TestCalculateDiff_MetricChanged(t *testing.T) {
+	t.Parallel()
+	current := []Entry{{Network: "10.0.0.0/8", Gateway: "192.168.1.1"}}
+	desired := []Entry{{Network: "10.0.0.0/8", Gateway: "192.168.1.1", Metric: 100}}
+	add, remove := CalculateDiff(current, desired)
+
+	if len(add) != 1 || add[0] != desired[0] {
+		t.Errorf("expected add of route with new metric; got %v", add)
+	}
+	if len(remove) != 1 || remove[0] != current[0] {
+		t.Errorf("expected remove of route with old metric; got %v", remove)
+	}
+}
+
 func TestCalculateDiff_DirectlyConnectedNotRemoved(t *testing.T) {
 	t.Parallel()
 	// Routes with empty gateway (directly connected) must not be removed.
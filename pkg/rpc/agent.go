@@ -0,0 +1,198 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/rpc/daemonpb"
+)
+
+// Agent holds the mesh secret in memory for `wgmesh agent` so interactive
+// commands can fetch it with secret.unlock instead of taking --secret. It
+// answers only secret.unlock and daemon.ping, over gRPC or JSON-RPC.
+type Agent struct {
+	socketPath string
+	version    string
+	listener   net.Listener
+	grpcServer *grpc.Server
+	grpcConns  *connListener
+
+	mu     sync.Mutex
+	secret string
+}
+
+// NewAgent creates an agent serving secret on socketPath.
+func NewAgent(socketPath, version, secret string) (*Agent, error) {
+	if socketPath == "" {
+		return nil, fmt.Errorf("socket path is required")
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("secret is required")
+	}
+	return &Agent{socketPath: socketPath, version: version, secret: secret}, nil
+}
+
+// AgentSocketPath returns the per-user agent socket: $WGMESH_AGENT_SOCKET,
+// else $XDG_RUNTIME_DIR/wgmesh-agent.sock, else /tmp/wgmesh-agent-<uid>.sock.
+func AgentSocketPath() string {
+	if path := os.Getenv("WGMESH_AGENT_SOCKET"); path != "" {
+		return path
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "wgmesh-agent.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("wgmesh-agent-%d.sock", os.Getuid()))
+}
+
+// Start listens on the agent socket. The socket is only accessible to its
+// owner; secret.unlock additionally checks peer credentials.
+func (a *Agent) Start() error {
+	if err := os.Remove(a.socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove existing socket: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(a.socketPath), 0700); err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	listener, err := net.Listen("unix", a.socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on socket: %w", err)
+	}
+	if err := os.Chmod(a.socketPath, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	a.listener = listener
+
+	a.grpcServer = newGRPCServer("")
+	daemonpb.RegisterDaemonServer(a.grpcServer, &agentService{a: a})
+	a.grpcConns = newConnListener()
+	go a.grpcServer.Serve(a.grpcConns)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go a.handleConnection(conn)
+		}
+	}()
+	return nil
+}
+
+// Stop closes the socket and forgets the secret.
+func (a *Agent) Stop() error {
+	a.mu.Lock()
+	a.secret = ""
+	a.mu.Unlock()
+
+	if a.listener != nil {
+		a.listener.Close()
+	}
+	if a.grpcServer != nil {
+		a.grpcServer.Stop()
+	}
+	if err := os.Remove(a.socketPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove socket: %w", err)
+	}
+	return nil
+}
+
+func (a *Agent) handleConnection(conn net.Conn) {
+	cred := peerCredentials(conn)
+	sc, isGRPC, err := sniffConn(conn, callerInfo{cred: cred})
+	if err != nil {
+		conn.Close()
+		return
+	}
+	if isGRPC {
+		a.grpcConns.add(sc)
+		return
+	}
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(sc)
+	enc := json.NewEncoder(conn)
+
+	for scanner.Scan() {
+		resp := &Response{JSONRPC: "2.0"}
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			resp.Error = &Error{Code: ErrCodeParseError, Message: fmt.Sprintf("failed to parse request: %v", err)}
+		} else {
+			resp.ID = req.ID
+			resp.Result, resp.Error = a.handleRequest(req.Method, cred)
+		}
+		if err := enc.Encode(resp); err != nil {
+			log.Printf("Agent: failed to write response: %v", err)
+			return
+		}
+	}
+}
+
+func (a *Agent) handleRequest(method string, cred *PeerCred) (interface{}, *Error) {
+	switch method {
+	case "secret.unlock":
+		if err := authorizeSecretAccess(cred); err != nil {
+			return nil, err
+		}
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return &SecretUnlockResult{Secret: a.secret}, nil
+	case "daemon.ping":
+		return &DaemonPingResult{Pong: true, Version: a.version}, nil
+	}
+	return nil, &Error{Code: ErrCodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", method)}
+}
+
+// agentService is the agent's part of the Daemon gRPC service.
+type agentService struct {
+	daemonpb.UnimplementedDaemonServer
+	a *Agent
+}
+
+func (g *agentService) Ping(_ context.Context, _ *daemonpb.PingRequest) (*daemonpb.PingResponse, error) {
+	return &daemonpb.PingResponse{Pong: true, Version: g.a.version}, nil
+}
+
+func (g *agentService) UnlockSecret(ctx context.Context, _ *daemonpb.UnlockSecretRequest) (*daemonpb.UnlockSecretResponse, error) {
+	result, err := g.a.handleRequest("secret.unlock", callerFrom(ctx).cred)
+	if err != nil {
+		return nil, status.Error(grpcCode(err.Code), err.Message)
+	}
+	return &daemonpb.UnlockSecretResponse{Secret: result.(*SecretUnlockResult).Secret}, nil
+}
+
+// UnlockSecret asks the agent or daemon listening on socketPath for the
+// mesh secret.
+func UnlockSecret(socketPath string) (string, error) {
+	client, err := NewClient(socketPath)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	result, err := client.Call("secret.unlock", nil)
+	if err != nil {
+		return "", err
+	}
+	resultMap, ok := result.(map[string]interface{})
+	if !ok {
+		return "", fmt.Errorf("invalid response format")
+	}
+	secret, _ := resultMap["secret"].(string)
+	if secret == "" {
+		return "", fmt.Errorf("no secret returned")
+	}
+	return secret, nil
+}
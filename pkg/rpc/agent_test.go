@@ -0,0 +1,79 @@
+package rpc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAgentUnlockSecret(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "agent.sock")
+	agent, err := NewAgent(socketPath, "test", "wgmesh://v1/agent-test-secret")
+	if err != nil {
+		t.Fatalf("NewAgent: %v", err)
+	}
+	if err := agent.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer agent.Stop()
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	secret, err := UnlockSecret(socketPath)
+	if err != nil {
+		t.Fatalf("UnlockSecret: %v", err)
+	}
+	if secret != "wgmesh://v1/agent-test-secret" {
+		t.Errorf("secret = %q", secret)
+	}
+
+	client, err := NewClient(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Call("daemon.ping", nil); err != nil {
+		t.Errorf("daemon.ping: %v", err)
+	}
+	if _, err := client.Call("peers.list", nil); err == nil {
+		t.Error("agent should not answer peers.list")
+	}
+
+	if err := agent.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if _, err := UnlockSecret(socketPath); err == nil {
+		t.Error("expected error after Stop")
+	}
+}
+
+func TestAuthorizeSecretAccess(t *testing.T) {
+	self := uint32(os.Geteuid())
+	tests := []struct {
+		name string
+		cred *PeerCred
+		ok   bool
+	}{
+		{name: "unknown caller relies on socket permissions", cred: nil, ok: true},
+		{name: "root", cred: &PeerCred{UID: 0}, ok: true},
+		{name: "same user", cred: &PeerCred{UID: self}, ok: true},
+		{name: "other user", cred: &PeerCred{UID: self + 1000}, ok: self+1000 == 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := authorizeSecretAccess(tt.cred)
+			if (err == nil) != tt.ok {
+				t.Errorf("authorizeSecretAccess(%+v) = %v, want ok=%v", tt.cred, err, tt.ok)
+			}
+			if err != nil && err.Code != ErrCodeUnauthorized {
+				t.Errorf("error code = %d, want %d", err.Code, ErrCodeUnauthorized)
+			}
+		})
+	}
+}
@@ -2,21 +2,42 @@ package rpc
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
+	"os"
+	"strings"
 	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
-// Client is an RPC client that connects to the daemon via Unix socket
+// Client is an RPC client that connects to the daemon via Unix socket.
+// It speaks gRPC, or the legacy JSON-RPC protocol when WGMESH_RPC_PROTOCOL
+// is "json" (for daemons older than the gRPC protocol).
 type Client struct {
 	socketPath string
-	conn       net.Conn
+	token      string
+	conn       net.Conn         // JSON-RPC
+	grpcConn   *grpc.ClientConn // gRPC
 	nextID     atomic.Int64
 }
 
-// NewClient creates a new RPC client connected to the given socket path
+// NewClient creates a new RPC client connected to the given socket path.
+// A "tcp://host:port" path connects to a daemon's TCP listener instead,
+// with the token from WGMESH_RPC_TOKEN.
 func NewClient(socketPath string) (*Client, error) {
+	if addr, ok := strings.CutPrefix(socketPath, "tcp://"); ok {
+		return NewTCPClient(addr, os.Getenv("WGMESH_RPC_TOKEN"))
+	}
+
 	conn, err := net.Dial("unix", socketPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to socket: %w", err)
@@ -24,21 +45,81 @@ func NewClient(socketPath string) (*Client, error) {
 
 	client := &Client{
 		socketPath: socketPath,
-		conn:       conn,
 	}
 	client.nextID.Store(1)
+	if legacyJSONClient() {
+		client.conn = conn
+		return client, nil
+	}
+
+	// gRPC dials lazily; the probe above reports a missing daemon now.
+	conn.Close()
+	client.grpcConn, err = grpc.NewClient("unix:"+socketPath,
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to socket: %w", err)
+	}
+	return client, nil
+}
+
+// NewTCPClient creates a new RPC client connected to a daemon's TCP
+// listener (see ServerConfig.TCPPort), authenticating with token.
+func NewTCPClient(addr, token string) (*Client, error) {
+	if token == "" {
+		return nil, fmt.Errorf("a token is required for RPC over TCP")
+	}
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+
+	client := &Client{
+		socketPath: "tcp://" + addr,
+		token:      token,
+	}
+	client.nextID.Store(1)
+	if legacyJSONClient() {
+		client.conn = conn
+		return client, nil
+	}
 
+	conn.Close()
+	client.grpcConn, err = grpc.NewClient("passthrough:///"+addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(tokenCredentials(token)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
 	return client, nil
 }
 
-// Call makes an RPC call to the daemon
+func legacyJSONClient() bool {
+	return os.Getenv("WGMESH_RPC_PROTOCOL") == "json"
+}
+
+// tokenCredentials sends the TCP token as a bearer token on every call.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (tokenCredentials) RequireTransportSecurity() bool { return false }
+
+// Call makes an RPC call to the daemon. Over gRPC, the method and params
+// are those of the JSON-RPC protocol and the result has the same shape.
 func (c *Client) Call(method string, params map[string]interface{}) (interface{}, error) {
+	if c.grpcConn != nil {
+		return c.callGRPC(method, params)
+	}
+
 	// Build request
 	req := &Request{
 		JSONRPC: "2.0",
 		Method:  method,
 		Params:  params,
 		ID:      c.nextID.Add(1),
+		Token:   c.token,
 	}
 
 	// Encode request
@@ -73,8 +154,53 @@ func (c *Client) Call(method string, params map[string]interface{}) (interface{}
 	return resp.Result, nil
 }
 
+// callGRPC makes the gRPC call corresponding to a JSON-RPC method.
+func (c *Client) callGRPC(method string, params map[string]interface{}) (interface{}, error) {
+	name, ok := grpcMethods[method]
+	if !ok {
+		return nil, fmt.Errorf("RPC error %d: method not found: %s", ErrCodeMethodNotFound, method)
+	}
+	md := daemonService.Methods().ByName(protoreflect.Name(name))
+	reqType, err := protoregistry.GlobalTypes.FindMessageByName(md.Input().FullName())
+	if err != nil {
+		return nil, err
+	}
+	respType, err := protoregistry.GlobalTypes.FindMessageByName(md.Output().FullName())
+	if err != nil {
+		return nil, err
+	}
+	req, resp := reqType.New().Interface(), respType.New().Interface()
+
+	if len(params) > 0 {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, req); err != nil {
+			return nil, fmt.Errorf("RPC error %d: invalid params: %v", ErrCodeInvalidParams, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	fullMethod := fmt.Sprintf("/%s/%s", daemonService.FullName(), name)
+	if err := c.grpcConn.Invoke(ctx, fullMethod, req, resp); err != nil {
+		st := status.Convert(err)
+		return nil, fmt.Errorf("RPC error %d: %s", jsonCode(st.Code()), st.Message())
+	}
+
+	result := messageToMap(resp.ProtoReflect())
+	if method == "state.export" {
+		return result["snapshot"], nil
+	}
+	return result, nil
+}
+
 // Close closes the connection to the daemon
 func (c *Client) Close() error {
+	if c.grpcConn != nil {
+		return c.grpcConn.Close()
+	}
 	if c.conn != nil {
 		return c.conn.Close()
 	}
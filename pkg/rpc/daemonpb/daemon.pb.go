@@ -0,0 +1,4496 @@
+// The wgmesh daemon RPC, served over gRPC on the daemon's Unix socket (and
+// on the optional TCP listener). Each method corresponds to one method of
+// the legacy JSON-RPC protocol, named in its comment; field names are the
+// JSON keys of that protocol. Optional request fields are the parameters
+// that protocol treats as absent when not given.
+//
+// Regenerate the Go and TypeScript clients with `make proto`.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: wgmesh/daemon/v1/daemon.proto
+
+package daemonpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingRequest) Reset() {
+	*x = PingRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingRequest) ProtoMessage() {}
+
+func (x *PingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingRequest.ProtoReflect.Descriptor instead.
+func (*PingRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{0}
+}
+
+type PingResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pong          bool                   `protobuf:"varint,1,opt,name=pong,proto3" json:"pong,omitempty"`
+	Version       string                 `protobuf:"bytes,2,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingResponse) Reset() {
+	*x = PingResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingResponse) ProtoMessage() {}
+
+func (x *PingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingResponse.ProtoReflect.Descriptor instead.
+func (*PingResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *PingResponse) GetPong() bool {
+	if x != nil {
+		return x.Pong
+	}
+	return false
+}
+
+func (x *PingResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{2}
+}
+
+type GetStatusResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	MeshIp         string                 `protobuf:"bytes,1,opt,name=mesh_ip,json=meshIp,proto3" json:"mesh_ip,omitempty"`
+	Pubkey         string                 `protobuf:"bytes,2,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	Uptime         int64                  `protobuf:"varint,3,opt,name=uptime,proto3" json:"uptime,omitempty"` // nanoseconds
+	Interface      string                 `protobuf:"bytes,4,opt,name=interface,proto3" json:"interface,omitempty"`
+	Version        string                 `protobuf:"bytes,5,opt,name=version,proto3" json:"version,omitempty"`
+	ClockSkew      int64                  `protobuf:"varint,6,opt,name=clock_skew,json=clockSkew,proto3" json:"clock_skew,omitempty"`                  // nanoseconds the local clock runs ahead of the mesh (negative: behind)
+	ClockSkewPeers int32                  `protobuf:"varint,7,opt,name=clock_skew_peers,json=clockSkewPeers,proto3" json:"clock_skew_peers,omitempty"` // peers the clock skew estimate is based on
+	Identity       string                 `protobuf:"bytes,8,opt,name=identity,proto3" json:"identity,omitempty"`                                      // identity key named in --approvers
+	MeshName       string                 `protobuf:"bytes,9,opt,name=mesh_name,json=meshName,proto3" json:"mesh_name,omitempty"`                      // display name of the mesh
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *GetStatusResponse) Reset() {
+	*x = GetStatusResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusResponse) ProtoMessage() {}
+
+func (x *GetStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetStatusResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetStatusResponse) GetMeshIp() string {
+	if x != nil {
+		return x.MeshIp
+	}
+	return ""
+}
+
+func (x *GetStatusResponse) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+func (x *GetStatusResponse) GetUptime() int64 {
+	if x != nil {
+		return x.Uptime
+	}
+	return 0
+}
+
+func (x *GetStatusResponse) GetInterface() string {
+	if x != nil {
+		return x.Interface
+	}
+	return ""
+}
+
+func (x *GetStatusResponse) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *GetStatusResponse) GetClockSkew() int64 {
+	if x != nil {
+		return x.ClockSkew
+	}
+	return 0
+}
+
+func (x *GetStatusResponse) GetClockSkewPeers() int32 {
+	if x != nil {
+		return x.ClockSkewPeers
+	}
+	return 0
+}
+
+func (x *GetStatusResponse) GetIdentity() string {
+	if x != nil {
+		return x.Identity
+	}
+	return ""
+}
+
+func (x *GetStatusResponse) GetMeshName() string {
+	if x != nil {
+		return x.MeshName
+	}
+	return ""
+}
+
+type GetReadinessRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinPeers      *int32                 `protobuf:"varint,1,opt,name=min_peers,json=minPeers,proto3,oneof" json:"min_peers,omitempty"` // peers with a recent handshake needed; default 1
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetReadinessRequest) Reset() {
+	*x = GetReadinessRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetReadinessRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReadinessRequest) ProtoMessage() {}
+
+func (x *GetReadinessRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReadinessRequest.ProtoReflect.Descriptor instead.
+func (*GetReadinessRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetReadinessRequest) GetMinPeers() int32 {
+	if x != nil && x.MinPeers != nil {
+		return *x.MinPeers
+	}
+	return 0
+}
+
+type GetReadinessResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ready         bool                   `protobuf:"varint,1,opt,name=ready,proto3" json:"ready,omitempty"`
+	InterfaceUp   bool                   `protobuf:"varint,2,opt,name=interface_up,json=interfaceUp,proto3" json:"interface_up,omitempty"`
+	Peers         int32                  `protobuf:"varint,3,opt,name=peers,proto3" json:"peers,omitempty"` // peers with a recent handshake
+	MinPeers      int32                  `protobuf:"varint,4,opt,name=min_peers,json=minPeers,proto3" json:"min_peers,omitempty"`
+	Reason        string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"` // why the mesh is not ready
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetReadinessResponse) Reset() {
+	*x = GetReadinessResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetReadinessResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReadinessResponse) ProtoMessage() {}
+
+func (x *GetReadinessResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReadinessResponse.ProtoReflect.Descriptor instead.
+func (*GetReadinessResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetReadinessResponse) GetReady() bool {
+	if x != nil {
+		return x.Ready
+	}
+	return false
+}
+
+func (x *GetReadinessResponse) GetInterfaceUp() bool {
+	if x != nil {
+		return x.InterfaceUp
+	}
+	return false
+}
+
+func (x *GetReadinessResponse) GetPeers() int32 {
+	if x != nil {
+		return x.Peers
+	}
+	return 0
+}
+
+func (x *GetReadinessResponse) GetMinPeers() int32 {
+	if x != nil {
+		return x.MinPeers
+	}
+	return 0
+}
+
+func (x *GetReadinessResponse) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type GetResourcesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResourcesRequest) Reset() {
+	*x = GetResourcesRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResourcesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResourcesRequest) ProtoMessage() {}
+
+func (x *GetResourcesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResourcesRequest.ProtoReflect.Descriptor instead.
+func (*GetResourcesRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{6}
+}
+
+type Resource struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"` // "fds", "goroutines" or "probe-sessions"
+	Used          int32                  `protobuf:"varint,2,opt,name=used,proto3" json:"used,omitempty"`
+	Limit         int32                  `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"` // 0 = not tracked
+	Pressure      bool                   `protobuf:"varint,4,opt,name=pressure,proto3" json:"pressure,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Resource) Reset() {
+	*x = Resource{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Resource) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Resource) ProtoMessage() {}
+
+func (x *Resource) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Resource.ProtoReflect.Descriptor instead.
+func (*Resource) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Resource) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Resource) GetUsed() int32 {
+	if x != nil {
+		return x.Used
+	}
+	return 0
+}
+
+func (x *Resource) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *Resource) GetPressure() bool {
+	if x != nil {
+		return x.Pressure
+	}
+	return false
+}
+
+type GetResourcesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pressure      bool                   `protobuf:"varint,1,opt,name=pressure,proto3" json:"pressure,omitempty"` // any resource is under pressure
+	Resources     []*Resource            `protobuf:"bytes,2,rep,name=resources,proto3" json:"resources,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetResourcesResponse) Reset() {
+	*x = GetResourcesResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetResourcesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetResourcesResponse) ProtoMessage() {}
+
+func (x *GetResourcesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetResourcesResponse.ProtoReflect.Descriptor instead.
+func (*GetResourcesResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GetResourcesResponse) GetPressure() bool {
+	if x != nil {
+		return x.Pressure
+	}
+	return false
+}
+
+func (x *GetResourcesResponse) GetResources() []*Resource {
+	if x != nil {
+		return x.Resources
+	}
+	return nil
+}
+
+type GetLANDiscoveryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLANDiscoveryRequest) Reset() {
+	*x = GetLANDiscoveryRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLANDiscoveryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLANDiscoveryRequest) ProtoMessage() {}
+
+func (x *GetLANDiscoveryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLANDiscoveryRequest.ProtoReflect.Descriptor instead.
+func (*GetLANDiscoveryRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{9}
+}
+
+type LANInterface struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Addresses     []string               `protobuf:"bytes,2,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	Joined        bool                   `protobuf:"varint,3,opt,name=joined,proto3" json:"joined,omitempty"`                       // member of the multicast group
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`                          // why the interface is not used
+	LastHeard     string                 `protobuf:"bytes,5,opt,name=last_heard,json=lastHeard,proto3" json:"last_heard,omitempty"` // RFC 3339; last peer announcement received on it
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LANInterface) Reset() {
+	*x = LANInterface{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LANInterface) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LANInterface) ProtoMessage() {}
+
+func (x *LANInterface) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LANInterface.ProtoReflect.Descriptor instead.
+func (*LANInterface) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *LANInterface) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *LANInterface) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
+	}
+	return nil
+}
+
+func (x *LANInterface) GetJoined() bool {
+	if x != nil {
+		return x.Joined
+	}
+	return false
+}
+
+func (x *LANInterface) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *LANInterface) GetLastHeard() string {
+	if x != nil {
+		return x.LastHeard
+	}
+	return ""
+}
+
+type GetLANDiscoveryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Enabled       bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Mode          string                 `protobuf:"bytes,2,opt,name=mode,proto3" json:"mode,omitempty"`   // "multicast" or "mdns"
+	Group         string                 `protobuf:"bytes,3,opt,name=group,proto3" json:"group,omitempty"` // multicast group and port
+	Broadcast     bool                   `protobuf:"varint,4,opt,name=broadcast,proto3" json:"broadcast,omitempty"`
+	Interfaces    []*LANInterface        `protobuf:"bytes,5,rep,name=interfaces,proto3" json:"interfaces,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetLANDiscoveryResponse) Reset() {
+	*x = GetLANDiscoveryResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetLANDiscoveryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetLANDiscoveryResponse) ProtoMessage() {}
+
+func (x *GetLANDiscoveryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetLANDiscoveryResponse.ProtoReflect.Descriptor instead.
+func (*GetLANDiscoveryResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetLANDiscoveryResponse) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *GetLANDiscoveryResponse) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *GetLANDiscoveryResponse) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *GetLANDiscoveryResponse) GetBroadcast() bool {
+	if x != nil {
+		return x.Broadcast
+	}
+	return false
+}
+
+func (x *GetLANDiscoveryResponse) GetInterfaces() []*LANInterface {
+	if x != nil {
+		return x.Interfaces
+	}
+	return nil
+}
+
+type GetDHTDiscoveryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDHTDiscoveryRequest) Reset() {
+	*x = GetDHTDiscoveryRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDHTDiscoveryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDHTDiscoveryRequest) ProtoMessage() {}
+
+func (x *GetDHTDiscoveryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDHTDiscoveryRequest.ProtoReflect.Descriptor instead.
+func (*GetDHTDiscoveryRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{12}
+}
+
+type GetDHTDiscoveryResponse struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Enabled          bool                   `protobuf:"varint,1,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	NatType          string                 `protobuf:"bytes,2,opt,name=nat_type,json=natType,proto3" json:"nat_type,omitempty"` // NAT type the intervals are based on
+	Peers            int32                  `protobuf:"varint,3,opt,name=peers,proto3" json:"peers,omitempty"`
+	TargetPeers      int32                  `protobuf:"varint,4,opt,name=target_peers,json=targetPeers,proto3" json:"target_peers,omitempty"`                // below this, announces and queries are more frequent
+	AnnounceInterval int64                  `protobuf:"varint,5,opt,name=announce_interval,json=announceInterval,proto3" json:"announce_interval,omitempty"` // nanoseconds
+	QueryInterval    int64                  `protobuf:"varint,6,opt,name=query_interval,json=queryInterval,proto3" json:"query_interval,omitempty"`          // nanoseconds
+	LastAnnounce     string                 `protobuf:"bytes,7,opt,name=last_announce,json=lastAnnounce,proto3" json:"last_announce,omitempty"`              // RFC 3339
+	LastQuery        string                 `protobuf:"bytes,8,opt,name=last_query,json=lastQuery,proto3" json:"last_query,omitempty"`                       // RFC 3339
+	Nodes            int32                  `protobuf:"varint,9,opt,name=nodes,proto3" json:"nodes,omitempty"`                                               // DHT routing table size
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *GetDHTDiscoveryResponse) Reset() {
+	*x = GetDHTDiscoveryResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDHTDiscoveryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDHTDiscoveryResponse) ProtoMessage() {}
+
+func (x *GetDHTDiscoveryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDHTDiscoveryResponse.ProtoReflect.Descriptor instead.
+func (*GetDHTDiscoveryResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *GetDHTDiscoveryResponse) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *GetDHTDiscoveryResponse) GetNatType() string {
+	if x != nil {
+		return x.NatType
+	}
+	return ""
+}
+
+func (x *GetDHTDiscoveryResponse) GetPeers() int32 {
+	if x != nil {
+		return x.Peers
+	}
+	return 0
+}
+
+func (x *GetDHTDiscoveryResponse) GetTargetPeers() int32 {
+	if x != nil {
+		return x.TargetPeers
+	}
+	return 0
+}
+
+func (x *GetDHTDiscoveryResponse) GetAnnounceInterval() int64 {
+	if x != nil {
+		return x.AnnounceInterval
+	}
+	return 0
+}
+
+func (x *GetDHTDiscoveryResponse) GetQueryInterval() int64 {
+	if x != nil {
+		return x.QueryInterval
+	}
+	return 0
+}
+
+func (x *GetDHTDiscoveryResponse) GetLastAnnounce() string {
+	if x != nil {
+		return x.LastAnnounce
+	}
+	return ""
+}
+
+func (x *GetDHTDiscoveryResponse) GetLastQuery() string {
+	if x != nil {
+		return x.LastQuery
+	}
+	return ""
+}
+
+func (x *GetDHTDiscoveryResponse) GetNodes() int32 {
+	if x != nil {
+		return x.Nodes
+	}
+	return 0
+}
+
+type LeaveMeshRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaveMeshRequest) Reset() {
+	*x = LeaveMeshRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaveMeshRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaveMeshRequest) ProtoMessage() {}
+
+func (x *LeaveMeshRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaveMeshRequest.ProtoReflect.Descriptor instead.
+func (*LeaveMeshRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{14}
+}
+
+type LeaveMeshResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Leaving       bool                   `protobuf:"varint,1,opt,name=leaving,proto3" json:"leaving,omitempty"` // the daemon is shutting down
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaveMeshResponse) Reset() {
+	*x = LeaveMeshResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaveMeshResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaveMeshResponse) ProtoMessage() {}
+
+func (x *LeaveMeshResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaveMeshResponse.ProtoReflect.Descriptor instead.
+func (*LeaveMeshResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *LeaveMeshResponse) GetLeaving() bool {
+	if x != nil {
+		return x.Leaving
+	}
+	return false
+}
+
+type RestartDaemonRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestartDaemonRequest) Reset() {
+	*x = RestartDaemonRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestartDaemonRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestartDaemonRequest) ProtoMessage() {}
+
+func (x *RestartDaemonRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestartDaemonRequest.ProtoReflect.Descriptor instead.
+func (*RestartDaemonRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{16}
+}
+
+type RestartDaemonResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Restarting    bool                   `protobuf:"varint,1,opt,name=restarting,proto3" json:"restarting,omitempty"` // the daemon is exec'ing its binary again
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestartDaemonResponse) Reset() {
+	*x = RestartDaemonResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestartDaemonResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestartDaemonResponse) ProtoMessage() {}
+
+func (x *RestartDaemonResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestartDaemonResponse.ProtoReflect.Descriptor instead.
+func (*RestartDaemonResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RestartDaemonResponse) GetRestarting() bool {
+	if x != nil {
+		return x.Restarting
+	}
+	return false
+}
+
+type Peer struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	Pubkey            string                 `protobuf:"bytes,1,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	Hostname          string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	MeshIp            string                 `protobuf:"bytes,3,opt,name=mesh_ip,json=meshIp,proto3" json:"mesh_ip,omitempty"`
+	Endpoint          string                 `protobuf:"bytes,4,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	LastSeen          string                 `protobuf:"bytes,5,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"` // RFC 3339
+	DiscoveredVia     []string               `protobuf:"bytes,6,rep,name=discovered_via,json=discoveredVia,proto3" json:"discovered_via,omitempty"`
+	RoutableNetworks  []string               `protobuf:"bytes,7,rep,name=routable_networks,json=routableNetworks,proto3" json:"routable_networks,omitempty"`
+	LatencyMs         *float64               `protobuf:"fixed64,8,opt,name=latency_ms,json=latencyMs,proto3,oneof" json:"latency_ms,omitempty"`
+	PacketLossPct     *float64               `protobuf:"fixed64,9,opt,name=packet_loss_pct,json=packetLossPct,proto3,oneof" json:"packet_loss_pct,omitempty"`
+	Installed         bool                   `protobuf:"varint,10,opt,name=installed,proto3" json:"installed,omitempty"` // in WireGuard, not just known
+	MeshIpv6          string                 `protobuf:"bytes,11,opt,name=mesh_ipv6,json=meshIpv6,proto3" json:"mesh_ipv6,omitempty"`
+	ControlEndpoint   string                 `protobuf:"bytes,12,opt,name=control_endpoint,json=controlEndpoint,proto3" json:"control_endpoint,omitempty"` // the peer's exchange listener
+	EndpointMethod    string                 `protobuf:"bytes,13,opt,name=endpoint_method,json=endpointMethod,proto3" json:"endpoint_method,omitempty"`    // discovery method that set endpoint
+	Candidates        []string               `protobuf:"bytes,14,rep,name=candidates,proto3" json:"candidates,omitempty"`                                  // other endpoints the peer announced
+	Probes            int32                  `protobuf:"varint,15,opt,name=probes,proto3" json:"probes,omitempty"`                                         // mesh probes in the loss window
+	ProbesLost        int32                  `protobuf:"varint,16,opt,name=probes_lost,json=probesLost,proto3" json:"probes_lost,omitempty"`
+	Introducer        bool                   `protobuf:"varint,17,opt,name=introducer,proto3" json:"introducer,omitempty"`
+	Version           string                 `protobuf:"bytes,18,opt,name=version,proto3" json:"version,omitempty"`                // wgmesh version the peer announces
+	NatType           string                 `protobuf:"bytes,19,opt,name=nat_type,json=natType,proto3" json:"nat_type,omitempty"` // none, cone, symmetric or unknown
+	Path              string                 `protobuf:"bytes,20,opt,name=path,proto3" json:"path,omitempty"`                      // direct, direct-lan or relay
+	RelayPubkey       string                 `protobuf:"bytes,21,opt,name=relay_pubkey,json=relayPubkey,proto3" json:"relay_pubkey,omitempty"`
+	Identity          string                 `protobuf:"bytes,22,opt,name=identity,proto3" json:"identity,omitempty"`                                                                   // Ed25519 key that signs the peer's announcements
+	Load              *IntroducerLoad        `protobuf:"bytes,23,opt,name=load,proto3" json:"load,omitempty"`                                                                           // reported by introducers
+	IntroducerElected bool                   `protobuf:"varint,24,opt,name=introducer_elected,json=introducerElected,proto3" json:"introducer_elected,omitempty"`                       // introducer by election rather than configuration
+	Tags              map[string]string      `protobuf:"bytes,25,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // operator-assigned labels, e.g. role=db
+	LastHandshake     string                 `protobuf:"bytes,26,opt,name=last_handshake,json=lastHandshake,proto3" json:"last_handshake,omitempty"`                                    // RFC 3339; empty before the first handshake
+	ProtocolRevision  int32                  `protobuf:"varint,27,opt,name=protocol_revision,json=protocolRevision,proto3" json:"protocol_revision,omitempty"`                          // 0 for peers that predate protocol revisions
+	Capabilities      []string               `protobuf:"bytes,28,rep,name=capabilities,proto3" json:"capabilities,omitempty"`                                                           // optional protocol features, e.g. "rotate"
+	Compatibility     string                 `protobuf:"bytes,29,opt,name=compatibility,proto3" json:"compatibility,omitempty"`                                                         // ok, legacy or incompatible
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Peer) Reset() {
+	*x = Peer{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Peer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Peer) ProtoMessage() {}
+
+func (x *Peer) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Peer.ProtoReflect.Descriptor instead.
+func (*Peer) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Peer) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+func (x *Peer) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *Peer) GetMeshIp() string {
+	if x != nil {
+		return x.MeshIp
+	}
+	return ""
+}
+
+func (x *Peer) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *Peer) GetLastSeen() string {
+	if x != nil {
+		return x.LastSeen
+	}
+	return ""
+}
+
+func (x *Peer) GetDiscoveredVia() []string {
+	if x != nil {
+		return x.DiscoveredVia
+	}
+	return nil
+}
+
+func (x *Peer) GetRoutableNetworks() []string {
+	if x != nil {
+		return x.RoutableNetworks
+	}
+	return nil
+}
+
+func (x *Peer) GetLatencyMs() float64 {
+	if x != nil && x.LatencyMs != nil {
+		return *x.LatencyMs
+	}
+	return 0
+}
+
+func (x *Peer) GetPacketLossPct() float64 {
+	if x != nil && x.PacketLossPct != nil {
+		return *x.PacketLossPct
+	}
+	return 0
+}
+
+func (x *Peer) GetInstalled() bool {
+	if x != nil {
+		return x.Installed
+	}
+	return false
+}
+
+func (x *Peer) GetMeshIpv6() string {
+	if x != nil {
+		return x.MeshIpv6
+	}
+	return ""
+}
+
+func (x *Peer) GetControlEndpoint() string {
+	if x != nil {
+		return x.ControlEndpoint
+	}
+	return ""
+}
+
+func (x *Peer) GetEndpointMethod() string {
+	if x != nil {
+		return x.EndpointMethod
+	}
+	return ""
+}
+
+func (x *Peer) GetCandidates() []string {
+	if x != nil {
+		return x.Candidates
+	}
+	return nil
+}
+
+func (x *Peer) GetProbes() int32 {
+	if x != nil {
+		return x.Probes
+	}
+	return 0
+}
+
+func (x *Peer) GetProbesLost() int32 {
+	if x != nil {
+		return x.ProbesLost
+	}
+	return 0
+}
+
+func (x *Peer) GetIntroducer() bool {
+	if x != nil {
+		return x.Introducer
+	}
+	return false
+}
+
+func (x *Peer) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *Peer) GetNatType() string {
+	if x != nil {
+		return x.NatType
+	}
+	return ""
+}
+
+func (x *Peer) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Peer) GetRelayPubkey() string {
+	if x != nil {
+		return x.RelayPubkey
+	}
+	return ""
+}
+
+func (x *Peer) GetIdentity() string {
+	if x != nil {
+		return x.Identity
+	}
+	return ""
+}
+
+func (x *Peer) GetLoad() *IntroducerLoad {
+	if x != nil {
+		return x.Load
+	}
+	return nil
+}
+
+func (x *Peer) GetIntroducerElected() bool {
+	if x != nil {
+		return x.IntroducerElected
+	}
+	return false
+}
+
+func (x *Peer) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Peer) GetLastHandshake() string {
+	if x != nil {
+		return x.LastHandshake
+	}
+	return ""
+}
+
+func (x *Peer) GetProtocolRevision() int32 {
+	if x != nil {
+		return x.ProtocolRevision
+	}
+	return 0
+}
+
+func (x *Peer) GetCapabilities() []string {
+	if x != nil {
+		return x.Capabilities
+	}
+	return nil
+}
+
+func (x *Peer) GetCompatibility() string {
+	if x != nil {
+		return x.Compatibility
+	}
+	return ""
+}
+
+type IntroducerLoad struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sessions      int32                  `protobuf:"varint,1,opt,name=sessions,proto3" json:"sessions,omitempty"` // rendezvous sessions in progress
+	RelayedPeers  int32                  `protobuf:"varint,2,opt,name=relayed_peers,json=relayedPeers,proto3" json:"relayed_peers,omitempty"`
+	Bps           float64                `protobuf:"fixed64,3,opt,name=bps,proto3" json:"bps,omitempty"`              // WireGuard throughput, bytes per second
+	Overloaded    bool                   `protobuf:"varint,4,opt,name=overloaded,proto3" json:"overloaded,omitempty"` // skipped for new rendezvous and relay assignments
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IntroducerLoad) Reset() {
+	*x = IntroducerLoad{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IntroducerLoad) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IntroducerLoad) ProtoMessage() {}
+
+func (x *IntroducerLoad) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IntroducerLoad.ProtoReflect.Descriptor instead.
+func (*IntroducerLoad) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *IntroducerLoad) GetSessions() int32 {
+	if x != nil {
+		return x.Sessions
+	}
+	return 0
+}
+
+func (x *IntroducerLoad) GetRelayedPeers() int32 {
+	if x != nil {
+		return x.RelayedPeers
+	}
+	return 0
+}
+
+func (x *IntroducerLoad) GetBps() float64 {
+	if x != nil {
+		return x.Bps
+	}
+	return 0
+}
+
+func (x *IntroducerLoad) GetOverloaded() bool {
+	if x != nil {
+		return x.Overloaded
+	}
+	return false
+}
+
+type ListPeersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tags          []string               `protobuf:"bytes,1,rep,name=tags,proto3" json:"tags,omitempty"` // "key=value" or "key" selectors a peer must all match
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPeersRequest) Reset() {
+	*x = ListPeersRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPeersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPeersRequest) ProtoMessage() {}
+
+func (x *ListPeersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPeersRequest.ProtoReflect.Descriptor instead.
+func (*ListPeersRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ListPeersRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type ListPeersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peers         []*Peer                `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPeersResponse) Reset() {
+	*x = ListPeersResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPeersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPeersResponse) ProtoMessage() {}
+
+func (x *ListPeersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPeersResponse.ProtoReflect.Descriptor instead.
+func (*ListPeersResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ListPeersResponse) GetPeers() []*Peer {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type GetPeerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pubkey        string                 `protobuf:"bytes,1,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPeerRequest) Reset() {
+	*x = GetPeerRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPeerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPeerRequest) ProtoMessage() {}
+
+func (x *GetPeerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPeerRequest.ProtoReflect.Descriptor instead.
+func (*GetPeerRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *GetPeerRequest) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+type CountPeersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountPeersRequest) Reset() {
+	*x = CountPeersRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountPeersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountPeersRequest) ProtoMessage() {}
+
+func (x *CountPeersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountPeersRequest.ProtoReflect.Descriptor instead.
+func (*CountPeersRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{23}
+}
+
+type CountPeersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Active        int32                  `protobuf:"varint,1,opt,name=active,proto3" json:"active,omitempty"`
+	Total         int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	Dead          int32                  `protobuf:"varint,3,opt,name=dead,proto3" json:"dead,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountPeersResponse) Reset() {
+	*x = CountPeersResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountPeersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountPeersResponse) ProtoMessage() {}
+
+func (x *CountPeersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountPeersResponse.ProtoReflect.Descriptor instead.
+func (*CountPeersResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *CountPeersResponse) GetActive() int32 {
+	if x != nil {
+		return x.Active
+	}
+	return 0
+}
+
+func (x *CountPeersResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *CountPeersResponse) GetDead() int32 {
+	if x != nil {
+		return x.Dead
+	}
+	return 0
+}
+
+type PeerStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Window        *string                `protobuf:"bytes,1,opt,name=window,proto3,oneof" json:"window,omitempty"` // sort by the rate over this window: 1m, 5m or 15m
+	Limit         *int32                 `protobuf:"varint,2,opt,name=limit,proto3,oneof" json:"limit,omitempty"`  // all peers if unset
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeerStatsRequest) Reset() {
+	*x = PeerStatsRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerStatsRequest) ProtoMessage() {}
+
+func (x *PeerStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerStatsRequest.ProtoReflect.Descriptor instead.
+func (*PeerStatsRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *PeerStatsRequest) GetWindow() string {
+	if x != nil && x.Window != nil {
+		return *x.Window
+	}
+	return ""
+}
+
+func (x *PeerStatsRequest) GetLimit() int32 {
+	if x != nil && x.Limit != nil {
+		return *x.Limit
+	}
+	return 0
+}
+
+type TrafficRate struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WindowSeconds int32                  `protobuf:"varint,1,opt,name=window_seconds,json=windowSeconds,proto3" json:"window_seconds,omitempty"`
+	RxBps         float64                `protobuf:"fixed64,2,opt,name=rx_bps,json=rxBps,proto3" json:"rx_bps,omitempty"` // bytes per second
+	TxBps         float64                `protobuf:"fixed64,3,opt,name=tx_bps,json=txBps,proto3" json:"tx_bps,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TrafficRate) Reset() {
+	*x = TrafficRate{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrafficRate) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrafficRate) ProtoMessage() {}
+
+func (x *TrafficRate) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrafficRate.ProtoReflect.Descriptor instead.
+func (*TrafficRate) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *TrafficRate) GetWindowSeconds() int32 {
+	if x != nil {
+		return x.WindowSeconds
+	}
+	return 0
+}
+
+func (x *TrafficRate) GetRxBps() float64 {
+	if x != nil {
+		return x.RxBps
+	}
+	return 0
+}
+
+func (x *TrafficRate) GetTxBps() float64 {
+	if x != nil {
+		return x.TxBps
+	}
+	return 0
+}
+
+type PeerStats struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pubkey        string                 `protobuf:"bytes,1,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	MeshIp        string                 `protobuf:"bytes,3,opt,name=mesh_ip,json=meshIp,proto3" json:"mesh_ip,omitempty"`
+	RxBytes       uint64                 `protobuf:"varint,4,opt,name=rx_bytes,json=rxBytes,proto3" json:"rx_bytes,omitempty"`
+	TxBytes       uint64                 `protobuf:"varint,5,opt,name=tx_bytes,json=txBytes,proto3" json:"tx_bytes,omitempty"`
+	Since         string                 `protobuf:"bytes,6,opt,name=since,proto3" json:"since,omitempty"` // RFC 3339
+	Rates         []*TrafficRate         `protobuf:"bytes,7,rep,name=rates,proto3" json:"rates,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeerStats) Reset() {
+	*x = PeerStats{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerStats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerStats) ProtoMessage() {}
+
+func (x *PeerStats) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerStats.ProtoReflect.Descriptor instead.
+func (*PeerStats) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *PeerStats) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+func (x *PeerStats) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *PeerStats) GetMeshIp() string {
+	if x != nil {
+		return x.MeshIp
+	}
+	return ""
+}
+
+func (x *PeerStats) GetRxBytes() uint64 {
+	if x != nil {
+		return x.RxBytes
+	}
+	return 0
+}
+
+func (x *PeerStats) GetTxBytes() uint64 {
+	if x != nil {
+		return x.TxBytes
+	}
+	return 0
+}
+
+func (x *PeerStats) GetSince() string {
+	if x != nil {
+		return x.Since
+	}
+	return ""
+}
+
+func (x *PeerStats) GetRates() []*TrafficRate {
+	if x != nil {
+		return x.Rates
+	}
+	return nil
+}
+
+type PeerStatsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peers         []*PeerStats           `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeerStatsResponse) Reset() {
+	*x = PeerStatsResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerStatsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerStatsResponse) ProtoMessage() {}
+
+func (x *PeerStatsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerStatsResponse.ProtoReflect.Descriptor instead.
+func (*PeerStatsResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *PeerStatsResponse) GetPeers() []*PeerStats {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type ListQuarantineRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListQuarantineRequest) Reset() {
+	*x = ListQuarantineRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListQuarantineRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListQuarantineRequest) ProtoMessage() {}
+
+func (x *ListQuarantineRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListQuarantineRequest.ProtoReflect.Descriptor instead.
+func (*ListQuarantineRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{29}
+}
+
+type QuarantinedPeer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pubkey        string                 `protobuf:"bytes,1,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	MeshIp        string                 `protobuf:"bytes,3,opt,name=mesh_ip,json=meshIp,proto3" json:"mesh_ip,omitempty"`
+	Endpoint      string                 `protobuf:"bytes,4,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Reason        string                 `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"` // mesh_ip_pinned or hostname_pinned
+	PinnedPubkey  string                 `protobuf:"bytes,6,opt,name=pinned_pubkey,json=pinnedPubkey,proto3" json:"pinned_pubkey,omitempty"`
+	FirstSeen     string                 `protobuf:"bytes,7,opt,name=first_seen,json=firstSeen,proto3" json:"first_seen,omitempty"` // RFC 3339
+	LastSeen      string                 `protobuf:"bytes,8,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`    // RFC 3339
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuarantinedPeer) Reset() {
+	*x = QuarantinedPeer{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuarantinedPeer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuarantinedPeer) ProtoMessage() {}
+
+func (x *QuarantinedPeer) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuarantinedPeer.ProtoReflect.Descriptor instead.
+func (*QuarantinedPeer) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *QuarantinedPeer) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+func (x *QuarantinedPeer) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *QuarantinedPeer) GetMeshIp() string {
+	if x != nil {
+		return x.MeshIp
+	}
+	return ""
+}
+
+func (x *QuarantinedPeer) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *QuarantinedPeer) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *QuarantinedPeer) GetPinnedPubkey() string {
+	if x != nil {
+		return x.PinnedPubkey
+	}
+	return ""
+}
+
+func (x *QuarantinedPeer) GetFirstSeen() string {
+	if x != nil {
+		return x.FirstSeen
+	}
+	return ""
+}
+
+func (x *QuarantinedPeer) GetLastSeen() string {
+	if x != nil {
+		return x.LastSeen
+	}
+	return ""
+}
+
+type ListQuarantineResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peers         []*QuarantinedPeer     `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListQuarantineResponse) Reset() {
+	*x = ListQuarantineResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListQuarantineResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListQuarantineResponse) ProtoMessage() {}
+
+func (x *ListQuarantineResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListQuarantineResponse.ProtoReflect.Descriptor instead.
+func (*ListQuarantineResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *ListQuarantineResponse) GetPeers() []*QuarantinedPeer {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type ApprovePeerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pubkey        string                 `protobuf:"bytes,1,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApprovePeerRequest) Reset() {
+	*x = ApprovePeerRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApprovePeerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApprovePeerRequest) ProtoMessage() {}
+
+func (x *ApprovePeerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApprovePeerRequest.ProtoReflect.Descriptor instead.
+func (*ApprovePeerRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ApprovePeerRequest) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+type ApprovePeerResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Approved      bool                   `protobuf:"varint,1,opt,name=approved,proto3" json:"approved,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApprovePeerResponse) Reset() {
+	*x = ApprovePeerResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApprovePeerResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApprovePeerResponse) ProtoMessage() {}
+
+func (x *ApprovePeerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApprovePeerResponse.ProtoReflect.Descriptor instead.
+func (*ApprovePeerResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ApprovePeerResponse) GetApproved() bool {
+	if x != nil {
+		return x.Approved
+	}
+	return false
+}
+
+type ForgetPeerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pubkey        string                 `protobuf:"bytes,1,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ForgetPeerRequest) Reset() {
+	*x = ForgetPeerRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForgetPeerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForgetPeerRequest) ProtoMessage() {}
+
+func (x *ForgetPeerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForgetPeerRequest.ProtoReflect.Descriptor instead.
+func (*ForgetPeerRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *ForgetPeerRequest) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+type ForgetPeerResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Forgotten     bool                   `protobuf:"varint,1,opt,name=forgotten,proto3" json:"forgotten,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ForgetPeerResponse) Reset() {
+	*x = ForgetPeerResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ForgetPeerResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ForgetPeerResponse) ProtoMessage() {}
+
+func (x *ForgetPeerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ForgetPeerResponse.ProtoReflect.Descriptor instead.
+func (*ForgetPeerResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *ForgetPeerResponse) GetForgotten() bool {
+	if x != nil {
+		return x.Forgotten
+	}
+	return false
+}
+
+type ListPendingRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPendingRequest) Reset() {
+	*x = ListPendingRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPendingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPendingRequest) ProtoMessage() {}
+
+func (x *ListPendingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPendingRequest.ProtoReflect.Descriptor instead.
+func (*ListPendingRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{36}
+}
+
+type PendingPeer struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pubkey        string                 `protobuf:"bytes,1,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	MeshIp        string                 `protobuf:"bytes,3,opt,name=mesh_ip,json=meshIp,proto3" json:"mesh_ip,omitempty"`
+	Endpoint      string                 `protobuf:"bytes,4,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Identity      string                 `protobuf:"bytes,5,opt,name=identity,proto3" json:"identity,omitempty"`                    // empty until the peer's own signed announcement arrives
+	FirstSeen     string                 `protobuf:"bytes,6,opt,name=first_seen,json=firstSeen,proto3" json:"first_seen,omitempty"` // RFC 3339
+	LastSeen      string                 `protobuf:"bytes,7,opt,name=last_seen,json=lastSeen,proto3" json:"last_seen,omitempty"`    // RFC 3339
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PendingPeer) Reset() {
+	*x = PendingPeer{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PendingPeer) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PendingPeer) ProtoMessage() {}
+
+func (x *PendingPeer) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PendingPeer.ProtoReflect.Descriptor instead.
+func (*PendingPeer) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *PendingPeer) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+func (x *PendingPeer) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *PendingPeer) GetMeshIp() string {
+	if x != nil {
+		return x.MeshIp
+	}
+	return ""
+}
+
+func (x *PendingPeer) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *PendingPeer) GetIdentity() string {
+	if x != nil {
+		return x.Identity
+	}
+	return ""
+}
+
+func (x *PendingPeer) GetFirstSeen() string {
+	if x != nil {
+		return x.FirstSeen
+	}
+	return ""
+}
+
+func (x *PendingPeer) GetLastSeen() string {
+	if x != nil {
+		return x.LastSeen
+	}
+	return ""
+}
+
+type ListPendingResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peers         []*PendingPeer         `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPendingResponse) Reset() {
+	*x = ListPendingResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPendingResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPendingResponse) ProtoMessage() {}
+
+func (x *ListPendingResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPendingResponse.ProtoReflect.Descriptor instead.
+func (*ListPendingResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *ListPendingResponse) GetPeers() []*PendingPeer {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type PingPeerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peer          string                 `protobuf:"bytes,1,opt,name=peer,proto3" json:"peer,omitempty"` // hostname, mesh IP or public key (prefix)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingPeerRequest) Reset() {
+	*x = PingPeerRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingPeerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingPeerRequest) ProtoMessage() {}
+
+func (x *PingPeerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingPeerRequest.ProtoReflect.Descriptor instead.
+func (*PingPeerRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *PingPeerRequest) GetPeer() string {
+	if x != nil {
+		return x.Peer
+	}
+	return ""
+}
+
+type PingPeerResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pubkey        string                 `protobuf:"bytes,1,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	MeshIp        string                 `protobuf:"bytes,3,opt,name=mesh_ip,json=meshIp,proto3" json:"mesh_ip,omitempty"`
+	Path          string                 `protobuf:"bytes,4,opt,name=path,proto3" json:"path,omitempty"`                        // direct, direct-lan or relay
+	RttMs         *float64               `protobuf:"fixed64,5,opt,name=rtt_ms,json=rttMs,proto3,oneof" json:"rtt_ms,omitempty"` // unset when the peer did not answer
+	Error         string                 `protobuf:"bytes,6,opt,name=error,proto3" json:"error,omitempty"`                      // why the peer did not answer
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PingPeerResponse) Reset() {
+	*x = PingPeerResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[40]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PingPeerResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PingPeerResponse) ProtoMessage() {}
+
+func (x *PingPeerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[40]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PingPeerResponse.ProtoReflect.Descriptor instead.
+func (*PingPeerResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *PingPeerResponse) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+func (x *PingPeerResponse) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *PingPeerResponse) GetMeshIp() string {
+	if x != nil {
+		return x.MeshIp
+	}
+	return ""
+}
+
+func (x *PingPeerResponse) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *PingPeerResponse) GetRttMs() float64 {
+	if x != nil && x.RttMs != nil {
+		return *x.RttMs
+	}
+	return 0
+}
+
+func (x *PingPeerResponse) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type RoutePeerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peer          string                 `protobuf:"bytes,1,opt,name=peer,proto3" json:"peer,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoutePeerRequest) Reset() {
+	*x = RoutePeerRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[41]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoutePeerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoutePeerRequest) ProtoMessage() {}
+
+func (x *RoutePeerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[41]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoutePeerRequest.ProtoReflect.Descriptor instead.
+func (*RoutePeerRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{41}
+}
+
+func (x *RoutePeerRequest) GetPeer() string {
+	if x != nil {
+		return x.Peer
+	}
+	return ""
+}
+
+type PeerRoute struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pubkey        string                 `protobuf:"bytes,1,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	Hostname      string                 `protobuf:"bytes,2,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	MeshIp        string                 `protobuf:"bytes,3,opt,name=mesh_ip,json=meshIp,proto3" json:"mesh_ip,omitempty"`
+	Endpoint      string                 `protobuf:"bytes,4,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	Path          string                 `protobuf:"bytes,5,opt,name=path,proto3" json:"path,omitempty"` // direct, direct-lan or relay
+	Installed     bool                   `protobuf:"varint,6,opt,name=installed,proto3" json:"installed,omitempty"`
+	RelayPubkey   string                 `protobuf:"bytes,7,opt,name=relay_pubkey,json=relayPubkey,proto3" json:"relay_pubkey,omitempty"`
+	RelayHostname string                 `protobuf:"bytes,8,opt,name=relay_hostname,json=relayHostname,proto3" json:"relay_hostname,omitempty"`
+	RelayMeshIp   string                 `protobuf:"bytes,9,opt,name=relay_mesh_ip,json=relayMeshIp,proto3" json:"relay_mesh_ip,omitempty"`
+	RelayEndpoint string                 `protobuf:"bytes,10,opt,name=relay_endpoint,json=relayEndpoint,proto3" json:"relay_endpoint,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeerRoute) Reset() {
+	*x = PeerRoute{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[42]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerRoute) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerRoute) ProtoMessage() {}
+
+func (x *PeerRoute) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[42]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerRoute.ProtoReflect.Descriptor instead.
+func (*PeerRoute) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{42}
+}
+
+func (x *PeerRoute) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+func (x *PeerRoute) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *PeerRoute) GetMeshIp() string {
+	if x != nil {
+		return x.MeshIp
+	}
+	return ""
+}
+
+func (x *PeerRoute) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *PeerRoute) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *PeerRoute) GetInstalled() bool {
+	if x != nil {
+		return x.Installed
+	}
+	return false
+}
+
+func (x *PeerRoute) GetRelayPubkey() string {
+	if x != nil {
+		return x.RelayPubkey
+	}
+	return ""
+}
+
+func (x *PeerRoute) GetRelayHostname() string {
+	if x != nil {
+		return x.RelayHostname
+	}
+	return ""
+}
+
+func (x *PeerRoute) GetRelayMeshIp() string {
+	if x != nil {
+		return x.RelayMeshIp
+	}
+	return ""
+}
+
+func (x *PeerRoute) GetRelayEndpoint() string {
+	if x != nil {
+		return x.RelayEndpoint
+	}
+	return ""
+}
+
+type ExportStateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportStateRequest) Reset() {
+	*x = ExportStateRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[43]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportStateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportStateRequest) ProtoMessage() {}
+
+func (x *ExportStateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[43]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportStateRequest.ProtoReflect.Descriptor instead.
+func (*ExportStateRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{43}
+}
+
+type ExportStateResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// The snapshot document, as written by `wgmesh state export`.
+	Snapshot      *structpb.Struct `protobuf:"bytes,1,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExportStateResponse) Reset() {
+	*x = ExportStateResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[44]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExportStateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExportStateResponse) ProtoMessage() {}
+
+func (x *ExportStateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[44]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExportStateResponse.ProtoReflect.Descriptor instead.
+func (*ExportStateResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{44}
+}
+
+func (x *ExportStateResponse) GetSnapshot() *structpb.Struct {
+	if x != nil {
+		return x.Snapshot
+	}
+	return nil
+}
+
+type ImportStateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Snapshot      *structpb.Struct       `protobuf:"bytes,1,opt,name=snapshot,proto3" json:"snapshot,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportStateRequest) Reset() {
+	*x = ImportStateRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[45]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportStateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportStateRequest) ProtoMessage() {}
+
+func (x *ImportStateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[45]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportStateRequest.ProtoReflect.Descriptor instead.
+func (*ImportStateRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{45}
+}
+
+func (x *ImportStateRequest) GetSnapshot() *structpb.Struct {
+	if x != nil {
+		return x.Snapshot
+	}
+	return nil
+}
+
+type ImportStateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Imported      int32                  `protobuf:"varint,1,opt,name=imported,proto3" json:"imported,omitempty"`
+	Skipped       int32                  `protobuf:"varint,2,opt,name=skipped,proto3" json:"skipped,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ImportStateResponse) Reset() {
+	*x = ImportStateResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ImportStateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ImportStateResponse) ProtoMessage() {}
+
+func (x *ImportStateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ImportStateResponse.ProtoReflect.Descriptor instead.
+func (*ImportStateResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *ImportStateResponse) GetImported() int32 {
+	if x != nil {
+		return x.Imported
+	}
+	return 0
+}
+
+func (x *ImportStateResponse) GetSkipped() int32 {
+	if x != nil {
+		return x.Skipped
+	}
+	return 0
+}
+
+type ListEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Since         *uint64                `protobuf:"varint,1,opt,name=since,proto3,oneof" json:"since,omitempty"` // only events with a higher sequence number
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEventsRequest) Reset() {
+	*x = ListEventsRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[47]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEventsRequest) ProtoMessage() {}
+
+func (x *ListEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[47]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEventsRequest.ProtoReflect.Descriptor instead.
+func (*ListEventsRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{47}
+}
+
+func (x *ListEventsRequest) GetSince() uint64 {
+	if x != nil && x.Since != nil {
+		return *x.Since
+	}
+	return 0
+}
+
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seq           uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	Time          string                 `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"` // RFC 3339
+	Type          string                 `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Pubkey        string                 `protobuf:"bytes,4,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	Details       map[string]string      `protobuf:"bytes,5,rep,name=details,proto3" json:"details,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[48]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[48]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{48}
+}
+
+func (x *Event) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Event) GetTime() string {
+	if x != nil {
+		return x.Time
+	}
+	return ""
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+func (x *Event) GetDetails() map[string]string {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+// A subscriber to the daemon's peer events, with its delivery counters.
+type EventSubscriber struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Queued        int32                  `protobuf:"varint,2,opt,name=queued,proto3" json:"queued,omitempty"` // events waiting to be read
+	Delivered     uint64                 `protobuf:"varint,3,opt,name=delivered,proto3" json:"delivered,omitempty"`
+	Coalesced     uint64                 `protobuf:"varint,4,opt,name=coalesced,proto3" json:"coalesced,omitempty"` // merged into an event already queued for the same peer
+	Dropped       uint64                 `protobuf:"varint,5,opt,name=dropped,proto3" json:"dropped,omitempty"`     // lost because the queue was full
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EventSubscriber) Reset() {
+	*x = EventSubscriber{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[49]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EventSubscriber) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventSubscriber) ProtoMessage() {}
+
+func (x *EventSubscriber) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[49]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EventSubscriber.ProtoReflect.Descriptor instead.
+func (*EventSubscriber) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{49}
+}
+
+func (x *EventSubscriber) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *EventSubscriber) GetQueued() int32 {
+	if x != nil {
+		return x.Queued
+	}
+	return 0
+}
+
+func (x *EventSubscriber) GetDelivered() uint64 {
+	if x != nil {
+		return x.Delivered
+	}
+	return 0
+}
+
+func (x *EventSubscriber) GetCoalesced() uint64 {
+	if x != nil {
+		return x.Coalesced
+	}
+	return 0
+}
+
+func (x *EventSubscriber) GetDropped() uint64 {
+	if x != nil {
+		return x.Dropped
+	}
+	return 0
+}
+
+type ListEventsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Events        []*Event               `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	Subscribers   []*EventSubscriber     `protobuf:"bytes,2,rep,name=subscribers,proto3" json:"subscribers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEventsResponse) Reset() {
+	*x = ListEventsResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[50]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEventsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEventsResponse) ProtoMessage() {}
+
+func (x *ListEventsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[50]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEventsResponse.ProtoReflect.Descriptor instead.
+func (*ListEventsResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{50}
+}
+
+func (x *ListEventsResponse) GetEvents() []*Event {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *ListEventsResponse) GetSubscribers() []*EventSubscriber {
+	if x != nil {
+		return x.Subscribers
+	}
+	return nil
+}
+
+type ListRoutesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRoutesRequest) Reset() {
+	*x = ListRoutesRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[51]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRoutesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRoutesRequest) ProtoMessage() {}
+
+func (x *ListRoutesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[51]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRoutesRequest.ProtoReflect.Descriptor instead.
+func (*ListRoutesRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{51}
+}
+
+type Route struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Network       string                 `protobuf:"bytes,1,opt,name=network,proto3" json:"network,omitempty"`
+	Pubkey        string                 `protobuf:"bytes,2,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	Standby       []string               `protobuf:"bytes,3,rep,name=standby,proto3" json:"standby,omitempty"` // other gateways for the same network
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Route) Reset() {
+	*x = Route{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[52]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Route) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Route) ProtoMessage() {}
+
+func (x *Route) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[52]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Route.ProtoReflect.Descriptor instead.
+func (*Route) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{52}
+}
+
+func (x *Route) GetNetwork() string {
+	if x != nil {
+		return x.Network
+	}
+	return ""
+}
+
+func (x *Route) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+func (x *Route) GetStandby() []string {
+	if x != nil {
+		return x.Standby
+	}
+	return nil
+}
+
+type RouteConflict struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	WinnerNetwork string                 `protobuf:"bytes,1,opt,name=winner_network,json=winnerNetwork,proto3" json:"winner_network,omitempty"`
+	WinnerPubkey  string                 `protobuf:"bytes,2,opt,name=winner_pubkey,json=winnerPubkey,proto3" json:"winner_pubkey,omitempty"`
+	LoserNetwork  string                 `protobuf:"bytes,3,opt,name=loser_network,json=loserNetwork,proto3" json:"loser_network,omitempty"`
+	LoserPubkey   string                 `protobuf:"bytes,4,opt,name=loser_pubkey,json=loserPubkey,proto3" json:"loser_pubkey,omitempty"`
+	Dropped       bool                   `protobuf:"varint,5,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RouteConflict) Reset() {
+	*x = RouteConflict{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[53]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RouteConflict) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RouteConflict) ProtoMessage() {}
+
+func (x *RouteConflict) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[53]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RouteConflict.ProtoReflect.Descriptor instead.
+func (*RouteConflict) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{53}
+}
+
+func (x *RouteConflict) GetWinnerNetwork() string {
+	if x != nil {
+		return x.WinnerNetwork
+	}
+	return ""
+}
+
+func (x *RouteConflict) GetWinnerPubkey() string {
+	if x != nil {
+		return x.WinnerPubkey
+	}
+	return ""
+}
+
+func (x *RouteConflict) GetLoserNetwork() string {
+	if x != nil {
+		return x.LoserNetwork
+	}
+	return ""
+}
+
+func (x *RouteConflict) GetLoserPubkey() string {
+	if x != nil {
+		return x.LoserPubkey
+	}
+	return ""
+}
+
+func (x *RouteConflict) GetDropped() bool {
+	if x != nil {
+		return x.Dropped
+	}
+	return false
+}
+
+type ListRoutesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Routes        []*Route               `protobuf:"bytes,1,rep,name=routes,proto3" json:"routes,omitempty"`
+	Conflicts     []*RouteConflict       `protobuf:"bytes,2,rep,name=conflicts,proto3" json:"conflicts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRoutesResponse) Reset() {
+	*x = ListRoutesResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[54]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRoutesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRoutesResponse) ProtoMessage() {}
+
+func (x *ListRoutesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[54]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRoutesResponse.ProtoReflect.Descriptor instead.
+func (*ListRoutesResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{54}
+}
+
+func (x *ListRoutesResponse) GetRoutes() []*Route {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+func (x *ListRoutesResponse) GetConflicts() []*RouteConflict {
+	if x != nil {
+		return x.Conflicts
+	}
+	return nil
+}
+
+type GetConfigRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"` // empty for every option
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetConfigRequest) Reset() {
+	*x = GetConfigRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[55]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetConfigRequest) ProtoMessage() {}
+
+func (x *GetConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[55]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetConfigRequest.ProtoReflect.Descriptor instead.
+func (*GetConfigRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{55}
+}
+
+func (x *GetConfigRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+type GetConfigResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Options       map[string]string      `protobuf:"bytes,1,rep,name=options,proto3" json:"options,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetConfigResponse) Reset() {
+	*x = GetConfigResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[56]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetConfigResponse) ProtoMessage() {}
+
+func (x *GetConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[56]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetConfigResponse.ProtoReflect.Descriptor instead.
+func (*GetConfigResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{56}
+}
+
+func (x *GetConfigResponse) GetOptions() map[string]string {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+type SetConfigRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Key   string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Required. Empty is a valid value for some options, so presence tells
+	// a missing value apart.
+	Value         *string `protobuf:"bytes,2,opt,name=value,proto3,oneof" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetConfigRequest) Reset() {
+	*x = SetConfigRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[57]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetConfigRequest) ProtoMessage() {}
+
+func (x *SetConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[57]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetConfigRequest.ProtoReflect.Descriptor instead.
+func (*SetConfigRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{57}
+}
+
+func (x *SetConfigRequest) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SetConfigRequest) GetValue() string {
+	if x != nil && x.Value != nil {
+		return *x.Value
+	}
+	return ""
+}
+
+type SetConfigResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Old           string                 `protobuf:"bytes,2,opt,name=old,proto3" json:"old,omitempty"`
+	Value         string                 `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"` // as normalized by the daemon
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetConfigResponse) Reset() {
+	*x = SetConfigResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[58]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetConfigResponse) ProtoMessage() {}
+
+func (x *SetConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[58]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetConfigResponse.ProtoReflect.Descriptor instead.
+func (*SetConfigResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{58}
+}
+
+func (x *SetConfigResponse) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *SetConfigResponse) GetOld() string {
+	if x != nil {
+		return x.Old
+	}
+	return ""
+}
+
+func (x *SetConfigResponse) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type RotateSecretRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NewSecret     *string                `protobuf:"bytes,1,opt,name=new_secret,json=newSecret,proto3,oneof" json:"new_secret,omitempty"` // generated if unset
+	Grace         *string                `protobuf:"bytes,2,opt,name=grace,proto3,oneof" json:"grace,omitempty"`                          // Go duration, e.g. 10m
+	Force         bool                   `protobuf:"varint,3,opt,name=force,proto3" json:"force,omitempty"`                               // rotate even though some active peers do not support it
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateSecretRequest) Reset() {
+	*x = RotateSecretRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[59]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateSecretRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateSecretRequest) ProtoMessage() {}
+
+func (x *RotateSecretRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[59]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateSecretRequest.ProtoReflect.Descriptor instead.
+func (*RotateSecretRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{59}
+}
+
+func (x *RotateSecretRequest) GetNewSecret() string {
+	if x != nil && x.NewSecret != nil {
+		return *x.NewSecret
+	}
+	return ""
+}
+
+func (x *RotateSecretRequest) GetGrace() string {
+	if x != nil && x.Grace != nil {
+		return *x.Grace
+	}
+	return ""
+}
+
+func (x *RotateSecretRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+type RotateSecretResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NewSecretUri  string                 `protobuf:"bytes,1,opt,name=new_secret_uri,json=newSecretUri,proto3" json:"new_secret_uri,omitempty"`
+	SwitchAt      string                 `protobuf:"bytes,2,opt,name=switch_at,json=switchAt,proto3" json:"switch_at,omitempty"` // RFC 3339
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateSecretResponse) Reset() {
+	*x = RotateSecretResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[60]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RotateSecretResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RotateSecretResponse) ProtoMessage() {}
+
+func (x *RotateSecretResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[60]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RotateSecretResponse.ProtoReflect.Descriptor instead.
+func (*RotateSecretResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{60}
+}
+
+func (x *RotateSecretResponse) GetNewSecretUri() string {
+	if x != nil {
+		return x.NewSecretUri
+	}
+	return ""
+}
+
+func (x *RotateSecretResponse) GetSwitchAt() string {
+	if x != nil {
+		return x.SwitchAt
+	}
+	return ""
+}
+
+type BroadcastRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Text          string                 `protobuf:"bytes,1,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BroadcastRequest) Reset() {
+	*x = BroadcastRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[61]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BroadcastRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BroadcastRequest) ProtoMessage() {}
+
+func (x *BroadcastRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[61]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BroadcastRequest.ProtoReflect.Descriptor instead.
+func (*BroadcastRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{61}
+}
+
+func (x *BroadcastRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type BroadcastResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	SentAt        string                 `protobuf:"bytes,2,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"` // RFC 3339
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BroadcastResponse) Reset() {
+	*x = BroadcastResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[62]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BroadcastResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BroadcastResponse) ProtoMessage() {}
+
+func (x *BroadcastResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[62]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BroadcastResponse.ProtoReflect.Descriptor instead.
+func (*BroadcastResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{62}
+}
+
+func (x *BroadcastResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BroadcastResponse) GetSentAt() string {
+	if x != nil {
+		return x.SentAt
+	}
+	return ""
+}
+
+type ListMessagesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMessagesRequest) Reset() {
+	*x = ListMessagesRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[63]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMessagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMessagesRequest) ProtoMessage() {}
+
+func (x *ListMessagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[63]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMessagesRequest.ProtoReflect.Descriptor instead.
+func (*ListMessagesRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{63}
+}
+
+type Message struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Pubkey        string                 `protobuf:"bytes,2,opt,name=pubkey,proto3" json:"pubkey,omitempty"` // node that sent it
+	Hostname      string                 `protobuf:"bytes,3,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Text          string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	SentAt        string                 `protobuf:"bytes,5,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`             // RFC 3339
+	ReceivedAt    string                 `protobuf:"bytes,6,opt,name=received_at,json=receivedAt,proto3" json:"received_at,omitempty"` // RFC 3339
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Message) Reset() {
+	*x = Message{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[64]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Message) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Message) ProtoMessage() {}
+
+func (x *Message) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[64]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Message.ProtoReflect.Descriptor instead.
+func (*Message) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{64}
+}
+
+func (x *Message) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Message) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+func (x *Message) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *Message) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *Message) GetSentAt() string {
+	if x != nil {
+		return x.SentAt
+	}
+	return ""
+}
+
+func (x *Message) GetReceivedAt() string {
+	if x != nil {
+		return x.ReceivedAt
+	}
+	return ""
+}
+
+type ListMessagesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*Message             `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMessagesResponse) Reset() {
+	*x = ListMessagesResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[65]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMessagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMessagesResponse) ProtoMessage() {}
+
+func (x *ListMessagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[65]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMessagesResponse.ProtoReflect.Descriptor instead.
+func (*ListMessagesResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{65}
+}
+
+func (x *ListMessagesResponse) GetMessages() []*Message {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+type UnlockSecretRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlockSecretRequest) Reset() {
+	*x = UnlockSecretRequest{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[66]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlockSecretRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlockSecretRequest) ProtoMessage() {}
+
+func (x *UnlockSecretRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[66]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlockSecretRequest.ProtoReflect.Descriptor instead.
+func (*UnlockSecretRequest) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{66}
+}
+
+type UnlockSecretResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Secret        string                 `protobuf:"bytes,1,opt,name=secret,proto3" json:"secret,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlockSecretResponse) Reset() {
+	*x = UnlockSecretResponse{}
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[67]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlockSecretResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlockSecretResponse) ProtoMessage() {}
+
+func (x *UnlockSecretResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_wgmesh_daemon_v1_daemon_proto_msgTypes[67]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlockSecretResponse.ProtoReflect.Descriptor instead.
+func (*UnlockSecretResponse) Descriptor() ([]byte, []int) {
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP(), []int{67}
+}
+
+func (x *UnlockSecretResponse) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+var File_wgmesh_daemon_v1_daemon_proto protoreflect.FileDescriptor
+
+const file_wgmesh_daemon_v1_daemon_proto_rawDesc = "" +
+	"\n" +
+	"\x1dwgmesh/daemon/v1/daemon.proto\x12\x10wgmesh.daemon.v1\x1a\x1cgoogle/protobuf/struct.proto\"\r\n" +
+	"\vPingRequest\"<\n" +
+	"\fPingResponse\x12\x12\n" +
+	"\x04pong\x18\x01 \x01(\bR\x04pong\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\tR\aversion\"\x12\n" +
+	"\x10GetStatusRequest\"\x96\x02\n" +
+	"\x11GetStatusResponse\x12\x17\n" +
+	"\amesh_ip\x18\x01 \x01(\tR\x06meshIp\x12\x16\n" +
+	"\x06pubkey\x18\x02 \x01(\tR\x06pubkey\x12\x16\n" +
+	"\x06uptime\x18\x03 \x01(\x03R\x06uptime\x12\x1c\n" +
+	"\tinterface\x18\x04 \x01(\tR\tinterface\x12\x18\n" +
+	"\aversion\x18\x05 \x01(\tR\aversion\x12\x1d\n" +
+	"\n" +
+	"clock_skew\x18\x06 \x01(\x03R\tclockSkew\x12(\n" +
+	"\x10clock_skew_peers\x18\a \x01(\x05R\x0eclockSkewPeers\x12\x1a\n" +
+	"\bidentity\x18\b \x01(\tR\bidentity\x12\x1b\n" +
+	"\tmesh_name\x18\t \x01(\tR\bmeshName\"E\n" +
+	"\x13GetReadinessRequest\x12 \n" +
+	"\tmin_peers\x18\x01 \x01(\x05H\x00R\bminPeers\x88\x01\x01B\f\n" +
+	"\n" +
+	"_min_peers\"\x9a\x01\n" +
+	"\x14GetReadinessResponse\x12\x14\n" +
+	"\x05ready\x18\x01 \x01(\bR\x05ready\x12!\n" +
+	"\finterface_up\x18\x02 \x01(\bR\vinterfaceUp\x12\x14\n" +
+	"\x05peers\x18\x03 \x01(\x05R\x05peers\x12\x1b\n" +
+	"\tmin_peers\x18\x04 \x01(\x05R\bminPeers\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\"\x15\n" +
+	"\x13GetResourcesRequest\"d\n" +
+	"\bResource\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x12\n" +
+	"\x04used\x18\x02 \x01(\x05R\x04used\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\x12\x1a\n" +
+	"\bpressure\x18\x04 \x01(\bR\bpressure\"l\n" +
+	"\x14GetResourcesResponse\x12\x1a\n" +
+	"\bpressure\x18\x01 \x01(\bR\bpressure\x128\n" +
+	"\tresources\x18\x02 \x03(\v2\x1a.wgmesh.daemon.v1.ResourceR\tresources\"\x18\n" +
+	"\x16GetLANDiscoveryRequest\"\x8d\x01\n" +
+	"\fLANInterface\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1c\n" +
+	"\taddresses\x18\x02 \x03(\tR\taddresses\x12\x16\n" +
+	"\x06joined\x18\x03 \x01(\bR\x06joined\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\x1d\n" +
+	"\n" +
+	"last_heard\x18\x05 \x01(\tR\tlastHeard\"\xbb\x01\n" +
+	"\x17GetLANDiscoveryResponse\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x12\n" +
+	"\x04mode\x18\x02 \x01(\tR\x04mode\x12\x14\n" +
+	"\x05group\x18\x03 \x01(\tR\x05group\x12\x1c\n" +
+	"\tbroadcast\x18\x04 \x01(\bR\tbroadcast\x12>\n" +
+	"\n" +
+	"interfaces\x18\x05 \x03(\v2\x1e.wgmesh.daemon.v1.LANInterfaceR\n" +
+	"interfaces\"\x18\n" +
+	"\x16GetDHTDiscoveryRequest\"\xb5\x02\n" +
+	"\x17GetDHTDiscoveryResponse\x12\x18\n" +
+	"\aenabled\x18\x01 \x01(\bR\aenabled\x12\x19\n" +
+	"\bnat_type\x18\x02 \x01(\tR\anatType\x12\x14\n" +
+	"\x05peers\x18\x03 \x01(\x05R\x05peers\x12!\n" +
+	"\ftarget_peers\x18\x04 \x01(\x05R\vtargetPeers\x12+\n" +
+	"\x11announce_interval\x18\x05 \x01(\x03R\x10announceInterval\x12%\n" +
+	"\x0equery_interval\x18\x06 \x01(\x03R\rqueryInterval\x12#\n" +
+	"\rlast_announce\x18\a \x01(\tR\flastAnnounce\x12\x1d\n" +
+	"\n" +
+	"last_query\x18\b \x01(\tR\tlastQuery\x12\x14\n" +
+	"\x05nodes\x18\t \x01(\x05R\x05nodes\"\x12\n" +
+	"\x10LeaveMeshRequest\"-\n" +
+	"\x11LeaveMeshResponse\x12\x18\n" +
+	"\aleaving\x18\x01 \x01(\bR\aleaving\"\x16\n" +
+	"\x14RestartDaemonRequest\"7\n" +
+	"\x15RestartDaemonResponse\x12\x1e\n" +
+	"\n" +
+	"restarting\x18\x01 \x01(\bR\n" +
+	"restarting\"\xd6\b\n" +
+	"\x04Peer\x12\x16\n" +
+	"\x06pubkey\x18\x01 \x01(\tR\x06pubkey\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x17\n" +
+	"\amesh_ip\x18\x03 \x01(\tR\x06meshIp\x12\x1a\n" +
+	"\bendpoint\x18\x04 \x01(\tR\bendpoint\x12\x1b\n" +
+	"\tlast_seen\x18\x05 \x01(\tR\blastSeen\x12%\n" +
+	"\x0ediscovered_via\x18\x06 \x03(\tR\rdiscoveredVia\x12+\n" +
+	"\x11routable_networks\x18\a \x03(\tR\x10routableNetworks\x12\"\n" +
+	"\n" +
+	"latency_ms\x18\b \x01(\x01H\x00R\tlatencyMs\x88\x01\x01\x12+\n" +
+	"\x0fpacket_loss_pct\x18\t \x01(\x01H\x01R\rpacketLossPct\x88\x01\x01\x12\x1c\n" +
+	"\tinstalled\x18\n" +
+	" \x01(\bR\tinstalled\x12\x1b\n" +
+	"\tmesh_ipv6\x18\v \x01(\tR\bmeshIpv6\x12)\n" +
+	"\x10control_endpoint\x18\f \x01(\tR\x0fcontrolEndpoint\x12'\n" +
+	"\x0fendpoint_method\x18\r \x01(\tR\x0eendpointMethod\x12\x1e\n" +
+	"\n" +
+	"candidates\x18\x0e \x03(\tR\n" +
+	"candidates\x12\x16\n" +
+	"\x06probes\x18\x0f \x01(\x05R\x06probes\x12\x1f\n" +
+	"\vprobes_lost\x18\x10 \x01(\x05R\n" +
+	"probesLost\x12\x1e\n" +
+	"\n" +
+	"introducer\x18\x11 \x01(\bR\n" +
+	"introducer\x12\x18\n" +
+	"\aversion\x18\x12 \x01(\tR\aversion\x12\x19\n" +
+	"\bnat_type\x18\x13 \x01(\tR\anatType\x12\x12\n" +
+	"\x04path\x18\x14 \x01(\tR\x04path\x12!\n" +
+	"\frelay_pubkey\x18\x15 \x01(\tR\vrelayPubkey\x12\x1a\n" +
+	"\bidentity\x18\x16 \x01(\tR\bidentity\x124\n" +
+	"\x04load\x18\x17 \x01(\v2 .wgmesh.daemon.v1.IntroducerLoadR\x04load\x12-\n" +
+	"\x12introducer_elected\x18\x18 \x01(\bR\x11introducerElected\x124\n" +
+	"\x04tags\x18\x19 \x03(\v2 .wgmesh.daemon.v1.Peer.TagsEntryR\x04tags\x12%\n" +
+	"\x0elast_handshake\x18\x1a \x01(\tR\rlastHandshake\x12+\n" +
+	"\x11protocol_revision\x18\x1b \x01(\x05R\x10protocolRevision\x12\"\n" +
+	"\fcapabilities\x18\x1c \x03(\tR\fcapabilities\x12$\n" +
+	"\rcompatibility\x18\x1d \x01(\tR\rcompatibility\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01B\r\n" +
+	"\v_latency_msB\x12\n" +
+	"\x10_packet_loss_pct\"\x83\x01\n" +
+	"\x0eIntroducerLoad\x12\x1a\n" +
+	"\bsessions\x18\x01 \x01(\x05R\bsessions\x12#\n" +
+	"\rrelayed_peers\x18\x02 \x01(\x05R\frelayedPeers\x12\x10\n" +
+	"\x03bps\x18\x03 \x01(\x01R\x03bps\x12\x1e\n" +
+	"\n" +
+	"overloaded\x18\x04 \x01(\bR\n" +
+	"overloaded\"&\n" +
+	"\x10ListPeersRequest\x12\x12\n" +
+	"\x04tags\x18\x01 \x03(\tR\x04tags\"A\n" +
+	"\x11ListPeersResponse\x12,\n" +
+	"\x05peers\x18\x01 \x03(\v2\x16.wgmesh.daemon.v1.PeerR\x05peers\"(\n" +
+	"\x0eGetPeerRequest\x12\x16\n" +
+	"\x06pubkey\x18\x01 \x01(\tR\x06pubkey\"\x13\n" +
+	"\x11CountPeersRequest\"V\n" +
+	"\x12CountPeersResponse\x12\x16\n" +
+	"\x06active\x18\x01 \x01(\x05R\x06active\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\x12\x12\n" +
+	"\x04dead\x18\x03 \x01(\x05R\x04dead\"_\n" +
+	"\x10PeerStatsRequest\x12\x1b\n" +
+	"\x06window\x18\x01 \x01(\tH\x00R\x06window\x88\x01\x01\x12\x19\n" +
+	"\x05limit\x18\x02 \x01(\x05H\x01R\x05limit\x88\x01\x01B\t\n" +
+	"\a_windowB\b\n" +
+	"\x06_limit\"b\n" +
+	"\vTrafficRate\x12%\n" +
+	"\x0ewindow_seconds\x18\x01 \x01(\x05R\rwindowSeconds\x12\x15\n" +
+	"\x06rx_bps\x18\x02 \x01(\x01R\x05rxBps\x12\x15\n" +
+	"\x06tx_bps\x18\x03 \x01(\x01R\x05txBps\"\xd9\x01\n" +
+	"\tPeerStats\x12\x16\n" +
+	"\x06pubkey\x18\x01 \x01(\tR\x06pubkey\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x17\n" +
+	"\amesh_ip\x18\x03 \x01(\tR\x06meshIp\x12\x19\n" +
+	"\brx_bytes\x18\x04 \x01(\x04R\arxBytes\x12\x19\n" +
+	"\btx_bytes\x18\x05 \x01(\x04R\atxBytes\x12\x14\n" +
+	"\x05since\x18\x06 \x01(\tR\x05since\x123\n" +
+	"\x05rates\x18\a \x03(\v2\x1d.wgmesh.daemon.v1.TrafficRateR\x05rates\"F\n" +
+	"\x11PeerStatsResponse\x121\n" +
+	"\x05peers\x18\x01 \x03(\v2\x1b.wgmesh.daemon.v1.PeerStatsR\x05peers\"\x17\n" +
+	"\x15ListQuarantineRequest\"\xf3\x01\n" +
+	"\x0fQuarantinedPeer\x12\x16\n" +
+	"\x06pubkey\x18\x01 \x01(\tR\x06pubkey\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x17\n" +
+	"\amesh_ip\x18\x03 \x01(\tR\x06meshIp\x12\x1a\n" +
+	"\bendpoint\x18\x04 \x01(\tR\bendpoint\x12\x16\n" +
+	"\x06reason\x18\x05 \x01(\tR\x06reason\x12#\n" +
+	"\rpinned_pubkey\x18\x06 \x01(\tR\fpinnedPubkey\x12\x1d\n" +
+	"\n" +
+	"first_seen\x18\a \x01(\tR\tfirstSeen\x12\x1b\n" +
+	"\tlast_seen\x18\b \x01(\tR\blastSeen\"Q\n" +
+	"\x16ListQuarantineResponse\x127\n" +
+	"\x05peers\x18\x01 \x03(\v2!.wgmesh.daemon.v1.QuarantinedPeerR\x05peers\",\n" +
+	"\x12ApprovePeerRequest\x12\x16\n" +
+	"\x06pubkey\x18\x01 \x01(\tR\x06pubkey\"1\n" +
+	"\x13ApprovePeerResponse\x12\x1a\n" +
+	"\bapproved\x18\x01 \x01(\bR\bapproved\"+\n" +
+	"\x11ForgetPeerRequest\x12\x16\n" +
+	"\x06pubkey\x18\x01 \x01(\tR\x06pubkey\"2\n" +
+	"\x12ForgetPeerResponse\x12\x1c\n" +
+	"\tforgotten\x18\x01 \x01(\bR\tforgotten\"\x14\n" +
+	"\x12ListPendingRequest\"\xce\x01\n" +
+	"\vPendingPeer\x12\x16\n" +
+	"\x06pubkey\x18\x01 \x01(\tR\x06pubkey\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x17\n" +
+	"\amesh_ip\x18\x03 \x01(\tR\x06meshIp\x12\x1a\n" +
+	"\bendpoint\x18\x04 \x01(\tR\bendpoint\x12\x1a\n" +
+	"\bidentity\x18\x05 \x01(\tR\bidentity\x12\x1d\n" +
+	"\n" +
+	"first_seen\x18\x06 \x01(\tR\tfirstSeen\x12\x1b\n" +
+	"\tlast_seen\x18\a \x01(\tR\blastSeen\"J\n" +
+	"\x13ListPendingResponse\x123\n" +
+	"\x05peers\x18\x01 \x03(\v2\x1d.wgmesh.daemon.v1.PendingPeerR\x05peers\"%\n" +
+	"\x0fPingPeerRequest\x12\x12\n" +
+	"\x04peer\x18\x01 \x01(\tR\x04peer\"\xb0\x01\n" +
+	"\x10PingPeerResponse\x12\x16\n" +
+	"\x06pubkey\x18\x01 \x01(\tR\x06pubkey\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x17\n" +
+	"\amesh_ip\x18\x03 \x01(\tR\x06meshIp\x12\x12\n" +
+	"\x04path\x18\x04 \x01(\tR\x04path\x12\x1a\n" +
+	"\x06rtt_ms\x18\x05 \x01(\x01H\x00R\x05rttMs\x88\x01\x01\x12\x14\n" +
+	"\x05error\x18\x06 \x01(\tR\x05errorB\t\n" +
+	"\a_rtt_ms\"&\n" +
+	"\x10RoutePeerRequest\x12\x12\n" +
+	"\x04peer\x18\x01 \x01(\tR\x04peer\"\xbb\x02\n" +
+	"\tPeerRoute\x12\x16\n" +
+	"\x06pubkey\x18\x01 \x01(\tR\x06pubkey\x12\x1a\n" +
+	"\bhostname\x18\x02 \x01(\tR\bhostname\x12\x17\n" +
+	"\amesh_ip\x18\x03 \x01(\tR\x06meshIp\x12\x1a\n" +
+	"\bendpoint\x18\x04 \x01(\tR\bendpoint\x12\x12\n" +
+	"\x04path\x18\x05 \x01(\tR\x04path\x12\x1c\n" +
+	"\tinstalled\x18\x06 \x01(\bR\tinstalled\x12!\n" +
+	"\frelay_pubkey\x18\a \x01(\tR\vrelayPubkey\x12%\n" +
+	"\x0erelay_hostname\x18\b \x01(\tR\rrelayHostname\x12\"\n" +
+	"\rrelay_mesh_ip\x18\t \x01(\tR\vrelayMeshIp\x12%\n" +
+	"\x0erelay_endpoint\x18\n" +
+	" \x01(\tR\rrelayEndpoint\"\x14\n" +
+	"\x12ExportStateRequest\"J\n" +
+	"\x13ExportStateResponse\x123\n" +
+	"\bsnapshot\x18\x01 \x01(\v2\x17.google.protobuf.StructR\bsnapshot\"I\n" +
+	"\x12ImportStateRequest\x123\n" +
+	"\bsnapshot\x18\x01 \x01(\v2\x17.google.protobuf.StructR\bsnapshot\"K\n" +
+	"\x13ImportStateResponse\x12\x1a\n" +
+	"\bimported\x18\x01 \x01(\x05R\bimported\x12\x18\n" +
+	"\askipped\x18\x02 \x01(\x05R\askipped\"8\n" +
+	"\x11ListEventsRequest\x12\x19\n" +
+	"\x05since\x18\x01 \x01(\x04H\x00R\x05since\x88\x01\x01B\b\n" +
+	"\x06_since\"\xd5\x01\n" +
+	"\x05Event\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12\x12\n" +
+	"\x04time\x18\x02 \x01(\tR\x04time\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x16\n" +
+	"\x06pubkey\x18\x04 \x01(\tR\x06pubkey\x12>\n" +
+	"\adetails\x18\x05 \x03(\v2$.wgmesh.daemon.v1.Event.DetailsEntryR\adetails\x1a:\n" +
+	"\fDetailsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x93\x01\n" +
+	"\x0fEventSubscriber\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06queued\x18\x02 \x01(\x05R\x06queued\x12\x1c\n" +
+	"\tdelivered\x18\x03 \x01(\x04R\tdelivered\x12\x1c\n" +
+	"\tcoalesced\x18\x04 \x01(\x04R\tcoalesced\x12\x18\n" +
+	"\adropped\x18\x05 \x01(\x04R\adropped\"\x8a\x01\n" +
+	"\x12ListEventsResponse\x12/\n" +
+	"\x06events\x18\x01 \x03(\v2\x17.wgmesh.daemon.v1.EventR\x06events\x12C\n" +
+	"\vsubscribers\x18\x02 \x03(\v2!.wgmesh.daemon.v1.EventSubscriberR\vsubscribers\"\x13\n" +
+	"\x11ListRoutesRequest\"S\n" +
+	"\x05Route\x12\x18\n" +
+	"\anetwork\x18\x01 \x01(\tR\anetwork\x12\x16\n" +
+	"\x06pubkey\x18\x02 \x01(\tR\x06pubkey\x12\x18\n" +
+	"\astandby\x18\x03 \x03(\tR\astandby\"\xbd\x01\n" +
+	"\rRouteConflict\x12%\n" +
+	"\x0ewinner_network\x18\x01 \x01(\tR\rwinnerNetwork\x12#\n" +
+	"\rwinner_pubkey\x18\x02 \x01(\tR\fwinnerPubkey\x12#\n" +
+	"\rloser_network\x18\x03 \x01(\tR\floserNetwork\x12!\n" +
+	"\floser_pubkey\x18\x04 \x01(\tR\vloserPubkey\x12\x18\n" +
+	"\adropped\x18\x05 \x01(\bR\adropped\"\x84\x01\n" +
+	"\x12ListRoutesResponse\x12/\n" +
+	"\x06routes\x18\x01 \x03(\v2\x17.wgmesh.daemon.v1.RouteR\x06routes\x12=\n" +
+	"\tconflicts\x18\x02 \x03(\v2\x1f.wgmesh.daemon.v1.RouteConflictR\tconflicts\"$\n" +
+	"\x10GetConfigRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\"\x9b\x01\n" +
+	"\x11GetConfigResponse\x12J\n" +
+	"\aoptions\x18\x01 \x03(\v20.wgmesh.daemon.v1.GetConfigResponse.OptionsEntryR\aoptions\x1a:\n" +
+	"\fOptionsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"I\n" +
+	"\x10SetConfigRequest\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x19\n" +
+	"\x05value\x18\x02 \x01(\tH\x00R\x05value\x88\x01\x01B\b\n" +
+	"\x06_value\"M\n" +
+	"\x11SetConfigResponse\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x10\n" +
+	"\x03old\x18\x02 \x01(\tR\x03old\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\tR\x05value\"\x83\x01\n" +
+	"\x13RotateSecretRequest\x12\"\n" +
+	"\n" +
+	"new_secret\x18\x01 \x01(\tH\x00R\tnewSecret\x88\x01\x01\x12\x19\n" +
+	"\x05grace\x18\x02 \x01(\tH\x01R\x05grace\x88\x01\x01\x12\x14\n" +
+	"\x05force\x18\x03 \x01(\bR\x05forceB\r\n" +
+	"\v_new_secretB\b\n" +
+	"\x06_grace\"Y\n" +
+	"\x14RotateSecretResponse\x12$\n" +
+	"\x0enew_secret_uri\x18\x01 \x01(\tR\fnewSecretUri\x12\x1b\n" +
+	"\tswitch_at\x18\x02 \x01(\tR\bswitchAt\"&\n" +
+	"\x10BroadcastRequest\x12\x12\n" +
+	"\x04text\x18\x01 \x01(\tR\x04text\"<\n" +
+	"\x11BroadcastResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\asent_at\x18\x02 \x01(\tR\x06sentAt\"\x15\n" +
+	"\x13ListMessagesRequest\"\x9b\x01\n" +
+	"\aMessage\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06pubkey\x18\x02 \x01(\tR\x06pubkey\x12\x1a\n" +
+	"\bhostname\x18\x03 \x01(\tR\bhostname\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\x12\x17\n" +
+	"\asent_at\x18\x05 \x01(\tR\x06sentAt\x12\x1f\n" +
+	"\vreceived_at\x18\x06 \x01(\tR\n" +
+	"receivedAt\"M\n" +
+	"\x14ListMessagesResponse\x125\n" +
+	"\bmessages\x18\x01 \x03(\v2\x19.wgmesh.daemon.v1.MessageR\bmessages\"\x15\n" +
+	"\x13UnlockSecretRequest\".\n" +
+	"\x14UnlockSecretResponse\x12\x16\n" +
+	"\x06secret\x18\x01 \x01(\tR\x06secret2\xd5\x13\n" +
+	"\x06Daemon\x12E\n" +
+	"\x04Ping\x12\x1d.wgmesh.daemon.v1.PingRequest\x1a\x1e.wgmesh.daemon.v1.PingResponse\x12T\n" +
+	"\tGetStatus\x12\".wgmesh.daemon.v1.GetStatusRequest\x1a#.wgmesh.daemon.v1.GetStatusResponse\x12]\n" +
+	"\fGetReadiness\x12%.wgmesh.daemon.v1.GetReadinessRequest\x1a&.wgmesh.daemon.v1.GetReadinessResponse\x12T\n" +
+	"\tLeaveMesh\x12\".wgmesh.daemon.v1.LeaveMeshRequest\x1a#.wgmesh.daemon.v1.LeaveMeshResponse\x12`\n" +
+	"\rRestartDaemon\x12&.wgmesh.daemon.v1.RestartDaemonRequest\x1a'.wgmesh.daemon.v1.RestartDaemonResponse\x12]\n" +
+	"\fGetResources\x12%.wgmesh.daemon.v1.GetResourcesRequest\x1a&.wgmesh.daemon.v1.GetResourcesResponse\x12f\n" +
+	"\x0fGetLANDiscovery\x12(.wgmesh.daemon.v1.GetLANDiscoveryRequest\x1a).wgmesh.daemon.v1.GetLANDiscoveryResponse\x12f\n" +
+	"\x0fGetDHTDiscovery\x12(.wgmesh.daemon.v1.GetDHTDiscoveryRequest\x1a).wgmesh.daemon.v1.GetDHTDiscoveryResponse\x12T\n" +
+	"\tListPeers\x12\".wgmesh.daemon.v1.ListPeersRequest\x1a#.wgmesh.daemon.v1.ListPeersResponse\x12C\n" +
+	"\aGetPeer\x12 .wgmesh.daemon.v1.GetPeerRequest\x1a\x16.wgmesh.daemon.v1.Peer\x12W\n" +
+	"\n" +
+	"CountPeers\x12#.wgmesh.daemon.v1.CountPeersRequest\x1a$.wgmesh.daemon.v1.CountPeersResponse\x12T\n" +
+	"\tPeerStats\x12\".wgmesh.daemon.v1.PeerStatsRequest\x1a#.wgmesh.daemon.v1.PeerStatsResponse\x12c\n" +
+	"\x0eListQuarantine\x12'.wgmesh.daemon.v1.ListQuarantineRequest\x1a(.wgmesh.daemon.v1.ListQuarantineResponse\x12Z\n" +
+	"\vApprovePeer\x12$.wgmesh.daemon.v1.ApprovePeerRequest\x1a%.wgmesh.daemon.v1.ApprovePeerResponse\x12W\n" +
+	"\n" +
+	"ForgetPeer\x12#.wgmesh.daemon.v1.ForgetPeerRequest\x1a$.wgmesh.daemon.v1.ForgetPeerResponse\x12Z\n" +
+	"\vListPending\x12$.wgmesh.daemon.v1.ListPendingRequest\x1a%.wgmesh.daemon.v1.ListPendingResponse\x12Q\n" +
+	"\bPingPeer\x12!.wgmesh.daemon.v1.PingPeerRequest\x1a\".wgmesh.daemon.v1.PingPeerResponse\x12L\n" +
+	"\tRoutePeer\x12\".wgmesh.daemon.v1.RoutePeerRequest\x1a\x1b.wgmesh.daemon.v1.PeerRoute\x12Z\n" +
+	"\vExportState\x12$.wgmesh.daemon.v1.ExportStateRequest\x1a%.wgmesh.daemon.v1.ExportStateResponse\x12Z\n" +
+	"\vImportState\x12$.wgmesh.daemon.v1.ImportStateRequest\x1a%.wgmesh.daemon.v1.ImportStateResponse\x12W\n" +
+	"\n" +
+	"ListEvents\x12#.wgmesh.daemon.v1.ListEventsRequest\x1a$.wgmesh.daemon.v1.ListEventsResponse\x12W\n" +
+	"\n" +
+	"ListRoutes\x12#.wgmesh.daemon.v1.ListRoutesRequest\x1a$.wgmesh.daemon.v1.ListRoutesResponse\x12T\n" +
+	"\tGetConfig\x12\".wgmesh.daemon.v1.GetConfigRequest\x1a#.wgmesh.daemon.v1.GetConfigResponse\x12T\n" +
+	"\tSetConfig\x12\".wgmesh.daemon.v1.SetConfigRequest\x1a#.wgmesh.daemon.v1.SetConfigResponse\x12]\n" +
+	"\fRotateSecret\x12%.wgmesh.daemon.v1.RotateSecretRequest\x1a&.wgmesh.daemon.v1.RotateSecretResponse\x12T\n" +
+	"\tBroadcast\x12\".wgmesh.daemon.v1.BroadcastRequest\x1a#.wgmesh.daemon.v1.BroadcastResponse\x12]\n" +
+	"\fListMessages\x12%.wgmesh.daemon.v1.ListMessagesRequest\x1a&.wgmesh.daemon.v1.ListMessagesResponse\x12]\n" +
+	"\fUnlockSecret\x12%.wgmesh.daemon.v1.UnlockSecretRequest\x1a&.wgmesh.daemon.v1.UnlockSecretResponseB9Z7github.com/atvirokodosprendimai/wgmesh/pkg/rpc/daemonpbb\x06proto3"
+
+var (
+	file_wgmesh_daemon_v1_daemon_proto_rawDescOnce sync.Once
+	file_wgmesh_daemon_v1_daemon_proto_rawDescData []byte
+)
+
+func file_wgmesh_daemon_v1_daemon_proto_rawDescGZIP() []byte {
+	file_wgmesh_daemon_v1_daemon_proto_rawDescOnce.Do(func() {
+		file_wgmesh_daemon_v1_daemon_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_wgmesh_daemon_v1_daemon_proto_rawDesc), len(file_wgmesh_daemon_v1_daemon_proto_rawDesc)))
+	})
+	return file_wgmesh_daemon_v1_daemon_proto_rawDescData
+}
+
+var file_wgmesh_daemon_v1_daemon_proto_msgTypes = make([]protoimpl.MessageInfo, 71)
+var file_wgmesh_daemon_v1_daemon_proto_goTypes = []any{
+	(*PingRequest)(nil),             // 0: wgmesh.daemon.v1.PingRequest
+	(*PingResponse)(nil),            // 1: wgmesh.daemon.v1.PingResponse
+	(*GetStatusRequest)(nil),        // 2: wgmesh.daemon.v1.GetStatusRequest
+	(*GetStatusResponse)(nil),       // 3: wgmesh.daemon.v1.GetStatusResponse
+	(*GetReadinessRequest)(nil),     // 4: wgmesh.daemon.v1.GetReadinessRequest
+	(*GetReadinessResponse)(nil),    // 5: wgmesh.daemon.v1.GetReadinessResponse
+	(*GetResourcesRequest)(nil),     // 6: wgmesh.daemon.v1.GetResourcesRequest
+	(*Resource)(nil),                // 7: wgmesh.daemon.v1.Resource
+	(*GetResourcesResponse)(nil),    // 8: wgmesh.daemon.v1.GetResourcesResponse
+	(*GetLANDiscoveryRequest)(nil),  // 9: wgmesh.daemon.v1.GetLANDiscoveryRequest
+	(*LANInterface)(nil),            // 10: wgmesh.daemon.v1.LANInterface
+	(*GetLANDiscoveryResponse)(nil), // 11: wgmesh.daemon.v1.GetLANDiscoveryResponse
+	(*GetDHTDiscoveryRequest)(nil),  // 12: wgmesh.daemon.v1.GetDHTDiscoveryRequest
+	(*GetDHTDiscoveryResponse)(nil), // 13: wgmesh.daemon.v1.GetDHTDiscoveryResponse
+	(*LeaveMeshRequest)(nil),        // 14: wgmesh.daemon.v1.LeaveMeshRequest
+	(*LeaveMeshResponse)(nil),       // 15: wgmesh.daemon.v1.LeaveMeshResponse
+	(*RestartDaemonRequest)(nil),    // 16: wgmesh.daemon.v1.RestartDaemonRequest
+	(*RestartDaemonResponse)(nil),   // 17: wgmesh.daemon.v1.RestartDaemonResponse
+	(*Peer)(nil),                    // 18: wgmesh.daemon.v1.Peer
+	(*IntroducerLoad)(nil),          // 19: wgmesh.daemon.v1.IntroducerLoad
+	(*ListPeersRequest)(nil),        // 20: wgmesh.daemon.v1.ListPeersRequest
+	(*ListPeersResponse)(nil),       // 21: wgmesh.daemon.v1.ListPeersResponse
+	(*GetPeerRequest)(nil),          // 22: wgmesh.daemon.v1.GetPeerRequest
+	(*CountPeersRequest)(nil),       // 23: wgmesh.daemon.v1.CountPeersRequest
+	(*CountPeersResponse)(nil),      // 24: wgmesh.daemon.v1.CountPeersResponse
+	(*PeerStatsRequest)(nil),        // 25: wgmesh.daemon.v1.PeerStatsRequest
+	(*TrafficRate)(nil),             // 26: wgmesh.daemon.v1.TrafficRate
+	(*PeerStats)(nil),               // 27: wgmesh.daemon.v1.PeerStats
+	(*PeerStatsResponse)(nil),       // 28: wgmesh.daemon.v1.PeerStatsResponse
+	(*ListQuarantineRequest)(nil),   // 29: wgmesh.daemon.v1.ListQuarantineRequest
+	(*QuarantinedPeer)(nil),         // 30: wgmesh.daemon.v1.QuarantinedPeer
+	(*ListQuarantineResponse)(nil),  // 31: wgmesh.daemon.v1.ListQuarantineResponse
+	(*ApprovePeerRequest)(nil),      // 32: wgmesh.daemon.v1.ApprovePeerRequest
+	(*ApprovePeerResponse)(nil),     // 33: wgmesh.daemon.v1.ApprovePeerResponse
+	(*ForgetPeerRequest)(nil),       // 34: wgmesh.daemon.v1.ForgetPeerRequest
+	(*ForgetPeerResponse)(nil),      // 35: wgmesh.daemon.v1.ForgetPeerResponse
+	(*ListPendingRequest)(nil),      // 36: wgmesh.daemon.v1.ListPendingRequest
+	(*PendingPeer)(nil),             // 37: wgmesh.daemon.v1.PendingPeer
+	(*ListPendingResponse)(nil),     // 38: wgmesh.daemon.v1.ListPendingResponse
+	(*PingPeerRequest)(nil),         // 39: wgmesh.daemon.v1.PingPeerRequest
+	(*PingPeerResponse)(nil),        // 40: wgmesh.daemon.v1.PingPeerResponse
+	(*RoutePeerRequest)(nil),        // 41: wgmesh.daemon.v1.RoutePeerRequest
+	(*PeerRoute)(nil),               // 42: wgmesh.daemon.v1.PeerRoute
+	(*ExportStateRequest)(nil),      // 43: wgmesh.daemon.v1.ExportStateRequest
+	(*ExportStateResponse)(nil),     // 44: wgmesh.daemon.v1.ExportStateResponse
+	(*ImportStateRequest)(nil),      // 45: wgmesh.daemon.v1.ImportStateRequest
+	(*ImportStateResponse)(nil),     // 46: wgmesh.daemon.v1.ImportStateResponse
+	(*ListEventsRequest)(nil),       // 47: wgmesh.daemon.v1.ListEventsRequest
+	(*Event)(nil),                   // 48: wgmesh.daemon.v1.Event
+	(*EventSubscriber)(nil),         // 49: wgmesh.daemon.v1.EventSubscriber
+	(*ListEventsResponse)(nil),      // 50: wgmesh.daemon.v1.ListEventsResponse
+	(*ListRoutesRequest)(nil),       // 51: wgmesh.daemon.v1.ListRoutesRequest
+	(*Route)(nil),                   // 52: wgmesh.daemon.v1.Route
+	(*RouteConflict)(nil),           // 53: wgmesh.daemon.v1.RouteConflict
+	(*ListRoutesResponse)(nil),      // 54: wgmesh.daemon.v1.ListRoutesResponse
+	(*GetConfigRequest)(nil),        // 55: wgmesh.daemon.v1.GetConfigRequest
+	(*GetConfigResponse)(nil),       // 56: wgmesh.daemon.v1.GetConfigResponse
+	(*SetConfigRequest)(nil),        // 57: wgmesh.daemon.v1.SetConfigRequest
+	(*SetConfigResponse)(nil),       // 58: wgmesh.daemon.v1.SetConfigResponse
+	(*RotateSecretRequest)(nil),     // 59: wgmesh.daemon.v1.RotateSecretRequest
+	(*RotateSecretResponse)(nil),    // 60: wgmesh.daemon.v1.RotateSecretResponse
+	(*BroadcastRequest)(nil),        // 61: wgmesh.daemon.v1.BroadcastRequest
+	(*BroadcastResponse)(nil),       // 62: wgmesh.daemon.v1.BroadcastResponse
+	(*ListMessagesRequest)(nil),     // 63: wgmesh.daemon.v1.ListMessagesRequest
+	(*Message)(nil),                 // 64: wgmesh.daemon.v1.Message
+	(*ListMessagesResponse)(nil),    // 65: wgmesh.daemon.v1.ListMessagesResponse
+	(*UnlockSecretRequest)(nil),     // 66: wgmesh.daemon.v1.UnlockSecretRequest
+	(*UnlockSecretResponse)(nil),    // 67: wgmesh.daemon.v1.UnlockSecretResponse
+	nil,                             // 68: wgmesh.daemon.v1.Peer.TagsEntry
+	nil,                             // 69: wgmesh.daemon.v1.Event.DetailsEntry
+	nil,                             // 70: wgmesh.daemon.v1.GetConfigResponse.OptionsEntry
+	(*structpb.Struct)(nil),         // 71: google.protobuf.Struct
+}
+var file_wgmesh_daemon_v1_daemon_proto_depIdxs = []int32{
+	7,  // 0: wgmesh.daemon.v1.GetResourcesResponse.resources:type_name -> wgmesh.daemon.v1.Resource
+	10, // 1: wgmesh.daemon.v1.GetLANDiscoveryResponse.interfaces:type_name -> wgmesh.daemon.v1.LANInterface
+	19, // 2: wgmesh.daemon.v1.Peer.load:type_name -> wgmesh.daemon.v1.IntroducerLoad
+	68, // 3: wgmesh.daemon.v1.Peer.tags:type_name -> wgmesh.daemon.v1.Peer.TagsEntry
+	18, // 4: wgmesh.daemon.v1.ListPeersResponse.peers:type_name -> wgmesh.daemon.v1.Peer
+	26, // 5: wgmesh.daemon.v1.PeerStats.rates:type_name -> wgmesh.daemon.v1.TrafficRate
+	27, // 6: wgmesh.daemon.v1.PeerStatsResponse.peers:type_name -> wgmesh.daemon.v1.PeerStats
+	30, // 7: wgmesh.daemon.v1.ListQuarantineResponse.peers:type_name -> wgmesh.daemon.v1.QuarantinedPeer
+	37, // 8: wgmesh.daemon.v1.ListPendingResponse.peers:type_name -> wgmesh.daemon.v1.PendingPeer
+	71, // 9: wgmesh.daemon.v1.ExportStateResponse.snapshot:type_name -> google.protobuf.Struct
+	71, // 10: wgmesh.daemon.v1.ImportStateRequest.snapshot:type_name -> google.protobuf.Struct
+	69, // 11: wgmesh.daemon.v1.Event.details:type_name -> wgmesh.daemon.v1.Event.DetailsEntry
+	48, // 12: wgmesh.daemon.v1.ListEventsResponse.events:type_name -> wgmesh.daemon.v1.Event
+	49, // 13: wgmesh.daemon.v1.ListEventsResponse.subscribers:type_name -> wgmesh.daemon.v1.EventSubscriber
+	52, // 14: wgmesh.daemon.v1.ListRoutesResponse.routes:type_name -> wgmesh.daemon.v1.Route
+	53, // 15: wgmesh.daemon.v1.ListRoutesResponse.conflicts:type_name -> wgmesh.daemon.v1.RouteConflict
+	70, // 16: wgmesh.daemon.v1.GetConfigResponse.options:type_name -> wgmesh.daemon.v1.GetConfigResponse.OptionsEntry
+	64, // 17: wgmesh.daemon.v1.ListMessagesResponse.messages:type_name -> wgmesh.daemon.v1.Message
+	0,  // 18: wgmesh.daemon.v1.Daemon.Ping:input_type -> wgmesh.daemon.v1.PingRequest
+	2,  // 19: wgmesh.daemon.v1.Daemon.GetStatus:input_type -> wgmesh.daemon.v1.GetStatusRequest
+	4,  // 20: wgmesh.daemon.v1.Daemon.GetReadiness:input_type -> wgmesh.daemon.v1.GetReadinessRequest
+	14, // 21: wgmesh.daemon.v1.Daemon.LeaveMesh:input_type -> wgmesh.daemon.v1.LeaveMeshRequest
+	16, // 22: wgmesh.daemon.v1.Daemon.RestartDaemon:input_type -> wgmesh.daemon.v1.RestartDaemonRequest
+	6,  // 23: wgmesh.daemon.v1.Daemon.GetResources:input_type -> wgmesh.daemon.v1.GetResourcesRequest
+	9,  // 24: wgmesh.daemon.v1.Daemon.GetLANDiscovery:input_type -> wgmesh.daemon.v1.GetLANDiscoveryRequest
+	12, // 25: wgmesh.daemon.v1.Daemon.GetDHTDiscovery:input_type -> wgmesh.daemon.v1.GetDHTDiscoveryRequest
+	20, // 26: wgmesh.daemon.v1.Daemon.ListPeers:input_type -> wgmesh.daemon.v1.ListPeersRequest
+	22, // 27: wgmesh.daemon.v1.Daemon.GetPeer:input_type -> wgmesh.daemon.v1.GetPeerRequest
+	23, // 28: wgmesh.daemon.v1.Daemon.CountPeers:input_type -> wgmesh.daemon.v1.CountPeersRequest
+	25, // 29: wgmesh.daemon.v1.Daemon.PeerStats:input_type -> wgmesh.daemon.v1.PeerStatsRequest
+	29, // 30: wgmesh.daemon.v1.Daemon.ListQuarantine:input_type -> wgmesh.daemon.v1.ListQuarantineRequest
+	32, // 31: wgmesh.daemon.v1.Daemon.ApprovePeer:input_type -> wgmesh.daemon.v1.ApprovePeerRequest
+	34, // 32: wgmesh.daemon.v1.Daemon.ForgetPeer:input_type -> wgmesh.daemon.v1.ForgetPeerRequest
+	36, // 33: wgmesh.daemon.v1.Daemon.ListPending:input_type -> wgmesh.daemon.v1.ListPendingRequest
+	39, // 34: wgmesh.daemon.v1.Daemon.PingPeer:input_type -> wgmesh.daemon.v1.PingPeerRequest
+	41, // 35: wgmesh.daemon.v1.Daemon.RoutePeer:input_type -> wgmesh.daemon.v1.RoutePeerRequest
+	43, // 36: wgmesh.daemon.v1.Daemon.ExportState:input_type -> wgmesh.daemon.v1.ExportStateRequest
+	45, // 37: wgmesh.daemon.v1.Daemon.ImportState:input_type -> wgmesh.daemon.v1.ImportStateRequest
+	47, // 38: wgmesh.daemon.v1.Daemon.ListEvents:input_type -> wgmesh.daemon.v1.ListEventsRequest
+	51, // 39: wgmesh.daemon.v1.Daemon.ListRoutes:input_type -> wgmesh.daemon.v1.ListRoutesRequest
+	55, // 40: wgmesh.daemon.v1.Daemon.GetConfig:input_type -> wgmesh.daemon.v1.GetConfigRequest
+	57, // 41: wgmesh.daemon.v1.Daemon.SetConfig:input_type -> wgmesh.daemon.v1.SetConfigRequest
+	59, // 42: wgmesh.daemon.v1.Daemon.RotateSecret:input_type -> wgmesh.daemon.v1.RotateSecretRequest
+	61, // 43: wgmesh.daemon.v1.Daemon.Broadcast:input_type -> wgmesh.daemon.v1.BroadcastRequest
+	63, // 44: wgmesh.daemon.v1.Daemon.ListMessages:input_type -> wgmesh.daemon.v1.ListMessagesRequest
+	66, // 45: wgmesh.daemon.v1.Daemon.UnlockSecret:input_type -> wgmesh.daemon.v1.UnlockSecretRequest
+	1,  // 46: wgmesh.daemon.v1.Daemon.Ping:output_type -> wgmesh.daemon.v1.PingResponse
+	3,  // 47: wgmesh.daemon.v1.Daemon.GetStatus:output_type -> wgmesh.daemon.v1.GetStatusResponse
+	5,  // 48: wgmesh.daemon.v1.Daemon.GetReadiness:output_type -> wgmesh.daemon.v1.GetReadinessResponse
+	15, // 49: wgmesh.daemon.v1.Daemon.LeaveMesh:output_type -> wgmesh.daemon.v1.LeaveMeshResponse
+	17, // 50: wgmesh.daemon.v1.Daemon.RestartDaemon:output_type -> wgmesh.daemon.v1.RestartDaemonResponse
+	8,  // 51: wgmesh.daemon.v1.Daemon.GetResources:output_type -> wgmesh.daemon.v1.GetResourcesResponse
+	11, // 52: wgmesh.daemon.v1.Daemon.GetLANDiscovery:output_type -> wgmesh.daemon.v1.GetLANDiscoveryResponse
+	13, // 53: wgmesh.daemon.v1.Daemon.GetDHTDiscovery:output_type -> wgmesh.daemon.v1.GetDHTDiscoveryResponse
+	21, // 54: wgmesh.daemon.v1.Daemon.ListPeers:output_type -> wgmesh.daemon.v1.ListPeersResponse
+	18, // 55: wgmesh.daemon.v1.Daemon.GetPeer:output_type -> wgmesh.daemon.v1.Peer
+	24, // 56: wgmesh.daemon.v1.Daemon.CountPeers:output_type -> wgmesh.daemon.v1.CountPeersResponse
+	28, // 57: wgmesh.daemon.v1.Daemon.PeerStats:output_type -> wgmesh.daemon.v1.PeerStatsResponse
+	31, // 58: wgmesh.daemon.v1.Daemon.ListQuarantine:output_type -> wgmesh.daemon.v1.ListQuarantineResponse
+	33, // 59: wgmesh.daemon.v1.Daemon.ApprovePeer:output_type -> wgmesh.daemon.v1.ApprovePeerResponse
+	35, // 60: wgmesh.daemon.v1.Daemon.ForgetPeer:output_type -> wgmesh.daemon.v1.ForgetPeerResponse
+	38, // 61: wgmesh.daemon.v1.Daemon.ListPending:output_type -> wgmesh.daemon.v1.ListPendingResponse
+	40, // 62: wgmesh.daemon.v1.Daemon.PingPeer:output_type -> wgmesh.daemon.v1.PingPeerResponse
+	42, // 63: wgmesh.daemon.v1.Daemon.RoutePeer:output_type -> wgmesh.daemon.v1.PeerRoute
+	44, // 64: wgmesh.daemon.v1.Daemon.ExportState:output_type -> wgmesh.daemon.v1.ExportStateResponse
+	46, // 65: wgmesh.daemon.v1.Daemon.ImportState:output_type -> wgmesh.daemon.v1.ImportStateResponse
+	50, // 66: wgmesh.daemon.v1.Daemon.ListEvents:output_type -> wgmesh.daemon.v1.ListEventsResponse
+	54, // 67: wgmesh.daemon.v1.Daemon.ListRoutes:output_type -> wgmesh.daemon.v1.ListRoutesResponse
+	56, // 68: wgmesh.daemon.v1.Daemon.GetConfig:output_type -> wgmesh.daemon.v1.GetConfigResponse
+	58, // 69: wgmesh.daemon.v1.Daemon.SetConfig:output_type -> wgmesh.daemon.v1.SetConfigResponse
+	60, // 70: wgmesh.daemon.v1.Daemon.RotateSecret:output_type -> wgmesh.daemon.v1.RotateSecretResponse
+	62, // 71: wgmesh.daemon.v1.Daemon.Broadcast:output_type -> wgmesh.daemon.v1.BroadcastResponse
+	65, // 72: wgmesh.daemon.v1.Daemon.ListMessages:output_type -> wgmesh.daemon.v1.ListMessagesResponse
+	67, // 73: wgmesh.daemon.v1.Daemon.UnlockSecret:output_type -> wgmesh.daemon.v1.UnlockSecretResponse
+	46, // [46:74] is the sub-list for method output_type
+	18, // [18:46] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
+}
+
+func init() { file_wgmesh_daemon_v1_daemon_proto_init() }
+func file_wgmesh_daemon_v1_daemon_proto_init() {
+	if File_wgmesh_daemon_v1_daemon_proto != nil {
+		return
+	}
+	file_wgmesh_daemon_v1_daemon_proto_msgTypes[4].OneofWrappers = []any{}
+	file_wgmesh_daemon_v1_daemon_proto_msgTypes[18].OneofWrappers = []any{}
+	file_wgmesh_daemon_v1_daemon_proto_msgTypes[25].OneofWrappers = []any{}
+	file_wgmesh_daemon_v1_daemon_proto_msgTypes[40].OneofWrappers = []any{}
+	file_wgmesh_daemon_v1_daemon_proto_msgTypes[47].OneofWrappers = []any{}
+	file_wgmesh_daemon_v1_daemon_proto_msgTypes[57].OneofWrappers = []any{}
+	file_wgmesh_daemon_v1_daemon_proto_msgTypes[59].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_wgmesh_daemon_v1_daemon_proto_rawDesc), len(file_wgmesh_daemon_v1_daemon_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   71,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_wgmesh_daemon_v1_daemon_proto_goTypes,
+		DependencyIndexes: file_wgmesh_daemon_v1_daemon_proto_depIdxs,
+		MessageInfos:      file_wgmesh_daemon_v1_daemon_proto_msgTypes,
+	}.Build()
+	File_wgmesh_daemon_v1_daemon_proto = out.File
+	file_wgmesh_daemon_v1_daemon_proto_goTypes = nil
+	file_wgmesh_daemon_v1_daemon_proto_depIdxs = nil
+}
@@ -0,0 +1,452 @@
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/rpc/daemonpb"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/tracing"
+)
+
+// The daemon's socket and TCP listener carry two protocols. A connection
+// that opens with the HTTP/2 preface is handed to the gRPC server; anything
+// else is line-delimited JSON-RPC, which is deprecated and only served when
+// ServerConfig.LegacyJSON is set.
+//
+// The gRPC methods run the same handlers as the JSON methods: requests are
+// turned into JSON-RPC params and results back into messages, relying on
+// the proto field names being the JSON keys (see daemon.proto).
+
+// grpcMethods maps each JSON-RPC method to its gRPC method on the Daemon
+// service.
+var grpcMethods = map[string]string{
+	"daemon.ping":      "Ping",
+	"daemon.status":    "GetStatus",
+	"daemon.ready":     "GetReadiness",
+	"daemon.leave":     "LeaveMesh",
+	"daemon.restart":   "RestartDaemon",
+	"daemon.resources": "GetResources",
+	"discovery.lan":    "GetLANDiscovery",
+	"discovery.dht":    "GetDHTDiscovery",
+	"peers.list":       "ListPeers",
+	"peers.get":        "GetPeer",
+	"peers.count":      "CountPeers",
+	"peers.stats":      "PeerStats",
+	"peers.quarantine": "ListQuarantine",
+	"peers.approve":    "ApprovePeer",
+	"peers.forget":     "ForgetPeer",
+	"peers.pending":    "ListPending",
+	"peers.ping":       "PingPeer",
+	"peers.route":      "RoutePeer",
+	"state.export":     "ExportState",
+	"state.import":     "ImportState",
+	"events.list":      "ListEvents",
+	"routes.list":      "ListRoutes",
+	"config.get":       "GetConfig",
+	"config.set":       "SetConfig",
+	"mesh.rotate":      "RotateSecret",
+	"mesh.broadcast":   "Broadcast",
+	"mesh.messages":    "ListMessages",
+	"secret.unlock":    "UnlockSecret",
+}
+
+// jsonMethodFor returns the JSON-RPC method of a full gRPC method name,
+// or "" for methods outside grpcMethods.
+func jsonMethodFor(fullMethod string) string {
+	name := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for method, grpcName := range grpcMethods {
+		if grpcName == name {
+			return method
+		}
+	}
+	return ""
+}
+
+// daemonService is the descriptor of the Daemon gRPC service.
+var daemonService = daemonpb.File_wgmesh_daemon_v1_daemon_proto.Services().ByName("Daemon")
+
+// callerInfo is what the server knows about the other end of a connection.
+// gRPC handlers get it as the connection's AuthInfo.
+type callerInfo struct {
+	credentials.CommonAuthInfo
+	cred   *PeerCred // nil on TCP and where peer credentials are unsupported
+	remote bool      // accepted on the TCP listener
+	denied *Error    // set when the caller failed authorizeLocal
+}
+
+func (callerInfo) AuthType() string { return "wgmesh" }
+
+// sniffedConn is a connection whose first bytes were peeked to pick the
+// protocol. Reads replay them.
+type sniffedConn struct {
+	net.Conn
+	r      *bufio.Reader
+	caller callerInfo
+}
+
+func (c *sniffedConn) Read(p []byte) (int, error) { return c.r.Read(p) }
+
+// sniffConn reports whether conn speaks gRPC, that is, HTTP/2.
+func sniffConn(conn net.Conn, caller callerInfo) (*sniffedConn, bool, error) {
+	sc := &sniffedConn{Conn: conn, r: bufio.NewReader(conn), caller: caller}
+	start, err := sc.r.Peek(3)
+	if err != nil {
+		return nil, false, err
+	}
+	return sc, string(start) == "PRI", nil // "PRI * HTTP/2.0"
+}
+
+// connCredentials hands sniffed connections to gRPC unchanged, with their
+// callerInfo. There is no transport security: the socket's permissions,
+// peer credentials and the TCP token are the access control.
+type connCredentials struct{}
+
+func (connCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	if sc, ok := conn.(*sniffedConn); ok {
+		return conn, sc.caller, nil
+	}
+	return conn, callerInfo{}, nil
+}
+
+func (connCredentials) ClientHandshake(_ context.Context, _ string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return conn, callerInfo{}, nil
+}
+
+func (connCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "wgmesh"}
+}
+
+func (connCredentials) Clone() credentials.TransportCredentials { return connCredentials{} }
+
+func (connCredentials) OverrideServerName(string) error { return nil }
+
+// connListener feeds connections accepted elsewhere to grpc.Server.Serve.
+type connListener struct {
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newConnListener() *connListener {
+	return &connListener{conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+// add hands conn to the gRPC server, or closes it if the server is gone.
+func (l *connListener) add(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		conn.Close()
+	}
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *connListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr { return &net.UnixAddr{Name: "wgmesh", Net: "unix"} }
+
+// newGRPCServer returns a gRPC server for sniffed connections. Callers
+// that failed authorizeLocal are refused; on TCP, every call must carry
+// token and the localOnlyMethods are refused.
+func newGRPCServer(token string) *grpc.Server {
+	authorize := func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		caller := callerFrom(ctx)
+		if caller.denied != nil {
+			return nil, status.Error(codes.PermissionDenied, caller.denied.Message)
+		}
+		if caller.remote {
+			md, _ := metadata.FromIncomingContext(ctx)
+			var got string
+			if auth := md.Get("authorization"); len(auth) == 1 {
+				got = auth[0]
+			}
+			if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+token)) != 1 {
+				return nil, status.Error(codes.Unauthenticated, "invalid token")
+			}
+			if method := jsonMethodFor(info.FullMethod); localOnlyMethods[method] {
+				return nil, status.Error(codes.PermissionDenied, method+" is not available over TCP")
+			}
+		}
+		return handler(ctx, req)
+	}
+	return grpc.NewServer(
+		grpc.Creds(connCredentials{}),
+		grpc.MaxRecvMsgSize(MaxRequestSize),
+		grpc.UnaryInterceptor(authorize),
+	)
+}
+
+func callerFrom(ctx context.Context) callerInfo {
+	if p, ok := peer.FromContext(ctx); ok {
+		if caller, ok := p.AuthInfo.(callerInfo); ok {
+			return caller
+		}
+	}
+	return callerInfo{}
+}
+
+// grpcService implements the Daemon gRPC service on top of a Server's
+// JSON-RPC handlers.
+type grpcService struct {
+	daemonpb.UnimplementedDaemonServer
+	s *Server
+}
+
+// callGRPC runs the JSON-RPC method behind a gRPC call and fills resp with
+// its result.
+func callGRPC[T proto.Message](ctx context.Context, s *Server, method string, req proto.Message, resp T) (T, error) {
+	var zero T
+	_, span := tracing.Start(ctx, "rpc.call",
+		tracing.String("rpc.system", "grpc"),
+		tracing.String("rpc.method", method))
+	defer span.End()
+
+	r := &Request{JSONRPC: "2.0", Method: method, Params: messageToMap(req.ProtoReflect())}
+	result := s.handleRequest(r, callerFrom(ctx).cred)
+	if result.Error != nil {
+		span.SetAttributes(tracing.Int("rpc.jsonrpc.error_code", result.Error.Code))
+		span.RecordError(errors.New(result.Error.Message))
+		return zero, status.Error(grpcCode(result.Error.Code), result.Error.Message)
+	}
+
+	data, err := json.Marshal(result.Result)
+	if err != nil {
+		return zero, status.Errorf(codes.Internal, "failed to encode result: %v", err)
+	}
+	if method == "state.export" {
+		// The JSON-RPC result is the snapshot document itself.
+		data = []byte(fmt.Sprintf(`{"snapshot":%s}`, data))
+	}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, resp); err != nil {
+		return zero, status.Errorf(codes.Internal, "failed to encode result: %v", err)
+	}
+	return resp, nil
+}
+
+func (g *grpcService) Ping(ctx context.Context, req *daemonpb.PingRequest) (*daemonpb.PingResponse, error) {
+	return callGRPC(ctx, g.s, "daemon.ping", req, &daemonpb.PingResponse{})
+}
+
+func (g *grpcService) GetStatus(ctx context.Context, req *daemonpb.GetStatusRequest) (*daemonpb.GetStatusResponse, error) {
+	return callGRPC(ctx, g.s, "daemon.status", req, &daemonpb.GetStatusResponse{})
+}
+
+func (g *grpcService) ListPeers(ctx context.Context, req *daemonpb.ListPeersRequest) (*daemonpb.ListPeersResponse, error) {
+	return callGRPC(ctx, g.s, "peers.list", req, &daemonpb.ListPeersResponse{})
+}
+
+func (g *grpcService) GetPeer(ctx context.Context, req *daemonpb.GetPeerRequest) (*daemonpb.Peer, error) {
+	return callGRPC(ctx, g.s, "peers.get", req, &daemonpb.Peer{})
+}
+
+func (g *grpcService) CountPeers(ctx context.Context, req *daemonpb.CountPeersRequest) (*daemonpb.CountPeersResponse, error) {
+	return callGRPC(ctx, g.s, "peers.count", req, &daemonpb.CountPeersResponse{})
+}
+
+func (g *grpcService) PeerStats(ctx context.Context, req *daemonpb.PeerStatsRequest) (*daemonpb.PeerStatsResponse, error) {
+	return callGRPC(ctx, g.s, "peers.stats", req, &daemonpb.PeerStatsResponse{})
+}
+
+func (g *grpcService) ListQuarantine(ctx context.Context, req *daemonpb.ListQuarantineRequest) (*daemonpb.ListQuarantineResponse, error) {
+	return callGRPC(ctx, g.s, "peers.quarantine", req, &daemonpb.ListQuarantineResponse{})
+}
+
+func (g *grpcService) ApprovePeer(ctx context.Context, req *daemonpb.ApprovePeerRequest) (*daemonpb.ApprovePeerResponse, error) {
+	return callGRPC(ctx, g.s, "peers.approve", req, &daemonpb.ApprovePeerResponse{})
+}
+
+func (g *grpcService) ForgetPeer(ctx context.Context, req *daemonpb.ForgetPeerRequest) (*daemonpb.ForgetPeerResponse, error) {
+	return callGRPC(ctx, g.s, "peers.forget", req, &daemonpb.ForgetPeerResponse{})
+}
+
+func (g *grpcService) ListPending(ctx context.Context, req *daemonpb.ListPendingRequest) (*daemonpb.ListPendingResponse, error) {
+	return callGRPC(ctx, g.s, "peers.pending", req, &daemonpb.ListPendingResponse{})
+}
+
+func (g *grpcService) PingPeer(ctx context.Context, req *daemonpb.PingPeerRequest) (*daemonpb.PingPeerResponse, error) {
+	return callGRPC(ctx, g.s, "peers.ping", req, &daemonpb.PingPeerResponse{})
+}
+
+func (g *grpcService) RoutePeer(ctx context.Context, req *daemonpb.RoutePeerRequest) (*daemonpb.PeerRoute, error) {
+	return callGRPC(ctx, g.s, "peers.route", req, &daemonpb.PeerRoute{})
+}
+
+func (g *grpcService) ExportState(ctx context.Context, req *daemonpb.ExportStateRequest) (*daemonpb.ExportStateResponse, error) {
+	return callGRPC(ctx, g.s, "state.export", req, &daemonpb.ExportStateResponse{})
+}
+
+func (g *grpcService) ImportState(ctx context.Context, req *daemonpb.ImportStateRequest) (*daemonpb.ImportStateResponse, error) {
+	return callGRPC(ctx, g.s, "state.import", req, &daemonpb.ImportStateResponse{})
+}
+
+func (g *grpcService) ListEvents(ctx context.Context, req *daemonpb.ListEventsRequest) (*daemonpb.ListEventsResponse, error) {
+	return callGRPC(ctx, g.s, "events.list", req, &daemonpb.ListEventsResponse{})
+}
+
+func (g *grpcService) ListRoutes(ctx context.Context, req *daemonpb.ListRoutesRequest) (*daemonpb.ListRoutesResponse, error) {
+	return callGRPC(ctx, g.s, "routes.list", req, &daemonpb.ListRoutesResponse{})
+}
+
+func (g *grpcService) GetConfig(ctx context.Context, req *daemonpb.GetConfigRequest) (*daemonpb.GetConfigResponse, error) {
+	return callGRPC(ctx, g.s, "config.get", req, &daemonpb.GetConfigResponse{})
+}
+
+func (g *grpcService) SetConfig(ctx context.Context, req *daemonpb.SetConfigRequest) (*daemonpb.SetConfigResponse, error) {
+	return callGRPC(ctx, g.s, "config.set", req, &daemonpb.SetConfigResponse{})
+}
+
+func (g *grpcService) RotateSecret(ctx context.Context, req *daemonpb.RotateSecretRequest) (*daemonpb.RotateSecretResponse, error) {
+	return callGRPC(ctx, g.s, "mesh.rotate", req, &daemonpb.RotateSecretResponse{})
+}
+
+func (g *grpcService) Broadcast(ctx context.Context, req *daemonpb.BroadcastRequest) (*daemonpb.BroadcastResponse, error) {
+	return callGRPC(ctx, g.s, "mesh.broadcast", req, &daemonpb.BroadcastResponse{})
+}
+
+func (g *grpcService) ListMessages(ctx context.Context, req *daemonpb.ListMessagesRequest) (*daemonpb.ListMessagesResponse, error) {
+	return callGRPC(ctx, g.s, "mesh.messages", req, &daemonpb.ListMessagesResponse{})
+}
+
+func (g *grpcService) GetReadiness(ctx context.Context, req *daemonpb.GetReadinessRequest) (*daemonpb.GetReadinessResponse, error) {
+	return callGRPC(ctx, g.s, "daemon.ready", req, &daemonpb.GetReadinessResponse{})
+}
+
+func (g *grpcService) GetResources(ctx context.Context, req *daemonpb.GetResourcesRequest) (*daemonpb.GetResourcesResponse, error) {
+	return callGRPC(ctx, g.s, "daemon.resources", req, &daemonpb.GetResourcesResponse{})
+}
+
+func (g *grpcService) GetLANDiscovery(ctx context.Context, req *daemonpb.GetLANDiscoveryRequest) (*daemonpb.GetLANDiscoveryResponse, error) {
+	return callGRPC(ctx, g.s, "discovery.lan", req, &daemonpb.GetLANDiscoveryResponse{})
+}
+
+func (g *grpcService) GetDHTDiscovery(ctx context.Context, req *daemonpb.GetDHTDiscoveryRequest) (*daemonpb.GetDHTDiscoveryResponse, error) {
+	return callGRPC(ctx, g.s, "discovery.dht", req, &daemonpb.GetDHTDiscoveryResponse{})
+}
+
+func (g *grpcService) LeaveMesh(ctx context.Context, req *daemonpb.LeaveMeshRequest) (*daemonpb.LeaveMeshResponse, error) {
+	return callGRPC(ctx, g.s, "daemon.leave", req, &daemonpb.LeaveMeshResponse{})
+}
+
+func (g *grpcService) RestartDaemon(ctx context.Context, req *daemonpb.RestartDaemonRequest) (*daemonpb.RestartDaemonResponse, error) {
+	return callGRPC(ctx, g.s, "daemon.restart", req, &daemonpb.RestartDaemonResponse{})
+}
+
+func (g *grpcService) UnlockSecret(ctx context.Context, req *daemonpb.UnlockSecretRequest) (*daemonpb.UnlockSecretResponse, error) {
+	return callGRPC(ctx, g.s, "secret.unlock", req, &daemonpb.UnlockSecretResponse{})
+}
+
+// grpcCode maps a JSON-RPC error code to a gRPC status code.
+func grpcCode(code int) codes.Code {
+	switch code {
+	case ErrCodeParseError, ErrCodeInvalidRequest, ErrCodeInvalidParams:
+		return codes.InvalidArgument
+	case ErrCodeMethodNotFound:
+		return codes.Unimplemented
+	case ErrCodeUnauthorized:
+		return codes.PermissionDenied
+	}
+	return codes.Internal
+}
+
+// jsonCode maps a gRPC status code back to a JSON-RPC error code.
+func jsonCode(code codes.Code) int {
+	switch code {
+	case codes.InvalidArgument:
+		return ErrCodeInvalidParams
+	case codes.Unimplemented:
+		return ErrCodeMethodNotFound
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return ErrCodeUnauthorized
+	}
+	return ErrCodeInternalError
+}
+
+// messageToMap converts m to what encoding/json decodes the equivalent
+// JSON-RPC object into: fields by name, numbers as float64, and unset
+// optional fields left out.
+func messageToMap(m protoreflect.Message) map[string]interface{} {
+	if s, ok := m.Interface().(*structpb.Struct); ok {
+		return s.AsMap()
+	}
+	out := make(map[string]interface{})
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.HasPresence() && !m.Has(fd) {
+			continue
+		}
+		name := string(fd.Name())
+		v := m.Get(fd)
+		switch {
+		case fd.IsList():
+			list := v.List()
+			items := make([]interface{}, list.Len())
+			for j := range items {
+				items[j] = fieldValue(fd, list.Get(j))
+			}
+			out[name] = items
+		case fd.IsMap():
+			entries := make(map[string]interface{}, v.Map().Len())
+			v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				entries[k.String()] = fieldValue(fd.MapValue(), mv)
+				return true
+			})
+			out[name] = entries
+		default:
+			out[name] = fieldValue(fd, v)
+		}
+	}
+	return out
+}
+
+func fieldValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageToMap(v.Message())
+	case protoreflect.BoolKind:
+		return v.Bool()
+	case protoreflect.StringKind:
+		return v.String()
+	case protoreflect.BytesKind:
+		return v.Bytes()
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float()
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return float64(v.Int())
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return float64(v.Uint())
+	case protoreflect.EnumKind:
+		return float64(v.Enum())
+	}
+	return nil
+}
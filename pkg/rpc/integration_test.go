@@ -1,9 +1,11 @@
 package rpc
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -15,14 +17,26 @@ func TestClientServerIntegration(t *testing.T) {
 	t.Cleanup(func() { os.Remove(socketPath) })
 
 	// Mock peer data
+	mockLossPct := 5.0
 	mockPeer := &PeerData{
-		WGPubKey:         "test-pubkey-abc123",
-		Hostname:         "node-test-1",
-		MeshIP:           "10.42.0.5",
-		Endpoint:         "203.0.113.10:51820",
-		LastSeen:         time.Now(),
-		DiscoveredVia:    []string{"dht", "gossip"},
-		RoutableNetworks: []string{"192.168.1.0/24"},
+		WGPubKey:          "test-pubkey-abc123",
+		Hostname:          "node-test-1",
+		MeshIP:            "10.42.0.5",
+		Endpoint:          "203.0.113.10:51820",
+		LastSeen:          time.Now(),
+		LastHandshake:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		DiscoveredVia:     []string{"dht", "gossip"},
+		RoutableNetworks:  []string{"192.168.1.0/24"},
+		PacketLossPct:     &mockLossPct,
+		Version:           "v0.9.0",
+		NATType:           "cone",
+		Path:              "relay",
+		RelayPubKey:       "relay-pubkey",
+		Probes:            20,
+		ProbesLost:        1,
+		Introducer:        true,
+		IntroducerElected: true,
+		Load:              &IntroducerLoad{Sessions: 3, RelayedPeers: 7, Bps: 1500, Overloaded: true},
 	}
 
 	// Mock peer without hostname (to test fallback behaviour)
@@ -39,8 +53,19 @@ func TestClientServerIntegration(t *testing.T) {
 		PubKey:    "local-pubkey-xyz789",
 		Uptime:    5 * time.Minute,
 		Interface: "wg0",
+		Identity:  "identity-key-abc",
+		MeshName:  "home lab",
 	}
 
+	mockEvents := []*EventData{
+		{Seq: 1, Time: time.Now(), Type: "endpoint_changed", PubKey: mockPeer.WGPubKey,
+			Details: map[string]string{"endpoint": "203.0.113.10:51820"}},
+		{Seq: 2, Time: time.Now(), Type: "endpoint_changed", PubKey: mockPeer.WGPubKey,
+			Details: map[string]string{"endpoint": "5.6.7.8:51820", "prev_endpoint": "203.0.113.10:51820"}},
+	}
+
+	logLevel := "info"
+
 	// Create server
 	config := ServerConfig{
 		SocketPath: socketPath,
@@ -63,6 +88,125 @@ func TestClientServerIntegration(t *testing.T) {
 		GetStatus: func() *StatusData {
 			return mockStatus
 		},
+		GetEvents: func(sinceSeq uint64) []*EventData {
+			var out []*EventData
+			for _, ev := range mockEvents {
+				if ev.Seq > sinceSeq {
+					out = append(out, ev)
+				}
+			}
+			return out
+		},
+		GetEventSubs: func() []*EventSubscriberData {
+			return []*EventSubscriberData{{Name: "roaming", Delivered: 40, Coalesced: 3, Dropped: 2}}
+		},
+		GetRoutes: func() ([]*RouteData, []*RouteConflictData) {
+			return []*RouteData{{Network: "192.168.10.0/24", PubKey: mockPeer.WGPubKey, Standby: []string{mockPeerNoHostname.WGPubKey}}},
+				[]*RouteConflictData{{
+					WinnerNetwork: "192.168.10.0/24", WinnerPubKey: mockPeer.WGPubKey,
+					LoserNetwork: "192.168.10.0/24", LoserPubKey: mockPeerNoHostname.WGPubKey,
+					Dropped: true,
+				}}
+		},
+		GetPeerStats: func() []*PeerStatsData {
+			rates := func(rx1m, rx5m float64) []TrafficRateData {
+				return []TrafficRateData{{Window: time.Minute, RxBps: rx1m}, {Window: 5 * time.Minute, RxBps: rx5m}}
+			}
+			return []*PeerStatsData{
+				{PubKey: mockPeer.WGPubKey, RxBytes: 1000, Since: time.Now(), Rates: rates(10, 500)},
+				{PubKey: mockPeerNoHostname.WGPubKey, RxBytes: 2000, Since: time.Now(), Rates: rates(200, 5)},
+			}
+		},
+		GetSecret: func() string { return "wgmesh://v1/integration-secret" },
+		RotateSecret: func(newSecret string, grace time.Duration, force bool) (*RotationData, error) {
+			if newSecret == "bad" {
+				return nil, fmt.Errorf("secret too short")
+			}
+			if newSecret == "old-peers" && !force {
+				return nil, fmt.Errorf("peers old-node do not support secret rotation")
+			}
+			return &RotationData{
+				NewSecretURI: "wgmesh://v1/" + newSecret,
+				SwitchAt:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC).Add(grace),
+			}, nil
+		},
+		Broadcast: func(text string) (*MessageData, error) {
+			return &MessageData{ID: "msg-1", PubKey: mockPeer.WGPubKey, Text: text, SentAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)}, nil
+		},
+		GetMessages: func() []*MessageData {
+			at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+			return []*MessageData{{ID: "msg-1", PubKey: mockPeer.WGPubKey, Hostname: "node1", Text: "maintenance at 22:00", SentAt: at, ReceivedAt: at.Add(time.Second)}}
+		},
+		GetQuarantine: func() []*QuarantineData {
+			return []*QuarantineData{
+				{PubKey: "impostor-key", MeshIP: "10.0.0.1", Reason: "mesh_ip_pinned", PinnedPubKey: mockPeer.WGPubKey, FirstSeen: time.Now(), LastSeen: time.Now()},
+			}
+		},
+		ApprovePeer: func(pubKey string) error {
+			if pubKey != "impostor-key" {
+				return fmt.Errorf("peer %s is not quarantined", pubKey)
+			}
+			return nil
+		},
+		ForgetPeer: func(pubKey string) error {
+			if pubKey != "retired-key" {
+				return fmt.Errorf("peer %s is not known", pubKey)
+			}
+			return nil
+		},
+		GetPending: func() []*PendingData {
+			return []*PendingData{
+				{PubKey: "newcomer-key", MeshIP: "10.0.0.7", Hostname: "laptop", Identity: "newcomer-identity", FirstSeen: time.Now(), LastSeen: time.Now()},
+			}
+		},
+		GetLAN: func() *LANData {
+			return &LANData{Mode: "multicast", Group: "239.192.1.2:51830", Broadcast: true, Interfaces: []LANInterfaceData{
+				{Name: "eth0", Addresses: []string{"192.168.1.10/24"}, Joined: true, LastHeard: time.Now()},
+				{Name: "wlan0", Error: "no IPv4 address"},
+			}}
+		},
+		GetDHT: func() *DHTData {
+			return &DHTData{NATType: "symmetric", Peers: 5, TargetPeers: 3, AnnounceInterval: 2 * time.Minute, QueryInterval: time.Minute, LastAnnounce: time.Now(), Nodes: 120}
+		},
+		PingPeer: func(peer string) (*PingData, error) {
+			switch peer {
+			case "node1":
+				return &PingData{PubKey: mockPeer.WGPubKey, Hostname: "node1", MeshIP: mockPeer.MeshIP, Path: "direct", RTT: 1500 * time.Microsecond}, nil
+			case "down":
+				return &PingData{PubKey: "down-key", MeshIP: "10.0.0.9", Path: "relay", Error: "no reply"}, nil
+			}
+			return nil, fmt.Errorf("no peer matches %q", peer)
+		},
+		GetPeerRoute: func(peer string) (*PeerRouteData, error) {
+			if peer != "node1" {
+				return nil, fmt.Errorf("no peer matches %q", peer)
+			}
+			return &PeerRouteData{PubKey: mockPeer.WGPubKey, Hostname: "node1", MeshIP: mockPeer.MeshIP, Path: "relay", Installed: true, RelayPubKey: "relay-key", RelayHostname: "intro1"}, nil
+		},
+		ExportState: func() ([]byte, error) {
+			return []byte(`{"version":1,"peers":[{"wg_pubkey":"test-pubkey-abc123"}]}`), nil
+		},
+		ImportState: func(snapshot []byte) (*StateImportData, error) {
+			var snap struct {
+				Version int               `json:"version"`
+				Peers   []json.RawMessage `json:"peers"`
+			}
+			if err := json.Unmarshal(snapshot, &snap); err != nil || snap.Version != 1 {
+				return nil, fmt.Errorf("unsupported snapshot")
+			}
+			return &StateImportData{Imported: len(snap.Peers), Skipped: 0}, nil
+		},
+		GetConfig: func() map[string]string {
+			return map[string]string{"log-level": logLevel, "force-relay": "false"}
+		},
+		SetConfig: func(key, value string) (string, error) {
+			if key != "log-level" {
+				return "", fmt.Errorf("option %q cannot be changed at runtime", key)
+			}
+			old := logLevel
+			logLevel = strings.ToLower(value)
+			return old, nil
+		},
 	}
 
 	server, err := NewServer(config)
@@ -160,6 +304,23 @@ func TestClientServerIntegration(t *testing.T) {
 		if peer["hostname"] != mockPeer.Hostname {
 			t.Errorf("expected hostname %s, got %v", mockPeer.Hostname, peer["hostname"])
 		}
+		if peer["packet_loss_pct"] != mockLossPct {
+			t.Errorf("expected packet_loss_pct %v, got %v", mockLossPct, peer["packet_loss_pct"])
+		}
+		if peer["last_handshake"] != "2026-01-02T03:04:05Z" {
+			t.Errorf("unexpected last_handshake %v", peer["last_handshake"])
+		}
+		if peer["version"] != "v0.9.0" || peer["nat_type"] != "cone" || peer["path"] != "relay" ||
+			peer["relay_pubkey"] != "relay-pubkey" || peer["probes"] != float64(20) || peer["probes_lost"] != float64(1) {
+			t.Errorf("peer details did not round-trip: %v", peer)
+		}
+		load, _ := peer["load"].(map[string]interface{})
+		if load["sessions"] != float64(3) || load["relayed_peers"] != float64(7) || load["bps"] != float64(1500) || load["overloaded"] != true {
+			t.Errorf("introducer load did not round-trip: %v", peer["load"])
+		}
+		if peer["introducer"] != true || peer["introducer_elected"] != true {
+			t.Errorf("elected introducer role did not round-trip: %v", peer)
+		}
 	})
 
 	// Test peers.get for peer without hostname
@@ -221,6 +382,377 @@ func TestClientServerIntegration(t *testing.T) {
 		if status["pubkey"] != mockStatus.PubKey {
 			t.Errorf("expected pubkey %s, got %v", mockStatus.PubKey, status["pubkey"])
 		}
+		if status["identity"] != mockStatus.Identity {
+			t.Errorf("expected identity %s, got %v", mockStatus.Identity, status["identity"])
+		}
+		if status["mesh_name"] != mockStatus.MeshName {
+			t.Errorf("expected mesh_name %s, got %v", mockStatus.MeshName, status["mesh_name"])
+		}
+	})
+
+	// Test events.list
+	t.Run("events.list", func(t *testing.T) {
+		result, err := client.Call("events.list", map[string]interface{}{"since": 1})
+		if err != nil {
+			t.Fatalf("events.list failed: %v", err)
+		}
+
+		events := result.(map[string]interface{})["events"].([]interface{})
+		if len(events) != 1 {
+			t.Fatalf("expected 1 event after seq 1, got %d", len(events))
+		}
+		ev := events[0].(map[string]interface{})
+		if ev["type"] != "endpoint_changed" {
+			t.Errorf("expected type endpoint_changed, got %v", ev["type"])
+		}
+		details := ev["details"].(map[string]interface{})
+		if details["endpoint"] != "5.6.7.8:51820" {
+			t.Errorf("expected endpoint 5.6.7.8:51820, got %v", details["endpoint"])
+		}
+
+		subs := result.(map[string]interface{})["subscribers"].([]interface{})
+		if len(subs) != 1 {
+			t.Fatalf("expected 1 subscriber, got %v", subs)
+		}
+		if sub := subs[0].(map[string]interface{}); sub["name"] != "roaming" || sub["delivered"] != float64(40) || sub["dropped"] != float64(2) {
+			t.Errorf("unexpected subscriber: %v", sub)
+		}
+	})
+
+	// Test routes.list
+	t.Run("routes.list", func(t *testing.T) {
+		result, err := client.Call("routes.list", nil)
+		if err != nil {
+			t.Fatalf("routes.list failed: %v", err)
+		}
+
+		res := result.(map[string]interface{})
+		routes := res["routes"].([]interface{})
+		if len(routes) != 1 || routes[0].(map[string]interface{})["network"] != "192.168.10.0/24" {
+			t.Fatalf("unexpected routes: %v", routes)
+		}
+		if standby, _ := routes[0].(map[string]interface{})["standby"].([]interface{}); len(standby) != 1 {
+			t.Errorf("expected 1 standby gateway, got %v", routes[0])
+		}
+		conflicts := res["conflicts"].([]interface{})
+		if len(conflicts) != 1 {
+			t.Fatalf("expected 1 conflict, got %d", len(conflicts))
+		}
+		c := conflicts[0].(map[string]interface{})
+		if c["loser_pubkey"] != mockPeerNoHostname.WGPubKey || c["dropped"] != true {
+			t.Errorf("unexpected conflict: %v", c)
+		}
+	})
+
+	// Test peers.stats
+	t.Run("peers.stats", func(t *testing.T) {
+		top := func(params map[string]interface{}) []interface{} {
+			t.Helper()
+			result, err := client.Call("peers.stats", params)
+			if err != nil {
+				t.Fatalf("peers.stats failed: %v", err)
+			}
+			return result.(map[string]interface{})["peers"].([]interface{})
+		}
+
+		peers := top(nil)
+		if len(peers) != 2 || peers[0].(map[string]interface{})["pubkey"] != mockPeerNoHostname.WGPubKey {
+			t.Fatalf("expected busiest 1m peer first, got %v", peers)
+		}
+		if rates := peers[0].(map[string]interface{})["rates"].([]interface{}); rates[0].(map[string]interface{})["window_seconds"] != float64(60) {
+			t.Errorf("unexpected rates: %v", rates)
+		}
+
+		peers = top(map[string]interface{}{"window": "5m", "limit": 1})
+		if len(peers) != 1 || peers[0].(map[string]interface{})["pubkey"] != mockPeer.WGPubKey {
+			t.Fatalf("expected busiest 5m peer only, got %v", peers)
+		}
+
+		if _, err := client.Call("peers.stats", map[string]interface{}{"window": "2h"}); err == nil {
+			t.Error("expected error for unsupported window")
+		}
+	})
+
+	// Test mesh.rotate
+	t.Run("mesh.rotate", func(t *testing.T) {
+		result, err := client.Call("mesh.rotate", map[string]interface{}{"new_secret": "next-secret", "grace": "1h"})
+		if err != nil {
+			t.Fatalf("mesh.rotate failed: %v", err)
+		}
+		resultMap := result.(map[string]interface{})
+		if resultMap["new_secret_uri"] != "wgmesh://v1/next-secret" {
+			t.Errorf("unexpected new_secret_uri: %v", resultMap["new_secret_uri"])
+		}
+		if resultMap["switch_at"] != "2026-01-02T04:04:05Z" {
+			t.Errorf("unexpected switch_at: %v", resultMap["switch_at"])
+		}
+
+		if _, err := client.Call("mesh.rotate", map[string]interface{}{"grace": "soon"}); err == nil {
+			t.Error("expected error for invalid grace")
+		}
+		if _, err := client.Call("mesh.rotate", map[string]interface{}{"new_secret": "bad"}); err == nil {
+			t.Error("expected error from daemon")
+		}
+		if _, err := client.Call("mesh.rotate", map[string]interface{}{"new_secret": "old-peers"}); err == nil {
+			t.Error("expected refusal without force")
+		}
+		if _, err := client.Call("mesh.rotate", map[string]interface{}{"new_secret": "old-peers", "force": true}); err != nil {
+			t.Errorf("mesh.rotate with force: %v", err)
+		}
+		if _, err := client.Call("mesh.rotate", map[string]interface{}{"force": "yes"}); err == nil {
+			t.Error("expected error for invalid force")
+		}
+	})
+
+	// Test mesh.broadcast and mesh.messages
+	t.Run("mesh.broadcast", func(t *testing.T) {
+		result, err := client.Call("mesh.broadcast", map[string]interface{}{"text": "maintenance at 22:00"})
+		if err != nil {
+			t.Fatalf("mesh.broadcast failed: %v", err)
+		}
+		resultMap := result.(map[string]interface{})
+		if resultMap["id"] != "msg-1" || resultMap["sent_at"] != "2026-01-02T03:04:05Z" {
+			t.Errorf("unexpected result: %v", resultMap)
+		}
+		if _, err := client.Call("mesh.broadcast", nil); err == nil {
+			t.Error("expected error for missing text")
+		}
+
+		result, err = client.Call("mesh.messages", nil)
+		if err != nil {
+			t.Fatalf("mesh.messages failed: %v", err)
+		}
+		messages := result.(map[string]interface{})["messages"].([]interface{})
+		if len(messages) != 1 {
+			t.Fatalf("expected 1 message, got %d", len(messages))
+		}
+		msg := messages[0].(map[string]interface{})
+		if msg["text"] != "maintenance at 22:00" || msg["hostname"] != "node1" || msg["received_at"] != "2026-01-02T03:04:06Z" {
+			t.Errorf("unexpected message: %v", msg)
+		}
+	})
+
+	// Test peers.quarantine and peers.approve
+	t.Run("peers.quarantine", func(t *testing.T) {
+		result, err := client.Call("peers.quarantine", nil)
+		if err != nil {
+			t.Fatalf("peers.quarantine failed: %v", err)
+		}
+		peers := result.(map[string]interface{})["peers"].([]interface{})
+		if len(peers) != 1 {
+			t.Fatalf("expected 1 quarantined peer, got %d", len(peers))
+		}
+		peer := peers[0].(map[string]interface{})
+		if peer["pubkey"] != "impostor-key" || peer["reason"] != "mesh_ip_pinned" || peer["pinned_pubkey"] != mockPeer.WGPubKey {
+			t.Errorf("unexpected quarantined peer: %v", peer)
+		}
+
+		result, err = client.Call("peers.approve", map[string]interface{}{"pubkey": "impostor-key"})
+		if err != nil {
+			t.Fatalf("peers.approve failed: %v", err)
+		}
+		if approved := result.(map[string]interface{})["approved"]; approved != true {
+			t.Errorf("unexpected approved: %v", approved)
+		}
+		if _, err := client.Call("peers.approve", map[string]interface{}{"pubkey": "unknown"}); err == nil {
+			t.Error("expected error approving a peer that is not quarantined")
+		}
+		if _, err := client.Call("peers.approve", nil); err == nil {
+			t.Error("expected error for missing pubkey")
+		}
+	})
+
+	// Test peers.forget
+	t.Run("peers.forget", func(t *testing.T) {
+		result, err := client.Call("peers.forget", map[string]interface{}{"pubkey": "retired-key"})
+		if err != nil {
+			t.Fatalf("peers.forget failed: %v", err)
+		}
+		if forgotten := result.(map[string]interface{})["forgotten"]; forgotten != true {
+			t.Errorf("unexpected forgotten: %v", forgotten)
+		}
+		if _, err := client.Call("peers.forget", map[string]interface{}{"pubkey": "unknown"}); err == nil {
+			t.Error("expected error forgetting an unknown peer")
+		}
+		if _, err := client.Call("peers.forget", nil); err == nil {
+			t.Error("expected error for missing pubkey")
+		}
+	})
+
+	// Test peers.pending
+	t.Run("peers.pending", func(t *testing.T) {
+		result, err := client.Call("peers.pending", nil)
+		if err != nil {
+			t.Fatalf("peers.pending failed: %v", err)
+		}
+		peers := result.(map[string]interface{})["peers"].([]interface{})
+		if len(peers) != 1 {
+			t.Fatalf("expected 1 pending peer, got %d", len(peers))
+		}
+		peer := peers[0].(map[string]interface{})
+		if peer["pubkey"] != "newcomer-key" || peer["hostname"] != "laptop" || peer["identity"] != "newcomer-identity" {
+			t.Errorf("unexpected pending peer: %v", peer)
+		}
+	})
+
+	// Test discovery.lan
+	t.Run("discovery.lan", func(t *testing.T) {
+		result, err := client.Call("discovery.lan", nil)
+		if err != nil {
+			t.Fatalf("discovery.lan failed: %v", err)
+		}
+		lan := result.(map[string]interface{})
+		if lan["enabled"] != true || lan["group"] != "239.192.1.2:51830" || lan["broadcast"] != true {
+			t.Errorf("unexpected LAN discovery state: %v", lan)
+		}
+		ifaces := lan["interfaces"].([]interface{})
+		if len(ifaces) != 2 {
+			t.Fatalf("expected 2 interfaces, got %d", len(ifaces))
+		}
+		eth0 := ifaces[0].(map[string]interface{})
+		if eth0["name"] != "eth0" || eth0["joined"] != true || eth0["last_heard"] == nil {
+			t.Errorf("unexpected interface: %v", eth0)
+		}
+		if wlan0 := ifaces[1].(map[string]interface{}); wlan0["error"] != "no IPv4 address" {
+			t.Errorf("unexpected interface: %v", wlan0)
+		}
+	})
+
+	// Test discovery.dht
+	t.Run("discovery.dht", func(t *testing.T) {
+		result, err := client.Call("discovery.dht", nil)
+		if err != nil {
+			t.Fatalf("discovery.dht failed: %v", err)
+		}
+		dht := result.(map[string]interface{})
+		if dht["enabled"] != true || dht["nat_type"] != "symmetric" || dht["announce_interval"] != float64(2*time.Minute) || dht["query_interval"] != float64(time.Minute) {
+			t.Errorf("unexpected DHT discovery state: %v", dht)
+		}
+		if lastQuery, _ := dht["last_query"].(string); dht["last_announce"] == nil || lastQuery != "" || dht["nodes"] != float64(120) {
+			t.Errorf("unexpected DHT discovery state: %v", dht)
+		}
+	})
+
+	// Test peers.ping and peers.route
+	t.Run("peers.ping", func(t *testing.T) {
+		result, err := client.Call("peers.ping", map[string]interface{}{"peer": "node1"})
+		if err != nil {
+			t.Fatalf("peers.ping failed: %v", err)
+		}
+		ping := result.(map[string]interface{})
+		if ping["rtt_ms"] != 1.5 || ping["path"] != "direct" || ping["pubkey"] != mockPeer.WGPubKey {
+			t.Errorf("unexpected ping result: %v", ping)
+		}
+
+		result, err = client.Call("peers.ping", map[string]interface{}{"peer": "down"})
+		if err != nil {
+			t.Fatalf("peers.ping failed: %v", err)
+		}
+		ping = result.(map[string]interface{})
+		if _, ok := ping["rtt_ms"]; ok || ping["error"] != "no reply" {
+			t.Errorf("unanswered ping should carry an error and no RTT: %v", ping)
+		}
+
+		if _, err := client.Call("peers.ping", map[string]interface{}{"peer": "unknown"}); err == nil {
+			t.Error("expected error pinging an unknown peer")
+		}
+		if _, err := client.Call("peers.ping", nil); err == nil {
+			t.Error("expected error for missing peer")
+		}
+	})
+
+	t.Run("peers.route", func(t *testing.T) {
+		result, err := client.Call("peers.route", map[string]interface{}{"peer": "node1"})
+		if err != nil {
+			t.Fatalf("peers.route failed: %v", err)
+		}
+		route := result.(map[string]interface{})
+		if route["path"] != "relay" || route["relay_pubkey"] != "relay-key" || route["relay_hostname"] != "intro1" || route["installed"] != true {
+			t.Errorf("unexpected route result: %v", route)
+		}
+		if _, err := client.Call("peers.route", map[string]interface{}{"peer": "unknown"}); err == nil {
+			t.Error("expected error for an unknown peer")
+		}
+	})
+
+	t.Run("state.export and state.import", func(t *testing.T) {
+		snapshot, err := client.Call("state.export", nil)
+		if err != nil {
+			t.Fatalf("state.export failed: %v", err)
+		}
+		snap := snapshot.(map[string]interface{})
+		if snap["version"] != float64(1) || len(snap["peers"].([]interface{})) != 1 {
+			t.Fatalf("unexpected snapshot: %v", snap)
+		}
+
+		result, err := client.Call("state.import", map[string]interface{}{"snapshot": snap})
+		if err != nil {
+			t.Fatalf("state.import failed: %v", err)
+		}
+		if imported := result.(map[string]interface{})["imported"]; imported != float64(1) {
+			t.Errorf("imported = %v, want 1", imported)
+		}
+
+		// A full peer store does not fit the scanner's default 64 KiB line.
+		peers := make([]interface{}, 1000)
+		for i := range peers {
+			peers[i] = map[string]interface{}{"wg_pubkey": fmt.Sprintf("%044d", i), "mesh_ip": "10.0.0.1", "endpoint": "203.0.113.1:51820"}
+		}
+		result, err = client.Call("state.import", map[string]interface{}{"snapshot": map[string]interface{}{"version": 1, "peers": peers}})
+		if err != nil {
+			t.Fatalf("state.import of a large snapshot failed: %v", err)
+		}
+		if imported := result.(map[string]interface{})["imported"]; imported != float64(1000) {
+			t.Errorf("imported = %v, want 1000", imported)
+		}
+
+		if _, err := client.Call("state.import", map[string]interface{}{"snapshot": map[string]interface{}{"version": 2}}); err == nil {
+			t.Error("expected error for an unsupported snapshot")
+		}
+		if _, err := client.Call("state.import", nil); err == nil {
+			t.Error("expected error without a snapshot")
+		}
+	})
+
+	t.Run("config.get and config.set", func(t *testing.T) {
+		result, err := client.Call("config.set", map[string]interface{}{"key": "log-level", "value": "DEBUG"})
+		if err != nil {
+			t.Fatalf("config.set failed: %v", err)
+		}
+		set := result.(map[string]interface{})
+		if set["old"] != "info" || set["value"] != "debug" {
+			t.Errorf("unexpected config.set result: %v", set)
+		}
+
+		result, err = client.Call("config.get", map[string]interface{}{"key": "log-level"})
+		if err != nil {
+			t.Fatalf("config.get failed: %v", err)
+		}
+		options := result.(map[string]interface{})["options"].(map[string]interface{})
+		if len(options) != 1 || options["log-level"] != "debug" {
+			t.Errorf("unexpected config.get result: %v", options)
+		}
+
+		if _, err := client.Call("config.get", map[string]interface{}{"key": "secret"}); err == nil {
+			t.Error("expected error for an unknown option")
+		}
+		if _, err := client.Call("config.set", map[string]interface{}{"key": "force-relay", "value": "true"}); err == nil {
+			t.Error("expected error when the daemon rejects the change")
+		}
+		if _, err := client.Call("config.set", map[string]interface{}{"key": "log-level"}); err == nil {
+			t.Error("expected error for a missing value")
+		}
+	})
+
+	// Test secret.unlock (the test process is the socket owner)
+	t.Run("secret.unlock", func(t *testing.T) {
+		result, err := client.Call("secret.unlock", nil)
+		if err != nil {
+			t.Fatalf("secret.unlock failed: %v", err)
+		}
+		if secret := result.(map[string]interface{})["secret"]; secret != "wgmesh://v1/integration-secret" {
+			t.Errorf("unexpected secret: %v", secret)
+		}
 	})
 
 	// Test invalid method
@@ -0,0 +1,30 @@
+package rpc
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCredentialsSupported reports whether peerCredentials works here.
+const peerCredentialsSupported = true
+
+// peerCredentials returns the credentials of the process on the other end
+// of a Unix socket connection, or nil if they cannot be determined.
+func peerCredentials(conn net.Conn) *PeerCred {
+	uc, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil
+	}
+	raw, err := uc.SyscallConn()
+	if err != nil {
+		return nil
+	}
+	var ucred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		ucred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil || credErr != nil {
+		return nil
+	}
+	return &PeerCred{UID: ucred.Uid, GID: ucred.Gid, PID: ucred.Pid}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package rpc
+
+import "net"
+
+// peerCredentialsSupported reports whether peerCredentials works here.
+const peerCredentialsSupported = false
+
+// peerCredentials is not implemented on this platform; access control falls
+// back to the socket's file permissions.
+func peerCredentials(conn net.Conn) *PeerCred {
+	return nil
+}
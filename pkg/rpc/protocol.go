@@ -12,6 +12,7 @@ type Request struct {
 	Method  string                 `json:"method"`
 	Params  map[string]interface{} `json:"params,omitempty"`
 	ID      interface{}            `json:"id"`
+	Token   string                 `json:"token,omitempty"` // required on the TCP listener
 }
 
 // Response represents a JSON-RPC 2.0 response
@@ -35,18 +36,59 @@ const (
 	ErrCodeMethodNotFound = -32601
 	ErrCodeInvalidParams  = -32602
 	ErrCodeInternalError  = -32603
+
+	// ErrCodeUnauthorized is an implementation-defined server error for
+	// callers that may not use a method.
+	ErrCodeUnauthorized = -32001
 )
 
+// MinTCPTokenLength is the shortest token accepted for the TCP listener.
+const MinTCPTokenLength = 16
+
+// MaxRequestSize bounds one request line. It leaves room for a state.import
+// snapshot of a full peer store.
+const MaxRequestSize = 4 << 20
+
 // PeerInfo represents peer information in RPC responses
 type PeerInfo struct {
-	PubKey           string   `json:"pubkey"`
-	Hostname         string   `json:"hostname,omitempty"`
-	MeshIP           string   `json:"mesh_ip"`
-	Endpoint         string   `json:"endpoint"`
-	LastSeen         string   `json:"last_seen"` // ISO 8601 format
-	DiscoveredVia    []string `json:"discovered_via"`
-	RoutableNetworks []string `json:"routable_networks,omitempty"`
-	LatencyMs        *float64 `json:"latency_ms,omitempty"`
+	PubKey            string          `json:"pubkey"`
+	Hostname          string          `json:"hostname,omitempty"`
+	MeshIP            string          `json:"mesh_ip"`
+	MeshIPv6          string          `json:"mesh_ipv6,omitempty"`
+	Endpoint          string          `json:"endpoint"`
+	ControlEndpoint   string          `json:"control_endpoint,omitempty"` // the peer's exchange listener
+	EndpointMethod    string          `json:"endpoint_method,omitempty"`  // discovery method that set Endpoint
+	Candidates        []string        `json:"candidates,omitempty"`       // other endpoints the peer announced
+	LastSeen          string          `json:"last_seen"`                  // ISO 8601 format
+	LastHandshake     string          `json:"last_handshake,omitempty"`   // ISO 8601; empty before the first handshake
+	DiscoveredVia     []string        `json:"discovered_via"`
+	RoutableNetworks  []string        `json:"routable_networks,omitempty"`
+	LatencyMs         *float64        `json:"latency_ms,omitempty"`
+	PacketLossPct     *float64        `json:"packet_loss_pct,omitempty"`
+	Probes            int             `json:"probes"`      // mesh probes in the loss window
+	ProbesLost        int             `json:"probes_lost"` // of which unanswered
+	Installed         bool            `json:"installed"`   // in WireGuard, not just known (see --max-installed-peers)
+	Introducer        bool            `json:"introducer,omitempty"`
+	IntroducerElected bool            `json:"introducer_elected,omitempty"` // by election rather than configuration
+	Version           string          `json:"version,omitempty"`            // wgmesh version the peer announces
+	Revision          int             `json:"protocol_revision,omitempty"`  // protocol revision the peer announces
+	Capabilities      []string        `json:"capabilities,omitempty"`       // optional protocol features the peer implements
+	Compatibility     string          `json:"compatibility,omitempty"`      // ok, legacy (no revision announced) or incompatible
+	NATType           string          `json:"nat_type,omitempty"`           // none, cone, symmetric or unknown
+	Path              string          `json:"path,omitempty"`               // direct, direct-lan or relay
+	RelayPubKey       string          `json:"relay_pubkey,omitempty"`
+	Identity          string          `json:"identity,omitempty"` // Ed25519 key that signs the peer's announcements
+	Load              *IntroducerLoad `json:"load,omitempty"`     // reported by introducers
+
+	Tags map[string]string `json:"tags,omitempty"` // operator-assigned labels, e.g. role=db
+}
+
+// IntroducerLoad is the load an introducer peer reports.
+type IntroducerLoad struct {
+	Sessions     int     `json:"sessions"`      // rendezvous sessions in progress
+	RelayedPeers int     `json:"relayed_peers"` // peers relaying through it
+	Bps          float64 `json:"bps"`           // WireGuard throughput, bytes per second
+	Overloaded   bool    `json:"overloaded"`    // over the limits; skipped for new rendezvous and relays
 }
 
 // PeersListResult represents the result of peers.list
@@ -68,6 +110,264 @@ type DaemonStatusResult struct {
 	Uptime    time.Duration `json:"uptime"`
 	Interface string        `json:"interface"`
 	Version   string        `json:"version"`
+	Identity  string        `json:"identity,omitempty"` // identity key named in --approvers
+
+	ClockSkew      time.Duration `json:"clock_skew,omitempty"`
+	ClockSkewPeers int           `json:"clock_skew_peers,omitempty"`
+	MeshName       string        `json:"mesh_name,omitempty"`
+}
+
+// TrafficRateInfo represents a peer's average throughput over one window
+type TrafficRateInfo struct {
+	WindowSeconds int     `json:"window_seconds"`
+	RxBps         float64 `json:"rx_bps"` // bytes per second
+	TxBps         float64 `json:"tx_bps"`
+}
+
+// PeerStatsInfo represents per-peer traffic accounting in RPC responses
+type PeerStatsInfo struct {
+	PubKey   string             `json:"pubkey"`
+	Hostname string             `json:"hostname,omitempty"`
+	MeshIP   string             `json:"mesh_ip,omitempty"`
+	RxBytes  uint64             `json:"rx_bytes"`
+	TxBytes  uint64             `json:"tx_bytes"`
+	Since    string             `json:"since"` // ISO 8601 format
+	Rates    []*TrafficRateInfo `json:"rates"`
+}
+
+// PeersStatsResult represents the result of peers.stats
+type PeersStatsResult struct {
+	Peers []*PeerStatsInfo `json:"peers"`
+}
+
+// EventInfo represents a daemon event in RPC responses
+type EventInfo struct {
+	Seq     uint64            `json:"seq"`
+	Time    string            `json:"time"` // ISO 8601 format
+	Type    string            `json:"type"`
+	PubKey  string            `json:"pubkey,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// EventSubscriberInfo represents a subscriber to the daemon's peer events
+// in RPC responses. Events for a peer that already has one queued are
+// merged into it; when the queue is full they are dropped.
+type EventSubscriberInfo struct {
+	Name      string `json:"name"`
+	Queued    int    `json:"queued"`
+	Delivered uint64 `json:"delivered"`
+	Coalesced uint64 `json:"coalesced"`
+	Dropped   uint64 `json:"dropped"`
+}
+
+// EventsListResult represents the result of events.list
+type EventsListResult struct {
+	Events      []*EventInfo           `json:"events"`
+	Subscribers []*EventSubscriberInfo `json:"subscribers,omitempty"` // peer event bus delivery counters
+}
+
+// QuarantinedPeerInfo represents a peer held back by identity pinning in RPC responses
+type QuarantinedPeerInfo struct {
+	PubKey       string `json:"pubkey"`
+	Hostname     string `json:"hostname,omitempty"`
+	MeshIP       string `json:"mesh_ip"`
+	Endpoint     string `json:"endpoint,omitempty"`
+	Reason       string `json:"reason"`        // mesh_ip_pinned or hostname_pinned
+	PinnedPubKey string `json:"pinned_pubkey"` // key the mesh IP or hostname is pinned to
+	FirstSeen    string `json:"first_seen"`    // ISO 8601 format
+	LastSeen     string `json:"last_seen"`     // ISO 8601 format
+}
+
+// PeersQuarantineResult represents the result of peers.quarantine
+type PeersQuarantineResult struct {
+	Peers []*QuarantinedPeerInfo `json:"peers"`
+}
+
+// PeersApproveResult represents the result of peers.approve
+type PeersApproveResult struct {
+	Approved bool `json:"approved"`
+}
+
+// PeersForgetResult represents the result of peers.forget
+type PeersForgetResult struct {
+	Forgotten bool `json:"forgotten"`
+}
+
+// PendingPeerInfo represents a peer waiting for approval in RPC responses
+type PendingPeerInfo struct {
+	PubKey    string `json:"pubkey"`
+	Hostname  string `json:"hostname,omitempty"`
+	MeshIP    string `json:"mesh_ip"`
+	Endpoint  string `json:"endpoint,omitempty"`
+	Identity  string `json:"identity,omitempty"` // empty until the peer's own signed announcement arrives
+	FirstSeen string `json:"first_seen"`         // ISO 8601 format
+	LastSeen  string `json:"last_seen"`          // ISO 8601 format
+}
+
+// PeersPendingResult represents the result of peers.pending
+type PeersPendingResult struct {
+	Peers []*PendingPeerInfo `json:"peers"`
+}
+
+// PeersPingResult represents the result of peers.ping
+type PeersPingResult struct {
+	PubKey   string   `json:"pubkey"`
+	Hostname string   `json:"hostname,omitempty"`
+	MeshIP   string   `json:"mesh_ip"`
+	Path     string   `json:"path"`             // direct, direct-lan or relay
+	RTTMs    *float64 `json:"rtt_ms,omitempty"` // nil when the peer did not answer
+	Error    string   `json:"error,omitempty"`  // why the peer did not answer
+}
+
+// PeersRouteResult represents the result of peers.route
+type PeersRouteResult struct {
+	PubKey        string `json:"pubkey"`
+	Hostname      string `json:"hostname,omitempty"`
+	MeshIP        string `json:"mesh_ip"`
+	Endpoint      string `json:"endpoint,omitempty"`
+	Path          string `json:"path"` // direct, direct-lan or relay
+	Installed     bool   `json:"installed"`
+	RelayPubKey   string `json:"relay_pubkey,omitempty"`
+	RelayHostname string `json:"relay_hostname,omitempty"`
+	RelayMeshIP   string `json:"relay_mesh_ip,omitempty"`
+	RelayEndpoint string `json:"relay_endpoint,omitempty"`
+}
+
+// StateImportResult represents the result of state.import
+type StateImportResult struct {
+	Imported int `json:"imported"`
+	Skipped  int `json:"skipped"` // own key, no key, or a mesh IP outside the mesh subnet
+}
+
+// ConfigGetResult represents the result of config.get
+type ConfigGetResult struct {
+	Options map[string]string `json:"options"`
+}
+
+// ConfigSetResult represents the result of config.set
+type ConfigSetResult struct {
+	Key   string `json:"key"`
+	Old   string `json:"old"`
+	Value string `json:"value"` // as normalized by the daemon
+}
+
+// RouteInfo represents a mesh route in RPC responses
+type RouteInfo struct {
+	Network string   `json:"network"`
+	PubKey  string   `json:"pubkey"`
+	Standby []string `json:"standby,omitempty"` // other gateways for the same network
+}
+
+// RouteConflictInfo represents overlapping route advertisements in RPC responses
+type RouteConflictInfo struct {
+	WinnerNetwork string `json:"winner_network"`
+	WinnerPubKey  string `json:"winner_pubkey"`
+	LoserNetwork  string `json:"loser_network"`
+	LoserPubKey   string `json:"loser_pubkey"`
+	Dropped       bool   `json:"dropped"`
+}
+
+// RoutesListResult represents the result of routes.list
+type RoutesListResult struct {
+	Routes    []*RouteInfo         `json:"routes"`
+	Conflicts []*RouteConflictInfo `json:"conflicts"`
+}
+
+// MeshRotateResult represents the result of mesh.rotate
+type MeshRotateResult struct {
+	NewSecretURI string `json:"new_secret_uri"`
+	SwitchAt     string `json:"switch_at"` // ISO 8601 format
+}
+
+// MeshBroadcastResult represents the result of mesh.broadcast
+type MeshBroadcastResult struct {
+	ID     string `json:"id"`
+	SentAt string `json:"sent_at"` // ISO 8601 format
+}
+
+// MessageInfo represents an operator broadcast in RPC responses
+type MessageInfo struct {
+	ID         string `json:"id"`
+	PubKey     string `json:"pubkey"` // node that sent it
+	Hostname   string `json:"hostname,omitempty"`
+	Text       string `json:"text"`
+	SentAt     string `json:"sent_at"`     // ISO 8601 format
+	ReceivedAt string `json:"received_at"` // ISO 8601 format
+}
+
+// MeshMessagesResult represents the result of mesh.messages
+type MeshMessagesResult struct {
+	Messages []*MessageInfo `json:"messages"`
+}
+
+// SecretUnlockResult represents the result of secret.unlock
+type SecretUnlockResult struct {
+	Secret string `json:"secret"`
+}
+
+// DaemonReadyResult represents the result of daemon.ready
+type DaemonReadyResult struct {
+	Ready       bool   `json:"ready"`
+	InterfaceUp bool   `json:"interface_up"`
+	Peers       int    `json:"peers"` // peers with a recent handshake
+	MinPeers    int    `json:"min_peers"`
+	Reason      string `json:"reason,omitempty"` // why the mesh is not ready
+}
+
+// ResourceInfo represents one watched resource in daemon.resources
+type ResourceInfo struct {
+	Name     string `json:"name"`
+	Used     int    `json:"used"`
+	Limit    int    `json:"limit"` // 0 = not tracked
+	Pressure bool   `json:"pressure"`
+}
+
+// DaemonResourcesResult represents the result of daemon.resources
+type DaemonResourcesResult struct {
+	Pressure  bool            `json:"pressure"` // any resource is under pressure
+	Resources []*ResourceInfo `json:"resources"`
+}
+
+// LANInterfaceInfo represents an interface LAN discovery runs on in RPC
+// responses
+type LANInterfaceInfo struct {
+	Name      string   `json:"name"`
+	Addresses []string `json:"addresses,omitempty"`
+	Joined    bool     `json:"joined"`               // member of the multicast group
+	Error     string   `json:"error,omitempty"`      // why the interface is not used
+	LastHeard string   `json:"last_heard,omitempty"` // ISO 8601 format; last peer announcement received on it
+}
+
+// DiscoveryLANResult represents the result of discovery.lan
+type DiscoveryLANResult struct {
+	Enabled    bool                `json:"enabled"`
+	Mode       string              `json:"mode,omitempty"`  // multicast or mdns
+	Group      string              `json:"group,omitempty"` // multicast group and port
+	Broadcast  bool                `json:"broadcast"`
+	Interfaces []*LANInterfaceInfo `json:"interfaces"`
+}
+
+// DiscoveryDHTResult represents the result of discovery.dht
+type DiscoveryDHTResult struct {
+	Enabled          bool          `json:"enabled"`
+	NATType          string        `json:"nat_type,omitempty"`
+	Peers            int           `json:"peers"`
+	TargetPeers      int           `json:"target_peers"`                // below this, announces and queries are more frequent
+	AnnounceInterval time.Duration `json:"announce_interval,omitempty"` // nanoseconds
+	QueryInterval    time.Duration `json:"query_interval,omitempty"`    // nanoseconds
+	LastAnnounce     string        `json:"last_announce,omitempty"`     // ISO 8601 format
+	LastQuery        string        `json:"last_query,omitempty"`        // ISO 8601 format
+	Nodes            int           `json:"nodes"`                       // DHT routing table size
+}
+
+// DaemonLeaveResult represents the result of daemon.leave
+type DaemonLeaveResult struct {
+	Leaving bool `json:"leaving"`
+}
+
+// DaemonRestartResult represents the result of daemon.restart
+type DaemonRestartResult struct {
+	Restarting bool `json:"restarting"`
 }
 
 // DaemonPingResult represents the result of daemon.ping
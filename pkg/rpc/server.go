@@ -3,34 +3,233 @@ package rpc
 import (
 	"bufio"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/rpc/daemonpb"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/tracing"
 )
 
 // PeerData represents peer information for RPC
 type PeerData struct {
-	WGPubKey         string
-	Hostname         string
-	MeshIP           string
-	Endpoint         string
-	LastSeen         time.Time
-	DiscoveredVia    []string
-	RoutableNetworks []string
-	LatencyMs        *float64
+	WGPubKey          string
+	Hostname          string
+	MeshIP            string
+	MeshIPv6          string
+	Endpoint          string
+	ControlEndpoint   string
+	EndpointMethod    string
+	Candidates        []string
+	LastSeen          time.Time
+	LastHandshake     time.Time // zero before the first handshake
+	DiscoveredVia     []string
+	RoutableNetworks  []string
+	LatencyMs         *float64
+	PacketLossPct     *float64
+	Probes            int
+	ProbesLost        int
+	Installed         bool
+	Introducer        bool
+	IntroducerElected bool
+	Version           string
+	Revision          int      // protocol revision; 0 for peers predating revisions
+	Capabilities      []string // optional protocol features, e.g. "rotate"
+	Compatibility     string   // ok, legacy or incompatible
+	NATType           string
+	Path              string
+	RelayPubKey       string
+	Identity          string
+	Load              *IntroducerLoad
+	Tags              map[string]string
 }
 
 // StatusData represents daemon status for RPC
 type StatusData struct {
-	MeshIP    string
+	MeshIP         string
+	PubKey         string
+	Uptime         time.Duration
+	Interface      string
+	Identity       string        // identity key; empty without one
+	ClockSkew      time.Duration // local clock minus the mesh's; 0 below the significance threshold
+	ClockSkewPeers int           // peers the estimate is based on
+	MeshName       string        // display name of the mesh; empty when unnamed
+}
+
+// EventData represents a daemon event for RPC
+type EventData struct {
+	Seq     uint64
+	Time    time.Time
+	Type    string
+	PubKey  string
+	Details map[string]string
+}
+
+// EventSubscriberData represents a subscriber to the daemon's peer events
+// for RPC
+type EventSubscriberData struct {
+	Name      string
+	Queued    int
+	Delivered uint64
+	Coalesced uint64
+	Dropped   uint64
+}
+
+// MessageData represents an operator broadcast for RPC
+type MessageData struct {
+	ID         string
+	PubKey     string
+	Hostname   string
+	Text       string
+	SentAt     time.Time
+	ReceivedAt time.Time
+}
+
+// RouteData represents a mesh route for RPC
+type RouteData struct {
+	Network string
+	PubKey  string
+	Standby []string
+}
+
+// RouteConflictData represents overlapping route advertisements for RPC
+type RouteConflictData struct {
+	WinnerNetwork string
+	WinnerPubKey  string
+	LoserNetwork  string
+	LoserPubKey   string
+	Dropped       bool
+}
+
+// TrafficRateData is a peer's average throughput over one window for RPC
+type TrafficRateData struct {
+	Window time.Duration
+	RxBps  float64
+	TxBps  float64
+}
+
+// PeerStatsData represents per-peer traffic accounting for RPC
+type PeerStatsData struct {
+	PubKey   string
+	Hostname string
+	MeshIP   string
+	RxBytes  uint64
+	TxBytes  uint64
+	Since    time.Time
+	Rates    []TrafficRateData
+}
+
+// QuarantineData represents a peer held back by identity pinning for RPC
+type QuarantineData struct {
+	PubKey       string
+	Hostname     string
+	MeshIP       string
+	Endpoint     string
+	Reason       string
+	PinnedPubKey string
+	FirstSeen    time.Time
+	LastSeen     time.Time
+}
+
+// PendingData represents a peer waiting for approval for RPC
+type PendingData struct {
 	PubKey    string
-	Uptime    time.Duration
-	Interface string
+	Hostname  string
+	MeshIP    string
+	Endpoint  string
+	Identity  string
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// LANData represents LAN discovery for RPC
+type LANData struct {
+	Mode       string
+	Group      string
+	Broadcast  bool
+	Interfaces []LANInterfaceData
+}
+
+// LANInterfaceData represents an interface LAN discovery runs on for RPC
+type LANInterfaceData struct {
+	Name      string
+	Addresses []string
+	Joined    bool
+	Error     string
+	LastHeard time.Time
+}
+
+// DHTData represents DHT discovery for RPC
+type DHTData struct {
+	NATType          string
+	Peers            int
+	TargetPeers      int
+	AnnounceInterval time.Duration
+	QueryInterval    time.Duration
+	LastAnnounce     time.Time
+	LastQuery        time.Time
+	Nodes            int
+}
+
+// PingData represents the outcome of one mesh ping for RPC
+type PingData struct {
+	PubKey   string
+	Hostname string
+	MeshIP   string
+	Path     string
+	RTT      time.Duration
+	Error    string
+}
+
+// PeerRouteData describes the path to a peer for RPC
+type PeerRouteData struct {
+	PubKey        string
+	Hostname      string
+	MeshIP        string
+	Endpoint      string
+	Path          string
+	Installed     bool
+	RelayPubKey   string
+	RelayHostname string
+	RelayMeshIP   string
+	RelayEndpoint string
+}
+
+// ReadinessData is what daemon.ready decides readiness from
+type ReadinessData struct {
+	InterfaceUp bool
+	Peers       int // peers with a recent WireGuard handshake
+}
+
+// ResourceData is one resource the daemon watches against a limit
+type ResourceData struct {
+	Name     string // "fds", "goroutines" or "probe-sessions"
+	Used     int
+	Limit    int  // 0 = not tracked on this platform
+	Pressure bool // past the daemon's pressure threshold
+}
+
+// StateImportData summarizes a peer store import for RPC
+type StateImportData struct {
+	Imported int
+	Skipped  int
+}
+
+// RotationData describes a started secret rotation for RPC
+type RotationData struct {
+	NewSecretURI string
+	SwitchAt     time.Time
 }
 
 // ServerConfig configures the RPC server with callback functions
@@ -41,12 +240,69 @@ type ServerConfig struct {
 	GetPeer       func(pubKey string) (*PeerData, bool)
 	GetPeerCounts func() (active, total, dead int)
 	GetStatus     func() *StatusData
+	GetEvents     func(sinceSeq uint64) []*EventData                                             // optional; events.list is unavailable without it
+	GetEventSubs  func() []*EventSubscriberData                                                  // optional; events.list leaves out subscribers without it
+	GetRoutes     func() ([]*RouteData, []*RouteConflictData)                                    // optional; routes.list is unavailable without it
+	GetPeerStats  func() []*PeerStatsData                                                        // optional; peers.stats is unavailable without it
+	RotateSecret  func(newSecret string, grace time.Duration, force bool) (*RotationData, error) // optional; mesh.rotate is unavailable without it
+	Broadcast     func(text string) (*MessageData, error)                                        // optional; mesh.broadcast is unavailable without it
+	GetMessages   func() []*MessageData                                                          // optional; mesh.messages is unavailable without it
+	GetSecret     func() string                                                                  // optional; secret.unlock is unavailable without it
+	GetQuarantine func() []*QuarantineData                                                       // optional; peers.quarantine is unavailable without it
+	ApprovePeer   func(pubKey string) error                                                      // optional; peers.approve is unavailable without it
+	ForgetPeer    func(pubKey string) error                                                      // optional; peers.forget is unavailable without it
+	GetPending    func() []*PendingData                                                          // optional; peers.pending is unavailable without it
+	PingPeer      func(peer string) (*PingData, error)                                           // optional; peers.ping is unavailable without it
+	GetPeerRoute  func(peer string) (*PeerRouteData, error)                                      // optional; peers.route is unavailable without it
+	ExportState   func() ([]byte, error)                                                         // optional; state.export is unavailable without it
+	ImportState   func(snapshot []byte) (*StateImportData, error)                                // optional; state.import is unavailable without it
+	GetConfig     func() map[string]string                                                       // optional; config.get is unavailable without it
+	SetConfig     func(key, value string) (old string, err error)                                // optional; config.set is unavailable without it
+	Leave         func() error                                                                   // optional; daemon.leave is unavailable without it
+	Restart       func() error                                                                   // optional; daemon.restart is unavailable without it
+	GetReadiness  func() *ReadinessData                                                          // optional; daemon.ready is unavailable without it
+	GetResources  func() []*ResourceData                                                         // optional; daemon.resources is unavailable without it
+	GetLAN        func() *LANData                                                                // optional; discovery.lan is unavailable without it; nil = LAN discovery off
+	GetDHT        func() *DHTData                                                                // optional; discovery.dht is unavailable without it; nil = no DHT discovery
+
+	// AllowUIDs and AllowGIDs open the socket to other local users. When
+	// either is set the socket is world-writable and callers are checked
+	// with SO_PEERCRED: root, the daemon's own user, a listed UID or a
+	// listed primary GID. Linux only.
+	AllowUIDs []uint32
+	AllowGIDs []uint32
+
+	// TCPPort, when set, also serves RPC over TCP on the node's mesh IP so
+	// it can be queried across the mesh. Every request must carry TCPToken,
+	// and the localOnlyMethods are never answered over TCP.
+	TCPPort  int
+	TCPToken string
+
+	// LegacyJSON also answers line-delimited JSON-RPC next to gRPC, for
+	// clients older than the gRPC protocol. Deprecated; it goes away in the
+	// next release.
+	LegacyJSON bool
+}
+
+// PeerCred identifies the process on the other end of a socket connection.
+type PeerCred struct {
+	UID uint32
+	GID uint32
+	PID int32
 }
 
 // Server implements an RPC server using Unix domain sockets
 type Server struct {
 	socketPath      string
 	listener        net.Listener
+	tcpListener     net.Listener
+	allowUIDs       []uint32
+	allowGIDs       []uint32
+	tcpPort         int
+	tcpToken        string
+	legacyJSON      bool
+	grpcServer      *grpc.Server
+	grpcConns       *connListener
 	version         string
 	ctx             context.Context
 	cancel          context.CancelFunc
@@ -54,6 +310,38 @@ type Server struct {
 	getPeerFn       func(pubKey string) (*PeerData, bool)
 	getPeerCountsFn func() (active, total, dead int)
 	getStatusFn     func() *StatusData
+	getEventsFn     func(sinceSeq uint64) []*EventData
+	getEventSubsFn  func() []*EventSubscriberData
+	getRoutesFn     func() ([]*RouteData, []*RouteConflictData)
+	getPeerStatsFn  func() []*PeerStatsData
+	rotateSecretFn  func(newSecret string, grace time.Duration, force bool) (*RotationData, error)
+	broadcastFn     func(text string) (*MessageData, error)
+	getMessagesFn   func() []*MessageData
+	getSecretFn     func() string
+	getQuarantineFn func() []*QuarantineData
+	approvePeerFn   func(pubKey string) error
+	forgetPeerFn    func(pubKey string) error
+	getPendingFn    func() []*PendingData
+	pingPeerFn      func(peer string) (*PingData, error)
+	getPeerRouteFn  func(peer string) (*PeerRouteData, error)
+	exportStateFn   func() ([]byte, error)
+	importStateFn   func(snapshot []byte) (*StateImportData, error)
+	getConfigFn     func() map[string]string
+	setConfigFn     func(key, value string) (string, error)
+	leaveFn         func() error
+	restartFn       func() error
+	getReadinessFn  func() *ReadinessData
+	getResourcesFn  func() []*ResourceData
+	getLANFn        func() *LANData
+	getDHTFn        func() *DHTData
+}
+
+// localOnlyMethods are never answered over TCP: one hands out the mesh
+// secret, the others stop the daemon.
+var localOnlyMethods = map[string]bool{
+	"secret.unlock":  true,
+	"daemon.leave":   true,
+	"daemon.restart": true,
 }
 
 // NewServer creates a new RPC server
@@ -65,6 +353,15 @@ func NewServer(config ServerConfig) (*Server, error) {
 	if config.GetPeers == nil || config.GetPeer == nil || config.GetPeerCounts == nil || config.GetStatus == nil {
 		return nil, fmt.Errorf("all callback functions are required")
 	}
+	if (len(config.AllowUIDs) > 0 || len(config.AllowGIDs) > 0) && !peerCredentialsSupported {
+		return nil, fmt.Errorf("allowing other users on the RPC socket needs SO_PEERCRED, which this platform lacks")
+	}
+	if config.TCPPort < 0 || config.TCPPort > 65535 {
+		return nil, fmt.Errorf("invalid RPC TCP port %d", config.TCPPort)
+	}
+	if config.TCPPort > 0 && len(config.TCPToken) < MinTCPTokenLength {
+		return nil, fmt.Errorf("RPC over TCP requires a token of at least %d characters", MinTCPTokenLength)
+	}
 
 	// Remove existing socket if it exists (handles race condition by ignoring ENOENT)
 	if err := os.Remove(config.SocketPath); err != nil && !os.IsNotExist(err) {
@@ -87,6 +384,11 @@ func NewServer(config ServerConfig) (*Server, error) {
 
 	s := &Server{
 		socketPath:      config.SocketPath,
+		allowUIDs:       config.AllowUIDs,
+		allowGIDs:       config.AllowGIDs,
+		tcpPort:         config.TCPPort,
+		tcpToken:        config.TCPToken,
+		legacyJSON:      config.LegacyJSON,
 		version:         config.Version,
 		ctx:             ctx,
 		cancel:          cancel,
@@ -94,6 +396,30 @@ func NewServer(config ServerConfig) (*Server, error) {
 		getPeerFn:       config.GetPeer,
 		getPeerCountsFn: config.GetPeerCounts,
 		getStatusFn:     config.GetStatus,
+		getEventsFn:     config.GetEvents,
+		getEventSubsFn:  config.GetEventSubs,
+		getRoutesFn:     config.GetRoutes,
+		getPeerStatsFn:  config.GetPeerStats,
+		rotateSecretFn:  config.RotateSecret,
+		broadcastFn:     config.Broadcast,
+		getMessagesFn:   config.GetMessages,
+		getSecretFn:     config.GetSecret,
+		getQuarantineFn: config.GetQuarantine,
+		approvePeerFn:   config.ApprovePeer,
+		forgetPeerFn:    config.ForgetPeer,
+		getPendingFn:    config.GetPending,
+		pingPeerFn:      config.PingPeer,
+		getPeerRouteFn:  config.GetPeerRoute,
+		exportStateFn:   config.ExportState,
+		importStateFn:   config.ImportState,
+		getConfigFn:     config.GetConfig,
+		setConfigFn:     config.SetConfig,
+		leaveFn:         config.Leave,
+		restartFn:       config.Restart,
+		getReadinessFn:  config.GetReadiness,
+		getResourcesFn:  config.GetResources,
+		getLANFn:        config.GetLAN,
+		getDHTFn:        config.GetDHT,
 	}
 
 	return s, nil
@@ -107,24 +433,52 @@ func (s *Server) Start() error {
 	}
 	s.listener = listener
 
-	// Set socket permissions to 0600 (owner only)
-	if err := os.Chmod(s.socketPath, 0600); err != nil {
+	// Set socket permissions to 0600 (owner only), unless other users are
+	// allowed in; peer credentials are checked on every connection then.
+	mode := os.FileMode(0600)
+	if len(s.allowUIDs) > 0 || len(s.allowGIDs) > 0 {
+		mode = 0666
+	}
+	if err := os.Chmod(s.socketPath, mode); err != nil {
 		s.listener.Close()
 		return fmt.Errorf("failed to set socket permissions: %w", err)
 	}
 
+	if s.tcpPort > 0 {
+		status := s.getStatusFn()
+		if status == nil || status.MeshIP == "" {
+			s.listener.Close()
+			return fmt.Errorf("cannot listen on TCP: mesh IP unknown")
+		}
+		addr := net.JoinHostPort(status.MeshIP, fmt.Sprint(s.tcpPort))
+		tcpListener, err := net.Listen("tcp", addr)
+		if err != nil {
+			s.listener.Close()
+			return fmt.Errorf("failed to listen on %s: %w", addr, err)
+		}
+		s.tcpListener = tcpListener
+		log.Printf("RPC server listening on tcp %s (token required)", addr)
+		go s.acceptLoop(tcpListener, true)
+	}
+
+	s.grpcServer = newGRPCServer(s.tcpToken)
+	daemonpb.RegisterDaemonServer(s.grpcServer, &grpcService{s: s})
+	s.grpcConns = newConnListener()
+	go s.grpcServer.Serve(s.grpcConns)
+
 	log.Printf("RPC server listening on %s", s.socketPath)
 
 	// Accept connections
-	go s.acceptLoop()
+	go s.acceptLoop(s.listener, false)
 
 	return nil
 }
 
-// acceptLoop accepts incoming connections
-func (s *Server) acceptLoop() {
+// acceptLoop accepts incoming connections. Connections on the TCP listener
+// are remote: they authenticate with the token instead of peer credentials.
+func (s *Server) acceptLoop(listener net.Listener, remote bool) {
 	for {
-		conn, err := s.listener.Accept()
+		conn, err := listener.Accept()
 		if err != nil {
 			select {
 			case <-s.ctx.Done():
@@ -135,16 +489,44 @@ func (s *Server) acceptLoop() {
 			}
 		}
 
-		go s.handleConnection(conn)
+		go s.handleConnection(conn, remote)
 	}
 }
 
-// handleConnection handles a single connection
-func (s *Server) handleConnection(conn net.Conn) {
+// handleConnection handles a single connection. gRPC connections are
+// passed on to the gRPC server; the rest are served as JSON-RPC.
+func (s *Server) handleConnection(conn net.Conn, remote bool) {
+	caller := callerInfo{remote: remote}
+	if !remote {
+		caller.cred = peerCredentials(conn)
+		caller.denied = s.authorizeLocal(caller.cred)
+	}
+	sc, isGRPC, err := sniffConn(conn, caller)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	if isGRPC {
+		s.grpcConns.add(sc)
+		return
+	}
 	defer conn.Close()
 
-	scanner := bufio.NewScanner(conn)
+	scanner := bufio.NewScanner(sc)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxRequestSize)
 	writer := bufio.NewWriter(conn)
+	if caller.denied != nil {
+		s.writeResponse(writer, &Response{JSONRPC: "2.0", Error: caller.denied})
+		return
+	}
+	if !s.legacyJSON {
+		s.writeResponse(writer, &Response{JSONRPC: "2.0", Error: &Error{
+			Code:    ErrCodeInvalidRequest,
+			Message: "JSON-RPC is disabled; use a gRPC client or start the daemon with --rpc-legacy-json",
+		}})
+		return
+	}
+	cred := caller.cred
 
 	for scanner.Scan() {
 		line := scanner.Bytes()
@@ -164,8 +546,28 @@ func (s *Server) handleConnection(conn net.Conn) {
 			continue
 		}
 
+		if remote {
+			if subtle.ConstantTimeCompare([]byte(req.Token), []byte(s.tcpToken)) != 1 {
+				// Drop the connection so tokens cannot be guessed over one.
+				s.writeResponse(writer, &Response{JSONRPC: "2.0", Error: &Error{Code: ErrCodeUnauthorized, Message: "invalid token"}, ID: req.ID})
+				return
+			}
+			if localOnlyMethods[req.Method] {
+				s.writeResponse(writer, &Response{JSONRPC: "2.0", Error: &Error{Code: ErrCodeUnauthorized, Message: req.Method + " is not available over TCP"}, ID: req.ID})
+				continue
+			}
+		}
+
 		// Handle request
-		resp := s.handleRequest(&req)
+		_, span := tracing.Start(context.Background(), "rpc.call",
+			tracing.String("rpc.system", "jsonrpc"),
+			tracing.String("rpc.method", req.Method))
+		resp := s.handleRequest(&req, cred)
+		if resp.Error != nil {
+			span.SetAttributes(tracing.Int("rpc.jsonrpc.error_code", resp.Error.Code))
+			span.RecordError(errors.New(resp.Error.Message))
+		}
+		span.End()
 		s.writeResponse(writer, resp)
 	}
 
@@ -192,8 +594,9 @@ func (s *Server) writeResponse(w *bufio.Writer, resp *Response) {
 	}
 }
 
-// handleRequest handles a single RPC request
-func (s *Server) handleRequest(req *Request) *Response {
+// handleRequest handles a single RPC request. cred identifies the caller
+// when the platform supports it.
+func (s *Server) handleRequest(req *Request, cred *PeerCred) *Response {
 	resp := &Response{
 		JSONRPC: "2.0",
 		ID:      req.ID,
@@ -234,6 +637,142 @@ func (s *Server) handleRequest(req *Request) *Response {
 			resp.Result = result
 		}
 
+	case "peers.stats":
+		result, err := s.handlePeersStats(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "peers.quarantine":
+		result, err := s.handlePeersQuarantine(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "peers.approve":
+		result, err := s.handlePeersApprove(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "peers.forget":
+		result, err := s.handlePeersForget(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "peers.pending":
+		result, err := s.handlePeersPending()
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "peers.ping":
+		result, err := s.handlePeersPing(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "peers.route":
+		result, err := s.handlePeersRoute(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "state.export":
+		result, err := s.handleStateExport(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "state.import":
+		result, err := s.handleStateImport(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "config.get":
+		result, err := s.handleConfigGet(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "config.set":
+		result, err := s.handleConfigSet(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "events.list":
+		result, err := s.handleEventsList(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "routes.list":
+		result, err := s.handleRoutesList(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "mesh.rotate":
+		result, err := s.handleMeshRotate(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "mesh.broadcast":
+		result, err := s.handleMeshBroadcast(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "mesh.messages":
+		result, err := s.handleMeshMessages()
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "secret.unlock":
+		result, err := s.handleSecretUnlock(cred)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
 	case "daemon.status":
 		result, err := s.handleDaemonStatus(req.Params)
 		if err != nil {
@@ -250,6 +789,54 @@ func (s *Server) handleRequest(req *Request) *Response {
 			resp.Result = result
 		}
 
+	case "daemon.ready":
+		result, err := s.handleDaemonReady(req.Params)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "daemon.resources":
+		result, err := s.handleDaemonResources()
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "discovery.lan":
+		result, err := s.handleDiscoveryLAN()
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "discovery.dht":
+		result, err := s.handleDiscoveryDHT()
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "daemon.leave":
+		result, err := s.handleDaemonLeave(cred)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
+	case "daemon.restart":
+		result, err := s.handleDaemonRestart(cred)
+		if err != nil {
+			resp.Error = err
+		} else {
+			resp.Result = result
+		}
+
 	default:
 		resp.Error = &Error{
 			Code:    ErrCodeMethodNotFound,
@@ -262,6 +849,10 @@ func (s *Server) handleRequest(req *Request) *Response {
 
 // handlePeersList implements peers.list
 func (s *Server) handlePeersList(params map[string]interface{}) (*PeersListResult, *Error) {
+	selectors, err := tagSelectors(params)
+	if err != nil {
+		return nil, err
+	}
 	peers := s.getPeersFn()
 
 	result := &PeersListResult{
@@ -269,21 +860,55 @@ func (s *Server) handlePeersList(params map[string]interface{}) (*PeersListResul
 	}
 
 	for _, peer := range peers {
-		result.Peers = append(result.Peers, &PeerInfo{
-			PubKey:           peer.WGPubKey,
-			Hostname:         peer.Hostname,
-			MeshIP:           peer.MeshIP,
-			Endpoint:         peer.Endpoint,
-			LastSeen:         peer.LastSeen.Format(time.RFC3339),
-			DiscoveredVia:    peer.DiscoveredVia,
-			RoutableNetworks: peer.RoutableNetworks,
-			LatencyMs:        peer.LatencyMs,
-		})
+		if !matchesTags(peer.Tags, selectors) {
+			continue
+		}
+		result.Peers = append(result.Peers, peerInfo(peer))
 	}
 
 	return result, nil
 }
 
+// tagSelectors reads the optional "tags" parameter of peers.list: a list
+// of "key=value" selectors, or bare "key" for any value.
+func tagSelectors(params map[string]interface{}) ([]string, *Error) {
+	raw, ok := params["tags"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	list, ok := raw.([]interface{})
+	if !ok {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: "invalid 'tags' parameter",
+		}
+	}
+	selectors := make([]string, 0, len(list))
+	for _, v := range list {
+		sel, ok := v.(string)
+		if !ok || sel == "" {
+			return nil, &Error{
+				Code:    ErrCodeInvalidParams,
+				Message: "invalid 'tags' parameter",
+			}
+		}
+		selectors = append(selectors, sel)
+	}
+	return selectors, nil
+}
+
+// matchesTags reports whether tags satisfy every selector.
+func matchesTags(tags map[string]string, selectors []string) bool {
+	for _, sel := range selectors {
+		key, want, hasValue := strings.Cut(sel, "=")
+		got, ok := tags[key]
+		if !ok || (hasValue && got != want) {
+			return false
+		}
+	}
+	return true
+}
+
 // handlePeersGet implements peers.get
 func (s *Server) handlePeersGet(params map[string]interface{}) (*PeerInfo, *Error) {
 	pubkey, ok := params["pubkey"].(string)
@@ -302,16 +927,45 @@ func (s *Server) handlePeersGet(params map[string]interface{}) (*PeerInfo, *Erro
 		}
 	}
 
-	return &PeerInfo{
-		PubKey:           peer.WGPubKey,
-		Hostname:         peer.Hostname,
-		MeshIP:           peer.MeshIP,
-		Endpoint:         peer.Endpoint,
-		LastSeen:         peer.LastSeen.Format(time.RFC3339),
-		DiscoveredVia:    peer.DiscoveredVia,
-		RoutableNetworks: peer.RoutableNetworks,
-		LatencyMs:        peer.LatencyMs,
-	}, nil
+	return peerInfo(peer), nil
+}
+
+// peerInfo converts a peer to its peers.list and peers.get form.
+func peerInfo(peer *PeerData) *PeerInfo {
+	info := &PeerInfo{
+		PubKey:            peer.WGPubKey,
+		Hostname:          peer.Hostname,
+		MeshIP:            peer.MeshIP,
+		MeshIPv6:          peer.MeshIPv6,
+		Endpoint:          peer.Endpoint,
+		ControlEndpoint:   peer.ControlEndpoint,
+		EndpointMethod:    peer.EndpointMethod,
+		Candidates:        peer.Candidates,
+		LastSeen:          peer.LastSeen.Format(time.RFC3339),
+		DiscoveredVia:     peer.DiscoveredVia,
+		RoutableNetworks:  peer.RoutableNetworks,
+		LatencyMs:         peer.LatencyMs,
+		PacketLossPct:     peer.PacketLossPct,
+		Probes:            peer.Probes,
+		ProbesLost:        peer.ProbesLost,
+		Installed:         peer.Installed,
+		Introducer:        peer.Introducer,
+		IntroducerElected: peer.IntroducerElected,
+		Version:           peer.Version,
+		Revision:          peer.Revision,
+		Capabilities:      peer.Capabilities,
+		Compatibility:     peer.Compatibility,
+		NATType:           peer.NATType,
+		Path:              peer.Path,
+		RelayPubKey:       peer.RelayPubKey,
+		Identity:          peer.Identity,
+		Load:              peer.Load,
+		Tags:              peer.Tags,
+	}
+	if !peer.LastHandshake.IsZero() {
+		info.LastHandshake = peer.LastHandshake.Format(time.RFC3339)
+	}
+	return info
 }
 
 // handlePeersCount implements peers.count
@@ -325,27 +979,847 @@ func (s *Server) handlePeersCount(params map[string]interface{}) (*PeersCountRes
 	}, nil
 }
 
-// handleDaemonStatus implements daemon.status
-func (s *Server) handleDaemonStatus(params map[string]interface{}) (*DaemonStatusResult, *Error) {
-	status := s.getStatusFn()
-	if status == nil {
+// handlePeersStats implements peers.stats. Peers are ordered by combined
+// rx+tx rate over the window named by the optional "window" parameter (a
+// duration such as "5m"; default the shortest window), busiest first. The
+// optional "limit" parameter truncates the list.
+func (s *Server) handlePeersStats(params map[string]interface{}) (*PeersStatsResult, *Error) {
+	if s.getPeerStatsFn == nil {
 		return nil, &Error{
-			Code:    ErrCodeInternalError,
-			Message: "daemon status unavailable",
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: peers.stats",
 		}
 	}
 
-	return &DaemonStatusResult{
-		MeshIP:    status.MeshIP,
-		PubKey:    status.PubKey,
-		Uptime:    status.Uptime,
-		Interface: status.Interface,
-		Version:   s.version,
-	}, nil
-}
+	var window time.Duration
+	if raw, ok := params["window"]; ok {
+		str, ok := raw.(string)
+		d, err := time.ParseDuration(str)
+		if !ok || err != nil || d <= 0 {
+			return nil, &Error{
+				Code:    ErrCodeInvalidParams,
+				Message: "invalid 'window' parameter",
+			}
+		}
+		window = d
+	}
+	limit := 0
+	if raw, ok := params["limit"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 0 {
+			return nil, &Error{
+				Code:    ErrCodeInvalidParams,
+				Message: "invalid 'limit' parameter",
+			}
+		}
+		limit = int(n)
+	}
 
-// handleDaemonPing implements daemon.ping
-func (s *Server) handleDaemonPing(params map[string]interface{}) (*DaemonPingResult, *Error) {
+	stats := s.getPeerStatsFn()
+	rateIdx := 0
+	if window > 0 && len(stats) > 0 {
+		rateIdx = -1
+		for i, r := range stats[0].Rates {
+			if r.Window == window {
+				rateIdx = i
+			}
+		}
+		if rateIdx < 0 {
+			return nil, &Error{
+				Code:    ErrCodeInvalidParams,
+				Message: fmt.Sprintf("unsupported window: %s", window),
+			}
+		}
+	}
+	busy := func(p *PeerStatsData) float64 {
+		if rateIdx >= len(p.Rates) {
+			return 0
+		}
+		return p.Rates[rateIdx].RxBps + p.Rates[rateIdx].TxBps
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		bi, bj := busy(stats[i]), busy(stats[j])
+		if bi != bj {
+			return bi > bj
+		}
+		return stats[i].PubKey < stats[j].PubKey
+	})
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+
+	result := &PeersStatsResult{
+		Peers: make([]*PeerStatsInfo, 0, len(stats)),
+	}
+	for _, p := range stats {
+		info := &PeerStatsInfo{
+			PubKey:   p.PubKey,
+			Hostname: p.Hostname,
+			MeshIP:   p.MeshIP,
+			RxBytes:  p.RxBytes,
+			TxBytes:  p.TxBytes,
+			Since:    p.Since.Format(time.RFC3339),
+			Rates:    make([]*TrafficRateInfo, 0, len(p.Rates)),
+		}
+		for _, r := range p.Rates {
+			info.Rates = append(info.Rates, &TrafficRateInfo{
+				WindowSeconds: int(r.Window / time.Second),
+				RxBps:         r.RxBps,
+				TxBps:         r.TxBps,
+			})
+		}
+		result.Peers = append(result.Peers, info)
+	}
+
+	return result, nil
+}
+
+// handleEventsList implements events.list. The optional "since" parameter
+// is the last sequence number the caller has seen.
+func (s *Server) handleEventsList(params map[string]interface{}) (*EventsListResult, *Error) {
+	if s.getEventsFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: events.list",
+		}
+	}
+
+	var since uint64
+	if raw, ok := params["since"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 0 {
+			return nil, &Error{
+				Code:    ErrCodeInvalidParams,
+				Message: "invalid 'since' parameter",
+			}
+		}
+		since = uint64(n)
+	}
+
+	events := s.getEventsFn(since)
+	result := &EventsListResult{
+		Events: make([]*EventInfo, 0, len(events)),
+	}
+	for _, ev := range events {
+		result.Events = append(result.Events, &EventInfo{
+			Seq:     ev.Seq,
+			Time:    ev.Time.Format(time.RFC3339),
+			Type:    ev.Type,
+			PubKey:  ev.PubKey,
+			Details: ev.Details,
+		})
+	}
+	if s.getEventSubsFn != nil {
+		for _, sub := range s.getEventSubsFn() {
+			result.Subscribers = append(result.Subscribers, &EventSubscriberInfo{
+				Name:      sub.Name,
+				Queued:    sub.Queued,
+				Delivered: sub.Delivered,
+				Coalesced: sub.Coalesced,
+				Dropped:   sub.Dropped,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// handlePeersQuarantine implements peers.quarantine
+func (s *Server) handlePeersQuarantine(params map[string]interface{}) (*PeersQuarantineResult, *Error) {
+	if s.getQuarantineFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: peers.quarantine",
+		}
+	}
+
+	peers := s.getQuarantineFn()
+	result := &PeersQuarantineResult{
+		Peers: make([]*QuarantinedPeerInfo, 0, len(peers)),
+	}
+	for _, p := range peers {
+		result.Peers = append(result.Peers, &QuarantinedPeerInfo{
+			PubKey:       p.PubKey,
+			Hostname:     p.Hostname,
+			MeshIP:       p.MeshIP,
+			Endpoint:     p.Endpoint,
+			Reason:       p.Reason,
+			PinnedPubKey: p.PinnedPubKey,
+			FirstSeen:    p.FirstSeen.Format(time.RFC3339),
+			LastSeen:     p.LastSeen.Format(time.RFC3339),
+		})
+	}
+	return result, nil
+}
+
+// handlePeersApprove implements peers.approve
+func (s *Server) handlePeersApprove(params map[string]interface{}) (*PeersApproveResult, *Error) {
+	if s.approvePeerFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: peers.approve",
+		}
+	}
+
+	pubkey, ok := params["pubkey"].(string)
+	if !ok || pubkey == "" {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: "missing or invalid 'pubkey' parameter",
+		}
+	}
+	if err := s.approvePeerFn(pubkey); err != nil {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: err.Error(),
+		}
+	}
+	return &PeersApproveResult{Approved: true}, nil
+}
+
+// handlePeersForget implements peers.forget
+func (s *Server) handlePeersForget(params map[string]interface{}) (*PeersForgetResult, *Error) {
+	if s.forgetPeerFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: peers.forget",
+		}
+	}
+
+	pubkey, ok := params["pubkey"].(string)
+	if !ok || pubkey == "" {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: "missing or invalid 'pubkey' parameter",
+		}
+	}
+	if err := s.forgetPeerFn(pubkey); err != nil {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: err.Error(),
+		}
+	}
+	return &PeersForgetResult{Forgotten: true}, nil
+}
+
+// handlePeersPending implements peers.pending
+func (s *Server) handlePeersPending() (*PeersPendingResult, *Error) {
+	if s.getPendingFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: peers.pending",
+		}
+	}
+
+	peers := s.getPendingFn()
+	result := &PeersPendingResult{
+		Peers: make([]*PendingPeerInfo, 0, len(peers)),
+	}
+	for _, p := range peers {
+		result.Peers = append(result.Peers, &PendingPeerInfo{
+			PubKey:    p.PubKey,
+			Hostname:  p.Hostname,
+			MeshIP:    p.MeshIP,
+			Endpoint:  p.Endpoint,
+			Identity:  p.Identity,
+			FirstSeen: p.FirstSeen.Format(time.RFC3339),
+			LastSeen:  p.LastSeen.Format(time.RFC3339),
+		})
+	}
+	return result, nil
+}
+
+// handlePeersPing implements peers.ping
+func (s *Server) handlePeersPing(params map[string]interface{}) (*PeersPingResult, *Error) {
+	if s.pingPeerFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: peers.ping",
+		}
+	}
+
+	peer, ok := params["peer"].(string)
+	if !ok || peer == "" {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: "missing or invalid 'peer' parameter",
+		}
+	}
+	ping, err := s.pingPeerFn(peer)
+	if err != nil {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: err.Error(),
+		}
+	}
+
+	result := &PeersPingResult{
+		PubKey:   ping.PubKey,
+		Hostname: ping.Hostname,
+		MeshIP:   ping.MeshIP,
+		Path:     ping.Path,
+		Error:    ping.Error,
+	}
+	if ping.Error == "" {
+		ms := float64(ping.RTT.Microseconds()) / 1000
+		result.RTTMs = &ms
+	}
+	return result, nil
+}
+
+// handlePeersRoute implements peers.route
+func (s *Server) handlePeersRoute(params map[string]interface{}) (*PeersRouteResult, *Error) {
+	if s.getPeerRouteFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: peers.route",
+		}
+	}
+
+	peer, ok := params["peer"].(string)
+	if !ok || peer == "" {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: "missing or invalid 'peer' parameter",
+		}
+	}
+	route, err := s.getPeerRouteFn(peer)
+	if err != nil {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: err.Error(),
+		}
+	}
+
+	return &PeersRouteResult{
+		PubKey:        route.PubKey,
+		Hostname:      route.Hostname,
+		MeshIP:        route.MeshIP,
+		Endpoint:      route.Endpoint,
+		Path:          route.Path,
+		Installed:     route.Installed,
+		RelayPubKey:   route.RelayPubKey,
+		RelayHostname: route.RelayHostname,
+		RelayMeshIP:   route.RelayMeshIP,
+		RelayEndpoint: route.RelayEndpoint,
+	}, nil
+}
+
+// handleStateExport implements state.export. The result is the snapshot
+// document itself, so it can be saved and fed to state.import unchanged.
+func (s *Server) handleStateExport(params map[string]interface{}) (json.RawMessage, *Error) {
+	if s.exportStateFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: state.export",
+		}
+	}
+
+	snapshot, err := s.exportStateFn()
+	if err != nil {
+		return nil, &Error{
+			Code:    ErrCodeInternalError,
+			Message: err.Error(),
+		}
+	}
+	return json.RawMessage(snapshot), nil
+}
+
+// handleStateImport implements state.import
+func (s *Server) handleStateImport(params map[string]interface{}) (*StateImportResult, *Error) {
+	if s.importStateFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: state.import",
+		}
+	}
+
+	raw, ok := params["snapshot"].(map[string]interface{})
+	if !ok {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: "missing or invalid 'snapshot' parameter",
+		}
+	}
+	snapshot, err := json.Marshal(raw)
+	if err != nil {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: "invalid 'snapshot' parameter",
+		}
+	}
+	imported, err := s.importStateFn(snapshot)
+	if err != nil {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: err.Error(),
+		}
+	}
+
+	return &StateImportResult{
+		Imported: imported.Imported,
+		Skipped:  imported.Skipped,
+	}, nil
+}
+
+// handleConfigGet implements config.get. With a 'key' parameter only that
+// option is returned.
+func (s *Server) handleConfigGet(params map[string]interface{}) (*ConfigGetResult, *Error) {
+	if s.getConfigFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: config.get",
+		}
+	}
+
+	options := s.getConfigFn()
+	if key, ok := params["key"].(string); ok && key != "" {
+		value, exists := options[key]
+		if !exists {
+			return nil, &Error{
+				Code:    ErrCodeInvalidParams,
+				Message: fmt.Sprintf("unknown option: %s", key),
+			}
+		}
+		options = map[string]string{key: value}
+	}
+	return &ConfigGetResult{Options: options}, nil
+}
+
+// handleConfigSet implements config.set
+func (s *Server) handleConfigSet(params map[string]interface{}) (*ConfigSetResult, *Error) {
+	if s.setConfigFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: config.set",
+		}
+	}
+
+	key, ok := params["key"].(string)
+	if !ok || key == "" {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: "missing or invalid 'key' parameter",
+		}
+	}
+	value, ok := params["value"].(string)
+	if !ok {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: "missing or invalid 'value' parameter",
+		}
+	}
+	old, err := s.setConfigFn(key, value)
+	if err != nil {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: err.Error(),
+		}
+	}
+
+	result := &ConfigSetResult{Key: key, Old: old, Value: value}
+	if s.getConfigFn != nil {
+		result.Value = s.getConfigFn()[key]
+	}
+	return result, nil
+}
+
+// handleRoutesList implements routes.list
+func (s *Server) handleRoutesList(params map[string]interface{}) (*RoutesListResult, *Error) {
+	if s.getRoutesFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: routes.list",
+		}
+	}
+
+	routes, conflicts := s.getRoutesFn()
+	result := &RoutesListResult{
+		Routes:    make([]*RouteInfo, 0, len(routes)),
+		Conflicts: make([]*RouteConflictInfo, 0, len(conflicts)),
+	}
+	for _, r := range routes {
+		result.Routes = append(result.Routes, &RouteInfo{
+			Network: r.Network,
+			PubKey:  r.PubKey,
+			Standby: r.Standby,
+		})
+	}
+	for _, c := range conflicts {
+		result.Conflicts = append(result.Conflicts, &RouteConflictInfo{
+			WinnerNetwork: c.WinnerNetwork,
+			WinnerPubKey:  c.WinnerPubKey,
+			LoserNetwork:  c.LoserNetwork,
+			LoserPubKey:   c.LoserPubKey,
+			Dropped:       c.Dropped,
+		})
+	}
+
+	return result, nil
+}
+
+// handleMeshRotate implements mesh.rotate. Optional parameters are
+// "new_secret" (generated by the daemon when absent), "grace", a duration
+// such as "24h" (daemon default when absent), and "force", to rotate even
+// though some peers do not support it.
+func (s *Server) handleMeshRotate(params map[string]interface{}) (*MeshRotateResult, *Error) {
+	if s.rotateSecretFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: mesh.rotate",
+		}
+	}
+
+	var newSecret string
+	if raw, ok := params["new_secret"]; ok {
+		str, ok := raw.(string)
+		if !ok {
+			return nil, &Error{
+				Code:    ErrCodeInvalidParams,
+				Message: "invalid 'new_secret' parameter",
+			}
+		}
+		newSecret = str
+	}
+	var grace time.Duration
+	if raw, ok := params["grace"]; ok {
+		str, ok := raw.(string)
+		d, err := time.ParseDuration(str)
+		if !ok || err != nil || d <= 0 {
+			return nil, &Error{
+				Code:    ErrCodeInvalidParams,
+				Message: "invalid 'grace' parameter",
+			}
+		}
+		grace = d
+	}
+	var force bool
+	if raw, ok := params["force"]; ok {
+		b, ok := raw.(bool)
+		if !ok {
+			return nil, &Error{
+				Code:    ErrCodeInvalidParams,
+				Message: "invalid 'force' parameter",
+			}
+		}
+		force = b
+	}
+
+	rotation, err := s.rotateSecretFn(newSecret, grace, force)
+	if err != nil {
+		return nil, &Error{
+			Code:    ErrCodeInternalError,
+			Message: err.Error(),
+		}
+	}
+
+	return &MeshRotateResult{
+		NewSecretURI: rotation.NewSecretURI,
+		SwitchAt:     rotation.SwitchAt.Format(time.RFC3339),
+	}, nil
+}
+
+// handleMeshBroadcast implements mesh.broadcast. The "text" parameter is
+// required.
+func (s *Server) handleMeshBroadcast(params map[string]interface{}) (*MeshBroadcastResult, *Error) {
+	if s.broadcastFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: mesh.broadcast",
+		}
+	}
+
+	text, ok := params["text"].(string)
+	if !ok || text == "" {
+		return nil, &Error{
+			Code:    ErrCodeInvalidParams,
+			Message: "missing or invalid 'text' parameter",
+		}
+	}
+
+	msg, err := s.broadcastFn(text)
+	if err != nil {
+		return nil, &Error{
+			Code:    ErrCodeInternalError,
+			Message: err.Error(),
+		}
+	}
+
+	return &MeshBroadcastResult{
+		ID:     msg.ID,
+		SentAt: msg.SentAt.Format(time.RFC3339),
+	}, nil
+}
+
+// handleMeshMessages implements mesh.messages
+func (s *Server) handleMeshMessages() (*MeshMessagesResult, *Error) {
+	if s.getMessagesFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: mesh.messages",
+		}
+	}
+
+	messages := s.getMessagesFn()
+	result := &MeshMessagesResult{
+		Messages: make([]*MessageInfo, 0, len(messages)),
+	}
+	for _, m := range messages {
+		result.Messages = append(result.Messages, &MessageInfo{
+			ID:         m.ID,
+			PubKey:     m.PubKey,
+			Hostname:   m.Hostname,
+			Text:       m.Text,
+			SentAt:     m.SentAt.Format(time.RFC3339),
+			ReceivedAt: m.ReceivedAt.Format(time.RFC3339),
+		})
+	}
+
+	return result, nil
+}
+
+// handleSecretUnlock implements secret.unlock
+func (s *Server) handleSecretUnlock(cred *PeerCred) (*SecretUnlockResult, *Error) {
+	if s.getSecretFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: secret.unlock",
+		}
+	}
+	if err := authorizeSecretAccess(cred); err != nil {
+		return nil, err
+	}
+	return &SecretUnlockResult{Secret: s.getSecretFn()}, nil
+}
+
+// handleDaemonReady implements daemon.ready. The mesh is ready once the
+// interface is up and at least "min_peers" peers (default 1) have a
+// recent handshake.
+func (s *Server) handleDaemonReady(params map[string]interface{}) (*DaemonReadyResult, *Error) {
+	if s.getReadinessFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: daemon.ready",
+		}
+	}
+
+	minPeers := 1
+	if raw, ok := params["min_peers"]; ok {
+		n, ok := raw.(float64)
+		if !ok || n < 0 {
+			return nil, &Error{
+				Code:    ErrCodeInvalidParams,
+				Message: "invalid 'min_peers' parameter",
+			}
+		}
+		minPeers = int(n)
+	}
+
+	readiness := s.getReadinessFn()
+	result := &DaemonReadyResult{
+		InterfaceUp: readiness.InterfaceUp,
+		Peers:       readiness.Peers,
+		MinPeers:    minPeers,
+	}
+	switch {
+	case !readiness.InterfaceUp:
+		result.Reason = "interface is not up"
+	case readiness.Peers < minPeers:
+		result.Reason = fmt.Sprintf("%d of %d peers have a recent handshake", readiness.Peers, minPeers)
+	default:
+		result.Ready = true
+	}
+	return result, nil
+}
+
+// handleDaemonResources implements daemon.resources
+func (s *Server) handleDaemonResources() (*DaemonResourcesResult, *Error) {
+	if s.getResourcesFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: daemon.resources",
+		}
+	}
+
+	result := &DaemonResourcesResult{Resources: make([]*ResourceInfo, 0)}
+	for _, r := range s.getResourcesFn() {
+		result.Resources = append(result.Resources, &ResourceInfo{
+			Name:     r.Name,
+			Used:     r.Used,
+			Limit:    r.Limit,
+			Pressure: r.Pressure,
+		})
+		if r.Pressure {
+			result.Pressure = true
+		}
+	}
+	return result, nil
+}
+
+// handleDiscoveryLAN implements discovery.lan
+func (s *Server) handleDiscoveryLAN() (*DiscoveryLANResult, *Error) {
+	if s.getLANFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: discovery.lan",
+		}
+	}
+
+	lan := s.getLANFn()
+	result := &DiscoveryLANResult{Interfaces: make([]*LANInterfaceInfo, 0)}
+	if lan == nil {
+		return result, nil
+	}
+	result.Enabled = true
+	result.Mode = lan.Mode
+	result.Group = lan.Group
+	result.Broadcast = lan.Broadcast
+	for _, iface := range lan.Interfaces {
+		info := &LANInterfaceInfo{
+			Name:      iface.Name,
+			Addresses: iface.Addresses,
+			Joined:    iface.Joined,
+			Error:     iface.Error,
+		}
+		if !iface.LastHeard.IsZero() {
+			info.LastHeard = iface.LastHeard.Format(time.RFC3339)
+		}
+		result.Interfaces = append(result.Interfaces, info)
+	}
+	return result, nil
+}
+
+// handleDiscoveryDHT implements discovery.dht
+func (s *Server) handleDiscoveryDHT() (*DiscoveryDHTResult, *Error) {
+	if s.getDHTFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: discovery.dht",
+		}
+	}
+
+	dht := s.getDHTFn()
+	result := &DiscoveryDHTResult{}
+	if dht == nil {
+		return result, nil
+	}
+	result.Enabled = true
+	result.NATType = dht.NATType
+	result.Peers = dht.Peers
+	result.TargetPeers = dht.TargetPeers
+	result.AnnounceInterval = dht.AnnounceInterval
+	result.QueryInterval = dht.QueryInterval
+	result.Nodes = dht.Nodes
+	if !dht.LastAnnounce.IsZero() {
+		result.LastAnnounce = dht.LastAnnounce.Format(time.RFC3339)
+	}
+	if !dht.LastQuery.IsZero() {
+		result.LastQuery = dht.LastQuery.Format(time.RFC3339)
+	}
+	return result, nil
+}
+
+// handleDaemonLeave implements daemon.leave
+func (s *Server) handleDaemonLeave(cred *PeerCred) (*DaemonLeaveResult, *Error) {
+	if s.leaveFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: daemon.leave",
+		}
+	}
+	if err := authorizeOwner(cred, "take this node out of the mesh"); err != nil {
+		return nil, err
+	}
+	if err := s.leaveFn(); err != nil {
+		return nil, &Error{
+			Code:    ErrCodeInternalError,
+			Message: err.Error(),
+		}
+	}
+	return &DaemonLeaveResult{Leaving: true}, nil
+}
+
+// handleDaemonRestart implements daemon.restart
+func (s *Server) handleDaemonRestart(cred *PeerCred) (*DaemonRestartResult, *Error) {
+	if s.restartFn == nil {
+		return nil, &Error{
+			Code:    ErrCodeMethodNotFound,
+			Message: "method not found: daemon.restart",
+		}
+	}
+	if err := authorizeOwner(cred, "restart the daemon"); err != nil {
+		return nil, err
+	}
+	if err := s.restartFn(); err != nil {
+		return nil, &Error{
+			Code:    ErrCodeInternalError,
+			Message: err.Error(),
+		}
+	}
+	return &DaemonRestartResult{Restarting: true}, nil
+}
+
+// authorizeSecretAccess only releases the secret to root or to processes
+// running as the server's own user.
+func authorizeSecretAccess(cred *PeerCred) *Error {
+	return authorizeOwner(cred, "read the mesh secret")
+}
+
+// authorizeOwner lets only root or processes running as the server's own
+// user do action. Without peer credentials the socket's 0600 permissions
+// are the only check.
+func authorizeOwner(cred *PeerCred, action string) *Error {
+	if cred == nil || cred.UID == 0 || cred.UID == uint32(os.Geteuid()) {
+		return nil
+	}
+	return &Error{
+		Code:    ErrCodeUnauthorized,
+		Message: fmt.Sprintf("uid %d may not %s", cred.UID, action),
+	}
+}
+
+// authorizeLocal checks a Unix socket caller against the allowed UIDs and
+// GIDs. Without either list, the socket's 0600 permissions are the check.
+func (s *Server) authorizeLocal(cred *PeerCred) *Error {
+	if len(s.allowUIDs) == 0 && len(s.allowGIDs) == 0 {
+		return nil
+	}
+	if cred != nil && (cred.UID == 0 || cred.UID == uint32(os.Geteuid()) ||
+		slices.Contains(s.allowUIDs, cred.UID) || slices.Contains(s.allowGIDs, cred.GID)) {
+		return nil
+	}
+	msg := "peer credentials unavailable"
+	if cred != nil {
+		msg = fmt.Sprintf("uid %d (gid %d) may not use the RPC socket", cred.UID, cred.GID)
+	}
+	return &Error{Code: ErrCodeUnauthorized, Message: msg}
+}
+
+// handleDaemonStatus implements daemon.status
+func (s *Server) handleDaemonStatus(params map[string]interface{}) (*DaemonStatusResult, *Error) {
+	status := s.getStatusFn()
+	if status == nil {
+		return nil, &Error{
+			Code:    ErrCodeInternalError,
+			Message: "daemon status unavailable",
+		}
+	}
+
+	return &DaemonStatusResult{
+		MeshIP:         status.MeshIP,
+		PubKey:         status.PubKey,
+		Uptime:         status.Uptime,
+		Interface:      status.Interface,
+		Version:        s.version,
+		Identity:       status.Identity,
+		ClockSkew:      status.ClockSkew,
+		ClockSkewPeers: status.ClockSkewPeers,
+		MeshName:       status.MeshName,
+	}, nil
+}
+
+// handleDaemonPing implements daemon.ping
+func (s *Server) handleDaemonPing(params map[string]interface{}) (*DaemonPingResult, *Error) {
 	return &DaemonPingResult{
 		Pong:    true,
 		Version: s.version,
@@ -359,6 +1833,12 @@ func (s *Server) Stop() error {
 	if s.listener != nil {
 		s.listener.Close()
 	}
+	if s.tcpListener != nil {
+		s.tcpListener.Close()
+	}
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+	}
 
 	// Remove socket file
 	if err := os.Remove(s.socketPath); err != nil && !os.IsNotExist(err) {
@@ -1,8 +1,19 @@
 package rpc
 
 import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
 func TestServerConfig(t *testing.T) {
@@ -109,3 +120,442 @@ func TestFormatSocketPath(t *testing.T) {
 		}
 	}
 }
+
+func TestAuthorizeLocal(t *testing.T) {
+	self := uint32(os.Geteuid())
+	open := &Server{}
+	restricted := &Server{allowUIDs: []uint32{self + 1000}, allowGIDs: []uint32{4242}}
+
+	tests := []struct {
+		name   string
+		server *Server
+		cred   *PeerCred
+		ok     bool
+	}{
+		{name: "no allow lists", server: open, cred: &PeerCred{UID: self + 2000}, ok: true},
+		{name: "root", server: restricted, cred: &PeerCred{UID: 0}, ok: true},
+		{name: "same user", server: restricted, cred: &PeerCred{UID: self}, ok: true},
+		{name: "allowed uid", server: restricted, cred: &PeerCred{UID: self + 1000, GID: 1}, ok: true},
+		{name: "allowed gid", server: restricted, cred: &PeerCred{UID: self + 2000, GID: 4242}, ok: true},
+		{name: "other user", server: restricted, cred: &PeerCred{UID: self + 2000, GID: 1}, ok: self+2000 == 0},
+		{name: "unknown caller", server: restricted, cred: nil, ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.server.authorizeLocal(tt.cred)
+			if (err == nil) != tt.ok {
+				t.Errorf("authorizeLocal(%+v) = %v, want ok=%v", tt.cred, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestTCPListenerRequiresToken(t *testing.T) {
+	// Pick a free port on loopback, which stands in for the mesh IP.
+	probe, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("no loopback TCP: %v", err)
+	}
+	port := probe.Addr().(*net.TCPAddr).Port
+	probe.Close()
+
+	socketPath := filepath.Join(os.TempDir(), fmt.Sprintf("wg-rpc-tcp-%d.sock", os.Getpid()))
+	t.Cleanup(func() { os.Remove(socketPath) })
+
+	token := "0123456789abcdef0123"
+	var left, restarted atomic.Bool
+	config := ServerConfig{
+		SocketPath:    socketPath,
+		Version:       "test",
+		GetPeers:      func() []*PeerData { return nil },
+		GetPeer:       func(string) (*PeerData, bool) { return nil, false },
+		GetPeerCounts: func() (int, int, int) { return 0, 0, 0 },
+		GetStatus:     func() *StatusData { return &StatusData{MeshIP: "127.0.0.1"} },
+		GetSecret:     func() string { return "wgmesh://v1/tcp-secret" },
+		Leave:         func() error { left.Store(true); return nil },
+		Restart:       func() error { restarted.Store(true); return nil },
+		TCPPort:       port,
+		TCPToken:      token,
+	}
+
+	if _, err := NewServer(ServerConfig{SocketPath: socketPath, GetPeers: config.GetPeers, GetPeer: config.GetPeer,
+		GetPeerCounts: config.GetPeerCounts, GetStatus: config.GetStatus, TCPPort: port, TCPToken: "short"}); err == nil {
+		t.Error("expected an error for a short token")
+	}
+
+	server, err := NewServer(config)
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	client, err := NewTCPClient(addr, token)
+	if err != nil {
+		t.Fatalf("NewTCPClient: %v", err)
+	}
+	defer client.Close()
+	if _, err := client.Call("daemon.ping", nil); err != nil {
+		t.Errorf("daemon.ping with the token failed: %v", err)
+	}
+	if _, err := client.Call("secret.unlock", nil); err == nil {
+		t.Error("secret.unlock must not be answered over TCP")
+	}
+	if _, err := client.Call("daemon.ping", nil); err != nil {
+		t.Errorf("connection should survive a refused secret.unlock: %v", err)
+	}
+	if _, err := client.Call("daemon.leave", nil); err == nil || left.Load() {
+		t.Error("daemon.leave must not be answered over TCP")
+	}
+	if _, err := client.Call("daemon.restart", nil); err == nil || restarted.Load() {
+		t.Error("daemon.restart must not be answered over TCP")
+	}
+
+	local, err := NewClient(socketPath)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer local.Close()
+	if _, err := local.Call("daemon.leave", nil); err != nil || !left.Load() {
+		t.Errorf("daemon.leave over the socket: %v, left=%v", err, left.Load())
+	}
+	if _, err := local.Call("daemon.restart", nil); err != nil || !restarted.Load() {
+		t.Errorf("daemon.restart over the socket: %v, restarted=%v", err, restarted.Load())
+	}
+
+	bad, err := NewTCPClient(addr, "wrong-token-wrong-token")
+	if err != nil {
+		t.Fatalf("NewTCPClient: %v", err)
+	}
+	defer bad.Close()
+	if _, err := bad.Call("daemon.ping", nil); err == nil {
+		t.Error("expected an error for a wrong token")
+	}
+
+	t.Setenv("WGMESH_RPC_TOKEN", token)
+	viaPath, err := NewClient("tcp://" + addr)
+	if err != nil {
+		t.Fatalf("NewClient(tcp://): %v", err)
+	}
+	defer viaPath.Close()
+	if _, err := viaPath.Call("daemon.ping", nil); err != nil {
+		t.Errorf("daemon.ping via tcp:// path failed: %v", err)
+	}
+}
+
+func TestLegacyJSON(t *testing.T) {
+	for _, legacy := range []bool{false, true} {
+		t.Run(fmt.Sprintf("LegacyJSON=%v", legacy), func(t *testing.T) {
+			socketPath := filepath.Join(os.TempDir(), fmt.Sprintf("wg-rpc-json-%d-%v.sock", os.Getpid(), legacy))
+			t.Cleanup(func() { os.Remove(socketPath) })
+
+			server, err := NewServer(ServerConfig{
+				SocketPath:    socketPath,
+				Version:       "test",
+				GetPeers:      func() []*PeerData { return nil },
+				GetPeer:       func(string) (*PeerData, bool) { return nil, false },
+				GetPeerCounts: func() (int, int, int) { return 1, 2, 0 },
+				GetStatus:     func() *StatusData { return &StatusData{} },
+				LegacyJSON:    legacy,
+			})
+			if err != nil {
+				t.Fatalf("failed to create server: %v", err)
+			}
+			if err := server.Start(); err != nil {
+				t.Fatalf("failed to start server: %v", err)
+			}
+			defer server.Stop()
+
+			t.Setenv("WGMESH_RPC_PROTOCOL", "json")
+			client, err := NewClient(socketPath)
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			defer client.Close()
+			result, err := client.Call("peers.count", nil)
+			if !legacy {
+				if err == nil {
+					t.Error("JSON-RPC must be refused without LegacyJSON")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("peers.count over JSON-RPC: %v", err)
+			}
+			if total := result.(map[string]interface{})["total"]; total != float64(2) {
+				t.Errorf("total = %v, want 2", total)
+			}
+		})
+	}
+}
+
+func TestGRPCMethodsCoverService(t *testing.T) {
+	mapped := make(map[string]bool)
+	for _, name := range grpcMethods {
+		if daemonService.Methods().ByName(protoreflect.Name(name)) == nil {
+			t.Errorf("grpcMethods names %s, which the Daemon service lacks", name)
+		}
+		mapped[name] = true
+	}
+	methods := daemonService.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		if name := string(methods.Get(i).Name()); !mapped[name] {
+			t.Errorf("Daemon.%s has no JSON-RPC method in grpcMethods", name)
+		}
+	}
+}
+
+// TestGRPCResultsFitMessages runs every JSON-RPC method behind the gRPC
+// service with every result field set, and checks that the result decodes
+// into the method's response message without dropping a key. gRPC clients
+// never see a JSON-RPC result field the proto does not declare.
+func TestGRPCResultsFitMessages(t *testing.T) {
+	config := ServerConfig{
+		SocketPath:    filepath.Join(t.TempDir(), "wgmesh.sock"),
+		Version:       "test",
+		GetPeers:      func() []*PeerData { return []*PeerData{filled[*PeerData]()} },
+		GetPeer:       func(string) (*PeerData, bool) { return filled[*PeerData](), true },
+		GetPeerCounts: func() (int, int, int) { return 1, 2, 1 },
+		GetStatus:     func() *StatusData { return filled[*StatusData]() },
+		GetEvents:     func(uint64) []*EventData { return []*EventData{filled[*EventData]()} },
+		GetEventSubs:  func() []*EventSubscriberData { return []*EventSubscriberData{filled[*EventSubscriberData]()} },
+		GetRoutes: func() ([]*RouteData, []*RouteConflictData) {
+			return []*RouteData{filled[*RouteData]()}, []*RouteConflictData{filled[*RouteConflictData]()}
+		},
+		GetPeerStats: func() []*PeerStatsData { return []*PeerStatsData{filled[*PeerStatsData]()} },
+		RotateSecret: func(string, time.Duration, bool) (*RotationData, error) { return filled[*RotationData](), nil },
+		Broadcast:    func(string) (*MessageData, error) { return filled[*MessageData](), nil },
+		GetMessages:  func() []*MessageData { return []*MessageData{filled[*MessageData]()} },
+		GetSecret:    func() string { return "wgmesh://secret" },
+		GetQuarantine: func() []*QuarantineData {
+			return []*QuarantineData{filled[*QuarantineData]()}
+		},
+		ApprovePeer:  func(string) error { return nil },
+		ForgetPeer:   func(string) error { return nil },
+		GetPending:   func() []*PendingData { return []*PendingData{filled[*PendingData]()} },
+		PingPeer:     func(string) (*PingData, error) { return filled[*PingData](), nil },
+		GetPeerRoute: func(string) (*PeerRouteData, error) { return filled[*PeerRouteData](), nil },
+		ExportState:  func() ([]byte, error) { return []byte(`{"version":1}`), nil },
+		ImportState:  func([]byte) (*StateImportData, error) { return filled[*StateImportData](), nil },
+		GetConfig:    func() map[string]string { return map[string]string{"log-level": "info"} },
+		SetConfig:    func(string, string) (string, error) { return "info", nil },
+		Leave:        func() error { return nil },
+		Restart:      func() error { return nil },
+		GetReadiness: func() *ReadinessData { return filled[*ReadinessData]() },
+		GetResources: func() []*ResourceData { return []*ResourceData{filled[*ResourceData]()} },
+		GetLAN:       func() *LANData { return filled[*LANData]() },
+		GetDHT:       func() *DHTData { return filled[*DHTData]() },
+	}
+	server, err := NewServer(config)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	params := map[string]map[string]interface{}{
+		"peers.get":      {"pubkey": "x"},
+		"peers.approve":  {"pubkey": "x"},
+		"peers.forget":   {"pubkey": "x"},
+		"peers.ping":     {"peer": "x"},
+		"peers.route":    {"peer": "x"},
+		"state.import":   {"snapshot": map[string]interface{}{"version": 1}},
+		"config.set":     {"key": "log-level", "value": "debug"},
+		"mesh.rotate":    {"secret": "wgmesh://new-secret"},
+		"mesh.broadcast": {"text": "hello"},
+	}
+
+	for method, name := range grpcMethods {
+		t.Run(method, func(t *testing.T) {
+			result := server.handleRequest(&Request{JSONRPC: "2.0", Method: method, Params: params[method]}, nil)
+			if result.Error != nil {
+				t.Fatalf("%s: %s", method, result.Error.Message)
+			}
+			data, err := json.Marshal(result.Result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if method == "state.export" {
+				data = []byte(fmt.Sprintf(`{"snapshot":%s}`, data))
+			}
+			desc := daemonService.Methods().ByName(protoreflect.Name(name)).Output()
+			msgType, err := protoregistry.GlobalTypes.FindMessageByName(desc.FullName())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := protojson.Unmarshal(data, msgType.New().Interface()); err != nil {
+				t.Errorf("%s result does not fit %s: %v\n%s", method, desc.FullName(), err, data)
+			}
+		})
+	}
+}
+
+// filled returns a T with every field set, recursively, so that no
+// omitempty field drops out of its JSON.
+func filled[T any]() T {
+	var v T
+	fill(reflect.ValueOf(&v).Elem())
+	return v
+}
+
+func fill(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer:
+		v.Set(reflect.New(v.Type().Elem()))
+		fill(v.Elem())
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			v.Set(reflect.ValueOf(time.Unix(1700000000, 0).UTC()))
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				fill(v.Field(i))
+			}
+		}
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), 1, 1)
+		fill(s.Index(0))
+		v.Set(s)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		key, elem := reflect.New(v.Type().Key()).Elem(), reflect.New(v.Type().Elem()).Elem()
+		fill(key)
+		fill(elem)
+		m.SetMapIndex(key, elem)
+		v.Set(m)
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1)
+	case reflect.Interface:
+		v.Set(reflect.ValueOf("x"))
+	}
+}
+
+func TestDaemonReady(t *testing.T) {
+	socketPath := filepath.Join(os.TempDir(), fmt.Sprintf("wg-rpc-ready-%d.sock", os.Getpid()))
+	t.Cleanup(func() { os.Remove(socketPath) })
+
+	var readiness atomic.Pointer[ReadinessData]
+	readiness.Store(&ReadinessData{InterfaceUp: true, Peers: 1})
+	server, err := NewServer(ServerConfig{
+		SocketPath:    socketPath,
+		Version:       "test",
+		GetPeers:      func() []*PeerData { return nil },
+		GetPeer:       func(string) (*PeerData, bool) { return nil, false },
+		GetPeerCounts: func() (int, int, int) { return 0, 0, 0 },
+		GetStatus:     func() *StatusData { return &StatusData{} },
+		GetReadiness:  readiness.Load,
+	})
+	if err != nil {
+		t.Fatalf("failed to create server: %v", err)
+	}
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	client, err := NewClient(socketPath)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+
+	tests := []struct {
+		params map[string]interface{}
+		ready  bool
+	}{
+		{params: nil, ready: true},
+		{params: map[string]interface{}{"min_peers": 2}, ready: false},
+		{params: map[string]interface{}{"min_peers": 0}, ready: true},
+	}
+	for _, tt := range tests {
+		result, err := client.Call("daemon.ready", tt.params)
+		if err != nil {
+			t.Fatalf("daemon.ready(%v): %v", tt.params, err)
+		}
+		status := result.(map[string]interface{})
+		if ready, _ := status["ready"].(bool); ready != tt.ready {
+			t.Errorf("daemon.ready(%v) = %v, want ready=%v", tt.params, status, tt.ready)
+		}
+	}
+
+	readiness.Store(&ReadinessData{})
+	result, err := client.Call("daemon.ready", map[string]interface{}{"min_peers": 0})
+	if err != nil {
+		t.Fatalf("daemon.ready: %v", err)
+	}
+	if status := result.(map[string]interface{}); status["ready"] == true || status["reason"] == "" {
+		t.Errorf("daemon.ready with the interface down = %v", status)
+	}
+}
+
+func TestDaemonResources(t *testing.T) {
+	s := &Server{getResourcesFn: func() []*ResourceData {
+		return []*ResourceData{
+			{Name: "fds", Used: 120, Limit: 1024},
+			{Name: "probe-sessions", Used: 980, Limit: 1024, Pressure: true},
+		}
+	}}
+	result, rpcErr := s.handleDaemonResources()
+	if rpcErr != nil {
+		t.Fatalf("daemon.resources: %v", rpcErr.Message)
+	}
+	if !result.Pressure || len(result.Resources) != 2 || result.Resources[1].Used != 980 {
+		t.Errorf("daemon.resources = %+v, want pressure from probe-sessions", result)
+	}
+
+	if _, rpcErr := (&Server{}).handleDaemonResources(); rpcErr == nil || rpcErr.Code != ErrCodeMethodNotFound {
+		t.Errorf("daemon.resources without GetResources = %v, want method not found", rpcErr)
+	}
+}
+
+func TestPeersListTagFilter(t *testing.T) {
+	s := &Server{getPeersFn: func() []*PeerData {
+		return []*PeerData{
+			{WGPubKey: "db1", Tags: map[string]string{"role": "db", "zone": "eu"}},
+			{WGPubKey: "db2", Tags: map[string]string{"role": "db", "zone": "us"}},
+			{WGPubKey: "web", Tags: map[string]string{"role": "web"}},
+			{WGPubKey: "untagged"},
+		}
+	}}
+
+	tests := []struct {
+		tags []interface{}
+		want string
+	}{
+		{tags: nil, want: "[db1 db2 web untagged]"},
+		{tags: []interface{}{"role=db"}, want: "[db1 db2]"},
+		{tags: []interface{}{"role=db", "zone=eu"}, want: "[db1]"},
+		{tags: []interface{}{"zone"}, want: "[db1 db2]"},
+		{tags: []interface{}{"role=cache"}, want: "[]"},
+	}
+	for _, tt := range tests {
+		params := map[string]interface{}{}
+		if tt.tags != nil {
+			params["tags"] = tt.tags
+		}
+		result, err := s.handlePeersList(params)
+		if err != nil {
+			t.Fatalf("peers.list %v: %v", tt.tags, err)
+		}
+		var got []string
+		for _, p := range result.Peers {
+			got = append(got, p.PubKey)
+		}
+		if fmt.Sprint(got) != tt.want {
+			t.Errorf("peers.list %v = %v, want %v", tt.tags, got, tt.want)
+		}
+	}
+
+	if _, err := s.handlePeersList(map[string]interface{}{"tags": "role=db"}); err == nil {
+		t.Error("a tags parameter that is not a list was accepted")
+	}
+}
@@ -1,11 +1,13 @@
 package ssh
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,14 +16,172 @@ import (
 )
 
 type Client struct {
-	conn *ssh.Client
+	conn    *ssh.Client
+	closers []io.Closer // jump host connections and the agent socket
+	sudo    bool
+}
+
+// Target describes how to reach a host over SSH. Unset fields fall back to
+// root on port 22 with the agent and the default keys.
+type Target struct {
+	Host         string
+	Port         int    // 0 = 22
+	User         string // "" = root; other users run commands through sudo -n
+	IdentityFile string // key tried after the agent, before the default keys
+	Jump         string // bastions to connect through, ssh -J style: [user@]host[:port][,...]
+	Agent        string // agent socket; "" = $SSH_AUTH_SOCK, "none" = no agent
 }
 
 func NewClient(host string, port int) (*Client, error) {
-	var authMethods []ssh.AuthMethod
+	return Connect(Target{Host: host, Port: port}, nil)
+}
+
+// Connect opens an SSH connection to t, through its jump hosts if it has
+// any. cfg, if not nil, fills in what t leaves unset, for t and for each
+// jump host, the way ssh_config does for ssh.
+func Connect(t Target, cfg *Config) (*Client, error) {
+	t = cfg.Apply(t)
+	c := &Client{sudo: t.User != "" && t.User != "root"}
+
+	auth, agentConn, err := authMethods(t)
+	if err != nil {
+		return nil, err
+	}
+	if agentConn != nil {
+		c.closers = append(c.closers, agentConn)
+	}
+
+	var via *ssh.Client
+	if t.Jump != "" {
+		for _, hop := range strings.Split(t.Jump, ",") {
+			hopTarget, err := ParseTarget(strings.TrimSpace(hop))
+			if err != nil {
+				c.Close()
+				return nil, fmt.Errorf("invalid jump host: %w", err)
+			}
+			hopTarget = cfg.Apply(hopTarget)
+			hopAuth, hopAgent, err := authMethods(hopTarget)
+			if err != nil {
+				c.Close()
+				return nil, err
+			}
+			if hopAgent != nil {
+				c.closers = append(c.closers, hopAgent)
+			}
+			conn, err := dial(via, hopTarget, hopAuth)
+			if err != nil {
+				c.Close()
+				return nil, fmt.Errorf("failed to reach jump host %s: %w", hopTarget.Host, err)
+			}
+			c.closers = append(c.closers, conn)
+			via = conn
+		}
+	}
+
+	conn, err := dial(via, t, auth)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	c.conn = conn
+	return c, nil
+}
+
+// ParseTarget parses [user@]host[:port].
+func ParseTarget(spec string) (Target, error) {
+	var t Target
+	if user, rest, ok := strings.Cut(spec, "@"); ok {
+		t.User, spec = user, rest
+	}
+	t.Host = spec
+	if host, port, err := net.SplitHostPort(spec); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return Target{}, fmt.Errorf("invalid port in %q", spec)
+		}
+		t.Host, t.Port = host, p
+	}
+	if t.Host == "" {
+		return Target{}, fmt.Errorf("no host in %q", spec)
+	}
+	return t, nil
+}
 
-	if sshAgent, err := net.Dial("unix", os.Getenv("SSH_AUTH_SOCK")); err == nil {
-		authMethods = append(authMethods, ssh.PublicKeysCallback(agent.NewClient(sshAgent).Signers))
+// dial connects to t directly, or through the already connected via.
+func dial(via *ssh.Client, t Target, auth []ssh.AuthMethod) (*ssh.Client, error) {
+	user := t.User
+	if user == "" {
+		user = "root"
+	}
+	port := t.Port
+	if port == 0 {
+		port = 22
+	}
+	config := &ssh.ClientConfig{
+		User:            user,
+		Auth:            auth,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         10 * time.Second,
+	}
+
+	addr := net.JoinHostPort(t.Host, strconv.Itoa(port))
+	if via == nil {
+		conn, err := ssh.Dial("tcp", addr, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to dial SSH: %w", err)
+		}
+		return conn, nil
+	}
+
+	netConn, err := via.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial SSH via jump host: %w", err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, addr, config)
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to dial SSH: %w", err)
+	}
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// authMethods returns the agent's keys, t's identity file and the default
+// keys, in that order, and the agent connection to close afterwards.
+func authMethods(t Target) ([]ssh.AuthMethod, io.Closer, error) {
+	var methods []ssh.AuthMethod
+	var agentConn io.Closer
+
+	socket := t.Agent
+	if socket == "" || socket == "SSH_AUTH_SOCK" {
+		socket = os.Getenv("SSH_AUTH_SOCK")
+	}
+	if socket != "" && socket != "none" {
+		if conn, err := net.Dial("unix", expandHome(socket)); err == nil {
+			agentConn = conn
+			methods = append(methods, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+
+	if t.IdentityFile != "" {
+		key, err := os.ReadFile(expandHome(t.IdentityFile))
+		if err != nil {
+			closeQuietly(agentConn)
+			return nil, nil, fmt.Errorf("failed to read identity file: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		var missing *ssh.PassphraseMissingError
+		switch {
+		case errors.As(err, &missing):
+			// The agent may hold the unlocked key.
+			if agentConn == nil {
+				return nil, nil, fmt.Errorf("identity file %s is passphrase-protected; add it to ssh-agent", t.IdentityFile)
+			}
+		case err != nil:
+			closeQuietly(agentConn)
+			return nil, nil, fmt.Errorf("invalid identity file %s: %w", t.IdentityFile, err)
+		default:
+			methods = append(methods, ssh.PublicKeys(signer))
+		}
 	}
 
 	homeDir, err := os.UserHomeDir()
@@ -35,30 +195,40 @@ func NewClient(host string, port int) (*Client, error) {
 		for _, keyPath := range keyPaths {
 			if key, err := os.ReadFile(keyPath); err == nil {
 				if signer, err := ssh.ParsePrivateKey(key); err == nil {
-					authMethods = append(authMethods, ssh.PublicKeys(signer))
+					methods = append(methods, ssh.PublicKeys(signer))
 				}
 			}
 		}
 	}
 
-	config := &ssh.ClientConfig{
-		User:            "root",
-		Auth:            authMethods,
-		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-		Timeout:         10 * time.Second,
-	}
+	return methods, agentConn, nil
+}
 
-	addr := fmt.Sprintf("%s:%d", host, port)
-	conn, err := ssh.Dial("tcp", addr, config)
-	if err != nil {
-		return nil, fmt.Errorf("failed to dial SSH: %w", err)
+func closeQuietly(c io.Closer) {
+	if c != nil {
+		c.Close()
 	}
-
-	return &Client{conn: conn}, nil
 }
 
 func (c *Client) Close() error {
-	return c.conn.Close()
+	var err error
+	if c.conn != nil {
+		err = c.conn.Close()
+	}
+	// Jump connections close innermost first.
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		c.closers[i].Close()
+	}
+	return err
+}
+
+// command wraps cmd in sudo for non-root users. -n makes a missing
+// NOPASSWD rule fail instead of waiting for a password.
+func (c *Client) command(cmd string) string {
+	if !c.sudo {
+		return cmd
+	}
+	return "sudo -n sh -c '" + strings.ReplaceAll(cmd, "'", `'\''`) + "'"
 }
 
 func (c *Client) Run(cmd string) (string, error) {
@@ -68,7 +238,7 @@ func (c *Client) Run(cmd string) (string, error) {
 	}
 	defer session.Close()
 
-	output, err := session.CombinedOutput(cmd)
+	output, err := session.CombinedOutput(c.command(cmd))
 	if err != nil {
 		return string(output), fmt.Errorf("command failed: %w", err)
 	}
@@ -83,7 +253,7 @@ func (c *Client) RunQuiet(cmd string) error {
 	}
 	defer session.Close()
 
-	return session.Run(cmd)
+	return session.Run(c.command(cmd))
 }
 
 func (c *Client) WriteFile(path string, content []byte, mode os.FileMode) error {
@@ -98,7 +268,7 @@ func (c *Client) WriteFile(path string, content []byte, mode os.FileMode) error
 		return fmt.Errorf("failed to get stdin: %w", err)
 	}
 
-	if err := session.Start(fmt.Sprintf("cat > %s && chmod %o %s", path, mode, path)); err != nil {
+	if err := session.Start(c.command(fmt.Sprintf("cat > %s && chmod %o %s", path, mode, path))); err != nil {
 		return fmt.Errorf("failed to start command: %w", err)
 	}
 
@@ -131,7 +301,7 @@ func (c *Client) RunWithStdin(cmd, stdinContent string) (string, error) {
 		return "", fmt.Errorf("failed to get stdin: %w", err)
 	}
 
-	if err := session.Start(cmd); err != nil {
+	if err := session.Start(c.command(cmd)); err != nil {
 		return "", fmt.Errorf("failed to start command: %w", err)
 	}
 
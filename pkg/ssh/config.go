@@ -0,0 +1,152 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Config is a parsed OpenSSH client config (ssh_config(5)). Only the
+// options that affect how wgmesh connects are read: HostName, User, Port,
+// IdentityFile, ProxyJump and IdentityAgent. Match blocks and Include are
+// not supported and are skipped.
+type Config struct {
+	blocks []configBlock
+}
+
+type configBlock struct {
+	patterns []string
+	options  map[string]string // lower-cased option -> first value in the block
+}
+
+// LoadConfig reads an ssh_config file.
+func LoadConfig(file string) (*Config, error) {
+	f, err := os.Open(expandHome(file))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ssh config: %w", err)
+	}
+	defer f.Close()
+
+	// Options before the first Host line apply to every host.
+	cfg := &Config{blocks: []configBlock{{patterns: []string{"*"}, options: map[string]string{}}}}
+	current := &cfg.blocks[0]
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value := splitConfigLine(line)
+		if value == "" {
+			return nil, fmt.Errorf("ssh config %s line %d: %q has no value", file, n, key)
+		}
+		switch key {
+		case "host":
+			cfg.blocks = append(cfg.blocks, configBlock{patterns: strings.Fields(value), options: map[string]string{}})
+			current = &cfg.blocks[len(cfg.blocks)-1]
+		case "match":
+			// Never matches, so its options are skipped.
+			cfg.blocks = append(cfg.blocks, configBlock{options: map[string]string{}})
+			current = &cfg.blocks[len(cfg.blocks)-1]
+		default:
+			if _, set := current.options[key]; !set {
+				current.options[key] = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ssh config: %w", err)
+	}
+	return cfg, nil
+}
+
+// splitConfigLine splits "Key value", "Key=value" or `Key "value"`.
+func splitConfigLine(line string) (string, string) {
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return strings.ToLower(line), ""
+	}
+	key := strings.ToLower(line[:i])
+	value := strings.TrimLeft(line[i:], " \t")
+	value = strings.TrimPrefix(value, "=")
+	value = strings.TrimSpace(value)
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+	return key, value
+}
+
+// matches reports whether host matches the block's patterns: at least one
+// positive pattern and no negated one.
+func (b *configBlock) matches(host string) bool {
+	matched := false
+	for _, p := range b.patterns {
+		negate := strings.HasPrefix(p, "!")
+		p = strings.TrimPrefix(p, "!")
+		if ok, _ := path.Match(p, host); ok {
+			if negate {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// lookup returns the first value of option among the blocks matching host.
+func (c *Config) lookup(host, option string) string {
+	for i := range c.blocks {
+		b := &c.blocks[i]
+		if v, ok := b.options[option]; ok && b.matches(host) {
+			return v
+		}
+	}
+	return ""
+}
+
+// Apply fills in the fields of t that are unset from the config entries
+// for t.Host. As with ssh, t.Host may be an alias that HostName resolves.
+func (c *Config) Apply(t Target) Target {
+	if c == nil {
+		return t
+	}
+	alias := t.Host
+	if v := c.lookup(alias, "hostname"); v != "" {
+		t.Host = strings.ReplaceAll(v, "%h", alias)
+	}
+	if v := c.lookup(alias, "user"); v != "" && t.User == "" {
+		t.User = v
+	}
+	if v := c.lookup(alias, "port"); v != "" && t.Port == 0 {
+		if p, err := strconv.Atoi(v); err == nil {
+			t.Port = p
+		}
+	}
+	if v := c.lookup(alias, "identityfile"); v != "" && t.IdentityFile == "" {
+		t.IdentityFile = strings.ReplaceAll(v, "%h", alias)
+	}
+	if v := c.lookup(alias, "proxyjump"); v != "" && t.Jump == "" && !strings.EqualFold(v, "none") {
+		t.Jump = v
+	}
+	if v := c.lookup(alias, "identityagent"); v != "" && t.Agent == "" {
+		t.Agent = v
+	}
+	return t
+}
+
+// expandHome expands a leading "~/" to the user's home directory.
+func expandHome(p string) string {
+	if !strings.HasPrefix(p, "~/") {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, p[2:])
+}
@@ -0,0 +1,96 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigApply(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(file, []byte(`# global defaults
+IdentityAgent none
+
+Host bastion
+    HostName 198.51.100.10
+    User jump
+
+Host web-* !web-legacy
+    User=deploy
+    Port 2222
+    ProxyJump bastion
+    IdentityFile "~/.ssh/web_ed25519"
+
+Match host db
+    User ignored
+
+Host *
+    User admin
+    Port 22
+`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(file)
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+
+	got := cfg.Apply(Target{Host: "web-1"})
+	want := Target{Host: "web-1", User: "deploy", Port: 2222, Jump: "bastion", IdentityFile: "~/.ssh/web_ed25519", Agent: "none"}
+	if got != want {
+		t.Errorf("Apply(web-1) = %+v, want %+v", got, want)
+	}
+
+	// Explicit fields win over the config.
+	got = cfg.Apply(Target{Host: "web-2", User: "root", Port: 22})
+	if got.User != "root" || got.Port != 22 || got.Jump != "bastion" {
+		t.Errorf("Apply(web-2 explicit) = %+v", got)
+	}
+
+	if got := cfg.Apply(Target{Host: "web-legacy"}); got.User != "admin" || got.Jump != "" {
+		t.Errorf("negated pattern should not match, got %+v", got)
+	}
+	if got := cfg.Apply(Target{Host: "bastion"}); got.Host != "198.51.100.10" || got.User != "jump" {
+		t.Errorf("Apply(bastion) = %+v", got)
+	}
+	if got := cfg.Apply(Target{Host: "db"}); got.User != "admin" {
+		t.Errorf("Match blocks should be skipped, got %+v", got)
+	}
+
+	var none *Config
+	if got := none.Apply(Target{Host: "x"}); got != (Target{Host: "x"}) {
+		t.Errorf("nil config changed the target: %+v", got)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Target
+		wantErr bool
+	}{
+		{"host", Target{Host: "host"}, false},
+		{"ops@host:2200", Target{Host: "host", User: "ops", Port: 2200}, false},
+		{"[2001:db8::1]:22", Target{Host: "2001:db8::1", Port: 22}, false},
+		{"host:99999", Target{}, true},
+		{"ops@", Target{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTarget(tt.spec)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTarget(%q) = %+v, %v; want %+v, err %v", tt.spec, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestClientCommandSudo(t *testing.T) {
+	root := &Client{}
+	if got := root.command("wg show"); got != "wg show" {
+		t.Errorf("root command = %q", got)
+	}
+	user := &Client{sudo: true}
+	if got := user.command("echo 'hi' > /etc/x"); got != `sudo -n sh -c 'echo '\''hi'\'' > /etc/x'` {
+		t.Errorf("sudo command = %q", got)
+	}
+}
@@ -0,0 +1,138 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer spans are recorded with.
+const instrumentationName = "github.com/atvirokodosprendimai/wgmesh"
+
+type provider struct {
+	tp     *sdktrace.TracerProvider
+	tracer trace.Tracer
+}
+
+// Init starts exporting spans if the OTEL environment asks for it and
+// returns a function that flushes pending spans and stops the exporter.
+// serviceName and version describe this process unless OTEL_SERVICE_NAME
+// overrides the name. When tracing is not configured, Init does nothing and
+// the returned function is a no-op.
+//
+// The exporter, batching and sampling are the SDK's, so everything they
+// read from the environment applies: the OTEL_EXPORTER_OTLP_* endpoint,
+// headers, timeout, compression and TLS settings, OTEL_BSP_* and
+// OTEL_TRACES_SAMPLER.
+func Init(serviceName, version string) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+
+	endpoint, err := tracesEndpoint()
+	if err != nil || endpoint == "" {
+		return noop, err
+	}
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") || os.Getenv("OTEL_TRACES_EXPORTER") == "none" {
+		return noop, nil
+	}
+
+	ctx := context.Background()
+	protocol := tracesProtocol()
+	var exp sdktrace.SpanExporter
+	if protocol == "grpc" {
+		exp, err = otlptracegrpc.New(ctx)
+	} else {
+		exp, err = otlptracehttp.New(ctx)
+	}
+	if err != nil {
+		return noop, fmt.Errorf("create OTLP %s exporter: %w", protocol, err)
+	}
+
+	// Later options win, so OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES
+	// override what the binary says about itself.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(serviceName), semconv.ServiceVersion(version)),
+		resource.WithHost(),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil && !errors.Is(err, resource.ErrPartialResource) {
+		_ = exp.Shutdown(ctx)
+		return noop, fmt.Errorf("build trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	p := &provider{tp: tp, tracer: tp.Tracer(instrumentationName, trace.WithInstrumentationVersion(version))}
+	if !active.CompareAndSwap(nil, p) {
+		_ = tp.Shutdown(ctx)
+		return noop, fmt.Errorf("tracing already initialized")
+	}
+
+	log.Printf("[Tracing] Exporting spans to %s over OTLP %s", endpoint, protocol)
+	return p.shutdown, nil
+}
+
+// tracesEndpoint returns the configured OTLP endpoint, or "" when tracing
+// is not configured. The exporters read it themselves; it is checked here
+// so a malformed value is reported instead of silently dropping spans.
+func tracesEndpoint() (string, error) {
+	raw := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"))
+	if raw == "" {
+		raw = strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
+	}
+	if raw == "" {
+		return "", nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid OTLP endpoint %q: want http(s)://host[:port]", raw)
+	}
+	return u.String(), nil
+}
+
+// tracesProtocol returns the OTLP transport to export with: "grpc" or the
+// specification's default, "http/protobuf". The SDK has no http/json
+// exporter, so that falls back to protobuf.
+func tracesProtocol() string {
+	p := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"))
+	if p == "" {
+		p = strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL"))
+	}
+	switch p {
+	case "", "http/protobuf":
+		return "http/protobuf"
+	case "grpc":
+		return "grpc"
+	default:
+		log.Printf("[Tracing] OTLP protocol %q is not supported, exporting http/protobuf", p)
+		return "http/protobuf"
+	}
+}
+
+// shutdown exports what is queued and stops the exporter. Spans started
+// afterwards are not recorded.
+func (p *provider) shutdown(ctx context.Context) error {
+	if !active.CompareAndSwap(p, nil) {
+		return nil
+	}
+	return p.tp.Shutdown(ctx)
+}
+
+// Flush exports every span ended so far, waiting at most until ctx is done.
+func Flush(ctx context.Context) error {
+	p := active.Load()
+	if p == nil {
+		return nil
+	}
+	return p.tp.ForceFlush(ctx)
+}
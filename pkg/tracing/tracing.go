@@ -0,0 +1,119 @@
+// Package tracing records OpenTelemetry spans for the mesh daemon and exports
+// them with the OpenTelemetry SDK's OTLP exporters.
+//
+// Tracing is off unless OTEL_EXPORTER_OTLP_ENDPOINT (or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT) is set, in which case Init installs an
+// SDK tracer provider with a batching OTLP exporter. While it is off, Start
+// returns a nil *Span whose methods do nothing, so instrumented code carries
+// no checks of its own.
+//
+// The package is a thin layer over the SDK so that the rest of the tree
+// does not import it directly.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attr is a span attribute. Values are string, int64 or bool.
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+// String returns a string attribute.
+func String(key, value string) Attr { return Attr{Key: key, Value: value} }
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attr { return Attr{Key: key, Value: int64(value)} }
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attr { return Attr{Key: key, Value: value} }
+
+// Span is one timed operation. A nil *Span is valid and records nothing.
+type Span struct {
+	span trace.Span
+}
+
+// active is the provider installed by Init, or nil while tracing is off.
+var active atomic.Pointer[provider]
+
+// Enabled reports whether spans are being exported.
+func Enabled() bool {
+	return active.Load() != nil
+}
+
+// Start begins a span named name. If ctx carries a span, the new span is
+// its child; otherwise it starts a new trace. The returned context carries
+// the new span for further children.
+func Start(ctx context.Context, name string, attrs ...Attr) (context.Context, *Span) {
+	p := active.Load()
+	if p == nil {
+		return ctx, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := p.tracer.Start(ctx, name, trace.WithAttributes(keyValues(attrs)...))
+	return ctx, &Span{span: span}
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attrs ...Attr) {
+	if s == nil {
+		return
+	}
+	s.span.SetAttributes(keyValues(attrs)...)
+}
+
+// RecordError marks the span as failed with err. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+// End finishes the span and queues it for export. Later calls do nothing.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.span.End()
+}
+
+// TraceID returns the span's trace ID in hex, or "" for a nil span. It lets
+// log lines be matched with traces.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+	return s.span.SpanContext().TraceID().String()
+}
+
+func keyValues(attrs []Attr) []attribute.KeyValue {
+	if len(attrs) == 0 {
+		return nil
+	}
+	kvs := make([]attribute.KeyValue, len(attrs))
+	for i, a := range attrs {
+		switch v := a.Value.(type) {
+		case string:
+			kvs[i] = attribute.String(a.Key, v)
+		case int64:
+			kvs[i] = attribute.Int64(a.Key, v)
+		case bool:
+			kvs[i] = attribute.Bool(a.Key, v)
+		default:
+			kvs[i] = attribute.String(a.Key, fmt.Sprint(v))
+		}
+	}
+	return kvs
+}
@@ -0,0 +1,203 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestStartWithoutEndpointIsNoop(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+
+	shutdown, err := Init("wgmesh", "test")
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	defer shutdown(context.Background())
+
+	if Enabled() {
+		t.Fatal("tracing should be off without an endpoint")
+	}
+	ctx, span := Start(context.Background(), "noop")
+	if span != nil || ctx == nil {
+		t.Fatal("Start should return the context and a nil span")
+	}
+	span.SetAttributes(String("k", "v"))
+	span.RecordError(errors.New("boom"))
+	span.End()
+}
+
+func TestInitRejectsBadEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "collector:4318")
+	if _, err := Init("wgmesh", "test"); err == nil {
+		t.Fatal("expected error for endpoint without scheme")
+	}
+}
+
+// exportSpans records a failed child under a parent span, ends the parent
+// twice, and shuts tracing down so that everything is exported.
+func exportSpans(t *testing.T) {
+	t.Helper()
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_SERVICE_NAME", "mesh-node")
+	t.Setenv("OTEL_BSP_SCHEDULE_DELAY", "60000")
+
+	shutdown, err := Init("wgmesh", "test")
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if !Enabled() {
+		t.Fatal("tracing should be on with an endpoint")
+	}
+
+	ctx, parent := Start(context.Background(), "reconcile", Int("peers", 3))
+	_, child := Start(ctx, "reconcile.apply")
+	child.RecordError(errors.New("wg failed"))
+	child.End()
+	parent.End()
+	parent.End()
+	if child.TraceID() != parent.TraceID() || len(parent.TraceID()) != 32 {
+		t.Errorf("trace IDs %q and %q", child.TraceID(), parent.TraceID())
+	}
+
+	if err := Flush(context.Background()); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdown(sctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if Enabled() {
+		t.Fatal("tracing should be off after shutdown")
+	}
+}
+
+// checkExport verifies what exportSpans sent.
+func checkExport(t *testing.T, requests []*coltracepb.ExportTraceServiceRequest) {
+	t.Helper()
+	if len(requests) != 1 {
+		t.Fatalf("got %d export requests, want 1", len(requests))
+	}
+	rs := requests[0].ResourceSpans[0]
+	if got := stringAttr(rs.Resource.Attributes, "service.name"); got != "mesh-node" {
+		t.Errorf("service.name = %q, want mesh-node", got)
+	}
+	if got := stringAttr(rs.Resource.Attributes, "service.version"); got != "test" {
+		t.Errorf("service.version = %q, want test", got)
+	}
+	spans := rs.ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("got %d spans, want 2 (End is idempotent)", len(spans))
+	}
+	c, p := spans[0], spans[1]
+	if p.Name != "reconcile" || c.Name != "reconcile.apply" {
+		t.Fatalf("unexpected span order: %s, %s", c.Name, p.Name)
+	}
+	if !bytes.Equal(c.TraceId, p.TraceId) || !bytes.Equal(c.ParentSpanId, p.SpanId) || len(p.ParentSpanId) != 0 {
+		t.Error("child span is not linked to its parent")
+	}
+	if c.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR || c.Status.GetMessage() != "wg failed" {
+		t.Errorf("child status = %v, want error", c.Status)
+	}
+	if p.Status.GetCode() == tracepb.Status_STATUS_CODE_ERROR || len(p.Attributes) != 1 || p.Attributes[0].Value.GetIntValue() != 3 {
+		t.Errorf("parent status %v, attributes %v", p.Status, p.Attributes)
+	}
+}
+
+func stringAttr(attrs []*commonpb.KeyValue, key string) string {
+	for _, a := range attrs {
+		if a.Key == key {
+			return a.Value.GetStringValue()
+		}
+	}
+	return ""
+}
+
+func TestExportOTLPHTTP(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []*coltracepb.ExportTraceServiceRequest
+		headers  []string
+		paths    []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		req := &coltracepb.ExportTraceServiceRequest{}
+		if err == nil {
+			err = proto.Unmarshal(body, req)
+		}
+		if err != nil {
+			t.Errorf("bad request body: %v", err)
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		headers = append(headers, r.Header.Get("Authorization"))
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer srv.Close()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", srv.URL)
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer%20token")
+	exportSpans(t)
+
+	mu.Lock()
+	defer mu.Unlock()
+	checkExport(t, requests)
+	if paths[0] != "/v1/traces" || headers[0] != "Bearer token" {
+		t.Errorf("path %q, Authorization %q", paths[0], headers[0])
+	}
+}
+
+// traceCollector is an OTLP gRPC trace receiver.
+type traceCollector struct {
+	coltracepb.UnimplementedTraceServiceServer
+
+	mu       sync.Mutex
+	requests []*coltracepb.ExportTraceServiceRequest
+}
+
+func (c *traceCollector) Export(_ context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests = append(c.requests, req)
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func TestExportOTLPGRPC(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector := &traceCollector{}
+	srv := grpc.NewServer()
+	coltracepb.RegisterTraceServiceServer(srv, collector)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://"+lis.Addr().String())
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "")
+	exportSpans(t)
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	checkExport(t, collector.requests)
+}
@@ -0,0 +1,322 @@
+// Package update finds wgmesh releases on GitHub, verifies them against the
+// release signing key and installs them over the running binary. Release
+// builds sign checksums.txt with an ed25519 key (see cmd/sign-release);
+// the archive for this platform must match the signed checksum before its
+// binary is installed.
+package update
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRepo is the GitHub repository releases come from.
+	DefaultRepo = "atvirokodosprendimai/wgmesh"
+	// DefaultAPIURL is the GitHub API endpoint.
+	DefaultAPIURL = "https://api.github.com"
+
+	// ChecksumsAsset and SignatureAsset are the release files that tie the
+	// archives to the signing key.
+	ChecksumsAsset = "checksums.txt"
+	SignatureAsset = "checksums.txt.sig"
+
+	maxChecksumsSize = 64 << 10
+	maxArchiveSize   = 200 << 20
+	requestTimeout   = 5 * time.Minute
+)
+
+// PublicKey is the base64 ed25519 key release checksums are signed with.
+// Release builds set it with -ldflags "-X .../pkg/update.PublicKey=...";
+// builds without it cannot update themselves.
+var PublicKey string
+
+// ErrNoKey is returned by NewClient for builds without a release key.
+var ErrNoKey = errors.New("this build has no release signing key; install updates with your package manager")
+
+// Release is a published wgmesh release.
+type Release struct {
+	Tag        string
+	Prerelease bool
+	Assets     map[string]string // file name → download URL
+}
+
+// Version returns the release version without the leading "v".
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+// Client fetches and verifies releases.
+type Client struct {
+	HTTP   *http.Client
+	APIURL string
+	Repo   string
+	Key    ed25519.PublicKey
+}
+
+// NewClient returns a client for DefaultRepo that trusts PublicKey.
+func NewClient() (*Client, error) {
+	if PublicKey == "" {
+		return nil, ErrNoKey
+	}
+	key, err := base64.StdEncoding.DecodeString(PublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid release signing key built into this binary")
+	}
+	return &Client{
+		HTTP:   &http.Client{Timeout: requestTimeout},
+		APIURL: DefaultAPIURL,
+		Repo:   DefaultRepo,
+		Key:    ed25519.PublicKey(key),
+	}, nil
+}
+
+// Latest returns the newest release that is not a prerelease.
+func (c *Client) Latest(ctx context.Context) (*Release, error) {
+	return c.release(ctx, "latest")
+}
+
+// Tag returns the release with the given tag, e.g. "v1.4.0".
+func (c *Client) Tag(ctx context.Context, tag string) (*Release, error) {
+	if !strings.HasPrefix(tag, "v") {
+		tag = "v" + tag
+	}
+	return c.release(ctx, "tags/"+tag)
+}
+
+func (c *Client) release(ctx context.Context, which string) (*Release, error) {
+	body, err := c.get(ctx, fmt.Sprintf("%s/repos/%s/releases/%s", c.APIURL, c.Repo, which), 1<<20)
+	if err != nil {
+		return nil, err
+	}
+	var rel struct {
+		TagName    string `json:"tag_name"`
+		Prerelease bool   `json:"prerelease"`
+		Assets     []struct {
+			Name string `json:"name"`
+			URL  string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.Unmarshal(body, &rel); err != nil {
+		return nil, fmt.Errorf("parse release: %w", err)
+	}
+	if rel.TagName == "" {
+		return nil, fmt.Errorf("release has no tag")
+	}
+	out := &Release{Tag: rel.TagName, Prerelease: rel.Prerelease, Assets: make(map[string]string)}
+	for _, a := range rel.Assets {
+		out.Assets[a.Name] = a.URL
+	}
+	return out, nil
+}
+
+// Download fetches the release's binary for this platform. It fails unless
+// checksums.txt carries a valid signature by c.Key and lists the archive's
+// SHA-256.
+func (c *Client) Download(ctx context.Context, rel *Release) ([]byte, error) {
+	archive := ArchiveName(rel.Version(), runtime.GOOS, runtime.GOARCH)
+	for _, name := range []string{ChecksumsAsset, SignatureAsset, archive} {
+		if rel.Assets[name] == "" {
+			return nil, fmt.Errorf("release %s has no %s", rel.Tag, name)
+		}
+	}
+
+	checksums, err := c.get(ctx, rel.Assets[ChecksumsAsset], maxChecksumsSize)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := c.get(ctx, rel.Assets[SignatureAsset], maxChecksumsSize)
+	if err != nil {
+		return nil, err
+	}
+	if err := VerifyChecksums(c.Key, checksums, sig); err != nil {
+		return nil, fmt.Errorf("release %s: %w", rel.Tag, err)
+	}
+	want, err := checksumFor(checksums, archive)
+	if err != nil {
+		return nil, fmt.Errorf("release %s: %w", rel.Tag, err)
+	}
+
+	data, err := c.get(ctx, rel.Assets[archive], maxArchiveSize)
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha256.Sum256(data); !bytes.Equal(sum[:], want) {
+		return nil, fmt.Errorf("release %s: %s does not match its signed checksum", rel.Tag, archive)
+	}
+	return extractBinary(data)
+}
+
+func (c *Client) get(ctx context.Context, url string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json, application/octet-stream")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("GET %s: response larger than %d bytes", url, limit)
+	}
+	return data, nil
+}
+
+// ArchiveName returns the release archive for a platform, as named by
+// .goreleaser.yml. Release builds for arm are ARMv7.
+func ArchiveName(version, goos, goarch string) string {
+	if goarch == "arm" {
+		goarch = "armv7"
+	}
+	return fmt.Sprintf("wgmesh_%s_%s_%s.tar.gz", version, goos, goarch)
+}
+
+// SignChecksums signs a checksums.txt, returning the base64 signature
+// stored as checksums.txt.sig.
+func SignChecksums(key ed25519.PrivateKey, checksums []byte) []byte {
+	return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, checksums)) + "\n")
+}
+
+// VerifyChecksums checks a checksums.txt.sig made by SignChecksums.
+func VerifyChecksums(key ed25519.PublicKey, checksums, sig []byte) error {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || !ed25519.Verify(key, checksums, raw) {
+		return errors.New("checksums.txt signature does not verify against the release key")
+	}
+	return nil
+}
+
+// checksumFor finds a file's SHA-256 in sha256sum output.
+func checksumFor(checksums []byte, name string) ([]byte, error) {
+	sc := bufio.NewScanner(bytes.NewReader(checksums))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return hex.DecodeString(fields[0])
+		}
+	}
+	return nil, fmt.Errorf("%s is not in %s", name, ChecksumsAsset)
+}
+
+// extractBinary returns the wgmesh binary of a release archive.
+func extractBinary(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.New("archive has no wgmesh binary")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag == tar.TypeReg && filepath.Base(hdr.Name) == "wgmesh" {
+			return io.ReadAll(io.LimitReader(tr, maxArchiveSize))
+		}
+	}
+}
+
+// Install replaces the binary at path with data. The new binary is written
+// next to it and renamed over it, so a crash leaves either the old or the
+// new binary, and a running process keeps its old image.
+func Install(path string, data []byte) error {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(resolved), ".wgmesh-update-*")
+	if err != nil {
+		return fmt.Errorf("cannot write next to %s (run as root?): %w", resolved, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), resolved)
+}
+
+// Newer reports whether release version latest is newer than current.
+// Versions are compared as MAJOR.MINOR.PATCH with an optional "v"; a
+// current version that is not one, such as "dev", is never older.
+func Newer(current, latest string) bool {
+	cur, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	next, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	for i := range cur {
+		if next[i] != cur[i] {
+			return next[i] > cur[i]
+		}
+	}
+	return false
+}
+
+func parseVersion(v string) ([3]int, bool) {
+	var out [3]int
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return out, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
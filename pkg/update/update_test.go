@@ -0,0 +1,163 @@
+package update
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func tarGz(t *testing.T, name string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{{"README.md", []byte("readme")}, {name, data}} {
+		if err := tw.WriteHeader(&tar.Header{Name: f.name, Mode: 0755, Size: int64(len(f.data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(f.data)
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// releaseServer serves release v1.2.0 with the given files; files maps
+// asset names to their contents.
+func releaseServer(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("/repos/o/r/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		var assets []map[string]string
+		for name := range files {
+			assets = append(assets, map[string]string{"name": name, "browser_download_url": srv.URL + "/dl/" + name})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"tag_name": "v1.2.0", "assets": assets})
+	})
+	mux.HandleFunc("/dl/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(files[strings.TrimPrefix(r.URL.Path, "/dl/")])
+	})
+	return srv
+}
+
+func TestDownloadVerifiesRelease(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	binary := []byte("#!/bin/sh\necho new wgmesh\n")
+	archiveName := ArchiveName("1.2.0", runtime.GOOS, runtime.GOARCH)
+	archive := tarGz(t, "wgmesh", binary)
+	sum := sha256.Sum256(archive)
+	checksums := []byte(fmt.Sprintf("%x  %s\n%x  other.tar.gz\n", sum, archiveName, sha256.Sum256(nil)))
+
+	files := map[string][]byte{
+		archiveName:    archive,
+		ChecksumsAsset: checksums,
+		SignatureAsset: SignChecksums(priv, checksums),
+	}
+	srv := releaseServer(t, files)
+	c := &Client{HTTP: srv.Client(), APIURL: srv.URL, Repo: "o/r", Key: pub}
+
+	rel, err := c.Latest(context.Background())
+	if err != nil {
+		t.Fatalf("Latest: %v", err)
+	}
+	if rel.Version() != "1.2.0" {
+		t.Errorf("Version = %q, want 1.2.0", rel.Version())
+	}
+	got, err := c.Download(context.Background(), rel)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if !bytes.Equal(got, binary) {
+		t.Errorf("binary = %q, want %q", got, binary)
+	}
+
+	// A swapped archive fails its signed checksum.
+	files[archiveName] = tarGz(t, "wgmesh", []byte("evil"))
+	if _, err := c.Download(context.Background(), rel); err == nil {
+		t.Error("Download accepted an archive that does not match the signed checksum")
+	}
+
+	// Checksums signed by another key are rejected.
+	_, other, _ := ed25519.GenerateKey(nil)
+	files[archiveName] = archive
+	files[SignatureAsset] = SignChecksums(other, checksums)
+	if _, err := c.Download(context.Background(), rel); err == nil || !strings.Contains(err.Error(), "signature") {
+		t.Errorf("Download with a foreign signature: err = %v", err)
+	}
+}
+
+func TestInstall(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wgmesh")
+	if err := os.WriteFile(path, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "wgmesh-link")
+	if err := os.Symlink(path, link); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Install(link, []byte("new")); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Errorf("binary = %q, %v; want new", data, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("mode = %v, %v; want 0755", info.Mode(), err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("leftover files: %v", entries)
+	}
+}
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            bool
+	}{
+		{"1.2.0", "v1.2.1", true},
+		{"v1.2.0", "1.10.0", true},
+		{"1.2.0", "v2.0.0", true},
+		{"1.2.0", "v1.2.0", false},
+		{"1.3.0", "v1.2.9", false},
+		{"1.2.0-rc1", "v1.2.0", false},
+		{"dev", "v9.9.9", false},
+		{"1.2.0", "nightly", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.current, tt.latest); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+}
+
+func TestArchiveName(t *testing.T) {
+	if got := ArchiveName("1.2.0", "linux", "arm"); got != "wgmesh_1.2.0_linux_armv7.tar.gz" {
+		t.Errorf("ArchiveName(arm) = %q", got)
+	}
+	if got := ArchiveName("1.2.0", "darwin", "arm64"); got != "wgmesh_1.2.0_darwin_arm64.tar.gz" {
+		t.Errorf("ArchiveName(darwin/arm64) = %q", got)
+	}
+}
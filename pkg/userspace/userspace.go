@@ -0,0 +1,398 @@
+// Package userspace runs WireGuard and a TCP/IP stack inside the process,
+// with wireguard-go and gVisor's netstack, so a mesh node needs no root
+// and no kernel interface. The daemon drives a Tunnel through
+// daemon.Tunnel; programs reach the mesh through its Listen and DialContext.
+package userspace
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.zx2c4.com/wireguard/conn"
+	"golang.zx2c4.com/wireguard/device"
+	"golang.zx2c4.com/wireguard/tun/netstack"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+// ErrNotUp is returned by a Tunnel that has not been brought up, or was
+// closed.
+var ErrNotUp = errors.New("userspace WireGuard is not up")
+
+// Tunnel is a wireguard-go device on a netstack. The interface name the
+// wireguard.Backend methods take is ignored: a Tunnel is its own device.
+type Tunnel struct {
+	mu    sync.Mutex
+	dev   *device.Device
+	net   *netstack.Net
+	addrs []netip.Addr
+}
+
+// New returns a Tunnel that is brought up by Up.
+func New() *Tunnel {
+	return &Tunnel{}
+}
+
+var logger = &device.Logger{
+	Verbosef: device.DiscardLogf,
+	Errorf: func(format string, args ...any) {
+		log.Printf("[WireGuard] "+format, args...)
+	},
+}
+
+// Up starts the device with the base64 privateKey on listenPort and gives
+// the stack addrs.
+func (t *Tunnel) Up(privateKey string, listenPort int, addrs []netip.Addr, mtu int) error {
+	key, err := hexKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("invalid private key: %w", err)
+	}
+	tunDev, tnet, err := netstack.CreateNetTUN(addrs, nil, mtu)
+	if err != nil {
+		return err
+	}
+	dev := device.NewDevice(tunDev, conn.NewDefaultBind(), logger)
+	if err := dev.IpcSet(fmt.Sprintf("private_key=%s\nlisten_port=%d\n", key, listenPort)); err != nil {
+		dev.Close()
+		return err
+	}
+	if err := dev.Up(); err != nil {
+		dev.Close()
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.dev != nil {
+		dev.Close()
+		return fmt.Errorf("userspace WireGuard is already up")
+	}
+	t.dev, t.net, t.addrs = dev, tnet, addrs
+	return nil
+}
+
+// Close stops the device. Listeners and connections on its stack fail
+// from then on.
+func (t *Tunnel) Close() error {
+	t.mu.Lock()
+	dev := t.dev
+	t.dev, t.net, t.addrs = nil, nil, nil
+	t.mu.Unlock()
+	if dev == nil {
+		return ErrNotUp
+	}
+	dev.Close()
+	return nil
+}
+
+func (t *Tunnel) device() (*device.Device, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.dev == nil {
+		return nil, ErrNotUp
+	}
+	return t.dev, nil
+}
+
+func (t *Tunnel) stack() (*netstack.Net, []netip.Addr, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.net == nil {
+		return nil, nil, ErrNotUp
+	}
+	return t.net, t.addrs, nil
+}
+
+// SetListenPort moves the WireGuard listen port.
+func (t *Tunnel) SetListenPort(port int) error {
+	dev, err := t.device()
+	if err != nil {
+		return err
+	}
+	return dev.IpcSet(fmt.Sprintf("listen_port=%d\n", port))
+}
+
+// SetPeer adds or updates a peer the way "wg set" does: a zero psk and
+// an empty endpoint or allowedIPs leave the current value alone.
+func (t *Tunnel) SetPeer(_, pubKey string, psk [32]byte, endpoint, allowedIPs string, keepalive int) error {
+	dev, err := t.device()
+	if err != nil {
+		return err
+	}
+	key, err := hexKey(pubKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "public_key=%s\n", key)
+	if psk != [32]byte{} {
+		fmt.Fprintf(&b, "preshared_key=%s\n", hex.EncodeToString(psk[:]))
+	}
+	if endpoint != "" {
+		addr, err := resolveEndpoint(endpoint)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "endpoint=%s\n", addr)
+	}
+	if allowedIPs != "" {
+		b.WriteString("replace_allowed_ips=true\n")
+		for _, prefix := range strings.Split(allowedIPs, ",") {
+			fmt.Fprintf(&b, "allowed_ip=%s\n", strings.TrimSpace(prefix))
+		}
+	}
+	fmt.Fprintf(&b, "persistent_keepalive_interval=%d\n", max(keepalive, 0))
+	return dev.IpcSet(b.String())
+}
+
+// resolveEndpoint turns host:port into the ip:port the device accepts,
+// looking the host up like wg does.
+func resolveEndpoint(endpoint string) (netip.AddrPort, error) {
+	if addr, err := netip.ParseAddrPort(endpoint); err == nil {
+		return addr, nil
+	}
+	addr, err := net.ResolveUDPAddr("udp", endpoint)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	return addr.AddrPort(), nil
+}
+
+// RemovePeer removes a peer.
+func (t *Tunnel) RemovePeer(_, pubKey string) error {
+	dev, err := t.device()
+	if err != nil {
+		return err
+	}
+	key, err := hexKey(pubKey)
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+	return dev.IpcSet(fmt.Sprintf("public_key=%s\nremove=true\n", key))
+}
+
+// GetPeers returns the configured peers.
+func (t *Tunnel) GetPeers(string) ([]wireguard.WGPeer, error) {
+	peers, err := t.peers()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]wireguard.WGPeer, 0, len(peers))
+	for _, p := range peers {
+		out = append(out, p.WGPeer)
+	}
+	return out, nil
+}
+
+// GetLatestHandshakes returns each peer's latest handshake as a Unix
+// timestamp, 0 for none.
+func (t *Tunnel) GetLatestHandshakes(string) (map[string]int64, error) {
+	peers, err := t.peers()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]int64, len(peers))
+	for _, p := range peers {
+		out[p.PublicKey] = p.handshake
+	}
+	return out, nil
+}
+
+// GetPeerTransfers returns each peer's byte counters.
+func (t *Tunnel) GetPeerTransfers(string) (map[string]wireguard.PeerTransfer, error) {
+	peers, err := t.peers()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]wireguard.PeerTransfer, len(peers))
+	for _, p := range peers {
+		out[p.PublicKey] = p.transfer
+	}
+	return out, nil
+}
+
+func (t *Tunnel) peers() ([]peerState, error) {
+	dev, err := t.device()
+	if err != nil {
+		return nil, err
+	}
+	uapi, err := dev.IpcGet()
+	if err != nil {
+		return nil, err
+	}
+	return parsePeers(uapi), nil
+}
+
+// peerState is one peer of the device's UAPI "get" output.
+type peerState struct {
+	wireguard.WGPeer
+	handshake int64
+	transfer  wireguard.PeerTransfer
+}
+
+// parsePeers reads the peers from UAPI "get" output: key=value lines where
+// each public_key starts a new peer. Keys are hex there and base64 here.
+func parsePeers(uapi string) []peerState {
+	var peers []peerState
+	var p *peerState
+	scanner := bufio.NewScanner(strings.NewReader(uapi))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		if key == "public_key" {
+			raw, err := hex.DecodeString(value)
+			if err != nil {
+				p = nil
+				continue
+			}
+			peers = append(peers, peerState{WGPeer: wireguard.WGPeer{PublicKey: base64.StdEncoding.EncodeToString(raw)}})
+			p = &peers[len(peers)-1]
+			continue
+		}
+		if p == nil {
+			continue
+		}
+		switch key {
+		case "endpoint":
+			p.Endpoint = value
+		case "allowed_ip":
+			p.AllowedIPs = append(p.AllowedIPs, value)
+		case "persistent_keepalive_interval":
+			p.PersistentKeepalive, _ = strconv.Atoi(value)
+		case "last_handshake_time_sec":
+			p.handshake, _ = strconv.ParseInt(value, 10, 64)
+		case "rx_bytes":
+			p.transfer.RxBytes, _ = strconv.ParseUint(value, 10, 64)
+		case "tx_bytes":
+			p.transfer.TxBytes, _ = strconv.ParseUint(value, 10, 64)
+		}
+	}
+	return peers
+}
+
+// hexKey converts a base64 WireGuard key to the hex the UAPI takes.
+func hexKey(key string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) != 32 {
+		return "", fmt.Errorf("key is %d bytes, want 32", len(raw))
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// Listen accepts TCP connections on the stack. An empty or unspecified
+// host listens on the mesh IPv4 address, or IPv6 for tcp6: the stack has
+// no wildcard binds.
+func (t *Tunnel) Listen(network, address string) (net.Listener, error) {
+	tnet, addrs, err := t.stack()
+	if err != nil {
+		return nil, err
+	}
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, net.UnknownNetworkError(network)
+	}
+	addr, err := localAddr(network, address, addrs)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := tnet.ListenTCPAddrPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	return ln, nil
+}
+
+// ListenPacket receives UDP datagrams on the stack, with the same
+// addresses as Listen.
+func (t *Tunnel) ListenPacket(network, address string) (net.PacketConn, error) {
+	tnet, addrs, err := t.stack()
+	if err != nil {
+		return nil, err
+	}
+	if network != "udp" && network != "udp4" && network != "udp6" {
+		return nil, net.UnknownNetworkError(network)
+	}
+	addr, err := localAddr(network, address, addrs)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := tnet.ListenUDPAddrPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// DialContext connects over the stack to an IP literal address.
+func (t *Tunnel) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	tnet, _, err := t.stack()
+	if err != nil {
+		return nil, err
+	}
+	addr, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", address, err)
+	}
+	switch network {
+	case "tcp", "tcp4", "tcp6":
+		conn, err := tnet.DialContextTCPAddrPort(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	case "udp", "udp4", "udp6":
+		conn, err := tnet.DialUDPAddrPort(netip.AddrPort{}, addr)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+	return nil, net.UnknownNetworkError(network)
+}
+
+// localAddr parses a listen address, replacing an empty or unspecified
+// host with the stack address of the network's family.
+func localAddr(network, address string, addrs []netip.Addr) (netip.AddrPort, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return netip.AddrPort{}, fmt.Errorf("invalid port %q", portStr)
+	}
+	var ip netip.Addr
+	if host != "" {
+		if ip, err = netip.ParseAddr(host); err != nil {
+			return netip.AddrPort{}, fmt.Errorf("listen on %q: host must be an IP address", address)
+		}
+	}
+	if !ip.IsValid() || ip.IsUnspecified() {
+		v6 := strings.HasSuffix(network, "6") || ip.Is6()
+		ip = netip.Addr{}
+		for _, addr := range addrs {
+			if addr.Is6() == v6 {
+				ip = addr
+				break
+			}
+		}
+		if !ip.IsValid() {
+			return netip.AddrPort{}, fmt.Errorf("listen on %q: no mesh address for %s", address, network)
+		}
+	}
+	return netip.AddrPortFrom(ip, uint16(port)), nil
+}
@@ -0,0 +1,155 @@
+package userspace
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/wireguard"
+)
+
+func TestParsePeers(t *testing.T) {
+	uapi := "private_key=e84b5a6d2717c1003a13b431570353dbaca9146cf150c5f8575680feba52027a\n" +
+		"listen_port=51820\n" +
+		"public_key=b85996fecc9c7f1fc6d2572a76eda11d59bcd20be8e543b15ce4bd85a8e75a33\n" +
+		"preshared_key=0000000000000000000000000000000000000000000000000000000000000000\n" +
+		"protocol_version=1\n" +
+		"endpoint=192.0.2.7:51820\n" +
+		"last_handshake_time_sec=1700000000\n" +
+		"last_handshake_time_nsec=5\n" +
+		"tx_bytes=38333\n" +
+		"rx_bytes=2224\n" +
+		"persistent_keepalive_interval=25\n" +
+		"allowed_ip=10.42.0.7/32\n" +
+		"allowed_ip=192.168.4.0/24\n" +
+		"public_key=58402e695ba1772b1cc9309755f043251ea77fdcf10fbe63989ceb7e19321376\n" +
+		"preshared_key=0000000000000000000000000000000000000000000000000000000000000000\n" +
+		"protocol_version=1\n" +
+		"last_handshake_time_sec=0\n" +
+		"last_handshake_time_nsec=0\n" +
+		"tx_bytes=0\n" +
+		"rx_bytes=0\n" +
+		"persistent_keepalive_interval=0\n"
+
+	want := []peerState{
+		{
+			WGPeer: wireguard.WGPeer{
+				PublicKey:           "uFmW/sycfx/G0lcqdu2hHVm80gvo5UOxXOS9hajnWjM=",
+				Endpoint:            "192.0.2.7:51820",
+				AllowedIPs:          []string{"10.42.0.7/32", "192.168.4.0/24"},
+				PersistentKeepalive: 25,
+			},
+			handshake: 1700000000,
+			transfer:  wireguard.PeerTransfer{RxBytes: 2224, TxBytes: 38333},
+		},
+		{WGPeer: wireguard.WGPeer{PublicKey: "WEAuaVuhdyscyTCXVfBDJR6nf9zxD75jmJzrfhkyE3Y="}},
+	}
+	if got := parsePeers(uapi); !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePeers =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestNotUp(t *testing.T) {
+	tun := New()
+	if _, err := tun.GetPeers(""); err != ErrNotUp {
+		t.Errorf("GetPeers err = %v, want ErrNotUp", err)
+	}
+	if _, err := tun.Listen("tcp", ":80"); err != ErrNotUp {
+		t.Errorf("Listen err = %v, want ErrNotUp", err)
+	}
+	if err := tun.Close(); err != ErrNotUp {
+		t.Errorf("Close err = %v, want ErrNotUp", err)
+	}
+}
+
+func newTestTunnel(t *testing.T, meshIP string) (*Tunnel, string, int) {
+	t.Helper()
+	privateKey, publicKey, err := wireguard.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Take a free port from the kernel and hand it to the device.
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+	pc.Close()
+	tun := New()
+	if err := tun.Up(privateKey, port, []netip.Addr{netip.MustParseAddr(meshIP)}, 1420); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = tun.Close() })
+	return tun, publicKey, port
+}
+
+// TestTunnelsConnect runs two tunnels in this process, peers them over
+// loopback and opens a TCP connection from one mesh IP to the other.
+func TestTunnelsConnect(t *testing.T) {
+	alpha, alphaKey, alphaPort := newTestTunnel(t, "10.99.0.1")
+	beta, betaKey, betaPort := newTestTunnel(t, "10.99.0.2")
+	var psk [32]byte
+	if err := alpha.SetPeer("", betaKey, psk, net.JoinHostPort("127.0.0.1", strconv.Itoa(betaPort)), "10.99.0.2/32", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := beta.SetPeer("", alphaKey, psk, net.JoinHostPort("127.0.0.1", strconv.Itoa(alphaPort)), "10.99.0.1/32", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := beta.Listen("tcp", ":8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("hello over the mesh"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := alpha.DialContext(ctx, "tcp", "10.99.0.2:8080")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	got, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello over the mesh" {
+		t.Errorf("read %q", got)
+	}
+
+	handshakes, err := alpha.GetLatestHandshakes("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handshakes[betaKey] == 0 {
+		t.Errorf("no handshake with beta recorded: %v", handshakes)
+	}
+	transfers, err := beta.GetPeerTransfers("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfers[alphaKey].RxBytes == 0 {
+		t.Errorf("no bytes from alpha counted: %v", transfers)
+	}
+
+	if err := alpha.RemovePeer("", betaKey); err != nil {
+		t.Fatal(err)
+	}
+	if peers, _ := alpha.GetPeers(""); len(peers) != 0 {
+		t.Errorf("peers after RemovePeer = %v", peers)
+	}
+}
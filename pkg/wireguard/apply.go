@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
 
 	"github.com/atvirokodosprendimai/wgmesh/pkg/ifname"
@@ -128,8 +129,9 @@ func ApplyFullConfiguration(client *ssh.Client, iface string, config *FullConfig
 	return nil
 }
 
-// SetPeer adds or updates a peer on the local WireGuard interface
-func SetPeer(iface, pubKey string, psk [32]byte, endpoint, allowedIPs string) error {
+// SetPeer adds or updates a peer on the local WireGuard interface.
+// keepalive is the PersistentKeepalive interval in seconds; 0 disables it.
+func SetPeer(iface, pubKey string, psk [32]byte, endpoint, allowedIPs string, keepalive int) error {
 	// Build wg set command
 	args := []string{"set", iface, "peer", pubKey}
 	var stdin strings.Reader
@@ -153,8 +155,12 @@ func SetPeer(iface, pubKey string, psk [32]byte, endpoint, allowedIPs string) er
 		args = append(args, "allowed-ips", allowedIPs)
 	}
 
-	// Add persistent keepalive for NAT traversal
-	args = append(args, "persistent-keepalive", "25")
+	// Persistent keepalive keeps NAT mappings open; "off" when not needed
+	if keepalive > 0 {
+		args = append(args, "persistent-keepalive", strconv.Itoa(keepalive))
+	} else {
+		args = append(args, "persistent-keepalive", "off")
+	}
 
 	cmd := exec.Command(wgPath, args...)
 	if hasStdin {
@@ -176,23 +182,41 @@ func RemovePeer(iface, pubKey string) error {
 	return nil
 }
 
-// GetPeers returns the list of peers on the local WireGuard interface
+// GetPeers returns the peers on the local WireGuard interface with their
+// endpoint, allowed IPs and keepalive as the kernel currently has them.
 func GetPeers(iface string) ([]WGPeer, error) {
-	cmd := exec.Command(wgPath, "show", iface, "peers")
+	cmd := exec.Command(wgPath, "show", iface, "dump")
 	output, err := cmd.Output()
 	if err != nil {
-		return nil, fmt.Errorf("wg show peers failed: %w", err)
+		return nil, fmt.Errorf("wg show dump failed: %w", err)
 	}
+	return parseDumpPeers(string(output)), nil
+}
 
+// parseDumpPeers parses the peer lines of `wg show <iface> dump`:
+// public key, preshared key, endpoint, allowed IPs, latest handshake,
+// rx, tx and persistent keepalive, tab-separated. The first line describes
+// the interface and has four fields.
+func parseDumpPeers(output string) []WGPeer {
 	var peers []WGPeer
-	for _, line := range strings.Split(string(output), "\n") {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			peers = append(peers, WGPeer{PublicKey: line})
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.Split(strings.TrimSpace(line), "\t")
+		if len(parts) < 8 {
+			continue
 		}
+		peer := WGPeer{PublicKey: parts[0]}
+		if parts[2] != "(none)" {
+			peer.Endpoint = parts[2]
+		}
+		if parts[3] != "(none)" {
+			peer.AllowedIPs = strings.Split(parts[3], ",")
+		}
+		if parts[7] != "off" {
+			peer.PersistentKeepalive, _ = strconv.Atoi(parts[7])
+		}
+		peers = append(peers, peer)
 	}
-
-	return peers, nil
+	return peers
 }
 
 // GetLatestHandshakes returns the most recent handshake time for each WG peer.
@@ -0,0 +1,21 @@
+package wireguard
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDumpPeers(t *testing.T) {
+	dump := "cHJpdmF0ZQ==\tcHVibGlj\t51820\toff\n" +
+		"a2V5MQ==\t(none)\t203.0.113.5:51820\t10.42.0.2/32,fd42::2/128\t1700000000\t100\t200\t25\n" +
+		"a2V5Mg==\tcHNr\t(none)\t(none)\t0\t0\t0\toff\n"
+
+	got := parseDumpPeers(dump)
+	want := []WGPeer{
+		{PublicKey: "a2V5MQ==", Endpoint: "203.0.113.5:51820", AllowedIPs: []string{"10.42.0.2/32", "fd42::2/128"}, PersistentKeepalive: 25},
+		{PublicKey: "a2V5Mg=="},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDumpPeers() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,39 @@
+package wireguard
+
+// Backend is the set of operations the mesh daemon performs on its local
+// WireGuard interface. Local runs the wg tool; FakeBackend keeps the
+// interface in memory so reconcile and health logic can be tested without
+// one.
+type Backend interface {
+	SetPeer(iface, pubKey string, psk [32]byte, endpoint, allowedIPs string, keepalive int) error
+	RemovePeer(iface, pubKey string) error
+	GetPeers(iface string) ([]WGPeer, error)
+	GetLatestHandshakes(iface string) (map[string]int64, error)
+	GetPeerTransfers(iface string) (map[string]PeerTransfer, error)
+}
+
+// Local is the Backend that drives the kernel (or wireguard-go) interface
+// through the package functions of the same names.
+type Local struct{}
+
+var _ Backend = Local{}
+
+func (Local) SetPeer(iface, pubKey string, psk [32]byte, endpoint, allowedIPs string, keepalive int) error {
+	return SetPeer(iface, pubKey, psk, endpoint, allowedIPs, keepalive)
+}
+
+func (Local) RemovePeer(iface, pubKey string) error {
+	return RemovePeer(iface, pubKey)
+}
+
+func (Local) GetPeers(iface string) ([]WGPeer, error) {
+	return GetPeers(iface)
+}
+
+func (Local) GetLatestHandshakes(iface string) (map[string]int64, error) {
+	return GetLatestHandshakes(iface)
+}
+
+func (Local) GetPeerTransfers(iface string) (map[string]PeerTransfer, error) {
+	return GetPeerTransfers(iface)
+}
@@ -0,0 +1,176 @@
+package wireguard
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// FakeBackend implements Backend in memory for tests. It keeps one set of
+// peers regardless of the interface name, ignores preshared keys, and
+// follows wg set where tests are likely to depend on it: an empty endpoint
+// or allowed-ips list leaves the current one, and an allowed IP moves to
+// the peer it was last given to.
+type FakeBackend struct {
+	mu         sync.Mutex
+	peers      map[string]*WGPeer
+	handshakes map[string]int64
+	transfers  map[string]PeerTransfer
+
+	// Err, when set, is returned by every operation.
+	Err error
+
+	// Sets and Removes count SetPeer and RemovePeer calls that succeeded.
+	Sets    int
+	Removes int
+}
+
+var _ Backend = (*FakeBackend)(nil)
+
+// NewFakeBackend returns a FakeBackend with no peers.
+func NewFakeBackend() *FakeBackend {
+	return &FakeBackend{
+		peers:      make(map[string]*WGPeer),
+		handshakes: make(map[string]int64),
+		transfers:  make(map[string]PeerTransfer),
+	}
+}
+
+func (f *FakeBackend) SetPeer(iface, pubKey string, psk [32]byte, endpoint, allowedIPs string, keepalive int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	if pubKey == "" {
+		return fmt.Errorf("wg set failed: empty public key")
+	}
+	p := f.peers[pubKey]
+	if p == nil {
+		p = &WGPeer{PublicKey: pubKey}
+		f.peers[pubKey] = p
+	}
+	if endpoint != "" {
+		p.Endpoint = endpoint
+	}
+	if allowedIPs != "" {
+		ips := strings.Split(allowedIPs, ",")
+		for key, other := range f.peers {
+			if key != pubKey {
+				other.AllowedIPs = without(other.AllowedIPs, ips)
+			}
+		}
+		p.AllowedIPs = ips
+	}
+	p.PersistentKeepalive = max(keepalive, 0)
+	f.Sets++
+	return nil
+}
+
+// without returns the entries of list that are not in drop.
+func without(list, drop []string) []string {
+	var out []string
+	for _, s := range list {
+		found := false
+		for _, d := range drop {
+			if s == d {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (f *FakeBackend) RemovePeer(iface, pubKey string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	delete(f.peers, pubKey)
+	delete(f.handshakes, pubKey)
+	delete(f.transfers, pubKey)
+	f.Removes++
+	return nil
+}
+
+// GetPeers returns the peers in public key order.
+func (f *FakeBackend) GetPeers(iface string) ([]WGPeer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	keys := make([]string, 0, len(f.peers))
+	for key := range f.peers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	peers := make([]WGPeer, len(keys))
+	for i, key := range keys {
+		p := *f.peers[key]
+		p.AllowedIPs = append([]string(nil), p.AllowedIPs...)
+		peers[i] = p
+	}
+	return peers, nil
+}
+
+// GetLatestHandshakes reports the times set with SetHandshake, and 0 for
+// the other peers, like wg for peers it never completed a handshake with.
+func (f *FakeBackend) GetLatestHandshakes(iface string) (map[string]int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	result := make(map[string]int64, len(f.peers))
+	for key := range f.peers {
+		result[key] = f.handshakes[key]
+	}
+	return result, nil
+}
+
+func (f *FakeBackend) GetPeerTransfers(iface string) (map[string]PeerTransfer, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	result := make(map[string]PeerTransfer, len(f.peers))
+	for key := range f.peers {
+		result[key] = f.transfers[key]
+	}
+	return result, nil
+}
+
+// Peer returns the peer with pubKey as the interface has it.
+func (f *FakeBackend) Peer(pubKey string) (WGPeer, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p, ok := f.peers[pubKey]
+	if !ok {
+		return WGPeer{}, false
+	}
+	peer := *p
+	peer.AllowedIPs = append([]string(nil), peer.AllowedIPs...)
+	return peer, true
+}
+
+// SetHandshake records a handshake with pubKey at unix time ts.
+func (f *FakeBackend) SetHandshake(pubKey string, ts int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handshakes[pubKey] = ts
+}
+
+// SetTransfer sets the cumulative byte counters of pubKey.
+func (f *FakeBackend) SetTransfer(pubKey string, rx, tx uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.transfers[pubKey] = PeerTransfer{RxBytes: rx, TxBytes: tx}
+}
@@ -0,0 +1,44 @@
+package wireguard
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFakeBackend(t *testing.T) {
+	wg := NewFakeBackend()
+	var psk [32]byte
+	if err := wg.SetPeer("wg0", "relay", psk, "203.0.113.1:51820", "10.42.0.1/32", 25); err != nil {
+		t.Fatal(err)
+	}
+	if err := wg.SetPeer("wg0", "target", psk, "203.0.113.2:51820", "10.42.0.2/32", 25); err != nil {
+		t.Fatal(err)
+	}
+	// Relaying target through relay takes its address away from it.
+	if err := wg.SetPeer("wg0", "relay", psk, "", "10.42.0.1/32,10.42.0.2/32", 0); err != nil {
+		t.Fatal(err)
+	}
+	wg.SetHandshake("target", 1700000000)
+
+	peers, _ := wg.GetPeers("wg0")
+	want := []WGPeer{
+		{PublicKey: "relay", Endpoint: "203.0.113.1:51820", AllowedIPs: []string{"10.42.0.1/32", "10.42.0.2/32"}},
+		{PublicKey: "target", Endpoint: "203.0.113.2:51820", PersistentKeepalive: 25},
+	}
+	if !reflect.DeepEqual(peers, want) {
+		t.Errorf("GetPeers() = %+v, want %+v", peers, want)
+	}
+	if hs, _ := wg.GetLatestHandshakes("wg0"); hs["target"] != 1700000000 || hs["relay"] != 0 {
+		t.Errorf("GetLatestHandshakes() = %v", hs)
+	}
+
+	if err := wg.RemovePeer("wg0", "target"); err != nil {
+		t.Fatal(err)
+	}
+	if hs, _ := wg.GetLatestHandshakes("wg0"); len(hs) != 1 {
+		t.Errorf("removed peer still reported: %v", hs)
+	}
+	if wg.Sets != 3 || wg.Removes != 1 {
+		t.Errorf("Sets, Removes = %d, %d, want 3, 1", wg.Sets, wg.Removes)
+	}
+}
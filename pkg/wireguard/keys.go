@@ -1,35 +1,43 @@
 package wireguard
 
 import (
-	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
-	"os/exec"
 	"strings"
 )
 
-// GenerateKeyPair generates a new WireGuard private/public key pair using the
-// `wg genkey` and `wg pubkey` commands.
+// GenerateKeyPair generates a new WireGuard private/public key pair, the
+// same as `wg genkey` and `wg pubkey` but without needing the wg tool, so
+// a userspace node can create its key.
 func GenerateKeyPair() (privateKey, publicKey string, err error) {
-	privCmd := exec.Command(wgPath, "genkey")
-	var privOut bytes.Buffer
-	privCmd.Stdout = &privOut
-
-	if err := privCmd.Run(); err != nil {
+	var raw [32]byte
+	if _, err := rand.Read(raw[:]); err != nil {
 		return "", "", fmt.Errorf("failed to generate private key: %w", err)
 	}
+	// Clamp the scalar like wg genkey does.
+	raw[0] &= 248
+	raw[31] = (raw[31] & 127) | 64
 
-	privateKey = strings.TrimSpace(privOut.String())
-
-	pubCmd := exec.Command(wgPath, "pubkey")
-	pubCmd.Stdin = strings.NewReader(privateKey)
-	var pubOut bytes.Buffer
-	pubCmd.Stdout = &pubOut
-
-	if err := pubCmd.Run(); err != nil {
+	privateKey = base64.StdEncoding.EncodeToString(raw[:])
+	publicKey, err = PublicKeyFromPrivate(privateKey)
+	if err != nil {
 		return "", "", fmt.Errorf("failed to generate public key: %w", err)
 	}
-
-	publicKey = strings.TrimSpace(pubOut.String())
-
 	return privateKey, publicKey, nil
 }
+
+// PublicKeyFromPrivate derives the public key of a base64 WireGuard private
+// key without shelling out to `wg pubkey`.
+func PublicKeyFromPrivate(privateKey string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(privateKey))
+	if err != nil || len(raw) != 32 {
+		return "", fmt.Errorf("invalid private key")
+	}
+	key, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid private key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(key.PublicKey().Bytes()), nil
+}
@@ -132,3 +132,85 @@ func RemovePersistentConfig(client *ssh.Client, iface string) error {
 
 	return nil
 }
+
+// ParseWgQuickConfig reads the interface and peers of a wg-quick config
+// file. Settings wgmesh does not manage (DNS, MTU, PostUp, ...) and
+// preshared keys are ignored.
+func ParseWgQuickConfig(text string) (*FullConfig, error) {
+	config := &FullConfig{}
+	var peer *WGPeer
+	section := ""
+
+	for n, line := range strings.Split(text, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.TrimSpace(line[1 : len(line)-1]))
+			switch section {
+			case "interface":
+			case "peer":
+				config.Peers = append(config.Peers, WGPeer{})
+				peer = &config.Peers[len(config.Peers)-1]
+			default:
+				return nil, fmt.Errorf("line %d: unknown section [%s]", n+1, section)
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n+1)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch section {
+		case "interface":
+			switch key {
+			case "privatekey":
+				config.Interface.PrivateKey = value
+			case "address":
+				if config.Interface.Address != "" {
+					value = config.Interface.Address + "," + value
+				}
+				config.Interface.Address = value
+			case "listenport":
+				if _, err := fmt.Sscanf(value, "%d", &config.Interface.ListenPort); err != nil {
+					return nil, fmt.Errorf("line %d: invalid ListenPort %q", n+1, value)
+				}
+			}
+		case "peer":
+			switch key {
+			case "publickey":
+				peer.PublicKey = value
+			case "endpoint":
+				peer.Endpoint = value
+			case "allowedips":
+				for _, ip := range strings.Split(value, ",") {
+					if ip = strings.TrimSpace(ip); ip != "" {
+						peer.AllowedIPs = append(peer.AllowedIPs, ip)
+					}
+				}
+			case "persistentkeepalive":
+				fmt.Sscanf(value, "%d", &peer.PersistentKeepalive)
+			}
+		default:
+			return nil, fmt.Errorf("line %d: setting outside of a section", n+1)
+		}
+	}
+
+	if config.Interface.PrivateKey == "" {
+		return nil, fmt.Errorf("no [Interface] PrivateKey")
+	}
+	for i, p := range config.Peers {
+		if p.PublicKey == "" {
+			return nil, fmt.Errorf("peer %d has no PublicKey", i+1)
+		}
+	}
+	return config, nil
+}
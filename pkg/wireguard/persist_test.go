@@ -0,0 +1,82 @@
+package wireguard
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/ssh"
+)
+
+func TestParseWgQuickConfigRoundTrip(t *testing.T) {
+	want := &FullConfig{
+		Interface: WGInterface{PrivateKey: "cHJpdmF0ZQ==", Address: "10.99.0.1/16", ListenPort: 51820},
+		Peers: []WGPeer{
+			{PublicKey: "a2V5QQ==", Endpoint: "203.0.113.1:51820", AllowedIPs: []string{"10.99.0.2/32", "192.168.10.0/24"}, PersistentKeepalive: 5},
+			{PublicKey: "a2V5Qg==", AllowedIPs: []string{"10.99.0.3/32"}, PersistentKeepalive: 5},
+		},
+	}
+	text := GenerateWgQuickConfig(want, []ssh.RouteEntry{{Network: "192.168.10.0/24", Gateway: "10.99.0.2"}})
+
+	got, err := ParseWgQuickConfig(text)
+	if err != nil {
+		t.Fatalf("ParseWgQuickConfig failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseWgQuickConfig() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseWgQuickConfigHandWritten(t *testing.T) {
+	got, err := ParseWgQuickConfig(`
+# office gateway
+[Interface]
+Address = 10.0.0.1/24, fd00::1/64
+PrivateKey = cHJpdmF0ZQ==
+DNS = 1.1.1.1
+
+[peer]
+PublicKey = a2V5QQ==
+PresharedKey = c2VjcmV0
+AllowedIPs = 10.0.0.2/32
+AllowedIPs = 172.16.0.0/16  # lab
+`)
+	if err != nil {
+		t.Fatalf("ParseWgQuickConfig failed: %v", err)
+	}
+	if got.Interface.Address != "10.0.0.1/24, fd00::1/64" {
+		t.Errorf("Address = %q", got.Interface.Address)
+	}
+	if len(got.Peers) != 1 || !reflect.DeepEqual(got.Peers[0].AllowedIPs, []string{"10.0.0.2/32", "172.16.0.0/16"}) {
+		t.Errorf("Peers = %+v", got.Peers)
+	}
+
+	for _, bad := range []string{
+		"[Interface]\nListenPort = 51820\n",
+		"PrivateKey = x\n",
+		"[Interface]\nPrivateKey = x\n[Peer]\nEndpoint = 1.2.3.4:5\n",
+		"[Tunnel]\n",
+	} {
+		if _, err := ParseWgQuickConfig(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestPublicKeyFromPrivate(t *testing.T) {
+	// RFC 7748 section 6.1 test vector (Alice).
+	priv, _ := hex.DecodeString("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	pub, _ := hex.DecodeString("8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a")
+
+	got, err := PublicKeyFromPrivate(base64.StdEncoding.EncodeToString(priv))
+	if err != nil {
+		t.Fatalf("PublicKeyFromPrivate failed: %v", err)
+	}
+	if want := base64.StdEncoding.EncodeToString(pub); got != want {
+		t.Errorf("PublicKeyFromPrivate() = %s, want %s", got, want)
+	}
+	if _, err := PublicKeyFromPrivate("not-a-key"); err == nil {
+		t.Error("expected error for invalid key")
+	}
+}
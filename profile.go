@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultProfileDir holds the named join configurations saved with
+// "wgmesh profile add".
+const defaultProfileDir = "/etc/wgmesh/profiles"
+
+var profileNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// profile is a named set of join flags, e.g. {"secret": "wgmesh://...",
+// "advertise-routes": "192.168.1.0/24"}.
+type profile struct {
+	Flags map[string]string `json:"flags"`
+}
+
+// profileDir returns the profile directory; WGMESH_PROFILE_DIR overrides it
+// for tests and non-root use.
+func profileDir() string {
+	if dir := os.Getenv("WGMESH_PROFILE_DIR"); dir != "" {
+		return dir
+	}
+	return defaultProfileDir
+}
+
+func profilePath(name string) (string, error) {
+	if !profileNameRe.MatchString(name) {
+		return "", fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return filepath.Join(profileDir(), name+".json"), nil
+}
+
+func loadProfile(name string) (*profile, error) {
+	path, err := profilePath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("profile %s does not exist (see 'wgmesh profile list')", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p profile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("profile %s: %w", name, err)
+	}
+	return &p, nil
+}
+
+// saveProfile writes a profile readable only by its owner: it usually
+// holds the mesh secret.
+func saveProfile(name string, p *profile) error {
+	path, err := profilePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// parseProfileFlags turns join flags as typed on the command line into a
+// profile. Flags are checked against join when the profile is used.
+func parseProfileFlags(args []string) (map[string]string, error) {
+	flags := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+			return nil, fmt.Errorf("unexpected argument %q: expected join flags", arg)
+		}
+		name := strings.TrimLeft(arg, "-")
+		value, hasValue := "", false
+		if k, v, ok := strings.Cut(name, "="); ok {
+			name, value, hasValue = k, v, true
+		}
+		if !hasValue {
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				i++
+				value = args[i]
+			} else {
+				// A boolean flag such as --gossip.
+				value = "true"
+			}
+		}
+		if name == "" || name == "profile" {
+			return nil, fmt.Errorf("invalid flag %q in a profile", arg)
+		}
+		flags[name] = value
+	}
+	return flags, nil
+}
+
+// applyProfile sets the flags of the named profile that were not given on
+// the command line.
+func applyProfile(fs *flag.FlagSet, name string) error {
+	p, err := loadProfile(name)
+	if err != nil {
+		return err
+	}
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, key := range sortedKeys(p.Flags) {
+		if set[key] {
+			continue
+		}
+		if fs.Lookup(key) == nil {
+			return fmt.Errorf("profile %s: unknown flag --%s", name, key)
+		}
+		if err := fs.Set(key, p.Flags[key]); err != nil {
+			return fmt.Errorf("profile %s: invalid --%s: %w", name, key, err)
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// profileCmd handles "wgmesh profile add|list|show|remove".
+func profileCmd() {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: wgmesh profile <add|list|show|remove>")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Commands:")
+		fmt.Fprintln(os.Stderr, "  add <name> <join flags>  Save join flags, e.g. --secret ... --advertise-routes ...")
+		fmt.Fprintln(os.Stderr, "  list                     List saved profiles")
+		fmt.Fprintln(os.Stderr, "  show <name>              Show a profile's flags (the secret is masked)")
+		fmt.Fprintln(os.Stderr, "  remove <name>            Delete a profile")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintf(os.Stderr, "Profiles are stored in %s; use one with 'wgmesh join --profile <name>'.\n", defaultProfileDir)
+		os.Exit(1)
+	}
+	if len(os.Args) < 3 {
+		usage()
+	}
+
+	switch action := os.Args[2]; action {
+	case "add":
+		if len(os.Args) < 4 {
+			fmt.Fprintln(os.Stderr, "Usage: wgmesh profile add <name> <join flags>")
+			os.Exit(1)
+		}
+		name := os.Args[3]
+		flags, err := parseProfileFlags(os.Args[4:])
+		if err == nil && len(flags) == 0 {
+			err = fmt.Errorf("no join flags given")
+		}
+		if err == nil {
+			err = saveProfile(name, &profile{Flags: flags})
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Saved profile %s; join with: wgmesh join --profile %s\n", name, name)
+
+	case "list":
+		entries, err := os.ReadDir(profileDir())
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		found := false
+		for _, e := range entries {
+			name, ok := strings.CutSuffix(e.Name(), ".json")
+			if !ok || e.IsDir() || !profileNameRe.MatchString(name) {
+				continue
+			}
+			found = true
+			fmt.Println(name)
+		}
+		if !found {
+			fmt.Println("No profiles")
+		}
+
+	case "show":
+		if len(os.Args) != 4 {
+			fmt.Fprintln(os.Stderr, "Usage: wgmesh profile show <name>")
+			os.Exit(1)
+		}
+		p, err := loadProfile(os.Args[3])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		for _, key := range sortedKeys(p.Flags) {
+			value := p.Flags[key]
+			if key == "secret" {
+				value = "********"
+			}
+			fmt.Printf("--%s=%s\n", key, value)
+		}
+
+	case "remove":
+		if len(os.Args) != 4 {
+			fmt.Fprintln(os.Stderr, "Usage: wgmesh profile remove <name>")
+			os.Exit(1)
+		}
+		path, err := profilePath(os.Args[3])
+		if err == nil {
+			err = os.Remove(path)
+		}
+		if os.IsNotExist(err) {
+			err = fmt.Errorf("profile %s does not exist", os.Args[3])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed profile %s\n", os.Args[3])
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown action: %s\n", action)
+		usage()
+	}
+}
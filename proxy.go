@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/proxy"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/rpc"
+)
+
+// proxyRefreshInterval is how often "wgmesh proxy" re-reads the peer list
+// from the daemon.
+const proxyRefreshInterval = 10 * time.Second
+
+// proxyCmd handles "wgmesh proxy": a SOCKS5 and HTTP proxy into the mesh
+// for machines that cannot run WireGuard themselves.
+func proxyCmd() {
+	fs := flag.NewFlagSet("proxy", flag.ExitOnError)
+	listen := fs.String("listen", "127.0.0.1:1080", "Address to accept SOCKS5 and HTTP proxy clients on")
+	socket := fs.String("socket", "", "Daemon RPC socket path (default: $WGMESH_SOCKET or the standard path)")
+	fs.Parse(os.Args[2:])
+
+	client := dialDaemon(*socket)
+	defer client.Close()
+
+	dir := &proxy.Directory{}
+	peers, err := proxyPeers(client)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+		os.Exit(1)
+	}
+	dir.Update(peers)
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to listen on %s: %v\n", *listen, err)
+		os.Exit(1)
+	}
+	if host, _, _ := net.SplitHostPort(*listen); !net.ParseIP(host).IsLoopback() {
+		fmt.Fprintf(os.Stderr, "Warning: anyone who can reach %s can use the proxy to reach the mesh\n", *listen)
+	}
+	fmt.Printf("Proxying into the mesh on %s (SOCKS5 and HTTP) for %d peers\n", ln.Addr(), len(peers)-1)
+
+	go func() {
+		for range time.Tick(proxyRefreshInterval) {
+			peers, err := proxyPeers(client)
+			if err != nil {
+				log.Printf("[Proxy] Failed to refresh peers: %v", err)
+				continue
+			}
+			dir.Update(peers)
+		}
+	}()
+
+	server := &proxy.Server{Directory: dir}
+	if err := server.Serve(ln); err != nil {
+		fmt.Fprintf(os.Stderr, "Proxy error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// proxyPeers returns the daemon's active peers and the node itself, which
+// the proxy may also reach by its mesh IP.
+func proxyPeers(client *rpc.Client) ([]proxy.Peer, error) {
+	result, err := client.Call("peers.list", nil)
+	if err != nil {
+		return nil, err
+	}
+	resultMap, _ := result.(map[string]interface{})
+	peersData, ok := resultMap["peers"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid peers data")
+	}
+
+	peers := make([]proxy.Peer, 0, len(peersData)+1)
+	for _, peerData := range peersData {
+		m, ok := peerData.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var p proxy.Peer
+		p.Hostname, _ = m["hostname"].(string)
+		p.MeshIP, _ = m["mesh_ip"].(string)
+		p.MeshIPv6, _ = m["mesh_ipv6"].(string)
+		routes, _ := m["routable_networks"].([]interface{})
+		for _, r := range routes {
+			if s, ok := r.(string); ok {
+				p.Routes = append(p.Routes, s)
+			}
+		}
+		peers = append(peers, p)
+	}
+
+	status, err := client.Call("daemon.status", nil)
+	if err != nil {
+		return nil, err
+	}
+	self := proxy.Peer{}
+	if m, ok := status.(map[string]interface{}); ok {
+		self.MeshIP, _ = m["mesh_ip"].(string)
+	}
+	self.Hostname, _ = os.Hostname()
+	return append(peers, self), nil
+}
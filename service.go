@@ -15,7 +15,9 @@ import (
 
 	lighthouse "github.com/atvirokodosprendimai/lighthouse-go"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/crypto"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
 	"github.com/atvirokodosprendimai/wgmesh/pkg/mesh"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/rpc"
 )
 
 const (
@@ -339,45 +341,41 @@ func serviceRemoveCmd() {
 	fmt.Printf("Service removed: %s\n", name)
 }
 
-// resolveSecret returns the secret from the flag or WGMESH_SECRET env var.
+// resolveSecret returns the secret from the flag or WGMESH_SECRET env var,
+// falling back to a running wgmesh agent or local daemon (secret.unlock).
 // The value is normalized to strip any wgmesh:// URI wrapper.
 func resolveSecret(flagValue string) string {
-	if flagValue != "" {
-		return normalizeSecret(flagValue)
-	}
-	return normalizeSecret(os.Getenv("WGMESH_SECRET"))
+	return normalizeSecret(resolveSecretURI(flagValue))
 }
 
-// normalizeSecret strips the wgmesh:// URI prefix, optional version segment,
-// and query parameters so callers can pass secrets in either raw or URI form.
-// Example: "wgmesh://v1/K7x2...?relay=true" → "K7x2..."
-func normalizeSecret(input string) string {
-	input = strings.TrimSpace(input)
-	if input == "" {
-		return ""
+// resolveSecretURI is resolveSecret without the normalization, for
+// commands that need the options of a v2 URI.
+func resolveSecretURI(flagValue string) string {
+	if flagValue != "" {
+		return strings.TrimSpace(flagValue)
 	}
-
-	const uriPrefix = "wgmesh://"
-	if !strings.HasPrefix(input, uriPrefix) {
-		return input
+	if env := os.Getenv("WGMESH_SECRET"); env != "" {
+		return strings.TrimSpace(env)
 	}
-
-	input = strings.TrimPrefix(input, uriPrefix)
-
-	// Strip optional version segment (e.g. "v1/")
-	parts := strings.SplitN(input, "/", 2)
-	if len(parts) == 2 {
-		input = parts[1]
-	} else {
-		input = parts[0]
+	for _, socketPath := range []string{rpc.AgentSocketPath(), getRPCSocketPath()} {
+		if secret, err := rpc.UnlockSecret(socketPath); err == nil {
+			return strings.TrimSpace(secret)
+		}
 	}
+	return ""
+}
 
-	// Strip query parameters
-	if idx := strings.Index(input, "?"); idx != -1 {
-		input = input[:idx]
+// normalizeSecret strips the wgmesh:// URI wrapper so callers can pass
+// secrets in either raw or URI form, exiting if a v2 URI fails its
+// signature check.
+// Example: "wgmesh://v1/K7x2...?relay=true" → "K7x2..."
+func normalizeSecret(input string) string {
+	secret, _, err := daemon.ParseSecretURI(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid secret: %v\n", err)
+		os.Exit(1)
 	}
-
-	return input
+	return secret
 }
 
 // resolveAccount loads or creates account configuration.
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// stateCmd handles "wgmesh state export|import": it dumps the running
+// daemon's peer store, or seeds it from a dump taken on another node.
+func stateCmd() {
+	usage := func() {
+		fmt.Fprintln(os.Stderr, "Usage: wgmesh state <export|import>")
+		fmt.Fprintln(os.Stderr, "")
+		fmt.Fprintln(os.Stderr, "Commands:")
+		fmt.Fprintln(os.Stderr, "  export            Write the peer store as JSON to stdout")
+		fmt.Fprintln(os.Stderr, "  import <file|->   Seed the peer store from an exported snapshot")
+		os.Exit(1)
+	}
+	if len(os.Args) < 3 {
+		usage()
+	}
+
+	action := os.Args[2]
+	fs := flag.NewFlagSet("state "+action, flag.ExitOnError)
+	socket := fs.String("socket", "", "Daemon RPC socket path (default: $WGMESH_SOCKET or the standard path)")
+	fs.Parse(os.Args[3:])
+
+	switch action {
+	case "export":
+		client := dialDaemon(*socket)
+		defer client.Close()
+
+		result, err := client.Call("state.export", nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+			os.Exit(1)
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode snapshot: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+
+	case "import":
+		if fs.NArg() != 1 {
+			fmt.Fprintln(os.Stderr, "Usage: wgmesh state import <file|->")
+			os.Exit(1)
+		}
+		snapshot, err := readSnapshot(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		client := dialDaemon(*socket)
+		defer client.Close()
+
+		result, err := client.Call("state.import", map[string]interface{}{"snapshot": snapshot})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "RPC error: %v\n", err)
+			os.Exit(1)
+		}
+		m, _ := result.(map[string]interface{})
+		imported, _ := m["imported"].(float64)
+		skipped, _ := m["skipped"].(float64)
+		fmt.Printf("Imported %d peers (%d skipped)\n", int(imported), int(skipped))
+
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown action: %s\n", action)
+		usage()
+	}
+}
+
+// readSnapshot reads an exported snapshot from path, or stdin for "-".
+func readSnapshot(path string) (map[string]interface{}, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,498 @@
+//go:build e2e
+
+// Package e2e runs real wgmesh daemons in Linux network namespaces joined by
+// veth links, with NAT routers, packet loss and IPv6-only hosts in between.
+// See README.md for the topology and how to run it.
+package e2e
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+)
+
+const (
+	// The "internet" is one bridge. 198.18.0.0/15 is reserved for network
+	// benchmarks, so it is not routed, yet wgmesh treats it as public (STUN
+	// results in private ranges are ignored). The IPv6 prefix must avoid
+	// 2001:db8::/32 for the same reason.
+	inetPrefix4 = "198.18.0."
+	inetPrefix6 = "2a01:e2e::"
+
+	wgPort        = "51820"
+	stunPort      = "3478"
+	convergeAfter = 3 * time.Minute
+)
+
+var wgmeshBin string
+
+func TestMain(m *testing.M) {
+	if bin := os.Getenv("WGMESH_E2E_BINARY"); bin != "" {
+		wgmeshBin = bin
+		os.Exit(m.Run())
+	}
+
+	dir, err := os.MkdirTemp("", "wgmesh-e2e")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	wgmeshBin = filepath.Join(dir, "wgmesh")
+	build := exec.Command("go", "build", "-o", wgmeshBin, "../..")
+	build.Stdout, build.Stderr = os.Stderr, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "building wgmesh: %v\n", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+var (
+	prereqOnce sync.Once
+	prereqErr  error
+)
+
+// requirePrereqs skips the test unless it runs as root with the tools and
+// kernel support the lab needs.
+func requirePrereqs(t *testing.T) {
+	t.Helper()
+	prereqOnce.Do(func() {
+		if os.Geteuid() != 0 {
+			prereqErr = fmt.Errorf("must run as root")
+			return
+		}
+		for _, tool := range []string{"ip", "nft", "tc", "wg", "ping", "sysctl"} {
+			if _, err := exec.LookPath(tool); err != nil {
+				prereqErr = fmt.Errorf("%s not found in PATH", tool)
+				return
+			}
+		}
+		ns := "wgmesh-e2e-probe"
+		if out, err := exec.Command("ip", "netns", "add", ns).CombinedOutput(); err != nil {
+			prereqErr = fmt.Errorf("cannot create network namespaces: %s", strings.TrimSpace(string(out)))
+			return
+		}
+		defer exec.Command("ip", "netns", "del", ns).Run()
+		if out, err := exec.Command("ip", "-n", ns, "link", "add", "wg0", "type", "wireguard").CombinedOutput(); err != nil {
+			prereqErr = fmt.Errorf("kernel lacks WireGuard: %s", strings.TrimSpace(string(out)))
+		}
+	})
+	if prereqErr != nil {
+		t.Skipf("skipping e2e test: %v", prereqErr)
+	}
+}
+
+// natKind selects how a NAT router maps outgoing flows.
+type natKind int
+
+const (
+	// coneNAT keeps one mapping per local address and port, the common
+	// home-router behaviour that hole punching relies on.
+	coneNAT natKind = iota
+	// symmetricNAT picks a new random port for every destination.
+	symmetricNAT
+)
+
+// lab is one isolated "internet" with two introducers on it. Every name it
+// creates carries a random id, so labs do not collide with each other or
+// with leftovers of an aborted run.
+type lab struct {
+	t       *testing.T
+	id      string
+	secret  string
+	dir     string
+	inet    string
+	env     []string
+	stun    []string
+	intros  []*node
+	nodes   []*node
+	ns      []string
+	subnets int
+}
+
+// node is one running daemon.
+type node struct {
+	lab   *lab
+	name  string
+	ns    string
+	iface string
+	wan   string // address other hosts on the internet see
+	log   string
+	cmd   *exec.Cmd
+	done  chan struct{}
+}
+
+// wgPeer is one peer line of `wg show <iface> dump`.
+type wgPeer struct {
+	endpoint   string
+	allowedIPs []string
+	handshake  time.Time
+}
+
+// newLab builds the internet bridge and starts two dual-stack introducers
+// that serve as DHT bootstrap nodes and STUN servers for everyone else.
+func newLab(t *testing.T) *lab {
+	t.Helper()
+	requirePrereqs(t)
+
+	var id [2]byte
+	rand.Read(id[:])
+	secret, err := daemon.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := daemon.NewConfig(daemon.DaemonOpts{Secret: secret, InterfaceName: "wm" + hex.EncodeToString(id[:])})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := &lab{t: t, id: hex.EncodeToString(id[:]), secret: secret, dir: t.TempDir()}
+	t.Cleanup(l.close)
+
+	l.inet = l.addNS("inet")
+	l.ip(l.inet, "link", "add", "br0", "type", "bridge")
+	l.ip(l.inet, "link", "set", "br0", "up")
+
+	// The DHT listens one port above the derived exchange port.
+	dhtPort := strconv.Itoa(int(cfg.Keys.GossipPort) + 1)
+	var bootstrap []string
+	var hosts [][3]string // name, namespace, address
+	for i, host := range []string{"10", "11"} {
+		name := fmt.Sprintf("intro%d", i+1)
+		hosts = append(hosts, [3]string{name, l.addHost(name, inetPrefix4+host, inetPrefix6+host), inetPrefix4 + host})
+		bootstrap = append(bootstrap,
+			net.JoinHostPort(inetPrefix4+host, dhtPort),
+			net.JoinHostPort(inetPrefix6+host, dhtPort))
+		l.stun = append(l.stun, net.JoinHostPort(inetPrefix4+host, stunPort))
+	}
+	l.env = []string{"WGMESH_DHT_BOOTSTRAP=" + strings.Join(bootstrap, ",")}
+
+	for _, h := range hosts {
+		l.intros = append(l.intros, l.start(h[0], h[1], h[2], "--introducer"))
+	}
+	return l
+}
+
+// addNS creates a namespace with loopback up and IPv6 DAD disabled, so
+// addresses are usable immediately.
+func (l *lab) addNS(name string) string {
+	ns := l.nsName(name)
+	l.run("ip", "netns", "add", ns)
+	l.ns = append(l.ns, ns)
+	l.ip(ns, "link", "set", "lo", "up")
+	l.sysctl(ns, "net.ipv6.conf.all.accept_dad=0", "net.ipv6.conf.default.accept_dad=0")
+	return ns
+}
+
+// nsName returns the namespace addNS creates for name.
+func (l *lab) nsName(name string) string {
+	return "wm" + l.id + "-" + name
+}
+
+// addHost creates a namespace plugged straight into the internet bridge.
+// Either address may be empty.
+func (l *lab) addHost(name, addr4, addr6 string) string {
+	ns := l.addNS(name)
+	port := "p-" + name
+	l.run("ip", "link", "add", "eth0", "netns", ns, "type", "veth", "peer", "name", port, "netns", l.inet)
+	l.ip(l.inet, "link", "set", port, "master", "br0", "up")
+	if addr4 != "" {
+		l.ip(ns, "addr", "add", addr4+"/24", "dev", "eth0")
+	}
+	if addr6 != "" {
+		l.ip(ns, "addr", "add", addr6+"/64", "dev", "eth0", "nodad")
+	}
+	l.ip(ns, "link", "set", "eth0", "up")
+	return ns
+}
+
+// addNATHost puts a host behind its own NAT router whose public address is
+// wan, and returns the host's namespace.
+func (l *lab) addNATHost(name, wan string, kind natKind) string {
+	router := l.addHost("r-"+name, wan, "")
+	host := l.addNS(name)
+
+	l.subnets++
+	lan := fmt.Sprintf("10.%d.0.", l.subnets)
+	l.run("ip", "link", "add", "lan0", "netns", router, "type", "veth", "peer", "name", "eth0", "netns", host)
+	l.ip(router, "addr", "add", lan+"1/24", "dev", "lan0")
+	l.ip(router, "link", "set", "lan0", "up")
+	l.ip(host, "addr", "add", lan+"2/24", "dev", "eth0")
+	l.ip(host, "link", "set", "eth0", "up")
+	l.ip(host, "route", "add", "default", "via", lan+"1")
+
+	l.sysctl(router, "net.ipv4.ip_forward=1")
+	masquerade := "masquerade"
+	if kind == symmetricNAT {
+		masquerade = "masquerade random"
+	}
+	ruleset := fmt.Sprintf(`table ip nat {
+	chain postrouting {
+		type nat hook postrouting priority srcnat;
+		oifname "eth0" %s
+	}
+}
+`, masquerade)
+	cmd := exec.Command("ip", "netns", "exec", router, "nft", "-f", "-")
+	cmd.Stdin = strings.NewReader(ruleset)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		l.t.Fatalf("nft in %s: %s: %v", router, out, err)
+	}
+	return host
+}
+
+// addLoss makes dev in ns drop the given share of outgoing packets.
+func (l *lab) addLoss(ns, dev string, percent int) {
+	l.run("ip", "netns", "exec", ns, "tc", "qdisc", "add", "dev", dev, "root", "netem", "loss", fmt.Sprintf("%d%%", percent))
+}
+
+// start runs `wgmesh join` in ns. wan is the address peers will see.
+func (l *lab) start(name, ns, wan string, extra ...string) *node {
+	n := &node{
+		lab:   l,
+		name:  name,
+		ns:    ns,
+		iface: "wm" + l.id + name,
+		wan:   wan,
+		log:   filepath.Join(l.dir, name+".log"),
+		done:  make(chan struct{}),
+	}
+	if len(n.iface) > 15 {
+		l.t.Fatalf("node name %q makes interface name %q too long", name, n.iface)
+	}
+
+	args := []string{"netns", "exec", ns, wgmeshBin, "join",
+		"--secret", l.secret,
+		"--interface", n.iface,
+		"--listen-port", wgPort,
+		"--gossip",
+		"--no-lan-discovery",
+		"--log-level", "debug",
+	}
+	for _, s := range l.stun {
+		args = append(args, "--stun-server", s)
+	}
+	args = append(args, extra...)
+
+	logFile, err := os.Create(n.log)
+	if err != nil {
+		l.t.Fatal(err)
+	}
+	n.cmd = exec.Command("ip", args...)
+	n.cmd.Env = append(os.Environ(), l.env...)
+	n.cmd.Env = append(n.cmd.Env, "WGMESH_SOCKET="+filepath.Join(l.dir, name+".sock"))
+	n.cmd.Stdout, n.cmd.Stderr = logFile, logFile
+	if err := n.cmd.Start(); err != nil {
+		logFile.Close()
+		l.t.Fatalf("starting %s: %v", name, err)
+	}
+	go func() {
+		n.cmd.Wait()
+		logFile.Close()
+		close(n.done)
+	}()
+	l.nodes = append(l.nodes, n)
+	return n
+}
+
+// close stops the daemons, printing their logs if the test failed, and
+// removes the namespaces and the daemons' state files.
+func (l *lab) close() {
+	for _, n := range l.nodes {
+		n.cmd.Process.Signal(syscall.SIGTERM)
+	}
+	for _, n := range l.nodes {
+		select {
+		case <-n.done:
+		case <-time.After(10 * time.Second):
+			n.cmd.Process.Kill()
+			<-n.done
+		}
+		if l.t.Failed() {
+			l.dumpLog(n)
+		}
+		files, _ := filepath.Glob(filepath.Join("/var/lib/wgmesh", n.iface+"*"))
+		for _, f := range files {
+			os.Remove(f)
+		}
+	}
+	for i := len(l.ns) - 1; i >= 0; i-- {
+		exec.Command("ip", "netns", "del", l.ns[i]).Run()
+	}
+}
+
+// dumpLog prints the tail of a node's log.
+func (l *lab) dumpLog(n *node) {
+	const tail = 200
+	f, err := os.Open(n.log)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > tail {
+			lines = lines[1:]
+		}
+	}
+	l.t.Logf("==== last %d log lines of %s ====\n%s", len(lines), n.name, strings.Join(lines, "\n"))
+}
+
+func (l *lab) run(name string, args ...string) string {
+	l.t.Helper()
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		l.t.Fatalf("%s %s: %s: %v", name, strings.Join(args, " "), strings.TrimSpace(string(out)), err)
+	}
+	return string(out)
+}
+
+func (l *lab) ip(ns string, args ...string) string {
+	l.t.Helper()
+	return l.run("ip", append([]string{"-n", ns}, args...)...)
+}
+
+func (l *lab) sysctl(ns string, settings ...string) {
+	l.t.Helper()
+	l.run("ip", append([]string{"netns", "exec", ns, "sysctl", "-q", "-w"}, settings...)...)
+}
+
+// eventually polls check every two seconds until it succeeds, failing the
+// test with the last error after timeout.
+func eventually(t *testing.T, timeout time.Duration, what string, check func() error) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		err := check()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s: not reached after %v: %v", what, timeout, err)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
+
+// exec runs a command inside the node's namespace.
+func (n *node) exec(name string, args ...string) (string, error) {
+	out, err := exec.Command("ip", append([]string{"netns", "exec", n.ns, name}, args...)...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s %s in %s: %s: %w", name, strings.Join(args, " "), n.name, strings.TrimSpace(string(out)), err)
+	}
+	return string(out), nil
+}
+
+// meshIP returns the node's IPv4 address on its WireGuard interface.
+func (n *node) meshIP() (string, error) {
+	out, err := n.exec("ip", "-4", "-o", "addr", "show", "dev", n.iface)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(out)
+	for i, f := range fields {
+		if f == "inet" && i+1 < len(fields) {
+			return strings.Split(fields[i+1], "/")[0], nil
+		}
+	}
+	return "", fmt.Errorf("%s has no mesh address yet", n.name)
+}
+
+func (n *node) pubKey() (string, error) {
+	out, err := n.exec("wg", "show", n.iface, "public-key")
+	return strings.TrimSpace(out), err
+}
+
+// peers parses `wg show <iface> dump`, keyed by public key.
+func (n *node) peers() (map[string]wgPeer, error) {
+	out, err := n.exec("wg", "show", n.iface, "dump")
+	if err != nil {
+		return nil, err
+	}
+	peers := make(map[string]wgPeer)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	for _, line := range lines[1:] { // the first line describes the interface
+		f := strings.Split(line, "\t")
+		if len(f) < 8 {
+			continue
+		}
+		p := wgPeer{endpoint: f[2]}
+		if f[3] != "(none)" {
+			p.allowedIPs = strings.Split(f[3], ",")
+		}
+		if ts, _ := strconv.ParseInt(f[4], 10, 64); ts > 0 {
+			p.handshake = time.Unix(ts, 0)
+		}
+		peers[f[0]] = p
+	}
+	return peers, nil
+}
+
+// ping sends one echo request over the mesh.
+func (n *node) ping(ip string) error {
+	_, err := n.exec("ping", "-c", "1", "-W", "2", ip)
+	return err
+}
+
+// waitConnected waits until every node can ping every other node's mesh
+// address.
+func (l *lab) waitConnected(timeout time.Duration, nodes ...*node) {
+	l.t.Helper()
+	eventually(l.t, timeout, "full mesh connectivity", func() error {
+		for _, from := range nodes {
+			for _, to := range nodes {
+				if from == to {
+					continue
+				}
+				ip, err := to.meshIP()
+				if err != nil {
+					return err
+				}
+				if err := from.ping(ip); err != nil {
+					return fmt.Errorf("%s cannot reach %s (%s): %w", from.name, to.name, ip, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// route returns the WireGuard peer that carries traffic from n to target's
+// mesh address, and that peer's entry.
+func (n *node) route(target *node) (string, wgPeer, error) {
+	ip, err := target.meshIP()
+	if err != nil {
+		return "", wgPeer{}, err
+	}
+	peers, err := n.peers()
+	if err != nil {
+		return "", wgPeer{}, err
+	}
+	for key, p := range peers {
+		for _, allowed := range p.allowedIPs {
+			if allowed == ip+"/32" {
+				return key, p, nil
+			}
+		}
+	}
+	return "", wgPeer{}, fmt.Errorf("%s has no WireGuard peer for %s", n.name, ip)
+}
@@ -0,0 +1,113 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestConeNATDirectPath checks that two nodes behind separate cone NATs
+// converge with the introducers and end up talking directly, through the
+// holes punched in both NATs.
+func TestConeNATDirectPath(t *testing.T) {
+	l := newLab(t)
+	a := l.start("a", l.addNATHost("a", inetPrefix4+"21", coneNAT), inetPrefix4+"21")
+	b := l.start("b", l.addNATHost("b", inetPrefix4+"22", coneNAT), inetPrefix4+"22")
+
+	l.waitConnected(convergeAfter, append(l.intros, a, b)...)
+
+	eventually(t, convergeAfter, "direct path a -> b", func() error {
+		return expectDirect(a, b)
+	})
+	eventually(t, convergeAfter, "direct path b -> a", func() error {
+		return expectDirect(b, a)
+	})
+}
+
+// TestSymmetricNATRelay checks that two nodes behind symmetric NATs, which
+// cannot punch through to each other, reach each other via an introducer.
+func TestSymmetricNATRelay(t *testing.T) {
+	l := newLab(t)
+	a := l.start("a", l.addNATHost("a", inetPrefix4+"21", symmetricNAT), inetPrefix4+"21")
+	b := l.start("b", l.addNATHost("b", inetPrefix4+"22", symmetricNAT), inetPrefix4+"22")
+
+	l.waitConnected(convergeAfter, append(l.intros, a, b)...)
+
+	eventually(t, convergeAfter, "relay path a -> b", func() error {
+		return l.expectRelayed(a, b)
+	})
+}
+
+// TestPacketLoss checks convergence when both NAT uplinks drop a tenth of
+// all packets, the exchange and DHT traffic included.
+func TestPacketLoss(t *testing.T) {
+	l := newLab(t)
+	a := l.start("a", l.addNATHost("a", inetPrefix4+"21", coneNAT), inetPrefix4+"21")
+	b := l.start("b", l.addNATHost("b", inetPrefix4+"22", coneNAT), inetPrefix4+"22")
+	l.addLoss(l.nsName("r-a"), "eth0", 10)
+	l.addLoss(l.nsName("r-b"), "eth0", 10)
+
+	l.waitConnected(2*convergeAfter, append(l.intros, a, b)...)
+}
+
+// TestIPv6Only checks that hosts without any IPv4 connectivity find each
+// other through the dual-stack introducers and tunnel over IPv6.
+func TestIPv6Only(t *testing.T) {
+	l := newLab(t)
+	a := l.start("a", l.addHost("a", "", inetPrefix6+"21"), inetPrefix6+"21")
+	b := l.start("b", l.addHost("b", "", inetPrefix6+"22"), inetPrefix6+"22")
+
+	l.waitConnected(convergeAfter, a, b)
+
+	eventually(t, convergeAfter, "direct IPv6 path a -> b", func() error {
+		return expectDirect(a, b)
+	})
+}
+
+// expectDirect checks that from reaches to through to's own WireGuard peer
+// entry, at to's public address, with a completed handshake.
+func expectDirect(from, to *node) error {
+	toKey, err := to.pubKey()
+	if err != nil {
+		return err
+	}
+	key, p, err := from.route(to)
+	if err != nil {
+		return err
+	}
+	if key != toKey {
+		return fmt.Errorf("%s routes %s via another peer", from.name, to.name)
+	}
+	host, _, err := net.SplitHostPort(p.endpoint)
+	if err != nil {
+		return fmt.Errorf("%s has no endpoint for %s: %q", from.name, to.name, p.endpoint)
+	}
+	if !net.ParseIP(host).Equal(net.ParseIP(to.wan)) {
+		return fmt.Errorf("%s reaches %s at %s, want %s", from.name, to.name, host, to.wan)
+	}
+	if time.Since(p.handshake) > 3*time.Minute {
+		return fmt.Errorf("%s has no recent handshake with %s", from.name, to.name)
+	}
+	return nil
+}
+
+// expectRelayed checks that from sends traffic for to through an introducer.
+func (l *lab) expectRelayed(from, to *node) error {
+	key, _, err := from.route(to)
+	if err != nil {
+		return err
+	}
+	for _, intro := range l.intros {
+		introKey, err := intro.pubKey()
+		if err != nil {
+			return err
+		}
+		if key == introKey {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s routes %s directly, not through an introducer", from.name, to.name)
+}
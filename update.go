@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/daemon"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/rpc"
+	"github.com/atvirokodosprendimai/wgmesh/pkg/update"
+)
+
+// updateCmd handles "wgmesh update": install the latest signed release over
+// this binary and restart the daemons running it in place.
+func updateCmd() {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	check := fs.Bool("check", false, "Only report whether a newer release is available")
+	tag := fs.String("version", "", "Install this release, e.g. v1.4.0, even if it is older (default: the latest)")
+	force := fs.Bool("force", false, "Reinstall even if this version is already running")
+	noRestart := fs.Bool("no-restart", false, "Install the binary but leave running daemons alone")
+	socket := fs.String("socket", "", "Restart only the daemon on this RPC socket (default: every mesh in "+daemon.NetworksPath()+")")
+	fs.Parse(os.Args[2:])
+
+	client, err := update.NewClient()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	var rel *update.Release
+	if *tag != "" {
+		rel, err = client.Tag(ctx, *tag)
+	} else {
+		rel, err = client.Latest(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to look up the release: %v\n", err)
+		os.Exit(1)
+	}
+
+	newer := update.Newer(version, rel.Version())
+	if *check {
+		if newer {
+			fmt.Printf("wgmesh %s is available (running %s)\n", rel.Version(), version)
+		} else {
+			fmt.Printf("wgmesh %s is up to date\n", version)
+		}
+		return
+	}
+	if !newer && *tag == "" && !*force {
+		fmt.Printf("wgmesh %s is up to date\n", version)
+		return
+	}
+	if rel.Version() == version && !*force {
+		fmt.Printf("wgmesh %s is already installed\n", version)
+		return
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Downloading wgmesh %s...\n", rel.Version())
+	data, err := client.Download(ctx, rel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to download the release: %v\n", err)
+		os.Exit(1)
+	}
+	if err := update.Install(exe, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to install the release: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Installed wgmesh %s at %s\n", rel.Version(), exe)
+
+	if *noRestart {
+		return
+	}
+	for _, path := range updateSockets(*socket) {
+		c, err := rpc.NewClient(path)
+		if err != nil {
+			continue
+		}
+		_, err = c.Call("daemon.restart", nil)
+		c.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to restart the daemon on %s: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("Restarting the daemon on %s; its tunnels stay up\n", path)
+	}
+}
+
+// updateSockets returns the RPC sockets of the daemons to restart: the one
+// given, else those of every joined mesh and the default one.
+func updateSockets(socket string) []string {
+	if socket != "" {
+		return []string{socket}
+	}
+	seen := make(map[string]bool)
+	var paths []string
+	add := func(path string) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	if recs, err := daemon.LoadNetworks(); err == nil {
+		for _, rec := range recs {
+			add(rec.SocketPath)
+		}
+	}
+	add(os.Getenv("WGMESH_SOCKET"))
+	add(getRPCSocketPath())
+	return paths
+}
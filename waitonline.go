@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/atvirokodosprendimai/wgmesh/pkg/rpc"
+)
+
+// waitOnlinePollInterval is how often wait-online asks the daemon.
+const waitOnlinePollInterval = time.Second
+
+// waitOnlineCmd handles "wgmesh wait-online": it blocks until the daemon
+// reports its interface up and enough peers with a recent handshake, so
+// that units and CI steps can wait for a usable mesh. A daemon that is not
+// answering yet counts as not ready.
+func waitOnlineCmd() {
+	fs := flag.NewFlagSet("wait-online", flag.ExitOnError)
+	timeout := fs.Duration("timeout", 60*time.Second, "Give up after this long (0 = wait forever)")
+	minPeers := fs.Int("min-peers", 1, "Peers with a recent handshake needed (0 = interface up is enough)")
+	quiet := fs.Bool("quiet", false, "Print nothing, only set the exit status")
+	socket := fs.String("socket", "", "Daemon RPC socket path (default: $WGMESH_SOCKET or the standard path)")
+	fs.Parse(os.Args[2:])
+
+	if *minPeers < 0 {
+		fmt.Fprintln(os.Stderr, "Error: --min-peers must not be negative")
+		os.Exit(1)
+	}
+	if *socket == "" {
+		*socket = os.Getenv("WGMESH_SOCKET")
+	}
+	if *socket == "" {
+		*socket = getRPCSocketPath()
+	}
+
+	start := time.Now()
+	for {
+		ready, reason := checkReady(*socket, *minPeers)
+		if ready {
+			if !*quiet {
+				fmt.Printf("Mesh is online after %v\n", time.Since(start).Round(time.Second))
+			}
+			return
+		}
+		if *timeout > 0 && time.Since(start) >= *timeout {
+			if !*quiet {
+				fmt.Fprintf(os.Stderr, "Mesh not online after %v: %s\n", *timeout, reason)
+			}
+			os.Exit(1)
+		}
+		time.Sleep(waitOnlinePollInterval)
+	}
+}
+
+// checkReady asks the daemon on socket whether the mesh is ready, returning
+// why not when it is not.
+func checkReady(socket string, minPeers int) (bool, string) {
+	client, err := rpc.NewClient(socket)
+	if err != nil {
+		return false, "daemon is not running"
+	}
+	defer client.Close()
+
+	result, err := client.Call("daemon.ready", map[string]interface{}{"min_peers": minPeers})
+	if err != nil {
+		return false, err.Error()
+	}
+	status, _ := result.(map[string]interface{})
+	ready, _ := status["ready"].(bool)
+	reason, _ := status["reason"].(string)
+	return ready, reason
+}